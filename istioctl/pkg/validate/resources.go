@@ -0,0 +1,237 @@
+// Copyright Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/go-multierror"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Severity is the severity of a Finding.
+type Severity string
+
+const (
+	// SeverityError is the severity of findings making the resource invalid.
+	SeverityError Severity = "Error"
+	// SeverityWarning is the severity of findings of valid resources which are likely not to have the intended effect.
+	SeverityWarning Severity = "Warning"
+)
+
+// Codes of the findings.
+const (
+	// CodeInvalidYAML is the code of documents which can't be decoded. Decoding stops at the first such finding.
+	CodeInvalidYAML = "InvalidYAML"
+	// CodeUnknownField is the code of unknown top-level fields of Istio resources.
+	CodeUnknownField = "UnknownField"
+	// CodeInvalidConfig is the code of invalid Istio configuration.
+	CodeInvalidConfig = "InvalidConfig"
+	// CodeInvalidService is the code of Services which can't be parsed, or whose ports don't follow the Istio
+	// requirements.
+	CodeInvalidService = "InvalidService"
+	// CodeInvalidIstioOperator is the code of invalid IstioOperator resources.
+	CodeInvalidIstioOperator = "InvalidIstioOperator"
+	// CodeUnknownValuesField is the code of warnings for IstioOperator values fields which are not in the values
+	// schema.
+	CodeUnknownValuesField = "UnknownValuesField"
+	// CodeServiceAnnotation is the code of warnings for the traffic annotations of Services.
+	CodeServiceAnnotation = "ServiceAnnotation"
+	// CodeMissingDeploymentLabel is the code of warnings for Deployments without the app and version labels.
+	CodeMissingDeploymentLabel = "MissingDeploymentLabel"
+)
+
+// Finding is an error or warning of a validated resource.
+type Finding struct {
+	// Kind, Name and Namespace identify the resource. They are empty for CodeInvalidYAML.
+	Kind      string
+	Name      string
+	Namespace string
+
+	Severity Severity
+	Code     string
+	Message  string
+}
+
+func (f Finding) String() string {
+	if f.Code == CodeInvalidYAML {
+		return fmt.Sprintf("%s [%s] %s", f.Severity, f.Code, f.Message)
+	}
+	return fmt.Sprintf("%s [%s] %s/%s/%s: %s", f.Severity, f.Code, f.Kind, f.Namespace, f.Name, f.Message)
+}
+
+// error returns the finding as reported by istioctl validate.
+func (f Finding) error() error {
+	if f.Code == CodeInvalidYAML {
+		return errors.New(f.Message)
+	}
+	return fmt.Errorf("%s/%s/%s: %s", f.Kind, f.Namespace, f.Name, f.Message)
+}
+
+// Resources validates the resources of the YAML or JSON documents as istioctl validate does, and returns their
+// findings. Each document may hold several resources separated by "---", and resources of kind List are validated
+// item by item. Resources which are neither Istio resources nor Services or Deployments are skipped.
+//
+// istioNamespace is the namespace of the Istio control plane, whose Services and Deployments are not checked. It
+// defaults to istio-system. IstioOperator resources are not rendered and their values are not checked against the
+// values schema.
+//
+// The error is only returned if the documents could not be validated; invalid documents are reported as findings.
+func Resources(istioNamespace string, docs [][]byte) ([]Finding, error) {
+	v := &validator{}
+	var findings []Finding
+	for i, doc := range docs {
+		f, err := v.validateDocumentSafely(istioNamespace, doc)
+		if err != nil {
+			return findings, fmt.Errorf("cannot validate document %d: %v", i, err)
+		}
+		findings = append(findings, f...)
+	}
+	return findings, nil
+}
+
+// validateDocumentSafely validates the document, returning an error if the validation panics.
+func (v *validator) validateDocumentSafely(istioNamespace string, doc []byte) (findings []Finding, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			v.warnings = nil
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return v.validateDocument(istioNamespace, bytes.NewReader(doc)), nil
+}
+
+// validateDocument returns the findings of the resources read from reader, in order.
+func (v *validator) validateDocument(istioNamespace string, reader io.Reader) []Finding {
+	decoder := yaml.NewDecoder(reader)
+	decoder.SetStrict(true)
+	var findings []Finding
+	for {
+		// YAML allows non-string keys and the produces generic keys for nested fields
+		raw := make(map[interface{}]interface{})
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			return findings
+		}
+		if err != nil {
+			return append(findings, Finding{Severity: SeverityError, Code: CodeInvalidYAML, Message: err.Error()})
+		}
+		if len(raw) == 0 {
+			continue
+		}
+		out := transformInterfaceMap(raw)
+		un := &unstructured.Unstructured{Object: out}
+		err = v.validateResource(istioNamespace, un)
+		findings = append(findings, errorFindings(un, err)...)
+		findings = append(findings, v.warnings...)
+		v.warnings = nil
+	}
+}
+
+// warn adds a warning of the resource to v.
+func (v *validator) warn(un *unstructured.Unstructured, code, message string) {
+	v.warnings = append(v.warnings, newFinding(un, SeverityWarning, code, message))
+}
+
+func newFinding(un *unstructured.Unstructured, severity Severity, code, message string) Finding {
+	return Finding{
+		Kind:      un.GetKind(),
+		Name:      un.GetName(),
+		Namespace: un.GetNamespace(),
+		Severity:  severity,
+		Code:      code,
+		Message:   message,
+	}
+}
+
+// findingError is an error of a resource, with the code of its finding.
+type findingError struct {
+	// resource is the list item the error is about, nil for the validated resource.
+	resource *unstructured.Unstructured
+	code     string
+	err      error
+}
+
+func (e *findingError) Error() string {
+	return e.err.Error()
+}
+
+// withCode sets the code of the errors which don't have one yet.
+func withCode(code string, err error) error {
+	return mapErrors(err, func(e *findingError) {
+		if e.code == "" {
+			e.code = code
+		}
+	})
+}
+
+// ofResource sets the resource of the errors of a list item which don't have one yet.
+func ofResource(un *unstructured.Unstructured, err error) error {
+	return mapErrors(err, func(e *findingError) {
+		if e.resource == nil {
+			e.resource = un
+		}
+	})
+}
+
+// mapErrors applies f to a copy of each error, wrapped as a findingError.
+func mapErrors(err error, f func(*findingError)) error {
+	if err == nil {
+		return nil
+	}
+	if m, ok := err.(*multierror.Error); ok {
+		var errs error
+		for _, e := range m.Errors {
+			errs = multierror.Append(errs, mapErrors(e, f))
+		}
+		return errs
+	}
+	fe := &findingError{err: err}
+	if e, ok := err.(*findingError); ok {
+		c := *e
+		fe = &c
+	}
+	f(fe)
+	return fe
+}
+
+// errorFindings returns the findings of the errors returned by validateResource for un.
+func errorFindings(un *unstructured.Unstructured, err error) []Finding {
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	if m, ok := err.(*multierror.Error); ok {
+		errs = m.Errors
+	}
+	findings := make([]Finding, 0, len(errs))
+	for _, e := range errs {
+		resource, code := un, CodeInvalidConfig
+		if fe, ok := e.(*findingError); ok {
+			if fe.resource != nil {
+				resource = fe.resource
+			}
+			if fe.code != "" {
+				code = fe.code
+			}
+		}
+		findings = append(findings, newFinding(resource, SeverityError, code, e.Error()))
+	}
+	return findings
+}
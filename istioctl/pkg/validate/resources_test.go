@@ -0,0 +1,184 @@
+// Copyright Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// findingKey identifies a finding without its message.
+type findingKey struct {
+	kind, name, namespace string
+	severity              Severity
+	code                  string
+}
+
+func findingKeys(findings []Finding) []findingKey {
+	var keys []findingKey
+	for _, f := range findings {
+		keys = append(keys, findingKey{f.Kind, f.Name, f.Namespace, f.Severity, f.Code})
+	}
+	return keys
+}
+
+func TestResources(t *testing.T) {
+	unnamedPortService := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: unnamed
+  namespace: default
+spec:
+  ports:
+  - port: 8080`
+	cases := []struct {
+		name string
+		docs []string
+		want []findingKey
+	}{
+		{
+			name: "valid",
+			docs: []string{validVirtualService, validPortNamingSvc},
+		},
+		{
+			name: "multi-document",
+			docs: []string{buildMultiDocYAML([]string{validVirtualService, invalidVirtualService, validVirtualService1})},
+			want: []findingKey{
+				{"VirtualService", "invalid-virtual-service", "", SeverityError, CodeInvalidConfig},
+			},
+		},
+		{
+			name: "unknown field",
+			docs: []string{invalidUnsupportedKey},
+			want: []findingKey{
+				{"DestinationRule", "productpage", "", SeverityError, CodeUnknownField},
+			},
+		},
+		{
+			name: "service ports",
+			docs: []string{unnamedPortService},
+			want: []findingKey{
+				{"Service", "unnamed", "default", SeverityError, CodeInvalidService},
+			},
+		},
+		{
+			name: "service list item",
+			docs: []string{invalidSvcList},
+			want: []findingKey{
+				{"Service", "details", "", SeverityError, CodeInvalidService},
+				{"Service", "hello", "", SeverityError, CodeInvalidService},
+			},
+		},
+		{
+			name: "deployment labels",
+			docs: []string{versionLabelMissingDeployment},
+			want: []findingKey{
+				{"Deployment", "hello", "", SeverityWarning, CodeMissingDeploymentLabel},
+				{"Deployment", "hello", "", SeverityWarning, CodeMissingDeploymentLabel},
+			},
+		},
+		{
+			name: "control plane deployment",
+			docs: []string{skippedDeployment},
+		},
+		{
+			name: "istio operator",
+			docs: []string{validIstioConfig, invalidIstioConfig},
+			want: []findingKey{
+				{"IstioOperator", "example-istiocontrolplane", "istio-system", SeverityError, CodeInvalidIstioOperator},
+			},
+		},
+		{
+			name: "invalid yaml",
+			docs: []string{validVirtualService + "\n---\n" + invalidYAML, validPortNamingSvc},
+			want: []findingKey{
+				{"", "", "", SeverityError, CodeInvalidYAML},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs [][]byte
+			for _, d := range tt.docs {
+				docs = append(docs, []byte(d))
+			}
+			findings, err := Resources("istio-system", docs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := findingKeys(findings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got findings %v, want %v", findings, tt.want)
+			}
+			for _, f := range findings {
+				if f.Message == "" {
+					t.Errorf("finding %v has no message", f)
+				}
+			}
+		})
+	}
+}
+
+func TestResourcesServiceWarnings(t *testing.T) {
+	svc := `
+apiVersion: v1
+kind: Service
+metadata:
+  name: lb
+  namespace: default
+  annotations:
+    traffic.istio.io/nodeSelector: '{"gateway": "true"}'
+spec:
+  type: LoadBalancer
+  ports:
+  - name: http
+    port: 80`
+	findings, err := Resources("istio-system", [][]byte{[]byte(svc)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []findingKey{{"Service", "lb", "default", SeverityWarning, CodeServiceAnnotation}}
+	if got := findingKeys(findings); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got findings %v, want %v", findings, want)
+	}
+	if !strings.Contains(findings[0].Message, "LoadBalancer") {
+		t.Errorf("unexpected message %q", findings[0].Message)
+	}
+}
+
+func TestResourcesMalformed(t *testing.T) {
+	for _, doc := range []string{
+		"{",
+		"- a\n- b",
+		"42",
+		"kind: Service\napiVersion: v1\nmetadata: {name: s}\nspec: 3",
+		"kind: Service\napiVersion: v1\nmetadata: {name: s}\nspec: {ports: 3}",
+		"kind: Service\napiVersion: v1\nmetadata: {name: s}\nspec: {ports: [1, {name: 3}, {protocol: [UDP]}]}",
+		"kind: Service\napiVersion: v1\nmetadata: {name: s}",
+		"kind: Service\napiVersion: v1\nmetadata: 3",
+		"kind: Deployment\napiVersion: apps/v1\nmetadata: {name: d, labels: 3}",
+		"kind: List\napiVersion: v1\nitems: [1, {kind: Service}]",
+		"kind: List\napiVersion: v1\nitems: 3",
+		"kind: VirtualService\napiVersion: networking.istio.io/v1alpha3\nmetadata: {name: v}\nspec: [1]",
+		"kind: IstioOperator\napiVersion: install.istio.io/v1alpha1\nmetadata: {name: i}\nspec: {profile: [1]}",
+	} {
+		t.Run(doc, func(t *testing.T) {
+			if _, err := Resources("istio-system", [][]byte{[]byte(doc)}); err != nil {
+				t.Errorf("validation failed: %v", err)
+			}
+		})
+	}
+}
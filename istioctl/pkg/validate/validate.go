@@ -19,22 +19,29 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"istio.io/api/label"
 	operator_istio "istio.io/istio/operator/pkg/apis/istio"
+	"istio.io/istio/operator/pkg/manifest"
 	"istio.io/istio/operator/pkg/name"
 	"istio.io/istio/operator/pkg/util"
+	"istio.io/istio/operator/pkg/util/clog"
 	operator_validate "istio.io/istio/operator/pkg/validate"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/kube"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -64,6 +71,15 @@ Example resource specifications include:
 )
 
 type validator struct {
+	// render enables rendering IstioOperator resources against the compiled in charts.
+	render bool
+	// strictValues fails IstioOperator resources with values fields which are not in the values schema, instead of
+	// warning about them.
+	strictValues bool
+	// iopRevisions maps a revision and install namespace to the name of the IstioOperator using it.
+	iopRevisions map[string]string
+	// warnings are the warnings of the resources validated since they were last collected.
+	warnings []Finding
 }
 
 func checkFields(un *unstructured.Unstructured) error {
@@ -94,7 +110,7 @@ func (v *validator) validateResource(istioNamespace string, un *unstructured.Uns
 			return fmt.Errorf("cannot parse proto message: %v", err)
 		}
 		if err = checkFields(un); err != nil {
-			return err
+			return withCode(CodeUnknownField, err)
 		}
 		return schema.Resource().ValidateConfig(*obj)
 	}
@@ -104,9 +120,9 @@ func (v *validator) validateResource(istioNamespace string, un *unstructured.Uns
 		_ = un.EachListItem(func(item runtime.Object) error {
 			castItem := item.(*unstructured.Unstructured)
 			if castItem.GetKind() == name.ServiceStr {
-				err := v.validateServicePortPrefix(istioNamespace, castItem)
+				err := v.validateService(istioNamespace, castItem)
 				if err != nil {
-					errs = multierror.Append(errs, err)
+					errs = multierror.Append(errs, ofResource(castItem, err))
 				}
 			}
 			if castItem.GetKind() == name.DeploymentStr {
@@ -120,7 +136,7 @@ func (v *validator) validateResource(istioNamespace string, un *unstructured.Uns
 		return errs
 	}
 	if un.GetKind() == name.ServiceStr {
-		return v.validateServicePortPrefix(istioNamespace, un)
+		return v.validateService(istioNamespace, un)
 	}
 
 	if un.GetKind() == name.DeploymentStr {
@@ -131,18 +147,9 @@ func (v *validator) validateResource(istioNamespace string, un *unstructured.Uns
 	if un.GetAPIVersion() == "install.istio.io/v1alpha1" {
 		if un.GetKind() == "IstioOperator" {
 			if err := checkFields(un); err != nil {
-				return err
-			}
-			// IstioOperator isn't part of pkg/config/schema/collections,
-			// usual conversion not available.  Convert unstructured to string
-			// and ask operator code to check.
-			un.SetCreationTimestamp(metav1.Time{}) // UnmarshalIstioOperator chokes on these
-			by := util.ToYAML(un)
-			iop, err := operator_istio.UnmarshalIstioOperator(by, false)
-			if err != nil {
-				return err
+				return withCode(CodeUnknownField, err)
 			}
-			return operator_validate.CheckIstioOperator(iop, true)
+			return withCode(CodeInvalidIstioOperator, v.validateIstioOperator(un))
 		}
 	}
 
@@ -152,17 +159,195 @@ func (v *validator) validateResource(istioNamespace string, un *unstructured.Uns
 	return nil
 }
 
+// validateIstioOperator returns the errors of the IstioOperator, whose fields have been checked.
+func (v *validator) validateIstioOperator(un *unstructured.Unstructured) error {
+	// IstioOperator isn't part of pkg/config/schema/collections,
+	// usual conversion not available.  Convert unstructured to string
+	// and ask operator code to check.
+	un.SetCreationTimestamp(metav1.Time{}) // UnmarshalIstioOperator chokes on these
+	by := util.ToYAML(un)
+	iop, err := operator_istio.UnmarshalIstioOperator(by, false)
+	if err != nil {
+		return err
+	}
+	if err := operator_validate.CheckIstioOperator(iop, true); err != nil {
+		return err
+	}
+	if err := operator_validate.CheckValuesSchema(iop.Spec.Values).ToError(); err != nil {
+		if v.strictValues {
+			return err
+		}
+		v.warn(un, CodeUnknownValuesField, err.Error())
+	}
+	if err := v.checkIstioOperatorRevision(un.GetName(), iop.Spec.Revision, iop.Spec.Namespace); err != nil {
+		return err
+	}
+	if v.render {
+		return renderIstioOperator(un, iop.Spec.Profile)
+	}
+	return nil
+}
+
+// checkIstioOperatorRevision reports an error if another IstioOperator seen by v installs the same revision into the
+// same namespace.
+func (v *validator) checkIstioOperatorRevision(iopName, revision, namespace string) error {
+	if namespace == "" {
+		namespace = name.IstioDefaultNamespace
+	}
+	key := revision + "/" + namespace
+	if other, f := v.iopRevisions[key]; f && other != iopName {
+		if revision == "" {
+			return fmt.Errorf("default revision in namespace %q is also installed by IstioOperator %q", namespace, other)
+		}
+		return fmt.Errorf("revision %q in namespace %q is also installed by IstioOperator %q", revision, namespace, other)
+	}
+	if v.iopRevisions == nil {
+		v.iopRevisions = map[string]string{}
+	}
+	v.iopRevisions[key] = iopName
+	return nil
+}
+
+// renderIstioOperator renders the manifests for the IstioOperator in memory against the compiled in charts and
+// returns any rendering or k8s overlay errors. The cluster is never contacted.
+func renderIstioOperator(un *unstructured.Unstructured, profile string) error {
+	iop := un.DeepCopy()
+	// Only the compiled in charts are used, a custom install package may require fetching.
+	unstructured.RemoveNestedField(iop.Object, "spec", "installPackagePath")
+	l := clog.NewConsoleLogger(ioutil.Discard, ioutil.Discard, nil)
+	if _, err := manifest.RenderIOPManifests(util.ToYAML(iop), profile, l); err != nil {
+		return fmt.Errorf("failed to render manifest: %v", err)
+	}
+	return nil
+}
+
+// validateService returns the errors of the Service, and warns of settings which are valid but likely not to have
+// the intended effect.
+func (v *validator) validateService(istioNamespace string, un *unstructured.Unstructured) error {
+	errs := v.validateServicePortPrefix(istioNamespace, un)
+	svc, err := toService(un)
+	if err != nil {
+		return withCode(CodeInvalidService, multierror.Append(errs, err))
+	}
+	if err := validateServicePorts(svc); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	for _, w := range serviceWarnings(svc) {
+		v.warn(un, CodeServiceAnnotation, w)
+	}
+	return withCode(CodeInvalidService, errs)
+}
+
+func toService(un *unstructured.Unstructured) (*corev1.Service, error) {
+	by, err := json.Marshal(un.Object)
+	if err != nil {
+		return nil, err
+	}
+	svc := &corev1.Service{}
+	if err := json.Unmarshal(by, svc); err != nil {
+		return nil, fmt.Errorf("cannot parse service: %v", err)
+	}
+	return svc, nil
+}
+
+// validateServicePorts reports duplicate port names, ports whose appProtocol contradicts the protocol of their
+// name prefix, and ports sharing a targetPort with a different protocol.
+func validateServicePorts(svc *corev1.Service) error {
+	var errs error
+	svcName := fmt.Sprintf("%s/%s", svc.Name, svc.Namespace)
+	names := map[string]struct{}{}
+	type target struct {
+		proto      corev1.Protocol
+		targetPort string
+	}
+	targets := map[target]corev1.ServicePort{}
+	for _, p := range svc.Spec.Ports {
+		if p.Name != "" {
+			if _, f := names[p.Name]; f {
+				errs = multierror.Append(errs, fmt.Errorf("service %q has duplicate port name %q", svcName, p.Name))
+			}
+			names[p.Name] = struct{}{}
+		}
+		if p.Protocol == corev1.ProtocolUDP {
+			continue
+		}
+
+		if p.AppProtocol != nil {
+			byName := kube.ConvertProtocol(0, p.Name, p.Protocol, nil)
+			byAppProtocol := kube.ConvertProtocol(0, "", p.Protocol, p.AppProtocol)
+			if byName != protocol.Unsupported && byAppProtocol != protocol.Unsupported && byName != byAppProtocol {
+				errs = multierror.Append(errs, fmt.Errorf("service %q port %q has appProtocol %q contradicting the %s protocol of its name",
+					svcName, servicePortID(p), *p.AppProtocol, byName))
+			}
+		}
+
+		t := target{proto: p.Protocol, targetPort: p.TargetPort.String()}
+		if p.TargetPort.String() == "0" || p.TargetPort.String() == "" {
+			t.targetPort = strconv.Itoa(int(p.Port))
+		}
+		if other, f := targets[t]; f {
+			proto := kube.ConvertProtocol(p.Port, p.Name, p.Protocol, p.AppProtocol)
+			otherProto := kube.ConvertProtocol(other.Port, other.Name, other.Protocol, other.AppProtocol)
+			if proto != otherProto {
+				errs = multierror.Append(errs, fmt.Errorf("service %q ports %q (%s) and %q (%s) map to the same targetPort %s with different protocols",
+					svcName, servicePortID(other), otherProto, servicePortID(p), proto, t.targetPort))
+			}
+			continue
+		}
+		targets[t] = p
+	}
+	return errs
+}
+
+// serviceWarnings returns warnings for the traffic annotations of NodePort and LoadBalancer services.
+func serviceWarnings(svc *corev1.Service) []string {
+	svcName := fmt.Sprintf("%s/%s", svc.Name, svc.Namespace)
+	selector, hasSelector := svc.Annotations[kubecontroller.NodeSelectorAnnotation]
+	var warnings []string
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeNodePort:
+		if _, isGateway := svc.Labels[label.IstioNetwork]; isGateway && !hasSelector {
+			warnings = append(warnings, fmt.Sprintf("service %q is a NodePort network gateway without the %q annotation,"+
+				" its node addresses will not be used for cross-network traffic", svcName, kubecontroller.NodeSelectorAnnotation))
+		}
+		if hasSelector {
+			var nodeSelector map[string]string
+			if err := json.Unmarshal([]byte(selector), &nodeSelector); err != nil {
+				warnings = append(warnings, fmt.Sprintf("service %q has an invalid %q annotation, it must be a JSON map of node labels: %v",
+					svcName, kubecontroller.NodeSelectorAnnotation, err))
+			}
+		}
+	case corev1.ServiceTypeLoadBalancer:
+		if hasSelector {
+			warnings = append(warnings, fmt.Sprintf("service %q is of type LoadBalancer, the %q annotation only applies to NodePort services",
+				svcName, kubecontroller.NodeSelectorAnnotation))
+		}
+	}
+	return warnings
+}
+
+// servicePortID identifies the port by its name, or by its number if it is unnamed.
+func servicePortID(p corev1.ServicePort) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return strconv.Itoa(int(p.Port))
+}
+
 func (v *validator) validateServicePortPrefix(istioNamespace string, un *unstructured.Unstructured) error {
 	var errs error
 	if un.GetNamespace() == handleNamespace(istioNamespace) {
 		return nil
 	}
-	spec := un.Object["spec"].(map[string]interface{})
-	if _, ok := spec["ports"]; ok {
-		ports := spec["ports"].([]interface{})
+	// The types of the fields are checked when converting to a Service, malformed ports are skipped here.
+	spec, _ := un.Object["spec"].(map[string]interface{})
+	if ports, ok := spec["ports"].([]interface{}); ok {
 		for _, port := range ports {
-			p := port.(map[string]interface{})
-			if p["protocol"] != nil && strings.EqualFold(p["protocol"].(string), serviceProtocolUDP) {
+			p, ok := port.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if proto, ok := p["protocol"].(string); ok && strings.EqualFold(proto, serviceProtocolUDP) {
 				continue
 			}
 			if p["name"] == nil {
@@ -170,9 +355,10 @@ func (v *validator) validateServicePortPrefix(istioNamespace string, un *unstruc
 					" See "+url.DeploymentRequirements, fmt.Sprintf("%s/%s/:", un.GetName(), un.GetNamespace())))
 				continue
 			}
-			if servicePortPrefixed(p["name"].(string)) {
+			portName, ok := p["name"].(string)
+			if ok && servicePortPrefixed(portName) {
 				errs = multierror.Append(errs, fmt.Errorf("service %q port %q does not follow the Istio naming convention."+
-					" See "+url.DeploymentRequirements, fmt.Sprintf("%s/%s/:", un.GetName(), un.GetNamespace()), p["name"].(string)))
+					" See "+url.DeploymentRequirements, fmt.Sprintf("%s/%s/:", un.GetName(), un.GetNamespace()), portName))
 			}
 		}
 	}
@@ -189,47 +375,30 @@ func (v *validator) validateDeploymentLabel(istioNamespace string, un *unstructu
 	labels := un.GetLabels()
 	for _, l := range istioDeploymentLabel {
 		if _, ok := labels[l]; !ok {
-			log.Warnf("deployment %q may not provide Istio metrics and telemetry without label %q."+
-				" See "+url.DeploymentRequirements, fmt.Sprintf("%s/%s:", un.GetName(), un.GetNamespace()), l)
+			v.warn(un, CodeMissingDeploymentLabel, fmt.Sprintf("deployment %q may not provide Istio metrics and telemetry without label %q."+
+				" See "+url.DeploymentRequirements, fmt.Sprintf("%s/%s:", un.GetName(), un.GetNamespace()), l))
 		}
 	}
 }
 
+// validateFile returns the errors of the resources read from reader, and logs their warnings.
 func (v *validator) validateFile(istioNamespace *string, reader io.Reader) error {
-	decoder := yaml.NewDecoder(reader)
-	decoder.SetStrict(true)
 	var errs error
-	for {
-		// YAML allows non-string keys and the produces generic keys for nested fields
-		raw := make(map[interface{}]interface{})
-		err := decoder.Decode(&raw)
-		if err == io.EOF {
-			return errs
-		}
-		if err != nil {
-			errs = multierror.Append(errs, err)
-			return errs
-		}
-		if len(raw) == 0 {
+	for _, f := range v.validateDocument(*istioNamespace, reader) {
+		if f.Severity == SeverityWarning {
+			log.Warn(f.Message)
 			continue
 		}
-		out := transformInterfaceMap(raw)
-		un := unstructured.Unstructured{Object: out}
-		err = v.validateResource(*istioNamespace, &un)
-		if err != nil {
-			errs = multierror.Append(errs, multierror.Prefix(err, fmt.Sprintf("%s/%s/%s:",
-				un.GetKind(), un.GetNamespace(), un.GetName())))
-		}
+		errs = multierror.Append(errs, f.error())
 	}
+	return errs
 }
 
-func validateFiles(istioNamespace *string, filenames []string, writer io.Writer) error {
+func validateFiles(istioNamespace *string, filenames []string, v *validator, writer io.Writer) error {
 	if len(filenames) == 0 {
 		return errMissingFilename
 	}
 
-	v := &validator{}
-
 	var errs, err error
 	var reader io.Reader
 	for _, filename := range filenames {
@@ -266,6 +435,8 @@ func validateFiles(istioNamespace *string, filenames []string, writer io.Writer)
 func NewValidateCommand(istioNamespace *string) *cobra.Command {
 	var filenames []string
 	var referential bool
+	var render bool
+	var strictValues bool
 
 	c := &cobra.Command{
 		Use:     "validate -f FILENAME [options]",
@@ -284,18 +455,28 @@ func NewValidateCommand(istioNamespace *string) *cobra.Command {
 		# Validate current services under 'default' namespace within the cluster
 		kubectl get services -o yaml | istioctl validate -f -
 
+		# Validate an IstioOperator, including rendering its manifest against the compiled in charts
+		istioctl validate --render -f manifests/profiles/demo.yaml
+
+		# Validate an IstioOperator, failing on values fields which are not in the values schema
+		istioctl validate --strict-values -f manifests/profiles/demo.yaml
+
 		# Also see the related command 'istioctl analyze'
 		istioctl analyze samples/bookinfo/networking/bookinfo-gateway.yaml
 `,
 		Args: cobra.NoArgs,
 		RunE: func(c *cobra.Command, _ []string) error {
-			return validateFiles(istioNamespace, filenames, c.OutOrStderr())
+			return validateFiles(istioNamespace, filenames, &validator{render: render, strictValues: strictValues}, c.OutOrStderr())
 		},
 	}
 
 	flags := c.PersistentFlags()
 	flags.StringSliceVarP(&filenames, "filename", "f", nil, "Names of files to validate")
 	flags.BoolVarP(&referential, "referential", "x", true, "Enable structural validation for policy and telemetry")
+	flags.BoolVar(&render, "render", false, "Render IstioOperator resources against the compiled in charts and "+
+		"report rendering and k8s overlay errors")
+	flags.BoolVar(&strictValues, "strict-values", false, "Fail on IstioOperator values fields which are not in "+
+		"the values schema, instead of warning about them")
 
 	return c
 }
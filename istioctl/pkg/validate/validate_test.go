@@ -24,7 +24,13 @@ import (
 	"testing"
 
 	"github.com/ghodss/yaml"
+	"github.com/hashicorp/go-multierror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"istio.io/api/label"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube"
 )
 
 const (
@@ -267,6 +273,58 @@ spec:
   addonComponents:
     grafana:
       enabled: true
+`
+	renderableIstioConfig = `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  namespace: istio-system
+  name: renderable
+spec:
+  profile: minimal
+  components:
+    pilot:
+      k8s:
+        overlays:
+        - kind: Deployment
+          name: istiod
+          patches:
+          - path: spec.template.metadata.labels.foo
+            value: bar
+`
+	brokenOverlayIstioConfig = `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  namespace: istio-system
+  name: broken-overlay
+spec:
+  profile: minimal
+  components:
+    pilot:
+      k8s:
+        overlays:
+        - kind: Deployment
+          name: not-istiod
+          patches:
+          - path: spec.template.metadata.labels.foo
+            value: bar
+`
+	// brokenStrategicMergeIstioConfig sets an env var without a name, which is the merge key of the env of a
+	// container, so it cannot be strategic merge patched into the istiod Deployment.
+	brokenStrategicMergeIstioConfig = `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  namespace: istio-system
+  name: broken-strategic-merge
+spec:
+  profile: minimal
+  components:
+    pilot:
+      k8s:
+        env:
+        - value: bar
 `
 	invalidDuplicateKey = `
 apiVersion: networking.istio.io/v1alpha3
@@ -383,6 +441,215 @@ func TestValidateResource(t *testing.T) {
 	}
 }
 
+func TestValidateServicePorts(t *testing.T) {
+	svc := fromYAML(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: multi
+  namespace: default
+spec:
+  ports:
+    - name: http-web
+      port: 80
+    - name: http-web
+      port: 81
+    - name: http-api
+      port: 8080
+      appProtocol: grpc
+    - name: tcp-metrics
+      port: 9090
+      targetPort: 8080
+    - name: grpc-admin
+      port: 9091
+      targetPort: 8080
+      appProtocol: grpc
+    - name: dns
+      port: 53
+      protocol: UDP
+    - name: tcp-dns
+      port: 53
+      protocol: TCP
+      appProtocol: tcp`)
+	v := &validator{}
+	err := v.validateResource("istio-system", svc)
+	if err == nil {
+		t.Fatal("expected service port errors")
+	}
+	want := []string{
+		`service "multi/default" has duplicate port name "http-web"`,
+		`service "multi/default" port "http-api" has appProtocol "grpc" contradicting the HTTP protocol of its name`,
+		`service "multi/default" ports "http-api" (GRPC) and "tcp-metrics" (TCP) map to the same targetPort 8080 with different protocols`,
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("expected error %q, got %v", w, err)
+		}
+	}
+	if got := len(err.(*multierror.Error).Errors); got != len(want) {
+		t.Errorf("expected %d errors, got %d: %v", len(want), got, err)
+	}
+}
+
+func TestServiceWarnings(t *testing.T) {
+	cases := []struct {
+		name string
+		svc  corev1.Service
+		want []string
+	}{
+		{
+			name: "nodeport gateway without node selector",
+			svc: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "istio-system", Labels: map[string]string{label.IstioNetwork: "n1"}},
+				Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort},
+			},
+			want: []string{`service "gw/istio-system" is a NodePort network gateway without the "traffic.istio.io/nodeSelector" annotation`},
+		},
+		{
+			name: "nodeport gateway with node selector",
+			svc: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "istio-system", Labels: map[string]string{label.IstioNetwork: "n1"},
+					Annotations: map[string]string{kubecontroller.NodeSelectorAnnotation: `{"role": "gateway"}`}},
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort},
+			},
+		},
+		{
+			name: "invalid node selector",
+			svc: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "istio-system",
+					Annotations: map[string]string{kubecontroller.NodeSelectorAnnotation: "role=gateway"}},
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort},
+			},
+			want: []string{`service "gw/istio-system" has an invalid "traffic.istio.io/nodeSelector" annotation`},
+		},
+		{
+			name: "load balancer with node selector",
+			svc: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "istio-system",
+					Annotations: map[string]string{kubecontroller.NodeSelectorAnnotation: `{"role": "gateway"}`}},
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			},
+			want: []string{`service "gw/istio-system" is of type LoadBalancer`},
+		},
+		{
+			name: "cluster ip",
+			svc: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "default", Labels: map[string]string{label.IstioNetwork: "n1"}},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serviceWarnings(&tt.svc)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected warnings %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Errorf("expected warning %q, got %q", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateIstioOperatorRender(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{
+			name: "renders cleanly",
+			in:   renderableIstioConfig,
+		},
+		{
+			name:    "broken k8s overlay",
+			in:      brokenOverlayIstioConfig,
+			wantErr: "Components.Pilot.K8S.Overlays",
+		},
+		{
+			name:    "broken k8s strategic merge patch",
+			in:      brokenStrategicMergeIstioConfig,
+			wantErr: "Components.Pilot.K8S.Env",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := &validator{render: true}
+			err := v.validateResource("istio-system", fromYAML(c.in))
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", c.wantErr, err)
+			}
+		})
+	}
+
+	// Without --render, overlay errors are not detected.
+	for _, in := range []string{brokenOverlayIstioConfig, brokenStrategicMergeIstioConfig} {
+		v := &validator{}
+		if err := v.validateResource("istio-system", fromYAML(in)); err != nil {
+			t.Fatalf("unexpected error without rendering: %v", err)
+		}
+	}
+}
+
+func TestValidateIstioOperatorStrictValues(t *testing.T) {
+	misspelledValues := `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  namespace: istio-system
+  name: misspelled
+spec:
+  values:
+    global:
+      proxyv2:
+        image: proxyv2
+`
+	misspelledFilename, closeMisspelledFile := createTestFile(t, misspelledValues)
+	defer closeMisspelledFile.Close()
+	istioNamespace := "istio-system"
+
+	var out bytes.Buffer
+	if err := validateFiles(&istioNamespace, []string{misspelledFilename}, &validator{}, &out); err != nil {
+		t.Fatalf("unexpected error without strict values: %v", err)
+	}
+	if want := fmt.Sprintf("%q is valid\n", misspelledFilename); out.String() != want {
+		t.Fatalf("got output %q, want %q", out.String(), want)
+	}
+	findings := (&validator{}).validateDocument(istioNamespace, strings.NewReader(misspelledValues))
+	if len(findings) != 1 || findings[0].Severity != SeverityWarning || findings[0].Code != CodeUnknownValuesField ||
+		!strings.Contains(findings[0].Message, "unknown field values.global.proxyv2") {
+		t.Fatalf("expected an unknown values field warning, got %v", findings)
+	}
+
+	out.Reset()
+	err := validateFiles(&istioNamespace, []string{misspelledFilename}, &validator{strictValues: true}, &out)
+	if err == nil || !strings.Contains(err.Error(), "unknown field values.global.proxyv2") {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestValidateIstioOperatorRevisionConflict(t *testing.T) {
+	v := &validator{}
+	if err := v.validateResource("istio-system", fromYAML(renderableIstioConfig)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := v.validateResource("istio-system", fromYAML(brokenOverlayIstioConfig))
+	if err == nil || !strings.Contains(err.Error(), `IstioOperator "renderable"`) {
+		t.Fatalf("expected revision conflict, got %v", err)
+	}
+}
+
 func buildMultiDocYAML(docs []string) string {
 	var b strings.Builder
 	for _, r := range docs {
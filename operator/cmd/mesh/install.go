@@ -157,6 +157,10 @@ func InstallManifests(setOverlay []string, inFilenames []string, force bool, dry
 		return err
 	}
 
+	if err := checkRevisionConflict(client, iop); err != nil {
+		return err
+	}
+
 	if err := createNamespace(clientset, iop.Namespace); err != nil {
 		return err
 	}
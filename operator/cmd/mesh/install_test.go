@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"istio.io/api/operator/v1alpha1"
+	iopv1alpha1 "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+)
+
+func TestRevisionConflict(t *testing.T) {
+	installed := func(name, revision, namespace string) unstructured.Unstructured {
+		un := unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"revision": revision, "namespace": namespace},
+		}}
+		un.SetName(name)
+		un.SetNamespace("istio-system")
+		return un
+	}
+	iop := &iopv1alpha1.IstioOperator{Spec: &v1alpha1.IstioOperatorSpec{Revision: "canary"}}
+	iop.Name = "installed-state-canary"
+	iop.Namespace = "istio-system"
+
+	tests := []struct {
+		desc      string
+		installed []unstructured.Unstructured
+		wantErr   bool
+	}{
+		{
+			desc:      "no other revision",
+			installed: []unstructured.Unstructured{installed("installed-state", "", "")},
+		},
+		{
+			desc:      "same operator upgraded",
+			installed: []unstructured.Unstructured{installed("installed-state-canary", "canary", "")},
+		},
+		{
+			desc:      "same revision in another namespace",
+			installed: []unstructured.Unstructured{installed("canary", "canary", "istio-other")},
+		},
+		{
+			desc:      "same revision in the same namespace",
+			installed: []unstructured.Unstructured{installed("canary", "canary", "istio-system")},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if err := revisionConflict(iop, tt.installed); (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
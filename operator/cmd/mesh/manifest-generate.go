@@ -46,6 +46,10 @@ type manifestGenerateArgs struct {
 	manifestsPath string
 	// revision is the Istio control plane revision the command targets.
 	revision string
+	// strictValues fails the generation if the values contain fields which are not in the values schema.
+	strictValues bool
+	// components is the list of components to render, all the components are rendered if it is empty.
+	components []string
 }
 
 func addManifestGenerateFlags(cmd *cobra.Command, args *manifestGenerateArgs) {
@@ -56,6 +60,12 @@ func addManifestGenerateFlags(cmd *cobra.Command, args *manifestGenerateArgs) {
 	cmd.PersistentFlags().StringVarP(&args.manifestsPath, "charts", "", "", ChartsDeprecatedStr)
 	cmd.PersistentFlags().StringVarP(&args.manifestsPath, "manifests", "d", "", ManifestsFlagHelpStr)
 	cmd.PersistentFlags().StringVarP(&args.revision, "revision", "r", "", revisionFlagHelpStr)
+	cmd.PersistentFlags().BoolVar(&args.strictValues, "strict-values", false,
+		"Fail if the values contain fields which are not in the values schema, instead of only warning.")
+	cmd.PersistentFlags().StringArrayVar(&args.components, "component", nil,
+		"Render only the given component, such as IngressGateways or Pilot, and the namespaces of its objects. "+
+			"Can be repeated. Valid components are: "+
+			strings.Join(componentNames(), ", ")+".")
 }
 
 func manifestGenerateCmd(rootArgs *rootArgs, mgArgs *manifestGenerateArgs, logOpts *log.Options) *cobra.Command {
@@ -73,6 +83,9 @@ func manifestGenerateCmd(rootArgs *rootArgs, mgArgs *manifestGenerateArgs, logOp
   # Generate the demo profile
   istioctl manifest generate --set profile=demo
 
+  # Generate the manifests of the ingress gateways only
+  istioctl manifest generate --component IngressGateways
+
   # To override a setting that includes dots, escape them with a backslash (\).  Your shell may require enclosing quotes.
   istioctl manifest generate --set "values.sidecarInjectorWebhook.injectedAnnotations.container\.apparmor\.security\.beta\.kubernetes\.io/istio-proxy=runtime/default"
 `,
@@ -94,13 +107,27 @@ func manifestGenerate(args *rootArgs, mgArgs *manifestGenerateArgs, logopts *log
 		return fmt.Errorf("could not configure logs: %s", err)
 	}
 
-	manifests, _, err := manifest.GenManifests(mgArgs.inFilename, applyFlagAliases(mgArgs.set, mgArgs.manifestsPath, mgArgs.revision), mgArgs.force, nil, l)
+	components, err := parseComponents(mgArgs.components)
+	if err != nil {
+		return err
+	}
+	setFlags := applyFlagAliases(mgArgs.set, mgArgs.manifestsPath, mgArgs.revision)
+	if mgArgs.strictValues {
+		if err := manifest.CheckUserValues(mgArgs.inFilename, setFlags, true, l); err != nil {
+			return err
+		}
+	}
+	manifests, _, err := manifest.GenManifests(mgArgs.inFilename, setFlags, mgArgs.force, nil, l)
+	if err != nil {
+		return err
+	}
+	manifests, err = filterComponents(manifests, components)
 	if err != nil {
 		return err
 	}
 
 	if mgArgs.outFilename == "" {
-		ordered, err := orderedManifests(manifests)
+		ordered, err := manifest.OrderedManifests(manifests)
 		if err != nil {
 			return fmt.Errorf("failed to order manifests: %v", err)
 		}
@@ -111,7 +138,7 @@ func manifestGenerate(args *rootArgs, mgArgs *manifestGenerateArgs, logopts *log
 		if err := os.MkdirAll(mgArgs.outFilename, os.ModePerm); err != nil {
 			return err
 		}
-		if err := RenderToDir(manifests, mgArgs.outFilename, args.dryRun, l); err != nil {
+		if err := RenderToDir(manifests, components, mgArgs.outFilename, args.dryRun, l); err != nil {
 			return err
 		}
 	}
@@ -119,55 +146,114 @@ func manifestGenerate(args *rootArgs, mgArgs *manifestGenerateArgs, logopts *log
 	return nil
 }
 
-// orderedManifests generates a list of manifests from the given map sorted by the default object order
-// This allows
-func orderedManifests(mm name.ManifestMap) ([]string, error) {
-	var rawOutput []string
-	var output []string
-	for _, mfs := range mm {
-		rawOutput = append(rawOutput, mfs...)
+// componentNames returns the names of the components which can be rendered on their own.
+func componentNames() []string {
+	var out []string
+	for _, c := range name.AllComponentNames {
+		if c == name.IstioOperatorComponentName || c == name.IstioOperatorCustomResourceName {
+			continue
+		}
+		out = append(out, string(c))
 	}
-	objects, err := object.ParseK8sObjectsFromYAMLManifest(strings.Join(rawOutput, helm.YAMLSeparator))
-	if err != nil {
-		return nil, err
+	return out
+}
+
+// parseComponents returns the component names given by the --component flag, or an error listing the valid names if
+// one of them is unknown.
+func parseComponents(components []string) (map[name.ComponentName]bool, error) {
+	if len(components) == 0 {
+		return nil, nil
 	}
-	// For a given group of objects, sort in order to avoid missing dependencies, such as creating CRDs first
-	objects.Sort(object.DefaultObjectOrder())
-	for _, obj := range objects {
-		yml, err := obj.YAML()
+	valid := componentNames()
+	out := make(map[name.ComponentName]bool)
+	for _, c := range components {
+		found := false
+		for _, v := range valid {
+			if c == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown component %q, valid components are: %s", c, strings.Join(valid, ", "))
+		}
+		out[name.ComponentName(c)] = true
+	}
+	return out, nil
+}
+
+// filterComponents returns the manifests of the given components only, with the shared resources they strictly
+// require: the namespaces of their objects, which istioctl install creates before applying the manifests. The CRDs
+// of the base component are not rendered unless it is selected. All the manifests are returned if components is
+// empty.
+func filterComponents(manifests name.ManifestMap, components map[name.ComponentName]bool) (name.ManifestMap, error) {
+	if len(components) == 0 {
+		return manifests, nil
+	}
+	out := make(name.ManifestMap)
+	namespaces := make(map[string]bool)
+	// Each namespace is rendered once, with the first component using it.
+	for _, c := range name.AllComponentNames {
+		if !components[c] || len(manifests[c]) == 0 {
+			continue
+		}
+		objs, err := object.ParseK8sObjectsFromYAMLManifest(strings.Join(manifests[c], helm.YAMLSeparator))
 		if err != nil {
 			return nil, err
 		}
-		output = append(output, string(yml))
+		var ms []string
+		for _, o := range objs {
+			if o.Namespace == "" || namespaces[o.Namespace] {
+				continue
+			}
+			namespaces[o.Namespace] = true
+			ms = append(ms, namespaceManifest(o.Namespace))
+		}
+		out[c] = append(ms, manifests[c]...)
 	}
+	return out, nil
+}
 
-	return output, nil
+// namespaceManifest returns the manifest of the namespace, as created by istioctl install.
+func namespaceManifest(namespace string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Namespace
+metadata:
+  labels:
+    istio-injection: disabled
+  name: %s
+`, namespace)
 }
 
-// RenderToDir writes manifests to a local filesystem directory tree.
-func RenderToDir(manifests name.ManifestMap, outputDir string, dryRun bool, l clog.Logger) error {
+// RenderToDir writes manifests to a local filesystem directory tree. If components is not empty, only the manifests
+// of these components are written, and the files of the other components are left untouched.
+func RenderToDir(manifests name.ManifestMap, components map[name.ComponentName]bool, outputDir string, dryRun bool,
+	l clog.Logger) error {
 	l.LogAndPrintf("Component dependencies tree: \n%s", helmreconciler.InstallTreeString())
 	l.LogAndPrintf("Rendering manifests to output dir %s", outputDir)
-	return renderRecursive(manifests, helmreconciler.InstallTree, outputDir, dryRun, l)
+	return renderRecursive(manifests, components, helmreconciler.InstallTree, outputDir, dryRun, l)
 }
 
-func renderRecursive(manifests name.ManifestMap, installTree helmreconciler.ComponentTree, outputDir string, dryRun bool, l clog.Logger) error {
+func renderRecursive(manifests name.ManifestMap, components map[name.ComponentName]bool, installTree helmreconciler.ComponentTree,
+	outputDir string, dryRun bool, l clog.Logger) error {
 	for k, v := range installTree {
 		componentName := string(k)
-		// In cases (like gateways) where multiple instances can exist, concatenate the manifests and apply as one.
-		ym := strings.Join(manifests[k], helm.YAMLSeparator)
-		l.LogAndPrintf("Rendering: %s", componentName)
 		dirName := filepath.Join(outputDir, componentName)
-		if !dryRun {
-			if err := os.MkdirAll(dirName, os.ModePerm); err != nil {
-				return fmt.Errorf("could not create directory %s; %s", outputDir, err)
+		if len(components) == 0 || components[k] {
+			// In cases (like gateways) where multiple instances can exist, concatenate the manifests and apply as one.
+			ym := strings.Join(manifests[k], helm.YAMLSeparator)
+			l.LogAndPrintf("Rendering: %s", componentName)
+			if !dryRun {
+				if err := os.MkdirAll(dirName, os.ModePerm); err != nil {
+					return fmt.Errorf("could not create directory %s; %s", outputDir, err)
+				}
 			}
-		}
-		fname := filepath.Join(dirName, componentName) + ".yaml"
-		l.LogAndPrintf("Writing manifest to %s", fname)
-		if !dryRun {
-			if err := ioutil.WriteFile(fname, []byte(ym), 0644); err != nil {
-				return fmt.Errorf("could not write manifest config; %s", err)
+			fname := filepath.Join(dirName, componentName) + ".yaml"
+			l.LogAndPrintf("Writing manifest to %s", fname)
+			if !dryRun {
+				if err := ioutil.WriteFile(fname, []byte(ym), 0644); err != nil {
+					return fmt.Errorf("could not write manifest config; %s", err)
+				}
 			}
 		}
 
@@ -176,7 +262,7 @@ func renderRecursive(manifests name.ManifestMap, installTree helmreconciler.Comp
 			// Leaf
 			return nil
 		}
-		if err := renderRecursive(manifests, kt, dirName, dryRun, l); err != nil {
+		if err := renderRecursive(manifests, components, kt, dirName, dryRun, l); err != nil {
 			return err
 		}
 	}
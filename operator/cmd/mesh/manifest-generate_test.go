@@ -26,8 +26,8 @@ import (
 	. "github.com/onsi/gomega"
 
 	"istio.io/istio/operator/pkg/compare"
-	"istio.io/istio/operator/pkg/helm"
 	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/manifesttest"
 	"istio.io/istio/operator/pkg/name"
 	"istio.io/istio/operator/pkg/object"
 	"istio.io/istio/operator/pkg/util"
@@ -44,9 +44,6 @@ const (
 	testTGZFilename  = istioTestVersion + "-linux.tar.gz"
 )
 
-// chartSourceType defines where charts used in the test come from.
-type chartSourceType string
-
 var (
 	operatorRootDir = filepath.Join(env.IstioSrc, "operator")
 
@@ -54,11 +51,11 @@ var (
 	testDataDir = filepath.Join(operatorRootDir, "cmd/mesh/testdata/manifest-generate")
 
 	// Snapshot charts are in testdata/manifest-generate/data-snapshot
-	snapshotCharts = chartSourceType(filepath.Join(testDataDir, "data-snapshot"))
+	snapshotCharts = manifesttest.ChartSource(filepath.Join(testDataDir, "data-snapshot"))
 	// Compiled in charts come from assets.gen.go
-	compiledInCharts chartSourceType = "COMPILED"
+	compiledInCharts = manifesttest.CompiledInCharts
 	// Live charts come from manifests/
-	liveCharts = chartSourceType(filepath.Join(env.IstioSrc, helm.OperatorSubdirFilePath))
+	liveCharts = manifesttest.LiveCharts
 )
 
 type testGroup []struct {
@@ -75,7 +72,7 @@ type testGroup []struct {
 	outputDir                   string
 	diffSelect                  string
 	diffIgnore                  string
-	chartSource                 chartSourceType
+	chartSource                 manifesttest.ChartSource
 }
 
 func TestManifestGenerateComponentHubTag(t *testing.T) {
@@ -109,7 +106,7 @@ func TestManifestGenerateComponentHubTag(t *testing.T) {
 			if tt.containerName != "" {
 				containerName = tt.containerName
 			}
-			container := mustGetContainer(g, os, tt.deploymentName, containerName)
+			container := manifesttest.MustGetContainer(t, os, tt.deploymentName, containerName)
 			g.Expect(container).Should(HavePathValueEqual(PathValue{"image", tt.want}))
 		}
 	}
@@ -127,31 +124,31 @@ func TestManifestGenerateGateways(t *testing.T) {
 	}
 
 	for _, objs := range objss {
-		g.Expect(objs.kind(name.HPAStr).size()).Should(Equal(3))
-		g.Expect(objs.kind(name.PDBStr).size()).Should(Equal(3))
-		g.Expect(objs.kind(name.ServiceStr).labels("istio=ingressgateway").size()).Should(Equal(3))
-		g.Expect(objs.kind(name.RoleStr).nameMatches(".*gateway.*").size()).Should(Equal(3))
-		g.Expect(objs.kind(name.RoleBindingStr).nameMatches(".*gateway.*").size()).Should(Equal(3))
-		g.Expect(objs.kind(name.SAStr).nameMatches(".*gateway.*").size()).Should(Equal(3))
-
-		dobj := mustGetDeployment(g, objs, "istio-ingressgateway")
+		g.Expect(objs.Kind(name.HPAStr).Size()).Should(Equal(3))
+		g.Expect(objs.Kind(name.PDBStr).Size()).Should(Equal(3))
+		g.Expect(objs.Kind(name.ServiceStr).Labels("istio=ingressgateway").Size()).Should(Equal(3))
+		g.Expect(objs.Kind(name.RoleStr).NameMatches(".*gateway.*").Size()).Should(Equal(3))
+		g.Expect(objs.Kind(name.RoleBindingStr).NameMatches(".*gateway.*").Size()).Should(Equal(3))
+		g.Expect(objs.Kind(name.SAStr).NameMatches(".*gateway.*").Size()).Should(Equal(3))
+
+		dobj := manifesttest.MustGetDeployment(t, objs, "istio-ingressgateway")
 		d := dobj.Unstructured()
 		c := dobj.Container("istio-proxy")
 		g.Expect(d).Should(HavePathValueContain(PathValue{"metadata.labels", toMap("aaa:aaa-val,bbb:bbb-val")}))
 		g.Expect(c).Should(HavePathValueEqual(PathValue{"resources.requests.cpu", "111m"}))
 		g.Expect(c).Should(HavePathValueEqual(PathValue{"resources.requests.memory", "999Mi"}))
 
-		dobj = mustGetDeployment(g, objs, "user-ingressgateway")
+		dobj = manifesttest.MustGetDeployment(t, objs, "user-ingressgateway")
 		d = dobj.Unstructured()
 		c = dobj.Container("istio-proxy")
 		g.Expect(d).Should(HavePathValueContain(PathValue{"metadata.labels", toMap("ccc:ccc-val,ddd:ddd-val")}))
 		g.Expect(c).Should(HavePathValueEqual(PathValue{"resources.requests.cpu", "555m"}))
 		g.Expect(c).Should(HavePathValueEqual(PathValue{"resources.requests.memory", "888Mi"}))
 
-		dobj = mustGetDeployment(g, objs, "ilb-gateway")
+		dobj = manifesttest.MustGetDeployment(t, objs, "ilb-gateway")
 		d = dobj.Unstructured()
 		c = dobj.Container("istio-proxy")
-		s := mustGetService(g, objs, "ilb-gateway").Unstructured()
+		s := manifesttest.MustGetService(t, objs, "ilb-gateway").Unstructured()
 		g.Expect(d).Should(HavePathValueContain(PathValue{"metadata.labels", toMap("app:istio-ingressgateway,istio:ingressgateway,release: istio")}))
 		g.Expect(c).Should(HavePathValueEqual(PathValue{"resources.requests.cpu", "333m"}))
 		g.Expect(c).Should(HavePathValueEqual(PathValue{"env.[name:PILOT_CERT_PROVIDER].value", "foobar"}))
@@ -160,16 +157,28 @@ func TestManifestGenerateGateways(t *testing.T) {
 		g.Expect(s).Should(HavePathValueContain(PathValue{"spec.ports.[1]", portVal("tcp-citadel-grpc-tls", 8060, 8060)}))
 		g.Expect(s).Should(HavePathValueContain(PathValue{"spec.ports.[2]", portVal("tcp-dns", 5353, -1)}))
 
-		for _, o := range objs.kind(name.HPAStr).objSlice {
+		for _, o := range objs.Kind(name.HPAStr).Objects() {
 			ou := o.Unstructured()
 			g.Expect(ou).Should(HavePathValueEqual(PathValue{"spec.minReplicas", int64(1)}))
 			g.Expect(ou).Should(HavePathValueEqual(PathValue{"spec.maxReplicas", int64(5)}))
 		}
 
-		checkRoleBindingsReferenceRoles(g, objs)
+		checkRoleBindingsReferenceRoles(t, objs)
 	}
 }
 
+// TestManifestGenerateGatewaysConcurrency tests that the concurrency and resources values of a gateway only apply to
+// that gateway.
+func TestManifestGenerateGatewaysConcurrency(t *testing.T) {
+	runTestGroup(t, testGroup{
+		{
+			desc:        "gateways_concurrency",
+			diffSelect:  "Deployment:*:*",
+			chartSource: liveCharts,
+		},
+	})
+}
+
 func TestManifestGenerateIstiodRemote(t *testing.T) {
 	g := NewWithT(t)
 
@@ -180,34 +189,34 @@ func TestManifestGenerateIstiodRemote(t *testing.T) {
 
 	for _, objs := range objss {
 		// check core CRDs exists
-		g.Expect(objs.kind(name.CRDStr).nameEquals("destinationrules.networking.istio.io")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.CRDStr).nameEquals("gateways.networking.istio.io")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.CRDStr).nameEquals("sidecars.networking.istio.io")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.CRDStr).nameEquals("virtualservices.networking.istio.io")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.CRDStr).nameEquals("adapters.config.istio.io")).Should(BeNil())
-		g.Expect(objs.kind(name.CRDStr).nameEquals("authorizationpolicies.security.istio.io")).Should(Not(BeNil()))
-
-		g.Expect(objs.kind(name.ClusterRoleStr).nameEquals("istiod-istio-system")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.ClusterRoleStr).nameEquals("istio-reader-istio-system")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.ClusterRoleBindingStr).nameEquals("istiod-pilot-istio-system")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.ClusterRoleBindingStr).nameEquals("istio-reader-istio-system")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.CMStr).nameEquals("istio-sidecar-injector")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.ServiceStr).nameEquals("istiod")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.SAStr).nameEquals("istio-reader-service-account")).Should(Not(BeNil()))
-		g.Expect(objs.kind(name.SAStr).nameEquals("istiod-service-account")).Should(Not(BeNil()))
-
-		mwc := mustGetMutatingWebhookConfiguration(g, objs, "istio-sidecar-injector").Unstructured()
+		g.Expect(objs.Kind(name.CRDStr).NameEquals("destinationrules.networking.istio.io")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.CRDStr).NameEquals("gateways.networking.istio.io")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.CRDStr).NameEquals("sidecars.networking.istio.io")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.CRDStr).NameEquals("virtualservices.networking.istio.io")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.CRDStr).NameEquals("adapters.config.istio.io")).Should(BeNil())
+		g.Expect(objs.Kind(name.CRDStr).NameEquals("authorizationpolicies.security.istio.io")).Should(Not(BeNil()))
+
+		g.Expect(objs.Kind(name.ClusterRoleStr).NameEquals("istiod-istio-system")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.ClusterRoleStr).NameEquals("istio-reader-istio-system")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.ClusterRoleBindingStr).NameEquals("istiod-pilot-istio-system")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.ClusterRoleBindingStr).NameEquals("istio-reader-istio-system")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.CMStr).NameEquals("istio-sidecar-injector")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.ServiceStr).NameEquals("istiod")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.SAStr).NameEquals("istio-reader-service-account")).Should(Not(BeNil()))
+		g.Expect(objs.Kind(name.SAStr).NameEquals("istiod-service-account")).Should(Not(BeNil()))
+
+		mwc := manifesttest.MustGetMutatingWebhookConfiguration(t, objs, "istio-sidecar-injector").Unstructured()
 		g.Expect(mwc).Should(HavePathValueEqual(PathValue{"webhooks.[0].clientConfig.url", "https://xxx:15017/inject/cluster/remote0/net/network2"}))
 		g.Expect(mwc).Should(HavePathValueContain(PathValue{"webhooks.[0].namespaceSelector.matchLabels", toMap("istio-injection:enabled")}))
 
-		vwc := mustGetValidatingWebhookConfiguration(g, objs, "istiod-istio-system").Unstructured()
+		vwc := manifesttest.MustGetValidatingWebhookConfiguration(t, objs, "istiod-istio-system").Unstructured()
 		g.Expect(vwc).Should(HavePathValueEqual(PathValue{"webhooks.[0].clientConfig.url", "https://xxx:15017/validate"}))
 
-		ep := mustGetEndpoint(g, objs, "istiod").Unstructured()
+		ep := manifesttest.MustGetEndpoint(t, objs, "istiod").Unstructured()
 		g.Expect(ep).Should(HavePathValueEqual(PathValue{"subsets.[0].addresses.[0]", endpointSubsetAddressVal("", "169.10.112.88", "")}))
 		g.Expect(ep).Should(HavePathValueContain(PathValue{"subsets.[0].ports.[0]", portVal("tcp-istiod", 15012, -1)}))
 
-		checkClusterRoleBindingsReferenceRoles(g, objs)
+		checkClusterRoleBindingsReferenceRoles(t, objs)
 	}
 }
 
@@ -217,26 +226,25 @@ func TestManifestGenerateAllOff(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	objs, err := parseObjectSetFromManifest(m)
+	objs, err := manifesttest.ParseObjectSetFromManifest(m)
 	if err != nil {
 		t.Fatal(err)
 	}
-	g.Expect(objs.size()).Should(Equal(0))
+	g.Expect(objs.Size()).Should(Equal(0))
 }
 
 func TestManifestGenerateFlagsMinimalProfile(t *testing.T) {
-	g := NewWithT(t)
 	// Change profile from empty to minimal using flag.
 	m, _, err := generateManifest("empty", "-s profile=minimal", liveCharts)
 	if err != nil {
 		t.Fatal(err)
 	}
-	objs, err := parseObjectSetFromManifest(m)
+	objs, err := manifesttest.ParseObjectSetFromManifest(m)
 	if err != nil {
 		t.Fatal(err)
 	}
 	// minimal profile always has istiod, empty does not.
-	mustGetDeployment(g, objs, "istiod")
+	manifesttest.MustGetDeployment(t, objs, "istiod")
 }
 
 func TestManifestGenerateFlagsSetHubTag(t *testing.T) {
@@ -245,12 +253,12 @@ func TestManifestGenerateFlagsSetHubTag(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	objs, err := parseObjectSetFromManifest(m)
+	objs, err := manifesttest.ParseObjectSetFromManifest(m)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	dobj := mustGetDeployment(g, objs, "istiod")
+	dobj := manifesttest.MustGetDeployment(t, objs, "istiod")
 
 	c := dobj.Container("discovery")
 	g.Expect(c).Should(HavePathValueEqual(PathValue{"image", "foo/pilot:bar"}))
@@ -262,20 +270,126 @@ func TestManifestGenerateFlagsSetValues(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	objs, err := parseObjectSetFromManifest(m)
+	objs, err := manifesttest.ParseObjectSetFromManifest(m)
 	if err != nil {
 		t.Fatal(err)
 	}
-	dobj := mustGetDeployment(g, objs, "istio-ingressgateway")
+	dobj := manifesttest.MustGetDeployment(t, objs, "istio-ingressgateway")
 
 	c := dobj.Container("istio-proxy")
 	g.Expect(c).Should(HavePathValueEqual(PathValue{"image", "gcr.io/istio-testing/myproxy:latest"}))
 
-	cm := objs.kind("ConfigMap").nameEquals("istio-sidecar-injector").Unstructured()
+	cm := objs.Kind("ConfigMap").NameEquals("istio-sidecar-injector").Unstructured()
 	// TODO: change values to some nicer format rather than text block.
 	g.Expect(cm).Should(HavePathValueMatchRegex(PathValue{"data.values", `.*"includeIPRanges"\: "172\.30\.0\.0/16,172\.21\.0\.0/16".*`}))
 }
 
+func TestManifestGenerateKubernetesVersion(t *testing.T) {
+	tests := []struct {
+		flags          string
+		wantAPIVersion string
+	}{
+		{"", "policy/v1beta1"},
+		{"-s values.global.kubernetesVersion=v1.20.4", "policy/v1beta1"},
+		{"-s values.global.kubernetesVersion=v1.21.0", "policy/v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantAPIVersion+tt.flags, func(t *testing.T) {
+			g := NewWithT(t)
+			m, _, err := generateManifest("default", tt.flags, liveCharts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			objs, err := manifesttest.ParseObjectSetFromManifest(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pdbs := objs.Kind(name.PDBStr).Objects()
+			g.Expect(pdbs).ShouldNot(BeEmpty())
+			for _, pdb := range pdbs {
+				g.Expect(pdb.UnstructuredObject().GetAPIVersion()).Should(Equal(tt.wantAPIVersion), pdb.Name)
+			}
+		})
+	}
+}
+
+// TestManifestGenerateHPAv2 tests that the autoscaling/v2 metrics and behavior of the istiod hpaSpec select the
+// autoscaling/v2 HPA, while the legacy hpaSpec of the gateway is unchanged.
+func TestManifestGenerateHPAv2(t *testing.T) {
+	runTestGroup(t, testGroup{
+		{
+			desc:        "hpa_v2",
+			diffSelect:  "HorizontalPodAutoscaler:*:*",
+			chartSource: liveCharts,
+		},
+	})
+
+	tests := []struct {
+		flags          string
+		wantAPIVersion string
+	}{
+		{"-s values.global.kubernetesVersion=v1.22.0", "autoscaling/v2beta2"},
+		{"-s values.global.kubernetesVersion=v1.23.0", "autoscaling/v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantAPIVersion+tt.flags, func(t *testing.T) {
+			g := NewWithT(t)
+			_, objs, err := generateManifest("hpa_v2", tt.flags, liveCharts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hpa := mustFindObject(t, objs, "istiod", name.HPAStr)
+			g.Expect(hpa.UnstructuredObject().GetAPIVersion()).Should(Equal(tt.wantAPIVersion))
+			hpa = mustFindObject(t, objs, "istio-ingressgateway", name.HPAStr)
+			g.Expect(hpa.UnstructuredObject().GetAPIVersion()).Should(Equal("autoscaling/v2beta1"))
+		})
+	}
+}
+
+func TestManifestGenerateHPAMixedFields(t *testing.T) {
+	flags := "-s components.pilot.k8s.hpaSpec.behavior.scaleDown.stabilizationWindowSeconds=600"
+	_, _, err := generateManifest("pilot_k8s_settings", flags, liveCharts)
+	if err == nil || !strings.Contains(err.Error(), "targetAverageUtilization") {
+		t.Fatalf("expected the legacy metrics with the behavior to be rejected, got %v", err)
+	}
+}
+
+// TestManifestGenerateWebhookPolicies tests the failurePolicy, reinvocationPolicy and extra namespaceSelector
+// expressions of a revisioned injection webhook. The default webhook is covered by the pilot goldens.
+func TestManifestGenerateWebhookPolicies(t *testing.T) {
+	runTestGroup(t, testGroup{
+		{
+			desc:        "webhook_policies",
+			diffSelect:  "MutatingWebhookConfiguration:*:*",
+			chartSource: liveCharts,
+		},
+	})
+}
+
+// TestManifestGenerateProxyConfigOverrides tests that the proxy config overrides of the values are rendered in the
+// mesh config map read by istiod.
+func TestManifestGenerateProxyConfigOverrides(t *testing.T) {
+	runTestGroup(t, testGroup{
+		{
+			desc:        "proxy_config_overrides",
+			diffSelect:  "ConfigMap:*:istio$",
+			chartSource: liveCharts,
+		},
+	})
+}
+
+// TestManifestGenerateMeshExtensions tests that the mesh extensions of the values are rendered in the mesh config map
+// read by istiod.
+func TestManifestGenerateMeshExtensions(t *testing.T) {
+	runTestGroup(t, testGroup{
+		{
+			desc:        "mesh_extensions",
+			diffSelect:  "ConfigMap:*:istio$",
+			chartSource: liveCharts,
+		},
+	})
+}
+
 func TestManifestGenerateFlags(t *testing.T) {
 	flagOutputDir := createTempDirOrFail(t, "flag-output")
 	flagOutputValuesDir := createTempDirOrFail(t, "flag-output-values")
@@ -338,6 +452,11 @@ func TestManifestGeneratePilot(t *testing.T) {
 			desc:       "pilot_merge_meshconfig",
 			diffSelect: "ConfigMap:*:istio$",
 		},
+		{
+			desc:        "pilot_namespace_controller",
+			diffSelect:  "Deployment:*:istiod",
+			chartSource: liveCharts,
+		},
 	})
 }
 
@@ -379,6 +498,102 @@ func TestManifestGenerateOrdered(t *testing.T) {
 		t.Errorf("stable_manifest: Manifest generation is not producing stable text output.")
 	}
 }
+
+func TestManifestGenerateManifestTestParity(t *testing.T) {
+	// The manifesttest package must render what istioctl manifest generate does.
+	inPath := filepath.Join(testDataDir, "input/all_on.yaml")
+	got, err := runManifestGenerate([]string{inPath}, "", snapshotCharts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := manifesttest.Render([]string{inPath}, nil, snapshotCharts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("manifesttest.Render() differs from manifest generate:\n%s", util.YAMLDiff(got, want))
+	}
+}
+
+func TestManifestGenerateComponent(t *testing.T) {
+	runTestGroup(t, testGroup{
+		{
+			desc:  "component_ingressgateways",
+			flags: "--component IngressGateways",
+		},
+	})
+}
+
+func TestManifestGenerateComponentSubset(t *testing.T) {
+	inPath := filepath.Join(testDataDir, "input/component_ingressgateways.yaml")
+	all, err := runManifestGenerate([]string{inPath}, "", snapshotCharts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := runManifestGenerate([]string{inPath}, "--component IngressGateways", snapshotCharts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allObjs, err := object.ParseK8sObjectsFromYAMLManifest(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotObjs, err := object.ParseK8sObjectsFromYAMLManifest(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Besides the namespace of the gateways, the output must be the gateway objects of the full render, and nothing
+	// else, such as the base CRDs.
+	want := make(map[string]*object.K8sObject)
+	for _, o := range allObjs {
+		if strings.Contains(o.Name, "ingressgateway") {
+			want[o.Hash()] = o
+		}
+	}
+	var gatewayObjs object.K8sObjects
+	for _, o := range gotObjs {
+		if o.Kind == name.NamespaceStr {
+			if o.Name != "istio-system" {
+				t.Errorf("unexpected namespace %s rendered for the ingress gateways", o.Name)
+			}
+			continue
+		}
+		gatewayObjs = append(gatewayObjs, o)
+	}
+	if len(gatewayObjs) == 0 || len(gatewayObjs) != len(want) {
+		t.Fatalf("got %d objects, want the %d ingress gateway objects:\n%s", len(gatewayObjs), len(want), gatewayObjs.Keys())
+	}
+	for _, o := range gatewayObjs {
+		w, ok := want[o.Hash()]
+		if !ok {
+			t.Fatalf("unexpected object %s rendered for the ingress gateways", o.Hash())
+		}
+		gotYAML, err := o.YAML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantYAML, err := w.YAML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(gotYAML) != string(wantYAML) {
+			t.Errorf("object %s differs from the full render:\n%s", o.Hash(), util.YAMLDiff(string(gotYAML), string(wantYAML)))
+		}
+	}
+}
+
+func TestManifestGenerateUnknownComponent(t *testing.T) {
+	inPath := filepath.Join(testDataDir, "input/all_on.yaml")
+	_, err := runManifestGenerate([]string{inPath}, "--component IngressGateway", snapshotCharts)
+	if err == nil {
+		t.Fatal("expected an error for the unknown component")
+	}
+	if !strings.Contains(err.Error(), "IngressGateways") {
+		t.Errorf("expected the error to list the valid components, got %v", err)
+	}
+}
+
 func TestManifestGenerateFlagAliases(t *testing.T) {
 	inPath := filepath.Join(testDataDir, "input/all_on.yaml")
 	gotSet, err := runManifestGenerate([]string{inPath}, "--set revision=foo", snapshotCharts)
@@ -666,23 +881,14 @@ func runTestGroup(t *testing.T, tests testGroup) {
 				t.Fatal(err)
 			}
 
-			for _, v := range []bool{true, false} {
-				diff, err := compare.ManifestDiffWithRenameSelectIgnore(got, want,
-					"", diffSelect, tt.diffIgnore, v)
-				if err != nil {
-					t.Fatal(err)
-				}
-				if diff != "" {
-					t.Errorf("%s: got:\n%s\nwant:\n%s\n(-got, +want)\n%s\n", tt.desc, "", "", diff)
-				}
-			}
+			manifesttest.AssertNoDiff(t, got, want, diffSelect, tt.diffIgnore)
 
 		})
 	}
 }
 
 // nolint: unparam
-func generateManifest(inFile, flags string, chartSource chartSourceType) (string, object.K8sObjects, error) {
+func generateManifest(inFile, flags string, chartSource manifesttest.ChartSource) (string, object.K8sObjects, error) {
 	inPath := filepath.Join(testDataDir, "input", inFile+".yaml")
 	manifest, err := runManifestGenerate([]string{inPath}, flags, chartSource)
 	if err != nil {
@@ -694,6 +900,6 @@ func generateManifest(inFile, flags string, chartSource chartSourceType) (string
 
 // runManifestGenerate runs the manifest generate command. If filenames is set, passes the given filenames as -f flag,
 // flags is passed to the command verbatim. If you set both flags and path, make sure to not use -f in flags.
-func runManifestGenerate(filenames []string, flags string, chartSource chartSourceType) (string, error) {
+func runManifestGenerate(filenames []string, flags string, chartSource manifesttest.ChartSource) (string, error) {
 	return runManifestCommand("generate", filenames, flags, chartSource)
 }
@@ -35,6 +35,7 @@ import (
 	"istio.io/istio/operator/pkg/controller/istiocontrolplane"
 	"istio.io/istio/operator/pkg/helmreconciler"
 	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/manifesttest"
 	"istio.io/istio/operator/pkg/name"
 	"istio.io/istio/operator/pkg/object"
 	"istio.io/istio/operator/pkg/translate"
@@ -128,8 +129,8 @@ func recreateTestEnv() error {
 // runManifestCommands runs all testedManifestCmds commands with the given input IOP file, flags and chartSource.
 // It returns an ObjectSet for each cmd type.
 // nolint: unparam
-func runManifestCommands(inFile, flags string, chartSource chartSourceType) (map[cmdType]*ObjectSet, error) {
-	out := make(map[cmdType]*ObjectSet)
+func runManifestCommands(inFile, flags string, chartSource manifesttest.ChartSource) (map[cmdType]*manifesttest.ObjectSet, error) {
+	out := make(map[cmdType]*manifesttest.ObjectSet)
 	for _, cmd := range testedManifestCmds {
 		log.Infof("\nRunning test command using %s\n", cmd)
 		switch cmd {
@@ -143,7 +144,7 @@ func runManifestCommands(inFile, flags string, chartSource chartSourceType) (map
 		default:
 		}
 
-		var objs *ObjectSet
+		var objs *manifesttest.ObjectSet
 		var err error
 		switch cmd {
 		case cmdGenerate:
@@ -151,7 +152,7 @@ func runManifestCommands(inFile, flags string, chartSource chartSourceType) (map
 			if err != nil {
 				return nil, err
 			}
-			objs, err = parseObjectSetFromManifest(m)
+			objs, err = manifesttest.ParseObjectSetFromManifest(m)
 			if err != nil {
 				return nil, err
 			}
@@ -171,13 +172,13 @@ func runManifestCommands(inFile, flags string, chartSource chartSourceType) (map
 }
 
 // fakeApplyManifest runs istioctl install.
-func fakeApplyManifest(inFile, flags string, chartSource chartSourceType) (*ObjectSet, error) {
+func fakeApplyManifest(inFile, flags string, chartSource manifesttest.ChartSource) (*manifesttest.ObjectSet, error) {
 	inPath := filepath.Join(testDataDir, "input", inFile+".yaml")
 	manifest, err := runManifestCommand("install", []string{inPath}, flags, chartSource)
 	if err != nil {
 		return nil, fmt.Errorf("error %s: %s", err, manifest)
 	}
-	return NewObjectSet(getAllIstioObjects()), nil
+	return manifesttest.NewObjectSet(getAllIstioObjects()), nil
 }
 
 // fakeApplyExtraResources applies any extra resources for the given test name.
@@ -195,7 +196,7 @@ func fakeApplyExtraResources(inFile string) error {
 	return nil
 }
 
-func fakeControllerReconcile(inFile string, chartSource chartSourceType) (*ObjectSet, error) {
+func fakeControllerReconcile(inFile string, chartSource manifesttest.ChartSource) (*manifesttest.ObjectSet, error) {
 	l := clog.NewDefaultLogger()
 	_, iops, err := manifest.GenerateConfig(
 		[]string{inFileAbsolutePath(inFile)},
@@ -231,13 +232,13 @@ func fakeControllerReconcile(inFile string, chartSource chartSourceType) (*Objec
 		return nil, err
 	}
 
-	return NewObjectSet(getAllIstioObjects()), nil
+	return manifesttest.NewObjectSet(getAllIstioObjects()), nil
 }
 
 // fakeInstallOperator installs the operator manifest resources into a cluster using the given reconciler.
 // The installation is for testing with a kubebuilder fake cluster only, since no functional Deployment will be
 // created.
-func fakeInstallOperator(reconciler *helmreconciler.HelmReconciler, chartSource chartSourceType, iop proto.Message) error {
+func fakeInstallOperator(reconciler *helmreconciler.HelmReconciler, chartSource manifesttest.ChartSource, iop proto.Message) error {
 	ocArgs := &operatorCommonArgs{
 		manifestsPath:     string(chartSource),
 		istioNamespace:    istioDefaultNamespace,
@@ -279,7 +280,7 @@ func applyWithReconciler(reconciler *helmreconciler.HelmReconciler, manifest str
 
 // runManifestCommand runs the given manifest command. If filenames is set, passes the given filenames as -f flag,
 // flags is passed to the command verbatim. If you set both flags and path, make sure to not use -f in flags.
-func runManifestCommand(command string, filenames []string, flags string, chartSource chartSourceType) (string, error) {
+func runManifestCommand(command string, filenames []string, flags string, chartSource manifesttest.ChartSource) (string, error) {
 	var args string
 	if command == "install" {
 		args = "install"
@@ -22,6 +22,7 @@ import (
 
 	"github.com/kylelemons/godebug/diff"
 
+	"istio.io/istio/operator/pkg/manifesttest"
 	"istio.io/istio/operator/pkg/util"
 )
 
@@ -70,7 +71,7 @@ func TestProfileDump(t *testing.T) {
 	}
 }
 
-func runProfileDump(profilePath, configPath string, chartSource chartSourceType, outfmt string) (string, error) {
+func runProfileDump(profilePath, configPath string, chartSource manifesttest.ChartSource, outfmt string) (string, error) {
 	cmd := "profile dump -f " + profilePath
 	if configPath != "" {
 		cmd += " --config-path " + configPath
@@ -26,7 +26,9 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -264,3 +266,49 @@ func saveIOPToCluster(reconciler *helmreconciler.HelmReconciler, iop string) err
 	}
 	return reconciler.ApplyObject(obj.UnstructuredObject())
 }
+
+// checkRevisionConflict returns an error if an IstioOperator in the cluster, other than iop, installs the same
+// revision into the same namespace. The check is skipped if the IstioOperator CRD is not installed.
+func checkRevisionConflict(c client.Client, iop *v1alpha1.IstioOperator) error {
+	iops := &unstructured.UnstructuredList{}
+	iops.SetGroupVersionKind(v1alpha1.IstioOperatorGVK.GroupVersion().WithKind(v1alpha1.IstioOperatorGVK.Kind + "List"))
+	if err := c.List(context.TODO(), iops); err != nil {
+		if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list IstioOperators: %v", err)
+	}
+	return revisionConflict(iop, iops.Items)
+}
+
+// revisionConflict returns an error if one of the installed IstioOperators, other than iop, installs the same
+// revision into the same namespace.
+func revisionConflict(iop *v1alpha1.IstioOperator, installed []unstructured.Unstructured) error {
+	revision, namespace := iop.Spec.Revision, v1alpha1.Namespace(iop.Spec)
+	if namespace == "" {
+		namespace = istioDefaultNamespace
+	}
+	for _, other := range installed {
+		if other.GetName() == iop.Name && other.GetNamespace() == iop.Namespace {
+			continue
+		}
+		otherRevision, _, _ := unstructured.NestedString(other.Object, "spec", "revision")
+		otherNamespace, _, _ := unstructured.NestedString(other.Object, "spec", "namespace")
+		if otherNamespace == "" {
+			otherNamespace, _, _ = unstructured.NestedString(other.Object, "spec", "values", "global", "istioNamespace")
+		}
+		if otherNamespace == "" {
+			otherNamespace = istioDefaultNamespace
+		}
+		if otherRevision != revision || otherNamespace != namespace {
+			continue
+		}
+		if revision == "" {
+			return fmt.Errorf("default revision in namespace %q is already installed by IstioOperator %s/%s",
+				namespace, other.GetNamespace(), other.GetName())
+		}
+		return fmt.Errorf("revision %q in namespace %q is already installed by IstioOperator %s/%s",
+			revision, namespace, other.GetNamespace(), other.GetName())
+	}
+	return nil
+}
@@ -23,16 +23,15 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/onsi/gomega"
 	"github.com/onsi/gomega/types"
 	labels2 "k8s.io/apimachinery/pkg/labels"
 
+	"istio.io/istio/operator/pkg/manifesttest"
 	name2 "istio.io/istio/operator/pkg/name"
 	"istio.io/istio/operator/pkg/object"
 	"istio.io/istio/operator/pkg/tpath"
 	"istio.io/istio/operator/pkg/util"
 	"istio.io/istio/pkg/test"
-	"istio.io/pkg/log"
 )
 
 // PathValue is a path/value type.
@@ -46,191 +45,6 @@ func (pv *PathValue) String() string {
 	return fmt.Sprintf("%s:%v", pv.path, pv.value)
 }
 
-// ObjectSet is a set of objects maintained both as a slice (for ordering) and map (for speed).
-type ObjectSet struct {
-	objSlice object.K8sObjects
-	objMap   map[string]*object.K8sObject
-	keySlice []string
-}
-
-// NewObjectSet creates a new ObjectSet from objs and returns a pointer to it.
-func NewObjectSet(objs object.K8sObjects) *ObjectSet {
-	ret := &ObjectSet{}
-	for _, o := range objs {
-		ret.append(o)
-	}
-	return ret
-}
-
-// parseObjectSetFromManifest parses an ObjectSet from the given manifest.
-func parseObjectSetFromManifest(manifest string) (*ObjectSet, error) {
-	objSlice, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
-	return NewObjectSet(objSlice), err
-}
-
-// append appends an object to o.
-func (o *ObjectSet) append(obj *object.K8sObject) {
-	h := obj.Hash()
-	o.objSlice = append(o.objSlice, obj)
-	if o.objMap == nil {
-		o.objMap = make(map[string]*object.K8sObject)
-	}
-	o.objMap[h] = obj
-	o.keySlice = append(o.keySlice, h)
-}
-
-// size reports the length of o.
-func (o *ObjectSet) size() int {
-	return len(o.keySlice)
-}
-
-// nameMatches returns a subset of o where objects names match the given regex.
-func (o *ObjectSet) nameMatches(nameRegex string) *ObjectSet {
-	ret := &ObjectSet{}
-	for k, v := range o.objMap {
-		_, _, objName := object.FromHash(k)
-		m, err := regexp.MatchString(nameRegex, objName)
-		if err != nil {
-			log.Error(err.Error())
-			continue
-		}
-		if m {
-			ret.append(v)
-		}
-	}
-	return ret
-}
-
-// nameEquals returns the object in o whose name matches "name", or nil if no object name matches.
-func (o *ObjectSet) nameEquals(name string) *object.K8sObject {
-	for k, v := range o.objMap {
-		_, _, objName := object.FromHash(k)
-		if objName == name {
-			return v
-		}
-	}
-	return nil
-}
-
-// kind returns a subset of o where kind matches the given value.
-func (o *ObjectSet) kind(kind string) *ObjectSet {
-	ret := &ObjectSet{}
-	for k, v := range o.objMap {
-		objKind, _, _ := object.FromHash(k)
-		if objKind == kind {
-			ret.append(v)
-		}
-	}
-	return ret
-}
-
-// namespace returns a subset of o where namespace matches the given value or fails if it's not found in objs.
-func (o *ObjectSet) namespace(namespace string) *ObjectSet {
-	ret := &ObjectSet{}
-	for k, v := range o.objMap {
-		_, objNamespace, _ := object.FromHash(k)
-		if objNamespace == namespace {
-			ret.append(v)
-		}
-	}
-	return ret
-}
-
-// labels returns a subset of o where the object's labels match all the given labels.
-func (o *ObjectSet) labels(labels ...string) *ObjectSet {
-	ret := &ObjectSet{}
-	for _, obj := range o.objMap {
-		hasAll := true
-		for _, l := range labels {
-			lkv := strings.Split(l, "=")
-			if len(lkv) != 2 {
-				panic("label must have format key=value")
-			}
-			if !hasLabel(obj, lkv[0], lkv[1]) {
-				hasAll = false
-				break
-			}
-		}
-		if hasAll {
-			ret.append(obj)
-		}
-	}
-	return ret
-}
-
-// HasLabel reports whether 0 has the given label.
-func hasLabel(o *object.K8sObject, label, value string) bool {
-	got, found, err := tpath.Find(o.UnstructuredObject().UnstructuredContent(), util.PathFromString("metadata.labels"))
-	if err != nil {
-		log.Errorf("bad path: %s", err)
-		return false
-	}
-	if !found {
-		return false
-	}
-	return got.(map[string]interface{})[label] == value
-}
-
-// mustGetService returns the service with the given name or fails if it's not found in objs.
-func mustGetService(g *gomega.WithT, objs *ObjectSet, name string) *object.K8sObject {
-	obj := objs.kind(name2.ServiceStr).nameEquals(name)
-	g.Expect(obj).Should(gomega.Not(gomega.BeNil()))
-	return obj
-}
-
-// mustGetDeployment returns the deployment with the given name or fails if it's not found in objs.
-func mustGetDeployment(g *gomega.WithT, objs *ObjectSet, deploymentName string) *object.K8sObject {
-	obj := objs.kind(name2.DeploymentStr).nameEquals(deploymentName)
-	g.Expect(obj).Should(gomega.Not(gomega.BeNil()))
-	return obj
-}
-
-// mustGetClusterRole returns the clusterRole with the given name or fails if it's not found in objs.
-func mustGetClusterRole(g *gomega.WithT, objs *ObjectSet, name string) *object.K8sObject {
-	obj := objs.kind(name2.ClusterRoleStr).nameEquals(name)
-	g.Expect(obj).Should(gomega.Not(gomega.BeNil()))
-	return obj
-}
-
-// mustGetRole returns the role with the given name or fails if it's not found in objs.
-func mustGetRole(g *gomega.WithT, objs *ObjectSet, name string) *object.K8sObject {
-	obj := objs.kind(name2.RoleStr).nameEquals(name)
-	g.Expect(obj).Should(gomega.Not(gomega.BeNil()))
-	return obj
-}
-
-// mustGetContainer returns the container tree with the given name in the deployment with the given name.
-func mustGetContainer(g *gomega.WithT, objs *ObjectSet, deploymentName, containerName string) map[string]interface{} {
-	obj := mustGetDeployment(g, objs, deploymentName)
-	container := obj.Container(containerName)
-	g.Expect(container).Should(gomega.Not(gomega.BeNil()), fmt.Sprintf("Expected to get container %s in deployment %s", containerName, deploymentName))
-	return container
-}
-
-// mustGetEndpoint returns the endpoint tree with the given name in the deployment with the given name.
-func mustGetEndpoint(g *gomega.WithT, objs *ObjectSet, endpointName string) *object.K8sObject {
-	obj := objs.kind(name2.EndpointStr).nameEquals(endpointName)
-	if obj == nil {
-		return nil
-	}
-	g.Expect(obj).Should(gomega.Not(gomega.BeNil()))
-	return obj
-}
-
-// mustGetMutatingWebhookConfiguration returns the mutatingWebhookConfiguration with the given name or fails if it's not found in objs.
-func mustGetMutatingWebhookConfiguration(g *gomega.WithT, objs *ObjectSet, mutatingWebhookConfigurationName string) *object.K8sObject {
-	obj := objs.kind(name2.MutatingWebhookConfigurationStr).nameEquals(mutatingWebhookConfigurationName)
-	g.Expect(obj).Should(gomega.Not(gomega.BeNil()))
-	return obj
-}
-
-// mustGetValidatingWebhookConfiguration returns the validatingWebhookConfiguration with the given name or fails if it's not found in objs.
-func mustGetValidatingWebhookConfiguration(g *gomega.WithT, objs *ObjectSet, validatingWebhookConfigurationName string) *object.K8sObject {
-	obj := objs.kind(name2.ValidatingWebhookConfigurationStr).nameEquals(validatingWebhookConfigurationName)
-	g.Expect(obj).Should(gomega.Not(gomega.BeNil()))
-	return obj
-}
-
 // HavePathValueEqual matches map[string]interface{} tree against a PathValue.
 func HavePathValueEqual(expected interface{}) types.GomegaMatcher {
 	return &HavePathValueEqualMatcher{
@@ -437,15 +251,6 @@ func findObject(objs object.K8sObjects, name, kind string) *object.K8sObject {
 	return nil
 }
 
-// mustGetValueAtPath returns the value at the given path in the unstructured tree t. Fails if the path is not found
-// in the tree.
-func mustGetValueAtPath(g *gomega.WithT, t map[string]interface{}, path string) interface{} {
-	got, f, err := tpath.GetPathContext(t, util.PathFromString(path), false)
-	g.Expect(err).Should(gomega.BeNil(), "path %s should exist (%s)", path, err)
-	g.Expect(f).Should(gomega.BeTrue(), "path %s should exist", path)
-	return got.Node
-}
-
 func createTempDirOrFail(t *testing.T, prefix string) string {
 	dir, err := ioutil.TempDir("", prefix)
 	if err != nil {
@@ -509,19 +314,19 @@ func portVal(name string, port, targetPort int64) map[string]interface{} {
 }
 
 // checkRoleBindingsReferenceRoles fails if any RoleBinding in objs references a Role that isn't found in objs.
-func checkRoleBindingsReferenceRoles(g *gomega.WithT, objs *ObjectSet) {
-	for _, o := range objs.kind(name2.RoleBindingStr).objSlice {
+func checkRoleBindingsReferenceRoles(t testing.TB, objs *manifesttest.ObjectSet) {
+	for _, o := range objs.Kind(name2.RoleBindingStr).Objects() {
 		ou := o.Unstructured()
-		rrname := mustGetValueAtPath(g, ou, "roleRef.name")
-		mustGetRole(g, objs, rrname.(string))
+		rrname := manifesttest.MustGetValueAtPath(t, ou, "roleRef.name")
+		manifesttest.MustGetRole(t, objs, rrname.(string))
 	}
 }
 
 // checkClusterRoleBindingsReferenceRoles fails if any RoleBinding in objs references a Role that isn't found in objs.
-func checkClusterRoleBindingsReferenceRoles(g *gomega.WithT, objs *ObjectSet) {
-	for _, o := range objs.kind(name2.ClusterRoleBindingStr).objSlice {
+func checkClusterRoleBindingsReferenceRoles(t testing.TB, objs *manifesttest.ObjectSet) {
+	for _, o := range objs.Kind(name2.ClusterRoleBindingStr).Objects() {
 		ou := o.Unstructured()
-		rrname := mustGetValueAtPath(g, ou, "roleRef.name")
-		mustGetClusterRole(g, objs, rrname.(string))
+		rrname := manifesttest.MustGetValueAtPath(t, ou, "roleRef.name")
+		manifesttest.MustGetClusterRole(t, objs, rrname.(string))
 	}
 }
@@ -961,9 +961,19 @@ type EgressGatewayConfig struct {
 	ConfigVolumes         []map[string]interface{} `protobuf:"bytes,23,opt,name=configVolumes,proto3" json:"configVolumes,omitempty"`
 	AdditionalContainers  []map[string]interface{} `protobuf:"bytes,24,opt,name=additionalContainers,proto3" json:"additionalContainers,omitempty"`
 	RunAsRoot             *protobuf.BoolValue            `protobuf:"bytes,26,opt,name=runAsRoot,proto3" json:"runAsRoot,omitempty"`
-	XXX_NoUnkeyedLiteral  struct{}                       `json:"-"`
-	XXX_unrecognized      []byte                         `json:"-"`
-	XXX_sizecache         int32                          `json:"-"`
+	// Number of worker threads for the gateway proxy. Unset or 0 leaves the proxy default.
+	Concurrency uint32 `protobuf:"varint,27,opt,name=concurrency,proto3" json:"concurrency,omitempty"`
+	// Metrics of the autoscaling/v2 HorizontalPodAutoscaler, replacing the CPU utilization target when set.
+	//
+	// See https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#support-for-multiple-metrics
+	AutoscaleMetrics []map[string]interface{} `protobuf:"bytes,28,opt,name=autoscaleMetrics,proto3" json:"autoscaleMetrics,omitempty"`
+	// Scaling behavior of the autoscaling/v2 HorizontalPodAutoscaler, e.g. scaleDown.stabilizationWindowSeconds.
+	//
+	// See https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#configurable-scaling-behavior
+	AutoscaleBehavior    map[string]interface{} `protobuf:"bytes,29,opt,name=autoscaleBehavior,proto3" json:"autoscaleBehavior,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
 }
 
 func (m *EgressGatewayConfig) Reset()         { *m = EgressGatewayConfig{} }
@@ -1167,6 +1177,27 @@ func (m *EgressGatewayConfig) GetRunAsRoot() *protobuf.BoolValue {
 	return nil
 }
 
+func (m *EgressGatewayConfig) GetConcurrency() uint32 {
+	if m != nil {
+		return m.Concurrency
+	}
+	return 0
+}
+
+func (m *EgressGatewayConfig) GetAutoscaleMetrics() []map[string]interface{} {
+	if m != nil {
+		return m.AutoscaleMetrics
+	}
+	return nil
+}
+
+func (m *EgressGatewayConfig) GetAutoscaleBehavior() map[string]interface{} {
+	if m != nil {
+		return m.AutoscaleBehavior
+	}
+	return nil
+}
+
 // Configuration for gateways.
 type GatewaysConfig struct {
 	// Configuration for an egress gateway.
@@ -1343,10 +1374,14 @@ type GlobalConfig struct {
 	// The address of the CA for CSR.
 	CaAddress string `protobuf:"bytes,61,opt,name=caAddress,proto3" json:"caAddress,omitempty"`
 	// Controls whether one central istiod is enabled.
-	CentralIstiod        *protobuf.BoolValue `protobuf:"bytes,62,opt,name=centralIstiod,proto3" json:"centralIstiod,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
+	CentralIstiod *protobuf.BoolValue `protobuf:"bytes,62,opt,name=centralIstiod,proto3" json:"centralIstiod,omitempty"`
+	// The Kubernetes version the manifests are rendered for, e.g. "v1.21". It selects the API version of the kinds
+	// whose older API versions are removed in newer Kubernetes versions, such as PodDisruptionBudget.
+	// If unset, the oldest supported API versions are used. It is detected from the cluster when installing.
+	KubernetesVersion    string   `protobuf:"bytes,63,opt,name=kubernetesVersion,proto3" json:"kubernetesVersion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *GlobalConfig) Reset()         { *m = GlobalConfig{} }
@@ -1658,6 +1693,13 @@ func (m *GlobalConfig) GetCentralIstiod() *protobuf.BoolValue {
 	return nil
 }
 
+func (m *GlobalConfig) GetKubernetesVersion() string {
+	if m != nil {
+		return m.KubernetesVersion
+	}
+	return ""
+}
+
 // Configuration for Security Token Service (STS) server.
 //
 // See https://tools.ietf.org/html/draft-ietf-oauth-token-exchange-16
@@ -1842,9 +1884,19 @@ type IngressGatewayConfig struct {
 	AdditionalContainers  []map[string]interface{} `protobuf:"bytes,37,opt,name=additionalContainers,proto3" json:"additionalContainers,omitempty"`
 	ConfigVolumes         []map[string]interface{} `protobuf:"bytes,38,opt,name=configVolumes,proto3" json:"configVolumes,omitempty"`
 	RunAsRoot             *protobuf.BoolValue            `protobuf:"bytes,45,opt,name=runAsRoot,proto3" json:"runAsRoot,omitempty"`
-	XXX_NoUnkeyedLiteral  struct{}                       `json:"-"`
-	XXX_unrecognized      []byte                         `json:"-"`
-	XXX_sizecache         int32                          `json:"-"`
+	// Number of worker threads for the gateway proxy. Unset or 0 leaves the proxy default.
+	Concurrency uint32 `protobuf:"varint,46,opt,name=concurrency,proto3" json:"concurrency,omitempty"`
+	// Metrics of the autoscaling/v2 HorizontalPodAutoscaler, replacing the CPU utilization target when set.
+	//
+	// See https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#support-for-multiple-metrics
+	AutoscaleMetrics []map[string]interface{} `protobuf:"bytes,47,opt,name=autoscaleMetrics,proto3" json:"autoscaleMetrics,omitempty"`
+	// Scaling behavior of the autoscaling/v2 HorizontalPodAutoscaler, e.g. scaleDown.stabilizationWindowSeconds.
+	//
+	// See https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#configurable-scaling-behavior
+	AutoscaleBehavior    map[string]interface{} `protobuf:"bytes,48,opt,name=autoscaleBehavior,proto3" json:"autoscaleBehavior,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
 }
 
 func (m *IngressGatewayConfig) Reset()         { *m = IngressGatewayConfig{} }
@@ -2098,6 +2150,27 @@ func (m *IngressGatewayConfig) GetRunAsRoot() *protobuf.BoolValue {
 	return nil
 }
 
+func (m *IngressGatewayConfig) GetConcurrency() uint32 {
+	if m != nil {
+		return m.Concurrency
+	}
+	return 0
+}
+
+func (m *IngressGatewayConfig) GetAutoscaleMetrics() []map[string]interface{} {
+	if m != nil {
+		return m.AutoscaleMetrics
+	}
+	return nil
+}
+
+func (m *IngressGatewayConfig) GetAutoscaleBehavior() map[string]interface{} {
+	if m != nil {
+		return m.AutoscaleBehavior
+	}
+	return nil
+}
+
 // IngressGatewayZvpnConfig enables cross-cluster access using SNI matching.
 type IngressGatewayZvpnConfig struct {
 	// Controls whether ZeroVPN is enabled.
@@ -2369,9 +2442,29 @@ type PilotConfig struct {
 	Plugins                 []string   `protobuf:"bytes,33,opt,name=plugins,proto3" json:"plugins,omitempty"`
 	Hub                     string             `protobuf:"bytes,34,opt,name=hub,proto3" json:"hub,omitempty"`
 	Tag                     interface{}     `protobuf:"bytes,35,opt,name=tag,proto3" json:"tag,omitempty"`
-	XXX_NoUnkeyedLiteral    struct{}           `json:"-"`
-	XXX_unrecognized        []byte             `json:"-"`
-	XXX_sizecache           int32              `json:"-"`
+	// Namespaces the istio-ca-root-cert configmap is always created in.
+	NamespaceControllerNamespaces []string `protobuf:"bytes,36,opt,name=namespaceControllerNamespaces,proto3" json:"namespaceControllerNamespaces,omitempty"`
+	// Label selector of the namespaces the istio-ca-root-cert configmap is created in, in addition to namespaceControllerNamespaces.
+	NamespaceControllerLabelSelector string `protobuf:"bytes,37,opt,name=namespaceControllerLabelSelector,proto3" json:"namespaceControllerLabelSelector,omitempty"`
+	// Controls whether the istio-ca-root-cert configmap is deleted from the namespaces out of scope.
+	NamespaceControllerCleanup *protobuf.BoolValue `protobuf:"bytes,38,opt,name=namespaceControllerCleanup,proto3" json:"namespaceControllerCleanup,omitempty"`
+	// Metrics of the autoscaling/v2 HorizontalPodAutoscaler, replacing the CPU utilization target when set.
+	//
+	// See https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#support-for-multiple-metrics
+	AutoscaleMetrics []map[string]interface{} `protobuf:"bytes,39,opt,name=autoscaleMetrics,proto3" json:"autoscaleMetrics,omitempty"`
+	// Scaling behavior of the autoscaling/v2 HorizontalPodAutoscaler, e.g. scaleDown.stabilizationWindowSeconds.
+	//
+	// See https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#configurable-scaling-behavior
+	AutoscaleBehavior map[string]interface{} `protobuf:"bytes,40,opt,name=autoscaleBehavior,proto3" json:"autoscaleBehavior,omitempty"`
+	// Overrides of the default proxy config of the mesh for the workloads of a namespace, or selected by labels,
+	// pushed to their agents. Rendered in the proxyConfigOverrides key of the mesh config map.
+	ProxyConfigOverrides []map[string]interface{} `protobuf:"bytes,41,opt,name=proxyConfigOverrides,proto3" json:"proxyConfigOverrides,omitempty"`
+	// Settings of the mesh applied by istiod which are not part of the mesh config, such as the egress gateway
+	// redirects. Rendered in the meshExtensions key of the mesh config map.
+	MeshExtensions       map[string]interface{} `protobuf:"bytes,42,opt,name=meshExtensions,proto3" json:"meshExtensions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
 }
 
 func (m *PilotConfig) Reset()         { *m = PilotConfig{} }
@@ -2595,6 +2688,55 @@ func (m *PilotConfig) GetTag() interface{} {
 	return nil
 }
 
+func (m *PilotConfig) GetNamespaceControllerNamespaces() []string {
+	if m != nil {
+		return m.NamespaceControllerNamespaces
+	}
+	return nil
+}
+
+func (m *PilotConfig) GetNamespaceControllerLabelSelector() string {
+	if m != nil {
+		return m.NamespaceControllerLabelSelector
+	}
+	return ""
+}
+
+func (m *PilotConfig) GetNamespaceControllerCleanup() *protobuf.BoolValue {
+	if m != nil {
+		return m.NamespaceControllerCleanup
+	}
+	return nil
+}
+
+func (m *PilotConfig) GetAutoscaleMetrics() []map[string]interface{} {
+	if m != nil {
+		return m.AutoscaleMetrics
+	}
+	return nil
+}
+
+func (m *PilotConfig) GetAutoscaleBehavior() map[string]interface{} {
+	if m != nil {
+		return m.AutoscaleBehavior
+	}
+	return nil
+}
+
+func (m *PilotConfig) GetProxyConfigOverrides() []map[string]interface{} {
+	if m != nil {
+		return m.ProxyConfigOverrides
+	}
+	return nil
+}
+
+func (m *PilotConfig) GetMeshExtensions() map[string]interface{} {
+	if m != nil {
+		return m.MeshExtensions
+	}
+	return nil
+}
+
 // Controls legacy k8s ingress. Only one pilot profile should enable ingress support.
 type PilotIngressConfig struct {
 	// Sets the type ingress service for Pilot.
@@ -3724,10 +3866,16 @@ type SidecarInjectorConfig struct {
 	// Enable objectSelector to filter out pods with no need for sidecar before calling istio-sidecar-injector.
 	ObjectSelector map[string]interface{} `protobuf:"bytes,21,opt,name=objectSelector,proto3" json:"objectSelector,omitempty"`
 	// Configure the injection url for sidecar injector webhook
-	InjectionURL         string   `protobuf:"bytes,22,opt,name=injectionURL,proto3" json:"injectionURL,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	InjectionURL string `protobuf:"bytes,22,opt,name=injectionURL,proto3" json:"injectionURL,omitempty"`
+	// failurePolicy of the sidecar injector webhook, Fail or Ignore. Defaults to Fail.
+	FailurePolicy string `protobuf:"bytes,23,opt,name=failurePolicy,proto3" json:"failurePolicy,omitempty"`
+	// reinvocationPolicy of the sidecar injector webhook, Never or IfNeeded.
+	ReinvocationPolicy string `protobuf:"bytes,24,opt,name=reinvocationPolicy,proto3" json:"reinvocationPolicy,omitempty"`
+	// Additional matchExpressions appended to the namespaceSelector of the sidecar injector webhook.
+	NamespaceSelectorMatchExpressions []map[string]interface{} `protobuf:"bytes,25,opt,name=namespaceSelectorMatchExpressions,proto3" json:"namespaceSelectorMatchExpressions,omitempty"`
+	XXX_NoUnkeyedLiteral              struct{}                       `json:"-"`
+	XXX_unrecognized                  []byte                         `json:"-"`
+	XXX_sizecache                     int32                          `json:"-"`
 }
 
 func (m *SidecarInjectorConfig) Reset()         { *m = SidecarInjectorConfig{} }
@@ -3803,6 +3951,27 @@ func (m *SidecarInjectorConfig) GetInjectionURL() string {
 	return ""
 }
 
+func (m *SidecarInjectorConfig) GetFailurePolicy() string {
+	if m != nil {
+		return m.FailurePolicy
+	}
+	return ""
+}
+
+func (m *SidecarInjectorConfig) GetReinvocationPolicy() string {
+	if m != nil {
+		return m.ReinvocationPolicy
+	}
+	return ""
+}
+
+func (m *SidecarInjectorConfig) GetNamespaceSelectorMatchExpressions() []map[string]interface{} {
+	if m != nil {
+		return m.NamespaceSelectorMatchExpressions
+	}
+	return nil
+}
+
 // Configuration for each of the supported tracers.
 type TracerConfig struct {
 	// Configuration for the datadog tracing service.
@@ -4408,297 +4577,309 @@ func init() {
 }
 
 var fileDescriptor_261260e22432516f = []byte{
-	// 4672 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdc, 0x3c, 0xc9, 0x72, 0x1c, 0x47,
-	0x76, 0x6c, 0xec, 0xfd, 0xba, 0x1b, 0x68, 0x24, 0x16, 0x16, 0x21, 0x88, 0x84, 0x4a, 0x12, 0x07,
-	0x22, 0x35, 0x20, 0x05, 0x61, 0x48, 0x8a, 0x5a, 0x2c, 0xac, 0x52, 0x6b, 0x00, 0xb0, 0x5d, 0x0d,
-	0x52, 0xcb, 0x78, 0x86, 0x2e, 0x54, 0x25, 0x1a, 0x29, 0x56, 0x57, 0xd6, 0x54, 0x65, 0x37, 0x00,
-	0x1d, 0xec, 0xf0, 0xc9, 0x37, 0x1f, 0xfc, 0x01, 0xf6, 0xc1, 0x8e, 0xf0, 0x27, 0x38, 0xfc, 0x07,
-	0x3e, 0x38, 0x1c, 0xbe, 0xf8, 0xe8, 0x08, 0x87, 0x6e, 0x3e, 0xfa, 0xe0, 0x98, 0x83, 0x2f, 0x8e,
-	0x5c, 0x6a, 0xed, 0xea, 0x05, 0x80, 0xe8, 0x71, 0xf8, 0x84, 0xae, 0xb7, 0x65, 0x56, 0xe6, 0xcb,
-	0xb7, 0xe5, 0x2b, 0xc0, 0x3d, 0xef, 0x55, 0xf3, 0x81, 0xe9, 0x91, 0xe0, 0x01, 0x09, 0x18, 0xa1,
-	0x0f, 0x3a, 0x1f, 0x98, 0x8e, 0x77, 0x6a, 0x7e, 0xf0, 0xa0, 0x63, 0x3a, 0x6d, 0x1c, 0xbc, 0x64,
-	0x17, 0x1e, 0x0e, 0xd6, 0x3c, 0x9f, 0x32, 0x8a, 0xa6, 0x42, 0xe4, 0xd2, 0xed, 0x26, 0xa5, 0x4d,
-	0x07, 0x3f, 0x10, 0xf0, 0xe3, 0xf6, 0xc9, 0x03, 0xbb, 0xed, 0x9b, 0x8c, 0x50, 0x57, 0x52, 0x2e,
-	0x7d, 0xde, 0x24, 0xec, 0xb4, 0x7d, 0xbc, 0x66, 0xd1, 0xd6, 0x83, 0x26, 0x6d, 0xd2, 0x98, 0x30,
-	0xfa, 0x91, 0x95, 0x70, 0xe6, 0x9b, 0x9e, 0x87, 0x7d, 0x35, 0xd6, 0xd2, 0x3c, 0x67, 0x13, 0x3f,
-	0x85, 0x00, 0x09, 0xd5, 0x0d, 0x80, 0x4d, 0xdf, 0x3a, 0xdd, 0xa6, 0xee, 0x09, 0x69, 0xa2, 0x79,
-	0x18, 0x37, 0x5b, 0xf6, 0xa3, 0x0d, 0xad, 0xb0, 0x52, 0x58, 0xad, 0x18, 0xf2, 0x01, 0x69, 0x30,
-	0xe9, 0x79, 0xd6, 0xa3, 0x0d, 0x07, 0x6b, 0x23, 0x02, 0x1e, 0x3e, 0x72, 0xfa, 0xe0, 0xc3, 0x8f,
-	0x1e, 0x9e, 0x6b, 0xa3, 0x92, 0x5e, 0x3c, 0xe8, 0xbf, 0x1b, 0x83, 0xe2, 0xf6, 0x61, 0x4d, 0xc9,
-	0xdc, 0x80, 0x49, 0xec, 0x9a, 0xc7, 0x0e, 0xb6, 0x85, 0xd4, 0xd2, 0xfa, 0xd2, 0x9a, 0x9c, 0xe9,
-	0x5a, 0x38, 0xd3, 0xb5, 0x2d, 0x4a, 0x9d, 0x17, 0x7c, 0x75, 0x8c, 0x90, 0x14, 0x55, 0x61, 0xf4,
-	0xb4, 0x7d, 0x2c, 0xc6, 0x2b, 0x1a, 0xfc, 0x27, 0x7a, 0x0f, 0x46, 0x99, 0xd9, 0x14, 0x23, 0x95,
-	0xd6, 0x6f, 0xae, 0x85, 0x2b, 0xb7, 0x76, 0x74, 0xe1, 0xe1, 0x9a, 0xcb, 0xb0, 0x7f, 0x62, 0x5a,
-	0xd8, 0xe0, 0x34, 0x7c, 0x5a, 0xa4, 0x65, 0x36, 0xb1, 0x36, 0x26, 0xd8, 0xe5, 0x03, 0xba, 0x0d,
-	0xe0, 0xb5, 0x1d, 0xa7, 0x4e, 0x1d, 0x62, 0x5d, 0x68, 0xe3, 0x02, 0x95, 0x80, 0xa0, 0x65, 0x28,
-	0x5a, 0x2e, 0xd9, 0x22, 0xee, 0x0e, 0xf1, 0xb5, 0x09, 0x81, 0x8e, 0x01, 0x9c, 0xdb, 0x72, 0x09,
-	0x7f, 0x27, 0x8e, 0x9e, 0x94, 0xdc, 0x31, 0x04, 0xad, 0xc2, 0x8c, 0x7a, 0xda, 0x23, 0x0e, 0x3e,
-	0x34, 0x5b, 0x58, 0x9b, 0x12, 0x44, 0x59, 0x30, 0x7a, 0x1f, 0x66, 0xf1, 0xb9, 0xe5, 0xb4, 0x6d,
-	0xf1, 0x18, 0x78, 0xa6, 0x85, 0x03, 0xad, 0xb8, 0x32, 0xba, 0x5a, 0x34, 0xba, 0x11, 0x68, 0x1f,
-	0xa6, 0x3d, 0x6a, 0x6f, 0xba, 0x2e, 0x65, 0x42, 0x1f, 0x02, 0x0d, 0xc4, 0x0a, 0xac, 0xa4, 0x57,
-	0xe0, 0xc0, 0xf4, 0x1a, 0xcc, 0x27, 0x6e, 0x33, 0x5a, 0x8a, 0xad, 0x11, 0xad, 0x60, 0x64, 0x78,
-	0xd1, 0x2a, 0x54, 0xbd, 0xc0, 0x7b, 0x69, 0x39, 0xed, 0x80, 0x61, 0xff, 0xa5, 0x4f, 0x1d, 0xac,
-	0x95, 0xc4, 0x34, 0xa7, 0xbd, 0xc0, 0xdb, 0x96, 0x60, 0x83, 0x3a, 0x18, 0x2d, 0xc1, 0x94, 0x43,
-	0x9b, 0xfb, 0xb8, 0x83, 0x1d, 0xad, 0x2c, 0x28, 0xa2, 0x67, 0xf4, 0x01, 0x4c, 0xf8, 0xd8, 0x33,
-	0x89, 0xaf, 0x55, 0xc4, 0x5c, 0x6e, 0xc5, 0x73, 0xd9, 0x3e, 0xac, 0x19, 0x02, 0x25, 0x77, 0xdf,
-	0x50, 0x84, 0x5c, 0x0b, 0xac, 0x53, 0x93, 0xb8, 0xd8, 0xd6, 0xa6, 0x07, 0x6b, 0x81, 0x22, 0x45,
-	0x6b, 0x30, 0xce, 0x4c, 0xe2, 0x32, 0x6d, 0x46, 0xf0, 0x68, 0xa9, 0x71, 0x8e, 0x38, 0x46, 0x0d,
-	0x23, 0xc9, 0xf4, 0x3d, 0x98, 0x4e, 0x23, 0xae, 0xa6, 0x7d, 0xfa, 0x5f, 0x8c, 0xc2, 0x4c, 0xe6,
-	0x4d, 0xfe, 0xef, 0xe8, 0xf1, 0x32, 0x14, 0x1d, 0xf3, 0x18, 0x3b, 0x75, 0x6a, 0x07, 0x42, 0x8d,
-	0xa7, 0x8c, 0x18, 0x80, 0xee, 0x42, 0xd9, 0xf2, 0xb1, 0xc9, 0xf0, 0x6e, 0x07, 0xbb, 0x2c, 0x90,
-	0x8a, 0x2c, 0x74, 0x21, 0x05, 0xe7, 0xfa, 0x6c, 0x63, 0x07, 0x33, 0x2c, 0xc4, 0x4c, 0x0a, 0x31,
-	0x09, 0x08, 0xd7, 0xd2, 0x63, 0x9f, 0xbe, 0xc2, 0x6e, 0x9d, 0xda, 0xfb, 0x5c, 0xfa, 0x2f, 0xf1,
-	0x85, 0xd2, 0xe8, 0x6e, 0x04, 0x7a, 0x08, 0x73, 0x69, 0xa0, 0x58, 0x06, 0xad, 0x28, 0xe8, 0xf3,
-	0x50, 0x5c, 0x3e, 0x71, 0x09, 0xdf, 0x26, 0xbe, 0x75, 0xd8, 0x17, 0x27, 0x06, 0xa4, 0xfc, 0x2e,
-	0x84, 0xfe, 0x0d, 0x2c, 0x6d, 0xd7, 0x9f, 0x1f, 0x99, 0x7e, 0x13, 0xb3, 0xe7, 0x8c, 0x38, 0xe4,
-	0x07, 0xa1, 0xd0, 0x6a, 0x6b, 0x9e, 0x82, 0xc6, 0x04, 0x6a, 0xb3, 0x83, 0x7d, 0xb3, 0x89, 0x13,
-	0x14, 0x62, 0xaf, 0xc6, 0x8d, 0x9e, 0x78, 0xfd, 0xbf, 0x0b, 0x50, 0x34, 0x70, 0x40, 0xdb, 0x3e,
-	0x3f, 0x6d, 0x8f, 0x61, 0xc2, 0x21, 0x2d, 0xc2, 0x02, 0xad, 0xb0, 0x32, 0xba, 0x5a, 0x5a, 0xbf,
-	0x13, 0xef, 0x4f, 0x44, 0xb4, 0xb6, 0x2f, 0x28, 0x76, 0x5d, 0xe6, 0x5f, 0x18, 0x8a, 0x1c, 0x7d,
-	0x0a, 0x53, 0x3e, 0xfe, 0x6d, 0x1b, 0x07, 0x2c, 0xd0, 0x46, 0x04, 0xeb, 0x5b, 0x79, 0xac, 0x86,
-	0xa2, 0x91, 0xcc, 0x11, 0xcb, 0xd2, 0x47, 0x50, 0x4a, 0x48, 0xe5, 0x5a, 0xf3, 0x0a, 0x5f, 0x88,
-	0xb9, 0x17, 0x0d, 0xfe, 0x93, 0xab, 0x82, 0xf0, 0x1f, 0x4a, 0x93, 0xe4, 0xc3, 0xd3, 0x91, 0x27,
-	0x85, 0xa5, 0x8f, 0xa1, 0x92, 0x92, 0x7a, 0x19, 0x66, 0xfd, 0xdf, 0xa6, 0xa0, 0xb2, 0x4d, 0x7d,
-	0xbc, 0x73, 0xd8, 0xb8, 0x96, 0x9a, 0xeb, 0x50, 0xb6, 0xa4, 0x98, 0x9a, 0x50, 0x58, 0x39, 0x50,
-	0x0a, 0x26, 0x2c, 0xa8, 0x7c, 0x3e, 0x52, 0xfa, 0xcf, 0x2d, 0x68, 0x04, 0x41, 0x6b, 0x80, 0xd4,
-	0x53, 0xdd, 0x69, 0x37, 0x89, 0x5b, 0x4b, 0xa8, 0x7e, 0x0e, 0x06, 0x7d, 0x09, 0x65, 0x97, 0xda,
-	0xb8, 0x81, 0x1d, 0x6c, 0x31, 0xea, 0x8b, 0xa3, 0x30, 0xac, 0x5d, 0x4c, 0x71, 0xf2, 0x33, 0xe3,
-	0x63, 0xcf, 0x21, 0x96, 0xb9, 0x4d, 0xdb, 0x2e, 0x13, 0x67, 0xa6, 0x22, 0xe9, 0x92, 0xf0, 0x1c,
-	0x5b, 0x3c, 0x79, 0x0d, 0x5b, 0xfc, 0x0b, 0x28, 0xfa, 0xa1, 0x62, 0x88, 0x93, 0x55, 0x5a, 0x9f,
-	0xcb, 0xd1, 0x19, 0xc1, 0x1b, 0x53, 0xa2, 0x7d, 0x98, 0xf1, 0xa9, 0xe3, 0x10, 0xb7, 0x79, 0x60,
-	0x9e, 0x37, 0xda, 0x7e, 0x53, 0x1e, 0xb3, 0xd2, 0xfa, 0xed, 0x2e, 0x5b, 0xf2, 0xcc, 0x97, 0xf3,
-	0xd8, 0xa3, 0x7e, 0x7d, 0x4b, 0xc8, 0xc9, 0xb2, 0xa2, 0x6f, 0x60, 0x21, 0x06, 0x3d, 0x77, 0xcd,
-	0x8e, 0x49, 0x1c, 0xbe, 0xa5, 0xca, 0xcb, 0x0c, 0x23, 0x33, 0x5f, 0x00, 0xa2, 0xb0, 0x2c, 0x5e,
-	0x98, 0x91, 0xcd, 0x93, 0x13, 0x7e, 0xa2, 0x2f, 0xc4, 0xe9, 0x8f, 0xb6, 0xab, 0x24, 0x06, 0xf8,
-	0x59, 0x7a, 0x80, 0x86, 0x43, 0x2c, 0xfc, 0xec, 0xa4, 0xc7, 0x0a, 0xf6, 0x15, 0x88, 0xce, 0x60,
-	0x25, 0x83, 0x3f, 0xc2, 0x7e, 0x2b, 0x3d, 0x68, 0xf9, 0xf2, 0x83, 0x0e, 0x14, 0x8a, 0x0e, 0xa0,
-	0xc4, 0xa8, 0x83, 0x7d, 0xa5, 0x13, 0x95, 0xcb, 0x8f, 0x91, 0xe4, 0x47, 0x7b, 0x50, 0x35, 0xdb,
-	0x8c, 0x06, 0x96, 0xe9, 0xe0, 0x5d, 0x75, 0x14, 0x07, 0xfb, 0xcc, 0x2e, 0x1e, 0x7e, 0x26, 0x23,
-	0xd8, 0x81, 0x79, 0x2e, 0x7c, 0x68, 0xc5, 0x48, 0xc1, 0xd2, 0x34, 0xc4, 0xd5, 0xaa, 0x59, 0x1a,
-	0xe2, 0xa2, 0xa7, 0x30, 0x6a, 0x79, 0x6d, 0x6d, 0x56, 0x4c, 0xe1, 0x9d, 0x84, 0x0b, 0xee, 0x69,
-	0x90, 0xc5, 0x3b, 0x71, 0x26, 0xfd, 0x1b, 0x58, 0xd9, 0xc1, 0x27, 0x66, 0xdb, 0x61, 0x75, 0x6a,
-	0xef, 0x90, 0xc0, 0x6f, 0x7b, 0x9c, 0x6c, 0xab, 0x6d, 0x37, 0xf1, 0xf5, 0x5c, 0xf4, 0xd7, 0xb0,
-	0xa8, 0x24, 0x47, 0x27, 0x45, 0xc9, 0x4b, 0x9a, 0x62, 0x29, 0x30, 0xcf, 0x14, 0x87, 0x36, 0x53,
-	0x05, 0x10, 0x11, 0x8b, 0xfe, 0x9f, 0x65, 0x98, 0xdb, 0x6d, 0xfa, 0x38, 0x08, 0xbe, 0x30, 0x19,
-	0x3e, 0x33, 0x2f, 0x94, 0xd8, 0xbc, 0x6d, 0x29, 0xfc, 0x04, 0xdb, 0x32, 0x32, 0xc4, 0xb6, 0x8c,
-	0xf6, 0xde, 0x96, 0xf1, 0x2b, 0x6c, 0x4b, 0x72, 0xc9, 0x27, 0x87, 0x37, 0xf2, 0xeb, 0x30, 0x8a,
-	0xdd, 0x8e, 0x32, 0x55, 0x03, 0x6d, 0x9e, 0xc1, 0x89, 0xd1, 0x26, 0x4c, 0x88, 0xd8, 0x44, 0x46,
-	0xb8, 0xa5, 0xf5, 0xf7, 0x62, 0xb6, 0x9c, 0x45, 0x5e, 0x13, 0x07, 0x2b, 0x72, 0xad, 0xe2, 0x01,
-	0x21, 0x18, 0x73, 0x79, 0x70, 0x70, 0x4b, 0x78, 0x02, 0xf1, 0xbb, 0xcb, 0xf6, 0xc3, 0x95, 0x6d,
-	0x7f, 0xb7, 0x4d, 0x2f, 0x5d, 0xc3, 0xa6, 0x0f, 0x32, 0x7a, 0xe5, 0xdf, 0x87, 0xd1, 0xab, 0xbc,
-	0x0e, 0xa3, 0x77, 0x1f, 0xc6, 0x3d, 0xea, 0xb3, 0x40, 0x9b, 0x16, 0xfb, 0xba, 0x10, 0x4b, 0xaf,
-	0x73, 0x70, 0x18, 0x97, 0x0b, 0x9a, 0xb4, 0xab, 0x9b, 0x19, 0xda, 0xd5, 0x7d, 0x02, 0x95, 0x00,
-	0x5b, 0x3e, 0x66, 0x2f, 0xa8, 0xd3, 0x6e, 0xe1, 0x40, 0xab, 0x8a, 0xb1, 0x16, 0x63, 0xd6, 0x46,
-	0x02, 0x6d, 0xa4, 0x89, 0x51, 0x1d, 0x50, 0x80, 0xfd, 0x0e, 0xb1, 0x70, 0x72, 0x77, 0x67, 0x87,
-	0xd4, 0xde, 0x1c, 0x5e, 0xae, 0x89, 0x3c, 0x7b, 0xd7, 0x90, 0xd4, 0x44, 0xfe, 0x1b, 0xdd, 0x87,
-	0xb1, 0x1f, 0x3a, 0x9e, 0xab, 0xcd, 0x65, 0xe3, 0xf9, 0xef, 0xb0, 0x4f, 0x5f, 0xd4, 0x0f, 0xd5,
-	0x42, 0x08, 0xa2, 0xac, 0xa7, 0x98, 0xbf, 0xa6, 0xa7, 0xc8, 0x09, 0x05, 0x16, 0x5e, 0x43, 0x28,
-	0xb0, 0x78, 0xdd, 0x50, 0xe0, 0x00, 0x2a, 0x96, 0x58, 0x86, 0x70, 0x1f, 0x6f, 0x5e, 0xea, 0xc5,
-	0x8d, 0x34, 0x37, 0xfa, 0x15, 0xcc, 0x9b, 0xb6, 0x4d, 0xf8, 0x1a, 0x98, 0x4e, 0x94, 0x27, 0x04,
-	0x9a, 0x76, 0x39, 0xa9, 0xb9, 0x42, 0xd0, 0x13, 0x28, 0xfa, 0x6d, 0x77, 0x33, 0x30, 0x28, 0x65,
-	0xda, 0xd2, 0x40, 0xe3, 0x18, 0x13, 0x8b, 0x18, 0x3e, 0x36, 0x5f, 0x97, 0x0a, 0xc3, 0xff, 0xa3,
-	0x00, 0xd3, 0xca, 0x10, 0x86, 0x5e, 0xec, 0x10, 0xe6, 0x44, 0xfd, 0xe8, 0x25, 0x16, 0x66, 0xb2,
-	0x29, 0xb1, 0xca, 0xe3, 0xbc, 0xd9, 0xd7, 0x8a, 0x1a, 0x48, 0x70, 0xee, 0x26, 0x19, 0x93, 0x26,
-	0x7f, 0x64, 0x78, 0x93, 0xff, 0x87, 0x30, 0x2f, 0x67, 0x41, 0xdc, 0xd4, 0x34, 0xc6, 0xb2, 0x2a,
-	0x51, 0x73, 0x73, 0xe6, 0x21, 0xdf, 0xa0, 0x96, 0x62, 0xd5, 0xff, 0x76, 0x16, 0xca, 0x5f, 0x38,
-	0xf4, 0x58, 0xac, 0x3a, 0x7f, 0xd3, 0x55, 0x18, 0x33, 0x7d, 0xeb, 0x54, 0xbd, 0xda, 0x7c, 0x2c,
-	0x33, 0x2e, 0x4c, 0x19, 0x82, 0x82, 0x67, 0x99, 0x52, 0x13, 0xf8, 0x7a, 0x47, 0x35, 0x12, 0x6d,
-	0x5d, 0x66, 0x99, 0x39, 0x28, 0xee, 0xb4, 0x95, 0xee, 0x98, 0x0e, 0xb1, 0x65, 0x46, 0x38, 0x3a,
-	0xd8, 0x69, 0x67, 0x79, 0xd0, 0x97, 0x70, 0xc7, 0x96, 0xd1, 0x86, 0x9c, 0xd0, 0x0b, 0x12, 0x90,
-	0x63, 0xe2, 0x10, 0x76, 0xd1, 0xc0, 0x8c, 0x11, 0xb7, 0x19, 0x68, 0x1b, 0xa2, 0x82, 0x33, 0x88,
-	0x0c, 0xbd, 0x80, 0x39, 0x45, 0x72, 0x98, 0x74, 0x60, 0x13, 0x97, 0x70, 0x3a, 0x79, 0x02, 0x90,
-	0x0b, 0x4b, 0x76, 0xcf, 0x48, 0x4b, 0x79, 0xf9, 0x7b, 0xb1, 0xf8, 0x41, 0x51, 0x99, 0x18, 0xa8,
-	0x8f, 0x44, 0x54, 0x87, 0xaa, 0x9d, 0x89, 0xbf, 0x54, 0x1e, 0xb2, 0xd2, 0x35, 0x4a, 0x26, 0x42,
-	0x13, 0xb2, 0xbb, 0xb8, 0xd1, 0xaf, 0x00, 0x29, 0xd8, 0x51, 0xc2, 0x46, 0x3e, 0xbe, 0xbc, 0x8d,
-	0xcc, 0x11, 0x13, 0xd6, 0x61, 0xca, 0x71, 0x1d, 0x66, 0x15, 0x66, 0x44, 0x3d, 0xa5, 0x1e, 0xd7,
-	0x04, 0x2b, 0xb2, 0x60, 0x97, 0x01, 0xa3, 0x7b, 0x50, 0x8d, 0x40, 0xd2, 0xe1, 0x04, 0xda, 0xbb,
-	0x62, 0xb7, 0xbb, 0xe0, 0xe8, 0x2e, 0x4c, 0x0b, 0xa5, 0x8f, 0xb5, 0x73, 0x5a, 0x96, 0xd7, 0xd2,
-	0x50, 0x6e, 0x66, 0x1c, 0xda, 0xdc, 0x0c, 0xbe, 0x0a, 0xa8, 0xab, 0xbd, 0x33, 0xd8, 0xcc, 0x44,
-	0xc4, 0xe8, 0x31, 0x4c, 0x3a, 0xb4, 0xd9, 0x24, 0x6e, 0x53, 0xf9, 0xb2, 0x84, 0x31, 0x90, 0xe7,
-	0x6a, 0x5f, 0xa2, 0xd5, 0xd1, 0x09, 0xa9, 0xd1, 0x36, 0x54, 0x5a, 0x38, 0x38, 0xdd, 0x3d, 0xf7,
-	0x4c, 0x37, 0xe0, 0x07, 0x01, 0x65, 0xd9, 0x0f, 0x92, 0x68, 0xc5, 0x9e, 0xe6, 0x41, 0x8b, 0x30,
-	0xc1, 0x01, 0xb5, 0x1d, 0xed, 0x17, 0xe2, 0xbd, 0xd4, 0x13, 0xda, 0x81, 0x32, 0xff, 0x75, 0x88,
-	0xd9, 0x19, 0xf5, 0x5f, 0x05, 0xca, 0x1d, 0x0e, 0x76, 0xb3, 0x29, 0x2e, 0xf4, 0x39, 0x94, 0x5b,
-	0x6d, 0x87, 0x11, 0x55, 0x88, 0x54, 0x9e, 0x67, 0x39, 0x31, 0xc3, 0x04, 0x56, 0x4d, 0x30, 0xc5,
-	0x81, 0x34, 0x98, 0x74, 0xa5, 0x34, 0xed, 0x67, 0x62, 0x82, 0xe1, 0x23, 0x7a, 0x04, 0x8b, 0x1e,
-	0xb5, 0x77, 0x0e, 0x1b, 0x0d, 0xcc, 0x8d, 0x49, 0xa2, 0xf6, 0x7a, 0x5f, 0xec, 0x65, 0x0f, 0x2c,
-	0xfa, 0x0d, 0x2c, 0xd3, 0x16, 0x61, 0x0d, 0x62, 0x63, 0xcb, 0xf4, 0x6b, 0xee, 0xf7, 0xe2, 0xbc,
-	0xc9, 0xc1, 0x0f, 0x4c, 0x4f, 0xbb, 0x3b, 0x70, 0xf3, 0xfa, 0xf2, 0xa3, 0xcf, 0xa0, 0x4c, 0xdd,
-	0xb8, 0xe2, 0xab, 0x7c, 0x63, 0x3f, 0x79, 0x29, 0x7a, 0x64, 0xc0, 0x22, 0xf5, 0xb8, 0x9e, 0x53,
-	0xff, 0xc0, 0x74, 0xcd, 0x26, 0xfe, 0x1a, 0x1f, 0x9f, 0x52, 0xfa, 0x2a, 0xd0, 0xde, 0x1b, 0x28,
-	0xa9, 0x07, 0x27, 0x7a, 0x08, 0xb3, 0x9e, 0x4f, 0xa8, 0x4f, 0xd8, 0xc5, 0xb6, 0x63, 0x06, 0x81,
-	0x28, 0xce, 0xbd, 0x11, 0x55, 0x12, 0xbb, 0x91, 0x22, 0x1c, 0xf4, 0xe9, 0xf9, 0x85, 0xb6, 0x2c,
-	0x06, 0x4d, 0x86, 0x83, 0x1c, 0x1c, 0x85, 0x83, 0xfc, 0x01, 0x3d, 0x86, 0xa2, 0xf8, 0x51, 0x73,
-	0x09, 0xd3, 0xde, 0xcc, 0x96, 0x90, 0xeb, 0x21, 0x4a, 0x31, 0xc5, 0xb4, 0xe8, 0x5d, 0x18, 0x0d,
-	0xec, 0x40, 0xbb, 0x9d, 0x8d, 0x20, 0x1b, 0x3b, 0xaa, 0x7c, 0x65, 0x70, 0x7c, 0x58, 0x62, 0xbd,
-	0x33, 0x44, 0x89, 0x75, 0x0d, 0x26, 0x98, 0x6f, 0x5a, 0xd8, 0xd7, 0xde, 0x12, 0xd4, 0x89, 0xd8,
-	0xf2, 0x48, 0xc0, 0xc3, 0x3a, 0xb6, 0xa4, 0x42, 0x2b, 0x50, 0x62, 0x7e, 0x3b, 0x60, 0x3b, 0xb4,
-	0x65, 0x12, 0x57, 0xd3, 0x85, 0x8e, 0x25, 0x41, 0x68, 0x1d, 0x26, 0xda, 0x01, 0x3e, 0xd8, 0xae,
-	0x6b, 0x6f, 0x0f, 0x5c, 0x7f, 0x45, 0x89, 0xd6, 0x00, 0xf9, 0xb8, 0x45, 0x19, 0xae, 0x13, 0x87,
-	0xb2, 0x4d, 0xdb, 0xe6, 0x0e, 0x53, 0x7b, 0x28, 0x4b, 0x5f, 0xdd, 0x18, 0x3e, 0x6b, 0x61, 0x4f,
-	0x6c, 0xed, 0x51, 0x76, 0xd6, 0x35, 0x01, 0x0f, 0x67, 0x2d, 0xa9, 0xd0, 0xfb, 0x30, 0xeb, 0x71,
-	0xfe, 0x6d, 0xec, 0xb3, 0xba, 0x4f, 0x3b, 0xc4, 0xc6, 0xbe, 0xf6, 0x44, 0x16, 0x5b, 0xbb, 0x10,
-	0x68, 0x19, 0x8a, 0xdf, 0x9f, 0x31, 0x65, 0x13, 0x3f, 0x92, 0x17, 0x21, 0x11, 0x40, 0xec, 0x01,
-	0x0b, 0xb4, 0xa7, 0x5d, 0x7b, 0x70, 0x14, 0xef, 0x01, 0x0b, 0xd0, 0x12, 0xcf, 0xc2, 0x3b, 0x44,
-	0x18, 0x9a, 0x8f, 0xe5, 0xfd, 0x41, 0xf8, 0x8c, 0xb6, 0x60, 0xba, 0x45, 0xdb, 0x2e, 0x3b, 0x60,
-	0x4e, 0xc0, 0x47, 0x0e, 0xb4, 0x4f, 0x06, 0x2e, 0x55, 0x86, 0x43, 0xdc, 0xd6, 0x98, 0xe1, 0x4a,
-	0x7d, 0xaa, 0x6e, 0x6b, 0x42, 0x00, 0xfa, 0x1c, 0x2a, 0x16, 0x76, 0x99, 0x6f, 0x3a, 0x72, 0x3d,
-	0xb4, 0xcf, 0x06, 0x0e, 0x90, 0x66, 0xd0, 0x7f, 0x0e, 0xc5, 0xe8, 0x8d, 0xf8, 0xae, 0xab, 0x74,
-	0x80, 0x27, 0x37, 0xea, 0x76, 0x2c, 0x09, 0xd2, 0x0d, 0x28, 0x27, 0x57, 0x9e, 0xbf, 0xa2, 0x8c,
-	0xa1, 0x36, 0x5d, 0xd3, 0xb9, 0x08, 0x48, 0x30, 0x44, 0xd4, 0x95, 0xe1, 0xd0, 0xef, 0xc3, 0x5c,
-	0x8e, 0x41, 0xe7, 0x61, 0xa4, 0x23, 0xae, 0x65, 0x64, 0x68, 0x29, 0x1f, 0xf4, 0xbf, 0xa9, 0xc2,
-	0x7c, 0x5e, 0x10, 0xf6, 0xff, 0xaa, 0x6e, 0xc1, 0xb7, 0xb5, 0x1d, 0x30, 0xda, 0x6a, 0xc8, 0xa5,
-	0x57, 0x61, 0x53, 0xff, 0x6d, 0x4d, 0x32, 0x24, 0xc3, 0x60, 0xb8, 0x74, 0xe5, 0xa3, 0x74, 0x99,
-	0xca, 0xc7, 0x56, 0x54, 0xf9, 0x98, 0x11, 0x59, 0xeb, 0xbd, 0xfe, 0xc1, 0x72, 0x6e, 0xe9, 0xe3,
-	0x2e, 0x4c, 0x3b, 0xd4, 0xb4, 0xb7, 0x4c, 0xc7, 0x74, 0x2d, 0xec, 0xd7, 0xea, 0xa2, 0x40, 0x57,
-	0x34, 0x32, 0x50, 0xf4, 0x14, 0xb4, 0x24, 0xa4, 0x21, 0x22, 0x2a, 0xc3, 0x74, 0x9b, 0x98, 0x27,
-	0xbc, 0xdc, 0xbb, 0xf5, 0xc4, 0xa3, 0x5d, 0x40, 0x29, 0x17, 0x2f, 0xd2, 0x77, 0x0d, 0xf5, 0xcb,
-	0xea, 0x73, 0x18, 0xa2, 0x2a, 0xcd, 0xfb, 0x7d, 0xaa, 0x34, 0x73, 0x3f, 0x61, 0x95, 0x66, 0xfe,
-	0x35, 0x56, 0x69, 0x16, 0x7e, 0x1f, 0x55, 0x9a, 0xc5, 0xd7, 0x5a, 0xa5, 0xb9, 0x39, 0x44, 0x95,
-	0x26, 0x7b, 0x0d, 0xa2, 0xf5, 0xb8, 0x06, 0xd9, 0x4a, 0x56, 0x73, 0x6e, 0x5d, 0x62, 0x1f, 0xfa,
-	0x95, 0x76, 0xde, 0xb8, 0x7e, 0x69, 0x67, 0xf9, 0x27, 0x28, 0xed, 0xbc, 0x99, 0x28, 0xed, 0x3c,
-	0x52, 0xa5, 0x1d, 0x19, 0x6e, 0xe8, 0xbd, 0xce, 0xef, 0x77, 0x1d, 0xcf, 0x4d, 0x55, 0x79, 0x72,
-	0xca, 0x32, 0x77, 0x5e, 0x43, 0x59, 0x66, 0xe5, 0xba, 0x65, 0x99, 0x0d, 0x58, 0xc0, 0xe7, 0x0c,
-	0xfb, 0xae, 0xe9, 0x1c, 0xf9, 0xe6, 0xc9, 0x09, 0xb1, 0x94, 0xcf, 0x97, 0x51, 0x4d, 0x3e, 0x32,
-	0x5b, 0xc3, 0x7a, 0xfb, 0x9a, 0x35, 0xac, 0x5f, 0x42, 0x59, 0xd5, 0x16, 0xa4, 0xe1, 0x79, 0xe7,
-	0x72, 0x45, 0x9c, 0x14, 0x73, 0xcf, 0xca, 0xd0, 0xbb, 0x3f, 0x45, 0x65, 0xa8, 0xab, 0x8a, 0x75,
-	0xf7, 0x5a, 0x55, 0xac, 0x54, 0xa1, 0xe9, 0xe7, 0xff, 0x4b, 0x85, 0xa6, 0x53, 0xd0, 0x7a, 0x29,
-	0xef, 0x15, 0x6f, 0x7e, 0x17, 0x61, 0x22, 0x68, 0x9f, 0x9c, 0x90, 0x73, 0x35, 0x98, 0x7a, 0xd2,
-	0xff, 0x14, 0xe6, 0x72, 0xd2, 0xc9, 0x2b, 0x0e, 0x22, 0x63, 0xea, 0xda, 0xfe, 0xd6, 0x10, 0x51,
-	0x94, 0xa2, 0xd4, 0x1d, 0x40, 0xdd, 0xd9, 0xe2, 0x15, 0xc7, 0x5f, 0x81, 0x92, 0x6a, 0x99, 0x11,
-	0x99, 0x90, 0x7c, 0xd3, 0x24, 0x48, 0xff, 0xf3, 0x02, 0xbc, 0xf1, 0xac, 0xcd, 0x8e, 0x69, 0xdb,
-	0xb5, 0x53, 0xe7, 0x45, 0x8d, 0xfb, 0x19, 0x8c, 0xb5, 0xa8, 0x2d, 0x59, 0xa7, 0x93, 0xb1, 0x40,
-	0x1f, 0xa6, 0xb5, 0x03, 0x6a, 0x63, 0x43, 0xf0, 0xe9, 0xab, 0x30, 0xc6, 0x9f, 0x50, 0x05, 0x8a,
-	0x9b, 0xfb, 0xfb, 0xcf, 0xbe, 0x7e, 0xb9, 0x79, 0xf8, 0x6d, 0xf5, 0x06, 0x9a, 0x85, 0x8a, 0xb1,
-	0xfb, 0x45, 0xad, 0x71, 0x64, 0x7c, 0xfb, 0xf2, 0xd9, 0xe1, 0xfe, 0xb7, 0xd5, 0x82, 0xfe, 0xbb,
-	0x32, 0x94, 0x44, 0xb2, 0x70, 0xad, 0x37, 0xce, 0x8b, 0x1a, 0x47, 0xae, 0x1b, 0x35, 0xf6, 0x88,
-	0x08, 0xb3, 0x91, 0xe5, 0x58, 0x4e, 0x64, 0x99, 0xf5, 0x4d, 0xe3, 0x3d, 0x7c, 0x53, 0xd4, 0x32,
-	0x33, 0x91, 0x6c, 0x99, 0x79, 0x07, 0x2a, 0x22, 0x7f, 0x6b, 0x98, 0x2d, 0x8f, 0x1b, 0x42, 0x71,
-	0x87, 0x55, 0x30, 0xd2, 0xc0, 0xf4, 0x2d, 0x45, 0x71, 0xe8, 0x5b, 0x8a, 0x55, 0x98, 0x91, 0x27,
-	0x3b, 0xce, 0xe1, 0x41, 0x75, 0x7e, 0xa5, 0xc1, 0x61, 0xe8, 0x5b, 0xba, 0x4a, 0xe8, 0x9b, 0x8d,
-	0xa5, 0xca, 0x57, 0x8e, 0xa5, 0x2c, 0xb8, 0xf3, 0x0a, 0x63, 0xcf, 0x74, 0x48, 0x87, 0x2f, 0x2d,
-	0x8f, 0x8c, 0xc5, 0xf1, 0x70, 0xb1, 0xc5, 0x07, 0xde, 0x6c, 0xe2, 0xa8, 0xad, 0x2b, 0xbb, 0xd3,
-	0x3b, 0xaa, 0x29, 0xd1, 0x18, 0x24, 0x01, 0xed, 0x43, 0xd5, 0xc6, 0x9e, 0x43, 0x2f, 0x5a, 0xd8,
-	0x65, 0xd2, 0x5a, 0xa9, 0x4b, 0xec, 0xc1, 0xfe, 0xb9, 0x8b, 0x93, 0xdb, 0x4a, 0x2b, 0x2a, 0xb8,
-	0xa0, 0xc1, 0xb6, 0x32, 0x22, 0x4e, 0x64, 0xe3, 0xf3, 0x43, 0x67, 0xe3, 0x2a, 0xda, 0x5f, 0xb8,
-	0x4c, 0xb4, 0x9f, 0xe3, 0xf3, 0xb5, 0xd7, 0xe0, 0xf3, 0x6f, 0x5d, 0xff, 0x2a, 0x26, 0xe5, 0xbd,
-	0x97, 0xae, 0xe9, 0xbd, 0x4f, 0xe1, 0x2d, 0x69, 0x31, 0xea, 0x7c, 0x39, 0x2d, 0xea, 0x34, 0x5c,
-	0xc2, 0x83, 0x51, 0x3e, 0x91, 0xd0, 0xb2, 0xa9, 0xb8, 0xac, 0xdf, 0xca, 0x0f, 0x16, 0x82, 0x4e,
-	0x60, 0xa5, 0x27, 0x51, 0xcd, 0x95, 0x03, 0xbd, 0x39, 0x70, 0xa0, 0x81, 0x32, 0x72, 0x32, 0x8d,
-	0xdb, 0xd7, 0xc8, 0x34, 0xfe, 0x00, 0xca, 0x52, 0x17, 0x65, 0xca, 0xa5, 0xe2, 0xc0, 0x37, 0x12,
-	0x61, 0x78, 0x6c, 0xa9, 0x55, 0x56, 0x96, 0x62, 0x40, 0x4f, 0xe0, 0xe6, 0xf7, 0x67, 0xaf, 0x02,
-	0x6e, 0x7c, 0x9c, 0x0e, 0xf6, 0x77, 0xcf, 0x99, 0x6f, 0xf2, 0x20, 0x60, 0x7b, 0x53, 0xc4, 0x7f,
-	0x45, 0xa3, 0x17, 0x1a, 0x7d, 0x08, 0x93, 0x9e, 0xe8, 0x96, 0x0a, 0x54, 0x69, 0xeb, 0x56, 0xce,
-	0x2e, 0xcb, 0x77, 0x30, 0x42, 0xca, 0xb0, 0x4c, 0xae, 0x77, 0xb5, 0x2b, 0xbe, 0x3d, 0xb8, 0x96,
-	0xa6, 0xff, 0x7d, 0x01, 0x90, 0x78, 0x1f, 0x15, 0x62, 0x28, 0x07, 0x74, 0x17, 0xa6, 0x55, 0x90,
-	0x16, 0x66, 0xed, 0x05, 0x55, 0x12, 0x4f, 0x41, 0xd1, 0x73, 0x58, 0x20, 0x11, 0x23, 0xe3, 0xea,
-	0x8b, 0xfd, 0x83, 0xd8, 0x67, 0x26, 0x5a, 0xf1, 0x72, 0xc9, 0x8c, 0x7c, 0x6e, 0xee, 0x5d, 0x42,
-	0x84, 0x63, 0x06, 0x81, 0x6a, 0x3c, 0x4b, 0xc1, 0xf4, 0x1a, 0xcc, 0x8a, 0x89, 0xa7, 0x5c, 0xf6,
-	0xd5, 0xfa, 0x52, 0x18, 0xcc, 0x1c, 0x61, 0x07, 0xb7, 0x30, 0xf3, 0xaf, 0x25, 0x08, 0xdd, 0x87,
-	0x91, 0xce, 0xba, 0xba, 0xac, 0x4a, 0x28, 0x4c, 0x24, 0xfc, 0xc5, 0xba, 0x4a, 0x3a, 0x46, 0x3a,
-	0xeb, 0xfa, 0x5f, 0x8e, 0xc2, 0x6c, 0x17, 0xe6, 0x8a, 0x03, 0x7f, 0x03, 0xb3, 0x2d, 0xcc, 0x4c,
-	0xdb, 0x64, 0xe6, 0x4b, 0x7c, 0x6e, 0x9d, 0x9a, 0xae, 0x6a, 0xc3, 0x2b, 0xad, 0xdf, 0xcf, 0x9d,
-	0xc7, 0x81, 0xa2, 0xde, 0x55, 0xc4, 0x6a, 0x5e, 0xd5, 0x56, 0x06, 0x8e, 0x76, 0x01, 0x3c, 0x9f,
-	0xb6, 0x30, 0x3b, 0xc5, 0xed, 0xb0, 0x20, 0xf6, 0x6e, 0xae, 0xc8, 0x7a, 0x44, 0xa6, 0x84, 0x25,
-	0x18, 0xd1, 0x97, 0x50, 0x0a, 0x98, 0x69, 0xbd, 0xb2, 0x7d, 0xd2, 0xc1, 0xbe, 0x5a, 0xa2, 0xbb,
-	0xb9, 0x72, 0x1a, 0x9c, 0x6e, 0x47, 0xd0, 0x29, 0x41, 0x49, 0x56, 0xf4, 0x47, 0x30, 0x6b, 0x5a,
-	0x16, 0x0e, 0x82, 0x97, 0x0e, 0x6d, 0xbe, 0xf4, 0xe2, 0xce, 0xf0, 0xd2, 0xfa, 0xc3, 0x5c, 0x79,
-	0x9b, 0x82, 0x7a, 0x9f, 0x36, 0xa5, 0xa6, 0xec, 0x11, 0x27, 0xbe, 0x88, 0x98, 0x31, 0xd3, 0x48,
-	0xdd, 0x84, 0xb7, 0x06, 0xae, 0x12, 0xfa, 0x04, 0x4a, 0x67, 0x66, 0xd0, 0x1a, 0x3e, 0xc6, 0x4a,
-	0x92, 0xeb, 0xff, 0x3a, 0x0a, 0x6f, 0xf4, 0x59, 0xb6, 0x2b, 0x6a, 0xc0, 0xb5, 0xe6, 0x84, 0x7e,
-	0x1d, 0xc6, 0x43, 0x2f, 0x69, 0x07, 0xfb, 0x3e, 0xb1, 0xb1, 0xda, 0xa2, 0x8d, 0xa1, 0xb6, 0x7a,
-	0x4d, 0xfe, 0x79, 0xa6, 0x78, 0x8d, 0x69, 0x2b, 0xf5, 0xbc, 0xf4, 0x63, 0x01, 0xa6, 0xd3, 0x24,
-	0xe8, 0x29, 0x4c, 0xa6, 0xef, 0xc7, 0x07, 0x3b, 0xed, 0x90, 0x01, 0x7d, 0xc9, 0xad, 0x93, 0x30,
-	0xfd, 0xea, 0x86, 0x46, 0xbd, 0xee, 0x60, 0x11, 0x19, 0x3e, 0xf4, 0x15, 0xcc, 0x50, 0xe5, 0xad,
-	0x42, 0x51, 0xa3, 0x43, 0x8a, 0xca, 0x32, 0xea, 0x7f, 0x35, 0x0e, 0xcb, 0xfd, 0xd4, 0xf8, 0x8a,
-	0x1b, 0xfb, 0x24, 0xbe, 0x3b, 0x1c, 0xb8, 0xa9, 0xc2, 0x9f, 0x45, 0x97, 0x87, 0x4f, 0x01, 0x5a,
-	0xd4, 0x25, 0x8c, 0xf2, 0x89, 0x0f, 0x71, 0x85, 0x9e, 0xa0, 0x46, 0x8f, 0x60, 0x8a, 0x51, 0x8f,
-	0x3a, 0xb4, 0x19, 0x36, 0x0e, 0xf4, 0xe3, 0x8c, 0x68, 0xd1, 0x0e, 0xcc, 0xd8, 0x24, 0xe0, 0x33,
-	0x8f, 0x42, 0x89, 0xc1, 0xf5, 0xde, 0x2c, 0x0b, 0xdf, 0xe0, 0xb4, 0x06, 0x0d, 0xdb, 0x28, 0x9c,
-	0xd5, 0x3c, 0xf4, 0x3d, 0x2c, 0x84, 0xfb, 0x14, 0xd9, 0x01, 0xb1, 0x96, 0x93, 0xc2, 0x41, 0x6d,
-	0x0c, 0x67, 0x81, 0xd6, 0x52, 0xbc, 0x46, 0xbe, 0x48, 0x74, 0x0a, 0xf3, 0x4a, 0xbd, 0xd2, 0x43,
-	0x4d, 0x5d, 0x63, 0xa8, 0x5c, 0x89, 0xfa, 0x06, 0x54, 0xd2, 0x43, 0x4f, 0xc1, 0xd8, 0xe1, 0xb3,
-	0xc3, 0xdd, 0xea, 0x0d, 0xfe, 0x6b, 0xef, 0xf9, 0xfe, 0x7e, 0xb5, 0x80, 0x66, 0xa0, 0xb4, 0x6b,
-	0x18, 0xcf, 0x8c, 0x86, 0xcc, 0x32, 0x47, 0xf4, 0xbf, 0x2b, 0xc0, 0xdd, 0xe1, 0xec, 0xe2, 0x15,
-	0x55, 0xf5, 0x0b, 0x98, 0x75, 0x68, 0xf3, 0x6b, 0xe2, 0xda, 0xf4, 0x2c, 0x4c, 0x3b, 0x94, 0xd2,
-	0xf6, 0xc9, 0x4b, 0xba, 0x79, 0xf4, 0x5d, 0xe5, 0xdb, 0x93, 0x41, 0x16, 0x7a, 0x08, 0x73, 0x41,
-	0xfb, 0x38, 0xb0, 0x7c, 0x72, 0x8c, 0xed, 0xb8, 0x81, 0xa1, 0x20, 0x6a, 0xe5, 0x79, 0x28, 0xfd,
-	0xb7, 0x50, 0x4a, 0x94, 0x4c, 0xa3, 0x72, 0x77, 0x21, 0x51, 0xee, 0x46, 0x30, 0xe6, 0x51, 0x9f,
-	0x89, 0x59, 0x8e, 0x1b, 0xe2, 0x37, 0x5a, 0x82, 0x29, 0x9e, 0x7c, 0x89, 0x6b, 0xa3, 0x51, 0x01,
-	0x8f, 0x9e, 0xd1, 0x6d, 0x00, 0xf9, 0x59, 0x82, 0xc0, 0x8e, 0x09, 0x6c, 0x02, 0xa2, 0xff, 0xf3,
-	0x24, 0x94, 0x12, 0xb7, 0xa7, 0x9c, 0x9e, 0xe7, 0xc4, 0xf2, 0x0a, 0x59, 0x35, 0xc6, 0x27, 0x20,
-	0x3c, 0xcb, 0x55, 0x25, 0x09, 0x75, 0x3b, 0x29, 0xbf, 0x71, 0x4a, 0x03, 0xd1, 0xfb, 0x30, 0x6b,
-	0xd1, 0x96, 0x47, 0x5d, 0x9e, 0x5e, 0x85, 0x5f, 0xf8, 0xc8, 0x6c, 0xb9, 0x1b, 0x11, 0xdf, 0x63,
-	0x89, 0xaf, 0x04, 0xda, 0x2d, 0x4f, 0x25, 0xc6, 0x43, 0xdc, 0x63, 0x85, 0x1c, 0x7c, 0xb1, 0xd5,
-	0x77, 0x4d, 0x2a, 0xc8, 0x96, 0x95, 0x3e, 0xd9, 0x8b, 0x91, 0x87, 0xe2, 0x29, 0x75, 0x08, 0xae,
-	0xab, 0x6b, 0x0c, 0xd5, 0x9b, 0x91, 0x01, 0xc7, 0xf9, 0xfe, 0x74, 0x32, 0xdf, 0x5f, 0x85, 0x19,
-	0xe2, 0xa6, 0xf9, 0xab, 0xaa, 0xb7, 0x23, 0x0d, 0x4e, 0x7d, 0xe6, 0x84, 0x32, 0x9f, 0x39, 0x3d,
-	0xe5, 0xe1, 0x0a, 0xe9, 0x10, 0x07, 0x37, 0xb1, 0xad, 0x2e, 0x2f, 0xfa, 0xda, 0xbc, 0x98, 0x1a,
-	0x6d, 0xc1, 0xb2, 0x8f, 0x4d, 0x9b, 0xb8, 0x38, 0x08, 0x6a, 0x2e, 0x61, 0xc4, 0x74, 0x76, 0xb0,
-	0x63, 0x5e, 0x34, 0xb0, 0x45, 0x5d, 0x5b, 0x5e, 0x5f, 0x54, 0x8c, 0xbe, 0x34, 0xe8, 0x11, 0x2c,
-	0x46, 0xf8, 0x3a, 0xf6, 0x09, 0xb5, 0x43, 0xee, 0x05, 0xc1, 0xdd, 0x03, 0x8b, 0x3e, 0x81, 0x5b,
-	0x11, 0x66, 0xcf, 0x24, 0x4e, 0xdb, 0xc7, 0x47, 0xa7, 0x3e, 0x0e, 0x4e, 0xa9, 0x63, 0x8b, 0x6b,
-	0x86, 0x8a, 0xd1, 0x9b, 0x80, 0x6b, 0x59, 0xc0, 0x4c, 0xd6, 0x16, 0x25, 0x55, 0xd1, 0x8d, 0x50,
-	0x31, 0x12, 0x90, 0x74, 0x95, 0x44, 0xbb, 0x44, 0x95, 0x24, 0xbc, 0x68, 0xbf, 0x25, 0x4c, 0x58,
-	0x35, 0xe6, 0x91, 0xf0, 0xe8, 0x8a, 0x7d, 0x1d, 0xe6, 0xd5, 0x2e, 0x87, 0x36, 0x5c, 0xea, 0xcb,
-	0xb2, 0xd8, 0x9e, 0x5c, 0x1c, 0xfa, 0x0c, 0x8a, 0x0e, 0x39, 0xc1, 0xd6, 0x85, 0xe5, 0x60, 0x55,
-	0x42, 0x1e, 0x6c, 0xdf, 0x63, 0x16, 0x64, 0xc3, 0x1d, 0xfe, 0xf2, 0x9b, 0x9e, 0x28, 0x25, 0x71,
-	0xbb, 0xf1, 0xdc, 0x65, 0xc4, 0x11, 0xa7, 0xaf, 0xc1, 0x4c, 0x9f, 0x85, 0x35, 0xe4, 0x7e, 0xfb,
-	0x3f, 0x48, 0x84, 0xfe, 0x1b, 0x98, 0xc9, 0x34, 0x37, 0xc4, 0xfa, 0x5b, 0x48, 0xea, 0x6f, 0x6a,
-	0x8d, 0xc7, 0x87, 0x5d, 0x63, 0x7d, 0x1b, 0x6e, 0xf6, 0xe8, 0x6f, 0xe7, 0x89, 0x9d, 0xe5, 0xb5,
-	0xc3, 0x22, 0xb1, 0xe5, 0xb5, 0x65, 0x27, 0x4f, 0x8b, 0xfa, 0x17, 0x61, 0xe1, 0x56, 0x3e, 0xe9,
-	0x5f, 0x40, 0x31, 0x6a, 0xa7, 0x40, 0x4f, 0x61, 0x9c, 0xd1, 0x57, 0xd8, 0xbd, 0xd4, 0xc7, 0x35,
-	0x92, 0x45, 0xff, 0x63, 0x28, 0x27, 0xef, 0x70, 0xd0, 0x32, 0x14, 0xc5, 0x1d, 0x7e, 0xdd, 0x64,
-	0xa7, 0x6a, 0x22, 0x31, 0x20, 0x32, 0xa8, 0x23, 0x09, 0x83, 0xca, 0x55, 0x51, 0x48, 0x10, 0x55,
-	0x57, 0xf5, 0xc5, 0x50, 0x0c, 0xd1, 0xff, 0xba, 0x00, 0x15, 0x95, 0x3d, 0x46, 0xd7, 0xee, 0x25,
-	0x33, 0x91, 0xba, 0x0f, 0x1b, 0x0d, 0x26, 0x99, 0x78, 0xc2, 0x18, 0xde, 0x7c, 0xd4, 0x43, 0x73,
-	0x5e, 0x31, 0x52, 0xb0, 0x68, 0xb6, 0xa3, 0x69, 0xf3, 0x9f, 0xed, 0x0e, 0xd6, 0xff, 0x61, 0x0c,
-	0x16, 0x72, 0x3b, 0x7f, 0xd0, 0x37, 0x70, 0x4b, 0x9a, 0xc9, 0xb8, 0xd5, 0x68, 0xeb, 0x42, 0xf5,
-	0xcb, 0x0d, 0x11, 0x71, 0xf7, 0x66, 0x46, 0xdf, 0xc2, 0x9c, 0x8b, 0x3b, 0x58, 0x0d, 0x78, 0xc5,
-	0xef, 0x6d, 0x8c, 0x3c, 0x19, 0xe2, 0x7e, 0xc5, 0x39, 0x33, 0x2f, 0x82, 0x8c, 0xec, 0xf2, 0x65,
-	0xef, 0x57, 0x72, 0x84, 0xa0, 0x7d, 0x98, 0xf3, 0xf1, 0x99, 0x4f, 0x18, 0xde, 0xf4, 0xbc, 0x2f,
-	0x8f, 0x8e, 0xea, 0x75, 0x9f, 0x1e, 0x63, 0x61, 0xb8, 0xfb, 0xaf, 0x45, 0x1e, 0x1b, 0x32, 0x60,
-	0x8e, 0x08, 0xf9, 0x38, 0x55, 0xcc, 0x19, 0xb6, 0x2f, 0x2d, 0x8f, 0x99, 0x87, 0x92, 0xf4, 0x38,
-	0xf5, 0xe2, 0xc3, 0xd6, 0x08, 0x33, 0x7c, 0xb2, 0x28, 0xf1, 0xbd, 0x2c, 0x97, 0x3e, 0x37, 0xf6,
-	0x85, 0x55, 0x16, 0x45, 0x89, 0x18, 0xa6, 0xff, 0xd9, 0x08, 0x94, 0x93, 0x3d, 0x48, 0xe8, 0x31,
-	0x4c, 0xf2, 0x04, 0xd2, 0xa6, 0xcd, 0xee, 0x36, 0x60, 0x49, 0xb8, 0x23, 0xd1, 0x61, 0xe7, 0x9f,
-	0xa2, 0x46, 0x9f, 0x72, 0xeb, 0xd8, 0x3c, 0x65, 0x01, 0xc3, 0x9e, 0xd2, 0xad, 0x3b, 0x59, 0xd6,
-	0x7d, 0x4e, 0xd0, 0x60, 0xd8, 0x0b, 0xbb, 0xae, 0x22, 0x0e, 0xb4, 0x01, 0x13, 0x3f, 0x10, 0xef,
-	0x15, 0x09, 0x5b, 0x67, 0x97, 0xb3, 0xbc, 0xdf, 0x09, 0x6c, 0xd8, 0x73, 0x24, 0x69, 0xd1, 0x76,
-	0x3a, 0x4b, 0x1f, 0xcb, 0x7e, 0x89, 0x23, 0x59, 0x1b, 0x31, 0x49, 0x4e, 0x82, 0xae, 0x3f, 0x80,
-	0xb9, 0x9c, 0x37, 0x43, 0x1a, 0x4c, 0x9a, 0xaa, 0xf5, 0x47, 0x1a, 0x92, 0xf0, 0x51, 0x6f, 0xc0,
-	0x42, 0xee, 0xfb, 0xf4, 0x66, 0x41, 0x2b, 0x50, 0x92, 0x99, 0xfb, 0x91, 0xb0, 0x74, 0xea, 0x72,
-	0x27, 0x01, 0xd2, 0xd7, 0x00, 0x75, 0xbf, 0x68, 0x9f, 0x49, 0xfc, 0x57, 0x01, 0x6e, 0xf6, 0x78,
-	0x3d, 0xf4, 0x10, 0xc6, 0x6d, 0x7c, 0xdc, 0x6e, 0x0e, 0x11, 0x0b, 0x4b, 0x42, 0xb4, 0x01, 0x0b,
-	0x2d, 0xf3, 0xfc, 0xb0, 0xdd, 0x3a, 0xc6, 0xfe, 0xb3, 0x93, 0x4d, 0xc6, 0x7c, 0x72, 0xdc, 0x66,
-	0x38, 0x50, 0x86, 0x29, 0x1f, 0xc9, 0x83, 0x87, 0x24, 0x22, 0x71, 0x04, 0xe4, 0x15, 0x4c, 0x0f,
-	0x2c, 0x7a, 0x0a, 0x5a, 0x02, 0x73, 0x80, 0x83, 0xc0, 0x6c, 0x86, 0xdf, 0x12, 0xcb, 0x8b, 0x99,
-	0x9e, 0x78, 0xfd, 0x4f, 0x00, 0xb6, 0xcc, 0x20, 0xb4, 0xc5, 0x5f, 0x01, 0x52, 0x81, 0xa0, 0xb1,
-	0x73, 0x84, 0x5b, 0x9e, 0x63, 0x32, 0x1c, 0x0c, 0xf1, 0xda, 0x39, 0x5c, 0x3c, 0xb4, 0xed, 0x44,
-	0xdd, 0xd8, 0xfc, 0xc0, 0xc8, 0x5d, 0x4a, 0x03, 0xf5, 0x27, 0x80, 0x64, 0x13, 0x96, 0x21, 0x5a,
-	0xe6, 0xd4, 0x3c, 0xb2, 0x67, 0xad, 0x90, 0x73, 0xd6, 0xfe, 0x69, 0x1c, 0x26, 0xc4, 0xe8, 0x01,
-	0x7a, 0x17, 0x46, 0x2d, 0x97, 0xa8, 0x63, 0x32, 0x97, 0xfa, 0xe2, 0x3c, 0xec, 0x6f, 0xb3, 0x5c,
-	0x82, 0x3e, 0x86, 0xb2, 0x68, 0xae, 0xb3, 0xa8, 0x8f, 0x6d, 0xb5, 0xaa, 0xa9, 0x02, 0x69, 0xea,
-	0xb3, 0x5a, 0x23, 0x45, 0x8c, 0x36, 0x60, 0x4a, 0xd5, 0x1f, 0x42, 0xf7, 0x9e, 0xf8, 0xb4, 0x3d,
-	0xfd, 0x21, 0x80, 0x11, 0x51, 0xa2, 0x35, 0x98, 0x68, 0x8a, 0x7e, 0x30, 0x95, 0x06, 0x2f, 0x66,
-	0x1b, 0x7f, 0xc3, 0x13, 0x28, 0xa9, 0x44, 0xf3, 0x07, 0xcf, 0x7c, 0x54, 0xa7, 0xd3, 0x42, 0x6e,
-	0xd5, 0xd9, 0x90, 0x34, 0xe8, 0x31, 0x14, 0x59, 0x98, 0xcf, 0xa9, 0x1e, 0xd4, 0x5b, 0x39, 0x59,
-	0x66, 0x68, 0x1d, 0x22, 0x5a, 0xf4, 0x35, 0x2c, 0x06, 0x69, 0x0f, 0xa7, 0xda, 0x48, 0xd5, 0x2d,
-	0x52, 0xc2, 0xd2, 0xe4, 0x7a, 0x42, 0xa3, 0x07, 0xbb, 0xe8, 0xdd, 0x57, 0xff, 0x90, 0x20, 0x8a,
-	0x85, 0x66, 0x87, 0xe8, 0xdd, 0xcf, 0xf0, 0xa0, 0x87, 0x50, 0x94, 0xdf, 0x30, 0xf0, 0x6d, 0x9d,
-	0xeb, 0xbd, 0xad, 0x53, 0x82, 0x6a, 0xdb, 0x25, 0xa9, 0xde, 0xc5, 0x85, 0x4c, 0xef, 0xe2, 0x63,
-	0x80, 0x16, 0x0e, 0xd4, 0x77, 0x09, 0x2a, 0xd4, 0xec, 0x59, 0x16, 0x4f, 0x90, 0xa2, 0x55, 0x18,
-	0x3b, 0x36, 0x03, 0xac, 0xe2, 0xc8, 0xc4, 0x67, 0x0e, 0xf1, 0x91, 0x31, 0x04, 0x05, 0xfa, 0x5c,
-	0xa9, 0x96, 0x52, 0x63, 0xd5, 0x67, 0xb0, 0x9c, 0xed, 0xf1, 0x4c, 0x2a, 0xb9, 0x91, 0xe2, 0xd0,
-	0x35, 0x58, 0xcc, 0x77, 0x44, 0xfa, 0x1d, 0x78, 0xb3, 0xaf, 0x6f, 0xd6, 0x17, 0x61, 0x3e, 0xef,
-	0x4e, 0x49, 0x9f, 0x85, 0x99, 0xcc, 0xad, 0x81, 0xfe, 0x6b, 0xa8, 0xa4, 0x3e, 0x72, 0xfa, 0x89,
-	0x3b, 0x08, 0x66, 0xa0, 0x92, 0x5a, 0xcd, 0x7b, 0x5f, 0xf5, 0xb8, 0x20, 0x40, 0x33, 0x50, 0x7a,
-	0x7e, 0xd8, 0xa8, 0xef, 0x6e, 0xd7, 0xf6, 0x6a, 0xbb, 0x3b, 0xd5, 0x1b, 0xa8, 0x04, 0x93, 0x3b,
-	0xbb, 0x7b, 0x9b, 0xcf, 0xf7, 0x8f, 0xaa, 0x05, 0x04, 0x30, 0xd1, 0x38, 0x32, 0x6a, 0xdb, 0x47,
-	0xd5, 0x11, 0x34, 0x09, 0xa3, 0xcf, 0xf6, 0xf6, 0xaa, 0xa3, 0xf7, 0x36, 0xc3, 0x74, 0x84, 0xa3,
-	0xa5, 0xc7, 0xaa, 0xde, 0x40, 0x95, 0x84, 0xa3, 0xac, 0x16, 0xb8, 0x18, 0xe5, 0x42, 0xab, 0x23,
-	0x7c, 0x90, 0x84, 0x67, 0xaa, 0x8e, 0x6e, 0x2d, 0xfe, 0xe3, 0x8f, 0xb7, 0x6f, 0xfc, 0xcb, 0x8f,
-	0xb7, 0x6f, 0xfc, 0xfb, 0x8f, 0xb7, 0x6f, 0x7c, 0x17, 0xfd, 0x2b, 0x97, 0xe3, 0x09, 0xf1, 0xb2,
-	0x1f, 0xfe, 0x4f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x2a, 0x2e, 0x0b, 0xce, 0x09, 0x46, 0x00, 0x00,
+	// 4864 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdc, 0x5c, 0x4b, 0x73, 0x1c, 0x47,
+	0x72, 0xe6, 0xe0, 0x3d, 0x39, 0x18, 0x60, 0x50, 0x78, 0xb0, 0x08, 0x82, 0x24, 0xd8, 0x4b, 0x52,
+	0x14, 0xb9, 0x0b, 0x52, 0x10, 0x97, 0xa2, 0xa8, 0xc7, 0x0a, 0x2f, 0x4a, 0xd0, 0x02, 0xe0, 0xb8,
+	0x07, 0xa4, 0x1e, 0xeb, 0x5d, 0xba, 0xd1, 0x5d, 0x18, 0x94, 0xd8, 0xd3, 0xd5, 0xdb, 0x5d, 0x33,
+	0x04, 0x74, 0xb0, 0xc3, 0x17, 0xfb, 0xe6, 0x83, 0x7f, 0x80, 0x7d, 0xf4, 0x4f, 0xb0, 0x7f, 0x82,
+	0x0f, 0x1b, 0x0e, 0x87, 0x23, 0x7c, 0x74, 0x84, 0x43, 0x37, 0xfb, 0xee, 0xf0, 0xc1, 0x17, 0x47,
+	0x3d, 0xfa, 0x39, 0x3d, 0x98, 0x01, 0x20, 0x86, 0x1c, 0xbe, 0x4d, 0x67, 0x65, 0x66, 0xbd, 0xb3,
+	0xb2, 0xbe, 0xcc, 0x1a, 0xb8, 0xe7, 0xbf, 0x6e, 0x3e, 0xb0, 0x7c, 0x1a, 0x3e, 0xa0, 0x21, 0xa7,
+	0xec, 0x41, 0xe7, 0x3d, 0xcb, 0xf5, 0x8f, 0xac, 0xf7, 0x1e, 0x74, 0x2c, 0xb7, 0x4d, 0xc2, 0x57,
+	0xfc, 0xc4, 0x27, 0xe1, 0x8a, 0x1f, 0x30, 0xce, 0xd0, 0x44, 0x54, 0xb8, 0x78, 0xbd, 0xc9, 0x58,
+	0xd3, 0x25, 0x0f, 0x24, 0xfd, 0xa0, 0x7d, 0xf8, 0xc0, 0x69, 0x07, 0x16, 0xa7, 0xcc, 0x53, 0x9c,
+	0x8b, 0x9f, 0x35, 0x29, 0x3f, 0x6a, 0x1f, 0xac, 0xd8, 0xac, 0xf5, 0xa0, 0xc9, 0x9a, 0x2c, 0x61,
+	0x8c, 0x7f, 0xe4, 0x35, 0xbc, 0x09, 0x2c, 0xdf, 0x27, 0x81, 0xae, 0x6b, 0x71, 0x4e, 0x88, 0xc9,
+	0x9f, 0x52, 0x81, 0xa2, 0x1a, 0x26, 0xc0, 0x5a, 0x60, 0x1f, 0x6d, 0x30, 0xef, 0x90, 0x36, 0xd1,
+	0x1c, 0x8c, 0x5a, 0x2d, 0xe7, 0xf1, 0x23, 0x5c, 0x5a, 0x2e, 0xdd, 0xad, 0x9a, 0xea, 0x03, 0x61,
+	0x18, 0xf7, 0x7d, 0xfb, 0xf1, 0x23, 0x97, 0xe0, 0x21, 0x49, 0x8f, 0x3e, 0x05, 0x7f, 0xf8, 0xfe,
+	0x87, 0x0f, 0x8f, 0xf1, 0xb0, 0xe2, 0x97, 0x1f, 0xc6, 0x7f, 0x8f, 0x40, 0x79, 0x63, 0x6f, 0x5b,
+	0xeb, 0x7c, 0x04, 0xe3, 0xc4, 0xb3, 0x0e, 0x5c, 0xe2, 0x48, 0xad, 0x95, 0xd5, 0xc5, 0x15, 0xd5,
+	0xd2, 0x95, 0xa8, 0xa5, 0x2b, 0xeb, 0x8c, 0xb9, 0x2f, 0xc5, 0xe8, 0x98, 0x11, 0x2b, 0xaa, 0xc1,
+	0xf0, 0x51, 0xfb, 0x40, 0xd6, 0x57, 0x36, 0xc5, 0x4f, 0xf4, 0x2e, 0x0c, 0x73, 0xab, 0x29, 0x6b,
+	0xaa, 0xac, 0x5e, 0x5e, 0x89, 0x46, 0x6e, 0x65, 0xff, 0xc4, 0x27, 0xdb, 0x1e, 0x27, 0xc1, 0xa1,
+	0x65, 0x13, 0x53, 0xf0, 0x88, 0x66, 0xd1, 0x96, 0xd5, 0x24, 0x78, 0x44, 0x8a, 0xab, 0x0f, 0x74,
+	0x1d, 0xc0, 0x6f, 0xbb, 0x6e, 0x9d, 0xb9, 0xd4, 0x3e, 0xc1, 0xa3, 0xb2, 0x28, 0x45, 0x41, 0x4b,
+	0x50, 0xb6, 0x3d, 0xba, 0x4e, 0xbd, 0x4d, 0x1a, 0xe0, 0x31, 0x59, 0x9c, 0x10, 0x84, 0xb4, 0xed,
+	0x51, 0xd1, 0x27, 0x51, 0x3c, 0xae, 0xa4, 0x13, 0x0a, 0xba, 0x0b, 0xd3, 0xfa, 0xeb, 0x19, 0x75,
+	0xc9, 0x9e, 0xd5, 0x22, 0x78, 0x42, 0x32, 0xe5, 0xc9, 0xe8, 0xe7, 0x30, 0x43, 0x8e, 0x6d, 0xb7,
+	0xed, 0xc8, 0xcf, 0xd0, 0xb7, 0x6c, 0x12, 0xe2, 0xf2, 0xf2, 0xf0, 0xdd, 0xb2, 0xd9, 0x5d, 0x80,
+	0x76, 0x60, 0xca, 0x67, 0xce, 0x9a, 0xe7, 0x31, 0x2e, 0xd7, 0x43, 0x88, 0x41, 0x8e, 0xc0, 0x72,
+	0x76, 0x04, 0x76, 0x2d, 0xbf, 0xc1, 0x03, 0xea, 0x35, 0xe3, 0xa1, 0x58, 0x1f, 0xc2, 0x25, 0x33,
+	0x27, 0x8b, 0xee, 0x42, 0xcd, 0x0f, 0xfd, 0x57, 0xb6, 0xdb, 0x0e, 0x39, 0x09, 0x5e, 0x05, 0xcc,
+	0x25, 0xb8, 0x22, 0x9b, 0x39, 0xe5, 0x87, 0xfe, 0x86, 0x22, 0x9b, 0xcc, 0x25, 0x68, 0x11, 0x26,
+	0x5c, 0xd6, 0xdc, 0x21, 0x1d, 0xe2, 0xe2, 0x49, 0xc9, 0x11, 0x7f, 0xa3, 0xf7, 0x60, 0x2c, 0x20,
+	0xbe, 0x45, 0x03, 0x5c, 0x95, 0x6d, 0xb9, 0x92, 0xb4, 0x65, 0x63, 0x6f, 0xdb, 0x94, 0x45, 0x6a,
+	0xf6, 0x4d, 0xcd, 0x28, 0x56, 0x81, 0x7d, 0x64, 0x51, 0x8f, 0x38, 0x78, 0xaa, 0xff, 0x2a, 0xd0,
+	0xac, 0x68, 0x05, 0x46, 0xb9, 0x45, 0x3d, 0x8e, 0xa7, 0xa5, 0x0c, 0xce, 0xd4, 0xb3, 0x2f, 0x4a,
+	0x74, 0x35, 0x8a, 0xcd, 0x78, 0x06, 0x53, 0xd9, 0x82, 0xf3, 0xad, 0x3e, 0xe3, 0xaf, 0x86, 0x61,
+	0x3a, 0xd7, 0x93, 0xff, 0x3b, 0xeb, 0x78, 0x09, 0xca, 0xae, 0x75, 0x40, 0xdc, 0x3a, 0x73, 0x42,
+	0xb9, 0x8c, 0x27, 0xcc, 0x84, 0x80, 0xee, 0xc0, 0xa4, 0x1d, 0x10, 0x8b, 0x93, 0xad, 0x0e, 0xf1,
+	0x78, 0xa8, 0x16, 0xb2, 0x5c, 0x0b, 0x19, 0xba, 0x58, 0xcf, 0x0e, 0x71, 0x09, 0x27, 0x52, 0xcd,
+	0xb8, 0x54, 0x93, 0xa2, 0x88, 0x55, 0x7a, 0x10, 0xb0, 0xd7, 0xc4, 0xab, 0x33, 0x67, 0x47, 0x68,
+	0xff, 0x35, 0x39, 0xd1, 0x2b, 0xba, 0xbb, 0x00, 0x3d, 0x84, 0xd9, 0x2c, 0x51, 0x0e, 0x03, 0x2e,
+	0x4b, 0xfe, 0xa2, 0x22, 0xa1, 0x9f, 0x7a, 0x54, 0x4c, 0x93, 0x98, 0x3a, 0x12, 0xc8, 0x1d, 0x03,
+	0x4a, 0x7f, 0x57, 0x81, 0xf1, 0x35, 0x2c, 0x6e, 0xd4, 0x5f, 0xec, 0x5b, 0x41, 0x93, 0xf0, 0x17,
+	0x9c, 0xba, 0xf4, 0x7b, 0xb9, 0xa0, 0xf5, 0xd4, 0x3c, 0x05, 0xcc, 0x65, 0xd1, 0x5a, 0x87, 0x04,
+	0x56, 0x93, 0xa4, 0x38, 0xe4, 0x5c, 0x8d, 0x9a, 0x3d, 0xcb, 0x8d, 0xff, 0x29, 0x41, 0xd9, 0x24,
+	0x21, 0x6b, 0x07, 0x62, 0xb7, 0x7d, 0x00, 0x63, 0x2e, 0x6d, 0x51, 0x1e, 0xe2, 0xd2, 0xf2, 0xf0,
+	0xdd, 0xca, 0xea, 0x8d, 0x64, 0x7e, 0x62, 0xa6, 0x95, 0x1d, 0xc9, 0xb1, 0xe5, 0xf1, 0xe0, 0xc4,
+	0xd4, 0xec, 0xe8, 0x13, 0x98, 0x08, 0xc8, 0xef, 0xdb, 0x24, 0xe4, 0x21, 0x1e, 0x92, 0xa2, 0x37,
+	0x8b, 0x44, 0x4d, 0xcd, 0xa3, 0x84, 0x63, 0x91, 0xc5, 0x0f, 0xa1, 0x92, 0xd2, 0x2a, 0x56, 0xcd,
+	0x6b, 0x72, 0x22, 0xdb, 0x5e, 0x36, 0xc5, 0x4f, 0xb1, 0x14, 0xe4, 0xf9, 0xa1, 0x57, 0x92, 0xfa,
+	0x78, 0x3a, 0xf4, 0xa4, 0xb4, 0xf8, 0x11, 0x54, 0x33, 0x5a, 0xcf, 0x22, 0x6c, 0xfc, 0xdb, 0x04,
+	0x54, 0x37, 0x58, 0x40, 0x36, 0xf7, 0x1a, 0x17, 0x5a, 0xe6, 0x06, 0x4c, 0xda, 0x4a, 0xcd, 0xb6,
+	0x5c, 0xb0, 0xaa, 0xa2, 0x0c, 0x4d, 0x5a, 0x50, 0xf5, 0xbd, 0xaf, 0xd7, 0x7f, 0xd9, 0x4c, 0x51,
+	0xd0, 0x0a, 0x20, 0xfd, 0x55, 0x77, 0xdb, 0x4d, 0xea, 0x6d, 0xa7, 0x96, 0x7e, 0x41, 0x09, 0xfa,
+	0x02, 0x26, 0x3d, 0xe6, 0x90, 0x06, 0x71, 0x89, 0xcd, 0x59, 0x80, 0x47, 0xcf, 0x60, 0x17, 0x33,
+	0x92, 0x62, 0xcf, 0x04, 0xc4, 0x77, 0xa9, 0x6d, 0x6d, 0xb0, 0xb6, 0xc7, 0xe5, 0x9e, 0xa9, 0x2a,
+	0xbe, 0x34, 0xbd, 0xc0, 0x16, 0x8f, 0x5f, 0xc0, 0x16, 0xff, 0x12, 0xca, 0x41, 0xb4, 0x30, 0xe4,
+	0xce, 0xaa, 0xac, 0xce, 0x16, 0xac, 0x19, 0x29, 0x9b, 0x70, 0xa2, 0x1d, 0x98, 0x0e, 0x98, 0xeb,
+	0x52, 0xaf, 0xb9, 0x6b, 0x1d, 0x37, 0xda, 0x41, 0x53, 0x6d, 0xb3, 0xca, 0xea, 0xf5, 0x2e, 0x5b,
+	0xf2, 0x3c, 0x50, 0xed, 0x78, 0xc6, 0x82, 0xfa, 0xba, 0xd4, 0x93, 0x17, 0x45, 0x5f, 0xc3, 0x7c,
+	0x42, 0x7a, 0xe1, 0x59, 0x1d, 0x8b, 0xba, 0x62, 0x4a, 0x31, 0x0c, 0xac, 0xb3, 0x58, 0x01, 0x62,
+	0xb0, 0x24, 0x3b, 0xcc, 0xe9, 0xda, 0xe1, 0xa1, 0xd8, 0xd1, 0x27, 0x72, 0xf7, 0xc7, 0xd3, 0x55,
+	0x91, 0x15, 0xbc, 0x93, 0xad, 0xa0, 0xe1, 0x52, 0x9b, 0x3c, 0x3f, 0xec, 0x31, 0x82, 0xa7, 0x2a,
+	0x44, 0x6f, 0x60, 0x39, 0x57, 0xbe, 0x4f, 0x82, 0x56, 0xb6, 0xd2, 0xc9, 0xb3, 0x57, 0xda, 0x57,
+	0x29, 0xda, 0x85, 0x0a, 0x67, 0x2e, 0x09, 0xf4, 0x9a, 0xa8, 0x9e, 0xbd, 0x8e, 0xb4, 0x3c, 0x7a,
+	0x06, 0x35, 0xab, 0xcd, 0x59, 0x68, 0x5b, 0x2e, 0xd9, 0xd2, 0x5b, 0xb1, 0xff, 0x99, 0xd9, 0x25,
+	0x23, 0xf6, 0x64, 0x4c, 0xdb, 0xb5, 0x8e, 0xe5, 0x19, 0x5a, 0x35, 0x33, 0xb4, 0x2c, 0x0f, 0xf5,
+	0x70, 0x2d, 0xcf, 0x43, 0x3d, 0xf4, 0x14, 0x86, 0x6d, 0xbf, 0x8d, 0x67, 0x64, 0x13, 0x6e, 0xa5,
+	0x8e, 0xe0, 0x9e, 0x06, 0x59, 0xf6, 0x49, 0x08, 0x19, 0x5f, 0xc3, 0xf2, 0x26, 0x39, 0xb4, 0xda,
+	0x2e, 0xaf, 0x33, 0x67, 0x93, 0x86, 0x41, 0xdb, 0x17, 0x6c, 0xeb, 0x6d, 0xa7, 0x49, 0x2e, 0x76,
+	0x44, 0x7f, 0x05, 0x0b, 0x5a, 0x73, 0xbc, 0x53, 0xb4, 0xbe, 0xb4, 0x29, 0x56, 0x0a, 0x8b, 0x4c,
+	0x71, 0x64, 0x33, 0x95, 0x50, 0x62, 0x8a, 0x8d, 0x7f, 0x98, 0x82, 0xd9, 0xad, 0x66, 0x40, 0xc2,
+	0xf0, 0x73, 0x8b, 0x93, 0x37, 0xd6, 0x89, 0x56, 0x5b, 0x34, 0x2d, 0xa5, 0x1f, 0x61, 0x5a, 0x86,
+	0x06, 0x98, 0x96, 0xe1, 0xde, 0xd3, 0x32, 0x7a, 0x8e, 0x69, 0x49, 0x0f, 0xf9, 0xf8, 0xe0, 0x46,
+	0x7e, 0x15, 0x86, 0x89, 0xd7, 0xc1, 0x13, 0x83, 0xd9, 0x3c, 0x53, 0x30, 0xa3, 0x35, 0x18, 0x93,
+	0xbe, 0x89, 0xf2, 0x70, 0x2b, 0xab, 0xef, 0x26, 0x62, 0x05, 0x83, 0xbc, 0x22, 0x37, 0x56, 0x7c,
+	0xb4, 0xca, 0x0f, 0x84, 0x60, 0xc4, 0x13, 0xce, 0xc1, 0x15, 0x79, 0x12, 0xc8, 0xdf, 0x5d, 0xb6,
+	0x1f, 0xce, 0x6d, 0xfb, 0xbb, 0x6d, 0x7a, 0xe5, 0x02, 0x36, 0xbd, 0x9f, 0xd1, 0x9b, 0xfc, 0x29,
+	0x8c, 0x5e, 0xf5, 0x6d, 0x18, 0xbd, 0xfb, 0x30, 0xea, 0xb3, 0x80, 0x87, 0x78, 0x4a, 0xce, 0xeb,
+	0x7c, 0xa2, 0xbd, 0x2e, 0xc8, 0x91, 0x5f, 0x2e, 0x79, 0xb2, 0x47, 0xdd, 0xf4, 0xc0, 0x47, 0xdd,
+	0xc7, 0x50, 0x0d, 0x89, 0x1d, 0x10, 0xfe, 0x92, 0xb9, 0xed, 0x16, 0x09, 0x71, 0x4d, 0xd6, 0xb5,
+	0x90, 0x88, 0x36, 0x52, 0xc5, 0x66, 0x96, 0x19, 0xd5, 0x01, 0x85, 0x24, 0xe8, 0x50, 0x9b, 0xa4,
+	0x67, 0x77, 0x66, 0xc0, 0xd5, 0x5b, 0x20, 0x2b, 0x56, 0xa2, 0xb8, 0xbd, 0x63, 0xa4, 0x56, 0xa2,
+	0xf8, 0x8d, 0xee, 0xc3, 0xc8, 0xf7, 0x1d, 0xdf, 0xc3, 0xb3, 0x79, 0x7f, 0xfe, 0x5b, 0x12, 0xb0,
+	0x97, 0xf5, 0x3d, 0x3d, 0x10, 0x92, 0x29, 0x7f, 0x52, 0xcc, 0x5d, 0xf0, 0xa4, 0x28, 0x70, 0x05,
+	0xe6, 0xdf, 0x82, 0x2b, 0xb0, 0x70, 0x51, 0x57, 0x60, 0x17, 0xaa, 0xb6, 0x1c, 0x86, 0x68, 0x1e,
+	0x2f, 0x9f, 0xa9, 0xe3, 0x66, 0x56, 0x1a, 0xfd, 0x06, 0xe6, 0x2c, 0xc7, 0xa1, 0x62, 0x0c, 0x2c,
+	0x37, 0xbe, 0x27, 0x84, 0x18, 0x9f, 0x4d, 0x6b, 0xa1, 0x12, 0xf4, 0x04, 0xca, 0x41, 0xdb, 0x5b,
+	0x0b, 0x4d, 0xc6, 0x38, 0x5e, 0xec, 0x6b, 0x1c, 0x13, 0x66, 0xb4, 0x0c, 0x15, 0x9b, 0x79, 0x76,
+	0x3b, 0x08, 0x88, 0x67, 0x9f, 0xe0, 0xab, 0xd2, 0x66, 0xa7, 0x49, 0xa8, 0x91, 0x3a, 0x42, 0x76,
+	0x09, 0x0f, 0xa8, 0x1d, 0xe2, 0xa5, 0xb3, 0x35, 0xba, 0x4b, 0x01, 0xda, 0x83, 0x99, 0x98, 0xb6,
+	0x4e, 0x8e, 0xac, 0x0e, 0x65, 0x01, 0xbe, 0x36, 0xe0, 0x2a, 0xef, 0x16, 0x95, 0x57, 0x91, 0xc4,
+	0x0a, 0x9f, 0xe9, 0x36, 0xf1, 0x1f, 0x25, 0x98, 0xd2, 0xf6, 0x3c, 0x3a, 0x8c, 0xf7, 0x60, 0x56,
+	0xc2, 0x60, 0xaf, 0x88, 0xb4, 0xf6, 0x4d, 0x55, 0xaa, 0x0f, 0xce, 0x6b, 0xa7, 0x1e, 0x06, 0x26,
+	0x92, 0x92, 0x5b, 0x69, 0xc1, 0xf4, 0xc9, 0x35, 0x34, 0xf8, 0xc9, 0xf5, 0x47, 0x30, 0xa7, 0x5a,
+	0x41, 0xbd, 0x4c, 0x33, 0x46, 0xf2, 0x2b, 0x7b, 0xdb, 0x2b, 0x68, 0x87, 0xea, 0xc1, 0x76, 0x46,
+	0xd4, 0xf8, 0x97, 0x19, 0x98, 0xfc, 0xdc, 0x65, 0x07, 0x96, 0xab, 0x7b, 0x7a, 0x17, 0x46, 0xac,
+	0xc0, 0x3e, 0xd2, 0x5d, 0x9b, 0x4b, 0x74, 0x26, 0xf8, 0x9a, 0x29, 0x39, 0xc4, 0x65, 0x59, 0x2d,
+	0x68, 0xb1, 0x6c, 0x62, 0xa8, 0x07, 0xaf, 0xaa, 0xcb, 0x72, 0x41, 0x91, 0xf0, 0x3d, 0xf4, 0x16,
+	0xb0, 0x5c, 0xea, 0xa8, 0x8b, 0xed, 0x70, 0x7f, 0xdf, 0x23, 0x2f, 0x83, 0xbe, 0x80, 0x1b, 0x8e,
+	0x72, 0x9a, 0x54, 0x83, 0x5e, 0xd2, 0x90, 0x1e, 0x50, 0x97, 0xf2, 0x93, 0x06, 0xe1, 0x9c, 0x7a,
+	0xcd, 0x10, 0x3f, 0x92, 0x40, 0x54, 0x3f, 0x36, 0xf4, 0x12, 0x66, 0x35, 0xcb, 0x5e, 0xfa, 0x1c,
+	0x1e, 0x3b, 0xc3, 0xd9, 0x59, 0xa4, 0x00, 0x79, 0xb0, 0xe8, 0xf4, 0x74, 0x18, 0xb5, 0xb3, 0x72,
+	0x2f, 0x51, 0xdf, 0xcf, 0xb9, 0x94, 0x15, 0x9d, 0xa2, 0x11, 0xd5, 0xa1, 0xe6, 0xe4, 0xdc, 0x48,
+	0x5c, 0xce, 0x77, 0xa2, 0xd8, 0xd1, 0x94, 0xba, 0xbb, 0xa4, 0xd1, 0x6f, 0x00, 0x69, 0xda, 0x7e,
+	0xca, 0xd4, 0x7f, 0x70, 0x76, 0x53, 0x5f, 0xa0, 0x26, 0x82, 0x93, 0x26, 0x13, 0x38, 0xe9, 0x2e,
+	0x4c, 0x4b, 0x58, 0xa8, 0x9e, 0x40, 0x9b, 0x55, 0x85, 0x3b, 0xe6, 0xc8, 0xe8, 0x1e, 0xd4, 0x62,
+	0x92, 0x3a, 0x37, 0x43, 0x7c, 0x5b, 0xce, 0x76, 0x17, 0x1d, 0xdd, 0x81, 0x29, 0xb9, 0xe8, 0x93,
+	0xd5, 0x39, 0xa5, 0x50, 0xc2, 0x2c, 0x55, 0x58, 0x4b, 0x97, 0x35, 0xd7, 0xc2, 0x2f, 0x43, 0xe6,
+	0xe1, 0x5b, 0xfd, 0xad, 0x65, 0xcc, 0x8c, 0x3e, 0x80, 0x71, 0x97, 0x35, 0x9b, 0xd4, 0x6b, 0xe2,
+	0x99, 0xbc, 0x31, 0x50, 0xfb, 0x6a, 0x47, 0x15, 0xeb, 0xad, 0x13, 0x71, 0xa3, 0x0d, 0xa8, 0xb6,
+	0x48, 0x78, 0xb4, 0x75, 0xec, 0x5b, 0x5e, 0x28, 0x36, 0x02, 0xca, 0x8b, 0xef, 0xa6, 0x8b, 0xb5,
+	0x78, 0x56, 0x06, 0x2d, 0xc0, 0x98, 0x20, 0x6c, 0x6f, 0xe2, 0x5f, 0xca, 0x7e, 0xe9, 0x2f, 0xb4,
+	0x09, 0x93, 0xe2, 0xd7, 0x1e, 0xe1, 0x6f, 0x58, 0xf0, 0x3a, 0xc4, 0xb3, 0xf9, 0xa5, 0xd0, 0xc3,
+	0x8e, 0x66, 0xa4, 0xd0, 0x67, 0x30, 0xd9, 0x6a, 0xbb, 0x9c, 0x6a, 0x3c, 0x55, 0x1f, 0xa0, 0x4b,
+	0xa9, 0x16, 0xa6, 0x4a, 0x75, 0x03, 0x33, 0x12, 0x02, 0x72, 0xf7, 0x94, 0x36, 0xfc, 0x8e, 0x6c,
+	0x60, 0xf4, 0x89, 0x1e, 0xc3, 0x82, 0xcf, 0x9c, 0xcd, 0xbd, 0x46, 0x83, 0x08, 0x63, 0x92, 0x82,
+	0x90, 0xef, 0xcb, 0xb9, 0xec, 0x51, 0x8a, 0x7e, 0x07, 0x4b, 0xac, 0x45, 0x79, 0x83, 0x3a, 0xc4,
+	0xb6, 0x82, 0x6d, 0xef, 0x3b, 0xb9, 0xdf, 0x54, 0xe5, 0xbb, 0x96, 0x8f, 0xef, 0xf4, 0x9d, 0xbc,
+	0x53, 0xe5, 0xd1, 0xa7, 0x30, 0xc9, 0xbc, 0x04, 0xb8, 0xc6, 0x97, 0xfb, 0xea, 0xcb, 0xf0, 0x23,
+	0x13, 0x16, 0x98, 0x2f, 0xd6, 0x39, 0x0b, 0x76, 0x2d, 0xcf, 0x6a, 0x92, 0xaf, 0xc8, 0xc1, 0x11,
+	0x63, 0xaf, 0x43, 0xfc, 0x6e, 0x5f, 0x4d, 0x3d, 0x24, 0xd1, 0x43, 0x98, 0xf1, 0x03, 0xca, 0x02,
+	0xca, 0x4f, 0x36, 0x5c, 0x2b, 0x0c, 0x45, 0x6d, 0xf8, 0x6a, 0x0c, 0x88, 0x76, 0x17, 0x4a, 0xaf,
+	0x36, 0x60, 0xc7, 0x27, 0xfa, 0x58, 0x4e, 0x7b, 0xb5, 0x82, 0x1c, 0x7b, 0xb5, 0xe2, 0x03, 0x7d,
+	0x00, 0x65, 0xf9, 0x63, 0xdb, 0xa3, 0x1c, 0x5f, 0xcb, 0x23, 0xe1, 0xf5, 0xa8, 0x48, 0x0b, 0x25,
+	0xbc, 0xe8, 0x36, 0x0c, 0x87, 0x4e, 0x88, 0xaf, 0xe7, 0x1d, 0xe1, 0xc6, 0xa6, 0x46, 0xe1, 0x4c,
+	0x51, 0x1e, 0x21, 0xc5, 0x37, 0x06, 0x40, 0x8a, 0x57, 0x60, 0x8c, 0x07, 0x96, 0x4d, 0x02, 0x7c,
+	0x73, 0xb9, 0x94, 0x75, 0x91, 0xf7, 0x25, 0x3d, 0x82, 0xe3, 0x15, 0x97, 0xf0, 0x55, 0x78, 0xd0,
+	0x0e, 0xf9, 0x26, 0x6b, 0x59, 0xd4, 0xc3, 0x86, 0x5c, 0x63, 0x69, 0x12, 0x5a, 0x85, 0xb1, 0x76,
+	0x48, 0x76, 0x37, 0xea, 0xf8, 0x67, 0x7d, 0xc7, 0x5f, 0x73, 0x0a, 0x04, 0x2f, 0x20, 0x2d, 0xc6,
+	0x49, 0x9d, 0xba, 0x8c, 0xaf, 0x39, 0x8e, 0x38, 0x30, 0xf1, 0x43, 0xa9, 0xbc, 0xa0, 0x44, 0xb4,
+	0x5a, 0xda, 0x13, 0x07, 0x3f, 0xce, 0xb7, 0x7a, 0x5b, 0xd2, 0xa3, 0x56, 0x2b, 0x2e, 0x81, 0x19,
+	0xfb, 0x42, 0x7e, 0x83, 0x04, 0xbc, 0x1e, 0xb0, 0x0e, 0x75, 0x48, 0x80, 0x9f, 0x28, 0xcc, 0xb8,
+	0xab, 0x40, 0xe0, 0xe4, 0xdf, 0xbd, 0xe1, 0xda, 0x26, 0x7e, 0x28, 0xb9, 0x12, 0x82, 0x9c, 0x03,
+	0x1e, 0xe2, 0xa7, 0x5d, 0x73, 0xb0, 0x9f, 0xcc, 0x01, 0x0f, 0x45, 0x18, 0x24, 0x20, 0x1d, 0x2a,
+	0x0d, 0xcd, 0x47, 0x2a, 0x0c, 0x12, 0x7d, 0xa3, 0x75, 0x98, 0x6a, 0x09, 0x5c, 0x70, 0x97, 0xbb,
+	0xa1, 0xa8, 0x39, 0xc4, 0x1f, 0xf7, 0x1d, 0xaa, 0x9c, 0x84, 0x68, 0xa4, 0x6d, 0x45, 0x23, 0xf5,
+	0x89, 0x6a, 0x64, 0x4c, 0x40, 0x9f, 0x41, 0xd5, 0x26, 0x1e, 0x0f, 0x2c, 0x57, 0x8d, 0x07, 0xfe,
+	0xb4, 0x6f, 0x05, 0x59, 0x01, 0x31, 0x64, 0xaf, 0xdb, 0x07, 0x24, 0xf0, 0x08, 0x27, 0xe1, 0x4b,
+	0x12, 0xc8, 0x8e, 0xfc, 0x4a, 0x0d, 0x59, 0x57, 0x81, 0xf1, 0x0b, 0x28, 0xc7, 0xfd, 0x17, 0x6b,
+	0x44, 0xdf, 0x81, 0xc4, 0x8d, 0x4e, 0x87, 0x04, 0xd3, 0x24, 0xc3, 0x84, 0xc9, 0xf4, 0x3c, 0x89,
+	0x01, 0x51, 0x1e, 0xd7, 0x9a, 0x67, 0xb9, 0x27, 0x21, 0x0d, 0x07, 0xf0, 0xd1, 0x72, 0x12, 0xc6,
+	0x7d, 0x98, 0x2d, 0x30, 0xff, 0xc2, 0xe9, 0x74, 0x65, 0x2c, 0x4a, 0x39, 0xa2, 0xea, 0xc3, 0xf8,
+	0xcf, 0x19, 0x98, 0x2b, 0x72, 0xd9, 0xfe, 0x5f, 0x81, 0x35, 0x62, 0x11, 0xb4, 0x43, 0xce, 0x5a,
+	0x0d, 0x35, 0xf4, 0x78, 0xac, 0x6f, 0x47, 0xb2, 0x02, 0x69, 0xa7, 0x19, 0xce, 0x0c, 0xf7, 0x54,
+	0xce, 0x02, 0xf7, 0xac, 0xc7, 0x70, 0xcf, 0xf4, 0xf2, 0x70, 0xd6, 0x55, 0xdb, 0xf6, 0x06, 0xc4,
+	0x7b, 0xee, 0xc0, 0x94, 0xcb, 0x2c, 0x67, 0xdd, 0x72, 0x2d, 0xcf, 0x26, 0xc1, 0x76, 0x5d, 0xa2,
+	0x92, 0x65, 0x33, 0x47, 0x15, 0x51, 0x9f, 0x34, 0xa5, 0x21, 0xfd, 0x2f, 0xd3, 0xf2, 0x9a, 0x44,
+	0xdc, 0xf2, 0xc5, 0x59, 0xd8, 0xb3, 0x1c, 0x6d, 0x01, 0xca, 0x38, 0x04, 0x12, 0xb3, 0xc0, 0xe8,
+	0x34, 0x28, 0xa3, 0x40, 0x20, 0x86, 0xa6, 0x7e, 0x7e, 0x0a, 0x34, 0x35, 0xfb, 0x23, 0x42, 0x53,
+	0x73, 0x6f, 0x11, 0x9a, 0x9a, 0xff, 0x29, 0xa0, 0xa9, 0x85, 0xb7, 0x0a, 0x4d, 0x5d, 0x1e, 0x00,
+	0x9a, 0xca, 0xc7, 0x7e, 0x70, 0x8f, 0xd8, 0xcf, 0x7a, 0x1a, 0xc2, 0xba, 0x72, 0x86, 0x79, 0x38,
+	0x0d, 0xcf, 0xba, 0x7a, 0x71, 0x3c, 0x6b, 0xe9, 0x47, 0xc0, 0xb3, 0xae, 0xa5, 0xf0, 0xac, 0xc7,
+	0x1a, 0xcf, 0x52, 0xce, 0x89, 0xd1, 0x6b, 0xff, 0x7e, 0xdb, 0xf1, 0xbd, 0x0c, 0xb4, 0x55, 0x80,
+	0x45, 0xdd, 0x78, 0x0b, 0x58, 0xd4, 0xf2, 0x45, 0xb1, 0xa8, 0x47, 0x30, 0x4f, 0x8e, 0x39, 0x09,
+	0x3c, 0xcb, 0xdd, 0x0f, 0xac, 0xc3, 0x43, 0x6a, 0x6b, 0x0f, 0x41, 0xf9, 0x40, 0xc5, 0x85, 0x79,
+	0xe0, 0xee, 0x67, 0x17, 0x04, 0xee, 0x7e, 0x0d, 0x93, 0x1a, 0x89, 0x50, 0x86, 0xe7, 0xd6, 0x99,
+	0xf4, 0x99, 0x19, 0xe1, 0x9e, 0x70, 0xd8, 0xed, 0x1f, 0x03, 0x0e, 0xeb, 0x82, 0xee, 0xee, 0x5c,
+	0x08, 0xba, 0xcb, 0xa0, 0x6b, 0xbf, 0xb8, 0x00, 0xba, 0xb6, 0x32, 0x18, 0xba, 0xf6, 0xe0, 0xad,
+	0xa0, 0x6b, 0x0f, 0x7f, 0x12, 0x74, 0xed, 0x08, 0x70, 0xaf, 0x3d, 0x78, 0xce, 0xa8, 0xfd, 0x02,
+	0x8c, 0x85, 0xed, 0xc3, 0x43, 0x7a, 0xac, 0x2b, 0xd3, 0x5f, 0xc6, 0x9f, 0xc1, 0x6c, 0xc1, 0x1d,
+	0xfa, 0x9c, 0x95, 0xa8, 0x8b, 0xc4, 0xf6, 0xce, 0xfa, 0x00, 0xce, 0xa0, 0xe6, 0x34, 0x5c, 0x40,
+	0xdd, 0x57, 0xe4, 0x73, 0xd6, 0x2f, 0x16, 0x8e, 0x52, 0x23, 0xaf, 0x7f, 0xaa, 0xa7, 0x69, 0x92,
+	0xf1, 0x97, 0x25, 0xb8, 0xfa, 0xbc, 0xcd, 0x0f, 0x58, 0xdb, 0x73, 0x32, 0xdb, 0x5e, 0xd7, 0xfb,
+	0x29, 0x8c, 0xb4, 0x98, 0xa3, 0x44, 0xa7, 0xd2, 0x2e, 0xcd, 0x29, 0x42, 0x2b, 0xbb, 0xcc, 0x21,
+	0xa6, 0x94, 0x33, 0xee, 0xc2, 0x88, 0xf8, 0x42, 0x55, 0x28, 0xaf, 0xed, 0xec, 0x3c, 0xff, 0xea,
+	0xd5, 0xda, 0xde, 0x37, 0xb5, 0x4b, 0x68, 0x06, 0xaa, 0xe6, 0xd6, 0xe7, 0xdb, 0x8d, 0x7d, 0xf3,
+	0x9b, 0x57, 0xcf, 0xf7, 0x76, 0xbe, 0xa9, 0x95, 0x8c, 0x3f, 0xcc, 0x40, 0x45, 0xde, 0x90, 0x2e,
+	0xd4, 0xe3, 0x22, 0xe7, 0x77, 0xe8, 0xa2, 0xce, 0x6f, 0x0f, 0xc7, 0x36, 0xef, 0x20, 0x8f, 0x14,
+	0x38, 0xc8, 0xf9, 0x23, 0x76, 0xb4, 0xc7, 0x11, 0x1b, 0xa7, 0x3b, 0x8d, 0xa5, 0xd3, 0x9d, 0x6e,
+	0x41, 0x55, 0x5e, 0x5a, 0x1b, 0x56, 0xcb, 0x17, 0xf6, 0x5c, 0xc6, 0x1f, 0x4b, 0x66, 0x96, 0x98,
+	0x8d, 0x30, 0x95, 0x07, 0x8e, 0x30, 0x89, 0xac, 0x3d, 0x39, 0xd4, 0x09, 0x70, 0x01, 0x3a, 0x6b,
+	0x2f, 0x4b, 0x8e, 0x3c, 0xf8, 0xca, 0x79, 0x3c, 0xf8, 0xbc, 0x4b, 0x38, 0x79, 0x6e, 0x97, 0xd0,
+	0x86, 0x1b, 0xaf, 0x09, 0xf1, 0x2d, 0x97, 0x76, 0xc4, 0xd0, 0x0a, 0x07, 0x5f, 0x6e, 0x0f, 0x8f,
+	0xd8, 0xa2, 0xe2, 0xb5, 0x26, 0x89, 0x53, 0xf2, 0xf2, 0x33, 0xbd, 0xa9, 0x13, 0x4a, 0xcd, 0x7e,
+	0x1a, 0xd0, 0x8e, 0xc0, 0x44, 0x7d, 0x97, 0x9d, 0xb4, 0x88, 0xc7, 0x95, 0xb5, 0xc2, 0x53, 0x83,
+	0x35, 0xd9, 0xec, 0x92, 0x14, 0x26, 0xdf, 0x8e, 0x51, 0x26, 0xd4, 0xdf, 0xe4, 0xc7, 0xcc, 0x29,
+	0x08, 0x62, 0x6e, 0x60, 0x08, 0x42, 0x5f, 0x5a, 0xe6, 0xcf, 0x72, 0x69, 0x29, 0x70, 0x5d, 0xf0,
+	0x5b, 0x70, 0x5d, 0xae, 0x5c, 0x3c, 0x8c, 0x96, 0x71, 0x42, 0x16, 0x2f, 0xe8, 0x84, 0x1c, 0xc1,
+	0x4d, 0x65, 0x31, 0xea, 0x62, 0x38, 0x6d, 0xe6, 0x36, 0x3c, 0x7a, 0x78, 0xa8, 0x1a, 0x12, 0x59,
+	0x36, 0xbc, 0xd4, 0x77, 0xe4, 0xfb, 0x2b, 0x41, 0x87, 0xb0, 0xdc, 0x93, 0x69, 0xdb, 0x53, 0x15,
+	0x5d, 0xeb, 0x5b, 0x51, 0x5f, 0x1d, 0x05, 0x17, 0xa6, 0xeb, 0x17, 0xb8, 0x30, 0xfd, 0x0a, 0x26,
+	0xd5, 0x5a, 0x54, 0x37, 0x47, 0xed, 0xce, 0x5e, 0x4d, 0xdd, 0x26, 0x12, 0x4b, 0xad, 0x58, 0xcc,
+	0x8c, 0x00, 0x7a, 0x02, 0x97, 0xbf, 0x7b, 0xf3, 0x3a, 0x14, 0xc6, 0xc7, 0xed, 0x90, 0x60, 0xeb,
+	0x98, 0x07, 0x96, 0xf0, 0x65, 0x36, 0xd6, 0xa4, 0x1b, 0x5b, 0x36, 0x7b, 0x15, 0xa3, 0xf7, 0x61,
+	0xdc, 0x97, 0x99, 0x6e, 0x21, 0xbe, 0x99, 0xc7, 0x15, 0xe3, 0x59, 0x56, 0x7d, 0x30, 0x23, 0xce,
+	0x28, 0x36, 0x60, 0x74, 0xa5, 0x9a, 0xfe, 0x6c, 0x00, 0x00, 0xf1, 0x15, 0x5c, 0xf3, 0x22, 0x5b,
+	0x27, 0xdc, 0x3f, 0xb1, 0x02, 0xd5, 0xf1, 0xa8, 0xd1, 0xe5, 0x5b, 0xfd, 0xda, 0x71, 0xba, 0x3c,
+	0xfa, 0x12, 0x96, 0x0b, 0x18, 0xb2, 0xb7, 0xc1, 0xdb, 0xb2, 0xe9, 0x7d, 0xf9, 0xd0, 0xb7, 0xb0,
+	0x58, 0xc0, 0xb3, 0xe1, 0x12, 0xcb, 0x6b, 0x0f, 0x82, 0x64, 0x9f, 0x22, 0x5d, 0xe8, 0x45, 0xbe,
+	0xf3, 0x56, 0xbc, 0xc8, 0xbb, 0xe7, 0xf6, 0x22, 0x85, 0xcb, 0xef, 0x27, 0x78, 0xf4, 0xf3, 0x0e,
+	0x09, 0x02, 0xea, 0x90, 0x08, 0x2a, 0x1f, 0xdc, 0xe5, 0x2f, 0x52, 0x82, 0xbe, 0x80, 0x29, 0x05,
+	0x75, 0x70, 0x22, 0xbd, 0xbf, 0x10, 0xdf, 0x1b, 0xb0, 0xa5, 0x39, 0x39, 0xe3, 0xef, 0x4b, 0x80,
+	0xe4, 0x26, 0xd1, 0x7e, 0xab, 0xf6, 0x6a, 0x44, 0x70, 0x49, 0x11, 0x22, 0x44, 0xab, 0xa4, 0x83,
+	0x4b, 0x19, 0x2a, 0x7a, 0x01, 0xf3, 0x34, 0x16, 0xd4, 0xd3, 0xb4, 0x9b, 0x38, 0x62, 0xa9, 0xdc,
+	0xdc, 0x42, 0x36, 0xb3, 0x58, 0x5a, 0xb8, 0x2c, 0x51, 0x81, 0x6b, 0x85, 0xa1, 0xce, 0x44, 0xcd,
+	0xd0, 0x8c, 0x6d, 0x98, 0x91, 0x0d, 0xcf, 0xf8, 0x81, 0xe7, 0x4b, 0x54, 0xe3, 0x30, 0xbd, 0x4f,
+	0x5c, 0xd2, 0x22, 0x3c, 0xb8, 0x90, 0x22, 0x74, 0x1f, 0x86, 0x3a, 0xab, 0x78, 0x38, 0x6f, 0x85,
+	0x62, 0xe5, 0x2f, 0x57, 0xf5, 0x85, 0x7c, 0xa8, 0xb3, 0x6a, 0xfc, 0xf5, 0x30, 0xcc, 0x74, 0x95,
+	0x9c, 0xb3, 0xe2, 0xaf, 0x61, 0xa6, 0x45, 0xb8, 0xe5, 0x58, 0xdc, 0x7a, 0x45, 0x8e, 0xed, 0x23,
+	0xcb, 0xd3, 0x79, 0xb9, 0x95, 0xd5, 0xfb, 0x85, 0xed, 0xd8, 0xd5, 0xdc, 0x5b, 0x9a, 0x59, 0xb7,
+	0xab, 0xd6, 0xca, 0xd1, 0xd1, 0x16, 0x80, 0x1f, 0xb0, 0x16, 0xe1, 0x47, 0xa4, 0x1d, 0x81, 0xc5,
+	0xb7, 0x0b, 0x55, 0xd6, 0x63, 0x36, 0xad, 0x2c, 0x25, 0x88, 0xbe, 0x80, 0x4a, 0xc8, 0x2d, 0xfb,
+	0xb5, 0x13, 0xd0, 0x0e, 0x09, 0xf4, 0x10, 0xdd, 0x29, 0xd4, 0xd3, 0x10, 0x7c, 0x9b, 0x92, 0x4f,
+	0x2b, 0x4a, 0x8b, 0xa2, 0x3f, 0x86, 0x19, 0xcb, 0xb6, 0x49, 0x18, 0xbe, 0x72, 0x59, 0xf3, 0x95,
+	0x9f, 0x3c, 0x15, 0xa9, 0xac, 0x3e, 0x2c, 0xd4, 0xb7, 0x26, 0xb9, 0x77, 0x58, 0x53, 0xad, 0x94,
+	0x67, 0xd4, 0x4d, 0x42, 0x7a, 0xd3, 0x56, 0xb6, 0xd0, 0xb0, 0xe0, 0x66, 0xdf, 0x51, 0x42, 0x1f,
+	0x43, 0xe5, 0x8d, 0x15, 0xb6, 0x06, 0x77, 0xdc, 0xd3, 0xec, 0xc6, 0xbf, 0x0e, 0xc3, 0xd5, 0x53,
+	0x86, 0xed, 0x9c, 0x2b, 0xe0, 0x42, 0x6d, 0x42, 0xbf, 0x8d, 0x9c, 0xec, 0x57, 0x4c, 0x1b, 0x19,
+	0x3d, 0x45, 0x8f, 0x06, 0x9a, 0xea, 0x95, 0xac, 0x81, 0x32, 0xa7, 0xec, 0xcc, 0xf7, 0xe2, 0x0f,
+	0x25, 0x98, 0xca, 0xb2, 0xa0, 0xa7, 0x30, 0x9e, 0xcd, 0x34, 0xe9, 0x6f, 0xbb, 0x22, 0x01, 0x61,
+	0xfe, 0xa8, 0xf2, 0x27, 0x74, 0xac, 0x13, 0x0f, 0x0d, 0xa8, 0x22, 0x27, 0x87, 0xbe, 0x84, 0x69,
+	0xd6, 0xe6, 0x69, 0x12, 0x1e, 0x1e, 0x50, 0x55, 0x5e, 0xd0, 0xf8, 0x9b, 0x51, 0x58, 0x3a, 0x6d,
+	0x19, 0x9f, 0x73, 0x62, 0x9f, 0x24, 0x51, 0xf8, 0xbe, 0x93, 0x2a, 0x9d, 0xa4, 0x88, 0x1d, 0x3d,
+	0x05, 0x68, 0x31, 0x8f, 0x72, 0x26, 0x1a, 0x3e, 0x40, 0x32, 0x4a, 0x8a, 0x1b, 0x3d, 0x86, 0x09,
+	0xce, 0x7c, 0xe6, 0xb2, 0x66, 0x94, 0x82, 0x73, 0x9a, 0x64, 0xcc, 0x8b, 0x36, 0x61, 0xda, 0xa1,
+	0xa1, 0x68, 0x79, 0xec, 0x9f, 0xf6, 0x8f, 0x85, 0xe4, 0x45, 0xc4, 0x04, 0x67, 0x57, 0xd0, 0xa0,
+	0x2f, 0x07, 0xf2, 0x2b, 0x0f, 0x7d, 0x07, 0xf3, 0xd1, 0x3c, 0xc5, 0x76, 0x40, 0x8e, 0xe5, 0xb8,
+	0x3c, 0xa0, 0x1e, 0x0d, 0x66, 0x81, 0x56, 0x32, 0xb2, 0x66, 0xb1, 0x4a, 0x74, 0x04, 0x73, 0xd4,
+	0xeb, 0xa6, 0xe3, 0x89, 0x0b, 0x54, 0x55, 0xa8, 0xd1, 0x78, 0x04, 0xd5, 0x6c, 0xd5, 0x13, 0x30,
+	0xb2, 0xf7, 0x7c, 0x6f, 0xab, 0x76, 0x49, 0xfc, 0x7a, 0xf6, 0x62, 0x67, 0xa7, 0x56, 0x42, 0xd3,
+	0x50, 0xd9, 0x32, 0xcd, 0xe7, 0x66, 0x43, 0x41, 0x17, 0x43, 0xc6, 0xdf, 0x95, 0xe0, 0xce, 0x60,
+	0x76, 0xf1, 0x9c, 0x4b, 0xf5, 0x73, 0x98, 0x71, 0x59, 0xf3, 0x2b, 0xea, 0x39, 0xec, 0x4d, 0x74,
+	0x97, 0xc5, 0x43, 0xfd, 0x2e, 0xbb, 0xdd, 0x32, 0xc6, 0x96, 0x3e, 0xdb, 0xd3, 0x9e, 0xbb, 0xc8,
+	0xc9, 0x0a, 0xdb, 0x07, 0xa1, 0x1d, 0xd0, 0x03, 0xe2, 0x24, 0xa9, 0x40, 0x25, 0x19, 0x47, 0x2a,
+	0x2a, 0x32, 0x7e, 0x0f, 0x95, 0x54, 0x38, 0x21, 0x0e, 0x05, 0x95, 0x52, 0xa1, 0x20, 0x04, 0x23,
+	0x22, 0xc8, 0x20, 0x5b, 0x39, 0x6a, 0xca, 0xdf, 0x22, 0xa0, 0x2c, 0x6e, 0xf4, 0x42, 0x54, 0xee,
+	0x9a, 0x51, 0x33, 0xfe, 0x16, 0x2f, 0x64, 0xd4, 0x3b, 0x25, 0x59, 0x3a, 0x22, 0x4b, 0x53, 0x14,
+	0xe3, 0x9f, 0xc6, 0xa1, 0x92, 0xca, 0x43, 0x10, 0xfc, 0xc2, 0x37, 0x54, 0xc9, 0x18, 0xfa, 0xa5,
+	0x4c, 0x8a, 0x22, 0xa0, 0x13, 0x8d, 0x73, 0xe9, 0x38, 0xbf, 0x7a, 0xf4, 0x98, 0x25, 0x8a, 0x10,
+	0xb1, 0xcd, 0x5a, 0x3e, 0xf3, 0xc4, 0x9d, 0x3d, 0x7a, 0xf2, 0xa7, 0x20, 0x98, 0xee, 0x82, 0x24,
+	0xc6, 0x2b, 0x9f, 0x0d, 0xb5, 0x5b, 0x3e, 0x2e, 0xf7, 0x9d, 0xc3, 0x9c, 0x84, 0x18, 0x6c, 0xfd,
+	0xd0, 0x51, 0xdf, 0xdc, 0x14, 0x0a, 0xae, 0xb2, 0x9a, 0x8a, 0x8a, 0x04, 0x4e, 0x13, 0x91, 0xeb,
+	0x3a, 0xc4, 0xa7, 0xb3, 0x9c, 0x72, 0xe4, 0x04, 0x44, 0x9a, 0x4a, 0x83, 0x48, 0x22, 0x4b, 0xca,
+	0xcb, 0xca, 0xab, 0xa0, 0x62, 0x9e, 0x9c, 0x79, 0xf7, 0x88, 0x72, 0xef, 0x1e, 0x9f, 0x0a, 0x77,
+	0x85, 0x76, 0xa8, 0x4b, 0x9a, 0xc4, 0xc1, 0xb3, 0x7d, 0xfb, 0x9d, 0xe2, 0x46, 0xeb, 0xb0, 0x14,
+	0x10, 0xcb, 0xa1, 0x1e, 0x09, 0x43, 0x91, 0x04, 0x42, 0x2d, 0x77, 0x93, 0xb8, 0xd6, 0x49, 0x83,
+	0xd8, 0xcc, 0x73, 0x54, 0x68, 0xaf, 0x6a, 0x9e, 0xca, 0x23, 0x72, 0x7f, 0xe2, 0xf2, 0x3a, 0x09,
+	0x28, 0x73, 0x22, 0xe9, 0x79, 0x29, 0xdd, 0xa3, 0x14, 0x7d, 0x0c, 0x57, 0xe2, 0x92, 0x67, 0x16,
+	0x75, 0xdb, 0x01, 0xd9, 0x3f, 0x0a, 0x48, 0x78, 0xc4, 0x5c, 0x47, 0x86, 0xe0, 0xaa, 0x66, 0x6f,
+	0x06, 0xb1, 0xca, 0x42, 0x6e, 0xf1, 0xb6, 0x0c, 0x37, 0xc8, 0xbc, 0x9e, 0xaa, 0x99, 0xa2, 0x64,
+	0xa1, 0x37, 0x7c, 0x06, 0xe8, 0x2d, 0x4a, 0x59, 0xb9, 0x22, 0x4d, 0x58, 0x2d, 0x91, 0x51, 0xf4,
+	0x38, 0x59, 0x65, 0x15, 0xe6, 0xf4, 0x2c, 0x47, 0x36, 0x5c, 0xad, 0x97, 0x25, 0x39, 0x3d, 0x85,
+	0x65, 0xe8, 0x53, 0x28, 0xbb, 0xf4, 0x90, 0xd8, 0x27, 0xb6, 0x4b, 0xf0, 0xad, 0x01, 0xed, 0x7b,
+	0x22, 0x82, 0x1c, 0xb8, 0x21, 0x3a, 0xbf, 0xe6, 0x4b, 0x7c, 0x52, 0xd8, 0x8d, 0x17, 0x1e, 0xa7,
+	0xae, 0xdc, 0x7d, 0x0d, 0x6e, 0x05, 0x3c, 0x8a, 0xaf, 0x9c, 0x36, 0xff, 0xfd, 0x54, 0x18, 0xbf,
+	0x83, 0xe9, 0x5c, 0x9a, 0x50, 0xb2, 0x7e, 0x4b, 0xe9, 0xf5, 0x9b, 0x19, 0xe3, 0xd1, 0x41, 0xc7,
+	0xd8, 0xd8, 0x80, 0xcb, 0x3d, 0x1e, 0xbc, 0xa0, 0x9a, 0xc2, 0x33, 0x75, 0xe4, 0x41, 0xa0, 0x94,
+	0x32, 0x27, 0xae, 0xc5, 0x82, 0x93, 0x28, 0x1a, 0xa0, 0xbe, 0x8c, 0xcf, 0xa1, 0x1c, 0x27, 0x26,
+	0xa1, 0xa7, 0x30, 0xca, 0xc5, 0x63, 0xce, 0x33, 0xbd, 0xb6, 0x53, 0x22, 0xc6, 0x9f, 0xc0, 0x64,
+	0x3a, 0xbe, 0x29, 0x72, 0x5f, 0x64, 0x36, 0x4c, 0xdd, 0xe2, 0x47, 0xba, 0x21, 0x09, 0x21, 0x36,
+	0xa8, 0x43, 0x29, 0x83, 0x2a, 0x96, 0xa2, 0xd4, 0x20, 0xa1, 0x7c, 0xfd, 0x84, 0x30, 0xa1, 0x18,
+	0x7f, 0x5b, 0x82, 0xaa, 0xbe, 0x3d, 0xc6, 0x29, 0x29, 0x15, 0x2b, 0x85, 0x07, 0x0d, 0xea, 0x0d,
+	0xa6, 0x85, 0xc4, 0x85, 0x31, 0x8a, 0x0a, 0xd6, 0x23, 0x73, 0x5e, 0x35, 0x33, 0xb4, 0xb8, 0xb5,
+	0xc3, 0x59, 0xf3, 0x9f, 0x7f, 0x2e, 0x60, 0xfc, 0xc5, 0x18, 0xcc, 0x17, 0xe6, 0xd0, 0xa1, 0xaf,
+	0xe1, 0x8a, 0x32, 0x93, 0x09, 0x68, 0xb2, 0x7e, 0xa2, 0x33, 0x4f, 0x07, 0xf0, 0xb8, 0x7b, 0x0b,
+	0xa3, 0x6f, 0x60, 0xd6, 0x23, 0x1d, 0xa2, 0x2b, 0x3c, 0xe7, 0x03, 0x3c, 0xb3, 0x48, 0x87, 0x8c,
+	0x3d, 0xba, 0x22, 0xdd, 0x3b, 0xa7, 0x7b, 0xf2, 0xac, 0xb1, 0xc7, 0x02, 0x25, 0x68, 0x07, 0x66,
+	0x03, 0xf2, 0x26, 0xa0, 0x9c, 0xac, 0xf9, 0xfe, 0x17, 0xfb, 0xfb, 0xf5, 0x7a, 0xc0, 0x0e, 0x08,
+	0xae, 0xf5, 0x1d, 0x8b, 0x22, 0x31, 0x64, 0xc2, 0x2c, 0x95, 0xfa, 0x49, 0x06, 0x21, 0x1c, 0x34,
+	0xc3, 0xb3, 0x48, 0x58, 0xb8, 0x92, 0xec, 0x20, 0xd3, 0xf1, 0x41, 0x81, 0xe7, 0x9c, 0x9c, 0x02,
+	0x25, 0xbe, 0x53, 0x18, 0xfc, 0x0b, 0x73, 0x07, 0x2f, 0x44, 0xa0, 0x44, 0x42, 0x13, 0xc7, 0xf9,
+	0xa1, 0x32, 0xce, 0x3a, 0x64, 0x7d, 0x59, 0x1d, 0xe7, 0x19, 0xa2, 0x4a, 0xc2, 0xa3, 0x5e, 0x87,
+	0x29, 0x9b, 0xa3, 0x59, 0x71, 0x94, 0x84, 0x97, 0x2f, 0x41, 0x6d, 0xb8, 0x19, 0xc3, 0x61, 0x51,
+	0x73, 0x76, 0x2d, 0x6e, 0x8b, 0xf0, 0x9f, 0x00, 0x44, 0xe4, 0x28, 0x5d, 0x39, 0xdb, 0x7c, 0xf6,
+	0xd7, 0x68, 0xfc, 0xf9, 0x10, 0x4c, 0xa6, 0x53, 0x13, 0x45, 0x42, 0xb0, 0xb8, 0x0d, 0x3b, 0xac,
+	0xd9, 0xfd, 0x3a, 0x40, 0x31, 0x6e, 0xaa, 0xe2, 0x28, 0x21, 0x58, 0x73, 0xa3, 0x4f, 0x84, 0xa9,
+	0x6f, 0x1e, 0xf1, 0x90, 0x13, 0x5f, 0x6f, 0x94, 0x1b, 0x79, 0xd1, 0x1d, 0xc1, 0xd0, 0xe0, 0xc4,
+	0xd7, 0xc2, 0x89, 0x04, 0x7a, 0x04, 0x63, 0xdf, 0x53, 0xff, 0x35, 0x8d, 0x32, 0xea, 0x97, 0xf2,
+	0xb2, 0xdf, 0xca, 0xd2, 0x28, 0x15, 0x51, 0xf1, 0xa2, 0x8d, 0x2c, 0xe4, 0x30, 0x92, 0x7f, 0x67,
+	0xa8, 0x44, 0x1b, 0x09, 0x4b, 0x01, 0xda, 0x60, 0x3c, 0x80, 0xd9, 0x82, 0x9e, 0x89, 0xe4, 0x5f,
+	0x4b, 0x67, 0x04, 0x2a, 0xab, 0x18, 0x7d, 0x1a, 0x0d, 0x98, 0x2f, 0xec, 0x4f, 0x6f, 0x11, 0x11,
+	0xfe, 0x54, 0x30, 0xc4, 0xbe, 0x34, 0xdb, 0x3a, 0xfc, 0x99, 0x22, 0x19, 0x2b, 0x80, 0xba, 0x3b,
+	0x7a, 0x4a, 0x23, 0xfe, 0xab, 0x04, 0x97, 0x7b, 0x74, 0x0f, 0x3d, 0x84, 0x51, 0x87, 0x1c, 0xb4,
+	0x9b, 0x03, 0x38, 0xf6, 0x8a, 0x51, 0xe4, 0x63, 0xb4, 0xac, 0xe3, 0xbd, 0x76, 0xeb, 0x80, 0x04,
+	0xcf, 0x0f, 0xd7, 0x38, 0x0f, 0xe8, 0x41, 0x9b, 0x93, 0x50, 0x5b, 0xd9, 0xe2, 0x42, 0xe1, 0x09,
+	0xa5, 0x0b, 0x52, 0xfb, 0x59, 0x05, 0x29, 0x7b, 0x94, 0x8a, 0x9c, 0xb1, 0x54, 0xc9, 0x2e, 0x09,
+	0x43, 0xab, 0x19, 0xfd, 0x53, 0x82, 0x0a, 0x5d, 0xf6, 0x2c, 0x37, 0xfe, 0x14, 0x60, 0xdd, 0x0a,
+	0xa3, 0x83, 0xe5, 0x4b, 0x40, 0xda, 0xab, 0x35, 0x37, 0xf7, 0x49, 0xcb, 0x77, 0x2d, 0x4e, 0xc2,
+	0x01, 0xba, 0x5d, 0x20, 0x25, 0x36, 0x76, 0x27, 0x7e, 0xa4, 0x21, 0x76, 0xbf, 0x9a, 0xa5, 0x2c,
+	0xd1, 0x78, 0x02, 0x48, 0x65, 0x5b, 0x9a, 0x32, 0x93, 0x56, 0xb7, 0x23, 0x6f, 0x38, 0x4a, 0xdd,
+	0x86, 0xc3, 0xf8, 0xc3, 0x28, 0x8c, 0xc9, 0xda, 0x43, 0x91, 0xf6, 0x6a, 0x7b, 0x14, 0x0f, 0xe5,
+	0x5d, 0x88, 0xf8, 0xff, 0x5a, 0x4c, 0x51, 0x8e, 0x3e, 0x82, 0x49, 0x99, 0x73, 0x6b, 0xb3, 0x80,
+	0x38, 0x7a, 0x54, 0x33, 0x21, 0x84, 0xcc, 0x9f, 0x06, 0x98, 0x19, 0x66, 0xf4, 0x08, 0x26, 0x34,
+	0x98, 0x12, 0xf9, 0x2a, 0xa9, 0x3f, 0xee, 0xc8, 0xbe, 0x0f, 0x32, 0x63, 0x4e, 0x91, 0x0c, 0xdc,
+	0x94, 0x89, 0x9f, 0xfa, 0x4e, 0xbf, 0x90, 0x7f, 0x0f, 0x10, 0xed, 0x40, 0xc5, 0x25, 0xb3, 0xbc,
+	0xc4, 0x35, 0x4e, 0xa7, 0x34, 0xce, 0x17, 0xc6, 0x65, 0x4c, 0xc5, 0x23, 0x52, 0xb5, 0x79, 0x74,
+	0x39, 0xc5, 0x97, 0xbb, 0x42, 0x19, 0x59, 0x7c, 0xd6, 0x4c, 0x78, 0xd1, 0x57, 0xb0, 0x10, 0x66,
+	0x8f, 0x6b, 0x9d, 0x5d, 0x8e, 0xab, 0x79, 0x4b, 0x53, 0x78, 0xac, 0x9b, 0x3d, 0xc4, 0xe5, 0x93,
+	0x1e, 0xfd, 0x77, 0x2b, 0xb1, 0x63, 0x37, 0x33, 0xc0, 0x93, 0x9e, 0x9c, 0x0c, 0x7a, 0x08, 0x65,
+	0xf5, 0xb4, 0x49, 0x4c, 0xeb, 0x6c, 0xef, 0x69, 0x9d, 0x90, 0x5c, 0x1b, 0x1e, 0xcd, 0xa4, 0x34,
+	0xcf, 0xe7, 0x52, 0x9a, 0x3f, 0x00, 0x10, 0x18, 0xbe, 0x92, 0xc1, 0xb7, 0xf2, 0xb3, 0x9e, 0x0d,
+	0x1c, 0xa5, 0x58, 0xc5, 0xeb, 0xa7, 0x03, 0x2b, 0x24, 0xf8, 0x76, 0xfe, 0xf5, 0x53, 0xb2, 0x65,
+	0x4c, 0xc9, 0x21, 0x1e, 0x47, 0xd0, 0xd4, 0x32, 0xc6, 0x77, 0xf2, 0x56, 0xb7, 0x7b, 0x91, 0x9b,
+	0x19, 0x09, 0x03, 0xc3, 0x42, 0xf1, 0xa9, 0x6a, 0xdc, 0x80, 0x6b, 0xa7, 0x1e, 0x4c, 0xc6, 0x02,
+	0xcc, 0x15, 0x45, 0x5d, 0x8d, 0x19, 0x98, 0xce, 0xc5, 0xb3, 0x8c, 0xdf, 0x42, 0x35, 0xf3, 0x84,
+	0xf3, 0x47, 0xce, 0xb1, 0x99, 0x86, 0x6a, 0x66, 0x34, 0xef, 0x7d, 0xd9, 0x23, 0xda, 0x21, 0xa0,
+	0x96, 0x17, 0x7b, 0x8d, 0xfa, 0xd6, 0xc6, 0xf6, 0xb3, 0xed, 0xad, 0xcd, 0xda, 0x25, 0x54, 0x81,
+	0xf1, 0xcd, 0xad, 0x67, 0x6b, 0x2f, 0x76, 0xf6, 0x6b, 0x25, 0x04, 0x30, 0xd6, 0xd8, 0x37, 0xb7,
+	0x37, 0xf6, 0x6b, 0x43, 0x68, 0x1c, 0x86, 0x9f, 0x3f, 0x7b, 0x56, 0x1b, 0xbe, 0xb7, 0x16, 0xdd,
+	0xad, 0x44, 0xb1, 0x3a, 0xb1, 0x6a, 0x97, 0x44, 0xfe, 0x49, 0x7c, 0xec, 0xd5, 0x4a, 0x42, 0x8d,
+	0x3e, 0x42, 0x6b, 0x43, 0xa2, 0x92, 0xd4, 0xc9, 0x54, 0x1b, 0x5e, 0x5f, 0xf8, 0xc7, 0x1f, 0xae,
+	0x5f, 0xfa, 0xe7, 0x1f, 0xae, 0x5f, 0xfa, 0xf7, 0x1f, 0xae, 0x5f, 0xfa, 0x36, 0xfe, 0xa3, 0xaa,
+	0x83, 0x31, 0xd9, 0xd9, 0xf7, 0xff, 0x77, 0x00, 0xad, 0x60, 0x20, 0xb8, 0xe7, 0x4a, 0x00, 0x00,
 }
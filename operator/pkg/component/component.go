@@ -456,6 +456,16 @@ func runComponent(c *CommonComponentFields) error {
 	return nil
 }
 
+// kubernetesVersion returns the Kubernetes version the manifests are rendered for, or an empty string if unset.
+func kubernetesVersion(iop *v1alpha1.IstioOperatorSpec) string {
+	global, ok := iop.Values["global"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	kv, _ := global["kubernetesVersion"].(string)
+	return kv
+}
+
 // renderManifest renders the manifest for the component defined by c and returns the resulting string.
 func renderManifest(c IstioComponent, cf *CommonComponentFields) (string, error) {
 	if !cf.started {
@@ -478,6 +488,11 @@ func renderManifest(c IstioComponent, cf *CommonComponentFields) (string, error)
 		log.Errorf("Error rendering the manifest: %s", err)
 		return "", err
 	}
+	// Select the API versions served by the target Kubernetes version.
+	my, err = translate.TranslateAPIVersions(my, kubernetesVersion(cf.InstallSpec))
+	if err != nil {
+		return "", err
+	}
 	my += helm.YAMLSeparator + "\n"
 	if devDbg {
 		scope.Infof("Initial manifest with merged values:\n%s\n", my)
@@ -522,7 +537,7 @@ func renderManifest(c IstioComponent, cf *CommonComponentFields) (string, error)
 	scope.Infof("Applying Kubernetes overlay: \n%s\n", kyo)
 	ret, err := patch.YAMLManifestPatch(my, cf.Namespace, overlays)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%s: %v", pathToK8sOverlay, err)
 	}
 
 	scope.Debugf("Manifest after resources and overlay: \n%s\n", ret)
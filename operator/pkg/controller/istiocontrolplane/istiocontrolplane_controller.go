@@ -291,6 +291,13 @@ func (r *ReconcileIstioOperator) Reconcile(request reconcile.Request) (reconcile
 		}
 		globalValues["jwtPolicy"] = string(jwtPolicy)
 	}
+	if _, ok := globalValues["kubernetesVersion"]; !ok {
+		if kubernetesVersion, err := util.DetectKubernetesVersion(r.config); err != nil {
+			scope.Warnf("Failed to detect the Kubernetes version: %v", err)
+		} else {
+			globalValues["kubernetesVersion"] = kubernetesVersion
+		}
+	}
 	reconciler, err := helmreconciler.NewHelmReconciler(r.client, r.config, iopMerged, nil)
 	if err != nil {
 		return reconcile.Result{}, err
@@ -31,6 +31,7 @@ import (
 	"istio.io/istio/operator/pkg/controlplane"
 	"istio.io/istio/operator/pkg/helm"
 	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
 	"istio.io/istio/operator/pkg/tpath"
 	"istio.io/istio/operator/pkg/translate"
 	"istio.io/istio/operator/pkg/util"
@@ -61,21 +62,60 @@ func GenManifests(inFilename []string, setFlags []string, force bool,
 		return nil, nil, err
 	}
 
-	t := translate.NewTranslator()
+	manifests, err := renderIOPS(mergedIOPS)
+	return manifests, mergedIOPS, err
+}
 
-	cp, err := controlplane.NewIstioControlPlane(mergedIOPS, t)
+// RenderIOPManifests generates a manifest map from the given IstioOperator YAML and profile, using the compiled in
+// profiles and charts. Unlike GenManifests, it never reads cluster specific values, so it can be used offline.
+func RenderIOPManifests(iopYAML, profile string, l clog.Logger) (name.ManifestMap, error) {
+	_, iops, err := GenIOPSFromProfile(profile, iopYAML, nil, false, false, nil, l)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	return renderIOPS(iops)
+}
+
+// OrderedManifests returns the objects of the given manifest map as YAML strings, sorted by the default object
+// order, so that dependencies such as CRDs come first. The order is stable across runs.
+func OrderedManifests(mm name.ManifestMap) ([]string, error) {
+	var rawOutput []string
+	var output []string
+	for _, mfs := range mm {
+		rawOutput = append(rawOutput, mfs...)
+	}
+	objects, err := object.ParseK8sObjectsFromYAMLManifest(strings.Join(rawOutput, helm.YAMLSeparator))
+	if err != nil {
+		return nil, err
+	}
+	// For a given group of objects, sort in order to avoid missing dependencies, such as creating CRDs first
+	objects.Sort(object.DefaultObjectOrder())
+	for _, obj := range objects {
+		yml, err := obj.YAML()
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, string(yml))
+	}
+
+	return output, nil
+}
+
+// renderIOPS renders the manifests for all components of the given IstioOperatorSpec.
+func renderIOPS(iops *v1alpha1.IstioOperatorSpec) (name.ManifestMap, error) {
+	cp, err := controlplane.NewIstioControlPlane(iops, translate.NewTranslator())
+	if err != nil {
+		return nil, err
 	}
 	if err := cp.Run(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	manifests, errs := cp.RenderManifest()
 	if errs != nil {
-		return manifests, mergedIOPS, errs.ToError()
+		return manifests, errs.ToError()
 	}
-	return manifests, mergedIOPS, nil
+	return manifests, nil
 }
 
 // GenerateConfig creates an IstioOperatorSpec from the following sources, overlaid sequentially:
@@ -100,6 +140,10 @@ func GenerateConfig(inFilenames []string, setFlags []string, force bool, kubeCon
 		return "", nil, err
 	}
 
+	if err := checkUserValues(fy, setFlags, false, l); err != nil {
+		return "", nil, err
+	}
+
 	iopsString, iops, err := GenIOPSFromProfile(profile, fy, setFlags, force, false, kubeConfig, l)
 
 	if err != nil {
@@ -117,6 +161,42 @@ func GenerateConfig(inFilenames []string, setFlags []string, force bool, kubeCon
 	return iopsString, iops, nil
 }
 
+// CheckUserValues checks the values in the user overlay files and --set flags against the values schema. If strict
+// is set, unknown fields cause an error, otherwise they are only logged as a warning, as they have no effect.
+func CheckUserValues(inFilenames []string, setFlags []string, strict bool, l clog.Logger) error {
+	fy, _, err := ReadYamlProfile(inFilenames, setFlags, true, l)
+	if err != nil {
+		return err
+	}
+	return checkUserValues(fy, setFlags, strict, l)
+}
+
+func checkUserValues(fileOverlayYAML string, setFlags []string, strict bool, l clog.Logger) error {
+	overlayYAML, err := overlaySetFlagValues(fileOverlayYAML, setFlags)
+	if err != nil {
+		return err
+	}
+	valuesYAML, err := tpath.GetConfigSubtree(overlayYAML, "spec.values")
+	if err != nil {
+		// no values in the user overlay.
+		return nil
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(valuesYAML), &values); err != nil {
+		return err
+	}
+	errs := validate.CheckValuesSchema(values)
+	if len(errs) == 0 {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("values contain fields which are not in the values schema: %v", errs)
+	}
+	l.LogAndError(fmt.Sprintf("Values contain fields which are not in the values schema and have no effect "+
+		"(use --strict-values to make this an error): %v", errs))
+	return nil
+}
+
 // GenIOPSFromProfile generates an IstioOperatorSpec from the given profile name or path, and overlay YAMLs from user
 // files and the --set flag. If successful, it returns an IstioOperatorSpec string and struct.
 func GenIOPSFromProfile(profileOrPath, fileOverlayYAML string, setFlags []string, skipValidation, allowUnknownField bool,
@@ -189,6 +269,11 @@ func GenIOPSFromProfile(profileOrPath, fileOverlayYAML string, setFlags []string
 	if err != nil {
 		return "", nil, err
 	}
+	// The autoscaling/v2 fields of the hpaSpec are rendered through the values, see TranslateK8sHPASpecs.
+	outYAML, err = translate.TranslateK8sHPASpecs(outYAML)
+	if err != nil {
+		return "", nil, err
+	}
 
 	// Grab just the IstioOperatorSpec subtree.
 	outYAML, err = tpath.GetSpecSubtree(outYAML)
@@ -367,6 +452,17 @@ func getClusterSpecificValues(config *rest.Config, force bool, l clog.Logger) (s
 		overlays = append(overlays, jwt)
 	}
 
+	kubernetesVersion, err := getKubernetesVersionOverlay(config)
+	if err != nil {
+		if force {
+			l.LogAndPrint(err)
+		} else {
+			return "", err
+		}
+	} else if kubernetesVersion != "" {
+		overlays = append(overlays, kubernetesVersion)
+	}
+
 	return makeTreeFromSetList(overlays)
 
 }
@@ -417,6 +513,17 @@ func getJwtTypeOverlay(config *rest.Config, l clog.Logger) (string, error) {
 	return "values.global.jwtPolicy=" + string(jwtPolicy), nil
 }
 
+func getKubernetesVersionOverlay(config *rest.Config) (string, error) {
+	kubernetesVersion, err := util.DetectKubernetesVersion(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the Kubernetes version. Use the --force flag to ignore this: %v", err)
+	}
+	if kubernetesVersion == "" {
+		return "", nil
+	}
+	return "values.global.kubernetesVersion=" + kubernetesVersion, nil
+}
+
 // unmarshalAndValidateIOPS unmarshals a string containing IstioOperator YAML, validates it, and returns a struct
 // representation if successful. If force is set, validation errors are written to logger rather than causing an
 // error.
@@ -19,9 +19,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"istio.io/istio/operator/pkg/util"
+	"istio.io/istio/operator/pkg/util/clog"
 	"istio.io/istio/pkg/test/env"
 )
 
@@ -96,3 +98,26 @@ func TestReadLayeredYAMLs(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckUserValues(t *testing.T) {
+	setFlags := []string{"values.global.proxyv2.image=proxy", "values.pilot.podAnnotations.foo=bar"}
+	var stdErr bytes.Buffer
+	l := clog.NewConsoleLogger(ioutil.Discard, &stdErr, nil)
+
+	err := CheckUserValues(nil, setFlags, true, l)
+	if err == nil || !strings.Contains(err.Error(), "unknown field values.global.proxyv2") {
+		t.Fatalf("expected unknown field error in strict mode, got %v", err)
+	}
+
+	if err := CheckUserValues(nil, setFlags, false, l); err != nil {
+		t.Fatalf("unexpected error without strict mode: %v", err)
+	}
+	if got := stdErr.String(); !strings.Contains(got, "unknown field values.global.proxyv2") ||
+		strings.Contains(got, "podAnnotations") {
+		t.Fatalf("expected a warning for only the unknown field, got %q", got)
+	}
+
+	if err := CheckUserValues(nil, []string{"values.global.proxy.image=proxy"}, true, l); err != nil {
+		t.Fatalf("unexpected error for known field: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifesttest
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"istio.io/istio/operator/pkg/name"
+)
+
+func TestRenderMinimalProfile(t *testing.T) {
+	g := gomega.NewWithT(t)
+	got, err := Render(nil, []string{"profile=minimal", "values.pilot.autoscaleMax=7"}, LiveCharts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := ParseObjectSetFromManifest(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hpa := MustGetHPA(t, objs, "istiod")
+	g.Expect(MustGetValueAtPath(t, hpa.Unstructured(), "spec.maxReplicas")).Should(gomega.BeEquivalentTo(7))
+	g.Expect(MustGetValueAtPath(t, hpa.Unstructured(), "spec.scaleTargetRef.name")).Should(gomega.Equal("istiod"))
+	MustGetDeployment(t, objs, "istiod")
+	g.Expect(objs.Kind(name.DeploymentStr).NameEquals("istio-ingressgateway")).Should(gomega.BeNil())
+	g.Expect(objs.Labels("app=istiod").Kind(name.HPAStr).Size()).Should(gomega.Equal(1))
+
+	t.Run("stable ordering", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			again, err := Render(nil, []string{"profile=minimal", "values.pilot.autoscaleMax=7"}, LiveCharts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != got {
+				t.Fatal("rendering the same input twice produced different manifests")
+			}
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		def, err := Render(nil, []string{"profile=minimal"}, LiveCharts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertNoDiff(t, got, def, "*:*:*", "HorizontalPodAutoscaler:*:istiod")
+		diff, err := Diff(got, def, "HorizontalPodAutoscaler:*:*", "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff == "" {
+			t.Error("expected the HorizontalPodAutoscalers to differ")
+		}
+	})
+}
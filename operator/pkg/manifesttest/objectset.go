@@ -0,0 +1,241 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifesttest
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/istio/operator/pkg/tpath"
+	"istio.io/istio/operator/pkg/util"
+	"istio.io/pkg/log"
+)
+
+// ObjectSet is a set of objects maintained both as a slice (for ordering) and map (for speed).
+type ObjectSet struct {
+	objSlice object.K8sObjects
+	objMap   map[string]*object.K8sObject
+	keySlice []string
+}
+
+// NewObjectSet creates a new ObjectSet from objs and returns a pointer to it.
+func NewObjectSet(objs object.K8sObjects) *ObjectSet {
+	ret := &ObjectSet{}
+	for _, o := range objs {
+		ret.append(o)
+	}
+	return ret
+}
+
+// ParseObjectSetFromManifest parses an ObjectSet from the given manifest.
+func ParseObjectSetFromManifest(manifest string) (*ObjectSet, error) {
+	objSlice, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+	return NewObjectSet(objSlice), err
+}
+
+// append appends an object to o.
+func (o *ObjectSet) append(obj *object.K8sObject) {
+	h := obj.Hash()
+	o.objSlice = append(o.objSlice, obj)
+	if o.objMap == nil {
+		o.objMap = make(map[string]*object.K8sObject)
+	}
+	o.objMap[h] = obj
+	o.keySlice = append(o.keySlice, h)
+}
+
+// Objects returns the objects of o, in the order they were added.
+func (o *ObjectSet) Objects() object.K8sObjects {
+	return o.objSlice
+}
+
+// Size reports the length of o.
+func (o *ObjectSet) Size() int {
+	return len(o.keySlice)
+}
+
+// NameMatches returns a subset of o where objects names match the given regex.
+func (o *ObjectSet) NameMatches(nameRegex string) *ObjectSet {
+	ret := &ObjectSet{}
+	for _, k := range o.keySlice {
+		_, _, objName := object.FromHash(k)
+		m, err := regexp.MatchString(nameRegex, objName)
+		if err != nil {
+			log.Error(err.Error())
+			continue
+		}
+		if m {
+			ret.append(o.objMap[k])
+		}
+	}
+	return ret
+}
+
+// NameEquals returns the object in o whose name matches "name", or nil if no object name matches.
+func (o *ObjectSet) NameEquals(name string) *object.K8sObject {
+	for _, k := range o.keySlice {
+		_, _, objName := object.FromHash(k)
+		if objName == name {
+			return o.objMap[k]
+		}
+	}
+	return nil
+}
+
+// Kind returns a subset of o where kind matches the given value.
+func (o *ObjectSet) Kind(kind string) *ObjectSet {
+	ret := &ObjectSet{}
+	for _, k := range o.keySlice {
+		objKind, _, _ := object.FromHash(k)
+		if objKind == kind {
+			ret.append(o.objMap[k])
+		}
+	}
+	return ret
+}
+
+// Namespace returns a subset of o where namespace matches the given value.
+func (o *ObjectSet) Namespace(namespace string) *ObjectSet {
+	ret := &ObjectSet{}
+	for _, k := range o.keySlice {
+		_, objNamespace, _ := object.FromHash(k)
+		if objNamespace == namespace {
+			ret.append(o.objMap[k])
+		}
+	}
+	return ret
+}
+
+// Labels returns a subset of o where the object's labels match all the given labels, in key=value format.
+func (o *ObjectSet) Labels(labels ...string) *ObjectSet {
+	ret := &ObjectSet{}
+	for _, k := range o.keySlice {
+		obj := o.objMap[k]
+		hasAll := true
+		for _, l := range labels {
+			lkv := strings.Split(l, "=")
+			if len(lkv) != 2 {
+				panic("label must have format key=value")
+			}
+			if !HasLabel(obj, lkv[0], lkv[1]) {
+				hasAll = false
+				break
+			}
+		}
+		if hasAll {
+			ret.append(obj)
+		}
+	}
+	return ret
+}
+
+// HasLabel reports whether o has the given label.
+func HasLabel(o *object.K8sObject, label, value string) bool {
+	got, found, err := tpath.Find(o.UnstructuredObject().UnstructuredContent(), util.PathFromString("metadata.labels"))
+	if err != nil {
+		log.Errorf("bad path: %s", err)
+		return false
+	}
+	if !found {
+		return false
+	}
+	return got.(map[string]interface{})[label] == value
+}
+
+// MustGet returns the object with the given kind and name or fails if it's not found in objs.
+func MustGet(t testing.TB, objs *ObjectSet, kind, objName string) *object.K8sObject {
+	t.Helper()
+	obj := objs.Kind(kind).NameEquals(objName)
+	if obj == nil {
+		t.Fatalf("Expected to get %s %s", kind, objName)
+	}
+	return obj
+}
+
+// MustGetService returns the service with the given name or fails if it's not found in objs.
+func MustGetService(t testing.TB, objs *ObjectSet, serviceName string) *object.K8sObject {
+	t.Helper()
+	return MustGet(t, objs, name.ServiceStr, serviceName)
+}
+
+// MustGetDeployment returns the deployment with the given name or fails if it's not found in objs.
+func MustGetDeployment(t testing.TB, objs *ObjectSet, deploymentName string) *object.K8sObject {
+	t.Helper()
+	return MustGet(t, objs, name.DeploymentStr, deploymentName)
+}
+
+// MustGetHPA returns the HorizontalPodAutoscaler with the given name or fails if it's not found in objs.
+func MustGetHPA(t testing.TB, objs *ObjectSet, hpaName string) *object.K8sObject {
+	t.Helper()
+	return MustGet(t, objs, name.HPAStr, hpaName)
+}
+
+// MustGetClusterRole returns the clusterRole with the given name or fails if it's not found in objs.
+func MustGetClusterRole(t testing.TB, objs *ObjectSet, clusterRoleName string) *object.K8sObject {
+	t.Helper()
+	return MustGet(t, objs, name.ClusterRoleStr, clusterRoleName)
+}
+
+// MustGetRole returns the role with the given name or fails if it's not found in objs.
+func MustGetRole(t testing.TB, objs *ObjectSet, roleName string) *object.K8sObject {
+	t.Helper()
+	return MustGet(t, objs, name.RoleStr, roleName)
+}
+
+// MustGetContainer returns the container tree with the given name in the deployment with the given name.
+func MustGetContainer(t testing.TB, objs *ObjectSet, deploymentName, containerName string) map[string]interface{} {
+	t.Helper()
+	obj := MustGetDeployment(t, objs, deploymentName)
+	container := obj.Container(containerName)
+	if container == nil {
+		t.Fatalf("Expected to get container %s in deployment %s", containerName, deploymentName)
+	}
+	return container
+}
+
+// MustGetEndpoint returns the endpoint with the given name or fails if it's not found in objs.
+func MustGetEndpoint(t testing.TB, objs *ObjectSet, endpointName string) *object.K8sObject {
+	t.Helper()
+	return MustGet(t, objs, name.EndpointStr, endpointName)
+}
+
+// MustGetMutatingWebhookConfiguration returns the mutatingWebhookConfiguration with the given name or fails if it's not found in objs.
+func MustGetMutatingWebhookConfiguration(t testing.TB, objs *ObjectSet, mutatingWebhookConfigurationName string) *object.K8sObject {
+	t.Helper()
+	return MustGet(t, objs, name.MutatingWebhookConfigurationStr, mutatingWebhookConfigurationName)
+}
+
+// MustGetValidatingWebhookConfiguration returns the validatingWebhookConfiguration with the given name or fails if it's not found in objs.
+func MustGetValidatingWebhookConfiguration(t testing.TB, objs *ObjectSet, validatingWebhookConfigurationName string) *object.K8sObject {
+	t.Helper()
+	return MustGet(t, objs, name.ValidatingWebhookConfigurationStr, validatingWebhookConfigurationName)
+}
+
+// MustGetValueAtPath returns the value at the given path in the unstructured tree. Fails if the path is not found
+// in the tree.
+func MustGetValueAtPath(t testing.TB, tree map[string]interface{}, path string) interface{} {
+	t.Helper()
+	got, f, err := tpath.GetPathContext(tree, util.PathFromString(path), false)
+	if err != nil {
+		t.Fatalf("path %s should exist (%s)", path, err)
+	}
+	if !f {
+		t.Fatalf("path %s should exist", path)
+	}
+	return got.Node
+}
@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package manifesttest provides utilities to unit test IstioOperator overlays: rendering manifests as
+// istioctl manifest generate does, querying the rendered objects and diffing manifests.
+package manifesttest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"istio.io/istio/operator/pkg/compare"
+	"istio.io/istio/operator/pkg/helm"
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/istio/operator/pkg/util/clog"
+	"istio.io/istio/pkg/test/env"
+)
+
+// ChartSource defines where the charts and profiles used to render manifests come from.
+type ChartSource string
+
+// CompiledInCharts are the charts compiled into the binary.
+const CompiledInCharts ChartSource = "COMPILED"
+
+// LiveCharts are the charts in the manifests/ directory of the Istio source tree.
+var LiveCharts = ChartSource(filepath.Join(env.IstioSrc, helm.OperatorSubdirFilePath))
+
+// Render renders the manifest for the given IstioOperator files and --set flags (in path=value format, without
+// the istioctl flag aliases) using the given charts. The objects are ordered as with istioctl manifest generate,
+// and the output is stable across runs.
+func Render(inFiles []string, setFlags []string, charts ChartSource) (string, error) {
+	if charts != CompiledInCharts {
+		setFlags = append(append([]string{}, setFlags...), "installPackagePath="+string(charts))
+	}
+	l := clog.NewConsoleLogger(ioutil.Discard, ioutil.Discard, nil)
+	manifests, _, err := manifest.GenManifests(inFiles, setFlags, false, nil, l)
+	if err != nil {
+		return "", err
+	}
+	ordered, err := manifest.OrderedManifests(manifests)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, m := range ordered {
+		sb.WriteString(m + object.YAMLSeparator)
+	}
+	return sb.String(), nil
+}
+
+// RenderObjectSet renders the manifest like Render, and parses it into an ObjectSet.
+func RenderObjectSet(inFiles []string, setFlags []string, charts ChartSource) (*ObjectSet, error) {
+	m, err := Render(inFiles, setFlags, charts)
+	if err != nil {
+		return nil, err
+	}
+	return ParseObjectSetFromManifest(m)
+}
+
+// Diff returns the differences between the got and want manifests, restricted to the objects matching
+// selectResources and ignoring the objects or paths matching ignoreResources, in the syntax of
+// istioctl manifest diff --select and --ignore. It returns an empty string if the manifests are equivalent.
+func Diff(got, want, selectResources, ignoreResources string, verbose bool) (string, error) {
+	return compare.ManifestDiffWithRenameSelectIgnore(got, want, "", selectResources, ignoreResources, verbose)
+}
+
+// AssertNoDiff fails if the got and want manifests differ, with the selectResources and ignoreResources of Diff.
+func AssertNoDiff(t testing.TB, got, want, selectResources, ignoreResources string) {
+	t.Helper()
+	for _, verbose := range []bool{true, false} {
+		diff, err := Diff(got, want, selectResources, ignoreResources, verbose)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff != "" {
+			t.Errorf("(-got, +want)\n%s\n", diff)
+		}
+	}
+}
@@ -333,7 +333,7 @@ func (os K8sObjects) YAMLManifest() (string, error) {
 	return b.String(), nil
 }
 
-// Sort will order the items in K8sObjects in order of score, group, kind, name.  The intent is to
+// Sort will order the items in K8sObjects in order of score, group, kind, name, namespace.  The intent is to
 // have a deterministic ordering in which K8sObjects are applied.
 func (os K8sObjects) Sort(score func(o *K8sObject) int) {
 	sort.Slice(os, func(i, j int) bool {
@@ -348,7 +348,12 @@ func (os K8sObjects) Sort(score func(o *K8sObject) int) {
 			(iScore == jScore &&
 				os[i].Group == os[j].Group &&
 				os[i].Kind == os[j].Kind &&
-				os[i].Name < os[j].Name)
+				os[i].Name < os[j].Name) ||
+			(iScore == jScore &&
+				os[i].Group == os[j].Group &&
+				os[i].Kind == os[j].Kind &&
+				os[i].Name == os[j].Name &&
+				os[i].Namespace < os[j].Namespace)
 	})
 }
 
@@ -419,7 +424,11 @@ func DefaultObjectOrder() func(o *K8sObject) int {
 	return func(o *K8sObject) int {
 		gk := o.Group + "/" + o.Kind
 		switch {
-		// Create CRDs asap - both because they are slow and because we will likely create instances of them soon
+		// The namespaced objects can only be created once their namespace exists
+		case gk == "/Namespace":
+			return -1001
+
+			// Create CRDs asap - both because they are slow and because we will likely create instances of them soon
 		case gk == "apiextensions.k8s.io/CustomResourceDefinition":
 			return -1000
 
@@ -494,3 +494,33 @@ spec:
 		})
 	}
 }
+
+func TestDefaultObjectOrder(t *testing.T) {
+	objs, err := ParseK8sObjectsFromYAMLManifest(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: sa
+  namespace: ns
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crd
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs.Sort(DefaultObjectOrder())
+	var got []string
+	for _, o := range objs {
+		got = append(got, o.Kind)
+	}
+	if want := "Namespace,CustomResourceDefinition,ServiceAccount"; strings.Join(got, ",") != want {
+		t.Errorf("got order %v, want %s", got, want)
+	}
+}
@@ -244,7 +244,7 @@ func (t *Translator) OverlayK8sSettings(yml string, iop *v1alpha1.IstioOperatorS
 		// strategic merge overlay m to the base object oo
 		mergedObj, err := MergeK8sObject(oo, m, path[1:])
 		if err != nil {
-			return "", err
+			return "", fmt.Errorf("%s: %v", inPath, err)
 		}
 		// Update the original object in objects slice, since the output should be ordered.
 		*(om[pe]) = *mergedObj
@@ -325,7 +325,7 @@ func (t *Translator) TranslateHelmValues(iop *v1alpha1.IstioOperatorSpec, compon
 		return "", err
 	}
 
-	mergedYAML, err = applyGatewayTranslations(mergedYAML, componentName, componentsSpec)
+	mergedYAML, err = applyGatewayTranslations(mergedYAML, globalVals, componentName, componentsSpec)
 	if err != nil {
 		return "", err
 	}
@@ -334,8 +334,11 @@ func (t *Translator) TranslateHelmValues(iop *v1alpha1.IstioOperatorSpec, compon
 }
 
 // applyGatewayTranslations writes gateway name gwName at the appropriate values path in iop and maps k8s.service.ports
-// to values. It returns the resulting YAML tree.
-func applyGatewayTranslations(iop []byte, componentName name.ComponentName, componentSpec interface{}) ([]byte, error) {
+// to values. Any values set under gateways.<gwName> in the validated values are overlaid on the values of the chart
+// gateway, so that settings like concurrency and resources can differ between gateways of the same kind. It returns
+// the resulting YAML tree.
+func applyGatewayTranslations(iop []byte, values map[string]interface{}, componentName name.ComponentName,
+	componentSpec interface{}) ([]byte, error) {
 	if !componentName.IsGateway() {
 		return iop, nil
 	}
@@ -345,6 +348,9 @@ func applyGatewayTranslations(iop []byte, componentName name.ComponentName, comp
 	}
 	gwSpec := componentSpec.(*v1alpha1.GatewaySpec)
 	k8s := gwSpec.K8S
+	if err := applyGatewayValuesOverride(iopt, values, componentName, gwSpec.Name); err != nil {
+		return nil, err
+	}
 	switch componentName {
 	case name.IngressComponentName:
 		setYAMLNodeByMapPath(iopt, util.PathFromString("gateways.istio-ingressgateway.name"), gwSpec.Name)
@@ -366,6 +372,43 @@ func applyGatewayTranslations(iop []byte, componentName name.ComponentName, comp
 	return yaml.Marshal(iopt)
 }
 
+// applyGatewayValuesOverride overlays the values tree at gateways.<gwName> in values onto the values of the chart
+// gateway for componentName in iopt. Only the validated values are read, see validate.CheckValues. A gateway named
+// after the chart gateway has nothing to overlay, since its values are already the defaults for every gateway of
+// that kind.
+func applyGatewayValuesOverride(iopt, values map[string]interface{}, componentName name.ComponentName, gwName string) error {
+	chartGateway := ""
+	switch componentName {
+	case name.IngressComponentName:
+		chartGateway = "istio-ingressgateway"
+	case name.EgressComponentName:
+		chartGateway = "istio-egressgateway"
+	}
+	if gwName == "" || gwName == chartGateway {
+		return nil
+	}
+	valuesGateways, _ := values["gateways"].(map[string]interface{})
+	override, ok := valuesGateways[gwName].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	gateways, ok := iopt["gateways"].(map[string]interface{})
+	if !ok {
+		gateways = make(map[string]interface{})
+		iopt["gateways"] = gateways
+	}
+	base, _ := gateways[chartGateway].(map[string]interface{})
+	if base == nil {
+		base = make(map[string]interface{})
+	}
+	merged, err := util.OverlayTrees(base, override)
+	if err != nil {
+		return fmt.Errorf("failed to apply values for gateway %s: %v", gwName, err)
+	}
+	gateways[chartGateway] = merged
+	return nil
+}
+
 // setYAMLNodeByMapPath sets the value at the given path to val in treeNode. The path cannot traverse lists and
 // treeNode must be a YAML tree unmarshaled into a plain map data structure.
 func setYAMLNodeByMapPath(treeNode interface{}, path util.Path, val interface{}) {
@@ -695,7 +738,7 @@ func MergeK8sObject(base *object.K8sObject, overlayNode interface{}, path util.P
 
 	// get a versioned object from the scheme, we can use the strategic patching mechanism
 	// (i.e. take advantage of patchStrategy in the type)
-	versionedObject, err := scheme.Scheme.New(base.GroupVersionKind())
+	versionedObject, err := scheme.Scheme.New(schemeGroupVersionKind(base.GroupVersionKind()))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,140 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// versionedKind is a kind rendered by the charts with an API version that is removed in newer Kubernetes versions.
+type versionedKind struct {
+	kind string
+	// oldAPIVersion is the API version in the charts.
+	oldAPIVersion string
+	// newAPIVersion replaces oldAPIVersion starting with minKubernetesVersion.
+	newAPIVersion        string
+	minKubernetesVersion *version.Version
+}
+
+var (
+	versionedKinds = []versionedKind{
+		{
+			kind:                 "PodDisruptionBudget",
+			oldAPIVersion:        "policy/v1beta1",
+			newAPIVersion:        "policy/v1",
+			minKubernetesVersion: version.MustParseGeneric("1.21"),
+		},
+		{
+			// The charts render autoscaling/v2beta1 unless the autoscaling/v2 metrics or behavior are set.
+			kind:                 "HorizontalPodAutoscaler",
+			oldAPIVersion:        "autoscaling/v2beta2",
+			newAPIVersion:        "autoscaling/v2",
+			minKubernetesVersion: version.MustParseGeneric("1.23"),
+		},
+	}
+
+	documentSeparator = regexp.MustCompile(`(?m)^---`)
+)
+
+// TranslateAPIVersions sets the apiVersion of the version-forked kinds in the given manifest to the newest one
+// served by kubernetesVersion, e.g. "v1.21". If kubernetesVersion is empty, the manifest is returned unchanged.
+func TranslateAPIVersions(manifest, kubernetesVersion string) (string, error) {
+	if kubernetesVersion == "" {
+		return manifest, nil
+	}
+	kv, err := ParseKubernetesVersion(kubernetesVersion)
+	if err != nil {
+		return "", err
+	}
+	var kinds []versionedKind
+	for _, vk := range versionedKinds {
+		if kv.AtLeast(vk.minKubernetesVersion) {
+			kinds = append(kinds, vk)
+		}
+	}
+	if len(kinds) == 0 {
+		return manifest, nil
+	}
+
+	// Documents are rewritten line by line, so that the rest of the manifest, including comments, is unchanged.
+	separators := documentSeparator.FindAllStringIndex(manifest, -1)
+	var sb strings.Builder
+	start := 0
+	for _, sep := range append(separators, []int{len(manifest), len(manifest)}) {
+		sb.WriteString(translateDocumentAPIVersion(manifest[start:sep[0]], kinds))
+		sb.WriteString(manifest[sep[0]:sep[1]])
+		start = sep[1]
+	}
+	return sb.String(), nil
+}
+
+// ParseKubernetesVersion parses a Kubernetes version such as "v1.21", "1.21" or "v1.21.3-gke.100".
+func ParseKubernetesVersion(kubernetesVersion string) (*version.Version, error) {
+	kv, err := version.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kubernetes version %q: %v", kubernetesVersion, err)
+	}
+	return kv, nil
+}
+
+func translateDocumentAPIVersion(doc string, kinds []versionedKind) string {
+	for _, vk := range kinds {
+		if !hasTopLevelField(doc, "kind", vk.kind) {
+			continue
+		}
+		lines := strings.Split(doc, "\n")
+		for i, l := range lines {
+			if strings.TrimRight(l, " \r") == "apiVersion: "+vk.oldAPIVersion {
+				lines[i] = "apiVersion: " + vk.newAPIVersion
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return doc
+}
+
+// schemeGroupVersionKind returns the GroupVersionKind to look up in the client scheme for gvk. The new API versions of
+// the versioned kinds may not be registered yet, their old API version is used instead, which has the same patch
+// strategies.
+func schemeGroupVersionKind(gvk schema.GroupVersionKind) schema.GroupVersionKind {
+	if scheme.Scheme.Recognizes(gvk) {
+		return gvk
+	}
+	for _, vk := range versionedKinds {
+		if vk.kind != gvk.Kind || vk.newAPIVersion != gvk.GroupVersion().String() {
+			continue
+		}
+		if gv, err := schema.ParseGroupVersion(vk.oldAPIVersion); err == nil {
+			return gv.WithKind(gvk.Kind)
+		}
+	}
+	return gvk
+}
+
+// hasTopLevelField reports whether the YAML document has the given top level string field.
+func hasTopLevelField(doc, field, value string) bool {
+	for _, l := range strings.Split(doc, "\n") {
+		if strings.TrimRight(l, " \r") == field+": "+value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"istio.io/istio/operator/pkg/compare"
+)
+
+const apiVersionsManifest = `# Source: istiod/templates/poddisruptionbudget.yaml
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: istiod
+  namespace: istio-system
+spec:
+  minAvailable: 1
+  selector:
+    matchLabels:
+      app: istiod
+---
+# Source: istiod/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+  annotations:
+    example: "apiVersion: policy/v1beta1"
+`
+
+func TestTranslateAPIVersions(t *testing.T) {
+	tests := []struct {
+		desc              string
+		kubernetesVersion string
+		wantPDBVersion    string
+		wantErr           bool
+	}{
+		{
+			desc:           "no version hint",
+			wantPDBVersion: "policy/v1beta1",
+		},
+		{
+			desc:              "old version",
+			kubernetesVersion: "v1.20.7",
+			wantPDBVersion:    "policy/v1beta1",
+		},
+		{
+			desc:              "minimum version",
+			kubernetesVersion: "1.21",
+			wantPDBVersion:    "policy/v1",
+		},
+		{
+			desc:              "provider version",
+			kubernetesVersion: "v1.22.3-gke.100",
+			wantPDBVersion:    "policy/v1",
+		},
+		{
+			desc:              "invalid version",
+			kubernetesVersion: "latest",
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := TranslateAPIVersions(apiVersionsManifest, tt.kubernetesVersion)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("TranslateAPIVersions(%s): got error %v, want error %v", tt.kubernetesVersion, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			want := strings.Replace(apiVersionsManifest, "apiVersion: policy/v1beta1\n", "apiVersion: "+tt.wantPDBVersion+"\n", 1)
+			if got != want {
+				t.Errorf("TranslateAPIVersions(%s): got:\n%s\nwant:\n%s", tt.kubernetesVersion, got, want)
+			}
+
+			// The comparison helpers must report the API version change.
+			diff, err := compare.ManifestDiff(apiVersionsManifest, got, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed := tt.wantPDBVersion != "policy/v1beta1"; changed != (diff != "") {
+				t.Errorf("TranslateAPIVersions(%s): got diff %q, want diff %v", tt.kubernetesVersion, diff, changed)
+			}
+		})
+	}
+}
+
+func TestSchemeGroupVersionKind(t *testing.T) {
+	tests := []struct {
+		in   schema.GroupVersionKind
+		want schema.GroupVersionKind
+	}{
+		{
+			in:   schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			want: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		},
+		{
+			in:   schema.GroupVersionKind{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"},
+			want: schema.GroupVersionKind{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"},
+		},
+		{
+			in:   schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"},
+			want: schema.GroupVersionKind{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"},
+		},
+		{
+			in:   schema.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"},
+			want: schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in.String(), func(t *testing.T) {
+			if got := schemeGroupVersionKind(tt.in); got != tt.want {
+				t.Errorf("schemeGroupVersionKind(%v): got %v, want %v", tt.in, got, tt.want)
+			}
+			if _, err := scheme.Scheme.New(schemeGroupVersionKind(tt.in)); err != nil {
+				t.Errorf("schemeGroupVersionKind(%v) is not registered: %v", tt.in, err)
+			}
+		})
+	}
+}
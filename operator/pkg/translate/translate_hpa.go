@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translate
+
+import (
+	"fmt"
+
+	"github.com/ghodss/yaml"
+)
+
+var (
+	// hpaMetricSources are the fields of a metric holding its source.
+	hpaMetricSources = []string{"resource", "containerResource", "pods", "object", "external"}
+	// hpaV2MetricFields are the fields of a metric source which only exist in autoscaling/v2. The target of an
+	// object metric source is the object in autoscaling/v2beta1, it is described by describedObject since.
+	hpaV2MetricFields = []string{"target", "metric", "describedObject"}
+	// hpaLegacyMetricFields are the fields of a metric source which only exist in autoscaling/v2beta1.
+	hpaLegacyMetricFields = []string{"targetAverageUtilization", "targetAverageValue", "targetValue", "metricName"}
+)
+
+// TranslateK8sHPASpecs moves the autoscaling/v2 fields of the k8s.hpaSpec of the components in the given
+// IstioOperator YAML, which the autoscaling/v2beta1 hpaSpec does not have, to the autoscaleMetrics and
+// autoscaleBehavior values of the component. The charts render an autoscaling/v2 HorizontalPodAutoscaler from these,
+// while a hpaSpec with the legacy fields only is overlaid unchanged. It returns an error if the legacy metric fields
+// are used together with the autoscaling/v2 metrics or behavior.
+func TranslateK8sHPASpecs(iopYAML string) (string, error) {
+	tree := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(iopYAML), &tree); err != nil {
+		return "", err
+	}
+	spec, _ := tree["spec"].(map[string]interface{})
+	components, _ := spec["components"].(map[string]interface{})
+	if components == nil {
+		return iopYAML, nil
+	}
+
+	changed := false
+	if pilot, ok := components["pilot"].(map[string]interface{}); ok {
+		c, err := translateK8sHPASpec(spec, pilot, []string{"values", "pilot"}, "pilot")
+		if err != nil {
+			return "", err
+		}
+		changed = changed || c
+	}
+	for _, gwType := range []string{"ingressGateways", "egressGateways"} {
+		gateways, _ := components[gwType].([]interface{})
+		for _, gw := range gateways {
+			gwSpec, ok := gw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			gwName, _ := gwSpec["name"].(string)
+			if gwName == "" {
+				continue
+			}
+			// The values of the gateways not named after the chart gateway are overlaid on the values of the chart
+			// gateway, see applyGatewayValuesOverride.
+			c, err := translateK8sHPASpec(spec, gwSpec, []string{"values", "gateways", gwName}, gwType+"."+gwName)
+			if err != nil {
+				return "", err
+			}
+			changed = changed || c
+		}
+	}
+	if !changed {
+		return iopYAML, nil
+	}
+	out, err := yaml.Marshal(tree)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// translateK8sHPASpec moves the autoscaling/v2 fields of the hpaSpec of the component to the values at valuesPath,
+// from the root of spec. It returns true if the tree was changed.
+func translateK8sHPASpec(spec, component map[string]interface{}, valuesPath []string,
+	componentPath string) (bool, error) {
+	k8s, _ := component["k8s"].(map[string]interface{})
+	hpaSpec, _ := k8s["hpaSpec"].(map[string]interface{})
+	values := getNode(spec, valuesPath)
+
+	metrics, _ := hpaSpec["metrics"].([]interface{})
+	legacyMetrics, v2Metrics := false, false
+	for _, m := range metrics {
+		legacy, v2 := hpaMetricAPIVersions(m)
+		legacyMetrics = legacyMetrics || legacy
+		v2Metrics = v2Metrics || v2
+	}
+	behavior, hasBehavior := hpaSpec["behavior"]
+	valuesV2 := values["autoscaleMetrics"] != nil || values["autoscaleBehavior"] != nil
+	if legacyMetrics && (v2Metrics || hasBehavior || valuesV2) {
+		return false, fmt.Errorf("components.%s.k8s.hpaSpec: the autoscaling/v2beta1 metric fields, such as "+
+			"targetAverageUtilization, cannot be used together with the autoscaling/v2 metrics or behavior", componentPath)
+	}
+	if !v2Metrics && !hasBehavior {
+		return false, nil
+	}
+
+	values = setNode(spec, valuesPath)
+	if v2Metrics {
+		values["autoscaleMetrics"] = metrics
+		delete(hpaSpec, "metrics")
+	}
+	if hasBehavior {
+		values["autoscaleBehavior"] = behavior
+		delete(hpaSpec, "behavior")
+	}
+	if len(hpaSpec) == 0 {
+		delete(k8s, "hpaSpec")
+	}
+	return true, nil
+}
+
+// hpaMetricAPIVersions returns whether the metric has fields of the autoscaling/v2beta1 and autoscaling/v2 metrics.
+func hpaMetricAPIVersions(metric interface{}) (legacy, v2 bool) {
+	m, ok := metric.(map[string]interface{})
+	if !ok {
+		return false, false
+	}
+	if m["type"] == "ContainerResource" {
+		v2 = true
+	}
+	for _, s := range hpaMetricSources {
+		source, ok := m[s].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, f := range hpaV2MetricFields {
+			if s == "object" && f == "target" {
+				continue
+			}
+			if _, ok := source[f]; ok {
+				v2 = true
+			}
+		}
+		for _, f := range hpaLegacyMetricFields {
+			if _, ok := source[f]; ok {
+				legacy = true
+			}
+		}
+	}
+	return legacy, v2
+}
+
+// getNode returns the node at the given path of the tree, or nil if it does not exist.
+func getNode(tree map[string]interface{}, path []string) map[string]interface{} {
+	node := tree
+	for _, p := range path {
+		node, _ = node[p].(map[string]interface{})
+	}
+	return node
+}
+
+// setNode returns the node at the given path of the tree, creating it if it does not exist.
+func setNode(tree map[string]interface{}, path []string) map[string]interface{} {
+	node := tree
+	for _, p := range path {
+		child, ok := node[p].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			node[p] = child
+		}
+		node = child
+	}
+	return node
+}
@@ -0,0 +1,278 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translate
+
+import (
+	"testing"
+
+	"istio.io/istio/operator/pkg/util"
+)
+
+func TestTranslateK8sHPASpecs(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "no components",
+			in: `
+spec:
+  profile: default
+`,
+			want: `
+spec:
+  profile: default
+`,
+		},
+		{
+			desc: "legacy metrics unchanged",
+			in: `
+spec:
+  components:
+    pilot:
+      k8s:
+        hpaSpec:
+          maxReplicas: 5
+          metrics:
+          - type: Resource
+            resource:
+              name: cpu
+              targetAverageUtilization: 60
+`,
+			want: `
+spec:
+  components:
+    pilot:
+      k8s:
+        hpaSpec:
+          maxReplicas: 5
+          metrics:
+          - type: Resource
+            resource:
+              name: cpu
+              targetAverageUtilization: 60
+`,
+		},
+		{
+			desc: "legacy object metric unchanged",
+			in: `
+spec:
+  components:
+    pilot:
+      k8s:
+        hpaSpec:
+          metrics:
+          - type: Object
+            object:
+              metricName: requests-per-second
+              target:
+                kind: Service
+                name: istiod
+              targetValue: 100
+`,
+			want: `
+spec:
+  components:
+    pilot:
+      k8s:
+        hpaSpec:
+          metrics:
+          - type: Object
+            object:
+              metricName: requests-per-second
+              target:
+                kind: Service
+                name: istiod
+              targetValue: 100
+`,
+		},
+		{
+			desc: "pilot metrics and behavior",
+			in: `
+spec:
+  components:
+    pilot:
+      k8s:
+        hpaSpec:
+          maxReplicas: 5
+          metrics:
+          - type: Resource
+            resource:
+              name: memory
+              target:
+                type: Utilization
+                averageUtilization: 75
+          behavior:
+            scaleDown:
+              stabilizationWindowSeconds: 600
+  values:
+    pilot:
+      autoscaleMin: 2
+`,
+			want: `
+spec:
+  components:
+    pilot:
+      k8s:
+        hpaSpec:
+          maxReplicas: 5
+  values:
+    pilot:
+      autoscaleMin: 2
+      autoscaleMetrics:
+      - type: Resource
+        resource:
+          name: memory
+          target:
+            type: Utilization
+            averageUtilization: 75
+      autoscaleBehavior:
+        scaleDown:
+          stabilizationWindowSeconds: 600
+`,
+		},
+		{
+			desc: "gateway behavior",
+			in: `
+spec:
+  components:
+    ingressGateways:
+    - name: istio-ingressgateway
+      enabled: true
+      k8s:
+        hpaSpec:
+          behavior:
+            scaleUp:
+              stabilizationWindowSeconds: 0
+    - name: ilb-gateway
+      k8s:
+        hpaSpec:
+          metrics:
+          - type: Pods
+            pods:
+              metric:
+                name: connections
+              target:
+                type: AverageValue
+                averageValue: 1k
+`,
+			want: `
+spec:
+  components:
+    ingressGateways:
+    - name: istio-ingressgateway
+      enabled: true
+      k8s: {}
+    - name: ilb-gateway
+      k8s: {}
+  values:
+    gateways:
+      istio-ingressgateway:
+        autoscaleBehavior:
+          scaleUp:
+            stabilizationWindowSeconds: 0
+      ilb-gateway:
+        autoscaleMetrics:
+        - type: Pods
+          pods:
+            metric:
+              name: connections
+            target:
+              type: AverageValue
+              averageValue: 1k
+`,
+		},
+		{
+			desc: "legacy metrics with behavior",
+			in: `
+spec:
+  components:
+    pilot:
+      k8s:
+        hpaSpec:
+          metrics:
+          - type: Resource
+            resource:
+              name: cpu
+              targetAverageUtilization: 60
+          behavior:
+            scaleDown:
+              stabilizationWindowSeconds: 600
+`,
+			wantErr: true,
+		},
+		{
+			desc: "legacy and autoscaling/v2 metrics",
+			in: `
+spec:
+  components:
+    egressGateways:
+    - name: istio-egressgateway
+      k8s:
+        hpaSpec:
+          metrics:
+          - type: Resource
+            resource:
+              name: cpu
+              targetAverageUtilization: 60
+          - type: ContainerResource
+            containerResource:
+              name: memory
+              container: istio-proxy
+              target:
+                type: Utilization
+                averageUtilization: 75
+`,
+			wantErr: true,
+		},
+		{
+			desc: "legacy metrics with autoscaling/v2 values",
+			in: `
+spec:
+  components:
+    pilot:
+      k8s:
+        hpaSpec:
+          metrics:
+          - type: Resource
+            resource:
+              name: cpu
+              targetAverageUtilization: 60
+  values:
+    pilot:
+      autoscaleBehavior:
+        scaleDown:
+          stabilizationWindowSeconds: 600
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := TranslateK8sHPASpecs(tt.in)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("TranslateK8sHPASpecs: got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !util.IsYAMLEqual(got, tt.want) {
+				t.Errorf("TranslateK8sHPASpecs: got:\n%s\nwant:\n%s\ndiff:\n%s", got, tt.want, util.YAMLDiff(got, tt.want))
+			}
+		})
+	}
+}
@@ -61,3 +61,20 @@ func DetectSupportedJWTPolicy(config *rest.Config) (JWTPolicy, error) {
 	}
 	return FirstPartyJWT, nil
 }
+
+// DetectKubernetesVersion queries the api-server for its version, e.g. "v1.21.3". It returns an empty string
+// if config is nil.
+func DetectKubernetesVersion(config *rest.Config) (string, error) {
+	if config == nil {
+		return "", nil
+	}
+	d, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return "", err
+	}
+	v, err := d.ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	return v.GitVersion, nil
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ghodss/yaml"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/version"
 
 	"istio.io/istio/operator/pkg/apis/istio/v1alpha1"
 	"istio.io/istio/operator/pkg/util"
@@ -120,6 +121,38 @@ func validateStringList(vf ValidatorFunc) ValidatorFunc {
 	}
 }
 
+// validateKubernetesVersion checks if val is a string with a Kubernetes version, e.g. "v1.21".
+func validateKubernetesVersion(path util.Path, val interface{}) util.Errors {
+	if !util.IsString(val) {
+		return util.NewErrs(fmt.Errorf("validateKubernetesVersion(%s) bad type %T, want string", path, val))
+	}
+	if val.(string) == "" {
+		return nil
+	}
+	if _, err := version.ParseGeneric(val.(string)); err != nil {
+		return util.NewErrs(fmt.Errorf("%s : invalid Kubernetes version %q: %s", path, val, err))
+	}
+	return nil
+}
+
+// validateStringEnum returns a validator function checking that val is empty or one of the allowed strings.
+func validateStringEnum(allowed ...string) ValidatorFunc {
+	return func(path util.Path, val interface{}) util.Errors {
+		if !util.IsString(val) {
+			return util.NewErrs(fmt.Errorf("validateStringEnum(%s) bad type %T, want string", path, val))
+		}
+		if val.(string) == "" {
+			return nil
+		}
+		for _, a := range allowed {
+			if val.(string) == a {
+				return nil
+			}
+		}
+		return util.NewErrs(fmt.Errorf("%s : invalid value %q, must be one of %v", path, val, allowed))
+	}
+}
+
 // validatePortNumberString checks if val is a string with a valid port number.
 func validatePortNumberString(path util.Path, val interface{}) util.Errors {
 	scope.Debugf("validatePortNumberString %v:", val)
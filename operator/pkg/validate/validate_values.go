@@ -15,6 +15,11 @@
 package validate
 
 import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
 	"github.com/ghodss/yaml"
 
 	"istio.io/istio/operator/pkg/apis/istio/v1alpha1"
@@ -24,25 +29,105 @@ import (
 var (
 	// DefaultValuesValidations maps a data path to a validation function.
 	DefaultValuesValidations = map[string]ValidatorFunc{
-		"global.proxy.includeIPRanges":     validateIPRangesOrStar,
-		"global.proxy.excludeIPRanges":     validateIPRangesOrStar,
-		"global.proxy.includeInboundPorts": validateStringList(validatePortNumberString),
-		"global.proxy.excludeInboundPorts": validateStringList(validatePortNumberString),
-		"meshConfig":                       validateMeshConfig,
+		"global.proxy.includeIPRanges":              validateIPRangesOrStar,
+		"global.proxy.excludeIPRanges":              validateIPRangesOrStar,
+		"global.proxy.includeInboundPorts":          validateStringList(validatePortNumberString),
+		"global.proxy.excludeInboundPorts":          validateStringList(validatePortNumberString),
+		"global.kubernetesVersion":                  validateKubernetesVersion,
+		"meshConfig":                                validateMeshConfig,
+		"sidecarInjectorWebhook.failurePolicy":      validateStringEnum("Fail", "Ignore"),
+		"sidecarInjectorWebhook.reinvocationPolicy": validateStringEnum("Never", "IfNeeded"),
+	}
+
+	// FreeFormValuesKeys are values keys whose subtree is passed through to the charts or to k8s resources as is,
+	// so its content is not checked against the values schema wherever the key appears.
+	FreeFormValuesKeys = map[string]bool{
+		"meshConfig":                       true,
+		"podAnnotations":                   true,
+		"env":                              true,
+		"nodeSelector":                     true,
+		"tolerations":                      true,
+		"readinessProbe":                   true,
+		"lifecycle":                        true,
+		"podAntiAffinityLabelSelector":     true,
+		"podAntiAffinityTermLabelSelector": true,
+	}
+
+	valuesType           = reflect.TypeOf(v1alpha1.Values{})
+	intOrStringForPBType = reflect.TypeOf(v1alpha1.IntOrStringForPB{})
+	gatewaysConfigType   = reflect.TypeOf(v1alpha1.GatewaysConfig{})
+	gatewayConfigTypes   = []reflect.Type{
+		reflect.TypeOf(v1alpha1.IngressGatewayConfig{}),
+		reflect.TypeOf(v1alpha1.EgressGatewayConfig{}),
 	}
 )
 
-// CheckValues validates the values in the given tree, which follows the Istio values.yaml schema.
+// CheckValues validates the values in the given tree, which follows the Istio values.yaml schema. Fields which are
+// not part of the schema are ignored here, CheckValuesSchema reports them.
 func CheckValues(root interface{}) util.Errors {
 	vs, err := yaml.Marshal(root)
 	if err != nil {
 		return util.Errors{err}
 	}
+	vs, gwValues, err := splitGatewayValues(vs)
+	if err != nil {
+		return util.Errors{err}
+	}
 	val := &v1alpha1.Values{}
-	if err := util.UnmarshalValuesWithJSONPB(string(vs), val, false); err != nil {
+	if err := util.UnmarshalValuesWithJSONPB(string(vs), val, true); err != nil {
 		return util.Errors{err}
 	}
-	return ValuesValidate(DefaultValuesValidations, root, nil)
+	var errs util.Errors
+	for _, gwName := range sortedKeys(gwValues) {
+		errs = util.AppendErr(errs, checkGatewayValues(gwName, gwValues[gwName]))
+	}
+	return util.AppendErrs(errs, ValuesValidate(DefaultValuesValidations, root, nil))
+}
+
+// splitGatewayValues removes the values of the gateways not named after a chart gateway from the values YAML vs
+// and returns them keyed by gateway name, along with the remaining values YAML. These values are overlaid on the
+// values of the chart gateway when rendering the named gateway.
+func splitGatewayValues(vs []byte) ([]byte, map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	if err := yaml.Unmarshal(vs, &tree); err != nil {
+		return nil, nil, err
+	}
+	gateways, ok := tree["gateways"].(map[string]interface{})
+	if !ok {
+		return vs, nil, nil
+	}
+	fields := valuesSchemaFields(gatewaysConfigType)
+	gwValues := make(map[string]interface{})
+	for k, v := range gateways {
+		if _, ok := fields[k]; !ok {
+			gwValues[k] = v
+			delete(gateways, k)
+		}
+	}
+	if len(gwValues) == 0 {
+		return vs, nil, nil
+	}
+	out, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, gwValues, nil
+}
+
+// checkGatewayValues returns an error if the values of gateway gwName are neither valid ingress nor egress
+// gateway values. Like CheckValues, it ignores fields which are not part of the schema.
+func checkGatewayValues(gwName string, node interface{}) error {
+	vs, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	if err := util.UnmarshalValuesWithJSONPB(string(vs), &v1alpha1.IngressGatewayConfig{}, true); err != nil {
+		if util.UnmarshalValuesWithJSONPB(string(vs), &v1alpha1.EgressGatewayConfig{}, true) == nil {
+			return nil
+		}
+		return fmt.Errorf("invalid values for gateway %s: %v", gwName, err)
+	}
+	return nil
 }
 
 // ValuesValidate validates the values of the tree using the supplied Func
@@ -65,3 +150,95 @@ func ValuesValidate(validations map[string]ValidatorFunc, node interface{}, path
 
 	return errs
 }
+
+// CheckValuesSchema returns an error with the full path of every key in the values tree which is not part of the
+// values schema. Free form subtrees, either because of their schema type or their key, are not checked.
+func CheckValuesSchema(values map[string]interface{}) util.Errors {
+	return checkValuesSchema(valuesType, values, util.Path{"values"})
+}
+
+func checkValuesSchema(t reflect.Type, node interface{}, path util.Path) (errs util.Errors) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		// Only the values types are checked, other structs such as wrappers and durations are leaves.
+		if t.PkgPath() != valuesType.PkgPath() || t == intOrStringForPBType {
+			return nil
+		}
+		nn, ok := node.(map[string]interface{})
+		if !ok {
+			// Type mismatches are reported when unmarshalling the values.
+			return nil
+		}
+		fields := valuesSchemaFields(t)
+		for _, k := range sortedKeys(nn) {
+			if FreeFormValuesKeys[k] {
+				continue
+			}
+			childPath := append(path[:len(path):len(path)], k)
+			ft, ok := fields[k]
+			if !ok && t == gatewaysConfigType {
+				// Values of a gateway not named after a chart gateway, see splitGatewayValues.
+				errs = util.AppendErrs(errs, checkGatewayValuesSchema(nn[k], childPath))
+				continue
+			}
+			if !ok {
+				errs = util.AppendErr(errs, fmt.Errorf("unknown field %s", childPath))
+				continue
+			}
+			errs = util.AppendErrs(errs, checkValuesSchema(ft, nn[k], childPath))
+		}
+	case reflect.Slice:
+		nl, ok := node.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, v := range nl {
+			childPath := append(path[:len(path)-1:len(path)-1], indexPathForSlice(path[len(path)-1], i))
+			errs = util.AppendErrs(errs, checkValuesSchema(t.Elem(), v, childPath))
+		}
+	}
+	return errs
+}
+
+// checkGatewayValuesSchema checks the values of a named gateway against the schema of each kind of gateway and
+// returns the errors of the first kind if it matches none of them.
+func checkGatewayValuesSchema(node interface{}, path util.Path) util.Errors {
+	var first util.Errors
+	for i, t := range gatewayConfigTypes {
+		errs := checkValuesSchema(t, node, path)
+		if len(errs) == 0 {
+			return nil
+		}
+		if i == 0 {
+			first = errs
+		}
+	}
+	return first
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valuesSchemaFields returns the types of the fields of the values struct t, keyed by both their proto and
+// JSON names, as jsonpb accepts either.
+func valuesSchemaFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		for _, opt := range strings.Split(f.Tag.Get("protobuf"), ",") {
+			if strings.HasPrefix(opt, "name=") || strings.HasPrefix(opt, "json=") {
+				out[opt[strings.IndexByte(opt, '=')+1:]] = f.Type
+			}
+		}
+	}
+	return out
+}
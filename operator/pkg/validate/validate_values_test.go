@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/ghodss/yaml"
@@ -71,6 +72,44 @@ global:
           command: ["/bin/sh", "-c", "sleep 30"]
 `,
 		},
+		{
+			desc: "KubernetesVersion",
+			yamlStr: `
+global:
+  kubernetesVersion: "v1.21.3-gke.100"
+`,
+		},
+		{
+			desc: "BadKubernetesVersion",
+			yamlStr: `
+global:
+  kubernetesVersion: "latest"
+`,
+			wantErrs: makeErrors([]string{`global.kubernetesVersion : invalid Kubernetes version "latest": could not parse "latest" as version`}),
+		},
+		{
+			desc: "WebhookPolicies",
+			yamlStr: `
+sidecarInjectorWebhook:
+  failurePolicy: Ignore
+  reinvocationPolicy: IfNeeded
+  namespaceSelectorMatchExpressions:
+  - key: team
+    operator: NotIn
+    values:
+    - infra
+`,
+		},
+		{
+			desc: "BadWebhookPolicies",
+			yamlStr: `
+sidecarInjectorWebhook:
+  failurePolicy: ignore
+  reinvocationPolicy: Always
+`,
+			wantErrs: makeErrors([]string{`sidecarInjectorWebhook.failurePolicy : invalid value "ignore", must be one of [Fail Ignore]`,
+				`sidecarInjectorWebhook.reinvocationPolicy : invalid value "Always", must be one of [Never IfNeeded]`}),
+		},
 		{
 			desc: "CNIConfig",
 			yamlStr: `
@@ -137,7 +176,6 @@ global:
   proxy:
     foo: "bar"
 `,
-			wantErrs: makeErrors([]string{`unknown field "foo" in v1alpha1.ProxyConfig`}),
 		},
 		{
 			desc: "unknown field",
@@ -145,7 +183,37 @@ global:
 cni:
   foo: "bar"
 `,
-			wantErrs: makeErrors([]string{`unknown field "foo" in v1alpha1.CNIConfig`}),
+		},
+		{
+			desc: "named gateway values",
+			yamlStr: `
+gateways:
+  istio-ingressgateway:
+    concurrency: 2
+  user-ingressgateway:
+    concurrency: 4
+    resources:
+      requests:
+        cpu: 444m
+`,
+		},
+		{
+			desc: "unknown field in named gateway values",
+			yamlStr: `
+gateways:
+  user-ingressgateway:
+    concurency: 4
+`,
+		},
+		{
+			desc: "bad type in named gateway values",
+			yamlStr: `
+gateways:
+  user-ingressgateway:
+    autoscaleEnabled: sometimes
+`,
+			wantErrs: makeErrors([]string{`invalid values for gateway user-ingressgateway: ` +
+				`json: cannot unmarshal string into Go value of type bool`}),
 		},
 	}
 
@@ -240,3 +308,91 @@ func makeErrors(estr []string) util.Errors {
 func yamlFileFilter(path string) bool {
 	return filepath.Base(path) == "values.yaml"
 }
+
+func TestCheckValuesSchema(t *testing.T) {
+	tests := []struct {
+		desc     string
+		yamlStr  string
+		wantErrs []string
+	}{
+		{
+			desc: "known fields",
+			yamlStr: `
+global:
+  proxy:
+    image: proxyv2
+  istioNamespace: istio-system
+gateways:
+  istio-ingressgateway:
+    ports:
+    - port: 80
+      name: http
+  istio_egressgateway:
+    enabled: true
+`,
+		},
+		{
+			desc: "misspelled field",
+			yamlStr: `
+global:
+  proxyv2:
+    image: proxyv2
+  proxy:
+    imag: proxyv2
+`,
+			wantErrs: []string{"unknown field values.global.proxy.imag", "unknown field values.global.proxyv2"},
+		},
+		{
+			desc: "unknown field in list",
+			yamlStr: `
+gateways:
+  istio-ingressgateway:
+    ports:
+    - port: 80
+      nmae: http
+`,
+			wantErrs: []string{"unknown field values.gateways.istio-ingressgateway.ports[0].nmae"},
+		},
+		{
+			desc: "named gateway values",
+			yamlStr: `
+gateways:
+  user-ingressgateway:
+    concurrency: 4
+  user-egressgateway:
+    concurency: 2
+`,
+			wantErrs: []string{"unknown field values.gateways.user-egressgateway.concurency"},
+		},
+		{
+			desc: "free form fields",
+			yamlStr: `
+meshConfig:
+  anything: goes
+pilot:
+  podAnnotations:
+    foo.example.com/bar: baz
+  nodeSelector:
+    disktype: ssd
+sidecarInjectorWebhook:
+  injectedAnnotations:
+    foo: bar
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := make(map[string]interface{})
+			if err := yaml.Unmarshal([]byte(tt.yamlStr), &root); err != nil {
+				t.Fatal(err)
+			}
+			var gotErrs []string
+			for _, err := range CheckValuesSchema(root) {
+				gotErrs = append(gotErrs, err.Error())
+			}
+			if !reflect.DeepEqual(gotErrs, tt.wantErrs) {
+				t.Errorf("CheckValuesSchema(%s): got %v, want %v", tt.desc, gotErrs, tt.wantErrs)
+			}
+		})
+	}
+}
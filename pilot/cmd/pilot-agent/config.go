@@ -20,16 +20,20 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gogo/protobuf/types"
 
 	"istio.io/api/annotation"
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/util/network"
 	"istio.io/istio/pkg/bootstrap"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/validation"
+	istio_agent "istio.io/istio/pkg/istio-agent"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
 	"istio.io/pkg/log"
 )
 
@@ -73,6 +77,49 @@ func constructProxyConfig() (meshconfig.ProxyConfig, error) {
 	return applyAnnotations(proxyConfig, annotations), nil
 }
 
+// terminationDrainDuration returns the drain duration configured for the proxy: the one pushed by istiod if any,
+// otherwise the one of the proxy config, re-read so that changes to the mounted mesh config and annotations since
+// startup are honored. The proxy config the agent started with is used if it can no longer be read.
+func terminationDrainDuration(startupConfig meshconfig.ProxyConfig, pushed *istio_agent.DynamicProxyConfig) time.Duration {
+	if ds, f := features.TerminationDrainDuration.Lookup(); f {
+		// Legacy environment variable is set, use that instead
+		return time.Second * time.Duration(ds)
+	}
+	if drainDuration, f := pushed.TerminationDrainDuration(); f {
+		return drainDuration
+	}
+	proxyConfig, err := constructProxyConfig()
+	if err != nil {
+		log.Warnf("failed to read proxy config, using termination drain duration from startup: %v", err)
+		proxyConfig = startupConfig
+	}
+	drainDuration, _ := types.DurationFromProto(proxyConfig.TerminationDrainDuration)
+	return drainDuration
+}
+
+// gatewayTopologyAnnotated returns whether the proxy.istio.io/config annotation of the pod sets the gateway topology,
+// which istiod then prefers to the proxy config it resolves.
+func gatewayTopologyAnnotated() bool {
+	annotations, err := readPodAnnotations()
+	if err != nil {
+		return false
+	}
+	return annotatesGatewayTopology(annotations[annotation.ProxyConfig.Name])
+}
+
+// annotatesGatewayTopology returns whether the proxy config of the proxy.istio.io/config annotation sets the gateway
+// topology.
+func annotatesGatewayTopology(proxyConfig string) bool {
+	if proxyConfig == "" {
+		return false
+	}
+	pc := &meshconfig.ProxyConfig{}
+	if err := gogoprotomarshal.ApplyYAML(proxyConfig, pc); err != nil {
+		return false
+	}
+	return pc.GatewayTopology != nil
+}
+
 // getMeshConfig gets the mesh config to use for proxy configuration
 // 1. First we take the default config
 // 2. Then we apply any settings from file (this comes from gateway mounting configmap)
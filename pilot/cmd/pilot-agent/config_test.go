@@ -120,3 +120,24 @@ extraStatTags: ["b"]
 		})
 	}
 }
+
+func TestAnnotatesGatewayTopology(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		want       bool
+	}{
+		{"no annotation", "", false},
+		{"other fields", "drainDuration: 1s", false},
+		{"topology", "gatewayTopology:\n  numTrustedProxies: 2", true},
+		{"json", `{"gatewayTopology":{"forwardClientCertDetails":"FORWARD_ONLY"}}`, true},
+		{"invalid", "gatewayTopology: [", false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := annotatesGatewayTopology(tt.annotation); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
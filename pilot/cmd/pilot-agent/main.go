@@ -19,26 +19,33 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/gogo/protobuf/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 	"google.golang.org/grpc/grpclog"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
-	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
+	"istio.io/istio/pilot/cmd/pilot-agent/systemd"
 	"istio.io/istio/pilot/pkg/model"
 	securityModel "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/util/network"
+	"istio.io/istio/pkg/bootstrap"
 	"istio.io/istio/pkg/cmd"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/envoy"
 	istio_agent "istio.io/istio/pkg/istio-agent"
+	"istio.io/istio/pkg/istio-agent/health"
 	"istio.io/istio/pkg/jwt"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
@@ -65,6 +72,7 @@ var (
 	role               = &model.Proxy{}
 	proxyIP            string
 	registryID         serviceregistry.ProviderID
+	clusterDomain      string
 	trustDomain        string
 	stsPort            int
 	tokenManagerPlugin string
@@ -79,6 +87,7 @@ var (
 	templateFile           string
 	loggingOptions         = log.DefaultOptions()
 	outlierLogPath         string
+	pidFile                string
 
 	instanceIPVar        = env.RegisterStringVar("INSTANCE_IP", "", "")
 	podNameVar           = env.RegisterStringVar("POD_NAME", "", "")
@@ -86,6 +95,23 @@ var (
 	kubeAppProberNameVar = env.RegisterStringVar(status.KubeAppProberEnvName, "", "")
 	clusterIDVar         = env.RegisterStringVar("ISTIO_META_CLUSTER_ID", "", "")
 	callCredentials      = env.RegisterBoolVar("CALL_CREDENTIALS", false, "Use JWT directly instead of MTLS")
+	loadStatsReporting   = env.RegisterBoolVar("ISTIO_LOAD_STATS_REPORTING", false,
+		"If enabled, Envoy reports upstream load stats to istiod over the XDS connection using the Load Reporting Service. "+
+			"Not supported with CALL_CREDENTIALS.")
+
+	exitOnZeroActiveConnections = env.RegisterBoolVar("EXIT_ON_ZERO_ACTIVE_CONNECTIONS", false,
+		"When set to true, terminates the proxy as soon as it has no active downstream connections while draining, "+
+			"rather than waiting for the whole termination drain duration.")
+
+	proxyMaxRestarts = env.RegisterIntVar("PROXY_CRASH_LOOP_MAX_RESTARTS", 0,
+		"The number of times the proxy is restarted after exiting with an error within PROXY_CRASH_LOOP_WINDOW, "+
+			"before it is considered crash looping and the agent stops restarting it. When 0, the agent exits "+
+			"with the proxy instead.")
+	proxyCrashLoopWindow = env.RegisterDurationVar("PROXY_CRASH_LOOP_WINDOW", 5*time.Minute,
+		"The window within which proxy restarts are counted to detect a crash loop.")
+	proxyCrashLoopDiagnostics = env.RegisterStringVar("PROXY_CRASH_LOOP_DIAGNOSTICS_FILE", "",
+		"The file the last lines of the proxy standard error are written to when it is crash looping. "+
+			"Defaults to envoy-crashloop.log in the proxy config path.")
 
 	pilotCertProvider = env.RegisterStringVar("PILOT_CERT_PROVIDER", "istiod",
 		"The provider of Pilot DNS certificate.").Get()
@@ -116,7 +142,14 @@ var (
 
 	caProviderEnv = env.RegisterStringVar("CA_PROVIDER", "Citadel", "name of authentication provider").Get()
 	// TODO: default to same as discovery address
-	caEndpointEnv = env.RegisterStringVar("CA_ADDR", "", "Address of the spiffee certificate provider. Defaults to discoveryAddress").Get()
+	caEndpointEnv  = env.RegisterStringVar("CA_ADDR", "", "Address of the spiffee certificate provider. Defaults to discoveryAddress").Get()
+	caEndpointsEnv = env.RegisterStringVar("CA_ENDPOINTS", "",
+		"JSON list of certificate providers to fail over between, in order of preference, for example "+
+			`[{"address": "ca.example.com:443", "provider": "Custom", "rootCert": "/etc/ca/root-cert.pem", "timeout": "5s"}, `+
+			`{"address": "istiod.istio-system.svc:15012"}]. If set, CA_ADDR and CA_PROVIDER are ignored.`).Get()
+	caPrimaryReprobeIntervalEnv = env.RegisterDurationVar("CA_PRIMARY_REPROBE_INTERVAL", 5*time.Minute,
+		"How long certificates are requested from a fallback provider in CA_ENDPOINTS before the first "+
+			"provider is tried again.").Get()
 
 	// TODO: this is a horribly named env, it's really TOKEN_EXCHANGE_PLUGINS - but to avoid breaking
 	// it's left unchanged. It may not be needed because we autodetect.
@@ -137,6 +170,15 @@ var (
 		"The grace period ratio for the cert rotation, by default 0.5.").Get()
 	secretRotationIntervalEnv = env.RegisterDurationVar("SECRET_ROTATION_CHECK_INTERVAL", 5*time.Minute,
 		"The ticker to detect and rotate the certificates, by default 5 minutes").Get()
+	certNotBeforeToleranceEnv = env.RegisterDurationVar("CERT_NOT_BEFORE_TOLERANCE", 60*time.Second,
+		"How far in the future the NotBefore of a certificate issued by the CA may be, because of clock skew, "+
+			"for the certificate to be used").Get()
+	cachedCertsStartTimeoutEnv = env.RegisterDurationVar("CACHED_CERTS_START_TIMEOUT", 0,
+		"If set, and the CA does not respond within this time at startup, the proxy is started in degraded mode "+
+			"with the valid certificate cached in OUTPUT_CERTS or provisioned in PROV_CERT, and the CA is retried "+
+			"in the background. Expired certificates are never used. Only the certificate is cached: the Envoy "+
+			"bootstrap is generated locally at each start, and the XDS configuration is received once istiod is "+
+			"reachable. Disabled by default.").Get()
 	staledConnectionRecycleIntervalEnv = env.RegisterDurationVar("STALED_CONNECTION_RECYCLE_RUN_INTERVAL", 5*time.Minute,
 		"The ticker to detect and close stale connections").Get()
 	initialBackoffInMilliSecEnv = env.RegisterIntVar("INITIAL_BACKOFF_MSEC", 0, "").Get()
@@ -156,6 +198,28 @@ var (
 	// This is a copy of the env var in the init code.
 	dnsCaptureByAgent = env.RegisterStringVar("ISTIO_META_DNS_CAPTURE", "",
 		"If set, enable the capture of outgoing DNS packets on port 53, redirecting to istio-agent on :15053")
+	dnsConfigFile = env.RegisterStringVar("ISTIO_META_DNS_CONFIG_FILE", "",
+		"Path to a file with static entries and forward zones for the agent DNS server, "+
+			"reloaded on change. Only used if ISTIO_META_DNS_CAPTURE is set")
+	xdsCompression = env.RegisterBoolVar("ISTIO_XDS_COMPRESSION", false,
+		"If enabled, the XDS requests of the agent are compressed with gzip, for istiod to compress its large responses "+
+			"if PILOT_XDS_COMPRESSION_THRESHOLD is set, which it must be. Only used if ISTIO_META_PROXY_XDS_VIA_AGENT is set")
+	routerStatusPort = env.RegisterIntVar("ROUTER_STATUS_PORT", 0,
+		"The port of the status server in router mode, overriding the status port of the proxy config. Envoy "+
+			"forwards the readiness probes it receives on port 15021 to it.")
+	routerReadinessPath = env.RegisterStringVar("ROUTER_READINESS_PATH", "/healthz/ready",
+		"The path of the readiness probe of the status server in router mode, also served by Envoy on port 15021.")
+	routerDisableAppProbers = env.RegisterBoolVar("ROUTER_DISABLE_APP_PROBERS", false,
+		"If enabled in router mode, the status server does not serve the application probes rewritten by the "+
+			"injector, as gateways have no application.")
+	routerHealthCheckPort = env.RegisterIntVar("ROUTER_HEALTH_CHECK_PORT", 0,
+		"If set in router mode, the readiness of the gateway is also served on this port, for the health checks of "+
+			"cloud load balancers. It additionally requires listeners for ROUTER_HEALTH_CHECK_LISTENER_PORTS.")
+	routerHealthCheckPath = env.RegisterStringVar("ROUTER_HEALTH_CHECK_PATH", "/healthz/ready",
+		"The path of the health check served on ROUTER_HEALTH_CHECK_PORT.")
+	routerHealthCheckListenerPorts = env.RegisterStringVar("ROUTER_HEALTH_CHECK_LISTENER_PORTS", "",
+		"Comma separated ports declared by the gateway, which Envoy must have listeners for to pass the health "+
+			"check served on ROUTER_HEALTH_CHECK_PORT.")
 
 	rootCmd = &cobra.Command{
 		Use:          "pilot-agent",
@@ -188,6 +252,13 @@ var (
 			podIP := net.ParseIP(instanceIPVar.Get()) // protobuf encoding of IP_ADDRESS type
 
 			log.Infof("Version %s", version.Info.String())
+			if pidFile != "" {
+				removePIDFile, err := systemd.WritePIDFile(pidFile)
+				if err != nil {
+					return err
+				}
+				defer removePIDFile()
+			}
 			role.Type = model.SidecarProxy
 			if len(args) > 0 {
 				role.Type = model.NodeType(args[0])
@@ -225,15 +296,15 @@ var (
 				role.IPAddresses = append(role.IPAddresses, "::1")
 			}
 
-			// Check if proxy runs in ipv4 or ipv6 environment to set Envoy's
+			// Check which IP families the proxy runs with to set Envoy's
 			// operational parameters correctly.
-			proxyIPv6 := isIPv6Proxy(role.IPAddresses)
+			ipFamilies := detectIPFamilies(role.IPAddresses)
+			log.Infof("Proxy IP families: ipv4=%v ipv6=%v, primary %s", ipFamilies.ipv4, ipFamilies.ipv6, ipFamilies.primary)
+			if err := validateRegistryFlags(podNamespace, role.DNSDomain); err != nil {
+				return err
+			}
 			if len(role.ID) == 0 {
-				if registryID == serviceregistry.Kubernetes {
-					role.ID = podName + "." + podNamespace
-				} else {
-					role.ID = role.IPAddresses[0]
-				}
+				role.ID = getProxyID(podName, podNamespace, role.IPAddresses)
 			}
 
 			proxyConfig, err := constructProxyConfig()
@@ -283,6 +354,10 @@ var (
 
 			secOpts.EnableGatewaySDS = enableGatewaySDSEnv
 			secOpts.CAProviderName = caProviderEnv
+			if secOpts.CAEndpoints, err = security.ParseCAEndpoints(caEndpointsEnv); err != nil {
+				return err
+			}
+			secOpts.CAPrimaryReprobeInterval = caPrimaryReprobeIntervalEnv
 
 			// TODO: extract from ProxyConfig
 			secOpts.TrustDomain = trustDomainEnv
@@ -293,6 +368,8 @@ var (
 			secOpts.SecretTTL = secretTTLEnv
 			secOpts.SecretRotationGracePeriodRatio = secretRotationGracePeriodRatioEnv
 			secOpts.RotationInterval = secretRotationIntervalEnv
+			secOpts.CertNotBeforeTolerance = certNotBeforeToleranceEnv
+			secOpts.CachedCertsStartTimeout = cachedCertsStartTimeoutEnv
 			secOpts.InitialBackoffInMilliSec = int64(initialBackoffInMilliSecEnv)
 			// Disable the secret eviction for istio agent.
 			secOpts.EvictionDuration = 0
@@ -317,9 +394,17 @@ var (
 				agentConfig.ProxyXDSViaAgent = true
 				if dnsCaptureByAgent.Get() != "" {
 					agentConfig.DNSCapture = true
+					agentConfig.DNSConfigFile = dnsConfigFile.Get()
 				}
+				agentConfig.XDSCompression = xdsCompression.Get()
 				agentConfig.ProxyNamespace = podNamespace
 				agentConfig.ProxyDomain = role.DNSDomain
+				// The probe of the WorkloadGroup of the workload, from the mesh config of its onboarding bundle.
+				if probe := proxyConfig.ProxyMetadata[health.ProbeProxyMetadata]; probe != "" {
+					if agentConfig.HealthProbe, err = health.ParseProbe(probe); err != nil {
+						return fmt.Errorf("failed to parse the health probe of the proxy config: %v", err)
+					}
+				}
 			}
 			sa := istio_agent.NewAgent(&proxyConfig, agentConfig, secOpts)
 
@@ -344,33 +429,37 @@ var (
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			// If a status port was provided, start handling status probes.
-			if proxyConfig.StatusPort > 0 {
-				if err := initStatusServer(ctx, proxyIPv6, proxyConfig); err != nil {
-					return err
-				}
-			}
-
 			// If security token service (STS) port is not zero, start STS server and
 			// listen on STS port for STS requests. For STS, see
 			// https://tools.ietf.org/html/draft-ietf-oauth-token-exchange-16.
+			// The bootstrap refers to the STS server as localhost, which may resolve to either loopback address
+			// on a dual-stack pod, so it listens on both.
 			if stsPort > 0 {
-				localHostAddr := localHostIPv4
-				if proxyIPv6 {
-					localHostAddr = localHostIPv6
-				}
 				tokenManager := tokenmanager.CreateTokenManager(tokenManagerPlugin,
 					tokenmanager.Config{CredFetcher: secOpts.CredFetcher, TrustDomain: secOpts.TrustDomain})
-				stsServer, err := stsserver.NewServer(stsserver.Config{
-					LocalHostAddr: localHostAddr,
-					LocalPort:     stsPort,
-				}, tokenManager)
-				if err != nil {
-					return err
+				for i, localHostAddr := range ipFamilies.localHostAddrs() {
+					stsServer, err := stsserver.NewServer(stsserver.Config{
+						LocalHostAddr: localHostAddr,
+						LocalPort:     stsPort,
+					}, tokenManager)
+					if err != nil {
+						if i == 0 {
+							return err
+						}
+						// the loopback of the secondary family may be unavailable, e.g. IPv6 disabled in the kernel.
+						log.Warnf("Failed to start STS server on %s: %v", localHostAddr, err)
+						continue
+					}
+					defer stsServer.Stop()
 				}
-				defer stsServer.Stop()
 			}
 
+			statusConfig, err := statusServerConfig(ipFamilies, proxyConfig)
+			if err != nil {
+				return err
+			}
+
+			rootCA := sa.FindRootCAForXDS()
 			envoyProxy := envoy.NewProxy(envoy.ProxyConfig{
 				Config:              proxyConfig,
 				Node:                role.ServiceNode(),
@@ -381,45 +470,126 @@ var (
 				STSPort:             stsPort,
 				OutlierLogPath:      outlierLogPath,
 				PilotCertProvider:   pilotCertProvider,
-				ProvCert:            sa.FindRootCAForXDS(),
+				ProvCert:            rootCA,
 				Sidecar:             role.Type == model.SidecarProxy,
 				ProxyViaAgent:       agentConfig.ProxyXDSViaAgent,
 				CallCredentials:     callCredentials.Get(),
+				IPFamily:            ipFamilies.primary,
+				LoadStatsReporting:  loadStatsReporting.Get(),
+				// The topology of an annotated gateway takes precedence over the proxy config resolved by istiod.
+				GatewayTopologyAnnotated: gatewayTopologyAnnotated(),
+				// Envoy forwards the readiness probes it receives on its readiness port to the status server.
+				StatusPort:    int(statusConfig.StatusPort),
+				ReadinessPath: statusConfig.ReadyPath,
 			})
 
-			drainDuration, _ := types.DurationFromProto(proxyConfig.TerminationDrainDuration)
-			if ds, f := features.TerminationDrainDuration.Lookup(); f {
-				// Legacy environment variable is set, us that instead
-				drainDuration = time.Second * time.Duration(ds)
+			agent := envoy.NewAgent(envoyProxy, envoy.DrainPolicy{
+				Duration: func() time.Duration {
+					return terminationDrainDuration(proxyConfig, sa.DynamicProxyConfig())
+				},
+				ExitOnIdle: exitOnZeroActiveConnections.Get(),
+				AdminPort:  uint32(proxyConfig.ProxyAdminPort),
+			}, proxyRestartPolicy(proxyConfig))
+
+			statusConfig.Restarter = agent
+			// If a status port was provided, start handling status probes.
+			if statusConfig.StatusPort > 0 {
+				if err := initStatusServer(ctx, statusConfig); err != nil {
+					return err
+				}
 			}
 
-			agent := envoy.NewAgent(envoyProxy, drainDuration)
+			// On VMs the root CA is provisioned as a file that Envoy only reads at startup, from the bootstrap,
+			// so restart Envoy when it is rotated.
+			var certs []string
+			if provCert != "" {
+				certs = append(certs, rootCA)
+			}
 
 			// Watcher is also kicking envoy start.
-			watcher := envoy.NewWatcher(agent.Restart)
+			watcher := envoy.NewWatcher(certs, agent.Restart)
 			go watcher.Run(ctx)
 
+			// When run by systemd, notify it once Envoy is ready, and send the watchdog heartbeats.
+			notifier := systemd.NewNotifierFromEnv()
+			go systemd.Run(ctx, systemdConfig(notifier, statusConfig, secOpts.WorkloadUDSPath))
+
 			// On SIGINT or SIGTERM, cancel the context, triggering a graceful shutdown
-			go cmd.WaitSignalFunc(cancel)
+			go cmd.WaitSignalFunc(func() {
+				// Notify systemd before Envoy starts draining.
+				if err := notifier.Notify(systemd.Stopping); err != nil {
+					log.Warnf("Failed to notify systemd of shutdown: %v", err)
+				}
+				cancel()
+			})
+			// On SIGUSR2, resume restarting a crash looping proxy
+			go waitResumeSignal(ctx, agent)
 
 			return agent.Run(ctx)
 		},
 	}
 )
 
-func initStatusServer(ctx context.Context, proxyIPv6 bool, proxyConfig meshconfig.ProxyConfig) error {
-	localHostAddr := localHostIPv4
-	if proxyIPv6 {
-		localHostAddr = localHostIPv6
-	}
-	prober := kubeAppProberNameVar.Get()
-	statusServer, err := status.NewServer(status.Config{
-		LocalHostAddr:  localHostAddr,
+// statusServerConfig returns the config of the status server. It listens on all addresses, and reaches Envoy's
+// admin port on the localhost address of the primary IP family, which is where Envoy binds it. In router mode, the
+// ports and paths of the probes can be customized; the readiness probe is also set up in the Envoy bootstrap, and
+// the ports must not conflict with the readiness port of Envoy.
+func statusServerConfig(ipFamilies proxyIPFamilies, proxyConfig meshconfig.ProxyConfig) (status.Config, error) {
+	cfg := status.Config{
+		LocalHostAddr:  ipFamilies.localHostAddrs()[0],
 		AdminPort:      uint16(proxyConfig.ProxyAdminPort),
 		StatusPort:     uint16(proxyConfig.StatusPort),
-		KubeAppProbers: prober,
+		KubeAppProbers: kubeAppProberNameVar.Get(),
 		NodeType:       role.Type,
-	})
+	}
+	if role.Type != model.Router {
+		return cfg, nil
+	}
+	if port := routerStatusPort.Get(); port > 0 {
+		cfg.StatusPort = uint16(port)
+	}
+	cfg.ReadyPath = routerReadinessPath.Get()
+	cfg.DisableAppProbers = routerDisableAppProbers.Get()
+	cfg.HealthCheckPort = uint16(routerHealthCheckPort.Get())
+	cfg.HealthCheckPath = routerHealthCheckPath.Get()
+	ports, err := parseListenerPorts(routerHealthCheckListenerPorts.Get())
+	if err != nil {
+		return status.Config{}, fmt.Errorf("invalid %s: %v", routerHealthCheckListenerPorts.Name, err)
+	}
+	cfg.HealthCheckListenerPorts = ports
+	if cfg.StatusPort == bootstrap.EnvoyReadinessPort {
+		return status.Config{}, fmt.Errorf("invalid %s: %d is the readiness port of Envoy", routerStatusPort.Name, cfg.StatusPort)
+	}
+	if cfg.HealthCheckPort == bootstrap.EnvoyReadinessPort {
+		return status.Config{}, fmt.Errorf("invalid %s: %d is the readiness port of Envoy",
+			routerHealthCheckPort.Name, cfg.HealthCheckPort)
+	}
+	if !strings.HasPrefix(cfg.ReadyPath, "/") {
+		return status.Config{}, fmt.Errorf("invalid %s %q: it must start with /", routerReadinessPath.Name, cfg.ReadyPath)
+	}
+	return cfg, nil
+}
+
+// parseListenerPorts parses comma separated ports.
+func parseListenerPorts(s string) ([]uint32, error) {
+	var ports []uint32
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+		ports = append(ports, uint32(port))
+	}
+	return ports, nil
+}
+
+// initStatusServer starts the status server.
+func initStatusServer(ctx context.Context, cfg status.Config) error {
+	statusServer, err := status.NewServer(cfg)
 	if err != nil {
 		return err
 	}
@@ -427,15 +597,121 @@ func initStatusServer(ctx context.Context, proxyIPv6 bool, proxyConfig meshconfi
 	return nil
 }
 
-func getDNSDomain(podNamespace, domain string) string {
-	if len(domain) == 0 {
-		if registryID == serviceregistry.Kubernetes {
-			domain = podNamespace + ".svc." + constants.DefaultKubernetesDomain
-		} else {
-			domain = ""
+// systemdConfig returns the config of the notifications sent to systemd. The agent is ready once Envoy is
+// live, the in-process SDS server being started before Envoy, and healthy while the status and SDS servers
+// are responsive.
+func systemdConfig(notifier *systemd.Notifier, statusConfig status.Config, sdsPath string) systemd.Config {
+	probe := &ready.Probe{
+		LocalHostAddr: statusConfig.LocalHostAddr,
+		AdminPort:     statusConfig.AdminPort,
+		NodeType:      role.Type,
+	}
+	checks := []systemd.Check{func() error {
+		conn, err := net.DialTimeout("unix", sdsPath, time.Second)
+		if err != nil {
+			return fmt.Errorf("SDS server is not responsive: %v", err)
+		}
+		return conn.Close()
+	}}
+	if statusConfig.StatusPort > 0 {
+		readyPath := statusConfig.ReadyPath
+		if readyPath == "" {
+			readyPath = "/healthz/ready"
+		}
+		client := &http.Client{Timeout: time.Second}
+		healthURL := fmt.Sprintf("http://%s:%d%s", statusConfig.LocalHostAddr, statusConfig.StatusPort, readyPath)
+		checks = append(checks, func() error {
+			// Any response will do, the readiness of Envoy is not a concern of the watchdog.
+			resp, err := client.Get(healthURL)
+			if err != nil {
+				return fmt.Errorf("status server is not responsive: %v", err)
+			}
+			return resp.Body.Close()
+		})
+	}
+	return systemd.Config{
+		Notifier:         notifier,
+		ReadyCheck:       probe.Check,
+		HealthChecks:     checks,
+		WatchdogInterval: systemd.WatchdogInterval(),
+	}
+}
+
+// proxyRestartPolicy returns the policy for restarting Envoy when it exits with an error.
+func proxyRestartPolicy(proxyConfig meshconfig.ProxyConfig) envoy.RestartPolicy {
+	diagnostics := proxyCrashLoopDiagnostics.Get()
+	if diagnostics == "" {
+		diagnostics = path.Join(proxyConfig.ConfigPath, "envoy-crashloop.log")
+	}
+	return envoy.RestartPolicy{
+		MaxRestarts:     proxyMaxRestarts.Get(),
+		Window:          proxyCrashLoopWindow.Get(),
+		DiagnosticsPath: diagnostics,
+	}
+}
+
+// waitResumeSignal resumes restarting a crash looping proxy on every SIGUSR2, until ctx is done.
+func waitResumeSignal(ctx context.Context, agent envoy.Agent) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR2)
+	defer signal.Stop(sigs)
+	for {
+		select {
+		case <-sigs:
+			log.Infof("Received SIGUSR2, resuming proxy restarts")
+			agent.ResumeRestarts()
+		case <-ctx.Done():
+			return
 		}
 	}
-	return domain
+}
+
+// validateRegistryFlags returns an error for cluster domain flags which cannot be honored. A service registry
+// the agent does not support is only warned about.
+func validateRegistryFlags(podNamespace, domain string) error {
+	if registryID != serviceregistry.Kubernetes && registryID != serviceregistry.Mock {
+		// Other registries used to be accepted, and are handled as Mock.
+		log.Warnf("unsupported service registry %q, options are {%s, %s}",
+			registryID, serviceregistry.Kubernetes, serviceregistry.Mock)
+	}
+	if clusterDomain == "" {
+		return nil
+	}
+	if strings.HasPrefix(clusterDomain, ".") || strings.HasSuffix(clusterDomain, ".") {
+		return fmt.Errorf("invalid cluster domain %q: must not start or end with a dot", clusterDomain)
+	}
+	if domain != "" {
+		return fmt.Errorf("--domain %q and --cluster-domain %q are mutually exclusive", domain, clusterDomain)
+	}
+	if podNamespace == "" {
+		return fmt.Errorf("--cluster-domain %q requires the POD_NAMESPACE environment variable to be set", clusterDomain)
+	}
+	return nil
+}
+
+// getProxyID returns the default ID of the proxy, which is the pod name and namespace with the Kubernetes
+// registry, and the first IP address of the proxy otherwise.
+func getProxyID(podName, podNamespace string, ipAddresses []string) string {
+	if registryID == serviceregistry.Kubernetes {
+		return podName + "." + podNamespace
+	}
+	return ipAddresses[0]
+}
+
+// getDNSDomain returns the domain if it is set explicitly. Otherwise names resolve within the pod namespace of
+// the cluster domain, which only defaults to cluster.local with the Kubernetes registry.
+func getDNSDomain(podNamespace, domain string) string {
+	if len(domain) != 0 {
+		return domain
+	}
+	suffix := clusterDomain
+	if suffix == "" && registryID == serviceregistry.Kubernetes {
+		suffix = constants.DefaultKubernetesDomain
+	}
+	if suffix == "" {
+		return ""
+	}
+	return podNamespace + ".svc." + suffix
 }
 
 func init() {
@@ -448,7 +724,10 @@ func init() {
 	proxyCmd.PersistentFlags().StringVar(&role.ID, "id", "",
 		"Proxy unique ID. If not provided uses ${POD_NAME}.${POD_NAMESPACE} from environment variables")
 	proxyCmd.PersistentFlags().StringVar(&role.DNSDomain, "domain", "",
-		"DNS domain suffix. If not provided uses ${POD_NAMESPACE}.svc.cluster.local")
+		"DNS domain suffix. If not provided uses ${POD_NAMESPACE}.svc.<cluster domain>")
+	proxyCmd.PersistentFlags().StringVar(&clusterDomain, "cluster-domain", "",
+		"The DNS domain of the cluster. Defaults to "+constants.DefaultKubernetesDomain+" with the Kubernetes registry, "+
+			"other registries only derive a domain from ${POD_NAMESPACE} if it is set. Cannot be used together with --domain.")
 	proxyCmd.PersistentFlags().StringVar(&trustDomain, "trust-domain", "",
 		"The domain to use for identities")
 
@@ -473,6 +752,8 @@ func init() {
 		"Go template bootstrap config")
 	proxyCmd.PersistentFlags().StringVar(&outlierLogPath, "outlierLogPath", "",
 		"The log path for outlier detection")
+	proxyCmd.PersistentFlags().StringVar(&pidFile, "pid-file", "",
+		"The file the PID of the agent is written to, and removed from on exit")
 
 	// Attach the Istio logging options to the command.
 	loggingOptions.AttachCobraFlags(rootCmd)
@@ -502,19 +783,56 @@ func main() {
 	}
 }
 
-// isIPv6Proxy check the addresses slice and returns true for a valid IPv6 address
-// for all other cases it returns false
-func isIPv6Proxy(ipAddrs []string) bool {
-	for i := 0; i < len(ipAddrs); i++ {
-		addr := net.ParseIP(ipAddrs[i])
+// proxyIPFamilies records the IP families of the proxy addresses.
+type proxyIPFamilies struct {
+	ipv4 bool
+	ipv6 bool
+	// primary is the family of the first address, which is the pod IP given by INSTANCE_IP if set.
+	primary bootstrap.IPFamily
+}
+
+// detectIPFamilies returns the IP families of the addresses. Invalid addresses are ignored.
+func detectIPFamilies(ipAddrs []string) proxyIPFamilies {
+	families := proxyIPFamilies{}
+	for _, ipAddr := range ipAddrs {
+		addr := net.ParseIP(ipAddr)
 		if addr == nil {
 			// Should not happen, invalid IP in proxy's IPAddresses slice should have been caught earlier,
 			// skip it to prevent a panic.
 			continue
 		}
+		family := bootstrap.IPFamilyIPv6
 		if addr.To4() != nil {
-			return false
+			family = bootstrap.IPFamilyIPv4
+			families.ipv4 = true
+		} else {
+			families.ipv6 = true
+		}
+		if families.primary == bootstrap.IPFamilyAuto {
+			families.primary = family
 		}
 	}
-	return true
+	if families.primary == bootstrap.IPFamilyAuto {
+		families.primary = bootstrap.IPFamilyIPv4
+	}
+	return families
+}
+
+func (f proxyIPFamilies) dualStack() bool {
+	return f.ipv4 && f.ipv6
+}
+
+// localHostAddrs returns the loopback address of the primary family, followed by the one of the other
+// family if the proxy is dual-stack.
+func (f proxyIPFamilies) localHostAddrs() []string {
+	if f.primary == bootstrap.IPFamilyIPv6 {
+		if f.dualStack() {
+			return []string{localHostIPv6, localHostIPv4}
+		}
+		return []string{localHostIPv6}
+	}
+	if f.dualStack() {
+		return []string{localHostIPv4, localHostIPv6}
+	}
+	return []string{localHostIPv4}
 }
@@ -15,15 +15,28 @@
 package main
 
 import (
+	"os"
+	"reflect"
 	"testing"
 
 	"github.com/onsi/gomega"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pkg/bootstrap"
+	"istio.io/istio/pkg/config/mesh"
 )
 
+// restoreRegistryFlags restores the registry flags changed by a test once it completes.
+func restoreRegistryFlags(t *testing.T) {
+	oldRegistryID, oldClusterDomain := registryID, clusterDomain
+	t.Cleanup(func() {
+		registryID, clusterDomain = oldRegistryID, oldClusterDomain
+	})
+}
+
 func TestPilotDefaultDomainKubernetes(t *testing.T) {
+	restoreRegistryFlags(t)
 	g := gomega.NewWithT(t)
 	role = &model.Proxy{}
 	role.DNSDomain = ""
@@ -35,6 +48,7 @@ func TestPilotDefaultDomainKubernetes(t *testing.T) {
 }
 
 func TestPilotDefaultDomainOthers(t *testing.T) {
+	restoreRegistryFlags(t)
 	g := gomega.NewWithT(t)
 	role = &model.Proxy{}
 	role.DNSDomain = ""
@@ -46,6 +60,7 @@ func TestPilotDefaultDomainOthers(t *testing.T) {
 }
 
 func TestPilotDomain(t *testing.T) {
+	restoreRegistryFlags(t)
 	g := gomega.NewWithT(t)
 	role.DNSDomain = "my.domain"
 	registryID = serviceregistry.Mock
@@ -55,32 +70,207 @@ func TestPilotDomain(t *testing.T) {
 	g.Expect(domain).To(gomega.Equal("my.domain"))
 }
 
-func TestIsIPv6Proxy(t *testing.T) {
-	tests := []struct {
+func TestPilotClusterDomain(t *testing.T) {
+	restoreRegistryFlags(t)
+	cases := []struct {
 		name     string
-		addrs    []string
-		expected bool
+		registry serviceregistry.ProviderID
+		domain   string
+		expected string
+	}{
+		{"mock registry with cluster domain", serviceregistry.Mock, "example.org", "default.svc.example.org"},
+		{"kubernetes registry with cluster domain", serviceregistry.Kubernetes, "corp.internal", "default.svc.corp.internal"},
+		{"kubernetes registry default", serviceregistry.Kubernetes, "", "default.svc.cluster.local"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			registryID = tt.registry
+			clusterDomain = tt.domain
+			if err := validateRegistryFlags("default", ""); err != nil {
+				t.Fatal(err)
+			}
+			if got := getDNSDomain("default", ""); got != tt.expected {
+				t.Fatalf("got domain %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateRegistryFlags(t *testing.T) {
+	restoreRegistryFlags(t)
+	cases := []struct {
+		name          string
+		registry      serviceregistry.ProviderID
+		clusterDomain string
+		namespace     string
+		domain        string
+		err           bool
+	}{
+		{name: "kubernetes", registry: serviceregistry.Kubernetes},
+		{name: "mock", registry: serviceregistry.Mock},
+		{name: "unsupported registry", registry: serviceregistry.MCP},
+		{name: "explicit domain", registry: serviceregistry.Mock, domain: "my.domain"},
+		{name: "cluster domain with explicit domain", registry: serviceregistry.Mock, clusterDomain: "example.org",
+			namespace: "default", domain: "my.domain", err: true},
+		{name: "cluster domain without namespace", registry: serviceregistry.Mock, clusterDomain: "example.org", err: true},
+		{name: "cluster domain with trailing dot", registry: serviceregistry.Kubernetes, clusterDomain: "example.org.",
+			namespace: "default", err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			registryID = tt.registry
+			clusterDomain = tt.clusterDomain
+			err := validateRegistryFlags(tt.namespace, tt.domain)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected err=%v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestGetProxyID(t *testing.T) {
+	restoreRegistryFlags(t)
+	registryID = serviceregistry.Kubernetes
+	if got := getProxyID("pod", "default", []string{"10.0.0.1"}); got != "pod.default" {
+		t.Fatalf("got ID %q, want pod.default", got)
+	}
+	registryID = serviceregistry.Mock
+	if got := getProxyID("pod", "default", []string{"10.0.0.1"}); got != "10.0.0.1" {
+		t.Fatalf("got ID %q, want 10.0.0.1", got)
+	}
+}
+
+func TestDetectIPFamilies(t *testing.T) {
+	tests := []struct {
+		name           string
+		addrs          []string
+		expected       proxyIPFamilies
+		localHostAddrs []string
 	}{
 		{
-			name:     "ipv4 only",
-			addrs:    []string{"1.1.1.1", "127.0.0.1", "2.2.2.2"},
-			expected: false,
+			name:           "ipv4 only",
+			addrs:          []string{"1.1.1.1", "127.0.0.1", "2.2.2.2"},
+			expected:       proxyIPFamilies{ipv4: true, primary: bootstrap.IPFamilyIPv4},
+			localHostAddrs: []string{localHostIPv4},
 		},
 		{
-			name:     "ipv6 only",
-			addrs:    []string{"1111:2222::1", "::1", "2222:3333::1"},
-			expected: true,
+			name:           "ipv6 only",
+			addrs:          []string{"1111:2222::1", "::1", "2222:3333::1"},
+			expected:       proxyIPFamilies{ipv6: true, primary: bootstrap.IPFamilyIPv6},
+			localHostAddrs: []string{localHostIPv6},
 		},
 		{
-			name:     "mixed ipv4 and ipv6",
-			addrs:    []string{"1111:2222::1", "::1", "127.0.0.1", "2.2.2.2", "2222:3333::1"},
-			expected: false,
+			name:           "dual-stack with ipv6 primary",
+			addrs:          []string{"1111:2222::1", "::1", "127.0.0.1", "2.2.2.2", "2222:3333::1"},
+			expected:       proxyIPFamilies{ipv4: true, ipv6: true, primary: bootstrap.IPFamilyIPv6},
+			localHostAddrs: []string{localHostIPv6, localHostIPv4},
+		},
+		{
+			name:           "dual-stack with ipv4 primary",
+			addrs:          []string{"2.2.2.2", "1111:2222::1"},
+			expected:       proxyIPFamilies{ipv4: true, ipv6: true, primary: bootstrap.IPFamilyIPv4},
+			localHostAddrs: []string{localHostIPv4, localHostIPv6},
+		},
+		{
+			name:           "invalid primary address",
+			addrs:          []string{"not-an-ip", "1111:2222::1"},
+			expected:       proxyIPFamilies{ipv6: true, primary: bootstrap.IPFamilyIPv6},
+			localHostAddrs: []string{localHostIPv6},
+		},
+		{
+			name:           "localhost fallback",
+			addrs:          []string{"127.0.0.1", "::1"},
+			expected:       proxyIPFamilies{ipv4: true, ipv6: true, primary: bootstrap.IPFamilyIPv4},
+			localHostAddrs: []string{localHostIPv4, localHostIPv6},
+		},
+		{
+			name:           "no addresses",
+			expected:       proxyIPFamilies{primary: bootstrap.IPFamilyIPv4},
+			localHostAddrs: []string{localHostIPv4},
 		},
 	}
 	for _, tt := range tests {
-		result := isIPv6Proxy(tt.addrs)
-		if result != tt.expected {
-			t.Errorf("Test %s failed, expected: %t got: %t", tt.name, tt.expected, result)
+		t.Run(tt.name, func(t *testing.T) {
+			result := detectIPFamilies(tt.addrs)
+			if result != tt.expected {
+				t.Errorf("expected: %+v got: %+v", tt.expected, result)
+			}
+			if got := result.localHostAddrs(); !reflect.DeepEqual(got, tt.localHostAddrs) {
+				t.Errorf("expected localhost addresses: %v got: %v", tt.localHostAddrs, got)
+			}
+		})
+	}
+}
+
+func TestParseListenerPorts(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []uint32
+		err  bool
+	}{
+		{"", nil, false},
+		{"8080", []uint32{8080}, false},
+		{"8080, 8443,", []uint32{8080, 8443}, false},
+		{"http", nil, true},
+		{"0", nil, true},
+		{"70000", nil, true},
+	}
+	for _, tt := range cases {
+		got, err := parseListenerPorts(tt.in)
+		if tt.err != (err != nil) {
+			t.Fatalf("%q: got error %v, want error %v", tt.in, err, tt.err)
 		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%q: got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRouterStatusServerConfig(t *testing.T) {
+	role = &model.Proxy{Type: model.Router}
+	defer func() { role = &model.Proxy{} }()
+	proxyConfig := mesh.DefaultProxyConfig()
+	ipFamilies := proxyIPFamilies{ipv4: true, primary: bootstrap.IPFamilyIPv4}
+
+	cases := []struct {
+		name string
+		env  map[string]string
+		err  bool
+	}{
+		{"defaults", nil, false},
+		{"custom probe", map[string]string{"ROUTER_STATUS_PORT": "15030", "ROUTER_READINESS_PATH": "/gateway/ready"}, false},
+		{"status port of Envoy", map[string]string{"ROUTER_STATUS_PORT": "15021"}, true},
+		{"health check port of Envoy", map[string]string{"ROUTER_HEALTH_CHECK_PORT": "15021"}, true},
+		{"relative path", map[string]string{"ROUTER_READINESS_PATH": "ready"}, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				k := k
+				old, f := os.LookupEnv(k)
+				_ = os.Setenv(k, v)
+				defer func() {
+					if f {
+						_ = os.Setenv(k, old)
+					} else {
+						_ = os.Unsetenv(k)
+					}
+				}()
+			}
+			cfg, err := statusServerConfig(ipFamilies, proxyConfig)
+			if tt.err != (err != nil) {
+				t.Fatalf("got error %v, want error %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			wantPort, wantPath := uint16(proxyConfig.StatusPort), "/healthz/ready"
+			if tt.env["ROUTER_STATUS_PORT"] != "" {
+				wantPort, wantPath = 15030, "/gateway/ready"
+			}
+			if cfg.StatusPort != wantPort || cfg.ReadyPath != wantPath {
+				t.Fatalf("got status port %d and path %q, want %d and %q", cfg.StatusPort, cfg.ReadyPath, wantPort, wantPath)
+			}
+		})
 	}
 }
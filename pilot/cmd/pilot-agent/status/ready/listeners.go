@@ -0,0 +1,50 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ready
+
+import (
+	"fmt"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/status/util"
+)
+
+// ListenersProbe checks that Envoy has listeners for all the ports, such as the ports declared by a gateway.
+// Unlike the readiness of Envoy, it is not cached, as the listeners are removed with the gateway config.
+type ListenersProbe struct {
+	LocalHostAddr string
+	AdminPort     uint16
+	Ports         []uint32
+}
+
+// Check executes the probe and returns an error if a listener is missing.
+func (p *ListenersProbe) Check() error {
+	if len(p.Ports) == 0 {
+		return nil
+	}
+	ports, err := util.GetListenerPorts(p.LocalHostAddr, p.AdminPort)
+	if err != nil {
+		return fmt.Errorf("failed to get listeners: %v", err)
+	}
+	var missing []uint32
+	for _, port := range p.Ports {
+		if !ports[port] {
+			missing = append(missing, port)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no listener configured for ports %v", missing)
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -37,6 +38,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/expfmt"
 	"go.opencensus.io/stats/view"
+	"golang.org/x/net/http2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -51,12 +53,22 @@ const (
 	readyPath = "/healthz/ready"
 	// quitPath is to notify the pilot agent to quit.
 	quitPath = "/quitquitquit"
+	// resumeProxyPath is to notify the pilot agent to resume restarting a crash looping proxy.
+	resumeProxyPath = "/resumeproxy"
 	// KubeAppProberEnvName is the name of the command line flag for pilot agent to pass app prober config.
 	// The json encoded string to pass app HTTP probe information from injector(istioctl or webhook).
 	// For example, ISTIO_KUBE_APP_PROBERS='{"/app-health/httpbin/livez":{"httpGet":{"path": "/hello", "port": 8080}}.
 	// indicates that httpbin container liveness prober port is 8080 and probing path is /hello.
 	// This environment variable should never be set manually.
 	KubeAppProberEnvName = "ISTIO_KUBE_APP_PROBERS"
+	// maxAppProbeResponseSize is the maximum size of the application probe response body passed through to the
+	// kubelet, which only reads this much of it.
+	maxAppProbeResponseSize = 10 * 1024
+	// defaultScrapeTimeout is the timeout of the scrapes of Envoy and the application, if the scrape request does not
+	// set X-Prometheus-Scrape-Timeout-Seconds.
+	defaultScrapeTimeout = 10 * time.Second
+	// scrapeUpMetric is the synthetic metric appended to the merged metrics, set to 0 for the scrapes which failed.
+	scrapeUpMetric = "istio_agent_scrape_up"
 )
 
 var PrometheusScrapingConfig = env.RegisterStringVar("ISTIO_PROMETHEUS_ANNOTATIONS", "", "")
@@ -77,6 +89,17 @@ type KubeAppProbers map[string]*Prober
 type Prober struct {
 	HTTPGet        *corev1.HTTPGetAction `json:"httpGet"`
 	TimeoutSeconds int32                 `json:"timeoutSeconds,omitempty"`
+	// HTTP2 indicates the application is probed with HTTP/2 prior knowledge, e.g. for gRPC health checks.
+	HTTP2 bool `json:"http2,omitempty"`
+}
+
+// ProxyRestarter is implemented by the agent restarting the proxy, so that a crash looping proxy is reported as
+// not ready and can be restarted on demand.
+type ProxyRestarter interface {
+	// CrashLoopError returns an error if the proxy is crash looping and no longer restarted.
+	CrashLoopError() error
+	// ResumeRestarts restarts a crash looping proxy.
+	ResumeRestarts()
 }
 
 // Config for the status server.
@@ -87,17 +110,39 @@ type Config struct {
 	NodeType       model.NodeType
 	StatusPort     uint16
 	AdminPort      uint16
+	// Restarter, if set, is checked by the readiness probe and resumed by resumeProxyPath.
+	Restarter ProxyRestarter
+	// ReadyPath is the path of the readiness probe. Defaults to /healthz/ready.
+	ReadyPath string
+	// DisableAppProbers disables the application probes rewritten by the injector, for the gateways which have no
+	// application. KubeAppProbers is ignored.
+	DisableAppProbers bool
+	// HealthCheckPort, if set, serves the readiness of the proxy on a second port at HealthCheckPath, for the health
+	// checks of cloud load balancers. On top of the readiness probe, it requires Envoy to have listeners for
+	// HealthCheckListenerPorts, so that traffic is only sent once the gateway serves its ports.
+	HealthCheckPort uint16
+	// HealthCheckPath is the path of the health check. Defaults to /healthz/ready.
+	HealthCheckPath          string
+	HealthCheckListenerPorts []uint32
 }
 
 // Server provides an endpoint for handling status probes.
 type Server struct {
-	ready               *ready.Probe
+	ready *ready.Probe
+	// readyMutex serializes the readiness checks, which are shared by the status and health check ports.
+	readyMutex          sync.Mutex
 	prometheus          *PrometheusScrapeConfiguration
 	mutex               sync.RWMutex
 	appKubeProbers      KubeAppProbers
 	statusPort          uint16
 	lastProbeSuccessful bool
 	envoyStatsPort      int
+	restarter           ProxyRestarter
+	readyPath           string
+	disableAppProbers   bool
+	healthCheckPort     uint16
+	healthCheckPath     string
+	listeners           *ready.ListenersProbe
 }
 
 func init() {
@@ -124,7 +169,26 @@ func NewServer(config Config) (*Server, error) {
 			AdminPort:     config.AdminPort,
 			NodeType:      config.NodeType,
 		},
-		envoyStatsPort: 15090,
+		envoyStatsPort:    15090,
+		restarter:         config.Restarter,
+		readyPath:         config.ReadyPath,
+		disableAppProbers: config.DisableAppProbers,
+		healthCheckPort:   config.HealthCheckPort,
+		healthCheckPath:   config.HealthCheckPath,
+		listeners: &ready.ListenersProbe{
+			LocalHostAddr: config.LocalHostAddr,
+			AdminPort:     config.AdminPort,
+			Ports:         config.HealthCheckListenerPorts,
+		},
+	}
+	if s.readyPath == "" {
+		s.readyPath = readyPath
+	}
+	if s.healthCheckPath == "" {
+		s.healthCheckPath = readyPath
+	}
+	if config.HealthCheckPort != 0 && config.HealthCheckPort == config.StatusPort {
+		return nil, fmt.Errorf("invalid health check port %d, it is the status port", config.HealthCheckPort)
 	}
 
 	// Enable prometheus server if its configured and a sidecar
@@ -155,6 +219,10 @@ func NewServer(config Config) (*Server, error) {
 	if config.KubeAppProbers == "" {
 		return s, nil
 	}
+	if config.DisableAppProbers {
+		log.Warnf("Application probers are disabled, ignoring %s", KubeAppProberEnvName)
+		return s, nil
+	}
 	if err := json.Unmarshal([]byte(config.KubeAppProbers), &s.appKubeProbers); err != nil {
 		return nil, fmt.Errorf("failed to decode app prober err = %v, json string = %v", err, config.KubeAppProbers)
 	}
@@ -186,13 +254,27 @@ func FormatProberURL(container string) (string, string, string) {
 func (s *Server) Run(ctx context.Context) {
 	log.Infof("Opening status port %d\n", s.statusPort)
 
-	mux := http.NewServeMux()
+	mux := s.statusMux()
 
-	// Add the handler for ready probes.
-	mux.HandleFunc(readyPath, s.handleReadyProbe)
-	mux.HandleFunc(`/stats/prometheus`, s.handleStats)
-	mux.HandleFunc(quitPath, s.handleQuit)
-	mux.HandleFunc("/app-health/", s.handleAppProbe)
+	if s.healthCheckPort != 0 {
+		log.Infof("Opening health check port %d", s.healthCheckPort)
+		hl, err := net.Listen("tcp", fmt.Sprintf(":%d", s.healthCheckPort))
+		if err != nil {
+			log.Errorf("Error listening on health check port: %v", err)
+			return
+		}
+		defer hl.Close()
+		go func() {
+			// Unlike the status server, the readiness probe of the proxy does not depend on it.
+			if err := http.Serve(hl, s.healthCheckMux()); err != nil {
+				select {
+				case <-ctx.Done():
+				default:
+					log.Errorf("Health check server failed: %v", err)
+				}
+			}
+		}()
+	}
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.statusPort))
 	if err != nil {
@@ -229,8 +311,57 @@ func (s *Server) Run(ctx context.Context) {
 	log.Info("Status server has successfully terminated")
 }
 
+// statusMux returns the handlers of the status port.
+func (s *Server) statusMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Add the handler for ready probes.
+	mux.HandleFunc(s.readyPath, s.handleReadyProbe)
+	mux.HandleFunc(`/stats/prometheus`, s.handleStats)
+	mux.HandleFunc(quitPath, s.handleQuit)
+	mux.HandleFunc(resumeProxyPath, s.handleResumeProxy)
+	if !s.disableAppProbers {
+		mux.HandleFunc("/app-health/", s.handleAppProbe)
+	}
+	return mux
+}
+
+// healthCheckMux returns the handlers of the health check port, which only serves the health check.
+func (s *Server) healthCheckMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(s.healthCheckPath, s.handleHealthCheck)
+	return mux
+}
+
+// handleHealthCheck serves the health check of cloud load balancers, which passes once the proxy is ready and
+// has listeners for the ports of the gateway.
+func (s *Server) handleHealthCheck(w http.ResponseWriter, _ *http.Request) {
+	err := s.readyError()
+	if err == nil {
+		err = s.listeners.Check()
+	}
+	if err != nil {
+		log.Debugf("Health check failed: %v", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// readyError returns an error if the proxy is not ready.
+func (s *Server) readyError() error {
+	if s.restarter != nil {
+		if err := s.restarter.CrashLoopError(); err != nil {
+			return err
+		}
+	}
+	s.readyMutex.Lock()
+	defer s.readyMutex.Unlock()
+	return s.ready.Check()
+}
+
 func (s *Server) handleReadyProbe(w http.ResponseWriter, _ *http.Request) {
-	err := s.ready.Check()
+	err := s.readyError()
 
 	s.mutex.Lock()
 	if err != nil {
@@ -267,37 +398,52 @@ type PrometheusScrapeConfiguration struct {
 
 // handleStats handles prometheus stats scraping. This will scrape envoy metrics, and, if configured,
 // the application metrics and merge them together.
-// The merge here is a simple string concatenation. This works for almost all cases, assuming the application
-// is not exposing the same metrics as Envoy.
+// The merge here is a simple string concatenation. Application metric families also exposed by Envoy or the agent are
+// renamed with the application_ prefix, as Prometheus rejects an exposition declaring the same family twice.
+// Envoy and the application are scraped concurrently, so that an upstream timing out does not delay the other.
 // Note that we do not return any errors here. If we do, we will drop metrics. For example, the app may be having issues,
-// but we still want Envoy metrics. Instead, errors are tracked in the failed scrape metrics/logs.
+// but we still want Envoy metrics. Instead, errors are tracked in the failed scrape metrics/logs, and in the
+// istio_agent_scrape_up metric of the response.
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	scrapeTotals.Increment()
 	var envoy, application, agent []byte
-	var err error
+	var envoyErr, appErr, err error
 	// Gather all the metrics we will merge
-	if envoy, err = s.scrape(fmt.Sprintf("http://localhost:%d/stats/prometheus", s.envoyStatsPort), r.Header); err != nil {
-		log.Errorf("failed scraping envoy metrics: %v", err)
-		envoyScrapeErrors.Increment()
-	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		envoy, envoyErr = s.scrape(fmt.Sprintf("http://localhost:%d/stats/prometheus", s.envoyStatsPort), r.Header)
+	}()
 	if s.prometheus != nil {
-		url := fmt.Sprintf("http://localhost:%s%s", s.prometheus.Port, s.prometheus.Path)
-		if application, err = s.scrape(url, r.Header); err != nil {
-			log.Errorf("failed scraping application metrics: %v", err)
-			appScrapeErrors.Increment()
-		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url := fmt.Sprintf("http://localhost:%s%s", s.prometheus.Port, s.prometheus.Path)
+			application, appErr = s.scrape(url, r.Header)
+		}()
 	}
 	if agent, err = scrapeAgentMetrics(); err != nil {
 		log.Errorf("failed scraping agent metrics: %v", err)
 		agentScrapeErrors.Increment()
 	}
+	wg.Wait()
+	if envoyErr != nil {
+		log.Errorf("failed scraping envoy metrics: %v", envoyErr)
+		envoyScrapeErrors.Increment()
+	}
+	if appErr != nil {
+		log.Errorf("failed scraping application metrics: %v", appErr)
+		appScrapeErrors.Increment()
+	}
+	application = renameConflictingFamilies(application, metricFamilyNames(envoy, agent))
 
 	// Write out the metrics
-	if _, err := w.Write(envoy); err != nil {
+	if _, err := w.Write(withTrailingNewline(envoy)); err != nil {
 		log.Errorf("failed to write envoy metrics: %v", err)
 		envoyScrapeErrors.Increment()
 	}
-	if _, err := w.Write(application); err != nil {
+	if _, err := w.Write(withTrailingNewline(application)); err != nil {
 		log.Errorf("failed to write application metrics: %v", err)
 		appScrapeErrors.Increment()
 	}
@@ -305,6 +451,112 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		log.Errorf("failed to write agent metrics: %v", err)
 		agentScrapeErrors.Increment()
 	}
+	if _, err := w.Write(s.scrapeUpMetrics(envoyErr, appErr)); err != nil {
+		log.Errorf("failed to write scrape metrics: %v", err)
+	}
+}
+
+// scrapeUpMetrics returns the istio_agent_scrape_up metric, reporting whether Envoy and, if configured, the
+// application could be scraped for this response.
+func (s *Server) scrapeUpMetrics(envoyErr, appErr error) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# HELP %s Whether the metrics merged into this response could be scraped.\n", scrapeUpMetric)
+	fmt.Fprintf(buf, "# TYPE %s gauge\n", scrapeUpMetric)
+	up := func(typ string, err error) {
+		v := 1
+		if err != nil {
+			v = 0
+		}
+		fmt.Fprintf(buf, "%s{type=%q} %d\n", scrapeUpMetric, typ, v)
+	}
+	up(ScrapeTypeEnvoy, envoyErr)
+	if s.prometheus != nil {
+		up(ScrapeTypeApp, appErr)
+	}
+	return buf.Bytes()
+}
+
+// metricFamilyNames returns the names of the metric families declared by the TYPE lines of the expositions.
+func metricFamilyNames(expositions ...[]byte) map[string]struct{} {
+	names := map[string]struct{}{}
+	typePrefix := []byte("# TYPE ")
+	for _, exposition := range expositions {
+		for len(exposition) > 0 {
+			line := exposition
+			if i := bytes.IndexByte(exposition, '\n'); i >= 0 {
+				line, exposition = exposition[:i], exposition[i+1:]
+			} else {
+				exposition = nil
+			}
+			if !bytes.HasPrefix(line, typePrefix) {
+				continue
+			}
+			if fields := bytes.Fields(line[len(typePrefix):]); len(fields) > 0 {
+				names[string(fields[0])] = struct{}{}
+			}
+		}
+	}
+	return names
+}
+
+// renameConflictingFamilies prefixes the application metric families whose name is taken with application_, so that
+// both are kept in the merged exposition. The application metrics are returned as is if none conflicts, or if they
+// can't be parsed.
+func renameConflictingFamilies(application []byte, taken map[string]struct{}) []byte {
+	taken[scrapeUpMetric] = struct{}{}
+	conflict := false
+	for name := range metricFamilyNames(application) {
+		if _, f := taken[name]; f {
+			conflict = true
+			break
+		}
+	}
+	if !conflict {
+		return application
+	}
+	parser := expfmt.TextParser{}
+	mfs, err := parser.TextToMetricFamilies(bytes.NewReader(application))
+	if err != nil {
+		log.Warnf("failed to parse application metrics conflicting with envoy or agent metrics: %v", err)
+		return application
+	}
+	names := make([]string, 0, len(mfs))
+	for name := range mfs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	enc := expfmt.NewEncoder(buf, expfmt.FmtText)
+	for _, name := range names {
+		mf := mfs[name]
+		renamed := name
+		for {
+			_, conflict := taken[renamed]
+			_, own := mfs[renamed]
+			if !conflict && (renamed == name || !own) {
+				break
+			}
+			renamed = ScrapeTypeApp + "_" + renamed
+		}
+		taken[renamed] = struct{}{}
+		if renamed != name {
+			log.Debugf("renamed application metric %s to %s, conflicting with envoy or agent metrics", name, renamed)
+			mf.Name = &renamed
+		}
+		if err := enc.Encode(mf); err != nil {
+			log.Warnf("failed to encode application metric %s: %v", renamed, err)
+			return application
+		}
+	}
+	return buf.Bytes()
+}
+
+// withTrailingNewline terminates the last line of the exposition, so that it can be concatenated with another.
+func withTrailingNewline(exposition []byte) []byte {
+	if len(exposition) == 0 || exposition[len(exposition)-1] == '\n' {
+		return exposition
+	}
+	return append(exposition, '\n')
 }
 
 func scrapeAgentMetrics() ([]byte, error) {
@@ -346,17 +598,17 @@ func getHeaderTimeout(timeout string) (time.Duration, error) {
 // This will attempt to mimic some of Prometheus functionality by passing some of the headers through
 // such as timeout and user agent
 func (s *Server) scrape(url string, header http.Header) ([]byte, error) {
-	ctx := context.Background()
+	timeout := defaultScrapeTimeout
 	if timeoutString := header.Get("X-Prometheus-Scrape-Timeout-Seconds"); timeoutString != "" {
-		timeout, err := getHeaderTimeout(timeoutString)
+		t, err := getHeaderTimeout(timeoutString)
 		if err != nil {
 			log.Warnf("Failed to parse timeout header %v: %v", timeoutString, err)
 		} else {
-			c, cancel := context.WithTimeout(ctx, timeout)
-			ctx = c
-			defer cancel()
+			timeout = t
 		}
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -398,6 +650,25 @@ func (s *Server) handleQuit(w http.ResponseWriter, r *http.Request) {
 	notifyExit()
 }
 
+func (s *Server) handleResumeProxy(w http.ResponseWriter, r *http.Request) {
+	if !isRequestFromLocalhost(r) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.restarter == nil || s.restarter.CrashLoopError() == nil {
+		http.Error(w, "Proxy is not crash looping", http.StatusConflict)
+		return
+	}
+	log.Infof("handling %s, resuming proxy restarts", resumeProxyPath)
+	s.restarter.ResumeRestarts()
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
+
 func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	// Validate the request first.
 	path := req.URL.Path
@@ -414,13 +685,10 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 
 	// Construct a request sent to the application.
 	httpClient := &http.Client{
-		Timeout: time.Duration(prober.TimeoutSeconds) * time.Second,
-		// We skip the verification since kubelet skips the verification for HTTPS prober as well
-		// https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-probes/#configure-probes
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		Timeout:   time.Duration(prober.TimeoutSeconds) * time.Second,
+		Transport: appProbeTransport(prober),
 	}
+	defer httpClient.CloseIdleConnections()
 	proberPath := prober.HTTPGet.Path
 	if !strings.HasPrefix(proberPath, "/") {
 		proberPath = "/" + proberPath
@@ -445,8 +713,12 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 		appReq.Header[name] = newValues
 	}
 
+	// Headers configured on the probe replace the ones sent by the kubelet, such as User-Agent.
 	for _, h := range prober.HTTPGet.HTTPHeaders {
-		if h.Name == "Host" || h.Name == ":authority" {
+		appReq.Header.Del(h.Name)
+	}
+	for _, h := range prober.HTTPGet.HTTPHeaders {
+		if strings.EqualFold(h.Name, "Host") || h.Name == ":authority" {
 			// Probe has specific host header override; honor it
 			appReq.Host = h.Value
 		} else {
@@ -467,8 +739,33 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 		_ = response.Body.Close()
 	}()
 
-	// We only write the status code to the response.
+	// We only write the status code and body to the response, so that application errors are passed through.
 	w.WriteHeader(response.StatusCode)
+	_, _ = io.Copy(w, io.LimitReader(response.Body, maxAppProbeResponseSize))
+}
+
+// appProbeTransport returns the transport used to send the probe to the application.
+func appProbeTransport(prober *Prober) http.RoundTripper {
+	// We skip the verification since kubelet skips the verification for HTTPS prober as well
+	// https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-probes/#configure-probes
+	// If the probe targeted a specific host rather than the pod itself, the certificate is verified for that host.
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if prober.HTTPGet.Host != "" {
+		tlsConfig = &tls.Config{ServerName: prober.HTTPGet.Host}
+	}
+	if !prober.HTTP2 {
+		return &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	if prober.HTTPGet.Scheme == corev1.URISchemeHTTPS {
+		return &http2.Transport{TLSClientConfig: tlsConfig}
+	}
+	return &http2.Transport{
+		// Speak HTTP/2 with prior knowledge over plaintext; http2.Transport otherwise requires TLS.
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
 }
 
 // notifyExit sends SIGTERM to itself
@@ -29,6 +29,9 @@ import (
 	"time"
 
 	"github.com/prometheus/common/expfmt"
+	"go.uber.org/atomic"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -56,6 +59,16 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
 		}
 	}
+	if r.URL.Path == "/user-agent" {
+		if ua := r.Header.Values("User-Agent"); len(ua) != 1 || ua[0] != testHeaderValue {
+			log.Errorf("Expected User-Agent to be overridden, got %v", ua)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	if r.URL.Path == "/error" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
 	if r.URL.Path != "/hello/sunnyvale" && r.URL.Path != "/" {
 		return
 	}
@@ -135,11 +148,10 @@ func TestNewServer(t *testing.T) {
 
 func TestStats(t *testing.T) {
 	cases := []struct {
-		name             string
-		envoy            string
-		app              string
-		output           string
-		expectParseError bool
+		name   string
+		envoy  string
+		app    string
+		output string
 	}{
 		{
 			name: "envoy metric only",
@@ -190,8 +202,7 @@ my_other_metric{} 0
 # TYPE istio_agent_scrapes_total counter
 istio_agent_scrapes_total`,
 		},
-		// When the application and envoy share a metric, Prometheus will fail. This negative check validates this
-		// assumption.
+		// When the application and envoy share a metric, Prometheus would fail. The application metric is renamed.
 		{
 			name: "conflict metric",
 			envoy: `# TYPE my_metric counter
@@ -206,25 +217,39 @@ my_metric{} 0
 my_metric{} 0
 # TYPE my_other_metric counter
 my_other_metric{} 0
-# TYPE my_metric counter
-my_metric{} 0
+# TYPE application_my_metric counter
+application_my_metric 0
 `,
-			expectParseError: true,
 		},
 		{
 			name: "conflict metric labeled",
 			envoy: `# TYPE my_metric counter
 my_metric{app="foo"} 0
 `,
-			app: `# TYPE my_metric counter
+			app: `# HELP my_metric app metric
+# TYPE my_metric counter
 my_metric{app="bar"} 0
+# TYPE application_my_metric counter
+application_my_metric{app="bar"} 1
 `,
 			output: `# TYPE my_metric counter
 my_metric{app="foo"} 0
-# TYPE my_metric counter
-my_metric{app="bar"} 0
+# TYPE application_my_metric counter
+application_my_metric{app="bar"} 1
+# HELP application_application_my_metric app metric
+# TYPE application_application_my_metric counter
+application_application_my_metric{app="bar"} 0
+`,
+		},
+		{
+			name:  "missing trailing newline",
+			envoy: "# TYPE my_metric counter\nmy_metric{} 0",
+			app:   "# TYPE my_other_metric counter\nmy_other_metric{} 0",
+			output: `# TYPE my_metric counter
+my_metric{} 0
+# TYPE my_other_metric counter
+my_other_metric{} 0
 `,
-			expectParseError: true,
 		},
 	}
 	for _, tt := range cases {
@@ -262,11 +287,8 @@ my_metric{app="bar"} 0
 			}
 
 			parser := expfmt.TextParser{}
-			mfMap, err := parser.TextToMetricFamilies(strings.NewReader(rec.Body.String()))
-			if err != nil && !tt.expectParseError {
+			if _, err := parser.TextToMetricFamilies(strings.NewReader(rec.Body.String())); err != nil {
 				t.Fatalf("failed to parse metrics: %v", err)
-			} else if err == nil && tt.expectParseError {
-				t.Fatalf("expected a prse error, got %+v", mfMap)
 			}
 		})
 	}
@@ -290,14 +312,16 @@ func TestStatsError(t *testing.T) {
 		t.Fatal(err)
 	}
 	cases := []struct {
-		name  string
-		envoy int
-		app   int
+		name    string
+		envoy   int
+		app     int
+		envoyUp float64
+		appUp   float64
 	}{
-		{"both pass", passPort, passPort},
-		{"envoy pass", passPort, failPort},
-		{"app pass", failPort, passPort},
-		{"both fail", failPort, failPort},
+		{"both pass", passPort, passPort, 1, 1},
+		{"envoy pass", passPort, failPort, 1, 0},
+		{"app pass", failPort, passPort, 0, 1},
+		{"both fail", failPort, failPort, 0, 0},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -314,10 +338,97 @@ func TestStatsError(t *testing.T) {
 			if rec.Code != 200 {
 				t.Fatalf("handleStats() => %v; want 200", rec.Code)
 			}
+			up := scrapeUp(t, rec.Body.String())
+			if up[ScrapeTypeEnvoy] != tt.envoyUp || up[ScrapeTypeApp] != tt.appUp {
+				t.Fatalf("got %s %v, want envoy %v and application %v", scrapeUpMetric, up, tt.envoyUp, tt.appUp)
+			}
+		})
+	}
+}
+
+func TestStatsTimeout(t *testing.T) {
+	metrics := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "# TYPE %s counter\n%s 1\n", name, name)
+		})
+	}
+	done := make(chan struct{})
+	defer close(done)
+	hang := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+	cases := []struct {
+		name    string
+		envoy   http.Handler
+		app     http.Handler
+		output  string
+		envoyUp float64
+		appUp   float64
+	}{
+		{"envoy hangs", hang, metrics("app_metric"), "app_metric 1", 0, 1},
+		{"app hangs", metrics("envoy_metric"), hang, "envoy_metric 1", 1, 0},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			envoy := httptest.NewServer(tt.envoy)
+			defer envoy.Close()
+			app := httptest.NewServer(tt.app)
+			defer app.Close()
+			envoyPort, err := strconv.Atoi(strings.Split(envoy.URL, ":")[2])
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := &Server{
+				prometheus: &PrometheusScrapeConfiguration{
+					Port: strings.Split(app.URL, ":")[2],
+				},
+				envoyStatsPort: envoyPort,
+			}
+			req := httptest.NewRequest("GET", "/stats/prometheus", nil)
+			req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "0.5")
+			rec := httptest.NewRecorder()
+			start := time.Now()
+			server.handleStats(rec, req)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("handleStats() took %v, expected the scrape timeout", elapsed)
+			}
+			if !strings.Contains(rec.Body.String(), tt.output) {
+				t.Fatalf("handleStats() => %v; want %v", rec.Body.String(), tt.output)
+			}
+			up := scrapeUp(t, rec.Body.String())
+			if up[ScrapeTypeEnvoy] != tt.envoyUp || up[ScrapeTypeApp] != tt.appUp {
+				t.Fatalf("got %s %v, want envoy %v and application %v", scrapeUpMetric, up, tt.envoyUp, tt.appUp)
+			}
 		})
 	}
 }
 
+// scrapeUp parses the merged metrics and returns the values of istio_agent_scrape_up by type.
+func scrapeUp(t *testing.T, metrics string) map[string]float64 {
+	t.Helper()
+	parser := expfmt.TextParser{}
+	mfs, err := parser.TextToMetricFamilies(strings.NewReader(metrics))
+	if err != nil {
+		t.Fatalf("failed to parse metrics: %v", err)
+	}
+	mf := mfs[scrapeUpMetric]
+	if mf == nil {
+		t.Fatalf("missing %s in %v", scrapeUpMetric, metrics)
+	}
+	up := map[string]float64{}
+	for _, m := range mf.Metric {
+		for _, l := range m.Label {
+			if l.GetName() == "type" {
+				up[l.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	return up
+}
+
 func TestAppProbe(t *testing.T) {
 	// Starts the application first.
 	listener, err := net.Listen("tcp", ":0")
@@ -377,6 +488,47 @@ func TestAppProbe(t *testing.T) {
 			},
 			statusCode: http.StatusOK,
 		},
+		{
+			probePath: "app-health/header/readyz",
+			config: KubeAppProbers{
+				"/app-health/header/readyz": &Prober{
+					HTTPGet: &v1.HTTPGetAction{
+						Port: intstr.IntOrString{IntVal: int32(appPort)},
+						Path: "/header",
+						HTTPHeaders: []v1.HTTPHeader{
+							{"host", testHostValue},
+							{testHeader, testHeaderValue},
+						},
+					},
+				},
+			},
+			statusCode: http.StatusOK,
+		},
+		{
+			probePath: "app-health/user-agent/readyz",
+			config: KubeAppProbers{
+				"/app-health/user-agent/readyz": &Prober{
+					HTTPGet: &v1.HTTPGetAction{
+						Port:        intstr.IntOrString{IntVal: int32(appPort)},
+						Path:        "/user-agent",
+						HTTPHeaders: []v1.HTTPHeader{{"User-Agent", testHeaderValue}},
+					},
+				},
+			},
+			statusCode: http.StatusOK,
+		},
+		{
+			probePath: "app-health/error/readyz",
+			config: KubeAppProbers{
+				"/app-health/error/readyz": &Prober{
+					HTTPGet: &v1.HTTPGetAction{
+						Port: intstr.IntOrString{IntVal: int32(appPort)},
+						Path: "/error",
+					},
+				},
+			},
+			statusCode: http.StatusServiceUnavailable,
+		},
 		{
 			probePath: "app-health/hello-world/readyz",
 			config: KubeAppProbers{
@@ -455,7 +607,8 @@ func TestHttpsAppProbe(t *testing.T) {
 	server, err := NewServer(Config{
 		StatusPort: 0,
 		KubeAppProbers: fmt.Sprintf(`{"/app-health/hello-world/readyz": {"httpGet": {"path": "/hello/sunnyvale", "port": %v, "scheme": "HTTPS"}},
-"/app-health/hello-world/livez": {"httpGet": {"port": %v, "scheme": "HTTPS"}}}`, appPort, appPort),
+"/app-health/hello-world/livez": {"httpGet": {"port": %v, "scheme": "HTTPS"}},
+"/app-health/verified/readyz": {"httpGet": {"host": "example.com", "port": %v, "scheme": "HTTPS"}}}`, appPort, appPort, appPort),
 	})
 	if err != nil {
 		t.Errorf("failed to create status server %v", err)
@@ -492,6 +645,11 @@ func TestHttpsAppProbe(t *testing.T) {
 			probePath:  fmt.Sprintf(":%v/app-health/hello-world/livez", statusPort),
 			statusCode: http.StatusOK,
 		},
+		{
+			// The self-signed certificate is verified, as the probe targets a specific host.
+			probePath:  fmt.Sprintf(":%v/app-health/verified/readyz", statusPort),
+			statusCode: http.StatusInternalServerError,
+		},
 	}
 	for _, tc := range testCases {
 		client := http.Client{}
@@ -510,6 +668,51 @@ func TestHttpsAppProbe(t *testing.T) {
 	}
 }
 
+func TestHTTP2AppProbe(t *testing.T) {
+	// Starts an application which only accepts HTTP/2 with prior knowledge.
+	app := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+		}
+	}), &http2.Server{}))
+	defer app.Close()
+	appPort := app.Listener.Addr().(*net.TCPAddr).Port
+
+	for _, tc := range []struct {
+		name       string
+		http2      bool
+		statusCode int
+	}{
+		{
+			name:       "http1",
+			statusCode: http.StatusHTTPVersionNotSupported,
+		},
+		{
+			name:       "http2",
+			http2:      true,
+			statusCode: http.StatusOK,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, err := NewServer(Config{StatusPort: 0})
+			if err != nil {
+				t.Fatalf("failed to create status server %v", err)
+			}
+			server.appKubeProbers = KubeAppProbers{
+				"/app-health/grpc/livez": &Prober{
+					HTTPGet: &v1.HTTPGetAction{Port: intstr.IntOrString{IntVal: int32(appPort)}},
+					HTTP2:   tc.http2,
+				},
+			}
+			rec := httptest.NewRecorder()
+			server.handleAppProbe(rec, httptest.NewRequest("GET", "/app-health/grpc/livez", nil))
+			if rec.Code != tc.statusCode {
+				t.Errorf("unexpected status code, want = %v, got = %v", tc.statusCode, rec.Code)
+			}
+		})
+	}
+}
+
 func TestHandleQuit(t *testing.T) {
 	statusPort := 15020
 	s, err := NewServer(Config{StatusPort: uint16(statusPort)})
@@ -582,3 +785,175 @@ func TestHandleQuit(t *testing.T) {
 		})
 	}
 }
+
+type fakeRestarter struct {
+	crashLoopErr error
+	resumed      int
+}
+
+func (f *fakeRestarter) CrashLoopError() error {
+	return f.crashLoopErr
+}
+
+func (f *fakeRestarter) ResumeRestarts() {
+	f.resumed++
+}
+
+func TestHandleResumeProxy(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		remoteAddr   string
+		crashLooping bool
+		expected     int
+	}{
+		{
+			name:         "should resume a crash looping proxy",
+			method:       "POST",
+			remoteAddr:   "127.0.0.1",
+			crashLooping: true,
+			expected:     http.StatusOK,
+		},
+		{
+			name:       "should reject when the proxy is not crash looping",
+			method:     "POST",
+			remoteAddr: "127.0.0.1",
+			expected:   http.StatusConflict,
+		},
+		{
+			name:         "should require POST method",
+			method:       "GET",
+			remoteAddr:   "127.0.0.1",
+			crashLooping: true,
+			expected:     http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "should require localhost",
+			method:       "POST",
+			crashLooping: true,
+			expected:     http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restarter := &fakeRestarter{}
+			if tt.crashLooping {
+				restarter.crashLoopErr = fmt.Errorf("crash looping")
+			}
+			s, err := NewServer(Config{StatusPort: 15020, Restarter: restarter})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(tt.method, resumeProxyPath, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr + ":15020"
+			}
+
+			resp := httptest.NewRecorder()
+			s.handleResumeProxy(resp, req)
+			if resp.Code != tt.expected {
+				t.Fatalf("Expected response code %v got %v", tt.expected, resp.Code)
+			}
+			wantResumed := 0
+			if tt.expected == http.StatusOK {
+				wantResumed = 1
+			}
+			if restarter.resumed != wantResumed {
+				t.Fatalf("Expected %d resumes, got %d", wantResumed, restarter.resumed)
+			}
+		})
+	}
+}
+
+func TestReadyProbeCrashLoop(t *testing.T) {
+	s, err := NewServer(Config{StatusPort: 15020, Restarter: &fakeRestarter{crashLoopErr: fmt.Errorf("crash looping")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := httptest.NewRecorder()
+	s.handleReadyProbe(resp, httptest.NewRequest("GET", readyPath, nil))
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected response code %v got %v", http.StatusServiceUnavailable, resp.Code)
+	}
+}
+
+func TestHealthCheckPort(t *testing.T) {
+	listeners := atomic.NewString(
+		`{"listener_statuses": [{"name": "0.0.0.0_8080", "local_address": {"socket_address": {"address": "0.0.0.0", "port_value": 8080}}}]}`)
+	envoy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stats":
+			_, _ = w.Write([]byte("cluster_manager.cds.update_success: 1\nlistener_manager.lds.update_success: 1\n" +
+				"server.state: 0\nlistener_manager.workers_started: 1"))
+		case "/listeners":
+			_, _ = w.Write([]byte(listeners.Load()))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer envoy.Close()
+	adminPort, err := strconv.Atoi(strings.Split(envoy.URL, ":")[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(Config{
+		LocalHostAddr:            "127.0.0.1",
+		AdminPort:                uint16(adminPort),
+		StatusPort:               15030,
+		ReadyPath:                "/gateway/ready",
+		DisableAppProbers:        true,
+		KubeAppProbers:           `{"/app-health/hello-world/readyz": {"httpGet": {"path": "/hello", "port": 8080}}}`,
+		HealthCheckPort:          15022,
+		HealthCheckPath:          "/lb/healthz",
+		HealthCheckListenerPorts: []uint32{8080, 8443},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := httptest.NewServer(s.statusMux())
+	defer status.Close()
+	healthCheck := httptest.NewServer(s.healthCheckMux())
+	defer healthCheck.Close()
+
+	get := func(url string) int {
+		t.Helper()
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	expect := func(url string, want int) {
+		t.Helper()
+		if got := get(url); got != want {
+			t.Fatalf("%s: got status %d, want %d", url, got, want)
+		}
+	}
+
+	// The proxy is ready, but the gateway has no listener for 8443 yet.
+	expect(status.URL+"/gateway/ready", http.StatusOK)
+	expect(healthCheck.URL+"/lb/healthz", http.StatusServiceUnavailable)
+	// The paths are only served on their own port, and the application probes are disabled.
+	expect(status.URL+"/healthz/ready", http.StatusNotFound)
+	expect(healthCheck.URL+"/gateway/ready", http.StatusNotFound)
+	expect(status.URL+"/app-health/hello-world/readyz", http.StatusNotFound)
+
+	listeners.Store(`{"listener_statuses": [` +
+		`{"name": "0.0.0.0_8080", "local_address": {"socket_address": {"address": "0.0.0.0", "port_value": 8080}}},` +
+		`{"name": "0.0.0.0_8443", "local_address": {"socket_address": {"address": "0.0.0.0", "port_value": 8443}}}]}`)
+	expect(status.URL+"/gateway/ready", http.StatusOK)
+	expect(healthCheck.URL+"/lb/healthz", http.StatusOK)
+}
+
+func TestHealthCheckPortConflict(t *testing.T) {
+	if _, err := NewServer(Config{StatusPort: 15030, HealthCheckPort: 15030}); err == nil {
+		t.Fatal("expected an error for a health check port conflicting with the status port")
+	}
+}
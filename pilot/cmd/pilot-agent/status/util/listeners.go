@@ -0,0 +1,47 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	"github.com/golang/protobuf/jsonpb"
+)
+
+// GetListenerPorts returns the ports Envoy has listeners bound to.
+func GetListenerPorts(localHostAddr string, adminPort uint16) (map[uint32]bool, error) {
+	// If the localHostAddr was not set, we use 'localhost' to void empty host in URL.
+	if localHostAddr == "" {
+		localHostAddr = "localhost"
+	}
+
+	b, err := doHTTPGet(fmt.Sprintf("http://%s:%d/listeners?format=json", localHostAddr, adminPort))
+	if err != nil {
+		return nil, err
+	}
+	listeners := &admin.Listeners{}
+	u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := u.Unmarshal(b, listeners); err != nil {
+		return nil, fmt.Errorf("failed to parse listeners: %v", err)
+	}
+	ports := map[uint32]bool{}
+	for _, l := range listeners.ListenerStatuses {
+		if port := l.GetLocalAddress().GetSocketAddress().GetPortValue(); port != 0 {
+			ports[port] = true
+		}
+	}
+	return ports, nil
+}
@@ -0,0 +1,211 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package systemd integrates the agent with systemd when it runs as a service, typically on VMs: it notifies the
+// service manager of the lifecycle of the agent, and sends the watchdog heartbeats.
+// See https://www.freedesktop.org/software/systemd/man/sd_notify.html.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/pkg/log"
+)
+
+const (
+	// Ready tells the service manager that the agent finished starting up.
+	Ready = "READY=1"
+	// Stopping tells the service manager that the agent is shutting down.
+	Stopping = "STOPPING=1"
+	// Watchdog is the keep-alive heartbeat of the agent.
+	Watchdog = "WATCHDOG=1"
+
+	notifySocketEnv = "NOTIFY_SOCKET"
+	watchdogUsecEnv = "WATCHDOG_USEC"
+	watchdogPidEnv  = "WATCHDOG_PID"
+
+	defaultReadyPollInterval = time.Second
+)
+
+// Notifier sends notifications to the service manager.
+type Notifier struct {
+	socket string
+
+	mu sync.Mutex
+	// stopping is set once the service manager was notified of the shutdown, after which nothing else is sent.
+	stopping bool
+}
+
+// NewNotifierFromEnv returns a notifier sending to $NOTIFY_SOCKET. The variable is only set by systemd for
+// services of type notify, so the notifier is a no-op otherwise, in particular on Kubernetes.
+func NewNotifierFromEnv() *Notifier {
+	return &Notifier{socket: os.Getenv(notifySocketEnv)}
+}
+
+// Enabled returns true if the agent is run by a service manager expecting notifications.
+func (n *Notifier) Enabled() bool {
+	return n != nil && n.socket != ""
+}
+
+// Notify sends the state to the service manager. It is a no-op if the notifier is not enabled, or once it
+// notified the service manager that the agent is stopping.
+func (n *Notifier) Notify(state string) error {
+	if !n.Enabled() {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopping {
+		return nil
+	}
+	if state == Stopping {
+		n.stopping = true
+	}
+	socket := n.socket
+	if strings.HasPrefix(socket, "@") {
+		// Abstract namespace socket.
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to the notify socket %s: %v", n.socket, err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("failed to notify %s: %v", state, err)
+	}
+	return nil
+}
+
+// WatchdogInterval returns the interval the service manager expects the watchdog heartbeats at, or 0 if the
+// watchdog is not enabled for the agent.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv(watchdogUsecEnv), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv(watchdogPidEnv); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		// The watchdog is meant for another process.
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Check returns an error if the condition it checks is not met.
+type Check func() error
+
+// Config for the notifications of the lifecycle of the agent.
+type Config struct {
+	Notifier *Notifier
+	// ReadyCheck passes once the agent is ready to serve.
+	ReadyCheck Check
+	// HealthChecks must all pass for a watchdog heartbeat to be sent, so that the service manager restarts an
+	// unresponsive agent.
+	HealthChecks []Check
+	// WatchdogInterval is the timeout of the watchdog. Heartbeats are sent at half of it. If 0, no heartbeat is
+	// sent.
+	WatchdogInterval time.Duration
+
+	// readyPollInterval is the interval the ready check is polled at.
+	readyPollInterval time.Duration
+}
+
+// Run notifies the service manager that the agent is ready once the ready check passes, then sends the watchdog
+// heartbeats while the health checks pass, until ctx is done. It is a no-op if the notifier is not enabled.
+func Run(ctx context.Context, cfg Config) {
+	if !cfg.Notifier.Enabled() {
+		return
+	}
+	pollInterval := cfg.readyPollInterval
+	if pollInterval == 0 {
+		pollInterval = defaultReadyPollInterval
+	}
+	if cfg.ReadyCheck != nil {
+		if !poll(ctx, pollInterval, cfg.ReadyCheck) {
+			return
+		}
+	}
+	if err := cfg.Notifier.Notify(Ready); err != nil {
+		log.Warnf("Failed to notify systemd of readiness: %v", err)
+	} else {
+		log.Infof("Notified systemd of readiness")
+	}
+
+	if cfg.WatchdogInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(cfg.WatchdogInterval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := healthy(cfg.HealthChecks); err != nil {
+				log.Warnf("Skipping systemd watchdog heartbeat, agent is unhealthy: %v", err)
+				continue
+			}
+			if err := cfg.Notifier.Notify(Watchdog); err != nil {
+				log.Warnf("Failed to send systemd watchdog heartbeat: %v", err)
+			}
+		}
+	}
+}
+
+// poll runs the check at the interval until it passes, and returns false if ctx is done first.
+func poll(ctx context.Context, interval time.Duration, check Check) bool {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := check()
+		if err == nil {
+			return true
+		}
+		log.Debugf("Agent not ready to notify systemd: %v", err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+func healthy(checks []Check) error {
+	for _, check := range checks {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePIDFile writes the PID of the agent to the file, and returns a function removing it.
+func WritePIDFile(path string) (func(), error) {
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write the PID file %s: %v", path, err)
+	}
+	return func() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to remove the PID file %s: %v", path, err)
+		}
+	}, nil
+}
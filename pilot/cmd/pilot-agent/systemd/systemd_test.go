@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// notifySocket stubs the notify socket of systemd, and returns the messages received on it.
+func notifySocket(t *testing.T) (*Notifier, <-chan string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	messages := make(chan string, 100)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			messages <- string(buf[:n])
+		}
+	}()
+	return &Notifier{socket: path}, messages
+}
+
+func expectMessage(t *testing.T, messages <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-messages:
+		if got != want {
+			t.Fatalf("got message %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for message %q", want)
+	}
+}
+
+func expectNoMessage(t *testing.T, messages <-chan string) {
+	t.Helper()
+	select {
+	case got := <-messages:
+		t.Fatalf("unexpected message %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRun(t *testing.T) {
+	notifier, messages := notifySocket(t)
+	ready := atomic.NewBool(false)
+	healthy := atomic.NewBool(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, Config{
+			Notifier: notifier,
+			ReadyCheck: func() error {
+				if !ready.Load() {
+					return errors.New("server is not live")
+				}
+				return nil
+			},
+			HealthChecks: []Check{func() error {
+				if !healthy.Load() {
+					return errors.New("status server is not responsive")
+				}
+				return nil
+			}},
+			WatchdogInterval:  20 * time.Millisecond,
+			readyPollInterval: time.Millisecond,
+		})
+		close(done)
+	}()
+
+	// Neither readiness nor heartbeats are notified before Envoy is live.
+	expectNoMessage(t, messages)
+	ready.Store(true)
+	expectMessage(t, messages, Ready)
+	expectMessage(t, messages, Watchdog)
+	expectMessage(t, messages, Watchdog)
+
+	// An unhealthy agent misses its heartbeats.
+	healthy.Store(false)
+	// Drain the heartbeat possibly sent before the health changed.
+	select {
+	case <-messages:
+	case <-time.After(100 * time.Millisecond):
+	}
+	expectNoMessage(t, messages)
+	healthy.Store(true)
+	expectMessage(t, messages, Watchdog)
+
+	// On SIGTERM, systemd is notified before the context is cancelled and Envoy drains.
+	if err := notifier.Notify(Stopping); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	<-done
+	for {
+		select {
+		case got := <-messages:
+			if got == Stopping {
+				// Nothing is sent after the shutdown was notified.
+				expectNoMessage(t, messages)
+				return
+			}
+			if got != Watchdog {
+				t.Fatalf("unexpected message %q while stopping", got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the shutdown notification")
+		}
+	}
+}
+
+func TestRunDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checked := atomic.NewBool(false)
+	// Without a notify socket, as on Kubernetes, Run returns right away.
+	Run(ctx, Config{
+		Notifier: &Notifier{},
+		ReadyCheck: func() error {
+			checked.Store(true)
+			return nil
+		},
+	})
+	if checked.Load() {
+		t.Fatal("unexpected ready check without a notify socket")
+	}
+	if err := (&Notifier{}).Notify(Ready); err != nil {
+		t.Fatalf("unexpected error of a disabled notifier: %v", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	cases := []struct {
+		usec, pid string
+		want      time.Duration
+	}{
+		{"", "", 0},
+		{"invalid", "", 0},
+		{"2000000", "", 2 * time.Second},
+		{"2000000", strconv.Itoa(os.Getpid()), 2 * time.Second},
+		{"2000000", "1", 0},
+	}
+	for _, tt := range cases {
+		t.Run(tt.usec+"/"+tt.pid, func(t *testing.T) {
+			os.Setenv(watchdogUsecEnv, tt.usec)
+			os.Setenv(watchdogPidEnv, tt.pid)
+			defer os.Unsetenv(watchdogUsecEnv)
+			defer os.Unsetenv(watchdogPidEnv)
+			if got := WatchdogInterval(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritePIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pilot-agent.pid")
+	remove, err := WritePIDFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(b)); got != strconv.Itoa(os.Getpid()) {
+		t.Errorf("got PID %s, want %d", got, os.Getpid())
+	}
+	remove()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the PID file to be removed, got %v", err)
+	}
+}
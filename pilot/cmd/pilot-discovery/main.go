@@ -125,6 +125,12 @@ func init() {
 		"File name for Istio mesh configuration. If not specified, a default mesh will be used.")
 	discoveryCmd.PersistentFlags().StringVar(&serverArgs.NetworksConfigFile, "networksConfig", "/etc/istio/config/meshNetworks",
 		"File name for Istio mesh networks configuration. If not specified, a default mesh networks will be used.")
+	discoveryCmd.PersistentFlags().StringVar(&serverArgs.ProxyConfigOverridesFile, "proxyConfigOverrides",
+		"/etc/istio/config/proxyConfigOverrides",
+		"File name for the overrides of the default proxy config for namespaces and workloads, pushed to the agents.")
+	discoveryCmd.PersistentFlags().StringVar(&serverArgs.MeshExtensionsFile, "meshExtensions",
+		"/etc/istio/config/meshExtensions",
+		"File name for the settings of the mesh applied by istiod which are not part of the mesh configuration.")
 	discoveryCmd.PersistentFlags().StringVarP(&serverArgs.Namespace, "namespace", "n", bootstrap.PodNamespaceVar.Get(),
 		"Select a namespace where the controller resides. If not set, uses ${POD_NAMESPACE} environment variable")
 	discoveryCmd.PersistentFlags().StringSliceVar(&serverArgs.Plugins, "plugins", bootstrap.DefaultPlugins,
@@ -113,7 +113,8 @@ func (s *Server) initConfigController(args *PilotArgs) error {
 	if err != nil {
 		return err
 	}
-	s.configController = aggregateConfigController
+	// Hide the configs quarantined after being rejected by proxies from the config store and the registries.
+	s.configController = model.NewQuarantineConfigStore(aggregateConfigController, s.XDSServer.ConfigQuarantine)
 
 	// Create the config store.
 	s.environment.IstioConfigStore = model.MakeIstioStore(s.configController)
@@ -132,6 +133,7 @@ func (s *Server) initK8SConfigStore(args *PilotArgs) error {
 	if err != nil {
 		return err
 	}
+	s.RWConfigStore = configController
 	s.ConfigStores = append(s.ConfigStores, configController)
 	if features.EnableServiceApis {
 		s.ConfigStores = append(s.ConfigStores, gateway.NewController(s.kubeClient, configController, args.RegistryOptions.KubeOptions))
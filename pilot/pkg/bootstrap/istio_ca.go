@@ -112,6 +112,10 @@ var (
 
 	caRSAKeySize = env.RegisterIntVar("CITADEL_SELF_SIGNED_CA_RSA_KEY_SIZE", 2048,
 		"Specify the RSA key size to use for self-signed Istio CA certificates.")
+
+	certBackdate = env.RegisterDurationVar("CITADEL_CERT_BACKDATE", 60*time.Second,
+		"How long before the time they are signed the workload and DNS certificates are valid, "+
+			"so that they are not rejected as not yet valid by hosts whose clock is behind.")
 )
 
 type CAOptions struct {
@@ -354,6 +358,7 @@ func (s *Server) createIstioCA(client corev1.CoreV1Interface, opts *CAOptions) (
 		}
 	}
 
+	caOpts.CertBackdate = certBackdate.Get()
 	istioCA, err := ca.NewIstioCA(caOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
@@ -16,6 +16,9 @@ package bootstrap
 
 import (
 	"encoding/json"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
@@ -51,6 +54,33 @@ func (s *Server) initMeshConfiguration(args *PilotArgs, fileWatcher filewatcher.
 	s.environment.Watcher = mesh.NewFixedWatcher(&meshConfig)
 }
 
+// initMeshReload reloads the mesh config file on SIGHUP, for file based setups where the file events may be missed.
+func (s *Server) initMeshReload() {
+	reloader, ok := s.environment.Watcher.(mesh.Reloader)
+	if !ok {
+		return
+	}
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGHUP)
+		go func() {
+			defer signal.Stop(sigs)
+			for {
+				select {
+				case <-stop:
+					return
+				case <-sigs:
+					log.Info("received SIGHUP, reloading mesh configuration")
+					if err := reloader.Reload(); err != nil {
+						log.Warnf("failed to reload mesh configuration, keeping the current one: %v", err)
+					}
+				}
+			}
+		}()
+		return nil
+	})
+}
+
 // initMeshNetworks loads the mesh networks configuration from the file provided
 // in the args and add a watcher for changes in this file.
 func (s *Server) initMeshNetworks(args *PilotArgs, fileWatcher filewatcher.FileWatcher) {
@@ -68,3 +98,37 @@ func (s *Server) initMeshNetworks(args *PilotArgs, fileWatcher filewatcher.FileW
 		s.environment.NetworksWatcher = mesh.NewFixedNetworksWatcher(nil)
 	}
 }
+
+// initProxyConfigOverrides loads the proxy config overrides from the file provided in the args and adds a watcher
+// for changes in this file.
+func (s *Server) initProxyConfigOverrides(args *PilotArgs, fileWatcher filewatcher.FileWatcher) {
+	if args.ProxyConfigOverridesFile != "" {
+		var err error
+		s.environment.ProxyConfigOverridesWatcher, err = mesh.NewProxyConfigOverridesWatcher(fileWatcher, args.ProxyConfigOverridesFile)
+		if err != nil {
+			log.Infoa(err)
+		}
+	}
+
+	if s.environment.ProxyConfigOverridesWatcher == nil {
+		log.Info("proxy config overrides not provided")
+		s.environment.ProxyConfigOverridesWatcher = mesh.NewFixedProxyConfigOverridesWatcher(nil)
+	}
+}
+
+// initMeshExtensions loads the mesh extensions from the file provided in the args and adds a watcher for changes
+// in this file.
+func (s *Server) initMeshExtensions(args *PilotArgs, fileWatcher filewatcher.FileWatcher) {
+	if args.MeshExtensionsFile != "" {
+		var err error
+		s.environment.MeshExtensionsWatcher, err = mesh.NewMeshExtensionsWatcher(fileWatcher, args.MeshExtensionsFile)
+		if err != nil {
+			log.Infoa(err)
+		}
+	}
+
+	if s.environment.MeshExtensionsWatcher == nil {
+		log.Info("mesh extensions not provided")
+		s.environment.MeshExtensionsWatcher = mesh.NewFixedMeshExtensionsWatcher(nil)
+	}
+}
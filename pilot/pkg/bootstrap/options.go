@@ -15,9 +15,11 @@
 package bootstrap
 
 import (
+	"strings"
 	"time"
 
 	"istio.io/istio/pilot/pkg/features"
+	kubesecrets "istio.io/istio/pilot/pkg/secrets/kube"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/keepalive"
@@ -62,6 +64,12 @@ type PilotArgs struct {
 	MCPOptions         MCPOptions
 	KeepaliveOptions   *keepalive.Options
 	ShutdownDuration   time.Duration
+	// SDSSecretScope restricts the secrets watched to serve SDS, when the SDS server is enabled.
+	SDSSecretScope kubesecrets.Scope
+	// ProxyConfigOverridesFile is the file holding the overrides of the default proxy config for namespaces and workloads.
+	ProxyConfigOverridesFile string
+	// MeshExtensionsFile is the file holding the settings of the mesh which are not part of the mesh config.
+	MeshExtensionsFile string
 }
 
 // DiscoveryServerOptions contains options for create a new discovery server instance.
@@ -150,4 +158,18 @@ func (p *PilotArgs) applyDefaults() {
 	p.KeepaliveOptions = keepalive.DefaultOption()
 	p.RegistryOptions.DistributionTrackingEnabled = features.EnableDistributionTracking
 	p.RegistryOptions.DistributionCacheRetention = features.DistributionHistoryRetention
+	p.SDSSecretScope.LabelSelector = features.SDSSecretLabelSelector
+	for _, ns := range strings.Split(features.SDSSecretNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			p.SDSSecretScope.Namespaces = append(p.SDSSecretScope.Namespaces, ns)
+		}
+	}
+	nsScope := &p.RegistryOptions.KubeOptions.NamespaceControllerScope
+	nsScope.LabelSelector = features.NamespaceControllerLabelSelector
+	nsScope.Cleanup = features.NamespaceControllerCleanup
+	for _, ns := range strings.Split(features.NamespaceControllerNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			nsScope.Namespaces = append(nsScope.Namespaces, ns)
+		}
+	}
 }
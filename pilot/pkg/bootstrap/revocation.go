@@ -0,0 +1,268 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
+)
+
+// maxOCSPResponseSize bounds the size of the responses read from the OCSP responder.
+const maxOCSPResponseSize = 1 << 20
+
+var (
+	resultTag = monitoring.MustCreateLabel("result")
+
+	revokedPeerCerts = monitoring.NewSum(
+		"pilot_secure_grpc_revoked_peer_certs",
+		"Number of client certificates rejected by the secure gRPC server because they are revoked by the CRL.",
+	)
+
+	ocspStapleRefreshes = monitoring.NewSum(
+		"pilot_secure_grpc_ocsp_staple_refreshes",
+		"Number of refreshes of the OCSP response stapled by the secure gRPC server, by result.",
+		monitoring.WithLabels(resultTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(revokedPeerCerts, ocspStapleRefreshes)
+}
+
+func loadCRL(file string) (*pkix.CertificateList, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	// ParseCRL accepts both PEM and DER encoded CRLs.
+	return x509.ParseCRL(b)
+}
+
+// initCRLWatch loads the CRL of PILOT_SECURE_GRPC_CRL_FILE into the peer certificate verifier, and reloads it when
+// the file changes. The current CRL is kept if the file cannot be reloaded.
+func (s *Server) initCRLWatch() error {
+	file := features.SecureGRPCCRLFile
+	if file == "" {
+		return nil
+	}
+	crl, err := loadCRL(file)
+	if err != nil {
+		return fmt.Errorf("failed to load the CRL %s: %v", file, err)
+	}
+	s.peerCertVerifier.SetRevocationList(crl)
+	log.Infof("adding watcher for CRL %s", file)
+	if err := s.fileWatcher.Add(file); err != nil {
+		return fmt.Errorf("could not watch %v: %v", file, err)
+	}
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go func() {
+			var crlTimerC <-chan time.Time
+			for {
+				select {
+				case <-crlTimerC:
+					crlTimerC = nil
+					crl, err := loadCRL(file)
+					if err != nil {
+						log.Errorf("error in reloading CRL %s, keeping the current one: %v", file, err)
+						continue
+					}
+					s.peerCertVerifier.SetRevocationList(crl)
+				case <-s.fileWatcher.Events(file):
+					if crlTimerC == nil {
+						crlTimerC = time.After(watchDebounceDelay)
+					}
+				case err := <-s.fileWatcher.Errors(file):
+					log.Errorf("error watching %v: %v", file, err)
+				case <-stop:
+					return
+				}
+			}
+		}()
+		return nil
+	})
+	return nil
+}
+
+// ocspStapler staples an OCSP response for the istiod certificate to the TLS handshakes. The response is fetched
+// from a responder, or read from a file, and refreshed periodically.
+type ocspStapler struct {
+	responderURL string
+	responseFile string
+	client       *http.Client
+
+	// certChanged is signaled when the serving certificate is not the one of the response, so that run refreshes
+	// the response without waiting for the next interval.
+	certChanged chan struct{}
+
+	mutex sync.RWMutex
+	// response is the DER encoded OCSP response for the certificate whose leaf is cert, valid until nextUpdate.
+	response   []byte
+	cert       []byte
+	nextUpdate time.Time
+	// requested is the leaf of the last certificate a refresh was requested for, to request it only once.
+	requested []byte
+}
+
+// newOCSPStapler returns the stapler configured by PILOT_SECURE_GRPC_OCSP_RESPONDER_URL or
+// PILOT_SECURE_GRPC_OCSP_RESPONSE_FILE, or nil if neither is set.
+func newOCSPStapler() *ocspStapler {
+	if features.SecureGRPCOCSPResponderURL == "" && features.SecureGRPCOCSPResponseFile == "" {
+		return nil
+	}
+	return &ocspStapler{
+		responderURL: features.SecureGRPCOCSPResponderURL,
+		responseFile: features.SecureGRPCOCSPResponseFile,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		certChanged:  make(chan struct{}, 1),
+	}
+}
+
+// refresh gets an OCSP response for the certificate, whose chain must include its issuer, and staples it if it is
+// valid and the certificate is good. The previous response is kept if the status cannot be refreshed, and dropped
+// if the certificate is not good.
+func (o *ocspStapler) refresh(cert *tls.Certificate) error {
+	if cert == nil || len(cert.Certificate) < 2 {
+		return fmt.Errorf("the certificate chain does not include the issuer")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return err
+	}
+
+	var der []byte
+	if o.responderURL != "" {
+		der, err = o.fetch(leaf, issuer)
+	} else {
+		der, err = ioutil.ReadFile(o.responseFile)
+	}
+	if err != nil {
+		return err
+	}
+	resp, err := ocsp.ParseResponseForCert(der, leaf, issuer)
+	if err != nil {
+		return fmt.Errorf("invalid OCSP response: %v", err)
+	}
+	if !resp.NextUpdate.IsZero() && time.Now().After(resp.NextUpdate) {
+		return fmt.Errorf("the OCSP response expired at %v", resp.NextUpdate)
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if resp.Status != ocsp.Good {
+		o.response, o.cert, o.nextUpdate = nil, nil, time.Time{}
+		return fmt.Errorf("the OCSP status of the istiod certificate is not good: %d", resp.Status)
+	}
+	o.response, o.cert, o.nextUpdate = der, cert.Certificate[0], resp.NextUpdate
+	return nil
+}
+
+func (o *ocspStapler) fetch(leaf, issuer *x509.Certificate) ([]byte, error) {
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client.Post(o.responderURL, "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the OCSP responder returned %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+}
+
+// update refreshes the response, and records the result.
+func (o *ocspStapler) update(cert *tls.Certificate) {
+	if err := o.refresh(cert); err != nil {
+		ocspStapleRefreshes.With(resultTag.Value("failure")).Increment()
+		log.Warnf("failed to refresh the OCSP response of the istiod certificate: %v", err)
+		return
+	}
+	ocspStapleRefreshes.With(resultTag.Value("success")).Increment()
+}
+
+// staple returns the certificate with the OCSP response stapled, if there is a valid response for it. A refresh is
+// requested if the certificate is not the one of the response, e.g. after the istiod certificate was rotated.
+func (o *ocspStapler) staple(cert *tls.Certificate) *tls.Certificate {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return cert
+	}
+	o.mutex.RLock()
+	response, responseCert, nextUpdate := o.response, o.cert, o.nextUpdate
+	o.mutex.RUnlock()
+	if !bytes.Equal(cert.Certificate[0], responseCert) {
+		o.requestRefresh(cert.Certificate[0])
+		return cert
+	}
+	if response == nil || (!nextUpdate.IsZero() && time.Now().After(nextUpdate)) {
+		return cert
+	}
+	stapled := *cert
+	stapled.OCSPStaple = response
+	return &stapled
+}
+
+// requestRefresh signals run to refresh the response for the certificate whose leaf is given, once per certificate
+// so that a certificate whose status cannot be refreshed does not trigger a refresh on every handshake.
+func (o *ocspStapler) requestRefresh(leaf []byte) {
+	o.mutex.Lock()
+	if bytes.Equal(leaf, o.requested) {
+		o.mutex.Unlock()
+		return
+	}
+	o.requested = leaf
+	o.mutex.Unlock()
+	select {
+	case o.certChanged <- struct{}{}:
+	default:
+	}
+}
+
+// run refreshes the response of the certificate returned by getCert at each interval, and when the serving
+// certificate changes, until stop is closed.
+func (o *ocspStapler) run(getCert func() *tls.Certificate, interval time.Duration, stop <-chan struct{}) {
+	o.update(getCert())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.update(getCert())
+		case <-o.certChanged:
+			o.update(getCert())
+		case <-stop:
+			return
+		}
+	}
+}
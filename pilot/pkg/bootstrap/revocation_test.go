@@ -0,0 +1,196 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// newOCSPTestCA returns a CA certificate and its key.
+func newOCSPTestCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, caKey
+}
+
+// newOCSPTestCert returns an istiod certificate with the serial number, signed by the CA, with its chain.
+func newOCSPTestCert(t *testing.T, serial int64, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "istiod"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{leafDER, ca.Raw}, PrivateKey: key}
+}
+
+// newOCSPTestResponder returns a fake responder answering with the status set, or failing if it is negative.
+func newOCSPTestResponder(ca *x509.Certificate, caKey *ecdsa.PrivateKey, status *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := atomic.LoadInt32(status)
+		if s < 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:       int(s),
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, caKey)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(resp)
+	}))
+}
+
+func TestOCSPStapler(t *testing.T) {
+	ca, caKey := newOCSPTestCA(t)
+	cert := newOCSPTestCert(t, 2, ca, caKey)
+	var status int32 = ocsp.Good
+	responder := newOCSPTestResponder(ca, caKey, &status)
+	defer responder.Close()
+
+	stapler := &ocspStapler{responderURL: responder.URL, client: responder.Client()}
+	if got := stapler.staple(cert); got.OCSPStaple != nil {
+		t.Fatal("expected no staple before the first refresh")
+	}
+
+	if err := stapler.refresh(cert); err != nil {
+		t.Fatal(err)
+	}
+	good := stapler.staple(cert).OCSPStaple
+	if good == nil {
+		t.Fatal("expected the good OCSP response to be stapled")
+	}
+	if cert.OCSPStaple != nil {
+		t.Fatal("the certificate of the server should not be modified")
+	}
+
+	// A response for another certificate is not stapled.
+	other := &tls.Certificate{Certificate: [][]byte{ca.Raw}}
+	if got := stapler.staple(other); got.OCSPStaple != nil {
+		t.Fatal("expected the response not to be stapled to another certificate")
+	}
+
+	// The previous response is kept if the responder fails.
+	atomic.StoreInt32(&status, -1)
+	if err := stapler.refresh(cert); err == nil {
+		t.Fatal("expected the refresh to fail")
+	}
+	if got := stapler.staple(cert).OCSPStaple; string(got) != string(good) {
+		t.Fatal("expected the previous response to be kept when the responder fails")
+	}
+
+	// The response is dropped once the certificate is revoked.
+	atomic.StoreInt32(&status, ocsp.Revoked)
+	if err := stapler.refresh(cert); err == nil {
+		t.Fatal("expected the refresh to fail for a revoked certificate")
+	}
+	if got := stapler.staple(cert).OCSPStaple; got != nil {
+		t.Fatal("expected no staple for a revoked certificate")
+	}
+}
+
+func TestOCSPStaplerCertRotation(t *testing.T) {
+	ca, caKey := newOCSPTestCA(t)
+	var status int32 = ocsp.Good
+	responder := newOCSPTestResponder(ca, caKey, &status)
+	defer responder.Close()
+
+	var current atomic.Value
+	current.Store(newOCSPTestCert(t, 2, ca, caKey))
+	getCert := func() *tls.Certificate {
+		return current.Load().(*tls.Certificate)
+	}
+	stapler := &ocspStapler{responderURL: responder.URL, client: responder.Client(), certChanged: make(chan struct{}, 1)}
+	stop := make(chan struct{})
+	defer close(stop)
+	// The interval is long enough for the refreshes to only be triggered by the certificate changes.
+	go stapler.run(getCert, time.Hour, stop)
+
+	waitForStaple := func(cert *tls.Certificate) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			if stapler.staple(cert).OCSPStaple == nil {
+				return fmt.Errorf("no OCSP response stapled")
+			}
+			return nil
+		}, retry.Timeout(5*time.Second))
+	}
+	waitForStaple(getCert())
+
+	// The response of the rotated certificate is fetched on its first handshake, not at the next interval.
+	rotated := newOCSPTestCert(t, 3, ca, caKey)
+	current.Store(rotated)
+	if got := stapler.staple(rotated).OCSPStaple; got != nil {
+		t.Fatal("expected the response of the previous certificate not to be stapled")
+	}
+	waitForStaple(rotated)
+}
@@ -19,11 +19,13 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"path"
+	"strconv"
 	"sync"
 	"time"
 
@@ -39,11 +41,14 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
+	"istio.io/istio/pilot/pkg/controller/workloadentry"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
+	"istio.io/istio/pilot/pkg/onboarding"
 	kubesecrets "istio.io/istio/pilot/pkg/secrets/kube"
+	authn_utils "istio.io/istio/pilot/pkg/security/authn/utils"
 	securityModel "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
@@ -119,10 +124,14 @@ type Server struct {
 	// kubeRegistry is the service registry handling the primary cluster.
 	kubeRegistry *kubecontroller.Controller
 	multicluster *kubecontroller.Multicluster
+	// secretsController reads the gateway secrets of the primary cluster for the SDS server, if enabled.
+	secretsController *kubesecrets.SecretsController
 
 	configController  model.ConfigStoreCache
 	ConfigStores      []model.ConfigStoreCache
 	serviceEntryStore *serviceentry.ServiceEntryStore
+	// RWConfigStore is the writable store of the Istio configs, if any. The aggregate configController is read only.
+	RWConfigStore model.ConfigStoreCache
 
 	httpServer       *http.Server // debug, monitoring and readiness Server.
 	httpsServer      *http.Server // webhooks HTTPS Server.
@@ -168,6 +177,8 @@ type Server struct {
 	requiredTerminations sync.WaitGroup
 	statusReporter       *status.Reporter
 	readinessProbes      map[string]readinessProbe
+	// fullReadinessProbes are only checked by /ready/full, in addition to readinessProbes.
+	fullReadinessProbes map[string]readinessProbe
 
 	// duration used for graceful shutdown.
 	shutdownDuration time.Duration
@@ -188,19 +199,30 @@ func NewServer(args *PilotArgs) (*Server, error) {
 	e.ServiceDiscovery = ac
 
 	s := &Server{
-		clusterID:       getClusterID(args),
-		environment:     e,
-		XDSServer:       xds.NewDiscoveryServer(e, args.Plugins),
-		fileWatcher:     filewatcher.NewWatcher(),
-		httpMux:         http.NewServeMux(),
-		monitoringMux:   http.NewServeMux(),
-		readinessProbes: make(map[string]readinessProbe),
+		clusterID:           getClusterID(args),
+		environment:         e,
+		XDSServer:           xds.NewDiscoveryServer(e, args.Plugins),
+		fileWatcher:         filewatcher.NewWatcher(),
+		httpMux:             http.NewServeMux(),
+		monitoringMux:       http.NewServeMux(),
+		readinessProbes:     make(map[string]readinessProbe),
+		fullReadinessProbes: make(map[string]readinessProbe),
 	}
 
 	if args.ShutdownDuration == 0 {
 		s.shutdownDuration = 10 * time.Second // If not specified set to 10 seconds.
 	}
 
+	if features.XDSCompressionThreshold > 0 {
+		xds.RegisterCompressor(features.XDSCompressionThreshold)
+	}
+
+	if err := authn_utils.ValidateDefaultInboundTLSSettings(); err != nil {
+		return nil, fmt.Errorf("invalid PILOT_INBOUND_TLS settings: %v", err)
+	}
+
+	s.XDSServer.InstanceID = args.PodName
+
 	if args.RegistryOptions.KubeOptions.WatchedNamespaces != "" {
 		// Add the control-plane namespace to the list of watched namespaces.
 		args.RegistryOptions.KubeOptions.WatchedNamespaces = fmt.Sprintf("%s,%s",
@@ -213,15 +235,20 @@ func NewServer(args *PilotArgs) (*Server, error) {
 
 	// TODO: revert to watching k8s (and merge with the file)
 	s.initMeshConfiguration(args, s.fileWatcher)
+	s.initMeshReload()
 
 	// Apply the arguments to the configuration.
 	if err := s.initKubeClient(args); err != nil {
 		return nil, fmt.Errorf("error initializing kube client: %v", err)
 	}
 
-	s.initSDSServer()
+	if err := s.initSDSServer(args); err != nil {
+		return nil, fmt.Errorf("error initializing sds server: %v", err)
+	}
 
 	s.initMeshNetworks(args, s.fileWatcher)
+	s.initProxyConfigOverrides(args, s.fileWatcher)
+	s.initMeshExtensions(args, s.fileWatcher)
 	s.initMeshHandlers()
 
 	// Parse and validate Istiod Address.
@@ -311,7 +338,11 @@ func NewServer(args *PilotArgs) (*Server, error) {
 	// Start CA. This should be called after CA and Istiod certs have been created.
 	s.startCA(caOpts)
 
-	s.initNamespaceController(args)
+	if err := s.initNamespaceController(args); err != nil {
+		return nil, fmt.Errorf("error initializing namespace controller: %v", err)
+	}
+	s.initWorkloadEntryController()
+	s.initWorkloadOnboarding(authenticators)
 
 	// TODO: don't run this if galley is started, one ctlz is enough
 	if args.CtrlZOptions != nil {
@@ -329,6 +360,7 @@ func NewServer(args *PilotArgs) (*Server, error) {
 	s.addReadinessProbe("discovery", func() (bool, error) {
 		return s.XDSServer.IsServerReady(), nil
 	})
+	s.addFullReadinessProbe("push backlog", s.pushBacklogReady)
 
 	return s, nil
 }
@@ -420,7 +452,7 @@ func (s *Server) WaitUntilCompletion() {
 }
 
 // initSDSServer starts the SDS server
-func (s *Server) initSDSServer() {
+func (s *Server) initSDSServer(args *PilotArgs) error {
 	if features.EnableSDSServer && s.kubeClient != nil {
 		if !features.EnableXDSIdentityCheck {
 			// Make sure we have security
@@ -428,8 +460,27 @@ func (s *Server) initSDSServer() {
 				"PILOT_ENABLE_XDS_IDENTITY_CHECK must be set to true for this feature.")
 		} else {
 			log.Infof("initializing Kubernetes credential reader")
-			sc := kubesecrets.NewSecretsController(s.kubeClient.KubeInformer().Core().V1().Secrets())
-			sc.AddEventHandler(func(name, namespace string) {
+			var sc *kubesecrets.SecretsController
+			if args.SDSSecretScope.IsEmpty() {
+				sc = kubesecrets.NewSecretsController(s.kubeClient.KubeInformer().Core().V1().Secrets())
+			} else {
+				log.Infof("restricting Kubernetes credential reader to namespaces %v and label selector %q",
+					args.SDSSecretScope.Namespaces, args.SDSSecretScope.LabelSelector)
+				var err error
+				if sc, err = kubesecrets.NewScopedSecretsController(s.kubeClient.Kube(), args.SDSSecretScope); err != nil {
+					return err
+				}
+				s.addStartFunc(func(stop <-chan struct{}) error {
+					go sc.Run(stop)
+					return nil
+				})
+			}
+			s.secretsController = sc
+			// Gateways in remote clusters read secrets from their own cluster, whose credential readers
+			// are created by the multicluster registry.
+			msc := kubesecrets.NewMulticluster(s.clusterID, sc, args.SDSSecretScope)
+			args.RegistryOptions.KubeOptions.ClusterHandlers = append(args.RegistryOptions.KubeOptions.ClusterHandlers, msc)
+			msc.AddEventHandler(func(name, namespace string) {
 				s.XDSServer.ConfigUpdate(&model.PushRequest{
 					Full: false,
 					ConfigsUpdated: map[model.ConfigKey]struct{}{
@@ -442,9 +493,10 @@ func (s *Server) initSDSServer() {
 					Reason: []model.TriggerReason{model.SecretTrigger},
 				})
 			})
-			s.XDSServer.Generators[v3.SecretType] = xds.NewSecretGen(sc, s.XDSServer.Cache)
+			s.XDSServer.Generators[v3.SecretType] = xds.NewSecretGen(msc, s.XDSServer.Cache, s.clusterID)
 		}
 	}
+	return nil
 }
 
 // initKubeClient creates the k8s client if running in an k8s environment.
@@ -489,17 +541,71 @@ func (s *Server) initKubeClient(args *PilotArgs) error {
 // The "http" portion of the readiness check is satisfied by the fact we've started listening on
 // this handler and everything has already initialized.
 func (s *Server) istiodReadyHandler(w http.ResponseWriter, _ *http.Request) {
-	for name, fn := range s.readinessProbes {
-		if ready, err := fn(); !ready {
-			log.Warnf("%s is not ready: %v", name, err)
-			w.WriteHeader(http.StatusServiceUnavailable)
-			return
-		}
+	if name, err := checkReadinessProbes(s.readinessProbes); name != "" {
+		log.Warnf("%s is not ready: %v", name, err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
 	}
 	// TODO check readiness of other secure gRPC and HTTP servers.
 	w.WriteHeader(http.StatusOK)
 }
 
+const (
+	pushQueueDepthHeader     = "X-Istiod-Push-Queue-Depth"
+	pendingConnectionsHeader = "X-Istiod-Pending-Connections"
+)
+
+// istiodFullReadyHandler is a stricter readiness check for external load balancers. In addition to /ready, it
+// fails while istiod is still working through a backlog of pushes, such as all proxies reconnecting after a
+// restart, so that load balancers can send new connections elsewhere. The kubelet should keep using /ready,
+// since an overloaded istiod is still able to serve.
+func (s *Server) istiodFullReadyHandler(w http.ResponseWriter, _ *http.Request) {
+	depth, pending := s.pushBacklog()
+	w.Header().Set(pushQueueDepthHeader, strconv.Itoa(depth))
+	w.Header().Set(pendingConnectionsHeader, strconv.Itoa(pending))
+	name, err := checkReadinessProbes(s.readinessProbes)
+	if name == "" {
+		name, err = checkReadinessProbes(s.fullReadinessProbes)
+	}
+	if name != "" {
+		// load balancers probe frequently, and a backlog is expected after a restart.
+		log.Debugf("%s is not fully ready: %v", name, err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkReadinessProbes returns the name of the first probe which is not ready, and its error.
+func checkReadinessProbes(probes map[string]readinessProbe) (string, error) {
+	for name, fn := range probes {
+		if ready, err := fn(); !ready {
+			return name, err
+		}
+	}
+	return "", nil
+}
+
+// pushBacklog returns the number of proxies waiting to be pushed or being pushed, and of connections waiting for
+// their initial config.
+func (s *Server) pushBacklog() (queueDepth int, pendingConnections int) {
+	return s.XDSServer.PushQueueDepth(), s.XDSServer.PendingConnections()
+}
+
+// pushBacklogReady reports ready once the push queue and the connections waiting for their initial config
+// are within the limits configured for /ready/full.
+func (s *Server) pushBacklogReady() (bool, error) {
+	depth, pending := s.pushBacklog()
+	if depth > features.FullReadyMaxPushQueueDepth {
+		return false, fmt.Errorf("%d proxies are waiting to be pushed, more than %d", depth, features.FullReadyMaxPushQueueDepth)
+	}
+	if pending > features.FullReadyMaxPendingConnections {
+		return false, fmt.Errorf("%d connections are waiting for their initial config, more than %d",
+			pending, features.FullReadyMaxPendingConnections)
+	}
+	return true, nil
+}
+
 // initIstiodAdminServer initializes monitoring, debug and readiness end points.
 func (s *Server) initIstiodAdminServer(args *PilotArgs, wh *inject.Webhook) error {
 	s.httpServer = &http.Server{
@@ -538,6 +644,7 @@ func (s *Server) initIstiodAdminServer(args *PilotArgs, wh *inject.Webhook) erro
 
 	// Readiness Handler.
 	s.httpMux.HandleFunc("/ready", s.istiodReadyHandler)
+	s.httpMux.HandleFunc("/ready/full", s.istiodFullReadyHandler)
 
 	s.HTTPListener = listener
 	return nil
@@ -645,18 +752,46 @@ func (s *Server) initSecureDiscoveryService(args *PilotArgs) error {
 	}
 	log.Info("initializing secure discovery service")
 
+	if err := s.initCRLWatch(); err != nil {
+		return err
+	}
+	getCertificate := s.getIstiodCertificate
+	if stapler := newOCSPStapler(); stapler != nil {
+		getCertificate = func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, err := s.getIstiodCertificate(info)
+			return stapler.staple(cert), err
+		}
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			go stapler.run(func() *tls.Certificate {
+				cert, _ := s.getIstiodCertificate(nil)
+				return cert
+			}, features.SecureGRPCOCSPRefreshInterval, stop)
+			return nil
+		})
+	}
+
 	cfg := &tls.Config{
-		GetCertificate: s.getIstiodCertificate,
+		GetCertificate: getCertificate,
 		ClientAuth:     tls.VerifyClientCertIfGiven,
 		ClientCAs:      s.peerCertVerifier.GetGeneralCertPool(),
 		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
 			err := s.peerCertVerifier.VerifyPeerCert(rawCerts, verifiedChains)
 			if err != nil {
+				if errors.Is(err, spiffe.ErrCertificateRevoked) {
+					revokedPeerCerts.Increment()
+				}
 				log.Infof("Could not verify certificate: %v", err)
 			}
 			return err
 		},
 	}
+	// The root certs of the SPIFFE bundle endpoints may be refreshed at runtime.
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		clientCfg := cfg.Clone()
+		clientCfg.GetConfigForClient = nil
+		clientCfg.ClientCAs = s.peerCertVerifier.GetGeneralCertPool()
+		return clientCfg, nil
+	}
 
 	tlsCreds := credentials.NewTLS(cfg)
 
@@ -725,6 +860,11 @@ func (s *Server) addReadinessProbe(name string, fn readinessProbe) {
 	s.readinessProbes[name] = fn
 }
 
+// adds a readiness probe which is only checked by /ready/full.
+func (s *Server) addFullReadinessProbe(name string, fn readinessProbe) {
+	s.fullReadinessProbes[name] = fn
+}
+
 // addRequireStartFunc adds a function that should terminate before the serve shuts down
 // This is useful to do cleanup activities
 // This is does not guarantee they will terminate gracefully - best effort only
@@ -765,6 +905,9 @@ func (s *Server) cachesSynced() bool {
 	if !s.configController.HasSynced() {
 		return false
 	}
+	if s.secretsController != nil && !s.secretsController.HasSynced() {
+		return false
+	}
 	return true
 }
 
@@ -781,6 +924,8 @@ func (s *Server) initRegistryEventHandlers() error {
 				Namespace: svc.Attributes.Namespace,
 			}: {}},
 			Reason: []model.TriggerReason{model.ServiceUpdate},
+			// The addresses of a network gateway service, such as its LoadBalancer IP, may have changed.
+			NetworkGatewaysChanged: model.IsNetworkGatewayService(s.environment.Networks(), svc.Hostname),
 		}
 		s.XDSServer.ConfigUpdate(pushReq)
 	}
@@ -788,6 +933,16 @@ func (s *Server) initRegistryEventHandlers() error {
 		return fmt.Errorf("append service handler failed: %v", err)
 	}
 
+	if features.AnnotateStaleEndpoints {
+		// The endpoints of the clusters whose registry became stale, or recovered, are flagged accordingly.
+		s.ServiceController().AppendStaleRegistryHandler(func(string, bool) {
+			s.XDSServer.ConfigUpdate(&model.PushRequest{
+				Full:   true,
+				Reason: []model.TriggerReason{model.EndpointUpdate},
+			})
+		})
+	}
+
 	if s.configController != nil {
 		configHandler := func(_, curr config.Config, event model.Event) {
 			pushReq := &model.PushRequest{
@@ -978,13 +1133,25 @@ func (s *Server) setPeerCertVerifier(tlsOptions TLSOptions) error {
 	}
 
 	if features.SpiffeBundleEndpoints != "" {
-		certMap, err := spiffe.RetrieveSpiffeBundleRootCertsFromStringInput(
-			features.SpiffeBundleEndpoints, []*x509.Certificate{})
+		endpoints, err := spiffe.ParseSpiffeBundleEndpoints(features.SpiffeBundleEndpoints)
+		if err != nil {
+			return err
+		}
+		certMap, err := spiffe.RetrieveSpiffeBundleRootCerts(endpoints, []*x509.Certificate{})
 		if err != nil {
 			return err
 		}
-		s.peerCertVerifier.AddMappings(certMap)
+		// The bundle certs are merged with the root cert of the same trust domain, and replaced on refresh.
+		s.peerCertVerifier.UpdateMappings(certMap)
+
+		refresher := spiffe.NewBundleRefresher(endpoints, []*x509.Certificate{}, s.peerCertVerifier,
+			features.SpiffeBundleRefreshInterval)
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			go refresher.Run(stop)
+			return nil
+		})
 	}
+	s.XDSServer.PeerCertVerifier = s.peerCertVerifier
 
 	return nil
 }
@@ -1019,10 +1186,18 @@ func (s *Server) initControllers(args *PilotArgs) error {
 }
 
 // initNamespaceController initializes namespace controller to sync config map.
-func (s *Server) initNamespaceController(args *PilotArgs) {
+func (s *Server) initNamespaceController(args *PilotArgs) error {
 	if s.CA != nil && s.kubeClient != nil {
 		// create namespace controller
-		nsController := kubecontroller.NewNamespaceController(s.fetchCARoot, s.kubeClient)
+		scope := args.RegistryOptions.KubeOptions.NamespaceControllerScope
+		if !scope.IsEmpty() {
+			log.Infof("namespace controller restricted to namespaces %v and label selector %q, cleanup: %v",
+				scope.Namespaces, scope.LabelSelector, scope.Cleanup)
+		}
+		nsController, err := kubecontroller.NewScopedNamespaceController(s.fetchCARoot, s.kubeClient, scope)
+		if err != nil {
+			return err
+		}
 		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
 			le := leaderelection.NewLeaderElection(args.Namespace, args.PodName, leaderelection.NamespaceController, s.kubeClient.Kube())
 			le.AddRunFunction(func(leaderStop <-chan struct{}) {
@@ -1032,6 +1207,50 @@ func (s *Server) initNamespaceController(args *PilotArgs) {
 			return nil
 		})
 	}
+	return nil
+}
+
+// initWorkloadEntryController initializes the controller recording the health reported by the agents on their
+// WorkloadEntry.
+func (s *Server) initWorkloadEntryController() {
+	if !features.WorkloadEntryHealthChecks || s.RWConfigStore == nil {
+		return
+	}
+	controller := workloadentry.NewController(s.RWConfigStore)
+	s.XDSServer.InternalGen.WorkloadEntryController = controller
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go controller.Run(stop)
+		return nil
+	})
+}
+
+// initWorkloadOnboarding serves the onboarding bundles of the WorkloadGroups on the HTTPS port, to the callers
+// authenticated as for the CA.
+func (s *Server) initWorkloadOnboarding(authenticators []authenticate.Authenticator) {
+	if !features.EnableWorkloadOnboarding {
+		return
+	}
+	opts := onboarding.Options{
+		Env:            s.environment,
+		Authenticators: authenticators,
+		RootCert: func() []byte {
+			if s.CA != nil {
+				return s.CA.GetCAKeyCertBundle().GetRootCertPem()
+			}
+			b, err := ioutil.ReadFile(s.caBundlePath)
+			if err != nil {
+				log.Warnf("failed to read the root certificate %s: %v", s.caBundlePath, err)
+			}
+			return b
+		},
+		ClusterID: s.clusterID,
+		TokenTTL:  features.WorkloadOnboardingTokenTTL,
+	}
+	if s.kubeClient != nil {
+		opts.KubeClient = s.kubeClient
+	}
+	onboarding.NewHandler(opts).Register(s.httpsMux)
+	log.Infof("serving the workload onboarding bundles on %s", onboarding.Path)
 }
 
 // initJwtPolicy initializes JwtPolicy.
@@ -1095,16 +1314,32 @@ func (s *Server) fetchCARoot() map[string]string {
 // initMeshHandlers initializes mesh and network handlers.
 func (s *Server) initMeshHandlers() {
 	log.Info("initializing mesh handlers")
-	// When the mesh config or networks change, do a full push.
+	// When the mesh config or networks change, do a full push. A mesh config change only regenerates the types
+	// affected by the fields changed.
+	lastMesh := s.environment.Mesh()
 	s.environment.AddMeshHandler(func() {
+		current := s.environment.Mesh()
+		diff := model.DiffMeshConfig(lastMesh, current)
+		lastMesh = current
 		// Inform ConfigGenerator about the mesh config change so that it can rebuild any cached config, before triggering full push.
-		s.XDSServer.ConfigGenerator.MeshConfigChanged(s.environment.Mesh())
+		s.XDSServer.ConfigGenerator.MeshConfigChanged(current)
+		s.XDSServer.MeshConfigUpdate(diff)
+	})
+	s.environment.AddNetworksHandler(func() {
 		s.XDSServer.ConfigUpdate(&model.PushRequest{
 			Full:   true,
 			Reason: []model.TriggerReason{model.GlobalUpdate},
 		})
 	})
-	s.environment.AddNetworksHandler(func() {
+	// The proxy config overrides are pushed to the agents, and the gateway topology to the gateway listeners.
+	s.environment.AddProxyConfigOverridesHandler(func() {
+		s.XDSServer.ConfigUpdate(&model.PushRequest{
+			Full:             true,
+			Reason:           []model.TriggerReason{model.GlobalUpdate},
+			MeshConfigImpact: model.MeshImpactProxyConfig | model.MeshImpactListeners,
+		})
+	})
+	s.environment.AddMeshExtensionsHandler(func() {
 		s.XDSServer.ConfigUpdate(&model.PushRequest{
 			Full:   true,
 			Reason: []model.TriggerReason{model.GlobalUpdate},
@@ -16,17 +16,26 @@ package bootstrap
 import (
 	"bytes"
 	"crypto/tls"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	. "github.com/onsi/gomega"
 
 	"istio.io/istio/pilot/pkg/features"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/test/util/retry"
 	"istio.io/istio/pkg/testcerts"
 	"istio.io/pkg/filewatcher"
 )
@@ -251,3 +260,75 @@ func checkCert(t *testing.T, s *Server, cert, key []byte) bool {
 	}
 	return bytes.Equal(actual.Certificate[0], expected.Certificate[0])
 }
+
+func TestIstiodFullReadyHandler(t *testing.T) {
+	oldDepth, oldPending := features.FullReadyMaxPushQueueDepth, features.FullReadyMaxPendingConnections
+	features.FullReadyMaxPushQueueDepth, features.FullReadyMaxPendingConnections = 0, 1
+	defer func() {
+		features.FullReadyMaxPushQueueDepth, features.FullReadyMaxPendingConnections = oldDepth, oldPending
+	}()
+
+	ds := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	s := &Server{
+		XDSServer:           ds.Discovery,
+		readinessProbes:     make(map[string]readinessProbe),
+		fullReadinessProbes: make(map[string]readinessProbe),
+	}
+	ready := true
+	s.addReadinessProbe("discovery", func() (bool, error) {
+		return ready, nil
+	})
+	s.addFullReadinessProbe("push backlog", s.pushBacklogReady)
+
+	probe := func(handler http.HandlerFunc) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec
+	}
+	expect := func(pending, code int) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			rec := probe(s.istiodFullReadyHandler)
+			if got := rec.Header().Get(pendingConnectionsHeader); got != strconv.Itoa(pending) {
+				return fmt.Errorf("got pending connections header %q, want %d", got, pending)
+			}
+			if got := rec.Header().Get(pushQueueDepthHeader); got != "0" {
+				return fmt.Errorf("got push queue depth header %q, want 0", got)
+			}
+			if rec.Code != code {
+				return fmt.Errorf("/ready/full: got %d, want %d", rec.Code, code)
+			}
+			return nil
+		}, retry.Timeout(time.Second))
+		// the kubelet probe is unaffected by the backlog.
+		if got := probe(s.istiodReadyHandler).Code; got != http.StatusOK {
+			t.Errorf("/ready: got %d, want %d", got, http.StatusOK)
+		}
+	}
+
+	expect(0, http.StatusOK)
+	// connections are pending until they are sent their initial config.
+	var streams []discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+	for i := 0; i < 2; i++ {
+		streams = append(streams, ds.ConnectADS())
+	}
+	expect(2, http.StatusServiceUnavailable)
+	for i, stream := range streams {
+		if err := stream.Send(&discovery.DiscoveryRequest{
+			Node:    &core.Node{Id: fmt.Sprintf("sidecar~1.1.1.%d~app%d.default~default.svc.cluster.local", i, i)},
+			TypeUrl: v3.ClusterType,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stream.Recv(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect(0, http.StatusOK)
+
+	// /ready/full is never ready before /ready is.
+	ready = false
+	if got := probe(s.istiodFullReadyHandler).Code; got != http.StatusServiceUnavailable {
+		t.Errorf("/ready/full: got %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
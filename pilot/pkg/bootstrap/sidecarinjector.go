@@ -19,6 +19,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"k8s.io/client-go/kubernetes"
+
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/kube/inject"
 	"istio.io/istio/pkg/webhooks"
@@ -60,6 +62,10 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 		Mux:            s.httpsMux,
 		Revision:       args.Revision,
 	}
+	if features.InjectionMaxInflightPerNamespace > 0 || features.InjectionMaxInflight > 0 {
+		parameters.MaxInflightPerNamespace, parameters.MaxInflight = injectionInflightLimits(s.kubeClient,
+			features.InjectionWebhookConfigName.Get(), features.InjectionMaxInflightPerNamespace, features.InjectionMaxInflight)
+	}
 
 	wh, err := inject.NewWebhook(parameters)
 	if err != nil {
@@ -85,3 +91,26 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 	})
 	return wh, nil
 }
+
+// injectionInflightLimits returns the inflight limits of the injection requests, which are only enforced if the
+// failurePolicy of the injection webhook is Fail. The API server does not retry the throttled requests: with Fail, the
+// pod creation is rejected and retried by its controller, while with Ignore the pod would be created without a sidecar.
+func injectionInflightLimits(client kubernetes.Interface, configName string, perNamespace, total int) (int, int) {
+	if configName == "" || client == nil {
+		log.Warnf("Injection throttling is disabled: the failurePolicy of the injection webhook cannot be checked " +
+			"without INJECTION_WEBHOOK_CONFIG_NAME")
+		return 0, 0
+	}
+	ignore, err := webhooks.FailurePolicyIgnore(client, configName, webhookName)
+	if err != nil {
+		log.Warnf("Injection throttling is disabled: failed to read the failurePolicy of the injection webhook %s: %v",
+			configName, err)
+		return 0, 0
+	}
+	if ignore {
+		log.Warnf("Injection throttling is disabled: the failurePolicy of the injection webhook %s is Ignore, "+
+			"the pods whose injection requests are throttled would be created without a sidecar", configName)
+		return 0, 0
+	}
+	return perNamespace, total
+}
@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"testing"
+
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestInjectionInflightLimits(t *testing.T) {
+	fail := admissionregistrationv1beta1.Fail
+	ignore := admissionregistrationv1beta1.Ignore
+	withPolicy := func(policy *admissionregistrationv1beta1.FailurePolicyType) kubernetes.Interface {
+		return fake.NewSimpleClientset(&admissionregistrationv1beta1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-sidecar-injector"},
+			Webhooks:   []admissionregistrationv1beta1.MutatingWebhook{{Name: webhookName, FailurePolicy: policy}},
+		})
+	}
+	cases := []struct {
+		name       string
+		client     kubernetes.Interface
+		configName string
+		enforced   bool
+	}{
+		{"fail", withPolicy(&fail), "istio-sidecar-injector", true},
+		// The throttled pods would be created without a sidecar.
+		{"ignore", withPolicy(&ignore), "istio-sidecar-injector", false},
+		{"default policy", withPolicy(nil), "istio-sidecar-injector", false},
+		{"missing config", fake.NewSimpleClientset(), "istio-sidecar-injector", false},
+		{"no config name", withPolicy(&fail), "", false},
+		{"no client", nil, "istio-sidecar-injector", false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			perNamespace, total := injectionInflightLimits(tt.client, tt.configName, 10, 100)
+			if tt.enforced && (perNamespace != 10 || total != 100) {
+				t.Fatalf("got limits %d and %d, want 10 and 100", perNamespace, total)
+			}
+			if !tt.enforced && (perNamespace != 0 || total != 0) {
+				t.Fatalf("got limits %d and %d, want throttling to be disabled", perNamespace, total)
+			}
+		})
+	}
+}
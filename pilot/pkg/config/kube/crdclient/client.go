@@ -230,6 +230,19 @@ func (cl *Client) Update(config config.Config) (string, error) {
 	return meta.GetResourceVersion(), nil
 }
 
+// UpdateStatus updates the status of the Istio config, which the CRDs serve as a subresource ignored by Update.
+func (cl *Client) UpdateStatus(config config.Config) (string, error) {
+	if config.Status == nil {
+		return "", fmt.Errorf("nil status for %v/%v", config.Name, config.Namespace)
+	}
+
+	meta, err := updateStatus(cl.istioClient, config, getObjectMetadata(config))
+	if err != nil {
+		return "", err
+	}
+	return meta.GetResourceVersion(), nil
+}
+
 // Delete implements store interface
 func (cl *Client) Delete(typ config.GroupVersionKind, name, namespace string) error {
 	return delete(cl.istioClient, cl.serviceApisClient, typ, name, namespace)
@@ -25,6 +25,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 
+	"istio.io/api/meta/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config"
@@ -174,3 +176,37 @@ func TestClient(t *testing.T) {
 		})
 	}
 }
+
+func TestClientUpdateStatus(t *testing.T) {
+	r := collections.IstioNetworkingV1Alpha3Workloadentries.Resource()
+	store := makeClient(t, collection.NewSchemasBuilder().MustAdd(collections.IstioNetworkingV1Alpha3Workloadentries).Build())
+	configMeta := config.Meta{
+		GroupVersionKind: r.GroupVersionKind(),
+		Name:             "name",
+		Namespace:        "namespace",
+	}
+	if _, err := store.Create(config.Config{
+		Meta: configMeta,
+		Spec: &networking.WorkloadEntry{Address: "10.0.0.1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	status := &v1alpha1.IstioStatus{Conditions: []*v1alpha1.IstioCondition{{Type: "Healthy", Status: "False"}}}
+	if _, err := store.(*Client).UpdateStatus(config.Config{
+		Meta:   configMeta,
+		Spec:   &networking.WorkloadEntry{Address: "10.0.0.1"},
+		Status: status,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		cfg := store.Get(r.GroupVersionKind(), configMeta.Name, configMeta.Namespace)
+		if cfg == nil {
+			return fmt.Errorf("no config")
+		}
+		if got, ok := cfg.Status.(*v1alpha1.IstioStatus); !ok || !reflect.DeepEqual(got, status) {
+			return fmt.Errorf("got status %v, want %v", cfg.Status, status)
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+}
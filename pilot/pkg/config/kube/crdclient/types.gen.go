@@ -33,6 +33,7 @@ import (
 	config "istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collections"
 
+	metav1alpha1 "istio.io/api/meta/v1alpha1"
 	networkingv1alpha3 "istio.io/api/networking/v1alpha3"
 	securityv1beta1 "istio.io/api/security/v1beta1"
 	clientnetworkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
@@ -205,6 +206,83 @@ func update(ic versionedclient.Interface, sc serviceapisclient.Interface, cfg co
 	}
 }
 
+func updateStatus(ic versionedclient.Interface, cfg config.Config, objMeta metav1.ObjectMeta) (metav1.Object, error) {
+	status, ok := cfg.Status.(*metav1alpha1.IstioStatus)
+	if !ok {
+		return nil, fmt.Errorf("unsupported status %T of %v", cfg.Status, cfg.GroupVersionKind)
+	}
+	switch cfg.GroupVersionKind {
+	case collections.IstioNetworkingV1Alpha3Destinationrules.Resource().GroupVersionKind():
+		return ic.NetworkingV1alpha3().DestinationRules(cfg.Namespace).UpdateStatus(context.TODO(), &clientnetworkingv1alpha3.DestinationRule{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*networkingv1alpha3.DestinationRule)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioNetworkingV1Alpha3Envoyfilters.Resource().GroupVersionKind():
+		return ic.NetworkingV1alpha3().EnvoyFilters(cfg.Namespace).UpdateStatus(context.TODO(), &clientnetworkingv1alpha3.EnvoyFilter{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*networkingv1alpha3.EnvoyFilter)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioNetworkingV1Alpha3Gateways.Resource().GroupVersionKind():
+		return ic.NetworkingV1alpha3().Gateways(cfg.Namespace).UpdateStatus(context.TODO(), &clientnetworkingv1alpha3.Gateway{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*networkingv1alpha3.Gateway)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioNetworkingV1Alpha3Serviceentries.Resource().GroupVersionKind():
+		return ic.NetworkingV1alpha3().ServiceEntries(cfg.Namespace).UpdateStatus(context.TODO(), &clientnetworkingv1alpha3.ServiceEntry{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*networkingv1alpha3.ServiceEntry)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioNetworkingV1Alpha3Sidecars.Resource().GroupVersionKind():
+		return ic.NetworkingV1alpha3().Sidecars(cfg.Namespace).UpdateStatus(context.TODO(), &clientnetworkingv1alpha3.Sidecar{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*networkingv1alpha3.Sidecar)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind():
+		return ic.NetworkingV1alpha3().VirtualServices(cfg.Namespace).UpdateStatus(context.TODO(), &clientnetworkingv1alpha3.VirtualService{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*networkingv1alpha3.VirtualService)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioNetworkingV1Alpha3Workloadentries.Resource().GroupVersionKind():
+		return ic.NetworkingV1alpha3().WorkloadEntries(cfg.Namespace).UpdateStatus(context.TODO(), &clientnetworkingv1alpha3.WorkloadEntry{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*networkingv1alpha3.WorkloadEntry)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioNetworkingV1Alpha3Workloadgroups.Resource().GroupVersionKind():
+		return ic.NetworkingV1alpha3().WorkloadGroups(cfg.Namespace).UpdateStatus(context.TODO(), &clientnetworkingv1alpha3.WorkloadGroup{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*networkingv1alpha3.WorkloadGroup)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioSecurityV1Beta1Authorizationpolicies.Resource().GroupVersionKind():
+		return ic.SecurityV1beta1().AuthorizationPolicies(cfg.Namespace).UpdateStatus(context.TODO(), &clientsecurityv1beta1.AuthorizationPolicy{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*securityv1beta1.AuthorizationPolicy)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioSecurityV1Beta1Peerauthentications.Resource().GroupVersionKind():
+		return ic.SecurityV1beta1().PeerAuthentications(cfg.Namespace).UpdateStatus(context.TODO(), &clientsecurityv1beta1.PeerAuthentication{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*securityv1beta1.PeerAuthentication)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	case collections.IstioSecurityV1Beta1Requestauthentications.Resource().GroupVersionKind():
+		return ic.SecurityV1beta1().RequestAuthentications(cfg.Namespace).UpdateStatus(context.TODO(), &clientsecurityv1beta1.RequestAuthentication{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*securityv1beta1.RequestAuthentication)),
+			Status:     *status,
+		}, metav1.UpdateOptions{})
+	default:
+		return nil, fmt.Errorf("unsupported type: %v", cfg.GroupVersionKind)
+	}
+}
+
 func delete(ic versionedclient.Interface, sc serviceapisclient.Interface, typ config.GroupVersionKind, name, namespace string) error {
 	switch typ {
 	case collections.IstioNetworkingV1Alpha3Destinationrules.Resource().GroupVersionKind():
@@ -255,7 +333,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioNetworkingV1Alpha3Envoyfilters.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -270,7 +349,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioNetworkingV1Alpha3Gateways.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -285,7 +365,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioNetworkingV1Alpha3Serviceentries.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -300,7 +381,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioNetworkingV1Alpha3Sidecars.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -315,7 +397,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -330,7 +413,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioNetworkingV1Alpha3Workloadentries.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -345,7 +429,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioNetworkingV1Alpha3Workloadgroups.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -360,7 +445,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioSecurityV1Beta1Authorizationpolicies.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -375,7 +461,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioSecurityV1Beta1Peerauthentications.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -390,7 +477,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.IstioSecurityV1Beta1Requestauthentications.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -405,7 +493,8 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) *config.
 				ResourceVersion:   obj.ResourceVersion,
 				CreationTimestamp: obj.CreationTimestamp.Time,
 			},
-			Spec: &obj.Spec,
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
 		}
 	},
 	collections.K8SServiceApisV1Alpha1Gatewayclasses.Resource().GroupVersionKind(): func(r runtime.Object) *config.Config {
@@ -15,12 +15,16 @@
 package gateway
 
 import (
-	"context"
 	"fmt"
+	"reflect"
+	"sync"
 
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	informersv1 "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	svc "sigs.k8s.io/service-apis/apis/v1alpha1"
 
 	"istio.io/istio/pilot/pkg/model"
@@ -37,16 +41,104 @@ var (
 	errUnsupportedType = fmt.Errorf("unsupported type: this operation only supports gateway & virtual service resource type")
 	_                  = svc.HTTPRoute{}
 	_                  = svc.GatewayClass{}
+
+	// sourceKinds are the kinds converted to Istio resources.
+	sourceKinds = []config.GroupVersionKind{
+		collections.K8SServiceApisV1Alpha1Gatewayclasses.Resource().GroupVersionKind(),
+		collections.K8SServiceApisV1Alpha1Gateways.Resource().GroupVersionKind(),
+		collections.K8SServiceApisV1Alpha1Httproutes.Resource().GroupVersionKind(),
+		collections.K8SServiceApisV1Alpha1Tcproutes.Resource().GroupVersionKind(),
+	}
 )
 
 type controller struct {
 	client kubernetes.Interface
 	cache  model.ConfigStoreCache
 	domain string
+
+	namespaceInformer cache.SharedIndexInformer
+	namespaceLister   listersv1.NamespaceLister
+
+	mu sync.Mutex
+	// generation is incremented whenever a converted resource or a namespace changes.
+	generation uint64
+	// converted caches the conversion output by the namespace it was listed for.
+	converted map[string]convertedResources
+	// conversions counts the calls to convertResources.
+	conversions int
+	// namespaceHandlers are notified when namespace labels change, as routes bound through
+	// namespace selectors may change.
+	namespaceHandlers []func(config.Config, config.Config, model.Event)
+}
+
+type convertedResources struct {
+	generation uint64
+	output     IstioResources
 }
 
 func NewController(client kubernetes.Interface, c model.ConfigStoreCache, options controller2.Options) model.ConfigStoreCache {
-	return &controller{client, c, options.DomainSuffix}
+	namespaceInformer := informersv1.NewNamespaceInformer(client, 0, cache.Indexers{})
+	gatewayController := &controller{
+		client:            client,
+		cache:             c,
+		domain:            options.DomainSuffix,
+		namespaceInformer: namespaceInformer,
+		namespaceLister:   listersv1.NewNamespaceLister(namespaceInformer.GetIndexer()),
+		converted:         map[string]convertedResources{},
+	}
+	for _, kind := range sourceKinds {
+		c.RegisterEventHandler(kind, func(config.Config, config.Config, model.Event) {
+			gatewayController.invalidate()
+		})
+	}
+	namespaceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			gatewayController.invalidate()
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			oldNs, ok := old.(*corev1.Namespace)
+			if !ok {
+				return
+			}
+			curNs, ok := cur.(*corev1.Namespace)
+			if !ok {
+				return
+			}
+			if !reflect.DeepEqual(oldNs.Labels, curNs.Labels) {
+				gatewayController.invalidate()
+				gatewayController.notifyNamespaceChange(curNs)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			gatewayController.invalidate()
+		},
+	})
+	return gatewayController
+}
+
+// invalidate drops the cached conversion output.
+func (c *controller) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+}
+
+// notifyNamespaceChange triggers the handlers registered for gateways, so that the converted resources
+// are listed again.
+func (c *controller) notifyNamespaceChange(ns *corev1.Namespace) {
+	c.mu.Lock()
+	handlers := c.namespaceHandlers
+	c.mu.Unlock()
+	cfg := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: collections.K8SServiceApisV1Alpha1Gateways.Resource().GroupVersionKind(),
+			Name:             ns.Name,
+			Namespace:        ns.Name,
+		},
+	}
+	for _, h := range handlers {
+		h(cfg, cfg, model.EventUpdate)
+	}
 }
 
 func (c *controller) GetLedger() ledger.Ledger {
@@ -64,39 +156,63 @@ func (c *controller) Schemas() collection.Schemas {
 	)
 }
 
-func (c controller) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
+func (c *controller) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
 	panic("get is not supported")
 }
 
-func (c controller) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+func (c *controller) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
 	if typ != gvk.Gateway && typ != gvk.VirtualService {
 		return nil, errUnsupportedType
 	}
 
+	output, err := c.convert(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers may sort the returned slice, so do not hand out the cached one.
+	switch typ {
+	case gvk.Gateway:
+		return append([]config.Config{}, output.Gateway...), nil
+	case gvk.VirtualService:
+		return append([]config.Config{}, output.VirtualService...), nil
+	}
+	return nil, errUnsupportedOp
+}
+
+// convert returns the Istio resources converted from the resources in the namespace, reusing the
+// previous conversion if none of them changed since.
+func (c *controller) convert(namespace string) (IstioResources, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, f := c.converted[namespace]; f && cached.generation == c.generation {
+		return cached.output, nil
+	}
+
 	gatewayClass, err := c.cache.List(collections.K8SServiceApisV1Alpha1Gatewayclasses.Resource().GroupVersionKind(), namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list type GatewayClass: %v", err)
+		return IstioResources{}, fmt.Errorf("failed to list type GatewayClass: %v", err)
 	}
 	gateway, err := c.cache.List(collections.K8SServiceApisV1Alpha1Gateways.Resource().GroupVersionKind(), namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list type Gateway: %v", err)
+		return IstioResources{}, fmt.Errorf("failed to list type Gateway: %v", err)
 	}
 	httpRoute, err := c.cache.List(collections.K8SServiceApisV1Alpha1Httproutes.Resource().GroupVersionKind(), namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list type HTTPRoute: %v", err)
+		return IstioResources{}, fmt.Errorf("failed to list type HTTPRoute: %v", err)
 	}
 	tcpRoute, err := c.cache.List(collections.K8SServiceApisV1Alpha1Tcproutes.Resource().GroupVersionKind(), namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list type TCPRoute: %v", err)
+		return IstioResources{}, fmt.Errorf("failed to list type TCPRoute: %v", err)
 	}
 
-	nsl, err := c.client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	nsl, err := c.namespaceLister.List(klabels.Everything())
 	if err != nil {
-		return nil, fmt.Errorf("failed to list type Namespaces: %v", err)
+		return IstioResources{}, fmt.Errorf("failed to list type Namespaces: %v", err)
 	}
 	namespaces := map[string]*corev1.Namespace{}
-	for i, ns := range nsl.Items {
-		namespaces[ns.Name] = &nsl.Items[i]
+	for _, ns := range nsl {
+		namespaces[ns.Name] = ns
 	}
 	input := &KubernetesResources{
 		GatewayClass: gatewayClass,
@@ -107,45 +223,47 @@ func (c controller) List(typ config.GroupVersionKind, namespace string) ([]confi
 		Domain:       c.domain,
 	}
 	output := convertResources(input)
-
-	switch typ {
-	case gvk.Gateway:
-		return output.Gateway, nil
-	case gvk.VirtualService:
-		return output.VirtualService, nil
-	}
-	return nil, errUnsupportedOp
+	reportConversionErrors(gateway, output.Errors)
+	c.conversions++
+	c.converted[namespace] = convertedResources{generation: c.generation, output: output}
+	return output, nil
 }
 
-func (c controller) Create(config config.Config) (revision string, err error) {
+func (c *controller) Create(config config.Config) (revision string, err error) {
 	return "", errUnsupportedOp
 }
 
-func (c controller) Update(config config.Config) (newRevision string, err error) {
+func (c *controller) Update(config config.Config) (newRevision string, err error) {
 	return "", errUnsupportedOp
 }
 
-func (c controller) Delete(typ config.GroupVersionKind, name, namespace string) error {
+func (c *controller) Delete(typ config.GroupVersionKind, name, namespace string) error {
 	return errUnsupportedOp
 }
 
-func (c controller) Version() string {
+func (c *controller) Version() string {
 	return c.cache.Version()
 }
 
-func (c controller) GetResourceAtVersion(version string, key string) (resourceVersion string, err error) {
+func (c *controller) GetResourceAtVersion(version string, key string) (resourceVersion string, err error) {
 	return c.cache.GetResourceAtVersion(version, key)
 }
 
-func (c controller) RegisterEventHandler(typ config.GroupVersionKind, handler func(config.Config, config.Config, model.Event)) {
+func (c *controller) RegisterEventHandler(typ config.GroupVersionKind, handler func(config.Config, config.Config, model.Event)) {
+	if typ == gvk.Gateway {
+		c.mu.Lock()
+		c.namespaceHandlers = append(c.namespaceHandlers, handler)
+		c.mu.Unlock()
+	}
 	c.cache.RegisterEventHandler(typ, func(prev, cur config.Config, event model.Event) {
 		handler(prev, cur, event)
 	})
 }
 
-func (c controller) Run(stop <-chan struct{}) {
+func (c *controller) Run(stop <-chan struct{}) {
+	c.namespaceInformer.Run(stop)
 }
 
-func (c controller) HasSynced() bool {
-	return c.cache.HasSynced()
+func (c *controller) HasSynced() bool {
+	return c.cache.HasSynced() && c.namespaceInformer.HasSynced()
 }
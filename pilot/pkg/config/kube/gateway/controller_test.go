@@ -15,14 +15,20 @@
 package gateway
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 	svc "sigs.k8s.io/service-apis/apis/v1alpha1"
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
 	controller2 "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
@@ -187,3 +193,129 @@ func TestListVirtualServiceResourceType(t *testing.T) {
 		g.Expect(c.Spec).To(Equal(expectedvs))
 	}
 }
+
+func TestListCachesConversion(t *testing.T) {
+	g := NewWithT(t)
+
+	clientSet := fake.NewSimpleClientset()
+	store := memory.NewSyncController(memory.Make(collections.All))
+	controller := NewController(clientSet, store, controller2.Options{}).(*controller)
+
+	for _, c := range []config.Config{
+		{
+			Meta: config.Meta{
+				GroupVersionKind: collections.K8SServiceApisV1Alpha1Gatewayclasses.Resource().GroupVersionKind(),
+				Name:             "gwclass",
+				Namespace:        "ns1",
+			},
+			Spec: gatewayClassSpec,
+		},
+		{
+			Meta: config.Meta{
+				GroupVersionKind: collections.K8SServiceApisV1Alpha1Gateways.Resource().GroupVersionKind(),
+				Name:             "gwspec",
+				Namespace:        "ns1",
+			},
+			Spec: gatewaySpec,
+		},
+	} {
+		if _, err := store.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, typ := range []config.GroupVersionKind{gvk.Gateway, gvk.VirtualService, gvk.Gateway} {
+		if _, err := controller.List(typ, "ns1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.Expect(controller.conversions).To(Equal(1))
+
+	// A change to any of the converted kinds invalidates the cache.
+	if _, err := store.Create(config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: collections.K8SServiceApisV1Alpha1Httproutes.Resource().GroupVersionKind(),
+			Name:             "http-route",
+			Namespace:        "ns1",
+		},
+		Spec: httpRouteSpec,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	vs, err := controller.List(gvk.VirtualService, "ns1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(vs).To(HaveLen(1))
+	g.Expect(controller.conversions).To(Equal(2))
+}
+
+func TestNamespaceLabelChangeInvalidatesCache(t *testing.T) {
+	g := NewWithT(t)
+
+	routeNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns2"}}
+	clientSet := fake.NewSimpleClientset(routeNamespace)
+	store := memory.NewSyncController(memory.Make(collections.All))
+	controller := NewController(clientSet, store, controller2.Options{}).(*controller)
+	notified := make(chan string, 10)
+	controller.RegisterEventHandler(gvk.Gateway, func(_, cur config.Config, _ model.Event) {
+		notified <- cur.Namespace
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go controller.Run(stop)
+	cache.WaitForCacheSync(stop, controller.HasSynced)
+
+	selectingGateway := gatewaySpec.DeepCopy()
+	selectingGateway.Listeners[0].Routes.RouteNamespaces.NamespaceSelector = metav1.LabelSelector{
+		MatchLabels: map[string]string{"gateway-access": "true"},
+	}
+	for _, c := range []config.Config{
+		{
+			Meta: config.Meta{
+				GroupVersionKind: collections.K8SServiceApisV1Alpha1Gatewayclasses.Resource().GroupVersionKind(),
+				Name:             "gwclass",
+				Namespace:        "ns1",
+			},
+			Spec: gatewayClassSpec,
+		},
+		{
+			Meta: config.Meta{
+				GroupVersionKind: collections.K8SServiceApisV1Alpha1Gateways.Resource().GroupVersionKind(),
+				Name:             "gwspec",
+				Namespace:        "ns1",
+			},
+			Spec: selectingGateway,
+		},
+		{
+			Meta: config.Meta{
+				GroupVersionKind: collections.K8SServiceApisV1Alpha1Httproutes.Resource().GroupVersionKind(),
+				Name:             "http-route",
+				Namespace:        "ns2",
+			},
+			Spec: httpRouteSpec,
+		},
+	} {
+		if _, err := store.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vs, err := controller.List(gvk.VirtualService, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(vs).To(HaveLen(0))
+
+	routeNamespace = routeNamespace.DeepCopy()
+	routeNamespace.Labels = map[string]string{"gateway-access": "true"}
+	if _, err := clientSet.CoreV1().Namespaces().Update(context.TODO(), routeNamespace, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ns := <-notified:
+		g.Expect(ns).To(Equal("ns2"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected gateway handlers to be notified of the namespace label change")
+	}
+	vs, err = controller.List(gvk.VirtualService, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(vs).To(HaveLen(1))
+}
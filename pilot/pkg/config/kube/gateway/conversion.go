@@ -117,16 +117,19 @@ func (r *KubernetesResources) fetchTCPRoutes(gatewayNamespace string, routes k8s
 type IstioResources struct {
 	Gateway        []config.Config
 	VirtualService []config.Config
+	// Errors are the errors of the listeners skipped by the conversion, keyed by the namespace/name of their Gateway.
+	Errors map[string][]error
 }
 
 var _ = k8s.HTTPRoute{}
 
 func convertResources(r *KubernetesResources) IstioResources {
 	result := IstioResources{}
-	gw, routeMap := convertGateway(r)
+	gw, routeMap, errs := convertGateway(r)
 	vs := convertVirtualService(r, routeMap)
 	result.Gateway = gw
 	result.VirtualService = vs
+	result.Errors = errs
 	return result
 }
 
@@ -430,9 +433,10 @@ func getGatewayClasses(r *KubernetesResources) map[string]struct{} {
 	return classes
 }
 
-func convertGateway(r *KubernetesResources) ([]config.Config, map[RouteKey][]string) {
+func convertGateway(r *KubernetesResources) ([]config.Config, map[RouteKey][]string, map[string][]error) {
 	result := []config.Config{}
 	routeToGateway := map[RouteKey][]string{}
+	errs := map[string][]error{}
 	classes := getGatewayClasses(r)
 	for _, obj := range r.Gateway {
 		kgw := obj.Spec.(*k8s.GatewaySpec)
@@ -443,6 +447,12 @@ func convertGateway(r *KubernetesResources) ([]config.Config, map[RouteKey][]str
 		name := obj.Name + "-" + constants.KubernetesGatewayName
 		var servers []*istio.Server
 		for _, l := range kgw.Listeners {
+			tls, err := buildTLS(l.Protocol, l.TLS)
+			if err != nil {
+				key := obj.Namespace + "/" + obj.Name
+				errs[key] = append(errs[key], fmt.Errorf("skipping listener on port %v: %v", l.Port, err))
+				continue
+			}
 			server := &istio.Server{
 				// Allow all hosts here. Specific routing will be determined by the virtual services
 				Hosts: buildHostnameMatch(l.Hostname),
@@ -453,7 +463,7 @@ func convertGateway(r *KubernetesResources) ([]config.Config, map[RouteKey][]str
 					Protocol: string(l.Protocol),
 					Name:     fmt.Sprintf("%v-%v-gateway-%s-%s", strings.ToLower(string(l.Protocol)), l.Port, obj.Name, obj.Namespace),
 				},
-				Tls: buildTLS(l.TLS),
+				Tls: tls,
 			}
 
 			servers = append(servers, server)
@@ -484,7 +494,7 @@ func convertGateway(r *KubernetesResources) ([]config.Config, map[RouteKey][]str
 		}
 		result = append(result, gatewayConfig)
 	}
-	return result, routeToGateway
+	return result, routeToGateway, errs
 }
 
 var tlsVersionConversionMap = map[string]istio.ServerTLSSettings_TLSProtocol{
@@ -494,19 +504,32 @@ var tlsVersionConversionMap = map[string]istio.ServerTLSSettings_TLSProtocol{
 	k8s.TLS1_3: istio.ServerTLSSettings_TLSV1_3,
 }
 
-func buildTLS(tls *k8s.TLSConfig) *istio.ServerTLSSettings {
-	if tls == nil {
-		return nil
+// buildTLS converts the TLS config of a listener. Listeners referencing a certificate terminate TLS
+// with it. As the API has no TLS mode yet, TLS listeners without a certificate pass TLS through to
+// the backends, routed based on SNI.
+func buildTLS(protocol k8s.ProtocolType, tls *k8s.TLSConfig) (*istio.ServerTLSSettings, error) {
+	if tls == nil || len(tls.CertificateRefs) == 0 {
+		switch protocol {
+		case k8s.TLSProtocolType:
+			return &istio.ServerTLSSettings{Mode: istio.ServerTLSSettings_PASSTHROUGH}, nil
+		case k8s.HTTPSProtocolType:
+			return nil, fmt.Errorf("%v listener requires a certificate reference to terminate TLS", protocol)
+		}
+		return nil, nil
 	}
 	// Explicitly not supported: file mounted
-	// Not yet implemented: TLS mode, https redirect, max protocol version, SANs, CipherSuites, VerifyCertificate
+	// Not yet implemented: https redirect, max protocol version, SANs, CipherSuites, VerifyCertificate
 
 	// TODO: "The SNI server_name must match a route host name for the Gateway to route the TLS request."
 	// Do we need to do something smarter here to support ^ ?
+	credentialName, err := buildSecretReference(tls.CertificateRefs)
+	if err != nil {
+		return nil, err
+	}
 	out := &istio.ServerTLSSettings{
 		HttpsRedirect:  false,
 		Mode:           istio.ServerTLSSettings_SIMPLE,
-		CredentialName: buildSecretReference(tls.CertificateRefs),
+		CredentialName: credentialName,
 	}
 	if tls.MinimumVersion != nil {
 		mv, f := tlsVersionConversionMap[*tls.MinimumVersion]
@@ -516,23 +539,26 @@ func buildTLS(tls *k8s.TLSConfig) *istio.ServerTLSSettings {
 			out.MinProtocolVersion = mv
 		}
 	}
-	return out
+	return out, nil
 }
 
-func buildSecretReference(refs []k8s.CertificateObjectReference) string {
-	// No certs provided
-	if len(refs) == 0 {
-		return ""
-	}
+// buildSecretReference returns the credentialName for the certificate references. Certificate references
+// are local to the namespace of the Gateway, so the secret is looked up the same way as any credentialName.
+// References to a secret of another namespace, as namespace/name, are rejected: there is no ReferencePolicy
+// to allow them yet.
+func buildSecretReference(refs []k8s.CertificateObjectReference) (string, error) {
 	ref := refs[0]
 	if len(refs) > 1 {
 		// TODO not sure how this is supposed to be implemented? Somehow needs to align with routes I think
-		log.Errorf("unsupported multiple certificate references")
+		log.Errorf("unsupported multiple certificate references, only %v is used", ref.Name)
+	}
+	if (ref.Group != "" && ref.Group != "v1" && ref.Group != "core") || (ref.Resource != "" && ref.Resource != "secrets") {
+		return "", fmt.Errorf("invalid certificate reference %v/%v/%v, only secrets are allowed", ref.Group, ref.Resource, ref.Name)
 	}
-	if (ref.Group != "" && ref.Group != "v1") || (ref.Resource != "" && ref.Resource != "secrets") {
-		log.Errorf("invalid certificate reference %v, only secret is allowed", ref)
+	if strings.Contains(ref.Name, "/") {
+		return "", fmt.Errorf("invalid certificate reference %v, secrets of other namespaces are not allowed", ref.Name)
 	}
-	return ref.Name
+	return ref.Name, nil
 }
 
 func buildHostnameMatch(hostname k8s.HostnameMatch) []string {
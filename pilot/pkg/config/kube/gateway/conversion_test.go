@@ -22,7 +22,9 @@ import (
 
 	"github.com/d4l3k/messagediff"
 	"github.com/ghodss/yaml"
+	k8s "sigs.k8s.io/service-apis/apis/v1alpha1"
 
+	istio "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/config"
@@ -31,13 +33,34 @@ import (
 )
 
 func TestConvertResources(t *testing.T) {
-	cases := []string{"simple", "mismatch", "tls", "weighted"}
+	cases := []struct {
+		name string
+		// errors is the expected number of listeners skipped by the conversion, by Gateway.
+		errors map[string]int
+	}{
+		{name: "simple"},
+		{name: "mismatch"},
+		{name: "tls", errors: map[string]int{"istio-system/gateway": 2}},
+		{name: "weighted"},
+	}
 	for _, tt := range cases {
-		t.Run(tt, func(t *testing.T) {
-			input := readConfig(t, fmt.Sprintf("testdata/%s.yaml", tt))
+		t.Run(tt.name, func(t *testing.T) {
+			input := readConfig(t, fmt.Sprintf("testdata/%s.yaml", tt.name))
 			output := convertResources(splitInput(input))
 
-			goldenFile := fmt.Sprintf("testdata/%s.yaml.golden", tt)
+			errors := map[string]int{}
+			for gw, errs := range output.Errors {
+				errors[gw] = len(errs)
+			}
+			if tt.errors == nil {
+				tt.errors = map[string]int{}
+			}
+			if !reflect.DeepEqual(errors, tt.errors) {
+				t.Fatalf("got conversion errors %v, want %v", output.Errors, tt.errors)
+			}
+			output.Errors = nil
+
+			goldenFile := fmt.Sprintf("testdata/%s.yaml.golden", tt.name)
 			if util.Refresh() {
 				res := append(output.Gateway, output.VirtualService...)
 				if err := ioutil.WriteFile(goldenFile, marshalYaml(t, res), 0644); err != nil {
@@ -146,3 +169,62 @@ func TestStandardizeWeight(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol k8s.ProtocolType
+		tls      *k8s.TLSConfig
+		want     *istio.ServerTLSSettings
+		wantErr  bool
+	}{
+		{
+			name:     "terminate",
+			protocol: k8s.HTTPSProtocolType,
+			tls:      &k8s.TLSConfig{CertificateRefs: []k8s.CertificateObjectReference{{Name: "cert"}}},
+			want:     &istio.ServerTLSSettings{Mode: istio.ServerTLSSettings_SIMPLE, CredentialName: "cert"},
+		},
+		{
+			name:     "passthrough",
+			protocol: k8s.TLSProtocolType,
+			want:     &istio.ServerTLSSettings{Mode: istio.ServerTLSSettings_PASSTHROUGH},
+		},
+		{
+			name:     "plaintext",
+			protocol: k8s.HTTPProtocolType,
+		},
+		{
+			name:     "https without certificate",
+			protocol: k8s.HTTPSProtocolType,
+			tls:      &k8s.TLSConfig{},
+			wantErr:  true,
+		},
+		{
+			name:     "cross namespace certificate",
+			protocol: k8s.HTTPSProtocolType,
+			tls: &k8s.TLSConfig{CertificateRefs: []k8s.CertificateObjectReference{
+				{Name: "other-namespace/cert"},
+			}},
+			wantErr: true,
+		},
+		{
+			name:     "non secret certificate",
+			protocol: k8s.HTTPSProtocolType,
+			tls: &k8s.TLSConfig{CertificateRefs: []k8s.CertificateObjectReference{
+				{Group: "v1", Resource: "configmaps", Name: "cert"},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTLS(tt.protocol, tt.tls)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildTLS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildTLS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"istio.io/istio/pkg/config"
+	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	nameTag = monitoring.MustCreateLabel("name")
+
+	conversionErrors = monitoring.NewGauge(
+		"pilot_k8s_gateway_conversion_errors",
+		"Number of listeners of a Gateway skipped when converting it to Istio resources.",
+		monitoring.WithLabels(nameTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(conversionErrors)
+}
+
+// reportConversionErrors logs the conversion errors of the gateways and records their number, including
+// zero so that the metric is reset once a gateway is fixed.
+func reportConversionErrors(gateways []config.Config, errs map[string][]error) {
+	for _, gw := range gateways {
+		key := gw.Namespace + "/" + gw.Name
+		for _, err := range errs[key] {
+			log.Errorf("gateway %s: %v", key, err)
+		}
+		conversionErrors.With(nameTag.Value(key)).Record(float64(len(errs[key])))
+	}
+}
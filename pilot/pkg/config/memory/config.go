@@ -192,7 +192,7 @@ func (cr *store) Create(cfg config.Config) (string, error) {
 			cfg.CreationTimestamp = tnow
 		}
 
-		_, err := cr.ledger.Put(config.Key(kind.Kind, cfg.Namespace, cfg.Name), cfg.ResourceVersion)
+		_, err := cr.ledger.Put(config.Key(kind.Kind, cfg.Name, cfg.Namespace), cfg.ResourceVersion)
 		if err != nil {
 			log.Warnf(ledgerLogf, err)
 		}
@@ -226,7 +226,7 @@ func (cr *store) Update(cfg config.Config) (string, error) {
 
 	rev := time.Now().String()
 	cfg.ResourceVersion = rev
-	_, err := cr.ledger.Put(config.Key(kind.Kind, cfg.Namespace, cfg.Name), cfg.ResourceVersion)
+	_, err := cr.ledger.Put(config.Key(kind.Kind, cfg.Name, cfg.Namespace), cfg.ResourceVersion)
 	if err != nil {
 		log.Warnf(ledgerLogf, err)
 	}
@@ -0,0 +1,197 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workloadentry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+
+	"istio.io/api/meta/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/queue"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/pkg/log"
+)
+
+// HealthCondition is the type of the condition of the status of a WorkloadEntry holding the health of its workload,
+// as last reported by its agent: the workload is unhealthy if the condition status is False, with the reason in its
+// message. Agents older than the health reporting never report it, and an entry without the condition is healthy.
+const HealthCondition = "Healthy"
+
+var healthLog = log.RegisterScope("wlehealth", "WorkloadEntry health reporting", 0)
+
+// HealthEvent is the health of a workload, as reported by its agent.
+type HealthEvent struct {
+	Healthy bool
+	// Message is the reason the workload is unhealthy.
+	Message string
+}
+
+// IsHealthy returns false if the WorkloadEntry was last reported unhealthy. Entries without a reported
+// health are healthy.
+func IsHealthy(cfg config.Config) bool {
+	c := healthCondition(cfg.Status)
+	return c == nil || c.Status != "False"
+}
+
+// healthCondition returns the health condition of the status of a WorkloadEntry, if any.
+func healthCondition(status config.Status) *v1alpha1.IstioCondition {
+	s, ok := status.(*v1alpha1.IstioStatus)
+	if !ok || s == nil {
+		return nil
+	}
+	for _, c := range s.Conditions {
+		if c.Type == HealthCondition {
+			return c
+		}
+	}
+	return nil
+}
+
+// statusUpdater is implemented by the config stores keeping the status of the configs apart from their spec, such
+// as the CRD client. The other stores update the status with the config.
+type statusUpdater interface {
+	UpdateStatus(config.Config) (string, error)
+}
+
+// Controller records the health of the workloads reported by their agents in the status of their WorkloadEntry.
+type Controller struct {
+	store model.ConfigStore
+	queue queue.Instance
+}
+
+// NewController creates a Controller updating the WorkloadEntries in store. It must be started with Run.
+func NewController(store model.ConfigStore) *Controller {
+	return &Controller{
+		store: store,
+		queue: queue.NewQueue(time.Second),
+	}
+}
+
+// Run processes the health events until stop is closed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	c.queue.Run(stop)
+}
+
+// QueueWorkloadEntryHealth queues the update of the WorkloadEntries of the proxy with the health reported by its
+// agent. The addresses of the proxy are reported by the proxy itself, so only the entries of the authenticated
+// identities of its connection, with their namespace and service account, are updated: a proxy cannot change the
+// health of the workloads of other service accounts. Nothing is updated for a connection without identities.
+func (c *Controller) QueueWorkloadEntryHealth(proxy *model.Proxy, identities []spiffe.Identity, event HealthEvent) {
+	if len(identities) == 0 {
+		healthLog.Debugf("ignoring the health reported by %s, which has no authenticated identity", proxy.ID)
+		return
+	}
+	identities = append([]spiffe.Identity(nil), identities...)
+	addresses := append([]string(nil), proxy.IPAddresses...)
+	c.queue.Push(func() error {
+		for _, id := range identities {
+			if err := c.updateWorkloadEntryHealth(id, addresses, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updateWorkloadEntryHealth updates the entries of the namespace and service account of the identity with one of
+// the addresses.
+func (c *Controller) updateWorkloadEntryHealth(id spiffe.Identity, addresses []string, event HealthEvent) error {
+	entries, err := c.store.List(gvk.WorkloadEntry, id.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list the workload entries of namespace %s: %v", id.Namespace, err)
+	}
+	status, message := "True", ""
+	if !event.Healthy {
+		status, message = "False", event.Message
+	}
+	for _, cfg := range entries {
+		wle := cfg.Spec.(*networking.WorkloadEntry)
+		if serviceAccount(wle) != id.ServiceAccount || !containsAddress(addresses, wle.Address) {
+			continue
+		}
+		if c := healthCondition(cfg.Status); c != nil && c.Status == status && c.Message == message {
+			continue
+		}
+		updated := cfg.DeepCopy()
+		updated.Status = withHealthCondition(cfg.Status, status, message)
+		// A conflicting update is retried by the queue, with the entry listed again.
+		if err := c.updateStatus(updated); err != nil {
+			return fmt.Errorf("failed to update the health of workload entry %s/%s: %v", cfg.Namespace, cfg.Name, err)
+		}
+		healthLog.Infof("workload entry %s/%s health is %s %s", cfg.Namespace, cfg.Name, status, message)
+	}
+	return nil
+}
+
+func (c *Controller) updateStatus(cfg config.Config) error {
+	var err error
+	if su, ok := c.store.(statusUpdater); ok {
+		_, err = su.UpdateStatus(cfg)
+	} else {
+		_, err = c.store.Update(cfg)
+	}
+	return err
+}
+
+// withHealthCondition returns a copy of the status of a WorkloadEntry with its health condition set, keeping the
+// other conditions.
+func withHealthCondition(current config.Status, status, message string) *v1alpha1.IstioStatus {
+	out := &v1alpha1.IstioStatus{}
+	if s, ok := current.(*v1alpha1.IstioStatus); ok && s != nil {
+		out = config.DeepCopy(s).(*v1alpha1.IstioStatus)
+	}
+	now := types.TimestampNow()
+	condition := &v1alpha1.IstioCondition{
+		Type:               HealthCondition,
+		Status:             status,
+		LastProbeTime:      now,
+		LastTransitionTime: now,
+		Message:            message,
+	}
+	for i, c := range out.Conditions {
+		if c.Type == HealthCondition {
+			if c.Status == status {
+				condition.LastTransitionTime = c.LastTransitionTime
+			}
+			out.Conditions[i] = condition
+			return out
+		}
+	}
+	out.Conditions = append(out.Conditions, condition)
+	return out
+}
+
+// serviceAccount returns the service account of the entry, default if unset.
+func serviceAccount(wle *networking.WorkloadEntry) string {
+	if wle.ServiceAccount == "" {
+		return "default"
+	}
+	return wle.ServiceAccount
+}
+
+func containsAddress(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
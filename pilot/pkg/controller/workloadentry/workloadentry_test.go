@@ -0,0 +1,154 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workloadentry
+
+import (
+	"testing"
+
+	"istio.io/api/meta/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/spiffe"
+)
+
+func TestUpdateWorkloadEntryHealth(t *testing.T) {
+	store := memory.Make(collections.Pilot)
+	for _, we := range []struct{ name, namespace, address, serviceAccount string }{
+		{"vm-1", "default", "10.0.0.1", "vm"},
+		{"vm-2", "default", "10.0.0.2", "vm"},
+		{"vm-1", "other", "10.0.0.1", "vm"},
+		{"db-1", "default", "10.0.0.1", ""},
+	} {
+		if _, err := store.Create(config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.WorkloadEntry,
+				Name:             we.name,
+				Namespace:        we.namespace,
+			},
+			Spec: &networking.WorkloadEntry{Address: we.address, ServiceAccount: we.serviceAccount},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewController(store)
+	get := func(name, namespace string) config.Config {
+		t.Helper()
+		cfg := store.Get(gvk.WorkloadEntry, name, namespace)
+		if cfg == nil {
+			t.Fatalf("workload entry %s/%s not found", namespace, name)
+		}
+		return *cfg
+	}
+	vm := spiffe.Identity{TrustDomain: "cluster.local", Namespace: "default", ServiceAccount: "vm"}
+
+	if err := c.updateWorkloadEntryHealth(vm, []string{"10.0.0.1"}, HealthEvent{Message: "connection refused"}); err != nil {
+		t.Fatal(err)
+	}
+	unhealthy := get("vm-1", "default")
+	cond := healthCondition(unhealthy.Status)
+	if IsHealthy(unhealthy) || cond.Message != "connection refused" {
+		t.Fatalf("expected vm-1 to be unhealthy, got status %v", unhealthy.Status)
+	}
+	// Only the entries of the namespace and service account of the proxy with its address are updated.
+	for _, cfg := range []config.Config{get("vm-2", "default"), get("vm-1", "other"), get("db-1", "default")} {
+		if cfg.Status != nil {
+			t.Errorf("unexpected health of %s/%s: %v", cfg.Namespace, cfg.Name, cfg.Status)
+		}
+	}
+
+	// An unchanged health does not update the entry.
+	if err := c.updateWorkloadEntryHealth(vm, []string{"10.0.0.1"}, HealthEvent{Message: "connection refused"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("vm-1", "default").ResourceVersion; got != unhealthy.ResourceVersion {
+		t.Errorf("expected the entry not to be updated, got version %s, want %s", got, unhealthy.ResourceVersion)
+	}
+
+	if err := c.updateWorkloadEntryHealth(vm, []string{"10.0.0.1"}, HealthEvent{Healthy: true}); err != nil {
+		t.Fatal(err)
+	}
+	healthy := get("vm-1", "default")
+	cond = healthCondition(healthy.Status)
+	if !IsHealthy(healthy) || cond == nil || cond.Status != "True" {
+		t.Fatalf("expected vm-1 to be healthy, got status %v", healthy.Status)
+	}
+	if cond.Message != "" {
+		t.Errorf("unexpected health message of a healthy entry: %v", cond.Message)
+	}
+}
+
+func TestHealthConditionKeepsOtherConditions(t *testing.T) {
+	reconciled := &v1alpha1.IstioCondition{Type: "Reconciled", Status: "True"}
+	current := &v1alpha1.IstioStatus{Conditions: []*v1alpha1.IstioCondition{reconciled}}
+
+	unhealthy := withHealthCondition(current, "False", "connection refused")
+	if len(unhealthy.Conditions) != 2 || unhealthy.Conditions[0].Type != "Reconciled" {
+		t.Fatalf("unexpected conditions %v", unhealthy.Conditions)
+	}
+	if len(current.Conditions) != 1 {
+		t.Fatalf("the current status was modified: %v", current.Conditions)
+	}
+	// The transition time is kept while the health does not change.
+	again := withHealthCondition(unhealthy, "False", "timeout")
+	if got, want := healthCondition(again).LastTransitionTime, healthCondition(unhealthy).LastTransitionTime; !got.Equal(want) {
+		t.Errorf("got transition time %v, want %v", got, want)
+	}
+}
+
+func TestIsHealthyWithoutReport(t *testing.T) {
+	// The entries of the workloads of agents not reporting their health, e.g. older agents, are healthy.
+	for _, status := range []config.Status{nil, &v1alpha1.IstioStatus{}, &v1alpha1.IstioStatus{
+		Conditions: []*v1alpha1.IstioCondition{{Type: "Reconciled", Status: "False"}},
+	}} {
+		if !IsHealthy(config.Config{Status: status}) {
+			t.Errorf("expected an entry with status %v to be healthy", status)
+		}
+	}
+}
+
+func TestQueueWorkloadEntryHealthWithoutIdentity(t *testing.T) {
+	store := memory.Make(collections.Pilot)
+	if _, err := store.Create(config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.WorkloadEntry,
+			Name:             "vm-1",
+			Namespace:        "default",
+		},
+		Spec: &networking.WorkloadEntry{Address: "10.0.0.1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c := NewController(store)
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.Run(stop)
+
+	proxy := &model.Proxy{ID: "vm-1.default", IPAddresses: []string{"10.0.0.1"}, ConfigNamespace: "default"}
+	c.QueueWorkloadEntryHealth(proxy, nil, HealthEvent{Message: "connection refused"})
+	// The queue processes the tasks in order.
+	done := make(chan struct{})
+	c.queue.Push(func() error {
+		close(done)
+		return nil
+	})
+	<-done
+	if cfg := store.Get(gvk.WorkloadEntry, "vm-1", "default"); !IsHealthy(*cfg) {
+		t.Fatalf("the health reported by a proxy without identity was recorded: %v", cfg.Status)
+	}
+}
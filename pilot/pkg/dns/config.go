@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds the operator provided configuration of the agent DNS server. It complements
+// the names learned from istiod, which always take precedence over it.
+type Config struct {
+	// StaticEntries are answered locally, for legacy hosts that are not in any registry.
+	StaticEntries []StaticEntry `json:"staticEntries,omitempty"`
+	// ForwardZones forward the queries for names in a zone to dedicated resolvers,
+	// instead of the ones in resolv.conf.
+	ForwardZones []ForwardZone `json:"forwardZones,omitempty"`
+}
+
+// StaticEntry maps a hostname to a set of IPv4 and/or IPv6 addresses.
+type StaticEntry struct {
+	Hostname  string   `json:"hostname"`
+	Addresses []string `json:"addresses"`
+	// TTL of the answers in seconds. Defaults to 30 seconds.
+	TTL uint32 `json:"ttl,omitempty"`
+}
+
+// ForwardZone forwards the queries for a zone (e.g. consul.) to a set of upstream resolvers.
+type ForwardZone struct {
+	Zone string `json:"zone"`
+	// Upstreams are the resolvers to use, as host or host:port. The port defaults to 53.
+	Upstreams []string `json:"upstreams"`
+}
+
+// ReadConfig reads and validates the Config from the given YAML or JSON file.
+func ReadConfig(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses and validates a YAML or JSON Config. Hostnames and zones are normalized
+// to lower case FQDNs, and upstreams to host:port.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse dns config: %v", err)
+	}
+	for i := range cfg.StaticEntries {
+		e := &cfg.StaticEntries[i]
+		if e.Hostname == "" {
+			return nil, fmt.Errorf("static entry %d: hostname is required", i)
+		}
+		e.Hostname = dns.Fqdn(strings.ToLower(e.Hostname))
+		if len(e.Addresses) == 0 {
+			return nil, fmt.Errorf("static entry %s: at least one address is required", e.Hostname)
+		}
+		for _, addr := range e.Addresses {
+			if net.ParseIP(addr) == nil {
+				return nil, fmt.Errorf("static entry %s: invalid address %q", e.Hostname, addr)
+			}
+		}
+		if e.TTL == 0 {
+			e.TTL = defaultTTLInSeconds
+		}
+	}
+	for i := range cfg.ForwardZones {
+		z := &cfg.ForwardZones[i]
+		if z.Zone == "" {
+			return nil, fmt.Errorf("forward zone %d: zone is required", i)
+		}
+		z.Zone = dns.Fqdn(strings.ToLower(z.Zone))
+		if len(z.Upstreams) == 0 {
+			return nil, fmt.Errorf("forward zone %s: at least one upstream is required", z.Zone)
+		}
+		for j, upstream := range z.Upstreams {
+			if _, _, err := net.SplitHostPort(upstream); err != nil {
+				upstream = net.JoinHostPort(upstream, "53")
+			}
+			z.Upstreams[j] = upstream
+		}
+	}
+	return cfg, nil
+}
+
+// localConfig is the Config compiled for lookups at query time.
+type localConfig struct {
+	static *LookupTable
+	// forwardZones are sorted by the length of the zone, longest first, so the most specific zone wins.
+	forwardZones []ForwardZone
+}
+
+func newLocalConfig(cfg *Config, searchNamespaces []string) *localConfig {
+	lc := &localConfig{
+		static: &LookupTable{
+			name4: map[string][]dns.RR{},
+			name6: map[string][]dns.RR{},
+			cname: map[string][]dns.RR{},
+		},
+		forwardZones: append([]ForwardZone{}, cfg.ForwardZones...),
+	}
+	for _, e := range cfg.StaticEntries {
+		ipv4, ipv6 := separateIPtypes(e.Addresses)
+		lc.static.buildDNSAnswers([]string{e.Hostname}, ipv4, ipv6, searchNamespaces)
+		for _, rr := range append(lc.static.name4[e.Hostname], lc.static.name6[e.Hostname]...) {
+			rr.Header().Ttl = e.TTL
+		}
+	}
+	sort.SliceStable(lc.forwardZones, func(i, j int) bool {
+		return len(lc.forwardZones[i].Zone) > len(lc.forwardZones[j].Zone)
+	})
+	return lc
+}
+
+// lookupHost returns the static answers for the host, if any, and whether the host has a static entry.
+// A host with a static entry but no address of the queried type, e.g. an AAAA query for an IPv4 only
+// entry, has no answers: it is answered locally with NODATA, as the upstreams don't know the host.
+func (lc *localConfig) lookupHost(qtype uint16, host string) ([]dns.RR, bool) {
+	if lc == nil {
+		return nil, false
+	}
+	if answers := lc.static.lookupHost(qtype, host); len(answers) > 0 {
+		return answers, true
+	}
+	if qtype != dns.TypeA && qtype != dns.TypeAAAA {
+		return nil, false
+	}
+	_, found4 := lc.static.name4[host]
+	_, found6 := lc.static.name6[host]
+	return nil, found4 || found6
+}
+
+// upstreams returns the resolvers of the most specific zone the host belongs to, or nil if
+// the host is not in any forward zone.
+func (lc *localConfig) upstreams(host string) []string {
+	if lc == nil {
+		return nil
+	}
+	for _, z := range lc.forwardZones {
+		if dns.IsSubDomain(z.Zone, host) {
+			return z.Upstreams
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	nds "istio.io/istio/pilot/pkg/proto"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// recorder is a dns.ResponseWriter recording the written message.
+type recorder struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (r *recorder) WriteMsg(m *dns.Msg) error {
+	r.msg = m
+	return nil
+}
+
+// startStubResolver starts a resolver answering every A query with the given IP.
+func startStubResolver(t *testing.T, ip string) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{
+		PacketConn:        pc,
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(req)
+			m.Answer = a(req.Question[0].Name, []net.IP{net.ParseIP(ip).To4()})
+			_ = w.WriteMsg(m)
+		}),
+	}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+	<-started
+	return pc.LocalAddr().String()
+}
+
+func newTestLocalDNSServer() *LocalDNSServer {
+	h := &LocalDNSServer{
+		upstreamClient: &dns.Client{Net: "udp", ReadTimeout: time.Second},
+	}
+	h.UpdateLookupTable(&nds.NameTable{
+		Table: map[string]*nds.NameTable_NameInfo{
+			"mesh.service.consul": {
+				Ips:      []string{"9.9.9.9"},
+				Registry: "External",
+			},
+		},
+	})
+	return h
+}
+
+func query(h *LocalDNSServer, host string, qtype uint16) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(host, qtype)
+	w := &recorder{}
+	h.ServeDNS(w, req)
+	return w.msg
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+staticEntries:
+- hostname: Legacy.Example.com
+  addresses: [1.2.3.4, "2001:db8::1"]
+forwardZones:
+- zone: consul
+  upstreams: [10.0.0.1, "10.0.0.2:8600"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.StaticEntries[0]; got.Hostname != "legacy.example.com." || got.TTL != defaultTTLInSeconds {
+		t.Errorf("unexpected static entry %+v", got)
+	}
+	if got := cfg.ForwardZones[0]; got.Zone != "consul." ||
+		got.Upstreams[0] != "10.0.0.1:53" || got.Upstreams[1] != "10.0.0.2:8600" {
+		t.Errorf("unexpected forward zone %+v", got)
+	}
+
+	for _, invalid := range []string{
+		`staticEntries: [{hostname: foo, addresses: [not-an-ip]}]`,
+		`staticEntries: [{hostname: foo}]`,
+		`forwardZones: [{zone: consul}]`,
+		`unknown: true`,
+	} {
+		if _, err := ParseConfig([]byte(invalid)); err == nil {
+			t.Errorf("expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestStaticEntriesAndForwardZones(t *testing.T) {
+	upstream := startStubResolver(t, "7.7.7.7")
+	h := newTestLocalDNSServer()
+	h.SetConfig(&Config{
+		StaticEntries: []StaticEntry{
+			{Hostname: "legacy.example.com.", Addresses: []string{"1.2.3.4", "2001:db8::1"}, TTL: 300},
+			{Hostname: "mesh.service.consul.", Addresses: []string{"5.5.5.5"}, TTL: 300},
+			{Hostname: "ipv4.example.com.", Addresses: []string{"1.2.3.5"}},
+		},
+		ForwardZones: []ForwardZone{{Zone: "consul.", Upstreams: []string{upstream}}},
+	})
+
+	staticA := a("legacy.example.com.", []net.IP{net.ParseIP("1.2.3.4").To4()})
+	staticA[0].Header().Ttl = 300
+	staticAAAA := aaaa("legacy.example.com.", []net.IP{net.ParseIP("2001:db8::1")})
+	staticAAAA[0].Header().Ttl = 300
+
+	cases := []struct {
+		name  string
+		host  string
+		qtype uint16
+		want  []dns.RR
+	}{
+		{
+			name:  "static A",
+			host:  "legacy.example.com.",
+			qtype: dns.TypeA,
+			want:  staticA,
+		},
+		{
+			name:  "static AAAA",
+			host:  "legacy.example.com.",
+			qtype: dns.TypeAAAA,
+			want:  staticAAAA,
+		},
+		{
+			name:  "static AAAA for an IPv4 only entry has no data",
+			host:  "ipv4.example.com.",
+			qtype: dns.TypeAAAA,
+		},
+		{
+			name:  "forward zone",
+			host:  "db.service.consul.",
+			qtype: dns.TypeA,
+			want:  a("db.service.consul.", []net.IP{net.ParseIP("7.7.7.7").To4()}),
+		},
+		{
+			name:  "mesh service takes precedence",
+			host:  "mesh.service.consul.",
+			qtype: dns.TypeA,
+			want:  a("mesh.service.consul.", []net.IP{net.ParseIP("9.9.9.9").To4()}),
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res := query(h, tt.host, tt.qtype)
+			if res.Rcode != dns.RcodeSuccess {
+				t.Fatalf("expected success for %s, got rcode %v", tt.host, res.Rcode)
+			}
+			if !equalsDNSrecords(res.Answer, tt.want) {
+				t.Errorf("dns responses for %s do not match. \n got %v\nwant %v", tt.host, res.Answer, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dns.yaml")
+	writeConfig := func(ip string) {
+		t.Helper()
+		cfg := fmt.Sprintf("staticEntries: [{hostname: legacy.example.com, addresses: [%s]}]", ip)
+		if err := ioutil.WriteFile(file, []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("1.1.1.1")
+
+	h := newTestLocalDNSServer()
+	if err := h.WatchConfigFile(file); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	expectAnswer := func(ip string) {
+		t.Helper()
+		want := a("legacy.example.com.", []net.IP{net.ParseIP(ip).To4()})
+		retry.UntilSuccessOrFail(t, func() error {
+			if got := query(h, "legacy.example.com.", dns.TypeA).Answer; !equalsDNSrecords(got, want) {
+				return fmt.Errorf("got %v, want %v", got, want)
+			}
+			return nil
+		}, retry.Timeout(5*time.Second))
+	}
+	expectAnswer("1.1.1.1")
+
+	writeConfig("2.2.2.2")
+	expectAnswer("2.2.2.2")
+
+	// An invalid config keeps the previous one.
+	if err := ioutil.WriteFile(file, []byte("staticEntries: [{hostname: foo}]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	expectAnswer("2.2.2.2")
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/miekg/dns"
 
 	nds "istio.io/istio/pilot/pkg/proto"
+	"istio.io/pkg/filewatcher"
 	"istio.io/pkg/log"
 )
 
@@ -30,6 +31,11 @@ import (
 type LocalDNSServer struct {
 	// Holds the pointer to the DNS lookup table
 	lookupTable atomic.Value
+	// Holds the pointer to the compiled operator provided Config
+	localConfig atomic.Value
+	// configWatcher watches the Config file, if any, and stopConfigWatch stops the reloads.
+	configWatcher   filewatcher.FileWatcher
+	stopConfigWatch chan struct{}
 
 	downstreamMux    *dns.ServeMux
 	downstreamServer *dns.Server
@@ -130,6 +136,52 @@ func (h *LocalDNSServer) StartDNS() {
 	}()
 }
 
+// SetConfig replaces the operator provided static entries and forward zones.
+func (h *LocalDNSServer) SetConfig(cfg *Config) {
+	h.localConfig.Store(newLocalConfig(cfg, h.searchNamespaces))
+}
+
+// WatchConfigFile loads the Config from the given file, and reloads it whenever the file changes.
+// If a reload fails, the previous Config is kept.
+func (h *LocalDNSServer) WatchConfigFile(filename string) error {
+	cfg, err := ReadConfig(filename)
+	if err != nil {
+		return err
+	}
+	h.SetConfig(cfg)
+
+	h.configWatcher = filewatcher.NewWatcher()
+	if err := h.configWatcher.Add(filename); err != nil {
+		return err
+	}
+	h.stopConfigWatch = make(chan struct{})
+	events, stop := h.configWatcher.Events(filename), h.stopConfigWatch
+	go func() {
+		var timerC <-chan time.Time
+		for {
+			select {
+			case <-stop:
+				return
+			case <-timerC:
+				timerC = nil
+				cfg, err := ReadConfig(filename)
+				if err != nil {
+					log.Warnf("failed to reload dns config from %s, keeping the previous one: %v", filename, err)
+					continue
+				}
+				log.Infof("reloaded dns config from %s", filename)
+				h.SetConfig(cfg)
+			case <-events:
+				// Use a timer to debounce configuration updates
+				if timerC == nil {
+					timerC = time.After(100 * time.Millisecond)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
 func (h *LocalDNSServer) UpdateLookupTable(nt *nds.NameTable) {
 	lookupTable := &LookupTable{
 		name4: map[string][]dns.RR{},
@@ -170,25 +222,29 @@ func (h *LocalDNSServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 		// clients usually do not do more than one query either.
 
 		lookupTable := h.lookupTable.Load().(*LookupTable)
-		var answers []dns.RR
+		cfg, _ := h.localConfig.Load().(*localConfig)
 
 		// This name will always end in a dot
 		hostname := strings.ToLower(req.Question[0].Name)
 
-		switch req.Question[0].Qtype {
-		case dns.TypeA:
-			answers = lookupTable.lookupHostIPv4(hostname)
-		case dns.TypeAAAA:
-			answers = lookupTable.lookupHostIPv6(hostname)
-			// TODO: handle PTR records for reverse dns lookups
+		// Names known to the mesh take precedence over the static entries and forward zones.
+		answers := lookupTable.lookupHost(req.Question[0].Qtype, hostname)
+		var static bool
+		if len(answers) == 0 {
+			answers, static = cfg.lookupHost(req.Question[0].Qtype, hostname)
 		}
 
-		if len(answers) > 0 {
+		if len(answers) > 0 || static {
+			dnsAnswered.Increment()
 			response = new(dns.Msg)
 			response.SetReply(req)
 			response.Answer = answers
 		} else {
-			response = h.queryUpstream(req)
+			upstreams := cfg.upstreams(hostname)
+			if upstreams == nil {
+				upstreams = h.resolvConfServers
+			}
+			response = h.queryUpstream(upstreams, req)
 		}
 	}
 
@@ -196,6 +252,11 @@ func (h *LocalDNSServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 }
 
 func (h *LocalDNSServer) Close() {
+	if h.configWatcher != nil {
+		close(h.stopConfigWatch)
+		_ = h.configWatcher.Close()
+		h.configWatcher = nil
+	}
 	if h.downstreamServer != nil {
 		if err := h.downstreamServer.Shutdown(); err != nil {
 			log.Errorf("error in shutting down dns downstreamServer :%v", err)
@@ -204,9 +265,9 @@ func (h *LocalDNSServer) Close() {
 }
 
 // TODO: Figure out how to send parallel queries to all nameservers
-func (h *LocalDNSServer) queryUpstream(req *dns.Msg) *dns.Msg {
+func (h *LocalDNSServer) queryUpstream(upstreams []string, req *dns.Msg) *dns.Msg {
 	var response *dns.Msg
-	for _, upstream := range h.resolvConfServers {
+	for _, upstream := range upstreams {
 		cResponse, _, err := h.upstreamClient.Exchange(req, upstream)
 		if err == nil && len(cResponse.Answer) > 0 {
 			response = cResponse
@@ -214,9 +275,12 @@ func (h *LocalDNSServer) queryUpstream(req *dns.Msg) *dns.Msg {
 		}
 	}
 	if response == nil {
+		dnsFailed.Increment()
 		response = new(dns.Msg)
 		response.SetReply(req)
 		response.Rcode = dns.RcodeNameError
+	} else {
+		dnsForwarded.Increment()
 	}
 	return response
 }
@@ -257,6 +321,17 @@ func generateAltHosts(hostname string, nameinfo *nds.NameTable_NameInfo, proxyNa
 	return out
 }
 
+func (table *LookupTable) lookupHost(qtype uint16, host string) []dns.RR {
+	switch qtype {
+	case dns.TypeA:
+		return table.lookupHostIPv4(host)
+	case dns.TypeAAAA:
+		return table.lookupHostIPv6(host)
+		// TODO: handle PTR records for reverse dns lookups
+	}
+	return nil
+}
+
 func (table *LookupTable) lookupHostIPv4(host string) []dns.RR {
 	ans := table.name4[host]
 	if len(ans) == 0 {
@@ -0,0 +1,38 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	resultTag = monitoring.MustCreateLabel("result")
+
+	dnsRequests = monitoring.NewSum(
+		"dns_requests",
+		"Total number of DNS requests handled by the agent, by result: answered locally, forwarded "+
+			"to an upstream resolver, or failed.",
+		monitoring.WithLabels(resultTag),
+	)
+
+	dnsAnswered  = dnsRequests.With(resultTag.Value("answered"))
+	dnsForwarded = dnsRequests.With(resultTag.Value("forwarded"))
+	dnsFailed    = dnsRequests.With(resultTag.Value("failed"))
+)
+
+func init() {
+	monitoring.MustRegister(dnsRequests)
+}
@@ -44,6 +44,13 @@ var (
 		"Limits the number of concurrent pushes allowed. On larger machines this can be increased for faster pushes",
 	).Get()
 
+	IncrementalPushThrottle = env.RegisterIntVar(
+		"PILOT_INCREMENTAL_PUSH_THROTTLE",
+		20,
+		"The number of the PILOT_PUSH_THROTTLE concurrent pushes reserved for incremental (EDS only) pushes, "+
+			"the others being reserved for full pushes, so that a burst of either can not starve the other.",
+	).Get()
+
 	// MaxRecvMsgSize The max receive buffer size of gRPC received channel of Pilot in bytes.
 	MaxRecvMsgSize = env.RegisterIntVar(
 		"ISTIO_GPRC_MAXRECVMSGSIZE",
@@ -76,6 +83,21 @@ var (
 			" EDS pushes may be delayed, but there will be fewer pushes. By default this is enabled",
 	)
 
+	DebounceAfterMax = env.RegisterDurationVar(
+		"PILOT_DEBOUNCE_AFTER_MAX",
+		0,
+		"If greater than PILOT_DEBOUNCE_AFTER, the debounce delay adapts to the push pressure: it is scaled between "+
+			"PILOT_DEBOUNCE_AFTER and this value by the fraction of connected proxies waiting in the push queue.",
+	).Get()
+
+	EDSBatchWindow = env.RegisterDurationVar(
+		"PILOT_EDS_BATCH_WINDOW",
+		0,
+		"If set, endpoint updates for the same service and cluster arriving within this window are merged into a "+
+			"single incremental push with the final endpoints. Updates requiring a full push are never batched. "+
+			"Disabled by default.",
+	).Get()
+
 	// HTTP10 will add "accept_http_10" to http outbound listeners. Can also be set only for specific sidecars via meta.
 	//
 	// Alpha in 1.1, may become the default or be turned into a Sidecar API or mesh setting. Only applies to namespaces
@@ -149,6 +171,16 @@ var (
 		false,
 		"Skip validating the peer is from the same trust domain when mTLS is enabled in authentication policy")
 
+	// TrustDomainAliasStats counts the inbound mTLS connections by trust domain of the peer, to measure the
+	// progress of a trust domain migration.
+	TrustDomainAliasStats = env.RegisterBoolVar(
+		"PILOT_TRUST_DOMAIN_ALIAS_STATS",
+		false,
+		"If enabled, when trust domain aliases are configured, shadow RBAC filters are added to the inbound mTLS "+
+			"filter chains to count the connections of each trust domain in the "+
+			"trust_domain.<trust domain>.rbac.shadow_allowed stat.",
+	).Get()
+
 	EnableProtocolSniffingForOutbound = env.RegisterBoolVar(
 		"PILOT_ENABLE_PROTOCOL_SNIFFING_FOR_OUTBOUND",
 		true,
@@ -167,6 +199,21 @@ var (
 		"If enabled, metadata exchange will be enabled for TCP using ALPN and Network Metadata Exchange filters in Envoy",
 	).Get()
 
+	InboundTLSMinProtocolVersion = env.RegisterStringVar("PILOT_INBOUND_TLS_MIN_PROTOCOL_VERSION", "",
+		"The mesh-wide minimum TLS version of the inbound mTLS filter chains, one of TLSV1_0, TLSV1_1, TLSV1_2 "+
+			"or TLSV1_3. It is overridden by the security.istio.io/tlsMinProtocolVersion annotation of PeerAuthentications. "+
+			"If unset, Envoy's default is used.").Get()
+
+	InboundTLSMaxProtocolVersion = env.RegisterStringVar("PILOT_INBOUND_TLS_MAX_PROTOCOL_VERSION", "",
+		"The mesh-wide maximum TLS version of the inbound mTLS filter chains, one of TLSV1_0, TLSV1_1, TLSV1_2 "+
+			"or TLSV1_3. It is overridden by the security.istio.io/tlsMaxProtocolVersion annotation of PeerAuthentications. "+
+			"If unset, Envoy's default is used.").Get()
+
+	InboundTLSCipherSuites = env.RegisterStringVar("PILOT_INBOUND_TLS_CIPHER_SUITES", "",
+		"The mesh-wide comma separated list of cipher suites of the inbound mTLS filter chains. It is overridden "+
+			"by the security.istio.io/tlsCipherSuites annotation of PeerAuthentications. "+
+			"If unset, Envoy's default is used.").Get()
+
 	ScopeGatewayToNamespace = env.RegisterBoolVar(
 		"PILOT_SCOPE_GATEWAY_TO_NAMESPACE",
 		false,
@@ -225,6 +272,58 @@ var (
 			"This option temporarily only supports gateways running in istio-system namespace.",
 	).Get()
 
+	SDSSecretNamespaces = env.RegisterStringVar(
+		"PILOT_SDS_SECRET_NAMESPACES",
+		"",
+		"Comma separated list of namespaces Istiod watches secrets in when ISTIOD_ENABLE_SDS_SERVER is enabled. "+
+			"If empty, secrets in all namespaces are watched.",
+	).Get()
+
+	SDSSecretLabelSelector = env.RegisterStringVar(
+		"PILOT_SDS_SECRET_LABEL_SELECTOR",
+		"",
+		"Label selector restricting the secrets Istiod watches when ISTIOD_ENABLE_SDS_SERVER is enabled, "+
+			"for example istio.io/credential=true. If empty, all secrets are watched.",
+	).Get()
+
+	EnableIstioMutualCredentialCA = env.RegisterBoolVar(
+		"PILOT_ENABLE_ISTIO_MUTUAL_CREDENTIAL_CA",
+		false,
+		"If enabled, gateway servers in ISTIO_MUTUAL mode with a credentialName validate client certificates "+
+			"against the CA of the credential, read from the <credentialName>-cacert SDS resource, for example "+
+			"to trust the workloads of another network at an east-west gateway. The secrets of these servers "+
+			"must then hold a ca.crt, or the listeners are not warmed.",
+	).Get()
+
+	NamespaceControllerNamespaces = env.RegisterStringVar(
+		"PILOT_NAMESPACE_CONTROLLER_NAMESPACES",
+		"",
+		"Comma separated list of namespaces Istiod creates the istio-ca-root-cert configmap in. If neither this nor "+
+			"PILOT_NAMESPACE_CONTROLLER_LABEL_SELECTOR is set, the configmap is created in all namespaces.",
+	).Get()
+
+	NamespaceControllerLabelSelector = env.RegisterStringVar(
+		"PILOT_NAMESPACE_CONTROLLER_LABEL_SELECTOR",
+		"",
+		"Label selector of the namespaces Istiod creates the istio-ca-root-cert configmap in, in addition to "+
+			"PILOT_NAMESPACE_CONTROLLER_NAMESPACES, for example istio-injection=enabled. The namespaces are "+
+			"re-evaluated as their labels change.",
+	).Get()
+
+	NamespaceControllerCleanup = env.RegisterBoolVar(
+		"PILOT_NAMESPACE_CONTROLLER_CLEANUP",
+		false,
+		"If enabled, Istiod deletes the istio-ca-root-cert configmap it created from the namespaces out of the "+
+			"scope set by PILOT_NAMESPACE_CONTROLLER_NAMESPACES and PILOT_NAMESPACE_CONTROLLER_LABEL_SELECTOR.",
+	).Get()
+
+	WorkloadEntryHealthChecks = env.RegisterBoolVar(
+		"PILOT_ENABLE_WORKLOAD_ENTRY_HEALTHCHECKS",
+		false,
+		"If enabled, the health of the workloads reported by their agents is recorded in the status of their "+
+			"WorkloadEntry, and the unhealthy WorkloadEntries are excluded from the endpoints.",
+	).Get()
+
 	EnableCRDValidation = env.RegisterBoolVar(
 		"PILOT_ENABLE_CRD_VALIDATION",
 		false,
@@ -260,6 +359,20 @@ var (
 			"See https://godoc.org/k8s.io/client-go/rest#Config Burst",
 	).Get()
 
+	StatusGCStaleMultiplier = env.RegisterIntVar(
+		"PILOT_STATUS_GC_STALE_MULTIPLIER",
+		10,
+		"If status is enabled, the status leader will delete the distribution report ConfigMap of any istiod "+
+			"which has not reported for this many stale intervals. Set to 0 to disable the cleanup.",
+	).Get()
+
+	StatusSink = env.RegisterStringVar(
+		"PILOT_STATUS_SINK",
+		"",
+		"If status is enabled, the status leader streams the distribution progress of every resource as JSON Lines "+
+			"to this address each update interval. Either unix:///path/to/socket or an http(s) URL receiving a POST.",
+	).Get()
+
 	// IstiodServiceCustomHost allow user to bring a custom address for istiod server
 	// for examples: istiod.mycompany.com
 	IstiodServiceCustomHost = env.RegisterStringVar("ISTIOD_CUSTOM_HOST", "",
@@ -320,6 +433,122 @@ var (
 		"If enabled, pilot will authorize XDS clients, to ensure they are acting only as namespaces they have permissions for.",
 	).Get()
 
+	XDSIdentityCheckMode = env.RegisterStringVar(
+		"PILOT_XDS_IDENTITY_CHECK_MODE",
+		"",
+		"Controls how PILOT_ENABLE_XDS_IDENTITY_CHECK handles identity mismatches. By default, a client is rejected only "+
+			"if none of its identities match the namespace and service account it claims. If set to STRICT, any mismatching "+
+			"identity causes the client to be rejected with PERMISSION_DENIED. If set to PERMISSIVE, mismatching clients are "+
+			"accepted but logged and listed at /debug/quarantinez.",
+	).Get()
+
+	XDSMaxResourceNamesPerRequest = env.RegisterIntVar(
+		"PILOT_XDS_MAX_RESOURCE_NAMES_PER_REQUEST",
+		0,
+		"The maximum number of resource names in a single XDS request. Requests with more resource names are rejected. "+
+			"Wildcard requests, without resource names, are not affected. If <= 0, the number is not limited.",
+	).Get()
+
+	XDSMaxWatchedResourcesPerConnection = env.RegisterIntVar(
+		"PILOT_XDS_MAX_WATCHED_RESOURCES_PER_CONNECTION",
+		0,
+		"The maximum number of resource names watched by an XDS connection, across all types. Requests that would "+
+			"exceed it are rejected. Wildcard subscriptions are not counted. If <= 0, the number is not limited.",
+	).Get()
+
+	XDSStrictResourceLimits = env.RegisterBoolVar(
+		"PILOT_XDS_STRICT_RESOURCE_LIMITS",
+		false,
+		"If enabled, XDS connections sending requests that exceed PILOT_XDS_MAX_RESOURCE_NAMES_PER_REQUEST or "+
+			"PILOT_XDS_MAX_WATCHED_RESOURCES_PER_CONNECTION are closed with RESOURCE_EXHAUSTED, instead of only "+
+			"having the request ignored.",
+	).Get()
+
+	ScopeGatewayVirtualServicesByHost = env.RegisterBoolVar(
+		"PILOT_SCOPE_GATEWAY_VIRTUAL_SERVICES_BY_HOST",
+		true,
+		"If enabled, virtual services bound to gateways are indexed by host, so that the routes of gateway servers "+
+			"without wildcard hosts are only built from the virtual services which may match their hosts.",
+	).Get()
+
+	XDSNackRetention = env.RegisterDurationVar(
+		"PILOT_XDS_NACK_RETENTION",
+		10*time.Minute,
+		"How long the last NACK of a proxy for a type is kept in /debug/nackz and counted as active, if the proxy "+
+			"does not ACK the type. The NACK of a type is cleared by the next ACK of the type, and the NACKs of a "+
+			"proxy are dropped when it disconnects.",
+	).Get()
+
+	NackQuarantineThreshold = env.RegisterFloatVar(
+		"PILOT_NACK_QUARANTINE_THRESHOLD",
+		0,
+		"If set, the percentage of the connected proxies which must start rejecting the same type of the config "+
+			"pushed for a set of changed configs, within PILOT_NACK_QUARANTINE_WINDOW of the push, for these configs "+
+			"to be quarantined: excluded from the pushed config until released with /debug/config_quarantinez. "+
+			"Disabled if 0.",
+	).Get()
+
+	NackQuarantineWindow = env.RegisterDurationVar(
+		"PILOT_NACK_QUARANTINE_WINDOW",
+		time.Minute,
+		"How long after a push the NACKs of its version are attributed to the configs it changed, "+
+			"see PILOT_NACK_QUARANTINE_THRESHOLD.",
+	).Get()
+
+	RolloutStatusWindow = env.RegisterDurationVar(
+		"PILOT_ROLLOUT_STATUS_WINDOW",
+		0,
+		"How long the distribution of a changed config to the proxies is tracked for /debug/rollout_status, "+
+			"from the push of the change. Disabled if 0.",
+	).Get()
+
+	ValidateGeneratedResources = env.RegisterBoolVar(
+		"PILOT_VALIDATE_GENERATED_RESOURCES",
+		false,
+		"If enabled, the generated clusters and listeners are validated before being sent, and the invalid ones "+
+			"are skipped and reported on /debug/adsz and /debug/push_status instead of being rejected by the proxy "+
+			"together with the rest of the response. As CDS and LDS are state of the world, the proxy then removes "+
+			"the previous version of a skipped resource. Each generated resource is validated, on every push.",
+	).Get()
+
+	ProxyStatusNamespaceLimit = env.RegisterIntVar(
+		"PILOT_PROXY_STATUS_NAMESPACE_LIMIT",
+		0,
+		"If positive, the proxy status metrics, such as pilot_no_ip, are also recorded by namespace of the proxy "+
+			"in pilot_proxy_status_by_namespace, and summarized by namespace in /debug/push_status. Only this number "+
+			"of namespaces with the most entries is reported for each metric, the others are reported as \"other\". "+
+			"Disabled if 0.",
+	).Get()
+
+	FilterNodeMetadata = env.RegisterBoolVar(
+		"PILOT_FILTER_NODE_METADATA",
+		true,
+		"If enabled, the node metadata keys accepted from proxies are limited to the keys known to Istio and "+
+			"PILOT_NODE_METADATA_ALLOWLIST, and their values to PILOT_NODE_METADATA_MAX_VALUE_SIZE. Note that "+
+			"EnvoyFilters matching dropped metadata keys no longer apply, unless PILOT_KEEP_RAW_NODE_METADATA is enabled.",
+	).Get()
+
+	NodeMetadataAllowlist = env.RegisterStringVar(
+		"PILOT_NODE_METADATA_ALLOWLIST",
+		"",
+		"Comma separated list of node metadata keys accepted from proxies in addition to the keys known to Istio, "+
+			"when PILOT_FILTER_NODE_METADATA is enabled. Other keys are dropped. Set to * to accept all keys.",
+	).Get()
+
+	NodeMetadataMaxValueSize = env.RegisterIntVar(
+		"PILOT_NODE_METADATA_MAX_VALUE_SIZE",
+		16*1024,
+		"The maximum size in bytes of a single node metadata value accepted from proxies, when "+
+			"PILOT_FILTER_NODE_METADATA is enabled. Larger values are dropped. If the size is <= 0, values are not limited.",
+	).Get()
+
+	KeepRawNodeMetadata = env.RegisterBoolVar(
+		"PILOT_KEEP_RAW_NODE_METADATA",
+		false,
+		"If enabled, the raw node metadata used for EnvoyFilter matching and custom generators is kept complete, "+
+			"including keys dropped when PILOT_FILTER_NODE_METADATA is enabled.",
+	).Get()
+
 	EnableServiceEntrySelectPods = env.RegisterBoolVar("PILOT_ENABLE_SERVICEENTRY_SELECT_PODS", true,
 		"If enabled, service entries with selectors will select pods from the cluster. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
@@ -338,16 +567,143 @@ var (
 			"Use || between <trustdomain, endpoint> tuples. Use | as delimiter between trust domain and endpoint in "+
 			"each tuple. For example: foo|https://url/for/foo||bar|https://url/for/bar").Get()
 
+	SpiffeBundleRefreshInterval = env.RegisterDurationVar("SPIFFE_BUNDLE_REFRESH_INTERVAL", 5*time.Minute,
+		"The interval at which the SPIFFE_BUNDLE_ENDPOINTS are fetched again, with up to 10% jitter. "+
+			"If the bundle of a trust domain cannot be fetched, its previous root certificates are kept. "+
+			"Set to 0 to only fetch the bundles at startup.").Get()
+
+	SecureGRPCCRLFile = env.RegisterStringVar("PILOT_SECURE_GRPC_CRL_FILE", "",
+		"If set, the path of a CRL, PEM or DER encoded, the client certificates presented to the secure gRPC server "+
+			"are checked against. Revoked certificates are rejected. The file is watched for changes.").Get()
+
+	SecureGRPCOCSPResponderURL = env.RegisterStringVar("PILOT_SECURE_GRPC_OCSP_RESPONDER_URL", "",
+		"If set, the URL of the OCSP responder queried for the status of the istiod certificate, which is stapled "+
+			"to the TLS handshakes of the secure gRPC server.").Get()
+
+	SecureGRPCOCSPResponseFile = env.RegisterStringVar("PILOT_SECURE_GRPC_OCSP_RESPONSE_FILE", "",
+		"If set, the path of a DER encoded OCSP response for the istiod certificate, stapled to the TLS handshakes "+
+			"of the secure gRPC server. Ignored if PILOT_SECURE_GRPC_OCSP_RESPONDER_URL is set.").Get()
+
+	SecureGRPCOCSPRefreshInterval = env.RegisterDurationVar("PILOT_SECURE_GRPC_OCSP_REFRESH_INTERVAL", time.Hour,
+		"The interval at which the OCSP response stapled by the secure gRPC server is refreshed. A response is kept "+
+			"until its next update if it cannot be refreshed.").Get()
+
 	EnableXDSCaching = env.RegisterBoolVar("PILOT_ENABLE_XDS_CACHE", true,
 		"If true, Pilot will cache XDS responses.").Get()
 
 	EnableXDSCacheMetrics = env.RegisterBoolVar("PILOT_XDS_CACHE_STATS", false,
 		"If true, Pilot will collect metrics for XDS cache efficiency.").Get()
 
+	EnableXDSNamespaceMetrics = env.RegisterBoolVar("PILOT_XDS_NAMESPACE_METRICS", false,
+		"If enabled, the XDS generation time and size metrics are also labeled with the namespace of the proxy. "+
+			"This increases the cardinality of these metrics with the number of namespaces.").Get()
+
 	XDSCacheMaxSize = env.RegisterIntVar("PILOT_XDS_CACHE_SIZE", 20000,
 		"The maximum number of cache entries for the XDS cache. If the size is <= 0, the cache will have no upper bound.").Get()
 
+	EnableResilientPushContextInit = env.RegisterBoolVar("PILOT_ENABLE_RESILIENT_PUSH_CONTEXT_INIT", false,
+		"If enabled, when a part of the push context, such as the EnvoyFilters, fails to be initialized, "+
+			"the previous push context's data is used for it instead of aborting the push. The failure is "+
+			"reported in the pilot_push_context_init_failures metric and in /debug/push_status.").Get()
+
+	PushContextInitConcurrency = env.RegisterIntVar("PILOT_PUSH_CONTEXT_INIT_CONCURRENCY", 1,
+		"The number of phases of the push context, such as the destination rules, the authorization policies "+
+			"and the EnvoyFilters, initialized concurrently when they do not depend on each other. The sidecar "+
+			"scopes are always initialized last. The phases are initialized sequentially by default.").Get()
+
+	EnableXDSCacheWarmStart = env.RegisterBoolVar("PILOT_ENABLE_XDS_CACHE_WARM_START", false,
+		"If enabled, Pilot persists part of the XDS cache on graceful shutdown, and pre-populates the cache "+
+			"on startup with the persisted entries whose dependent configs are unchanged.").Get()
+
+	XDSCacheWarmStartPath = env.RegisterStringVar("PILOT_XDS_CACHE_WARM_START_PATH", "/var/lib/istio/xds-cache/snapshot.json",
+		"The file the XDS cache is persisted to when PILOT_ENABLE_XDS_CACHE_WARM_START is enabled. "+
+			"It should be on a volume that outlives the pod, for example a mounted persistent volume.").Get()
+
+	XDSCacheWarmStartMaxEntries = env.RegisterIntVar("PILOT_XDS_CACHE_WARM_START_MAX_ENTRIES", 10000,
+		"The maximum number of XDS cache entries persisted for the warm start. The most recently used entries are kept.").Get()
+
 	AllowMetadataCertsInMutualTLS = env.RegisterBoolVar("PILOT_ALLOW_METADATA_CERTS_DR_MUTUAL_TLS", false,
 		"If true, Pilot will allow certs specified in Metadata to override DR certs in MUTUAL TLS mode. "+
 			"This is only enabled for migration and will be removed soon.").Get()
+
+	LoadReportingInterval = env.RegisterDurationVar("PILOT_LOAD_REPORTING_INTERVAL", 10*time.Second,
+		"The interval at which proxies reporting load stats to istiod, enabled by ISTIO_LOAD_STATS_REPORTING "+
+			"on the proxy, are asked to send a report.").Get()
+
+	LoadReportingClusters = env.RegisterStringVar("PILOT_LOAD_REPORTING_CLUSTERS", "",
+		"Comma separated list of the clusters proxies report load stats for. If empty, all clusters are reported.").Get()
+
+	FullReadyMaxPushQueueDepth = env.RegisterIntVar("PILOT_FULL_READY_MAX_PUSH_QUEUE_DEPTH", 100,
+		"The /ready/full endpoint, intended for external load balancers, reports istiod as not ready while more "+
+			"proxies than this are waiting to be pushed.").Get()
+
+	FullReadyMaxPendingConnections = env.RegisterIntVar("PILOT_FULL_READY_MAX_PENDING_CONNECTIONS", 100,
+		"The /ready/full endpoint, intended for external load balancers, reports istiod as not ready while more "+
+			"connections than this have not been sent their initial config.").Get()
+
+	RegistryStaleThreshold = env.RegisterDurationVar("PILOT_REGISTRY_STALE_THRESHOLD", 5*time.Minute,
+		"The time after which a service registry which did not sync with its source, such as the API server of a "+
+			"remote cluster, is reported stale by /debug/registryhealthz and the pilot_registry_stale metric.").Get()
+
+	RemoteRegistrySyncTimeout = env.RegisterDurationVar("PILOT_REMOTE_REGISTRY_SYNC_TIMEOUT", 0,
+		"If set, the registry of a remote cluster which did not complete its initial sync within this time after "+
+			"being added is considered degraded, and no longer blocks the readiness of istiod. Disabled by default.").Get()
+
+	AnnotateStaleEndpoints = env.RegisterBoolVar("PILOT_ANNOTATE_STALE_ENDPOINTS", false,
+		"If enabled, the endpoints of a stale registry are sent with the stale_registry field set in their istio "+
+			"metadata, so that tooling can detect endpoints which may be out of date.").Get()
+
+	XDSStartupStabilizationWindow = env.RegisterDurationVar("PILOT_XDS_STARTUP_STABILIZATION_WINDOW", 0,
+		"If set, the full pushes to a proxy connected for less than this long are deferred until then and sent "+
+			"as a single push, unless triggered by a change of the proxy itself or of security policies or secrets, "+
+			"to avoid draining its listeners right after startup. Disabled by default.").Get()
+
+	EnablePartialXDSResponses = env.RegisterBoolVar("PILOT_ENABLE_PARTIAL_XDS_RESPONSES", false,
+		"If enabled, when a proxy adds resource names to a subscription, such as routes requested on demand, only "+
+			"the added resources are generated and sent, by the generators supporting it. Full pushes still send "+
+			"all the watched resources.").Get()
+
+	InjectionMaxInflightPerNamespace = env.RegisterIntVar("INJECTION_WEBHOOK_MAX_INFLIGHT_PER_NAMESPACE", 0,
+		"If set, the sidecar injection requests of a namespace in excess of this number being served concurrently "+
+			"are rejected with a 503, so that a namespace creating many pods does not slow down the injection of "+
+			"the others. The API server does not retry them: with the Fail failurePolicy the pod creation fails, "+
+			"and is retried by the pod controller if any, so throttling is disabled unless the failurePolicy of "+
+			"the INJECTION_WEBHOOK_CONFIG_NAME webhook is Fail, as with Ignore the pod would be created without "+
+			"a sidecar. Disabled by default.").Get()
+
+	InjectionMaxInflight = env.RegisterIntVar("INJECTION_WEBHOOK_MAX_INFLIGHT", 0,
+		"If set, the sidecar injection requests in excess of this number being served concurrently, across all the "+
+			"namespaces, are rejected with a 503, as with INJECTION_WEBHOOK_MAX_INFLIGHT_PER_NAMESPACE. "+
+			"Disabled by default.").Get()
+
+	MaxServicesPerNamespace = env.RegisterIntVar("PILOT_MAX_SERVICES_PER_NAMESPACE", 0,
+		"If set, the services of a namespace in excess of this number are ignored, the oldest ones being kept. "+
+			"The services ignored are reported by the pilot_service_cardinality_limit_exceeded metric and "+
+			"/debug/cardinalityz. Disabled by default.").Get()
+
+	MaxEndpointsPerService = env.RegisterIntVar("PILOT_MAX_ENDPOINTS_PER_SERVICE", 0,
+		"If set, the endpoints of a service in a cluster in excess of this number are ignored, the endpoints "+
+			"with the lowest addresses being kept. The services truncated are reported by the "+
+			"pilot_endpoint_cardinality_limit_exceeded metric and /debug/cardinalityz. Disabled by default.").Get()
+
+	MaxEndpointsPerShard = env.RegisterIntVar("PILOT_MAX_ENDPOINTS_PER_SHARD", 0,
+		"If set, the endpoints of a cluster in excess of this number, across all its services, are ignored: the "+
+			"endpoints of the services updated once the limit is reached are truncated. Disabled by default.").Get()
+
+	StrictCardinalityLimits = env.RegisterBoolVar("PILOT_STRICT_CARDINALITY_LIMITS", false,
+		"If enabled, all the endpoints of a service exceeding PILOT_MAX_ENDPOINTS_PER_SERVICE or "+
+			"PILOT_MAX_ENDPOINTS_PER_SHARD are ignored, instead of the endpoints in excess of the limit.").Get()
+
+	EnableWorkloadOnboarding = env.RegisterBoolVar("PILOT_ENABLE_WORKLOAD_ONBOARDING", false,
+		"If enabled, istiod serves /onboarding/workloadgroup on its HTTPS port, returning a tar.gz with the root "+
+			"certificate, the cluster.env and mesh.yaml of the ?namespace= and ?name= WorkloadGroup, and a token "+
+			"of its service account, to callers authenticated for the namespace of the WorkloadGroup.").Get()
+
+	WorkloadOnboardingTokenTTL = env.RegisterDurationVar("PILOT_WORKLOAD_ONBOARDING_TOKEN_TTL", time.Hour,
+		"The lifetime of the service account tokens included in the workload onboarding bundles.").Get()
+
+	XDSCompressionThreshold = env.RegisterIntVar("PILOT_XDS_COMPRESSION_THRESHOLD", 0,
+		"If set, istiod supports the gzip grpc-encoding, and compresses the messages of at least this number of bytes, "+
+			"such as large EDS responses, sent to the clients compressing their requests with gzip, like the agents "+
+			"with ISTIO_XDS_COMPRESSION set. The smaller messages are stored uncompressed. Disabled by default.").Get()
 )
@@ -202,6 +202,98 @@ func (policy *AuthenticationPolicies) GetNamespaceMutualTLSMode(namespace string
 	return policy.globalMutualTLSMode
 }
 
+// GetServiceMutualTLSMode returns the MutualTLSMode defined by the workload-level PeerAuthentication in the
+// namespace which applies to all workloads selected by serviceSelector on the given service port. As the workloads
+// are not known, a policy is assumed to apply to all of them if its selector is a subset of serviceSelector, and to
+// some of them if the two selectors do not conflict. Port level settings apply to the workload ports returned by
+// targetPorts, which is only called for policies with port level settings. Policies with an UNSET mode use the
+// inherited mode. The return value is MTLSUnknown if no workload-level policy applies to all workloads. ambiguous
+// is true if the workloads may be split across different effective modes.
+func (policy *AuthenticationPolicies) GetServiceMutualTLSMode(namespace string, serviceSelector labels.Instance,
+	targetPorts func() []uint32, inherited MutualTLSMode) (mode MutualTLSMode, ambiguous bool) {
+	if len(serviceSelector) == 0 {
+		return MTLSUnknown, false
+	}
+	mode = MTLSUnknown
+	var partialModes []MutualTLSMode
+	// Configs are sorted by creation time, so the first policy selecting all workloads takes precedence.
+	for idx := range policy.peerAuthentications[namespace] {
+		spec := policy.peerAuthentications[namespace][idx].Spec.(*v1beta1.PeerAuthentication)
+		selector := labels.Instance(spec.GetSelector().GetMatchLabels())
+		if len(selector) == 0 || selectorsConflict(selector, serviceSelector) {
+			continue
+		}
+		if !selector.SubsetOf(serviceSelector) {
+			policyMode, policyAmbiguous := workloadMutualTLSMode(spec, targetPorts, inherited)
+			if policyAmbiguous {
+				return MTLSUnknown, true
+			}
+			partialModes = append(partialModes, policyMode)
+			continue
+		}
+		if mode == MTLSUnknown {
+			policyMode, policyAmbiguous := workloadMutualTLSMode(spec, targetPorts, inherited)
+			if policyAmbiguous {
+				return MTLSUnknown, true
+			}
+			mode = policyMode
+		}
+	}
+
+	effective := mode
+	if effective == MTLSUnknown {
+		effective = inherited
+	}
+	for _, m := range partialModes {
+		if m != effective {
+			return MTLSUnknown, true
+		}
+	}
+	return mode, false
+}
+
+// workloadMutualTLSMode returns the MutualTLSMode of the workload-level policy for the workload ports returned by
+// targetPorts. ambiguous is true if the port level settings of the policy differ between these ports.
+func workloadMutualTLSMode(spec *v1beta1.PeerAuthentication, targetPorts func() []uint32,
+	inherited MutualTLSMode) (mode MutualTLSMode, ambiguous bool) {
+	workloadMode := v1beta1.PeerAuthentication_MutualTLS_UNSET
+	if spec.Mtls != nil {
+		workloadMode = spec.Mtls.Mode
+	}
+	toMutualTLSMode := func(m v1beta1.PeerAuthentication_MutualTLS_Mode) MutualTLSMode {
+		if m == v1beta1.PeerAuthentication_MutualTLS_UNSET {
+			return inherited
+		}
+		return apiModeToMutualTLSMode(m)
+	}
+	mode = toMutualTLSMode(workloadMode)
+	if len(spec.PortLevelMtls) == 0 {
+		return mode, false
+	}
+	for i, port := range targetPorts() {
+		portMode := workloadMode
+		if portMTLS, ok := spec.PortLevelMtls[port]; ok && portMTLS.GetMode() != v1beta1.PeerAuthentication_MutualTLS_UNSET {
+			portMode = portMTLS.Mode
+		}
+		if i == 0 {
+			mode = toMutualTLSMode(portMode)
+		} else if toMutualTLSMode(portMode) != mode {
+			return MTLSUnknown, true
+		}
+	}
+	return mode, false
+}
+
+// selectorsConflict returns true if no workload can be selected by both selectors.
+func selectorsConflict(a, b labels.Instance) bool {
+	for k, v := range a {
+		if bv, ok := b[k]; ok && bv != v {
+			return true
+		}
+	}
+	return false
+}
+
 // GetJwtPoliciesForWorkload returns a list of JWT policies matching to labels.
 func (policy *AuthenticationPolicies) GetJwtPoliciesForWorkload(namespace string,
 	workloadLabels labels.Collection) []*config.Config {
@@ -15,12 +15,19 @@
 package model
 
 import (
+	"strconv"
+
 	authpb "istio.io/api/security/v1beta1"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/security"
 	istiolog "istio.io/pkg/log"
 )
 
+// DryRunAnnotation puts an AuthorizationPolicy in dry-run mode when set to "true": the policy is evaluated, and the
+// requests it would deny are counted, but it is not enforced. It is only supported for ALLOW and DENY policies.
+const DryRunAnnotation = security.DryRunAnnotation
+
 var (
 	authzLog = istiolog.RegisterScope("authorization", "Istio Authorization Policy", 0)
 )
@@ -29,6 +36,8 @@ type AuthorizationPolicy struct {
 	Name      string                      `json:"name"`
 	Namespace string                      `json:"namespace"`
 	Spec      *authpb.AuthorizationPolicy `json:"spec"`
+	// DryRun is true if the policy is only evaluated, and not enforced.
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
 // AuthorizationPolicies organizes AuthorizationPolicy by namespace.
@@ -53,10 +62,12 @@ func GetAuthorizationPolicies(env *Environment) (*AuthorizationPolicies, error)
 	}
 	sortConfigByCreationTime(policies)
 	for _, config := range policies {
+		spec := config.Spec.(*authpb.AuthorizationPolicy)
 		authzConfig := AuthorizationPolicy{
 			Name:      config.Name,
 			Namespace: config.Namespace,
-			Spec:      config.Spec.(*authpb.AuthorizationPolicy),
+			Spec:      spec,
+			DryRun:    isDryRun(config.Name, config.Namespace, config.Annotations, spec.GetAction()),
 		}
 		policy.NamespaceToPolicies[config.Namespace] =
 			append(policy.NamespaceToPolicies[config.Namespace], authzConfig)
@@ -65,6 +76,27 @@ func GetAuthorizationPolicies(env *Environment) (*AuthorizationPolicies, error)
 	return policy, nil
 }
 
+// isDryRun returns true if the annotations of the policy put it in dry-run mode. A policy with an invalid value, or
+// whose action does not support dry-run, is enforced.
+func isDryRun(name, namespace string, annotations map[string]string, action authpb.AuthorizationPolicy_Action) bool {
+	value, f := annotations[DryRunAnnotation]
+	if !f {
+		return false
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		authzLog.Warnf("enforcing authorization policy %s.%s with invalid %s annotation %q",
+			namespace, name, DryRunAnnotation, value)
+		return false
+	}
+	if dryRun && action != authpb.AuthorizationPolicy_ALLOW && action != authpb.AuthorizationPolicy_DENY {
+		authzLog.Warnf("enforcing authorization policy %s.%s, the %s annotation is not supported for %s policies",
+			namespace, name, DryRunAnnotation, action)
+		return false
+	}
+	return dryRun
+}
+
 // ListAuthorizationPolicies returns the deny, allow, and audit AuthorizationPolicies for the workload in the given namespace.
 func (policy *AuthorizationPolicies) ListAuthorizationPolicies(namespace string, workload labels.Collection) (
 	denyPolicies []AuthorizationPolicy, allowPolicies []AuthorizationPolicy, auditPolicies []AuthorizationPolicy) {
@@ -318,6 +318,38 @@ func TestAuthorizationPolicies_ListAuthorizationPolicies(t *testing.T) {
 	}
 }
 
+func TestGetAuthorizationPoliciesDryRun(t *testing.T) {
+	policy := &authpb.AuthorizationPolicy{}
+	withAnnotation := func(cfg config.Config, value string) config.Config {
+		cfg.Annotations = map[string]string{DryRunAnnotation: value}
+		return cfg
+	}
+	authzPolicies := createFakeAuthorizationPolicies([]config.Config{
+		newConfig("enforced", "bar", policy),
+		withAnnotation(newConfig("dry-run", "bar", policy), "true"),
+		withAnnotation(newConfig("not-dry-run", "bar", policy), "false"),
+		withAnnotation(newConfig("invalid", "bar", policy), "yes please"),
+		withAnnotation(newConfig("dry-run-deny", "bar", &authpb.AuthorizationPolicy{Action: authpb.AuthorizationPolicy_DENY}), "true"),
+		withAnnotation(newConfig("audit", "bar", &authpb.AuthorizationPolicy{Action: authpb.AuthorizationPolicy_AUDIT}), "true"),
+	}, t)
+	got := map[string]bool{}
+	for _, p := range authzPolicies.NamespaceToPolicies["bar"] {
+		got[p.Name] = p.DryRun
+	}
+	want := map[string]bool{
+		"enforced":     false,
+		"dry-run":      true,
+		"not-dry-run":  false,
+		"invalid":      false,
+		"dry-run-deny": true,
+		// Dry-run is not supported for AUDIT policies.
+		"audit": false,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got dry-run policies %v, want %v", got, want)
+	}
+}
+
 func createFakeAuthorizationPolicies(configs []config.Config, t *testing.T) *AuthorizationPolicies {
 	store := &authzFakeStore{}
 	for _, cfg := range configs {
@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// The cardinality limits of the services and endpoints.
+const (
+	// ServicesPerNamespaceLimit is PILOT_MAX_SERVICES_PER_NAMESPACE.
+	ServicesPerNamespaceLimit = "servicesPerNamespace"
+	// EndpointsPerServiceLimit is PILOT_MAX_ENDPOINTS_PER_SERVICE.
+	EndpointsPerServiceLimit = "endpointsPerService"
+	// EndpointsPerShardLimit is PILOT_MAX_ENDPOINTS_PER_SHARD.
+	EndpointsPerShardLimit = "endpointsPerShard"
+)
+
+// CardinalityViolation is a service exceeding a cardinality limit.
+type CardinalityViolation struct {
+	// Limit is the limit exceeded.
+	Limit string `json:"limit"`
+	// Max is the value of the limit.
+	Max int `json:"max"`
+	// Cluster is the cluster of the endpoints, unset for the services per namespace limit.
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace"`
+	Hostname  string `json:"hostname"`
+	// Count is the number of services of the namespace, or of endpoints of the service.
+	Count int `json:"count"`
+	// Kept is the number of endpoints of the service kept, 0 if the service is ignored.
+	Kept int `json:"kept"`
+}
+
+// Key returns the key of the violation in the push status.
+func (v CardinalityViolation) Key() string {
+	if v.Cluster == "" {
+		return v.Namespace + "/" + v.Hostname
+	}
+	return v.Cluster + "/" + v.Namespace + "/" + v.Hostname
+}
+
+func (v CardinalityViolation) String() string {
+	switch v.Limit {
+	case ServicesPerNamespaceLimit:
+		return fmt.Sprintf("service %s ignored: namespace %s has %d services, exceeding the limit of %d",
+			v.Hostname, v.Namespace, v.Count, v.Max)
+	case EndpointsPerShardLimit:
+		return fmt.Sprintf("service %s/%s: %d of %d endpoints kept in cluster %s, exceeding the limit of %d "+
+			"endpoints per cluster", v.Namespace, v.Hostname, v.Kept, v.Count, v.Cluster, v.Max)
+	default:
+		return fmt.Sprintf("service %s/%s: %d of %d endpoints kept in cluster %s, exceeding the limit of %d",
+			v.Namespace, v.Hostname, v.Kept, v.Count, v.Cluster, v.Max)
+	}
+}
+
+// limitServicesPerNamespace returns the services without those of the namespaces with more than
+// PILOT_MAX_SERVICES_PER_NAMESPACE services in excess of the limit. The oldest services are kept, by hostname for
+// those created at the same time, so that the same services are kept whatever their order.
+func (ps *PushContext) limitServicesPerNamespace(services []*Service) []*Service {
+	limit := features.MaxServicesPerNamespace
+	if limit <= 0 {
+		return services
+	}
+	byNamespace := map[string][]*Service{}
+	for _, s := range services {
+		byNamespace[s.Attributes.Namespace] = append(byNamespace[s.Attributes.Namespace], s)
+	}
+	ignored := map[*Service]struct{}{}
+	for ns, nsServices := range byNamespace {
+		if len(nsServices) <= limit {
+			continue
+		}
+		sort.SliceStable(nsServices, func(i, j int) bool {
+			if !nsServices[i].CreationTime.Equal(nsServices[j].CreationTime) {
+				return nsServices[i].CreationTime.Before(nsServices[j].CreationTime)
+			}
+			return nsServices[i].Hostname < nsServices[j].Hostname
+		})
+		for _, s := range nsServices[limit:] {
+			ignored[s] = struct{}{}
+			v := CardinalityViolation{
+				Limit:     ServicesPerNamespaceLimit,
+				Max:       limit,
+				Namespace: ns,
+				Hostname:  string(s.Hostname),
+				Count:     len(nsServices),
+			}
+			ps.serviceCardinalityViolations = append(ps.serviceCardinalityViolations, v)
+			ps.AddMetric(ServiceCardinalityLimitExceeded, v.Key(), "", v.String())
+		}
+	}
+	if len(ignored) == 0 {
+		return services
+	}
+	sort.Slice(ps.serviceCardinalityViolations, func(i, j int) bool {
+		return ps.serviceCardinalityViolations[i].Key() < ps.serviceCardinalityViolations[j].Key()
+	})
+	log.Warnf("%d services ignored, exceeding the limit of %d services per namespace", len(ignored), limit)
+	kept := make([]*Service, 0, len(services)-len(ignored))
+	for _, s := range services {
+		if _, f := ignored[s]; !f {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// ServiceCardinalityViolations returns the services ignored because their namespace has too many services.
+func (ps *PushContext) ServiceCardinalityViolations() []CardinalityViolation {
+	return ps.serviceCardinalityViolations
+}
+
+// TruncateEndpoints returns the n endpoints with the lowest addresses and ports, so that the same endpoints are
+// kept whatever their order. The endpoints are not modified.
+func TruncateEndpoints(endpoints []*IstioEndpoint, n int) []*IstioEndpoint {
+	if n >= len(endpoints) {
+		return endpoints
+	}
+	if n <= 0 {
+		return nil
+	}
+	sorted := make([]*IstioEndpoint, len(endpoints))
+	copy(sorted, endpoints)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Address != sorted[j].Address {
+			return sorted[i].Address < sorted[j].Address
+		}
+		if sorted[i].EndpointPort != sorted[j].EndpointPort {
+			return sorted[i].EndpointPort < sorted[j].EndpointPort
+		}
+		return sorted[i].ServicePortName < sorted[j].ServicePortName
+	})
+	return sorted[:n]
+}
@@ -0,0 +1,158 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestServicesPerNamespaceLimit(t *testing.T) {
+	defaultLimit := features.MaxServicesPerNamespace
+	features.MaxServicesPerNamespace = 3
+	defer func() { features.MaxServicesPerNamespace = defaultLimit }()
+
+	now := time.Now()
+	var services []*Service
+	// The services of a ServiceEntry with many hosts, created at the same time.
+	for i := 0; i < 5; i++ {
+		services = append(services, &Service{
+			Hostname:     host.Name(fmt.Sprintf("svc-%d.example.com", i)),
+			CreationTime: now,
+			Attributes:   ServiceAttributes{Namespace: "noisy"},
+		})
+	}
+	services = append(services, &Service{
+		Hostname:     "old.example.com",
+		CreationTime: now.Add(-time.Hour),
+		Attributes:   ServiceAttributes{Namespace: "noisy"},
+	}, &Service{
+		Hostname:     "quiet.example.com",
+		CreationTime: now,
+		Attributes:   ServiceAttributes{Namespace: "quiet"},
+	})
+
+	initServices := func(services []*Service) *PushContext {
+		env := &Environment{
+			Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+			IstioConfigStore: &istioConfigStore{ConfigStore: NewFakeStore()},
+			ServiceDiscovery: &localServiceDiscovery{services: services},
+		}
+		ps := NewPushContext()
+		ps.Mesh = env.Mesh()
+		ps.initDefaultExportMaps()
+		if err := ps.initServiceRegistry(env, nil, nil); err != nil {
+			t.Fatalf("init services failed: %v", err)
+		}
+		return ps
+	}
+	hostnames := func(ps *PushContext) []string {
+		var out []string
+		for h := range ps.ServiceByHostname {
+			out = append(out, string(h))
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	ps := initServices(services)
+	want := []string{"old.example.com", "quiet.example.com", "svc-0.example.com", "svc-1.example.com"}
+	if got := hostnames(ps); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got services %v, want %v", got, want)
+	}
+
+	// The same services are kept whatever the order of the registry.
+	shuffled := make([]*Service, 0, len(services))
+	for i := len(services) - 1; i >= 0; i-- {
+		shuffled = append(shuffled, services[i])
+	}
+	if got := hostnames(initServices(shuffled)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got services %v, want %v", got, want)
+	}
+
+	var ignored []string
+	for _, v := range ps.ServiceCardinalityViolations() {
+		if v.Limit != ServicesPerNamespaceLimit || v.Namespace != "noisy" || v.Count != 6 || v.Max != 3 {
+			t.Errorf("unexpected violation %+v", v)
+		}
+		ignored = append(ignored, v.Hostname)
+	}
+	if want := []string{"svc-2.example.com", "svc-3.example.com", "svc-4.example.com"}; !reflect.DeepEqual(ignored, want) {
+		t.Errorf("got ignored services %v, want %v", ignored, want)
+	}
+	status := ps.ProxyStatus[ServiceCardinalityLimitExceeded.Name()]
+	if _, f := status["noisy/svc-4.example.com"]; !f || len(status) != 3 {
+		t.Errorf("expected the ignored services in the push status, got %v", status)
+	}
+
+	// The violations are kept when the services are not recomputed.
+	next := NewPushContext()
+	next.copyServiceRegistry(ps)
+	if len(next.ServiceCardinalityViolations()) != 3 || len(next.ProxyStatus[ServiceCardinalityLimitExceeded.Name()]) != 3 {
+		t.Errorf("expected the violations to be copied")
+	}
+}
+
+func TestServicesPerNamespaceLimitDisabled(t *testing.T) {
+	services := []*Service{{Hostname: "a"}, {Hostname: "b"}}
+	ps := NewPushContext()
+	if got := ps.limitServicesPerNamespace(services); !reflect.DeepEqual(got, services) {
+		t.Errorf("got %v, want all the services", got)
+	}
+	if len(ps.ServiceCardinalityViolations()) != 0 {
+		t.Errorf("unexpected violations %v", ps.ServiceCardinalityViolations())
+	}
+}
+
+func TestTruncateEndpoints(t *testing.T) {
+	endpoints := []*IstioEndpoint{
+		{Address: "10.0.0.3", EndpointPort: 80},
+		{Address: "10.0.0.1", EndpointPort: 8080},
+		{Address: "10.0.0.2", EndpointPort: 80},
+		{Address: "10.0.0.1", EndpointPort: 80},
+	}
+	addresses := func(eps []*IstioEndpoint) []string {
+		var out []string
+		for _, e := range eps {
+			out = append(out, fmt.Sprintf("%s:%d", e.Address, e.EndpointPort))
+		}
+		return out
+	}
+	want := []string{"10.0.0.1:80", "10.0.0.1:8080", "10.0.0.2:80"}
+	if got := addresses(TruncateEndpoints(endpoints, 3)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	reversed := []*IstioEndpoint{endpoints[3], endpoints[2], endpoints[1], endpoints[0]}
+	if got := addresses(TruncateEndpoints(reversed, 3)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if endpoints[0].Address != "10.0.0.3" {
+		t.Errorf("the endpoints were modified")
+	}
+	if got := TruncateEndpoints(endpoints, 4); len(got) != 4 {
+		t.Errorf("expected all the endpoints, got %v", addresses(got))
+	}
+	if got := TruncateEndpoints(endpoints, 0); len(got) != 0 {
+		t.Errorf("expected no endpoint, got %v", addresses(got))
+	}
+}
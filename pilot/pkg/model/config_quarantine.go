@@ -0,0 +1,270 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	quarantineKindTag = monitoring.MustCreateLabel("kind")
+
+	// configQuarantined is the number of configs of each kind excluded from the push context because they were
+	// rejected by proxies. The quarantined configs are listed by the config_quarantinez debug endpoint.
+	configQuarantined = monitoring.NewGauge(
+		"pilot_config_quarantined",
+		"Number of configs excluded from the pushed config because they were rejected by proxies.",
+		monitoring.WithLabels(quarantineKindTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(configQuarantined)
+}
+
+// QuarantinedConfig is a config excluded from the push context.
+type QuarantinedConfig struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Reason    string    `json:"reason"`
+	Time      time.Time `json:"time"`
+}
+
+// ConfigQuarantine holds the configs excluded from the push context because proxies rejected the config generated
+// from them. The configs stay quarantined until they are released, even if they are updated.
+type ConfigQuarantine struct {
+	mu      sync.RWMutex
+	configs map[ConfigKey]QuarantinedConfig
+	// store is the store hiding the quarantined configs, notified when configs are quarantined or released.
+	store *quarantineConfigStore
+}
+
+// NewConfigQuarantine returns an empty quarantine.
+func NewConfigQuarantine() *ConfigQuarantine {
+	return &ConfigQuarantine{configs: map[ConfigKey]QuarantinedConfig{}}
+}
+
+// Add quarantines the config, returning false if it already was. The handlers of the store hiding the quarantined
+// configs are notified of the deletion of the config.
+func (q *ConfigQuarantine) Add(key ConfigKey, reason string, t time.Time) bool {
+	q.mu.Lock()
+	if _, f := q.configs[key]; f {
+		q.mu.Unlock()
+		return false
+	}
+	q.configs[key] = QuarantinedConfig{
+		Kind:      key.Kind.Kind,
+		Name:      key.Name,
+		Namespace: key.Namespace,
+		Reason:    reason,
+		Time:      t,
+	}
+	q.recordLocked(key.Kind.Kind)
+	store := q.store
+	q.mu.Unlock()
+
+	if store != nil {
+		store.quarantined(key)
+	}
+	return true
+}
+
+// Release releases the quarantined configs of the kind, name and namespace, and returns their keys. The handlers of
+// the store hiding the quarantined configs are notified of the addition of the configs.
+func (q *ConfigQuarantine) Release(kind, name, namespace string) []ConfigKey {
+	q.mu.Lock()
+	var released []ConfigKey
+	for key := range q.configs {
+		if key.Kind.Kind == kind && key.Name == name && key.Namespace == namespace {
+			delete(q.configs, key)
+			released = append(released, key)
+		}
+	}
+	q.recordLocked(kind)
+	store := q.store
+	q.mu.Unlock()
+
+	if store != nil {
+		for _, key := range released {
+			store.released(key)
+		}
+	}
+	return released
+}
+
+// Contains returns whether the config is quarantined.
+func (q *ConfigQuarantine) Contains(key ConfigKey) bool {
+	if q == nil {
+		return false
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	_, f := q.configs[key]
+	return f
+}
+
+// Empty returns whether no config is quarantined.
+func (q *ConfigQuarantine) Empty() bool {
+	if q == nil {
+		return true
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return len(q.configs) == 0
+}
+
+// List returns the quarantined configs, sorted by kind, namespace and name.
+func (q *ConfigQuarantine) List() []QuarantinedConfig {
+	q.mu.RLock()
+	out := make([]QuarantinedConfig, 0, len(q.configs))
+	for _, c := range q.configs {
+		out = append(out, c)
+	}
+	q.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// AddMetrics adds each quarantined config to the push status of the push context, keyed by kind, namespace and name.
+func (q *ConfigQuarantine) AddMetrics(push *PushContext) {
+	if q.Empty() {
+		return
+	}
+	for _, c := range q.List() {
+		push.AddMetric(ProxyStatusQuarantinedConfig, fmt.Sprintf("%s/%s/%s", c.Kind, c.Namespace, c.Name), "", c.Reason)
+	}
+}
+
+// recordLocked records the number of quarantined configs of the kind.
+func (q *ConfigQuarantine) recordLocked(kind string) {
+	n := 0
+	for key := range q.configs {
+		if key.Kind.Kind == kind {
+			n++
+		}
+	}
+	configQuarantined.With(quarantineKindTag.Value(kind)).Record(float64(n))
+}
+
+// quarantineConfigStore hides the quarantined configs of the store. The events of the quarantined configs are not
+// passed to the handlers, which are instead notified of their deletion when they are quarantined, and of their
+// addition when they are released, so that the configs are also hidden from the registries built from the events,
+// such as the ServiceEntry registry. The ledger of the store is updated likewise, so that the config version and
+// the distribution status reflect the quarantined configs.
+type quarantineConfigStore struct {
+	ConfigStoreCache
+	quarantine *ConfigQuarantine
+
+	mu       sync.RWMutex
+	handlers map[config.GroupVersionKind][]func(config.Config, config.Config, Event)
+}
+
+// NewQuarantineConfigStore returns a store hiding the configs of the quarantine from the reads and the event
+// handlers of the store.
+func NewQuarantineConfigStore(store ConfigStoreCache, quarantine *ConfigQuarantine) ConfigStoreCache {
+	s := &quarantineConfigStore{
+		ConfigStoreCache: store,
+		quarantine:       quarantine,
+		handlers:         map[config.GroupVersionKind][]func(config.Config, config.Config, Event){},
+	}
+	quarantine.mu.Lock()
+	quarantine.store = s
+	quarantine.mu.Unlock()
+	return s
+}
+
+func (s *quarantineConfigStore) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
+	if s.quarantine.Contains(ConfigKey{Kind: typ, Name: name, Namespace: namespace}) {
+		return nil
+	}
+	return s.ConfigStoreCache.Get(typ, name, namespace)
+}
+
+func (s *quarantineConfigStore) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	configs, err := s.ConfigStoreCache.List(typ, namespace)
+	if err != nil || s.quarantine.Empty() {
+		return configs, err
+	}
+	out := make([]config.Config, 0, len(configs))
+	for _, c := range configs {
+		if !s.quarantine.Contains(ConfigKey{Kind: typ, Name: c.Name, Namespace: c.Namespace}) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *quarantineConfigStore) RegisterEventHandler(kind config.GroupVersionKind, handler func(config.Config, config.Config, Event)) {
+	s.mu.Lock()
+	s.handlers[kind] = append(s.handlers[kind], handler)
+	s.mu.Unlock()
+	s.ConfigStoreCache.RegisterEventHandler(kind, func(old config.Config, cur config.Config, event Event) {
+		if s.quarantine.Contains(ConfigKey{Kind: kind, Name: cur.Name, Namespace: cur.Namespace}) {
+			// The config stays quarantined until it is released, even if it is updated.
+			return
+		}
+		handler(old, cur, event)
+	})
+}
+
+// quarantined removes the config from the ledger and notifies the handlers of its deletion.
+func (s *quarantineConfigStore) quarantined(key ConfigKey) {
+	cfg := s.ConfigStoreCache.Get(key.Kind, key.Name, key.Namespace)
+	if cfg == nil {
+		return
+	}
+	// The ledger does not hash the keys it deletes, so the entry is cleared with an empty value instead.
+	if _, err := s.GetLedger().Put(config.Key(key.Kind.Kind, key.Name, key.Namespace), ""); err != nil {
+		log.Errorf("failed to delete quarantined %s %s/%s from the ledger: %v", key.Kind.Kind, key.Namespace, key.Name, err)
+	}
+	s.notify(key.Kind, *cfg, EventDelete)
+}
+
+// released puts the config back in the ledger and notifies the handlers of its addition.
+func (s *quarantineConfigStore) released(key ConfigKey) {
+	cfg := s.ConfigStoreCache.Get(key.Kind, key.Name, key.Namespace)
+	if cfg == nil {
+		// Deleted while quarantined.
+		return
+	}
+	if _, err := s.GetLedger().Put(config.Key(key.Kind.Kind, key.Name, key.Namespace), cfg.ResourceVersion); err != nil {
+		log.Errorf("failed to put released %s %s/%s in the ledger: %v", key.Kind.Kind, key.Namespace, key.Name, err)
+	}
+	s.notify(key.Kind, *cfg, EventAdd)
+}
+
+func (s *quarantineConfigStore) notify(kind config.GroupVersionKind, cfg config.Config, event Event) {
+	s.mu.RLock()
+	handlers := s.handlers[kind]
+	s.mu.RUnlock()
+	for _, h := range handlers {
+		h(config.Config{}, cfg, event)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/pkg/ledger"
+)
+
+// quarantinedValue returns the value of the pilot_config_quarantined gauge for the config kind.
+func quarantinedValue(t *testing.T, kind string) float64 {
+	t.Helper()
+	rows, err := view.RetrieveData(configQuarantined.Name())
+	if err != nil {
+		t.Fatalf("failed to get value for gauge %s: %v", configQuarantined.Name(), err)
+	}
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key.Name() == "kind" && tag.Value == kind {
+				return row.Data.(*view.LastValueData).Value
+			}
+		}
+	}
+	return 0
+}
+
+func TestConfigQuarantine(t *testing.T) {
+	store := &quarantineTestStore{
+		configs: []config.Config{
+			{
+				Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "bad", Namespace: "ns", ResourceVersion: "1"},
+				Spec: &networking.VirtualService{},
+			},
+			{
+				Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "good", Namespace: "ns", ResourceVersion: "1"},
+				Spec: &networking.VirtualService{},
+			},
+		},
+		ledger: ledger.Make(time.Minute),
+	}
+	for _, c := range store.configs {
+		if _, err := store.ledger.Put(config.Key(c.GroupVersionKind.Kind, c.Name, c.Namespace), c.ResourceVersion); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q := NewConfigQuarantine()
+	quarantined := NewQuarantineConfigStore(store, q)
+	var events []string
+	quarantined.RegisterEventHandler(gvk.VirtualService, func(_ config.Config, cur config.Config, event Event) {
+		events = append(events, event.String()+" "+cur.Name)
+	})
+	version := quarantined.Version()
+
+	listed := func() []string {
+		configs, err := quarantined.List(gvk.VirtualService, "ns")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, c := range configs {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	bad := ConfigKey{Kind: gvk.VirtualService, Name: "bad", Namespace: "ns"}
+	if !q.Add(bad, "rejected", time.Now()) {
+		t.Fatal("expected the config to be quarantined")
+	}
+	if q.Add(bad, "rejected again", time.Now()) {
+		t.Fatal("expected the config to be quarantined once")
+	}
+	if got := listed(); len(got) != 1 || got[0] != "good" {
+		t.Fatalf("expected only the good config to be listed, got %v", got)
+	}
+	if quarantined.Get(gvk.VirtualService, "bad", "ns") != nil {
+		t.Fatal("expected the quarantined config to be hidden")
+	}
+	if quarantined.Get(gvk.VirtualService, "good", "ns") == nil {
+		t.Fatal("expected the good config to be found")
+	}
+	if got := q.List(); len(got) != 1 || got[0].Name != "bad" || got[0].Kind != "VirtualService" || got[0].Reason != "rejected" {
+		t.Fatalf("unexpected quarantined configs %+v", got)
+	}
+	if v := quarantinedValue(t, "VirtualService"); v != 1 {
+		t.Fatalf("expected the quarantine gauge to be 1, got %v", v)
+	}
+	if quarantined.Version() == version {
+		t.Fatal("expected the config version to change with the quarantine")
+	}
+	push := NewPushContext()
+	q.AddMetrics(push)
+	if got := push.ProxyStatus[ProxyStatusQuarantinedConfig.Name()]["VirtualService/ns/bad"]; got.Message != "rejected" {
+		t.Fatalf("expected the quarantined config in the push status, got %+v", push.ProxyStatus)
+	}
+
+	// The updates of the quarantined config are hidden from the handlers.
+	store.handle(store.configs[0], EventUpdate)
+	store.handle(store.configs[1], EventUpdate)
+	if want := []string{"delete bad", "update good"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+
+	if released := q.Release("DestinationRule", "bad", "ns"); len(released) != 0 {
+		t.Fatalf("expected no config of another kind to be released, got %v", released)
+	}
+	if released := q.Release("VirtualService", "bad", "ns"); len(released) != 1 || released[0] != bad {
+		t.Fatalf("expected the config to be released, got %v", released)
+	}
+	if got := listed(); len(got) != 2 {
+		t.Fatalf("expected both configs to be listed after the release, got %v", got)
+	}
+	if !q.Empty() {
+		t.Fatal("expected the quarantine to be empty")
+	}
+	if v := quarantinedValue(t, "VirtualService"); v != 0 {
+		t.Fatalf("expected the quarantine gauge to be 0, got %v", v)
+	}
+	if quarantined.Version() != version {
+		t.Fatal("expected the config version to be restored by the release")
+	}
+	if want := []string{"delete bad", "update good", "add bad"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+}
+
+type quarantineTestStore struct {
+	ConfigStoreCache
+	configs  []config.Config
+	ledger   ledger.Ledger
+	handlers []func(config.Config, config.Config, Event)
+}
+
+func (s *quarantineTestStore) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
+	for _, c := range s.configs {
+		if c.GroupVersionKind == typ && c.Name == name && c.Namespace == namespace {
+			return &c
+		}
+	}
+	return nil
+}
+
+func (s *quarantineTestStore) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	return s.configs, nil
+}
+
+func (s *quarantineTestStore) RegisterEventHandler(_ config.GroupVersionKind, handler func(config.Config, config.Config, Event)) {
+	s.handlers = append(s.handlers, handler)
+}
+
+func (s *quarantineTestStore) Version() string {
+	return s.ledger.RootHash()
+}
+
+func (s *quarantineTestStore) GetLedger() ledger.Ledger {
+	return s.ledger
+}
+
+// handle passes an event of the config to the handlers.
+func (s *quarantineTestStore) handle(cfg config.Config, event Event) {
+	for _, h := range s.handlers {
+		h(cfg, cfg, event)
+	}
+}
@@ -34,6 +34,7 @@ import (
 	structpb "github.com/golang/protobuf/ptypes/struct"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
@@ -62,6 +63,13 @@ type Environment struct {
 	// service registries.
 	mesh.NetworksWatcher
 
+	// ProxyConfigOverridesWatcher provides the overrides of the default proxy config of the mesh for namespaces
+	// and workloads, pushed to the agents of the proxies.
+	ProxyConfigOverridesWatcher mesh.ProxyConfigOverridesWatcher
+
+	// MeshExtensionsWatcher provides the settings of the mesh which are not part of the mesh config.
+	MeshExtensionsWatcher mesh.MeshExtensionsWatcher
+
 	// PushContext holds informations during push generation. It is reset on config change, at the beginning
 	// of the pushAll. It will hold all errors and stats and possibly caches needed during the entire cache computation.
 	// DO NOT USE EXCEPT FOR TESTS AND HANDLING OF NEW CONNECTIONS.
@@ -123,6 +131,34 @@ func (e *Environment) AddNetworksHandler(h func()) {
 	}
 }
 
+// ProxyConfigOverrides returns the overrides of the default proxy config of the mesh, if any.
+func (e *Environment) ProxyConfigOverrides() *mesh.ProxyConfigOverrides {
+	if e != nil && e.ProxyConfigOverridesWatcher != nil {
+		return e.ProxyConfigOverridesWatcher.ProxyConfigOverrides()
+	}
+	return nil
+}
+
+func (e *Environment) AddProxyConfigOverridesHandler(h func()) {
+	if e != nil && e.ProxyConfigOverridesWatcher != nil {
+		e.ProxyConfigOverridesWatcher.AddProxyConfigOverridesHandler(h)
+	}
+}
+
+// MeshExtensions returns the settings of the mesh which are not part of the mesh config, if any.
+func (e *Environment) MeshExtensions() *mesh.MeshExtensions {
+	if e != nil && e.MeshExtensionsWatcher != nil {
+		return e.MeshExtensionsWatcher.MeshExtensions()
+	}
+	return nil
+}
+
+func (e *Environment) AddMeshExtensionsHandler(h func()) {
+	if e != nil && e.MeshExtensionsWatcher != nil {
+		e.MeshExtensionsWatcher.AddMeshExtensionsHandler(h)
+	}
+}
+
 func (e *Environment) AddMetric(metric monitoring.Metric, key string, proxyID, msg string) {
 	if e != nil && e.PushContext != nil {
 		e.PushContext.AddMetric(metric, key, proxyID, msg)
@@ -144,6 +180,32 @@ type XdsResourceGenerator interface {
 	Generate(proxy *Proxy, push *PushContext, w *WatchedResource, updates *PushRequest) Resources
 }
 
+// XdsContextResourceGenerator is implemented by generators able to build their resources with the scratch buffers
+// of a GenerationContext. The returned resources must not reference the buffers of the context, which is
+// released once they are sent.
+type XdsContextResourceGenerator interface {
+	XdsResourceGenerator
+	GenerateWithContext(ctx *GenerationContext, proxy *Proxy, push *PushContext, w *WatchedResource, updates *PushRequest) Resources
+}
+
+// XdsDiagnosticResourceGenerator is implemented by generators which skip the invalid resources they build, instead of
+// sending them and having the proxy reject the whole response. The skipped resources are returned as diagnostics,
+// and generation proceeds with the remaining resources.
+type XdsDiagnosticResourceGenerator interface {
+	XdsContextResourceGenerator
+	GenerateWithDiagnostics(ctx *GenerationContext, proxy *Proxy, push *PushContext, w *WatchedResource,
+		updates *PushRequest) (Resources, GenerationDiagnostics)
+}
+
+// XdsPartialResourceGenerator is implemented by generators able to generate only some of the watched resources,
+// when a proxy adds resource names to its subscription. The response only includes the resources of the names,
+// the proxy keeps the other resources it was sent. It must only be implemented for types whose responses may omit
+// watched resources, such as RDS and EDS.
+type XdsPartialResourceGenerator interface {
+	XdsResourceGenerator
+	GeneratePartial(proxy *Proxy, push *PushContext, w *WatchedResource, names []string, updates *PushRequest) Resources
+}
+
 // Proxy contains information about an specific instance of a proxy (envoy sidecar, gateway,
 // etc). The Proxy is initialized when a sidecar connects to Pilot, and populated from
 // 'node' info in the protocol as well as data extracted from registries.
@@ -194,6 +256,10 @@ type Proxy struct {
 	// service instances associated with the proxy
 	ServiceInstances []*ServiceInstance
 
+	// workloadLabels are the metadata labels merged with the labels of the service instances.
+	// Computed when the service instances are set.
+	workloadLabels labels.Instance
+
 	// Istio version associated with the Proxy
 	IstioVersion *IstioVersion
 
@@ -382,9 +448,6 @@ type BootstrapNodeMetadata struct {
 	// replaces POD_NAME
 	InstanceName string `json:"NAME,omitempty"`
 
-	// WorkloadName specifies the name of the workload represented by this node.
-	WorkloadName string `json:"WORKLOAD_NAME,omitempty"`
-
 	// Owner specifies the workload owner (opaque string). Typically, this is the owning controller of
 	// of the workload instance (ex: k8s deployment for a k8s pod).
 	Owner string `json:"OWNER,omitempty"`
@@ -414,9 +477,17 @@ type NodeMetadata struct {
 	// this field should be preferred if it is present.
 	ProxyConfig *NodeMetaProxyConfig `json:"PROXY_CONFIG,omitempty"`
 
+	// GatewayTopologyAnnotated is set if the gateway topology of the ProxyConfig is set by the proxy.istio.io/config
+	// annotation of the pod, rather than read from the mesh config when the proxy started. It then takes precedence
+	// over the proxy config resolved by istiod.
+	GatewayTopologyAnnotated StringBool `json:"GATEWAY_TOPOLOGY_ANNOTATED,omitempty"`
+
 	// IstioVersion specifies the Istio version associated with the proxy
 	IstioVersion string `json:"ISTIO_VERSION,omitempty"`
 
+	// WorkloadName specifies the name of the workload represented by this node.
+	WorkloadName string `json:"WORKLOAD_NAME,omitempty"`
+
 	// Labels specifies the set of workload instance (ex: k8s pod) labels associated with this node.
 	Labels map[string]string `json:"LABELS,omitempty"`
 
@@ -478,6 +549,15 @@ type NodeMetadata struct {
 	// Alpha in 1.1, based on feedback may be turned into an API or change. Set to "1" to enable.
 	HTTP10 string `json:"HTTP10,omitempty"`
 
+	// AccessLogSampling is the percentage of the access log entries emitted by the inbound listeners, optionally
+	// overridden per port, e.g. "10,8080:50". Outbound listeners are not sampled.
+	AccessLogSampling string `json:"sidecar.istio.io/accessLogSampling,omitempty"`
+
+	// InboundProxyProtocol indicates the inbound traffic of the workload carries a PROXY protocol (v1 or v2) header,
+	// typically because it is sent through an L4 load balancer. It will add the proxy_protocol listener filter to the
+	// inbound listeners of a sidecar, or to the listeners of a gateway. Set to "1" to enable.
+	InboundProxyProtocol string `json:"INBOUND_PROXY_PROTOCOL,omitempty"`
+
 	// Generator indicates the client wants to use a custom Generator plugin.
 	Generator string `json:"GENERATOR,omitempty"`
 
@@ -649,7 +729,7 @@ func (node *Proxy) SetSidecarScope(ps *PushContext) {
 	sidecarScope := node.SidecarScope
 
 	if node.Type == SidecarProxy {
-		workloadLabels := labels.Collection{node.Metadata.Labels}
+		workloadLabels := labels.Collection{node.WorkloadLabels()}
 		node.SidecarScope = ps.getSidecarScope(node, workloadLabels)
 	} else {
 		// Gateways should just have a default scope with egress: */*
@@ -689,9 +769,48 @@ func (node *Proxy) SetServiceInstances(serviceDiscovery ServiceDiscovery) error
 	})
 
 	node.ServiceInstances = instances
+	node.mergeWorkloadLabels()
 	return nil
 }
 
+// mergeWorkloadLabels merges the labels of the service instances, such as the labels of the WorkloadEntry
+// of a VM, into the metadata labels. The metadata labels take precedence on conflicting keys, and
+// otherwise the instances are considered in order.
+func (node *Proxy) mergeWorkloadLabels() {
+	var merged labels.Instance
+	for _, instance := range node.ServiceInstances {
+		if instance.Endpoint == nil {
+			continue
+		}
+		for k, v := range instance.Endpoint.Labels {
+			if merged == nil {
+				merged = labels.Instance{}
+			}
+			if _, f := merged[k]; !f {
+				merged[k] = v
+			}
+		}
+	}
+	if merged != nil && node.Metadata != nil {
+		for k, v := range node.Metadata.Labels {
+			merged[k] = v
+		}
+	}
+	node.workloadLabels = merged
+}
+
+// WorkloadLabels returns the labels used to select the Sidecar, EnvoyFilter and Gateway configs applying
+// to the proxy: its metadata labels, merged with the labels of its service instances if they are set.
+func (node *Proxy) WorkloadLabels() labels.Instance {
+	if node.workloadLabels != nil {
+		return node.workloadLabels
+	}
+	if node.Metadata == nil {
+		return nil
+	}
+	return node.Metadata.Labels
+}
+
 // SetWorkloadLabels will set the node.Metadata.Labels only when it is nil.
 func (node *Proxy) SetWorkloadLabels(env *Environment) error {
 	// First get the workload labels from node meta
@@ -764,20 +883,38 @@ func GetNetworkView(node *Proxy) map[string]bool {
 }
 
 // ParseMetadata parses the opaque Metadata from an Envoy Node into string key-value pairs.
-// Any non-string values are ignored.
+// Any non-string values are ignored. If PILOT_FILTER_NODE_METADATA is enabled, keys which are not allowed, or whose
+// values are too large, are dropped.
 func ParseMetadata(metadata *structpb.Struct) (*NodeMetadata, error) {
 	if metadata == nil {
 		return &NodeMetadata{}, nil
 	}
 
+	filtered := metadata
+	if features.FilterNodeMetadata {
+		filtered = filterNodeMetadata(metadata, allowedNodeMetadataKeys, features.NodeMetadataMaxValueSize)
+	}
+
 	buf := &bytes.Buffer{}
-	if err := (&jsonpb.Marshaler{OrigName: true}).Marshal(buf, metadata); err != nil {
-		return nil, fmt.Errorf("failed to read node metadata %v: %v", metadata, err)
+	if err := (&jsonpb.Marshaler{OrigName: true}).Marshal(buf, filtered); err != nil {
+		return nil, fmt.Errorf("failed to read node metadata %v: %v", filtered, err)
 	}
 	meta := &BootstrapNodeMetadata{}
 	if err := json.Unmarshal(buf.Bytes(), meta); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal node metadata (%v): %v", buf.String(), err)
 	}
+
+	if features.KeepRawNodeMetadata && filtered != metadata {
+		buf.Reset()
+		if err := (&jsonpb.Marshaler{OrigName: true}).Marshal(buf, metadata); err != nil {
+			return nil, fmt.Errorf("failed to read node metadata %v: %v", metadata, err)
+		}
+		var raw map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal node metadata (%v): %v", buf.String(), err)
+		}
+		meta.Raw = raw
+	}
 	return &meta.NodeMetadata, nil
 }
 
@@ -941,6 +1078,11 @@ func (node *Proxy) GetInterceptionMode() TrafficInterceptionMode {
 	return InterceptionRedirect
 }
 
+// InboundProxyProtocol returns true if the inbound traffic of the proxy carries a PROXY protocol header.
+func (node *Proxy) InboundProxyProtocol() bool {
+	return node != nil && node.Metadata != nil && node.Metadata.InboundProxyProtocol == "1"
+}
+
 // SidecarDNSListenerPort specifes the port at which the sidecar hosts a DNS resolver listener.
 // TODO: customize me. tools/istio-iptables package also has this hardcoded.
 const SidecarDNSListenerPort = 15013
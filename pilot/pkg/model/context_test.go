@@ -17,7 +17,9 @@ package model_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,9 +27,11 @@ import (
 	"github.com/golang/protobuf/jsonpb"
 	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
 	"istio.io/istio/pilot/pkg/serviceregistry/mock"
@@ -298,13 +302,10 @@ func TestParseMetadata(t *testing.T) {
 				Metadata: &model.NodeMetadata{Raw: map[string]interface{}{}}},
 		},
 		{
-			name:     "Capture Arbitrary Metadata",
+			name:     "Drop Arbitrary Metadata",
 			metadata: map[string]interface{}{"foo": "bar"},
 			out: &model.Proxy{Type: "sidecar", IPAddresses: []string{"1.1.1.1"}, DNSDomain: "domain", ID: "id", IstioVersion: model.MaxIstioVersion,
-				Metadata: &model.NodeMetadata{
-					Raw: map[string]interface{}{
-						"foo": "bar",
-					}},
+				Metadata: &model.NodeMetadata{Raw: map[string]interface{}{}},
 			},
 		},
 		{
@@ -364,6 +365,108 @@ func TestParseMetadata(t *testing.T) {
 	}
 }
 
+func droppedMetadataCount(t *testing.T, reason string) float64 {
+	t.Helper()
+	rows, err := view.RetrieveData("pilot_node_metadata_dropped")
+	if err != nil {
+		t.Fatalf("failed to get value for pilot_node_metadata_dropped: %v", err)
+	}
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key.Name() == "reason" && tag.Value == reason {
+				return row.Data.(*view.SumData).Value
+			}
+		}
+	}
+	return 0
+}
+
+func TestParseMetadataFilter(t *testing.T) {
+	metadata := map[string]interface{}{
+		"NAMESPACE": "default",
+		"LABELS":    map[string]string{"app": "foo"},
+		"OWNER":     strings.Repeat("x", features.NodeMetadataMaxValueSize+1),
+	}
+	for i := 0; i < 100; i++ {
+		metadata[fmt.Sprintf("JUNK_%d", i)] = strings.Repeat("junk", 256)
+	}
+	meta, err := mapToStruct(metadata)
+	if err != nil {
+		t.Fatalf("failed to setup metadata: %v", err)
+	}
+
+	unknown := droppedMetadataCount(t, "unknown_key")
+	oversized := droppedMetadataCount(t, "oversized")
+	parsed, err := model.ParseMetadata(meta)
+	if err != nil {
+		t.Fatalf("failed to parse metadata: %v", err)
+	}
+	if got := droppedMetadataCount(t, "unknown_key") - unknown; got != 100 {
+		t.Errorf("expected 100 unknown keys to be dropped, got %v", got)
+	}
+	if got := droppedMetadataCount(t, "oversized") - oversized; got != 1 {
+		t.Errorf("expected 1 oversized key to be dropped, got %v", got)
+	}
+
+	if parsed.Namespace != "default" || parsed.Labels["app"] != "foo" {
+		t.Errorf("expected known metadata to be kept, got %+v", parsed)
+	}
+	want := map[string]interface{}{
+		"NAMESPACE": "default",
+		"LABELS":    map[string]interface{}{"app": "foo"},
+	}
+	if !reflect.DeepEqual(parsed.Raw, want) {
+		t.Errorf("got raw metadata %v, want %v", parsed.Raw, want)
+	}
+	raw, err := json.Marshal(parsed.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) > 100 {
+		t.Errorf("expected filtered metadata to be small, got %d bytes", len(raw))
+	}
+
+	// The metadata kept for the proxy, and echoed in its config and debug output, shrinks by the dropped values.
+	filteredSize := metadataSize(t, parsed)
+	features.FilterNodeMetadata = false
+	unfiltered, err := model.ParseMetadata(meta)
+	features.FilterNodeMetadata = true
+	if err != nil {
+		t.Fatalf("failed to parse metadata: %v", err)
+	}
+	if unfilteredSize := metadataSize(t, unfiltered); unfilteredSize-filteredSize < 100*len(strings.Repeat("junk", 256)) {
+		t.Errorf("expected the filter to drop the junk metadata, got %d bytes filtered and %d bytes unfiltered",
+			filteredSize, unfilteredSize)
+	}
+
+	features.KeepRawNodeMetadata = true
+	defer func() { features.KeepRawNodeMetadata = false }()
+	parsed, err = model.ParseMetadata(meta)
+	if err != nil {
+		t.Fatalf("failed to parse metadata: %v", err)
+	}
+	if len(parsed.Raw) != len(metadata) {
+		t.Errorf("expected raw metadata to be complete, got %d keys", len(parsed.Raw))
+	}
+	if parsed.Namespace != "default" {
+		t.Errorf("expected known metadata to be kept, got %+v", parsed)
+	}
+}
+
+// metadataSize returns the size of the metadata kept for a proxy, including its raw metadata.
+func metadataSize(t *testing.T, m *model.NodeMetadata) int {
+	t.Helper()
+	structured, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(m.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(structured) + len(raw)
+}
+
 func mapToStruct(msg map[string]interface{}) (*structpb.Struct, error) {
 	b, err := json.Marshal(msg)
 	if err != nil {
@@ -17,11 +17,46 @@ package model
 import (
 	"fmt"
 
+	"github.com/gogo/protobuf/proto"
+
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/visibility"
 )
 
+// Types of the conflicts between destination rules merged for the same host.
+const (
+	DestinationRuleConflictTLSMode  = "tls-mode"
+	DestinationRuleConflictLBPolicy = "lb-policy"
+	DestinationRuleConflictOutlier  = "outlier"
+	DestinationRuleConflictSubset   = "subset"
+)
+
+// DestinationRuleMerge describes the destination rules of a namespace merged for a host, as displayed by
+// /debug/destrulez.
+type DestinationRuleMerge struct {
+	Host      host.Name `json:"host"`
+	Namespace string    `json:"namespace"`
+	// Scope is the index the merge belongs to: "local" for the rules applying to the proxies of the namespace,
+	// "exported" for the rules exported to other namespaces and "root" for the private rules of the root namespace.
+	Scope string `json:"scope"`
+	// Configs are the names of the merged destination rules, in merge order.
+	Configs []string `json:"configs"`
+	// TrafficPolicy is the name of the destination rule the top level traffic policy is taken from, if any.
+	TrafficPolicy string `json:"trafficPolicy,omitempty"`
+	// Conflicts are the fields of the merged destination rules ignored because an earlier rule sets them.
+	Conflicts []DestinationRuleConflict `json:"conflicts,omitempty"`
+}
+
+// DestinationRuleConflict is a field of a destination rule ignored while merging it.
+type DestinationRuleConflict struct {
+	Type string `json:"type"`
+	// Config is the name of the destination rule whose field is ignored.
+	Config  string `json:"config"`
+	Message string `json:"message"`
+}
+
 // This function merges one or more destination rules for a given host string
 // into a single destination rule. Note that it does not perform inheritance style merging.
 // IOW, given three dest rules (*.foo.com, *.foo.com, *.com), calling this function for
@@ -37,6 +72,8 @@ func (ps *PushContext) mergeDestinationRule(p *processedDestRules, destRuleConfi
 	resolvedHost := ResolveShortnameToFQDN(rule.Host, destRuleConfig.Meta)
 
 	if mdr, exists := p.destRule[resolvedHost]; exists {
+		merge := p.merges[resolvedHost]
+		merge.Configs = append(merge.Configs, destRuleConfig.Name)
 		// Deep copy destination rule, to prevent mutate it later when merge with a new one.
 		// This can happen when there are more than one destination rule of same host in one namespace.
 		copied := mdr.DeepCopy()
@@ -59,6 +96,8 @@ func (ps *PushContext) mergeDestinationRule(p *processedDestRules, destRuleConfi
 				ps.AddMetric(DuplicatedSubsets, string(resolvedHost), "",
 					fmt.Sprintf("Duplicate subset %s found while merging destination rules for %s",
 						subset.Name, string(resolvedHost)))
+				merge.addConflict(DestinationRuleConflictSubset, destRuleConfig.Name,
+					fmt.Sprintf("subset %s is already defined", subset.Name))
 			}
 		}
 
@@ -66,6 +105,9 @@ func (ps *PushContext) mergeDestinationRule(p *processedDestRules, destRuleConfi
 		// traffic policy, use the one from the incoming rule.
 		if mergedRule.TrafficPolicy == nil && rule.TrafficPolicy != nil {
 			mergedRule.TrafficPolicy = rule.TrafficPolicy
+			merge.TrafficPolicy = destRuleConfig.Name
+		} else if rule.TrafficPolicy != nil {
+			merge.addTrafficPolicyConflicts(mergedRule.TrafficPolicy, rule.TrafficPolicy, destRuleConfig.Name)
 		}
 
 		// If there is no exportTo in the existing rule and
@@ -81,4 +123,34 @@ func (ps *PushContext) mergeDestinationRule(p *processedDestRules, destRuleConfi
 	p.hosts = append(p.hosts, resolvedHost)
 	p.destRule[resolvedHost] = &destRuleConfig
 	p.exportTo[resolvedHost] = exportToMap
+	merge := &DestinationRuleMerge{
+		Host:      resolvedHost,
+		Namespace: destRuleConfig.Namespace,
+		Configs:   []string{destRuleConfig.Name},
+	}
+	if rule.TrafficPolicy != nil {
+		merge.TrafficPolicy = destRuleConfig.Name
+	}
+	p.merges[resolvedHost] = merge
+}
+
+func (m *DestinationRuleMerge) addConflict(conflictType, configName, message string) {
+	m.Conflicts = append(m.Conflicts, DestinationRuleConflict{Type: conflictType, Config: configName, Message: message})
+}
+
+// addTrafficPolicyConflicts records the fields set by the ignored traffic policy of a rule which differ from the
+// traffic policy in use.
+func (m *DestinationRuleMerge) addTrafficPolicyConflicts(inUse, ignored *networking.TrafficPolicy, configName string) {
+	if ignored.Tls != nil && inUse.GetTls().GetMode() != ignored.Tls.Mode {
+		m.addConflict(DestinationRuleConflictTLSMode, configName, fmt.Sprintf("TLS mode %v is ignored, %s sets %v",
+			ignored.Tls.Mode, m.TrafficPolicy, inUse.GetTls().GetMode()))
+	}
+	if ignored.LoadBalancer != nil && !proto.Equal(inUse.GetLoadBalancer(), ignored.LoadBalancer) {
+		m.addConflict(DestinationRuleConflictLBPolicy, configName,
+			fmt.Sprintf("load balancer settings are ignored, %s sets them", m.TrafficPolicy))
+	}
+	if ignored.OutlierDetection != nil && !proto.Equal(inUse.GetOutlierDetection(), ignored.OutlierDetection) {
+		m.addConflict(DestinationRuleConflictOutlier, configName,
+			fmt.Sprintf("outlier detection settings are ignored, %s sets them", m.TrafficPolicy))
+	}
 }
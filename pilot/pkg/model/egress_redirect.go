@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+// egressRedirect is the egress gateway service the sidecars send the traffic of a host to.
+type egressRedirect struct {
+	gateway host.Name
+	// port of the gateway service, 0 for the port with the number of the port of the host.
+	port int
+}
+
+// egressRedirects maps the hosts routed through an egress gateway to the gateway, see
+// mesh.EgressGatewayRedirect.
+type egressRedirects struct {
+	// mesh are the redirects of all the namespaces.
+	mesh map[host.Name]egressRedirect
+	// byNamespace are the redirects of a namespace. They take precedence over mesh.
+	byNamespace map[string]map[host.Name]egressRedirect
+}
+
+// newEgressRedirects returns the egress gateway redirects of the mesh extensions.
+func newEgressRedirects(extensions *mesh.MeshExtensions) egressRedirects {
+	out := egressRedirects{mesh: map[host.Name]egressRedirect{}, byNamespace: map[string]map[host.Name]egressRedirect{}}
+	if extensions == nil {
+		return out
+	}
+	for _, r := range extensions.EgressGatewayRedirects {
+		redirects := out.mesh
+		if r.Namespace != "" {
+			if out.byNamespace[r.Namespace] == nil {
+				out.byNamespace[r.Namespace] = map[host.Name]egressRedirect{}
+			}
+			redirects = out.byNamespace[r.Namespace]
+		}
+		for _, h := range r.Hosts {
+			if host.Name(h) == host.Name(r.Gateway) {
+				continue
+			}
+			redirects[host.Name(h)] = egressRedirect{gateway: host.Name(r.Gateway), port: r.Port}
+		}
+	}
+	return out
+}
+
+func (r egressRedirects) lookup(namespace string, hostname host.Name) (egressRedirect, bool) {
+	if gw, f := r.byNamespace[namespace][hostname]; f {
+		return gw, true
+	}
+	gw, f := r.mesh[hostname]
+	return gw, f
+}
+
+// EgressGatewayHostname returns the hostname of the egress gateway service the hostname is routed through for the
+// proxy. Only sidecars are redirected: the gateways, including the egress gateway, resolve the hostname itself.
+func (ps *PushContext) EgressGatewayHostname(proxy *Proxy, hostname host.Name) (host.Name, bool) {
+	if proxy == nil || proxy.Type != SidecarProxy {
+		return "", false
+	}
+	r, f := ps.egressRedirects.lookup(proxy.ConfigNamespace, hostname)
+	return r.gateway, f
+}
+
+// EgressGatewayService returns the egress gateway service and port the proxy sends the traffic of the hostname and
+// port to, if the hostname is routed through an egress gateway. It is only meant for building the clusters and
+// endpoints of the hostname: the cluster keeps the name and DestinationRule of the hostname, with the endpoints of
+// the gateway.
+func (ps *PushContext) EgressGatewayService(proxy *Proxy, hostname host.Name, port int) (*Service, *Port) {
+	if proxy == nil || proxy.Type != SidecarProxy {
+		return nil, nil
+	}
+	r, f := ps.egressRedirects.lookup(proxy.ConfigNamespace, hostname)
+	if !f {
+		return nil, nil
+	}
+	svc := ps.ServiceForHostname(proxy, r.gateway)
+	if svc == nil {
+		// The gateway service need not be imported by the Sidecar of the proxy.
+		svc = ps.ServiceByHostname[r.gateway]
+	}
+	if svc == nil {
+		log.Debugf("egress gateway %s of %s not found", r.gateway, hostname)
+		return nil, nil
+	}
+	gwPort := port
+	if r.port != 0 {
+		gwPort = r.port
+	}
+	p, f := svc.Ports.GetByPort(gwPort)
+	if !f {
+		log.Debugf("egress gateway %s of %s has no port %d", r.gateway, hostname, gwPort)
+		return nil, nil
+	}
+	return svc, p
+}
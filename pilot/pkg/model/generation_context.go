@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"sync"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
+	"istio.io/istio/pilot/pkg/util/sets"
+)
+
+// GenerationContext holds scratch buffers reused across config generations, to avoid allocating them for
+// every proxy on every push.
+//
+// A GenerationContext is obtained with GetGenerationContext for a single generation of a single connection, and
+// must not be shared by concurrent generations. Slices and sets obtained from it, including the results of
+// generators built from them, are only valid until Release is called: the caller must be done with them, for
+// example by having marshaled the generated resources, before releasing the context.
+//
+// A nil *GenerationContext is valid and allocates new buffers every time, for callers which keep the results.
+type GenerationContext struct {
+	listeners []*listener.Listener
+	// tcpListeners and httpListeners are the temporary outbound listeners of a sidecar, collated before the
+	// listeners are returned.
+	tcpListeners  []*listener.Listener
+	httpListeners []*listener.Listener
+	clusters      []*cluster.Cluster
+	names         sets.Set
+	// diagnostics holds the resources skipped by the builders of this generation.
+	diagnostics GenerationDiagnostics
+}
+
+var generationContextPool = sync.Pool{
+	New: func() interface{} {
+		return &GenerationContext{names: sets.Set{}}
+	},
+}
+
+// GetGenerationContext returns an empty GenerationContext from the pool.
+func GetGenerationContext() *GenerationContext {
+	return generationContextPool.Get().(*GenerationContext)
+}
+
+// Release resets the context and returns it to the pool. The context and all buffers obtained from it must not
+// be used afterwards.
+func (gc *GenerationContext) Release() {
+	if gc == nil {
+		return
+	}
+	// Drop the references to the generated resources so that they can be garbage collected, but keep the capacity.
+	gc.listeners = clearListeners(gc.listeners)
+	gc.tcpListeners = clearListeners(gc.tcpListeners)
+	gc.httpListeners = clearListeners(gc.httpListeners)
+	for i := range gc.clusters {
+		gc.clusters[i] = nil
+	}
+	gc.clusters = gc.clusters[:0]
+	for name := range gc.names {
+		delete(gc.names, name)
+	}
+	// The diagnostics are kept by the connection after the generation, so their slice can't be reused.
+	gc.diagnostics = nil
+	generationContextPool.Put(gc)
+}
+
+// Listeners returns an empty slice with at least the capacity n. Only one slice is handed out per context: it
+// must be given back with KeepListeners before Listeners is called again.
+func (gc *GenerationContext) Listeners(n int) []*listener.Listener {
+	if gc == nil || cap(gc.listeners) < n {
+		return make([]*listener.Listener, 0, n)
+	}
+	return gc.listeners[:0]
+}
+
+// KeepListeners stores the slice obtained from Listeners, and grown by the caller, so that its capacity is reused
+// by the next generations.
+func (gc *GenerationContext) KeepListeners(l []*listener.Listener) {
+	if gc != nil {
+		gc.listeners = l
+	}
+}
+
+// OutboundListeners returns two empty slices for the tcp and http outbound listeners of a sidecar, with the capacity
+// left by the previous generations. They must be given back with KeepOutboundListeners before OutboundListeners is
+// called again.
+func (gc *GenerationContext) OutboundListeners() (tcp, http []*listener.Listener) {
+	if gc == nil {
+		return nil, nil
+	}
+	return gc.tcpListeners[:0], gc.httpListeners[:0]
+}
+
+// KeepOutboundListeners stores the slices obtained from OutboundListeners, and grown by the caller, so that their
+// capacity is reused by the next generations.
+func (gc *GenerationContext) KeepOutboundListeners(tcp, http []*listener.Listener) {
+	if gc != nil {
+		gc.tcpListeners, gc.httpListeners = tcp, http
+	}
+}
+
+// Clusters returns an empty slice, with the capacity left by the previous generations. Only one slice is handed
+// out per context: it must be given back with KeepClusters before Clusters is called again.
+func (gc *GenerationContext) Clusters() []*cluster.Cluster {
+	if gc == nil {
+		return make([]*cluster.Cluster, 0)
+	}
+	return gc.clusters[:0]
+}
+
+// KeepClusters stores the slice obtained from Clusters, and grown by the caller, so that its capacity is reused
+// by the next generations.
+func (gc *GenerationContext) KeepClusters(c []*cluster.Cluster) {
+	if gc != nil {
+		gc.clusters = c
+	}
+}
+
+// Names returns an empty set of names.
+func (gc *GenerationContext) Names() sets.Set {
+	if gc == nil {
+		return sets.Set{}
+	}
+	for name := range gc.names {
+		delete(gc.names, name)
+	}
+	return gc.names
+}
+
+// AddDiagnostic records a resource skipped by a builder. It is a no-op on a nil context, whose callers don't
+// report diagnostics.
+func (gc *GenerationContext) AddDiagnostic(d GenerationDiagnostic) {
+	if gc != nil {
+		gc.diagnostics = append(gc.diagnostics, d)
+	}
+}
+
+// Diagnostics returns the diagnostics recorded by the builders of this generation. The slice stays valid after
+// Release.
+func (gc *GenerationContext) Diagnostics() GenerationDiagnostics {
+	if gc == nil {
+		return nil
+	}
+	return gc.diagnostics
+}
+
+// clearListeners drops the references to the listeners of l and returns it emptied.
+func clearListeners(l []*listener.Listener) []*listener.Listener {
+	for i := range l {
+		l[i] = nil
+	}
+	return l[:0]
+}
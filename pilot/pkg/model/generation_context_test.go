@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+)
+
+func TestGenerationContextRelease(t *testing.T) {
+	gc := &GenerationContext{names: map[string]struct{}{}}
+
+	l := append(gc.Listeners(2), &listener.Listener{Name: "a"}, &listener.Listener{Name: "b"})
+	gc.KeepListeners(l)
+	tcp, http := gc.OutboundListeners()
+	tcp = append(tcp, &listener.Listener{Name: "tcp"})
+	http = append(http, &listener.Listener{Name: "http"})
+	gc.KeepOutboundListeners(tcp, http)
+	c := append(gc.Clusters(), &cluster.Cluster{Name: "a"})
+	gc.KeepClusters(c)
+	gc.Names().Insert("a")
+	gc.AddDiagnostic(GenerationDiagnostic{Resource: "a", Category: "cluster.LoadAssignment"})
+	diags := gc.Diagnostics()
+
+	gc.Release()
+	if len(gc.listeners) != 0 || cap(gc.listeners) < 2 {
+		t.Fatalf("listeners not reset: len %d cap %d", len(gc.listeners), cap(gc.listeners))
+	}
+	if l[0] != nil || l[1] != nil {
+		t.Fatalf("released listeners are still referenced")
+	}
+	if len(gc.tcpListeners) != 0 || len(gc.httpListeners) != 0 || tcp[0] != nil || http[0] != nil {
+		t.Fatalf("outbound listeners not reset")
+	}
+	if len(gc.clusters) != 0 || c[0] != nil {
+		t.Fatalf("clusters not reset")
+	}
+	if len(gc.names) != 0 {
+		t.Fatalf("names not reset")
+	}
+	if gc.diagnostics != nil {
+		t.Fatalf("diagnostics not reset")
+	}
+	if len(diags) != 1 || diags[0].Resource != "a" {
+		t.Fatalf("returned diagnostics changed by release: %v", diags)
+	}
+}
+
+func TestNilGenerationContext(t *testing.T) {
+	var gc *GenerationContext
+	if l := gc.Listeners(3); len(l) != 0 || cap(l) != 3 {
+		t.Fatalf("unexpected listeners len %d cap %d", len(l), cap(l))
+	}
+	if tcp, http := gc.OutboundListeners(); len(tcp) != 0 || len(http) != 0 {
+		t.Fatalf("unexpected outbound listeners %v %v", tcp, http)
+	}
+	if c := gc.Clusters(); c == nil || len(c) != 0 {
+		t.Fatalf("unexpected clusters %v", c)
+	}
+	if n := gc.Names(); n == nil || len(n) != 0 {
+		t.Fatalf("unexpected names %v", n)
+	}
+	gc.KeepListeners(nil)
+	gc.KeepOutboundListeners(nil, nil)
+	gc.KeepClusters(nil)
+	gc.AddDiagnostic(GenerationDiagnostic{Resource: "a"})
+	if d := gc.Diagnostics(); d != nil {
+		t.Fatalf("unexpected diagnostics %v", d)
+	}
+	gc.Release()
+}
@@ -0,0 +1,30 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+// GenerationDiagnostic reports a resource which was not sent to a proxy, as it was invalid or could not be built.
+type GenerationDiagnostic struct {
+	// Resource is the name of the resource, e.g. the name of the cluster.
+	Resource string `json:"resource"`
+	// Category is the kind of error, e.g. "cluster.OutlierDetection" for a cluster with an invalid outlier detection.
+	Category string `json:"category"`
+	// Config is the config the resource was generated from, if known.
+	Config *ConfigKey `json:"config,omitempty"`
+	// Message describes the error.
+	Message string `json:"message"`
+}
+
+// GenerationDiagnostics holds the diagnostics of the resources skipped by a generation.
+type GenerationDiagnostics []GenerationDiagnostic
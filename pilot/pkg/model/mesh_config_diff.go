@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+)
+
+// MeshConfigImpact is the set of XDS types affected by a change of the mesh config. The zero value is not
+// restricted to any type, and affects all of them.
+type MeshConfigImpact uint8
+
+const (
+	MeshImpactListeners MeshConfigImpact = 1 << iota
+	MeshImpactRoutes
+	MeshImpactClusters
+	MeshImpactEndpoints
+	// MeshImpactProxyConfig is the proxy config pushed to the agents, see ProxyConfigOverrides.
+	MeshImpactProxyConfig
+
+	MeshImpactAll = MeshImpactListeners | MeshImpactRoutes | MeshImpactClusters | MeshImpactEndpoints |
+		MeshImpactProxyConfig
+)
+
+var meshImpactNames = []struct {
+	impact MeshConfigImpact
+	name   string
+}{
+	{MeshImpactListeners, "listeners"},
+	{MeshImpactRoutes, "routes"},
+	{MeshImpactClusters, "clusters"},
+	{MeshImpactEndpoints, "endpoints"},
+	{MeshImpactProxyConfig, "proxyconfig"},
+}
+
+// Affects returns true if the types of impact are affected.
+func (i MeshConfigImpact) Affects(impact MeshConfigImpact) bool {
+	return i == 0 || i&impact != 0
+}
+
+// Names returns the names of the types affected.
+func (i MeshConfigImpact) Names() []string {
+	if i == 0 {
+		i = MeshImpactAll
+	}
+	var out []string
+	for _, n := range meshImpactNames {
+		if i&n.impact != 0 {
+			out = append(out, n.name)
+		}
+	}
+	return out
+}
+
+func (i MeshConfigImpact) String() string {
+	return strings.Join(i.Names(), ",")
+}
+
+// meshConfigFieldImpact maps the mesh config fields, by JSON name, to the XDS types they are used to generate.
+// A field missing from the map is presumed to affect all types, so a field must only be added once every use
+// of it in the generation of XDS was checked.
+var meshConfigFieldImpact = map[string]MeshConfigImpact{
+	"accessLogEncoding":           MeshImpactListeners,
+	"accessLogFile":               MeshImpactListeners,
+	"accessLogFormat":             MeshImpactListeners,
+	"connectTimeout":              MeshImpactClusters,
+	"dnsRefreshRate":              MeshImpactClusters,
+	"enableAutoMtls":              MeshImpactClusters,
+	"enableEnvoyAccessLogService": MeshImpactListeners,
+	"enableTracing":               MeshImpactListeners,
+	"inboundClusterStatName":      MeshImpactClusters | MeshImpactListeners,
+	"localityLbSetting":           MeshImpactClusters | MeshImpactEndpoints,
+	"outboundClusterStatName":     MeshImpactClusters | MeshImpactListeners,
+	"outboundTrafficPolicy":       MeshImpactListeners | MeshImpactRoutes | MeshImpactClusters,
+	"protocolDetectionTimeout":    MeshImpactListeners,
+	"proxyHttpPort":               MeshImpactListeners,
+	"tcpKeepalive":                MeshImpactClusters,
+	"thriftConfig":                MeshImpactListeners,
+}
+
+// MeshConfigDiff is the difference between two mesh configs.
+type MeshConfigDiff struct {
+	// Fields are the JSON names of the fields changed.
+	Fields []string
+	// Impact is the set of XDS types affected by the change.
+	Impact MeshConfigImpact
+}
+
+// DiffMeshConfig returns the fields changed between the previous and current mesh configs, and the XDS types they
+// affect. If either is unset, all types are affected.
+func DiffMeshConfig(prev, curr *meshconfig.MeshConfig) MeshConfigDiff {
+	if prev == nil || curr == nil {
+		return MeshConfigDiff{Impact: MeshImpactAll}
+	}
+	diff := MeshConfigDiff{}
+	ov, nv := reflect.ValueOf(prev).Elem(), reflect.ValueOf(curr).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		field := ov.Type().Field(i)
+		if strings.HasPrefix(field.Name, "XXX_") {
+			// Internal fields of the generated message.
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		diff.Fields = append(diff.Fields, name)
+		if impact, f := meshConfigFieldImpact[name]; f {
+			diff.Impact |= impact
+		} else {
+			diff.Impact = MeshImpactAll
+		}
+	}
+	sort.Strings(diff.Fields)
+	if len(diff.Fields) == 0 {
+		// The handlers are only called on changes, so the difference was not detected: be safe.
+		diff.Impact = MeshImpactAll
+	}
+	return diff
+}
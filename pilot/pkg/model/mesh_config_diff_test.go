@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestDiffMeshConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		update func(m *meshconfig.MeshConfig)
+		fields []string
+		impact MeshConfigImpact
+	}{
+		{
+			name:   "access log format",
+			update: func(m *meshconfig.MeshConfig) { m.AccessLogFormat = "[%START_TIME%]" },
+			fields: []string{"accessLogFormat"},
+			impact: MeshImpactListeners,
+		},
+		{
+			name: "listeners and clusters",
+			update: func(m *meshconfig.MeshConfig) {
+				m.AccessLogFile = "/dev/stdout"
+				m.ConnectTimeout = types.DurationProto(0)
+			},
+			fields: []string{"accessLogFile", "connectTimeout"},
+			impact: MeshImpactListeners | MeshImpactClusters,
+		},
+		{
+			name: "unknown field",
+			update: func(m *meshconfig.MeshConfig) {
+				m.AccessLogFile = "/dev/stdout"
+				m.TrustDomain = "example.com"
+			},
+			fields: []string{"accessLogFile", "trustDomain"},
+			impact: MeshImpactAll,
+		},
+		{
+			name:   "no change",
+			update: func(m *meshconfig.MeshConfig) {},
+			impact: MeshImpactAll,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := mesh.DefaultMeshConfig()
+			curr := mesh.DefaultMeshConfig()
+			tt.update(&curr)
+			got := DiffMeshConfig(&prev, &curr)
+			if !reflect.DeepEqual(got.Fields, tt.fields) || got.Impact != tt.impact {
+				t.Fatalf("got fields %v with impact %v, want %v with impact %v", got.Fields, got.Impact, tt.fields, tt.impact)
+			}
+		})
+	}
+
+	if got := DiffMeshConfig(nil, &meshconfig.MeshConfig{}); got.Impact != MeshImpactAll {
+		t.Errorf("expected an unknown previous mesh config to affect all types, got %v", got.Impact)
+	}
+}
+
+func TestMeshConfigImpact(t *testing.T) {
+	impact := MeshImpactListeners | MeshImpactRoutes
+	if !impact.Affects(MeshImpactListeners) || impact.Affects(MeshImpactClusters) {
+		t.Errorf("unexpected types affected by %v", impact)
+	}
+	if unrestricted := MeshConfigImpact(0); !unrestricted.Affects(MeshImpactEndpoints) {
+		t.Errorf("expected an unrestricted push to affect all types")
+	}
+	if got := impact.String(); got != "listeners,routes" {
+		t.Errorf("got %q", got)
+	}
+}
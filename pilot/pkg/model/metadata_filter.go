@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"golang.org/x/time/rate"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/pkg/monitoring"
+)
+
+func init() {
+	monitoring.MustRegister(nodeMetadataDropped)
+}
+
+var (
+	reasonTag = monitoring.MustCreateLabel("reason")
+
+	nodeMetadataDropped = monitoring.NewSum(
+		"pilot_node_metadata_dropped",
+		"Total number of node metadata keys dropped because they are not allowed or too large.",
+		monitoring.WithLabels(reasonTag),
+	)
+
+	nodeMetadataUnknownKey = nodeMetadataDropped.With(reasonTag.Value("unknown_key"))
+	nodeMetadataOversized  = nodeMetadataDropped.With(reasonTag.Value("oversized"))
+
+	// allowedNodeMetadataKeys is the set of node metadata keys accepted from proxies. If nil, all keys are accepted.
+	allowedNodeMetadataKeys = buildNodeMetadataAllowlist(features.NodeMetadataAllowlist)
+
+	// nodeMetadataWarnLimiter throttles the warnings for dropped keys, as a misconfigured client may reconnect often.
+	nodeMetadataWarnLimiter = rate.NewLimiter(rate.Every(10*time.Second), 1)
+)
+
+// buildNodeMetadataAllowlist returns the keys of BootstrapNodeMetadata together with the comma separated extra keys.
+// It returns nil if extra contains "*".
+func buildNodeMetadataAllowlist(extra string) map[string]struct{} {
+	allowed := map[string]struct{}{}
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous {
+				collect(f.Type)
+				continue
+			}
+			if key := strings.Split(f.Tag.Get("json"), ",")[0]; key != "" && key != "-" {
+				allowed[key] = struct{}{}
+			}
+		}
+	}
+	collect(reflect.TypeOf(BootstrapNodeMetadata{}))
+
+	for _, key := range strings.Split(extra, ",") {
+		key = strings.TrimSpace(key)
+		if key == "*" {
+			return nil
+		}
+		if key != "" {
+			allowed[key] = struct{}{}
+		}
+	}
+	return allowed
+}
+
+// filterNodeMetadata returns metadata without the keys that are not in allowed or whose values are larger than
+// maxValueSize. If nothing is dropped, metadata is returned as is.
+func filterNodeMetadata(metadata *structpb.Struct, allowed map[string]struct{}, maxValueSize int) *structpb.Struct {
+	var dropped []string
+	for k, v := range metadata.Fields {
+		if allowed != nil {
+			if _, f := allowed[k]; !f {
+				nodeMetadataUnknownKey.Increment()
+				dropped = append(dropped, k)
+				continue
+			}
+		}
+		if maxValueSize > 0 && proto.Size(v) > maxValueSize {
+			nodeMetadataOversized.Increment()
+			dropped = append(dropped, k)
+		}
+	}
+	if len(dropped) == 0 {
+		return metadata
+	}
+
+	if nodeMetadataWarnLimiter.Allow() {
+		sort.Strings(dropped)
+		log.Warnf("dropped %d node metadata keys which are not allowed or too large: %v", len(dropped), dropped)
+	}
+	out := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(metadata.Fields)-len(dropped))}
+	for k, v := range metadata.Fields {
+		out.Fields[k] = v
+	}
+	for _, k := range dropped {
+		delete(out.Fields, k)
+	}
+	return out
+}
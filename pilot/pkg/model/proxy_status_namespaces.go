@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"istio.io/pkg/monitoring"
+)
+
+const (
+	// unknownNamespace is the namespace of the proxy status entries without a proxy.
+	unknownNamespace = "unknown"
+	// otherNamespaces is the namespace of the proxy status entries of the namespaces beyond the limit.
+	otherNamespaces = "other"
+)
+
+var (
+	proxyStatusMetricTag    = monitoring.MustCreateLabel("metric")
+	proxyStatusNamespaceTag = monitoring.MustCreateLabel("namespace")
+
+	// proxyStatusNamespaces tracks the entries of the proxy status metrics by namespace of the proxy, if
+	// PILOT_PROXY_STATUS_NAMESPACE_LIMIT is set.
+	proxyStatusNamespaces = monitoring.NewGauge(
+		"pilot_proxy_status_by_namespace",
+		"Entries of the proxy status metrics, by metric and namespace of the proxy.",
+		monitoring.WithLabels(proxyStatusMetricTag, proxyStatusNamespaceTag),
+	)
+
+	// recordedNamespacesMutex protects recordedNamespaces.
+	recordedNamespacesMutex sync.Mutex
+	// recordedNamespaces holds the namespaces last recorded for each metric, to reset those no longer reported.
+	recordedNamespaces = map[string]map[string]struct{}{}
+)
+
+func init() {
+	monitoring.MustRegister(proxyStatusNamespaces)
+}
+
+// NamespaceCount is the number of entries of a proxy status metric for the proxies of a namespace.
+type NamespaceCount struct {
+	Namespace string `json:"namespace"`
+	Count     int    `json:"count"`
+}
+
+// proxyStatusNamespace returns the namespace of a proxy ID, of the form <pod name>.<namespace>.
+func proxyStatusNamespace(proxyID string) string {
+	if i := strings.LastIndexByte(proxyID, '.'); i >= 0 && i < len(proxyID)-1 {
+		return proxyID[i+1:]
+	}
+	return unknownNamespace
+}
+
+// proxyStatusByNamespace counts the entries of the proxy status metrics by namespace, sorted by decreasing count.
+// Only the limit namespaces with the most entries are kept, the entries of the others are counted as "other".
+func proxyStatusByNamespace(status map[string]map[string]ProxyPushStatus, limit int) map[string][]NamespaceCount {
+	out := map[string][]NamespaceCount{}
+	for _, pm := range metrics {
+		entries := status[pm.Name()]
+		if len(entries) == 0 {
+			continue
+		}
+		byNamespace := map[string]int{}
+		for _, ev := range entries {
+			byNamespace[proxyStatusNamespace(ev.Proxy)]++
+		}
+		counts := make([]NamespaceCount, 0, len(byNamespace))
+		for ns, count := range byNamespace {
+			counts = append(counts, NamespaceCount{Namespace: ns, Count: count})
+		}
+		sort.Slice(counts, func(i, j int) bool {
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Namespace < counts[j].Namespace
+		})
+		if len(counts) > limit {
+			other := NamespaceCount{Namespace: otherNamespaces}
+			for _, c := range counts[limit:] {
+				other.Count += c.Count
+			}
+			counts = append(counts[:limit], other)
+		}
+		out[pm.Name()] = counts
+	}
+	return out
+}
+
+// recordProxyStatusNamespaces records the entries of the proxy status metrics by namespace, resetting the namespaces
+// which are no longer reported.
+func recordProxyStatusNamespaces(counts map[string][]NamespaceCount) {
+	recordedNamespacesMutex.Lock()
+	defer recordedNamespacesMutex.Unlock()
+	recorded := map[string]map[string]struct{}{}
+	for metric, nsCounts := range counts {
+		recorded[metric] = map[string]struct{}{}
+		for _, c := range nsCounts {
+			recordProxyStatusNamespace(metric, c.Namespace, float64(c.Count))
+			recorded[metric][c.Namespace] = struct{}{}
+		}
+	}
+	for metric, namespaces := range recordedNamespaces {
+		for ns := range namespaces {
+			if _, f := recorded[metric][ns]; !f {
+				recordProxyStatusNamespace(metric, ns, 0)
+			}
+		}
+	}
+	recordedNamespaces = recorded
+}
+
+func recordProxyStatusNamespace(metric, namespace string, value float64) {
+	proxyStatusNamespaces.With(proxyStatusMetricTag.Value(metric), proxyStatusNamespaceTag.Value(namespace)).Record(value)
+}
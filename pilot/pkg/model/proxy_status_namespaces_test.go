@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.opencensus.io/stats/view"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// proxyStatusNamespaceValue returns the value of pilot_proxy_status_by_namespace for the metric and namespace.
+func proxyStatusNamespaceValue(t *testing.T, metric, namespace string) float64 {
+	t.Helper()
+	rows, err := view.RetrieveData(proxyStatusNamespaces.Name())
+	if err != nil {
+		t.Fatalf("failed to get value for gauge %s: %v", proxyStatusNamespaces.Name(), err)
+	}
+	for _, row := range rows {
+		tags := map[string]string{}
+		for _, tag := range row.Tags {
+			tags[tag.Key.Name()] = tag.Value
+		}
+		if tags["metric"] == metric && tags["namespace"] == namespace {
+			return row.Data.(*view.LastValueData).Value
+		}
+	}
+	return 0
+}
+
+func TestProxyStatusNamespaces(t *testing.T) {
+	defer func(limit int) {
+		features.ProxyStatusNamespaceLimit = limit
+	}(features.ProxyStatusNamespaceLimit)
+
+	newPush := func(proxies map[string]int) *PushContext {
+		push := NewPushContext()
+		for ns, n := range proxies {
+			for i := 0; i < n; i++ {
+				id := fmt.Sprintf("pod-%d.%s", i, ns)
+				push.AddMetric(ProxyStatusNoService, id, id, "")
+			}
+		}
+		push.AddMetric(DuplicatedSubsets, "reviews.default.svc.cluster.local", "", "duplicate subset")
+		return push
+	}
+	statusNamespaces := func(push *PushContext) map[string][]NamespaceCount {
+		t.Helper()
+		status, err := push.StatusJSON(StatusFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out struct {
+			Namespaces map[string][]NamespaceCount `json:"namespaces"`
+		}
+		if err := json.Unmarshal(status, &out); err != nil {
+			t.Fatal(err)
+		}
+		return out.Namespaces
+	}
+	noService := ProxyStatusNoService.Name()
+
+	// The metrics are not labeled by default.
+	features.ProxyStatusNamespaceLimit = 0
+	push := newPush(map[string]int{"a": 1})
+	push.UpdateMetrics()
+	if got := statusNamespaces(push); got != nil {
+		t.Fatalf("expected no namespaces in the push status, got %v", got)
+	}
+	if v := proxyStatusNamespaceValue(t, noService, "a"); v != 0 {
+		t.Fatalf("expected no labeled metric, got %v", v)
+	}
+
+	features.ProxyStatusNamespaceLimit = 2
+	push = newPush(map[string]int{"a": 3, "b": 2, "c": 1})
+	push.UpdateMetrics()
+	want := map[string][]NamespaceCount{
+		noService:                {{"a", 3}, {"b", 2}, {otherNamespaces, 1}},
+		DuplicatedSubsets.Name(): {{unknownNamespace, 1}},
+	}
+	if got := statusNamespaces(push); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got namespaces %v, want %v", got, want)
+	}
+	for ns, count := range map[string]float64{"a": 3, "b": 2, otherNamespaces: 1, "c": 0} {
+		if v := proxyStatusNamespaceValue(t, noService, ns); v != count {
+			t.Errorf("expected %v entries for namespace %s, got %v", count, ns, v)
+		}
+	}
+	if v := proxyStatusNamespaceValue(t, DuplicatedSubsets.Name(), unknownNamespace); v != 1 {
+		t.Errorf("expected 1 entry without proxy, got %v", v)
+	}
+
+	// Namespaces no longer reported are reset.
+	push = newPush(map[string]int{"b": 1, "c": 2})
+	push.UpdateMetrics()
+	for ns, count := range map[string]float64{"a": 0, "b": 1, "c": 2, otherNamespaces: 0} {
+		if v := proxyStatusNamespaceValue(t, noService, ns); v != count {
+			t.Errorf("expected %v entries for namespace %s, got %v", count, ns, v)
+		}
+	}
+}
@@ -15,21 +15,29 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/hashicorp/go-multierror"
+
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/gateway"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -86,6 +94,11 @@ type PushContext struct {
 	ServiceByHostname             map[host.Name]*Service            `json:"-"`
 	// ServiceAccounts contains a map of hostname and port to service accounts.
 	ServiceAccounts map[host.Name]map[int][]string `json:"-"`
+	// egressRedirects are the hosts the sidecars route through an egress gateway service.
+	egressRedirects egressRedirects
+
+	// serviceCardinalityViolations are the services ignored because of PILOT_MAX_SERVICES_PER_NAMESPACE.
+	serviceCardinalityViolations []CardinalityViolation
 
 	// VirtualService related
 	// This contains all virtual services visible to this namespace extracted from
@@ -95,6 +108,11 @@ type PushContext struct {
 	privateVirtualServicesByNamespaceAndGateway map[string]map[string][]config.Config
 	// This contains all virtual services whose exportTo is "*", keyed by gateway
 	publicVirtualServicesByGateway map[string][]config.Config
+	// The host indexes of the three virtual service lists above, with the same keys.
+	// See VirtualServicesForGatewayAndHosts.
+	virtualServiceHostIndexExportedToNamespaceByGateway map[string]map[string]virtualServiceHostIndex
+	privateVirtualServiceHostIndexByNamespaceAndGateway map[string]map[string]virtualServiceHostIndex
+	publicVirtualServiceHostIndexByGateway              map[string]virtualServiceHostIndex
 
 	// destination rules are of three types:
 	//  namespaceLocalDestRules: all public/private dest rules pertaining to a service defined in a given namespace
@@ -104,7 +122,10 @@ type PushContext struct {
 	rootNamespaceLocalDestRules  *processedDestRules
 
 	// clusterLocalHosts extracted from the MeshConfig
-	clusterLocalHosts host.Names
+	clusterLocalHosts clusterLocalHosts
+
+	// staleClusters are the clusters whose registry is stale, when PILOT_ANNOTATE_STALE_ENDPOINTS is enabled.
+	staleClusters map[string]struct{}
 
 	// sidecars for each namespace
 	sidecarsByNamespace map[string][]*SidecarScope
@@ -128,6 +149,12 @@ type PushContext struct {
 	// Networks configuration.
 	Networks *meshconfig.MeshNetworks `json:"-"`
 
+	// ProxyConfigOverrides of the default proxy config of the mesh, for namespaces and workloads.
+	ProxyConfigOverrides *mesh.ProxyConfigOverrides `json:"-"`
+
+	// MeshExtensions are the settings of the mesh which are not part of the mesh config.
+	MeshExtensions *mesh.MeshExtensions `json:"-"`
+
 	// Discovery interface for listing services and instances.
 	ServiceDiscovery `json:"-"`
 
@@ -139,6 +166,14 @@ type PushContext struct {
 
 	initDone bool
 
+	// degradedPhases holds the init phases which failed, and whose data was copied from the previous
+	// push context instead. Only set if PILOT_ENABLE_RESILIENT_PUSH_CONTEXT_INIT is enabled.
+	degradedPhases map[string]DegradedPhase
+
+	// proxyStatusNamespaces holds the entries of each proxy status metric by namespace, sorted by decreasing count.
+	// Only set if PILOT_PROXY_STATUS_NAMESPACE_LIMIT is set.
+	proxyStatusNamespaces map[string][]NamespaceCount
+
 	Version string
 
 	// cache gateways addresses for each network
@@ -161,6 +196,8 @@ type processedDestRules struct {
 	exportTo map[host.Name]map[visibility.Instance]bool
 	// Map of dest rule host and the merged destination rules for that host
 	destRule map[host.Name]*config.Config
+	// Map of dest rule host and how the destination rules for that host were merged
+	merges map[host.Name]*DestinationRuleMerge
 }
 
 // XDSUpdater is used for direct updates of the xDS model and incremental push.
@@ -236,6 +273,20 @@ type PushRequest struct {
 	// There should only be multiple reasons if the push request is the result of two distinct triggers, rather than
 	// classifying a single trigger as having multiple reasons.
 	Reason []TriggerReason
+
+	// ServiceVisibilityDiff holds the services whose visibility changed compared to the previous push context.
+	// It is only set once Push is initialized incrementally from a previous push context. A merged request holds the
+	// changes since the push context before the first request, or nil if either diff is unknown.
+	ServiceVisibilityDiff *ServiceVisibilityDiff
+
+	// NetworkGatewaysChanged is set when a service used as a network gateway by the mesh networks changed, so that
+	// the network gateways are recomputed even if the push context is initialized incrementally. They are also
+	// recomputed when a ServiceEntry key of ConfigsUpdated names a network gateway service.
+	NetworkGatewaysChanged bool
+
+	// MeshConfigImpact restricts a push triggered by a mesh config change to the XDS types it affects, which are
+	// the only ones regenerated and removed from the cache. If unset, all types are affected.
+	MeshConfigImpact MeshConfigImpact
 }
 
 type TriggerReason string
@@ -257,8 +308,51 @@ const (
 	DebugTrigger TriggerReason = "debug"
 	// Describes a push triggered for a Secret change
 	SecretTrigger TriggerReason = "secret"
+	// Describes a push forced by an administrator, to a namespace or a proxy
+	AdminTrigger TriggerReason = "admin"
+	// Describes a push triggered by configs quarantined after being rejected by proxies, or released
+	QuarantineTrigger TriggerReason = "quarantine"
+	// Describes a push in response to a request of the proxy
+	ProxyRequest TriggerReason = "proxyrequest"
+
+	// MultipleTriggers is the reason label of a push merged from requests with different reasons.
+	MultipleTriggers TriggerReason = "multiple"
 )
 
+// knownTriggerReasons are the reasons a push may be labeled with in metrics.
+var knownTriggerReasons = map[TriggerReason]struct{}{
+	EndpointUpdate:    {},
+	ConfigUpdate:      {},
+	ServiceUpdate:     {},
+	ProxyUpdate:       {},
+	GlobalUpdate:      {},
+	UnknownTrigger:    {},
+	DebugTrigger:      {},
+	SecretTrigger:     {},
+	AdminTrigger:      {},
+	QuarantineTrigger: {},
+	ProxyRequest:      {},
+}
+
+// ReasonLabel returns the reason to label the metrics of the push with: the reason of the request, or
+// MultipleTriggers if the requests merged into it have different reasons. Reasons which are not known are
+// reported as UnknownTrigger, so that the cardinality of the metrics stays bounded.
+func (pr *PushRequest) ReasonLabel() TriggerReason {
+	if pr == nil || len(pr.Reason) == 0 {
+		return UnknownTrigger
+	}
+	reason := pr.Reason[0]
+	for _, r := range pr.Reason[1:] {
+		if r != reason {
+			return MultipleTriggers
+		}
+	}
+	if _, f := knownTriggerReasons[reason]; !f {
+		return UnknownTrigger
+	}
+	return reason
+}
+
 // Merge two update requests together
 func (first *PushRequest) Merge(other *PushRequest) *PushRequest {
 	if first == nil {
@@ -275,13 +369,23 @@ func (first *PushRequest) Merge(other *PushRequest) *PushRequest {
 		// If either is full we need a full push
 		Full: first.Full || other.Full,
 
+		NetworkGatewaysChanged: first.NetworkGatewaysChanged || other.NetworkGatewaysChanged,
+
 		// The other push context is presumed to be later and more up to date
 		Push: other.Push,
 
+		// The proxies pending the first push have not seen its visibility changes either
+		ServiceVisibilityDiff: first.ServiceVisibilityDiff.Merge(other.ServiceVisibilityDiff),
+
 		// Merge the two reasons. Note that we shouldn't deduplicate here, or we would under count
 		Reason: append(first.Reason, other.Reason...),
 	}
 
+	// Only restrict the types pushed if both are restricted
+	if first.MeshConfigImpact != 0 && other.MeshConfigImpact != 0 {
+		merged.MeshConfigImpact = first.MeshConfigImpact | other.MeshConfigImpact
+	}
+
 	// Do not merge when any one is empty
 	if len(first.ConfigsUpdated) > 0 && len(other.ConfigsUpdated) > 0 {
 		merged.ConfigsUpdated = make(map[ConfigKey]struct{}, len(first.ConfigsUpdated)+len(other.ConfigsUpdated))
@@ -408,12 +512,95 @@ var (
 		"Duplicate subsets across destination rules for same host",
 	)
 
+	destRuleConflictTypeTag = monitoring.MustCreateLabel("type")
+
+	// DestinationRuleConflicts tracks the fields of destination rules ignored while merging them with other
+	// destination rules for the same host, by type of conflict.
+	DestinationRuleConflicts = monitoring.NewGauge(
+		"pilot_destrule_conflicts",
+		"Fields of destination rules ignored while merging destination rules for the same host.",
+		monitoring.WithLabels(destRuleConflictTypeTag),
+	)
+
+	hostCollisionOutcomeTag = monitoring.MustCreateLabel("outcome")
+
+	// ServiceEntryHostCollisions tracks the hosts of the ServiceEntries of each namespace which are also the host of
+	// a Kubernetes service, by how the collision was resolved.
+	ServiceEntryHostCollisions = monitoring.NewGauge(
+		"pilot_service_entry_host_collisions",
+		"Hosts of the ServiceEntries of a namespace which are also the host of a Kubernetes service, by outcome.",
+		monitoring.WithLabels(hostCollisionOutcomeTag),
+	)
+
+	// AmbiguousServiceMTLSMode tracks services whose workloads are selected by PeerAuthentications with
+	// different mTLS modes, so that the mTLS mode inferred for auto mTLS falls back to permissive.
+	AmbiguousServiceMTLSMode = monitoring.NewGauge(
+		"pilot_service_mtls_mode_ambiguous",
+		"Services whose workloads have different mTLS modes, for which auto mTLS uses the permissive mode.",
+	)
+
+	// VirtualServiceGatewayBindingDenied tracks virtual services which reference a gateway that is not
+	// exported to their namespace, and were not bound to it.
+	VirtualServiceGatewayBindingDenied = monitoring.NewGauge(
+		"pilot_vs_gateway_binding_denied",
+		"Virtual services referencing gateways which are not exported to their namespace.",
+	)
+
+	// ProxyStatusInvalidResources tracks the configs, or the resources if their config is not known, from which
+	// invalid resources were generated for a proxy and skipped.
+	ProxyStatusInvalidResources = monitoring.NewGauge(
+		"pilot_xds_invalid_resources",
+		"Configs from which invalid resources were generated and not sent to the proxies.",
+	)
+
+	// ProxyStatusInvalidProxyConfig tracks the proxies for which the proxy config overrides resulted in an
+	// invalid proxy config, and which use the default proxy config of the mesh instead.
+	ProxyStatusInvalidProxyConfig = monitoring.NewGauge(
+		"pilot_xds_invalid_proxy_config",
+		"Proxies for which the proxy config overrides are invalid.",
+	)
+
+	// ProxyStatusQuarantinedConfig tracks the configs excluded from the push context because proxies rejected the
+	// config generated from them, see ConfigQuarantine.
+	ProxyStatusQuarantinedConfig = monitoring.NewGauge(
+		"pilot_xds_quarantined_configs",
+		"Configs excluded from the pushed config because proxies rejected the config generated from them.",
+	)
+
+	// ServiceCardinalityLimitExceeded tracks the services ignored because of PILOT_MAX_SERVICES_PER_NAMESPACE.
+	ServiceCardinalityLimitExceeded = monitoring.NewGauge(
+		"pilot_service_cardinality_limit_exceeded",
+		"Services ignored because their namespace has more services than the limit.",
+	)
+
+	// EndpointCardinalityLimitExceeded tracks the services whose endpoints were truncated or ignored because of
+	// PILOT_MAX_ENDPOINTS_PER_SERVICE or PILOT_MAX_ENDPOINTS_PER_SHARD.
+	EndpointCardinalityLimitExceeded = monitoring.NewGauge(
+		"pilot_endpoint_cardinality_limit_exceeded",
+		"Services whose endpoints in a cluster were truncated or ignored because they exceed the limits.",
+	)
+
 	// totalVirtualServices tracks the total number of virtual service
 	totalVirtualServices = monitoring.NewGauge(
 		"pilot_virt_services",
 		"Total virtual services known to pilot.",
 	)
 
+	initPhaseTag = monitoring.MustCreateLabel("phase")
+
+	// pushContextInitFailures tracks the init phases of the push context which failed.
+	pushContextInitFailures = monitoring.NewSum(
+		"pilot_push_context_init_failures",
+		"Number of failures initializing a phase of the push context.",
+		monitoring.WithLabels(initPhaseTag),
+	)
+
+	// networkGatewaysRecomputations tracks the computations of the gateways of the mesh networks.
+	networkGatewaysRecomputations = monitoring.NewSum(
+		"pilot_network_gateways_recomputations",
+		"Number of times the gateways of the mesh networks were computed.",
+	)
+
 	// LastPushStatus preserves the metrics and data collected during lasts global push.
 	// It can be used by debugging tools to inspect the push event. It will be reset after each push with the
 	// new version.
@@ -434,6 +621,14 @@ var (
 		ProxyStatusClusterNoInstances,
 		DuplicatedDomains,
 		DuplicatedSubsets,
+		DestinationRuleConflicts,
+		AmbiguousServiceMTLSMode,
+		VirtualServiceGatewayBindingDenied,
+		ProxyStatusInvalidResources,
+		ProxyStatusInvalidProxyConfig,
+		ProxyStatusQuarantinedConfig,
+		ServiceCardinalityLimitExceeded,
+		EndpointCardinalityLimitExceeded,
 	}
 )
 
@@ -441,7 +636,7 @@ func init() {
 	for _, m := range metrics {
 		monitoring.MustRegister(m)
 	}
-	monitoring.MustRegister(totalVirtualServices)
+	monitoring.MustRegister(totalVirtualServices, pushContextInitFailures, ServiceEntryHostCollisions, networkGatewaysRecomputations)
 }
 
 // NewPushContext creates a new PushContext structure to track push status.
@@ -467,14 +662,126 @@ func NewPushContext() *PushContext {
 	}
 }
 
-// JSON implements json.Marshaller, with a lock.
-func (ps *PushContext) StatusJSON() ([]byte, error) {
+// StatusFilter selects the entries of the push status, and how they are reported. The zero value selects all
+// of them.
+type StatusFilter struct {
+	// Metrics are the names of the metrics reported. All are reported if empty.
+	Metrics []string
+	// Namespaces are the namespaces of the proxies, derived from their IDs, of the entries reported. All are
+	// reported if empty.
+	Namespaces []string
+	// Limit is the maximum number of entries reported per metric, if positive. The entries with the lowest keys are
+	// reported.
+	Limit int
+	// Redact replaces the proxy IDs with stable hashes.
+	Redact bool
+}
+
+func (f StatusFilter) unfiltered() bool {
+	return len(f.Metrics) == 0 && len(f.Namespaces) == 0 && f.Limit <= 0 && !f.Redact
+}
+
+// StatusJSON marshals the proxy status selected by the filter, with a lock.
+func (ps *PushContext) StatusJSON(filter StatusFilter) ([]byte, error) {
 	if ps == nil {
 		return []byte{'{', '}'}, nil
 	}
 	ps.proxyStatusMutex.RLock()
 	defer ps.proxyStatusMutex.RUnlock()
-	return json.MarshalIndent(ps.ProxyStatus, "", "    ")
+	if filter.unfiltered() && len(ps.degradedPhases) == 0 && len(ps.proxyStatusNamespaces) == 0 {
+		return json.MarshalIndent(ps.ProxyStatus, "", "    ")
+	}
+	out := make(map[string]interface{}, len(ps.ProxyStatus)+2)
+	for k, v := range ps.ProxyStatus {
+		if !containsOrEmpty(filter.Metrics, k) {
+			continue
+		}
+		if !filter.unfiltered() {
+			if v = filterProxyStatus(v, filter); len(v) == 0 {
+				continue
+			}
+		}
+		out[k] = v
+	}
+	if len(ps.degradedPhases) > 0 {
+		out["degraded"] = ps.degradedPhases
+	}
+	if len(ps.proxyStatusNamespaces) > 0 {
+		namespaces := make(map[string][]NamespaceCount, len(ps.proxyStatusNamespaces))
+		for metric, counts := range ps.proxyStatusNamespaces {
+			if !containsOrEmpty(filter.Metrics, metric) {
+				continue
+			}
+			for _, c := range counts {
+				if containsOrEmpty(filter.Namespaces, c.Namespace) {
+					namespaces[metric] = append(namespaces[metric], c)
+				}
+			}
+		}
+		if len(namespaces) > 0 {
+			out["namespaces"] = namespaces
+		}
+	}
+	return json.MarshalIndent(out, "", "    ")
+}
+
+// filterProxyStatus returns the entries of a metric selected by the filter.
+func filterProxyStatus(entries map[string]ProxyPushStatus, filter StatusFilter) map[string]ProxyPushStatus {
+	keys := make([]string, 0, len(entries))
+	for k, ev := range entries {
+		if len(filter.Namespaces) > 0 && !containsOrEmpty(filter.Namespaces, proxyStatusNamespace(ev.Proxy)) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if filter.Limit > 0 && len(keys) > filter.Limit {
+		keys = keys[:filter.Limit]
+	}
+	out := make(map[string]ProxyPushStatus, len(keys))
+	for _, k := range keys {
+		ev := entries[k]
+		if filter.Redact && ev.Proxy != "" {
+			redacted := redactProxyID(ev.Proxy)
+			// The proxy ID is commonly the key of the entry, or part of its message.
+			k = strings.ReplaceAll(k, ev.Proxy, redacted)
+			ev.Message = strings.ReplaceAll(ev.Message, ev.Proxy, redacted)
+			ev.Proxy = redacted
+		}
+		out[k] = ev
+	}
+	return out
+}
+
+// redactProxyID returns a stable hash of the proxy ID, so the entries of a proxy can still be correlated.
+func redactProxyID(proxyID string) string {
+	sum := sha256.Sum256([]byte(proxyID))
+	return "proxy-" + hex.EncodeToString(sum[:8])
+}
+
+func containsOrEmpty(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DegradedPhase describes an init phase of the push context which failed.
+type DegradedPhase struct {
+	// Error is the error of the last failure.
+	Error string `json:"error"`
+	// ConsecutiveFailures is the number of consecutive push contexts in which the phase failed.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+}
+
+// Degraded returns the init phases which failed, and whose data was copied from the previous push context.
+func (ps *PushContext) Degraded() map[string]DegradedPhase {
+	return ps.degradedPhases
 }
 
 // OnConfigChange is called when a config change is detected.
@@ -488,13 +795,17 @@ func (ps *PushContext) OnConfigChange() {
 // UpdateMetrics will update the prometheus metrics based on the
 // current status of the push.
 func (ps *PushContext) UpdateMetrics() {
-	ps.proxyStatusMutex.RLock()
-	defer ps.proxyStatusMutex.RUnlock()
+	ps.proxyStatusMutex.Lock()
+	defer ps.proxyStatusMutex.Unlock()
 
 	for _, pm := range metrics {
 		mmap := ps.ProxyStatus[pm.Name()]
 		pm.Record(float64(len(mmap)))
 	}
+	if limit := features.ProxyStatusNamespaceLimit; limit > 0 {
+		ps.proxyStatusNamespaces = proxyStatusByNamespace(ps.ProxyStatus, limit)
+		recordProxyStatusNamespaces(ps.proxyStatusNamespaces)
+	}
 }
 
 func virtualServiceDestinations(v *networking.VirtualService) []*networking.Destination {
@@ -627,6 +938,109 @@ func (ps *PushContext) VirtualServicesForGateway(proxy *Proxy, gateway string) [
 	return res
 }
 
+// VirtualServicesForGatewayAndHost is VirtualServicesForGatewayAndHosts for a single host.
+func (ps *PushContext) VirtualServicesForGatewayAndHost(proxy *Proxy, gateway string, hostname host.Name) []config.Config {
+	return ps.VirtualServicesForGatewayAndHosts(proxy, gateway, []host.Name{hostname})
+}
+
+// VirtualServicesForGatewayAndHosts lists the virtual services bound to the gateway which may match any of the
+// hosts, in the same order as VirtualServicesForGateway. The result is a superset of the virtual services with
+// hosts intersecting the hosts, so callers still need to check the intersection. If any of the hosts is a
+// wildcard, or the gateway is the mesh gateway, this is the same as VirtualServicesForGateway.
+func (ps *PushContext) VirtualServicesForGatewayAndHosts(proxy *Proxy, gateway string, hosts []host.Name) []config.Config {
+	if !features.ScopeGatewayVirtualServicesByHost || gateway == constants.IstioMeshGateway {
+		return ps.VirtualServicesForGateway(proxy, gateway)
+	}
+	for _, h := range hosts {
+		if h.IsWildCarded() {
+			return ps.VirtualServicesForGateway(proxy, gateway)
+		}
+	}
+	ns := proxy.ConfigNamespace
+	res := ps.privateVirtualServiceHostIndexByNamespaceAndGateway[ns][gateway].lookup(
+		ps.privateVirtualServicesByNamespaceAndGateway[ns][gateway], hosts)
+	res = append(res, ps.virtualServiceHostIndexExportedToNamespaceByGateway[ns][gateway].lookup(
+		ps.virtualServicesExportedToNamespaceByGateway[ns][gateway], hosts)...)
+	res = append(res, ps.publicVirtualServiceHostIndexByGateway[gateway].lookup(
+		ps.publicVirtualServicesByGateway[gateway], hosts)...)
+	return res
+}
+
+// virtualServiceHostIndex maps the first-level wildcard-normalized hosts of a list of virtual services
+// (see virtualServiceHostIndexKey) to the positions of the virtual services in the list.
+type virtualServiceHostIndex map[string][]int
+
+func newVirtualServiceHostIndex(vses []config.Config) virtualServiceHostIndex {
+	index := virtualServiceHostIndex{}
+	for i, vs := range vses {
+		seen := map[string]struct{}{}
+		for _, h := range vs.Spec.(*networking.VirtualService).Hosts {
+			key := virtualServiceHostIndexKey(h)
+			if _, f := seen[key]; f {
+				continue
+			}
+			seen[key] = struct{}{}
+			index[key] = append(index[key], i)
+		}
+	}
+	return index
+}
+
+// lookup returns the virtual services of vses, the list the index was built from, with hosts which
+// may match any of the non wildcard hosts, in their order in vses.
+func (index virtualServiceHostIndex) lookup(vses []config.Config, hosts []host.Name) []config.Config {
+	if len(index) == 0 {
+		return nil
+	}
+	positions := []int{}
+	seen := map[int]struct{}{}
+	for _, h := range hosts {
+		for _, key := range virtualServiceHostLookupKeys(string(h)) {
+			for _, i := range index[key] {
+				if _, f := seen[i]; !f {
+					seen[i] = struct{}{}
+					positions = append(positions, i)
+				}
+			}
+		}
+	}
+	sort.Ints(positions)
+	res := make([]config.Config, 0, len(positions))
+	for _, i := range positions {
+		res = append(res, vses[i])
+	}
+	return res
+}
+
+// virtualServiceHostIndexKey returns the first-level wildcard-normalized form of a virtual service host: its
+// first label is replaced by a wildcard, so that both foo.example.com and *.example.com are indexed as
+// *.example.com. Hosts without a dot, including *, are indexed as is.
+func virtualServiceHostIndexKey(h string) string {
+	if i := strings.IndexByte(h, '.'); i >= 0 {
+		return "*" + h[i:]
+	}
+	return h
+}
+
+// virtualServiceHostLookupKeys returns the index keys of all the virtual service hosts which may match the
+// non wildcard host h: *.<parent> for each parent domain of h, and *. Hosts without a dot are also
+// looked up as is.
+func virtualServiceHostLookupKeys(h string) []string {
+	keys := []string{}
+	if !strings.Contains(h, ".") {
+		keys = append(keys, h)
+	}
+	for rest := h; ; {
+		i := strings.IndexByte(rest, '.')
+		if i < 0 {
+			break
+		}
+		rest = rest[i+1:]
+		keys = append(keys, "*."+rest)
+	}
+	return append(keys, "*")
+}
+
 // getSidecarScope returns a SidecarScope object associated with the
 // proxy. The SidecarScope object is a semi-processed view of the service
 // registry, and config state associated with the sidecar crd. The scope contains
@@ -764,11 +1178,47 @@ func (ps *PushContext) getExportedDestinationRuleFromNamespace(owningNamespace s
 	return nil
 }
 
+// IsConfigVisible returns true if the VirtualService, DestinationRule or ServiceEntry is visible to the namespace:
+// defined in it, or exported to it, explicitly or by the default exportTo of the mesh for its kind. Configs of the
+// other kinds are only visible to their namespace.
+func (ps *PushContext) IsConfigVisible(cfg config.Config, namespace string) bool {
+	if cfg.Namespace == namespace {
+		return true
+	}
+	var exportTo []string
+	var defaultExportTo map[visibility.Instance]bool
+	switch spec := cfg.Spec.(type) {
+	case *networking.VirtualService:
+		exportTo, defaultExportTo = spec.ExportTo, ps.defaultVirtualServiceExportTo
+	case *networking.DestinationRule:
+		exportTo, defaultExportTo = spec.ExportTo, ps.defaultDestinationRuleExportTo
+	case *networking.ServiceEntry:
+		exportTo, defaultExportTo = spec.ExportTo, ps.defaultServiceExportTo
+	default:
+		return false
+	}
+	if len(exportTo) == 0 {
+		return defaultExportTo[visibility.Public] || defaultExportTo[visibility.Instance(namespace)]
+	}
+	for _, e := range exportTo {
+		if visibility.Instance(e) == visibility.Public || e == namespace {
+			return true
+		}
+	}
+	return false
+}
+
 // IsClusterLocal indicates whether the endpoints for the service should only be accessible to clients
 // within the cluster.
 func (ps *PushContext) IsClusterLocal(service *Service) bool {
-	_, ok := MostSpecificHostMatch(service.Hostname, ps.clusterLocalHosts)
-	return ok
+	return ps.clusterLocalHosts.isClusterLocal(service.Hostname)
+}
+
+// IsStaleCluster returns whether the registry of the cluster is stale, so that its endpoints may be out of date.
+// It is always false unless PILOT_ANNOTATE_STALE_ENDPOINTS is enabled.
+func (ps *PushContext) IsStaleCluster(clusterID string) bool {
+	_, f := ps.staleClusters[clusterID]
+	return f
 }
 
 // SubsetToLabels returns the labels associated with a subset of a given service.
@@ -808,6 +1258,8 @@ func (ps *PushContext) InitContext(env *Environment, oldPushContext *PushContext
 
 	ps.Mesh = env.Mesh()
 	ps.Networks = env.Networks()
+	ps.ProxyConfigOverrides = env.ProxyConfigOverrides()
+	ps.MeshExtensions = env.MeshExtensions()
 	ps.ServiceDiscovery = env
 	ps.IstioConfigStore = env
 	ps.Version = env.Version()
@@ -817,58 +1269,240 @@ func (ps *PushContext) InitContext(env *Environment, oldPushContext *PushContext
 	// use the default export map
 	ps.initDefaultExportMaps()
 
+	phases := &initPhases{ps: ps, old: oldPushContext, ran: sets.NewSet()}
 	// create new or incremental update
 	if pushReq == nil || oldPushContext == nil || !oldPushContext.initDone || len(pushReq.ConfigsUpdated) == 0 {
-		if err := ps.createNewContext(env); err != nil {
+		if err := ps.createNewContext(env, phases); err != nil {
 			return err
 		}
 	} else {
-		if err := ps.updateContext(env, oldPushContext, pushReq); err != nil {
+		if err := ps.updateContext(env, oldPushContext, pushReq, phases); err != nil {
 			return err
 		}
 	}
-
-	// TODO: only do this when meshnetworks or gateway service changed
-	ps.initMeshNetworks()
+	phases.inheritDegraded()
 
 	ps.initClusterLocalHosts(env)
+	ps.initStaleClusters(env)
 
 	ps.initDone = true
 	return nil
 }
 
-func (ps *PushContext) createNewContext(env *Environment) error {
-	if err := ps.initServiceRegistry(env); err != nil {
-		return err
-	}
+// Init phases of the push context, as reported when they fail.
+const (
+	servicesInitPhase         = "services"
+	virtualServicesInitPhase  = "virtualservices"
+	destinationRulesInitPhase = "destinationrules"
+	authnInitPhase            = "authn"
+	authzInitPhase            = "authz"
+	envoyFiltersInitPhase     = "envoyfilters"
+	gatewaysInitPhase         = "gateways"
+	sidecarsInitPhase         = "sidecars"
+)
 
-	if err := ps.initVirtualServices(env); err != nil {
-		return err
-	}
+// sidecarsInitDependencies are the phases initialized before the sidecar scopes, which are built from their data.
+var sidecarsInitDependencies = []string{servicesInitPhase, virtualServicesInitPhase, destinationRulesInitPhase,
+	authnInitPhase, authzInitPhase, envoyFiltersInitPhase, gatewaysInitPhase}
 
-	if err := ps.initDestinationRules(env); err != nil {
-		return err
-	}
+// initPhases runs the init phases of a push context.
+type initPhases struct {
+	ps  *PushContext
+	old *PushContext
 
-	if err := ps.initAuthnPolicies(env); err != nil {
-		return err
+	// mutex protects ran and the degraded phases of ps, as phases may be initialized concurrently.
+	mutex sync.Mutex
+	// ran holds the phases which were initialized, rather than copied from the old push context.
+	ran sets.Set
+}
+
+// initStep is an init phase of the push context, initialized once the phases it depends on are.
+type initStep struct {
+	phase string
+	// after are the phases the step depends on. The phases which are not initialized by the same runSteps, but
+	// copied from the old push context, are ignored.
+	after    []string
+	init     func() error
+	fallback func(old *PushContext)
+}
+
+// runSteps initializes the phases of the steps, running up to PILOT_PUSH_CONTEXT_INIT_CONCURRENCY of the steps
+// whose dependencies are initialized concurrently, in the order of the steps. The steps depending on a phase which
+// failed are not run. The errors of the phases are returned together, prefixed by their phase.
+//
+// The init functions of steps which may run concurrently must only write the fields of the push context of their
+// phase, and read those of the phases they depend on.
+func (p *initPhases) runSteps(steps []initStep) error {
+	workers := features.PushContextInitConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	type result struct {
+		phase string
+		err   error
+	}
+	scheduled := sets.NewSet()
+	for _, step := range steps {
+		scheduled.Insert(step.phase)
+	}
+	results := make(chan result, len(steps))
+	started := sets.NewSet()
+	// done holds the phases which completed, with their error.
+	done := map[string]error{}
+	running := 0
+	var errs *multierror.Error
+	for len(done) < len(steps) {
+		for progress := true; progress; {
+			progress = false
+			for _, step := range steps {
+				if started.Contains(step.phase) {
+					continue
+				}
+				ready, skip := true, false
+				for _, dep := range step.after {
+					if !scheduled.Contains(dep) {
+						continue
+					}
+					if err, f := done[dep]; !f {
+						ready = false
+					} else if err != nil {
+						skip = true
+					}
+				}
+				switch {
+				case skip:
+					log.Warnf("%s not initialized, as a phase it depends on failed", step.phase)
+					started.Insert(step.phase)
+					done[step.phase] = fmt.Errorf("dependency failed")
+					progress = true
+				case ready && running < workers:
+					started.Insert(step.phase)
+					running++
+					progress = true
+					go func(step initStep) {
+						results <- result{phase: step.phase, err: p.run(step.phase, step.init, step.fallback)}
+					}(step)
+				}
+			}
+		}
+		if running == 0 {
+			if len(done) < len(steps) {
+				blocked := scheduled.Difference(started).UnsortedList()
+				sort.Strings(blocked)
+				return fmt.Errorf("push context init phases %v depend on phases which are not initialized", blocked)
+			}
+			break
+		}
+		r := <-results
+		running--
+		done[r.phase] = r.err
+		if r.err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %v", r.phase, r.err))
+		}
 	}
+	return errs.ErrorOrNil()
+}
 
-	if err := ps.initAuthorizationPolicies(env); err != nil {
-		authzLog.Errorf("failed to initialize authorization policies: %v", err)
+// run initializes a phase. If it fails and PILOT_ENABLE_RESILIENT_PUSH_CONTEXT_INIT is enabled, fallback
+// copies the data of the phase from the old push context instead, and the push context is marked as degraded.
+func (p *initPhases) run(phase string, init func() error, fallback func(old *PushContext)) error {
+	p.mutex.Lock()
+	p.ran.Insert(phase)
+	p.mutex.Unlock()
+	err := init()
+	if err == nil {
+		return nil
+	}
+	pushContextInitFailures.With(initPhaseTag.Value(phase)).Increment()
+	if !features.EnableResilientPushContextInit || p.old == nil || !p.old.initDone {
 		return err
 	}
+	log.Errorf("failed to initialize %s, using the ones of the previous push context: %v", phase, err)
+	fallback(p.old)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.ps.degradedPhases == nil {
+		p.ps.degradedPhases = map[string]DegradedPhase{}
+	}
+	p.ps.degradedPhases[phase] = DegradedPhase{
+		Error:               err.Error(),
+		ConsecutiveFailures: p.old.degradedPhases[phase].ConsecutiveFailures + 1,
+	}
+	return nil
+}
 
-	if err := ps.initEnvoyFilters(env); err != nil {
-		return err
+// inheritDegraded keeps the degraded phases of the old push context which were copied as is.
+func (p *initPhases) inheritDegraded() {
+	if p.old == nil {
+		return
 	}
+	for phase, degraded := range p.old.degradedPhases {
+		if p.ran.Contains(phase) {
+			continue
+		}
+		if p.ps.degradedPhases == nil {
+			p.ps.degradedPhases = map[string]DegradedPhase{}
+		}
+		p.ps.degradedPhases[phase] = degraded
+	}
+}
 
-	if err := ps.initGateways(env); err != nil {
+func (ps *PushContext) createNewContext(env *Environment, phases *initPhases) error {
+	if err := phases.runSteps([]initStep{
+		{
+			phase:    servicesInitPhase,
+			init:     func() error { return ps.initServiceRegistry(env, nil, nil) },
+			fallback: ps.copyServiceRegistry,
+		},
+		{
+			phase:    virtualServicesInitPhase,
+			init:     func() error { return ps.initVirtualServices(env) },
+			fallback: ps.copyVirtualServices,
+		},
+		{
+			phase:    destinationRulesInitPhase,
+			init:     func() error { return ps.initDestinationRules(env) },
+			fallback: ps.copyDestinationRules,
+		},
+		{
+			phase:    authnInitPhase,
+			init:     func() error { return ps.initAuthnPolicies(env) },
+			fallback: ps.copyAuthnPolicies,
+		},
+		{
+			phase:    authzInitPhase,
+			init:     func() error { return ps.initAuthorizationPoliciesLogged(env) },
+			fallback: ps.copyAuthorizationPolicies,
+		},
+		{
+			phase:    envoyFiltersInitPhase,
+			init:     func() error { return ps.initEnvoyFilters(env) },
+			fallback: ps.copyEnvoyFilters,
+		},
+		{
+			phase:    gatewaysInitPhase,
+			init:     func() error { return ps.initGateways(env) },
+			fallback: ps.copyGateways,
+		},
+		// Must be initialized in the end
+		{
+			phase:    sidecarsInitPhase,
+			after:    sidecarsInitDependencies,
+			init:     func() error { return ps.initSidecarScopes(env) },
+			fallback: ps.copySidecarScopes,
+		},
+	}); err != nil {
 		return err
 	}
 
-	// Must be initialized in the end
-	if err := ps.initSidecarScopes(env); err != nil {
+	ps.initMeshNetworks()
+	return nil
+}
+
+// initAuthorizationPoliciesLogged initializes the authorization policies, logging the failures.
+func (ps *PushContext) initAuthorizationPoliciesLogged(env *Environment) error {
+	if err := ps.initAuthorizationPolicies(env); err != nil {
+		authzLog.Errorf("failed to initialize authorization policies: %v", err)
 		return err
 	}
 	return nil
@@ -877,21 +1511,29 @@ func (ps *PushContext) createNewContext(env *Environment) error {
 func (ps *PushContext) updateContext(
 	env *Environment,
 	oldPushContext *PushContext,
-	pushReq *PushRequest) error {
+	pushReq *PushRequest,
+	phases *initPhases) error {
 
 	var servicesChanged, virtualServicesChanged, destinationRulesChanged, gatewayChanged,
 		authnChanged, authzChanged, envoyFiltersChanged, sidecarsChanged bool
+	networkGatewaysChanged := pushReq.NetworkGatewaysChanged
 
 	for conf := range pushReq.ConfigsUpdated {
 		switch conf.Kind {
 		case gvk.ServiceEntry:
 			servicesChanged = true
+			// The service may be the registryServiceName of a network gateway, from any registry.
+			if IsNetworkGatewayService(ps.Networks, host.Name(conf.Name)) {
+				networkGatewaysChanged = true
+			}
 		case gvk.DestinationRule:
 			destinationRulesChanged = true
 		case gvk.VirtualService:
 			virtualServicesChanged = true
 		case gvk.Gateway:
 			gatewayChanged = true
+			// the gateways a virtual service may bind to depend on the exportTo of the gateways.
+			virtualServicesChanged = true
 		case gvk.Sidecar:
 			sidecarsChanged = true
 		case gvk.EnvoyFilter:
@@ -910,96 +1552,338 @@ func (ps *PushContext) updateContext(
 		}
 	}
 
+	// The phases which did not change are copied from the old push context, the others are initialized.
+	var steps []initStep
 	if servicesChanged {
-		// Services have changed. initialize service registry
-		if err := ps.initServiceRegistry(env); err != nil {
-			return err
-		}
+		// Services have changed. initialize service registry, only recomputing the service accounts
+		// of the changed hostnames.
+		steps = append(steps, initStep{
+			phase: servicesInitPhase,
+			init: func() error {
+				if err := ps.initServiceRegistry(env, oldPushContext, changedServiceHostnames(pushReq.ConfigsUpdated)); err != nil {
+					return err
+				}
+				diff := ps.ServicesVisibilityDiff(oldPushContext)
+				if !diff.IsEmpty() {
+					log.Infof("Service visibility changed: %d added, %d removed, %d changed",
+						len(diff.Added), len(diff.Removed), len(diff.Changed))
+				}
+				pushReq.ServiceVisibilityDiff = diff
+				return nil
+			},
+			fallback: func(old *PushContext) {
+				ps.copyServiceRegistry(old)
+				pushReq.ServiceVisibilityDiff = &ServiceVisibilityDiff{}
+			},
+		})
 	} else {
-		ps.privateServicesByNamespace = oldPushContext.privateServicesByNamespace
-		ps.servicesExportedToNamespace = oldPushContext.servicesExportedToNamespace
-		ps.publicServices = oldPushContext.publicServices
-		ps.ServiceByHostnameAndNamespace = oldPushContext.ServiceByHostnameAndNamespace
-		ps.ServiceByHostname = oldPushContext.ServiceByHostname
-		ps.ServiceAccounts = oldPushContext.ServiceAccounts
+		// The services are copied, so their visibility is known to be unchanged.
+		ps.copyServiceRegistry(oldPushContext)
+		pushReq.ServiceVisibilityDiff = &ServiceVisibilityDiff{}
 	}
 
 	if virtualServicesChanged {
-		if err := ps.initVirtualServices(env); err != nil {
-			return err
-		}
+		steps = append(steps, initStep{
+			phase:    virtualServicesInitPhase,
+			init:     func() error { return ps.initVirtualServices(env) },
+			fallback: ps.copyVirtualServices,
+		})
 	} else {
-		ps.virtualServicesExportedToNamespaceByGateway = oldPushContext.virtualServicesExportedToNamespaceByGateway
-		ps.privateVirtualServicesByNamespaceAndGateway = oldPushContext.privateVirtualServicesByNamespaceAndGateway
-		ps.publicVirtualServicesByGateway = oldPushContext.publicVirtualServicesByGateway
+		ps.copyVirtualServices(oldPushContext)
 	}
 
 	if destinationRulesChanged {
-		if err := ps.initDestinationRules(env); err != nil {
-			return err
-		}
+		steps = append(steps, initStep{
+			phase:    destinationRulesInitPhase,
+			init:     func() error { return ps.initDestinationRules(env) },
+			fallback: ps.copyDestinationRules,
+		})
 	} else {
-		ps.namespaceLocalDestRules = oldPushContext.namespaceLocalDestRules
-		ps.exportedDestRulesByNamespace = oldPushContext.exportedDestRulesByNamespace
-		ps.rootNamespaceLocalDestRules = oldPushContext.rootNamespaceLocalDestRules
+		ps.copyDestinationRules(oldPushContext)
 	}
 
 	if authnChanged {
-		if err := ps.initAuthnPolicies(env); err != nil {
-			return err
-		}
+		steps = append(steps, initStep{
+			phase:    authnInitPhase,
+			init:     func() error { return ps.initAuthnPolicies(env) },
+			fallback: ps.copyAuthnPolicies,
+		})
 	} else {
-		ps.AuthnBetaPolicies = oldPushContext.AuthnBetaPolicies
+		ps.copyAuthnPolicies(oldPushContext)
 	}
 
 	if authzChanged {
-		if err := ps.initAuthorizationPolicies(env); err != nil {
-			authzLog.Errorf("failed to initialize authorization policies: %v", err)
-			return err
-		}
+		steps = append(steps, initStep{
+			phase:    authzInitPhase,
+			init:     func() error { return ps.initAuthorizationPoliciesLogged(env) },
+			fallback: ps.copyAuthorizationPolicies,
+		})
 	} else {
-		ps.AuthzPolicies = oldPushContext.AuthzPolicies
+		ps.copyAuthorizationPolicies(oldPushContext)
 	}
 
 	if envoyFiltersChanged {
-		if err := ps.initEnvoyFilters(env); err != nil {
-			return err
-		}
+		steps = append(steps, initStep{
+			phase:    envoyFiltersInitPhase,
+			init:     func() error { return ps.initEnvoyFilters(env) },
+			fallback: ps.copyEnvoyFilters,
+		})
 	} else {
-		ps.envoyFiltersByNamespace = oldPushContext.envoyFiltersByNamespace
+		ps.copyEnvoyFilters(oldPushContext)
 	}
 
 	if gatewayChanged {
-		if err := ps.initGateways(env); err != nil {
-			return err
-		}
+		steps = append(steps, initStep{
+			phase:    gatewaysInitPhase,
+			init:     func() error { return ps.initGateways(env) },
+			fallback: ps.copyGateways,
+		})
 	} else {
-		ps.gatewaysByNamespace = oldPushContext.gatewaysByNamespace
-		ps.allGateways = oldPushContext.allGateways
+		ps.copyGateways(oldPushContext)
 	}
 
 	// Must be initialized in the end
-	// Sidecars need to be updated if services, virtual services, destination rules, or the sidecar configs change
-	if servicesChanged || virtualServicesChanged || destinationRulesChanged || sidecarsChanged {
-		if err := ps.initSidecarScopes(env); err != nil {
-			return err
-		}
+	// Sidecars need to be updated if services, virtual services, destination rules, or the sidecar configs change.
+	// When only sidecar configs changed, only the scopes of their namespaces are rebuilt.
+	if servicesChanged || virtualServicesChanged || destinationRulesChanged {
+		steps = append(steps, initStep{
+			phase:    sidecarsInitPhase,
+			after:    sidecarsInitDependencies,
+			init:     func() error { return ps.initSidecarScopes(env) },
+			fallback: ps.copySidecarScopes,
+		})
+	} else if sidecarsChanged {
+		steps = append(steps, initStep{
+			phase: sidecarsInitPhase,
+			after: sidecarsInitDependencies,
+			init: func() error {
+				return ps.updateSidecarScopes(env, oldPushContext, changedSidecarNamespaces(pushReq.ConfigsUpdated))
+			},
+			fallback: ps.copySidecarScopes,
+		})
+	} else {
+		ps.copySidecarScopes(oldPushContext)
+	}
+
+	if err := phases.runSteps(steps); err != nil {
+		return err
+	}
+
+	// The mesh networks are only read when the push context is created, the addresses of their gateways only
+	// change with the gateway services.
+	if networkGatewaysChanged {
+		ps.initMeshNetworks()
 	} else {
-		ps.sidecarsByNamespace = oldPushContext.sidecarsByNamespace
+		ps.networkGateways = oldPushContext.networkGateways
 	}
 
 	return nil
 }
 
+func (ps *PushContext) copyServiceRegistry(old *PushContext) {
+	ps.privateServicesByNamespace = old.privateServicesByNamespace
+	ps.servicesExportedToNamespace = old.servicesExportedToNamespace
+	ps.publicServices = old.publicServices
+	ps.ServiceByHostnameAndNamespace = old.ServiceByHostnameAndNamespace
+	ps.ServiceByHostname = old.ServiceByHostname
+	ps.ServiceAccounts = old.ServiceAccounts
+	ps.egressRedirects = old.egressRedirects
+	ps.serviceCardinalityViolations = old.serviceCardinalityViolations
+	for _, v := range ps.serviceCardinalityViolations {
+		ps.AddMetric(ServiceCardinalityLimitExceeded, v.Key(), "", v.String())
+	}
+}
+
+func (ps *PushContext) copyVirtualServices(old *PushContext) {
+	ps.virtualServicesExportedToNamespaceByGateway = old.virtualServicesExportedToNamespaceByGateway
+	ps.privateVirtualServicesByNamespaceAndGateway = old.privateVirtualServicesByNamespaceAndGateway
+	ps.publicVirtualServicesByGateway = old.publicVirtualServicesByGateway
+	ps.virtualServiceHostIndexExportedToNamespaceByGateway = old.virtualServiceHostIndexExportedToNamespaceByGateway
+	ps.privateVirtualServiceHostIndexByNamespaceAndGateway = old.privateVirtualServiceHostIndexByNamespaceAndGateway
+	ps.publicVirtualServiceHostIndexByGateway = old.publicVirtualServiceHostIndexByGateway
+}
+
+func (ps *PushContext) copyDestinationRules(old *PushContext) {
+	ps.namespaceLocalDestRules = old.namespaceLocalDestRules
+	ps.exportedDestRulesByNamespace = old.exportedDestRulesByNamespace
+	ps.rootNamespaceLocalDestRules = old.rootNamespaceLocalDestRules
+}
+
+func (ps *PushContext) copyAuthnPolicies(old *PushContext) {
+	ps.AuthnBetaPolicies = old.AuthnBetaPolicies
+}
+
+func (ps *PushContext) copyAuthorizationPolicies(old *PushContext) {
+	ps.AuthzPolicies = old.AuthzPolicies
+}
+
+func (ps *PushContext) copyEnvoyFilters(old *PushContext) {
+	ps.envoyFiltersByNamespace = old.envoyFiltersByNamespace
+}
+
+func (ps *PushContext) copyGateways(old *PushContext) {
+	ps.gatewaysByNamespace = old.gatewaysByNamespace
+	ps.allGateways = old.allGateways
+}
+
+func (ps *PushContext) copySidecarScopes(old *PushContext) {
+	ps.sidecarsByNamespace = old.sidecarsByNamespace
+}
+
+// ServiceVisibilityKey identifies a service by hostname and namespace.
+type ServiceVisibilityKey struct {
+	Hostname  host.Name
+	Namespace string
+}
+
+// ServiceVisibilityDiff lists the services whose visibility differs between two push contexts.
+type ServiceVisibilityDiff struct {
+	// Added are the services which are only visible in the new push context.
+	Added []ServiceVisibilityKey
+	// Removed are the services which are only visible in the old push context, including deleted services.
+	Removed []ServiceVisibilityKey
+	// Changed are the services which are visible in both push contexts, but to different namespaces.
+	Changed []ServiceVisibilityKey
+}
+
+// IsEmpty returns true if no service visibility changed.
+func (d *ServiceVisibilityDiff) IsEmpty() bool {
+	return d == nil || len(d.Added)+len(d.Removed)+len(d.Changed) == 0
+}
+
+// ServicesVisibilityDiff returns the services whose visibility changed compared to old. It is computed
+// from the public, private and exported services, so it is empty if they were copied from old.
+func (ps *PushContext) ServicesVisibilityDiff(old *PushContext) *ServiceVisibilityDiff {
+	diff := &ServiceVisibilityDiff{}
+	if old == nil {
+		old = NewPushContext()
+	} else if sameServiceIndex(ps, old) {
+		return diff
+	}
+	oldVisibility := old.serviceVisibility()
+	for key, namespaces := range ps.serviceVisibility() {
+		oldNamespaces, f := oldVisibility[key]
+		if !f {
+			diff.Added = append(diff.Added, key)
+		} else if !reflect.DeepEqual(namespaces, oldNamespaces) {
+			diff.Changed = append(diff.Changed, key)
+		}
+		delete(oldVisibility, key)
+	}
+	for key := range oldVisibility {
+		diff.Removed = append(diff.Removed, key)
+	}
+	sortServiceVisibilityKeys(diff.Added)
+	sortServiceVisibilityKeys(diff.Removed)
+	sortServiceVisibilityKeys(diff.Changed)
+	return diff
+}
+
+// Merge returns the services whose visibility changed from the push context before d to the push context of
+// next, next being the diff of a later push context compared to the push context of d. If either diff is
+// unknown, so is the result.
+func (d *ServiceVisibilityDiff) Merge(next *ServiceVisibilityDiff) *ServiceVisibilityDiff {
+	if d == nil || next == nil {
+		return nil
+	}
+	// Whether each service was visible before d, and is visible after next.
+	type visible struct{ before, after bool }
+	services := map[ServiceVisibilityKey]*visible{}
+	for _, key := range d.Added {
+		services[key] = &visible{before: false, after: true}
+	}
+	for _, key := range d.Removed {
+		services[key] = &visible{before: true, after: false}
+	}
+	for _, key := range d.Changed {
+		services[key] = &visible{before: true, after: true}
+	}
+	set := func(keys []ServiceVisibilityKey, before, after bool) {
+		for _, key := range keys {
+			if v, f := services[key]; f {
+				v.after = after
+			} else {
+				services[key] = &visible{before: before, after: after}
+			}
+		}
+	}
+	set(next.Added, false, true)
+	set(next.Removed, true, false)
+	set(next.Changed, true, true)
+
+	// A service added by d and removed by next is not visible in either push context, so it is dropped.
+	merged := &ServiceVisibilityDiff{}
+	for key, v := range services {
+		switch {
+		case v.before && v.after:
+			merged.Changed = append(merged.Changed, key)
+		case v.after:
+			merged.Added = append(merged.Added, key)
+		case v.before:
+			merged.Removed = append(merged.Removed, key)
+		}
+	}
+	sortServiceVisibilityKeys(merged.Added)
+	sortServiceVisibilityKeys(merged.Removed)
+	sortServiceVisibilityKeys(merged.Changed)
+	return merged
+}
+
+// sameServiceIndex returns true if both push contexts share the service visibility maps, which is the
+// case if the services were not recomputed.
+func sameServiceIndex(a, b *PushContext) bool {
+	// publicServices is copied along with the maps, and may not be compared as empty slices share a pointer.
+	return reflect.ValueOf(a.privateServicesByNamespace).Pointer() == reflect.ValueOf(b.privateServicesByNamespace).Pointer() &&
+		reflect.ValueOf(a.servicesExportedToNamespace).Pointer() == reflect.ValueOf(b.servicesExportedToNamespace).Pointer()
+}
+
+// serviceVisibility returns the namespaces each visible service is visible to, with "*" for public services.
+func (ps *PushContext) serviceVisibility() map[ServiceVisibilityKey]map[string]struct{} {
+	out := map[ServiceVisibilityKey]map[string]struct{}{}
+	add := func(s *Service, namespace string) {
+		key := ServiceVisibilityKey{Hostname: s.Hostname, Namespace: s.Attributes.Namespace}
+		if out[key] == nil {
+			out[key] = map[string]struct{}{}
+		}
+		out[key][namespace] = struct{}{}
+	}
+	for _, s := range ps.publicServices {
+		add(s, string(visibility.Public))
+	}
+	for ns, services := range ps.privateServicesByNamespace {
+		for _, s := range services {
+			add(s, ns)
+		}
+	}
+	for ns, services := range ps.servicesExportedToNamespace {
+		for _, s := range services {
+			add(s, ns)
+		}
+	}
+	return out
+}
+
+func sortServiceVisibilityKeys(keys []ServiceVisibilityKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Hostname != keys[j].Hostname {
+			return keys[i].Hostname < keys[j].Hostname
+		}
+		return keys[i].Namespace < keys[j].Namespace
+	})
+}
+
 // Caches list of services in the registry, and creates a map
-// of hostname to service
-func (ps *PushContext) initServiceRegistry(env *Environment) error {
+// of hostname to service. If oldPushContext is not nil, the service accounts
+// of hostnames not in changedHosts are copied from it.
+func (ps *PushContext) initServiceRegistry(env *Environment, oldPushContext *PushContext, changedHosts map[host.Name]struct{}) error {
 	services, err := env.Services()
 	if err != nil {
 		return err
 	}
 	// Sort the services in order of creation.
 	allServices := sortServicesByCreationTime(services)
+	collisions := resolveServiceEntryHostCollisions(env, allServices)
+	allServices = ps.limitServicesPerNamespace(collisions.services)
 	for _, s := range allServices {
 		ns := s.Attributes.Namespace
 		if len(s.Attributes.ExportTo) == 0 {
@@ -1036,12 +1920,49 @@ func (ps *PushContext) initServiceRegistry(env *Environment) error {
 		ps.ServiceByHostnameAndNamespace[s.Hostname][s.Attributes.Namespace] = s
 		ps.ServiceByHostname[s.Hostname] = s
 	}
+	// The Kubernetes services replace the ServiceEntry services they are preferred to, but not the ServiceEntry
+	// services of the other namespaces.
+	for c, s := range collisions.preferred {
+		if isCollidingServiceEntry(ps.ServiceByHostname[c.host], c) {
+			ps.ServiceByHostname[c.host] = s
+		}
+		if isCollidingServiceEntry(ps.ServiceByHostnameAndNamespace[c.host][s.Attributes.Namespace], c) {
+			ps.ServiceByHostnameAndNamespace[c.host][s.Attributes.Namespace] = s
+		}
+	}
+	ps.egressRedirects = newEgressRedirects(env.MeshExtensions())
 
-	ps.initServiceAccounts(env, allServices)
+	if oldPushContext != nil {
+		ps.updateServiceAccounts(env, allServices, oldPushContext, changedHosts)
+	} else {
+		ps.initServiceAccounts(env, allServices)
+	}
 
 	return nil
 }
 
+// changedServiceHostnames returns the hostnames of the services updated by a push.
+func changedServiceHostnames(configsUpdated map[ConfigKey]struct{}) map[host.Name]struct{} {
+	hosts := map[host.Name]struct{}{}
+	for key := range configsUpdated {
+		if key.Kind == gvk.ServiceEntry {
+			hosts[host.Name(key.Name)] = struct{}{}
+		}
+	}
+	return hosts
+}
+
+// changedSidecarNamespaces returns the namespaces of the Sidecar configs in configsUpdated.
+func changedSidecarNamespaces(configsUpdated map[ConfigKey]struct{}) sets.Set {
+	namespaces := sets.NewSet()
+	for key := range configsUpdated {
+		if key.Kind == gvk.Sidecar {
+			namespaces.Insert(key.Namespace)
+		}
+	}
+	return namespaces
+}
+
 // sortServicesByCreationTime sorts the list of services in ascending order by their creation time (if available).
 func sortServicesByCreationTime(services []*Service) []*Service {
 	sort.SliceStable(services, func(i, j int) bool {
@@ -1065,6 +1986,24 @@ func (ps *PushContext) initServiceAccounts(env *Environment, services []*Service
 	}
 }
 
+// updateServiceAccounts caches the list of service accounts in the registry, only computing them for
+// the changed hostnames, and copying the others from the old push context. The copied maps are shared
+// and must not be modified.
+func (ps *PushContext) updateServiceAccounts(env *Environment, services []*Service,
+	oldPushContext *PushContext, changedHosts map[host.Name]struct{}) {
+	recompute := make([]*Service, 0, len(changedHosts))
+	for _, svc := range services {
+		if _, f := changedHosts[svc.Hostname]; !f {
+			if accounts, f := oldPushContext.ServiceAccounts[svc.Hostname]; f {
+				ps.ServiceAccounts[svc.Hostname] = accounts
+				continue
+			}
+		}
+		recompute = append(recompute, svc)
+	}
+	ps.initServiceAccounts(env, recompute)
+}
+
 // Caches list of authentication policies
 func (ps *PushContext) initAuthnPolicies(env *Environment) error {
 	// Init beta policy.
@@ -1097,6 +2036,12 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 
 	totalVirtualServices.Record(float64(len(virtualServices)))
 
+	gateways, err := env.List(gvk.Gateway, NamespaceAll)
+	if err != nil {
+		return err
+	}
+	gatewayExportTo := gatewayExportToByName(gateways)
+
 	// TODO(rshriram): parse each virtual service and maintain a map of the
 	// virtualservice name, the list of registry hosts in the VS and non
 	// registry DNS names in the VS.  This should cut down processing in
@@ -1113,7 +2058,7 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 	for _, virtualService := range vservices {
 		ns := virtualService.Namespace
 		rule := virtualService.Spec.(*networking.VirtualService)
-		gwNames := getGatewayNames(rule, virtualService.Meta)
+		gwNames := ps.bindableGatewayNames(virtualService, getGatewayNames(rule, virtualService.Meta), gatewayExportTo)
 		if len(rule.ExportTo) == 0 {
 			// No exportTo in virtualService. Use the global default
 			// We only honor ., *
@@ -1172,9 +2117,38 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 		}
 	}
 
+	ps.initVirtualServiceHostIndexes()
+
 	return nil
 }
 
+// initVirtualServiceHostIndexes builds the host indexes of the virtual services bound to gateways.
+// The mesh gateway is skipped, as sidecars do not look up virtual services by gateway.
+func (ps *PushContext) initVirtualServiceHostIndexes() {
+	ps.virtualServiceHostIndexExportedToNamespaceByGateway = map[string]map[string]virtualServiceHostIndex{}
+	ps.privateVirtualServiceHostIndexByNamespaceAndGateway = map[string]map[string]virtualServiceHostIndex{}
+	ps.publicVirtualServiceHostIndexByGateway = map[string]virtualServiceHostIndex{}
+	if !features.ScopeGatewayVirtualServicesByHost {
+		return
+	}
+	indexByGateway := func(vsesByGateway map[string][]config.Config) map[string]virtualServiceHostIndex {
+		out := map[string]virtualServiceHostIndex{}
+		for gw, vses := range vsesByGateway {
+			if gw != constants.IstioMeshGateway {
+				out[gw] = newVirtualServiceHostIndex(vses)
+			}
+		}
+		return out
+	}
+	for ns, byGateway := range ps.virtualServicesExportedToNamespaceByGateway {
+		ps.virtualServiceHostIndexExportedToNamespaceByGateway[ns] = indexByGateway(byGateway)
+	}
+	for ns, byGateway := range ps.privateVirtualServicesByNamespaceAndGateway {
+		ps.privateVirtualServiceHostIndexByNamespaceAndGateway[ns] = indexByGateway(byGateway)
+	}
+	ps.publicVirtualServiceHostIndexByGateway = indexByGateway(ps.publicVirtualServicesByGateway)
+}
+
 var meshGateways = []string{constants.IstioMeshGateway}
 
 func getGatewayNames(vs *networking.VirtualService, meta config.Meta) []string {
@@ -1193,6 +2167,55 @@ func getGatewayNames(vs *networking.VirtualService, meta config.Meta) []string {
 	return res
 }
 
+// gatewayExportToByName returns the namespaces which may bind to each gateway restricted by the exportTo
+// annotation, keyed by namespace/name. Gateways which are not restricted are omitted.
+func gatewayExportToByName(gateways []config.Config) map[string]map[visibility.Instance]bool {
+	out := map[string]map[visibility.Instance]bool{}
+	for _, gw := range gateways {
+		exportTo := gateway.ExportTo(gw.Annotations)
+		if exportTo == nil {
+			continue
+		}
+		exportToMap := make(map[visibility.Instance]bool, len(exportTo))
+		for _, e := range exportTo {
+			if visibility.Instance(e) == visibility.Private {
+				exportToMap[visibility.Instance(gw.Namespace)] = true
+			} else {
+				exportToMap[visibility.Instance(e)] = true
+			}
+		}
+		out[gw.Namespace+"/"+gw.Name] = exportToMap
+	}
+	return out
+}
+
+// bindableGatewayNames filters gwNames down to the gateways the virtual service is allowed to bind to.
+// Binding to the mesh gateway is always allowed.
+func (ps *PushContext) bindableGatewayNames(vs config.Config, gwNames []string,
+	gatewayExportTo map[string]map[visibility.Instance]bool) []string {
+	if len(gatewayExportTo) == 0 {
+		return gwNames
+	}
+	var out, denied []string
+	for _, gw := range gwNames {
+		exportTo, restricted := gatewayExportTo[gw]
+		if !restricted || gw == constants.IstioMeshGateway ||
+			exportTo[visibility.Public] || exportTo[visibility.Instance(vs.Namespace)] {
+			out = append(out, gw)
+		} else {
+			denied = append(denied, gw)
+		}
+	}
+	if len(denied) > 0 {
+		key := vs.Namespace + "/" + vs.Name
+		log.Warnf("virtual service %s is not bound to gateways %v, which are not exported to namespace %s",
+			key, denied, vs.Namespace)
+		ps.AddMetric(VirtualServiceGatewayBindingDenied, key, "",
+			fmt.Sprintf("gateways %v are not exported to namespace %s", denied, vs.Namespace))
+	}
+	return out
+}
+
 func (ps *PushContext) initDefaultExportMaps() {
 	ps.defaultDestinationRuleExportTo = make(map[visibility.Instance]bool)
 	if ps.Mesh.DefaultDestinationRuleExportTo != nil {
@@ -1241,6 +2264,70 @@ func (ps *PushContext) initSidecarScopes(env *Environment) error {
 		return err
 	}
 
+	ps.sidecarsByNamespace = ps.buildSidecarScopes(sidecarConfigs, ps.serviceNamespaces())
+	return nil
+}
+
+// updateSidecarScopes rebuilds the sidecar scopes of the given namespaces only, reusing the scopes of the
+// previous push context for the other namespaces. This is only valid when nothing but the Sidecar configs
+// in these namespaces changed; a change in the root namespace affects the default scope of every namespace,
+// and requires a full rebuild.
+func (ps *PushContext) updateSidecarScopes(env *Environment, oldPushContext *PushContext, namespaces sets.Set) error {
+	if ps.Mesh.RootNamespace != "" && namespaces.Contains(ps.Mesh.RootNamespace) {
+		return ps.initSidecarScopes(env)
+	}
+
+	// The root namespace's sidecar config is listed so that the default scopes are derived from it,
+	// but only the scopes of the changed namespaces are kept.
+	listNamespaces := namespaces.UnsortedList()
+	if ps.Mesh.RootNamespace != "" {
+		listNamespaces = append(listNamespaces, ps.Mesh.RootNamespace)
+	}
+	var sidecarConfigs []config.Config
+	for _, ns := range listNamespaces {
+		configs, err := env.List(gvk.Sidecar, ns)
+		if err != nil {
+			return err
+		}
+		sidecarConfigs = append(sidecarConfigs, configs...)
+	}
+
+	defaultNamespaces := sets.NewSet()
+	for ns := range ps.serviceNamespaces() {
+		if namespaces.Contains(ns) {
+			defaultNamespaces.Insert(ns)
+		}
+	}
+	scopes := ps.buildSidecarScopes(sidecarConfigs, defaultNamespaces)
+
+	ps.sidecarsByNamespace = make(map[string][]*SidecarScope, len(oldPushContext.sidecarsByNamespace))
+	for ns, sidecars := range oldPushContext.sidecarsByNamespace {
+		if !namespaces.Contains(ns) {
+			ps.sidecarsByNamespace[ns] = sidecars
+		}
+	}
+	for ns := range namespaces {
+		if sidecars, f := scopes[ns]; f {
+			ps.sidecarsByNamespace[ns] = sidecars
+		}
+	}
+	return nil
+}
+
+// serviceNamespaces returns the namespaces with at least one service.
+func (ps *PushContext) serviceNamespaces() sets.Set {
+	namespaces := sets.NewSet()
+	for _, nsMap := range ps.ServiceByHostnameAndNamespace {
+		for ns := range nsMap {
+			namespaces.Insert(ns)
+		}
+	}
+	return namespaces
+}
+
+// buildSidecarScopes converts the sidecar configs into sidecar scopes by namespace, and adds a default scope
+// to each of the given namespaces that does not have a non-workloadSelector sidecar config.
+func (ps *PushContext) buildSidecarScopes(sidecarConfigs []config.Config, namespaces sets.Set) map[string][]*SidecarScope {
 	sortConfigByCreationTime(sidecarConfigs)
 
 	sidecarConfigWithSelector := make([]config.Config, 0)
@@ -1261,10 +2348,10 @@ func (ps *PushContext) initSidecarScopes(env *Environment) error {
 	sidecarConfigs = append(sidecarConfigs, sidecarConfigWithSelector...)
 	sidecarConfigs = append(sidecarConfigs, sidecarConfigWithoutSelector...)
 
-	ps.sidecarsByNamespace = make(map[string][]*SidecarScope, sidecarNum)
+	sidecarsByNamespace := make(map[string][]*SidecarScope, sidecarNum)
 	for _, sidecarConfig := range sidecarConfigs {
 		sidecarConfig := sidecarConfig
-		ps.sidecarsByNamespace[sidecarConfig.Namespace] = append(ps.sidecarsByNamespace[sidecarConfig.Namespace],
+		sidecarsByNamespace[sidecarConfig.Namespace] = append(sidecarsByNamespace[sidecarConfig.Namespace],
 			ConvertToSidecarScope(ps, &sidecarConfig, sidecarConfig.Namespace))
 	}
 
@@ -1285,19 +2372,13 @@ func (ps *PushContext) initSidecarScopes(env *Environment) error {
 	// build sidecar scopes for namespaces that do not have a non-workloadSelector sidecar CRD object.
 	// Derive the sidecar scope from the root namespace's sidecar object if present. Else fallback
 	// to the default Istio behavior mimicked by the DefaultSidecarScopeForNamespace function.
-	namespaces := sets.NewSet()
-	for _, nsMap := range ps.ServiceByHostnameAndNamespace {
-		for ns := range nsMap {
-			namespaces.Insert(ns)
-		}
-	}
 	for ns := range namespaces {
 		if _, exist := sidecarsWithoutSelectorByNamespace[ns]; !exist {
-			ps.sidecarsByNamespace[ns] = append(ps.sidecarsByNamespace[ns], ConvertToSidecarScope(ps, rootNSConfig, ns))
+			sidecarsByNamespace[ns] = append(sidecarsByNamespace[ns], ConvertToSidecarScope(ps, rootNSConfig, ns))
 		}
 	}
 
-	return nil
+	return sidecarsByNamespace
 }
 
 // Split out of DestinationRule expensive conversions - once per push.
@@ -1323,6 +2404,7 @@ func newProcessedDestRules() *processedDestRules {
 		hosts:    make([]host.Name, 0),
 		exportTo: map[host.Name]map[visibility.Instance]bool{},
 		destRule: map[host.Name]*config.Config{},
+		merges:   map[host.Name]*DestinationRuleMerge{},
 	}
 }
 
@@ -1394,6 +2476,72 @@ func (ps *PushContext) SetDestinationRules(configs []config.Config) {
 	ps.namespaceLocalDestRules = namespaceLocalDestRules
 	ps.exportedDestRulesByNamespace = exportedDestRulesByNamespace
 	ps.rootNamespaceLocalDestRules = rootNamespaceLocalDestRules
+	recordDestinationRuleConflicts(namespaceLocalDestRules, exportedDestRulesByNamespace,
+		map[string]*processedDestRules{ps.Mesh.GetRootNamespace(): rootNamespaceLocalDestRules})
+}
+
+// DestinationRuleMerges returns how the destination rules for the host were merged, in each namespace and scope.
+func (ps *PushContext) DestinationRuleMerges(hostname host.Name) []DestinationRuleMerge {
+	out := make([]DestinationRuleMerge, 0)
+	add := func(scope string, p *processedDestRules) {
+		if p == nil {
+			return
+		}
+		if m, f := p.merges[hostname]; f {
+			merge := *m
+			merge.Scope = scope
+			out = append(out, merge)
+		}
+	}
+	for _, ns := range sortedDestRuleNamespaces(ps.namespaceLocalDestRules) {
+		add("local", ps.namespaceLocalDestRules[ns])
+	}
+	for _, ns := range sortedDestRuleNamespaces(ps.exportedDestRulesByNamespace) {
+		add("exported", ps.exportedDestRulesByNamespace[ns])
+	}
+	add("root", ps.rootNamespaceLocalDestRules)
+	return out
+}
+
+func sortedDestRuleNamespaces(m map[string]*processedDestRules) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recordDestinationRuleConflicts records the number of conflicts by type. A rule merged in several scopes of its
+// namespace is counted once.
+func recordDestinationRuleConflicts(processed ...map[string]*processedDestRules) {
+	type conflictKey struct {
+		conflictType, namespace, config, message string
+		host                                     host.Name
+	}
+	seen := map[conflictKey]struct{}{}
+	counts := map[string]int{
+		DestinationRuleConflictTLSMode:  0,
+		DestinationRuleConflictLBPolicy: 0,
+		DestinationRuleConflictOutlier:  0,
+		DestinationRuleConflictSubset:   0,
+	}
+	for _, byNamespace := range processed {
+		for _, p := range byNamespace {
+			for _, m := range p.merges {
+				for _, c := range m.Conflicts {
+					key := conflictKey{c.Type, m.Namespace, c.Config, c.Message, m.Host}
+					if _, f := seen[key]; !f {
+						seen[key] = struct{}{}
+						counts[c.Type]++
+					}
+				}
+			}
+		}
+	}
+	for conflictType, n := range counts {
+		DestinationRuleConflicts.With(destRuleConflictTypeTag.Value(conflictType)).Record(float64(n))
+	}
 }
 
 func (ps *PushContext) initAuthorizationPolicies(env *Environment) error {
@@ -1441,8 +2589,8 @@ func (ps *PushContext) EnvoyFilters(proxy *Proxy) *EnvoyFilterWrapper {
 		for _, efw := range ps.envoyFiltersByNamespace[ps.Mesh.RootNamespace] {
 			var workloadLabels labels.Collection
 			// This should never happen except in tests.
-			if proxy.Metadata != nil && len(proxy.Metadata.Labels) > 0 {
-				workloadLabels = labels.Collection{proxy.Metadata.Labels}
+			if l := proxy.WorkloadLabels(); len(l) > 0 {
+				workloadLabels = labels.Collection{l}
 			}
 			if efw.workloadSelector == nil || workloadLabels.IsSupersetOf(efw.workloadSelector) {
 				matchedEnvoyFilters = append(matchedEnvoyFilters, efw)
@@ -1455,8 +2603,8 @@ func (ps *PushContext) EnvoyFilters(proxy *Proxy) *EnvoyFilterWrapper {
 		for _, efw := range ps.envoyFiltersByNamespace[proxy.ConfigNamespace] {
 			var workloadLabels labels.Collection
 			// This should never happen except in tests.
-			if proxy.Metadata != nil && len(proxy.Metadata.Labels) > 0 {
-				workloadLabels = labels.Collection{proxy.Metadata.Labels}
+			if l := proxy.WorkloadLabels(); len(l) > 0 {
+				workloadLabels = labels.Collection{l}
 			}
 			if efw.workloadSelector == nil || workloadLabels.IsSupersetOf(efw.workloadSelector) {
 				matchedEnvoyFilters = append(matchedEnvoyFilters, efw)
@@ -1531,8 +2679,8 @@ func (ps *PushContext) mergeGateways(proxy *Proxy) *MergedGateway {
 			gatewaySelector := labels.Instance(gw.GetSelector())
 			var workloadLabels labels.Collection
 			// This should never happen except in tests.
-			if proxy.Metadata != nil && len(proxy.Metadata.Labels) > 0 {
-				workloadLabels = labels.Collection{proxy.Metadata.Labels}
+			if l := proxy.WorkloadLabels(); len(l) > 0 {
+				workloadLabels = labels.Collection{l}
 			}
 			if workloadLabels.IsSupersetOf(gatewaySelector) {
 				out = append(out, cfg)
@@ -1552,6 +2700,7 @@ func (ps *PushContext) initMeshNetworks() {
 		return
 	}
 
+	networkGatewaysRecomputations.Increment()
 	ps.networkGateways = map[string][]*Gateway{}
 	for network, networkConf := range ps.Networks.Networks {
 		gws := networkConf.Gateways
@@ -1574,6 +2723,28 @@ func (ps *PushContext) initMeshNetworks() {
 	}
 }
 
+// clusterLocalHosts holds the hosts marked cluster-local or not by the mesh config serviceSettings, and the
+// hosts that are cluster-local by default.
+type clusterLocalHosts struct {
+	// rules are the hosts of the serviceSettings, sorted from the most to the least specific.
+	rules host.Names
+	// local is whether the hosts of rules are cluster-local. If a host is in several serviceSettings,
+	// the first one wins.
+	local map[host.Name]bool
+	// defaults are the hosts that are cluster-local unless a rule matches them.
+	defaults host.Names
+}
+
+// isClusterLocal returns whether the most specific rule matching h is cluster-local, falling back to the defaults
+// if no rule matches.
+func (c clusterLocalHosts) isClusterLocal(h host.Name) bool {
+	if rule, ok := MostSpecificHostMatch(h, c.rules); ok {
+		return c.local[rule]
+	}
+	_, ok := MostSpecificHostMatch(h, c.defaults)
+	return ok
+}
+
 func (ps *PushContext) initClusterLocalHosts(e *Environment) {
 	// Create the default list of cluster-local hosts.
 	domainSuffix := e.GetDomainSuffix()
@@ -1591,35 +2762,58 @@ func (ps *PushContext) initClusterLocalHosts(e *Environment) {
 		defaultClusterLocalHosts = append(defaultClusterLocalHosts, discoveryHost)
 	}
 
-	// Collect the cluster-local hosts.
-	clusterLocalHosts := make([]host.Name, 0)
+	// Collect the cluster-local and non-cluster-local hosts. They take precedence over the defaults, so that
+	// a default can be overridden, or only a host within it excluded.
+	rules := make([]host.Name, 0)
+	local := make(map[host.Name]bool)
 	for _, serviceSettings := range ps.Mesh.ServiceSettings {
-		if serviceSettings.Settings.ClusterLocal {
-			for _, h := range serviceSettings.Hosts {
-				clusterLocalHosts = append(clusterLocalHosts, host.Name(h))
-			}
-		} else {
-			// Remove defaults if specified to be non-cluster-local.
-			for _, h := range serviceSettings.Hosts {
-				for i, defaultClusterLocalHost := range defaultClusterLocalHosts {
-					if len(defaultClusterLocalHost) > 0 && strings.HasSuffix(h, string(defaultClusterLocalHost[1:])) {
-						// This default was explicitly overridden, so remove it.
-						defaultClusterLocalHosts[i] = ""
-					}
-				}
+		for _, h := range serviceSettings.Hosts {
+			if _, f := local[host.Name(h)]; f {
+				continue
 			}
+			rules = append(rules, host.Name(h))
+			local[host.Name(h)] = serviceSettings.Settings.GetClusterLocal()
 		}
 	}
 
-	// Add any remaining defaults to the end of the list.
-	for _, defaultClusterLocalHost := range defaultClusterLocalHosts {
-		if len(defaultClusterLocalHost) > 0 {
-			clusterLocalHosts = append(clusterLocalHosts, defaultClusterLocalHost)
-		}
+	sort.Sort(host.Names(rules))
+	sort.Sort(host.Names(defaultClusterLocalHosts))
+	ps.clusterLocalHosts = clusterLocalHosts{
+		rules:    rules,
+		local:    local,
+		defaults: defaultClusterLocalHosts,
 	}
+}
 
-	sort.Sort(host.Names(clusterLocalHosts))
-	ps.clusterLocalHosts = clusterLocalHosts
+func (ps *PushContext) initStaleClusters(e *Environment) {
+	if !features.AnnotateStaleEndpoints {
+		return
+	}
+	reporter, ok := e.ServiceDiscovery.(StaleRegistryReporter)
+	if !ok {
+		return
+	}
+	clusters := reporter.StaleClusters()
+	if len(clusters) == 0 {
+		return
+	}
+	ps.staleClusters = make(map[string]struct{}, len(clusters))
+	for _, c := range clusters {
+		ps.staleClusters[c] = struct{}{}
+	}
+}
+
+// IsNetworkGatewayService returns whether the service of the hostname is the registryServiceName of a gateway of
+// the mesh networks.
+func IsNetworkGatewayService(networks *meshconfig.MeshNetworks, hostname host.Name) bool {
+	for _, network := range networks.GetNetworks() {
+		for _, gw := range network.GetGateways() {
+			if gw.GetRegistryServiceName() == string(hostname) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func getNetworkRegistries(network *meshconfig.Network) []string {
@@ -1687,9 +2881,10 @@ func (ps *PushContext) NetworkGatewaysByNetwork(network string) []*Gateway {
 
 // BestEffortInferServiceMTLSMode infers the mTLS mode for the service + port from all authentication
 // policies (both alpha and beta) in the system. The function always returns MTLSUnknown for external service.
-// The resulst is a best effort. It is because the PeerAuthentication is workload-based, this function is unable
-// to compute the correct service mTLS mode without knowing service to workload binding. For now, this
-// function uses only mesh and namespace level PeerAuthentication and ignore workload & port level policies.
+// The result is a best effort. It is because the PeerAuthentication is workload-based, this function is unable
+// to compute the correct service mTLS mode without knowing service to workload binding. Workload and port level
+// PeerAuthentication are approximated by comparing the policy selector with the service selector. If the
+// service workloads may have different modes, MTLSPermissive is returned.
 // This function is used to give a hint for auto-mTLS configuration on client side.
 func (ps *PushContext) BestEffortInferServiceMTLSMode(service *Service, port *Port) MutualTLSMode {
 	if service.MeshExternal {
@@ -1697,12 +2892,99 @@ func (ps *PushContext) BestEffortInferServiceMTLSMode(service *Service, port *Po
 		return MTLSUnknown
 	}
 
-	// First , check mTLS settings from beta policy (i.e PeerAuthentication) at namespace / mesh level.
-	// If the mode is not unknown, use it.
-	if serviceMTLSMode := ps.AuthnBetaPolicies.GetNamespaceMutualTLSMode(service.Attributes.Namespace); serviceMTLSMode != MTLSUnknown {
-		return serviceMTLSMode
+	// First, check mTLS settings from beta policy (i.e PeerAuthentication) at namespace / mesh level.
+	namespaceMTLSMode := ps.AuthnBetaPolicies.GetNamespaceMutualTLSMode(service.Attributes.Namespace)
+	inheritedMTLSMode := namespaceMTLSMode
+	if inheritedMTLSMode == MTLSUnknown {
+		inheritedMTLSMode = MTLSPermissive
+	}
+
+	// Then, check workload level PeerAuthentication selecting the service workloads, which take precedence.
+	// Their port level settings refer to the workload ports, which are only resolved when needed.
+	var targetPorts []uint32
+	resolveTargetPorts := func() []uint32 {
+		if targetPorts == nil {
+			targetPorts = ps.serviceTargetPorts(service, port)
+		}
+		return targetPorts
+	}
+	workloadMTLSMode, ambiguous := ps.AuthnBetaPolicies.GetServiceMutualTLSMode(service.Attributes.Namespace,
+		service.Attributes.LabelSelectors, resolveTargetPorts, inheritedMTLSMode)
+	if ambiguous {
+		ps.AddMetric(AmbiguousServiceMTLSMode, string(service.Hostname), "",
+			fmt.Sprintf("workloads of service %s port %d have different mTLS modes", service.Hostname, port.Port))
+		return MTLSPermissive
+	}
+	if workloadMTLSMode != MTLSUnknown {
+		return workloadMTLSMode
+	}
+	if namespaceMTLSMode != MTLSUnknown {
+		return namespaceMTLSMode
 	}
 
 	// When all are failed, default to permissive.
 	return MTLSPermissive
 }
+
+// serviceTargetPorts returns the distinct workload ports targeted by the service port, as found in the endpoints
+// of the service. The service port is returned if the service has no endpoints.
+func (ps *PushContext) serviceTargetPorts(service *Service, port *Port) []uint32 {
+	var ports []uint32
+	seen := map[uint32]struct{}{}
+	if ps.ServiceDiscovery != nil {
+		for _, instance := range ps.ServiceDiscovery.InstancesByPort(service, port.Port, nil) {
+			if _, f := seen[instance.Endpoint.EndpointPort]; !f {
+				seen[instance.Endpoint.EndpointPort] = struct{}{}
+				ports = append(ports, instance.Endpoint.EndpointPort)
+			}
+		}
+	}
+	if len(ports) == 0 {
+		return []uint32{uint32(port.Port)}
+	}
+	return ports
+}
+
+// EffectiveProxyConfig returns the proxy config of the mesh with the ProxyConfigOverrides of the namespace and
+// workload of the proxy applied. If the overrides are invalid for the proxy, the default of the mesh is returned.
+func (ps *PushContext) EffectiveProxyConfig(proxy *Proxy) *meshconfig.ProxyConfig {
+	var def *meshconfig.ProxyConfig
+	if ps.Mesh != nil {
+		def = ps.Mesh.DefaultConfig
+	}
+	pc, err := ps.ProxyConfigOverrides.EffectiveProxyConfig(def, proxy.ConfigNamespace, proxy.WorkloadLabels())
+	if err != nil {
+		ps.AddMetric(ProxyStatusInvalidProxyConfig, proxy.ID, proxy.ID, err.Error())
+	}
+	return pc
+}
+
+// GatewayTopology returns the topology of the network in front of the gateway proxy, from its EffectiveProxyConfig,
+// which is applied with a push when the mesh config or the ProxyConfigOverrides change.
+// The proxy config of the proxy metadata, read when the proxy connects, is the default proxy config of the mesh at
+// that time merged with the proxy.istio.io/config annotation of the pod. Its topology fields only take precedence if
+// the agent reports that the annotation sets the topology, so that the topology of the mesh config, which every proxy
+// carries in its metadata, neither hides the ProxyConfigOverrides nor later changes of the mesh config. Agents older
+// than 1.8 do not report it, and their metadata always takes precedence.
+func (ps *PushContext) GatewayTopology(proxy *Proxy) *meshconfig.Topology {
+	out := &meshconfig.Topology{}
+	if pc := ps.EffectiveProxyConfig(proxy); pc != nil && pc.GatewayTopology != nil {
+		out.NumTrustedProxies = pc.GatewayTopology.NumTrustedProxies
+		out.ForwardClientCertDetails = pc.GatewayTopology.ForwardClientCertDetails
+	}
+	if proxy.Metadata == nil || proxy.Metadata.ProxyConfig == nil || proxy.Metadata.ProxyConfig.GatewayTopology == nil {
+		return out
+	}
+	legacy := proxy.IstioVersion != nil && proxy.IstioVersion.Compare(&IstioVersion{Major: 1, Minor: 8, Patch: -1}) < 0
+	if !legacy && !bool(proxy.Metadata.GatewayTopologyAnnotated) {
+		return out
+	}
+	topology := proxy.Metadata.ProxyConfig.GatewayTopology
+	if topology.NumTrustedProxies > 0 {
+		out.NumTrustedProxies = topology.NumTrustedProxies
+	}
+	if topology.ForwardClientCertDetails != meshconfig.Topology_UNDEFINED {
+		out.ForwardClientCertDetails = topology.ForwardClientCertDetails
+	}
+	return out
+}
@@ -15,9 +15,14 @@
 package model
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -27,14 +32,19 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	securityBeta "istio.io/api/security/v1beta1"
 	selectorpb "istio.io/api/type/v1beta1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/gateway"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
+	"istio.io/istio/pkg/test"
 )
 
 func TestMergeUpdateRequest(t *testing.T) {
@@ -98,6 +108,44 @@ func TestMergeUpdateRequest(t *testing.T) {
 				Kind: config.GroupVersionKind{Kind: "cfg2"}}: {}}},
 			PushRequest{Full: true, ConfigsUpdated: nil},
 		},
+		{
+			"merge mesh config impact",
+			&PushRequest{Full: true, MeshConfigImpact: MeshImpactListeners},
+			&PushRequest{Full: true, MeshConfigImpact: MeshImpactClusters},
+			PushRequest{Full: true, MeshConfigImpact: MeshImpactListeners | MeshImpactClusters},
+		},
+		{
+			"skip mesh config impact merge: one unrestricted",
+			&PushRequest{Full: true, MeshConfigImpact: MeshImpactListeners},
+			&PushRequest{Full: true},
+			PushRequest{Full: true},
+		},
+		{
+			"merge service visibility diffs",
+			&PushRequest{Full: true, ServiceVisibilityDiff: &ServiceVisibilityDiff{
+				Added:   []ServiceVisibilityKey{{Hostname: "a", Namespace: "ns"}, {Hostname: "b", Namespace: "ns"}},
+				Removed: []ServiceVisibilityKey{{Hostname: "c", Namespace: "ns"}},
+				Changed: []ServiceVisibilityKey{{Hostname: "d", Namespace: "ns"}},
+			}},
+			&PushRequest{Full: true, ServiceVisibilityDiff: &ServiceVisibilityDiff{
+				Removed: []ServiceVisibilityKey{{Hostname: "b", Namespace: "ns"}, {Hostname: "d", Namespace: "ns"}},
+				Added:   []ServiceVisibilityKey{{Hostname: "c", Namespace: "ns"}},
+				Changed: []ServiceVisibilityKey{{Hostname: "e", Namespace: "ns"}},
+			}},
+			PushRequest{Full: true, ServiceVisibilityDiff: &ServiceVisibilityDiff{
+				Added:   []ServiceVisibilityKey{{Hostname: "a", Namespace: "ns"}},
+				Removed: []ServiceVisibilityKey{{Hostname: "d", Namespace: "ns"}},
+				Changed: []ServiceVisibilityKey{{Hostname: "c", Namespace: "ns"}, {Hostname: "e", Namespace: "ns"}},
+			}},
+		},
+		{
+			"skip service visibility diff merge: one unknown",
+			&PushRequest{Full: true, ServiceVisibilityDiff: &ServiceVisibilityDiff{
+				Added: []ServiceVisibilityKey{{Hostname: "a", Namespace: "ns"}},
+			}},
+			&PushRequest{Full: true},
+			PushRequest{Full: true},
+		},
 	}
 
 	for _, tt := range cases {
@@ -110,6 +158,144 @@ func TestMergeUpdateRequest(t *testing.T) {
 	}
 }
 
+func TestPushRequestReasonLabel(t *testing.T) {
+	cases := []struct {
+		name     string
+		requests []*PushRequest
+		want     TriggerReason
+	}{
+		{"nil", []*PushRequest{nil}, UnknownTrigger},
+		{"no reason", []*PushRequest{{Full: true}}, UnknownTrigger},
+		{"single reason", []*PushRequest{{Reason: []TriggerReason{EndpointUpdate}}}, EndpointUpdate},
+		{
+			"merged with the same reason",
+			[]*PushRequest{{Reason: []TriggerReason{ConfigUpdate}}, {Full: true, Reason: []TriggerReason{ConfigUpdate}}},
+			ConfigUpdate,
+		},
+		{
+			"merged with mixed reasons",
+			[]*PushRequest{{Reason: []TriggerReason{EndpointUpdate}}, {Full: true, Reason: []TriggerReason{ConfigUpdate}}},
+			MultipleTriggers,
+		},
+		{"not known reason", []*PushRequest{{Reason: []TriggerReason{"custom"}}}, UnknownTrigger},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *PushRequest
+			for _, r := range tt.requests {
+				req = req.Merge(r)
+			}
+			if got := req.ReasonLabel(); got != tt.want {
+				t.Errorf("got reason label %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusJSONFilter(t *testing.T) {
+	push := NewPushContext()
+	for _, id := range []string{"a-1.ns-a", "a-2.ns-a", "a-3.ns-a", "b-1.ns-b"} {
+		push.AddMetric(ProxyStatusNoService, id, id, "")
+	}
+	push.AddMetric(ProxyStatusConflictInboundListener, "b-1.ns-b", "b-1.ns-b", "Conflicting inbound listener on b-1.ns-b:80")
+	push.AddMetric(DuplicatedSubsets, "reviews.default.svc.cluster.local", "", "duplicate subset")
+	noService, inbound, subsets := ProxyStatusNoService.Name(), ProxyStatusConflictInboundListener.Name(), DuplicatedSubsets.Name()
+
+	status := func(filter StatusFilter) map[string]map[string]ProxyPushStatus {
+		t.Helper()
+		b, err := push.StatusJSON(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := map[string]map[string]ProxyPushStatus{}
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	keys := func(status map[string]map[string]ProxyPushStatus) map[string][]string {
+		out := map[string][]string{}
+		for metric, entries := range status {
+			for k := range entries {
+				out[metric] = append(out[metric], k)
+			}
+			sort.Strings(out[metric])
+		}
+		return out
+	}
+
+	cases := []struct {
+		name   string
+		filter StatusFilter
+		want   map[string][]string
+	}{
+		{
+			name:   "unfiltered",
+			filter: StatusFilter{},
+			want: map[string][]string{
+				noService: {"a-1.ns-a", "a-2.ns-a", "a-3.ns-a", "b-1.ns-b"},
+				inbound:   {"b-1.ns-b"},
+				subsets:   {"reviews.default.svc.cluster.local"},
+			},
+		},
+		{
+			name:   "metric",
+			filter: StatusFilter{Metrics: []string{inbound, subsets}},
+			want: map[string][]string{
+				inbound: {"b-1.ns-b"},
+				subsets: {"reviews.default.svc.cluster.local"},
+			},
+		},
+		{
+			name:   "namespace",
+			filter: StatusFilter{Namespaces: []string{"ns-b"}},
+			want: map[string][]string{
+				noService: {"b-1.ns-b"},
+				inbound:   {"b-1.ns-b"},
+			},
+		},
+		{
+			name:   "limit",
+			filter: StatusFilter{Metrics: []string{noService}, Namespaces: []string{"ns-a"}, Limit: 2},
+			want: map[string][]string{
+				noService: {"a-1.ns-a", "a-2.ns-a"},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keys(status(tt.filter)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got entries %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("redact", func(t *testing.T) {
+		got := status(StatusFilter{Redact: true})
+		redacted := redactProxyID("b-1.ns-b")
+		if redacted == "b-1.ns-b" || redacted != redactProxyID("b-1.ns-b") {
+			t.Fatalf("expected a stable hash of the proxy ID, got %s", redacted)
+		}
+		want := ProxyPushStatus{Proxy: redacted, Message: "Conflicting inbound listener on " + redacted + ":80"}
+		if !reflect.DeepEqual(got[inbound], map[string]ProxyPushStatus{redacted: want}) {
+			t.Fatalf("got redacted entries %v, want %v", got[inbound], want)
+		}
+		if len(got[noService]) != 4 {
+			t.Fatalf("expected the entries of all proxies, got %v", got[noService])
+		}
+		for k, ev := range got[noService] {
+			if strings.Contains(k, ".ns-") || strings.Contains(ev.Proxy, ".ns-") {
+				t.Errorf("expected the proxy ID to be redacted, got %s: %+v", k, ev)
+			}
+		}
+		// Entries without proxy are kept as is.
+		if _, f := got[subsets]["reviews.default.svc.cluster.local"]; !f {
+			t.Errorf("expected the entry without proxy to be kept, got %v", got[subsets])
+		}
+	})
+}
+
 func TestEnvoyFilters(t *testing.T) {
 	proxyVersionRegex := regexp.MustCompile(`1\.4.*`)
 	envoyFilters := []*EnvoyFilterWrapper{
@@ -229,6 +415,71 @@ func TestEnvoyFilters(t *testing.T) {
 	}
 }
 
+func TestWorkloadEntryLabelSelection(t *testing.T) {
+	patches := map[networking.EnvoyFilter_ApplyTo][]*EnvoyFilterConfigPatchWrapper{
+		networking.EnvoyFilter_LISTENER: {{Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{}}},
+	}
+	gateway := func(name string, selector map[string]string) config.Config {
+		return config.Config{
+			Meta: config.Meta{Name: name, Namespace: "test-ns", GroupVersionKind: gvk.Gateway},
+			Spec: &networking.Gateway{
+				Selector: selector,
+				Servers: []*networking.Server{{
+					Hosts: []string{"*"},
+					Port:  &networking.Port{Name: name, Number: 80, Protocol: "HTTP"},
+				}},
+			},
+		}
+	}
+	push := &PushContext{
+		Mesh: &meshconfig.MeshConfig{RootNamespace: "istio-system"},
+		envoyFiltersByNamespace: map[string][]*EnvoyFilterWrapper{
+			"test-ns": {
+				{workloadSelector: map[string]string{"role": "db"}, Patches: patches},
+				// The metadata labels take precedence over the WorkloadEntry labels.
+				{workloadSelector: map[string]string{"version": "v2"}, Patches: patches},
+			},
+		},
+		allGateways: []config.Config{
+			gateway("we", map[string]string{"role": "db"}),
+			gateway("conflict", map[string]string{"version": "v2"}),
+		},
+	}
+
+	newProxy := func(instanceLabels map[string]string) *Proxy {
+		proxy := &Proxy{
+			Metadata:        &NodeMetadata{Labels: map[string]string{"app": "vm", "version": "v1"}},
+			ConfigNamespace: "test-ns",
+			ServiceInstances: []*ServiceInstance{{
+				Endpoint: &IstioEndpoint{Labels: instanceLabels},
+			}},
+		}
+		proxy.mergeWorkloadLabels()
+		return proxy
+	}
+
+	vm := newProxy(map[string]string{"role": "db", "version": "v2"})
+	want := labels.Instance{"app": "vm", "role": "db", "version": "v1"}
+	if got := vm.WorkloadLabels(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got workload labels %v, want %v", got, want)
+	}
+	if got := len(push.EnvoyFilters(vm).Patches[networking.EnvoyFilter_LISTENER]); got != 1 {
+		t.Errorf("expected the envoyfilter selecting the WorkloadEntry labels to apply, got %d patches", got)
+	}
+	vm.Type = Router
+	if got := push.mergeGateways(vm); got == nil || len(got.GatewayNameForServer) != 1 {
+		t.Errorf("expected only the gateway selecting the WorkloadEntry labels to apply, got %+v", got)
+	}
+
+	pod := newProxy(nil)
+	if got := push.EnvoyFilters(pod); got != nil {
+		t.Errorf("expected no envoyfilter to apply without WorkloadEntry labels, got %+v", got)
+	}
+	if got := push.mergeGateways(pod); got != nil {
+		t.Errorf("expected no gateway to apply without WorkloadEntry labels, got %+v", got)
+	}
+}
+
 func TestSidecarScope(t *testing.T) {
 	ps := NewPushContext()
 	env := &Environment{Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"})}
@@ -315,11 +566,289 @@ func TestSidecarScope(t *testing.T) {
 	}
 }
 
+func TestSidecarScopesIncrementalUpdate(t *testing.T) {
+	configStore := NewFakeStore()
+	env := &Environment{
+		Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		ServiceDiscovery: serviceAccountsDiscovery(3),
+		IstioConfigStore: &istioConfigStore{ConfigStore: configStore},
+	}
+	sidecar := func(name, namespace string, selector map[string]string) config.Config {
+		spec := &networking.Sidecar{Egress: []*networking.IstioEgressListener{{Hosts: []string{"*/*"}}}}
+		if selector != nil {
+			spec.WorkloadSelector = &networking.WorkloadSelector{Labels: selector}
+		}
+		return config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.Sidecar, Name: name, Namespace: namespace},
+			Spec: spec,
+		}
+	}
+	if _, err := configStore.Create(sidecar("global", "istio-system", nil)); err != nil {
+		t.Fatal(err)
+	}
+	oldPush := NewPushContext()
+	if err := oldPush.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	sidecarsUpdate := func(oldPush *PushContext, keys ...ConfigKey) *PushContext {
+		t.Helper()
+		pushReq := &PushRequest{Full: true, ConfigsUpdated: map[ConfigKey]struct{}{}}
+		for _, key := range keys {
+			pushReq.ConfigsUpdated[key] = struct{}{}
+		}
+		push := NewPushContext()
+		if err := push.InitContext(env, oldPush, pushReq); err != nil {
+			t.Fatal(err)
+		}
+		return push
+	}
+	scopes := func(ps *PushContext, ns string) []string {
+		out := []string{}
+		for _, scope := range ps.sidecarsByNamespace[ns] {
+			out = append(out, scopeToSidecar(scope))
+		}
+		return out
+	}
+
+	// A Sidecar in ns-1 only rebuilds the scopes of ns-1.
+	if _, err := configStore.Create(sidecar("app", "ns-1", map[string]string{"app": "foo"})); err != nil {
+		t.Fatal(err)
+	}
+	push := sidecarsUpdate(oldPush, ConfigKey{Kind: gvk.Sidecar, Name: "app", Namespace: "ns-1"})
+	if got, want := scopes(push, "ns-1"), []string{"ns-1/app", "istio-system/global"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got ns-1 scopes %v, want %v", got, want)
+	}
+	for _, ns := range []string{"ns-0", "ns-2", "istio-system"} {
+		if push.sidecarsByNamespace[ns][0] != oldPush.sidecarsByNamespace[ns][0] {
+			t.Errorf("expected scopes of %v to be reused", ns)
+		}
+	}
+	full := NewPushContext()
+	if err := full.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for ns := range full.sidecarsByNamespace {
+		if got, want := scopes(push, ns), scopes(full, ns); !reflect.DeepEqual(got, want) {
+			t.Errorf("incremental scopes of %v %v do not match a full recomputation %v", ns, got, want)
+		}
+	}
+
+	// A Sidecar in the root namespace rebuilds the scopes of every namespace. The fake store does not delete, so
+	// the store is rebuilt without the root Sidecar.
+	configStore = NewFakeStore()
+	if _, err := configStore.Create(sidecar("app", "ns-1", map[string]string{"app": "foo"})); err != nil {
+		t.Fatal(err)
+	}
+	env.IstioConfigStore = &istioConfigStore{ConfigStore: configStore}
+	rootPush := sidecarsUpdate(push, ConfigKey{Kind: gvk.Sidecar, Name: "global", Namespace: "istio-system"})
+	for _, ns := range []string{"ns-0", "ns-1", "ns-2"} {
+		if got := scopeToSidecar(rootPush.sidecarsByNamespace[ns][len(rootPush.sidecarsByNamespace[ns])-1]); got != ns+"/" {
+			t.Errorf("expected default scope for %v after root sidecar removal, got %v", ns, got)
+		}
+	}
+}
+
+// listErrorStore fails to list the configs of a kind while err is set.
+type listErrorStore struct {
+	ConfigStore
+	kind config.GroupVersionKind
+	err  error
+}
+
+func (s *listErrorStore) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	if typ == s.kind && s.err != nil {
+		return nil, s.err
+	}
+	return s.ConfigStore.List(typ, namespace)
+}
+
+func TestNetworkGatewaysUpdate(t *testing.T) {
+	gwSvc := &Service{
+		Hostname: "istio-ingressgateway.istio-system.svc.cluster.local",
+		Attributes: ServiceAttributes{
+			Namespace:                "istio-system",
+			ClusterExternalAddresses: map[string][]string{"cluster-1": {"1.1.1.1"}},
+		},
+	}
+	env := &Environment{
+		Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		ServiceDiscovery: &localServiceDiscovery{services: []*Service{gwSvc}},
+		IstioConfigStore: &istioConfigStore{ConfigStore: NewFakeStore()},
+		NetworksWatcher: mesh.NewFixedNetworksWatcher(&meshconfig.MeshNetworks{Networks: map[string]*meshconfig.Network{
+			"network-1": {
+				Endpoints: []*meshconfig.Network_NetworkEndpoints{{
+					Ne: &meshconfig.Network_NetworkEndpoints_FromRegistry{FromRegistry: "cluster-1"},
+				}},
+				Gateways: []*meshconfig.Network_IstioNetworkGateway{{
+					Gw:   &meshconfig.Network_IstioNetworkGateway_RegistryServiceName{RegistryServiceName: string(gwSvc.Hostname)},
+					Port: 15443,
+				}},
+			},
+		}}),
+	}
+	if !IsNetworkGatewayService(env.Networks(), gwSvc.Hostname) {
+		t.Fatalf("expected %s to be a network gateway service", gwSvc.Hostname)
+	}
+	if IsNetworkGatewayService(env.Networks(), "other.default.svc.cluster.local") {
+		t.Fatal("expected other service not to be a network gateway service")
+	}
+
+	push := NewPushContext()
+	if err := push.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	expectGateway := func(push *PushContext, addr string) {
+		t.Helper()
+		want := map[string][]*Gateway{"network-1": {{Addr: addr, Port: 15443}}}
+		if got := push.NetworkGateways(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got network gateways %v, want %v", got, want)
+		}
+	}
+	expectGateway(push, "1.1.1.1")
+
+	update := func(oldPush *PushContext, svcName string, gatewaysChanged bool) *PushContext {
+		t.Helper()
+		push := NewPushContext()
+		if err := push.InitContext(env, oldPush, &PushRequest{
+			Full: true,
+			ConfigsUpdated: map[ConfigKey]struct{}{
+				{Kind: gvk.ServiceEntry, Name: svcName, Namespace: "istio-system"}: {},
+			},
+			NetworkGatewaysChanged: gatewaysChanged,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return push
+	}
+	// The LoadBalancer IP of the gateway service is replaced.
+	gwSvc.Attributes.ClusterExternalAddresses = map[string][]string{"cluster-1": {"2.2.2.2"}}
+
+	recomputations := getCounterValue("pilot_network_gateways_recomputations", t)
+	push = update(push, "other.istio-system.svc.cluster.local", false)
+	expectGateway(push, "1.1.1.1")
+	// The service handler flags the gateway services of any key.
+	push = update(push, "other.istio-system.svc.cluster.local", true)
+	expectGateway(push, "2.2.2.2")
+
+	// The gateway service is a ServiceEntry, whose registry pushes its hostname without the flag.
+	gwSvc.Attributes.ServiceRegistry = externalRegistry
+	gwSvc.Attributes.ClusterExternalAddresses = map[string][]string{"cluster-1": {"3.3.3.3"}}
+	push = update(push, string(gwSvc.Hostname), false)
+	expectGateway(push, "3.3.3.3")
+	if got := getCounterValue("pilot_network_gateways_recomputations", t) - recomputations; got != 2 {
+		t.Fatalf("got %v network gateways recomputations, want 2", got)
+	}
+}
+
+func TestResilientInitContext(t *testing.T) {
+	defer func(old bool) { features.EnableResilientPushContextInit = old }(features.EnableResilientPushContextInit)
+	features.EnableResilientPushContextInit = true
+
+	configStore := &listErrorStore{ConfigStore: NewFakeStore(), kind: gvk.EnvoyFilter}
+	env := &Environment{
+		Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		ServiceDiscovery: serviceAccountsDiscovery(1),
+		IstioConfigStore: &istioConfigStore{ConfigStore: configStore},
+	}
+	if _, err := configStore.Create(config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.EnvoyFilter, Name: "ef", Namespace: "istio-system"},
+		Spec: &networking.EnvoyFilter{ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{{
+			ApplyTo: networking.EnvoyFilter_CLUSTER,
+			Patch:   &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_MERGE},
+		}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	oldPush := NewPushContext()
+	if err := oldPush.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(oldPush *PushContext, kind config.GroupVersionKind) (*PushContext, error) {
+		push := NewPushContext()
+		err := push.InitContext(env, oldPush, &PushRequest{
+			Full:           true,
+			ConfigsUpdated: map[ConfigKey]struct{}{{Kind: kind, Name: "ef", Namespace: "istio-system"}: {}},
+		})
+		return push, err
+	}
+	expectDegraded := func(push *PushContext, want map[string]DegradedPhase) {
+		t.Helper()
+		if got := push.Degraded(); !reflect.DeepEqual(got, want) && len(got)+len(want) > 0 {
+			t.Fatalf("got degraded phases %v, want %v", got, want)
+		}
+	}
+	proxy := &Proxy{Metadata: &NodeMetadata{}, ConfigNamespace: "default"}
+
+	// The EnvoyFilters of the previous push context are used when they fail to be listed.
+	configStore.err = errors.New("bad envoyfilter")
+	push, err := update(oldPush, gvk.EnvoyFilter)
+	if err != nil {
+		t.Fatalf("expected the push context to be initialized, got %v", err)
+	}
+	if got := push.EnvoyFilters(proxy); got == nil || len(got.Patches[networking.EnvoyFilter_CLUSTER]) != 1 {
+		t.Fatalf("expected the previous envoyfilters to be used, got %+v", got)
+	}
+	expectDegraded(push, map[string]DegradedPhase{envoyFiltersInitPhase: {Error: "bad envoyfilter", ConsecutiveFailures: 1}})
+	status, err := push.StatusJSON(StatusFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(status), "bad envoyfilter") {
+		t.Errorf("expected the push status to report the failure, got %s", status)
+	}
+
+	// The degraded state is kept by push contexts which do not initialize the EnvoyFilters.
+	push, err = update(push, gvk.VirtualService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectDegraded(push, map[string]DegradedPhase{envoyFiltersInitPhase: {Error: "bad envoyfilter", ConsecutiveFailures: 1}})
+
+	// Consecutive failures are counted.
+	push, err = update(push, gvk.EnvoyFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectDegraded(push, map[string]DegradedPhase{envoyFiltersInitPhase: {Error: "bad envoyfilter", ConsecutiveFailures: 2}})
+
+	// The push context recovers once the EnvoyFilters can be listed again.
+	configStore.err = nil
+	push, err = update(push, gvk.EnvoyFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectDegraded(push, nil)
+
+	// Without the resilient mode, the failure aborts the push.
+	features.EnableResilientPushContextInit = false
+	configStore.err = errors.New("bad envoyfilter")
+	if _, err := update(push, gvk.EnvoyFilter); err == nil {
+		t.Fatalf("expected the push context init to fail")
+	}
+}
+
 func TestBestEffortInferServiceMTLSMode(t *testing.T) {
 	const partialNS string = "partial"
 	const wholeNS string = "whole"
+	const permissiveNS string = "permissive"
 	ps := NewPushContext()
-	env := &Environment{Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"})}
+	serviceName := host.Name("some-service")
+	portLevelService := &Service{Hostname: host.Name(fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, permissiveNS))}
+	// service port 80 targets the workload port 8080, and service port 81 both 8080 and 9090.
+	targetPort := func(servicePort int, endpointPort uint32) *ServiceInstance {
+		return &ServiceInstance{
+			Service:     portLevelService,
+			ServicePort: &Port{Port: servicePort},
+			Endpoint:    &IstioEndpoint{EndpointPort: endpointPort},
+		}
+	}
+	env := &Environment{
+		Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		ServiceDiscovery: &localServiceDiscovery{instances: []*ServiceInstance{
+			targetPort(80, 8080), targetPort(81, 8080), targetPort(81, 9090),
+		}},
+	}
 	ps.Mesh = env.Mesh()
 	ps.ServiceDiscovery = env
 
@@ -334,6 +863,22 @@ func TestBestEffortInferServiceMTLSMode(t *testing.T) {
 			"version": "v1",
 		},
 	}, securityBeta.PeerAuthentication_MutualTLS_DISABLE))
+	// workload level STRICT policy in a permissive namespace.
+	configStore.Create(*createTestPeerAuthenticationResource("default", permissiveNS, time.Now(), nil,
+		securityBeta.PeerAuthentication_MutualTLS_PERMISSIVE))
+	configStore.Create(*createTestPeerAuthenticationResource("strict-workload", permissiveNS, time.Now(),
+		&selectorpb.WorkloadSelector{MatchLabels: map[string]string{"app": "strict"}}, securityBeta.PeerAuthentication_MutualTLS_STRICT))
+	// workload level STRICT policy with port level DISABLE.
+	portLevel := createTestPeerAuthenticationResource("port-level", permissiveNS, time.Now(),
+		&selectorpb.WorkloadSelector{MatchLabels: map[string]string{"app": "port-level"}}, securityBeta.PeerAuthentication_MutualTLS_STRICT)
+	portLevel.Spec.(*securityBeta.PeerAuthentication).PortLevelMtls = map[uint32]*securityBeta.PeerAuthentication_MutualTLS{
+		8080: {Mode: securityBeta.PeerAuthentication_MutualTLS_DISABLE},
+	}
+	configStore.Create(*portLevel)
+	// workload level DISABLE policy selecting only some workloads of a service in a strict namespace.
+	configStore.Create(*createTestPeerAuthenticationResource("mixed", wholeNS, time.Now(),
+		&selectorpb.WorkloadSelector{MatchLabels: map[string]string{"app": "mixed", "version": "v1"}},
+		securityBeta.PeerAuthentication_MutualTLS_DISABLE))
 
 	store := istioConfigStore{ConfigStore: configStore}
 	env.IstioConfigStore = &store
@@ -344,8 +889,10 @@ func TestBestEffortInferServiceMTLSMode(t *testing.T) {
 	cases := []struct {
 		name             string
 		serviceNamespace string
+		serviceSelector  map[string]string
 		servicePort      int
 		wanted           MutualTLSMode
+		wantAmbiguous    bool
 	}{
 		{
 			name:             "from namespace policy",
@@ -359,13 +906,64 @@ func TestBestEffortInferServiceMTLSMode(t *testing.T) {
 			servicePort:      80,
 			wanted:           MTLSPermissive,
 		},
+		{
+			name:             "workload strict in permissive namespace",
+			serviceNamespace: permissiveNS,
+			serviceSelector:  map[string]string{"app": "strict"},
+			servicePort:      80,
+			wanted:           MTLSStrict,
+		},
+		{
+			name:             "workload policy not selecting service",
+			serviceNamespace: permissiveNS,
+			serviceSelector:  map[string]string{"app": "other"},
+			servicePort:      80,
+			wanted:           MTLSPermissive,
+		},
+		{
+			name:             "port level disable on target port",
+			serviceNamespace: permissiveNS,
+			serviceSelector:  map[string]string{"app": "port-level"},
+			servicePort:      80,
+			wanted:           MTLSDisable,
+		},
+		{
+			name:             "port level disable on some target ports",
+			serviceNamespace: permissiveNS,
+			serviceSelector:  map[string]string{"app": "port-level"},
+			servicePort:      81,
+			wanted:           MTLSPermissive,
+			wantAmbiguous:    true,
+		},
+		{
+			name:             "port level override for other port",
+			serviceNamespace: permissiveNS,
+			serviceSelector:  map[string]string{"app": "port-level"},
+			servicePort:      90,
+			wanted:           MTLSStrict,
+		},
+		{
+			name:             "mixed selection",
+			serviceNamespace: wholeNS,
+			serviceSelector:  map[string]string{"app": "mixed"},
+			servicePort:      80,
+			wanted:           MTLSPermissive,
+			wantAmbiguous:    true,
+		},
+		{
+			name:             "mixed selection fully selected",
+			serviceNamespace: wholeNS,
+			serviceSelector:  map[string]string{"app": "mixed", "version": "v1"},
+			servicePort:      80,
+			wanted:           MTLSDisable,
+		},
 	}
-	serviceName := host.Name("some-service")
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			ps.ProxyStatus = map[string]map[string]ProxyPushStatus{}
 			service := &Service{
 				Hostname:   host.Name(fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, tc.serviceNamespace)),
-				Attributes: ServiceAttributes{Namespace: tc.serviceNamespace},
+				Attributes: ServiceAttributes{Namespace: tc.serviceNamespace, LabelSelectors: tc.serviceSelector},
 			}
 			// Intentionally use the externalService with the same name and namespace for test, though
 			// these attributes don't matter.
@@ -384,6 +982,9 @@ func TestBestEffortInferServiceMTLSMode(t *testing.T) {
 			if got := ps.BestEffortInferServiceMTLSMode(externalService, port); got != MTLSUnknown {
 				t.Fatalf("MTLS mode for external service should always be %s, but got %s", MTLSUnknown, got)
 			}
+			if _, got := ps.ProxyStatus[AmbiguousServiceMTLSMode.Name()][string(service.Hostname)]; got != tc.wantAmbiguous {
+				t.Fatalf("want ambiguous mTLS mode recorded %v, but got %v", tc.wantAmbiguous, got)
+			}
 		})
 	}
 }
@@ -445,6 +1046,69 @@ func TestSetDestinationRuleMerging(t *testing.T) {
 	}
 }
 
+func TestSetDestinationRuleConflicts(t *testing.T) {
+	ps := NewPushContext()
+	ps.defaultDestinationRuleExportTo = map[visibility.Instance]bool{visibility.Public: true}
+	testhost := "httpbin.org"
+	roundRobin := &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_ROUND_ROBIN},
+	}
+	rule := func(name string, created int, policy *networking.TrafficPolicy, subsets ...string) config.Config {
+		dr := &networking.DestinationRule{Host: testhost, TrafficPolicy: policy}
+		for _, s := range subsets {
+			dr.Subsets = append(dr.Subsets, &networking.Subset{Name: s})
+		}
+		return config.Config{
+			Meta: config.Meta{Name: name, Namespace: "test", CreationTimestamp: time.Unix(int64(created), 0)},
+			Spec: dr,
+		}
+	}
+	ps.SetDestinationRules([]config.Config{
+		rule("rule3", 3, &networking.TrafficPolicy{
+			Tls:              &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_SIMPLE},
+			LoadBalancer:     roundRobin,
+			OutlierDetection: &networking.OutlierDetection{ConsecutiveErrors: 5},
+		}, "v1", "v3"),
+		rule("rule1", 1, &networking.TrafficPolicy{
+			Tls:          &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_ISTIO_MUTUAL},
+			LoadBalancer: roundRobin,
+		}, "v1"),
+		rule("rule2", 2, &networking.TrafficPolicy{
+			Tls: &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_DISABLE},
+		}, "v2"),
+	})
+
+	// The merge result is unchanged: the oldest rule wins the top level traffic policy.
+	merged := ps.namespaceLocalDestRules["test"].destRule[host.Name(testhost)].Spec.(*networking.DestinationRule)
+	if got := merged.TrafficPolicy.Tls.Mode; got != networking.ClientTLSSettings_ISTIO_MUTUAL {
+		t.Errorf("want TLS mode %v, got %v", networking.ClientTLSSettings_ISTIO_MUTUAL, got)
+	}
+	if len(merged.Subsets) != 3 {
+		t.Errorf("want 3 subsets, got %v", merged.Subsets)
+	}
+
+	merges := ps.DestinationRuleMerges(host.Name(testhost))
+	if len(merges) != 2 || merges[0].Scope != "local" || merges[1].Scope != "exported" {
+		t.Fatalf("want local and exported merges, got %+v", merges)
+	}
+	got := merges[0]
+	if !reflect.DeepEqual(got.Configs, []string{"rule1", "rule2", "rule3"}) || got.TrafficPolicy != "rule1" {
+		t.Errorf("unexpected merge %+v", got)
+	}
+	want := []DestinationRuleConflict{
+		{Type: DestinationRuleConflictTLSMode, Config: "rule2", Message: "TLS mode DISABLE is ignored, rule1 sets ISTIO_MUTUAL"},
+		{Type: DestinationRuleConflictSubset, Config: "rule3", Message: "subset v1 is already defined"},
+		{Type: DestinationRuleConflictTLSMode, Config: "rule3", Message: "TLS mode SIMPLE is ignored, rule1 sets ISTIO_MUTUAL"},
+		{Type: DestinationRuleConflictOutlier, Config: "rule3", Message: "outlier detection settings are ignored, rule1 sets them"},
+	}
+	if !reflect.DeepEqual(got.Conflicts, want) {
+		t.Errorf("want conflicts %+v, got %+v", want, got.Conflicts)
+	}
+	if len(ps.DestinationRuleMerges("unknown.org")) != 0 {
+		t.Errorf("want no merges for an unknown host")
+	}
+}
+
 func TestSetDestinationRuleWithExportTo(t *testing.T) {
 	ps := NewPushContext()
 	ps.Mesh = &meshconfig.MeshConfig{RootNamespace: "istio-system"}
@@ -766,48 +1430,134 @@ func TestVirtualServiceWithExportTo(t *testing.T) {
 	}
 }
 
-func TestServiceWithExportTo(t *testing.T) {
+func TestVirtualServiceGatewayExportTo(t *testing.T) {
 	ps := NewPushContext()
 	env := &Environment{Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "zzz"})}
 	ps.Mesh = env.Mesh()
 	ps.ServiceDiscovery = env
+	configStore := NewFakeStore()
 
-	svc1 := &Service{
-		Hostname: "svc1",
-		Attributes: ServiceAttributes{
-			Namespace: "test1",
-			ExportTo:  map[visibility.Instance]bool{visibility.Private: true, visibility.Instance("ns1"): true},
-		},
-	}
-	svc2 := &Service{
-		Hostname: "svc2",
-		Attributes: ServiceAttributes{
-			Namespace: "test2",
-			ExportTo: map[visibility.Instance]bool{visibility.Instance("test1"): true,
-				visibility.Instance("ns1"):   true,
-				visibility.Instance("test2"): true},
+	gateways := []config.Config{
+		{
+			Meta: config.Meta{Name: "open", Namespace: "gw-ns", GroupVersionKind: gvk.Gateway},
+			Spec: &networking.Gateway{},
 		},
-	}
-	svc3 := &Service{
-		Hostname: "svc3",
-		Attributes: ServiceAttributes{
-			Namespace: "test3",
-			ExportTo: map[visibility.Instance]bool{visibility.Instance("test1"): true,
-				visibility.Public:            true,
-				visibility.Instance("test2"): true},
+		{
+			Meta: config.Meta{
+				Name:             "restricted",
+				Namespace:        "gw-ns",
+				GroupVersionKind: gvk.Gateway,
+				Annotations:      map[string]string{gateway.ExportToAnnotation: "., allowed"},
+			},
+			Spec: &networking.Gateway{},
 		},
 	}
-	svc4 := &Service{
-		Hostname: "svc4",
-		Attributes: ServiceAttributes{
-			Namespace: "test4",
-		},
+	vsIn := func(ns string) config.Config {
+		return config.Config{
+			Meta: config.Meta{Name: "vs", Namespace: ns, GroupVersionKind: gvk.VirtualService},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{ns + ".example.com"},
+				Gateways: []string{"gw-ns/open", "gw-ns/restricted", constants.IstioMeshGateway},
+			},
+		}
 	}
-	env.ServiceDiscovery = &localServiceDiscovery{
-		services: []*Service{svc1, svc2, svc3, svc4},
+	for _, c := range append(gateways, vsIn("gw-ns"), vsIn("allowed"), vsIn("denied")) {
+		if _, err := configStore.Create(c); err != nil {
+			t.Fatalf("could not create %v", c.Name)
+		}
 	}
+
+	env.IstioConfigStore = &istioConfigStore{ConfigStore: configStore}
 	ps.initDefaultExportMaps()
-	if err := ps.initServiceRegistry(env); err != nil {
+	if err := ps.initVirtualServices(env); err != nil {
+		t.Fatalf("init virtual services failed: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		gateway   string
+		wantHosts []string
+	}{
+		{
+			name:      "no restriction",
+			gateway:   "gw-ns/open",
+			wantHosts: []string{"allowed.example.com", "denied.example.com", "gw-ns.example.com"},
+		},
+		{
+			name:      "restricted",
+			gateway:   "gw-ns/restricted",
+			wantHosts: []string{"allowed.example.com", "gw-ns.example.com"},
+		},
+		{
+			name:      "mesh",
+			gateway:   constants.IstioMeshGateway,
+			wantHosts: []string{"allowed.example.com", "denied.example.com", "gw-ns.example.com"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			gotHosts := make([]string, 0)
+			for _, r := range ps.VirtualServicesForGateway(&Proxy{ConfigNamespace: "gw-ns"}, tt.gateway) {
+				gotHosts = append(gotHosts, r.Spec.(*networking.VirtualService).Hosts...)
+			}
+			sort.Strings(gotHosts)
+			if !reflect.DeepEqual(gotHosts, tt.wantHosts) {
+				t.Errorf("want %+v, got %+v", tt.wantHosts, gotHosts)
+			}
+		})
+	}
+
+	denied := ps.ProxyStatus[VirtualServiceGatewayBindingDenied.Name()]
+	if len(denied) != 1 {
+		t.Fatalf("expected one denied virtual service, got %v", denied)
+	}
+	if _, f := denied["denied/vs"]; !f {
+		t.Errorf("expected denied/vs to be reported, got %v", denied)
+	}
+}
+
+func TestServiceWithExportTo(t *testing.T) {
+	ps := NewPushContext()
+	env := &Environment{Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "zzz"})}
+	ps.Mesh = env.Mesh()
+	ps.ServiceDiscovery = env
+
+	svc1 := &Service{
+		Hostname: "svc1",
+		Attributes: ServiceAttributes{
+			Namespace: "test1",
+			ExportTo:  map[visibility.Instance]bool{visibility.Private: true, visibility.Instance("ns1"): true},
+		},
+	}
+	svc2 := &Service{
+		Hostname: "svc2",
+		Attributes: ServiceAttributes{
+			Namespace: "test2",
+			ExportTo: map[visibility.Instance]bool{visibility.Instance("test1"): true,
+				visibility.Instance("ns1"):   true,
+				visibility.Instance("test2"): true},
+		},
+	}
+	svc3 := &Service{
+		Hostname: "svc3",
+		Attributes: ServiceAttributes{
+			Namespace: "test3",
+			ExportTo: map[visibility.Instance]bool{visibility.Instance("test1"): true,
+				visibility.Public:            true,
+				visibility.Instance("test2"): true},
+		},
+	}
+	svc4 := &Service{
+		Hostname: "svc4",
+		Attributes: ServiceAttributes{
+			Namespace: "test4",
+		},
+	}
+	env.ServiceDiscovery = &localServiceDiscovery{
+		services: []*Service{svc1, svc2, svc3, svc4},
+	}
+	ps.initDefaultExportMaps()
+	if err := ps.initServiceRegistry(env, nil, nil); err != nil {
 		t.Fatalf("init services failed: %v", err)
 	}
 
@@ -844,6 +1594,464 @@ func TestServiceWithExportTo(t *testing.T) {
 	}
 }
 
+func TestServiceEntryHostCollisionPolicy(t *testing.T) {
+	now := time.Now()
+	kube := &Service{
+		Hostname:     "svc.default.svc.cluster.local",
+		CreationTime: now,
+		Attributes:   ServiceAttributes{Namespace: "default", ServiceRegistry: kubernetesRegistry},
+	}
+	external := &Service{
+		Hostname:     "svc.default.svc.cluster.local",
+		CreationTime: now.Add(time.Minute),
+		Attributes:   ServiceAttributes{Namespace: "default", ServiceRegistry: externalRegistry},
+	}
+	other := &Service{
+		Hostname:     "other.example.com",
+		CreationTime: now,
+		Attributes:   ServiceAttributes{Namespace: "default", ServiceRegistry: externalRegistry},
+	}
+
+	cases := []struct {
+		name   string
+		policy string
+		// shadowing is the namespace of the ServiceEntry annotated to shadow the colliding host, if any
+		shadowing string
+		// wantRegistries are the registries of the services of the colliding host visible to the proxy, in order
+		wantRegistries []string
+		// wantIndexed is the registry of the service indexed by the colliding host
+		wantIndexed string
+	}{
+		{
+			name:           "allow",
+			policy:         mesh.HostCollisionAllow,
+			wantRegistries: []string{kubernetesRegistry, externalRegistry},
+			wantIndexed:    externalRegistry,
+		},
+		{
+			name:           "reject",
+			policy:         mesh.HostCollisionReject,
+			wantRegistries: []string{kubernetesRegistry},
+			wantIndexed:    kubernetesRegistry,
+		},
+		{
+			name:           "reject shadowing allowed",
+			policy:         mesh.HostCollisionReject,
+			shadowing:      "default",
+			wantRegistries: []string{kubernetesRegistry, externalRegistry},
+			wantIndexed:    externalRegistry,
+		},
+		{
+			name:           "prefer kubernetes",
+			policy:         mesh.HostCollisionPreferKubernetes,
+			wantRegistries: []string{kubernetesRegistry, externalRegistry},
+			wantIndexed:    kubernetesRegistry,
+		},
+		{
+			name:           "prefer kubernetes shadowing allowed",
+			policy:         mesh.HostCollisionPreferKubernetes,
+			shadowing:      "default",
+			wantRegistries: []string{kubernetesRegistry, externalRegistry},
+			wantIndexed:    externalRegistry,
+		},
+		{
+			name:           "reject shadowing allowed in another namespace",
+			policy:         mesh.HostCollisionReject,
+			shadowing:      "other",
+			wantRegistries: []string{kubernetesRegistry},
+			wantIndexed:    kubernetesRegistry,
+		},
+		{
+			name:           "unknown policy rejects",
+			policy:         "DENY",
+			wantRegistries: []string{kubernetesRegistry},
+			wantIndexed:    kubernetesRegistry,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFakeStore()
+			se := config.Config{
+				Meta: config.Meta{
+					GroupVersionKind: gvk.ServiceEntry,
+					Name:             "svc",
+					Namespace:        "default",
+				},
+				Spec: &networking.ServiceEntry{Hosts: []string{string(external.Hostname)}},
+			}
+			if tt.shadowing != "" {
+				se.Namespace = tt.shadowing
+				se.Annotations = map[string]string{ServiceEntryHostShadowingAnnotation: "true"}
+			}
+			if _, err := store.Create(se); err != nil {
+				t.Fatal(err)
+			}
+			env := &Environment{
+				Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+				IstioConfigStore: &istioConfigStore{ConfigStore: store},
+				ServiceDiscovery: &localServiceDiscovery{services: []*Service{kube, external, other}},
+				MeshExtensionsWatcher: mesh.NewFixedMeshExtensionsWatcher(&mesh.MeshExtensions{
+					ServiceEntryHostCollisionPolicy: tt.policy,
+				}),
+			}
+			ps := NewPushContext()
+			ps.Mesh = env.Mesh()
+			ps.initDefaultExportMaps()
+			if err := ps.initServiceRegistry(env, nil, nil); err != nil {
+				t.Fatalf("init services failed: %v", err)
+			}
+
+			var gotRegistries []string
+			for _, s := range ps.Services(&Proxy{ConfigNamespace: "default"}) {
+				if s.Hostname == kube.Hostname {
+					gotRegistries = append(gotRegistries, s.Attributes.ServiceRegistry)
+				}
+			}
+			if !reflect.DeepEqual(gotRegistries, tt.wantRegistries) {
+				t.Errorf("got services from %v, want %v", gotRegistries, tt.wantRegistries)
+			}
+			if got := ps.ServiceByHostname[kube.Hostname].Attributes.ServiceRegistry; got != tt.wantIndexed {
+				t.Errorf("got service indexed from %s, want %s", got, tt.wantIndexed)
+			}
+			if ps.ServiceByHostname[other.Hostname] != other {
+				t.Errorf("service %s without collision not indexed", other.Hostname)
+			}
+			// Only the current collisions are remembered for logging.
+			loggedHostCollisionsMu.Lock()
+			logged := len(loggedHostCollisions)
+			loggedHostCollisionsMu.Unlock()
+			if logged != 1 {
+				t.Errorf("expected the only collision to be logged, got %d", logged)
+			}
+		})
+	}
+}
+
+// TestServiceEntryHostCollisionPreferKubernetesPerNamespace tests that a Kubernetes service preferred to the
+// ServiceEntries of a namespace does not replace the ServiceEntries of another namespace allowed to shadow it.
+func TestServiceEntryHostCollisionPreferKubernetesPerNamespace(t *testing.T) {
+	now := time.Now()
+	kube := &Service{
+		Hostname:     "svc.default.svc.cluster.local",
+		CreationTime: now,
+		Attributes:   ServiceAttributes{Namespace: "default", ServiceRegistry: kubernetesRegistry},
+	}
+	preferred := &Service{
+		Hostname:     kube.Hostname,
+		CreationTime: now.Add(time.Minute),
+		Attributes:   ServiceAttributes{Namespace: "preferred", ServiceRegistry: externalRegistry},
+	}
+	shadowing := &Service{
+		Hostname:     kube.Hostname,
+		CreationTime: now.Add(2 * time.Minute),
+		Attributes:   ServiceAttributes{Namespace: "shadowing", ServiceRegistry: externalRegistry},
+	}
+
+	store := NewFakeStore()
+	for _, ns := range []string{"preferred", "shadowing"} {
+		se := config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.ServiceEntry,
+				Name:             "svc",
+				Namespace:        ns,
+			},
+			Spec: &networking.ServiceEntry{Hosts: []string{string(kube.Hostname)}},
+		}
+		if ns == "shadowing" {
+			se.Annotations = map[string]string{ServiceEntryHostShadowingAnnotation: "true"}
+		}
+		if _, err := store.Create(se); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := &Environment{
+		Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		IstioConfigStore: &istioConfigStore{ConfigStore: store},
+		ServiceDiscovery: &localServiceDiscovery{services: []*Service{shadowing, preferred, kube}},
+		MeshExtensionsWatcher: mesh.NewFixedMeshExtensionsWatcher(&mesh.MeshExtensions{
+			ServiceEntryHostCollisionPolicy: mesh.HostCollisionPreferKubernetes,
+		}),
+	}
+	ps := NewPushContext()
+	ps.Mesh = env.Mesh()
+	ps.initDefaultExportMaps()
+	if err := ps.initServiceRegistry(env, nil, nil); err != nil {
+		t.Fatalf("init services failed: %v", err)
+	}
+
+	if got := ps.ServiceByHostname[kube.Hostname]; got != shadowing {
+		t.Errorf("got service indexed from namespace %s, want shadowing", got.Attributes.Namespace)
+	}
+	byNamespace := ps.ServiceByHostnameAndNamespace[kube.Hostname]
+	if byNamespace["shadowing"] != shadowing || byNamespace["preferred"] != preferred || byNamespace["default"] != kube {
+		t.Errorf("unexpected services indexed by namespace: %v", byNamespace)
+	}
+	var got []*Service
+	for _, s := range ps.Services(&Proxy{ConfigNamespace: "default"}) {
+		if s.Hostname == kube.Hostname {
+			got = append(got, s)
+		}
+	}
+	if want := []*Service{kube, preferred, shadowing}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got services %v, want %v", got, want)
+	}
+}
+
+func TestEgressGatewayService(t *testing.T) {
+	https := &Port{Name: "https", Port: 443, Protocol: protocol.TLS}
+	external := &Service{
+		Hostname:   "api.example.com",
+		Ports:      PortList{https},
+		Attributes: ServiceAttributes{Namespace: "default", ServiceRegistry: externalRegistry},
+	}
+	other := &Service{
+		Hostname:   "other.example.com",
+		Ports:      PortList{https, {Name: "tcp", Port: 9000, Protocol: protocol.TCP}},
+		Attributes: ServiceAttributes{Namespace: "istio-system", ServiceRegistry: externalRegistry},
+	}
+	gatewayTLS := &Port{Name: "tls", Port: 443, Protocol: protocol.TLS}
+	gatewayTCP := &Port{Name: "tcp", Port: 15443, Protocol: protocol.TCP}
+	gateway := &Service{
+		Hostname:   "istio-egressgateway.istio-system.svc.cluster.local",
+		Ports:      PortList{gatewayTLS, gatewayTCP},
+		Attributes: ServiceAttributes{Namespace: "istio-system", ServiceRegistry: kubernetesRegistry},
+	}
+	extensions := &mesh.MeshExtensions{
+		EgressGatewayRedirects: []mesh.EgressGatewayRedirect{
+			{Namespace: "default", Hosts: []string{string(external.Hostname)}, Gateway: string(gateway.Hostname)},
+			{Hosts: []string{string(other.Hostname)}, Gateway: string(gateway.Hostname)},
+			{Namespace: "tcp", Hosts: []string{string(other.Hostname)}, Gateway: string(gateway.Hostname), Port: 15443},
+		},
+	}
+	env := &Environment{
+		Watcher:               mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		MeshExtensionsWatcher: mesh.NewFixedMeshExtensionsWatcher(extensions),
+		IstioConfigStore:      &istioConfigStore{ConfigStore: NewFakeStore()},
+		ServiceDiscovery:      &localServiceDiscovery{services: []*Service{external, other, gateway}},
+	}
+	ps := NewPushContext()
+	ps.Mesh = env.Mesh()
+	ps.initDefaultExportMaps()
+	if err := ps.initServiceRegistry(env, nil, nil); err != nil {
+		t.Fatalf("init services failed: %v", err)
+	}
+
+	sidecar := &Proxy{Type: SidecarProxy, ConfigNamespace: "default"}
+	otherSidecar := &Proxy{Type: SidecarProxy, ConfigNamespace: "other"}
+	tcpSidecar := &Proxy{Type: SidecarProxy, ConfigNamespace: "tcp"}
+	egressGateway := &Proxy{Type: Router, ConfigNamespace: "istio-system"}
+	cases := []struct {
+		name     string
+		proxy    *Proxy
+		hostname host.Name
+		port     int
+		want     *Service
+		wantPort *Port
+	}{
+		{"sidecar of the namespace", sidecar, external.Hostname, 443, gateway, gatewayTLS},
+		{"egress gateway", egressGateway, external.Hostname, 443, nil, nil},
+		{"sidecar of another namespace", otherSidecar, external.Hostname, 443, nil, nil},
+		{"all namespaces", otherSidecar, other.Hostname, 443, gateway, gatewayTLS},
+		{"all namespaces egress gateway", egressGateway, other.Hostname, 443, nil, nil},
+		{"no gateway port with the number of the port", otherSidecar, other.Hostname, 9000, nil, nil},
+		{"gateway port", tcpSidecar, other.Hostname, 9000, gateway, gatewayTCP},
+		{"gateway service", sidecar, gateway.Hostname, 443, nil, nil},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotPort := ps.EgressGatewayService(tt.proxy, tt.hostname, tt.port)
+			if got != tt.want || gotPort != tt.wantPort {
+				t.Errorf("got service %v port %v, want %v port %v", got, gotPort, tt.want, tt.wantPort)
+			}
+			// The other callers keep resolving the hostname itself.
+			if svc := ps.ServiceForHostname(tt.proxy, tt.hostname); svc == nil || svc.Hostname != tt.hostname {
+				t.Errorf("got service %v for %s", svc, tt.hostname)
+			}
+		})
+	}
+}
+
+func TestServicesVisibilityDiff(t *testing.T) {
+	newService := func(name, namespace string, exportTo visibility.Instance) *Service {
+		svc := &Service{
+			Hostname:   host.Name(name + "." + namespace + ".svc.cluster.local"),
+			Attributes: ServiceAttributes{Name: name, Namespace: namespace},
+		}
+		if exportTo != "" {
+			svc.Attributes.ExportTo = map[visibility.Instance]bool{exportTo: true}
+		}
+		return svc
+	}
+	sd := &localServiceDiscovery{}
+	env := &Environment{
+		Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		ServiceDiscovery: sd,
+		IstioConfigStore: &istioConfigStore{ConfigStore: NewFakeStore()},
+	}
+
+	sd.services = []*Service{
+		newService("svc1", "test1", visibility.Public),
+		newService("svc2", "test2", ""),
+		newService("svc3", "test3", visibility.Public),
+	}
+	oldPush := NewPushContext()
+	if err := oldPush.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	sd.services = []*Service{
+		newService("svc1", "test1", visibility.Private),
+		newService("svc2", "test2", ""),
+		newService("svc4", "test4", visibility.None),
+		newService("svc5", "test5", visibility.Public),
+	}
+	pushReq := &PushRequest{
+		Full:           true,
+		ConfigsUpdated: map[ConfigKey]struct{}{{Kind: gvk.ServiceEntry, Name: "svc1", Namespace: "test1"}: {}},
+	}
+	push := NewPushContext()
+	if err := push.InitContext(env, oldPush, pushReq); err != nil {
+		t.Fatal(err)
+	}
+	want := &ServiceVisibilityDiff{
+		Added:   []ServiceVisibilityKey{{Hostname: "svc5.test5.svc.cluster.local", Namespace: "test5"}},
+		Removed: []ServiceVisibilityKey{{Hostname: "svc3.test3.svc.cluster.local", Namespace: "test3"}},
+		Changed: []ServiceVisibilityKey{{Hostname: "svc1.test1.svc.cluster.local", Namespace: "test1"}},
+	}
+	if !reflect.DeepEqual(pushReq.ServiceVisibilityDiff, want) {
+		t.Fatalf("got visibility diff %+v, want %+v", pushReq.ServiceVisibilityDiff, want)
+	}
+	if got := push.ServicesVisibilityDiff(oldPush); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got visibility diff %+v, want %+v", got, want)
+	}
+
+	// services are not recomputed when they did not change, so the diff is empty.
+	pushReq = &PushRequest{
+		Full:           true,
+		ConfigsUpdated: map[ConfigKey]struct{}{{Kind: gvk.VirtualService, Name: "vs", Namespace: "test1"}: {}},
+	}
+	next := NewPushContext()
+	if err := next.InitContext(env, push, pushReq); err != nil {
+		t.Fatal(err)
+	}
+	if pushReq.ServiceVisibilityDiff == nil || !pushReq.ServiceVisibilityDiff.IsEmpty() {
+		t.Fatalf("expected an empty visibility diff when services did not change, got %+v", pushReq.ServiceVisibilityDiff)
+	}
+	if diff := next.ServicesVisibilityDiff(push); !diff.IsEmpty() {
+		t.Fatalf("expected empty visibility diff for copied services, got %+v", diff)
+	}
+}
+
+// serviceAccountsDiscovery returns a service discovery with n services, each with a TCP and a UDP port,
+// and a distinct service account.
+func serviceAccountsDiscovery(n int) *localServiceDiscovery {
+	sd := &localServiceDiscovery{serviceAccounts: map[host.Name][]string{}}
+	for i := 0; i < n; i++ {
+		h := host.Name(fmt.Sprintf("svc-%d.ns-%d.svc.cluster.local", i, i%10))
+		sd.services = append(sd.services, &Service{
+			Hostname:   h,
+			Attributes: ServiceAttributes{Name: fmt.Sprintf("svc-%d", i), Namespace: fmt.Sprintf("ns-%d", i%10)},
+			Ports: PortList{
+				{Name: "http", Port: 80, Protocol: protocol.HTTP},
+				{Name: "dns", Port: 53, Protocol: protocol.UDP},
+			},
+		})
+		sd.serviceAccounts[h] = []string{fmt.Sprintf("spiffe://cluster.local/ns/ns-%d/sa/sa-%d", i%10, i)}
+	}
+	return sd
+}
+
+func TestServiceAccountsIncrementalUpdate(t *testing.T) {
+	sd := serviceAccountsDiscovery(100)
+	env := &Environment{
+		Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		ServiceDiscovery: sd,
+		IstioConfigStore: &istioConfigStore{ConfigStore: NewFakeStore()},
+	}
+	oldPush := NewPushContext()
+	if err := oldPush.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the accounts of one service, add a service and remove another one.
+	changed := sd.services[5]
+	sd.serviceAccounts[changed.Hostname] = []string{"spiffe://cluster.local/ns/ns-5/sa/rotated"}
+	added := &Service{
+		Hostname:   "added.ns-1.svc.cluster.local",
+		Attributes: ServiceAttributes{Name: "added", Namespace: "ns-1"},
+		Ports:      PortList{{Name: "grpc", Port: 8080, Protocol: protocol.GRPC}},
+	}
+	sd.serviceAccounts[added.Hostname] = []string{"spiffe://cluster.local/ns/ns-1/sa/added"}
+	removed := sd.services[7]
+	sd.services = append(append(sd.services[:7:7], sd.services[8:]...), added)
+
+	pushReq := &PushRequest{
+		Full: true,
+		ConfigsUpdated: map[ConfigKey]struct{}{
+			{Kind: gvk.ServiceEntry, Name: string(changed.Hostname), Namespace: changed.Attributes.Namespace}: {},
+			{Kind: gvk.ServiceEntry, Name: string(added.Hostname), Namespace: added.Attributes.Namespace}:     {},
+			{Kind: gvk.ServiceEntry, Name: string(removed.Hostname), Namespace: removed.Attributes.Namespace}: {},
+		},
+	}
+	sd.serviceAccountLookups = 0
+	push := NewPushContext()
+	if err := push.InitContext(env, oldPush, pushReq); err != nil {
+		t.Fatal(err)
+	}
+	if sd.serviceAccountLookups != 2 {
+		t.Errorf("expected service accounts to be looked up for the 2 changed services, got %d lookups", sd.serviceAccountLookups)
+	}
+
+	full := NewPushContext()
+	if err := full.InitContext(env, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(push.ServiceAccounts, full.ServiceAccounts) {
+		t.Fatalf("incremental service accounts do not match a full recomputation")
+	}
+	if _, f := push.ServiceAccounts[removed.Hostname]; f {
+		t.Errorf("expected service accounts of removed service %v to be dropped", removed.Hostname)
+	}
+	if got := oldPush.ServiceAccounts[changed.Hostname][80]; !reflect.DeepEqual(got, []string{"spiffe://cluster.local/ns/ns-5/sa/sa-5"}) {
+		t.Errorf("expected old push context to be unmodified, got %v", got)
+	}
+}
+
+func BenchmarkInitServiceAccounts(b *testing.B) {
+	sd := serviceAccountsDiscovery(10000)
+	env := &Environment{
+		Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		ServiceDiscovery: sd,
+		IstioConfigStore: &istioConfigStore{ConfigStore: NewFakeStore()},
+	}
+	oldPush := NewPushContext()
+	if err := oldPush.InitContext(env, nil, nil); err != nil {
+		b.Fatal(err)
+	}
+	changed := map[host.Name]struct{}{sd.services[42].Hostname: {}}
+
+	b.Run("full", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			ps := NewPushContext()
+			ps.Mesh = env.Mesh()
+			ps.initDefaultExportMaps()
+			if err := ps.initServiceRegistry(env, nil, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("one changed", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			ps := NewPushContext()
+			ps.Mesh = env.Mesh()
+			ps.initDefaultExportMaps()
+			if err := ps.initServiceRegistry(env, oldPush, changed); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func TestIsClusterLocal(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -939,6 +2147,144 @@ func TestIsClusterLocal(t *testing.T) {
 			host:     "s.ns3.svc.cluster.local",
 			expected: false,
 		},
+		{
+			name: "wildcard local",
+			m: meshconfig.MeshConfig{
+				// Mark team-a cluster-local, except for one host.
+				ServiceSettings: []*meshconfig.MeshConfig_ServiceSettings{
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: true,
+						},
+						Hosts: []string{"*.team-a.svc.cluster.local"},
+					},
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: false,
+						},
+						Hosts: []string{"public.team-a.svc.cluster.local"},
+					},
+				},
+			},
+			host:     "s.team-a.svc.cluster.local",
+			expected: true,
+		},
+		{
+			name: "non-local exception to wildcard local",
+			m: meshconfig.MeshConfig{
+				ServiceSettings: []*meshconfig.MeshConfig_ServiceSettings{
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: true,
+						},
+						Hosts: []string{"*.team-a.svc.cluster.local"},
+					},
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: false,
+						},
+						Hosts: []string{"public.team-a.svc.cluster.local"},
+					},
+				},
+			},
+			host:     "public.team-a.svc.cluster.local",
+			expected: false,
+		},
+		{
+			name: "wildcard non-local",
+			m: meshconfig.MeshConfig{
+				// Remove the default for kube-system, except for one host.
+				ServiceSettings: []*meshconfig.MeshConfig_ServiceSettings{
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: false,
+						},
+						Hosts: []string{"*.kube-system.svc.cluster.local"},
+					},
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: true,
+						},
+						Hosts: []string{"dns.kube-system.svc.cluster.local"},
+					},
+				},
+			},
+			host:     "s.kube-system.svc.cluster.local",
+			expected: false,
+		},
+		{
+			name: "local exception to wildcard non-local",
+			m: meshconfig.MeshConfig{
+				ServiceSettings: []*meshconfig.MeshConfig_ServiceSettings{
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: false,
+						},
+						Hosts: []string{"*.kube-system.svc.cluster.local"},
+					},
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: true,
+						},
+						Hosts: []string{"dns.kube-system.svc.cluster.local"},
+					},
+				},
+			},
+			host:     "dns.kube-system.svc.cluster.local",
+			expected: true,
+		},
+		{
+			name: "non-local exception to default",
+			m: meshconfig.MeshConfig{
+				// Exclude one host from the kube-system default only.
+				ServiceSettings: []*meshconfig.MeshConfig_ServiceSettings{
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: false,
+						},
+						Hosts: []string{"public.kube-system.svc.cluster.local"},
+					},
+				},
+			},
+			host:     "s.kube-system.svc.cluster.local",
+			expected: true,
+		},
+		{
+			name: "non-local host in default",
+			m: meshconfig.MeshConfig{
+				ServiceSettings: []*meshconfig.MeshConfig_ServiceSettings{
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: false,
+						},
+						Hosts: []string{"public.kube-system.svc.cluster.local"},
+					},
+				},
+			},
+			host:     "public.kube-system.svc.cluster.local",
+			expected: false,
+		},
+		{
+			name: "first setting of a host wins",
+			m: meshconfig.MeshConfig{
+				ServiceSettings: []*meshconfig.MeshConfig_ServiceSettings{
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: false,
+						},
+						Hosts: []string{"*.team-a.svc.cluster.local"},
+					},
+					{
+						Settings: &meshconfig.MeshConfig_ServiceSettings_Settings{
+							ClusterLocal: true,
+						},
+						Hosts: []string{"*.team-a.svc.cluster.local"},
+					},
+				},
+			},
+			host:     "s.team-a.svc.cluster.local",
+			expected: false,
+		},
 	}
 
 	for _, c := range cases {
@@ -963,6 +2309,12 @@ func TestIsClusterLocal(t *testing.T) {
 // MockDiscovery is an in-memory ServiceDiscover with mock services
 type localServiceDiscovery struct {
 	services []*Service
+	// serviceAccounts are the service accounts of the services, by hostname.
+	serviceAccounts map[host.Name][]string
+	// serviceAccountLookups counts the calls to GetIstioServiceAccounts.
+	serviceAccountLookups int
+	// instances are the service instances returned by InstancesByPort.
+	instances []*ServiceInstance
 }
 
 func (l *localServiceDiscovery) Services() ([]*Service, error) {
@@ -970,11 +2322,22 @@ func (l *localServiceDiscovery) Services() ([]*Service, error) {
 }
 
 func (l *localServiceDiscovery) GetService(hostname host.Name) (*Service, error) {
-	panic("implement me")
+	for _, s := range l.services {
+		if s.Hostname == hostname {
+			return s, nil
+		}
+	}
+	return nil, nil
 }
 
 func (l *localServiceDiscovery) InstancesByPort(svc *Service, servicePort int, labels labels.Collection) []*ServiceInstance {
-	panic("implement me")
+	var out []*ServiceInstance
+	for _, instance := range l.instances {
+		if instance.Service.Hostname == svc.Hostname && instance.ServicePort.Port == servicePort {
+			out = append(out, instance)
+		}
+	}
+	return out
 }
 
 func (l *localServiceDiscovery) GetProxyServiceInstances(proxy *Proxy) ([]*ServiceInstance, error) {
@@ -994,5 +2357,360 @@ func (l *localServiceDiscovery) WorkloadHealthCheckInfo(addr string) ProbeList {
 }
 
 func (l *localServiceDiscovery) GetIstioServiceAccounts(svc *Service, ports []int) []string {
-	panic("implement me")
+	l.serviceAccountLookups++
+	return l.serviceAccounts[svc.Hostname]
+}
+
+// gatewayVirtualServicesPushContext returns a PushContext with n virtual services bound to gateway, spread over
+// hosts hosts in 20 teams, some of them wildcards, with mixed visibility.
+func gatewayVirtualServicesPushContext(t testing.TB, gateway string, n, hosts int) *PushContext {
+	t.Helper()
+	ps := NewPushContext()
+	env := &Environment{Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"})}
+	ps.Mesh = env.Mesh()
+	ps.ServiceDiscovery = env
+	configStore := NewFakeStore()
+	for i := 0; i < n; i++ {
+		h := fmt.Sprintf("svc-%d.team-%d.example.com", i%hosts, i%20)
+		switch {
+		case i == 0:
+			h = "*"
+		case i == 1:
+			h = "*.example.com"
+		case i%25 == 0:
+			h = fmt.Sprintf("*.team-%d.example.com", i%20)
+		}
+		vs := &networking.VirtualService{
+			Gateways: []string{gateway},
+			Hosts:    []string{h},
+		}
+		switch i % 3 {
+		case 0:
+			vs.ExportTo = []string{"."}
+		case 2:
+			vs.ExportTo = []string{"ns-0"}
+		}
+		if i%7 == 0 {
+			vs.Hosts = append(vs.Hosts, fmt.Sprintf("alias-%d.other.org", i))
+		}
+		c := config.Config{
+			Meta: config.Meta{
+				Name:             fmt.Sprintf("vs-%d", i),
+				Namespace:        fmt.Sprintf("ns-%d", i%5),
+				GroupVersionKind: gvk.VirtualService,
+			},
+			Spec: vs,
+		}
+		if _, err := configStore.Create(c); err != nil {
+			t.Fatalf("could not create %v: %v", c.Name, err)
+		}
+	}
+	env.IstioConfigStore = &istioConfigStore{ConfigStore: configStore}
+	ps.initDefaultExportMaps()
+	if err := ps.initVirtualServices(env); err != nil {
+		t.Fatalf("init virtual services failed: %v", err)
+	}
+	return ps
+}
+
+// filterVirtualServicesByHosts returns the names of the virtual services with hosts intersecting hosts.
+func filterVirtualServicesByHosts(vses []config.Config, hosts []host.Name) []string {
+	res := []string{}
+	for _, vs := range vses {
+		if len(host.NewNames(vs.Spec.(*networking.VirtualService).Hosts).Intersection(hosts)) > 0 {
+			res = append(res, vs.Namespace+"/"+vs.Name)
+		}
+	}
+	return res
+}
+
+func TestVirtualServicesForGatewayAndHosts(t *testing.T) {
+	gateway := "istio-system/gateway"
+	ps := gatewayVirtualServicesPushContext(t, gateway, 500, 200)
+
+	cases := [][]host.Name{
+		{"svc-7.team-7.example.com"},
+		{"svc-123.team-3.example.com", "svc-42.team-2.example.com"},
+		{"unknown.team-5.example.com"},
+		{"unknown.example.com"},
+		{"alias-14.other.org"},
+		{"nothing.org"},
+		{"example.com"},
+		{"*.team-3.example.com"},
+		{"svc-1.team-1.example.com", "*.example.com"},
+	}
+	for _, proxyNs := range []string{"ns-0", "ns-1", "istio-system"} {
+		proxy := &Proxy{ConfigNamespace: proxyNs}
+		full := ps.VirtualServicesForGateway(proxy, gateway)
+		for _, hosts := range cases {
+			t.Run(fmt.Sprintf("%s-%v", proxyNs, hosts), func(t *testing.T) {
+				indexed := ps.VirtualServicesForGatewayAndHosts(proxy, gateway, hosts)
+				if len(indexed) > len(full) {
+					t.Fatalf("indexed lookup returned %d virtual services, more than the %d bound to the gateway", len(indexed), len(full))
+				}
+				want := filterVirtualServicesByHosts(full, hosts)
+				got := filterVirtualServicesByHosts(indexed, hosts)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("indexed lookup got %v, want %v", got, want)
+				}
+			})
+		}
+	}
+
+	// The indexed lookup only returns candidates for the hosts.
+	proxy := &Proxy{ConfigNamespace: "ns-1"}
+	hosts := []host.Name{"svc-7.team-7.example.com"}
+	indexed := ps.VirtualServicesForGatewayAndHosts(proxy, gateway, hosts)
+	if all := ps.VirtualServicesForGateway(proxy, gateway); len(indexed) >= len(all) {
+		t.Errorf("expected indexed lookup to return fewer than the %d virtual services of the gateway, got %d", len(all), len(indexed))
+	}
+}
+
+func BenchmarkVirtualServicesForGatewayAndHosts(b *testing.B) {
+	gateway := "istio-system/gateway"
+	ps := gatewayVirtualServicesPushContext(b, gateway, 500, 200)
+	proxy := &Proxy{ConfigNamespace: "ns-0"}
+	hosts := []host.Name{"svc-7.team-7.example.com"}
+	b.Run("full", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_ = filterVirtualServicesByHosts(ps.VirtualServicesForGateway(proxy, gateway), hosts)
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_ = filterVirtualServicesByHosts(ps.VirtualServicesForGatewayAndHosts(proxy, gateway, hosts), hosts)
+		}
+	})
+}
+
+func TestRunInitSteps(t *testing.T) {
+	defer func(old int) { features.PushContextInitConcurrency = old }(features.PushContextInitConcurrency)
+	features.PushContextInitConcurrency = 2
+
+	var mu sync.Mutex
+	var order []string
+	inflight, maxInflight := 0, 0
+	step := func(phase string, err error, after ...string) initStep {
+		return initStep{
+			phase: phase,
+			after: after,
+			init: func() error {
+				mu.Lock()
+				inflight++
+				if inflight > maxInflight {
+					maxInflight = inflight
+				}
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				inflight--
+				order = append(order, phase)
+				mu.Unlock()
+				return err
+			},
+			fallback: func(*PushContext) {},
+		}
+	}
+
+	phases := &initPhases{ps: NewPushContext(), ran: sets.NewSet()}
+	if err := phases.runSteps([]initStep{
+		step("a", nil),
+		step("b", nil),
+		step("c", nil),
+		step("d", nil),
+		step("last", nil, "a", "b", "c", "d", "copied"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if maxInflight != 2 {
+		t.Errorf("expected 2 phases to be initialized concurrently, got %d", maxInflight)
+	}
+	if len(order) != 5 || order[4] != "last" {
+		t.Errorf("expected the dependent phase to be initialized last, got %v", order)
+	}
+
+	// The dependents of a failed phase are not initialized, and the errors name their phase.
+	order = nil
+	phases = &initPhases{ps: NewPushContext(), ran: sets.NewSet()}
+	err := phases.runSteps([]initStep{
+		step("a", errors.New("bad a")),
+		step("b", errors.New("bad b")),
+		step("c", nil),
+		step("last", nil, "a", "c"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "a: bad a") || !strings.Contains(err.Error(), "b: bad b") {
+		t.Fatalf("expected the errors of a and b, got %v", err)
+	}
+	sort.Strings(order)
+	if !reflect.DeepEqual(order, []string{"a", "b", "c"}) {
+		t.Errorf("expected last not to be initialized, got %v", order)
+	}
+
+	// A step depending on itself is never initialized.
+	phases = &initPhases{ps: NewPushContext(), ran: sets.NewSet()}
+	if err := phases.runSteps([]initStep{step("a", nil, "a")}); err == nil {
+		t.Fatal("expected a dependency cycle to fail")
+	}
+}
+
+// initContextTestEnv returns an environment with n services and configs of all the kinds of the push context.
+func initContextTestEnv(t test.Failer, n int) *Environment {
+	store := NewFakeStore()
+	create := func(kind config.GroupVersionKind, name, namespace string, spec config.Spec) {
+		if _, err := store.Create(config.Config{
+			Meta: config.Meta{GroupVersionKind: kind, Name: name, Namespace: namespace},
+			Spec: spec,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		ns := fmt.Sprintf("ns-%d", i%10)
+		h := fmt.Sprintf("svc-%d.%s.svc.cluster.local", i, ns)
+		name := fmt.Sprintf("cfg-%d", i)
+		create(gvk.VirtualService, name, ns, &networking.VirtualService{
+			Hosts:    []string{h},
+			Gateways: []string{"gateway", "mesh"},
+			Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: h, Subset: "v1"}}},
+			}},
+		})
+		create(gvk.DestinationRule, name, ns, &networking.DestinationRule{
+			Host:    h,
+			Subsets: []*networking.Subset{{Name: "v1", Labels: map[string]string{"version": "v1"}}},
+		})
+		create(gvk.AuthorizationPolicy, name, ns, &securityBeta.AuthorizationPolicy{
+			Selector: &selectorpb.WorkloadSelector{MatchLabels: map[string]string{"app": name}},
+		})
+		create(gvk.PeerAuthentication, name, ns, &securityBeta.PeerAuthentication{
+			Selector: &selectorpb.WorkloadSelector{MatchLabels: map[string]string{"app": name}},
+		})
+		if i%10 == 0 {
+			create(gvk.Gateway, "gateway", ns, &networking.Gateway{
+				Servers: []*networking.Server{{Hosts: []string{"*"}, Port: &networking.Port{Number: 80, Protocol: "HTTP", Name: "http"}}},
+			})
+			create(gvk.EnvoyFilter, "ef", ns, &networking.EnvoyFilter{ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{{
+				ApplyTo: networking.EnvoyFilter_CLUSTER,
+				Patch:   &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_MERGE},
+			}}})
+			create(gvk.Sidecar, "default", ns, &networking.Sidecar{
+				Egress: []*networking.IstioEgressListener{{Hosts: []string{"./*", "istio-system/*"}}},
+			})
+		}
+	}
+	return &Environment{
+		Watcher:          mesh.NewFixedWatcher(&meshconfig.MeshConfig{RootNamespace: "istio-system"}),
+		ServiceDiscovery: serviceAccountsDiscovery(n),
+		IstioConfigStore: &istioConfigStore{ConfigStore: store},
+	}
+}
+
+// sidecarScopesDigest summarizes the sidecar scopes, by namespace: their config, and the services and destination
+// rules they select.
+func sidecarScopesDigest(ps *PushContext) map[string][]string {
+	out := map[string][]string{}
+	for ns, scopes := range ps.sidecarsByNamespace {
+		for _, sc := range scopes {
+			name := "default"
+			if sc.Config != nil {
+				name = sc.Config.Name
+			}
+			var services, rules []string
+			for _, s := range sc.services {
+				services = append(services, string(s.Hostname))
+			}
+			for h := range sc.destinationRules {
+				rules = append(rules, string(h))
+			}
+			sort.Strings(rules)
+			out[ns] = append(out[ns], fmt.Sprintf("%s services=%v rules=%v", name, services, rules))
+		}
+	}
+	return out
+}
+
+func TestParallelInitContext(t *testing.T) {
+	defer func(old int) { features.PushContextInitConcurrency = old }(features.PushContextInitConcurrency)
+	env := initContextTestEnv(t, 100)
+	initContext := func(concurrency int) *PushContext {
+		features.PushContextInitConcurrency = concurrency
+		ps := NewPushContext()
+		if err := ps.InitContext(env, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		return ps
+	}
+	serial, parallel := initContext(1), initContext(8)
+
+	fields := []struct {
+		name    string
+		extract func(ps *PushContext) interface{}
+	}{
+		{"publicServices", func(ps *PushContext) interface{} { return ps.publicServices }},
+		{"privateServicesByNamespace", func(ps *PushContext) interface{} { return ps.privateServicesByNamespace }},
+		{"ServiceByHostname", func(ps *PushContext) interface{} { return ps.ServiceByHostname }},
+		{"ServiceAccounts", func(ps *PushContext) interface{} { return ps.ServiceAccounts }},
+		{"publicVirtualServicesByGateway", func(ps *PushContext) interface{} { return ps.publicVirtualServicesByGateway }},
+		{"namespaceLocalDestRules", func(ps *PushContext) interface{} { return ps.namespaceLocalDestRules }},
+		{"exportedDestRulesByNamespace", func(ps *PushContext) interface{} { return ps.exportedDestRulesByNamespace }},
+		{"AuthnBetaPolicies", func(ps *PushContext) interface{} { return ps.AuthnBetaPolicies }},
+		{"AuthzPolicies", func(ps *PushContext) interface{} { return ps.AuthzPolicies }},
+		{"gatewaysByNamespace", func(ps *PushContext) interface{} { return ps.gatewaysByNamespace }},
+		{"envoyFilters", func(ps *PushContext) interface{} {
+			out := map[string]int{}
+			for ns, efs := range ps.envoyFiltersByNamespace {
+				for _, ef := range efs {
+					out[ns] += len(ef.Patches[networking.EnvoyFilter_CLUSTER])
+				}
+			}
+			return out
+		}},
+		{"sidecarScopes", func(ps *PushContext) interface{} { return sidecarScopesDigest(ps) }},
+		{"ProxyStatus", func(ps *PushContext) interface{} { return ps.ProxyStatus }},
+	}
+	for _, f := range fields {
+		if got, want := f.extract(parallel), f.extract(serial); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s differs from the serial initialization:\ngot  %v\nwant %v", f.name, got, want)
+		}
+	}
+	if len(serial.sidecarsByNamespace) == 0 {
+		t.Errorf("expected sidecar scopes to be initialized")
+	}
+
+	// Incremental updates of several phases are also identical.
+	pushReq := &PushRequest{Full: true, ConfigsUpdated: map[ConfigKey]struct{}{
+		{Kind: gvk.DestinationRule, Name: "cfg-1", Namespace: "ns-1"}:     {},
+		{Kind: gvk.AuthorizationPolicy, Name: "cfg-1", Namespace: "ns-1"}: {},
+		{Kind: gvk.EnvoyFilter, Name: "ef", Namespace: "ns-0"}:            {},
+	}}
+	update := func(old *PushContext, concurrency int) *PushContext {
+		features.PushContextInitConcurrency = concurrency
+		ps := NewPushContext()
+		if err := ps.InitContext(env, old, pushReq); err != nil {
+			t.Fatal(err)
+		}
+		return ps
+	}
+	serial, parallel = update(serial, 1), update(parallel, 8)
+	for _, f := range fields {
+		if got, want := f.extract(parallel), f.extract(serial); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s differs from the serial update:\ngot  %v\nwant %v", f.name, got, want)
+		}
+	}
+}
+
+func BenchmarkInitContext(b *testing.B) {
+	defer func(old int) { features.PushContextInitConcurrency = old }(features.PushContextInitConcurrency)
+	env := initContextTestEnv(b, 1000)
+	for _, concurrency := range []int{1, 8} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			features.PushContextInitConcurrency = concurrency
+			for n := 0; n < b.N; n++ {
+				ps := NewPushContext()
+				if err := ps.InitContext(env, nil, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
 }
@@ -489,6 +489,13 @@ type ServiceDiscovery interface {
 	GetIstioServiceAccounts(svc *Service, ports []int) []string
 }
 
+// StaleRegistryReporter is implemented by the service discoveries reporting the clusters whose registry is stale,
+// so that their endpoints may be out of date.
+type StaleRegistryReporter interface {
+	// StaleClusters returns the clusters whose registry did not sync for longer than PILOT_REGISTRY_STALE_THRESHOLD.
+	StaleClusters() []string
+}
+
 // GetNames returns port names
 func (ports PortList) GetNames() []string {
 	names := make([]string, 0, len(ports))
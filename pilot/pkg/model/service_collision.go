@@ -0,0 +1,186 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"sync"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+// ServiceEntryHostShadowingAnnotation allows the hosts of a ServiceEntry to shadow Kubernetes services, whatever the
+// ServiceEntryHostCollisionPolicy of the mesh extensions.
+const ServiceEntryHostShadowingAnnotation = "networking.istio.io/allowHostShadowing"
+
+// The registries of the services, as in serviceregistry.ProviderID, which can't be imported here.
+const (
+	kubernetesRegistry = "Kubernetes"
+	externalRegistry   = "External"
+)
+
+// Outcomes of a ServiceEntry host colliding with a Kubernetes service.
+const (
+	hostCollisionAllowed             = "allowed"
+	hostCollisionShadowingAllowed    = "shadowing_allowed"
+	hostCollisionRejected            = "rejected"
+	hostCollisionKubernetesPreferred = "kubernetes_preferred"
+)
+
+var (
+	// loggedHostCollisions holds the outcome logged for each current collision, so that it is logged once.
+	loggedHostCollisions   = map[hostCollision]string{}
+	loggedHostCollisionsMu sync.Mutex
+)
+
+// hostCollision is a host of the ServiceEntries of a namespace which is also the host of a Kubernetes service.
+type hostCollision struct {
+	host      host.Name
+	namespace string
+}
+
+// hostCollisionResolution is how the services of the hosts shared by ServiceEntries and Kubernetes services are used.
+type hostCollisionResolution struct {
+	// services are the services to use, in order of precedence.
+	services []*Service
+	// preferred are the Kubernetes services to index by their host instead of the ServiceEntry services of the
+	// namespace colliding with them.
+	preferred map[hostCollision]*Service
+}
+
+// resolveServiceEntryHostCollisions applies the ServiceEntryHostCollisionPolicy of the mesh extensions to the
+// ServiceEntry services whose host is also the host of a Kubernetes service. The services must be sorted by creation
+// time.
+func resolveServiceEntryHostCollisions(env *Environment, services []*Service) hostCollisionResolution {
+	kubeServices := map[host.Name]*Service{}
+	for _, s := range services {
+		if s.Attributes.ServiceRegistry == kubernetesRegistry {
+			if _, f := kubeServices[s.Hostname]; !f {
+				kubeServices[s.Hostname] = s
+			}
+		}
+	}
+	colliding := map[hostCollision]struct{}{}
+	for _, s := range services {
+		if _, f := kubeServices[s.Hostname]; f && s.Attributes.ServiceRegistry == externalRegistry {
+			colliding[hostCollision{s.Hostname, s.Attributes.Namespace}] = struct{}{}
+		}
+	}
+	outcomes := map[hostCollision]string{}
+	defer func() {
+		counts := map[string]int{}
+		for _, outcome := range outcomes {
+			counts[outcome]++
+		}
+		for _, outcome := range []string{hostCollisionAllowed, hostCollisionShadowingAllowed,
+			hostCollisionRejected, hostCollisionKubernetesPreferred} {
+			ServiceEntryHostCollisions.With(hostCollisionOutcomeTag.Value(outcome)).Record(float64(counts[outcome]))
+		}
+	}()
+	policy := mesh.HostCollisionAllow
+	if extensions := env.MeshExtensions(); extensions != nil && extensions.ServiceEntryHostCollisionPolicy != "" {
+		policy = extensions.ServiceEntryHostCollisionPolicy
+	}
+	defer logHostCollisions(outcomes, policy)
+	if len(colliding) == 0 {
+		return hostCollisionResolution{services: services}
+	}
+
+	shadowing := hostShadowingServiceEntries(env)
+	out := hostCollisionResolution{services: make([]*Service, 0, len(services)), preferred: map[hostCollision]*Service{}}
+	rejected := map[hostCollision]struct{}{}
+	for c := range colliding {
+		outcome := hostCollisionAllowed
+		switch {
+		case policy == mesh.HostCollisionAllow:
+		case shadowing[c]:
+			// ServiceEntries annotated to shadow the host are used as with ALLOW.
+			outcome = hostCollisionShadowingAllowed
+		case policy == mesh.HostCollisionPreferKubernetes:
+			outcome = hostCollisionKubernetesPreferred
+			out.preferred[c] = kubeServices[c.host]
+		default:
+			// REJECT, and unknown policies fail closed.
+			outcome = hostCollisionRejected
+			rejected[c] = struct{}{}
+		}
+		outcomes[c] = outcome
+	}
+
+	// The Kubernetes service of a host preferred to the ServiceEntries of a namespace comes before them, so that it
+	// takes precedence. The ServiceEntries of the other namespaces keep their order.
+	added := make(map[*Service]struct{}, len(kubeServices))
+	for _, s := range services {
+		switch s.Attributes.ServiceRegistry {
+		case kubernetesRegistry:
+			if _, f := added[s]; f {
+				continue
+			}
+			added[s] = struct{}{}
+		case externalRegistry:
+			c := hostCollision{s.Hostname, s.Attributes.Namespace}
+			if _, f := rejected[c]; f {
+				continue
+			}
+			if kube, f := out.preferred[c]; f {
+				if _, f := added[kube]; !f {
+					added[kube] = struct{}{}
+					out.services = append(out.services, kube)
+				}
+			}
+		}
+		out.services = append(out.services, s)
+	}
+	return out
+}
+
+// isCollidingServiceEntry returns true if s is a ServiceEntry service of the host and namespace of the collision.
+func isCollidingServiceEntry(s *Service, c hostCollision) bool {
+	return s != nil && s.Attributes.ServiceRegistry == externalRegistry && s.Hostname == c.host && s.Attributes.Namespace == c.namespace
+}
+
+// hostShadowingServiceEntries returns the hosts of the ServiceEntries annotated to shadow Kubernetes services, in
+// the namespace of the ServiceEntries.
+func hostShadowingServiceEntries(env *Environment) map[hostCollision]bool {
+	out := map[hostCollision]bool{}
+	if env.IstioConfigStore == nil {
+		return out
+	}
+	for _, cfg := range env.ServiceEntries() {
+		if cfg.Annotations[ServiceEntryHostShadowingAnnotation] != "true" {
+			continue
+		}
+		for _, h := range cfg.Spec.(*networking.ServiceEntry).Hosts {
+			out[hostCollision{host.Name(h), cfg.Namespace}] = true
+		}
+	}
+	return out
+}
+
+// logHostCollisions logs the outcome of the ServiceEntry hosts colliding with Kubernetes services, once per host and
+// namespace unless the outcome changes. Only the current collisions are remembered.
+func logHostCollisions(outcomes map[hostCollision]string, policy string) {
+	loggedHostCollisionsMu.Lock()
+	defer loggedHostCollisionsMu.Unlock()
+	for c, outcome := range outcomes {
+		if loggedHostCollisions[c] == outcome {
+			continue
+		}
+		log.Warnf("ServiceEntry host %s of namespace %s is also the host of a Kubernetes service, collision %s by policy %s",
+			c.host, c.namespace, outcome, policy)
+	}
+	loggedHostCollisions = outcomes
+}
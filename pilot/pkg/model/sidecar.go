@@ -467,6 +467,13 @@ func (sc *SidecarScope) DependsOnConfig(config ConfigKey) bool {
 		return true
 	}
 
+	// A Sidecar in the root namespace may be the default for every namespace. Otherwise, a Sidecar only affects
+	// the proxies it is selected for; since the scope is selected by the proxy labels, this is the case if
+	// the scope was built from it.
+	if config.Kind == gvk.Sidecar && config.Namespace != sc.RootNamespace {
+		return sc.Config != nil && config.Name == sc.Config.Name && config.Namespace == sc.Config.Namespace
+	}
+
 	// This kind of config will trigger a change if made in the root namespace or the same namespace
 	if _, f := sidecarScopeNamespaceConfigTypes[config.Kind]; f {
 		return config.Namespace == sc.RootNamespace || config.Namespace == sc.Config.Namespace
@@ -0,0 +1,193 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+)
+
+// DefaultSidecarName is reported as the selected Sidecar of a scope not backed by a Sidecar config.
+const DefaultSidecarName = "default"
+
+// SidecarScopeDebug describes the sidecar scope of a workload, as displayed by /debug/sidecarz.
+type SidecarScopeDebug struct {
+	// Sidecar is the namespace/name of the selected Sidecar, or DefaultSidecarName if none applies.
+	Sidecar         string                `json:"sidecar"`
+	EgressListeners []EgressListenerDebug `json:"egressListeners"`
+	// Host explains whether the requested host is imported by the scope.
+	Host *SidecarHostDebug `json:"host,omitempty"`
+}
+
+// EgressListenerDebug describes an egress listener of a sidecar scope.
+type EgressListenerDebug struct {
+	Port uint32 `json:"port,omitempty"`
+	Bind string `json:"bind,omitempty"`
+	// Hosts are the namespace/dnsName entries of the listener, with "." resolved to the config namespace.
+	Hosts []string `json:"hosts"`
+	// Services are the namespace/hostname of the services imported by the listener.
+	Services []string `json:"services"`
+}
+
+// SidecarHostDebug explains whether a host is imported by a sidecar scope.
+type SidecarHostDebug struct {
+	Host     string `json:"host"`
+	Included bool   `json:"included"`
+	// EgressListener is the index of the first egress listener importing the host.
+	EgressListener *int `json:"egressListener,omitempty"`
+	// MatchedEntry is the egress host entry importing the host.
+	MatchedEntry string `json:"matchedEntry,omitempty"`
+	// ClosestEntries are the egress host entries matching either the namespace or the dnsName of a host
+	// which is not imported, or both if the service is filtered out for another reason.
+	ClosestEntries []EgressEntryDebug `json:"closestEntries,omitempty"`
+	Reason         string             `json:"reason"`
+}
+
+// EgressEntryDebug is an egress host entry which does not import a host.
+type EgressEntryDebug struct {
+	EgressListener int    `json:"egressListener"`
+	Entry          string `json:"entry"`
+	Mismatch       string `json:"mismatch"`
+}
+
+// SidecarScopeForWorkload returns the sidecar scope a proxy of a workload with the labels in the namespace gets.
+func (ps *PushContext) SidecarScopeForWorkload(namespace string, workloadLabels labels.Collection) *SidecarScope {
+	return ps.getSidecarScope(&Proxy{ConfigNamespace: namespace}, workloadLabels)
+}
+
+// Debug describes the sidecar scope of proxies in the config namespace and, if the hostname is not empty,
+// explains whether it is imported.
+func (sc *SidecarScope) Debug(ps *PushContext, configNamespace, hostname string) *SidecarScopeDebug {
+	out := &SidecarScopeDebug{
+		Sidecar:         DefaultSidecarName,
+		EgressListeners: make([]EgressListenerDebug, 0, len(sc.EgressListeners)),
+	}
+	if sc.Config != nil && sc.Config.Name != "" {
+		out.Sidecar = sc.Config.Namespace + "/" + sc.Config.Name
+	}
+	for _, ilw := range sc.EgressListeners {
+		l := EgressListenerDebug{
+			Port:     ilw.IstioListener.GetPort().GetNumber(),
+			Bind:     ilw.IstioListener.GetBind(),
+			Hosts:    ilw.hostEntries(),
+			Services: make([]string, 0, len(ilw.services)),
+		}
+		for _, svc := range ilw.services {
+			l.Services = append(l.Services, svc.Attributes.Namespace+"/"+string(svc.Hostname))
+		}
+		sort.Strings(l.Services)
+		out.EgressListeners = append(out.EgressListeners, l)
+	}
+	if hostname != "" {
+		out.Host = sc.explainHost(ps, configNamespace, host.Name(hostname))
+	}
+	return out
+}
+
+func (sc *SidecarScope) explainHost(ps *PushContext, configNamespace string, hostname host.Name) *SidecarHostDebug {
+	out := &SidecarHostDebug{Host: string(hostname)}
+	services := ps.ServiceByHostnameAndNamespace[hostname]
+	if len(services) == 0 {
+		out.Reason = fmt.Sprintf("no service with hostname %s is known to istiod", hostname)
+		return out
+	}
+	namespaces := make([]string, 0, len(services))
+	for ns := range services {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	for i, ilw := range sc.EgressListeners {
+		for _, svc := range ilw.services {
+			if svc.Hostname == hostname {
+				listener := i
+				out.Included = true
+				out.EgressListener = &listener
+				out.MatchedEntry = ilw.matchingEntry(svc)
+				out.Reason = fmt.Sprintf("imported from namespace %s by egress listener %d", svc.Attributes.Namespace, i)
+				return out
+			}
+		}
+	}
+
+	filtered := false
+	for i, ilw := range sc.EgressListeners {
+		for _, ns := range sortedListenerNamespaces(ilw.listenerHosts) {
+			_, nsMatch := services[ns]
+			nsMatch = nsMatch || ns == wildcardNamespace
+			for _, h := range ilw.listenerHosts[ns] {
+				hostMatch := h.Matches(hostname)
+				entry := EgressEntryDebug{EgressListener: i, Entry: ns + "/" + string(h)}
+				switch {
+				case nsMatch && hostMatch:
+					filtered = true
+					entry.Mismatch = fmt.Sprintf("the service is not exported to namespace %s", configNamespace)
+					if port := ilw.IstioListener.GetPort().GetNumber(); port != 0 {
+						entry.Mismatch += fmt.Sprintf(", or has no port %d", port)
+					}
+				case nsMatch:
+					entry.Mismatch = fmt.Sprintf("dnsName %s does not match %s", h, hostname)
+				case hostMatch:
+					entry.Mismatch = fmt.Sprintf("the service is in namespaces %v, not %s", namespaces, ns)
+				default:
+					continue
+				}
+				out.ClosestEntries = append(out.ClosestEntries, entry)
+			}
+		}
+	}
+	if filtered {
+		out.Reason = "egress host entries match the service, but it is filtered out by the listener"
+	} else {
+		out.Reason = fmt.Sprintf("no egress host entry matches the service in namespaces %v", namespaces)
+	}
+	return out
+}
+
+// hostEntries returns the sorted namespace/dnsName host entries of the listener.
+func (ilw *IstioEgressListenerWrapper) hostEntries() []string {
+	out := make([]string, 0, len(ilw.listenerHosts))
+	for ns, hosts := range ilw.listenerHosts {
+		for _, h := range hosts {
+			out = append(out, ns+"/"+string(h))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// matchingEntry returns the host entry importing the service, preferring entries of its namespace.
+func (ilw *IstioEgressListenerWrapper) matchingEntry(svc *Service) string {
+	for _, ns := range []string{svc.Attributes.Namespace, wildcardNamespace} {
+		for _, h := range ilw.listenerHosts[ns] {
+			if h.Matches(svc.Hostname) {
+				return ns + "/" + string(h)
+			}
+		}
+	}
+	return ""
+}
+
+func sortedListenerNamespaces(m map[string][]host.Name) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
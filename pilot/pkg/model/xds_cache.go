@@ -16,10 +16,12 @@ package model
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/hashicorp/golang-lru/simplelru"
+	"go.uber.org/atomic"
 
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/util/sets"
@@ -89,13 +91,38 @@ type XdsCache interface {
 	ClearAll()
 	// Keys returns all currently configured keys. This is for testing/debug only
 	Keys() []string
+	// Snapshot returns up to limit entries of the cache, along with the configs they depend on.
+	// The most recently used entries are returned first.
+	Snapshot(limit int) []XdsCacheSnapshotEntry
+}
+
+// XdsCacheSnapshotEntry is a cached XDS resource, along with the configs it depends on.
+type XdsCacheSnapshotEntry struct {
+	Key              string
+	DependentConfigs []ConfigKey
+	Value            *any.Any
+}
+
+// dependentConfigsByKey inverts a config index into the configs each cache key depends on.
+func dependentConfigsByKey(configIndex map[ConfigKey]sets.Set) map[string][]ConfigKey {
+	out := map[string][]ConfigKey{}
+	for config, keys := range configIndex {
+		for k := range keys {
+			out[k] = append(out[k], config)
+		}
+	}
+	return out
 }
 
 // inMemoryCache is a simple implementation of Cache that uses in memory map.
 type inMemoryCache struct {
 	store       map[string]*any.Any
 	configIndex map[ConfigKey]sets.Set
-	mu          sync.RWMutex
+	// used is the time of the last use of each key, on the clock ticking on every use, so that snapshots return
+	// the most recently used entries first.
+	used  map[string]*atomic.Uint64
+	clock atomic.Uint64
+	mu    sync.RWMutex
 }
 
 // NewXdsCache returns an instance of a cache.
@@ -104,6 +131,7 @@ func NewXdsCache() XdsCache {
 		return &inMemoryCache{
 			store:       map[string]*any.Any{},
 			configIndex: map[ConfigKey]sets.Set{},
+			used:        map[string]*atomic.Uint64{},
 		}
 	}
 	return &lruCache{
@@ -120,6 +148,10 @@ func (c *inMemoryCache) Add(entry XdsCacheEntry, value *any.Any) {
 	defer c.mu.Unlock()
 	k := entry.Key()
 	c.store[k] = value
+	if c.used[k] == nil {
+		c.used[k] = atomic.NewUint64(0)
+	}
+	c.used[k].Store(c.clock.Inc())
 	indexConfig(c.configIndex, k, entry)
 }
 
@@ -131,6 +163,7 @@ func (c *inMemoryCache) Get(entry XdsCacheEntry) (*any.Any, bool) {
 	defer c.mu.RUnlock()
 	k, f := c.store[entry.Key()]
 	if f {
+		c.used[entry.Key()].Store(c.clock.Inc())
 		hit()
 	} else {
 		miss()
@@ -146,6 +179,7 @@ func (c *inMemoryCache) Clear(configs map[ConfigKey]struct{}) {
 		delete(c.configIndex, ckey)
 		for keys := range referenced {
 			delete(c.store, keys)
+			delete(c.used, keys)
 		}
 	}
 }
@@ -155,6 +189,7 @@ func (c *inMemoryCache) ClearAll() {
 	defer c.mu.Unlock()
 	c.store = map[string]*any.Any{}
 	c.configIndex = map[ConfigKey]sets.Set{}
+	c.used = map[string]*atomic.Uint64{}
 }
 
 func (c *inMemoryCache) Keys() []string {
@@ -167,6 +202,30 @@ func (c *inMemoryCache) Keys() []string {
 	return keys
 }
 
+func (c *inMemoryCache) Snapshot(limit int) []XdsCacheSnapshotEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	configs := dependentConfigsByKey(c.configIndex)
+	keys := make([]string, 0, len(c.store))
+	for k := range c.store {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.used[keys[i]].Load() > c.used[keys[j]].Load()
+	})
+	if limit < 0 {
+		limit = 0
+	}
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	out := make([]XdsCacheSnapshotEntry, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, XdsCacheSnapshotEntry{Key: k, DependentConfigs: configs[k], Value: c.store[k]})
+	}
+	return out
+}
+
 type lruCache struct {
 	store simplelru.LRUCache
 
@@ -240,6 +299,23 @@ func (l *lruCache) Keys() []string {
 	return keys
 }
 
+func (l *lruCache) Snapshot(limit int) []XdsCacheSnapshotEntry {
+	// The store is only peeked, so that taking a snapshot does not change the recency of entries.
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	configs := dependentConfigsByKey(l.configIndex)
+	// Keys are ordered from the oldest to the newest.
+	iKeys := l.store.Keys()
+	out := make([]XdsCacheSnapshotEntry, 0, len(iKeys))
+	for i := len(iKeys) - 1; i >= 0 && len(out) < limit; i-- {
+		k := iKeys[i].(string)
+		if v, ok := l.store.Peek(k); ok {
+			out = append(out, XdsCacheSnapshotEntry{Key: k, DependentConfigs: configs[k], Value: v.(*any.Any)})
+		}
+	}
+	return out
+}
+
 // DisabledCache is a cache that is always empty
 type DisabledCache struct{}
 
@@ -256,3 +332,5 @@ func (d DisabledCache) Clear(configsUpdated map[ConfigKey]struct{}) {}
 func (d DisabledCache) ClearAll() {}
 
 func (d DisabledCache) Keys() []string { return nil }
+
+func (d DisabledCache) Snapshot(int) []XdsCacheSnapshotEntry { return nil }
@@ -31,10 +31,12 @@ type ConfigGenerator interface {
 	// BuildListeners returns the list of inbound/outbound listeners for the given proxy. This is the LDS output
 	// Internally, the computation will be optimized to ensure that listeners are computed only
 	// once and shared across multiple invocations of this function.
-	BuildListeners(node *model.Proxy, push *model.PushContext) []*listener.Listener
+	// If ctx is not nil, temporary buffers are taken from it and the result is only valid until it is released.
+	BuildListeners(node *model.Proxy, push *model.PushContext, ctx *model.GenerationContext) []*listener.Listener
 
 	// BuildClusters returns the list of clusters for the given proxy. This is the CDS output
-	BuildClusters(node *model.Proxy, push *model.PushContext) []*cluster.Cluster
+	// If ctx is not nil, temporary buffers are taken from it.
+	BuildClusters(node *model.Proxy, push *model.PushContext, ctx *model.GenerationContext) []*cluster.Cluster
 
 	// BuildHTTPRoutes returns the list of HTTP routes for the given proxy. This is the RDS output
 	BuildHTTPRoutes(node *model.Proxy, push *model.PushContext, routeNames []string) []*route.RouteConfiguration
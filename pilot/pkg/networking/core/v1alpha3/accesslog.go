@@ -16,7 +16,13 @@ package v1alpha3
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -24,11 +30,14 @@ import (
 	grpcaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	structpb "github.com/golang/protobuf/ptypes/struct"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/pkg/log"
 )
 
@@ -53,6 +62,11 @@ const (
 	// EnvoyAccessLogCluster is the cluster name that has details for server implementing Envoy ALS.
 	// This cluster is created in bootstrap.
 	EnvoyAccessLogCluster = "envoy_accesslog_service"
+
+	// accessLogFormatExtensionPrefix marks a mesh accessLogFormat as an extension of the default format rather
+	// than a replacement. For TEXT encoding, the rest of the format is appended to EnvoyTextLogFormat. For JSON
+	// encoding, the rest of the format is a JSON object whose fields are added to EnvoyJSONLogFormat.
+	accessLogFormatExtensionPrefix = "+"
 )
 
 var (
@@ -93,6 +107,60 @@ var (
 
 	// accessLogBuilder is used to set accessLog to filters
 	accessLogBuilder = newAccessLogBuilder()
+
+	// invalidAccessLogSamplings holds, per proxy ID, the last invalid access log sampling warned about, so an
+	// invalid annotation is logged once per proxy rather than on every listener build.
+	invalidAccessLogSamplings sync.Map
+
+	accessLogOperatorRegex = regexp.MustCompile(`^([A-Z_0-9]+)(\([^()]*\))?(:[0-9]+)?$`)
+
+	// accessLogOperators are the Envoy command operators accepted in access log format extensions, mapped to
+	// whether they require a parameter, e.g. %REQ(X-REQUEST-ID)%.
+	accessLogOperators = map[string]bool{
+		"START_TIME":                             false,
+		"PROTOCOL":                               false,
+		"RESPONSE_CODE":                          false,
+		"RESPONSE_CODE_DETAILS":                  false,
+		"RESPONSE_FLAGS":                         false,
+		"BYTES_RECEIVED":                         false,
+		"BYTES_SENT":                             false,
+		"DURATION":                               false,
+		"REQUEST_DURATION":                       false,
+		"RESPONSE_DURATION":                      false,
+		"RESPONSE_TX_DURATION":                   false,
+		"ROUTE_NAME":                             false,
+		"UPSTREAM_HOST":                          false,
+		"UPSTREAM_CLUSTER":                       false,
+		"UPSTREAM_LOCAL_ADDRESS":                 false,
+		"UPSTREAM_TRANSPORT_FAILURE_REASON":      false,
+		"DOWNSTREAM_REMOTE_ADDRESS":              false,
+		"DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT": false,
+		"DOWNSTREAM_DIRECT_REMOTE_ADDRESS":       false,
+		"DOWNSTREAM_DIRECT_REMOTE_ADDRESS_WITHOUT_PORT": false,
+		"DOWNSTREAM_LOCAL_ADDRESS":                      false,
+		"DOWNSTREAM_LOCAL_ADDRESS_WITHOUT_PORT":         false,
+		"DOWNSTREAM_LOCAL_PORT":                         false,
+		"DOWNSTREAM_LOCAL_URI_SAN":                      false,
+		"DOWNSTREAM_PEER_URI_SAN":                       false,
+		"DOWNSTREAM_LOCAL_SUBJECT":                      false,
+		"DOWNSTREAM_PEER_SUBJECT":                       false,
+		"DOWNSTREAM_PEER_ISSUER":                        false,
+		"DOWNSTREAM_TLS_SESSION_ID":                     false,
+		"DOWNSTREAM_TLS_CIPHER":                         false,
+		"DOWNSTREAM_TLS_VERSION":                        false,
+		"DOWNSTREAM_PEER_FINGERPRINT_256":               false,
+		"DOWNSTREAM_PEER_SERIAL":                        false,
+		"CONNECTION_ID":                                 false,
+		"GRPC_STATUS":                                   false,
+		"REQUESTED_SERVER_NAME":                         false,
+		"HOSTNAME":                                      false,
+		"LOCAL_REPLY_BODY":                              false,
+		"REQ":                                           true,
+		"RESP":                                          true,
+		"TRAILER":                                       true,
+		"DYNAMIC_METADATA":                              true,
+		"FILTER_STATE":                                  true,
+	}
 )
 
 type AccessLogBuilder struct {
@@ -104,6 +172,90 @@ type AccessLogBuilder struct {
 	// file accessLog
 	mutex               sync.RWMutex
 	cachedFileAccessLog *accesslog.AccessLog
+	// cachedFilteredAccessLogs holds copies of the file and gRPC access logs with a filter attached.
+	cachedFilteredAccessLogs map[filteredAccessLogKey]*accesslog.AccessLog
+}
+
+// accessLogFilter holds the conditions under which an access log entry is emitted. An entry is emitted if
+// any of the set conditions match; if none are set, every entry is emitted.
+type accessLogFilter struct {
+	// minStatusCode, if non zero, matches HTTP responses with at least this status code.
+	minStatusCode uint32
+	// responseFlags matches requests and connections with any response flag set.
+	responseFlags bool
+	// minDuration, if non zero, matches requests and connections lasting at least this long.
+	minDuration time.Duration
+	// sampling applies on top of the conditions.
+	sampling accessLogSampling
+}
+
+// accessLogSampling is the share of the access log entries emitted. The zero value emits every entry.
+type accessLogSampling struct {
+	sampled bool
+	// perMillion is the number of entries emitted per million, if sampled.
+	perMillion uint32
+}
+
+// inboundAccessLogSampling returns the sampling of the access logs of the inbound listener of the node on port.
+// The sidecar.istio.io/accessLogSampling annotation holds the percentage of the entries emitted, e.g. "10", and
+// per port percentages overriding it, e.g. "10,8080:50" emits 50% of the entries on port 8080 and 10% on the
+// other ports. An invalid annotation is ignored, with a warning logged once per proxy.
+func inboundAccessLogSampling(node *model.Proxy, port int) accessLogSampling {
+	if node == nil || node.Metadata == nil || node.Metadata.AccessLogSampling == "" {
+		return accessLogSampling{}
+	}
+	sampling, err := parseAccessLogSampling(node.Metadata.AccessLogSampling, port)
+	if err != nil {
+		if warned, ok := invalidAccessLogSamplings.Load(node.ID); !ok || warned != node.Metadata.AccessLogSampling {
+			invalidAccessLogSamplings.Store(node.ID, node.Metadata.AccessLogSampling)
+			log.Warnf("ignoring invalid access log sampling %q of proxy %s: %v", node.Metadata.AccessLogSampling, node.ID, err)
+		}
+		return accessLogSampling{}
+	}
+	return sampling
+}
+
+// parseAccessLogSampling returns the sampling of port in the sidecar.istio.io/accessLogSampling annotation value.
+func parseAccessLogSampling(value string, port int) (accessLogSampling, error) {
+	var sampling accessLogSampling
+	var portSampling *accessLogSampling
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		percent := entry
+		entryPort := 0
+		if i := strings.IndexByte(entry, ':'); i >= 0 {
+			p, err := strconv.Atoi(entry[:i])
+			if err != nil || p <= 0 || p > 65535 {
+				return accessLogSampling{}, fmt.Errorf("invalid port %q", entry[:i])
+			}
+			entryPort, percent = p, entry[i+1:]
+		}
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || math.IsNaN(p) || p < 0 || p > 100 {
+			return accessLogSampling{}, fmt.Errorf("invalid percentage %q", percent)
+		}
+		s := accessLogSampling{}
+		// Emitting every entry requires no sampling.
+		if perMillion := uint32(math.Round(p * 10000)); perMillion < 1000000 {
+			s = accessLogSampling{sampled: true, perMillion: perMillion}
+		}
+		switch entryPort {
+		case 0:
+			sampling = s
+		case port:
+			portSampling = &s
+		}
+	}
+	if portSampling != nil {
+		return *portSampling, nil
+	}
+	return sampling, nil
+}
+
+type filteredAccessLogKey struct {
+	accessLog *accesslog.AccessLog
+	filter    accessLogFilter
+	tcp       bool
 }
 
 func newAccessLogBuilder() *AccessLogBuilder {
@@ -113,23 +265,157 @@ func newAccessLogBuilder() *AccessLogBuilder {
 	}
 }
 
-func (b *AccessLogBuilder) setTCPAccessLog(mesh *meshconfig.MeshConfig, config *tcp.TcpProxy) {
-	if mesh.AccessLogFile != "" {
-		config.AccessLog = append(config.AccessLog, b.buildFileAccessLog(mesh))
+// meshAccessLogFilter returns the access log filter of the mesh extensions.
+func meshAccessLogFilter(extensions *mesh.MeshExtensions) accessLogFilter {
+	if extensions == nil || extensions.AccessLogFilter == nil {
+		return accessLogFilter{}
+	}
+	f := extensions.AccessLogFilter
+	return accessLogFilter{
+		minStatusCode: uint32(f.MinStatusCode),
+		responseFlags: f.ResponseFlags,
+		minDuration:   f.MinDuration.Duration,
+	}
+}
+
+func (b *AccessLogBuilder) setTCPAccessLog(push *model.PushContext, config *tcp.TcpProxy, sampling accessLogSampling) {
+	filter := meshAccessLogFilter(push.MeshExtensions)
+	filter.sampling = sampling
+	if push.Mesh.AccessLogFile != "" {
+		config.AccessLog = append(config.AccessLog, b.withFilter(b.buildFileAccessLog(push.Mesh), filter, true))
 	}
 
-	if mesh.EnableEnvoyAccessLogService {
-		config.AccessLog = append(config.AccessLog, b.tcpGrpcAccessLog)
+	if push.Mesh.EnableEnvoyAccessLogService {
+		config.AccessLog = append(config.AccessLog, b.withFilter(b.tcpGrpcAccessLog, filter, true))
 	}
 }
 
-func (b *AccessLogBuilder) setHTTPAccessLog(mesh *meshconfig.MeshConfig, connectionManager *hcm.HttpConnectionManager) {
-	if mesh.AccessLogFile != "" {
-		connectionManager.AccessLog = append(connectionManager.AccessLog, b.buildFileAccessLog(mesh))
+func (b *AccessLogBuilder) setHTTPAccessLog(push *model.PushContext, connectionManager *hcm.HttpConnectionManager,
+	sampling accessLogSampling) {
+	filter := meshAccessLogFilter(push.MeshExtensions)
+	filter.sampling = sampling
+	if push.Mesh.AccessLogFile != "" {
+		connectionManager.AccessLog = append(connectionManager.AccessLog, b.withFilter(b.buildFileAccessLog(push.Mesh), filter, false))
+	}
+
+	if push.Mesh.EnableEnvoyAccessLogService {
+		connectionManager.AccessLog = append(connectionManager.AccessLog, b.withFilter(b.httpGrpcAccessLog, filter, false))
+	}
+}
+
+// withFilter returns a copy of al with the filter attached, or al itself if the filter has no conditions
+// applicable to the protocol. Copies are cached per access log, filter and protocol, so changing the filter
+// takes effect on the next push.
+func (b *AccessLogBuilder) withFilter(al *accesslog.AccessLog, filter accessLogFilter, tcp bool) *accesslog.AccessLog {
+	key := filteredAccessLogKey{accessLog: al, filter: filter, tcp: tcp}
+	b.mutex.RLock()
+	cached := b.cachedFilteredAccessLogs[key]
+	b.mutex.RUnlock()
+	if cached != nil {
+		return cached
+	}
+
+	alf := buildAccessLogFilter(filter, tcp)
+	if alf == nil {
+		return al
+	}
+	filtered := &accesslog.AccessLog{
+		Name:       al.Name,
+		Filter:     alf,
+		ConfigType: al.ConfigType,
 	}
 
-	if mesh.EnableEnvoyAccessLogService {
-		connectionManager.AccessLog = append(connectionManager.AccessLog, b.httpGrpcAccessLog)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.cachedFilteredAccessLogs == nil {
+		b.cachedFilteredAccessLogs = map[filteredAccessLogKey]*accesslog.AccessLog{}
+	}
+	b.cachedFilteredAccessLogs[key] = filtered
+	return filtered
+}
+
+// buildAccessLogFilter returns the Envoy filter matching any of the conditions of filter, sampled if required.
+// Status codes are not applicable to TCP, so minStatusCode is ignored for it.
+func buildAccessLogFilter(filter accessLogFilter, tcp bool) *accesslog.AccessLogFilter {
+	conditions := buildAccessLogConditions(filter, tcp)
+	if !filter.sampling.sampled {
+		return conditions
+	}
+	sampling := &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_RuntimeFilter{
+			RuntimeFilter: &accesslog.RuntimeFilter{
+				RuntimeKey: "access_log.inbound_sampling",
+				PercentSampled: &xdstype.FractionalPercent{
+					Numerator:   filter.sampling.perMillion,
+					Denominator: xdstype.FractionalPercent_MILLION,
+				},
+			},
+		},
+	}
+	if conditions == nil {
+		return sampling
+	}
+	return &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_AndFilter{
+			AndFilter: &accesslog.AndFilter{Filters: []*accesslog.AccessLogFilter{sampling, conditions}},
+		},
+	}
+}
+
+// buildAccessLogConditions returns the Envoy filter matching any of the conditions of filter, or nil if there is
+// none.
+func buildAccessLogConditions(filter accessLogFilter, tcp bool) *accesslog.AccessLogFilter {
+	var filters []*accesslog.AccessLogFilter
+	if filter.minStatusCode > 0 && !tcp {
+		filters = append(filters, &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_StatusCodeFilter{
+				StatusCodeFilter: &accesslog.StatusCodeFilter{
+					Comparison: &accesslog.ComparisonFilter{
+						Op: accesslog.ComparisonFilter_GE,
+						Value: &core.RuntimeUInt32{
+							DefaultValue: filter.minStatusCode,
+							RuntimeKey:   "access_log.min_status_code",
+						},
+					},
+				},
+			},
+		})
+	}
+	if filter.responseFlags {
+		filters = append(filters, &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_ResponseFlagFilter{
+				// No flags matches any response flag.
+				ResponseFlagFilter: &accesslog.ResponseFlagFilter{},
+			},
+		})
+	}
+	if filter.minDuration > 0 {
+		filters = append(filters, &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_DurationFilter{
+				DurationFilter: &accesslog.DurationFilter{
+					Comparison: &accesslog.ComparisonFilter{
+						Op: accesslog.ComparisonFilter_GE,
+						Value: &core.RuntimeUInt32{
+							DefaultValue: uint32(filter.minDuration.Milliseconds()),
+							RuntimeKey:   "access_log.min_duration",
+						},
+					},
+				},
+			},
+		})
+	}
+
+	switch len(filters) {
+	case 0:
+		return nil
+	case 1:
+		return filters[0]
+	default:
+		return &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_OrFilter{
+				OrFilter: &accesslog.OrFilter{Filters: filters},
+			},
+		}
 	}
 }
 
@@ -144,10 +430,17 @@ func (b *AccessLogBuilder) buildFileAccessLog(mesh *meshconfig.MeshConfig) *acce
 		Path: mesh.AccessLogFile,
 	}
 
+	extension, extended := accessLogFormatExtension(mesh.AccessLogFormat)
 	switch mesh.AccessLogEncoding {
 	case meshconfig.MeshConfig_TEXT:
 		formatString := EnvoyTextLogFormat
-		if mesh.AccessLogFormat != "" {
+		if extended {
+			if err := validateAccessLogOperators(extension); err == nil {
+				formatString = strings.TrimSuffix(EnvoyTextLogFormat, "\n") + " " + extension + "\n"
+			} else {
+				log.Errorf("error parsing provided text log format extension, default log format will be used: %v", err)
+			}
+		} else if mesh.AccessLogFormat != "" {
 			formatString = mesh.AccessLogFormat
 		}
 		fl.AccessLogFormat = &fileaccesslog.FileAccessLog_Format{
@@ -155,7 +448,12 @@ func (b *AccessLogBuilder) buildFileAccessLog(mesh *meshconfig.MeshConfig) *acce
 		}
 	case meshconfig.MeshConfig_JSON:
 		var jsonLog *structpb.Struct
-		if mesh.AccessLogFormat != "" {
+		if extended {
+			var err error
+			if jsonLog, err = extendJSONLogFormat(extension); err != nil {
+				log.Errorf("error parsing provided json log format extension, default log format will be used: %v", err)
+			}
+		} else if mesh.AccessLogFormat != "" {
 			jsonFields := map[string]string{}
 			err := json.Unmarshal([]byte(mesh.AccessLogFormat), &jsonFields)
 			if err == nil {
@@ -191,6 +489,68 @@ func (b *AccessLogBuilder) buildFileAccessLog(mesh *meshconfig.MeshConfig) *acce
 	return al
 }
 
+// accessLogFormatExtension returns the extension to the default format, if format is one.
+func accessLogFormatExtension(format string) (string, bool) {
+	if !strings.HasPrefix(format, accessLogFormatExtensionPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(format, accessLogFormatExtensionPrefix)), true
+}
+
+// extendJSONLogFormat returns EnvoyJSONLogFormat with the fields of the JSON object extension added.
+// Fields in the extension take precedence over default fields with the same name.
+func extendJSONLogFormat(extension string) (*structpb.Struct, error) {
+	jsonFields := map[string]string{}
+	if err := json.Unmarshal([]byte(extension), &jsonFields); err != nil {
+		return nil, err
+	}
+	jsonLog := &structpb.Struct{
+		Fields: make(map[string]*structpb.Value, len(EnvoyJSONLogFormat.Fields)+len(jsonFields)),
+	}
+	for key, value := range EnvoyJSONLogFormat.Fields {
+		jsonLog.Fields[key] = value
+	}
+	for key, value := range jsonFields {
+		if err := validateAccessLogOperators(value); err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+		jsonLog.Fields[key] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: value}}
+	}
+	return jsonLog, nil
+}
+
+// validateAccessLogOperators checks that every %OPERATOR% in format is a known Envoy command operator. As in Envoy,
+// "%%" outside of an operator is an escaped '%', so that "%START_TIME%%PROTOCOL%" still holds two operators.
+func validateAccessLogOperators(format string) error {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			i++
+			continue
+		}
+		end := strings.IndexByte(format[i+1:], '%')
+		if end < 0 {
+			return fmt.Errorf("unterminated command operator in %q", format)
+		}
+		operator := format[i+1 : i+1+end]
+		i += end + 1
+		match := accessLogOperatorRegex.FindStringSubmatch(operator)
+		if match == nil {
+			return fmt.Errorf("invalid command operator %%%s%%", operator)
+		}
+		needsParam, f := accessLogOperators[match[1]]
+		if !f {
+			return fmt.Errorf("unknown command operator %%%s%%", operator)
+		}
+		if needsParam && len(match[2]) <= 2 {
+			return fmt.Errorf("command operator %%%s%% requires a parameter", operator)
+		}
+	}
+	return nil
+}
+
 func (b *AccessLogBuilder) getCachedFileAccessLog() *accesslog.AccessLog {
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
@@ -242,5 +602,6 @@ func buildHTTPGrpcAccessLog() *accesslog.AccessLog {
 func (b *AccessLogBuilder) reset() {
 	b.mutex.Lock()
 	b.cachedFileAccessLog = nil
+	b.cachedFilteredAccessLogs = nil
 	b.mutex.Unlock()
 }
@@ -0,0 +1,422 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	fileaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func buildTestFileAccessLog(t *testing.T, mesh *meshconfig.MeshConfig) *fileaccesslog.FileAccessLog {
+	t.Helper()
+	b := newAccessLogBuilder()
+	al := b.buildFileAccessLog(mesh)
+	if cached := b.buildFileAccessLog(mesh); cached != al {
+		t.Fatalf("expected cached access log to be reused")
+	}
+	fl := &fileaccesslog.FileAccessLog{}
+	if err := ptypes.UnmarshalAny(al.GetTypedConfig(), fl); err != nil {
+		t.Fatal(err)
+	}
+	return fl
+}
+
+func TestFileAccessLogJSONExtension(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		extra  map[string]string
+	}{
+		{
+			name:   "filter state and dynamic metadata",
+			format: `+{"peer": "%FILTER_STATE(istio.peer_principal)%", "authz": "%DYNAMIC_METADATA(envoy.filters.http.rbac:shadow_engine_result)%"}`,
+			extra: map[string]string{
+				"peer":  "%FILTER_STATE(istio.peer_principal)%",
+				"authz": "%DYNAMIC_METADATA(envoy.filters.http.rbac:shadow_engine_result)%",
+			},
+		},
+		{
+			name:   "unknown operator",
+			format: `+{"peer": "%NOT_AN_OPERATOR%"}`,
+		},
+		{
+			name:   "missing parameter",
+			format: `+{"peer": "%FILTER_STATE%"}`,
+		},
+		{
+			name:   "unterminated operator",
+			format: `+{"peer": "%FILTER_STATE(istio.peer_principal)"}`,
+		},
+		{
+			name:   "invalid json",
+			format: `+{"peer"`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			fl := buildTestFileAccessLog(t, &meshconfig.MeshConfig{
+				AccessLogFile:     "/dev/stdout",
+				AccessLogEncoding: meshconfig.MeshConfig_JSON,
+				AccessLogFormat:   tt.format,
+			})
+			fields := fl.GetJsonFormat().GetFields()
+			if len(fields) != len(EnvoyJSONLogFormat.Fields)+len(tt.extra) {
+				t.Fatalf("expected %d fields, got %d", len(EnvoyJSONLogFormat.Fields)+len(tt.extra), len(fields))
+			}
+			for key := range EnvoyJSONLogFormat.Fields {
+				if _, f := fields[key]; !f {
+					t.Errorf("missing default field %q", key)
+				}
+			}
+			for key, value := range tt.extra {
+				if got := fields[key].GetStringValue(); got != value {
+					t.Errorf("field %q: got %q, want %q", key, got, value)
+				}
+			}
+		})
+	}
+	if _, f := EnvoyJSONLogFormat.Fields["peer"]; f {
+		t.Fatalf("default json log format was modified")
+	}
+}
+
+func TestFileAccessLogTextExtension(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "filter state",
+			format: "+ %FILTER_STATE(istio.peer_principal)%",
+			want:   strings.TrimSuffix(EnvoyTextLogFormat, "\n") + " %FILTER_STATE(istio.peer_principal)%\n",
+		},
+		{
+			name:   "escaped percent",
+			format: "+ 100%% %FILTER_STATE(istio.peer_principal)%%PROTOCOL%",
+			want:   strings.TrimSuffix(EnvoyTextLogFormat, "\n") + " 100%% %FILTER_STATE(istio.peer_principal)%%PROTOCOL%\n",
+		},
+		{
+			name:   "invalid operator",
+			format: "+ %FILTER_STATE()%",
+			want:   EnvoyTextLogFormat,
+		},
+		{
+			name:   "replacement",
+			format: "%FILTER_STATE(istio.peer_principal)%",
+			want:   "%FILTER_STATE(istio.peer_principal)%",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			fl := buildTestFileAccessLog(t, &meshconfig.MeshConfig{
+				AccessLogFile:     "/dev/stdout",
+				AccessLogEncoding: meshconfig.MeshConfig_TEXT,
+				AccessLogFormat:   tt.format,
+			})
+			if got := fl.GetFormat(); got != tt.want {
+				t.Fatalf("got format %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// accessLogFilterPush returns a push context with the mesh config m, and an access log filter in the mesh
+// extensions if any condition is set.
+func accessLogFilterPush(m *meshconfig.MeshConfig, minStatusCode int, responseFlags bool, minDuration time.Duration) *model.PushContext {
+	push := &model.PushContext{Mesh: m}
+	if minStatusCode > 0 || responseFlags || minDuration > 0 {
+		push.MeshExtensions = &mesh.MeshExtensions{
+			AccessLogFilter: &mesh.AccessLogFilter{
+				MinStatusCode: minStatusCode,
+				ResponseFlags: responseFlags,
+				MinDuration:   metav1.Duration{Duration: minDuration},
+			},
+		}
+	}
+	return push
+}
+
+func TestAccessLogFilter(t *testing.T) {
+	statusCodeFilter := &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_StatusCodeFilter{
+			StatusCodeFilter: &accesslog.StatusCodeFilter{
+				Comparison: &accesslog.ComparisonFilter{
+					Op:    accesslog.ComparisonFilter_GE,
+					Value: &core.RuntimeUInt32{DefaultValue: 500, RuntimeKey: "access_log.min_status_code"},
+				},
+			},
+		},
+	}
+	responseFlagFilter := &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_ResponseFlagFilter{
+			ResponseFlagFilter: &accesslog.ResponseFlagFilter{},
+		},
+	}
+	durationFilter := &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_DurationFilter{
+			DurationFilter: &accesslog.DurationFilter{
+				Comparison: &accesslog.ComparisonFilter{
+					Op:    accesslog.ComparisonFilter_GE,
+					Value: &core.RuntimeUInt32{DefaultValue: 2000, RuntimeKey: "access_log.min_duration"},
+				},
+			},
+		},
+	}
+	or := func(filters ...*accesslog.AccessLogFilter) *accesslog.AccessLogFilter {
+		return &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_OrFilter{OrFilter: &accesslog.OrFilter{Filters: filters}},
+		}
+	}
+
+	cases := []struct {
+		name          string
+		minStatusCode int
+		responseFlags bool
+		minDuration   time.Duration
+		http          *accesslog.AccessLogFilter
+		tcp           *accesslog.AccessLogFilter
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:          "status code",
+			minStatusCode: 500,
+			http:          statusCodeFilter,
+		},
+		{
+			name:          "response flags",
+			responseFlags: true,
+			http:          responseFlagFilter,
+			tcp:           responseFlagFilter,
+		},
+		{
+			name:          "status code or response flags",
+			minStatusCode: 500,
+			responseFlags: true,
+			http:          or(statusCodeFilter, responseFlagFilter),
+			tcp:           responseFlagFilter,
+		},
+		{
+			name:          "all",
+			minStatusCode: 500,
+			responseFlags: true,
+			minDuration:   2 * time.Second,
+			http:          or(statusCodeFilter, responseFlagFilter, durationFilter),
+			tcp:           or(responseFlagFilter, durationFilter),
+		},
+	}
+	mesh := &meshconfig.MeshConfig{
+		AccessLogFile:               "/dev/stdout",
+		EnableEnvoyAccessLogService: true,
+	}
+	b := newAccessLogBuilder()
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			push := accessLogFilterPush(mesh, tt.minStatusCode, tt.responseFlags, tt.minDuration)
+
+			connectionManager := &hcm.HttpConnectionManager{}
+			b.setHTTPAccessLog(push, connectionManager, accessLogSampling{})
+			tcpProxy := &tcp.TcpProxy{}
+			b.setTCPAccessLog(push, tcpProxy, accessLogSampling{})
+			if len(connectionManager.AccessLog) != 2 || len(tcpProxy.AccessLog) != 2 {
+				t.Fatalf("expected file and gRPC access logs, got %v and %v", connectionManager.AccessLog, tcpProxy.AccessLog)
+			}
+			for i := range connectionManager.AccessLog {
+				if got := connectionManager.AccessLog[i].Filter; !proto.Equal(got, tt.http) {
+					t.Errorf("http access log %v: got filter %v, want %v", connectionManager.AccessLog[i].Name, got, tt.http)
+				}
+				if got := tcpProxy.AccessLog[i].Filter; !proto.Equal(got, tt.tcp) {
+					t.Errorf("tcp access log %v: got filter %v, want %v", tcpProxy.AccessLog[i].Name, got, tt.tcp)
+				}
+			}
+
+			again := &hcm.HttpConnectionManager{}
+			b.setHTTPAccessLog(push, again, accessLogSampling{})
+			for i := range again.AccessLog {
+				if again.AccessLog[i] != connectionManager.AccessLog[i] {
+					t.Errorf("expected cached access log %v to be reused", again.AccessLog[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestInboundAccessLogSampling(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		port       int
+		want       accessLogSampling
+	}{
+		{
+			name: "no annotation",
+			port: 8080,
+		},
+		{
+			name:       "workload",
+			annotation: "10",
+			port:       8080,
+			want:       accessLogSampling{sampled: true, perMillion: 100000},
+		},
+		{
+			name:       "fraction",
+			annotation: "0.5",
+			port:       8080,
+			want:       accessLogSampling{sampled: true, perMillion: 5000},
+		},
+		{
+			name:       "port override",
+			annotation: "10, 8080:50",
+			port:       8080,
+			want:       accessLogSampling{sampled: true, perMillion: 500000},
+		},
+		{
+			name:       "other port",
+			annotation: "8080:50,10",
+			port:       9090,
+			want:       accessLogSampling{sampled: true, perMillion: 100000},
+		},
+		{
+			name:       "port only",
+			annotation: "8080:50",
+			port:       9090,
+		},
+		{
+			name:       "port without sampling",
+			annotation: "10,8080:100",
+			port:       8080,
+		},
+		{
+			name:       "none",
+			annotation: "0",
+			port:       8080,
+			want:       accessLogSampling{sampled: true},
+		},
+		{
+			name:       "invalid percentage",
+			annotation: "10%",
+			port:       8080,
+		},
+		{
+			name:       "out of range",
+			annotation: "150",
+			port:       8080,
+		},
+		{
+			name:       "invalid port",
+			annotation: "10,http:50",
+			port:       8080,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &model.Proxy{Metadata: &model.NodeMetadata{AccessLogSampling: tt.annotation}}
+			if got := inboundAccessLogSampling(node, tt.port); got != tt.want {
+				t.Errorf("got sampling %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessLogSamplingFilter(t *testing.T) {
+	sampling10 := &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_RuntimeFilter{
+			RuntimeFilter: &accesslog.RuntimeFilter{
+				RuntimeKey: "access_log.inbound_sampling",
+				PercentSampled: &xdstype.FractionalPercent{
+					Numerator:   100000,
+					Denominator: xdstype.FractionalPercent_MILLION,
+				},
+			},
+		},
+	}
+	mesh := &meshconfig.MeshConfig{AccessLogFile: "/dev/stdout"}
+	b := newAccessLogBuilder()
+	push := accessLogFilterPush(mesh, 0, false, 0)
+
+	node := &model.Proxy{Metadata: &model.NodeMetadata{AccessLogSampling: "10"}}
+	sampled := &hcm.HttpConnectionManager{}
+	b.setHTTPAccessLog(push, sampled, inboundAccessLogSampling(node, 8080))
+	if len(sampled.AccessLog) != 1 || !proto.Equal(sampled.AccessLog[0].Filter, sampling10) {
+		t.Fatalf("expected the access log to be sampled, got %v", sampled.AccessLog)
+	}
+	tcpProxy := &tcp.TcpProxy{}
+	b.setTCPAccessLog(push, tcpProxy, inboundAccessLogSampling(node, 8080))
+	if len(tcpProxy.AccessLog) != 1 || !proto.Equal(tcpProxy.AccessLog[0].Filter, sampling10) {
+		t.Fatalf("expected the tcp access log to be sampled, got %v", tcpProxy.AccessLog)
+	}
+
+	// Without the annotation, and for outbound listeners, the access log is not filtered.
+	plain := &hcm.HttpConnectionManager{}
+	b.setHTTPAccessLog(push, plain, inboundAccessLogSampling(&model.Proxy{Metadata: &model.NodeMetadata{}}, 8080))
+	outbound := &hcm.HttpConnectionManager{}
+	b.setHTTPAccessLog(push, outbound, accessLogSampling{})
+	for _, cm := range []*hcm.HttpConnectionManager{plain, outbound} {
+		if len(cm.AccessLog) != 1 || cm.AccessLog[0].Filter != nil {
+			t.Errorf("expected an unfiltered access log, got %v", cm.AccessLog)
+		}
+	}
+	if plain.AccessLog[0] == sampled.AccessLog[0] {
+		t.Errorf("expected the sampled access log not to be reused")
+	}
+
+	// Sampling applies on top of the other conditions.
+	filtered := &hcm.HttpConnectionManager{}
+	b.setHTTPAccessLog(accessLogFilterPush(mesh, 0, true, 0), filtered, inboundAccessLogSampling(node, 8080))
+	want := &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_AndFilter{
+			AndFilter: &accesslog.AndFilter{Filters: []*accesslog.AccessLogFilter{sampling10, {
+				FilterSpecifier: &accesslog.AccessLogFilter_ResponseFlagFilter{
+					ResponseFlagFilter: &accesslog.ResponseFlagFilter{},
+				},
+			}}},
+		},
+	}
+	if len(filtered.AccessLog) != 1 || !proto.Equal(filtered.AccessLog[0].Filter, want) {
+		t.Errorf("got filter %v, want %v", filtered.AccessLog, want)
+	}
+}
+
+func TestInboundAccessLogSamplingWarnsOncePerProxy(t *testing.T) {
+	node := &model.Proxy{ID: "warn-once.default", Metadata: &model.NodeMetadata{AccessLogSampling: "10%"}}
+	t.Cleanup(func() { invalidAccessLogSamplings.Delete(node.ID) })
+
+	inboundAccessLogSampling(node, 8080)
+	if warned, _ := invalidAccessLogSamplings.Load(node.ID); warned != "10%" {
+		t.Fatalf("got warned sampling %v, want %q", warned, "10%")
+	}
+	node.Metadata.AccessLogSampling = "150"
+	inboundAccessLogSampling(node, 8080)
+	if warned, _ := invalidAccessLogSamplings.Load(node.ID); warned != "150" {
+		t.Fatalf("got warned sampling %v, want %q", warned, "150")
+	}
+}
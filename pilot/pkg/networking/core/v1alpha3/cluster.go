@@ -95,17 +95,19 @@ func getDefaultCircuitBreakerThresholds() *cluster.CircuitBreakers_Thresholds {
 // For outbound: Cluster for each service/subset hostname or cidr with SNI set to service hostname
 // Cluster type based on resolution
 // For inbound (sidecar only): Cluster for each inbound endpoint port and for each service port
-func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, push *model.PushContext) []*cluster.Cluster {
-	clusters := make([]*cluster.Cluster, 0)
+func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, push *model.PushContext, ctx *model.GenerationContext) []*cluster.Cluster {
+	// clusters is only used until normalizeClusters copies it, so it is taken from the generation context.
+	clusters := ctx.Clusters()
 	envoyFilterPatches := push.EnvoyFilters(proxy)
 	cb := NewClusterBuilder(proxy, push)
+	cb.ctx = ctx
 	instances := proxy.ServiceInstances
 
 	switch proxy.Type {
 	case model.SidecarProxy:
 		// Setup outbound clusters
 		outboundPatcher := clusterPatcher{envoyFilterPatches, networking.EnvoyFilter_SIDECAR_OUTBOUND}
-		clusters = append(clusters, configgen.buildOutboundClusters(cb, outboundPatcher)...)
+		clusters = configgen.buildOutboundClusters(clusters, cb, outboundPatcher)
 		// Add a blackhole and passthrough cluster for catching traffic to unresolved routes
 		clusters = outboundPatcher.conditionallyAppend(clusters, cb.buildBlackHoleCluster(), cb.buildDefaultPassthroughCluster())
 		clusters = append(clusters, outboundPatcher.insertedClusters()...)
@@ -118,7 +120,7 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, push *mo
 		clusters = append(clusters, inboundPatcher.insertedClusters()...)
 	default: // Gateways
 		patcher := clusterPatcher{envoyFilterPatches, networking.EnvoyFilter_GATEWAY}
-		clusters = append(clusters, configgen.buildOutboundClusters(cb, patcher)...)
+		clusters = configgen.buildOutboundClusters(clusters, cb, patcher)
 		// Gateways do not require the default passthrough cluster as they do not have original dst listeners.
 		clusters = patcher.conditionallyAppend(clusters, cb.buildBlackHoleCluster())
 		if proxy.Type == model.Router && proxy.GetRouterMode() == model.SniDnatRouter {
@@ -127,15 +129,16 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, push *mo
 		clusters = append(clusters, patcher.insertedClusters()...)
 	}
 
-	clusters = normalizeClusters(push, proxy, clusters)
+	out := normalizeClusters(push, proxy, clusters, ctx.Names())
+	ctx.KeepClusters(clusters)
 
-	return clusters
+	return out
 }
 
 // resolves cluster name conflicts. there can be duplicate cluster names if there are conflicting service definitions.
 // for any clusters that share the same name the first cluster is kept and the others are discarded.
-func normalizeClusters(metrics model.Metrics, proxy *model.Proxy, clusters []*cluster.Cluster) []*cluster.Cluster {
-	have := sets.Set{}
+// have must be empty, it is used to track the names of the clusters kept.
+func normalizeClusters(metrics model.Metrics, proxy *model.Proxy, clusters []*cluster.Cluster, have sets.Set) []*cluster.Cluster {
 	out := make([]*cluster.Cluster, 0, len(clusters))
 	for _, cluster := range clusters {
 		if !have.Contains(cluster.Name) {
@@ -149,8 +152,8 @@ func normalizeClusters(metrics model.Metrics, proxy *model.Proxy, clusters []*cl
 	return out
 }
 
-func (configgen *ConfigGeneratorImpl) buildOutboundClusters(cb *ClusterBuilder, cp clusterPatcher) []*cluster.Cluster {
-	clusters := make([]*cluster.Cluster, 0)
+// buildOutboundClusters appends the outbound clusters to clusters.
+func (configgen *ConfigGeneratorImpl) buildOutboundClusters(clusters []*cluster.Cluster, cb *ClusterBuilder, cp clusterPatcher) []*cluster.Cluster {
 	networkView := model.GetNetworkView(cb.proxy)
 
 	var services []*model.Service
@@ -164,10 +167,15 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(cb *ClusterBuilder,
 			if port.Protocol == protocol.UDP {
 				continue
 			}
-			lbEndpoints := cb.buildLocalityLbEndpoints(networkView, service, port.Port, nil)
+			// The endpoints of the hosts routed through an egress gateway are those of the gateway service.
+			epService, epPort := service, port.Port
+			if gw, gwPort := cb.push.EgressGatewayService(cb.proxy, service.Hostname, port.Port); gw != nil {
+				epService, epPort = gw, gwPort.Port
+			}
+			lbEndpoints := cb.buildLocalityLbEndpoints(networkView, epService, epPort, nil)
 
 			// create default cluster
-			discoveryType := convertResolution(cb.proxy, service)
+			discoveryType := convertResolution(cb.proxy, epService)
 			clusterName := model.BuildSubsetKey(model.TrafficDirectionOutbound, "", service.Hostname, port.Port)
 			defaultCluster := cb.buildDefaultCluster(clusterName, discoveryType, lbEndpoints, model.TrafficDirectionOutbound, port, service.MeshExternal)
 			if defaultCluster == nil {
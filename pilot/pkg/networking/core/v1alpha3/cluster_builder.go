@@ -40,6 +40,8 @@ var (
 type ClusterBuilder struct {
 	proxy *model.Proxy
 	push  *model.PushContext
+	// ctx receives the diagnostics of the clusters which could not be built. It may be nil.
+	ctx *model.GenerationContext
 }
 
 // NewClusterBuilder builds an instance of ClusterBuilder.
@@ -207,6 +209,11 @@ func (cb *ClusterBuilder) buildDefaultCluster(name string, discoveryType cluster
 		if len(localityLbEndpoints) == 0 {
 			cb.push.AddMetric(model.DNSNoEndpointClusters, c.Name, cb.proxy.ID,
 				fmt.Sprintf("%s cluster without endpoints %s found while pushing CDS", discoveryType.String(), c.Name))
+			cb.ctx.AddDiagnostic(model.GenerationDiagnostic{
+				Resource: c.Name,
+				Category: "cluster.LoadAssignment",
+				Message:  fmt.Sprintf("%s cluster has no endpoints", discoveryType.String()),
+			})
 			return nil
 		}
 		c.LoadAssignment = &endpoint.ClusterLoadAssignment{
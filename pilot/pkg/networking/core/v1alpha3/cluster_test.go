@@ -47,6 +47,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
 )
@@ -3087,11 +3088,79 @@ func TestBuildStaticClusterWithNoEndPoint(t *testing.T) {
 	cg := NewConfigGenTest(t, TestOptions{
 		Services: []*model.Service{service},
 	})
-	clusters := cg.Clusters(cg.SetupProxy(nil))
+	ctx := model.GetGenerationContext()
+	defer ctx.Release()
+	clusters := cg.ConfigGen.BuildClusters(cg.SetupProxy(nil), cg.PushContext(), ctx)
 	xdstest.ValidateClusters(t, clusters)
 
 	// Expect to ignore STRICT_DNS cluster without endpoints.
 	g.Expect(xdstest.MapKeys(xdstest.ExtractClusters(clusters))).To(Equal([]string{"BlackHoleCluster", "InboundPassthroughClusterIpv4", "PassthroughCluster"}))
+	// The skipped cluster is reported as a generation diagnostic.
+	diags := ctx.Diagnostics()
+	g.Expect(diags).To(HaveLen(1))
+	g.Expect(diags[0].Resource).To(Equal("outbound|8080||static.test"))
+	g.Expect(diags[0].Category).To(Equal("cluster.LoadAssignment"))
+}
+
+func TestBuildClustersEgressGatewayRedirect(t *testing.T) {
+	g := NewWithT(t)
+
+	cg := NewConfigGenTest(t, TestOptions{
+		ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: external
+  namespace: default
+spec:
+  hosts:
+  - api.example.com
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: egressgateway
+  namespace: istio-system
+spec:
+  hosts:
+  - istio-egressgateway.istio-system.svc.cluster.local
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+`,
+		MeshExtensions: &mesh.MeshExtensions{
+			EgressGatewayRedirects: []mesh.EgressGatewayRedirect{{
+				Namespace: "default",
+				Hosts:     []string{"api.example.com"},
+				Gateway:   "istio-egressgateway.istio-system.svc.cluster.local",
+			}},
+		},
+	})
+	name := "outbound|443||api.example.com"
+
+	// The sidecar keeps the cluster of the host but resolves it to the endpoints of the gateway over EDS.
+	sidecar := xdstest.ExtractCluster(name, cg.Clusters(cg.SetupProxy(nil)))
+	g.Expect(sidecar).NotTo(BeNil())
+	g.Expect(sidecar.GetClusterDiscoveryType()).To(Equal(&cluster.Cluster_Type{Type: cluster.Cluster_EDS}))
+
+	// The gateway itself resolves the host. It has no virtual service routing to it, so its clusters are not
+	// filtered.
+	gwClusters := features.FilterGatewayClusterConfig
+	features.FilterGatewayClusterConfig = false
+	defer func() { features.FilterGatewayClusterConfig = gwClusters }()
+	gateway := xdstest.ExtractCluster(name, cg.Clusters(cg.SetupProxy(&model.Proxy{Type: model.Router, ConfigNamespace: "istio-system"})))
+	g.Expect(gateway).NotTo(BeNil())
+	g.Expect(gateway.GetClusterDiscoveryType()).To(Equal(&cluster.Cluster_Type{Type: cluster.Cluster_STRICT_DNS}))
+	g.Expect(xdstest.ExtractEndpoints(gateway.LoadAssignment)).To(Equal([]string{"api.example.com"}))
 }
 
 func TestShouldH2Upgrade(t *testing.T) {
@@ -61,6 +61,12 @@ type TestOptions struct {
 	MeshConfig      *meshconfig.MeshConfig
 	NetworksWatcher mesh.NetworksWatcher
 
+	// If provided, these overrides of the default proxy config of the mesh will be used
+	ProxyConfigOverrides *mesh.ProxyConfigOverrides
+
+	// If provided, these settings of the mesh which are not part of the mesh config will be used
+	MeshExtensions *mesh.MeshExtensions
+
 	// Additional service registries to use. A ServiceEntry and memory registry will always be created.
 	ServiceRegistries []serviceregistry.Instance
 
@@ -128,6 +134,12 @@ func NewConfigGenTest(t test.Failer, opts TestOptions) *ConfigGenTest {
 		opts.NetworksWatcher = mesh.NewFixedNetworksWatcher(nil)
 	}
 	env.NetworksWatcher = opts.NetworksWatcher
+	if opts.ProxyConfigOverrides != nil {
+		env.ProxyConfigOverridesWatcher = mesh.NewFixedProxyConfigOverridesWatcher(opts.ProxyConfigOverrides)
+	}
+	if opts.MeshExtensions != nil {
+		env.MeshExtensionsWatcher = mesh.NewFixedMeshExtensionsWatcher(opts.MeshExtensions)
+	}
 
 	// Setup configuration. This should be done after registries are added so they can process events.
 	for _, cfg := range configs {
@@ -195,22 +207,22 @@ func (f *ConfigGenTest) SetupProxy(p *model.Proxy) *model.Proxy {
 
 	// Initialize data structures
 	pc := f.PushContext()
-	p.SetSidecarScope(pc)
-	p.SetGatewaysForProxy(pc)
 	if err := p.SetServiceInstances(f.env.ServiceDiscovery); err != nil {
 		f.t.Fatal(err)
 	}
+	p.SetSidecarScope(pc)
+	p.SetGatewaysForProxy(pc)
 	p.DiscoverIPVersions()
 	return p
 }
 
 // TODO do we need lock around push context?
 func (f *ConfigGenTest) Listeners(p *model.Proxy) []*listener.Listener {
-	return f.ConfigGen.BuildListeners(p, f.PushContext())
+	return f.ConfigGen.BuildListeners(p, f.PushContext(), nil)
 }
 
 func (f *ConfigGenTest) Clusters(p *model.Proxy) []*cluster.Cluster {
-	return f.ConfigGen.BuildClusters(p, f.PushContext())
+	return f.ConfigGen.BuildClusters(p, f.PushContext(), nil)
 }
 
 func (f *ConfigGenTest) Routes(p *model.Proxy) []*route.RouteConfiguration {
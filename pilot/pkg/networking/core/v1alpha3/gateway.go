@@ -55,7 +55,7 @@ func (configgen *ConfigGeneratorImpl) buildGatewayListeners(builder *ListenerBui
 	actualWildcard, _ := getActualWildcardAndLocalHost(builder.node)
 	errs := &multierror.Error{}
 	listeners := make([]*listener.Listener, 0, len(mergedGateway.Servers))
-	proxyConfig := builder.node.Metadata.ProxyConfigOrDefault(builder.push.Mesh.DefaultConfig)
+	topology := builder.push.GatewayTopology(builder.node)
 	for portNumber, servers := range mergedGateway.Servers {
 		var si *model.ServiceInstance
 		services := make(map[host.Name]struct{}, len(builder.node.ServiceInstances))
@@ -84,6 +84,9 @@ func (configgen *ConfigGeneratorImpl) buildGatewayListeners(builder *ListenerBui
 			port:       &model.Port{Port: int(portNumber)},
 			bindToPort: true,
 			class:      ListenerClassGateway,
+			// The traffic of the gateway, typically behind an L4 load balancer, may carry a PROXY protocol header.
+			needProxyProtocol: builder.node.InboundProxyProtocol(),
+			ctx:               builder.ctx,
 		}
 
 		p := protocol.Parse(servers[0].Port.Protocol)
@@ -94,7 +97,7 @@ func (configgen *ConfigGeneratorImpl) buildGatewayListeners(builder *ListenerBui
 			// We only need to look at the first server in the list as the merge logic
 			// ensures that all servers are of same type.
 			routeName := mergedGateway.RouteNamesByServer[servers[0]]
-			opts.filterChainOpts = []*filterChainOpts{configgen.createGatewayHTTPFilterChainOpts(builder.node, servers[0], routeName, "", proxyConfig)}
+			opts.filterChainOpts = []*filterChainOpts{configgen.createGatewayHTTPFilterChainOpts(builder.node, servers[0], routeName, "", topology)}
 			filterChains = append(filterChains, istionetworking.FilterChain{ListenerProtocol: istionetworking.ListenerProtocolHTTP})
 		} else {
 			// build http connection manager with TLS context, for HTTPS servers using simple/mutual TLS
@@ -109,7 +112,7 @@ func (configgen *ConfigGeneratorImpl) buildGatewayListeners(builder *ListenerBui
 					// This is a HTTPS server, where we are doing TLS termination. Build a http connection manager with TLS context
 					routeName := mergedGateway.RouteNamesByServer[server]
 					filterChainOpts = append(filterChainOpts, configgen.createGatewayHTTPFilterChainOpts(builder.node, server,
-						routeName, constants.DefaultSdsUdsPath, proxyConfig))
+						routeName, constants.DefaultSdsUdsPath, topology))
 					filterChains = append(filterChains, istionetworking.FilterChain{
 						ListenerProtocol:   istionetworking.ListenerProtocolHTTP,
 						IstioMutualGateway: server.Tls.Mode == networking.ServerTLSSettings_ISTIO_MUTUAL,
@@ -152,6 +155,11 @@ func (configgen *ConfigGeneratorImpl) buildGatewayListeners(builder *ListenerBui
 		// Filters are serialized one time into an opaque struct once we have the complete list.
 		if err := buildCompleteFilterChain(mutable, opts); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("gateway omitting listener %q due to: %v", mutable.Listener.Name, err.Error()))
+			builder.ctx.AddDiagnostic(model.GenerationDiagnostic{
+				Resource: mutable.Listener.Name,
+				Category: "listener.FilterChains",
+				Message:  err.Error(),
+			})
 			continue
 		}
 
@@ -227,9 +235,16 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(node *model.Pr
 		var virtualServices []config.Config
 		var exists bool
 
-		if virtualServices, exists = gatewayVirtualServices[gatewayName]; !exists {
-			virtualServices = push.VirtualServicesForGateway(node, gatewayName)
-			gatewayVirtualServices[gatewayName] = virtualServices
+		// Servers with wildcard hosts need all virtual services bound to the gateway, which are shared
+		// across servers. Other servers only need the ones which may match their hosts.
+		hostnames := gatewayServerHostnames(server)
+		if hasWildcardHostname(hostnames) {
+			if virtualServices, exists = gatewayVirtualServices[gatewayName]; !exists {
+				virtualServices = push.VirtualServicesForGateway(node, gatewayName)
+				gatewayVirtualServices[gatewayName] = virtualServices
+			}
+		} else {
+			virtualServices = push.VirtualServicesForGatewayAndHosts(node, gatewayName, hostnames)
 		}
 
 		for _, virtualService := range virtualServices {
@@ -324,7 +339,7 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(node *model.Pr
 
 // builds a HTTP connection manager for servers of type HTTP or HTTPS (mode: simple/mutual)
 func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(node *model.Proxy, server *networking.Server,
-	routeName string, sdsPath string, proxyConfig *meshconfig.ProxyConfig) *filterChainOpts {
+	routeName string, sdsPath string, topology *meshconfig.Topology) *filterChainOpts {
 
 	serverProto := protocol.Parse(server.Port.Protocol)
 
@@ -337,10 +352,10 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(node *mod
 	xffNumTrustedHops := uint32(0)
 	forwardClientCertDetails := util.MeshConfigToEnvoyForwardClientCertDetails(meshconfig.Topology_SANITIZE_SET)
 
-	if proxyConfig != nil && proxyConfig.GatewayTopology != nil {
-		xffNumTrustedHops = proxyConfig.GatewayTopology.NumTrustedProxies
-		if proxyConfig.GatewayTopology.ForwardClientCertDetails != meshconfig.Topology_UNDEFINED {
-			forwardClientCertDetails = util.MeshConfigToEnvoyForwardClientCertDetails(proxyConfig.GatewayTopology.ForwardClientCertDetails)
+	if topology != nil {
+		xffNumTrustedHops = topology.NumTrustedProxies
+		if topology.ForwardClientCertDetails != meshconfig.Topology_UNDEFINED {
+			forwardClientCertDetails = util.MeshConfigToEnvoyForwardClientCertDetails(topology.ForwardClientCertDetails)
 		}
 	}
 
@@ -414,8 +429,12 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(node *mod
 // ISTIO_MUTUAL  |    ENABLED    |   DISABLED  | support SDS at gateway to terminate workload mTLS, with internal workloads
 // 											   | for egress or with another trusted cluster for ingress)
 // ISTIO_MUTUAL  |    DISABLED   |   DISABLED  | use file-mounted secret paths to terminate workload mTLS from gateway
+// ISTIO_MUTUAL  |       *       |   ENABLED   | support SDS at gateway to terminate workload mTLS with the cert of the
+// 											   | credentialName, and its CA if PILOT_ENABLE_ISTIO_MUTUAL_CREDENTIAL_CA is
+// 											   | set, e.g. for cross-network trust at an east-west gateway
 //
-// Note that ISTIO_MUTUAL TLS mode and ingressSds should not be used simultaneously on the same ingress gateway.
+// Note that ISTIO_MUTUAL TLS mode and ingressSds should not be used simultaneously on the same ingress gateway, unless
+// the credentialName is meant to override the workload certificate and CA.
 func buildGatewayListenerTLSContext(
 	server *networking.Server, sdsPath string, metadata *model.NodeMetadata) *tls.DownstreamTlsContext {
 	// Server.TLS cannot be nil or passthrough. But as a safety guard, return nil
@@ -675,6 +694,27 @@ func pickMatchingGatewayHosts(gatewayServerHosts map[host.Name]bool, virtualServ
 	return matchingHosts
 }
 
+// gatewayServerHostnames returns the hostnames of the server hosts, without their namespace.
+func gatewayServerHostnames(server *networking.Server) []host.Name {
+	hostnames := make([]host.Name, 0, len(server.Hosts))
+	for _, h := range server.Hosts {
+		if i := strings.IndexByte(h, '/'); i >= 0 {
+			h = h[i+1:]
+		}
+		hostnames = append(hostnames, host.Name(h))
+	}
+	return hostnames
+}
+
+func hasWildcardHostname(hostnames []host.Name) bool {
+	for _, h := range hostnames {
+		if h.IsWildCarded() {
+			return true
+		}
+	}
+	return false
+}
+
 func convertTLSMatchToL4Match(tlsMatch *networking.TLSMatchAttributes) *networking.L4MatchAttributes {
 	return &networking.L4MatchAttributes{
 		DestinationSubnets: tlsMatch.DestinationSubnets,
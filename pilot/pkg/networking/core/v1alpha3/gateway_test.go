@@ -15,6 +15,7 @@
 package v1alpha3
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"testing"
@@ -23,6 +24,7 @@ import (
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	auth "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
@@ -36,6 +38,7 @@ import (
 	"istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/proto"
 )
@@ -47,6 +50,8 @@ func TestBuildGatewayListenerTlsContext(t *testing.T) {
 		sdsPath   string
 		result    *auth.DownstreamTlsContext
 		istiodSds bool
+		// credentialCA enables PILOT_ENABLE_ISTIO_MUTUAL_CREDENTIAL_CA.
+		credentialCA bool
 	}{
 		{
 			name: "mesh SDS enabled, tls mode ISTIO_MUTUAL",
@@ -405,6 +410,122 @@ func TestBuildGatewayListenerTlsContext(t *testing.T) {
 				RequireClientCertificate: proto.BoolTrue,
 			},
 		},
+		{
+			// Credential name is specified on an ISTIO_MUTUAL server, SDS configs are generated for fetching key/cert
+			// only unless the credential CA is enabled.
+			name: "credential name tls ISTIO_MUTUAL",
+			server: &networking.Server{
+				Hosts: []string{"*.local"},
+				Tls: &networking.ServerTLSSettings{
+					Mode:           networking.ServerTLSSettings_ISTIO_MUTUAL,
+					CredentialName: "eastwest-cert",
+				},
+			},
+			result: &auth.DownstreamTlsContext{
+				CommonTlsContext: &auth.CommonTlsContext{
+					AlpnProtocols: util.ALPNHttp,
+					TlsCertificateSdsSecretConfigs: []*auth.SdsSecretConfig{
+						{
+							Name: "eastwest-cert",
+							SdsConfig: &core.ConfigSource{
+								InitialFetchTimeout: features.InitialFetchTimeout,
+								ResourceApiVersion:  core.ApiVersion_V3,
+								ConfigSourceSpecifier: &core.ConfigSource_ApiConfigSource{
+									ApiConfigSource: &core.ApiConfigSource{
+										ApiType:             core.ApiConfigSource_GRPC,
+										TransportApiVersion: core.ApiVersion_V3,
+										GrpcServices: []*core.GrpcService{
+											{
+												TargetSpecifier: &core.GrpcService_GoogleGrpc_{
+													GoogleGrpc: &core.GrpcService_GoogleGrpc{
+														TargetUri:  model.CredentialNameSDSUdsPath,
+														StatPrefix: model.SDSStatPrefix,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				RequireClientCertificate: proto.BoolTrue,
+			},
+		},
+		{
+			// Credential name is specified on an ISTIO_MUTUAL server with the credential CA enabled, e.g. at an
+			// east-west gateway with custom certs, SDS configs are generated for fetching key/cert and the root cert
+			// overriding the workload ones.
+			name:         "credential name tls ISTIO_MUTUAL with credential CA",
+			credentialCA: true,
+			server: &networking.Server{
+				Hosts: []string{"*.local"},
+				Tls: &networking.ServerTLSSettings{
+					Mode:           networking.ServerTLSSettings_ISTIO_MUTUAL,
+					CredentialName: "eastwest-cert",
+				},
+			},
+			result: &auth.DownstreamTlsContext{
+				CommonTlsContext: &auth.CommonTlsContext{
+					AlpnProtocols: util.ALPNHttp,
+					TlsCertificateSdsSecretConfigs: []*auth.SdsSecretConfig{
+						{
+							Name: "eastwest-cert",
+							SdsConfig: &core.ConfigSource{
+								InitialFetchTimeout: features.InitialFetchTimeout,
+								ResourceApiVersion:  core.ApiVersion_V3,
+								ConfigSourceSpecifier: &core.ConfigSource_ApiConfigSource{
+									ApiConfigSource: &core.ApiConfigSource{
+										ApiType:             core.ApiConfigSource_GRPC,
+										TransportApiVersion: core.ApiVersion_V3,
+										GrpcServices: []*core.GrpcService{
+											{
+												TargetSpecifier: &core.GrpcService_GoogleGrpc_{
+													GoogleGrpc: &core.GrpcService_GoogleGrpc{
+														TargetUri:  model.CredentialNameSDSUdsPath,
+														StatPrefix: model.SDSStatPrefix,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					ValidationContextType: &auth.CommonTlsContext_CombinedValidationContext{
+						CombinedValidationContext: &auth.CommonTlsContext_CombinedCertificateValidationContext{
+							DefaultValidationContext: &auth.CertificateValidationContext{},
+							ValidationContextSdsSecretConfig: &auth.SdsSecretConfig{
+								Name: "eastwest-cert-cacert",
+								SdsConfig: &core.ConfigSource{
+									InitialFetchTimeout: features.InitialFetchTimeout,
+									ResourceApiVersion:  core.ApiVersion_V3,
+									ConfigSourceSpecifier: &core.ConfigSource_ApiConfigSource{
+										ApiConfigSource: &core.ApiConfigSource{
+											ApiType:             core.ApiConfigSource_GRPC,
+											TransportApiVersion: core.ApiVersion_V3,
+											GrpcServices: []*core.GrpcService{
+												{
+													TargetSpecifier: &core.GrpcService_GoogleGrpc_{
+														GoogleGrpc: &core.GrpcService_GoogleGrpc{
+															TargetUri:  model.CredentialNameSDSUdsPath,
+															StatPrefix: model.SDSStatPrefix,
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				RequireClientCertificate: proto.BoolTrue,
+			},
+		},
 		{
 			// Credential name and VerifyCertificateSpki options are specified, SDS configs are generated for fetching
 			// key/cert and root cert
@@ -572,6 +693,9 @@ func TestBuildGatewayListenerTlsContext(t *testing.T) {
 			old := features.EnableSDSServer
 			features.EnableSDSServer = tc.istiodSds
 			defer func() { features.EnableSDSServer = old }()
+			oldCredentialCA := features.EnableIstioMutualCredentialCA
+			features.EnableIstioMutualCredentialCA = tc.credentialCA
+			defer func() { features.EnableIstioMutualCredentialCA = oldCredentialCA }()
 			ret := buildGatewayListenerTLSContext(tc.server, tc.sdsPath, &pilot_model.NodeMetadata{})
 			if diff := cmp.Diff(tc.result, ret, protocmp.Transform()); diff != "" {
 				t.Errorf("got diff: %v", diff)
@@ -582,12 +706,12 @@ func TestBuildGatewayListenerTlsContext(t *testing.T) {
 
 func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 	testCases := []struct {
-		name        string
-		node        *pilot_model.Proxy
-		server      *networking.Server
-		routeName   string
-		proxyConfig *meshconfig.ProxyConfig
-		result      *filterChainOpts
+		name      string
+		node      *pilot_model.Proxy
+		server    *networking.Server
+		routeName string
+		topology  *meshconfig.Topology
+		result    *filterChainOpts
 	}{
 		{
 			name: "HTTP1.0 mode enabled",
@@ -597,8 +721,8 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 			server: &networking.Server{
 				Port: &networking.Port{},
 			},
-			routeName:   "some-route",
-			proxyConfig: nil,
+			routeName: "some-route",
+			topology:  nil,
 			result: &filterChainOpts{
 				sniHosts:   nil,
 				tlsContext: nil,
@@ -634,8 +758,8 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 					Mode: networking.ServerTLSSettings_ISTIO_MUTUAL,
 				},
 			},
-			routeName:   "some-route",
-			proxyConfig: nil,
+			routeName: "some-route",
+			topology:  nil,
 			result: &filterChainOpts{
 				sniHosts: []string{"example.org"},
 				tlsContext: &auth.DownstreamTlsContext{
@@ -721,8 +845,8 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 					Mode: networking.ServerTLSSettings_ISTIO_MUTUAL,
 				},
 			},
-			routeName:   "some-route",
-			proxyConfig: nil,
+			routeName: "some-route",
+			topology:  nil,
 			result: &filterChainOpts{
 				sniHosts: []string{"example.org", "test.org"},
 				tlsContext: &auth.DownstreamTlsContext{
@@ -808,8 +932,8 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 					Mode: networking.ServerTLSSettings_ISTIO_MUTUAL,
 				},
 			},
-			routeName:   "some-route",
-			proxyConfig: nil,
+			routeName: "some-route",
+			topology:  nil,
 			result: &filterChainOpts{
 				sniHosts: []string{"*.example.org", "example.org"},
 				tlsContext: &auth.DownstreamTlsContext{
@@ -890,11 +1014,9 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 				Port: &networking.Port{},
 			},
 			routeName: "some-route",
-			proxyConfig: &meshconfig.ProxyConfig{
-				GatewayTopology: &meshconfig.Topology{
-					NumTrustedProxies:        2,
-					ForwardClientCertDetails: meshconfig.Topology_APPEND_FORWARD,
-				},
+			topology: &meshconfig.Topology{
+				NumTrustedProxies:        2,
+				ForwardClientCertDetails: meshconfig.Topology_APPEND_FORWARD,
 			},
 			result: &filterChainOpts{
 				sniHosts:   nil,
@@ -930,11 +1052,9 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 				},
 			},
 			routeName: "some-route",
-			proxyConfig: &meshconfig.ProxyConfig{
-				GatewayTopology: &meshconfig.Topology{
-					NumTrustedProxies:        3,
-					ForwardClientCertDetails: meshconfig.Topology_FORWARD_ONLY,
-				},
+			topology: &meshconfig.Topology{
+				NumTrustedProxies:        3,
+				ForwardClientCertDetails: meshconfig.Topology_FORWARD_ONLY,
 			},
 			result: &filterChainOpts{
 				sniHosts: []string{"example.org"},
@@ -1023,11 +1143,9 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 				},
 			},
 			routeName: "some-route",
-			proxyConfig: &meshconfig.ProxyConfig{
-				GatewayTopology: &meshconfig.Topology{
-					NumTrustedProxies:        3,
-					ForwardClientCertDetails: meshconfig.Topology_FORWARD_ONLY,
-				},
+			topology: &meshconfig.Topology{
+				NumTrustedProxies:        3,
+				ForwardClientCertDetails: meshconfig.Topology_FORWARD_ONLY,
 			},
 			result: &filterChainOpts{
 				sniHosts: []string{"example.org"},
@@ -1111,7 +1229,7 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 			tc.node.MergedGateway = &pilot_model.MergedGateway{SNIHostsByServer: map[*networking.Server][]string{
 				tc.server: pilot_model.GetSNIHostsForServer(tc.server),
 			}}
-			ret := cgi.createGatewayHTTPFilterChainOpts(tc.node, tc.server, tc.routeName, "", tc.proxyConfig)
+			ret := cgi.createGatewayHTTPFilterChainOpts(tc.node, tc.server, tc.routeName, "", tc.topology)
 			if diff := cmp.Diff(tc.result.tlsContext, ret.tlsContext, protocmp.Transform()); diff != "" {
 				t.Errorf("got diff in tls context: %v", diff)
 			}
@@ -1396,3 +1514,194 @@ func TestBuildGatewayListeners(t *testing.T) {
 		xdstest.ValidateListeners(t, builder.gatewayListeners)
 	}
 }
+
+func TestBuildGatewayListenersProxyProtocol(t *testing.T) {
+	gw := &networking.Gateway{
+		Servers: []*networking.Server{
+			{
+				Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+				Hosts: []string{"*"},
+			},
+			{
+				Port:  &networking.Port{Name: "https", Number: 443, Protocol: "HTTPS"},
+				Hosts: []string{"*.example.com"},
+				Tls:   &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_SIMPLE, CredentialName: "example-cert"},
+			},
+		},
+	}
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprint(enabled), func(t *testing.T) {
+			cg := NewConfigGenTest(t, TestOptions{
+				Configs: []config.Config{{Meta: config.Meta{GroupVersionKind: gvk.Gateway}, Spec: gw}},
+			})
+			node := pilot_model.Proxy{
+				Type:        proxyGateway.Type,
+				IPAddresses: proxyGateway.IPAddresses,
+				ID:          proxyGateway.ID,
+				DNSDomain:   proxyGateway.DNSDomain,
+				Metadata: &pilot_model.NodeMetadata{
+					Namespace: proxyGateway.Metadata.Namespace,
+					Labels:    proxyGateway.Metadata.Labels,
+				},
+				ConfigNamespace: proxyGateway.ConfigNamespace,
+			}
+			if enabled {
+				node.Metadata.InboundProxyProtocol = "1"
+			}
+			proxy := cg.SetupProxy(&node)
+			builder := cg.ConfigGen.buildGatewayListeners(&ListenerBuilder{node: proxy, push: cg.PushContext()})
+			if len(builder.gatewayListeners) != 2 {
+				t.Fatalf("expected 2 listeners, got %v", xdstest.ExtractListenerNames(builder.gatewayListeners))
+			}
+			for _, l := range builder.gatewayListeners {
+				var names []string
+				for _, lf := range l.ListenerFilters {
+					names = append(names, lf.Name)
+				}
+				if !enabled {
+					if len(names) > 0 && names[0] == wellknown.ProxyProtocol {
+						t.Errorf("unexpected proxy_protocol listener filter in listener %s", l.Name)
+					}
+					continue
+				}
+				// The header must be consumed before the TLS inspector of the HTTPS server reads the ClientHello.
+				if len(names) == 0 || names[0] != wellknown.ProxyProtocol {
+					t.Errorf("expected listener %s filters to start with %q, got %v", l.Name, wellknown.ProxyProtocol, names)
+				}
+			}
+			xdstest.ValidateListeners(t, builder.gatewayListeners)
+		})
+	}
+}
+
+func TestGatewayTopology(t *testing.T) {
+	overrides := &mesh.ProxyConfigOverrides{Overrides: []mesh.ProxyConfigOverride{{
+		Namespace:   "not-default",
+		ProxyConfig: []byte(`{"gatewayTopology":{"numTrustedProxies":2,"forwardClientCertDetails":"FORWARD_ONLY"}}`),
+	}}}
+	cases := []struct {
+		name         string
+		meshTopology *meshconfig.Topology
+		metadata     *meshconfig.Topology
+		annotated    bool
+		version      string
+		overrides    *mesh.ProxyConfigOverrides
+		hops         uint32
+		fcc          hcm.HttpConnectionManager_ForwardClientCertDetails
+	}{
+		{
+			name: "default",
+			hops: 0,
+			fcc:  hcm.HttpConnectionManager_SANITIZE_SET,
+		},
+		{
+			name:      "metadata only",
+			metadata:  &meshconfig.Topology{NumTrustedProxies: 1, ForwardClientCertDetails: meshconfig.Topology_APPEND_FORWARD},
+			annotated: true,
+			hops:      1,
+			fcc:       hcm.HttpConnectionManager_APPEND_FORWARD,
+		},
+		{
+			name:      "push config only",
+			overrides: overrides,
+			hops:      2,
+			fcc:       hcm.HttpConnectionManager_FORWARD_ONLY,
+		},
+		{
+			name:      "metadata takes precedence",
+			metadata:  &meshconfig.Topology{NumTrustedProxies: 1, ForwardClientCertDetails: meshconfig.Topology_APPEND_FORWARD},
+			annotated: true,
+			overrides: overrides,
+			hops:      1,
+			fcc:       hcm.HttpConnectionManager_APPEND_FORWARD,
+		},
+		{
+			name:      "metadata fields unset",
+			metadata:  &meshconfig.Topology{NumTrustedProxies: 3},
+			annotated: true,
+			overrides: overrides,
+			hops:      3,
+			fcc:       hcm.HttpConnectionManager_FORWARD_ONLY,
+		},
+		{
+			// The metadata of the proxy includes the topology of the mesh config, which is not an override.
+			name:         "metadata not annotated",
+			meshTopology: &meshconfig.Topology{NumTrustedProxies: 1, ForwardClientCertDetails: meshconfig.Topology_APPEND_FORWARD},
+			metadata:     &meshconfig.Topology{NumTrustedProxies: 1, ForwardClientCertDetails: meshconfig.Topology_APPEND_FORWARD},
+			overrides:    overrides,
+			hops:         2,
+			fcc:          hcm.HttpConnectionManager_FORWARD_ONLY,
+		},
+		{
+			// The mesh config changed after the proxy connected, its metadata carrying the former mesh default.
+			name:         "mesh default changed after connection",
+			meshTopology: &meshconfig.Topology{NumTrustedProxies: 4, ForwardClientCertDetails: meshconfig.Topology_FORWARD_ONLY},
+			metadata:     &meshconfig.Topology{NumTrustedProxies: 1, ForwardClientCertDetails: meshconfig.Topology_APPEND_FORWARD},
+			hops:         4,
+			fcc:          hcm.HttpConnectionManager_FORWARD_ONLY,
+		},
+		{
+			// Older agents do not report whether the topology is annotated.
+			name:      "older proxy",
+			metadata:  &meshconfig.Topology{NumTrustedProxies: 3},
+			version:   "1.7.0",
+			overrides: overrides,
+			hops:      3,
+			fcc:       hcm.HttpConnectionManager_FORWARD_ONLY,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mesh.DefaultMeshConfig()
+			m.DefaultConfig.GatewayTopology = tt.meshTopology
+			cg := NewConfigGenTest(t, TestOptions{
+				Configs: []config.Config{{Meta: config.Meta{GroupVersionKind: gvk.Gateway}, Spec: &networking.Gateway{
+					Servers: []*networking.Server{{Port: &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"}}},
+				}}},
+				MeshConfig:           &m,
+				ProxyConfigOverrides: tt.overrides,
+			})
+			proxy := pilot_model.Proxy{
+				Type:            proxyGateway.Type,
+				IPAddresses:     proxyGateway.IPAddresses,
+				ID:              proxyGateway.ID,
+				DNSDomain:       proxyGateway.DNSDomain,
+				ConfigNamespace: proxyGateway.ConfigNamespace,
+			}
+			metadata := *proxyGateway.Metadata
+			if tt.metadata != nil {
+				metadata.ProxyConfig = &pilot_model.NodeMetaProxyConfig{GatewayTopology: tt.metadata}
+			}
+			metadata.GatewayTopologyAnnotated = pilot_model.StringBool(tt.annotated)
+			if tt.version != "" {
+				metadata.IstioVersion = tt.version
+				proxy.IstioVersion = pilot_model.ParseIstioVersion(tt.version)
+			}
+			proxy.Metadata = &metadata
+			builder := cg.ConfigGen.buildGatewayListeners(&ListenerBuilder{node: cg.SetupProxy(&proxy), push: cg.PushContext()})
+			if len(builder.gatewayListeners) != 1 {
+				t.Fatalf("expected one listener, got %v", xdstest.ExtractListenerNames(builder.gatewayListeners))
+			}
+			for _, fc := range builder.gatewayListeners[0].FilterChains {
+				for _, f := range fc.Filters {
+					if f.Name != wellknown.HTTPConnectionManager {
+						continue
+					}
+					cm := &hcm.HttpConnectionManager{}
+					if err := ptypes.UnmarshalAny(f.GetTypedConfig(), cm); err != nil {
+						t.Fatal(err)
+					}
+					if cm.XffNumTrustedHops != tt.hops {
+						t.Errorf("got xff_num_trusted_hops %d, want %d", cm.XffNumTrustedHops, tt.hops)
+					}
+					if cm.ForwardClientCertDetails != tt.fcc {
+						t.Errorf("got forward_client_cert_details %v, want %v", cm.ForwardClientCertDetails, tt.fcc)
+					}
+					return
+				}
+			}
+			t.Fatal("no http connection manager found")
+		})
+	}
+}
@@ -276,8 +276,9 @@ func init() {
 
 // BuildListeners produces a list of listeners and referenced clusters for all proxies
 func (configgen *ConfigGeneratorImpl) BuildListeners(node *model.Proxy,
-	push *model.PushContext) []*listener.Listener {
+	push *model.PushContext, ctx *model.GenerationContext) []*listener.Listener {
 	builder := NewListenerBuilder(node, push)
+	builder.ctx = ctx
 
 	switch node.Type {
 	case model.SidecarProxy:
@@ -308,7 +309,8 @@ func (configgen *ConfigGeneratorImpl) buildSidecarListeners(push *model.PushCont
 // configuration for co-located service proxyInstances.
 func (configgen *ConfigGeneratorImpl) buildSidecarInboundListeners(
 	node *model.Proxy,
-	push *model.PushContext) []*listener.Listener {
+	push *model.PushContext,
+	ctx *model.GenerationContext) []*listener.Listener {
 
 	var listeners []*listener.Listener
 	listenerMap := make(map[int]*inboundListenerEntry)
@@ -371,6 +373,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundListeners(
 				bind:       bind,
 				port:       &port,
 				bindToPort: false,
+				ctx:        ctx,
 			}
 
 			pluginParams := &plugin.InputParams{
@@ -424,6 +427,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundListeners(
 				bind:       bind,
 				port:       listenPort,
 				bindToPort: bindToPort,
+				ctx:        ctx,
 			}
 
 			// we don't need to set other fields of the endpoint here as
@@ -548,7 +552,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundListenerForPortOrUDS(no
 
 	if len(allChains) == 0 {
 		// add one empty entry to the list so we generate a default listener below
-		allChains = []istionetworking.FilterChain{{}}
+		allChains = []istionetworking.FilterChain{defaultInboundFilterChain(pluginParams.Node)}
 	}
 
 	tlsInspectorEnabled := false
@@ -612,7 +616,7 @@ allChainsLabel:
 
 		case istionetworking.ListenerProtocolTCP:
 			filterChainMatch = chain.FilterChainMatch
-			tcpNetworkFilters = buildInboundNetworkFilters(pluginParams.Push, pluginParams.ServiceInstance)
+			tcpNetworkFilters = buildInboundNetworkFilters(pluginParams.Push, node, pluginParams.ServiceInstance)
 
 		case istionetworking.ListenerProtocolAuto:
 			// Make sure id is not out of boundary of filterChainMatchOption
@@ -636,11 +640,16 @@ allChainsLabel:
 						chain.TLSContext.CommonTlsContext.AlpnProtocols, tcpMxcALPN)
 				}
 			} else {
-				tcpNetworkFilters = buildInboundNetworkFilters(pluginParams.Push, pluginParams.ServiceInstance)
+				tcpNetworkFilters = buildInboundNetworkFilters(pluginParams.Push, node, pluginParams.ServiceInstance)
 			}
 		default:
 			log.Warnf("Unsupported inbound protocol %v for port %#v", pluginParams.ListenerProtocol,
 				pluginParams.ServiceInstance.ServicePort)
+			listenerOpts.ctx.AddDiagnostic(model.GenerationDiagnostic{
+				Resource: listenerOpts.bind + "_" + strconv.Itoa(listenerOpts.port.Port),
+				Category: "listener.Protocol",
+				Message:  fmt.Sprintf("unsupported inbound protocol %v", pluginParams.ListenerProtocol),
+			})
 			return nil
 		}
 
@@ -670,6 +679,11 @@ allChainsLabel:
 	// Filters are serialized one time into an opaque struct once we have the complete list.
 	if err := buildCompleteFilterChain(mutable, listenerOpts); err != nil {
 		log.Warna("buildSidecarInboundListeners ", err.Error())
+		listenerOpts.ctx.AddDiagnostic(model.GenerationDiagnostic{
+			Resource: mutable.Listener.Name,
+			Category: "listener.FilterChains",
+			Message:  err.Error(),
+		})
 		return nil
 	}
 
@@ -736,14 +750,15 @@ func (c outboundListenerConflict) addMetric(metrics model.Metrics) {
 
 // buildSidecarOutboundListeners generates http and tcp listeners for
 // outbound connections from the proxy based on the sidecar scope associated with the proxy.
+// The returned listeners are taken from ctx, if set.
 func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.Proxy,
-	push *model.PushContext) []*listener.Listener {
+	push *model.PushContext, ctx *model.GenerationContext) []*listener.Listener {
 
 	noneMode := node.GetInterceptionMode() == model.InterceptionNone
 
 	actualWildcard, actualLocalHostAddress := getActualWildcardAndLocalHost(node)
 
-	var tcpListeners, httpListeners []*listener.Listener
+	tcpListeners, httpListeners := ctx.OutboundListeners()
 	// For conflict resolution
 	listenerMap := make(map[string]*outboundListenerEntry)
 
@@ -830,6 +845,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 				bind:       bind,
 				port:       listenPort,
 				bindToPort: bindToPort,
+				ctx:        ctx,
 			}
 
 			for _, service := range services {
@@ -875,6 +891,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 				push:       push,
 				proxy:      node,
 				bindToPort: bindToPort,
+				ctx:        ctx,
 			}
 
 			pluginParams := &plugin.InputParams{
@@ -958,6 +975,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 		configgen.appendListenerFallthroughRouteForCompleteListener(listener, node, push)
 	}
 	removeListenerFilterTimeout(tcpListeners)
+	ctx.KeepOutboundListeners(tcpListeners, httpListeners)
 	return tcpListeners
 }
 
@@ -1452,6 +1470,11 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListenerForPortOrUDS(n
 	// Filters are serialized one time into an opaque struct once we have the complete list.
 	if err := buildCompleteFilterChain(mutable, listenerOpts); err != nil {
 		log.Warna("buildSidecarOutboundListeners: ", err.Error())
+		listenerOpts.ctx.AddDiagnostic(model.GenerationDiagnostic{
+			Resource: mutable.Listener.Name,
+			Category: "listener.FilterChains",
+			Message:  err.Error(),
+		})
 		return
 	}
 
@@ -1463,6 +1486,12 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListenerForPortOrUDS(n
 		!isConflictWithWellKnownPort(listenerOpts.port.Protocol, currentListenerEntry.protocol, conflictType) {
 		log.Warnf("conflict happens on a well known port %d, incoming protocol %v, existing protocol %v, conflict type %v",
 			listenerOpts.port.Port, listenerOpts.port.Protocol, currentListenerEntry.protocol, conflictType)
+		listenerOpts.ctx.AddDiagnostic(model.GenerationDiagnostic{
+			Resource: mutable.Listener.Name,
+			Category: "listener.PortConflict",
+			Message: fmt.Sprintf("protocol %v conflicts with the existing protocol %v on well known port %d",
+				listenerOpts.port.Protocol, currentListenerEntry.protocol, listenerOpts.port.Port),
+		})
 		return
 	}
 
@@ -1615,8 +1644,12 @@ type buildListenerOpts struct {
 	bindToPort        bool
 	skipUserFilters   bool
 	needHTTPInspector bool
+	// needProxyProtocol adds the proxy_protocol listener filter, regardless of the listener filters of the chains.
+	needProxyProtocol bool
 	class             ListenerClass
 	service           *model.Service
+	// ctx receives the diagnostics of the listeners which could not be built. It may be nil.
+	ctx *model.GenerationContext
 }
 
 func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpListenerOpts,
@@ -1687,7 +1720,11 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 		connectionManager.RouteSpecifier = &hcm.HttpConnectionManager_RouteConfig{RouteConfig: httpOpts.routeConfig}
 	}
 
-	accessLogBuilder.setHTTPAccessLog(listenerOpts.push.Mesh, connectionManager)
+	sampling := accessLogSampling{}
+	if listenerOpts.class == ListenerClassSidecarInbound && listenerOpts.port != nil {
+		sampling = inboundAccessLogSampling(listenerOpts.proxy, listenerOpts.port.Port)
+	}
+	accessLogBuilder.setHTTPAccessLog(listenerOpts.push, connectionManager, sampling)
 
 	if listenerOpts.push.Mesh.EnableTracing {
 		proxyConfig := listenerOpts.proxy.Metadata.ProxyConfigOrDefault(listenerOpts.push.Mesh.DefaultConfig)
@@ -1847,6 +1884,12 @@ func buildListener(opts buildListenerOpts) *listener.Listener {
 	listenerFiltersMap := make(map[string]bool)
 	var listenerFilters []*listener.ListenerFilter
 
+	// The proxy_protocol filter consumes the PROXY protocol header, so it must precede the inspectors of the payload.
+	if opts.needProxyProtocol || hasProxyProtocol(opts.filterChainOpts) {
+		listenerFiltersMap[wellknown.ProxyProtocol] = true
+		listenerFilters = append(listenerFilters, xdsfilters.ProxyProtocol)
+	}
+
 	// add a TLS inspector if we need to detect ServerName or ALPN
 	needTLSInspector := false
 	for _, chain := range opts.filterChainOpts {
@@ -2265,6 +2308,18 @@ func isConflictWithWellKnownPort(incoming, existing protocol.Instance, conflict
 	return true
 }
 
+// hasProxyProtocol returns true if one of the filter chains needs the proxy_protocol listener filter.
+func hasProxyProtocol(chains []*filterChainOpts) bool {
+	for _, chain := range chains {
+		for _, filter := range chain.listenerFilters {
+			if filter.Name == wellknown.ProxyProtocol {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func appendListenerFilters(filters []*listener.ListenerFilter) []*listener.ListenerFilter {
 	hasTLSInspector := false
 	hasHTTPInspector := false
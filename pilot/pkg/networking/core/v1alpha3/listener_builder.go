@@ -64,6 +64,9 @@ type ListenerBuilder struct {
 	httpProxyListener       *listener.Listener
 	virtualOutboundListener *listener.Listener
 	virtualInboundListener  *listener.Listener
+	// ctx provides the buffers of the outbound listeners and of the listeners returned by getListeners, and
+	// receives the diagnostics of the skipped listeners, if set.
+	ctx *model.GenerationContext
 }
 
 // Setup the filter chain match so that the match should work under both
@@ -96,10 +99,22 @@ func amendFilterChainMatchFromInboundListener(chain *listener.FilterChain, l *li
 		if filter.Name == wellknown.HttpInspector {
 			res.HTTPInspector = true
 		}
+		if filter.Name == wellknown.ProxyProtocol {
+			res.ProxyProtocol = true
+		}
 	}
 	return res
 }
 
+// defaultInboundFilterChain returns the inbound filter chain used when no plugin sets up the filter chains, e.g.
+// when mTLS is disabled.
+func defaultInboundFilterChain(node *model.Proxy) istionetworking.FilterChain {
+	if node.InboundProxyProtocol() {
+		return istionetworking.FilterChain{ListenerFilters: []*listener.ListenerFilter{xdsfilters.ProxyProtocol}}
+	}
+	return istionetworking.FilterChain{}
+}
+
 func isBindtoPort(l *listener.Listener) bool {
 	v1 := l.GetDeprecatedV1()
 	if v1 == nil {
@@ -118,6 +133,7 @@ func isBindtoPort(l *listener.Listener) bool {
 type enabledInspector struct {
 	HTTPInspector bool
 	TLSInspector  bool
+	ProxyProtocol bool
 }
 
 // Accumulate the filter chains from per proxy service listeners
@@ -141,6 +157,7 @@ func reduceInboundListenerToFilterChains(listeners []*listener.Listener) ([]*lis
 				prev := inspectorsMap[port]
 				prev.HTTPInspector = prev.HTTPInspector || inspectors.HTTPInspector
 				prev.TLSInspector = prev.TLSInspector || inspectors.TLSInspector
+				prev.ProxyProtocol = prev.ProxyProtocol || inspectors.ProxyProtocol
 				inspectorsMap[port] = prev
 			}
 		}
@@ -157,16 +174,16 @@ func needsTLS(inspectors map[int]enabledInspector) bool {
 	return false
 }
 
-func (lb *ListenerBuilder) aggregateVirtualInboundListener(needTLSForPassThroughFilterChain bool) *ListenerBuilder {
-	// Deprecated by envoyproxy. Replaced
-	// 1. filter chains in this listener
-	// 2. explicit original_dst listener filter
-	// UseOriginalDst: proto.BoolTrue,
-	// nolint: staticcheck
-	lb.virtualInboundListener.HiddenEnvoyDeprecatedUseOriginalDst = nil
-	lb.virtualInboundListener.ListenerFilters = append(lb.virtualInboundListener.ListenerFilters,
-		xdsfilters.OriginalDestination,
-	)
+func needsProxyProtocol(inspectors map[int]enabledInspector) bool {
+	for _, i := range inspectors {
+		if i.ProxyProtocol {
+			return true
+		}
+	}
+	return false
+}
+
+func (lb *ListenerBuilder) aggregateVirtualInboundListener(passThroughInspectors enabledInspector) *ListenerBuilder {
 	// TODO: Trim the inboundListeners properly. Those that have been added to filter chains should
 	// be removed while those that haven't been added need to remain in the inboundListeners list.
 	filterChains, inspectors := reduceInboundListenerToFilterChains(lb.inboundListeners)
@@ -177,7 +194,25 @@ func (lb *ListenerBuilder) aggregateVirtualInboundListener(needTLSForPassThrough
 	lb.virtualInboundListener.FilterChains =
 		append(lb.virtualInboundListener.FilterChains, filterChains...)
 
-	if needsTLS(inspectors) || needTLSForPassThroughFilterChain {
+	// Note: the proxy_protocol filter should be first. It consumes the PROXY protocol header before the TLS
+	// inspector reads the ClientHello, and the original_dst filter must then override the destination of the
+	// header with the original destination of the connection.
+	if needsProxyProtocol(inspectors) || passThroughInspectors.ProxyProtocol {
+		lb.virtualInboundListener.ListenerFilters =
+			append(lb.virtualInboundListener.ListenerFilters, buildProxyProtocol(inspectors))
+	}
+
+	// Deprecated by envoyproxy. Replaced
+	// 1. filter chains in this listener
+	// 2. explicit original_dst listener filter
+	// UseOriginalDst: proto.BoolTrue,
+	// nolint: staticcheck
+	lb.virtualInboundListener.HiddenEnvoyDeprecatedUseOriginalDst = nil
+	lb.virtualInboundListener.ListenerFilters = append(lb.virtualInboundListener.ListenerFilters,
+		xdsfilters.OriginalDestination,
+	)
+
+	if needsTLS(inspectors) || passThroughInspectors.TLSInspector {
 		lb.virtualInboundListener.ListenerFilters =
 			append(lb.virtualInboundListener.ListenerFilters, buildTLSInspector(inspectors))
 	}
@@ -236,6 +271,30 @@ func buildTLSInspector(inspectors map[int]enabledInspector) *listener.ListenerFi
 	return filter
 }
 
+// buildProxyProtocol creates a proxy_protocol filter. Based on the configured ports, this may be enabled
+// for only some ports.
+func buildProxyProtocol(inspectors map[int]enabledInspector) *listener.ListenerFilter {
+	ports := make([]int, 0, len(inspectors))
+	// Collect all ports where the proxy_protocol filter is disabled.
+	for p, i := range inspectors {
+		if !i.ProxyProtocol {
+			ports = append(ports, p)
+		}
+	}
+	// No need to filter, return the cached version enabled for all ports
+	if len(ports) == 0 {
+		return xdsfilters.ProxyProtocol
+	}
+	// Ensure consistent ordering as we are looping over a map
+	sort.Ints(ports)
+	filter := &listener.ListenerFilter{
+		Name:           wellknown.ProxyProtocol,
+		ConfigType:     xdsfilters.ProxyProtocol.ConfigType,
+		FilterDisabled: listenerPredicateExcludePorts(ports),
+	}
+	return filter
+}
+
 // buildHTTPInspector creates an http inspector filter. Based on the configured ports, this may be enabled
 // for only some ports.
 func buildHTTPInspector(inspectors map[int]enabledInspector) *listener.ListenerFilter {
@@ -294,12 +353,12 @@ func NewListenerBuilder(node *model.Proxy, push *model.PushContext) *ListenerBui
 }
 
 func (lb *ListenerBuilder) buildSidecarInboundListeners(configgen *ConfigGeneratorImpl) *ListenerBuilder {
-	lb.inboundListeners = configgen.buildSidecarInboundListeners(lb.node, lb.push)
+	lb.inboundListeners = configgen.buildSidecarInboundListeners(lb.node, lb.push, lb.ctx)
 	return lb
 }
 
 func (lb *ListenerBuilder) buildSidecarOutboundListeners(configgen *ConfigGeneratorImpl) *ListenerBuilder {
-	lb.outboundListeners = configgen.buildSidecarOutboundListeners(lb.node, lb.push)
+	lb.outboundListeners = configgen.buildSidecarOutboundListeners(lb.node, lb.push, lb.ctx)
 	return lb
 }
 
@@ -348,7 +407,7 @@ func (lb *ListenerBuilder) buildVirtualInboundListener(configgen *ConfigGenerato
 
 	actualWildcard, _ := getActualWildcardAndLocalHost(lb.node)
 	// add an extra listener that binds to the port that is the recipient of the iptables redirect
-	filterChains, needTLSForPassThroughFilterChain := buildInboundCatchAllNetworkFilterChains(configgen, lb.node, lb.push)
+	filterChains, passThroughInspectors := buildInboundCatchAllNetworkFilterChains(configgen, lb.node, lb.push)
 	if features.EnableProtocolSniffingForInbound {
 		filterChains = append(filterChains, buildInboundCatchAllHTTPFilterChains(configgen, lb.node, lb.push)...)
 	}
@@ -360,7 +419,7 @@ func (lb *ListenerBuilder) buildVirtualInboundListener(configgen *ConfigGenerato
 		TrafficDirection:                    core.TrafficDirection_INBOUND,
 		FilterChains:                        filterChains,
 	}
-	lb.aggregateVirtualInboundListener(needTLSForPassThroughFilterChain)
+	lb.aggregateVirtualInboundListener(passThroughInspectors)
 
 	return lb
 }
@@ -407,7 +466,7 @@ func (lb *ListenerBuilder) getListeners() []*listener.Listener {
 
 		nListener := nInbound + nOutbound + nHTTPProxy + nVirtual + nVirtualInbound
 
-		listeners := make([]*listener.Listener, 0, nListener)
+		listeners := lb.ctx.Listeners(nListener)
 		listeners = append(listeners, lb.inboundListeners...)
 		listeners = append(listeners, lb.outboundListeners...)
 		if lb.httpProxyListener != nil {
@@ -428,6 +487,7 @@ func (lb *ListenerBuilder) getListeners() []*listener.Listener {
 			nHTTPProxy,
 			nVirtual,
 			nVirtualInbound)
+		lb.ctx.KeepListeners(listeners)
 		return listeners
 	}
 
@@ -435,10 +495,9 @@ func (lb *ListenerBuilder) getListeners() []*listener.Listener {
 }
 
 // Create pass through filter chains matching ipv4 address and ipv6 address independently.
-// This function also returns a boolean indicating whether or not the TLS inspector is needed
-// for the filter chain.
+// This function also returns the listener filter inspectors needed for the filter chains.
 func buildInboundCatchAllNetworkFilterChains(configgen *ConfigGeneratorImpl,
-	node *model.Proxy, push *model.PushContext) ([]*listener.FilterChain, bool) {
+	node *model.Proxy, push *model.PushContext) ([]*listener.FilterChain, enabledInspector) {
 	// ipv4 and ipv6 feature detect
 	ipVersions := make([]string, 0, 2)
 	if node.SupportsIPv4() {
@@ -449,7 +508,7 @@ func buildInboundCatchAllNetworkFilterChains(configgen *ConfigGeneratorImpl,
 	}
 	filterChains := make([]*listener.FilterChain, 0, 2)
 
-	needTLS := false
+	inspectors := enabledInspector{}
 	for _, clusterName := range ipVersions {
 		tcpProxy := &tcp.TcpProxy{
 			StatPrefix:       clusterName,
@@ -463,7 +522,8 @@ func buildInboundCatchAllNetworkFilterChains(configgen *ConfigGeneratorImpl,
 			matchingIP = "::0/0"
 		}
 
-		accessLogBuilder.setTCPAccessLog(push.Mesh, tcpProxy)
+		// The destination port is unknown, only the sampling of the workload applies.
+		accessLogBuilder.setTCPAccessLog(push, tcpProxy, inboundAccessLogSampling(node, 0))
 		tcpProxyFilter := &listener.Filter{
 			Name:       wellknown.TCPProxy,
 			ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(tcpProxy)},
@@ -482,7 +542,7 @@ func buildInboundCatchAllNetworkFilterChains(configgen *ConfigGeneratorImpl,
 
 		if len(allChains) == 0 {
 			// Add one empty entry to the list if none of the plugins are interested in updating the filter chains.
-			allChains = []istionetworking.FilterChain{{}}
+			allChains = []istionetworking.FilterChain{defaultInboundFilterChain(node)}
 		}
 		// Override the filter chain match to make sure the pass through filter chain captures the pass through traffic.
 		for i := range allChains {
@@ -522,9 +582,11 @@ func buildInboundCatchAllNetworkFilterChains(configgen *ConfigGeneratorImpl,
 				}
 			}
 			for _, filter := range chain.ListenerFilters {
-				if filter.Name == wellknown.TlsInspector {
-					needTLS = true
-					break
+				switch filter.Name {
+				case wellknown.TlsInspector:
+					inspectors.TLSInspector = true
+				case wellknown.ProxyProtocol:
+					inspectors.ProxyProtocol = true
 				}
 			}
 			filterChain.Name = VirtualInboundListenerName
@@ -532,7 +594,7 @@ func buildInboundCatchAllNetworkFilterChains(configgen *ConfigGeneratorImpl,
 		}
 	}
 
-	return filterChains, needTLS
+	return filterChains, inspectors
 }
 
 func buildInboundCatchAllHTTPFilterChains(configgen *ConfigGeneratorImpl, node *model.Proxy, push *model.PushContext) []*listener.FilterChain {
@@ -604,6 +666,7 @@ func buildInboundCatchAllHTTPFilterChains(configgen *ConfigGeneratorImpl, node *
 			proxy: node,
 			bind:  matchingIP,
 			port:  port,
+			class: ListenerClassSidecarInbound,
 		}
 		// Construct the actual filter chains for each of the filter chain from the plugin.
 		for _, chain := range allChains {
@@ -616,9 +679,12 @@ func buildInboundCatchAllHTTPFilterChains(configgen *ConfigGeneratorImpl, node *
 				ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(connectionManager)},
 			}
 
+			// Copy the network filters of the plugins, which may be shared, before appending the connection manager.
+			filters := make([]*listener.Filter, 0, len(chain.TCP)+1)
+			filters = append(filters, chain.TCP...)
 			filterChain := &listener.FilterChain{
 				FilterChainMatch: chain.FilterChainMatch,
-				Filters:          []*listener.Filter{filter},
+				Filters:          append(filters, filter),
 			}
 			if chain.TLSContext != nil {
 				filterChain.FilterChainMatch.TransportProtocol = "tls"
@@ -663,7 +729,7 @@ func buildOutboundCatchAllNetworkFiltersOnly(push *model.PushContext, node *mode
 		StatPrefix:       egressCluster,
 		ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: egressCluster},
 	}
-	accessLogBuilder.setTCPAccessLog(push.Mesh, tcpProxy)
+	accessLogBuilder.setTCPAccessLog(push, tcpProxy, accessLogSampling{})
 	filterStack = append(filterStack, &listener.Filter{
 		Name:       wellknown.TCPProxy,
 		ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(tcpProxy)},
@@ -22,18 +22,23 @@ import (
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbactcppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
 	wellknown "github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/gogo/protobuf/jsonpb"
 	"github.com/gogo/protobuf/types"
+	"github.com/golang/protobuf/ptypes"
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	"istio.io/istio/pilot/pkg/networking/util"
+	authn_utils "istio.io/istio/pilot/pkg/security/authn/utils"
+	authz_model "istio.io/istio/pilot/pkg/security/authz/model"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
 )
@@ -709,6 +714,196 @@ func TestInboundListenerFilters(t *testing.T) {
 	}
 }
 
+func TestInboundListenerProxyProtocol(t *testing.T) {
+	services := []*model.Service{
+		buildServiceWithPort("test1.com", 80, protocol.HTTP, tnow),
+		buildServiceWithPort("test2.com", 81, protocol.Unsupported, tnow),
+		buildServiceWithPort("test3.com", 82, protocol.TCP, tnow),
+	}
+	instances := make([]*model.ServiceInstance, 0, len(services))
+	for _, s := range services {
+		instances = append(instances, &model.ServiceInstance{
+			Service: s,
+			Endpoint: &model.IstioEndpoint{
+				EndpointPort: uint32(s.Ports[0].Port),
+				Address:      "1.1.1.1",
+			},
+			ServicePort: s.Ports[0],
+		})
+	}
+	for _, tt := range []struct {
+		name   string
+		config string
+		// mxcPorts are the ports with a filter chain for the TCP metadata exchange ALPN.
+		mxcPorts []int
+	}{
+		{"permissive", "", []int{81, 82}},
+		{"disable", disableMode, nil},
+		{"strict", strictMode, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := NewConfigGenTest(t, TestOptions{
+				Services:     services,
+				Instances:    instances,
+				ConfigString: tt.config,
+			})
+			listeners := cg.Listeners(cg.SetupProxy(&model.Proxy{
+				Metadata: &model.NodeMetadata{InboundProxyProtocol: "1"},
+			}))
+			virtualInbound := xdstest.ExtractListener("virtualInbound", listeners)
+			var names []string
+			for _, lf := range virtualInbound.ListenerFilters {
+				names = append(names, lf.Name)
+			}
+			// The header must be consumed first, and the original destination restored after it.
+			if len(names) < 3 || names[0] != wellknown.ProxyProtocol || names[1] != wellknown.OriginalDestination ||
+				names[2] != wellknown.TlsInspector {
+				t.Fatalf("expected listener filters to start with [%q, %q, %q], found %v",
+					wellknown.ProxyProtocol, wellknown.OriginalDestination, wellknown.TlsInspector, names)
+			}
+			evaluateListenerFilterPredicates(t, virtualInbound.ListenerFilters[0].FilterDisabled, map[int]bool{
+				80:   false,
+				81:   false,
+				82:   false,
+				1000: false,
+			})
+			// The metadata exchange is negotiated with the ALPN of the TLS handshake, which the TLS inspector can
+			// only read once the header is consumed.
+			for _, port := range tt.mxcPorts {
+				if !hasMxcFilterChain(virtualInbound, port) {
+					t.Errorf("expected a filter chain for the %q ALPN on port %d", tcpMxcALPN, port)
+				}
+			}
+			for _, l := range listeners {
+				if l.Name == virtualInbound.Name {
+					continue
+				}
+				if _, f := xdstest.ExtractListenerFilters(l)[wellknown.ProxyProtocol]; f {
+					t.Errorf("unexpected proxy_protocol listener filter in listener %s", l.Name)
+				}
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		cg := NewConfigGenTest(t, TestOptions{
+			Services:  services,
+			Instances: instances,
+		})
+		for _, l := range cg.Listeners(cg.SetupProxy(nil)) {
+			if _, f := xdstest.ExtractListenerFilters(l)[wellknown.ProxyProtocol]; f {
+				t.Errorf("unexpected proxy_protocol listener filter in listener %s", l.Name)
+			}
+		}
+	})
+}
+
+func TestInboundListenerTrustDomainStats(t *testing.T) {
+	defer func(old bool) { features.TrustDomainAliasStats = old }(features.TrustDomainAliasStats)
+	features.TrustDomainAliasStats = true
+
+	services := []*model.Service{
+		buildServiceWithPort("test1.com", 80, protocol.HTTP, tnow),
+		buildServiceWithPort("test2.com", 81, protocol.Unsupported, tnow),
+		buildServiceWithPort("test3.com", 82, protocol.TCP, tnow),
+	}
+	instances := make([]*model.ServiceInstance, 0, len(services))
+	for _, s := range services {
+		instances = append(instances, &model.ServiceInstance{
+			Service: s,
+			Endpoint: &model.IstioEndpoint{
+				EndpointPort: uint32(s.Ports[0].Port),
+				Address:      "1.1.1.1",
+			},
+			ServicePort: s.Ports[0],
+		})
+	}
+	for _, tt := range []struct {
+		name    string
+		config  string
+		aliases []string
+		// want are the trust domains counted on the mTLS filter chains.
+		want []string
+	}{
+		{"permissive", "", []string{"old.local"}, []string{"cluster.local", "old.local"}},
+		{"strict", strictMode, []string{"old.local", "older.local"}, []string{"cluster.local", "old.local", "older.local"}},
+		{"disable", disableMode, []string{"old.local"}, nil},
+		{"no aliases", strictMode, nil, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mesh.DefaultMeshConfig()
+			m.TrustDomainAliases = tt.aliases
+			cg := NewConfigGenTest(t, TestOptions{
+				Services:     services,
+				Instances:    instances,
+				ConfigString: tt.config,
+				MeshConfig:   &m,
+			})
+			virtualInbound := xdstest.ExtractListener("virtualInbound", cg.Listeners(cg.SetupProxy(nil)))
+			// The filters are added to the filter chains of the service ports by OnInboundListener, and to the
+			// passthrough TCP and HTTP filter chains by OnInboundPassthrough.
+			counted := map[string]bool{}
+			for _, fc := range virtualInbound.FilterChains {
+				got := trustDomainStats(t, fc)
+				if fc.TransportSocket == nil {
+					if len(got) > 0 {
+						t.Errorf("unexpected trust domain stats %v on plaintext filter chain %s", got, fc.Name)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("filter chain %s: got trust domain stats %v, want %v", fc.Name, got, tt.want)
+				}
+				switch fc.Name {
+				case VirtualInboundListenerName, virtualInboundCatchAllHTTPFilterChainName:
+					counted[fc.Name] = true
+				default:
+					counted["service"] = true
+				}
+			}
+			for _, kind := range []string{"service", VirtualInboundListenerName, virtualInboundCatchAllHTTPFilterChainName} {
+				if tt.want != nil && !counted[kind] {
+					t.Errorf("expected %s mTLS filter chains", kind)
+				}
+			}
+		})
+	}
+}
+
+// trustDomainStats returns the trust domains counted by the shadow RBAC filters of the filter chain.
+func trustDomainStats(t *testing.T, fc *listener.FilterChain) []string {
+	t.Helper()
+	var trustDomains []string
+	for _, f := range fc.Filters {
+		if f.Name != authz_model.RBACTCPFilterName {
+			continue
+		}
+		rbac := &rbactcppb.RBAC{}
+		if err := ptypes.UnmarshalAny(f.GetTypedConfig(), rbac); err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(rbac.StatPrefix, authn_utils.TrustDomainStatPrefix) {
+			trustDomains = append(trustDomains,
+				strings.TrimSuffix(strings.TrimPrefix(rbac.StatPrefix, authn_utils.TrustDomainStatPrefix), "."))
+		}
+	}
+	return trustDomains
+}
+
+func hasMxcFilterChain(l *listener.Listener, port int) bool {
+	for _, fc := range l.FilterChains {
+		if fc.FilterChainMatch.GetDestinationPort().GetValue() != uint32(port) {
+			continue
+		}
+		for _, alpn := range fc.FilterChainMatch.GetApplicationProtocols() {
+			if alpn == tcpMxcALPN {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func evaluateListenerFilterPredicates(t testing.TB, predicate *listener.ListenerFilterChainMatchPredicate, expected map[int]bool) {
 	t.Helper()
 	for port, expect := range expected {
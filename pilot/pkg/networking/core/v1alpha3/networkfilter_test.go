@@ -99,7 +99,7 @@ func TestInboundNetworkFilterStatPrefix(t *testing.T) {
 				Endpoint: &model.IstioEndpoint{},
 			}
 
-			listeners := buildInboundNetworkFilters(env.PushContext, instance)
+			listeners := buildInboundNetworkFilters(env.PushContext, &model.Proxy{Metadata: &model.NodeMetadata{}}, instance)
 			tcp := &tcp.TcpProxy{}
 			ptypes.UnmarshalAny(listeners[0].GetTypedConfig(), tcp)
 			if tcp.StatPrefix != tt.expectedStatPrefix {
@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	"istio.io/istio/pilot/pkg/security/authn/factory"
+	authn_utils "istio.io/istio/pilot/pkg/security/authn/utils"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/labels"
 )
@@ -60,7 +61,15 @@ func (Plugin) OnInboundListener(in *plugin.InputParams, mutable *networking.Muta
 		// Only care about sidecar.
 		return nil
 	}
-	return buildFilter(in, mutable)
+	if err := buildFilter(in, mutable); err != nil {
+		return err
+	}
+	for i := range mutable.Listener.FilterChains {
+		// Only the mTLS filter chains authenticate the trust domain of the peer.
+		filters := authn_utils.BuildTrustDomainStatsFiltersForTransportSocket(mutable.Listener.FilterChains[i].TransportSocket)
+		mutable.FilterChains[i].TCP = append(mutable.FilterChains[i].TCP, filters...)
+	}
+	return nil
 }
 
 func buildFilter(in *plugin.InputParams, mutable *networking.MutableObjects) error {
@@ -92,6 +101,11 @@ func buildFilter(in *plugin.InputParams, mutable *networking.MutableObjects) err
 
 // OnInboundPassthrough is called whenever a new passthrough filter chain is added to the LDS output.
 func (Plugin) OnInboundPassthrough(in *plugin.InputParams, mutable *networking.MutableObjects) error {
+	for i := range mutable.FilterChains {
+		// Only the mTLS filter chains authenticate the trust domain of the peer.
+		filters := authn_utils.BuildTrustDomainStatsFiltersForTLSContext(mutable.FilterChains[i].TLSContext)
+		mutable.FilterChains[i].TCP = append(mutable.FilterChains[i].TCP, filters...)
+	}
 	return nil
 }
 
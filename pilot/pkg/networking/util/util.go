@@ -44,6 +44,7 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/util/strcase"
 	"istio.io/pkg/log"
 )
@@ -406,6 +407,24 @@ func BuildConfigInfoMetadata(config config.Meta) *core.Metadata {
 	}
 }
 
+// ConfigKeyFromMetadata returns the config of the "istio" metadata built by BuildConfigInfoMetadata, or nil if the
+// metadata does not reference a config.
+func ConfigKeyFromMetadata(md *core.Metadata) *model.ConfigKey {
+	s := md.GetFilterMetadata()[IstioMetadataKey].GetFields()["config"].GetStringValue()
+	// /apis/<group>/<version>/namespaces/<namespace>/<kind>/<name>
+	parts := strings.Split(s, "/")
+	if len(parts) != 8 || parts[1] != "apis" || parts[4] != "namespaces" {
+		return nil
+	}
+	for _, c := range collections.Pilot.All() {
+		gvk := c.Resource().GroupVersionKind()
+		if gvk.Group == parts[2] && gvk.Version == parts[3] && strcase.CamelCaseToKebabCase(gvk.Kind) == parts[6] {
+			return &model.ConfigKey{Kind: gvk, Name: parts[7], Namespace: parts[5]}
+		}
+	}
+	return nil
+}
+
 // AddSubsetToMetadata will build a new core.Metadata struct containing the
 // subset name supplied. This is used for telemetry reporting. A new core.Metadata
 // is created to prevent modification to shared base Metadata across subsets, etc.
@@ -382,8 +382,15 @@ func TestBuildConfigInfoMetadata(t *testing.T) {
 			if diff, equal := messagediff.PrettyDiff(got, v.want); !equal {
 				tt.Errorf("BuildConfigInfoMetadata(%v) produced incorrect result:\ngot: %v\nwant: %v\nDiff: %s", v.in, got, v.want, diff)
 			}
+			want := &model.ConfigKey{Kind: v.in.GroupVersionKind, Name: v.in.Name, Namespace: v.in.Namespace}
+			if key := ConfigKeyFromMetadata(got); !reflect.DeepEqual(key, want) {
+				tt.Errorf("ConfigKeyFromMetadata(%v): got %v, want %v", got, key, want)
+			}
 		})
 	}
+	if key := ConfigKeyFromMetadata(nil); key != nil {
+		t.Errorf("ConfigKeyFromMetadata(nil): got %v, want nil", key)
+	}
 }
 
 func TestAddSubsetToMetadata(t *testing.T) {
@@ -0,0 +1,383 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package onboarding serves the bundle of files a workload running outside of Kubernetes, such as a VM, needs to
+// join the mesh as an instance of a WorkloadGroup.
+package onboarding
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/istio-agent/health"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+	"istio.io/istio/security/pkg/server/ca/authenticate"
+	"istio.io/pkg/log"
+)
+
+// Path is the path of the onboarding handler.
+const Path = "/onboarding/workloadgroup"
+
+// The files of a bundle.
+const (
+	RootCertFile   = "root-cert.pem"
+	ClusterEnvFile = "cluster.env"
+	MeshConfigFile = "mesh.yaml"
+	TokenFile      = "istio-token"
+)
+
+// HealthProbeAnnotation holds the JSON health probe of the application of the workloads of a WorkloadGroup, with
+// the httpGet or tcpSocket action and the timings of a Kubernetes readiness probe. It is passed to the agent of the
+// workload in the mesh config of the bundle.
+const HealthProbeAnnotation = "istio.io/health-probe"
+
+var onboardingLog = log.RegisterScope("onboarding", "workload onboarding debugging", 0)
+
+// Options configure the onboarding Handler.
+type Options struct {
+	// Env provides the mesh config, the proxy config overrides and the WorkloadGroups.
+	Env *model.Environment
+	// Authenticators authenticate the callers, as for the CSRs of the CA.
+	Authenticators []authenticate.Authenticator
+	// RootCert returns the PEM encoded root certificate of the mesh.
+	RootCert func() []byte
+	// KubeClient creates the service account tokens. The bundles include no token if nil.
+	KubeClient kubernetes.Interface
+	// ClusterID is the ID of the cluster of the WorkloadGroups.
+	ClusterID string
+	// TokenTTL is the lifetime of the service account tokens.
+	TokenTTL time.Duration
+}
+
+// Handler serves the onboarding bundle of the ?namespace= and ?name= WorkloadGroup: a tar.gz of the root
+// certificate of the mesh, the cluster.env and mesh.yaml of the workload, and a short-lived token of the service
+// account of the WorkloadGroup. The caller must be authenticated with the identity of the service account of the
+// WorkloadGroup.
+type Handler struct {
+	Options
+}
+
+// NewHandler returns the onboarding handler. The tokens are valid for an hour if TokenTTL is unset.
+func NewHandler(opts Options) *Handler {
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = time.Hour
+	}
+	return &Handler{Options: opts}
+}
+
+// Register adds the handler to the mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.Handle(Path, h)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	namespace, name := req.URL.Query().Get("namespace"), req.URL.Query().Get("name")
+	if namespace == "" || name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide the namespace and name of a WorkloadGroup in the query string"))
+		return
+	}
+
+	identities, err := h.authenticate(req)
+	if err != nil {
+		onboardingLog.Warnf("unauthenticated onboarding request for %s/%s from %s: %v", namespace, name, req.RemoteAddr, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("authentication failure"))
+		return
+	}
+	cfg := h.Env.IstioConfigStore.Get(gvk.WorkloadGroup, name, namespace)
+	if cfg == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, "WorkloadGroup %s/%s not found", namespace, name)
+		return
+	}
+	wg := cfg.Spec.(*networking.WorkloadGroup)
+	serviceAccount := workloadServiceAccount(wg)
+	caller, authorized := authorize(identities, namespace, serviceAccount)
+	if !authorized {
+		onboardingLog.Warnf("onboarding request for %s/%s denied to %v", namespace, name, identities)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprintf(w, "the caller is not authorized for service account %s/%s", namespace, serviceAccount)
+		return
+	}
+
+	b, err := h.bundle(req.Context(), name, namespace, wg, cfg.Annotations[HealthProbeAnnotation])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to generate the onboarding bundle: %v", err)
+		return
+	}
+	onboardingLog.Infof("onboarding bundle of %s/%s generated for %s", namespace, name, caller)
+	w.Header().Add("Content-Type", "application/gzip")
+	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s.tar.gz", namespace, name))
+	// The bundle includes a token.
+	w.Header().Add("Cache-Control", "no-store")
+	_, _ = w.Write(b)
+}
+
+// authenticate returns the identities of the caller, authenticated by the first authenticator accepting the
+// bearer token or the client certificate of the request.
+func (h *Handler) authenticate(req *http.Request) ([]string, error) {
+	md := metadata.MD{}
+	if v := req.Header.Values("Authorization"); len(v) > 0 {
+		md["authorization"] = v
+	}
+	if v := req.Header.Get("ClusterID"); v != "" {
+		md["clusterid"] = []string{v}
+	}
+	ctx := metadata.NewIncomingContext(req.Context(), md)
+	if req.TLS != nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: *req.TLS}})
+	}
+
+	var errs []string
+	for _, authn := range h.Authenticators {
+		u, err := authn.Authenticate(ctx)
+		if err == nil && u != nil && len(u.Identities) > 0 {
+			return u.Identities, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", authn.AuthenticatorType(), err))
+	}
+	return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// authorize returns the identity of the caller authorizing it for the service account of the namespace, if any. The
+// bundle includes a token of the service account, so only callers with its identity get it.
+func authorize(identities []string, namespace, serviceAccount string) (string, bool) {
+	for _, id := range identities {
+		parsed, err := spiffe.ParseIdentity(id)
+		if err == nil && parsed.Namespace == namespace && parsed.ServiceAccount == serviceAccount {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// bundle returns the tar.gz of the onboarding files of the WorkloadGroup.
+func (h *Handler) bundle(ctx context.Context, name, namespace string, wg *networking.WorkloadGroup,
+	probe string) ([]byte, error) {
+	we := wg.Template
+	if we == nil {
+		we = &networking.WorkloadEntry{}
+	}
+	serviceAccount := workloadServiceAccount(wg)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	add := func(file string, mode int64, content []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: file, Mode: mode, Size: int64(len(content)), ModTime: now}); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+
+	var rootCert []byte
+	if h.RootCert != nil {
+		rootCert = h.RootCert()
+	}
+	if len(rootCert) == 0 {
+		return nil, fmt.Errorf("the root certificate of the mesh is not available")
+	}
+	if err := add(RootCertFile, 0644, rootCert); err != nil {
+		return nil, err
+	}
+	if err := add(ClusterEnvFile, 0644, clusterEnv(name, namespace, serviceAccount, we)); err != nil {
+		return nil, err
+	}
+	meshYAML, err := h.meshConfig(name, namespace, serviceAccount, wg, we, probe)
+	if err != nil {
+		return nil, err
+	}
+	if err := add(MeshConfigFile, 0644, meshYAML); err != nil {
+		return nil, err
+	}
+	if h.KubeClient != nil {
+		token, err := h.token(ctx, namespace, serviceAccount)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(TokenFile, 0600, []byte(token)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// workloadServiceAccount returns the service account of the workloads of the WorkloadGroup, default if unset.
+func workloadServiceAccount(wg *networking.WorkloadGroup) string {
+	if wg.Template == nil || wg.Template.ServiceAccount == "" {
+		return "default"
+	}
+	return wg.Template.ServiceAccount
+}
+
+// clusterEnv returns the cluster.env of the workload, with a variable per line sorted by name.
+func clusterEnv(name, namespace, serviceAccount string, we *networking.WorkloadEntry) []byte {
+	ports := make([]string, 0, len(we.Ports))
+	for _, p := range we.Ports {
+		ports = append(ports, fmt.Sprint(p))
+	}
+	sort.Strings(ports)
+	// Capture all the inbound traffic if no port is set.
+	inboundPorts := "*"
+	if len(ports) > 0 {
+		inboundPorts = strings.Join(ports, ",")
+	}
+	env := map[string]string{
+		"ISTIO_INBOUND_PORTS": inboundPorts,
+		"ISTIO_NAMESPACE":     namespace,
+		"ISTIO_SERVICE":       fmt.Sprintf("%s.%s", name, namespace),
+		"ISTIO_SERVICE_CIDR":  "*",
+		"SERVICE_ACCOUNT":     serviceAccount,
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, env[k])
+	}
+	return []byte(b.String())
+}
+
+// meshConfig returns the mesh.yaml of the workload: the proxy config of the mesh, with the ProxyConfigOverrides
+// of the namespace and labels of the WorkloadGroup applied, and the metadata of the workload, including its health
+// probe if any.
+func (h *Handler) meshConfig(name, namespace, serviceAccount string, wg *networking.WorkloadGroup,
+	we *networking.WorkloadEntry, probe string) ([]byte, error) {
+	m := h.Env.Mesh()
+	if m == nil {
+		return nil, fmt.Errorf("the mesh config is not available")
+	}
+
+	labels := map[string]string{}
+	if wg.Metadata != nil {
+		for k, v := range wg.Metadata.Labels {
+			labels[k] = v
+		}
+	}
+	// The labels of the template take precedence over those of the metadata.
+	for k, v := range we.Labels {
+		labels[k] = v
+	}
+
+	pc, err := h.Env.ProxyConfigOverrides().EffectiveProxyConfig(m.DefaultConfig, namespace, labels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy config overrides for the workload: %v", err)
+	}
+	if pc == nil {
+		pc = &meshconfig.ProxyConfig{}
+	}
+	pc = proto.Clone(pc).(*meshconfig.ProxyConfig)
+
+	md := map[string]string{}
+	for k, v := range pc.ProxyMetadata {
+		md[k] = v
+	}
+	md["CANONICAL_SERVICE"], md["CANONICAL_REVISION"] = inject.ExtractCanonicalServiceLabels(labels, name)
+	md["POD_NAMESPACE"] = namespace
+	md["SERVICE_ACCOUNT"] = serviceAccount
+	md["TRUST_DOMAIN"] = m.TrustDomain
+	md["ISTIO_META_CLUSTER_ID"] = h.ClusterID
+	md["ISTIO_META_MESH_ID"] = pc.MeshId
+	md["ISTIO_META_NETWORK"] = we.Network
+	md["ISTIO_META_WORKLOAD_NAME"] = name
+	if len(we.Ports) > 0 {
+		b, err := json.Marshal(we.Ports)
+		if err != nil {
+			return nil, err
+		}
+		md["ISTIO_META_POD_PORTS"] = string(b)
+	}
+	if probe != "" {
+		p, err := health.ParseProbe(probe)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation of the WorkloadGroup: %v", HealthProbeAnnotation, err)
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		md[health.ProbeProxyMetadata] = string(b)
+	}
+	labels["service.istio.io/canonical-name"] = md["CANONICAL_SERVICE"]
+	labels["service.istio.io/canonical-version"] = md["CANONICAL_REVISION"]
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return nil, err
+	}
+	md["ISTIO_METAJSON_LABELS"] = string(b)
+	pc.ProxyMetadata = md
+
+	yml, err := gogoprotomarshal.ToYAML(pc)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(yml), nil
+}
+
+// token returns a token of the service account, valid for TokenTTL, for the audiences of the CA.
+func (h *Handler) token(ctx context.Context, namespace, serviceAccount string) (string, error) {
+	ttl := int64(h.TokenTTL.Seconds())
+	tr, err := h.KubeClient.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, serviceAccount,
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				Audiences:         security.TokenAudiences,
+				ExpirationSeconds: &ttl,
+			},
+		}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("could not create a token of service account %s/%s: %v", namespace, serviceAccount, err)
+	}
+	return tr.Status.Token, nil
+}
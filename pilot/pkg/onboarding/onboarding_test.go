@@ -0,0 +1,256 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onboarding
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/istio-agent/health"
+	"istio.io/istio/pkg/jwt"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+	"istio.io/istio/security/pkg/server/ca/authenticate"
+)
+
+const rootCert = "-----BEGIN CERTIFICATE-----\nroot\n-----END CERTIFICATE-----\n"
+
+// callers are the service accounts of the bearer tokens accepted by the API server.
+var callers = map[string]string{
+	"vm-token":         "system:serviceaccount:vm:vm-sa",
+	"vm-default-token": "system:serviceaccount:vm:default",
+	"other-token":      "system:serviceaccount:other:default",
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	store := model.MakeIstioStore(memory.Make(collections.Pilot))
+	if _, err := store.Create(config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.WorkloadGroup,
+			Name:             "reviews",
+			Namespace:        "vm",
+		},
+		Spec: &networking.WorkloadGroup{
+			Metadata: &networking.WorkloadGroup_ObjectMeta{
+				Labels:      map[string]string{"app": "reviews", "version": "v2"},
+				Annotations: map[string]string{"internal": "do-not-leak"},
+			},
+			Template: &networking.WorkloadEntry{
+				Ports:          map[string]uint32{"http": 9080},
+				Network:        "vm-network",
+				ServiceAccount: "vm-sa",
+				Labels:         map[string]string{"tier": "backend"},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := mesh.DefaultMeshConfig()
+	m.TrustDomain = "example.com"
+	m.DefaultConfig.DiscoveryAddress = "istiod.istio-system.svc:15012"
+	m.DefaultConfig.MeshId = "mesh1"
+	env := &model.Environment{
+		Watcher:          mesh.NewFixedWatcher(&m),
+		IstioConfigStore: store,
+	}
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		username, f := callers[review.Spec.Token]
+		review.Status = authenticationv1.TokenReviewStatus{
+			Authenticated: f,
+			User:          authenticationv1.UserInfo{Username: username, Groups: []string{"system:serviceaccounts"}},
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "serviceaccounts", func(action ktesting.Action) (bool, runtime.Object, error) {
+		create := action.(ktesting.CreateActionImpl)
+		tr := create.GetObject().(*authenticationv1.TokenRequest)
+		if *tr.Spec.ExpirationSeconds != int64((10 * time.Minute).Seconds()) {
+			t.Errorf("unexpected token expiration %d", *tr.Spec.ExpirationSeconds)
+		}
+		tr.Status.Token = "token-of-" + create.GetNamespace() + "/" + create.Name
+		return true, tr, nil
+	})
+
+	return NewHandler(Options{
+		Env: env,
+		Authenticators: []authenticate.Authenticator{
+			authenticate.NewKubeJWTAuthenticator(client, "Kubernetes", nil, "example.com", jwt.PolicyThirdParty),
+		},
+		RootCert:   func() []byte { return []byte(rootCert) },
+		KubeClient: client,
+		ClusterID:  "cluster1",
+		TokenTTL:   10 * time.Minute,
+	})
+}
+
+func serve(h *Handler, token, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", Path+"?"+query, nil)
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func readBundle(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(content)
+	}
+	return files
+}
+
+func TestOnboardingBundle(t *testing.T) {
+	h := newTestHandler(t)
+	w := serve(h, "vm-token", "namespace=vm&name=reviews")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("expected the bundle not to be cached, got %q", got)
+	}
+	files := readBundle(t, w.Body.Bytes())
+	if len(files) != 4 {
+		t.Errorf("unexpected files %v", files)
+	}
+
+	if files[RootCertFile] != rootCert {
+		t.Errorf("got root cert %q", files[RootCertFile])
+	}
+	if files[TokenFile] != "token-of-vm/vm-sa" {
+		t.Errorf("got token %q", files[TokenFile])
+	}
+	wantEnv := "ISTIO_INBOUND_PORTS=9080\nISTIO_NAMESPACE=vm\nISTIO_SERVICE=reviews.vm\nISTIO_SERVICE_CIDR=*\n" +
+		"SERVICE_ACCOUNT=vm-sa\n"
+	if files[ClusterEnvFile] != wantEnv {
+		t.Errorf("got cluster.env\n%s\nwant\n%s", files[ClusterEnvFile], wantEnv)
+	}
+
+	pc := &meshconfig.ProxyConfig{}
+	if err := gogoprotomarshal.ApplyYAML(files[MeshConfigFile], pc); err != nil {
+		t.Fatalf("invalid mesh.yaml: %v", err)
+	}
+	if pc.DiscoveryAddress != "istiod.istio-system.svc:15012" {
+		t.Errorf("got discovery address %q", pc.DiscoveryAddress)
+	}
+	md := pc.ProxyMetadata
+	for k, want := range map[string]string{
+		"TRUST_DOMAIN":             "example.com",
+		"ISTIO_META_NETWORK":       "vm-network",
+		"ISTIO_META_CLUSTER_ID":    "cluster1",
+		"ISTIO_META_MESH_ID":       "mesh1",
+		"ISTIO_META_WORKLOAD_NAME": "reviews",
+		"POD_NAMESPACE":            "vm",
+		"SERVICE_ACCOUNT":          "vm-sa",
+		"CANONICAL_SERVICE":        "reviews",
+		"CANONICAL_REVISION":       "v2",
+	} {
+		if md[k] != want {
+			t.Errorf("got %s=%q, want %q", k, md[k], want)
+		}
+	}
+	probe, err := health.ParseProbe(md[health.ProbeProxyMetadata])
+	if err != nil {
+		t.Fatalf("invalid health probe: %v", err)
+	}
+	if probe.HTTPGet == nil || probe.HTTPGet.Path != "/ready" || probe.HTTPGet.Port.IntValue() != 9080 || probe.PeriodSeconds != 5 {
+		t.Errorf("unexpected health probe %+v", probe)
+	}
+	labels := map[string]string{}
+	if err := json.Unmarshal([]byte(md["ISTIO_METAJSON_LABELS"]), &labels); err != nil {
+		t.Fatal(err)
+	}
+	if labels["app"] != "reviews" || labels["version"] != "v2" || labels["tier"] != "backend" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+
+	for name, content := range files {
+		if strings.Contains(content, "do-not-leak") {
+			t.Errorf("%s includes the annotations of the WorkloadGroup", name)
+		}
+	}
+}
+
+func TestOnboardingAuthorization(t *testing.T) {
+	h := newTestHandler(t)
+	cases := []struct {
+		name  string
+		token string
+		query string
+		code  int
+	}{
+		{"no token", "", "namespace=vm&name=reviews", http.StatusUnauthorized},
+		{"invalid token", "invalid", "namespace=vm&name=reviews", http.StatusUnauthorized},
+		{"wrong namespace", "other-token", "namespace=vm&name=reviews", http.StatusForbidden},
+		{"wrong service account", "vm-default-token", "namespace=vm&name=reviews", http.StatusForbidden},
+		{"missing name", "vm-token", "namespace=vm", http.StatusBadRequest},
+		{"unknown WorkloadGroup", "vm-token", "namespace=vm&name=ratings", http.StatusNotFound},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(h, tt.token, tt.query)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), "token-of-") || strings.Contains(w.Body.String(), "BEGIN CERTIFICATE") {
+				t.Fatalf("the rejected request got a bundle")
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"sync"
+
+	"istio.io/istio/pilot/pkg/secrets"
+	kubelib "istio.io/istio/pkg/kube"
+	"istio.io/pkg/log"
+)
+
+// Multicluster holds a SecretsController per cluster, so that gateways read secrets from the cluster
+// they run in rather than from the config cluster. Remote clusters are added and removed by the
+// multicluster service registry through ClusterAdded and ClusterDeleted.
+type Multicluster struct {
+	configCluster string
+	scope         Scope
+
+	m           sync.Mutex // protects controllers and handlers
+	controllers map[string]*SecretsController
+	handlers    []func(name, namespace string)
+}
+
+var _ secrets.MulticlusterController = &Multicluster{}
+
+// NewMulticluster creates a Multicluster resolving secrets of the config cluster with the given controller.
+// Controllers of remote clusters are restricted to the same scope as the config cluster one.
+func NewMulticluster(configCluster string, configController *SecretsController, scope Scope) *Multicluster {
+	return &Multicluster{
+		configCluster: configCluster,
+		scope:         scope,
+		controllers:   map[string]*SecretsController{configCluster: configController},
+	}
+}
+
+// ClusterAdded creates the SecretsController of a remote cluster. Unscoped controllers use the shared
+// informers of the clients, which are expected to be started by the caller.
+func (m *Multicluster) ClusterAdded(clients kubelib.Client, clusterID string, stop <-chan struct{}) error {
+	var sc *SecretsController
+	if m.scope.IsEmpty() {
+		sc = NewSecretsController(clients.KubeInformer().Core().V1().Secrets())
+	} else {
+		var err error
+		if sc, err = NewScopedSecretsController(clients.Kube(), m.scope); err != nil {
+			return err
+		}
+		go sc.Run(stop)
+	}
+	log.Infof("initializing Kubernetes credential reader for cluster %s", clusterID)
+
+	m.m.Lock()
+	defer m.m.Unlock()
+	for _, h := range m.handlers {
+		sc.AddEventHandler(h)
+	}
+	m.controllers[clusterID] = sc
+	return nil
+}
+
+// ClusterDeleted drops the SecretsController of a remote cluster. Its informers are stopped by the caller.
+func (m *Multicluster) ClusterDeleted(clusterID string) error {
+	if clusterID == m.configCluster {
+		return nil
+	}
+	m.m.Lock()
+	defer m.m.Unlock()
+	delete(m.controllers, clusterID)
+	return nil
+}
+
+// ForCluster returns the SecretsController of the cluster. An empty cluster resolves to the config cluster.
+func (m *Multicluster) ForCluster(clusterID string) (secrets.Controller, error) {
+	if clusterID == "" {
+		clusterID = m.configCluster
+	}
+	m.m.Lock()
+	defer m.m.Unlock()
+	sc, f := m.controllers[clusterID]
+	if !f {
+		return nil, fmt.Errorf("cluster %s is not known to the credential reader", clusterID)
+	}
+	return sc, nil
+}
+
+// AddEventHandler registers the handler for secret events of all current and future clusters.
+func (m *Multicluster) AddEventHandler(f func(name, namespace string)) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.handlers = append(m.handlers, f)
+	for _, sc := range m.controllers {
+		sc.AddEventHandler(f)
+	}
+}
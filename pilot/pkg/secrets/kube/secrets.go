@@ -15,10 +15,18 @@
 package kube
 
 import (
+	"context"
+	"fmt"
+	"reflect"
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	informersv1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 
 	"istio.io/istio/pilot/pkg/secrets"
@@ -45,20 +53,110 @@ const (
 	GatewaySdsCaSuffix = "-cacert"
 )
 
+// Scope restricts the secrets watched by a SecretsController.
+type Scope struct {
+	// Namespaces to watch secrets in. If empty, secrets in all namespaces are watched.
+	Namespaces []string
+	// LabelSelector restricts the watched secrets to the ones matching it. If empty, all secrets are watched.
+	LabelSelector string
+}
+
+// IsEmpty returns true if the scope does not restrict the watched secrets.
+func (s Scope) IsEmpty() bool {
+	return len(s.Namespaces) == 0 && s.LabelSelector == ""
+}
+
+type secretInformer struct {
+	informer cache.SharedIndexInformer
+	lister   listersv1.SecretLister
+}
+
 type SecretsController struct {
-	secrets informersv1.SecretInformer
+	scope Scope
+	// client and selector are set for scoped controllers, to tell secrets filtered out by the label
+	// selector from missing ones.
+	client   kubernetes.Interface
+	selector labels.Selector
+	// informers are keyed by the namespace they watch, which is metav1.NamespaceAll when not restricted.
+	informers map[string]secretInformer
 }
 
 var _ secrets.Controller = &SecretsController{}
 
 func NewSecretsController(informer informersv1.SecretInformer) *SecretsController {
 	// Informer is lazy loaded, load it now
-	_ = informer.Informer()
-	return &SecretsController{informer}
+	return &SecretsController{
+		informers: map[string]secretInformer{
+			metav1.NamespaceAll: {informer: informer.Informer(), lister: informer.Lister()},
+		},
+	}
+}
+
+// NewScopedSecretsController creates a controller only watching the secrets within the scope. Unlike
+// NewSecretsController, its informers are not shared and must be started with Run.
+func NewScopedSecretsController(client kubernetes.Interface, scope Scope) (*SecretsController, error) {
+	selector, err := labels.Parse(scope.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret label selector %q: %v", scope.LabelSelector, err)
+	}
+	namespaces := scope.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	s := &SecretsController{
+		scope:     scope,
+		client:    client,
+		selector:  selector,
+		informers: map[string]secretInformer{},
+	}
+	for _, ns := range namespaces {
+		informer := informersv1.NewFilteredSecretInformer(client, ns, 0,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+			func(options *metav1.ListOptions) {
+				options.LabelSelector = scope.LabelSelector
+			})
+		s.informers[ns] = secretInformer{informer: informer, lister: listersv1.NewSecretLister(informer.GetIndexer())}
+	}
+	return s, nil
+}
+
+// Run starts the informers of a controller created by NewScopedSecretsController, and waits for them to sync.
+func (s *SecretsController) Run(stop <-chan struct{}) {
+	synced := make([]cache.InformerSynced, 0, len(s.informers))
+	for _, si := range s.informers {
+		go si.informer.Run(stop)
+		synced = append(synced, si.informer.HasSynced)
+	}
+	cache.WaitForCacheSync(stop, synced...)
+}
+
+// HasSynced returns true once the informers of the controller are synced.
+func (s *SecretsController) HasSynced() bool {
+	for _, si := range s.informers {
+		if !si.informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// lister returns the lister for secrets in the namespace, or nil if the namespace is not watched.
+func (s *SecretsController) lister(namespace string) listersv1.SecretNamespaceLister {
+	if si, f := s.informers[metav1.NamespaceAll]; f {
+		return si.lister.Secrets(namespace)
+	}
+	if si, f := s.informers[namespace]; f {
+		return si.lister.Secrets(namespace)
+	}
+	return nil
 }
 
 func (s *SecretsController) GetKeyAndCert(name, namespace string) (key []byte, cert []byte) {
-	k8sSecret, err := s.secrets.Lister().Secrets(namespace).Get(name)
+	lister := s.lister(namespace)
+	if lister == nil {
+		return nil, nil
+	}
+	k8sSecret, err := lister.Get(name)
 	if err != nil {
 		return nil, nil
 	}
@@ -67,12 +165,16 @@ func (s *SecretsController) GetKeyAndCert(name, namespace string) (key []byte, c
 }
 
 func (s *SecretsController) GetCaCert(name, namespace string) (cert []byte) {
+	lister := s.lister(namespace)
+	if lister == nil {
+		return nil
+	}
 	strippedName := strings.TrimSuffix(name, GatewaySdsCaSuffix)
-	k8sSecret, err := s.secrets.Lister().Secrets(namespace).Get(strippedName)
+	k8sSecret, err := lister.Get(strippedName)
 	var rootCert []byte
 	if err != nil {
 		// Could not fetch cert, look for legacy secret with -cacert suffix
-		k8sSecret, caCertErr := s.secrets.Lister().Secrets(namespace).Get(name)
+		k8sSecret, caCertErr := lister.Get(name)
 		if caCertErr != nil {
 			return nil
 		}
@@ -81,7 +183,7 @@ func (s *SecretsController) GetCaCert(name, namespace string) (cert []byte) {
 		rootCert = extractRoot(k8sSecret)
 		// Secret exists, but does not have the ca cert. Fall back to -cacert secret
 		if rootCert == nil {
-			k8sSecret, caCertErr := s.secrets.Lister().Secrets(namespace).Get(name)
+			k8sSecret, caCertErr := lister.Get(name)
 			if caCertErr != nil {
 				return nil
 			}
@@ -91,6 +193,44 @@ func (s *SecretsController) GetCaCert(name, namespace string) (cert []byte) {
 	return rootCert
 }
 
+// SecretError returns why the secret can't be read, or nil if it exists. As the informers of a controller
+// scoped by a label selector only hold the matching secrets, the secrets they miss are read from the API
+// server to tell the ones filtered out from the missing ones.
+func (s *SecretsController) SecretError(name, namespace string) error {
+	if !s.HasSynced() {
+		return secrets.ErrNotSynced
+	}
+	name = strings.TrimSuffix(name, GatewaySdsCaSuffix)
+	lister := s.lister(namespace)
+	if lister == nil {
+		return &secrets.OutOfScopeError{
+			Name:      name,
+			Namespace: namespace,
+			Reason:    fmt.Sprintf("namespace %s is not one of the watched namespaces %v", namespace, s.scope.Namespaces),
+		}
+	}
+	if _, err := lister.Get(name); !errors.IsNotFound(err) {
+		return err
+	}
+	if s.client != nil && s.scope.LabelSelector != "" {
+		scrt, err := s.client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		switch {
+		case err == nil && !s.selector.Matches(labels.Set(scrt.Labels)):
+			return &secrets.OutOfScopeError{
+				Name:      name,
+				Namespace: namespace,
+				Reason:    fmt.Sprintf("its labels do not match the watched label selector %q", s.scope.LabelSelector),
+			}
+		case err == nil:
+			// The informer did not receive the secret yet.
+			return nil
+		case !errors.IsNotFound(err):
+			return fmt.Errorf("failed to get secret %s/%s: %v", namespace, name, err)
+		}
+	}
+	return fmt.Errorf("%s/%s: %w", namespace, name, secrets.ErrSecretNotFound)
+}
+
 // extractKeyAndCert extracts server key, certificate
 func extractKeyAndCert(scrt *v1.Secret) (key, cert []byte) {
 	if len(scrt.Data[GenericScrtCert]) > 0 {
@@ -131,16 +271,36 @@ func (s *SecretsController) AddEventHandler(f func(name string, namespace string
 		}
 		f(scrt.Name, scrt.Namespace)
 	}
-	s.secrets.Informer().AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				handler(obj)
-			},
-			UpdateFunc: func(old, cur interface{}) {
-				handler(cur)
-			},
-			DeleteFunc: func(obj interface{}) {
-				handler(obj)
-			},
-		})
+	// Informers only receive events for secrets within the scope.
+	for _, si := range s.informers {
+		si.informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					handler(obj)
+				},
+				UpdateFunc: func(old, cur interface{}) {
+					if !secretChanged(old, cur) {
+						// Periodic resyncs would otherwise be handled as rotations of all the secrets.
+						return
+					}
+					handler(cur)
+				},
+				DeleteFunc: func(obj interface{}) {
+					handler(obj)
+				},
+			})
+	}
+}
+
+// secretChanged returns whether the update changed the type or data of the secret.
+func secretChanged(old, cur interface{}) bool {
+	oldScrt, ok := old.(*v1.Secret)
+	if !ok {
+		return true
+	}
+	curScrt, ok := cur.(*v1.Secret)
+	if !ok {
+		return true
+	}
+	return oldScrt.Type != curScrt.Type || !reflect.DeepEqual(oldScrt.Data, curScrt.Data)
 }
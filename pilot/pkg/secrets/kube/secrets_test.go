@@ -15,13 +15,20 @@
 package kube
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"istio.io/istio/pilot/pkg/secrets"
 	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
 )
 
 func makeSecret(name string, data map[string]string) *corev1.Secret {
@@ -112,3 +119,119 @@ func TestSecretsController(t *testing.T) {
 		})
 	}
 }
+
+func TestScopedSecretsController(t *testing.T) {
+	inScope := makeSecret("in-scope", map[string]string{TLSSecretCert: "cert", TLSSecretKey: "key"})
+	inScope.Labels = map[string]string{"istio.io/credential": "true"}
+	unlabeled := makeSecret("unlabeled", map[string]string{TLSSecretCert: "cert", TLSSecretKey: "key"})
+	otherNamespace := makeSecret("other-namespace", map[string]string{TLSSecretCert: "cert", TLSSecretKey: "key"})
+	otherNamespace.Namespace = "other"
+	otherNamespace.Labels = inScope.Labels
+
+	client := kube.NewFakeClient(inScope, unlabeled, otherNamespace)
+	sc, err := NewScopedSecretsController(client.Kube(), Scope{
+		Namespaces:    []string{"default"},
+		LabelSelector: "istio.io/credential=true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	events := map[string]int{}
+	sc.AddEventHandler(func(name, namespace string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events[namespace+"/"+name]++
+	})
+	if err := sc.SecretError("in-scope", "default"); err != secrets.ErrNotSynced {
+		t.Errorf("expected not synced error before the controller runs, got %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	sc.Run(stop)
+	if !sc.HasSynced() {
+		t.Fatal("expected the controller to be synced")
+	}
+
+	if _, cert := sc.GetKeyAndCert("in-scope", "default"); string(cert) != "cert" {
+		t.Errorf("expected in-scope secret to be watched, got cert %q", cert)
+	}
+	if err := sc.SecretError("in-scope", "default"); err != nil {
+		t.Errorf("expected no error for in-scope secret, got %v", err)
+	}
+	for _, tt := range []struct{ name, namespace string }{
+		{"unlabeled", "default"},
+		{"other-namespace", "other"},
+	} {
+		if _, cert := sc.GetKeyAndCert(tt.name, tt.namespace); cert != nil {
+			t.Errorf("expected %s/%s to be out of scope, got cert %q", tt.namespace, tt.name, cert)
+		}
+		var scopeErr *secrets.OutOfScopeError
+		if err := sc.SecretError(tt.name, tt.namespace); !errors.As(err, &scopeErr) {
+			t.Errorf("expected out of scope error for %s/%s, got %v", tt.namespace, tt.name, err)
+		}
+	}
+	// Missing secrets are told apart from the ones filtered out by the label selector.
+	if err := sc.SecretError("missing", "default"); !errors.Is(err, secrets.ErrSecretNotFound) {
+		t.Errorf("expected not found error for default/missing, got %v", err)
+	}
+
+	// Secrets created outside of the watched namespaces do not trigger events.
+	created := makeSecret("created", map[string]string{TLSSecretCert: "cert", TLSSecretKey: "key"})
+	created.Namespace = "other"
+	created.Labels = inScope.Labels
+	if _, err := client.Kube().CoreV1().Secrets("other").Create(context.TODO(), created, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	created = created.DeepCopy()
+	created.Namespace = "default"
+	if _, err := client.Kube().CoreV1().Secrets("default").Create(context.TODO(), created, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if events["default/created"] == 0 {
+			return fmt.Errorf("expected event for default/created, got %v", events)
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+	mu.Lock()
+	defer mu.Unlock()
+	for _, key := range []string{"default/unlabeled", "other/other-namespace", "other/created"} {
+		if events[key] != 0 {
+			t.Errorf("expected no events for out of scope secret %s, got %v", key, events)
+		}
+	}
+}
+
+func TestScopedSecretsControllerInvalidSelector(t *testing.T) {
+	if _, err := NewScopedSecretsController(kube.NewFakeClient().Kube(), Scope{LabelSelector: "a in (b"}); err == nil {
+		t.Fatal("expected error for invalid label selector")
+	}
+}
+
+func TestSecretChanged(t *testing.T) {
+	rotated := makeSecret("generic", map[string]string{GenericScrtCert: "rotated-cert", GenericScrtKey: "rotated-key"})
+	resynced := genericCert.DeepCopy()
+	resynced.ResourceVersion = "2"
+	retyped := genericCert.DeepCopy()
+	retyped.Type = corev1.SecretTypeTLS
+	cases := []struct {
+		name string
+		cur  *corev1.Secret
+		want bool
+	}{
+		{"resync", genericCert, false},
+		{"metadata only", resynced, false},
+		{"rotated", rotated, true},
+		{"type", retyped, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := secretChanged(genericCert, tt.cur); got != tt.want {
+				t.Fatalf("got changed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -14,8 +14,45 @@
 
 package secrets
 
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSecretNotFound is wrapped by the errors of Controller.SecretError for secrets which do not exist.
+	ErrSecretNotFound = errors.New("secret not found")
+	// ErrNotSynced is returned by Controller.SecretError while the secrets are not synced yet.
+	ErrNotSynced = errors.New("secrets are not synced yet")
+)
+
+// OutOfScopeError is returned by Controller.SecretError for secrets which are not watched by the controller,
+// because of their namespace or labels.
+type OutOfScopeError struct {
+	Name      string
+	Namespace string
+	// Reason tells which part of the scope filters the secret out.
+	Reason string
+}
+
+func (e *OutOfScopeError) Error() string {
+	return fmt.Sprintf("secret %s/%s is not watched: %s", e.Namespace, e.Name, e.Reason)
+}
+
 type Controller interface {
 	GetKeyAndCert(name, namespace string) (key []byte, cert []byte)
 	GetCaCert(name, namespace string) (cert []byte)
 	AddEventHandler(func(name, namespace string))
+	// SecretError returns why the secret can't be read: an *OutOfScopeError if the controller does not
+	// watch it, an error wrapping ErrSecretNotFound if it does not exist, or ErrNotSynced. It returns nil
+	// if the secret exists.
+	SecretError(name, namespace string) error
+}
+
+// MulticlusterController resolves the secrets Controller of the cluster a proxy runs in.
+type MulticlusterController interface {
+	// ForCluster returns the Controller for secrets of the cluster, or an error if the cluster is not known.
+	ForCluster(cluster string) (Controller, error)
+	// AddEventHandler registers a handler for secret events of all current and future clusters.
+	AddEventHandler(func(name, namespace string))
 }
@@ -15,27 +15,103 @@
 package utils
 
 import (
+	"strings"
+	"sync"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbactcppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/protobuf/ptypes"
 
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/networking/util"
+	authz_model "istio.io/istio/pilot/pkg/security/authz/model"
 	authn_model "istio.io/istio/pilot/pkg/security/model"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pkg/config/security"
 	protovalue "istio.io/istio/pkg/proto"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/pkg/log"
 )
 
 const (
 	// Service account for Pilot (hardcoded values at setup time)
 	PilotSvcAccName string = "istio-pilot-service-account"
+
+	// TrustDomainStatPrefix is the stat prefix of the shadow RBAC filters counting the connections of a trust domain.
+	TrustDomainStatPrefix = "trust_domain."
+)
+
+var (
+	defaultInboundTLSOnce     sync.Once
+	defaultInboundTLSSettings security.InboundTLSSettings
+	defaultInboundTLSErr      error
 )
 
+func loadDefaultInboundTLSSettings() {
+	defaultInboundTLSOnce.Do(func() {
+		settings, err := security.ParseInboundTLSSettings(features.InboundTLSMinProtocolVersion,
+			features.InboundTLSMaxProtocolVersion, features.InboundTLSCipherSuites)
+		if err != nil {
+			defaultInboundTLSErr = err
+			return
+		}
+		defaultInboundTLSSettings = settings
+	})
+}
+
+// ValidateDefaultInboundTLSSettings returns an error if the PILOT_INBOUND_TLS_* environment variables are invalid,
+// so that istiod fails to start rather than silently using Envoy's defaults.
+func ValidateDefaultInboundTLSSettings() error {
+	loadDefaultInboundTLSSettings()
+	return defaultInboundTLSErr
+}
+
+// DefaultInboundTLSSettings returns the mesh-wide inbound TLS settings, configured with the
+// PILOT_INBOUND_TLS_* environment variables. Invalid settings are ignored, istiod refuses to start with them.
+func DefaultInboundTLSSettings() security.InboundTLSSettings {
+	loadDefaultInboundTLSSettings()
+	return defaultInboundTLSSettings
+}
+
+// InboundTLSParams converts the inbound TLS settings to the TLS parameters of a DownstreamTlsContext.
+// It returns nil if none of the settings is set.
+func InboundTLSParams(settings security.InboundTLSSettings) *tls.TlsParameters {
+	if settings.IsEmpty() {
+		return nil
+	}
+	return &tls.TlsParameters{
+		TlsMinimumProtocolVersion: convertTLSProtocol(settings.MinProtocolVersion),
+		TlsMaximumProtocolVersion: convertTLSProtocol(settings.MaxProtocolVersion),
+		CipherSuites:              settings.CipherSuites,
+	}
+}
+
+func convertTLSProtocol(version string) tls.TlsParameters_TlsProtocol {
+	switch version {
+	case "TLSV1_0":
+		return tls.TlsParameters_TLSv1_0
+	case "TLSV1_1":
+		return tls.TlsParameters_TLSv1_1
+	case "TLSV1_2":
+		return tls.TlsParameters_TLSv1_2
+	case "TLSV1_3":
+		return tls.TlsParameters_TLSv1_3
+	default:
+		return tls.TlsParameters_TLS_AUTO
+	}
+}
+
 // BuildInboundFilterChain returns the filter chain(s) corresponding to the mTLS mode.
+// If tlsParams is not nil, it pins the TLS versions and cipher suites accepted by the mTLS filter chain.
 func BuildInboundFilterChain(mTLSMode model.MutualTLSMode, sdsUdsPath string, node *model.Proxy,
-	listenerProtocol networking.ListenerProtocol, trustDomainAliases []string) []networking.FilterChain {
+	listenerProtocol networking.ListenerProtocol, trustDomainAliases []string,
+	tlsParams *tls.TlsParameters) []networking.FilterChain {
 	if mTLSMode == model.MTLSDisable || mTLSMode == model.MTLSUnknown {
 		return nil
 	}
@@ -78,12 +154,14 @@ func BuildInboundFilterChain(mTLSMode model.MutualTLSMode, sdsUdsPath string, no
 		}
 	}
 	authn_model.ApplyToCommonTLSContext(ctx.CommonTlsContext, meta, sdsUdsPath, []string{} /*subjectAltNames*/, trustDomainAliases)
+	ctx.CommonTlsContext.TlsParams = tlsParams
 
 	if mTLSMode == model.MTLSStrict {
 		log.Debug("Allow only istio mutual TLS traffic")
 		return []networking.FilterChain{
 			{
-				TLSContext: ctx,
+				TLSContext:      ctx,
+				ListenerFilters: inboundListenerFilters(node),
 			}}
 	}
 	if mTLSMode == model.MTLSPermissive {
@@ -92,14 +170,103 @@ func BuildInboundFilterChain(mTLSMode model.MutualTLSMode, sdsUdsPath string, no
 			{
 				FilterChainMatch: alpnIstioMatch,
 				TLSContext:       ctx,
-				ListenerFilters: []*listener.ListenerFilter{
-					xdsfilters.TLSInspector,
-				},
+				ListenerFilters:  inboundListenerFilters(node, xdsfilters.TLSInspector),
 			},
 			{
 				FilterChainMatch: &listener.FilterChainMatch{},
+				ListenerFilters:  inboundListenerFilters(node),
 			},
 		}
 	}
 	return nil
 }
+
+// inboundListenerFilters returns the listener filters of an inbound filter chain of the node, preceded by the
+// proxy_protocol listener filter if the inbound traffic of the node carries a PROXY protocol header. The header
+// must be consumed before the TLS inspector reads the ClientHello.
+func inboundListenerFilters(node *model.Proxy, filters ...*listener.ListenerFilter) []*listener.ListenerFilter {
+	if !node.InboundProxyProtocol() {
+		return filters
+	}
+	return append([]*listener.ListenerFilter{xdsfilters.ProxyProtocol}, filters...)
+}
+
+// BuildTrustDomainStatsFilters returns the shadow RBAC network filters counting the mTLS connections authenticated
+// under each trust domain, in the trust_domain.<trust domain>.rbac.shadow_allowed stat. The trust domains are the
+// primary trust domain followed by its aliases, so operators can compare the connections of the aliases with those
+// of the primary trust domain during a migration. The filters don't enforce anything, and are only built if
+// PILOT_TRUST_DOMAIN_ALIAS_STATS is enabled and there are aliases.
+func BuildTrustDomainStatsFilters(trustDomains []string) []*listener.Filter {
+	if !features.TrustDomainAliasStats {
+		return nil
+	}
+	var domains []string
+	seen := map[string]struct{}{}
+	for _, td := range trustDomains {
+		if _, f := seen[td]; f || td == "" {
+			continue
+		}
+		seen[td] = struct{}{}
+		domains = append(domains, td)
+	}
+	if len(domains) < 2 {
+		return nil
+	}
+	filters := make([]*listener.Filter, 0, len(domains))
+	for _, td := range domains {
+		config := &rbactcppb.RBAC{
+			StatPrefix: TrustDomainStatPrefix + td + ".",
+			// Without enforced rules, the filter only records whether the shadow rules match.
+			ShadowRules: &rbacpb.RBAC{
+				Action: rbacpb.RBAC_ALLOW,
+				Policies: map[string]*rbacpb.Policy{
+					td: {
+						Permissions: []*rbacpb.Permission{{Rule: &rbacpb.Permission_Any{Any: true}}},
+						Principals: []*rbacpb.Principal{{Identifier: &rbacpb.Principal_Authenticated_{
+							Authenticated: &rbacpb.Principal_Authenticated{
+								PrincipalName: &matcher.StringMatcher{
+									MatchPattern: &matcher.StringMatcher_Prefix{Prefix: spiffe.URIPrefix + td + "/"},
+								},
+							},
+						}}},
+					},
+				},
+			},
+		}
+		filters = append(filters, &listener.Filter{
+			Name:       authz_model.RBACTCPFilterName,
+			ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(config)},
+		})
+	}
+	return filters
+}
+
+// BuildTrustDomainStatsFiltersForTLSContext returns the trust domain stats filters of an mTLS filter chain, for the
+// trust domains of the SPIFFE SAN matchers of its TLS context. The filters are built for each filter chain, so
+// that filter chains validating distinct trust domains, or patched by EnvoyFilters, don't share them.
+func BuildTrustDomainStatsFiltersForTLSContext(ctx *tls.DownstreamTlsContext) []*listener.Filter {
+	if !features.TrustDomainAliasStats || ctx == nil {
+		return nil
+	}
+	var trustDomains []string
+	for _, m := range ctx.GetCommonTlsContext().GetCombinedValidationContext().GetDefaultValidationContext().GetMatchSubjectAltNames() {
+		if p := m.GetPrefix(); strings.HasPrefix(p, spiffe.URIPrefix) && strings.HasSuffix(p, "/") {
+			trustDomains = append(trustDomains, strings.TrimSuffix(strings.TrimPrefix(p, spiffe.URIPrefix), "/"))
+		}
+	}
+	return BuildTrustDomainStatsFilters(trustDomains)
+}
+
+// BuildTrustDomainStatsFiltersForTransportSocket returns the trust domain stats filters of an mTLS filter chain
+// with the transport socket, see BuildTrustDomainStatsFiltersForTLSContext.
+func BuildTrustDomainStatsFiltersForTransportSocket(ts *core.TransportSocket) []*listener.Filter {
+	if !features.TrustDomainAliasStats || ts.GetTypedConfig() == nil {
+		return nil
+	}
+	ctx := &tls.DownstreamTlsContext{}
+	if err := ptypes.UnmarshalAny(ts.GetTypedConfig(), ctx); err != nil {
+		log.Warnf("failed to decode the TLS context of the transport socket %s: %v", ts.Name, err)
+		return nil
+	}
+	return BuildTrustDomainStatsFiltersForTLSContext(ctx)
+}
@@ -15,19 +15,27 @@
 package utils
 
 import (
+	"sync"
 	"testing"
 	"time"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbactcppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
 	auth "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
+	"istio.io/istio/pilot/pkg/networking/util"
+	authz_model "istio.io/istio/pilot/pkg/security/authz/model"
 	authn_model "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/config/security"
 	protovalue "istio.io/istio/pkg/proto"
 	"istio.io/istio/pkg/spiffe"
 )
@@ -210,10 +218,251 @@ func TestBuildInboundFilterChain(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := BuildInboundFilterChain(tt.args.mTLSMode, tt.args.sdsUdsPath, tt.args.node, tt.args.listenerProtocol, tt.args.trustDomains)
+			got := BuildInboundFilterChain(tt.args.mTLSMode, tt.args.sdsUdsPath, tt.args.node, tt.args.listenerProtocol, tt.args.trustDomains, nil)
 			if diff := cmp.Diff(got, tt.want, protocmp.Transform()); diff != "" {
 				t.Errorf("BuildInboundFilterChain() = %v", diff)
 			}
 		})
 	}
 }
+
+func TestBuildInboundFilterChainTLSParams(t *testing.T) {
+	settings, err := security.ParseInboundTLSSettings("TLSV1_2", "TLSV1_3", "ECDHE-ECDSA-AES256-GCM-SHA384, ECDHE-RSA-AES256-GCM-SHA384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &auth.TlsParameters{
+		TlsMinimumProtocolVersion: auth.TlsParameters_TLSv1_2,
+		TlsMaximumProtocolVersion: auth.TlsParameters_TLSv1_3,
+		CipherSuites:              []string{"ECDHE-ECDSA-AES256-GCM-SHA384", "ECDHE-RSA-AES256-GCM-SHA384"},
+	}
+	if diff := cmp.Diff(InboundTLSParams(settings), want, protocmp.Transform()); diff != "" {
+		t.Fatalf("InboundTLSParams() = %v", diff)
+	}
+	if got := InboundTLSParams(security.InboundTLSSettings{}); got != nil {
+		t.Fatalf("expected no TLS parameters for empty settings, got %v", got)
+	}
+
+	node := &model.Proxy{Metadata: &model.NodeMetadata{}}
+	for _, mode := range []model.MutualTLSMode{model.MTLSStrict, model.MTLSPermissive} {
+		for _, protocol := range []networking.ListenerProtocol{networking.ListenerProtocolTCP, networking.ListenerProtocolHTTP} {
+			chains := BuildInboundFilterChain(mode, "", node, protocol, nil, InboundTLSParams(settings))
+			if len(chains) == 0 || chains[0].TLSContext == nil {
+				t.Fatalf("%v/%v: expected an mTLS filter chain, got %v", mode, protocol, chains)
+			}
+			got := chains[0].TLSContext.CommonTlsContext.TlsParams
+			if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
+				t.Errorf("%v/%v: unexpected TLS parameters %v", mode, protocol, diff)
+			}
+		}
+	}
+}
+
+func TestValidateDefaultInboundTLSSettings(t *testing.T) {
+	oldMin, oldMax := features.InboundTLSMinProtocolVersion, features.InboundTLSMaxProtocolVersion
+	t.Cleanup(func() {
+		features.InboundTLSMinProtocolVersion, features.InboundTLSMaxProtocolVersion = oldMin, oldMax
+		defaultInboundTLSOnce, defaultInboundTLSSettings, defaultInboundTLSErr = sync.Once{}, security.InboundTLSSettings{}, nil
+	})
+
+	features.InboundTLSMinProtocolVersion, features.InboundTLSMaxProtocolVersion = "TLSV1_3", "TLSV1_2"
+	defaultInboundTLSOnce = sync.Once{}
+	if err := ValidateDefaultInboundTLSSettings(); err == nil {
+		t.Fatalf("expected a minimum TLS version greater than the maximum to be rejected")
+	}
+	if got := DefaultInboundTLSSettings(); !got.IsEmpty() {
+		t.Fatalf("expected the invalid settings to be ignored, got %+v", got)
+	}
+}
+
+func TestBuildInboundFilterChainProxyProtocol(t *testing.T) {
+	listenerFilterNames := func(chain networking.FilterChain) []string {
+		var names []string
+		for _, lf := range chain.ListenerFilters {
+			names = append(names, lf.Name)
+		}
+		return names
+	}
+	for _, protocol := range []networking.ListenerProtocol{networking.ListenerProtocolTCP, networking.ListenerProtocolHTTP} {
+		for _, enabled := range []bool{false, true} {
+			node := &model.Proxy{Metadata: &model.NodeMetadata{}}
+			var proxyProtocol []string
+			if enabled {
+				node.Metadata.InboundProxyProtocol = "1"
+				proxyProtocol = []string{wellknown.ProxyProtocol}
+			}
+
+			strict := BuildInboundFilterChain(model.MTLSStrict, "", node, protocol, nil, nil)
+			if len(strict) != 1 {
+				t.Fatalf("%v: expected 1 strict filter chain, got %d", protocol, len(strict))
+			}
+			if diff := cmp.Diff(listenerFilterNames(strict[0]), proxyProtocol); diff != "" {
+				t.Errorf("%v/proxy protocol %v: unexpected strict listener filters %v", protocol, enabled, diff)
+			}
+
+			permissive := BuildInboundFilterChain(model.MTLSPermissive, "", node, protocol, nil, nil)
+			if len(permissive) != 2 {
+				t.Fatalf("%v: expected 2 permissive filter chains, got %d", protocol, len(permissive))
+			}
+			// The PROXY protocol header must be consumed before the TLS inspector reads the ClientHello.
+			want := append(append([]string{}, proxyProtocol...), wellknown.TlsInspector)
+			if diff := cmp.Diff(listenerFilterNames(permissive[0]), want); diff != "" {
+				t.Errorf("%v/proxy protocol %v: unexpected permissive mTLS listener filters %v", protocol, enabled, diff)
+			}
+			if diff := cmp.Diff(listenerFilterNames(permissive[1]), proxyProtocol); diff != "" {
+				t.Errorf("%v/proxy protocol %v: unexpected permissive plaintext listener filters %v", protocol, enabled, diff)
+			}
+		}
+	}
+}
+
+func TestBuildInboundFilterChainTrustDomainAliases(t *testing.T) {
+	cases := []struct {
+		name         string
+		trustDomains []string
+		want         []string
+	}{
+		{
+			name: "no trust domain",
+		},
+		{
+			name:         "no alias",
+			trustDomains: []string{"cluster.local"},
+			want:         []string{"spiffe://cluster.local/"},
+		},
+		{
+			name:         "one alias",
+			trustDomains: []string{"cluster.local", "old.local"},
+			want:         []string{"spiffe://cluster.local/", "spiffe://old.local/"},
+		},
+		{
+			name:         "multiple aliases",
+			trustDomains: []string{"new.local", "cluster.local", "", "old.local", "cluster.local"},
+			// The primary trust domain comes first, followed by the aliases in their configured order.
+			want: []string{"spiffe://new.local/", "spiffe://cluster.local/", "spiffe://old.local/"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			chains := BuildInboundFilterChain(model.MTLSStrict, "", &model.Proxy{Metadata: &model.NodeMetadata{}},
+				networking.ListenerProtocolTCP, tt.trustDomains, nil)
+			if len(chains) != 1 {
+				t.Fatalf("expected 1 filter chain, got %d", len(chains))
+			}
+			var got []string
+			for _, m := range chains[0].TLSContext.GetCommonTlsContext().GetCombinedValidationContext().
+				GetDefaultValidationContext().GetMatchSubjectAltNames() {
+				got = append(got, m.GetPrefix())
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("unexpected SAN matchers %v", diff)
+			}
+		})
+	}
+}
+
+func TestBuildTrustDomainStatsFilters(t *testing.T) {
+	defer func(enabled bool) {
+		features.TrustDomainAliasStats = enabled
+	}(features.TrustDomainAliasStats)
+
+	features.TrustDomainAliasStats = false
+	if got := BuildTrustDomainStatsFilters([]string{"cluster.local", "old.local"}); got != nil {
+		t.Fatalf("expected no filter when disabled, got %v", got)
+	}
+
+	features.TrustDomainAliasStats = true
+	for _, trustDomains := range [][]string{nil, {"cluster.local"}, {"cluster.local", "cluster.local", ""}} {
+		if got := BuildTrustDomainStatsFilters(trustDomains); got != nil {
+			t.Errorf("expected no filter without alias for %v, got %v", trustDomains, got)
+		}
+	}
+
+	cases := []struct {
+		name         string
+		trustDomains []string
+		want         []string
+	}{
+		{
+			name:         "one alias",
+			trustDomains: []string{"cluster.local", "old.local"},
+			want:         []string{"cluster.local", "old.local"},
+		},
+		{
+			name:         "multiple aliases",
+			trustDomains: []string{"new.local", "cluster.local", "old.local", "cluster.local"},
+			want:         []string{"new.local", "cluster.local", "old.local"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := BuildTrustDomainStatsFilters(tt.trustDomains)
+			if len(filters) != len(tt.want) {
+				t.Fatalf("expected %d filters, got %d", len(tt.want), len(filters))
+			}
+			for i, td := range tt.want {
+				if filters[i].Name != authz_model.RBACTCPFilterName {
+					t.Errorf("%s: unexpected filter %s", td, filters[i].Name)
+				}
+				config := &rbactcppb.RBAC{}
+				if err := ptypes.UnmarshalAny(filters[i].GetTypedConfig(), config); err != nil {
+					t.Fatal(err)
+				}
+				if want := TrustDomainStatPrefix + td + "."; config.StatPrefix != want {
+					t.Errorf("%s: got stat prefix %q, want %q", td, config.StatPrefix, want)
+				}
+				// The filters must never enforce anything.
+				if config.Rules != nil {
+					t.Errorf("%s: expected no enforced rules, got %v", td, config.Rules)
+				}
+				policy := config.GetShadowRules().GetPolicies()[td]
+				if config.GetShadowRules().GetAction() != rbacpb.RBAC_ALLOW || len(policy.GetPrincipals()) != 1 {
+					t.Fatalf("%s: unexpected shadow rules %v", td, config.GetShadowRules())
+				}
+				prefix := policy.GetPrincipals()[0].GetAuthenticated().GetPrincipalName().GetPrefix()
+				if want := spiffe.URIPrefix + td + "/"; prefix != want {
+					t.Errorf("%s: got principal prefix %q, want %q", td, prefix, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTrustDomainStatsFiltersForTLSContext(t *testing.T) {
+	defer func(enabled bool) {
+		features.TrustDomainAliasStats = enabled
+	}(features.TrustDomainAliasStats)
+	features.TrustDomainAliasStats = true
+
+	chains := BuildInboundFilterChain(model.MTLSStrict, "", &model.Proxy{Metadata: &model.NodeMetadata{}},
+		networking.ListenerProtocolTCP, []string{"cluster.local", "old.local"}, nil)
+	first := BuildTrustDomainStatsFiltersForTLSContext(chains[0].TLSContext)
+	second := BuildTrustDomainStatsFiltersForTLSContext(chains[0].TLSContext)
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("expected a filter for each trust domain of the SAN matchers, got %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		// Each filter chain gets its own filters.
+		if first[i] == second[i] {
+			t.Errorf("filter %d is shared", i)
+		}
+		config := &rbactcppb.RBAC{}
+		if err := ptypes.UnmarshalAny(first[i].GetTypedConfig(), config); err != nil {
+			t.Fatal(err)
+		}
+		if want := TrustDomainStatPrefix + []string{"cluster.local", "old.local"}[i] + "."; config.StatPrefix != want {
+			t.Errorf("got stat prefix %q, want %q", config.StatPrefix, want)
+		}
+	}
+
+	ts := &core.TransportSocket{
+		Name:       "envoy.transport_sockets.tls",
+		ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: util.MessageToAny(chains[0].TLSContext)},
+	}
+	if got := BuildTrustDomainStatsFiltersForTransportSocket(ts); len(got) != 2 {
+		t.Errorf("expected the filters of the transport socket, got %v", got)
+	}
+	if got := BuildTrustDomainStatsFiltersForTLSContext(nil); got != nil {
+		t.Errorf("expected no filter for a plaintext filter chain, got %v", got)
+	}
+}
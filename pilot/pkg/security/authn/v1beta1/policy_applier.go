@@ -24,6 +24,7 @@ import (
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	envoy_jwt "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/jwt_authn/v3"
 	http_conn "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/golang/protobuf/ptypes/empty"
 
 	"istio.io/api/security/v1beta1"
@@ -34,6 +35,7 @@ import (
 	authn_utils "istio.io/istio/pilot/pkg/security/authn/utils"
 	authn_model "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/security"
 	authn_alpha "istio.io/istio/pkg/envoy/config/authentication/v1alpha1"
 	authn_filter "istio.io/istio/pkg/envoy/config/filter/http/authn/v2alpha1"
 	"istio.io/pkg/log"
@@ -53,6 +55,9 @@ type v1beta1PolicyApplier struct {
 	processedJwtRules []*v1beta1.JWTRule
 
 	consolidatedPeerPolicy *v1beta1.PeerAuthentication
+
+	// inboundTLSParams are the TLS parameters of the inbound mTLS filter chains, or nil for Envoy's defaults.
+	inboundTLSParams *tls.TlsParameters
 }
 
 func (a *v1beta1PolicyApplier) JwtFilter() *http_conn.HttpFilter {
@@ -185,7 +190,8 @@ func (a *v1beta1PolicyApplier) InboundFilterChain(endpointPort uint32, sdsUdsPat
 	listenerProtocol networking.ListenerProtocol, trustDomainAliases []string) []networking.FilterChain {
 	effectiveMTLSMode := a.getMutualTLSModeForPort(endpointPort)
 	authnLog.Debugf("InboundFilterChain: build inbound filter change for %v:%d in %s mode", node.ID, endpointPort, effectiveMTLSMode)
-	return authn_utils.BuildInboundFilterChain(effectiveMTLSMode, sdsUdsPath, node, listenerProtocol, trustDomainAliases)
+	return authn_utils.BuildInboundFilterChain(effectiveMTLSMode, sdsUdsPath, node, listenerProtocol, trustDomainAliases,
+		a.inboundTLSParams)
 }
 
 // NewPolicyApplier returns new applier for v1beta1 authentication policies.
@@ -213,6 +219,7 @@ func NewPolicyApplier(rootNamespace string,
 		peerPolices:            peerPolicies,
 		processedJwtRules:      processedJwtRules,
 		consolidatedPeerPolicy: composePeerAuthentication(rootNamespace, peerPolicies),
+		inboundTLSParams:       authn_utils.InboundTLSParams(composeInboundTLSSettings(rootNamespace, peerPolicies)),
 	}
 }
 
@@ -399,31 +406,7 @@ func getMutualTLSMode(mtls *v1beta1.PeerAuthentication_MutualTLS) model.MutualTL
 // replaced with config from workload-level, UNSET in workload-level config will be replaced with
 // one in namespace-level and so on.
 func composePeerAuthentication(rootNamespace string, configs []*config.Config) *v1beta1.PeerAuthentication {
-	var meshCfg, namespaceCfg, workloadCfg *config.Config
-
-	for _, cfg := range configs {
-		spec := cfg.Spec.(*v1beta1.PeerAuthentication)
-		if spec.Selector == nil || len(spec.Selector.MatchLabels) == 0 {
-			// Namespace-level or mesh-level policy
-			if cfg.Namespace == rootNamespace {
-				if meshCfg == nil || cfg.CreationTimestamp.Before(meshCfg.CreationTimestamp) {
-					authnLog.Debugf("Switch selected mesh policy to %s.%s (%v)", cfg.Name, cfg.Namespace, cfg.CreationTimestamp)
-					meshCfg = cfg
-				}
-			} else {
-				if namespaceCfg == nil || cfg.CreationTimestamp.Before(namespaceCfg.CreationTimestamp) {
-					authnLog.Debugf("Switch selected namespace policy to %s.%s (%v)", cfg.Name, cfg.Namespace, cfg.CreationTimestamp)
-					namespaceCfg = cfg
-				}
-			}
-		} else if cfg.Namespace != rootNamespace {
-			// Workload level policy, aka the one with selector and not in root namespace.
-			if workloadCfg == nil || cfg.CreationTimestamp.Before(workloadCfg.CreationTimestamp) {
-				authnLog.Debugf("Switch selected workload policy to %s.%s (%v)", cfg.Name, cfg.Namespace, cfg.CreationTimestamp)
-				workloadCfg = cfg
-			}
-		}
-	}
+	meshCfg, namespaceCfg, workloadCfg := selectPeerAuthentications(rootNamespace, configs)
 
 	if meshCfg == nil && namespaceCfg == nil && workloadCfg == nil {
 		// Return nil so that caller can fallback to apply alpha policy. Once we deprecate alpha API,
@@ -476,6 +459,56 @@ func composePeerAuthentication(rootNamespace string, configs []*config.Config) *
 	return &outputPolicy
 }
 
+// selectPeerAuthentications returns the mesh, namespace and workload level policies among the given ones.
+// For each level, the oldest policy is selected.
+func selectPeerAuthentications(rootNamespace string, configs []*config.Config) (meshCfg, namespaceCfg, workloadCfg *config.Config) {
+	for _, cfg := range configs {
+		spec := cfg.Spec.(*v1beta1.PeerAuthentication)
+		if spec.Selector == nil || len(spec.Selector.MatchLabels) == 0 {
+			// Namespace-level or mesh-level policy
+			if cfg.Namespace == rootNamespace {
+				if meshCfg == nil || cfg.CreationTimestamp.Before(meshCfg.CreationTimestamp) {
+					authnLog.Debugf("Switch selected mesh policy to %s.%s (%v)", cfg.Name, cfg.Namespace, cfg.CreationTimestamp)
+					meshCfg = cfg
+				}
+			} else {
+				if namespaceCfg == nil || cfg.CreationTimestamp.Before(namespaceCfg.CreationTimestamp) {
+					authnLog.Debugf("Switch selected namespace policy to %s.%s (%v)", cfg.Name, cfg.Namespace, cfg.CreationTimestamp)
+					namespaceCfg = cfg
+				}
+			}
+		} else if cfg.Namespace != rootNamespace {
+			// Workload level policy, aka the one with selector and not in root namespace.
+			if workloadCfg == nil || cfg.CreationTimestamp.Before(workloadCfg.CreationTimestamp) {
+				authnLog.Debugf("Switch selected workload policy to %s.%s (%v)", cfg.Name, cfg.Namespace, cfg.CreationTimestamp)
+				workloadCfg = cfg
+			}
+		}
+	}
+	return meshCfg, namespaceCfg, workloadCfg
+}
+
+// composeInboundTLSSettings returns the inbound TLS settings of the most specific policy (workload,
+// namespace, then mesh level) with TLS settings annotations, falling back to the mesh-wide defaults.
+// Invalid annotations are rejected by validation, and ignored here.
+func composeInboundTLSSettings(rootNamespace string, configs []*config.Config) security.InboundTLSSettings {
+	meshCfg, namespaceCfg, workloadCfg := selectPeerAuthentications(rootNamespace, configs)
+	for _, cfg := range []*config.Config{workloadCfg, namespaceCfg, meshCfg} {
+		if cfg == nil {
+			continue
+		}
+		settings, err := security.ParseInboundTLSAnnotations(cfg.Annotations)
+		if err != nil {
+			authnLog.Warnf("ignoring the invalid TLS settings of %s.%s: %v", cfg.Name, cfg.Namespace, err)
+			continue
+		}
+		if !settings.IsEmpty() {
+			return settings
+		}
+	}
+	return authn_utils.DefaultInboundTLSSettings()
+}
+
 func isMtlsModeUnset(mtls *v1beta1.PeerAuthentication_MutualTLS) bool {
 	return mtls == nil || mtls.Mode == v1beta1.PeerAuthentication_MutualTLS_UNSET
 }
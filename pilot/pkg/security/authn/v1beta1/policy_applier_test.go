@@ -37,6 +37,7 @@ import (
 	pilotutil "istio.io/istio/pilot/pkg/networking/util"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/security"
 	authn_alpha "istio.io/istio/pkg/envoy/config/authentication/v1alpha1"
 	authn_filter "istio.io/istio/pkg/envoy/config/filter/http/authn/v2alpha1"
 	protovalue "istio.io/istio/pkg/proto"
@@ -2095,3 +2096,84 @@ func TestGetMutualTLSMode(t *testing.T) {
 		})
 	}
 }
+
+func TestComposeInboundTLSSettings(t *testing.T) {
+	peerPolicy := func(namespace string, selector map[string]string, annotations map[string]string) *config.Config {
+		spec := &v1beta1.PeerAuthentication{}
+		if selector != nil {
+			spec.Selector = &type_beta.WorkloadSelector{MatchLabels: selector}
+		}
+		return &config.Config{
+			Meta: config.Meta{
+				Name:        "default",
+				Namespace:   namespace,
+				Annotations: annotations,
+			},
+			Spec: spec,
+		}
+	}
+	tls12 := map[string]string{security.TLSMinProtocolVersionAnnotation: "TLSV1_2"}
+	tls13 := map[string]string{
+		security.TLSMinProtocolVersionAnnotation: "TLSV1_3",
+		security.TLSCipherSuitesAnnotation:       "ECDHE-RSA-AES256-GCM-SHA384",
+	}
+	invalid := map[string]string{
+		security.TLSMinProtocolVersionAnnotation: "TLSV1_3",
+		security.TLSMaxProtocolVersionAnnotation: "TLSV1_2",
+	}
+	app := map[string]string{"app": "foo"}
+
+	tests := []struct {
+		name    string
+		configs []*config.Config
+		want    security.InboundTLSSettings
+	}{
+		{
+			name: "no annotations",
+			configs: []*config.Config{
+				peerPolicy("root-namespace", nil, nil),
+				peerPolicy("foo", app, nil),
+			},
+			want: security.InboundTLSSettings{},
+		},
+		{
+			name: "mesh",
+			configs: []*config.Config{
+				peerPolicy("root-namespace", nil, tls12),
+				peerPolicy("foo", nil, nil),
+			},
+			want: security.InboundTLSSettings{MinProtocolVersion: "TLSV1_2"},
+		},
+		{
+			name: "namespace overrides mesh",
+			configs: []*config.Config{
+				peerPolicy("root-namespace", nil, tls12),
+				peerPolicy("foo", nil, tls13),
+			},
+			want: security.InboundTLSSettings{MinProtocolVersion: "TLSV1_3", CipherSuites: []string{"ECDHE-RSA-AES256-GCM-SHA384"}},
+		},
+		{
+			name: "workload overrides namespace",
+			configs: []*config.Config{
+				peerPolicy("foo", nil, tls13),
+				peerPolicy("foo", app, tls12),
+			},
+			want: security.InboundTLSSettings{MinProtocolVersion: "TLSV1_2"},
+		},
+		{
+			name: "invalid workload settings are ignored",
+			configs: []*config.Config{
+				peerPolicy("foo", nil, tls12),
+				peerPolicy("foo", app, invalid),
+			},
+			want: security.InboundTLSSettings{MinProtocolVersion: "TLSV1_2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := composeInboundTLSSettings("root-namespace", tt.configs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("composeInboundTLSSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
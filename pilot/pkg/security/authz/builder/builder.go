@@ -16,12 +16,14 @@ package builder
 
 import (
 	"fmt"
+	"strings"
 
 	tcppb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
 	rbachttppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	rbactcppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -35,6 +37,10 @@ var (
 	authzLog = log.RegisterScope("authorization", "Istio Authorization Policy", 0)
 )
 
+// shadowRulesStatPrefixField is the number of the shadow_rules_stat_prefix field of the HTTP RBAC filter config,
+// which the vendored go-control-plane does not have yet.
+const shadowRulesStatPrefixField = 3
+
 // General setting to control behavior
 type Option struct {
 	IsIstioVersionGE15     bool
@@ -43,11 +49,13 @@ type Option struct {
 
 // Builder builds Istio authorization policy to Envoy RBAC filter.
 type Builder struct {
-	trustDomainBundle trustdomain.Bundle
-	denyPolicies      []model.AuthorizationPolicy
-	allowPolicies     []model.AuthorizationPolicy
-	auditPolicies     []model.AuthorizationPolicy
-	option            Option
+	trustDomainBundle   trustdomain.Bundle
+	denyPolicies        []model.AuthorizationPolicy
+	allowPolicies       []model.AuthorizationPolicy
+	auditPolicies       []model.AuthorizationPolicy
+	dryRunDenyPolicies  []model.AuthorizationPolicy
+	dryRunAllowPolicies []model.AuthorizationPolicy
+	option              Option
 }
 
 // New returns a new builder for the given workload with the authorization policy.
@@ -58,13 +66,29 @@ func New(trustDomainBundle trustdomain.Bundle, workload labels.Collection, names
 	if len(denyPolicies) == 0 && len(allowPolicies) == 0 && len(auditPolicies) == 0 {
 		return nil
 	}
+	denyPolicies, dryRunDenyPolicies := splitDryRun(denyPolicies)
+	allowPolicies, dryRunAllowPolicies := splitDryRun(allowPolicies)
 	return &Builder{
-		trustDomainBundle: trustDomainBundle,
-		denyPolicies:      denyPolicies,
-		allowPolicies:     allowPolicies,
-		auditPolicies:     auditPolicies,
-		option:            option,
+		trustDomainBundle:   trustDomainBundle,
+		denyPolicies:        denyPolicies,
+		allowPolicies:       allowPolicies,
+		auditPolicies:       auditPolicies,
+		dryRunDenyPolicies:  dryRunDenyPolicies,
+		dryRunAllowPolicies: dryRunAllowPolicies,
+		option:              option,
+	}
+}
+
+// splitDryRun splits the policies enforced from those in dry-run mode.
+func splitDryRun(policies []model.AuthorizationPolicy) (enforced, dryRun []model.AuthorizationPolicy) {
+	for _, policy := range policies {
+		if policy.DryRun {
+			dryRun = append(dryRun, policy)
+		} else {
+			enforced = append(enforced, policy)
+		}
 	}
+	return
 }
 
 // BuildHTTP returns the RBAC HTTP filters built from the authorization policy.
@@ -74,6 +98,9 @@ func (b Builder) BuildHTTP() []*httppb.HttpFilter {
 	if auditConfig := build(b.auditPolicies, b.trustDomainBundle, rbacpb.RBAC_LOG, false, b.option); auditConfig != nil {
 		filters = append(filters, createHTTPFilter(auditConfig))
 	}
+	for _, dryRun := range b.buildDryRun(false) {
+		filters = append(filters, createHTTPFilter(withShadowRulesStatPrefix(dryRun.config, dryRun.statPrefix)))
+	}
 	if denyConfig := build(b.denyPolicies, b.trustDomainBundle, rbacpb.RBAC_DENY, false, b.option); denyConfig != nil {
 		filters = append(filters, createHTTPFilter(denyConfig))
 	}
@@ -89,18 +116,60 @@ func (b Builder) BuildTCP() []*tcppb.Filter {
 	var filters []*tcppb.Filter
 
 	if auditConfig := build(b.auditPolicies, b.trustDomainBundle, rbacpb.RBAC_LOG, true, b.option); auditConfig != nil {
-		filters = append(filters, createTCPFilter(auditConfig))
+		filters = append(filters, createTCPFilter(auditConfig, authzmodel.RBACTCPFilterStatPrefix))
+	}
+	for _, dryRun := range b.buildDryRun(true) {
+		filters = append(filters, createTCPFilter(dryRun.config, dryRun.statPrefix))
 	}
 	if denyConfig := build(b.denyPolicies, b.trustDomainBundle, rbacpb.RBAC_DENY, true, b.option); denyConfig != nil {
-		filters = append(filters, createTCPFilter(denyConfig))
+		filters = append(filters, createTCPFilter(denyConfig, authzmodel.RBACTCPFilterStatPrefix))
 	}
 	if allowConfig := build(b.allowPolicies, b.trustDomainBundle, rbacpb.RBAC_ALLOW, true, b.option); allowConfig != nil {
-		filters = append(filters, createTCPFilter(allowConfig))
+		filters = append(filters, createTCPFilter(allowConfig, authzmodel.RBACTCPFilterStatPrefix))
 	}
 
 	return filters
 }
 
+// dryRunConfig is the RBAC config of a policy in dry-run mode.
+type dryRunConfig struct {
+	config *rbachttppb.RBAC
+	// statPrefix identifies the policy in the shadow rules stats of the filter.
+	statPrefix string
+}
+
+// buildDryRun builds an RBAC config with only shadow rules for each policy in dry-run mode, so the requests the
+// policy would deny are counted in the shadow_denied stat without being denied. Each policy gets its own filter,
+// so the stats are prefixed per policy, and the HTTP filter also reports the policy in its shadow_effective_policy_id
+// dynamic metadata. The filters are placed before the enforced ones, to evaluate all the requests.
+func (b Builder) buildDryRun(forTCP bool) []dryRunConfig {
+	var configs []dryRunConfig
+	for _, policy := range b.dryRunDenyPolicies {
+		policies := []model.AuthorizationPolicy{policy}
+		if config := build(policies, b.trustDomainBundle, rbacpb.RBAC_DENY, forTCP, b.option); config != nil {
+			configs = append(configs, newDryRunConfig(config, rbacpb.RBAC_DENY, policy))
+		}
+	}
+	for _, policy := range b.dryRunAllowPolicies {
+		// A request is denied if none of the ALLOW policies match it, so the policy is evaluated along with the
+		// enforced ones to count the requests denied once it is enforced.
+		policies := append(append([]model.AuthorizationPolicy{}, b.allowPolicies...), policy)
+		if config := build(policies, b.trustDomainBundle, rbacpb.RBAC_ALLOW, forTCP, b.option); config != nil {
+			configs = append(configs, newDryRunConfig(config, rbacpb.RBAC_ALLOW, policy))
+		}
+	}
+	return configs
+}
+
+func newDryRunConfig(config *rbachttppb.RBAC, action rbacpb.RBAC_Action, policy model.AuthorizationPolicy) dryRunConfig {
+	return dryRunConfig{
+		// Without enforced rules, the filter only records whether the shadow rules match.
+		config: &rbachttppb.RBAC{ShadowRules: config.Rules},
+		statPrefix: fmt.Sprintf("%s%s.%s.%s.", authzmodel.RBACDryRunStatPrefix,
+			strings.ToLower(action.String()), policy.Namespace, policy.Name),
+	}
+}
+
 func build(policies []model.AuthorizationPolicy, tdBundle trustdomain.Bundle,
 	action rbacpb.RBAC_Action, forTCP bool, option Option) *rbachttppb.RBAC {
 	if len(policies) == 0 {
@@ -146,6 +215,15 @@ func build(policies []model.AuthorizationPolicy, tdBundle trustdomain.Bundle,
 	return &rbachttppb.RBAC{Rules: rules}
 }
 
+// withShadowRulesStatPrefix sets the shadow_rules_stat_prefix of the HTTP RBAC config. It is set as an unknown field
+// of the message, which is serialized with it.
+func withShadowRulesStatPrefix(config *rbachttppb.RBAC, statPrefix string) *rbachttppb.RBAC {
+	b := protowire.AppendTag(nil, shadowRulesStatPrefixField, protowire.BytesType)
+	b = protowire.AppendString(b, statPrefix)
+	config.ProtoReflect().SetUnknown(b)
+	return config
+}
+
 // nolint: interfacer
 func createHTTPFilter(config *rbachttppb.RBAC) *httppb.HttpFilter {
 	if config == nil {
@@ -157,13 +235,14 @@ func createHTTPFilter(config *rbachttppb.RBAC) *httppb.HttpFilter {
 	}
 }
 
-func createTCPFilter(config *rbachttppb.RBAC) *tcppb.Filter {
+func createTCPFilter(config *rbachttppb.RBAC, statPrefix string) *tcppb.Filter {
 	if config == nil {
 		return nil
 	}
 	rbacConfig := &rbactcppb.RBAC{
-		Rules:      config.Rules,
-		StatPrefix: authzmodel.RBACTCPFilterStatPrefix,
+		Rules:       config.Rules,
+		ShadowRules: config.ShadowRules,
+		StatPrefix:  statPrefix,
 	}
 	return &tcppb.Filter{
 		Name:       authzmodel.RBACTCPFilterName,
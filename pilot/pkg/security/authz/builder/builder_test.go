@@ -16,11 +16,18 @@ package builder
 
 import (
 	"io/ioutil"
+	"reflect"
+	"sort"
 	"testing"
 
 	tcppb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbachttppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	rbactcppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
 	"github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/config/memory"
@@ -126,6 +133,15 @@ func TestGenerator_GenerateHTTP(t *testing.T) {
 			input: "audit-all-in.yaml",
 			want:  []string{"audit-all-out.yaml"},
 		},
+		{
+			name:  "dry-run",
+			input: "dry-run-in.yaml",
+			want: []string{
+				"dry-run-shadow-deny-out.yaml",
+				"dry-run-shadow-allow-out.yaml",
+				"dry-run-deny-out.yaml",
+				"dry-run-allow-out.yaml"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -184,6 +200,134 @@ func TestGenerator_GenerateTCP(t *testing.T) {
 	}
 }
 
+func TestGenerator_GenerateTCPDryRun(t *testing.T) {
+	g := New(trustdomain.Bundle{}, httpbin, "foo", yamlPolicy(t, basePath+"dry-run-in.yaml"), Option{IsIstioVersionGE15: true})
+	if g == nil {
+		t.Fatalf("failed to create generator")
+	}
+	type filter struct {
+		statPrefix string
+		rules      []string
+		shadow     []string
+	}
+	var got []filter
+	for _, f := range g.BuildTCP() {
+		config := &rbactcppb.RBAC{}
+		if err := ptypes.UnmarshalAny(f.GetTypedConfig(), config); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filter{
+			statPrefix: config.StatPrefix,
+			rules:      policyNames(config.GetRules()),
+			shadow:     policyNames(config.GetShadowRules()),
+		})
+	}
+	want := []filter{
+		{
+			statPrefix: "istio_dry_run.deny.foo.httpbin-deny-dry-run.",
+			shadow:     []string{"ns[foo]-policy[httpbin-deny-dry-run]-rule[0]"},
+		},
+		{
+			statPrefix: "istio_dry_run.allow.foo.httpbin-allow-dry-run.",
+			shadow:     []string{"ns[foo]-policy[httpbin-allow-dry-run]-rule[0]", "ns[foo]-policy[httpbin-allow]-rule[0]"},
+		},
+		{statPrefix: "tcp.", rules: []string{"ns[foo]-policy[httpbin-deny]-rule[0]"}},
+		{statPrefix: "tcp.", rules: []string{"ns[foo]-policy[httpbin-allow]-rule[0]"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got filters %+v, want %+v", got, want)
+	}
+}
+
+func TestGenerator_GenerateHTTPDryRun(t *testing.T) {
+	g := New(trustdomain.Bundle{}, httpbin, "foo", yamlPolicy(t, basePath+"dry-run-in.yaml"), Option{IsIstioVersionGE15: true})
+	if g == nil {
+		t.Fatalf("failed to create generator")
+	}
+	var got []string
+	for _, f := range g.BuildHTTP() {
+		config := &rbachttppb.RBAC{}
+		if err := ptypes.UnmarshalAny(f.GetTypedConfig(), config); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, shadowRulesStatPrefix(t, config))
+	}
+	want := []string{"istio_dry_run.deny.foo.httpbin-deny-dry-run.", "istio_dry_run.allow.foo.httpbin-allow-dry-run.", "", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got shadow rules stat prefixes %q, want %q", got, want)
+	}
+}
+
+func TestGenerator_GenerateDryRunRemoved(t *testing.T) {
+	data, err := ioutil.ReadFile(basePath + "dry-run-in.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _, err := crd.ParseInputs(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var configs []*config.Config
+	for i := range c {
+		// Removing the annotation enforces the policies.
+		delete(c[i].Annotations, model.DryRunAnnotation)
+		configs = append(configs, &c[i])
+	}
+	g := New(trustdomain.Bundle{}, httpbin, "foo", newAuthzPolicies(t, configs), Option{IsIstioVersionGE15: true})
+	if g == nil {
+		t.Fatalf("failed to create generator")
+	}
+	type filter struct {
+		rules  []string
+		shadow []string
+	}
+	var got []filter
+	for _, f := range g.BuildHTTP() {
+		config := &rbachttppb.RBAC{}
+		if err := ptypes.UnmarshalAny(f.GetTypedConfig(), config); err != nil {
+			t.Fatal(err)
+		}
+		if prefix := shadowRulesStatPrefix(t, config); prefix != "" {
+			t.Errorf("unexpected shadow rules stat prefix %q", prefix)
+		}
+		got = append(got, filter{rules: policyNames(config.GetRules()), shadow: policyNames(config.GetShadowRules())})
+	}
+	want := []filter{
+		{rules: []string{"ns[foo]-policy[httpbin-deny-dry-run]-rule[0]", "ns[foo]-policy[httpbin-deny]-rule[0]"}},
+		{rules: []string{"ns[foo]-policy[httpbin-allow-dry-run]-rule[0]", "ns[foo]-policy[httpbin-allow]-rule[0]"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got filters %+v, want %+v", got, want)
+	}
+}
+
+// shadowRulesStatPrefix returns the shadow_rules_stat_prefix of the HTTP RBAC config, if set.
+func shadowRulesStatPrefix(t *testing.T, config *rbachttppb.RBAC) string {
+	t.Helper()
+	b := config.ProtoReflect().GetUnknown()
+	if len(b) == 0 {
+		return ""
+	}
+	num, typ, n := protowire.ConsumeTag(b)
+	if n < 0 || num != shadowRulesStatPrefixField || typ != protowire.BytesType {
+		t.Fatalf("unexpected unknown fields %v", b)
+	}
+	prefix, m := protowire.ConsumeString(b[n:])
+	if m < 0 {
+		t.Fatalf("invalid shadow rules stat prefix %v", b)
+	}
+	return prefix
+}
+
+func policyNames(rules *rbacpb.RBAC) []string {
+	var names []string
+	for name := range rules.GetPolicies() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func verify(t *testing.T, gots []proto.Message, wants []string, forTCP bool) {
 	t.Helper()
 
@@ -31,6 +31,9 @@ const (
 	// RBACTCPFilterName is the name of the RBAC network filter in envoy.
 	RBACTCPFilterName       = "envoy.filters.network.rbac"
 	RBACTCPFilterStatPrefix = "tcp."
+	// RBACDryRunStatPrefix prefixes the shadow rules stats of the filters of the policies in dry-run mode, followed by
+	// the action, namespace and name of the policy.
+	RBACDryRunStatPrefix = "istio_dry_run."
 
 	attrRequestHeader    = "request.headers"             // header name is surrounded by brackets, e.g. "request.headers[User-Agent]".
 	attrSrcIP            = "source.ip"                   // supports both single ip and cidr, e.g. "10.1.2.3" or "10.1.0.0/16".
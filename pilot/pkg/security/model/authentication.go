@@ -226,9 +226,16 @@ func ConstructValidationContext(rootCAFilePath string, subjectAltNames []string)
 	return ret
 }
 
+// appendURIPrefixToTrustDomain returns the SPIFFE URI prefixes of the trust domains, in the same order, skipping
+// empty and duplicated trust domains.
 func appendURIPrefixToTrustDomain(trustDomainAliases []string) []string {
 	var res []string
+	seen := map[string]struct{}{}
 	for _, td := range trustDomainAliases {
+		if _, f := seen[td]; f || td == "" {
+			continue
+		}
+		seen[td] = struct{}{}
 		res = append(res, spiffe.URIPrefix+td+"/")
 	}
 	return res
@@ -297,7 +304,11 @@ func ApplyCredentialSDSToServerCommonTLSContext(tlsContext *tls.CommonTlsContext
 	}
 	// If tls mode is MUTUAL, create SDS config for gateway/sidecar to fetch certificate validation context
 	// at gateway agent. Otherwise, use the static certificate validation context config.
-	if tlsOpts.Mode == networking.ServerTLSSettings_MUTUAL {
+	// With PILOT_ENABLE_ISTIO_MUTUAL_CREDENTIAL_CA, an ISTIO_MUTUAL server with a credentialName overrides both the
+	// workload certificate and the root CA, e.g. to trust the workloads of another network at an east-west gateway,
+	// so the client certificates are validated against the CA of the credential as well.
+	if tlsOpts.Mode == networking.ServerTLSSettings_MUTUAL ||
+		(tlsOpts.Mode == networking.ServerTLSSettings_ISTIO_MUTUAL && features.EnableIstioMutualCredentialCA) {
 		defaultValidationContext := &tls.CertificateValidationContext{
 			MatchSubjectAltNames:  util.StringToExactMatch(tlsOpts.SubjectAltNames),
 			VerifyCertificateSpki: tlsOpts.VerifyCertificateSpki,
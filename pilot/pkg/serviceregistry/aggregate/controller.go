@@ -17,6 +17,7 @@ package aggregate
 import (
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 
@@ -44,6 +45,14 @@ type Controller struct {
 	registries []serviceregistry.Instance
 	storeLock  sync.RWMutex
 	meshHolder mesh.Holder
+
+	// healthMutex protects the fields below, tracking the health of the registries.
+	healthMutex sync.RWMutex
+	// addedAt is the time the registries were added, by cluster.
+	addedAt          map[string]time.Time
+	staleClusters    map[string]bool
+	degradedClusters map[string]bool
+	staleHandlers    []func(cluster string, stale bool)
 }
 
 type Options struct {
@@ -66,6 +75,15 @@ func (c *Controller) AddRegistry(registry serviceregistry.Instance) {
 	registries := c.registries
 	registries = append(registries, registry)
 	c.registries = registries
+
+	c.healthMutex.Lock()
+	if c.addedAt == nil {
+		c.addedAt = map[string]time.Time{}
+	}
+	if _, f := c.addedAt[registry.Cluster()]; !f {
+		c.addedAt[registry.Cluster()] = time.Now()
+	}
+	c.healthMutex.Unlock()
 }
 
 // DeleteRegistry deletes specified registry from the aggregated controller
@@ -85,6 +103,9 @@ func (c *Controller) DeleteRegistry(clusterID string) {
 	registries := c.registries
 	registries = append(registries[:index], registries[index+1:]...)
 	c.registries = registries
+	c.healthMutex.Lock()
+	delete(c.addedAt, clusterID)
+	c.healthMutex.Unlock()
 	log.Infof("Registry for the cluster %s has been deleted.", clusterID)
 }
 
@@ -308,15 +329,18 @@ func (c *Controller) Run(stop <-chan struct{}) {
 	for _, r := range c.GetRegistries() {
 		go r.Run(stop)
 	}
+	go c.monitorRegistryHealth(stop)
 
 	<-stop
 	log.Info("Registry Aggregator terminated")
 }
 
-// HasSynced returns true when all registries have synced
+// HasSynced returns true when all registries have synced, or are remote cluster registries degraded by
+// PILOT_REMOTE_REGISTRY_SYNC_TIMEOUT.
 func (c *Controller) HasSynced() bool {
+	now := time.Now()
 	for _, r := range c.GetRegistries() {
-		if !r.HasSynced() {
+		if !r.HasSynced() && !isDegraded(r, c.registryAddedTime(r.Cluster()), now) {
 			return false
 		}
 	}
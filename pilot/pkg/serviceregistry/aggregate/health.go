@@ -0,0 +1,216 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"sort"
+	"time"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	clusterTag = monitoring.MustCreateLabel("cluster")
+
+	// registryStale is 1 for the clusters whose registry is stale, and 0 otherwise.
+	registryStale = monitoring.NewGauge(
+		"pilot_registry_stale",
+		"Whether the service registry of a cluster did not sync with its source for longer than "+
+			"PILOT_REGISTRY_STALE_THRESHOLD, by cluster.",
+		monitoring.WithLabels(clusterTag),
+	)
+
+	// registryHealthCheckInterval is the interval the health of the registries is updated at.
+	registryHealthCheckInterval = 10 * time.Second
+)
+
+func init() {
+	monitoring.MustRegister(registryStale)
+}
+
+var _ model.StaleRegistryReporter = &Controller{}
+
+// RegistryHealth is the state of the synchronization of a registry with its source.
+type RegistryHealth struct {
+	Cluster  string `json:"cluster"`
+	Provider string `json:"provider"`
+	// Synced is true once the registry completed its initial sync.
+	Synced bool `json:"synced"`
+	// LastSync is the last time the registry synced with its source, for the registries reporting it.
+	LastSync *time.Time `json:"lastSync,omitempty"`
+	// Error is the last error syncing the registry, if it did not sync successfully since.
+	Error string `json:"error,omitempty"`
+	// Stale is true if the registry did not sync for longer than PILOT_REGISTRY_STALE_THRESHOLD, so its services
+	// and endpoints may be out of date.
+	Stale bool `json:"stale"`
+	// Degraded is true if the registry of a remote cluster did not complete its initial sync within
+	// PILOT_REMOTE_REGISTRY_SYNC_TIMEOUT, and no longer blocks the readiness of istiod.
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// RegistryHealth returns the health of all registries.
+func (c *Controller) RegistryHealth() []RegistryHealth {
+	now := time.Now()
+	registries := c.GetRegistries()
+	out := make([]RegistryHealth, 0, len(registries))
+	for _, r := range registries {
+		out = append(out, c.registryHealth(r, now))
+	}
+	return out
+}
+
+func (c *Controller) registryHealth(r serviceregistry.Instance, now time.Time) RegistryHealth {
+	h := RegistryHealth{
+		Cluster:  r.Cluster(),
+		Provider: string(r.Provider()),
+		Synced:   r.HasSynced(),
+	}
+	added := c.registryAddedTime(r.Cluster())
+	if reporter, ok := r.(serviceregistry.SyncStatusReporter); ok {
+		status := reporter.SyncStatus()
+		h.Error = status.Error
+		// A registry which never synced is stale once it was added for longer than the threshold.
+		since := added
+		if !status.LastSync.IsZero() {
+			lastSync := status.LastSync
+			h.LastSync = &lastSync
+			since = lastSync
+		}
+		h.Stale = now.Sub(since) > features.RegistryStaleThreshold
+	}
+	h.Degraded = !h.Synced && isDegraded(r, added, now)
+	return h
+}
+
+// isDegraded returns true if the registry is a remote cluster registry that did not complete its initial sync
+// within PILOT_REMOTE_REGISTRY_SYNC_TIMEOUT.
+func isDegraded(r serviceregistry.Instance, added, now time.Time) bool {
+	timeout := features.RemoteRegistrySyncTimeout
+	if timeout <= 0 || !isRemoteRegistry(r) {
+		return false
+	}
+	return now.Sub(added) > timeout
+}
+
+// isRemoteRegistry returns true for the registries of the remote clusters.
+func isRemoteRegistry(r serviceregistry.Instance) bool {
+	if r.Provider() != serviceregistry.Kubernetes {
+		return false
+	}
+	// The registry of the local cluster may be named after the provider rather than the cluster.
+	return r.Cluster() != "" && r.Cluster() != string(serviceregistry.Kubernetes) && r.Cluster() != features.ClusterName
+}
+
+func (c *Controller) registryAddedTime(cluster string) time.Time {
+	c.healthMutex.RLock()
+	defer c.healthMutex.RUnlock()
+	return c.addedAt[cluster]
+}
+
+// AppendStaleRegistryHandler adds a handler notified when the registry of a cluster becomes stale, or recovers.
+func (c *Controller) AppendStaleRegistryHandler(f func(cluster string, stale bool)) {
+	c.healthMutex.Lock()
+	defer c.healthMutex.Unlock()
+	c.staleHandlers = append(c.staleHandlers, f)
+}
+
+// StaleClusters implements model.StaleRegistryReporter, with the clusters found stale by the last health update.
+func (c *Controller) StaleClusters() []string {
+	c.healthMutex.RLock()
+	defer c.healthMutex.RUnlock()
+	var out []string
+	for cluster, stale := range c.staleClusters {
+		if stale {
+			out = append(out, cluster)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// monitorRegistryHealth periodically updates the health of the registries, until stop.
+func (c *Controller) monitorRegistryHealth(stop <-chan struct{}) {
+	ticker := time.NewTicker(registryHealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		c.updateRegistryHealth()
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// updateRegistryHealth records the health of the registries, and notifies the handlers of the clusters whose
+// registry became stale or recovered.
+func (c *Controller) updateRegistryHealth() {
+	stale := map[string]bool{}
+	degraded := map[string]bool{}
+	for _, h := range c.RegistryHealth() {
+		// Several registries may share a cluster, such as the service entries of the config cluster.
+		stale[h.Cluster] = stale[h.Cluster] || h.Stale
+		if h.Degraded {
+			degraded[h.Cluster] = true
+		}
+	}
+
+	c.healthMutex.Lock()
+	previous, previousDegraded := c.staleClusters, c.degradedClusters
+	c.staleClusters, c.degradedClusters = stale, degraded
+	handlers := c.staleHandlers
+	c.healthMutex.Unlock()
+
+	for cluster := range degraded {
+		if !previousDegraded[cluster] {
+			log.Warnf("Registry of cluster %s did not sync within %v, no longer waiting for it to be ready",
+				cluster, features.RemoteRegistrySyncTimeout)
+		}
+	}
+
+	for cluster, s := range stale {
+		recordRegistryStale(cluster, s)
+		if s == previous[cluster] {
+			continue
+		}
+		if s {
+			log.Warnf("Registry of cluster %s is stale, it did not sync for more than %v",
+				cluster, features.RegistryStaleThreshold)
+		} else {
+			log.Infof("Registry of cluster %s is no longer stale", cluster)
+		}
+		for _, f := range handlers {
+			f(cluster, s)
+		}
+	}
+	// Reset the clusters whose registries were deleted.
+	for cluster := range previous {
+		if _, f := stale[cluster]; !f {
+			recordRegistryStale(cluster, false)
+		}
+	}
+}
+
+func recordRegistryStale(cluster string, stale bool) {
+	value := 0.0
+	if stale {
+		value = 1
+	}
+	registryStale.With(clusterTag.Value(cluster)).Record(value)
+}
@@ -0,0 +1,226 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/mock"
+)
+
+// syncingRegistry is a registry reporting its sync status, which can be made to stop syncing.
+type syncingRegistry struct {
+	serviceregistry.Simple
+
+	mutex  sync.Mutex
+	synced bool
+	status serviceregistry.SyncStatus
+}
+
+func newSyncingRegistry(cluster string) *syncingRegistry {
+	return &syncingRegistry{
+		Simple: serviceregistry.Simple{
+			ProviderID: serviceregistry.Kubernetes,
+			ClusterID:  cluster,
+			Controller: &mock.Controller{},
+		},
+	}
+}
+
+func (r *syncingRegistry) HasSynced() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.synced
+}
+
+func (r *syncingRegistry) SyncStatus() serviceregistry.SyncStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.status
+}
+
+func (r *syncingRegistry) sync(at time.Time, err string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.synced = true
+	r.status = serviceregistry.SyncStatus{LastSync: at, Error: err}
+}
+
+// staleValue returns the value of the pilot_registry_stale gauge for the cluster.
+func staleValue(t *testing.T, cluster string) float64 {
+	t.Helper()
+	rows, err := view.RetrieveData(registryStale.Name())
+	if err != nil {
+		t.Fatalf("failed to get value for gauge %s: %v", registryStale.Name(), err)
+	}
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key.Name() == "cluster" && tag.Value == cluster {
+				return row.Data.(*view.LastValueData).Value
+			}
+		}
+	}
+	return 0
+}
+
+func TestRegistryHealth(t *testing.T) {
+	defer func(threshold time.Duration) { features.RegistryStaleThreshold = threshold }(features.RegistryStaleThreshold)
+	features.RegistryStaleThreshold = time.Minute
+
+	ctrl := NewController(Options{})
+	healthy := newSyncingRegistry("cluster-healthy")
+	stopped := newSyncingRegistry("cluster-stopped")
+	ctrl.AddRegistry(healthy)
+	ctrl.AddRegistry(stopped)
+	// Registries not reporting their sync status are never stale.
+	ctrl.AddRegistry(serviceregistry.Simple{ProviderID: serviceregistry.Mock, ClusterID: "cluster-mock", Controller: &mock.Controller{}})
+
+	type notification struct {
+		cluster string
+		stale   bool
+	}
+	var notifications []notification
+	ctrl.AppendStaleRegistryHandler(func(cluster string, stale bool) {
+		notifications = append(notifications, notification{cluster, stale})
+	})
+
+	now := time.Now()
+	healthy.sync(now, "")
+	stopped.sync(now, "")
+	ctrl.updateRegistryHealth()
+	if got := ctrl.StaleClusters(); len(got) != 0 {
+		t.Fatalf("expected no stale cluster, got %v", got)
+	}
+	if len(notifications) != 0 {
+		t.Fatalf("unexpected notifications %v", notifications)
+	}
+
+	// The remote API server becomes unreachable, and the registry stops syncing.
+	stopped.sync(now.Add(-2*time.Minute), "failed to reach the API server: connection refused")
+	ctrl.updateRegistryHealth()
+
+	health := map[string]RegistryHealth{}
+	for _, h := range ctrl.RegistryHealth() {
+		health[h.Cluster] = h
+	}
+	if h := health["cluster-stopped"]; !h.Stale || h.LastSync == nil || h.Error == "" {
+		t.Fatalf("expected cluster-stopped to be stale with its last sync and error, got %+v", h)
+	}
+	if h := health["cluster-healthy"]; h.Stale || h.Error != "" || !h.Synced {
+		t.Fatalf("expected cluster-healthy to be healthy, got %+v", h)
+	}
+	if h := health["cluster-mock"]; h.Stale || h.LastSync != nil {
+		t.Fatalf("expected cluster-mock not to report its sync, got %+v", h)
+	}
+	if got, want := ctrl.StaleClusters(), []string{"cluster-stopped"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got stale clusters %v, want %v", got, want)
+	}
+	if v := staleValue(t, "cluster-stopped"); v != 1 {
+		t.Fatalf("expected pilot_registry_stale to be 1 for cluster-stopped, got %v", v)
+	}
+	if v := staleValue(t, "cluster-healthy"); v != 0 {
+		t.Fatalf("expected pilot_registry_stale to be 0 for cluster-healthy, got %v", v)
+	}
+
+	// Handlers are only notified of the transitions.
+	ctrl.updateRegistryHealth()
+	if want := []notification{{"cluster-stopped", true}}; !reflect.DeepEqual(notifications, want) {
+		t.Fatalf("got notifications %v, want %v", notifications, want)
+	}
+
+	// The registry recovers.
+	stopped.sync(time.Now(), "")
+	ctrl.updateRegistryHealth()
+	if got := ctrl.StaleClusters(); len(got) != 0 {
+		t.Fatalf("expected no stale cluster after recovery, got %v", got)
+	}
+	if v := staleValue(t, "cluster-stopped"); v != 0 {
+		t.Fatalf("expected pilot_registry_stale to be reset for cluster-stopped, got %v", v)
+	}
+	if want := []notification{{"cluster-stopped", true}, {"cluster-stopped", false}}; !reflect.DeepEqual(notifications, want) {
+		t.Fatalf("got notifications %v, want %v", notifications, want)
+	}
+}
+
+func TestRegistryHealthNeverSynced(t *testing.T) {
+	defer func(threshold time.Duration) { features.RegistryStaleThreshold = threshold }(features.RegistryStaleThreshold)
+	features.RegistryStaleThreshold = time.Minute
+
+	ctrl := NewController(Options{})
+	r := newSyncingRegistry("cluster-1")
+	ctrl.AddRegistry(r)
+	ctrl.updateRegistryHealth()
+	if got := ctrl.StaleClusters(); len(got) != 0 {
+		t.Fatalf("expected a newly added registry not to be stale, got %v", got)
+	}
+
+	// A registry which never synced is stale once it was added for longer than the threshold.
+	ctrl.addedAt["cluster-1"] = time.Now().Add(-2 * time.Minute)
+	ctrl.updateRegistryHealth()
+	if got, want := ctrl.StaleClusters(), []string{"cluster-1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got stale clusters %v, want %v", got, want)
+	}
+
+	// The gauge is reset once the registry is deleted.
+	ctrl.DeleteRegistry("cluster-1")
+	ctrl.updateRegistryHealth()
+	if v := staleValue(t, "cluster-1"); v != 0 {
+		t.Fatalf("expected pilot_registry_stale to be reset for the deleted cluster-1, got %v", v)
+	}
+}
+
+func TestHasSyncedRemoteRegistrySyncTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { features.RemoteRegistrySyncTimeout = timeout }(features.RemoteRegistrySyncTimeout)
+
+	ctrl := NewController(Options{})
+	local := newSyncingRegistry(features.ClusterName)
+	remote := newSyncingRegistry("remote-cluster")
+	ctrl.AddRegistry(local)
+	ctrl.AddRegistry(remote)
+	local.sync(time.Now(), "")
+	// The remote registry never completes its initial sync.
+	ctrl.addedAt["remote-cluster"] = time.Now().Add(-2 * time.Minute)
+
+	features.RemoteRegistrySyncTimeout = 0
+	if ctrl.HasSynced() {
+		t.Fatal("expected the unsynced remote registry to block the sync without a timeout")
+	}
+
+	features.RemoteRegistrySyncTimeout = time.Minute
+	if !ctrl.HasSynced() {
+		t.Fatal("expected the remote registry not synced within the timeout to be degraded")
+	}
+	for _, h := range ctrl.RegistryHealth() {
+		if h.Degraded != (h.Cluster == "remote-cluster") {
+			t.Fatalf("unexpected degraded state of %+v", h)
+		}
+	}
+
+	// The local registry always blocks the sync.
+	ctrl.addedAt[features.ClusterName] = time.Now().Add(-2 * time.Minute)
+	local.mutex.Lock()
+	local.synced = false
+	local.mutex.Unlock()
+	if ctrl.HasSynced() {
+		t.Fatal("expected the unsynced local registry to block the sync")
+	}
+}
@@ -15,6 +15,8 @@
 package serviceregistry
 
 import (
+	"time"
+
 	"istio.io/istio/pilot/pkg/model"
 )
 
@@ -31,6 +33,19 @@ type Instance interface {
 	Cluster() string
 }
 
+// SyncStatus is the state of the synchronization of a registry with its source.
+type SyncStatus struct {
+	// LastSync is the last time the registry successfully synced with its source. It is zero if it never did.
+	LastSync time.Time
+	// Error is the last error syncing the registry, if it did not sync successfully since.
+	Error string
+}
+
+// SyncStatusReporter is implemented by the registries reporting the state of their synchronization.
+type SyncStatusReporter interface {
+	SyncStatus() SyncStatus
+}
+
 var _ Instance = &Simple{}
 
 // Simple Instance implementation, where fields are set individually.
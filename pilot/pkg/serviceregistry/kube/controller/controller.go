@@ -129,6 +129,12 @@ type Options struct {
 
 	// Maximum burst for throttle when communicating with the kubernetes API
 	KubernetesAPIBurst int
+
+	// ClusterHandlers are notified when remote clusters are added to or removed from a Multicluster.
+	ClusterHandlers []ClusterHandler
+
+	// NamespaceControllerScope restricts the namespaces the CA root certificate configmap is created in.
+	NamespaceControllerScope NamespaceScope
 }
 
 // EndpointMode decides what source to use to get endpoint information
@@ -217,6 +223,9 @@ type Controller struct {
 	// Network name for the registry as specified by the MeshNetworks configmap
 	networkForRegistry string
 
+	// syncStatus tracks the synchronization with the API server.
+	syncStatus syncStatusTracker
+
 	once sync.Once
 }
 
@@ -271,6 +280,11 @@ func NewController(kubeClient kubelib.Client, options Options) *Controller {
 	})
 	registerHandlers(c.pods.informer, c.queue, "Pods", c.pods.onEvent, nil)
 
+	c.trackSync(c.serviceInformer, "Services")
+	c.trackSync(c.endpoints.getInformer(), "Endpoints")
+	c.trackSync(c.nodeInformer, "Nodes")
+	c.trackSync(c.pods.informer, "Pods")
+
 	return c
 }
 
@@ -555,6 +569,7 @@ func (c *Controller) Run(stop <-chan struct{}) {
 		c.networksWatcher.AddNetworksHandler(c.reloadNetworkLookup)
 		c.reloadNetworkLookup()
 	}
+	go c.checkSync(stop)
 	cache.WaitForCacheSync(stop, c.HasSynced)
 	c.queue.Run(stop)
 	log.Infof("Controller terminated")
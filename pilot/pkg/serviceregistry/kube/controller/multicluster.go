@@ -44,6 +44,14 @@ var (
 	validationWebhookConfigNameTemplate = "istiod-" + validationWebhookConfigNameTemplateVar
 )
 
+// ClusterHandler is notified when a remote cluster is added to or removed from a Multicluster.
+type ClusterHandler interface {
+	// ClusterAdded is called before the informers of the clients are started. The stop channel is
+	// closed when the cluster is removed.
+	ClusterAdded(clients kubelib.Client, clusterID string, stop <-chan struct{}) error
+	ClusterDeleted(clusterID string) error
+}
+
 type kubeController struct {
 	*Controller
 	stopCh chan struct{}
@@ -64,9 +72,11 @@ type Multicluster struct {
 
 	// fetchCaRoot maps the certificate name to the certificate
 	fetchCaRoot      func() map[string]string
+	namespaceScope   NamespaceScope
 	caBundlePath     string
 	secretNamespace  string
 	secretController *secretcontroller.Controller
+	clusterHandlers  []ClusterHandler
 }
 
 // NewMulticluster initializes data structure to store multicluster information
@@ -90,8 +100,10 @@ func NewMulticluster(kc kubernetes.Interface, secretNamespace string, opts Optio
 		networksWatcher:       networksWatcher,
 		metrics:               opts.Metrics,
 		fetchCaRoot:           opts.FetchCaRoot,
+		namespaceScope:        opts.NamespaceControllerScope,
 		caBundlePath:          opts.CABundlePath,
 		secretNamespace:       secretNamespace,
+		clusterHandlers:       opts.ClusterHandlers,
 	}
 	mc.initSecretController(kc)
 
@@ -132,8 +144,11 @@ func (m *Multicluster) AddMemberCluster(clients kubelib.Client, clusterID string
 	go kubectl.Run(stopCh)
 	webhookConfigName := strings.ReplaceAll(validationWebhookConfigNameTemplate, validationWebhookConfigNameTemplateVar, m.secretNamespace)
 	if m.fetchCaRoot != nil {
-		nc := NewNamespaceController(m.fetchCaRoot, clients)
-		go nc.Run(stopCh)
+		if nc, err := NewScopedNamespaceController(m.fetchCaRoot, clients, m.namespaceScope); err != nil {
+			log.Errorf("failed to create the namespace controller of cluster %s: %v", clusterID, err)
+		} else {
+			go nc.Run(stopCh)
+		}
 		go webhooks.PatchCertLoop(features.InjectionWebhookConfigName.Get(), webhookName, m.caBundlePath, clients.Kube(), stopCh)
 		valicationWebhookController := webhooks.CreateValidationWebhookController(clients, webhookConfigName,
 			m.secretNamespace, m.caBundlePath, true)
@@ -142,6 +157,12 @@ func (m *Multicluster) AddMemberCluster(clients kubelib.Client, clusterID string
 		}
 	}
 
+	for _, h := range m.clusterHandlers {
+		if err := h.ClusterAdded(clients, clusterID, stopCh); err != nil {
+			log.Errorf("failed to handle addition of cluster %s: %v", clusterID, err)
+		}
+	}
+
 	clients.RunAndWait(stopCh)
 	return nil
 }
@@ -171,6 +192,11 @@ func (m *Multicluster) DeleteMemberCluster(clusterID string) error {
 	}
 	close(m.remoteKubeControllers[clusterID].stopCh)
 	delete(m.remoteKubeControllers, clusterID)
+	for _, h := range m.clusterHandlers {
+		if err := h.ClusterDeleted(clusterID); err != nil {
+			log.Warnf("failed to handle removal of cluster %s: %v", clusterID, err)
+		}
+	}
 	if m.XDSUpdater != nil {
 		m.XDSUpdater.ConfigUpdate(&model.PushRequest{Full: true})
 	}
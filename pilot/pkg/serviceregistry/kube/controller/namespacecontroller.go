@@ -20,7 +20,9 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 
@@ -43,12 +45,32 @@ var (
 	configMapLabel = map[string]string{"istio.io/config": "true"}
 )
 
+// NamespaceScope restricts the namespaces a NamespaceController reconciles the configmap in.
+type NamespaceScope struct {
+	// Namespaces always in scope.
+	Namespaces []string
+	// LabelSelector selects the namespaces in scope, in addition to Namespaces. It is re-evaluated as the labels
+	// of the namespaces change.
+	LabelSelector string
+	// Cleanup deletes the configmap from the namespaces out of scope.
+	Cleanup bool
+}
+
+// IsEmpty returns true if the scope does not restrict the namespaces.
+func (s NamespaceScope) IsEmpty() bool {
+	return len(s.Namespaces) == 0 && s.LabelSelector == ""
+}
+
 // NamespaceController manages reconciles a configmap in each namespace with a desired set of data.
 type NamespaceController struct {
 	// getData is the function to fetch the data we will insert into the config map
 	getData func() map[string]string
 	client  corev1.CoreV1Interface
 
+	scope NamespaceScope
+	// selector is the parsed scope label selector, nil if the scope does not restrict the namespaces.
+	selector labels.Selector
+
 	queue              queue.Instance
 	namespacesInformer cache.SharedInformer
 	configMapInformer  cache.SharedInformer
@@ -56,11 +78,30 @@ type NamespaceController struct {
 
 // NewNamespaceController returns a pointer to a newly constructed NamespaceController instance.
 func NewNamespaceController(data func() map[string]string, kubeClient kube.Client) *NamespaceController {
+	c, _ := NewScopedNamespaceController(data, kubeClient, NamespaceScope{})
+	return c
+}
+
+// NewScopedNamespaceController returns a NamespaceController only reconciling the configmap in the namespaces within
+// the scope.
+func NewScopedNamespaceController(data func() map[string]string, kubeClient kube.Client,
+	scope NamespaceScope) (*NamespaceController, error) {
 	c := &NamespaceController{
 		getData: data,
 		client:  kubeClient.CoreV1(),
+		scope:   scope,
 		queue:   queue.NewQueue(time.Second),
 	}
+	if !scope.IsEmpty() {
+		selector := labels.Nothing()
+		if scope.LabelSelector != "" {
+			var err error
+			if selector, err = labels.Parse(scope.LabelSelector); err != nil {
+				return nil, fmt.Errorf("invalid namespace label selector %q: %v", scope.LabelSelector, err)
+			}
+		}
+		c.selector = selector
+	}
 
 	c.configMapInformer = kubeClient.KubeInformer().Core().V1().ConfigMaps().Informer()
 	c.configMapInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -70,7 +111,7 @@ func NewNamespaceController(data func() map[string]string, kubeClient kube.Clien
 				log.Errorf("failed to convert to configmap: %v", err)
 			}
 			// This is a change to a configmap we don't watch, ignore it
-			if cm.Name != CACertNamespaceConfigMap {
+			if cm.Name != CACertNamespaceConfigMap || !c.namespaceInScope(cm.Namespace) {
 				return
 			}
 			c.queue.Push(func() error {
@@ -93,7 +134,7 @@ func NewNamespaceController(data func() map[string]string, kubeClient kube.Clien
 				}
 				// If the namespace is terminating, we may get into a loop of trying to re-add the configmap back
 				// We should make sure the namespace still exists
-				if ns.Status.Phase != v1.NamespaceTerminating {
+				if ns.Status.Phase != v1.NamespaceTerminating && c.inScope(ns) {
 					return c.insertDataForNamespace(cm.Namespace)
 				}
 				return nil
@@ -115,7 +156,7 @@ func NewNamespaceController(data func() map[string]string, kubeClient kube.Clien
 		},
 	})
 
-	return c
+	return c, nil
 }
 
 // Run starts the NamespaceController until a value is sent to stopCh.
@@ -138,14 +179,67 @@ func (nc *NamespaceController) insertDataForNamespace(ns string) error {
 }
 
 // On namespace change, update the config map.
-// If terminating, this will be skipped
+// If terminating, this will be skipped. If the namespace is out of scope, the config map is removed if the
+// scope requires it.
 func (nc *NamespaceController) namespaceChange(ns *v1.Namespace) error {
-	if ns.Status.Phase != v1.NamespaceTerminating {
+	if ns.Status.Phase == v1.NamespaceTerminating {
+		return nil
+	}
+	if nc.inScope(ns) {
 		return nc.insertDataForNamespace(ns.Name)
 	}
+	if nc.scope.Cleanup {
+		return nc.removeDataForNamespace(ns.Name)
+	}
 	return nil
 }
 
+// removeDataForNamespace deletes the config map of the namespace, if it was created by the controller.
+func (nc *NamespaceController) removeDataForNamespace(ns string) error {
+	cm, err := nc.client.ConfigMaps(ns).Get(context.TODO(), CACertNamespaceConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// Leave alone a config map of the same name not managed by Istio.
+	if !labels.SelectorFromSet(configMapLabel).Matches(labels.Set(cm.Labels)) {
+		return nil
+	}
+	log.Infof("removing %s from namespace %s out of scope", CACertNamespaceConfigMap, ns)
+	if err := nc.client.ConfigMaps(ns).Delete(context.TODO(), CACertNamespaceConfigMap, metav1.DeleteOptions{}); err != nil &&
+		!errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// inScope returns true if the config map must be reconciled in the namespace.
+func (nc *NamespaceController) inScope(ns *v1.Namespace) bool {
+	if nc.selector == nil {
+		return true
+	}
+	for _, name := range nc.scope.Namespaces {
+		if name == ns.Name {
+			return true
+		}
+	}
+	return nc.selector.Matches(labels.Set(ns.Labels))
+}
+
+// namespaceInScope returns true if the config map must be reconciled in the namespace, looked up by name.
+// Namespaces not seen yet are out of scope, the config map is reconciled once they are added.
+func (nc *NamespaceController) namespaceInScope(name string) bool {
+	if nc.selector == nil {
+		return true
+	}
+	obj, f, err := nc.namespacesInformer.GetStore().GetByKey(name)
+	if err != nil || !f {
+		return false
+	}
+	return nc.inScope(obj.(*v1.Namespace))
+}
+
 // When a config map is changed, merge the data into the configmap
 func (nc *NamespaceController) configMapChange(cm *v1.ConfigMap) error {
 	if err := certutil.UpdateDataInConfigMap(nc.client, cm.DeepCopy(), nc.getData()); err != nil {
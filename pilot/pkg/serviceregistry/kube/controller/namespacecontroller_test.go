@@ -54,6 +54,100 @@ func TestNamespaceController(t *testing.T) {
 	expectConfigMap(t, client, "foo", testdata)
 }
 
+func TestNamespaceControllerScope(t *testing.T) {
+	client := kube.NewFakeClient()
+	testdata := map[string]string{"key": "value"}
+	nc, err := NewScopedNamespaceController(func() map[string]string {
+		return testdata
+	}, client, NamespaceScope{Namespaces: []string{"istio-system"}, LabelSelector: "istio-injection=enabled", Cleanup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	client.RunAndWait(stop)
+	nc.Run(stop)
+
+	createNamespace(t, client, "istio-system")
+	expectConfigMap(t, client, "istio-system", testdata)
+	createNamespace(t, client, "foo")
+	expectNoConfigMap(t, client, "foo")
+
+	// The namespace gains the label.
+	updateNamespaceLabels(t, client, "foo", map[string]string{"istio-injection": "enabled"})
+	expectConfigMap(t, client, "foo", testdata)
+
+	// The namespace loses the label.
+	updateNamespaceLabels(t, client, "foo", nil)
+	expectNoConfigMap(t, client, "foo")
+
+	// A configmap not created by Istio is left alone.
+	if _, err := client.CoreV1().ConfigMaps("foo").Create(context.TODO(), &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: CACertNamespaceConfigMap, Namespace: "foo"},
+		Data:       map[string]string{"user": "data"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	updateNamespaceLabels(t, client, "foo", map[string]string{"team": "a"})
+	expectConfigMap(t, client, "foo", map[string]string{"user": "data"})
+}
+
+func TestNamespaceControllerScopeWithoutCleanup(t *testing.T) {
+	client := kube.NewFakeClient()
+	testdata := map[string]string{"key": "value"}
+	nc, err := NewScopedNamespaceController(func() map[string]string {
+		return testdata
+	}, client, NamespaceScope{LabelSelector: "istio-injection=enabled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	client.RunAndWait(stop)
+	nc.Run(stop)
+
+	createNamespace(t, client, "foo")
+	updateNamespaceLabels(t, client, "foo", map[string]string{"istio-injection": "enabled"})
+	expectConfigMap(t, client, "foo", testdata)
+
+	// Without cleanup, the configmap is kept but no longer reconciled.
+	updateNamespaceLabels(t, client, "foo", nil)
+	deleteConfigMap(t, client, "foo")
+	expectNoConfigMap(t, client, "foo")
+}
+
+func TestNamespaceControllerInvalidScope(t *testing.T) {
+	if _, err := NewScopedNamespaceController(func() map[string]string {
+		return nil
+	}, kube.NewFakeClient(), NamespaceScope{LabelSelector: "a=b=c"}); err == nil {
+		t.Fatal("expected an invalid label selector to be rejected")
+	}
+}
+
+func updateNamespaceLabels(t *testing.T, client kubernetes.Interface, ns string, labels map[string]string) {
+	t.Helper()
+	if _, err := client.CoreV1().Namespaces().Update(context.TODO(), &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: ns, Labels: labels},
+	}, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectNoConfigMap(t *testing.T, client kubernetes.Interface, ns string) {
+	t.Helper()
+	// Give the controller the time to act on the namespace.
+	time.Sleep(time.Millisecond * 200)
+	retry.UntilSuccessOrFail(t, func() error {
+		cm, err := client.CoreV1().ConfigMaps(ns).Get(context.TODO(), CACertNamespaceConfigMap, metav1.GetOptions{})
+		if err == nil {
+			return fmt.Errorf("unexpected configmap %+v", cm)
+		}
+		return nil
+	}, retry.Timeout(time.Second*2))
+}
+
 func deleteConfigMap(t *testing.T, client kubernetes.Interface, ns string) {
 	t.Helper()
 	if err := client.CoreV1().ConfigMaps(ns).Delete(context.TODO(), CACertNamespaceConfigMap, metav1.DeleteOptions{}); err != nil {
@@ -0,0 +1,146 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/pkg/log"
+)
+
+// syncCheckInterval is the interval the reachability of the API server is checked at.
+var syncCheckInterval = 30 * time.Second
+
+var _ serviceregistry.SyncStatusReporter = &Controller{}
+
+// apiServerSync is the source of the sync status for the reachability checks of the API server.
+const apiServerSync = "API server"
+
+// syncStatusTracker tracks the synchronization of the controller with the API server. The errors are tracked by
+// source, an informer or the reachability checks of the API server, so that the error of a source is only cleared
+// once that source syncs again.
+type syncStatusTracker struct {
+	mutex    sync.RWMutex
+	lastSync time.Time
+	// errors holds the last error of each source which did not sync since, by source.
+	errors map[string]string
+}
+
+// synced records the sync of the source, and clears its error.
+func (t *syncStatusTracker) synced(source string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.lastSync = time.Now()
+	delete(t.errors, source)
+}
+
+// syncedAll records the sync of all the sources.
+func (t *syncStatusTracker) syncedAll() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.lastSync = time.Now()
+	t.errors = nil
+}
+
+func (t *syncStatusTracker) failed(source string, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.errors == nil {
+		t.errors = map[string]string{}
+	}
+	t.errors[source] = err.Error()
+}
+
+// get returns the sync status, with the errors of the sources which did not sync since, sorted by source.
+func (t *syncStatusTracker) get() serviceregistry.SyncStatus {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	sources := make([]string, 0, len(t.errors))
+	for source := range t.errors {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	errs := make([]string, 0, len(sources))
+	for _, source := range sources {
+		errs = append(errs, t.errors[source])
+	}
+	return serviceregistry.SyncStatus{LastSync: t.lastSync, Error: strings.Join(errs, "; ")}
+}
+
+// SyncStatus implements serviceregistry.SyncStatusReporter. The controller is synced while its informers can list
+// and watch the API server.
+func (c *Controller) SyncStatus() serviceregistry.SyncStatus {
+	return c.syncStatus.get()
+}
+
+// trackSync records the list and watch errors of the informer, which are otherwise only logged, and the changes it
+// watched, which show that the API server is reachable.
+func (c *Controller) trackSync(informer cache.SharedIndexInformer, otype string) {
+	if err := informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		c.syncStatus.failed(otype, fmt.Errorf("failed to watch %s: %v", otype, err))
+		cache.DefaultWatchErrorHandler(r, err)
+	}); err != nil {
+		log.Debugf("not tracking the watch errors of %s for cluster %s: %v", otype, c.clusterID, err)
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) {
+			c.syncStatus.synced(otype)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			// The periodic resyncs replay the cached objects, and don't reach the API server.
+			if oldObj, ok := old.(metav1.Object); ok {
+				if curObj, ok := cur.(metav1.Object); ok && oldObj.GetResourceVersion() == curObj.GetResourceVersion() {
+					return
+				}
+			}
+			c.syncStatus.synced(otype)
+		},
+		DeleteFunc: func(interface{}) {
+			c.syncStatus.synced(otype)
+		},
+	})
+}
+
+// checkSync records the sync of the informers, and then checks periodically that the API server is reachable, until
+// stop. The informers keep retrying to watch it, and only report when they recover with the changes they watch: a
+// reachable API server does not clear their errors.
+func (c *Controller) checkSync(stop <-chan struct{}) {
+	if !cache.WaitForCacheSync(stop, c.HasSynced) {
+		return
+	}
+	c.syncStatus.syncedAll()
+	ticker := time.NewTicker(syncCheckInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.client.Discovery().ServerVersion(); err != nil {
+			c.syncStatus.failed(apiServerSync, fmt.Errorf("failed to reach the API server: %v", err))
+		} else {
+			c.syncStatus.synced(apiServerSync)
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
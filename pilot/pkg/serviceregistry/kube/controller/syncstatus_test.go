@@ -0,0 +1,71 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestSyncStatusFromEvents(t *testing.T) {
+	controller, _ := NewFakeControllerWithOptions(FakeControllerOptions{})
+	defer controller.Stop()
+
+	// The informers synced.
+	retry.UntilSuccessOrFail(t, func() error {
+		if controller.SyncStatus().LastSync.IsZero() {
+			return fmt.Errorf("no sync recorded")
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+
+	controller.syncStatus.failed("Services", errors.New("failed to watch Services: connection refused"))
+	lastSync := controller.SyncStatus().LastSync
+	// A watched change shows the API server is reachable again, before the next periodic check.
+	createService(controller, "svc1", "nsA", nil, []int32{8080}, map[string]string{"app": "a"}, t)
+	retry.UntilSuccessOrFail(t, func() error {
+		status := controller.SyncStatus()
+		if status.Error != "" || !status.LastSync.After(lastSync) {
+			return fmt.Errorf("got sync status %+v, want a sync after %v", status, lastSync)
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+}
+
+func TestSyncStatusBySource(t *testing.T) {
+	tracker := &syncStatusTracker{}
+	tracker.failed("Services", errors.New("failed to watch Services: connection refused"))
+	tracker.failed("Pods", errors.New("failed to watch Pods: connection refused"))
+	tracker.failed(apiServerSync, errors.New("failed to reach the API server: timeout"))
+
+	// A reachable API server does not clear the errors of the informers still failing.
+	tracker.synced(apiServerSync)
+	want := "failed to watch Pods: connection refused; failed to watch Services: connection refused"
+	if got := tracker.get(); got.Error != want || got.LastSync.IsZero() {
+		t.Fatalf("got sync status %+v, want error %q", got, want)
+	}
+	tracker.synced("Services")
+	if got := tracker.get().Error; got != "failed to watch Pods: connection refused" {
+		t.Fatalf("expected only the error of the pods, got %q", got)
+	}
+	tracker.syncedAll()
+	if got := tracker.get().Error; got != "" {
+		t.Fatalf("expected no error once all synced, got %q", got)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/mcp"
+	"istio.io/istio/pilot/pkg/serviceregistry/util/xdsfake"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -163,7 +164,7 @@ func TestListCorrectTypeNoData(t *testing.T) {
 func TestListAllNameSpace(t *testing.T) {
 	g := NewWithT(t)
 
-	fx := NewFakeXDS()
+	fx := xdsfake.NewUpdater()
 	testControllerOptions.XDSUpdater = fx
 	controller := mcp.NewController(testControllerOptions)
 
@@ -348,7 +349,7 @@ func TestApplyMetadataNameWithoutNamespace(t *testing.T) {
 func TestApplyChangeNoObjects(t *testing.T) {
 	g := NewWithT(t)
 
-	fx := NewFakeXDS()
+	fx := xdsfake.NewUpdater()
 	testControllerOptions.XDSUpdater = fx
 	controller := mcp.NewController(testControllerOptions)
 
@@ -385,7 +386,7 @@ func TestApplyChangeNoObjects(t *testing.T) {
 func TestApplyConfigUpdate(t *testing.T) {
 	g := NewWithT(t)
 
-	fx := NewFakeXDS()
+	fx := xdsfake.NewUpdater()
 	testControllerOptions.XDSUpdater = fx
 	controller := mcp.NewController(testControllerOptions)
 
@@ -401,8 +402,9 @@ func TestApplyConfigUpdate(t *testing.T) {
 	err := controller.Apply(change)
 	g.Expect(err).ToNot(HaveOccurred())
 
-	event := <-fx.Events
-	g.Expect(event).To(Equal("ConfigUpdate"))
+	// The MCP controller does not set the configs updated, so any config update is expected.
+	_, err = fx.Wait(xdsfake.OfType(xdsfake.ConfigUpdate), fx.Timeout)
+	g.Expect(err).ToNot(HaveOccurred())
 }
 
 func TestInvalidResource(t *testing.T) {
@@ -703,42 +705,10 @@ func makeMessage(value []byte, typeURL string) (proto.Message, error) {
 	return nil, err
 }
 
-var _ model.XDSUpdater = &FakeXdsUpdater{}
-
-type FakeXdsUpdater struct {
-	Events    chan string
-	Endpoints chan []*model.IstioEndpoint
-}
-
-func NewFakeXDS() *FakeXdsUpdater {
-	return &FakeXdsUpdater{
-		Events:    make(chan string, 100),
-		Endpoints: make(chan []*model.IstioEndpoint, 100),
-	}
-}
-
-func (f *FakeXdsUpdater) ConfigUpdate(*model.PushRequest) {
-	f.Events <- "ConfigUpdate"
-}
-
-func (f *FakeXdsUpdater) EDSUpdate(_, _, _ string, entry []*model.IstioEndpoint) {
-	f.Events <- "EDSUpdate"
-	f.Endpoints <- entry
-}
-
-func (f *FakeXdsUpdater) EDSCacheUpdate(_, _, _ string, _ []*model.IstioEndpoint) {
-}
-
-func (f *FakeXdsUpdater) SvcUpdate(_, _, _ string, _ model.Event) {
-}
-
-func (f *FakeXdsUpdater) ProxyUpdate(_, _ string) {
-}
-
 func TestApplyIncrementalChangeRemove(t *testing.T) {
 	g := NewWithT(t)
 
-	fx := NewFakeXDS()
+	fx := xdsfake.NewUpdater()
 	testControllerOptions.XDSUpdater = fx
 	controller := mcp.NewController(testControllerOptions)
 
@@ -758,8 +728,8 @@ func TestApplyIncrementalChangeRemove(t *testing.T) {
 	g.Expect(entries).To(HaveLen(1))
 	g.Expect(entries[0].Name).To(Equal("test-gateway"))
 
-	update := <-fx.Events
-	g.Expect(update).To(Equal("ConfigUpdate"))
+	_, err = fx.WaitForConfigUpdate(gvk.Gateway)
+	g.Expect(err).ToNot(HaveOccurred())
 
 	message2 := convertToResource(g, collections.IstioNetworkingV1Alpha3Gateways.Resource().Proto(), gateway2)
 	change = convertToChange([]proto.Message{message2},
@@ -775,8 +745,8 @@ func TestApplyIncrementalChangeRemove(t *testing.T) {
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(entries).To(HaveLen(2))
 
-	update = <-fx.Events
-	g.Expect(update).To(Equal("ConfigUpdate"))
+	_, err = fx.WaitForConfigUpdate(gvk.Gateway)
+	g.Expect(err).ToNot(HaveOccurred())
 
 	for _, gw := range entries {
 		g.Expect(gw.GroupVersionKind).To(Equal(gvk.Gateway))
@@ -804,14 +774,15 @@ func TestApplyIncrementalChangeRemove(t *testing.T) {
 	g.Expect(entries[0].Name).To(Equal("test-gateway2"))
 	g.Expect(entries[0].Spec).To(Equal(message2))
 
-	update = <-fx.Events
-	g.Expect(update).To(Equal("ConfigUpdate"))
+	// The MCP controller does not set the configs updated, so any config update is expected.
+	_, err = fx.Wait(xdsfake.OfType(xdsfake.ConfigUpdate), fx.Timeout)
+	g.Expect(err).ToNot(HaveOccurred())
 }
 
 func TestApplyIncrementalChange(t *testing.T) {
 	g := NewWithT(t)
 
-	fx := NewFakeXDS()
+	fx := xdsfake.NewUpdater()
 	testControllerOptions.XDSUpdater = fx
 	controller := mcp.NewController(testControllerOptions)
 
@@ -831,8 +802,8 @@ func TestApplyIncrementalChange(t *testing.T) {
 	g.Expect(entries).To(HaveLen(1))
 	g.Expect(entries[0].Name).To(Equal("test-gateway"))
 
-	update := <-fx.Events
-	g.Expect(update).To(Equal("ConfigUpdate"))
+	_, err = fx.WaitForConfigUpdate(gvk.Gateway)
+	g.Expect(err).ToNot(HaveOccurred())
 
 	message2 := convertToResource(g, collections.IstioNetworkingV1Alpha3Gateways.Resource().Proto(), gateway2)
 	change = convertToChange([]proto.Message{message2},
@@ -858,8 +829,9 @@ func TestApplyIncrementalChange(t *testing.T) {
 		}
 	}
 
-	update = <-fx.Events
-	g.Expect(update).To(Equal("ConfigUpdate"))
+	// The MCP controller does not set the configs updated, so any config update is expected.
+	_, err = fx.Wait(xdsfake.OfType(xdsfake.ConfigUpdate), fx.Timeout)
+	g.Expect(err).ToNot(HaveOccurred())
 }
 
 func setIncremental() func(*sink.Change) {
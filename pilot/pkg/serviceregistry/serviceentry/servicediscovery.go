@@ -22,6 +22,8 @@ import (
 	"go.uber.org/atomic"
 
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/controller/workloadentry"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pkg/config"
@@ -111,6 +113,11 @@ func (s *ServiceEntryStore) workloadEntryHandler(old, curr config.Config, event
 		namespace: curr.Namespace,
 	}
 
+	// An unhealthy entry is handled as deleted, so that it is removed from the endpoints until it is healthy again.
+	if features.WorkloadEntryHealthChecks && !workloadentry.IsHealthy(curr) {
+		event = model.EventDelete
+	}
+
 	// fire off the k8s handlers
 	if len(s.workloadHandlers) > 0 {
 		si := convertWorkloadEntryToWorkloadInstance(curr.Namespace, curr)
@@ -627,6 +634,9 @@ func (s *ServiceEntryStore) maybeRefreshIndexes() {
 	}
 
 	for _, wcfg := range wles {
+		if features.WorkloadEntryHealthChecks && !workloadentry.IsHealthy(wcfg) {
+			continue
+		}
 		wle := wcfg.Spec.(*networking.WorkloadEntry)
 		key := configKey{
 			kind:      workloadEntryConfigType,
@@ -23,8 +23,11 @@ import (
 	"time"
 
 	"istio.io/api/label"
+	"istio.io/api/meta/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/controller/workloadentry"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
@@ -612,6 +615,71 @@ func TestServiceDiscoveryWorkloadUpdate(t *testing.T) {
 		expectEvents(t, events, Event{kind: "eds", host: "selector.com", namespace: selector.Namespace, endpoints: 2})
 	})
 }
+
+func TestServiceDiscoveryWorkloadHealth(t *testing.T) {
+	defaultHealthChecks := features.WorkloadEntryHealthChecks
+	features.WorkloadEntryHealthChecks = true
+	defer func() { features.WorkloadEntryHealthChecks = defaultHealthChecks }()
+	store, sd, events, stopFn := initServiceDiscovery()
+	defer stopFn()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	healthController := workloadentry.NewController(store)
+	go healthController.Run(stop)
+	proxy := &model.Proxy{IPAddresses: []string{"2.2.2.2"}, ConfigNamespace: selector.Namespace}
+	identities := []spiffe.Identity{{TrustDomain: "cluster.local", Namespace: selector.Namespace, ServiceAccount: "default"}}
+
+	wle := createWorkloadEntry("wl", selector.Name,
+		&networking.WorkloadEntry{
+			Address:        "2.2.2.2",
+			Labels:         map[string]string{"app": "wle"},
+			ServiceAccount: "default",
+		})
+	createConfigs([]*config.Config{selector}, store, t)
+	expectEvents(t, events,
+		Event{kind: "svcupdate", host: "selector.com", namespace: selector.Namespace},
+		Event{kind: "xds"})
+	createConfigs([]*config.Config{wle}, store, t)
+	instances := []*model.ServiceInstance{
+		makeInstanceWithServiceAccount(selector, "2.2.2.2", 444,
+			selector.Spec.(*networking.ServiceEntry).Ports[0], map[string]string{"app": "wle"}, "default"),
+		makeInstanceWithServiceAccount(selector, "2.2.2.2", 445,
+			selector.Spec.(*networking.ServiceEntry).Ports[1], map[string]string{"app": "wle"}, "default"),
+	}
+	expectServiceInstances(t, sd, selector, 0, instances)
+	expectEvents(t, events, Event{kind: "eds", host: "selector.com", namespace: selector.Namespace, endpoints: 2})
+
+	t.Run("unhealthy", func(t *testing.T) {
+		healthController.QueueWorkloadEntryHealth(proxy, identities, workloadentry.HealthEvent{Healthy: false, Message: "connection refused"})
+		expectEvents(t, events, Event{kind: "eds", host: "selector.com", namespace: selector.Namespace, endpoints: 0})
+		expectServiceInstances(t, sd, selector, 0, []*model.ServiceInstance{})
+		cfg := store.Get(gvk.WorkloadEntry, wle.Name, wle.Namespace)
+		if status := cfg.Status.(*v1alpha1.IstioStatus); len(status.Conditions) != 1 ||
+			status.Conditions[0].Status != "False" || status.Conditions[0].Message != "connection refused" {
+			t.Fatalf("unexpected health status %v", cfg.Status)
+		}
+	})
+
+	t.Run("healthy", func(t *testing.T) {
+		healthController.QueueWorkloadEntryHealth(proxy, identities, workloadentry.HealthEvent{Healthy: true})
+		expectEvents(t, events, Event{kind: "eds", host: "selector.com", namespace: selector.Namespace, endpoints: 2})
+		expectServiceInstances(t, sd, selector, 0, instances)
+		cfg := store.Get(gvk.WorkloadEntry, wle.Name, wle.Namespace)
+		if !workloadentry.IsHealthy(*cfg) {
+			t.Fatalf("unexpected health status of a healthy entry %v", cfg.Status)
+		}
+	})
+
+	t.Run("unhealthy after restart", func(t *testing.T) {
+		healthController.QueueWorkloadEntryHealth(proxy, identities, workloadentry.HealthEvent{Healthy: false})
+		expectEvents(t, events, Event{kind: "eds", host: "selector.com", namespace: selector.Namespace, endpoints: 0})
+		// The indexes rebuilt from the store do not include the unhealthy entry.
+		sd.refreshIndexes.Store(true)
+		expectServiceInstances(t, sd, selector, 0, []*model.ServiceInstance{})
+	})
+}
+
 func TestServiceDiscoveryWorkloadChangeLabel(t *testing.T) {
 	store, sd, events, stopFn := initServiceDiscovery()
 	defer stopFn()
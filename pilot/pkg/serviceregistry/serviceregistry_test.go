@@ -33,6 +33,7 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
+	"istio.io/istio/pilot/pkg/serviceregistry/util/xdsfake"
 	"istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
@@ -42,68 +43,16 @@ import (
 	"istio.io/istio/pkg/test/util/retry"
 )
 
-type Event struct {
-	kind      string
-	host      string
-	namespace string
-	endpoints int
-	pushReq   *model.PushRequest
-}
-
-type FakeXdsUpdater struct {
-	// Events tracks notifications received by the updater
-	Events chan Event
-}
-
-var _ model.XDSUpdater = &FakeXdsUpdater{}
-
-func (fx *FakeXdsUpdater) EDSUpdate(_, hostname string, namespace string, entry []*model.IstioEndpoint) {
-	fx.Events <- Event{kind: "eds", host: hostname, namespace: namespace, endpoints: len(entry)}
-}
-
-func (fx *FakeXdsUpdater) EDSCacheUpdate(_, hostname string, namespace string, entry []*model.IstioEndpoint) {
-	fx.Events <- Event{kind: "edscache", host: hostname, namespace: namespace, endpoints: len(entry)}
-}
-
-func (fx *FakeXdsUpdater) ConfigUpdate(req *model.PushRequest) {
-	fx.Events <- Event{kind: "xds", pushReq: req}
-}
-
-func (fx *FakeXdsUpdater) ProxyUpdate(_, _ string) {
-}
-
-func (fx *FakeXdsUpdater) SvcUpdate(_, hostname string, namespace string, _ model.Event) {
-	fx.Events <- Event{kind: "svcupdate", host: hostname, namespace: namespace}
-}
-
-func (fx *FakeXdsUpdater) Wait(et string) *Event {
-	for {
-		select {
-		case e := <-fx.Events:
-			if e.kind == et {
-				return &e
-			}
-			continue
-		case <-time.After(5 * time.Second):
-			return nil
-		}
-	}
-}
-
 func setupTest(t *testing.T) (
 	*kubecontroller.Controller,
 	*serviceentry.ServiceEntryStore,
 	model.ConfigStoreCache,
 	kubernetes.Interface,
-	*FakeXdsUpdater) {
+	*xdsfake.Updater) {
 	t.Helper()
 	client := kubeclient.NewFakeClient()
 
-	eventch := make(chan Event, 100)
-
-	xdsUpdater := &FakeXdsUpdater{
-		Events: eventch,
-	}
+	xdsUpdater := xdsfake.NewUpdater()
 	kc := kubecontroller.NewController(client, kubecontroller.Options{XDSUpdater: xdsUpdater, DomainSuffix: "cluster.local"})
 	configController := memory.NewController(memory.Make(collections.Pilot))
 
@@ -225,12 +174,9 @@ func TestWorkloadInstances(t *testing.T) {
 		// make service populated later than endpoint
 		makeService(t, kube, service)
 
-		event := xdsUpdater.Wait("edscache")
-		if event == nil {
-			t.Fatalf("expecting edscache event")
-		}
-		if event.endpoints != 1 {
-			t.Errorf("expecting 1 endpoints, but got %d ", event.endpoints)
+		event := xdsUpdater.WaitOrFail(t, xdsfake.OfType(xdsfake.EDSCacheUpdate))
+		if len(event.Endpoints) != 1 {
+			t.Errorf("expecting 1 endpoints, but got %d ", len(event.Endpoints))
 		}
 
 		instances := []ServiceInstanceResponse{{
@@ -337,12 +283,9 @@ func TestWorkloadInstances(t *testing.T) {
 
 		makeService(t, kube, service)
 
-		event := xdsUpdater.Wait("edscache")
-		if event == nil {
-			t.Fatalf("expecting edscache event")
-		}
-		if event.endpoints != 1 {
-			t.Errorf("expecting 1 endpoints, but got %d ", event.endpoints)
+		event := xdsUpdater.WaitOrFail(t, xdsfake.OfType(xdsfake.EDSCacheUpdate))
+		if len(event.Endpoints) != 1 {
+			t.Errorf("expecting 1 endpoints, but got %d ", len(event.Endpoints))
 		}
 
 		instances := []ServiceInstanceResponse{{
@@ -0,0 +1,297 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package xdsfake provides an in-memory model.XDSUpdater recording every call, for tests of registries and
+// controllers notifying the XDS server.
+//
+// The Updater makes the following guarantees:
+//   - calls are recorded synchronously, in the order they are made, and are never dropped or blocked on, so the
+//     code under test behaves as with a real XDS server regardless of how the test consumes events.
+//   - all methods are safe for concurrent use.
+//   - Wait and the WaitFor helpers consume events: each returns the first matching event recorded after the
+//     event returned by the previous successful wait, so successive waits observe events in call order.
+//   - Events and AssertOrder see every recorded event, whether it was consumed by a wait or not.
+package xdsfake
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/test"
+)
+
+// EventType is the XDSUpdater method an Event records.
+type EventType string
+
+// Types of the recorded events, one per XDSUpdater method.
+const (
+	EDSUpdate      EventType = "eds"
+	EDSCacheUpdate EventType = "edscache"
+	SvcUpdate      EventType = "service"
+	ConfigUpdate   EventType = "xds"
+	ProxyUpdate    EventType = "proxy"
+)
+
+// DefaultTimeout is the timeout of the WaitFor helpers of a new Updater.
+const DefaultTimeout = 5 * time.Second
+
+// Event is a call recorded by the Updater.
+type Event struct {
+	Type EventType
+	// Time at which the call was made.
+	Time time.Time
+
+	// Shard, Hostname and Namespace are set for EDS and service updates.
+	Shard     string
+	Hostname  string
+	Namespace string
+	// Endpoints are set for EDS updates.
+	Endpoints []*model.IstioEndpoint
+	// SvcEvent is set for service updates.
+	SvcEvent model.Event
+	// PushRequest is set for config updates.
+	PushRequest *model.PushRequest
+	// ClusterID and IP are set for proxy updates.
+	ClusterID string
+	IP        string
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case EDSUpdate, EDSCacheUpdate:
+		return fmt.Sprintf("%s %s/%s (%d endpoints)", e.Type, e.Namespace, e.Hostname, len(e.Endpoints))
+	case SvcUpdate:
+		return fmt.Sprintf("%s %s/%s %s", e.Type, e.Namespace, e.Hostname, e.SvcEvent)
+	case ConfigUpdate:
+		if e.PushRequest == nil {
+			return string(e.Type)
+		}
+		configs := make([]string, 0, len(e.PushRequest.ConfigsUpdated))
+		for key := range e.PushRequest.ConfigsUpdated {
+			configs = append(configs, fmt.Sprintf("%s/%s/%s", key.Kind.Kind, key.Namespace, key.Name))
+		}
+		sort.Strings(configs)
+		return fmt.Sprintf("%s full=%v %v", e.Type, e.PushRequest.Full, configs)
+	case ProxyUpdate:
+		return fmt.Sprintf("%s %s/%s", e.Type, e.ClusterID, e.IP)
+	}
+	return string(e.Type)
+}
+
+// Matcher selects recorded events.
+type Matcher func(Event) bool
+
+// OfType matches all events of the type.
+func OfType(t EventType) Matcher {
+	return func(e Event) bool {
+		return e.Type == t
+	}
+}
+
+// EDSFor matches the EDS updates of the hostname.
+func EDSFor(hostname string) Matcher {
+	return func(e Event) bool {
+		return e.Type == EDSUpdate && e.Hostname == hostname
+	}
+}
+
+// SvcFor matches the service updates of the hostname.
+func SvcFor(hostname string) Matcher {
+	return func(e Event) bool {
+		return e.Type == SvcUpdate && e.Hostname == hostname
+	}
+}
+
+// ConfigFor matches the config updates including a config of the kind.
+func ConfigFor(kind config.GroupVersionKind) Matcher {
+	return func(e Event) bool {
+		if e.Type != ConfigUpdate || e.PushRequest == nil {
+			return false
+		}
+		for key := range e.PushRequest.ConfigsUpdated {
+			if key.Kind == kind {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Updater is an in-memory model.XDSUpdater recording all calls. The zero value is not usable, use NewUpdater.
+type Updater struct {
+	// Delegate, if set, is called after each call is recorded, so that the Updater can observe a real XDSUpdater.
+	Delegate model.XDSUpdater
+	// Timeout of the WaitFor helpers.
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	events []Event
+	// next is the index of the first event not consumed by a wait.
+	next int
+	// notify is closed and replaced whenever an event is recorded.
+	notify chan struct{}
+}
+
+var _ model.XDSUpdater = &Updater{}
+
+// NewUpdater creates an Updater with the DefaultTimeout.
+func NewUpdater() *Updater {
+	return &Updater{
+		Timeout: DefaultTimeout,
+		notify:  make(chan struct{}),
+	}
+}
+
+func (u *Updater) record(e Event) {
+	e.Time = time.Now()
+	u.mu.Lock()
+	u.events = append(u.events, e)
+	close(u.notify)
+	u.notify = make(chan struct{})
+	u.mu.Unlock()
+}
+
+func (u *Updater) EDSUpdate(shard, hostname string, namespace string, entry []*model.IstioEndpoint) {
+	u.record(Event{Type: EDSUpdate, Shard: shard, Hostname: hostname, Namespace: namespace, Endpoints: entry})
+	if u.Delegate != nil {
+		u.Delegate.EDSUpdate(shard, hostname, namespace, entry)
+	}
+}
+
+func (u *Updater) EDSCacheUpdate(shard, hostname string, namespace string, entry []*model.IstioEndpoint) {
+	u.record(Event{Type: EDSCacheUpdate, Shard: shard, Hostname: hostname, Namespace: namespace, Endpoints: entry})
+	if u.Delegate != nil {
+		u.Delegate.EDSCacheUpdate(shard, hostname, namespace, entry)
+	}
+}
+
+func (u *Updater) SvcUpdate(shard, hostname string, namespace string, event model.Event) {
+	u.record(Event{Type: SvcUpdate, Shard: shard, Hostname: hostname, Namespace: namespace, SvcEvent: event})
+	if u.Delegate != nil {
+		u.Delegate.SvcUpdate(shard, hostname, namespace, event)
+	}
+}
+
+func (u *Updater) ConfigUpdate(req *model.PushRequest) {
+	u.record(Event{Type: ConfigUpdate, PushRequest: req})
+	if u.Delegate != nil {
+		u.Delegate.ConfigUpdate(req)
+	}
+}
+
+func (u *Updater) ProxyUpdate(clusterID, ip string) {
+	u.record(Event{Type: ProxyUpdate, ClusterID: clusterID, IP: ip})
+	if u.Delegate != nil {
+		u.Delegate.ProxyUpdate(clusterID, ip)
+	}
+}
+
+// Events returns a copy of all recorded events, in call order.
+func (u *Updater) Events() []Event {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]Event(nil), u.events...)
+}
+
+// Reset drops all recorded events.
+func (u *Updater) Reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.events = nil
+	u.next = 0
+}
+
+// Wait returns the first event matching m recorded after the last event returned by a wait, waiting up to
+// the timeout for it. Events skipped while searching are not returned by later waits.
+func (u *Updater) Wait(m Matcher, timeout time.Duration) (Event, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		u.mu.Lock()
+		for i := u.next; i < len(u.events); i++ {
+			if m(u.events[i]) {
+				u.next = i + 1
+				e := u.events[i]
+				u.mu.Unlock()
+				return e, nil
+			}
+		}
+		notify := u.notify
+		u.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return Event{}, fmt.Errorf("timed out after %v waiting for event, got %s", timeout, u.dump())
+		}
+	}
+}
+
+// WaitForEDSUpdate waits for the next EDS update of the hostname.
+func (u *Updater) WaitForEDSUpdate(hostname string) (Event, error) {
+	return u.Wait(EDSFor(hostname), u.Timeout)
+}
+
+// WaitForSvcUpdate waits for the next service update of the hostname.
+func (u *Updater) WaitForSvcUpdate(hostname string) (Event, error) {
+	return u.Wait(SvcFor(hostname), u.Timeout)
+}
+
+// WaitForConfigUpdate waits for the next config update including a config of the kind.
+func (u *Updater) WaitForConfigUpdate(kind config.GroupVersionKind) (Event, error) {
+	return u.Wait(ConfigFor(kind), u.Timeout)
+}
+
+// WaitOrFail is like Wait with the Updater timeout, failing the test on timeout.
+func (u *Updater) WaitOrFail(t test.Failer, m Matcher) Event {
+	t.Helper()
+	e, err := u.Wait(m, u.Timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// AssertOrder fails the test unless the recorded events include, in this order, one event matching each of
+// the matchers. Other events may be recorded in between.
+func (u *Updater) AssertOrder(t test.Failer, matchers ...Matcher) {
+	t.Helper()
+	events := u.Events()
+	i := 0
+	for _, e := range events {
+		if i < len(matchers) && matchers[i](e) {
+			i++
+		}
+	}
+	if i < len(matchers) {
+		t.Fatalf("matched %d of %d events in order, got %s", i, len(matchers), u.dump())
+	}
+}
+
+func (u *Updater) dump() string {
+	events := u.Events()
+	if len(events) == 0 {
+		return "no events"
+	}
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		out = append(out, e.String())
+	}
+	return "[" + strings.Join(out, ", ") + "]"
+}
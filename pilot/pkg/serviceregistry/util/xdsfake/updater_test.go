@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xdsfake
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestUpdaterRecords(t *testing.T) {
+	u := NewUpdater()
+	delegate := NewUpdater()
+	u.Delegate = delegate
+
+	eps := []*model.IstioEndpoint{{Address: "1.1.1.1"}}
+	u.EDSUpdate("shard", "a.example.com", "ns", eps)
+	u.SvcUpdate("shard", "a.example.com", "ns", model.EventAdd)
+	u.ConfigUpdate(&model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.Gateway, Name: "gw"}: {}}})
+	u.ProxyUpdate("cluster", "10.0.0.1")
+
+	events := u.Events()
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %v", events)
+	}
+	if e := events[0]; e.Type != EDSUpdate || e.Shard != "shard" || e.Hostname != "a.example.com" || e.Namespace != "ns" || len(e.Endpoints) != 1 {
+		t.Errorf("unexpected EDS event %+v", e)
+	}
+	if e := events[1]; e.Type != SvcUpdate || e.SvcEvent != model.EventAdd {
+		t.Errorf("unexpected service event %+v", e)
+	}
+	if e := events[3]; e.Type != ProxyUpdate || e.ClusterID != "cluster" || e.IP != "10.0.0.1" {
+		t.Errorf("unexpected proxy event %+v", e)
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Time.Before(events[i-1].Time) {
+			t.Errorf("event %d recorded before event %d", i, i-1)
+		}
+	}
+	if got := len(delegate.Events()); got != 4 {
+		t.Errorf("expected the delegate to get 4 calls, got %d", got)
+	}
+
+	u.Reset()
+	if got := u.Events(); len(got) != 0 {
+		t.Errorf("expected no events after reset, got %v", got)
+	}
+}
+
+func TestUpdaterWait(t *testing.T) {
+	u := NewUpdater()
+	u.Timeout = 100 * time.Millisecond
+
+	go func() {
+		u.EDSUpdate("", "a.example.com", "ns", nil)
+		u.ConfigUpdate(&model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.ServiceEntry}: {}}})
+		u.EDSUpdate("", "b.example.com", "ns", nil)
+	}()
+
+	if _, err := u.WaitForEDSUpdate("b.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	// Events recorded before the one returned by the previous wait are consumed.
+	if _, err := u.WaitForConfigUpdate(gvk.ServiceEntry); err == nil {
+		t.Fatal("expected the config update to be consumed")
+	}
+	if _, err := u.WaitForEDSUpdate("a.example.com"); err == nil {
+		t.Fatal("expected the EDS update of a.example.com to be consumed")
+	}
+	u.AssertOrder(t, EDSFor("a.example.com"), ConfigFor(gvk.ServiceEntry), EDSFor("b.example.com"))
+}
+
+func TestUpdaterConcurrent(t *testing.T) {
+	u := NewUpdater()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				u.ProxyUpdate("cluster", "10.0.0.1")
+			}
+		}()
+	}
+	for i := 0; i < 1000; i++ {
+		u.WaitOrFail(t, OfType(ProxyUpdate))
+	}
+	wg.Wait()
+	if got := len(u.Events()); got != 1000 {
+		t.Fatalf("expected 1000 events, got %d", got)
+	}
+}
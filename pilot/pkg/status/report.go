@@ -15,6 +15,9 @@
 package status
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"strings"
 
 	"gopkg.in/yaml.v2"
@@ -28,6 +31,9 @@ type DistributionReport struct {
 	Reporter            string         `json:"reporter"`
 	DataPlaneCount      int            `json:"dataPlaneCount"`
 	InProgressResources map[string]int `json:"inProgressResources"`
+	// Heartbeat is the time the report was written. The status leader only counts a report with a new heartbeat
+	// as hearing from the reporter, as the informer also redelivers the unchanged reports of dead reporters.
+	Heartbeat string `json:"heartbeat,omitempty"`
 }
 
 func ReportFromYaml(content []byte) (DistributionReport, error) {
@@ -66,6 +72,11 @@ func (r Resource) String() string {
 	return strings.Join([]string{r.Group, r.Version, r.Resource, r.Namespace, r.Name, r.ResourceVersion}, "/")
 }
 
+// IsContentHashed returns true if the version of the resource is a hash of its spec rather than a resourceVersion.
+func (r Resource) IsContentHashed() bool {
+	return strings.HasPrefix(r.ResourceVersion, contentHashPrefix)
+}
+
 func (r *Resource) ToModelKey() string {
 	// we have a resource here, but model keys use kind.  Use the schema to find the correct kind.
 	found, _ := collections.All.FindByPlural(r.Group, r.Version, r.Resource)
@@ -77,12 +88,62 @@ func ResourceFromModelConfig(c config.Config) *Resource {
 	if gvr == nil {
 		return nil
 	}
+	version := c.ResourceVersion
+	if version == "" {
+		// some config sources, such as files and MCP, do not populate resourceVersion. Track those by content instead.
+		var err error
+		if version, err = ContentHash(c.Spec); err != nil {
+			scope.Errorf("Unable to hash the spec of %s: %v", c.Key(), err)
+			return nil
+		}
+	}
 	return &Resource{
 		GroupVersionResource: *gvr,
 		Namespace:            c.Namespace,
 		Name:                 c.Name,
-		ResourceVersion:      c.ResourceVersion,
+		ResourceVersion:      version,
+	}
+}
+
+// contentHashPrefix marks a Resource version computed by ContentHash.
+const contentHashPrefix = "hash:"
+
+// ContentHash returns a version for a config spec derived from its content. The spec is normalized to a map
+// first, so that the hash is stable. The specs read from kubernetes as unstructured must be converted with
+// typedSpec first, as the typed specs omit the default values.
+func ContentHash(spec config.Spec) (string, error) {
+	m, err := config.ToMap(spec)
+	if err != nil {
+		return "", err
+	}
+	// map keys are sorted when marshaled, which makes the result stable.
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return contentHashPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// typedSpec converts the unstructured spec of a resource to the spec type of its schema, so that it is serialized
+// by ContentHash as the spec of the config store is. The spec is returned unchanged if the schema is unknown.
+func typedSpec(gvr schema.GroupVersionResource, spec interface{}) (config.Spec, error) {
+	found, ok := collections.All.FindByPlural(gvr.Group, gvr.Version, gvr.Resource)
+	if !ok {
+		return spec, nil
+	}
+	out, err := found.Resource().NewInstance()
+	if err != nil {
+		return nil, err
+	}
+	js, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ApplyJSON(out, string(js)); err != nil {
+		return nil, err
 	}
+	return out, nil
 }
 
 func GVKtoGVR(in config.GroupVersionKind) *schema.GroupVersionResource {
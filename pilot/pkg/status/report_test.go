@@ -20,6 +20,10 @@ import (
 
 	"github.com/onsi/gomega"
 	"gopkg.in/yaml.v2"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
 )
 
 func TestReportSerialization(t *testing.T) {
@@ -43,3 +47,37 @@ func TestReportSerialization(t *testing.T) {
 		t.Errorf("Report Serialization mutated the Report. got = %v, want %v", out, in)
 	}
 }
+
+func TestResourceFromModelConfigContentHash(t *testing.T) {
+	gomega.RegisterTestingT(t)
+	cfg := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind(),
+			Namespace:        "default",
+			Name:             "vs",
+		},
+		Spec: &networking.VirtualService{Hosts: []string{"foo.example.com"}, Gateways: []string{"gw"}},
+	}
+	res := ResourceFromModelConfig(cfg)
+	gomega.Expect(res).NotTo(gomega.BeNil())
+	gomega.Expect(res.IsContentHashed()).To(gomega.BeTrue())
+	// the version must survive the round trip through the distribution report.
+	gomega.Expect(*ResourceFromString(res.String())).To(gomega.Equal(*res))
+
+	// the same spec read from kubernetes must hash identically, so the leader can match it to the object.
+	unstructuredSpec := map[string]interface{}{
+		"gateways": []interface{}{"gw"},
+		"hosts":    []interface{}{"foo.example.com"},
+	}
+	hash, err := ContentHash(unstructuredSpec)
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	gomega.Expect(hash).To(gomega.Equal(res.ResourceVersion))
+
+	cfg.Spec = &networking.VirtualService{Hosts: []string{"bar.example.com"}}
+	gomega.Expect(ResourceFromModelConfig(cfg).ResourceVersion).NotTo(gomega.Equal(res.ResourceVersion))
+
+	cfg.ResourceVersion = "7"
+	res = ResourceFromModelConfig(cfg)
+	gomega.Expect(res.IsContentHashed()).To(gomega.BeFalse())
+	gomega.Expect(res.ResourceVersion).To(gomega.Equal("7"))
+}
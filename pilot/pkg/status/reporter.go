@@ -16,10 +16,12 @@ package status
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -47,6 +49,19 @@ type inProgressEntry struct {
 	Resource
 	// the number of reports we have written with this resource at 100%
 	completedIterations int
+	// for content hashed resources, the version the config store recorded in its ledger for this content,
+	// which is what we must find at the versions acked by dataplanes.
+	storeVersion string
+	// false until storeVersion has been read from the ledger, which may not be available when the resource is added.
+	storeVersionResolved bool
+}
+
+// expectedVersion returns the version the ledger holds for the resource once it has been distributed.
+func (e *inProgressEntry) expectedVersion() (string, bool) {
+	if !e.IsContentHashed() {
+		return e.ResourceVersion, true
+	}
+	return e.storeVersion, e.storeVersionResolved
 }
 
 type Reporter struct {
@@ -65,6 +80,7 @@ type Reporter struct {
 	clock                  clock.Clock
 	store                  model.ConfigStore
 	distributionEventQueue chan distributionEvent
+	ledgerLimiter          *rate.Limiter
 }
 
 var _ xds.DistributionStatusCache = &Reporter{}
@@ -88,6 +104,8 @@ func (r *Reporter) Start(clientSet kubernetes.Interface, namespace string, store
 	r.status = make(map[string]string)
 	r.reverseStatus = make(map[string]map[string]struct{})
 	r.inProgressResources = make(map[string]*inProgressEntry)
+	// the ledger may be unavailable for a long time, don't log on every report.
+	r.ledgerLimiter = rate.NewLimiter(rate.Every(time.Minute), 1)
 	go r.readFromEventQueue()
 	if !writeMode {
 		return
@@ -124,9 +142,12 @@ func (r *Reporter) Start(clientSet kubernetes.Interface, namespace string, store
 
 // build a distribution report to send to status leader
 func (r *Reporter) buildReport() (DistributionReport, []Resource) {
+	r.resolveStoreVersions()
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	var finishedResources []Resource
+	var ledgerErrors int
+	var lastLedgerErr error
 	out := DistributionReport{
 		Reporter:            r.PodName,
 		DataPlaneCount:      len(r.status),
@@ -136,20 +157,26 @@ func (r *Reporter) buildReport() (DistributionReport, []Resource) {
 	for _, ipr := range r.inProgressResources {
 		res := ipr.Resource
 		key := res.String()
+		expected, ok := ipr.expectedVersion()
+		if !ok {
+			// we don't yet know which version of the ledger contains this content, try again on the next report.
+			continue
+		}
 		// for every version (nonce) of the config currently in play
 		for nonce, dataplanes := range r.reverseStatus {
 
 			// check to see if this version of the config contains this version of the resource
 			// it might be more optimal to provide for a full dump of the config at a certain version?
 			dpVersion, err := r.store.GetResourceAtVersion(nonce, res.ToModelKey())
-			if err == nil && dpVersion == res.ResourceVersion {
+			if err == nil && dpVersion == expected {
 				if _, ok := out.InProgressResources[key]; !ok {
 					out.InProgressResources[key] = len(dataplanes)
 				} else {
 					out.InProgressResources[key] += len(dataplanes)
 				}
 			} else if err != nil {
-				scope.Errorf("Encountered error retrieving version %s of key %s from Store: %v", nonce, key, err)
+				ledgerErrors++
+				lastLedgerErr = err
 				continue
 			} else if nonce == r.store.Version() {
 				scope.Warnf("Cache appears to be missing latest version of %s", key)
@@ -163,9 +190,47 @@ func (r *Reporter) buildReport() (DistributionReport, []Resource) {
 			}
 		}
 	}
+	if ledgerErrors > 0 {
+		r.ledgerWarnf("Unable to retrieve %d resource versions from the config store ledger, "+
+			"distribution will be reported once it is available: %v", ledgerErrors, lastLedgerErr)
+	}
 	return out, finishedResources
 }
 
+// resolveStoreVersions reads the ledger version of content hashed resources which were added while the
+// ledger was unavailable.
+func (r *Reporter) resolveStoreVersions() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, ipr := range r.inProgressResources {
+		if !ipr.IsContentHashed() || ipr.storeVersionResolved {
+			continue
+		}
+		ipr.storeVersion, ipr.storeVersionResolved = r.lookupStoreVersion(key)
+	}
+}
+
+// lookupStoreVersion returns the version of the config the store currently records in its ledger.
+// Resources from sources which don't populate resourceVersion are tracked by hash, but the store still
+// records its own version in the ledger, so dataplane acks must be matched against that instead.
+func (r *Reporter) lookupStoreVersion(key string) (string, bool) {
+	if r.store == nil {
+		return "", false
+	}
+	version, err := r.store.GetResourceAtVersion(r.store.Version(), key)
+	if err != nil {
+		scope.Debugf("Unable to retrieve the current version of %s from the config store ledger: %v", key, err)
+		return "", false
+	}
+	return version, true
+}
+
+func (r *Reporter) ledgerWarnf(template string, args ...interface{}) {
+	if r.ledgerLimiter == nil || r.ledgerLimiter.Allow() {
+		scope.Warnf(fmt.Sprintf(template, args...))
+	}
+}
+
 // For efficiency, we don't want to be checking on resources that have already reached 100% distribution.
 // When this happens, we remove them from our watch list.
 func (r *Reporter) removeCompletedResource(completedResources []Resource) {
@@ -195,12 +260,18 @@ func (r *Reporter) AddInProgressResource(res config.Config) {
 		scope.Errorf("Unable to locate schema for %v, will not update status.", res)
 		return
 	}
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.inProgressResources[myRes.ToModelKey()] = &inProgressEntry{
+	key := myRes.ToModelKey()
+	entry := &inProgressEntry{
 		Resource:            *myRes,
 		completedIterations: 0,
 	}
+	if myRes.IsContentHashed() {
+		// the store has already recorded this change, so the current ledger version is the one to look for.
+		entry.storeVersion, entry.storeVersionResolved = r.lookupStoreVersion(key)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inProgressResources[key] = entry
 }
 
 func (r *Reporter) DeleteInProgressResource(res config.Config) {
@@ -212,6 +283,7 @@ func (r *Reporter) DeleteInProgressResource(res config.Config) {
 // generate a distribution report and write it to a ConfigMap for the leader to read.
 func (r *Reporter) writeReport(ctx context.Context) {
 	report, finishedResources := r.buildReport()
+	report.Heartbeat = r.clock.Now().UTC().Format(time.RFC3339Nano)
 	go r.removeCompletedResource(finishedResources)
 	//write to kubernetes here.
 	reportbytes, err := yaml.Marshal(report)
@@ -281,12 +353,8 @@ func (r *Reporter) processEvent(conID string, distributionType xds.EventType, no
 	r.dirty = true
 	key := conID + distributionType // TODO: delimit?
 	r.deleteKeyFromReverseMap(key)
-	var version string
-	if len(nonce) > 12 {
-		version = nonce[:xds.VersionLen]
-	} else {
-		version = nonce
-	}
+	// the config version prefixing the nonce, which is also what is registered for acks, see xds.AckedConfigVersion.
+	version := xds.AckedConfigVersion("", nonce)
 	// touch
 	r.status[key] = version
 	if _, ok := r.reverseStatus[version]; !ok {
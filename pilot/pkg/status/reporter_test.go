@@ -21,7 +21,9 @@ import (
 	. "github.com/onsi/gomega"
 	"k8s.io/utils/clock"
 
+	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -47,6 +49,20 @@ func TestStatusMaps(t *testing.T) {
 	Expect(r.reverseStatus).To(Equal(map[string]map[string]struct{}{"a": {"conB": x}, "c": {"conC": x}, "d": {"conD": x}}))
 }
 
+// TestStatusMapsAcrossVersionFormats checks proxies acking with the legacy version info, during upgrades, and with
+// the config version are counted at the same config version.
+func TestStatusMapsAcrossVersionFormats(t *testing.T) {
+	RegisterTestingT(t)
+	r := initReporterWithoutStarting()
+	typ := ""
+	configVersion := "AAAAAAAAAAAA"
+	r.processEvent("conA", typ, xds.AckedConfigVersion("2020-10-01T10:00:00Z/3", configVersion+"a-uuid"))
+	r.processEvent("conB", typ, xds.AckedConfigVersion(configVersion, configVersion+"another-uuid"))
+	r.processEvent("conC", typ, configVersion+"pushed-uuid")
+	x := struct{}{}
+	Expect(r.reverseStatus).To(Equal(map[string]map[string]struct{}{configVersion: {"conA": x, "conB": x, "conC": x}}))
+}
+
 func initReporterWithoutStarting() (out Reporter) {
 	out.PodName = "tespod"
 	out.inProgressResources = map[string]*inProgressEntry{}
@@ -134,3 +150,96 @@ func TestBuildReport(t *testing.T) {
 	}))
 	Expect(r.inProgressResources).NotTo(ContainElement(resources[0]))
 }
+
+func TestBuildReportContentHashed(t *testing.T) {
+	RegisterTestingT(t)
+	r := initReporterWithoutStarting()
+	store := memory.MakeWithLedger(collections.All, ledger.Make(time.Minute), false)
+	r.store = store
+	// wire the reporter to config events the way pilot does.
+	controller := memory.NewSyncController(store)
+	gvk := collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind()
+	controller.RegisterEventHandler(gvk, func(_, curr config.Config, event model.Event) {
+		if event != model.EventDelete {
+			r.AddInProgressResource(curr)
+		} else {
+			r.DeleteInProgressResource(curr)
+		}
+	})
+	initialVersion := store.Version()
+
+	// file based config sources create configs without a resourceVersion.
+	vs := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk,
+			Namespace:        "default",
+			Name:             "from-file",
+		},
+		Spec: virtualServiceSpec("foo.example.com"),
+	}
+	_, err := controller.Create(vs)
+	Expect(err).NotTo(HaveOccurred())
+	res := *ResourceFromModelConfig(vs)
+	Expect(res.IsContentHashed()).To(BeTrue())
+
+	r.processEvent("conA", "", store.Version())
+	r.processEvent("conB", "", store.Version())
+	r.processEvent("conC", "", initialVersion)
+	rpt, _ := r.buildReport()
+	Expect(rpt.DataPlaneCount).To(Equal(3))
+	Expect(rpt.InProgressResources).To(Equal(map[string]int{res.String(): 2}))
+
+	// an update changes the hash, and dataplanes must ack the new version before it is counted.
+	vs.Spec = virtualServiceSpec("bar.example.com")
+	_, err = controller.Update(vs)
+	Expect(err).NotTo(HaveOccurred())
+	updated := *ResourceFromModelConfig(vs)
+	Expect(updated.ResourceVersion).NotTo(Equal(res.ResourceVersion))
+	rpt, _ = r.buildReport()
+	Expect(rpt.InProgressResources).To(BeEmpty())
+	r.processEvent("conC", "", store.Version())
+	rpt, _ = r.buildReport()
+	Expect(rpt.InProgressResources).To(Equal(map[string]int{updated.String(): 1}))
+}
+
+func TestBuildReportLedgerUnavailable(t *testing.T) {
+	RegisterTestingT(t)
+	r := initReporterWithoutStarting()
+	store := memory.MakeWithLedger(collections.All, &model.DisabledLedger{}, false)
+	r.store = store
+	vs := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind(),
+			Namespace:        "default",
+			Name:             "from-file",
+		},
+		Spec: virtualServiceSpec("foo.example.com"),
+	}
+	_, err := store.Create(vs)
+	Expect(err).NotTo(HaveOccurred())
+	r.AddInProgressResource(vs)
+	res := *ResourceFromModelConfig(vs)
+	r.processEvent("conA", "", "unknown")
+	rpt, prunes := r.buildReport()
+	r.removeCompletedResource(prunes)
+	Expect(rpt.InProgressResources).To(BeEmpty())
+	// the resource must still be tracked, so it is reported once the ledger is available.
+	Expect(r.inProgressResources).To(HaveKey(res.ToModelKey()))
+
+	l := ledger.Make(time.Minute)
+	_, err = l.Put(res.ToModelKey(), "1")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(store.SetLedger(l)).To(Succeed())
+	r.processEvent("conA", "", l.RootHash())
+	rpt, _ = r.buildReport()
+	Expect(rpt.InProgressResources).To(Equal(map[string]int{res.String(): 1}))
+}
+
+func virtualServiceSpec(host string) *networking.VirtualService {
+	return &networking.VirtualService{
+		Hosts: []string{host},
+		Http: []*networking.HTTPRoute{{
+			Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: host}}},
+		}},
+	}
+}
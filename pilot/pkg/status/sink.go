@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sinkTimeout bounds a single write to a DistributionSink, so that a slow consumer can only delay the sink.
+const sinkTimeout = 5 * time.Second
+
+// DistributionEvent is the distribution progress of a single resource, as computed by one writeAllStatus cycle.
+type DistributionEvent struct {
+	// Resource is the group/version/resource/namespace/name of the config.
+	Resource        string    `json:"resource"`
+	ResourceVersion string    `json:"resourceVersion"`
+	Acked           int       `json:"acked"`
+	Total           int       `json:"total"`
+	StaleReporters  []string  `json:"staleReporters,omitempty"`
+	Time            time.Time `json:"time"`
+}
+
+func newDistributionEvent(r Resource, p Progress, staleReporters []string, now time.Time) DistributionEvent {
+	return DistributionEvent{
+		Resource:        strings.Join([]string{r.Group, r.Version, r.Resource, r.Namespace, r.Name}, "/"),
+		ResourceVersion: r.ResourceVersion,
+		Acked:           p.AckedInstances,
+		Total:           p.TotalInstances,
+		StaleReporters:  staleReporters,
+		Time:            now,
+	}
+}
+
+// DistributionSink receives the distribution progress of all resources once per UpdateInterval.
+type DistributionSink interface {
+	// Emit writes the events of one interval. It must be safe to call from a single goroutine at a time.
+	Emit(events []DistributionEvent) error
+}
+
+// NewSink returns the DistributionSink for target, which is either unix:///path/to/socket or an http(s) URL.
+func NewSink(target string) (DistributionSink, error) {
+	switch {
+	case strings.HasPrefix(target, "unix://"):
+		return &unixSink{path: strings.TrimPrefix(target, "unix://")}, nil
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		return &httpSink{url: target, client: &http.Client{Timeout: sinkTimeout}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported distribution sink %q, must be a unix:// or http(s):// address", target)
+	}
+}
+
+// encodeEvents encodes the events as JSON Lines.
+func encodeEvents(events []DistributionEvent) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// unixSink streams events to a Unix socket, reconnecting after a failed write.
+type unixSink struct {
+	path string
+	conn net.Conn
+}
+
+func (s *unixSink) Emit(events []DistributionEvent) error {
+	b, err := encodeEvents(events)
+	if err != nil {
+		return err
+	}
+	if s.conn == nil {
+		if s.conn, err = net.DialTimeout("unix", s.path, sinkTimeout); err != nil {
+			s.conn = nil
+			return err
+		}
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+	if _, err := s.conn.Write(b); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// httpSink pushes the events of each interval to a URL as a single request.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSink) Emit(events []DistributionEvent) error {
+	b, err := encodeEvents(events)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/x-ndjson", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("distribution sink %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// MemorySink keeps the emitted events in memory.
+type MemorySink struct {
+	mu        sync.Mutex
+	intervals [][]DistributionEvent
+}
+
+var _ DistributionSink = &MemorySink{}
+
+func (s *MemorySink) Emit(events []DistributionEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.intervals = append(s.intervals, events)
+	return nil
+}
+
+// Intervals returns the events emitted so far, one slice per interval.
+func (s *MemorySink) Intervals() [][]DistributionEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]DistributionEvent{}, s.intervals...)
+}
@@ -18,18 +18,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/types"
+	"golang.org/x/time/rate"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/utils/clock"
@@ -37,11 +41,22 @@ import (
 	"istio.io/api/meta/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
 )
 
 var scope = log.RegisterScope("status",
 	"CRD distribution status debugging", 0)
 
+var contentHashedResources = monitoring.NewGauge(
+	"pilot_status_content_hashed_resources",
+	"Resources whose distribution is tracked by a hash of their spec, because their config source does not "+
+		"populate resourceVersion.",
+)
+
+func init() {
+	monitoring.MustRegister(contentHashedResources)
+}
+
 type Progress struct {
 	AckedInstances int
 	TotalInstances int
@@ -53,9 +68,11 @@ func (p *Progress) PlusEquals(p2 Progress) {
 }
 
 type DistributionController struct {
-	mu               sync.RWMutex
-	CurrentState     map[Resource]map[string]Progress
-	ObservationTime  map[string]time.Time
+	mu              sync.RWMutex
+	CurrentState    map[Resource]map[string]Progress
+	ObservationTime map[string]time.Time
+	// heartbeats are the heartbeats of the last reports of the reporters.
+	heartbeats       map[string]string
 	UpdateInterval   time.Duration
 	dynamicClient    dynamic.Interface
 	clock            clock.Clock
@@ -63,6 +80,15 @@ type DistributionController struct {
 	currentlyWriting ResourceLock
 	StaleInterval    time.Duration
 	cmInformer       cache.SharedIndexInformer
+	cmClient         corev1.ConfigMapInterface
+	// StaleReportGCMultiplier is the multiple of StaleInterval after which the distribution report
+	// ConfigMap of a reporter which has not been heard from is deleted. Zero disables cleanup.
+	StaleReportGCMultiplier int
+	gcLimiter               *rate.Limiter
+	// Sink, if set, receives the distribution progress of every resource once per UpdateInterval.
+	Sink        DistributionSink
+	sinkEvents  chan []DistributionEvent
+	sinkLimiter *rate.Limiter
 }
 
 func NewController(restConfig rest.Config, namespace string) *DistributionController {
@@ -74,6 +100,17 @@ func NewController(restConfig rest.Config, namespace string) *DistributionContro
 		StaleInterval:   time.Minute,
 		clock:           clock.RealClock{},
 	}
+	c.StaleReportGCMultiplier = features.StatusGCStaleMultiplier
+	// deletes are rare, but a large number of istiod pods may churn at once; don't hammer the api server.
+	c.gcLimiter = rate.NewLimiter(rate.Every(time.Second), 5)
+	if features.StatusSink != "" {
+		sink, err := NewSink(features.StatusSink)
+		if err != nil {
+			scope.Errorf("Distribution progress will not be streamed: %v", err)
+		} else {
+			c.Sink = sink
+		}
+	}
 
 	// client-go defaults to 5 QPS, with 10 Boost, which is insufficient for updating status on all the config
 	// in the mesh.  These values can be configured using environment variables for tuning (see pilot/pkg/features)
@@ -84,8 +121,11 @@ func NewController(restConfig rest.Config, namespace string) *DistributionContro
 		scope.Fatalf("Could not connect to kubernetes: %s", err)
 	}
 
+	kubeClient := kubernetes.NewForConfigOrDie(&restConfig)
+	c.cmClient = kubeClient.CoreV1().ConfigMaps(namespace)
+
 	// configmap informer
-	i := informers.NewSharedInformerFactoryWithOptions(kubernetes.NewForConfigOrDie(&restConfig), 1*time.Minute,
+	i := informers.NewSharedInformerFactoryWithOptions(kubeClient, 1*time.Minute,
 		informers.WithNamespace(namespace),
 		informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
 			listOptions.LabelSelector = labels.Set(map[string]string{labelKey: "true"}).AsSelector().String()
@@ -104,9 +144,37 @@ func (c *DistributionController) Start(stop <-chan struct{}) {
 	ctx := NewIstioContext(stop)
 	go c.cmInformer.Run(ctx.Done())
 
-	//  create Status Writer
-	t := c.clock.Tick(c.UpdateInterval)
+	c.startStatusWriter(ctx)
+
+	// Start is only called once we hold the status leader lock, so only a single istiod will be
+	// collecting abandoned distribution reports at any time.
+	if c.StaleReportGCMultiplier > 0 {
+		go func() {
+			gc := c.clock.Tick(c.StaleInterval)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-gc:
+					c.cleanupStaleReports(ctx)
+				}
+			}
+		}()
+	}
+}
 
+// startStatusWriter writes the status of all resources, and emits them to the Sink if set, every UpdateInterval.
+func (c *DistributionController) startStatusWriter(ctx context.Context) {
+	if c.Sink != nil {
+		// a single pending interval is buffered, a slow sink drops intervals rather than delaying status writes.
+		c.sinkEvents = make(chan []DistributionEvent, 1)
+		if c.sinkLimiter == nil {
+			c.sinkLimiter = rate.NewLimiter(rate.Every(time.Minute), 1)
+		}
+		go c.runSink(ctx)
+	}
+
+	t := c.clock.Tick(c.UpdateInterval)
 	go func() {
 		for {
 			select {
@@ -122,6 +190,33 @@ func (c *DistributionController) Start(stop <-chan struct{}) {
 	}()
 }
 
+func (c *DistributionController) runSink(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case events := <-c.sinkEvents:
+			if err := c.Sink.Emit(events); err != nil {
+				c.sinkWarnf("Failed to write distribution progress to sink: %v", err)
+			}
+		}
+	}
+}
+
+func (c *DistributionController) emit(events []DistributionEvent) {
+	select {
+	case c.sinkEvents <- events:
+	default:
+		c.sinkWarnf("Distribution sink is falling behind, dropping progress of %d resources", len(events))
+	}
+}
+
+func (c *DistributionController) sinkWarnf(template string, args ...interface{}) {
+	if c.sinkLimiter.Allow() {
+		scope.Warnf(fmt.Sprintf(template, args...))
+	}
+}
+
 func (c *DistributionController) handleReport(d DistributionReport) {
 	defer c.mu.Unlock()
 	c.mu.Lock()
@@ -132,27 +227,71 @@ func (c *DistributionController) handleReport(d DistributionReport) {
 		}
 		c.CurrentState[res][d.Reporter] = Progress{d.InProgressResources[resstr], d.DataPlaneCount}
 	}
-	c.ObservationTime[d.Reporter] = c.clock.Now()
+	// The informer redelivers the unchanged report of a reporter on resyncs, which must not count as hearing from it.
+	// Reports without heartbeat, from older reporters, always do.
+	if d.Heartbeat == "" || d.Heartbeat != c.heartbeats[d.Reporter] {
+		c.ObservationTime[d.Reporter] = c.clock.Now()
+		if c.heartbeats == nil {
+			c.heartbeats = map[string]string{}
+		}
+		c.heartbeats[d.Reporter] = d.Heartbeat
+	}
+}
+
+// ContentHashedResources returns the number of resources being tracked by a hash of their spec, because
+// their config source does not populate resourceVersion.
+func (c *DistributionController) ContentHashedResources() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.contentHashedResourcesLocked()
+}
+
+func (c *DistributionController) contentHashedResourcesLocked() int {
+	count := 0
+	for res := range c.CurrentState {
+		if res.IsContentHashed() {
+			count++
+		}
+	}
+	return count
 }
 
 func (c *DistributionController) writeAllStatus(ctx context.Context) (staleReporters []string) {
 	defer c.mu.RUnlock()
 	c.mu.RLock()
+	var events []DistributionEvent
+	now := c.clock.Now()
+	contentHashedResources.Record(float64(c.contentHashedResourcesLocked()))
 	for config, fractions := range c.CurrentState {
 		var distributionState Progress
+		var resourceStaleReporters []string
 		for reporter, w := range fractions {
 			// check for stale data here
 			if c.clock.Since(c.ObservationTime[reporter]) > c.StaleInterval {
 				scope.Warnf("Status reporter %s has not been heard from since %v, deleting report.",
 					reporter, c.ObservationTime[reporter])
-				staleReporters = append(staleReporters, reporter)
+				resourceStaleReporters = append(resourceStaleReporters, reporter)
 			} else {
 				distributionState.PlusEquals(w)
 			}
 		}
+		staleReporters = append(staleReporters, resourceStaleReporters...)
 		if distributionState.TotalInstances > 0 { // this is necessary when all reports are stale.
 			go c.writeStatus(ctx, config, distributionState)
 		}
+		if c.sinkEvents != nil {
+			sort.Strings(resourceStaleReporters)
+			events = append(events, newDistributionEvent(config, distributionState, resourceStaleReporters, now))
+		}
+	}
+	if c.sinkEvents != nil {
+		sort.Slice(events, func(i, j int) bool {
+			if events[i].Resource != events[j].Resource {
+				return events[i].Resource < events[j].Resource
+			}
+			return events[i].ResourceVersion < events[j].ResourceVersion
+		})
+		c.emit(events)
 	}
 	return
 }
@@ -174,8 +313,13 @@ func (c *DistributionController) writeStatus(ctx context.Context, config Resourc
 	defer c.currentlyWriting.Unlock(config)
 	resourceInterface := c.initK8sResource(config.GroupVersionResource).
 		Namespace(config.Namespace)
+	getOptions := metav1.GetOptions{ResourceVersion: config.ResourceVersion}
+	if config.IsContentHashed() {
+		// there is no resourceVersion to ask for, read the latest and compare content instead.
+		getOptions = metav1.GetOptions{}
+	}
 	// should this be moved to some sort of InformerCache for speed?
-	current, err := resourceInterface.Get(ctx, config.Name, metav1.GetOptions{ResourceVersion: config.ResourceVersion})
+	current, err := resourceInterface.Get(ctx, config.Name, getOptions)
 	if err != nil {
 		if errors.IsGone(err) || errors.IsNotFound(err) {
 			// this resource has been deleted.  prune its state and move on.
@@ -186,7 +330,7 @@ func (c *DistributionController) writeStatus(ctx context.Context, config Resourc
 		return
 
 	}
-	if config.ResourceVersion != current.GetResourceVersion() {
+	if !isCurrentVersion(config, current) {
 		// this distribution report is for an old version of the object.  Prune and continue.
 		c.pruneOldVersion(config)
 		return
@@ -204,6 +348,24 @@ func (c *DistributionController) writeStatus(ctx context.Context, config Resourc
 	}
 }
 
+// isCurrentVersion returns true if the distribution report for config describes the current object.
+func isCurrentVersion(config Resource, current *unstructured.Unstructured) bool {
+	if !config.IsContentHashed() {
+		return config.ResourceVersion == current.GetResourceVersion()
+	}
+	spec, err := typedSpec(config.GroupVersionResource, current.Object["spec"])
+	if err != nil {
+		scope.Warnf("Unable to read the spec of %s/%s: %v", config.Namespace, config.Name, err)
+		return false
+	}
+	hash, err := ContentHash(spec)
+	if err != nil {
+		scope.Warnf("Unable to hash the spec of %s/%s: %v", config.Namespace, config.Name, err)
+		return false
+	}
+	return hash == config.ResourceVersion
+}
+
 func (c *DistributionController) pruneOldVersion(config Resource) {
 	defer c.mu.Unlock()
 	c.mu.Lock()
@@ -221,6 +383,57 @@ func (c *DistributionController) removeStaleReporters(staleReporters []string) {
 	}
 }
 
+// cleanupStaleReports deletes the distribution report ConfigMaps of reporters which have not been observed
+// within StaleReportGCMultiplier * StaleInterval. removeStaleReporters only drops in memory state, so without
+// this the ConfigMaps of terminated istiod pods accumulate forever.
+func (c *DistributionController) cleanupStaleReports(ctx context.Context) {
+	if c.cmClient == nil || c.StaleReportGCMultiplier <= 0 {
+		return
+	}
+	cms, err := c.cmClient.List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set(map[string]string{labelKey: "true"}).AsSelector().String(),
+	})
+	if err != nil {
+		scope.Errorf("Failed to list distribution reports for cleanup: %v", err)
+		return
+	}
+	threshold := c.StaleInterval * time.Duration(c.StaleReportGCMultiplier)
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		// never touch a configmap we did not create, regardless of what the api server returned.
+		if cm.Labels[labelKey] != "true" {
+			continue
+		}
+		dr, err := ReportFromYaml([]byte(cm.Data[dataField]))
+		if err != nil || dr.Reporter == "" {
+			scope.Debugf("skipping cleanup of unparseable distribution report %s: %v", cm.Name, err)
+			continue
+		}
+		c.mu.RLock()
+		lastSeen, ok := c.ObservationTime[dr.Reporter]
+		c.mu.RUnlock()
+		// reporters we have never observed may simply not have been delivered by the informer yet.
+		if !ok || c.clock.Since(lastSeen) <= threshold {
+			continue
+		}
+		if c.gcLimiter != nil {
+			if err := c.gcLimiter.Wait(ctx); err != nil {
+				return
+			}
+		}
+		scope.Infof("Deleting distribution report %s, reporter %s has not been heard from since %v",
+			cm.Name, dr.Reporter, lastSeen)
+		if err := c.cmClient.Delete(ctx, cm.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			scope.Warnf("Failed to delete stale distribution report %s: %v", cm.Name, err)
+			continue
+		}
+		c.mu.Lock()
+		delete(c.ObservationTime, dr.Reporter)
+		delete(c.heartbeats, dr.Reporter)
+		c.mu.Unlock()
+	}
+}
+
 func GetTypedStatus(in interface{}) (out v1alpha1.IstioStatus, err error) {
 	var statusBytes []byte
 	if statusBytes, err = json.Marshal(in); err == nil {
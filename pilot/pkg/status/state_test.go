@@ -15,13 +15,29 @@
 package status
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/utils/clock"
 
 	"istio.io/api/meta/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
 )
 
 var statusStillPropagating = v1alpha1.IstioStatus{
@@ -194,3 +210,270 @@ func Test_getTypedStatus(t *testing.T) {
 		})
 	}
 }
+
+func reportConfigMap(t *testing.T, name, reporter string, labeled bool) *corev1.ConfigMap {
+	t.Helper()
+	b, err := yaml.Marshal(DistributionReport{Reporter: reporter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system"},
+		Data:       map[string]string{dataField: string(b)},
+	}
+	if labeled {
+		cm.Labels = map[string]string{labelKey: "true"}
+	}
+	return cm
+}
+
+func TestCleanupStaleReports(t *testing.T) {
+	now := time.Now()
+	client := fake.NewSimpleClientset(
+		reportConfigMap(t, "fresh-distribution", "fresh", true),
+		reportConfigMap(t, "stale-distribution", "stale", true),
+		reportConfigMap(t, "unknown-distribution", "unknown", true),
+		reportConfigMap(t, "unlabeled-distribution", "unlabeled", false),
+	)
+	c := &DistributionController{
+		ObservationTime: map[string]time.Time{
+			"fresh":     now.Add(-time.Minute),
+			"stale":     now.Add(-time.Hour),
+			"unlabeled": now.Add(-time.Hour),
+		},
+		StaleInterval:           time.Minute,
+		StaleReportGCMultiplier: 5,
+		clock:                   clock.RealClock{},
+		cmClient:                client.CoreV1().ConfigMaps("istio-system"),
+		gcLimiter:               rate.NewLimiter(rate.Inf, 1),
+	}
+	c.cleanupStaleReports(context.Background())
+
+	cms, err := client.CoreV1().ConfigMaps("istio-system").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]struct{}{}
+	for _, cm := range cms.Items {
+		got[cm.Name] = struct{}{}
+	}
+	want := map[string]struct{}{
+		"fresh-distribution":     {},
+		"unknown-distribution":   {},
+		"unlabeled-distribution": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cleanupStaleReports() left %v, want %v", got, want)
+	}
+	if _, ok := c.ObservationTime["stale"]; ok {
+		t.Errorf("expected stale reporter to be forgotten after cleanup")
+	}
+}
+
+// steppedClock is a clock whose time only changes with step.
+type steppedClock struct {
+	clock.RealClock
+	now time.Time
+}
+
+func (c *steppedClock) Now() time.Time                  { return c.now }
+func (c *steppedClock) Since(t time.Time) time.Duration { return c.now.Sub(t) }
+func (c *steppedClock) step(d time.Duration)            { c.now = c.now.Add(d) }
+
+func TestReportHeartbeat(t *testing.T) {
+	start := time.Now()
+	fc := &steppedClock{now: start}
+	c := &DistributionController{
+		CurrentState:    map[Resource]map[string]Progress{},
+		ObservationTime: map[string]time.Time{},
+		clock:           fc,
+	}
+	h := &DistroReportHandler{dc: c}
+	report := func(heartbeat string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istiod-distribution", Namespace: "istio-system"},
+			Data:       map[string]string{dataField: "reporter: istiod\nheartbeat: " + heartbeat + "\n"},
+		}
+	}
+	observed := func(want time.Time) {
+		t.Helper()
+		if got := c.ObservationTime["istiod"]; !got.Equal(want) {
+			t.Fatalf("got observation time %v, want %v", got, want)
+		}
+	}
+
+	first := report("a")
+	h.OnAdd(first)
+	observed(start)
+
+	// A resync redelivers the unchanged report of a reporter, which may be dead.
+	fc.step(time.Minute)
+	h.OnUpdate(first, first)
+	observed(start)
+
+	// A live reporter writes the same report with a new heartbeat.
+	second := report("b")
+	h.OnUpdate(first, second)
+	observed(start.Add(time.Minute))
+}
+
+func TestDistributionSink(t *testing.T) {
+	vs := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1alpha3",
+		"kind":       "VirtualService",
+		"metadata": map[string]interface{}{
+			"name":            "vs",
+			"namespace":       "default",
+			"resourceVersion": "1",
+		},
+	}}
+	res := Resource{
+		GroupVersionResource: schema.GroupVersionResource{
+			Group:    "networking.istio.io",
+			Version:  "v1alpha3",
+			Resource: "virtualservices",
+		},
+		Namespace:       "default",
+		Name:            "vs",
+		ResourceVersion: "1",
+	}
+	now := time.Now()
+	sink := &MemorySink{}
+	c := &DistributionController{
+		CurrentState: map[Resource]map[string]Progress{
+			res: {
+				"fresh-1": {AckedInstances: 2, TotalInstances: 3},
+				"fresh-2": {AckedInstances: 1, TotalInstances: 1},
+				"stale":   {AckedInstances: 0, TotalInstances: 5},
+			},
+		},
+		ObservationTime: map[string]time.Time{
+			"fresh-1": now.Add(time.Hour),
+			"fresh-2": now.Add(time.Hour),
+			"stale":   now.Add(-time.Hour),
+		},
+		UpdateInterval: 50 * time.Millisecond,
+		StaleInterval:  time.Minute,
+		dynamicClient:  dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), vs),
+		clock:          clock.RealClock{},
+		knownResources: map[schema.GroupVersionResource]dynamic.NamespaceableResourceInterface{},
+		Sink:           sink,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	c.startStatusWriter(ctx)
+
+	var intervals [][]DistributionEvent
+	for deadline := time.Now().Add(5 * time.Second); len(intervals) < 4; time.Sleep(c.UpdateInterval) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 4 intervals to be emitted, got %d", len(intervals))
+		}
+		intervals = sink.Intervals()
+	}
+	cancel()
+	// ticks may be delayed but never come early, so there can not be more intervals than elapsed UpdateIntervals.
+	if maxIntervals := int(time.Since(start)/c.UpdateInterval) + 1; len(intervals) > maxIntervals {
+		t.Fatalf("expected at most %d intervals to be emitted, got %d", maxIntervals, len(intervals))
+	}
+
+	for i, events := range intervals {
+		want := []DistributionEvent{{
+			Resource:        "networking.istio.io/v1alpha3/virtualservices/default/vs",
+			ResourceVersion: "1",
+			Acked:           3,
+			Total:           4,
+		}}
+		// the stale reporter is only reported in the cycle which drops it.
+		if i == 0 {
+			want[0].StaleReporters = []string{"stale"}
+		}
+		for j := range events {
+			want[j].Time = events[j].Time
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("interval %d: got events %+v, want %+v", i, events, want)
+		}
+	}
+}
+
+func TestIsCurrentVersionContentHashed(t *testing.T) {
+	spec := map[string]interface{}{"hosts": []interface{}{"foo.example.com"}}
+	hash, err := ContentHash(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "vs", "namespace": "default", "resourceVersion": "5"},
+		"spec":     spec,
+	}}
+	res := Resource{Namespace: "default", Name: "vs", ResourceVersion: hash}
+	if !isCurrentVersion(res, current) {
+		t.Errorf("expected report for %s to match the current object", hash)
+	}
+	current.Object["spec"] = map[string]interface{}{"hosts": []interface{}{"bar.example.com"}}
+	if isCurrentVersion(res, current) {
+		t.Errorf("expected report for %s to be stale after the spec changed", hash)
+	}
+	if !isCurrentVersion(Resource{ResourceVersion: "5"}, current) {
+		t.Errorf("expected resourceVersion to be compared for resources which are not content hashed")
+	}
+
+	c := &DistributionController{CurrentState: map[Resource]map[string]Progress{
+		res:                               {},
+		{Name: "a", ResourceVersion: "1"}: {},
+	}}
+	if got := c.ContentHashedResources(); got != 1 {
+		t.Errorf("expected 1 content hashed resource, got %d", got)
+	}
+}
+
+func TestWriteStatusContentHashed(t *testing.T) {
+	// The config store omits the default values, which kubernetes returns as set.
+	cfg := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Namespace: "default", Name: "vs"},
+		Spec: &networking.VirtualService{
+			Hosts: []string{"foo.example.com"},
+			Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "foo"}}},
+			}},
+		},
+	}
+	res := ResourceFromModelConfig(cfg)
+	if res == nil || !res.IsContentHashed() {
+		t.Fatalf("expected a content hashed resource, got %v", res)
+	}
+	vs := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1alpha3",
+		"kind":       "VirtualService",
+		"metadata":   map[string]interface{}{"name": "vs", "namespace": "default", "resourceVersion": "1"},
+		"spec": map[string]interface{}{
+			"hosts":    []interface{}{"foo.example.com"},
+			"gateways": []interface{}{},
+			"http": []interface{}{map[string]interface{}{
+				"route": []interface{}{map[string]interface{}{
+					"destination": map[string]interface{}{"host": "foo"},
+					"weight":      int64(0),
+				}},
+			}},
+		},
+	}}
+	c := &DistributionController{
+		CurrentState:   map[Resource]map[string]Progress{*res: {"r": {AckedInstances: 1, TotalInstances: 1}}},
+		dynamicClient:  dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), vs),
+		clock:          clock.RealClock{},
+		knownResources: map[schema.GroupVersionResource]dynamic.NamespaceableResourceInterface{},
+	}
+	c.writeStatus(context.Background(), *res, Progress{AckedInstances: 1, TotalInstances: 1})
+
+	got, err := c.dynamicClient.Resource(res.GroupVersionResource).Namespace("default").Get(context.Background(), "vs", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, f := got.Object["status"]; !f {
+		t.Fatalf("expected the status to be written for the current spec, got %v", got.Object)
+	}
+	if _, f := c.CurrentState[*res]; !f {
+		t.Fatalf("expected the report to be kept for the current spec")
+	}
+}
@@ -19,11 +19,15 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
@@ -32,6 +36,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/util/sets"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/spiffe"
 	istiolog "istio.io/pkg/log"
@@ -63,6 +68,13 @@ type Connection struct {
 	// Defines associated identities for the connection
 	Identities []string
 
+	// spiffeIdentities are the Identities parsed as SPIFFE identities.
+	spiffeIdentities connectionIdentities
+
+	// CertExpiry is the expiry of the client certificate presented on the connection, or the
+	// zero time if there is none.
+	CertExpiry time.Time
+
 	// Time of connection, for debugging
 	Connect time.Time
 
@@ -82,6 +94,36 @@ type Connection struct {
 	// Original node metadata, to avoid unmarshal/marshal.
 	// This is included in internal events.
 	node *core.Node
+
+	// quarantineReason is set when the identity of the connection does not match the proxy it claims to be,
+	// but the connection was accepted because PILOT_XDS_IDENTITY_CHECK_MODE is PERMISSIVE.
+	quarantineReason string
+
+	// bytesSent is the serialized size of the resources sent since the connection was established, by type.
+	bytesSentMutex sync.Mutex
+	bytesSent      map[string]int64
+
+	// diagnostics holds the resources skipped as invalid by the last generation of each type, by type, and
+	// generationErrors the number of resources skipped since the connection was established, by category.
+	diagnosticsMutex sync.Mutex
+	diagnostics      map[string]model.GenerationDiagnostics
+	generationErrors map[string]int64
+
+	// lastFullPush is the time of the last full push sent on the connection.
+	lastFullPushMutex sync.RWMutex
+	lastFullPush      time.Time
+
+	// deferredPush holds the full pushes deferred during the startup stabilization window of the connection,
+	// merged, and deferredPushes their number.
+	deferredPushMutex sync.Mutex
+	deferredPush      *model.PushRequest
+	deferredPushes    int
+
+	// nacks holds the last NACK of each type, by type. A NACK is cleared by the next ACK of the type, or after
+	// features.XDSNackRetention. nacksClosed is set when the connection is closed, and its NACKs dropped.
+	nacksMutex  sync.Mutex
+	nacks       map[string]*NackEvent
+	nacksClosed bool
 }
 
 // Event represents a config or registry event that results in a push.
@@ -168,13 +210,42 @@ func (s *DiscoveryServer) receive(con *Connection, reqChannel chan *discovery.Di
 // handles 'push' requests and close - the code will eventually call the 'push' code, and it needs more mutex
 // protection. Original code avoided the mutexes by doing both 'push' and 'process requests' in same thread.
 func (s *DiscoveryServer) processRequest(req *discovery.DiscoveryRequest, con *Connection) error {
+	// The health reported by the agent is not a watched type, and is not responded to.
+	if req.TypeUrl == v3.HealthInfoType {
+		if s.InternalGen != nil {
+			s.InternalGen.OnHealthInfo(con.proxy, con.SpiffeIdentities(), req)
+		}
+		return nil
+	}
+
 	if s.StatusReporter != nil {
-		s.StatusReporter.RegisterEvent(con.ConID, req.TypeUrl, req.ResponseNonce)
+		s.StatusReporter.RegisterEvent(con.ConID, req.TypeUrl, AckedConfigVersion(req.VersionInfo, req.ResponseNonce))
 	}
 
+	if err := checkResourceLimits(con, req); err != nil {
+		adsLog.Warnf("ADS:%s: REJECT %s: %v", v3.GetShortType(req.TypeUrl), con.ConID, err)
+		xdsResourceLimitRejects.With(typeTag.Value(v3.GetMetricType(req.TypeUrl))).Increment()
+		if features.XDSStrictResourceLimits {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		// The request is ignored, as if it was never sent, so the watched resources are unchanged.
+		return nil
+	}
+
+	// The resource names watched before the request, if the proxy was sent a response it acknowledges.
+	var previousNames []string
+	con.proxy.RLock()
+	if w := con.proxy.WatchedResources[req.TypeUrl]; w != nil && w.NonceSent != "" && req.ResponseNonce == w.NonceSent {
+		previousNames = w.ResourceNames
+	}
+	con.proxy.RUnlock()
+
 	if !s.shouldRespond(con, req) {
 		return nil
 	}
+	if req.TypeUrl == v3.SecretType {
+		s.secretWatchers.update(con, req.ResourceNames)
+	}
 
 	push := s.globalPushContext()
 
@@ -191,8 +262,19 @@ func (s *DiscoveryServer) processRequest(req *discovery.DiscoveryRequest, con *C
 		// TODO move this to just directly using the resource TypeUrl
 		g = s.Generators["api"] // default to "MCP" generators - any type supported by store
 	}
+	if features.EnablePartialXDSResponses {
+		if pg, ok := g.(model.XdsPartialResourceGenerator); ok {
+			if added := addedResourceNames(previousNames, req.ResourceNames); len(added) > 0 {
+				adsLog.Debugf("ADS:%s: PARTIAL %s added resources: %v", v3.GetShortType(req.TypeUrl), con.ConID, added)
+				g = partialGenerator{gen: pg, names: added}
+			}
+		}
+	}
 
-	return s.pushXds(con, push, g, versionInfo(), con.Watched(req.TypeUrl), &model.PushRequest{Full: true})
+	return s.pushXds(con, push, g, configVersionInfo(push), con.Watched(req.TypeUrl), &model.PushRequest{
+		Full:   true,
+		Reason: []model.TriggerReason{model.ProxyRequest},
+	})
 }
 
 // StreamAggregatedResources implements the ADS interface.
@@ -235,6 +317,16 @@ func (s *DiscoveryServer) StreamAggregatedResources(stream discovery.AggregatedD
 
 	con := newConnection(peerAddr, stream)
 	con.Identities = ids
+	con.CertExpiry = clientCertExpiry(ctx)
+
+	// the connection is pending until the response to its first request has been sent.
+	s.pendingConnections.Inc()
+	pending := true
+	defer func() {
+		if pending {
+			s.pendingConnections.Dec()
+		}
+	}()
 
 	// Do not call: defer close(con.pushChannel). The push channel will be garbage collected
 	// when the connection is no longer used. Closing the channel can cause subtle race conditions
@@ -269,6 +361,10 @@ func (s *DiscoveryServer) StreamAggregatedResources(stream discovery.AggregatedD
 			if err != nil {
 				return err
 			}
+			if pending {
+				pending = false
+				s.pendingConnections.Dec()
+			}
 
 		case pushEv := <-con.pushChannel:
 			// TODO: possible race condition: if a config change happens while the envoy
@@ -297,8 +393,10 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 		adsLog.Warnf("ADS:%s: ACK ERROR %s %s:%s", stype, con.ConID, errCode.String(), request.ErrorDetail.GetMessage())
 		incrementXDSRejects(request.TypeUrl, con.proxy.ID, errCode.String())
 		if s.InternalGen != nil {
-			s.InternalGen.OnNack(con.proxy, request)
+			s.InternalGen.OnNack(con, request)
 		}
+		s.trackNack(con, request)
+		s.rollouts.onNack(con.ConID, request.TypeUrl, request.ResponseNonce, request.ErrorDetail.GetMessage())
 		return false
 	}
 
@@ -345,6 +443,12 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 	con.proxy.WatchedResources[request.TypeUrl].LastRequest = request
 	con.proxy.Unlock()
 
+	if s.InternalGen != nil {
+		s.InternalGen.OnAck(con, request)
+	}
+	s.nackTracker.onAck(con.ConID, request.TypeUrl)
+	s.rollouts.onAck(con.ConID, request.TypeUrl, request.ResponseNonce)
+
 	// Envoy can send two DiscoveryRequests with same version and nonce
 	// when it detects a new resource. We should respond if they change.
 	if listEqualUnordered(previousResources, request.ResourceNames) {
@@ -357,6 +461,60 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 	return true
 }
 
+// checkResourceLimits returns an error if the request has more resource names than allowed per request, or if
+// watching them would exceed the number of resources allowed per connection. Wildcard requests, without resource
+// names, are never rejected.
+func checkResourceLimits(con *Connection, request *discovery.DiscoveryRequest) error {
+	names := len(request.ResourceNames)
+	if names == 0 {
+		return nil
+	}
+	if limit := features.XDSMaxResourceNamesPerRequest; limit > 0 && names > limit {
+		return fmt.Errorf("request has %d resource names, exceeding the limit of %d", names, limit)
+	}
+	if limit := features.XDSMaxWatchedResourcesPerConnection; limit > 0 {
+		total := names
+		con.proxy.RLock()
+		for typeURL, w := range con.proxy.WatchedResources {
+			// The request replaces the resource names watched for its type.
+			if typeURL != request.TypeUrl {
+				total += len(w.ResourceNames)
+			}
+		}
+		con.proxy.RUnlock()
+		if total > limit {
+			return fmt.Errorf("connection would watch %d resources, exceeding the limit of %d", total, limit)
+		}
+	}
+	return nil
+}
+
+// addedResourceNames returns the names of current which are not in previous, if current only adds names to
+// previous. It returns nil if names were removed, or if either list is empty, as all the resources are then watched.
+func addedResourceNames(previous, current []string) []string {
+	if len(previous) == 0 || len(current) == 0 {
+		return nil
+	}
+	removed := sets.NewSet(previous...)
+	seen := sets.NewSet()
+	var added []string
+	for _, n := range current {
+		if seen.Contains(n) {
+			continue
+		}
+		seen.Insert(n)
+		if removed.Contains(n) {
+			delete(removed, n)
+		} else {
+			added = append(added, n)
+		}
+	}
+	if len(removed) > 0 {
+		return nil
+	}
+	return added
+}
+
 // listEqualUnordered checks that two lists contain all the same elements
 func listEqualUnordered(a []string, b []string) bool {
 	if len(a) != len(b) {
@@ -395,12 +553,27 @@ func (s *DiscoveryServer) initConnection(node *core.Node, con *Connection) error
 	con.proxy = proxy
 	con.ConID = connectionID(node.Id)
 	con.node = node
+	recordClientCertExpiry(con)
+	if invalid, err := con.InvalidIdentities(); invalid > 0 {
+		adsLog.Debugf("XDS: %v presented %d identities which are not SPIFFE identities: %v", con.PeerAddr, invalid, err)
+	}
 
 	if features.EnableXDSIdentityCheck && con.Identities != nil {
 		// TODO: allow locking down, rejecting unauthenticated requests.
-		if err := checkConnectionIdentity(con); err != nil {
-			adsLog.Warnf("Unauthorized XDS: %v with identity %v: %v", con.PeerAddr, con.Identities, err)
-			return fmt.Errorf("authorization failed: %v", err)
+		mode := strings.ToUpper(features.XDSIdentityCheckMode)
+		if err := checkConnectionIdentity(con, mode == identityCheckStrict); err != nil {
+			recordIdentityMismatch(err)
+			switch mode {
+			case identityCheckPermissive:
+				adsLog.Warnf("Quarantined XDS: %v with identity %v: %v", con.PeerAddr, con.Identities, err)
+				con.quarantineReason = err.Error()
+			case identityCheckStrict:
+				adsLog.Warnf("Unauthorized XDS: %v with identity %v: %v", con.PeerAddr, con.Identities, err)
+				return status.Errorf(codes.PermissionDenied, "authorization failed: %v", err)
+			default:
+				adsLog.Warnf("Unauthorized XDS: %v with identity %v: %v", con.PeerAddr, con.Identities, err)
+				return fmt.Errorf("authorization failed: %v", err)
+			}
 		}
 	}
 
@@ -412,21 +585,102 @@ func (s *DiscoveryServer) initConnection(node *core.Node, con *Connection) error
 	return nil
 }
 
-func checkConnectionIdentity(con *Connection) error {
-	for _, rawID := range con.Identities {
-		spiffeID, err := spiffe.ParseIdentity(rawID)
-		if err != nil {
-			continue
-		}
+const (
+	identityCheckStrict     = "STRICT"
+	identityCheckPermissive = "PERMISSIVE"
+)
+
+// identityMismatchError is returned by checkConnectionIdentity. The reason is used to label metrics.
+type identityMismatchError struct {
+	reason string
+	msg    string
+}
+
+func (e *identityMismatchError) Error() string {
+	return e.msg
+}
+
+// checkConnectionIdentity verifies the identities of the connection match the namespace and service account
+// claimed by the proxy. By default, a single matching identity is sufficient; if requireAll is set, every
+// SPIFFE identity presented must match.
+func checkConnectionIdentity(con *Connection, requireAll bool) error {
+	matched := false
+	var mismatch *identityMismatchError
+	for _, spiffeID := range con.SpiffeIdentities() {
 		if con.proxy.ConfigNamespace != "" && spiffeID.Namespace != con.proxy.ConfigNamespace {
+			mismatch = &identityMismatchError{
+				reason: "namespace",
+				msg:    fmt.Sprintf("identity %v does not match namespace %v", spiffeID, con.proxy.ConfigNamespace),
+			}
+		} else if con.proxy.Metadata.ServiceAccount != "" && spiffeID.ServiceAccount != con.proxy.Metadata.ServiceAccount {
+			mismatch = &identityMismatchError{
+				reason: "service_account",
+				msg:    fmt.Sprintf("identity %v does not match service account %v", spiffeID, con.proxy.Metadata.ServiceAccount),
+			}
+		} else {
+			if !requireAll {
+				return nil
+			}
+			matched = true
 			continue
 		}
-		if con.proxy.Metadata.ServiceAccount != "" && spiffeID.ServiceAccount != con.proxy.Metadata.ServiceAccount {
-			continue
+		if requireAll {
+			return mismatch
 		}
+	}
+	if matched {
 		return nil
 	}
-	return fmt.Errorf("no identities (%v) matched %v/%v", con.Identities, con.proxy.ConfigNamespace, con.proxy.Metadata.ServiceAccount)
+	reason := "no_identity"
+	if mismatch != nil {
+		reason = mismatch.reason
+	}
+	return &identityMismatchError{
+		reason: reason,
+		msg:    fmt.Sprintf("no identities (%v) matched %v/%v", con.Identities, con.proxy.ConfigNamespace, con.proxy.Metadata.ServiceAccount),
+	}
+}
+
+// parseSpiffeIdentity parses the identities of the connections, replaced in tests.
+var parseSpiffeIdentity = spiffe.ParseIdentity
+
+// connectionIdentities holds the identities of a connection parsed as SPIFFE identities. They are parsed once, as
+// the identities of a connection don't change.
+type connectionIdentities struct {
+	once   sync.Once
+	parsed []spiffe.Identity
+	// invalid is the number of identities which are not SPIFFE identities, and firstErr the error parsing the first.
+	invalid  int
+	firstErr error
+}
+
+func (ci *connectionIdentities) parse(raw []string) {
+	ci.once.Do(func() {
+		for _, id := range raw {
+			parsed, err := parseSpiffeIdentity(id)
+			if err != nil {
+				if ci.invalid == 0 {
+					ci.firstErr = err
+				}
+				ci.invalid++
+				continue
+			}
+			ci.parsed = append(ci.parsed, parsed)
+		}
+	})
+}
+
+// SpiffeIdentities returns the identities of the connection which are valid SPIFFE identities.
+func (con *Connection) SpiffeIdentities() []spiffe.Identity {
+	con.spiffeIdentities.parse(con.Identities)
+	return con.spiffeIdentities.parsed
+}
+
+// InvalidIdentities returns the number of identities of the connection which are not valid SPIFFE identities, and
+// the error parsing the first of them.
+func (con *Connection) InvalidIdentities() (int, error) {
+	con.spiffeIdentities.parse(con.Identities)
+	return con.spiffeIdentities.invalid, con.spiffeIdentities.firstErr
 }
 
 func connectionID(node string) string {
@@ -523,6 +777,15 @@ func (s *DiscoveryServer) DeltaAggregatedResources(stream discovery.AggregatedDi
 // Compute and send the new configuration for a connection. This is blocking and may be slow
 // for large configs. The method will hold a lock on con.pushMutex.
 func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
+	if s.deferPush(con, pushEv.pushRequest) {
+		adsLog.Debugf("Deferring push to %v during its startup stabilization window", con.ConID)
+		return nil
+	}
+	// A full push which is not deferred also delivers the pushes deferred so far. Incremental pushes are sent
+	// alone, the deferred pushes are kept until the end of the window or the next full push.
+	if pushEv.pushRequest.Full {
+		pushEv.pushRequest = con.takeDeferredPush().Merge(pushEv.pushRequest)
+	}
 	pushRequest := pushEv.pushRequest
 
 	if pushRequest.Full {
@@ -537,29 +800,76 @@ func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 		if pushRequest.Full {
 			// Only report for full versions, incremental pushes do not have a new version
 			reportAllEvents(s.StatusReporter, con.ConID, pushRequest.Push.Version, nil)
+			s.rollouts.onSkipped(con.ConID, configVersionInfo(pushRequest.Push))
 		}
 		return nil
 	}
 
-	currentVersion := versionInfo()
+	currentVersion := configVersionInfo(pushRequest.Push)
 
 	// Send pushes to all generators
 	// Each Generator is responsible for determining if the push event requires a push
+	var clusters model.Resources
 	for _, w := range getPushResources(con.proxy.WatchedResources) {
-		err := s.pushXds(con, pushRequest.Push, s.Generators[w.TypeUrl], currentVersion, w, pushRequest)
+		if w.TypeUrl == v3.EndpointType && clusters != nil {
+			// Envoy only requests the endpoints of new clusters once they are received, and would warm them
+			// without endpoints until the next push. Push the endpoints of the clusters just sent with them.
+			watchClusterEndpoints(con, w, clusters)
+		}
+		sent, err := s.pushXdsResources(con, pushRequest.Push, s.Generators[w.TypeUrl], currentVersion, w, pushRequest)
 		if err != nil {
 			return err
 		}
+		if w.TypeUrl == v3.ClusterType && pushRequest.Full {
+			clusters = sent
+		}
 	}
 	if pushRequest.Full {
 		// Report all events for unwatched resources. Watched resources will be reported in pushXds or on ack.
 		reportAllEvents(s.StatusReporter, con.ConID, pushRequest.Push.Version, con.proxy.WatchedResources)
+		con.recordFullPush(time.Now())
+		s.rollouts.onPushDone(con.ConID, currentVersion)
 	}
 
-	proxiesConvergeDelay.Record(time.Since(pushRequest.Start).Seconds())
+	proxiesConvergeDelay.With(pushRequestLabels(pushRequest)...).Record(time.Since(pushRequest.Start).Seconds())
 	return nil
 }
 
+// watchClusterEndpoints adds the EDS service names of the clusters to the endpoints watched by the connection. The
+// names watched are replaced by those requested by Envoy on its next EDS request, which is answered if they differ.
+func watchClusterEndpoints(con *Connection, w *model.WatchedResource, clusters model.Resources) {
+	watched := make(map[string]struct{}, len(w.ResourceNames))
+	for _, name := range w.ResourceNames {
+		watched[name] = struct{}{}
+	}
+	var added []string
+	for _, r := range clusters {
+		c := &cluster.Cluster{}
+		if err := ptypes.UnmarshalAny(r, c); err != nil {
+			adsLog.Warnf("ADS:EDS: failed to decode cluster sent to %s: %v", con.ConID, err)
+			continue
+		}
+		if c.GetType() != cluster.Cluster_EDS {
+			continue
+		}
+		name := c.Name
+		if serviceName := c.GetEdsClusterConfig().GetServiceName(); serviceName != "" {
+			name = serviceName
+		}
+		if _, f := watched[name]; !f {
+			watched[name] = struct{}{}
+			added = append(added, name)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	adsLog.Debugf("ADS:EDS: %s watches the endpoints of %d new clusters", con.ConID, len(added))
+	con.proxy.Lock()
+	w.ResourceNames = append(append(make([]string, 0, len(w.ResourceNames)+len(added)), w.ResourceNames...), added...)
+	con.proxy.Unlock()
+}
+
 // PushOrder defines the order that updates will be pushed in. Any types not listed here will be pushed in random
 // order after the types listed here
 var PushOrder = []string{v3.ClusterType, v3.EndpointType, v3.ListenerType, v3.RouteType, v3.SecretType}
@@ -650,13 +960,43 @@ func AdsPushAll(s *DiscoveryServer) {
 	})
 }
 
+// ForcePush enqueues a full push to the connections of the proxies in namespace, or to the connection of the proxy
+// with the given proxy or connection ID, and returns the number of connections targeted. The pushes go through the
+// push queue, so they are throttled like any other push.
+func (s *DiscoveryServer) ForcePush(namespace, proxyID string) int {
+	s.adsClientsMutex.RLock()
+	targets := make([]*Connection, 0)
+	for conID, con := range s.adsClients {
+		if (namespace != "" && con.proxy.ConfigNamespace == namespace) ||
+			(proxyID != "" && (conID == proxyID || con.proxy.ID == proxyID)) {
+			targets = append(targets, con)
+		}
+	}
+	s.adsClientsMutex.RUnlock()
+
+	req := &model.PushRequest{
+		Full:   true,
+		Push:   s.globalPushContext(),
+		Start:  time.Now(),
+		Reason: []model.TriggerReason{model.AdminTrigger},
+	}
+	for _, con := range targets {
+		s.pushQueue.Enqueue(con, req)
+	}
+	adsLog.Infof("XDS: Forced push to %d connections (namespace %q, proxy %q)", len(targets), namespace, proxyID)
+	return len(targets)
+}
+
 // AdsPushAll implements old style invalidation, generated when any rule or endpoint changes.
 // Primary code path is from v1 discoveryService.clearCache(), which is added as a handler
 // to the model ConfigStorageCache and Controller.
 func (s *DiscoveryServer) AdsPushAll(version string, req *model.PushRequest) {
 	// If we don't know what updated, cannot safely cache. Clear the whole cache
 	if len(req.ConfigsUpdated) == 0 {
-		s.Cache.ClearAll()
+		// The cache only holds endpoints and secrets, which are kept if a mesh config change does not affect endpoints.
+		if req.MeshConfigImpact.Affects(model.MeshImpactEndpoints) {
+			s.Cache.ClearAll()
+		}
 	} else {
 		// Otherwise, just clear the updated configs
 		s.Cache.Clear(req.ConfigsUpdated)
@@ -681,17 +1021,22 @@ func (s *DiscoveryServer) AdsPushAll(version string, req *model.PushRequest) {
 
 // Send a signal to all connections, with a push event.
 func (s *DiscoveryServer) startPush(req *model.PushRequest) {
-
-	// Push config changes, iterating over connected envoys. This cover ADS and EDS(0.7), both share
-	// the same connection table
-	s.adsClientsMutex.RLock()
-
-	// Create a temp map to avoid locking the add/remove
-	pending := make([]*Connection, 0, len(s.adsClients))
-	for _, v := range s.adsClients {
-		pending = append(pending, v)
+	var pending []*Connection
+	if onlySecretsUpdated(req) {
+		// Secret changes only affect the connections watching them over SDS, others are skipped entirely.
+		pending = s.secretWatchers.watching(req.ConfigsUpdated)
+	} else {
+		// Push config changes, iterating over connected envoys. This cover ADS and EDS(0.7), both share
+		// the same connection table
+		s.adsClientsMutex.RLock()
+
+		// Create a temp map to avoid locking the add/remove
+		pending = make([]*Connection, 0, len(s.adsClients))
+		for _, v := range s.adsClients {
+			pending = append(pending, v)
+		}
+		s.adsClientsMutex.RUnlock()
 	}
-	s.adsClientsMutex.RUnlock()
 
 	if adsLog.DebugEnabled() {
 		currentlyPending := s.pushQueue.Pending()
@@ -699,6 +1044,14 @@ func (s *DiscoveryServer) startPush(req *model.PushRequest) {
 			adsLog.Infof("Starting new push while %v were still pending", currentlyPending)
 		}
 	}
+	if req.Full {
+		conIDs := make([]string, 0, len(pending))
+		for _, p := range pending {
+			conIDs = append(conIDs, p.ConID)
+		}
+		s.rollouts.recordTargets(configVersionInfo(req.Push), conIDs)
+	}
+
 	req.Start = time.Now()
 	for _, p := range pending {
 		s.pushQueue.Enqueue(p, req)
@@ -723,14 +1076,17 @@ func (s *DiscoveryServer) removeCon(conID string) {
 		delete(s.adsClients, conID)
 		recordXDSClients(con.proxy.Metadata.IstioVersion, -1)
 	}
+	s.secretWatchers.remove(conID)
+	s.nackTracker.onDisconnect(conID)
+	s.rollouts.onDisconnect(conID)
 
 	if s.StatusReporter != nil {
 		go s.StatusReporter.RegisterDisconnect(conID, AllEventTypes)
 	}
 }
 
-// Send with timeout
-func (conn *Connection) send(res *discovery.DiscoveryResponse) error {
+// Send with timeout. Returns the serialized size of the resources sent, which is 0 if the send failed.
+func (conn *Connection) send(res *discovery.DiscoveryResponse) (int, error) {
 	errChan := make(chan error, 1)
 	// hardcoded for now - not sure if we need a setting
 	t := time.NewTimer(sendTimeout)
@@ -744,13 +1100,14 @@ func (conn *Connection) send(res *discovery.DiscoveryResponse) error {
 		// TODO: wait for ACK
 		adsLog.Infof("Timeout writing %s", conn.ConID)
 		xdsResponseWriteTimeouts.Increment()
-		return status.Errorf(codes.DeadlineExceeded, "timeout sending")
+		return 0, status.Errorf(codes.DeadlineExceeded, "timeout sending")
 	case err := <-errChan:
+		sz := 0
 		if err == nil {
-			sz := 0
 			for _, rc := range res.Resources {
 				sz += len(rc.Value)
 			}
+			conn.recordBytesSent(res.TypeUrl, sz)
 			conn.proxy.Lock()
 			if res.Nonce != "" {
 				if conn.proxy.WatchedResources[res.TypeUrl] == nil {
@@ -768,8 +1125,41 @@ func (conn *Connection) send(res *discovery.DiscoveryResponse) error {
 		if !t.Stop() {
 			<-t.C
 		}
-		return err
+		return sz, err
+	}
+}
+
+func (conn *Connection) recordBytesSent(typeURL string, sz int) {
+	conn.bytesSentMutex.Lock()
+	defer conn.bytesSentMutex.Unlock()
+	if conn.bytesSent == nil {
+		conn.bytesSent = map[string]int64{}
+	}
+	conn.bytesSent[typeURL] += int64(sz)
+}
+
+// BytesSent returns the serialized size of the resources sent on the connection since it was established, by type.
+func (conn *Connection) BytesSent() map[string]int64 {
+	conn.bytesSentMutex.Lock()
+	defer conn.bytesSentMutex.Unlock()
+	out := make(map[string]int64, len(conn.bytesSent))
+	for typeURL, sz := range conn.bytesSent {
+		out[typeURL] = sz
 	}
+	return out
+}
+
+func (conn *Connection) recordFullPush(t time.Time) {
+	conn.lastFullPushMutex.Lock()
+	defer conn.lastFullPushMutex.Unlock()
+	conn.lastFullPush = t
+}
+
+// LastFullPush returns the time of the last full push sent on the connection, or the zero time if there was none.
+func (conn *Connection) LastFullPush() time.Time {
+	conn.lastFullPushMutex.RLock()
+	defer conn.lastFullPushMutex.RUnlock()
+	return conn.lastFullPush
 }
 
 // nolint
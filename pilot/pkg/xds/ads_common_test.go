@@ -15,11 +15,33 @@
 package xds
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
 	model "istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/adsc"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/spiffe"
@@ -55,6 +77,13 @@ func TestProxyNeedsPush(t *testing.T) {
 		Type: model.SidecarProxy, IPAddresses: []string{"127.0.0.1"}, Metadata: &model.NodeMetadata{},
 		SidecarScope: &model.SidecarScope{Config: proxyCfg, RootNamespace: nsRoot}}
 	gateway := &model.Proxy{Type: model.Router}
+	sidecarWithPrevScope := &model.Proxy{
+		Type: model.SidecarProxy, IPAddresses: []string{"127.0.0.1"}, Metadata: &model.NodeMetadata{},
+		SidecarScope: &model.SidecarScope{Config: proxyCfg, RootNamespace: nsRoot},
+		PrevSidecarScope: &model.SidecarScope{
+			Config:        &config.Config{Meta: config.Meta{Name: scName, Namespace: nsName}},
+			RootNamespace: nsRoot,
+		}}
 
 	sidecarScopeKindNames := map[config.GroupVersionKind]string{
 		gvk.ServiceEntry: svcName, gvk.VirtualService: vsName, gvk.DestinationRule: drName}
@@ -100,6 +129,18 @@ func TestProxyNeedsPush(t *testing.T) {
 			{Kind: gvk.ServiceEntry, Name: svcName + invalidNameSuffix, Namespace: nsName}:   {},
 		}, false},
 		{"empty configsUpdated for sidecar", sidecar, nil, true},
+		{"sidecar config selecting other workloads in same namespace", sidecar, map[model.ConfigKey]struct{}{
+			{
+				Kind: gvk.Sidecar,
+				Name: scName, Namespace: nsName}: {}}, false},
+		{"sidecar config previously selecting sidecar", sidecarWithPrevScope, map[model.ConfigKey]struct{}{
+			{
+				Kind: gvk.Sidecar,
+				Name: scName, Namespace: nsName}: {}}, true},
+		{"sidecar config in root namespace", sidecar, map[model.ConfigKey]struct{}{
+			{
+				Kind: gvk.Sidecar,
+				Name: scName, Namespace: nsRoot}: {}}, true},
 	}
 
 	for kind, name := range sidecarScopeKindNames {
@@ -143,11 +184,16 @@ func TestProxyNeedsPush(t *testing.T) {
 			if nodeType == model.SidecarProxy {
 				proxy = sidecar
 			}
+			name := generalName + invalidNameSuffix
+			if kind == gvk.Sidecar {
+				// A Sidecar only affects the proxies it is selected for.
+				name = generalName
+			}
 			cases = append(cases, Case{
 				name:  fmt.Sprintf("kind %s affect %s", kind, nodeType),
 				proxy: proxy,
 				configs: map[model.ConfigKey]struct{}{
-					{Kind: kind, Name: generalName + invalidNameSuffix, Namespace: nsName}: {}},
+					{Kind: kind, Name: name, Namespace: nsName}: {}},
 				want: true,
 			})
 		}
@@ -236,9 +282,395 @@ func TestCheckConnectionIdentity(t *testing.T) {
 				proxy:      &model.Proxy{ConfigNamespace: tt.namespace, Metadata: &model.NodeMetadata{ServiceAccount: tt.sa}},
 				Identities: tt.identity,
 			}
-			if err := checkConnectionIdentity(con); (err == nil) != tt.success {
+			if err := checkConnectionIdentity(con, false); (err == nil) != tt.success {
 				t.Fatalf("expected success=%v, got err=%v", tt.success, err)
 			}
 		})
 	}
 }
+
+func TestCheckConnectionIdentityStrict(t *testing.T) {
+	cases := []struct {
+		name     string
+		identity []string
+		reason   string
+	}{
+		{
+			name:     "matching identity",
+			identity: []string{spiffe.Identity{"cluster.local", "namespace", "serviceaccount"}.String()},
+		},
+		{
+			name: "namespace mismatch",
+			identity: []string{
+				spiffe.Identity{"cluster.local", "bad", "serviceaccount"}.String(),
+				spiffe.Identity{"cluster.local", "namespace", "serviceaccount"}.String(),
+			},
+			reason: "namespace",
+		},
+		{
+			name:     "service account mismatch",
+			identity: []string{spiffe.Identity{"cluster.local", "namespace", "bad"}.String()},
+			reason:   "service_account",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			con := &Connection{
+				proxy:      &model.Proxy{ConfigNamespace: "namespace", Metadata: &model.NodeMetadata{ServiceAccount: "serviceaccount"}},
+				Identities: tt.identity,
+			}
+			err := checkConnectionIdentity(con, true)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("expected success, got err=%v", err)
+				}
+				return
+			}
+			mismatch, ok := err.(*identityMismatchError)
+			if !ok {
+				t.Fatalf("expected identity mismatch error, got %v", err)
+			}
+			if mismatch.reason != tt.reason {
+				t.Fatalf("expected reason %v, got %v", tt.reason, mismatch.reason)
+			}
+		})
+	}
+}
+
+func TestConnectionIdentitiesParsedOnce(t *testing.T) {
+	parses := 0
+	parseSpiffeIdentity = func(s string) (spiffe.Identity, error) {
+		parses++
+		return spiffe.ParseIdentity(s)
+	}
+	t.Cleanup(func() { parseSpiffeIdentity = spiffe.ParseIdentity })
+
+	valid := spiffe.Identity{TrustDomain: "cluster.local", Namespace: "namespace", ServiceAccount: "serviceaccount"}
+	con := &Connection{
+		proxy:      &model.Proxy{ConfigNamespace: "namespace", Metadata: &model.NodeMetadata{ServiceAccount: "serviceaccount"}},
+		Identities: []string{"spiffe://cluster.local/not-a-workload", valid.String()},
+	}
+	for i := 0; i < 3; i++ {
+		// The malformed identity is skipped, and does not fail the check in either mode.
+		if err := checkConnectionIdentity(con, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := checkConnectionIdentity(con, true); err != nil {
+			t.Fatalf("unexpected error in strict mode: %v", err)
+		}
+	}
+	if got := con.SpiffeIdentities(); !reflect.DeepEqual(got, []spiffe.Identity{valid}) {
+		t.Fatalf("got identities %v, want %v", got, []spiffe.Identity{valid})
+	}
+	invalid, err := con.InvalidIdentities()
+	if invalid != 1 || err == nil {
+		t.Fatalf("expected 1 invalid identity with its error, got %d: %v", invalid, err)
+	}
+	if parses != len(con.Identities) {
+		t.Fatalf("expected each identity to be parsed once, got %d parses", parses)
+	}
+}
+
+func TestResourceLimits(t *testing.T) {
+	oldNames, oldWatched, oldStrict := features.XDSMaxResourceNamesPerRequest,
+		features.XDSMaxWatchedResourcesPerConnection, features.XDSStrictResourceLimits
+	features.XDSMaxResourceNamesPerRequest = 100
+	features.XDSMaxWatchedResourcesPerConnection = 150
+	t.Cleanup(func() {
+		features.XDSMaxResourceNamesPerRequest, features.XDSMaxWatchedResourcesPerConnection,
+			features.XDSStrictResourceLimits = oldNames, oldWatched, oldStrict
+	})
+
+	names := func(n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = "outbound|80||svc-" + strconv.Itoa(i) + ".default.svc.cluster.local"
+		}
+		return out
+	}
+	newCon := func() *Connection {
+		return &Connection{
+			ConID: "test",
+			proxy: &model.Proxy{WatchedResources: map[string]*model.WatchedResource{
+				v3.ListenerType: {TypeUrl: v3.ListenerType},
+				v3.RouteType:    {TypeUrl: v3.RouteType, ResourceNames: names(100)},
+			}},
+		}
+	}
+	s := &DiscoveryServer{}
+
+	t.Run("resource name explosion", func(t *testing.T) {
+		con := newCon()
+		req := &discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResourceNames: names(1000000)}
+		if err := s.processRequest(req, con); err != nil {
+			t.Fatalf("expected the request to be ignored, got %v", err)
+		}
+		if _, f := con.proxy.WatchedResources[v3.EndpointType]; f {
+			t.Fatal("expected the resource names not to be watched")
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		features.XDSStrictResourceLimits = true
+		defer func() { features.XDSStrictResourceLimits = false }()
+		req := &discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResourceNames: names(1000000)}
+		if err := s.processRequest(req, newCon()); status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("expected RESOURCE_EXHAUSTED, got %v", err)
+		}
+	})
+
+	cases := []struct {
+		name    string
+		typeURL string
+		names   int
+		reject  bool
+	}{
+		{"wildcard", v3.ClusterType, 0, false},
+		{"within limits", v3.EndpointType, 50, false},
+		{"per request limit", v3.EndpointType, 101, true},
+		{"per connection limit", v3.EndpointType, 51, true},
+		// Routes replace the 100 route names already watched.
+		{"replaced names", v3.RouteType, 100, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkResourceLimits(newCon(), &discovery.DiscoveryRequest{TypeUrl: tt.typeURL, ResourceNames: names(tt.names)})
+			if (err != nil) != tt.reject {
+				t.Fatalf("expected reject=%v, got %v", tt.reject, err)
+			}
+		})
+	}
+}
+
+func TestInitConnectionPermissiveIdentity(t *testing.T) {
+	mode := features.XDSIdentityCheckMode
+	features.XDSIdentityCheckMode = identityCheckPermissive
+	defer func() { features.XDSIdentityCheckMode = mode }()
+
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	con := newConnection("1.1.1.1", nil)
+	con.Identities = []string{spiffe.Identity{"cluster.local", "default", "bad"}.String()}
+	node := &core.Node{
+		Id:       "sidecar~1.1.1.1~app.default~default.svc.cluster.local",
+		Metadata: model.NodeMetadata{Namespace: "default", ServiceAccount: "app"}.ToStruct(),
+	}
+	if err := s.Discovery.initConnection(node, con); err != nil {
+		t.Fatalf("expected connection to be accepted, got %v", err)
+	}
+	defer s.Discovery.removeCon(con.ConID)
+
+	rr := httptest.NewRecorder()
+	s.Discovery.quarantinez(rr, httptest.NewRequest("GET", "/debug/quarantinez", nil))
+	var got []QuarantinedClient
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ConnectionID != con.ConID {
+		t.Fatalf("expected %v to be quarantined, got %+v", con.ConID, got)
+	}
+}
+
+func TestClientCertExpiry(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	peerContext := func(authInfo credentials.AuthInfo) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{
+			Addr:     &net.TCPAddr{IP: net.ParseIP("1.1.1.1")},
+			AuthInfo: authInfo,
+		})
+	}
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want time.Time
+	}{
+		{
+			name: "client certificate",
+			ctx: peerContext(credentials.TLSInfo{State: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{NotAfter: expiry}, {NotAfter: expiry.Add(time.Hour)}},
+			}}),
+			want: expiry,
+		},
+		{
+			name: "tls without client certificate",
+			ctx:  peerContext(credentials.TLSInfo{}),
+		},
+		{
+			name: "plaintext",
+			ctx:  peerContext(nil),
+		},
+		{
+			name: "no peer",
+			ctx:  context.Background(),
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientCertExpiry(tt.ctx); !got.Equal(tt.want) {
+				t.Fatalf("expected expiry %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCertz(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	now := time.Now().Truncate(time.Second)
+	connected := map[string]struct{}{}
+	disconnect := func(con *Connection) {
+		s.Discovery.removeCon(con.ConID)
+		delete(connected, con.ConID)
+	}
+	t.Cleanup(func() {
+		for conID := range connected {
+			s.Discovery.removeCon(conID)
+		}
+	})
+	connect := func(ip, namespace string, expiry time.Time) *Connection {
+		t.Helper()
+		con := newConnection(ip, nil)
+		con.CertExpiry = expiry
+		node := &core.Node{
+			Id:       fmt.Sprintf("sidecar~%s~app.%s~%s.svc.cluster.local", ip, namespace, namespace),
+			Metadata: model.NodeMetadata{Namespace: namespace}.ToStruct(),
+		}
+		if err := s.Discovery.initConnection(node, con); err != nil {
+			t.Fatal(err)
+		}
+		connected[con.ConID] = struct{}{}
+		return con
+	}
+	certz := func() map[string][]ClientCert {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.Discovery.certz(rr, httptest.NewRequest("GET", "/debug/certz", nil))
+		got := map[string][]ClientCert{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	later := connect("1.1.1.1", "default", now.Add(24*time.Hour))
+	sooner := connect("1.1.1.2", "default", now.Add(time.Hour))
+	jwt := connect("1.1.1.3", "default", time.Time{})
+	other := connect("1.1.1.4", "other", now.Add(-time.Minute))
+
+	got := certz()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 namespaces, got %+v", got)
+	}
+	def := got["default"]
+	if len(def) != 3 || def[0].ConnectionID != sooner.ConID || def[1].ConnectionID != later.ConID || def[2].ConnectionID != jwt.ConID {
+		t.Fatalf("expected default connections sorted by expiry, got %+v", def)
+	}
+	if def[2].Expiry != nil || def[2].TimeToExpiry != "no cert" {
+		t.Errorf("expected no cert for JWT only connection, got %+v", def[2])
+	}
+	if o := got["other"]; len(o) != 1 || o[0].ConnectionID != other.ConID || !o[0].Expiry.Equal(now.Add(-time.Minute)) {
+		t.Errorf("expected expired certificate in other namespace, got %+v", o)
+	}
+
+	// A reconnect with a rotated certificate replaces the expiry of the previous connection.
+	disconnect(sooner)
+	rotated := connect("1.1.1.2", "default", now.Add(48*time.Hour))
+	def = certz()["default"]
+	if len(def) != 3 || def[1].ConnectionID != rotated.ConID || !def[1].Expiry.Equal(now.Add(48*time.Hour)) {
+		t.Fatalf("expected rotated certificate to be reported, got %+v", def)
+	}
+}
+
+func TestTrustdomainz(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	trustdomainz := func() map[string][]TrustedRoot {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.Discovery.trustdomainz(rr, httptest.NewRequest("GET", "/debug/trustdomainz", nil))
+		got := map[string][]TrustedRoot{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	if got := trustdomainz(); len(got) != 0 {
+		t.Fatalf("expected no trust domains without TLS, got %+v", got)
+	}
+
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	root := func(name string) *x509.Certificate {
+		return &x509.Certificate{Raw: []byte(name), Subject: pkix.Name{CommonName: name}, NotAfter: expiry}
+	}
+	s.Discovery.PeerCertVerifier = spiffe.NewPeerCertVerifier()
+	s.Discovery.PeerCertVerifier.AddMapping("cluster.local", []*x509.Certificate{root("local")})
+	s.Discovery.PeerCertVerifier.UpdateMappings(map[string][]*x509.Certificate{"foo.domain.com": {root("foo")}})
+
+	// A refresh of the SPIFFE bundle of foo replaces its root certificate.
+	s.Discovery.PeerCertVerifier.UpdateMappings(map[string][]*x509.Certificate{"foo.domain.com": {root("rotated")}})
+	localFingerprint, fooFingerprint := sha256.Sum256([]byte("local")), sha256.Sum256([]byte("rotated"))
+	got := trustdomainz()
+	want := map[string][]TrustedRoot{
+		"cluster.local":  {{Subject: "CN=local", Fingerprint: hex.EncodeToString(localFingerprint[:]), Expiry: expiry}},
+		"foo.domain.com": {{Subject: "CN=rotated", Fingerprint: hex.EncodeToString(fooFingerprint[:]), Expiry: expiry}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got trusted roots %+v, want %+v", got, want)
+	}
+}
+
+func TestForcePush(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	watch := []string{v3.ClusterType}
+	a1 := s.Connect(&model.Proxy{ConfigNamespace: "a", IPAddresses: []string{"1.1.1.1"}}, watch, watch)
+	a2 := s.Connect(&model.Proxy{ConfigNamespace: "a", IPAddresses: []string{"1.1.1.2"}}, watch, watch)
+	b := s.Connect(&model.Proxy{ConfigNamespace: "b", IPAddresses: []string{"1.1.1.3"}}, watch, watch)
+
+	forcePush := func(query string, wantConnections int) {
+		t.Helper()
+		for _, ads := range []*adsc.ADSC{a1, a2, b} {
+			ads.WaitClear()
+		}
+		rr := httptest.NewRecorder()
+		s.Discovery.forcePush(rr, httptest.NewRequest("POST", "/debug/force_push?"+query, nil))
+		got := ForcePushResponse{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+		}
+		if got.Connections != wantConnections {
+			t.Fatalf("expected %d connections to be targeted, got %d", wantConnections, got.Connections)
+		}
+	}
+	expectPush := func(ads *adsc.ADSC, want bool) {
+		t.Helper()
+		timeout := 5 * time.Second
+		if !want {
+			timeout = 200 * time.Millisecond
+		}
+		if _, err := ads.Wait(timeout, v3.ClusterType); (err == nil) != want {
+			t.Fatalf("expected push %v, got error %v", want, err)
+		}
+	}
+
+	forcePush("namespace=a", 2)
+	expectPush(a1, true)
+	expectPush(a2, true)
+	expectPush(b, false)
+
+	forcePush("proxy=test-1.b", 1)
+	expectPush(b, true)
+	expectPush(a1, false)
+
+	for _, tt := range []struct {
+		method string
+		query  string
+		want   int
+	}{
+		{"GET", "namespace=a", http.StatusMethodNotAllowed},
+		{"POST", "", http.StatusBadRequest},
+		{"POST", "namespace=a&proxy=test-1.b", http.StatusBadRequest},
+	} {
+		rr := httptest.NewRecorder()
+		s.Discovery.forcePush(rr, httptest.NewRequest(tt.method, "/debug/force_push?"+tt.query, nil))
+		if rr.Code != tt.want {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.query, tt.want, rr.Code)
+		}
+	}
+}
@@ -20,8 +20,11 @@ import (
 	"time"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 
 	mesh "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
@@ -39,6 +42,7 @@ import (
 	istioagent "istio.io/istio/pkg/istio-agent"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/test/util/retry"
 	"istio.io/istio/tests/util"
 )
 
@@ -1075,3 +1079,123 @@ func TestXdsCache(t *testing.T) {
 	assertEndpoints(ads)
 	t.Logf("endpoints: %+v", ads.GetEndpoints())
 }
+
+func TestAdsPushNewClusterEndpoints(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	addService := func(name, ip string) string {
+		hostname := name + ".default.svc.cluster.local"
+		s.Discovery.MemRegistry.AddService(host.Name(hostname), &model.Service{
+			Hostname: host.Name(hostname),
+			Address:  "10.11.0." + ip,
+			Ports: []*model.Port{
+				{
+					Name:     "http-main",
+					Port:     2080,
+					Protocol: protocol.HTTP,
+				},
+			},
+			Attributes: model.ServiceAttributes{
+				Name:      name,
+				Namespace: "default",
+			},
+		})
+		s.Discovery.MemRegistry.SetEndpoints(hostname, "default", newEndpointWithAccount("10.2.0."+ip, "hello-sa", "v1"))
+		return "outbound|2080||" + hostname
+	}
+	existing := addService("existing", "1")
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+	time.Sleep(time.Millisecond * 200)
+
+	adscon := s.ConnectADS()
+	node := sidecarID("1.1.1.1", "app3")
+	if err := sendCDSReq(node, adscon); err != nil {
+		t.Fatal(err)
+	}
+	cds, err := adsReceive(adscon, 15*time.Second)
+	if err != nil {
+		t.Fatal("Recv failed", err)
+	}
+	if err := sendXdsAck(node, adscon, cds, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := sendEDSReq([]string{existing}, node, "", "", adscon); err != nil {
+		t.Fatal(err)
+	}
+	eds, err := adsReceive(adscon, 15*time.Second)
+	if err != nil {
+		t.Fatal("Recv failed", err)
+	}
+	if err := sendXdsAck(node, adscon, eds, []string{existing}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The endpoints of the new cluster are pushed after CDS, before Envoy requests them.
+	added := addService("added", "2")
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+	cds, err = adsReceive(adscon, 15*time.Second)
+	if err != nil {
+		t.Fatal("Recv failed", err)
+	}
+	if cds.TypeUrl != v3.ClusterType {
+		t.Fatalf("Expecting %v got %v", v3.ClusterType, cds.TypeUrl)
+	}
+	eds, err = adsReceive(adscon, 15*time.Second)
+	if err != nil {
+		t.Fatal("Recv failed", err)
+	}
+	if eds.TypeUrl != v3.EndpointType {
+		t.Fatalf("Expecting %v got %v", v3.EndpointType, eds.TypeUrl)
+	}
+	got := map[string][]string{}
+	for _, r := range eds.Resources {
+		cla := &endpoint.ClusterLoadAssignment{}
+		if err := ptypes.UnmarshalAny(r, cla); err != nil {
+			t.Fatal(err)
+		}
+		got[cla.ClusterName] = xdstest.ExtractEndpoints(cla)
+	}
+	if len(got[existing]) == 0 || len(got[added]) == 0 {
+		t.Fatalf("expected the endpoints of %s and %s in the same push, got %v", existing, added, got)
+	}
+}
+
+// sendXdsAck ACKs the response, with the resource names subscribed to.
+func sendXdsAck(node string, client AdsClient, res *discovery.DiscoveryResponse, names []string) error {
+	return client.Send(&discovery.DiscoveryRequest{
+		ResponseNonce: res.Nonce,
+		VersionInfo:   res.VersionInfo,
+		Node: &core.Node{
+			Id:       node,
+			Metadata: nodeMetadata,
+		},
+		TypeUrl:       res.TypeUrl,
+		ResourceNames: names,
+	})
+}
+
+func TestPendingConnections(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	ads := s.ConnectADS()
+	// The connection is pending until it is sent the response to its first request.
+	retry.UntilSuccessOrFail(t, func() error {
+		if got := s.Discovery.PendingConnections(); got != 1 {
+			return fmt.Errorf("expected 1 pending connection, got %d", got)
+		}
+		return nil
+	}, retry.Timeout(time.Second))
+	if err := ads.Send(&discovery.DiscoveryRequest{
+		Node:    &core.Node{Id: "sidecar~1.1.1.1~app.default~default.svc.cluster.local"},
+		TypeUrl: v3.ClusterType,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ads.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if got := s.Discovery.PendingConnections(); got != 0 {
+			return fmt.Errorf("expected no pending connection, got %d", got)
+		}
+		return nil
+	}, retry.Timeout(time.Second))
+}
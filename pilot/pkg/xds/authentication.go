@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
@@ -61,3 +62,17 @@ func (s *DiscoveryServer) authenticate(ctx context.Context) ([]string, error) {
 	adsLog.Errora("Failed to authenticate client from ", peerInfo.Addr.String(), " ", strings.Join(authFailMsgs, "; "))
 	return nil, errors.New("authentication failure")
 }
+
+// clientCertExpiry returns the expiry of the leaf client certificate presented on the connection, or the
+// zero time if the client did not present one, for example when authenticated with a JWT only.
+func clientCertExpiry(ctx context.Context) time.Time {
+	peerInfo, ok := peer.FromContext(ctx)
+	if !ok {
+		return time.Time{}
+	}
+	tlsInfo, ok := peerInfo.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return time.Time{}
+	}
+	return tlsInfo.State.PeerCertificates[0].NotAfter
+}
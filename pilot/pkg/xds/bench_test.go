@@ -114,7 +114,7 @@ func BenchmarkRouteGeneration(b *testing.B) {
 		b.Run(tt.Name, func(b *testing.B) {
 			s, proxy := setupAndInitializeTest(b, tt)
 			// To determine which routes to generate, first gen listeners once (not part of benchmark) and extract routes
-			l := s.Discovery.ConfigGenerator.BuildListeners(proxy, s.PushContext())
+			l := s.Discovery.ConfigGenerator.BuildListeners(proxy, s.PushContext(), nil)
 			routeNames := xdstest.ExtractRoutesFromListeners(l)
 			if len(routeNames) == 0 {
 				b.Fatal("Got no route names!")
@@ -168,6 +168,41 @@ func BenchmarkListenerGeneration(b *testing.B) {
 	}
 }
 
+// BenchmarkGenerationContext compares the generation of clusters and listeners for a sidecar in a mesh with 1k
+// services, with and without the buffers of a pooled GenerationContext, as done by pushXds.
+func BenchmarkGenerationContext(b *testing.B) {
+	disableLogging()
+	tt := ConfigInput{
+		Name:      "empty",
+		Services:  1000,
+		ProxyType: model.SidecarProxy,
+	}
+	for _, typeURL := range []string{v3.ClusterType, v3.ListenerType} {
+		b.Run(v3.GetShortType(typeURL), func(b *testing.B) {
+			s, proxy := setupAndInitializeTest(b, tt)
+			gen := s.Discovery.Generators[typeURL].(model.XdsContextResourceGenerator)
+			b.Run("without-context", func(b *testing.B) {
+				b.ReportAllocs()
+				for n := 0; n < b.N; n++ {
+					if c := gen.Generate(proxy, s.PushContext(), nil, nil); len(c) == 0 {
+						b.Fatal("Got no resources!")
+					}
+				}
+			})
+			b.Run("with-context", func(b *testing.B) {
+				b.ReportAllocs()
+				for n := 0; n < b.N; n++ {
+					ctx := model.GetGenerationContext()
+					if c := gen.GenerateWithContext(ctx, proxy, s.PushContext(), nil, nil); len(c) == 0 {
+						b.Fatal("Got no resources!")
+					}
+					ctx.Release()
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkNameTableGeneration(b *testing.B) {
 	disableLogging()
 	for _, tt := range testCases {
@@ -0,0 +1,275 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// endpointCardinality applies PILOT_MAX_ENDPOINTS_PER_SERVICE and PILOT_MAX_ENDPOINTS_PER_SHARD to the endpoints
+// of the services, and records the services exceeding them. A nil endpointCardinality does not limit.
+type endpointCardinality struct {
+	mutex sync.Mutex
+	// requested are the endpoints of each service within PILOT_MAX_ENDPOINTS_PER_SERVICE, by cluster and
+	// namespace/hostname. They are only tracked with PILOT_MAX_ENDPOINTS_PER_SHARD, whose budget is allocated to
+	// the services of the cluster in key order, so the endpoints kept don't depend on the order of the updates.
+	requested map[string]map[string][]*model.IstioEndpoint
+	// kept is the number of endpoints kept for each service, by cluster and namespace/hostname.
+	kept map[string]map[string]int
+	// violations are the services exceeding a limit, by key.
+	violations map[string]model.CardinalityViolation
+}
+
+// shardUpdate is the endpoints kept for another service of the cluster, whose share of
+// PILOT_MAX_ENDPOINTS_PER_SHARD changed with an update.
+type shardUpdate struct {
+	hostname  string
+	namespace string
+	endpoints []*model.IstioEndpoint
+}
+
+func newEndpointCardinality() *endpointCardinality {
+	return &endpointCardinality{
+		requested:  map[string]map[string][]*model.IstioEndpoint{},
+		kept:       map[string]map[string]int{},
+		violations: map[string]model.CardinalityViolation{},
+	}
+}
+
+// limit returns the endpoints of the service in the cluster to keep. If a limit is exceeded, the endpoints with the
+// lowest addresses are kept, or none with PILOT_STRICT_CARDINALITY_LIMITS, and the violation is returned. The other
+// services of the cluster whose endpoints kept changed are returned as well.
+func (c *endpointCardinality) limit(cluster, hostname, namespace string,
+	endpoints []*model.IstioEndpoint) ([]*model.IstioEndpoint, *model.CardinalityViolation, []shardUpdate) {
+	maxPerService, maxPerShard := features.MaxEndpointsPerService, features.MaxEndpointsPerShard
+	if c == nil || (maxPerService <= 0 && maxPerShard <= 0) {
+		return endpoints, nil, nil
+	}
+	key := namespace + "/" + hostname
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var v *model.CardinalityViolation
+	requested := endpoints
+	if maxPerService > 0 && len(endpoints) > maxPerService {
+		v = c.violation(model.EndpointsPerServiceLimit, maxPerService, cluster, key, len(endpoints))
+		requested = model.TruncateEndpoints(endpoints, c.truncated(maxPerService))
+	}
+	if maxPerShard <= 0 {
+		c.setViolation(cluster, key, v, len(requested))
+		return requested, v, nil
+	}
+
+	c.setRequested(cluster, key, requested)
+	var kept []*model.IstioEndpoint
+	var updates []shardUpdate
+	allocation := c.allocate(cluster, maxPerShard)
+	for k, n := range allocation {
+		eps := c.requested[cluster][k]
+		truncated := model.TruncateEndpoints(eps, n)
+		if k == key {
+			kept = truncated
+			if n < len(eps) {
+				v = c.violation(model.EndpointsPerShardLimit, maxPerShard, cluster, key, len(endpoints))
+			}
+			c.setViolation(cluster, key, v, n)
+			continue
+		}
+		if n == c.kept[cluster][k] {
+			continue
+		}
+		// The violation of another service is only updated for the limit per shard, its own limit is unchanged.
+		if n < len(eps) {
+			c.setViolation(cluster, k, c.violation(model.EndpointsPerShardLimit, maxPerShard, cluster, k, len(eps)), n)
+		} else if prev, f := c.violations[cluster+"/"+k]; f && prev.Limit == model.EndpointsPerShardLimit {
+			c.setViolation(cluster, k, nil, n)
+		} else {
+			c.setKept(cluster, k, n)
+		}
+		ns, host := splitServiceKey(k)
+		updates = append(updates, shardUpdate{hostname: host, namespace: ns, endpoints: truncated})
+	}
+	if _, f := allocation[key]; !f {
+		// No endpoint is requested: the update is empty, or the service exceeds its limit in strict mode.
+		c.setViolation(cluster, key, v, 0)
+	}
+	return kept, v, updates
+}
+
+// allocate returns the number of endpoints kept for each service of the cluster, allocating the budget of the
+// cluster to the services in key order.
+func (c *endpointCardinality) allocate(cluster string, maxPerShard int) map[string]int {
+	keys := make([]string, 0, len(c.requested[cluster]))
+	for k := range c.requested[cluster] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make(map[string]int, len(keys))
+	available := maxPerShard
+	for _, k := range keys {
+		n := len(c.requested[cluster][k])
+		if n > available {
+			n = c.truncated(available)
+		}
+		available -= n
+		out[k] = n
+	}
+	return out
+}
+
+// truncated returns the number of endpoints kept for a service exceeding a limit of max endpoints.
+func (c *endpointCardinality) truncated(max int) int {
+	if features.StrictCardinalityLimits {
+		return 0
+	}
+	return max
+}
+
+func (c *endpointCardinality) violation(limit string, max int, cluster, key string,
+	count int) *model.CardinalityViolation {
+	namespace, hostname := splitServiceKey(key)
+	return &model.CardinalityViolation{
+		Limit: limit, Max: max, Cluster: cluster, Namespace: namespace, Hostname: hostname, Count: count,
+	}
+}
+
+// setViolation records the violation of the service keeping n endpoints, or clears it if v is nil.
+func (c *endpointCardinality) setViolation(cluster, key string, v *model.CardinalityViolation, n int) {
+	if v == nil {
+		delete(c.violations, cluster+"/"+key)
+	} else {
+		v.Kept = n
+		if prev, f := c.violations[v.Key()]; !f || prev != *v {
+			adsLog.Warnf("endpoint cardinality limit exceeded: %v", v)
+		}
+		c.violations[v.Key()] = *v
+	}
+	c.setKept(cluster, key, n)
+}
+
+// remove forgets the endpoints of the service in the cluster, once the service is deleted. The other services of
+// the cluster whose endpoints kept changed are returned.
+func (c *endpointCardinality) remove(cluster, hostname, namespace string) []shardUpdate {
+	if c == nil {
+		return nil
+	}
+	_, _, updates := c.limit(cluster, hostname, namespace, nil)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.violations, cluster+"/"+namespace+"/"+hostname)
+	return updates
+}
+
+func (c *endpointCardinality) setRequested(cluster, key string, endpoints []*model.IstioEndpoint) {
+	if len(endpoints) == 0 {
+		delete(c.requested[cluster], key)
+		if len(c.requested[cluster]) == 0 {
+			delete(c.requested, cluster)
+		}
+		return
+	}
+	if c.requested[cluster] == nil {
+		c.requested[cluster] = map[string][]*model.IstioEndpoint{}
+	}
+	c.requested[cluster][key] = endpoints
+}
+
+func (c *endpointCardinality) setKept(cluster, key string, n int) {
+	if n == 0 {
+		delete(c.kept[cluster], key)
+		if len(c.kept[cluster]) == 0 {
+			delete(c.kept, cluster)
+		}
+		return
+	}
+	if c.kept[cluster] == nil {
+		c.kept[cluster] = map[string]int{}
+	}
+	c.kept[cluster][key] = n
+}
+
+// splitServiceKey returns the namespace and hostname of a namespace/hostname key.
+func splitServiceKey(key string) (string, string) {
+	parts := strings.SplitN(key, "/", 2)
+	return parts[0], parts[1]
+}
+
+// list returns the services exceeding an endpoint limit, sorted by key.
+func (c *endpointCardinality) list() []model.CardinalityViolation {
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	out := make([]model.CardinalityViolation, 0, len(c.violations))
+	for _, v := range c.violations {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key() < out[j].Key()
+	})
+	return out
+}
+
+// recordMetrics adds the violations to the push status.
+func (c *endpointCardinality) recordMetrics(push *model.PushContext) {
+	for _, v := range c.list() {
+		push.AddMetric(model.EndpointCardinalityLimitExceeded, v.Key(), "", v.String())
+	}
+}
+
+// CardinalityLimits are the cardinality limits in effect, 0 if disabled.
+type CardinalityLimits struct {
+	MaxServicesPerNamespace int  `json:"maxServicesPerNamespace"`
+	MaxEndpointsPerService  int  `json:"maxEndpointsPerService"`
+	MaxEndpointsPerShard    int  `json:"maxEndpointsPerShard"`
+	Strict                  bool `json:"strict"`
+}
+
+// CardinalityReport is the response of /debug/cardinalityz.
+type CardinalityReport struct {
+	Limits CardinalityLimits `json:"limits"`
+	// Services are the services ignored because their namespace has too many services.
+	Services []model.CardinalityViolation `json:"services"`
+	// Endpoints are the services whose endpoints in a cluster were truncated or ignored.
+	Endpoints []model.CardinalityViolation `json:"endpoints"`
+}
+
+// cardinalityz lists the services and endpoints ignored because they exceed the cardinality limits.
+func (s *DiscoveryServer) cardinalityz(w http.ResponseWriter, req *http.Request) {
+	report := CardinalityReport{
+		Limits: CardinalityLimits{
+			MaxServicesPerNamespace: features.MaxServicesPerNamespace,
+			MaxEndpointsPerService:  features.MaxEndpointsPerService,
+			MaxEndpointsPerShard:    features.MaxEndpointsPerShard,
+			Strict:                  features.StrictCardinalityLimits,
+		},
+		Services:  s.globalPushContext().ServiceCardinalityViolations(),
+		Endpoints: s.endpointCardinality.list(),
+	}
+	if report.Services == nil {
+		report.Services = []model.CardinalityViolation{}
+	}
+	if report.Endpoints == nil {
+		report.Endpoints = []model.CardinalityViolation{}
+	}
+	writeDebugJSON(w, req, report)
+}
@@ -0,0 +1,215 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func setCardinalityLimits(t *testing.T, perService, perShard int, strict bool) {
+	t.Helper()
+	oldPerService, oldPerShard, oldStrict := features.MaxEndpointsPerService, features.MaxEndpointsPerShard,
+		features.StrictCardinalityLimits
+	features.MaxEndpointsPerService, features.MaxEndpointsPerShard, features.StrictCardinalityLimits = perService, perShard, strict
+	t.Cleanup(func() {
+		features.MaxEndpointsPerService, features.MaxEndpointsPerShard, features.StrictCardinalityLimits =
+			oldPerService, oldPerShard, oldStrict
+	})
+}
+
+func reversed(eps []*model.IstioEndpoint) []*model.IstioEndpoint {
+	out := make([]*model.IstioEndpoint, 0, len(eps))
+	for i := len(eps) - 1; i >= 0; i-- {
+		out = append(out, eps[i])
+	}
+	return out
+}
+
+func shardAddresses(s *DiscoveryServer, cluster, hostname, namespace string) []string {
+	var out []string
+	if ep, f := s.EndpointShardsByService[hostname][namespace]; f {
+		for _, e := range ep.Shards[cluster] {
+			out = append(out, e.Address)
+		}
+	}
+	return out
+}
+
+func TestEndpointsPerServiceLimit(t *testing.T) {
+	setCardinalityLimits(t, 3, 0, false)
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+
+	eps := makeEndpoints(10)
+	s.edsCacheUpdate("cluster", "a.com", "ns", eps)
+	kept := shardAddresses(s, "cluster", "a.com", "ns")
+	if want := []string{"10.0.0.0", "10.0.0.1", "10.0.0.2"}; !reflect.DeepEqual(kept, want) {
+		t.Fatalf("got endpoints %v, want %v", kept, want)
+	}
+	// The same endpoints are kept whatever their order.
+	s.edsCacheUpdate("cluster", "a.com", "ns", reversed(eps))
+	if got := shardAddresses(s, "cluster", "a.com", "ns"); !reflect.DeepEqual(got, kept) {
+		t.Fatalf("got endpoints %v, want %v", got, kept)
+	}
+	if len(eps) != 10 || eps[0].Address != "10.0.0.0" {
+		t.Fatalf("the endpoints of the registry were modified")
+	}
+
+	want := []model.CardinalityViolation{{
+		Limit: model.EndpointsPerServiceLimit, Max: 3, Cluster: "cluster", Namespace: "ns", Hostname: "a.com", Count: 10, Kept: 3,
+	}}
+	if got := s.endpointCardinality.list(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got violations %+v, want %+v", got, want)
+	}
+	status := s.globalPushContext().ProxyStatus[model.EndpointCardinalityLimitExceeded.Name()]
+	if _, f := status["cluster/ns/a.com"]; !f {
+		t.Fatalf("expected the service in the push status, got %v", status)
+	}
+
+	// Within the limit, the violation is cleared.
+	s.edsCacheUpdate("cluster", "a.com", "ns", makeEndpoints(2))
+	if got := s.endpointCardinality.list(); len(got) != 0 {
+		t.Fatalf("expected no violation, got %+v", got)
+	}
+}
+
+func TestEndpointsPerShardLimit(t *testing.T) {
+	setCardinalityLimits(t, 0, 5, false)
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+
+	s.edsCacheUpdate("cluster", "a.com", "ns", makeEndpoints(3))
+	s.edsCacheUpdate("other", "b.com", "ns", makeEndpoints(4))
+	s.edsCacheUpdate("cluster", "b.com", "ns", makeEndpoints(4))
+	if got := len(shardAddresses(s, "cluster", "a.com", "ns")); got != 3 {
+		t.Fatalf("expected the endpoints of a.com to be kept, got %d", got)
+	}
+	if got := len(shardAddresses(s, "other", "b.com", "ns")); got != 4 {
+		t.Fatalf("expected the endpoints of b.com in the other cluster to be kept, got %d", got)
+	}
+	if got := shardAddresses(s, "cluster", "b.com", "ns"); !reflect.DeepEqual(got, []string{"10.0.0.0", "10.0.0.1"}) {
+		t.Fatalf("expected the endpoints of b.com to be truncated, got %v", got)
+	}
+	want := []model.CardinalityViolation{{
+		Limit: model.EndpointsPerShardLimit, Max: 5, Cluster: "cluster", Namespace: "ns", Hostname: "b.com", Count: 4, Kept: 2,
+	}}
+	if got := s.endpointCardinality.list(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got violations %+v, want %+v", got, want)
+	}
+
+	// Once the endpoints of a.com are deleted, those of b.com fit in the cluster.
+	s.edsCacheUpdate("cluster", "a.com", "ns", nil)
+	s.edsCacheUpdate("cluster", "b.com", "ns", makeEndpoints(4))
+	if got := len(shardAddresses(s, "cluster", "b.com", "ns")); got != 4 {
+		t.Fatalf("expected the endpoints of b.com to be kept, got %d", got)
+	}
+	if got := s.endpointCardinality.list(); len(got) != 0 {
+		t.Fatalf("expected no violation, got %+v", got)
+	}
+}
+
+func TestStrictCardinalityLimits(t *testing.T) {
+	setCardinalityLimits(t, 3, 0, true)
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+
+	s.edsCacheUpdate("cluster", "a.com", "ns", makeEndpoints(2))
+	s.edsCacheUpdate("cluster", "b.com", "ns", makeEndpoints(10))
+	if got := len(shardAddresses(s, "cluster", "a.com", "ns")); got != 2 {
+		t.Fatalf("expected the endpoints of a.com to be kept, got %d", got)
+	}
+	if got := shardAddresses(s, "cluster", "b.com", "ns"); len(got) != 0 {
+		t.Fatalf("expected the endpoints of b.com to be ignored, got %v", got)
+	}
+	if got := s.endpointCardinality.list(); len(got) != 1 || got[0].Kept != 0 || got[0].Count != 10 {
+		t.Fatalf("unexpected violations %+v", got)
+	}
+}
+
+func TestEndpointsPerShardLimitOrder(t *testing.T) {
+	setCardinalityLimits(t, 0, 5, false)
+	// The endpoints kept are the same whatever the order of the updates.
+	for _, order := range [][]string{{"a.com", "b.com"}, {"b.com", "a.com"}} {
+		s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+		for _, hostname := range order {
+			s.edsCacheUpdate("cluster", hostname, "ns", makeEndpoints(4))
+		}
+		if got := len(shardAddresses(s, "cluster", "a.com", "ns")); got != 4 {
+			t.Fatalf("%v: expected the endpoints of a.com to be kept, got %d", order, got)
+		}
+		if got := shardAddresses(s, "cluster", "b.com", "ns"); !reflect.DeepEqual(got, []string{"10.0.0.0"}) {
+			t.Fatalf("%v: expected the endpoints of b.com to be truncated, got %v", order, got)
+		}
+		want := []model.CardinalityViolation{{
+			Limit: model.EndpointsPerShardLimit, Max: 5, Cluster: "cluster", Namespace: "ns", Hostname: "b.com", Count: 4, Kept: 1,
+		}}
+		if got := s.endpointCardinality.list(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: got violations %+v, want %+v", order, got, want)
+		}
+	}
+}
+
+func TestStrictEndpointsPerShardLimit(t *testing.T) {
+	setCardinalityLimits(t, 0, 5, true)
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+
+	s.edsCacheUpdate("cluster", "a.com", "ns", makeEndpoints(3))
+	s.edsCacheUpdate("cluster", "b.com", "ns", makeEndpoints(4))
+	if got := shardAddresses(s, "cluster", "b.com", "ns"); len(got) != 0 {
+		t.Fatalf("expected the endpoints of b.com to be ignored, got %v", got)
+	}
+	// The violation is kept although the shard of b.com is emptied.
+	want := []model.CardinalityViolation{{
+		Limit: model.EndpointsPerShardLimit, Max: 5, Cluster: "cluster", Namespace: "ns", Hostname: "b.com", Count: 4, Kept: 0,
+	}}
+	if got := s.endpointCardinality.list(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got violations %+v, want %+v", got, want)
+	}
+
+	// Once the endpoints of a.com are deleted, those of b.com are kept without another update of b.com.
+	s.edsCacheUpdate("cluster", "a.com", "ns", nil)
+	if got := len(shardAddresses(s, "cluster", "b.com", "ns")); got != 4 {
+		t.Fatalf("expected the endpoints of b.com to be kept, got %d", got)
+	}
+	if got := s.endpointCardinality.list(); len(got) != 0 {
+		t.Fatalf("expected no violation, got %+v", got)
+	}
+}
+
+func TestCardinalityz(t *testing.T) {
+	setCardinalityLimits(t, 1, 0, false)
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+	s.edsCacheUpdate("cluster", "a.com", "ns", makeEndpoints(2))
+
+	rr := httptest.NewRecorder()
+	s.cardinalityz(rr, httptest.NewRequest("GET", "/debug/cardinalityz", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+	report := CardinalityReport{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Limits.MaxEndpointsPerService != 1 {
+		t.Errorf("got limits %+v", report.Limits)
+	}
+	if len(report.Services) != 0 || len(report.Endpoints) != 1 || report.Endpoints[0].Hostname != "a.com" {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
@@ -15,6 +15,7 @@
 package xds
 
 import (
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config"
@@ -52,6 +53,10 @@ func cdsNeedsPush(req *model.PushRequest, proxy *model.Proxy) bool {
 		// CDS only handles full push
 		return false
 	}
+	if !req.MeshConfigImpact.Affects(model.MeshImpactClusters) {
+		// The mesh config change does not affect clusters
+		return false
+	}
 	// If none set, we will always push
 	if len(req.ConfigsUpdated) == 0 {
 		return true
@@ -70,14 +75,34 @@ func cdsNeedsPush(req *model.PushRequest, proxy *model.Proxy) bool {
 	return false
 }
 
+var _ model.XdsDiagnosticResourceGenerator = CdsGenerator{}
+
 func (c CdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource, req *model.PushRequest) model.Resources {
+	return c.GenerateWithContext(nil, proxy, push, w, req)
+}
+
+func (c CdsGenerator) GenerateWithContext(ctx *model.GenerationContext, proxy *model.Proxy, push *model.PushContext,
+	w *model.WatchedResource, req *model.PushRequest) model.Resources {
+	resources, _ := c.GenerateWithDiagnostics(ctx, proxy, push, w, req)
+	return resources
+}
+
+func (c CdsGenerator) GenerateWithDiagnostics(ctx *model.GenerationContext, proxy *model.Proxy, push *model.PushContext,
+	w *model.WatchedResource, req *model.PushRequest) (model.Resources, model.GenerationDiagnostics) {
 	if !cdsNeedsPush(req, proxy) {
-		return nil
+		return nil, nil
+	}
+	rawClusters := c.Server.ConfigGenerator.BuildClusters(proxy, push, ctx)
+	// The resources skipped by the builders are always reported, the validation of the built ones is opt-in.
+	diags := ctx.Diagnostics()
+	if features.ValidateGeneratedResources {
+		var invalid model.GenerationDiagnostics
+		rawClusters, invalid = validateClusters(rawClusters)
+		diags = append(diags, invalid...)
 	}
-	rawClusters := c.Server.ConfigGenerator.BuildClusters(proxy, push)
 	resources := model.Resources{}
 	for _, c := range rawClusters {
 		resources = append(resources, util.MessageToAny(c))
 	}
-	return resources
+	return resources, diags
 }
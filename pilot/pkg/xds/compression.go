@@ -0,0 +1,125 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// compressorName is the grpc-encoding of the compressor, the same as the gzip compressor of gRPC.
+const compressorName = "gzip"
+
+var registerCompressorOnce sync.Once
+
+// RegisterCompressor registers the gzip compressor of istiod, compressing the messages of at least threshold bytes.
+// gRPC compresses the responses of a stream with the encoding of its requests, so only the clients compressing
+// their requests with gzip, such as the agents with ISTIO_XDS_COMPRESSION set, get gzip responses; Envoy and the
+// other clients, which don't, get uncompressed responses. It must be called before the gRPC servers are started.
+func RegisterCompressor(threshold int) {
+	registerCompressorOnce.Do(func() {
+		encoding.RegisterCompressor(newThresholdCompressor(threshold))
+	})
+}
+
+// thresholdCompressor is a gzip encoding.Compressor which only compresses the messages of at least threshold bytes.
+// The smaller messages are stored in the gzip stream without compression, which any gzip decompressor reads, to
+// save the CPU of their compression.
+type thresholdCompressor struct {
+	threshold int
+	// writers pools the gzip writers, by compression level.
+	writers map[int]*sync.Pool
+}
+
+var _ encoding.Compressor = &thresholdCompressor{}
+
+func newThresholdCompressor(threshold int) *thresholdCompressor {
+	c := &thresholdCompressor{threshold: threshold, writers: map[int]*sync.Pool{}}
+	for _, level := range []int{gzip.NoCompression, gzip.DefaultCompression} {
+		level := level
+		c.writers[level] = &sync.Pool{New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, level)
+			return w
+		}}
+	}
+	return c
+}
+
+func (c *thresholdCompressor) Name() string {
+	return compressorName
+}
+
+// Compress returns a writer buffering the message, which is written to w when the writer is closed, once its size
+// is known.
+func (c *thresholdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return &thresholdWriter{c: c, w: w}, nil
+}
+
+func (c *thresholdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+type thresholdWriter struct {
+	c   *thresholdCompressor
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (t *thresholdWriter) Write(p []byte) (int, error) {
+	return t.buf.Write(p)
+}
+
+func (t *thresholdWriter) Close() error {
+	size := t.buf.Len()
+	compressed := size >= t.c.threshold
+	level := gzip.NoCompression
+	if compressed {
+		level = gzip.DefaultCompression
+	}
+	start := time.Now()
+	cw := &countingWriter{w: t.w}
+	pool := t.c.writers[level]
+	gz := pool.Get().(*gzip.Writer)
+	defer pool.Put(gz)
+	gz.Reset(cw)
+	if _, err := gz.Write(t.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if compressed {
+		xdsCompressedResponses.Increment()
+		xdsCompressionTime.Record(time.Since(start).Seconds())
+		xdsCompressionBytesSaved.Record(float64(size - cw.n))
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/proto"
+	"go.opencensus.io/stats/view"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func sumMetric(t *testing.T, name string) float64 {
+	t.Helper()
+	rows, err := view.RetrieveData(name)
+	if err != nil {
+		return 0
+	}
+	total := 0.0
+	for _, row := range rows {
+		switch data := row.Data.(type) {
+		case *view.SumData:
+			total += data.Value
+		case *view.DistributionData:
+			total += float64(data.Count)
+		}
+	}
+	return total
+}
+
+func TestXdsCompression(t *testing.T) {
+	if prev := encoding.GetCompressor(compressorName); prev != nil {
+		defer encoding.RegisterCompressor(prev)
+	}
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	connect := func(compress bool) discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient {
+		opts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithBlock(), grpc.WithContextDialer(
+			func(context.Context, string) (net.Conn, error) {
+				return s.listener.Dial()
+			})}
+		if compress {
+			opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(compressorName)))
+		}
+		conn, err := grpc.Dial("buffcon", opts...)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		client, err := discovery.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+	req := &discovery.DiscoveryRequest{
+		Node:    &core.Node{Id: "sidecar~1.1.1.1~app.default~default.svc.cluster.local"},
+		TypeUrl: v3.ClusterType,
+	}
+	// The CDS response is larger than the threshold, the request smaller.
+	const threshold = 1024
+
+	cases := []struct {
+		name       string
+		threshold  int
+		compress   bool
+		compressed bool
+	}{
+		{"large response", threshold, true, true},
+		{"small response", 1 << 30, true, false},
+		{"client without compression", threshold, false, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// The in process client shares the compressor of the server.
+			encoding.RegisterCompressor(newThresholdCompressor(tt.threshold))
+			compressedBefore := sumMetric(t, "pilot_xds_compressed_responses")
+			savedBefore := sumMetric(t, "pilot_xds_compression_bytes_saved")
+
+			client := connect(tt.compress)
+			if err := client.Send(req); err != nil {
+				t.Fatal(err)
+			}
+			res, err := client.Recv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.TypeUrl != v3.ClusterType || len(res.Resources) == 0 {
+				t.Fatalf("unexpected response %v", res)
+			}
+			if size := proto.Size(res); size < threshold {
+				t.Fatalf("the response of %d bytes is smaller than the threshold", size)
+			}
+
+			compressed := sumMetric(t, "pilot_xds_compressed_responses") - compressedBefore
+			saved := sumMetric(t, "pilot_xds_compression_bytes_saved") - savedBefore
+			if tt.compressed && (compressed != 1 || saved <= 0) {
+				t.Errorf("expected the response to be compressed, got %v compressed messages saving %v bytes", compressed, saved)
+			}
+			if !tt.compressed && compressed != 0 {
+				t.Errorf("expected no compressed message, got %v", compressed)
+			}
+		})
+	}
+}
@@ -15,12 +15,16 @@
 package xds
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"net/http"
 	"net/http/pprof"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -92,6 +96,15 @@ type AdsClient struct {
 	ConnectionID string    `json:"connectionId"`
 	ConnectedAt  time.Time `json:"connectedAt"`
 	PeerAddress  string    `json:"address"`
+	// BytesSent is the serialized size of the resources sent since the connection was established, by type.
+	BytesSent map[string]int64 `json:"bytesSent,omitempty"`
+	// GenerationErrors is the number of resources skipped as invalid since the connection was established, by
+	// category.
+	GenerationErrors map[string]int64 `json:"generationErrors,omitempty"`
+	// Diagnostics holds the resources skipped as invalid by the last generation of each type, by type.
+	Diagnostics map[string]model.GenerationDiagnostics `json:"diagnostics,omitempty"`
+	// DeferredPush describes the pushes deferred during the startup stabilization window of the connection.
+	DeferredPush *DeferredPush `json:"deferredPush,omitempty"`
 }
 
 // AdsClients is collection of AdsClient connected to this Istiod.
@@ -99,6 +112,33 @@ type AdsClients struct {
 	Connected []AdsClient `json:"clients"`
 }
 
+// QuarantinedClient describes a connected proxy whose identity did not match the proxy it claims to be.
+type QuarantinedClient struct {
+	ConnectionID string   `json:"connectionId"`
+	ProxyID      string   `json:"proxy"`
+	PeerAddress  string   `json:"address"`
+	Identities   []string `json:"identities"`
+	Reason       string   `json:"reason"`
+}
+
+// ClientCert describes the client certificate presented by a connected proxy.
+type ClientCert struct {
+	ConnectionID string `json:"connectionId"`
+	ProxyID      string `json:"proxy"`
+	// Expiry is unset if the proxy did not present a client certificate, e.g. when authenticated with a JWT only.
+	Expiry *time.Time `json:"expiry,omitempty"`
+	// TimeToExpiry is negative for expired certificates, and "no cert" if there is no certificate.
+	TimeToExpiry string `json:"timeToExpiry"`
+}
+
+// TrustedRoot describes a root certificate trusted to verify the client certificates of a trust domain.
+type TrustedRoot struct {
+	Subject string `json:"subject"`
+	// Fingerprint is the SHA-256 fingerprint of the certificate, in hex.
+	Fingerprint string    `json:"fingerprint"`
+	Expiry      time.Time `json:"expiry"`
+}
+
 // SyncStatus is the synchronization status between Pilot and a given Envoy
 type SyncStatus struct {
 	ProxyID       string `json:"proxy,omitempty"`
@@ -153,33 +193,59 @@ func (s *DiscoveryServer) AddDebugHandlers(mux *http.ServeMux, enableProfiling b
 		s.addDebugHandler(mux, "/debug/pprof/trace", "A trace of execution of the current program.", pprof.Trace)
 	}
 
-	mux.HandleFunc("/debug", s.Debug)
+	s.addDebugHandler(mux, "/debug", "Lists the debug handlers", s.Debug)
 
 	s.addDebugHandler(mux, "/debug/edsz", "Status and debug interface for EDS", s.Edsz)
-	s.addDebugHandler(mux, "/debug/adsz", "Status and debug interface for ADS", s.adsz)
+	s.addVersionedDebugHandler(mux, "/debug/index", "Debug handlers and the schema versions of their responses, "+
+		"?pretty=false for compact JSON", debugSchemaV1, s.debugIndex)
+	s.addVersionedDebugHandler(mux, "/debug/adsz", "Status and debug interface for ADS", debugSchemaV1, s.adsz)
 	s.addDebugHandler(mux, "/debug/adsz?push=true", "Initiates push of the current state to all connected endpoints", s.adsz)
-
+	s.addDebugHandler(mux, "/debug/force_push", "POST with ?namespace= or ?proxy= to force a full push to a namespace or a proxy", s.forcePush)
+
+	s.addDebugHandler(mux, "/debug/quarantinez", "Connected proxies whose identity did not match their claimed identity", s.quarantinez)
+	s.addDebugHandler(mux, "/debug/config_quarantinez",
+		"Configs quarantined after being rejected by proxies, POST with ?kind=&name=&namespace= to release a config", s.configQuarantinez)
+	s.addDebugHandler(mux, "/debug/rollout_status", "Distribution of the last change of the ?kind=&name=&namespace= config "+
+		"to the proxies, streamed until all the proxies it was pushed to ACKed it or ?timeout=", s.rolloutStatus)
+	s.addDebugHandler(mux, "/debug/nackz", "Last rejected config of each proxy and type, ?proxyID= to filter on a proxy", s.nackz)
+	s.addDebugHandler(mux, "/debug/loadz", "Load stats reported by proxies, by cluster, ?proxyID= to filter on a proxy", s.loadz)
+	s.addDebugHandler(mux, "/debug/certz", "Client certificate expiry of all connected proxies, grouped by namespace", s.certz)
+	s.addDebugHandler(mux, "/debug/trustdomainz", "Trusted trust domains and the fingerprints of their root certificates", s.trustdomainz)
+	s.addDebugHandler(mux, "/debug/inventoryz", "Inventory of the connected proxies, ?namespace= to filter on a namespace, "+
+		"?limit= and ?after= the last connection ID to paginate", s.inventoryz)
 	s.addDebugHandler(mux, "/debug/syncz", "Synchronization status of all Envoys connected to this Pilot instance", s.Syncz)
+	s.addDebugHandler(mux, "/debug/mesh", "Effective mesh config and networks, and the source of selected mesh config fields", s.meshz)
+	s.addDebugHandler(mux, "/debug/proxyconfigz", "Effective proxy config of the ?proxy= or the ?namespace= and ?labels= of a workload, "+
+		"and the fields only applied once the proxy restarts", s.proxyconfigz)
 	s.addDebugHandler(mux, "/debug/config_distribution", "Version status of all Envoys connected to this Pilot instance", s.distributedVersions)
 
-	s.addDebugHandler(mux, "/debug/registryz", "Debug support for registry", s.registryz)
+	s.addVersionedDebugHandler(mux, "/debug/registryz", "Debug support for registry", debugSchemaV1, s.registryz)
+	s.addDebugHandler(mux, "/debug/registryhealthz", "Sync state and staleness of the service registry of each cluster", s.registryhealthz)
 	s.addDebugHandler(mux, "/debug/endpointz", "Debug support for endpoints", s.endpointz)
 	s.addDebugHandler(mux, "/debug/endpointShardz", "Info about the endpoint shards", s.endpointShardz)
+	s.addVersionedDebugHandler(mux, "/debug/cardinalityz", "Services and endpoints ignored because they exceed the "+
+		"cardinality limits, and the limits in effect", debugSchemaV1, s.cardinalityz)
 	s.addDebugHandler(mux, "/debug/cachez", "Info about the internal XDS caches", s.cachez)
-	s.addDebugHandler(mux, "/debug/configz", "Debug support for config", s.configz)
+	s.addVersionedDebugHandler(mux, "/debug/configz", "Debug support for config", debugSchemaV1, s.configz)
+	s.addDebugHandler(mux, "/debug/bundle", "tar.gz of the configs and services visible to the ?namespace=, and the mesh config, "+
+		"?kind= to filter on kinds, ?start=&count= to paginate", s.bundle)
+	s.addDebugHandler(mux, "/debug/configz/diff", "Diff of the config generated for the passed in proxy1 and proxy2", s.configDiff)
 	s.addDebugHandler(mux, "/debug/resourcesz", "Debug support for watched resources", s.resourcez)
 	s.addDebugHandler(mux, "/debug/instancesz", "Debug support for service instances", s.instancesz)
+	s.addDebugHandler(mux, "/debug/sidecarz", "Sidecar scope of the ?proxy= or the ?namespace= and ?labels= of a workload, "+
+		"?host= explains whether a host is imported", s.sidecarz)
+	s.addDebugHandler(mux, "/debug/destrulez", "Destination rules merged for the ?host=, and the fields ignored because of conflicts", s.destrulez)
 
 	s.addDebugHandler(mux, "/debug/authorizationz", "Internal authorization policies", s.Authorizationz)
 	s.addDebugHandler(mux, "/debug/config_dump", "ConfigDump in the form of the Envoy admin config dump API for passed in proxyID", s.ConfigDump)
-	s.addDebugHandler(mux, "/debug/push_status", "Last PushContext Details", s.PushStatusHandler)
+	s.addVersionedDebugHandler(mux, "/debug/push_status", "Last PushContext Details", debugSchemaV1, s.PushStatusHandler)
 
 	s.addDebugHandler(mux, "/debug/inject", "Active inject template", s.InjectTemplateHandler(webhook))
 }
 
 func (s *DiscoveryServer) addDebugHandler(mux *http.ServeMux, path string, help string,
 	handler func(http.ResponseWriter, *http.Request)) {
-	s.debugHandlers[path] = help
+	s.debugHandlers[path] = debugHandler{help: help}
 	mux.HandleFunc(path, handler)
 }
 
@@ -215,26 +281,206 @@ func (s *DiscoveryServer) Syncz(w http.ResponseWriter, _ *http.Request) {
 	_, _ = w.Write(out)
 }
 
-// registryz providees debug support for registry - adding and listing model items.
-// Can be combined with the push debug interface to reproduce changes.
-func (s *DiscoveryServer) registryz(w http.ResponseWriter, req *http.Request) {
-	_ = req.ParseForm()
+// quarantinez lists the proxies accepted despite an identity mismatch, when PILOT_XDS_IDENTITY_CHECK_MODE is PERMISSIVE.
+func (s *DiscoveryServer) quarantinez(w http.ResponseWriter, _ *http.Request) {
+	quarantined := make([]QuarantinedClient, 0)
+	s.adsClientsMutex.RLock()
+	for _, con := range s.adsClients {
+		if con.quarantineReason == "" {
+			continue
+		}
+		quarantined = append(quarantined, QuarantinedClient{
+			ConnectionID: con.ConID,
+			ProxyID:      con.proxy.ID,
+			PeerAddress:  con.PeerAddr,
+			Identities:   con.Identities,
+			Reason:       con.quarantineReason,
+		})
+	}
+	s.adsClientsMutex.RUnlock()
+	sort.Slice(quarantined, func(i, j int) bool {
+		return quarantined[i].ConnectionID < quarantined[j].ConnectionID
+	})
+	out, err := json.MarshalIndent(&quarantined, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal quarantinez information: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// configQuarantinez lists the configs quarantined when PILOT_NACK_QUARANTINE_THRESHOLD is set, or releases one.
+func (s *DiscoveryServer) configQuarantinez(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
+		q := req.URL.Query()
+		kind, name, namespace := q.Get("kind"), q.Get("name"), q.Get("namespace")
+		if kind == "" || name == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("You must provide a kind and a name in the query string"))
+			return
+		}
+		if !s.ReleaseQuarantinedConfig(kind, name, namespace) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Config not quarantined"))
+			return
+		}
+	}
+	out, err := json.MarshalIndent(s.ConfigQuarantine.List(), "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal config quarantine: %v", err)
+		return
+	}
 	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
 
-	all, err := s.Env.ServiceDiscovery.Services()
+// nackz lists the last NACK of each connected proxy and type. Superseded NACKs are retained for PILOT_XDS_NACK_RETENTION.
+func (s *DiscoveryServer) nackz(w http.ResponseWriter, req *http.Request) {
+	nacks := make([]NackEvent, 0)
+	if s.InternalGen != nil {
+		nacks = s.InternalGen.Nacks(req.URL.Query().Get("proxyID"))
+	}
+	out, err := json.MarshalIndent(&nacks, "", "    ")
 	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal nackz information: %v", err)
 		return
 	}
-	_, _ = fmt.Fprintln(w, "[")
-	for _, svc := range all {
-		b, err := json.MarshalIndent(svc, "", "  ")
-		if err != nil {
-			return
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// loadz lists the load reported by each proxy over LRS, by cluster.
+func (s *DiscoveryServer) loadz(w http.ResponseWriter, req *http.Request) {
+	loads := map[string]map[string]ClusterLoad{}
+	if s.LoadReporting != nil {
+		loads = s.LoadReporting.Loads(req.URL.Query().Get("proxyID"))
+	}
+	out, err := json.MarshalIndent(&loads, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal loadz information: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// certz lists the client certificate expiry of all connected proxies, grouped by namespace and sorted by expiry,
+// proxies without a client certificate last.
+func (s *DiscoveryServer) certz(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
+	certs := map[string][]ClientCert{}
+	s.adsClientsMutex.RLock()
+	for _, con := range s.adsClients {
+		cert := ClientCert{
+			ConnectionID: con.ConID,
+			ProxyID:      con.proxy.ID,
+			TimeToExpiry: "no cert",
 		}
-		_, _ = w.Write(b)
-		_, _ = fmt.Fprintln(w, ",")
+		if !con.CertExpiry.IsZero() {
+			expiry := con.CertExpiry
+			cert.Expiry = &expiry
+			cert.TimeToExpiry = expiry.Sub(now).Round(time.Second).String()
+		}
+		ns := con.proxy.ConfigNamespace
+		certs[ns] = append(certs[ns], cert)
 	}
-	_, _ = fmt.Fprintln(w, "{}]")
+	s.adsClientsMutex.RUnlock()
+	for _, nsCerts := range certs {
+		sort.Slice(nsCerts, func(i, j int) bool {
+			ei, ej := nsCerts[i].Expiry, nsCerts[j].Expiry
+			if (ei == nil) != (ej == nil) {
+				return ej == nil
+			}
+			if ei != nil && !ei.Equal(*ej) {
+				return ei.Before(*ej)
+			}
+			return nsCerts[i].ConnectionID < nsCerts[j].ConnectionID
+		})
+	}
+	out, err := json.MarshalIndent(&certs, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal certz information: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// trustdomainz lists the trust domains whose client certificates are trusted, and their root certificates.
+func (s *DiscoveryServer) trustdomainz(w http.ResponseWriter, _ *http.Request) {
+	roots := map[string][]TrustedRoot{}
+	if s.PeerCertVerifier != nil {
+		for trustDomain, certs := range s.PeerCertVerifier.TrustDomainCerts() {
+			roots[trustDomain] = make([]TrustedRoot, 0, len(certs))
+			for _, cert := range certs {
+				fingerprint := sha256.Sum256(cert.Raw)
+				roots[trustDomain] = append(roots[trustDomain], TrustedRoot{
+					Subject:     cert.Subject.String(),
+					Fingerprint: hex.EncodeToString(fingerprint[:]),
+					Expiry:      cert.NotAfter,
+				})
+			}
+		}
+	}
+	out, err := json.MarshalIndent(&roots, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal trustdomainz information: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// registryhealthz shows the sync state of the service registries, by cluster.
+func (s *DiscoveryServer) registryhealthz(w http.ResponseWriter, _ *http.Request) {
+	health := []aggregate.RegistryHealth{}
+	if agg, ok := s.Env.ServiceDiscovery.(*aggregate.Controller); ok {
+		health = agg.RegistryHealth()
+	}
+	out, err := json.MarshalIndent(health, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal registryhealthz information: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// registryz providees debug support for registry - adding and listing model items.
+// Can be combined with the push debug interface to reproduce changes.
+// The services can be paginated with ?start=<n>&count=<n>, and filtered with ?namespace= and ?host=. Unpaginated
+// responses follow RegistryzV1.
+func (s *DiscoveryServer) registryz(w http.ResponseWriter, req *http.Request) {
+	page, err := parseDebugPageRequest(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintln(w, err)
+		return
+	}
+	all, err := s.debugServices(page)
+	if err != nil {
+		return
+	}
+	if !page.checkUnpagedLimit(w, len(all)) {
+		return
+	}
+	if page.paged {
+		start, end := page.bounds(len(all))
+		writeDebugPage(w, start, end, len(all), func(i int) interface{} {
+			return all[i]
+		})
+		return
+	}
+
+	writeDebugJSON(w, req, RegistryzV1(all))
 }
 
 // Dumps info about the endpoint shards, tracked using the new direct interface.
@@ -261,42 +507,106 @@ func (s *DiscoveryServer) cachez(w http.ResponseWriter, req *http.Request) {
 	_, _ = w.Write(bytes)
 }
 
-// Endpoint debugging
+// EndpointzEntry holds the endpoints of a port of a service.
+type EndpointzEntry struct {
+	Svc string                   `json:"svc"`
+	Ep  []*model.ServiceInstance `json:"ep"`
+}
+
+// Endpoint debugging. The endpoints are listed per service port, which can be paginated with
+// ?start=<n>&count=<n>, and filtered with ?namespace= and ?host=. A page also ends once it holds debugUnpagedLimit
+// endpoints, the next page then starts after its last item. The entries are written as their endpoints are looked
+// up, so that only the endpoints of a single service port are held at a time.
 func (s *DiscoveryServer) endpointz(w http.ResponseWriter, req *http.Request) {
-	_ = req.ParseForm()
-	w.Header().Add("Content-Type", "application/json")
-	brief := req.Form.Get("brief")
-	if brief != "" {
-		svc, _ := s.Env.ServiceDiscovery.Services()
-		for _, ss := range svc {
-			for _, p := range ss.Ports {
-				all := s.Env.ServiceDiscovery.InstancesByPort(ss, p.Port, nil)
-				for _, svc := range all {
-					_, _ = fmt.Fprintf(w, "%s:%s %s:%d %v %s\n", ss.Hostname,
-						p.Name, svc.Endpoint.Address, svc.Endpoint.EndpointPort, svc.Endpoint.Labels,
-						svc.Endpoint.ServiceAccount)
-				}
+	page, err := parseDebugPageRequest(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintln(w, err)
+		return
+	}
+	svc, _ := s.debugServices(page)
+	type servicePort struct {
+		svc  *model.Service
+		port *model.Port
+	}
+	var ports []servicePort
+	for _, ss := range svc {
+		for _, p := range ss.Ports {
+			ports = append(ports, servicePort{ss, p})
+		}
+	}
+	total := len(ports)
+	start, end := page.bounds(total)
+	entry := func(i int) EndpointzEntry {
+		sp := ports[i]
+		return EndpointzEntry{
+			Svc: fmt.Sprintf("%s:%s", sp.svc.Hostname, sp.port.Name),
+			Ep:  s.Env.ServiceDiscovery.InstancesByPort(sp.svc, sp.port.Port, nil),
+		}
+	}
+
+	if !page.paged {
+		// The limit applies to the endpoints serialized, whatever the number of services and ports they belong to.
+		// They are counted before the response is written, so that it can be rejected: as soon as the limit is
+		// exceeded, and without holding the endpoints counted.
+		endpoints := 0
+		for i := start; i < end; i++ {
+			endpoints += len(entry(i).Ep)
+			if !page.checkUnpagedLimit(w, endpoints) {
+				return
+			}
+		}
+	}
+	// next returns the entry of the i-th service port of the page, or false once the page is full.
+	endpoints := 0
+	next := func(i int) (EndpointzEntry, bool) {
+		if page.paged && endpoints >= debugUnpagedLimit {
+			return EndpointzEntry{}, false
+		}
+		e := entry(i)
+		endpoints += len(e.Ep)
+		return e, true
+	}
+
+	if req.URL.Query().Get("brief") != "" {
+		w.Header().Add("Content-Type", "application/json")
+		for i := start; i < end; i++ {
+			entry, ok := next(i)
+			if !ok {
+				return
+			}
+			for _, svc := range entry.Ep {
+				_, _ = fmt.Fprintf(w, "%s %s:%d %v %s\n", entry.Svc, svc.Endpoint.Address,
+					svc.Endpoint.EndpointPort, svc.Endpoint.Labels, svc.Endpoint.ServiceAccount)
 			}
 		}
 		return
 	}
 
-	svc, _ := s.Env.ServiceDiscovery.Services()
+	if page.paged {
+		writeDebugPage(w, start, end, total, func(i int) interface{} {
+			if entry, ok := next(i); ok {
+				return entry
+			}
+			return nil
+		})
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
 	_, _ = fmt.Fprint(w, "[\n")
-	for _, ss := range svc {
-		for _, p := range ss.Ports {
-			all := s.Env.ServiceDiscovery.InstancesByPort(ss, p.Port, nil)
-			_, _ = fmt.Fprintf(w, "\n{\"svc\": \"%s:%s\", \"ep\": [\n", ss.Hostname, p.Name)
-			for _, svc := range all {
-				b, err := json.MarshalIndent(svc, "  ", "  ")
-				if err != nil {
-					return
-				}
-				_, _ = w.Write(b)
-				_, _ = fmt.Fprint(w, ",\n")
+	for i := start; i < end; i++ {
+		entry, _ := next(i)
+		_, _ = fmt.Fprintf(w, "\n{\"svc\": \"%s\", \"ep\": [\n", entry.Svc)
+		for _, svc := range entry.Ep {
+			b, err := json.MarshalIndent(svc, "  ", "  ")
+			if err != nil {
+				return
 			}
-			_, _ = fmt.Fprint(w, "\n{}]},")
+			_, _ = w.Write(b)
+			_, _ = fmt.Fprint(w, ",\n")
 		}
+		_, _ = fmt.Fprint(w, "\n{}]},")
 	}
 	_, _ = fmt.Fprint(w, "\n{}]\n")
 }
@@ -372,36 +682,27 @@ func (s *DiscoveryServer) getResourceVersion(nonce, key string, cache map[string
 	return result
 }
 
-type kubernetesConfig struct {
-	config.Config
-}
-
-func (k kubernetesConfig) MarshalJSON() ([]byte, error) {
-	cfg, err := crd.ConvertConfig(k.Config)
-	if err != nil {
-		return nil, err
-	}
-	return json.Marshal(cfg)
-}
-
-// Config debugging.
+// Config debugging, the response follows ConfigzV1.
 func (s *DiscoveryServer) configz(w http.ResponseWriter, req *http.Request) {
-	configs := []kubernetesConfig{}
+	configs := ConfigzV1{}
+	var err error
 	s.Env.IstioConfigStore.Schemas().ForEach(func(schema collection.Schema) bool {
 		cfg, _ := s.Env.IstioConfigStore.List(schema.Resource().GroupVersionKind(), "")
 		for _, c := range cfg {
-			configs = append(configs, kubernetesConfig{c})
+			var obj crd.IstioObject
+			if obj, err = crd.ConvertConfig(c); err != nil {
+				return true
+			}
+			configs = append(configs, obj.(*crd.IstioKind))
 		}
 		return false
 	})
-	b, err := json.MarshalIndent(configs, "  ", "  ")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(err.Error()))
 		return
 	}
-	w.Header().Add("Content-Type", "application/json")
-	_, _ = w.Write(b)
+	writeDebugJSON(w, req, configs)
 }
 
 // Resource debugging.
@@ -421,25 +722,37 @@ func (s *DiscoveryServer) resourcez(w http.ResponseWriter, _ *http.Request) {
 // AuthorizationDebug holds debug information for authorization policy.
 type AuthorizationDebug struct {
 	AuthorizationPolicies *model.AuthorizationPolicies `json:"authorization_policies"`
+	// DryRunPolicies are the namespace/name of the policies in dry-run mode, which are not enforced.
+	DryRunPolicies []string `json:"dry_run_policies,omitempty"`
 }
 
 // Authorizationz dumps the internal authorization policies.
 func (s *DiscoveryServer) Authorizationz(w http.ResponseWriter, req *http.Request) {
 	w.Header().Add("Content-Type", "application/json")
 
+	policies := s.globalPushContext().AuthzPolicies
 	info := AuthorizationDebug{
-		AuthorizationPolicies: s.globalPushContext().AuthzPolicies,
+		AuthorizationPolicies: policies,
+	}
+	if policies != nil {
+		for _, nsPolicies := range policies.NamespaceToPolicies {
+			for _, p := range nsPolicies {
+				if p.DryRun {
+					info.DryRunPolicies = append(info.DryRunPolicies, p.Namespace+"/"+p.Name)
+				}
+			}
+		}
+		sort.Strings(info.DryRunPolicies)
 	}
 	if b, err := json.MarshalIndent(info, "  ", "  "); err == nil {
 		_, _ = w.Write(b)
 	}
 }
 
-// adsz implements a status and debug interface for ADS.
+// adsz implements a status and debug interface for ADS, the response follows AdsClients.
 // It is mapped to /debug/adsz
 func (s *DiscoveryServer) adsz(w http.ResponseWriter, req *http.Request) {
 	_ = req.ParseForm()
-	w.Header().Add("Content-Type", "application/json")
 	if req.Form.Get("push") != "" {
 		AdsPushAll(s)
 		s.adsClientsMutex.RLock()
@@ -458,12 +771,51 @@ func (s *DiscoveryServer) adsz(w http.ResponseWriter, req *http.Request) {
 			ConnectionID: c.ConID,
 			ConnectedAt:  c.Connect,
 			PeerAddress:  c.PeerAddr,
+			BytesSent:    map[string]int64{},
+		}
+		for typeURL, sz := range c.BytesSent() {
+			adsClient.BytesSent[v3.GetShortType(typeURL)] = sz
 		}
+		adsClient.GenerationErrors = c.GenerationErrors()
+		for typeURL, diags := range c.Diagnostics() {
+			if adsClient.Diagnostics == nil {
+				adsClient.Diagnostics = map[string]model.GenerationDiagnostics{}
+			}
+			adsClient.Diagnostics[v3.GetShortType(typeURL)] = diags
+		}
+		adsClient.DeferredPush = c.DeferredPush()
 		adsClients.Connected = append(adsClients.Connected, adsClient)
 	}
-	if b, err := json.MarshalIndent(adsClients, "  ", "  "); err == nil {
-		_, _ = w.Write(b)
+	writeDebugJSON(w, req, adsClients)
+}
+
+// ForcePushResponse is the response of /debug/force_push.
+type ForcePushResponse struct {
+	// Connections is the number of connections a full push was enqueued for.
+	Connections int `json:"connections"`
+}
+
+// forcePush enqueues a full push to the proxies of a namespace, or to a single proxy.
+func (s *DiscoveryServer) forcePush(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = w.Write([]byte("Only POST is supported"))
+		return
+	}
+	namespace, proxyID := req.URL.Query().Get("namespace"), req.URL.Query().Get("proxy")
+	if (namespace == "") == (proxyID == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide either a namespace or a proxy in the query string"))
+		return
 	}
+	out, err := json.MarshalIndent(&ForcePushResponse{Connections: s.ForcePush(namespace, proxyID)}, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal force push response: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
 }
 
 // ConfigDump returns information in the form of the Envoy admin API config dump for the specified proxy
@@ -500,7 +852,7 @@ func (s *DiscoveryServer) ConfigDump(w http.ResponseWriter, req *http.Request) {
 // It is used in debugging to create a consistent object for comparison between Envoy and Pilot outputs
 func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, error) {
 	dynamicActiveClusters := make([]*adminapi.ClustersConfigDump_DynamicCluster, 0)
-	clusters := s.ConfigGenerator.BuildClusters(conn.proxy, s.globalPushContext())
+	clusters := s.ConfigGenerator.BuildClusters(conn.proxy, s.globalPushContext(), nil)
 
 	for _, cs := range clusters {
 		cluster, err := ptypes.MarshalAny(cs)
@@ -518,7 +870,7 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 	}
 
 	dynamicActiveListeners := make([]*adminapi.ListenersConfigDump_DynamicListener, 0)
-	listeners := s.ConfigGenerator.BuildListeners(conn.proxy, s.globalPushContext())
+	listeners := s.ConfigGenerator.BuildListeners(conn.proxy, s.globalPushContext(), nil)
 	for _, cs := range listeners {
 		listener, err := ptypes.MarshalAny(cs)
 		if err != nil {
@@ -609,20 +961,66 @@ func (s *DiscoveryServer) InjectTemplateHandler(webhook *inject.Webhook) func(ht
 	}
 }
 
-// PushStatusHandler dumps the last PushContext
+// PushStatusHandler dumps the last PushContext. The entries can be filtered with the metric and namespace query
+// parameters, which may be repeated or comma separated, limited per metric with the limit parameter, and the proxy
+// IDs redacted with redact=true. The response follows PushStatusV1.
 func (s *DiscoveryServer) PushStatusHandler(w http.ResponseWriter, req *http.Request) {
-	if model.LastPushStatus == nil {
+	filter, err := pushStatusFilter(req.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
 		return
 	}
-	out, err := model.LastPushStatus.StatusJSON()
+	status := PushStatusV1{}
+	out, err := model.LastPushStatus.StatusJSON(filter)
+	if err == nil {
+		err = json.Unmarshal(out, &status)
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = fmt.Fprintf(w, "unable to marshal push information: %v", err)
 		return
 	}
-	w.Header().Add("Content-Type", "application/json")
+	if s.debounceOptions.debounceAfterMax > s.debounceOptions.debounceAfter {
+		status.DebounceDelay = s.debounceOptions.effectiveDebounceAfter().String()
+	}
+	writeDebugJSON(w, req, status)
+}
 
-	_, _ = w.Write(out)
+// pushStatusFilter returns the filter of the push status from the query parameters.
+func pushStatusFilter(q url.Values) (model.StatusFilter, error) {
+	filter := model.StatusFilter{
+		Metrics:    queryValues(q, "metric"),
+		Namespaces: queryValues(q, "namespace"),
+	}
+	if limit := q.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 0 {
+			return filter, fmt.Errorf("invalid limit %q, must be a non-negative integer", limit)
+		}
+		filter.Limit = l
+	}
+	if redact := q.Get("redact"); redact != "" {
+		r, err := strconv.ParseBool(redact)
+		if err != nil {
+			return filter, fmt.Errorf("invalid redact %q, must be a boolean", redact)
+		}
+		filter.Redact = r
+	}
+	return filter, nil
+}
+
+// queryValues returns the values of a query parameter, which may be repeated or comma separated.
+func queryValues(q url.Values, key string) []string {
+	var out []string
+	for _, v := range q[key] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
 }
 
 // lists all the supported debug endpoints.
@@ -638,7 +1036,7 @@ func (s *DiscoveryServer) Debug(w http.ResponseWriter, req *http.Request) {
 		deps = append(deps, debugEndpoint{
 			Name: k,
 			Href: k,
-			Help: v,
+			Help: v.help,
 		})
 	}
 
@@ -0,0 +1,288 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+	istioversion "istio.io/pkg/version"
+)
+
+// bundleServiceKind is the kind of the services of the registry in a bundle.
+const bundleServiceKind = "Service"
+
+// bundleConfigKinds are the kinds of the configs included in the bundle of a namespace, with whether the configs of
+// the root namespace apply to the namespace. The VirtualServices, DestinationRules and ServiceEntries of the other
+// namespaces are included if they are exported to the namespace.
+var bundleConfigKinds = []struct {
+	kind          config.GroupVersionKind
+	rootNamespace bool
+}{
+	{gvk.VirtualService, false},
+	{gvk.DestinationRule, false},
+	{gvk.ServiceEntry, false},
+	{gvk.Gateway, false},
+	{gvk.Sidecar, true},
+	{gvk.EnvoyFilter, true},
+	{gvk.PeerAuthentication, true},
+	{gvk.RequestAuthentication, true},
+	{gvk.AuthorizationPolicy, true},
+}
+
+// BundleManifest describes the contents of the bundle of a namespace.
+type BundleManifest struct {
+	Namespace     string    `json:"namespace"`
+	RootNamespace string    `json:"rootNamespace"`
+	GeneratedAt   time.Time `json:"generatedAt"`
+	IstioVersion  string    `json:"istioVersion"`
+	// ConfigVersion is the version of the push context the bundle was generated from.
+	ConfigVersion string `json:"configVersion"`
+	// Kinds are the kinds the bundle was filtered on, if any.
+	Kinds []string `json:"kinds,omitempty"`
+	// Start is the index of the first resource of the bundle, and Total the number of resources matching the
+	// filters, in all the bundles of the namespace.
+	Start     int              `json:"start"`
+	Total     int              `json:"total"`
+	Resources []BundleResource `json:"resources"`
+}
+
+// BundleResource describes a resource of a bundle, written in its own YAML file.
+type BundleResource struct {
+	Kind            string `json:"kind"`
+	Namespace       string `json:"namespace"`
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	File            string `json:"file"`
+}
+
+// bundleItem is a resource of a bundle and its content.
+type bundleItem struct {
+	BundleResource
+	content interface{}
+}
+
+// bundle returns a tar.gz of the configs visible to the ?namespace=, those of the root namespace which apply to it,
+// the services visible to it, and the mesh config and networks. Each resource is written in its own YAML file,
+// described by manifest.json. The kinds can be filtered with ?kind=, a comma separated list, and the resources
+// paginated with ?start= and ?count=. Secrets are never included: credentials are only referenced by name.
+func (s *DiscoveryServer) bundle(w http.ResponseWriter, req *http.Request) {
+	p, err := parseDebugPageRequest(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintln(w, err)
+		return
+	}
+	if p.namespace == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a namespace in the query string"))
+		return
+	}
+	var kinds []string
+	if k := req.URL.Query().Get("kind"); k != "" {
+		kinds = strings.Split(k, ",")
+	}
+
+	push := s.globalPushContext()
+	items, err := s.bundleItems(push, p.namespace, kinds)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to list the resources of the namespace: %v", err)
+		return
+	}
+	if !p.checkUnpagedLimit(w, len(items)) {
+		return
+	}
+	start, end := p.bounds(len(items))
+
+	manifest := BundleManifest{
+		Namespace:     p.namespace,
+		RootNamespace: push.Mesh.GetRootNamespace(),
+		GeneratedAt:   time.Now(),
+		IstioVersion:  istioversion.Info.String(),
+		ConfigVersion: push.Version,
+		Kinds:         kinds,
+		Start:         start,
+		Total:         len(items),
+		Resources:     make([]BundleResource, 0, end-start),
+	}
+	for _, item := range items[start:end] {
+		manifest.Resources = append(manifest.Resources, item.BundleResource)
+	}
+
+	b, err := s.writeBundle(manifest, items[start:end])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to write the bundle: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/gzip")
+	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=bundle-%s.tar.gz", p.namespace))
+	_, _ = w.Write(b)
+}
+
+// bundleItems returns the resources of the bundle of the namespace, of the kinds if set, sorted by kind, namespace
+// and name.
+func (s *DiscoveryServer) bundleItems(push *model.PushContext, namespace string, kinds []string) ([]bundleItem, error) {
+	included := func(kind string) bool {
+		if len(kinds) == 0 {
+			return true
+		}
+		for _, k := range kinds {
+			if strings.EqualFold(k, kind) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var items []bundleItem
+	rootNamespace := push.Mesh.GetRootNamespace()
+	for _, k := range bundleConfigKinds {
+		if !included(k.kind.Kind) {
+			continue
+		}
+		if _, f := s.Env.IstioConfigStore.Schemas().FindByGroupVersionKind(k.kind); !f {
+			continue
+		}
+		configs, err := s.Env.IstioConfigStore.List(k.kind, model.NamespaceAll)
+		if err != nil {
+			return nil, err
+		}
+		for _, cfg := range configs {
+			applies := push.IsConfigVisible(cfg, namespace) || (k.rootNamespace && cfg.Namespace == rootNamespace)
+			if !applies {
+				continue
+			}
+			obj, err := crd.ConvertConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, bundleItem{
+				BundleResource: BundleResource{
+					Kind:            cfg.GroupVersionKind.Kind,
+					Namespace:       cfg.Namespace,
+					Name:            cfg.Name,
+					ResourceVersion: cfg.ResourceVersion,
+				},
+				content: obj,
+			})
+		}
+	}
+	if included(bundleServiceKind) {
+		for _, svc := range push.Services(&model.Proxy{ConfigNamespace: namespace}) {
+			items = append(items, bundleItem{
+				BundleResource: BundleResource{
+					Kind:      bundleServiceKind,
+					Namespace: svc.Attributes.Namespace,
+					Name:      string(svc.Hostname),
+				},
+				content: svc,
+			})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind < items[j].Kind
+		}
+		if items[i].Namespace != items[j].Namespace {
+			return items[i].Namespace < items[j].Namespace
+		}
+		return items[i].Name < items[j].Name
+	})
+	for i := range items {
+		items[i].File = path.Join(items[i].Kind, items[i].Namespace, items[i].Name+".yaml")
+	}
+	return items, nil
+}
+
+// writeBundle returns the tar.gz of the manifest, the mesh config and networks, and the resources.
+func (s *DiscoveryServer) writeBundle(manifest BundleManifest, items []bundleItem) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	add := func(name string, content []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: manifest.GeneratedAt,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := add("manifest.json", b); err != nil {
+		return nil, err
+	}
+	if m := s.Env.Mesh(); m != nil {
+		yml, err := gogoprotomarshal.ToYAML(m)
+		if err != nil {
+			return nil, err
+		}
+		if err := add("mesh/meshconfig.yaml", []byte(yml)); err != nil {
+			return nil, err
+		}
+	}
+	if n := s.Env.Networks(); n != nil {
+		yml, err := gogoprotomarshal.ToYAML(n)
+		if err != nil {
+			return nil, err
+		}
+		if err := add("mesh/meshnetworks.yaml", []byte(yml)); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range items {
+		yml, err := yaml.Marshal(item.content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", item.File, err)
+		}
+		if err := add(item.File, yml); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
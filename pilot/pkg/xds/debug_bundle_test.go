@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const bundleConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: local
+  namespace: foo
+spec:
+  hosts:
+  - local.example.com
+  http:
+  - route:
+    - destination:
+        host: local.example.com
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: public
+  namespace: bar
+spec:
+  hosts:
+  - public.example.com
+  exportTo:
+  - "*"
+  http:
+  - route:
+    - destination:
+        host: public.example.com
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: private
+  namespace: bar
+spec:
+  hosts:
+  - private.example.com
+  exportTo:
+  - "."
+  http:
+  - route:
+    - destination:
+        host: private.example.com
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: mesh-wide
+  namespace: istio-system
+spec: {}
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: other
+  namespace: bar
+spec: {}
+---
+apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: foo
+spec:
+  mtls:
+    mode: STRICT
+`
+
+func TestBundle(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: bundleConfig})
+	bundle := func(query string) (*BundleManifest, []string) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.Discovery.bundle(rr, httptest.NewRequest("GET", "/debug/bundle?"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("unexpected response %d: %s", rr.Code, rr.Body.String())
+		}
+		gz, err := gzip.NewReader(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := tar.NewReader(gz)
+		manifest := &BundleManifest{}
+		var files []string
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, hdr.Name)
+			if hdr.Name == "manifest.json" {
+				b, err := ioutil.ReadAll(tr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal(b, manifest); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		sort.Strings(files)
+		return manifest, files
+	}
+
+	manifest, files := bundle("namespace=foo&kind=VirtualService,AuthorizationPolicy,PeerAuthentication")
+	want := []string{
+		"AuthorizationPolicy/istio-system/mesh-wide.yaml",
+		"PeerAuthentication/foo/default.yaml",
+		"VirtualService/bar/public.yaml",
+		"VirtualService/foo/local.yaml",
+		"manifest.json",
+		"mesh/meshconfig.yaml",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("got files %v, want %v", files, want)
+	}
+	if manifest.Namespace != "foo" || manifest.RootNamespace != "istio-system" || manifest.Total != 4 || len(manifest.Resources) != 4 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+
+	manifest, _ = bundle("namespace=foo&kind=VirtualService,AuthorizationPolicy,PeerAuthentication&start=1&count=2")
+	if manifest.Start != 1 || manifest.Total != 4 || len(manifest.Resources) != 2 ||
+		manifest.Resources[0].File != "PeerAuthentication/foo/default.yaml" {
+		t.Fatalf("unexpected paginated manifest %+v", manifest)
+	}
+
+	rr := httptest.NewRecorder()
+	s.Discovery.bundle(rr, httptest.NewRequest("GET", "/debug/bundle", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a bundle without namespace to be rejected, got %d", rr.Code)
+	}
+}
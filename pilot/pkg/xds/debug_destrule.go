@@ -0,0 +1,42 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"istio.io/istio/pkg/config/host"
+)
+
+// destrulez displays the destination rules merged for the ?host=, and the fields ignored because of conflicts.
+func (s *DiscoveryServer) destrulez(w http.ResponseWriter, req *http.Request) {
+	hostname := req.URL.Query().Get("host")
+	if hostname == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a host in the query string"))
+		return
+	}
+
+	out, err := json.MarshalIndent(s.globalPushContext().DestinationRuleMerges(host.Name(hostname)), "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal destination rule merges: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/golang/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// configDiffTimeout bounds the time spent generating the configuration of both proxies for a diff.
+const configDiffTimeout = 10 * time.Second
+
+const (
+	resourceAdded   = "added"
+	resourceRemoved = "removed"
+	resourceChanged = "changed"
+)
+
+// ConfigDiff is the difference between the configuration generated for two proxies.
+type ConfigDiff struct {
+	Proxy1 string `json:"proxy1"`
+	Proxy2 string `json:"proxy2"`
+	// Types holds the differing resources, keyed by the short xDS type (CDS, LDS, RDS, EDS).
+	Types map[string][]ResourceDiff `json:"types,omitempty"`
+}
+
+// ResourceDiff describes a single resource which differs between two proxies.
+type ResourceDiff struct {
+	Name string `json:"name"`
+	// Classification is added if the resource is only generated for proxy2, removed if it is only generated
+	// for proxy1 and changed if it is generated for both with different content.
+	Classification string `json:"classification"`
+}
+
+// configDiff generates the configuration for the two proxies given by the proxy1 and proxy2 parameters and
+// returns the resources which differ between them.
+func (s *DiscoveryServer) configDiff(w http.ResponseWriter, req *http.Request) {
+	proxy1, proxy2 := req.URL.Query().Get("proxy1"), req.URL.Query().Get("proxy2")
+	if proxy1 == "" || proxy2 == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide proxy1 and proxy2 in the query string"))
+		return
+	}
+	con1, con2 := s.getProxyConnection(proxy1), s.getProxyConnection(proxy2)
+	if con1 == nil || con2 == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("Proxy not connected to this Pilot instance. It may be connected to another instance."))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), configDiffTimeout)
+	defer cancel()
+	result := make(chan *ConfigDiff, 1)
+	go func() {
+		result <- s.diffConfig(con1, con2, s.globalPushContext())
+	}()
+
+	var diff *ConfigDiff
+	select {
+	case diff = <-result:
+	case <-ctx.Done():
+		w.WriteHeader(http.StatusGatewayTimeout)
+		_, _ = w.Write([]byte(fmt.Sprintf("timed out generating config diff: %v", ctx.Err())))
+		return
+	}
+	b, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// diffConfig compares the configuration generated for both connections with the given push context.
+// Only the generated resources are compared, so per connection state such as nonces and versions never
+// shows up as a difference. The connections are not modified.
+func (s *DiscoveryServer) diffConfig(con1, con2 *Connection, push *model.PushContext) *ConfigDiff {
+	resources1 := s.debugResources(con1, push)
+	resources2 := s.debugResources(con2, push)
+	diff := &ConfigDiff{
+		Proxy1: con1.proxy.ID,
+		Proxy2: con2.proxy.ID,
+		Types:  map[string][]ResourceDiff{},
+	}
+	for _, typeURL := range []string{v3.ClusterType, v3.ListenerType, v3.RouteType, v3.EndpointType} {
+		if d := diffResources(resources1[typeURL], resources2[typeURL]); len(d) > 0 {
+			diff.Types[v3.GetShortType(typeURL)] = d
+		}
+	}
+	return diff
+}
+
+// debugResources generates the CDS, LDS, RDS and EDS resources for the connection, keyed by type URL and name.
+func (s *DiscoveryServer) debugResources(con *Connection, push *model.PushContext) map[string]map[string]proto.Message {
+	out := map[string]map[string]proto.Message{
+		v3.ClusterType:  {},
+		v3.ListenerType: {},
+		v3.RouteType:    {},
+		v3.EndpointType: {},
+	}
+	for _, c := range s.ConfigGenerator.BuildClusters(con.proxy, push, nil) {
+		out[v3.ClusterType][c.Name] = c
+		if c.GetType() != cluster.Cluster_EDS {
+			continue
+		}
+		name := c.Name
+		if serviceName := c.GetEdsClusterConfig().GetServiceName(); serviceName != "" {
+			name = serviceName
+		}
+		out[v3.EndpointType][name] = s.generateEndpoints(NewEndpointBuilder(name, con.proxy, push))
+	}
+	for _, l := range s.ConfigGenerator.BuildListeners(con.proxy, push, nil) {
+		out[v3.ListenerType][l.Name] = l
+	}
+	for _, r := range s.ConfigGenerator.BuildHTTPRoutes(con.proxy, push, con.Routes()) {
+		out[v3.RouteType][r.Name] = r
+	}
+	return out
+}
+
+// diffResources returns the resources which differ between the two sets, sorted by name.
+func diffResources(resources1, resources2 map[string]proto.Message) []ResourceDiff {
+	var out []ResourceDiff
+	for name, r1 := range resources1 {
+		r2, f := resources2[name]
+		switch {
+		case !f:
+			out = append(out, ResourceDiff{Name: name, Classification: resourceRemoved})
+		case !proto.Equal(r1, r2):
+			out = append(out, ResourceDiff{Name: name, Classification: resourceChanged})
+		}
+	}
+	for name := range resources2 {
+		if _, f := resources1[name]; !f {
+			out = append(out, ResourceDiff{Name: name, Classification: resourceAdded})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,142 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+const configDiffConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: a
+  namespace: ns-a
+spec:
+  hosts:
+  - a.example.com
+  addresses:
+  - 240.0.0.1
+  ports:
+  - number: 9000
+    name: tcp
+    protocol: TCP
+  resolution: STATIC
+  endpoints:
+  - address: 10.1.0.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: b
+  namespace: ns-b
+spec:
+  hosts:
+  - b.example.com
+  addresses:
+  - 240.0.0.2
+  ports:
+  - number: 9000
+    name: tcp
+    protocol: TCP
+  resolution: STATIC
+  endpoints:
+  - address: 10.1.0.2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: default
+  namespace: ns-a
+spec:
+  egress:
+  - hosts:
+    - "./*"
+`
+
+func TestConfigDiff(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: configDiffConfig})
+	for _, ns := range []string{"ns-a", "ns-b"} {
+		s.Connect(&model.Proxy{
+			ID:              "app." + ns,
+			ConfigNamespace: ns,
+			Metadata:        &model.NodeMetadata{Namespace: ns},
+			IPAddresses:     []string{"10.0.0.1"},
+		}, nil, []string{v3.ClusterType})
+	}
+
+	cases := []struct {
+		name     string
+		query    string
+		wantCode int
+		want     map[string][]ResourceDiff
+	}{
+		{
+			name:     "sidecar scope difference",
+			query:    "?proxy1=test-1.ns-a&proxy2=test-1.ns-b",
+			wantCode: http.StatusOK,
+			want: map[string][]ResourceDiff{
+				"CDS": {{Name: "outbound|9000||b.example.com", Classification: resourceAdded}},
+				"LDS": {{Name: "240.0.0.2_9000", Classification: resourceAdded}},
+				"EDS": {{Name: "outbound|9000||b.example.com", Classification: resourceAdded}},
+			},
+		},
+		{
+			name:     "same proxy",
+			query:    "?proxy1=test-1.ns-a&proxy2=test-1.ns-a",
+			wantCode: http.StatusOK,
+			want:     map[string][]ResourceDiff{},
+		},
+		{
+			name:     "proxy not found",
+			query:    "?proxy1=test-1.ns-a&proxy2=not-found",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "missing proxy",
+			query:    "?proxy1=test-1.ns-a",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			s.Discovery.configDiff(rr, httptest.NewRequest("GET", "/debug/configz/diff"+tt.query, nil))
+			if rr.Code != tt.wantCode {
+				t.Fatalf("wanted response code %v, got %v: %s", tt.wantCode, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			got := &ConfigDiff{}
+			if err := json.Unmarshal(rr.Body.Bytes(), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Types == nil {
+				got.Types = map[string][]ResourceDiff{}
+			}
+			if !reflect.DeepEqual(got.Types, tt.want) {
+				t.Fatalf("got diff %+v, want %+v", got.Types, tt.want)
+			}
+		})
+	}
+}
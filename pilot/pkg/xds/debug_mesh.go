@@ -0,0 +1,146 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+// Sources of the value of a mesh config field, reported by /debug/mesh.
+const (
+	// MeshSourceDefault is reported for fields with the default value.
+	MeshSourceDefault = "default"
+	// MeshSourceMeshConfig is reported for fields set by the mesh config istiod started with.
+	MeshSourceMeshConfig = "meshConfig"
+	// MeshSourceReload is reported for fields changed by a reload of the mesh config at runtime.
+	MeshSourceReload = "reload"
+)
+
+// meshProvenanceFields are the mesh config fields, by JSON name, whose source is reported by /debug/mesh.
+var meshProvenanceFields = []string{
+	"accessLogEncoding",
+	"accessLogFile",
+	"accessLogFormat",
+	"connectTimeout",
+	"defaultDestinationRuleExportTo",
+	"defaultServiceExportTo",
+	"defaultVirtualServiceExportTo",
+	"dnsRefreshRate",
+	"enableAutoMtls",
+	"enableEnvoyAccessLogService",
+	"enablePrometheusMerge",
+	"enableTracing",
+	"ingressControllerMode",
+	"localityLbSetting",
+	"outboundTrafficPolicy",
+	"protocolDetectionTimeout",
+	"rootNamespace",
+	"trustDomain",
+}
+
+// MeshDebug is the effective mesh configuration displayed on /debug/mesh.
+type MeshDebug struct {
+	Mesh         map[string]interface{} `json:"mesh"`
+	MeshNetworks map[string]interface{} `json:"meshNetworks,omitempty"`
+	// Provenance maps selected mesh config fields to the source of their value. A field set to its default
+	// value is reported as coming from the default, even if it is also set explicitly.
+	Provenance map[string]string `json:"provenance"`
+	// LastReload is unset if the mesh config was not changed since istiod started.
+	LastReload *time.Time `json:"lastReload,omitempty"`
+	// LastChange is unset if no mesh config change was pushed since istiod started.
+	LastChange *MeshChangeDebug `json:"lastChange,omitempty"`
+}
+
+// MeshChangeDebug is the classification of the last mesh config change pushed.
+type MeshChangeDebug struct {
+	// Fields are the mesh config fields changed.
+	Fields []string `json:"fields"`
+	// Impact are the XDS types regenerated for the change.
+	Impact []string `json:"impact"`
+}
+
+// meshz displays the effective mesh config and networks, and where the value of selected fields came from.
+func (s *DiscoveryServer) meshz(w http.ResponseWriter, _ *http.Request) {
+	if s.Env == nil || s.Env.Watcher == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprint(w, "mesh config is not initialized")
+		return
+	}
+	out, err := meshDebug(s.Env.Watcher.Snapshot(), s.Env.NetworksWatcher)
+	if err == nil {
+		s.lastMeshConfigDiffMutex.RLock()
+		if diff := s.lastMeshConfigDiff; diff != nil {
+			out.LastChange = &MeshChangeDebug{Fields: diff.Fields, Impact: diff.Impact.Names()}
+		}
+		s.lastMeshConfigDiffMutex.RUnlock()
+		var yml []byte
+		if yml, err = yaml.Marshal(out); err == nil {
+			w.Header().Add("Content-Type", "application/yaml")
+			_, _ = w.Write(yml)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = fmt.Fprintf(w, "unable to marshal mesh information: %v", err)
+}
+
+func meshDebug(snapshot mesh.Snapshot, networks mesh.NetworksWatcher) (*MeshDebug, error) {
+	current, err := gogoprotomarshal.ToJSONMap(snapshot.Mesh)
+	if err != nil {
+		return nil, err
+	}
+	initial, err := gogoprotomarshal.ToJSONMap(snapshot.Initial)
+	if err != nil {
+		return nil, err
+	}
+	defaultMesh := mesh.DefaultMeshConfig()
+	defaults, err := gogoprotomarshal.ToJSONMap(&defaultMesh)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &MeshDebug{
+		Mesh:       current,
+		Provenance: make(map[string]string, len(meshProvenanceFields)),
+	}
+	for _, field := range meshProvenanceFields {
+		switch value := current[field]; {
+		case reflect.DeepEqual(value, defaults[field]):
+			out.Provenance[field] = MeshSourceDefault
+		case reflect.DeepEqual(value, initial[field]):
+			out.Provenance[field] = MeshSourceMeshConfig
+		default:
+			out.Provenance[field] = MeshSourceReload
+		}
+	}
+	if !snapshot.LastReload.IsZero() {
+		lastReload := snapshot.LastReload
+		out.LastReload = &lastReload
+	}
+	if networks != nil && networks.Networks() != nil {
+		if out.MeshNetworks, err = gogoprotomarshal.ToJSONMap(networks.Networks()); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,112 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/pkg/filewatcher"
+)
+
+func TestMeshz(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mesh")
+	writeMesh := func(content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeMesh("trustDomain: initial.local\n")
+
+	fw := filewatcher.NewWatcher()
+	defer fw.Close()
+	w, err := mesh.NewWatcher(fw, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &DiscoveryServer{Env: &model.Environment{
+		Watcher:         w,
+		NetworksWatcher: mesh.NewFixedNetworksWatcher(nil),
+	}}
+	meshz := func() MeshDebug {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.meshz(rr, httptest.NewRequest("GET", "/debug/mesh", nil))
+		var out MeshDebug
+		if err := yaml.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+			t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+		}
+		return out
+	}
+
+	out := meshz()
+	if out.Mesh["trustDomain"] != "initial.local" || out.Mesh["enableAutoMtls"] != true {
+		t.Fatalf("unexpected mesh config %v", out.Mesh)
+	}
+	if got := out.Provenance["trustDomain"]; got != MeshSourceMeshConfig {
+		t.Errorf("expected trustDomain from %v, got %v", MeshSourceMeshConfig, got)
+	}
+	if got := out.Provenance["enableAutoMtls"]; got != MeshSourceDefault {
+		t.Errorf("expected enableAutoMtls from %v, got %v", MeshSourceDefault, got)
+	}
+	if out.LastReload != nil {
+		t.Errorf("expected no reload, got %v", out.LastReload)
+	}
+	if out.LastChange != nil {
+		t.Errorf("expected no mesh config change, got %v", out.LastChange)
+	}
+
+	reloaded := make(chan struct{}, 1)
+	w.AddMeshHandler(func() {
+		reloaded <- struct{}{}
+	})
+	beforeReload := time.Now()
+	writeMesh("trustDomain: initial.local\nenableAutoMtls: false\n")
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for mesh config reload")
+	}
+
+	out = meshz()
+	if out.Mesh["enableAutoMtls"] != false {
+		t.Fatalf("expected reloaded enableAutoMtls, got %v", out.Mesh["enableAutoMtls"])
+	}
+	if got := out.Provenance["enableAutoMtls"]; got != MeshSourceReload {
+		t.Errorf("expected enableAutoMtls from %v, got %v", MeshSourceReload, got)
+	}
+	if got := out.Provenance["trustDomain"]; got != MeshSourceMeshConfig {
+		t.Errorf("expected trustDomain from %v, got %v", MeshSourceMeshConfig, got)
+	}
+	if out.LastReload == nil || out.LastReload.Before(beforeReload.Truncate(time.Second)) {
+		t.Errorf("expected reload after %v, got %v", beforeReload, out.LastReload)
+	}
+
+	s.lastMeshConfigDiff = &model.MeshConfigDiff{Fields: []string{"accessLogFile"}, Impact: model.MeshImpactListeners}
+	out = meshz()
+	want := &MeshChangeDebug{Fields: []string{"accessLogFile"}, Impact: []string{"listeners"}}
+	if !reflect.DeepEqual(out.LastChange, want) {
+		t.Errorf("got last change %+v, want %+v", out.LastChange, want)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+const (
+	// debugDefaultPageSize is the page size of the paginated debug endpoints when no count is requested.
+	debugDefaultPageSize = 100
+	// debugMaxPageSize caps the page size of the paginated debug endpoints.
+	debugMaxPageSize = 1000
+)
+
+// debugUnpagedLimit is the maximum number of items the paginated debug endpoints return in a single,
+// unpaginated response, counted in services for registryz and in endpoints for endpointz. Larger responses are
+// rejected, as building them can exhaust the memory of istiod.
+var debugUnpagedLimit = 5000
+
+// DebugPage is a page of the items of a paginated debug endpoint, returned when ?start= or ?count= is set.
+type DebugPage struct {
+	// Start is the index of the first item of the page.
+	Start int `json:"start"`
+	// Total is the number of items matching the filters, in all pages.
+	Total int `json:"total"`
+	// Items are the items of the page.
+	Items []json.RawMessage `json:"items"`
+}
+
+// debugPageRequest holds the paging and filtering parameters of a paginated debug endpoint:
+// ?start=<n>&count=<n>&namespace=<namespace>&host=<hostname substring>
+type debugPageRequest struct {
+	paged     bool
+	start     int
+	count     int
+	namespace string
+	host      string
+}
+
+func parseDebugPageRequest(req *http.Request) (debugPageRequest, error) {
+	q := req.URL.Query()
+	p := debugPageRequest{
+		count:     debugDefaultPageSize,
+		namespace: q.Get("namespace"),
+		host:      q.Get("host"),
+	}
+	if v := q.Get("start"); v != "" {
+		start, err := strconv.Atoi(v)
+		if err != nil || start < 0 {
+			return p, fmt.Errorf("invalid start %q", v)
+		}
+		p.paged, p.start = true, start
+	}
+	if v := q.Get("count"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil || count <= 0 {
+			return p, fmt.Errorf("invalid count %q", v)
+		}
+		if count > debugMaxPageSize {
+			count = debugMaxPageSize
+		}
+		p.paged, p.count = true, count
+	}
+	return p, nil
+}
+
+func (p debugPageRequest) matches(svc *model.Service) bool {
+	if p.namespace != "" && svc.Attributes.Namespace != p.namespace {
+		return false
+	}
+	return p.host == "" || strings.Contains(string(svc.Hostname), p.host)
+}
+
+// bounds returns the range of the requested items, out of total items.
+func (p debugPageRequest) bounds(total int) (int, int) {
+	if !p.paged {
+		return 0, total
+	}
+	start, end := p.start, p.start+p.count
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// checkUnpagedLimit rejects unpaginated requests for more than debugUnpagedLimit items.
+// It returns false if the request was rejected.
+func (p debugPageRequest) checkUnpagedLimit(w http.ResponseWriter, total int) bool {
+	if p.paged || total <= debugUnpagedLimit {
+		return true
+	}
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	_, _ = fmt.Fprintf(w, "%d items exceed the limit of %d for a single response. "+
+		"Paginate with ?start=0&count=%d (at most %d items per page), "+
+		"or filter with ?namespace=<namespace> or ?host=<hostname substring>.\n",
+		total, debugUnpagedLimit, debugDefaultPageSize, debugMaxPageSize)
+	return false
+}
+
+// debugServices returns the services matching the filters of the request, sorted by hostname and namespace.
+func (s *DiscoveryServer) debugServices(p debugPageRequest) ([]*model.Service, error) {
+	all, err := s.Env.ServiceDiscovery.Services()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*model.Service, 0, len(all))
+	for _, svc := range all {
+		if p.matches(svc) {
+			out = append(out, svc)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Hostname != out[j].Hostname {
+			return out[i].Hostname < out[j].Hostname
+		}
+		return out[i].Attributes.Namespace < out[j].Attributes.Namespace
+	})
+	return out, nil
+}
+
+// writeDebugPage streams the items in [start, end) as a DebugPage. Each item is encoded as it is written,
+// so that the whole page is never held in memory. The page ends early if item returns nil.
+func writeDebugPage(w http.ResponseWriter, start, end, total int, item func(i int) interface{}) {
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = fmt.Fprintf(w, "{\"start\": %d, \"total\": %d, \"items\": [\n", start, total)
+	enc := json.NewEncoder(w)
+	for i := start; i < end; i++ {
+		v := item(i)
+		if v == nil {
+			break
+		}
+		if i > start {
+			_, _ = fmt.Fprint(w, ",")
+		}
+		if err := enc.Encode(v); err != nil {
+			return
+		}
+	}
+	_, _ = fmt.Fprint(w, "]}\n")
+}
@@ -0,0 +1,207 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/memory"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+// countingDiscovery counts the instances looked up by port.
+type countingDiscovery struct {
+	*memory.ServiceDiscovery
+	lookups int
+}
+
+func (d *countingDiscovery) InstancesByPort(svc *model.Service, port int, l labels.Collection) []*model.ServiceInstance {
+	d.lookups++
+	return d.ServiceDiscovery.InstancesByPort(svc, port, l)
+}
+
+func newPagingTestServer(t *testing.T, services int) *DiscoveryServer {
+	t.Helper()
+	sd := memory.NewServiceDiscovery(nil)
+	for i := 0; i < services; i++ {
+		ns := fmt.Sprintf("ns-%d", i%2)
+		hostname := host.Name(fmt.Sprintf("svc-%04d.%s.svc.cluster.local", i, ns))
+		sd.AddService(hostname, &model.Service{
+			Hostname:   hostname,
+			Attributes: model.ServiceAttributes{Namespace: ns},
+			Ports:      model.PortList{{Name: "http-main", Port: 80, Protocol: protocol.HTTP}},
+		})
+		sd.AddEndpoint(hostname, "http-main", 80, fmt.Sprintf("10.0.%d.%d", i/256, i%256), 8080)
+	}
+	return &DiscoveryServer{Env: &model.Environment{ServiceDiscovery: sd}}
+}
+
+func TestDebugPagination(t *testing.T) {
+	oldLimit := debugUnpagedLimit
+	debugUnpagedLimit = 1000
+	t.Cleanup(func() { debugUnpagedLimit = oldLimit })
+
+	s := newPagingTestServer(t, 2000)
+	get := func(handler http.HandlerFunc, query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/debug?"+query, nil))
+		return rr
+	}
+	getPage := func(handler http.HandlerFunc, query string) DebugPage {
+		t.Helper()
+		rr := get(handler, query)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d: %s", query, rr.Code, rr.Body.String())
+		}
+		page := DebugPage{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatalf("%s: invalid page %q: %v", query, rr.Body.String(), err)
+		}
+		return page
+	}
+	hostname := func(item json.RawMessage) string {
+		svc := &model.Service{}
+		if err := json.Unmarshal(item, svc); err != nil {
+			t.Fatal(err)
+		}
+		return string(svc.Hostname)
+	}
+
+	t.Run("registryz pages", func(t *testing.T) {
+		cases := []struct {
+			query     string
+			total     int
+			items     int
+			firstHost string
+		}{
+			{"start=0&count=10", 2000, 10, "svc-0000.ns-0.svc.cluster.local"},
+			{"start=1995&count=10", 2000, 5, "svc-1995.ns-1.svc.cluster.local"},
+			{"start=2000", 2000, 0, ""},
+			{"start=10", 2000, debugDefaultPageSize, "svc-0010.ns-0.svc.cluster.local"},
+			{"count=5000", 2000, debugMaxPageSize, "svc-0000.ns-0.svc.cluster.local"},
+			{"start=0&namespace=ns-1", 1000, debugDefaultPageSize, "svc-0001.ns-1.svc.cluster.local"},
+			{"start=0&host=svc-001", 10, 10, "svc-0010.ns-0.svc.cluster.local"},
+		}
+		for _, tt := range cases {
+			page := getPage(s.registryz, tt.query)
+			if page.Total != tt.total || len(page.Items) != tt.items {
+				t.Errorf("%s: got %d of %d items, want %d of %d", tt.query, len(page.Items), page.Total, tt.items, tt.total)
+				continue
+			}
+			if tt.items > 0 && hostname(page.Items[0]) != tt.firstHost {
+				t.Errorf("%s: got first service %s, want %s", tt.query, hostname(page.Items[0]), tt.firstHost)
+			}
+		}
+	})
+
+	t.Run("endpointz pages", func(t *testing.T) {
+		page := getPage(s.endpointz, "start=10&count=5")
+		if page.Total != 2000 || len(page.Items) != 5 {
+			t.Fatalf("got %d of %d items, want 5 of 2000", len(page.Items), page.Total)
+		}
+		entry := EndpointzEntry{}
+		if err := json.Unmarshal(page.Items[0], &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Svc != "svc-0010.ns-0.svc.cluster.local:http-main" || len(entry.Ep) != 1 {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+	})
+
+	t.Run("unpaged responses are limited", func(t *testing.T) {
+		for _, handler := range []http.HandlerFunc{s.registryz, s.endpointz} {
+			rr := get(handler, "")
+			if rr.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("expected status 413, got %d", rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), "?start=0&count=") {
+				t.Errorf("expected pagination instructions, got %q", rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("endpointz limit counts endpoints", func(t *testing.T) {
+		sd := &countingDiscovery{ServiceDiscovery: memory.NewServiceDiscovery(nil)}
+		for _, name := range []string{"large-1", "large-2"} {
+			hostname := host.Name(name + ".ns.svc.cluster.local")
+			sd.AddService(hostname, &model.Service{
+				Hostname:   hostname,
+				Attributes: model.ServiceAttributes{Namespace: "ns"},
+				Ports:      model.PortList{{Name: "http-main", Port: 80, Protocol: protocol.HTTP}},
+			})
+			for i := 0; i <= debugUnpagedLimit; i++ {
+				sd.AddEndpoint(hostname, "http-main", 80, fmt.Sprintf("10.1.%d.%d", i/256, i%256), 8080)
+			}
+		}
+		large := &DiscoveryServer{Env: &model.Environment{ServiceDiscovery: sd}}
+		if rr := get(large.endpointz, ""); rr.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected a service with %d endpoints to exceed the limit, got status %d", debugUnpagedLimit+1, rr.Code)
+		}
+		// The request is rejected once the first service exceeds the limit, without looking up the second one.
+		if sd.lookups != 1 {
+			t.Fatalf("expected the endpoints of a single service to be looked up, got %d lookups", sd.lookups)
+		}
+		if rr := get(large.endpointz, "namespace=other"); rr.Code != http.StatusOK {
+			t.Fatalf("expected a filtered response to be allowed, got status %d", rr.Code)
+		}
+		// A page ends once it holds the limit of endpoints, the next one starts after its last item.
+		for start := 0; start < 2; start++ {
+			page := getPage(large.endpointz, fmt.Sprintf("start=%d", start))
+			if page.Total != 2 || len(page.Items) != 1 {
+				t.Fatalf("start=%d: got %d of %d items, want 1 of 2", start, len(page.Items), page.Total)
+			}
+		}
+	})
+
+	t.Run("unpaged responses are backward compatible", func(t *testing.T) {
+		rr := get(s.registryz, "namespace=ns-0")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+			t.Fatal(err)
+		}
+		// The list is terminated with an empty object.
+		if len(items) != 1001 {
+			t.Errorf("expected 1000 services, got %d items", len(items))
+		}
+
+		rr = get(s.endpointz, "host=svc-000")
+		items = nil
+		if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+			t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+		}
+		if len(items) != 11 {
+			t.Errorf("expected 10 service ports, got %d items", len(items))
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"start=-1", "count=0", "start=a"} {
+			if rr := get(s.registryz, query); rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", query, rr.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestPushStatusFilter(t *testing.T) {
+	cases := []struct {
+		query string
+		want  model.StatusFilter
+		err   bool
+	}{
+		{"", model.StatusFilter{}, false},
+		{
+			"metric=pilot_no_ip,pilot_duplicate_envoy_clusters&metric=pilot_eds_no_instances&namespace=default",
+			model.StatusFilter{
+				Metrics:    []string{"pilot_no_ip", "pilot_duplicate_envoy_clusters", "pilot_eds_no_instances"},
+				Namespaces: []string{"default"},
+			},
+			false,
+		},
+		{"limit=10&redact=true", model.StatusFilter{Limit: 10, Redact: true}, false},
+		{"limit=-1", model.StatusFilter{}, true},
+		{"limit=ten", model.StatusFilter{}, true},
+		{"redact=yes", model.StatusFilter{}, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := pushStatusFilter(q)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+)
+
+const (
+	// debugSchemaV1 is the version of the schemas of the typed debug responses.
+	debugSchemaV1 = "v1"
+
+	// DebugSchemaHeader is the response header holding the schema version of a typed debug response.
+	DebugSchemaHeader = "X-Istio-Debug-Schema"
+)
+
+// debugHandler describes a registered debug handler.
+type debugHandler struct {
+	help string
+	// schema is the version of the schema of the response, or empty if the response is not typed.
+	schema string
+}
+
+// DebugHandlerInfo describes a debug endpoint in /debug/index.
+type DebugHandlerInfo struct {
+	Path string `json:"path"`
+	Help string `json:"help"`
+	// Schema is the version of the schema of the response, unset if the response is not typed.
+	Schema string `json:"schema,omitempty"`
+}
+
+// DebugIndex is the v1 schema of /debug/index.
+type DebugIndex struct {
+	Handlers []DebugHandlerInfo `json:"handlers"`
+}
+
+// ConfigzV1 is the v1 schema of /debug/configz: the configs of all the kinds, in their Kubernetes form.
+type ConfigzV1 []*crd.IstioKind
+
+// RegistryzV1 is the v1 schema of /debug/registryz when it is not paginated. As before the schema was declared, the
+// list of services is terminated with an empty object.
+type RegistryzV1 []*model.Service
+
+func (r RegistryzV1) MarshalJSON() ([]byte, error) {
+	out := make([]interface{}, 0, len(r)+1)
+	for _, svc := range r {
+		out = append(out, svc)
+	}
+	out = append(out, struct{}{})
+	return json.Marshal(out)
+}
+
+func (r *RegistryzV1) UnmarshalJSON(b []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return err
+	}
+	if n := len(items); n > 0 && string(bytes.TrimSpace(items[n-1])) == "{}" {
+		items = items[:n-1]
+	}
+	out := make(RegistryzV1, 0, len(items))
+	for _, item := range items {
+		svc := &model.Service{}
+		if err := json.Unmarshal(item, svc); err != nil {
+			return err
+		}
+		out = append(out, svc)
+	}
+	*r = out
+	return nil
+}
+
+// PushStatusV1 is the v1 schema of /debug/push_status. The entries of each metric are top level fields, keyed by
+// metric name, next to the other fields.
+type PushStatusV1 struct {
+	// Metrics are the entries of each proxy status metric, by metric and key.
+	Metrics map[string]map[string]model.ProxyPushStatus `json:"-"`
+	// Degraded are the init phases which failed, and whose data was copied from the previous push context.
+	Degraded map[string]model.DegradedPhase `json:"degraded,omitempty"`
+	// Namespaces summarizes the entries of each metric by namespace.
+	Namespaces map[string][]model.NamespaceCount `json:"namespaces,omitempty"`
+	// DebounceDelay is the adaptive debounce delay in effect, only set if the debounce is adaptive.
+	DebounceDelay string `json:"debounceDelay,omitempty"`
+}
+
+// pushStatusFields are the fields of PushStatusV1 which are not metrics.
+var pushStatusFields = map[string]bool{"degraded": true, "namespaces": true, "debounceDelay": true}
+
+func (p PushStatusV1) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(p.Metrics)+3)
+	for metric, entries := range p.Metrics {
+		out[metric] = entries
+	}
+	if len(p.Degraded) > 0 {
+		out["degraded"] = p.Degraded
+	}
+	if len(p.Namespaces) > 0 {
+		out["namespaces"] = p.Namespaces
+	}
+	if p.DebounceDelay != "" {
+		out["debounceDelay"] = p.DebounceDelay
+	}
+	return json.Marshal(out)
+}
+
+func (p *PushStatusV1) UnmarshalJSON(b []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	// The alias has no methods, so that the known fields are decoded without recursing.
+	type pushStatusFieldsV1 PushStatusV1
+	known := pushStatusFieldsV1{}
+	if err := json.Unmarshal(b, &known); err != nil {
+		return err
+	}
+	*p = PushStatusV1(known)
+	for k, v := range fields {
+		if pushStatusFields[k] {
+			continue
+		}
+		entries := map[string]model.ProxyPushStatus{}
+		if err := json.Unmarshal(v, &entries); err != nil {
+			return fmt.Errorf("invalid metric %s: %v", k, err)
+		}
+		if p.Metrics == nil {
+			p.Metrics = map[string]map[string]model.ProxyPushStatus{}
+		}
+		p.Metrics[k] = entries
+	}
+	return nil
+}
+
+// addVersionedDebugHandler registers a debug handler whose response follows the schema version, which is listed in
+// /debug/index and set in the DebugSchemaHeader of the responses.
+func (s *DiscoveryServer) addVersionedDebugHandler(mux *http.ServeMux, path string, help string, schema string,
+	handler func(http.ResponseWriter, *http.Request)) {
+	s.debugHandlers[path] = debugHandler{help: help, schema: schema}
+	mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(DebugSchemaHeader, schema)
+		handler(w, req)
+	})
+}
+
+// debugIndex lists all the registered debug handlers, with the version of the schema of their response.
+func (s *DiscoveryServer) debugIndex(w http.ResponseWriter, req *http.Request) {
+	index := DebugIndex{Handlers: make([]DebugHandlerInfo, 0, len(s.debugHandlers))}
+	for path, h := range s.debugHandlers {
+		index.Handlers = append(index.Handlers, DebugHandlerInfo{Path: path, Help: h.help, Schema: h.schema})
+	}
+	sort.Slice(index.Handlers, func(i, j int) bool {
+		return index.Handlers[i].Path < index.Handlers[j].Path
+	})
+	writeDebugJSON(w, req, index)
+}
+
+// writeDebugJSON writes the typed debug response, indented unless ?pretty=false.
+func writeDebugJSON(w http.ResponseWriter, req *http.Request, v interface{}) {
+	var b []byte
+	var err error
+	if pretty, perr := strconv.ParseBool(req.URL.Query().Get("pretty")); perr == nil && !pretty {
+		b, err = json.Marshal(v)
+	} else {
+		b, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal debug information: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
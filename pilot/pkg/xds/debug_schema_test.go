@@ -0,0 +1,227 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+const debugSchemaTestConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: external
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+`
+
+func newDebugSchemaTestMux(t *testing.T) (*DiscoveryServer, *http.ServeMux) {
+	t.Helper()
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: debugSchemaTestConfig}).Discovery
+	mux := http.NewServeMux()
+	s.AddDebugHandlers(mux, false, nil)
+	return s, mux
+}
+
+func getDebug(t *testing.T, mux *http.ServeMux, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("%s: unexpected status %d: %s", path, rr.Code, rr.Body.String())
+	}
+	return rr
+}
+
+func TestDebugIndex(t *testing.T) {
+	_, mux := newDebugSchemaTestMux(t)
+	rr := getDebug(t, mux, "/debug/index")
+	if got := rr.Header().Get(DebugSchemaHeader); got != debugSchemaV1 {
+		t.Fatalf("got schema %q, want %q", got, debugSchemaV1)
+	}
+	index := DebugIndex{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &index); err != nil {
+		t.Fatal(err)
+	}
+	// The handlers, sorted by path, with the schema of the versioned ones.
+	want := []struct {
+		path   string
+		schema string
+	}{
+		{"/debug", ""},
+		{"/debug/adsz", debugSchemaV1},
+		{"/debug/adsz?push=true", ""},
+		{"/debug/authorizationz", ""},
+		{"/debug/bundle", ""},
+		{"/debug/cachez", ""},
+		{"/debug/cardinalityz", debugSchemaV1},
+		{"/debug/certz", ""},
+		{"/debug/config_distribution", ""},
+		{"/debug/config_dump", ""},
+		{"/debug/config_quarantinez", ""},
+		{"/debug/configz", debugSchemaV1},
+		{"/debug/configz/diff", ""},
+		{"/debug/destrulez", ""},
+		{"/debug/edsz", ""},
+		{"/debug/endpointShardz", ""},
+		{"/debug/endpointz", ""},
+		{"/debug/force_push", ""},
+		{"/debug/index", debugSchemaV1},
+		{"/debug/inject", ""},
+		{"/debug/instancesz", ""},
+		{"/debug/inventoryz", ""},
+		{"/debug/loadz", ""},
+		{"/debug/mesh", ""},
+		{"/debug/nackz", ""},
+		{"/debug/proxyconfigz", ""},
+		{"/debug/push_status", debugSchemaV1},
+		{"/debug/quarantinez", ""},
+		{"/debug/registryhealthz", ""},
+		{"/debug/registryz", debugSchemaV1},
+		{"/debug/resourcesz", ""},
+		{"/debug/rollout_status", ""},
+		{"/debug/sidecarz", ""},
+		{"/debug/syncz", ""},
+		{"/debug/trustdomainz", ""},
+	}
+	if len(index.Handlers) != len(want) {
+		t.Fatalf("got %d handlers %+v, want %d", len(index.Handlers), index.Handlers, len(want))
+	}
+	for i, h := range index.Handlers {
+		if h.Path != want[i].path || h.Schema != want[i].schema {
+			t.Errorf("got handler %s with schema %q, want %s with schema %q", h.Path, h.Schema, want[i].path, want[i].schema)
+		}
+		if h.Help == "" {
+			t.Errorf("handler %s has no help", h.Path)
+		}
+	}
+
+	compact := getDebug(t, mux, "/debug/index?pretty=false")
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, rr.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if compact.Body.String() != buf.String() {
+		t.Errorf("expected a compact response, got %s", compact.Body.String())
+	}
+}
+
+func TestVersionedDebugResponses(t *testing.T) {
+	_, mux := newDebugSchemaTestMux(t)
+	cases := []struct {
+		path  string
+		typed interface{}
+		check func(t *testing.T, typed interface{})
+	}{
+		{"/debug/adsz", &AdsClients{}, nil},
+		{"/debug/push_status", &PushStatusV1{}, nil},
+		{"/debug/configz", &ConfigzV1{}, func(t *testing.T, typed interface{}) {
+			for _, cfg := range *typed.(*ConfigzV1) {
+				if cfg.Kind == "ServiceEntry" && cfg.Name == "external" && cfg.Spec["hosts"] != nil {
+					return
+				}
+			}
+			t.Errorf("missing ServiceEntry external in %+v", typed)
+		}},
+		{"/debug/registryz", &RegistryzV1{}, func(t *testing.T, typed interface{}) {
+			for _, svc := range *typed.(*RegistryzV1) {
+				if svc.Hostname == "example.com" {
+					return
+				}
+			}
+			t.Errorf("missing service example.com in %+v", typed)
+		}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := getDebug(t, mux, tt.path+"?pretty=false")
+			if got := rr.Header().Get(DebugSchemaHeader); got != debugSchemaV1 {
+				t.Fatalf("got schema %q, want %q", got, debugSchemaV1)
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), tt.typed); err != nil {
+				t.Fatalf("response does not follow the schema: %v: %s", err, rr.Body.String())
+			}
+			if tt.check != nil {
+				tt.check(t, tt.typed)
+			}
+		})
+	}
+}
+
+func TestPushStatusV1RoundTrip(t *testing.T) {
+	status := PushStatusV1{
+		Metrics: map[string]map[string]model.ProxyPushStatus{
+			"pilot_no_ip": {"10.0.0.1": {Proxy: "a.default", Message: "no ip"}},
+		},
+		Degraded:      map[string]model.DegradedPhase{"sidecars": {Error: "failed", ConsecutiveFailures: 2}},
+		Namespaces:    map[string][]model.NamespaceCount{"pilot_no_ip": {{Namespace: "default", Count: 1}}},
+		DebounceDelay: "100ms",
+	}
+	b, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, f := fields["pilot_no_ip"]; !f {
+		t.Errorf("expected the metrics to be top level fields, got %s", b)
+	}
+	got := PushStatusV1{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, status) {
+		t.Errorf("got %+v, want %+v", got, status)
+	}
+}
+
+func TestRegistryzV1RoundTrip(t *testing.T) {
+	services := RegistryzV1{{Hostname: "a.example.com"}, {Hostname: "b.example.com"}}
+	b, err := json.Marshal(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		t.Fatal(err)
+	}
+	// The shape of the response before the schema was declared must be kept.
+	if len(items) != 3 || string(items[2]) != "{}" {
+		t.Errorf("expected the services to be terminated with an empty object, got %s", b)
+	}
+	got := RegistryzV1{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Hostname != "a.example.com" || got[1].Hostname != "b.example.com" {
+		t.Errorf("got %+v, want %+v", got, services)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/labels"
+)
+
+// sidecarz displays the sidecar scope of a connected proxy, or of a workload given by its namespace and labels,
+// and explains whether the host passed with ?host= is imported by it.
+func (s *DiscoveryServer) sidecarz(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	proxyID, namespace := query.Get("proxy"), query.Get("namespace")
+	if (proxyID == "") == (namespace == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide either a proxy or a namespace in the query string"))
+		return
+	}
+
+	push := s.globalPushContext()
+	var scope *model.SidecarScope
+	if proxyID != "" {
+		con := s.getProxyConnection(proxyID)
+		if con == nil || con.proxy.SidecarScope == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Proxy not connected to this Pilot instance"))
+			return
+		}
+		namespace = con.proxy.ConfigNamespace
+		scope = con.proxy.SidecarScope
+	} else {
+		workloadLabels, err := parseWorkloadLabels(query.Get("labels"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		scope = push.SidecarScopeForWorkload(namespace, labels.Collection{workloadLabels})
+	}
+
+	out, err := json.MarshalIndent(scope.Debug(push, namespace, query.Get("host")), "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal sidecar scope: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// parseWorkloadLabels parses labels of the form key1=value1,key2=value2.
+func parseWorkloadLabels(in string) (labels.Instance, error) {
+	out := labels.Instance{}
+	if in == "" {
+		return out, nil
+	}
+	for _, kv := range strings.Split(in, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid label %q, labels must be of the form key1=value1,key2=value2", kv)
+		}
+		out[parts[0]] = parts[1]
+	}
+	return out, nil
+}
@@ -0,0 +1,141 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+const sidecarzConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: a
+  namespace: a
+spec:
+  hosts:
+  - a.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: b
+  namespace: b
+spec:
+  hosts:
+  - b.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: restricted
+  namespace: app
+spec:
+  egress:
+  - hosts:
+    - a/*
+`
+
+func TestSidecarz(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: sidecarzConfig})
+	sidecarz := func(query string) (int, *model.SidecarScopeDebug) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.Discovery.sidecarz(rr, httptest.NewRequest("GET", "/debug/sidecarz?"+query, nil))
+		if rr.Code != http.StatusOK {
+			return rr.Code, nil
+		}
+		out := &model.SidecarScopeDebug{}
+		if err := json.Unmarshal(rr.Body.Bytes(), out); err != nil {
+			t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+		}
+		return rr.Code, out
+	}
+
+	t.Run("default scope", func(t *testing.T) {
+		_, out := sidecarz("namespace=other&host=b.example.com")
+		if out.Sidecar != model.DefaultSidecarName {
+			t.Fatalf("expected the default sidecar, got %v", out.Sidecar)
+		}
+		if len(out.EgressListeners) != 1 || !reflect.DeepEqual(out.EgressListeners[0].Hosts, []string{"*/*"}) {
+			t.Fatalf("unexpected egress listeners %+v", out.EgressListeners)
+		}
+		if !out.Host.Included || out.Host.MatchedEntry != "*/*" {
+			t.Fatalf("expected b.example.com to be imported by */*, got %+v", out.Host)
+		}
+	})
+
+	t.Run("namespace entries", func(t *testing.T) {
+		_, out := sidecarz("namespace=app&labels=app=foo,version=v1&host=a.example.com")
+		if out.Sidecar != "app/restricted" {
+			t.Fatalf("expected the app/restricted sidecar, got %v", out.Sidecar)
+		}
+		if len(out.EgressListeners) != 1 || !reflect.DeepEqual(out.EgressListeners[0].Services, []string{"a/a.example.com"}) {
+			t.Fatalf("unexpected egress listeners %+v", out.EgressListeners)
+		}
+		if !out.Host.Included || out.Host.MatchedEntry != "a/*" || *out.Host.EgressListener != 0 {
+			t.Fatalf("expected a.example.com to be imported by a/*, got %+v", out.Host)
+		}
+	})
+
+	t.Run("excluded host", func(t *testing.T) {
+		_, out := sidecarz("namespace=app&host=b.example.com")
+		if out.Host.Included {
+			t.Fatalf("expected b.example.com not to be imported, got %+v", out.Host)
+		}
+		want := []model.EgressEntryDebug{{
+			EgressListener: 0,
+			Entry:          "a/*",
+			Mismatch:       "the service is in namespaces [b], not a",
+		}}
+		if !reflect.DeepEqual(out.Host.ClosestEntries, want) {
+			t.Fatalf("expected closest entries %+v, got %+v", want, out.Host.ClosestEntries)
+		}
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		_, out := sidecarz("namespace=app&host=unknown.example.com")
+		if out.Host.Included || out.Host.Reason == "" {
+			t.Fatalf("expected unknown.example.com to be explained as unknown, got %+v", out.Host)
+		}
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		for _, query := range []string{"", "namespace=app&proxy=foo", "namespace=app&labels=app"} {
+			if code, _ := sidecarz(query); code != http.StatusBadRequest {
+				t.Errorf("query %q: expected %v, got %v", query, http.StatusBadRequest, code)
+			}
+		}
+		if code, _ := sidecarz("proxy=unknown"); code != http.StatusNotFound {
+			t.Errorf("expected %v for an unknown proxy, got %v", http.StatusNotFound, code)
+		}
+	})
+}
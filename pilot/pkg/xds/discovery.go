@@ -20,6 +20,7 @@ import (
 	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
 	"github.com/google/uuid"
 	"go.uber.org/atomic"
 	"google.golang.org/grpc"
@@ -35,14 +36,16 @@ import (
 	"istio.io/istio/pilot/pkg/util/sets"
 	v2 "istio.io/istio/pilot/pkg/xds/v2"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
+	"istio.io/pkg/monitoring"
 )
 
 var (
 	versionMutex sync.RWMutex
-	// version is the timestamp of the last registry event.
+	// version is the version info of the last full push, see configVersionInfo.
 	version = "0"
-	// versionNum counts versions
+	// versionNum counts legacy versions
 	versionNum = atomic.NewUint64(0)
 
 	periodicRefreshMetrics = 10 * time.Second
@@ -64,6 +67,37 @@ type debounceOptions struct {
 
 	// enableEDSDebounce indicates whether EDS pushes should be debounced.
 	enableEDSDebounce bool
+
+	// debounceAfterMax is the upper bound of debounceAfter when it adapts to the push pressure.
+	// Adaptive debouncing is disabled unless it is greater than debounceAfter.
+	debounceAfterMax time.Duration
+
+	// pushPressure returns the number of proxies waiting to be pushed or being pushed, and the number of connected
+	// proxies.
+	pushPressure func() (pending, clients int)
+}
+
+// effectiveDebounceAfter returns the delay to wait for after the last event before pushing.
+func (o debounceOptions) effectiveDebounceAfter() time.Duration {
+	if o.debounceAfterMax <= o.debounceAfter || o.pushPressure == nil {
+		return o.debounceAfter
+	}
+	pending, clients := o.pushPressure()
+	d := adaptiveDebounceAfter(o.debounceAfter, o.debounceAfterMax, pending, clients)
+	debounceDelay.Record(d.Seconds())
+	return d
+}
+
+// adaptiveDebounceAfter scales the debounce delay between min and max by the fraction of the connected clients
+// waiting for or in a push, so that changes are batched longer while pushes pile up.
+func adaptiveDebounceAfter(min, max time.Duration, pending, clients int) time.Duration {
+	if pending <= 0 || clients <= 0 {
+		return min
+	}
+	if pending >= clients {
+		return max
+	}
+	return min + time.Duration(int64(max-min)*int64(pending)/int64(clients))
 }
 
 // DiscoveryServer is Pilot's gRPC implementation for Envoy's xds APIs
@@ -85,7 +119,7 @@ type DiscoveryServer struct {
 	// Normal istio clients use the default generator - will not be impacted by this.
 	Generators map[string]model.XdsResourceGenerator
 
-	concurrentPushLimit chan struct{}
+	concurrentPushLimit *pushLimiter
 
 	// mutex protecting global structs updated or read by ADS service, including ConfigsUpdated and
 	// shards.
@@ -95,6 +129,9 @@ type DiscoveryServer struct {
 	// incremental updates. This is keyed by service and namespace
 	EndpointShardsByService map[string]map[string]*EndpointShards
 
+	// endpointCardinality applies the limits of the number of endpoints of the services.
+	endpointCardinality *endpointCardinality
+
 	pushChannel chan *model.PushRequest
 
 	// mutex used for config update scheduling (former cache update mutex)
@@ -104,12 +141,15 @@ type DiscoveryServer struct {
 	pushQueue *PushQueue
 
 	// debugHandlers is the list of all the supported debug handlers.
-	debugHandlers map[string]string
+	debugHandlers map[string]debugHandler
 
 	// adsClients reflect active gRPC channels, for both ADS and EDS.
 	adsClients      map[string]*Connection
 	adsClientsMutex sync.RWMutex
 
+	// secretWatchers indexes the connections by the secrets they watch over SDS.
+	secretWatchers *secretWatchers
+
 	StatusReporter DistributionStatusCache
 
 	// Authenticators for XDS requests. Should be same/subset of the CA authenticators.
@@ -121,10 +161,38 @@ type DiscoveryServer struct {
 	// serverReady indicates caches have been synced up and server is ready to process requests.
 	serverReady bool
 
+	// pendingConnections is the number of connections which have not yet been sent their initial config.
+	pendingConnections atomic.Int32
+
 	debounceOptions debounceOptions
 
 	// Cache for XDS resources
 	Cache model.XdsCache
+
+	// edsBatcher merges endpoint updates before they are applied. Nil if batching is disabled.
+	edsBatcher *edsBatcher
+
+	// LoadReporting aggregates the load stats reported by proxies over the Load Reporting Service.
+	LoadReporting *LoadReportingServer
+
+	// PeerCertVerifier holds the root certificates trusted for each trust domain, if TLS is enabled.
+	PeerCertVerifier *spiffe.PeerCertVerifier
+
+	// ConfigQuarantine holds the configs excluded from the push context because proxies rejected them.
+	ConfigQuarantine *model.ConfigQuarantine
+
+	// nackTracker quarantines the configs of pushes rejected by features.NackQuarantineThreshold of the proxies.
+	nackTracker *nackTracker
+
+	// rollouts tracks the distribution of the changed configs to the proxies, for /debug/rollout_status.
+	rollouts *rolloutTracker
+
+	// InstanceID identifies this Istiod instance to the tooling inspecting its connections, e.g. its pod name.
+	InstanceID string
+
+	// lastMeshConfigDiff is the last mesh config change pushed, reported by /debug/mesh.
+	lastMeshConfigDiff      *model.MeshConfigDiff
+	lastMeshConfigDiffMutex sync.RWMutex
 }
 
 // EndpointShards holds the set of endpoint shards of a service. Registries update
@@ -153,18 +221,25 @@ func NewDiscoveryServer(env *model.Environment, plugins []string) *DiscoveryServ
 		Env:                     env,
 		Generators:              map[string]model.XdsResourceGenerator{},
 		EndpointShardsByService: map[string]map[string]*EndpointShards{},
-		concurrentPushLimit:     make(chan struct{}, features.PushThrottle),
+		endpointCardinality:     newEndpointCardinality(),
+		concurrentPushLimit:     newPushLimiter(features.PushThrottle, features.IncrementalPushThrottle),
 		pushChannel:             make(chan *model.PushRequest, 10),
 		pushQueue:               NewPushQueue(),
-		debugHandlers:           map[string]string{},
+		debugHandlers:           map[string]debugHandler{},
 		adsClients:              map[string]*Connection{},
+		secretWatchers:          newSecretWatchers(),
 		serverReady:             false,
 		debounceOptions: debounceOptions{
 			debounceAfter:     features.DebounceAfter,
 			debounceMax:       features.DebounceMax,
 			enableEDSDebounce: features.EnableEDSDebounce.Get(),
+			debounceAfterMax:  features.DebounceAfterMax,
 		},
-		Cache: model.DisabledCache{},
+		Cache:            model.DisabledCache{},
+		LoadReporting:    newLoadReportingServerFromFeatures(),
+		ConfigQuarantine: model.NewConfigQuarantine(),
+		nackTracker:      newNackTracker(),
+		rollouts:         newRolloutTracker(),
 	}
 
 	// Flush cached discovery responses when detecting jwt public key change.
@@ -172,6 +247,16 @@ func NewDiscoveryServer(env *model.Environment, plugins []string) *DiscoveryServ
 		out.ConfigUpdate(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.UnknownTrigger}})
 	}
 
+	if features.EDSBatchWindow > 0 {
+		out.edsBatcher = newEdsBatcher(features.EDSBatchWindow)
+	}
+
+	out.LoadReporting.authenticate = out.authenticate
+
+	out.debounceOptions.pushPressure = func() (int, int) {
+		return out.PushQueueDepth(), out.adsClientCount()
+	}
+
 	out.initGenerators()
 
 	if features.EnableXDSCaching {
@@ -183,14 +268,25 @@ func NewDiscoveryServer(env *model.Environment, plugins []string) *DiscoveryServ
 	return out
 }
 
-// Register adds the ADS handler to the grpc server
+// Register adds the ADS and LRS handlers to the grpc server
 func (s *DiscoveryServer) Register(rpcs *grpc.Server) {
 	// Register v3 server
 	discovery.RegisterAggregatedDiscoveryServiceServer(rpcs, s)
+	lrs.RegisterLoadReportingServiceServer(rpcs, s.LoadReporting)
 }
 
 // CachesSynced is called when caches have been synced so that server can accept connections.
 func (s *DiscoveryServer) CachesSynced() {
+	if features.EnableXDSCacheWarmStart {
+		// The persisted XDS cache is loaded before the server is ready, so that the proxies connecting right away
+		// are served from it. Its entries are checked against a push context of the synced configs.
+		if _, err := s.initPushContext(&model.PushRequest{
+			Full:   true,
+			Reason: []model.TriggerReason{model.GlobalUpdate},
+		}, s.globalPushContext()); err == nil {
+			s.loadXdsCache()
+		}
+	}
 	s.updateMutex.Lock()
 	s.serverReady = true
 	s.updateMutex.Unlock()
@@ -202,6 +298,16 @@ func (s *DiscoveryServer) IsServerReady() bool {
 	return s.serverReady
 }
 
+// PushQueueDepth returns the number of proxies waiting to be pushed or being pushed.
+func (s *DiscoveryServer) PushQueueDepth() int {
+	return s.pushQueue.Backlog()
+}
+
+// PendingConnections returns the number of connections which have not yet been sent their initial config.
+func (s *DiscoveryServer) PendingConnections() int {
+	return int(s.pendingConnections.Load())
+}
+
 func (s *DiscoveryServer) Start(stopCh <-chan struct{}) {
 	go s.handleUpdates(stopCh)
 	go s.periodicRefreshMetrics(stopCh)
@@ -244,12 +350,16 @@ func (s *DiscoveryServer) periodicRefreshMetrics(stopCh <-chan struct{}) {
 			if model.LastPushStatus != push {
 				model.LastPushStatus = push
 				push.UpdateMetrics()
-				out, _ := model.LastPushStatus.StatusJSON()
+				out, _ := model.LastPushStatus.StatusJSON(model.StatusFilter{})
 				adsLog.Infof("Push Status: %s", string(out))
 			}
 			model.LastPushMutex.Unlock()
 
 			push.Mutex.Unlock()
+
+			if s.InternalGen != nil {
+				s.InternalGen.pruneNacks()
+			}
 		case <-stopCh:
 			return
 		}
@@ -278,8 +388,12 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 		return
 	}
 
-	versionLocal := time.Now().Format(time.RFC3339) + "/" + strconv.FormatUint(versionNum.Load(), 10)
-	versionNum.Inc()
+	versionLocal := push.Version
+	if versionLocal == "" {
+		// Without a config version, fall back to a version local to this instance.
+		versionLocal = time.Now().Format(time.RFC3339) + "/" + strconv.FormatUint(versionNum.Load(), 10)
+		versionNum.Inc()
+	}
 	initContextTime := time.Since(t0)
 	adsLog.Debugf("InitContext %v for push took %s", versionLocal, initContextTime)
 
@@ -287,6 +401,9 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 	version = versionLocal
 	versionMutex.Unlock()
 
+	s.nackTracker.recordPush(versionLocal, req.ConfigsUpdated)
+	s.rollouts.recordPush(versionLocal, rolloutConfigs(req))
+
 	req.Push = push
 	go s.AdsPushAll(versionLocal, req)
 }
@@ -315,6 +432,19 @@ func (s *DiscoveryServer) ConfigUpdate(req *model.PushRequest) {
 	s.pushChannel <- req
 }
 
+// MeshConfigUpdate requests the push of a mesh config change, restricted to the XDS types it affects.
+func (s *DiscoveryServer) MeshConfigUpdate(diff model.MeshConfigDiff) {
+	adsLog.Infof("mesh config fields %v changed, affecting %v", diff.Fields, diff.Impact)
+	s.lastMeshConfigDiffMutex.Lock()
+	s.lastMeshConfigDiff = &diff
+	s.lastMeshConfigDiffMutex.Unlock()
+	s.ConfigUpdate(&model.PushRequest{
+		Full:             true,
+		Reason:           []model.TriggerReason{model.GlobalUpdate},
+		MeshConfigImpact: diff.Impact,
+	})
+}
+
 // Debouncing and push request happens in a separate thread, it uses locks
 // and we want to avoid complications, ConfigUpdate may already hold other locks.
 // handleUpdates processes events from pushChannel
@@ -347,8 +477,9 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 	pushWorker := func() {
 		eventDelay := time.Since(startDebounce)
 		quietTime := time.Since(lastConfigUpdateTime)
+		debounceAfter := opts.effectiveDebounceAfter()
 		// it has been too long or quiet enough
-		if eventDelay >= opts.debounceMax || quietTime >= opts.debounceAfter {
+		if eventDelay >= opts.debounceMax || quietTime >= debounceAfter {
 			if req != nil {
 				pushCounter++
 				adsLog.Infof("Push debounce stable[%d] %d: %v since last change, %v since last push, full=%v",
@@ -361,7 +492,7 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 				debouncedEvents = 0
 			}
 		} else {
-			timeChan = time.After(opts.debounceAfter - quietTime)
+			timeChan = time.After(debounceAfter - quietTime)
 		}
 	}
 
@@ -383,7 +514,7 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 
 			lastConfigUpdateTime = time.Now()
 			if debouncedEvents == 0 {
-				timeChan = time.After(opts.debounceAfter)
+				timeChan = time.After(opts.effectiveDebounceAfter())
 				startDebounce = lastConfigUpdateTime
 			}
 			debouncedEvents++
@@ -399,7 +530,53 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 	}
 }
 
-func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQueue) {
+// pushLimiter splits the concurrent pushes into a pool for full pushes and a pool for incremental pushes, as
+// incremental pushes are much cheaper than full pushes and a burst of either should not starve the other.
+type pushLimiter struct {
+	full        chan struct{}
+	incremental chan struct{}
+}
+
+// newPushLimiter returns a limiter of total concurrent pushes, of which incremental are reserved for the incremental
+// pushes and the others for the full pushes. Each pool has at least a slot; with a total of 1, the pools are shared.
+func newPushLimiter(total, incremental int) *pushLimiter {
+	if total < 2 {
+		pool := make(chan struct{}, 1)
+		return &pushLimiter{full: pool, incremental: pool}
+	}
+	if incremental < 1 {
+		incremental = 1
+	}
+	if incremental > total-1 {
+		incremental = total - 1
+	}
+	return &pushLimiter{
+		full:        make(chan struct{}, total-incremental),
+		incremental: make(chan struct{}, incremental),
+	}
+}
+
+// doSendPushes pushes the proxies of the queue, with a worker per pool of the limiter. A shared pool has a single
+// worker pushing both classes, as a worker holds a slot while it waits for a push of its class.
+func doSendPushes(stopCh <-chan struct{}, limiter *pushLimiter, queue *PushQueue) {
+	if limiter.full == limiter.incremental {
+		sendPoolPushes(stopCh, limiter.full, sharedPushPoolWaitTime, queue, anyPush)
+		return
+	}
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sendPoolPushes(stopCh, limiter.full, fullPushPoolWaitTime, queue, fullPush)
+	}()
+	sendPoolPushes(stopCh, limiter.incremental, incrementalPushPoolWaitTime, queue, incrementalPush)
+	wg.Wait()
+}
+
+// sendPoolPushes pushes the proxies of the queue pending a push of the class, with at most as many concurrent
+// pushes as the slots of the pool.
+func sendPoolPushes(stopCh <-chan struct{}, pool chan struct{}, waitTime monitoring.Metric, queue *PushQueue,
+	class pushClass) {
 	for {
 		select {
 		case <-stopCh:
@@ -407,19 +584,26 @@ func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQu
 		default:
 			// We can send to it until it is full, then it will block until a pushes finishes and reads from it.
 			// This limits the number of pushes that can happen concurrently
-			semaphore <- struct{}{}
+			start := time.Now()
+			select {
+			case pool <- struct{}{}:
+			case <-stopCh:
+				return
+			}
+			waitTime.Record(time.Since(start).Seconds())
 
 			// Get the next proxy to push. This will block if there are no updates required.
-			client, push, shuttingdown := queue.Dequeue()
+			client, push, shuttingdown := queue.dequeue(class)
 
 			if shuttingdown {
+				<-pool
 				return
 			}
 			recordPushTriggers(push.Reason...)
-			// Signals that a push is done by reading from the semaphore, allowing another send on it.
+			// Signals that a push is done by reading from the pool, allowing another send on it.
 			doneFunc := func() {
 				queue.MarkDone(client)
-				<-semaphore
+				<-pool
 			}
 
 			proxiesQueueTime.Record(time.Since(push.Start).Seconds())
@@ -455,6 +639,8 @@ func (s *DiscoveryServer) initPushContext(req *model.PushRequest, oldPushContext
 	if err := s.UpdateServiceShards(push); err != nil {
 		return nil, err
 	}
+	s.endpointCardinality.recordMetrics(push)
+	s.ConfigQuarantine.AddMetrics(push)
 
 	s.updateMutex.Lock()
 	s.Env.PushContext = push
@@ -475,6 +661,7 @@ func (s *DiscoveryServer) initGenerators() {
 	s.Generators[v3.RouteType] = &RdsGenerator{Server: s}
 	s.Generators[v3.EndpointType] = edsGen
 	s.Generators[v3.NameTableType] = &NdsGenerator{Server: s}
+	s.Generators[v3.ProxyConfigType] = &ProxyConfigGenerator{}
 
 	s.Generators["grpc"] = &grpcgen.GrpcConfigGenerator{}
 	epGen := &EdsV2Generator{edsGen}
@@ -490,5 +677,8 @@ func (s *DiscoveryServer) initGenerators() {
 
 // shutdown shutsdown DiscoveryServer components.
 func (s *DiscoveryServer) Shutdown() {
+	if features.EnableXDSCacheWarmStart {
+		s.saveXdsCache()
+	}
 	s.pushQueue.ShutDown()
 }
@@ -61,7 +61,7 @@ func TestSendPushesManyPushes(t *testing.T) {
 	stopCh := make(chan struct{})
 	defer close(stopCh)
 
-	semaphore := make(chan struct{}, 2)
+	limiter := newPushLimiter(4, 2)
 	queue := NewPushQueue()
 	defer queue.ShutDown()
 
@@ -87,7 +87,7 @@ func TestSendPushesManyPushes(t *testing.T) {
 			}
 		}()
 	}
-	go doSendPushes(stopCh, semaphore, queue)
+	go doSendPushes(stopCh, limiter, queue)
 
 	for push := 0; push < 100; push++ {
 		for _, proxy := range proxies {
@@ -111,7 +111,7 @@ func TestSendPushesSinglePush(t *testing.T) {
 	stopCh := make(chan struct{})
 	defer close(stopCh)
 
-	semaphore := make(chan struct{}, 2)
+	limiter := newPushLimiter(4, 2)
 	queue := NewPushQueue()
 	defer queue.ShutDown()
 
@@ -141,7 +141,7 @@ func TestSendPushesSinglePush(t *testing.T) {
 			}
 		}()
 	}
-	go doSendPushes(stopCh, semaphore, queue)
+	go doSendPushes(stopCh, limiter, queue)
 
 	for _, proxy := range proxies {
 		queue.Enqueue(proxy, &model.PushRequest{Push: &model.PushContext{}})
@@ -162,6 +162,138 @@ func TestSendPushesSinglePush(t *testing.T) {
 	}
 }
 
+func TestSendPushesSeparatePools(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	limiter := newPushLimiter(4, 2)
+	queue := NewPushQueue()
+	defer queue.ShutDown()
+
+	proxies := createProxies(6)
+	full, incremental := proxies[:4], proxies[4:]
+
+	// Receivers of full pushes never complete them, so the full pool is saturated after two pushes.
+	fullPushes := int32(0)
+	for _, proxy := range full {
+		proxy := proxy
+		go func() {
+			select {
+			case <-proxy.pushChannel:
+				atomic.AddInt32(&fullPushes, 1)
+			case <-stopCh:
+			}
+		}()
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(len(incremental))
+	for _, proxy := range incremental {
+		proxy := proxy
+		go func() {
+			select {
+			case p := <-proxy.pushChannel:
+				if p.pushRequest.Full {
+					t.Errorf("expected incremental push for %v", proxy.ConID)
+				}
+				p.done()
+				wg.Done()
+			case <-stopCh:
+			}
+		}()
+	}
+	go doSendPushes(stopCh, limiter, queue)
+
+	for _, proxy := range full {
+		queue.Enqueue(proxy, &model.PushRequest{Full: true, Push: &model.PushContext{}})
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if got := atomic.LoadInt32(&fullPushes); got != 2 {
+			return fmt.Errorf("expected 2 full pushes, got %v", got)
+		}
+		return nil
+	}, retry.Timeout(time.Second))
+
+	for _, proxy := range incremental {
+		queue.Enqueue(proxy, &model.PushRequest{Push: &model.PushContext{}})
+	}
+	if !wgDoneOrTimeout(wg, time.Second) {
+		t.Fatalf("expected incremental pushes to proceed while the full push pool is saturated")
+	}
+	if got := atomic.LoadInt32(&fullPushes); got != 2 {
+		t.Fatalf("expected full pushes to stay limited to 2, got %v", got)
+	}
+	// The full pushes waiting for a slot are still queued.
+	if got := queue.Pending(); got != 2 {
+		t.Fatalf("expected 2 pending full pushes, got %v", got)
+	}
+}
+
+func TestSendPushesSharedPool(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	limiter := newPushLimiter(1, 1)
+	queue := NewPushQueue()
+	defer queue.ShutDown()
+
+	proxies := createProxies(4)
+	incremental, full := proxies[:2], proxies[2:]
+
+	wg := &sync.WaitGroup{}
+	for _, proxy := range proxies {
+		proxy := proxy
+		go func() {
+			for {
+				select {
+				case p := <-proxy.pushChannel:
+					p.done()
+					wg.Done()
+				case <-stopCh:
+					return
+				}
+			}
+		}()
+	}
+	go doSendPushes(stopCh, limiter, queue)
+
+	// Only incremental pushes, then only full pushes, are queued: neither class waits for the other.
+	for _, class := range []struct {
+		proxies []*Connection
+		full    bool
+	}{{incremental, false}, {full, true}} {
+		wg.Add(len(class.proxies))
+		for _, proxy := range class.proxies {
+			queue.Enqueue(proxy, &model.PushRequest{Full: class.full, Push: &model.PushContext{}})
+		}
+		if !wgDoneOrTimeout(wg, time.Second) {
+			t.Fatalf("expected the pushes (full=%v) to proceed with a shared pool", class.full)
+		}
+	}
+}
+
+func TestNewPushLimiter(t *testing.T) {
+	cases := []struct {
+		total, incremental        int
+		wantFull, wantIncremental int
+	}{
+		{100, 20, 80, 20},
+		{100, 0, 99, 1},
+		{100, 100, 1, 99},
+		{2, 2, 1, 1},
+	}
+	for _, tt := range cases {
+		l := newPushLimiter(tt.total, tt.incremental)
+		if cap(l.full) != tt.wantFull || cap(l.incremental) != tt.wantIncremental {
+			t.Errorf("newPushLimiter(%d, %d) got pools of %d full and %d incremental pushes, want %d and %d",
+				tt.total, tt.incremental, cap(l.full), cap(l.incremental), tt.wantFull, tt.wantIncremental)
+		}
+	}
+	// A single push at a time is shared by both classes.
+	if l := newPushLimiter(1, 1); l.full != l.incremental || cap(l.full) != 1 {
+		t.Errorf("expected a single shared pool")
+	}
+}
+
 type fakeStream struct {
 	grpc.ServerStream
 }
@@ -324,6 +456,63 @@ func TestDebounce(t *testing.T) {
 	}
 }
 
+func TestAdaptiveDebounce(t *testing.T) {
+	clients := 100
+	pending := 0
+	opts := debounceOptions{
+		debounceAfter:    100 * time.Millisecond,
+		debounceAfterMax: time.Second,
+		pushPressure: func() (int, int) {
+			return pending, clients
+		},
+	}
+
+	// A mass rollout piles up pushes, then the queue drains.
+	backlog := []int{0, 10, 50, 100, 200, 50, 0}
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		190 * time.Millisecond,
+		550 * time.Millisecond,
+		time.Second,
+		time.Second,
+		550 * time.Millisecond,
+		100 * time.Millisecond,
+	}
+	for i, p := range backlog {
+		pending = p
+		if got := opts.effectiveDebounceAfter(); got != expected[i] {
+			t.Errorf("with %d of %d clients pending: got delay %v, want %v", p, clients, got, expected[i])
+		}
+	}
+
+	clients = 0
+	pending = 10
+	if got := opts.effectiveDebounceAfter(); got != opts.debounceAfter {
+		t.Errorf("without clients: got delay %v, want %v", got, opts.debounceAfter)
+	}
+
+	opts.debounceAfterMax = 0
+	clients = 100
+	pending = 100
+	if got := opts.effectiveDebounceAfter(); got != opts.debounceAfter {
+		t.Errorf("with adaptive debounce disabled: got delay %v, want %v", got, opts.debounceAfter)
+	}
+}
+
+func TestPushPressure(t *testing.T) {
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+	for i := 0; i < 2; i++ {
+		s.pushQueue.Enqueue(&Connection{ConID: fmt.Sprint(i)}, &model.PushRequest{Full: true})
+	}
+	// The pushes in flight are still part of the backlog, although they left the queue.
+	for i := 0; i < 2; i++ {
+		s.pushQueue.Dequeue()
+	}
+	if pending, _ := s.debounceOptions.pushPressure(); pending != 2 {
+		t.Fatalf("expected 2 pushes in the backlog, got %d", pending)
+	}
+}
+
 func TestShouldRespond(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -454,3 +643,26 @@ func TestShouldRespond(t *testing.T) {
 		})
 	}
 }
+
+func TestAddedResourceNames(t *testing.T) {
+	cases := []struct {
+		name     string
+		previous []string
+		current  []string
+		want     []string
+	}{
+		{"added", []string{"a"}, []string{"a", "b"}, []string{"b"}},
+		{"added and removed", []string{"a", "b"}, []string{"a", "c"}, nil},
+		{"unchanged", []string{"a", "b"}, []string{"b", "a"}, nil},
+		{"wildcard before", nil, []string{"a"}, nil},
+		{"wildcard after", []string{"a"}, nil, nil},
+		{"duplicates", []string{"a"}, []string{"a", "b", "b"}, []string{"b"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addedResourceNames(tt.previous, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -77,7 +77,13 @@ func (s *DiscoveryServer) SvcUpdate(cluster, hostname string, namespace string,
 	// prevent memory leaks.
 	if event == model.EventDelete {
 		inboundServiceDeletes.Increment()
-		s.deleteService(cluster, hostname, namespace)
+		if s.edsBatcher != nil {
+			s.edsBatcher.applyNow(edsBatchKey{cluster: cluster, hostname: hostname, namespace: namespace}, func() {
+				s.deleteService(cluster, hostname, namespace)
+			})
+		} else {
+			s.deleteService(cluster, hostname, namespace)
+		}
 	} else {
 		inboundServiceUpdates.Increment()
 	}
@@ -91,17 +97,44 @@ func (s *DiscoveryServer) SvcUpdate(cluster, hostname string, namespace string,
 func (s *DiscoveryServer) EDSUpdate(clusterID, serviceName string, namespace string,
 	istioEndpoints []*model.IstioEndpoint) {
 	inboundEDSUpdates.Increment()
+	if s.edsBatcher != nil {
+		key := edsBatchKey{cluster: clusterID, hostname: serviceName, namespace: namespace}
+		// Updates which require a full push are applied right away; batching is only meant to absorb endpoint churn.
+		if !s.edsUpdateRequiresFullPush(serviceName, namespace, istioEndpoints) {
+			edsBatchedUpdates.Increment()
+			s.edsBatcher.add(key, istioEndpoints, func(key edsBatchKey, endpoints []*model.IstioEndpoint) {
+				s.edsUpdate(key.cluster, key.hostname, key.namespace, endpoints)
+			})
+			return
+		}
+		edsImmediateUpdates.Increment()
+		s.edsBatcher.applyNow(key, func() {
+			s.edsUpdate(clusterID, serviceName, namespace, istioEndpoints)
+		})
+		return
+	}
+	edsImmediateUpdates.Increment()
+	s.edsUpdate(clusterID, serviceName, namespace, istioEndpoints)
+}
+
+// edsUpdate updates the endpoint shards and triggers a push.
+func (s *DiscoveryServer) edsUpdate(clusterID, serviceName string, namespace string,
+	istioEndpoints []*model.IstioEndpoint) {
 	// Update the endpoint shards
-	fp := s.edsCacheUpdate(clusterID, serviceName, namespace, istioEndpoints)
+	fp, updated := s.edsCacheUpdateShards(clusterID, serviceName, namespace, istioEndpoints)
+	configsUpdated := map[model.ConfigKey]struct{}{{
+		Kind:      gvk.ServiceEntry,
+		Name:      serviceName,
+		Namespace: namespace,
+	}: {}}
+	for _, u := range updated {
+		configsUpdated[model.ConfigKey{Kind: gvk.ServiceEntry, Name: u.hostname, Namespace: u.namespace}] = struct{}{}
+	}
 	// Trigger a push
 	s.ConfigUpdate(&model.PushRequest{
-		Full: fp,
-		ConfigsUpdated: map[model.ConfigKey]struct{}{{
-			Kind:      gvk.ServiceEntry,
-			Name:      serviceName,
-			Namespace: namespace,
-		}: {}},
-		Reason: []model.TriggerReason{model.EndpointUpdate},
+		Full:           fp,
+		ConfigsUpdated: configsUpdated,
+		Reason:         []model.TriggerReason{model.EndpointUpdate},
 	})
 }
 
@@ -123,6 +156,30 @@ func (s *DiscoveryServer) EDSCacheUpdate(clusterID, serviceName string, namespac
 // It also tracks the changes to ServiceAccounts. It returns whether a full push
 // is needed or incremental push is sufficient.
 func (s *DiscoveryServer) edsCacheUpdate(clusterID, hostname string, namespace string,
+	istioEndpoints []*model.IstioEndpoint) bool {
+	fullPush, _ := s.edsCacheUpdateShards(clusterID, hostname, namespace, istioEndpoints)
+	return fullPush
+}
+
+// edsCacheUpdateShards is edsCacheUpdate, also returning the other services of the cluster whose endpoints were
+// updated because the cardinality limits reallocated their share of the cluster.
+func (s *DiscoveryServer) edsCacheUpdateShards(clusterID, hostname string, namespace string,
+	istioEndpoints []*model.IstioEndpoint) (bool, []shardUpdate) {
+	istioEndpoints, violation, updated := s.endpointCardinality.limit(clusterID, hostname, namespace, istioEndpoints)
+	if violation != nil {
+		s.globalPushContext().AddMetric(model.EndpointCardinalityLimitExceeded, violation.Key(), "", violation.String())
+	}
+	fullPush := s.updateEndpointShard(clusterID, hostname, namespace, istioEndpoints)
+	for _, u := range updated {
+		if s.updateEndpointShard(clusterID, u.hostname, u.namespace, u.endpoints) {
+			fullPush = true
+		}
+	}
+	return fullPush, updated
+}
+
+// updateEndpointShard sets the endpoints of the service in the cluster, and returns whether a full push is needed.
+func (s *DiscoveryServer) updateEndpointShard(clusterID, hostname string, namespace string,
 	istioEndpoints []*model.IstioEndpoint) bool {
 	if len(istioEndpoints) == 0 {
 		// Should delete the service EndpointShards when endpoints become zero to prevent memory leak,
@@ -167,6 +224,31 @@ func (s *DiscoveryServer) edsCacheUpdate(clusterID, hostname string, namespace s
 	return fullPush
 }
 
+// edsUpdateRequiresFullPush reports whether edsCacheUpdate would request a full push for the endpoints of the
+// shard, because the service has not been seen before or the service accounts change. The service accounts are
+// compared the same way as in updateEndpointShard, so that removed service accounts are detected as well.
+func (s *DiscoveryServer) edsUpdateRequiresFullPush(hostname, namespace string,
+	istioEndpoints []*model.IstioEndpoint) bool {
+	if len(istioEndpoints) == 0 {
+		return false
+	}
+	s.mutex.RLock()
+	ep, f := s.EndpointShardsByService[hostname][namespace]
+	s.mutex.RUnlock()
+	if !f {
+		return true
+	}
+	serviceAccounts := sets.Set{}
+	for _, e := range istioEndpoints {
+		if e.ServiceAccount != "" {
+			serviceAccounts.Insert(e.ServiceAccount)
+		}
+	}
+	ep.mutex.RLock()
+	defer ep.mutex.RUnlock()
+	return !serviceAccounts.Equals(ep.ServiceAccounts)
+}
+
 func (s *DiscoveryServer) getOrCreateEndpointShard(serviceName, namespace string) (*EndpointShards, bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -189,6 +271,7 @@ func (s *DiscoveryServer) getOrCreateEndpointShard(serviceName, namespace string
 
 // deleteEndpointShards deletes matching endpoint shards from EndpointShardsByService map. This is called when
 // endpoints are deleted.
+// The cardinality limits are not updated: the shard may be emptied by the limits themselves.
 func (s *DiscoveryServer) deleteEndpointShards(cluster, serviceName, namespace string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -203,6 +286,10 @@ func (s *DiscoveryServer) deleteEndpointShards(cluster, serviceName, namespace s
 // deleteService deletes all service related references from EndpointShardsByService. This is called
 // when a service is deleted.
 func (s *DiscoveryServer) deleteService(cluster, serviceName, namespace string) {
+	// The deletion of a service triggers a full push, which includes the services given its share of the cluster.
+	for _, u := range s.endpointCardinality.remove(cluster, serviceName, namespace) {
+		s.updateEndpointShard(cluster, u.hostname, u.namespace, u.endpoints)
+	}
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -240,20 +327,25 @@ func (s *DiscoveryServer) loadAssignmentsForCluster(b EndpointBuilder) *endpoint
 	// against such behavior and returns nil. When the updated cluster warms up in Envoy, it would update with new endpoints
 	// automatically.
 	// Gateways use EDS for Passthrough cluster. So we should allow Passthrough here.
-	if b.service.Resolution == model.DNSLB {
-		adsLog.Infof("cluster %s in eds cluster, but its resolution now is updated to %v, skipping it.", b.clusterName, b.service.Resolution)
+	svc, hostname, port := b.service, b.hostname, b.port
+	if b.redirected() {
+		// The endpoints of a hostname routed through an egress gateway are those of the gateway.
+		svc, hostname, port = b.endpointService, b.endpointService.Hostname, b.endpointPort
+	}
+	if svc.Resolution == model.DNSLB {
+		adsLog.Infof("cluster %s in eds cluster, but its resolution now is updated to %v, skipping it.", b.clusterName, svc.Resolution)
 		return nil
 	}
 
-	svcPort, f := b.service.Ports.GetByPort(b.port)
+	svcPort, f := svc.Ports.GetByPort(port)
 	if !f {
 		// Shouldn't happen here
-		adsLog.Debugf("can not find the service port %d for cluster %s", b.port, b.clusterName)
+		adsLog.Debugf("can not find the service port %d for cluster %s", port, b.clusterName)
 		return buildEmptyClusterLoadAssignment(b.clusterName)
 	}
 
 	s.mutex.RLock()
-	epShards, f := s.EndpointShardsByService[string(b.hostname)][b.service.Attributes.Namespace]
+	epShards, f := s.EndpointShardsByService[string(hostname)][svc.Attributes.Namespace]
 	s.mutex.RUnlock()
 	if !f {
 		// Shouldn't happen here
@@ -344,6 +436,10 @@ func (eds *EdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w
 	if !edsNeedsPush(req.ConfigsUpdated) {
 		return nil
 	}
+	// The endpoints of the clusters pushed are pushed with them, so that they are not warmed without endpoints.
+	if !req.MeshConfigImpact.Affects(model.MeshImpactEndpoints | model.MeshImpactClusters) {
+		return nil
+	}
 	var edsUpdatedServices map[string]struct{}
 	if !req.Full {
 		edsUpdatedServices = model.ConfigNamesOfKind(req.ConfigsUpdated, gvk.ServiceEntry)
@@ -357,9 +453,13 @@ func (eds *EdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w
 		if edsUpdatedServices != nil {
 			_, _, hostname, _ := model.ParseSubsetKey(clusterName)
 			if _, ok := edsUpdatedServices[string(hostname)]; !ok {
-				// Cluster was not updated, skip recomputing. This happens when we get an incremental update for a
-				// specific Hostname. On connect or for full push edsUpdatedServices will be empty.
-				continue
+				// The endpoints of the hostnames routed through an egress gateway are those of the gateway.
+				gw, redirected := push.EgressGatewayHostname(proxy, hostname)
+				if _, gwOk := edsUpdatedServices[string(gw)]; !redirected || !gwOk {
+					// Cluster was not updated, skip recomputing. This happens when we get an incremental update for a
+					// specific Hostname. On connect or for full push edsUpdatedServices will be empty.
+					continue
+				}
 			}
 		}
 		builder := NewEndpointBuilder(clusterName, proxy, push)
@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// edsBatchKey identifies the endpoints of a service in a single shard.
+type edsBatchKey struct {
+	cluster   string
+	hostname  string
+	namespace string
+}
+
+type edsBatch struct {
+	endpoints []*model.IstioEndpoint
+	timer     *time.Timer
+}
+
+// edsBatcher coalesces endpoint updates for the same shard and hostname arriving within a window.
+// Registries send the full endpoint set of a shard on every update, so only the last update of a
+// window needs to be applied. During a rolling deployment this turns a push per pod into a push per window.
+type edsBatcher struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[edsBatchKey]*edsBatch
+
+	// applyMu orders flushes with the updates applied by applyNow. It is acquired before mu, and held while
+	// applying updates, so that add does not wait for them.
+	applyMu sync.Mutex
+}
+
+func newEdsBatcher(window time.Duration) *edsBatcher {
+	return &edsBatcher{
+		window:  window,
+		pending: map[edsBatchKey]*edsBatch{},
+	}
+}
+
+// add records endpoints as the latest state for key. flush is called with the latest endpoints once the
+// window for key expires; the window starts with the first update for the key. Flushes are ordered with the updates
+// applied by applyNow, but do not block add.
+func (b *edsBatcher) add(key edsBatchKey, endpoints []*model.IstioEndpoint, flush func(edsBatchKey, []*model.IstioEndpoint)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if batch, f := b.pending[key]; f {
+		batch.endpoints = endpoints
+		return
+	}
+	batch := &edsBatch{endpoints: endpoints}
+	b.pending[key] = batch
+	batch.timer = time.AfterFunc(b.window, func() {
+		b.applyMu.Lock()
+		defer b.applyMu.Unlock()
+		b.mu.Lock()
+		if b.pending[key] != batch {
+			// cancelled, and possibly replaced by a newer batch with its own timer.
+			b.mu.Unlock()
+			return
+		}
+		delete(b.pending, key)
+		endpoints := batch.endpoints
+		b.mu.Unlock()
+		flush(key, endpoints)
+	})
+}
+
+// applyNow drops any pending update for key and calls apply, which updates key out of band. Otherwise the pending
+// update, or one being flushed concurrently, would overwrite it with stale endpoints.
+func (b *edsBatcher) applyNow(key edsBatchKey, apply func()) {
+	b.applyMu.Lock()
+	defer b.applyMu.Unlock()
+	b.mu.Lock()
+	if batch, f := b.pending[key]; f {
+		batch.timer.Stop()
+		delete(b.pending, key)
+	}
+	b.mu.Unlock()
+	apply()
+}
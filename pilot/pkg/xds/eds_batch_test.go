@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+const batchWindow = 100 * time.Millisecond
+
+func newBatchingServer(hostnames ...string) *DiscoveryServer {
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+	s.edsBatcher = newEdsBatcher(batchWindow)
+	s.pushChannel = make(chan *model.PushRequest, 100)
+	// seed the shards, so that further updates are incremental and can be batched
+	for _, h := range hostnames {
+		s.edsCacheUpdate("cluster", h, "ns", makeEndpoints(1))
+	}
+	return s
+}
+
+func makeEndpoints(n int) []*model.IstioEndpoint {
+	eps := make([]*model.IstioEndpoint, 0, n)
+	for i := 0; i < n; i++ {
+		eps = append(eps, &model.IstioEndpoint{Address: fmt.Sprintf("10.0.0.%d", i), ServicePortName: "http"})
+	}
+	return eps
+}
+
+// collectPushes returns all the push requests triggered until twice the batch window elapsed.
+func collectPushes(s *DiscoveryServer) []*model.PushRequest {
+	var pushes []*model.PushRequest
+	timeout := time.After(2 * batchWindow)
+	for {
+		select {
+		case req := <-s.pushChannel:
+			pushes = append(pushes, req)
+		case <-timeout:
+			return pushes
+		}
+	}
+}
+
+func TestEDSUpdateBatching(t *testing.T) {
+	s := newBatchingServer("a.com")
+	for i := 1; i <= 50; i++ {
+		s.EDSUpdate("cluster", "a.com", "ns", makeEndpoints(i))
+	}
+	pushes := collectPushes(s)
+	if len(pushes) != 1 {
+		t.Fatalf("expected a single push, got %d", len(pushes))
+	}
+	if pushes[0].Full {
+		t.Fatalf("expected an incremental push")
+	}
+	if got := len(s.EndpointShardsByService["a.com"]["ns"].Shards["cluster"]); got != 50 {
+		t.Fatalf("expected final state of 50 endpoints, got %d", got)
+	}
+}
+
+func TestEDSUpdateBatchingPerHostname(t *testing.T) {
+	s := newBatchingServer("a.com", "b.com")
+	for i := 1; i <= 10; i++ {
+		s.EDSUpdate("cluster", "a.com", "ns", makeEndpoints(i))
+		s.EDSUpdate("cluster", "b.com", "ns", makeEndpoints(i+1))
+	}
+	pushes := collectPushes(s)
+	if len(pushes) != 2 {
+		t.Fatalf("expected a push per hostname, got %d", len(pushes))
+	}
+	pushed := map[string]struct{}{}
+	for _, p := range pushes {
+		for k := range p.ConfigsUpdated {
+			pushed[k.Name] = struct{}{}
+		}
+	}
+	if len(pushed) != 2 {
+		t.Fatalf("expected pushes for both hostnames, got %v", pushed)
+	}
+	if got := len(s.EndpointShardsByService["a.com"]["ns"].Shards["cluster"]); got != 10 {
+		t.Fatalf("expected final state of 10 endpoints for a.com, got %d", got)
+	}
+	if got := len(s.EndpointShardsByService["b.com"]["ns"].Shards["cluster"]); got != 11 {
+		t.Fatalf("expected final state of 11 endpoints for b.com, got %d", got)
+	}
+}
+
+func TestEDSUpdateBatchingBypass(t *testing.T) {
+	s := newBatchingServer()
+	// a new service requires a full push, which is never delayed
+	s.EDSUpdate("cluster", "new.com", "ns", makeEndpoints(1))
+	select {
+	case req := <-s.pushChannel:
+		if !req.Full {
+			t.Fatalf("expected a full push for a new service")
+		}
+	default:
+		t.Fatalf("expected an immediate push for a new service")
+	}
+
+	// a pending batch must not resurrect a deleted service
+	s.EDSUpdate("cluster", "new.com", "ns", makeEndpoints(2))
+	s.SvcUpdate("cluster", "new.com", "ns", model.EventDelete)
+	if pushes := collectPushes(s); len(pushes) != 0 {
+		t.Fatalf("expected no push after delete, got %d", len(pushes))
+	}
+	if _, f := s.EndpointShardsByService["new.com"]; f {
+		t.Fatalf("expected service shards to be deleted")
+	}
+}
+
+func TestEDSUpdateBatchingServiceAccountChange(t *testing.T) {
+	s := newBatchingServer()
+	withServiceAccounts := func(sas ...string) []*model.IstioEndpoint {
+		eps := makeEndpoints(len(sas))
+		for i, sa := range sas {
+			eps[i].ServiceAccount = sa
+		}
+		return eps
+	}
+	s.edsCacheUpdate("cluster", "a.com", "ns", withServiceAccounts("sa-1", "sa-2"))
+
+	expectFullPush := func(reason string) {
+		t.Helper()
+		select {
+		case req := <-s.pushChannel:
+			if !req.Full {
+				t.Fatalf("expected a full push for %s", reason)
+			}
+		default:
+			t.Fatalf("expected an immediate push for %s", reason)
+		}
+	}
+
+	// a new service account requires an immediate full push.
+	s.EDSUpdate("cluster", "a.com", "ns", withServiceAccounts("sa-1", "sa-2", "sa-3"))
+	expectFullPush("an added service account")
+
+	// so does the removal of the last endpoint of a service account.
+	s.EDSUpdate("cluster", "a.com", "ns", withServiceAccounts("sa-1", "sa-2"))
+	expectFullPush("a removed service account")
+
+	// a new endpoint with a known service account is batched.
+	s.EDSUpdate("cluster", "a.com", "ns", withServiceAccounts("sa-1", "sa-2", "sa-2"))
+	pushes := collectPushes(s)
+	if len(pushes) != 1 || pushes[0].Full {
+		t.Fatalf("expected a single incremental push, got %v", pushes)
+	}
+}
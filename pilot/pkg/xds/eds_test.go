@@ -36,8 +36,10 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/adsc"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/test/env"
@@ -234,6 +236,63 @@ func TestEdsWeightedServiceEntry(t *testing.T) {
 	}
 }
 
+func TestEdsEgressGatewayRedirect(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+		ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: external
+  namespace: default
+spec:
+  hosts:
+  - api.example.com
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+  resolution: STATIC
+  endpoints:
+  - address: 2.2.2.2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: egressgateway
+  namespace: istio-system
+spec:
+  hosts:
+  - istio-egressgateway.istio-system.svc.cluster.local
+  ports:
+  - number: 443
+    name: tls
+    protocol: TLS
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+`,
+		MeshExtensions: &mesh.MeshExtensions{
+			EgressGatewayRedirects: []mesh.EgressGatewayRedirect{{
+				Namespace: "default",
+				Hosts:     []string{"api.example.com"},
+				Gateway:   "istio-egressgateway.istio-system.svc.cluster.local",
+			}},
+		},
+	})
+	name := "outbound|443||api.example.com"
+
+	// The sidecar is sent the endpoints of the gateway for the cluster of the host.
+	sidecar := xdstest.ExtractLoadAssignments(s.Endpoints(s.SetupProxy(nil)))
+	if got := sidecar[name]; !reflect.DeepEqual(got, []string{"10.0.0.1"}) {
+		t.Errorf("got sidecar endpoints %v, want the gateway endpoints", got)
+	}
+	// The gateway is sent the endpoints of the host.
+	gateway := xdstest.ExtractLoadAssignments(s.Endpoints(s.SetupProxy(&model.Proxy{Type: model.Router, ConfigNamespace: "istio-system"})))
+	if got := gateway[name]; !reflect.DeepEqual(got, []string{"2.2.2.2"}) {
+		t.Errorf("got gateway endpoints %v, want the endpoints of the host", got)
+	}
+}
+
 var watchEds = []string{v3.ClusterType, v3.EndpointType}
 var watchAll = []string{v3.ClusterType, v3.EndpointType, v3.ListenerType, v3.RouteType}
 
@@ -16,10 +16,13 @@ package xds
 
 import (
 	"sort"
+	"strconv"
 	"strings"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/golang/protobuf/ptypes/wrappers"
 
 	networkingapi "istio.io/api/networking/v1alpha3"
@@ -27,9 +30,13 @@ import (
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema/gvk"
 )
 
+// staleRegistryMetadataKey is the key of the istio metadata flagging the endpoints of a stale registry.
+const staleRegistryMetadataKey = "stale_registry"
+
 type EndpointBuilder struct {
 	// These fields define the primary key for an endpoint, and can be used as a cache key
 	clusterName     string
@@ -39,6 +46,10 @@ type EndpointBuilder struct {
 	locality        *core.Locality
 	destinationRule *config.Config
 	service         *model.Service
+	// endpointService and endpointPort are those of the egress gateway, for a hostname routed through it, and
+	// those of the cluster otherwise.
+	endpointService *model.Service
+	endpointPort    int
 
 	// These fields are provided for convenience only
 	subsetName string
@@ -50,6 +61,10 @@ type EndpointBuilder struct {
 func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.PushContext) EndpointBuilder {
 	_, subsetName, hostname, port := model.ParseSubsetKey(clusterName)
 	svc := push.ServiceForHostname(proxy, hostname)
+	epSvc, epPort := svc, port
+	if gw, gwPort := push.EgressGatewayService(proxy, hostname, port); svc != nil && gw != nil {
+		epSvc, epPort = gw, gwPort.Port
+	}
 	return EndpointBuilder{
 		clusterName:     clusterName,
 		network:         proxy.Metadata.Network,
@@ -58,6 +73,8 @@ func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.Push
 		locality:        proxy.Locality,
 		service:         svc,
 		destinationRule: push.DestinationRule(proxy, svc),
+		endpointService: epSvc,
+		endpointPort:    epPort,
 
 		push:       push,
 		subsetName: subsetName,
@@ -82,6 +99,10 @@ func (b EndpointBuilder) Key() string {
 	if b.service != nil {
 		params = append(params, string(b.service.Hostname)+"/"+b.service.Attributes.Namespace)
 	}
+	if b.redirected() {
+		params = append(params, string(b.endpointService.Hostname)+"/"+b.endpointService.Attributes.Namespace,
+			strconv.Itoa(b.endpointPort))
+	}
 	if b.networkView != nil {
 		nv := make([]string, 0, len(b.networkView))
 		for nw := range b.networkView {
@@ -113,9 +134,18 @@ func (b EndpointBuilder) DependentConfigs() []model.ConfigKey {
 	if b.service != nil {
 		configs = append(configs, model.ConfigKey{Kind: gvk.ServiceEntry, Name: string(b.service.Hostname), Namespace: b.service.Attributes.Namespace})
 	}
+	if b.redirected() {
+		configs = append(configs, model.ConfigKey{Kind: gvk.ServiceEntry, Name: string(b.endpointService.Hostname),
+			Namespace: b.endpointService.Attributes.Namespace})
+	}
 	return configs
 }
 
+// redirected returns true if the endpoints are those of the egress gateway the hostname is routed through.
+func (b EndpointBuilder) redirected() bool {
+	return b.endpointService != nil && b.endpointService != b.service
+}
+
 func (b *EndpointBuilder) canViewNetwork(network string) bool {
 	if b.networkView == nil {
 		return true
@@ -130,12 +160,17 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 ) []*endpoint.LocalityLbEndpoints {
 	localityEpMap := make(map[string]*endpoint.LocalityLbEndpoints)
 
-	// get the subset labels
-	epLabels := getSubSetLabels(b.DestinationRule(), b.subsetName)
+	// get the subset labels. They select the endpoints of the hostname, not those of the egress gateway.
+	var epLabels labels.Collection
+	epService := b.endpointService
+	if !b.redirected() {
+		epLabels = getSubSetLabels(b.DestinationRule(), b.subsetName)
+		epService = b.service
+	}
 
 	// Determine whether or not the target service is considered local to the cluster
 	// and should, therefore, not be accessed from outside the cluster.
-	isClusterLocal := b.push.IsClusterLocal(b.service)
+	isClusterLocal := b.push.IsClusterLocal(epService)
 
 	shards.mutex.Lock()
 	// The shards are updated independently, now need to filter and merge
@@ -146,6 +181,7 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 		if isClusterLocal && (clusterID != b.clusterID) {
 			continue
 		}
+		stale := b.push.IsStaleCluster(clusterID)
 
 		for _, ep := range endpoints {
 			if svcPort.Name != ep.ServicePortName {
@@ -167,7 +203,11 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 			if ep.EnvoyEndpoint == nil {
 				ep.EnvoyEndpoint = buildEnvoyLbEndpoint(ep)
 			}
-			locLbEps.LbEndpoints = append(locLbEps.LbEndpoints, ep.EnvoyEndpoint)
+			lbEp := ep.EnvoyEndpoint
+			if stale {
+				lbEp = buildStaleLbEndpoint(lbEp)
+			}
+			locLbEps.LbEndpoints = append(locLbEps.LbEndpoints, lbEp)
 		}
 	}
 	shards.mutex.Unlock()
@@ -217,3 +257,25 @@ func buildEnvoyLbEndpoint(e *model.IstioEndpoint) *endpoint.LbEndpoint {
 
 	return ep
 }
+
+// buildStaleLbEndpoint returns a copy of the endpoint flagged with the stale_registry istio metadata, as the registry
+// of its cluster is stale. The endpoint is kept, as it is likely still valid.
+func buildStaleLbEndpoint(ep *endpoint.LbEndpoint) *endpoint.LbEndpoint {
+	out := proto.Clone(ep).(*endpoint.LbEndpoint)
+	if out.Metadata == nil {
+		out.Metadata = &core.Metadata{}
+	}
+	if out.Metadata.FilterMetadata == nil {
+		out.Metadata.FilterMetadata = map[string]*structpb.Struct{}
+	}
+	md := out.Metadata.FilterMetadata[util.IstioMetadataKey]
+	if md == nil {
+		md = &structpb.Struct{}
+		out.Metadata.FilterMetadata[util.IstioMetadataKey] = md
+	}
+	if md.Fields == nil {
+		md.Fields = map[string]*structpb.Value{}
+	}
+	md.Fields[staleRegistryMetadataKey] = &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: true}}
+	return out
+}
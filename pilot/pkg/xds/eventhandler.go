@@ -29,7 +29,8 @@ var AllEventTypes = []EventType{
 // EventHandler allows for generic monitoring of xDS ACKS and disconnects, for the purpose of tracking
 // Config distribution through the mesh.
 type DistributionStatusCache interface {
-	// RegisterEvent notifies the implementer of an xDS ACK, and must be non-blocking
+	// RegisterEvent notifies the implementer of an xDS ACK, and must be non-blocking. The nonce is either a nonce
+	// or a config version, which nonces are prefixed with, see AckedConfigVersion.
 	RegisterEvent(conID string, eventType EventType, nonce string)
 	RegisterDisconnect(s string, types []EventType)
 	QueryLastNonce(conID string, eventType EventType) (noncePrefix string)
@@ -22,6 +22,7 @@ import (
 
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/test/bufconn"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -41,6 +42,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
 	kubelib "istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/test"
 )
@@ -59,6 +61,8 @@ type FakeOptions struct {
 	// If provided, this mesh config will be used
 	MeshConfig      *meshconfig.MeshConfig
 	NetworksWatcher mesh.NetworksWatcher
+	// If provided, these settings of the mesh which are not part of the mesh config will be used
+	MeshExtensions *mesh.MeshExtensions
 }
 
 type FakeDiscoveryServer struct {
@@ -66,6 +70,8 @@ type FakeDiscoveryServer struct {
 	t         test.Failer
 	Discovery *DiscoveryServer
 	listener  *bufconn.Listener
+	// SecretsClient is the client the gateway credentials are read from, secret changes are pushed over SDS.
+	SecretsClient kubelib.Client
 }
 
 func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServer {
@@ -90,7 +96,8 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 	secretFake := kubelib.NewFakeClient(k8sObjects...)
 	sc := kubesecrets.NewSecretsController(secretFake.KubeInformer().Core().V1().Secrets())
 	secretFake.RunAndWait(stop)
-	s.Generators[v3.SecretType] = NewSecretGen(sc, &model.DisabledCache{})
+	msc := kubesecrets.NewMulticluster("Kubernetes", sc, kubesecrets.Scope{})
+	s.Generators[v3.SecretType] = NewSecretGen(msc, &model.DisabledCache{}, "Kubernetes")
 
 	cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{
 		Configs:             opts.Configs,
@@ -98,6 +105,7 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 		ConfigTemplateInput: opts.ConfigTemplateInput,
 		MeshConfig:          opts.MeshConfig,
 		NetworksWatcher:     opts.NetworksWatcher,
+		MeshExtensions:      opts.MeshExtensions,
 		ServiceRegistries:   []serviceregistry.Instance{k8s},
 		PushContextLock:     &s.updateMutex,
 	})
@@ -160,12 +168,20 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 	s.CachesSynced()
 	s.Start(stop)
 	cg.ServiceEntryRegistry.ResyncEDS()
+	// TODO code re-use from server.go
+	msc.AddEventHandler(func(name, namespace string) {
+		s.ConfigUpdate(&model.PushRequest{
+			ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.Secret, Name: name, Namespace: namespace}: {}},
+			Reason:         []model.TriggerReason{model.SecretTrigger},
+		})
+	})
 
 	fake := &FakeDiscoveryServer{
 		t:             t,
 		Discovery:     s,
 		listener:      listener,
 		ConfigGenTest: cg,
+		SecretsClient: secretFake,
 	}
 
 	// currently meshNetworks gateways are stored on the push context
@@ -201,6 +217,25 @@ func (f *FakeDiscoveryServer) ConnectADS() discovery.AggregatedDiscoveryService_
 	return client
 }
 
+// ConnectLRS starts an LRS connection to the server. It will automatically be cleaned up when the test ends
+func (f *FakeDiscoveryServer) ConnectLRS() lrs.LoadReportingService_StreamLoadStatsClient {
+	conn, err := grpc.Dial("buffcon", grpc.WithInsecure(), grpc.WithBlock(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return f.listener.Dial()
+	}))
+	if err != nil {
+		f.t.Fatalf("failed to connect: %v", err)
+	}
+	client, err := lrs.NewLoadReportingServiceClient(conn).StreamLoadStats(context.Background())
+	if err != nil {
+		f.t.Fatalf("stream load stats failed: %s", err)
+	}
+	f.t.Cleanup(func() {
+		_ = client.CloseSend()
+		_ = conn.Close()
+	})
+	return client
+}
+
 // ConnectADS starts an ADS connection to the server using adsc. It will automatically be cleaned up when the test ends
 // watch can be configured to determine the resources to watch initially, and wait can be configured to determine what
 // resources we should initially wait for.
@@ -215,6 +250,7 @@ func (f *FakeDiscoveryServer) Connect(p *model.Proxy, watch []string, wait []str
 	}
 	adscConn, err := adsc.Dial("buffcon", "", &adsc.Config{
 		IP:        p.IPAddresses[0],
+		NodeType:  string(p.Type),
 		Meta:      p.Metadata.ToStruct(),
 		Locality:  p.Locality,
 		Namespace: p.ConfigNamespace,
@@ -62,6 +62,17 @@ func init() {
 	controlPlane = &corev3.ControlPlane{Identifier: string(byVersion)}
 }
 
+// partialGenerator generates the resources of the names added to a subscription only.
+type partialGenerator struct {
+	gen   model.XdsPartialResourceGenerator
+	names []string
+}
+
+func (p partialGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource,
+	req *model.PushRequest) model.Resources {
+	return p.gen.GeneratePartial(proxy, push, w, p.names, req)
+}
+
 var SkipLogTypes = map[string]struct{}{
 	v3.EndpointType: {},
 }
@@ -71,21 +82,47 @@ var SkipLogTypes = map[string]struct{}{
 // choose to send partial or even no response if there are no changes.
 func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 	gen model.XdsResourceGenerator, currentVersion string, w *model.WatchedResource, req *model.PushRequest) error {
+	_, err := s.pushXdsResources(con, push, gen, currentVersion, w, req)
+	return err
+}
+
+// pushXdsResources is pushXds, returning the resources sent. They are nil if no response was sent.
+func (s *DiscoveryServer) pushXdsResources(con *Connection, push *model.PushContext,
+	gen model.XdsResourceGenerator, currentVersion string, w *model.WatchedResource, req *model.PushRequest) (model.Resources, error) {
 	if gen == nil {
-		return nil
+		return nil, nil
 	}
 
 	t0 := time.Now()
 
-	cl := gen.Generate(con.proxy, push, w, req)
+	var cl model.Resources
+	switch cg := gen.(type) {
+	case model.XdsDiagnosticResourceGenerator:
+		// The context is only used by this generation, and the resources no longer reference it once generated.
+		ctx := model.GetGenerationContext()
+		var diags model.GenerationDiagnostics
+		cl, diags = cg.GenerateWithDiagnostics(ctx, con.proxy, push, w, req)
+		ctx.Release()
+		if cl != nil {
+			// The remaining resources are still sent, the skipped ones are reported.
+			recordGenerationDiagnostics(con, push, w.TypeUrl, diags)
+		}
+	case model.XdsContextResourceGenerator:
+		ctx := model.GetGenerationContext()
+		cl = cg.GenerateWithContext(ctx, con.proxy, push, w, req)
+		ctx.Release()
+	default:
+		cl = gen.Generate(con.proxy, push, w, req)
+	}
+	recordGenerationTime(w.TypeUrl, con.proxy, time.Since(t0))
 	if cl == nil {
 		// If we have nothing to send, report that we got an ACK for this version.
 		if s.StatusReporter != nil {
 			s.StatusReporter.RegisterEvent(con.ConID, w.TypeUrl, push.Version)
 		}
-		return nil // No push needed.
+		return nil, nil // No push needed.
 	}
-	defer func() { recordPushTime(w.TypeUrl, time.Since(t0)) }()
+	defer func() { recordPushTime(w.TypeUrl, req, time.Since(t0)) }()
 
 	resp := &discovery.DiscoveryResponse{
 		TypeUrl:     w.TypeUrl,
@@ -94,15 +131,19 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 		Resources:   cl,
 	}
 
-	err := con.send(resp)
+	sz, err := con.send(resp)
 	if err != nil {
 		recordSendError(w.TypeUrl, con.ConID, err)
-		return err
+		return nil, err
+	}
+	recordPushSize(w.TypeUrl, con.proxy, sz)
+	if req != nil && req.Full {
+		s.rollouts.onPushed(con.ConID, w.TypeUrl, currentVersion, resp.Nonce)
 	}
 
 	// Some types handle logs inside Generate, skip them here
 	if _, f := SkipLogTypes[w.TypeUrl]; !f {
 		adsLog.Infof("%s: PUSH for node:%s resources:%d", v3.GetShortType(w.TypeUrl), con.proxy.ID, len(cl))
 	}
-	return nil
+	return cl, nil
 }
@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// TestGenerationContextConcurrentPushes generates clusters and listeners concurrently, each generation with its own
+// pooled context as for concurrent connections, and checks the resources match the ones generated without context.
+func TestGenerationContextConcurrentPushes(t *testing.T) {
+	for _, tt := range []ConfigInput{
+		{Name: "empty", Services: 20, ProxyType: model.SidecarProxy},
+		{Name: "gateways", Services: 20, ProxyType: model.Router},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			s, proxy := setupAndInitializeTest(t, tt)
+			for _, typeURL := range []string{v3.ClusterType, v3.ListenerType} {
+				gen := s.Discovery.Generators[typeURL].(model.XdsContextResourceGenerator)
+				expected := gen.Generate(proxy, s.PushContext(), nil, nil)
+				if len(expected) == 0 {
+					t.Fatalf("got no %s", v3.GetShortType(typeURL))
+				}
+
+				var wg sync.WaitGroup
+				errs := make(chan string, 10)
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for n := 0; n < 20; n++ {
+							ctx := model.GetGenerationContext()
+							got := gen.GenerateWithContext(ctx, proxy, s.PushContext(), nil, nil)
+							ctx.Release()
+							if !resourcesEqual(got, expected) {
+								errs <- v3.GetShortType(typeURL)
+								return
+							}
+						}
+					}()
+				}
+				wg.Wait()
+				close(errs)
+				for e := range errs {
+					t.Errorf("%s generated with a context differ from the ones generated without", e)
+				}
+			}
+		})
+	}
+}
+
+func resourcesEqual(a, b model.Resources) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].TypeUrl != b[i].TypeUrl || !bytes.Equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// repeatedField matches the field of a validation error of an element of a repeated field, e.g. "FilterChains[2]".
+var repeatedField = regexp.MustCompile(`^(.*)\[(\d+)\]$`)
+
+// maxLoggedDiagnostics bounds the distinct diagnostics remembered as logged. Once reached, they are forgotten, and
+// logged again the next time they occur.
+const maxLoggedDiagnostics = 1000
+
+// loggedDiagnostics holds the distinct diagnostics already logged. The same invalid resource is usually generated for
+// many proxies, on every push: it is only logged once, and all its occurrences are counted by xdsGenerationErrors.
+var loggedDiagnostics = struct {
+	sync.Mutex
+	seen map[string]struct{}
+}{seen: map[string]struct{}{}}
+
+// shouldLogDiagnostic returns true the first time the diagnostic of the type is seen.
+func shouldLogDiagnostic(typeURL string, d model.GenerationDiagnostic) bool {
+	key := typeURL + "/" + d.Category + "/" + d.Resource + "/" + d.Message
+	loggedDiagnostics.Lock()
+	defer loggedDiagnostics.Unlock()
+	if _, f := loggedDiagnostics.seen[key]; f {
+		return false
+	}
+	if len(loggedDiagnostics.seen) >= maxLoggedDiagnostics {
+		loggedDiagnostics.seen = map[string]struct{}{}
+	}
+	loggedDiagnostics.seen[key] = struct{}{}
+	return true
+}
+
+// validationField is implemented by the validation errors of the envoy resources.
+type validationField interface {
+	Field() string
+}
+
+// diagnoseInvalidResource returns the diagnostic of the validation error of the resource, generated from the config
+// of the metadata. The category is the type of the resource and the invalid field, e.g. "cluster.OutlierDetection",
+// and the index of the element of a repeated field, returned separately, is left out to bound the categories.
+func diagnoseInvalidResource(typ, name string, md *core.Metadata, err error) (model.GenerationDiagnostic, int) {
+	d := model.GenerationDiagnostic{
+		Resource: name,
+		Category: typ,
+		Config:   util.ConfigKeyFromMetadata(md),
+		Message:  err.Error(),
+	}
+	index := -1
+	if v, ok := err.(validationField); ok && v.Field() != "" {
+		field := v.Field()
+		if m := repeatedField.FindStringSubmatch(field); m != nil {
+			field = m[1]
+			index, _ = strconv.Atoi(m[2])
+		}
+		d.Category = typ + "." + field
+	}
+	return d, index
+}
+
+// validateClusters returns the valid clusters, and the diagnostics of the invalid ones. The given slice is returned
+// if all the clusters are valid, it is not modified otherwise.
+func validateClusters(clusters []*cluster.Cluster) ([]*cluster.Cluster, model.GenerationDiagnostics) {
+	var diags model.GenerationDiagnostics
+	var valid []*cluster.Cluster
+	for i, c := range clusters {
+		if err := c.Validate(); err != nil {
+			if valid == nil {
+				valid = append(make([]*cluster.Cluster, 0, len(clusters)), clusters[:i]...)
+			}
+			d, _ := diagnoseInvalidResource("cluster", c.Name, c.Metadata, err)
+			diags = append(diags, d)
+			continue
+		}
+		if valid != nil {
+			valid = append(valid, c)
+		}
+	}
+	if valid == nil {
+		return clusters, nil
+	}
+	return valid, diags
+}
+
+// validateListeners returns the valid listeners, and the diagnostics of the invalid ones. The given slice is returned
+// if all the listeners are valid, it is not modified otherwise.
+func validateListeners(listeners []*listener.Listener) ([]*listener.Listener, model.GenerationDiagnostics) {
+	var diags model.GenerationDiagnostics
+	var valid []*listener.Listener
+	for i, l := range listeners {
+		if err := l.Validate(); err != nil {
+			if valid == nil {
+				valid = append(make([]*listener.Listener, 0, len(listeners)), listeners[:i]...)
+			}
+			d, index := diagnoseInvalidResource("listener", l.Name, l.Metadata, err)
+			if d.Config == nil && index >= 0 && d.Category == "listener.FilterChains" && index < len(l.FilterChains) {
+				// The filter chains of the gateways reference the config they were generated from.
+				d.Config = util.ConfigKeyFromMetadata(l.FilterChains[index].Metadata)
+			}
+			diags = append(diags, d)
+			continue
+		}
+		if valid != nil {
+			valid = append(valid, l)
+		}
+	}
+	if valid == nil {
+		return listeners, nil
+	}
+	return valid, diags
+}
+
+// recordGenerationDiagnostics records the resources of the type skipped by a generation for the connection, on the
+// connection, the generation error metric and the push status. Each distinct diagnostic is only logged once.
+func recordGenerationDiagnostics(con *Connection, push *model.PushContext, typeURL string, diags model.GenerationDiagnostics) {
+	con.recordGenerationDiagnostics(typeURL, diags)
+	for _, d := range diags {
+		if shouldLogDiagnostic(typeURL, d) {
+			adsLog.Warnf("%s: skipped invalid resource %s for node:%s: %s (further occurrences are only counted by %s)",
+				v3.GetShortType(typeURL), d.Resource, con.proxy.ID, d.Message, xdsGenerationErrors.Name())
+		}
+		xdsGenerationErrors.With(typeTag.Value(v3.GetMetricType(typeURL)), categoryTag.Value(d.Category)).Increment()
+		key := d.Resource
+		if d.Config != nil {
+			key = fmt.Sprintf("%s/%s/%s", d.Config.Kind.Kind, d.Config.Namespace, d.Config.Name)
+		}
+		push.AddMetric(model.ProxyStatusInvalidResources, key, con.proxy.ID, d.Category+": "+d.Message)
+	}
+}
+
+// recordGenerationDiagnostics records the diagnostics of the last generation of the type. The errors are counted by
+// category since the connection was established.
+func (conn *Connection) recordGenerationDiagnostics(typeURL string, diags model.GenerationDiagnostics) {
+	conn.diagnosticsMutex.Lock()
+	defer conn.diagnosticsMutex.Unlock()
+	if len(diags) == 0 {
+		delete(conn.diagnostics, typeURL)
+		return
+	}
+	if conn.diagnostics == nil {
+		conn.diagnostics = map[string]model.GenerationDiagnostics{}
+		conn.generationErrors = map[string]int64{}
+	}
+	conn.diagnostics[typeURL] = diags
+	for _, d := range diags {
+		conn.generationErrors[d.Category]++
+	}
+}
+
+// GenerationErrors returns the number of resources skipped as invalid since the connection was established, by
+// category.
+func (conn *Connection) GenerationErrors() map[string]int64 {
+	conn.diagnosticsMutex.Lock()
+	defer conn.diagnosticsMutex.Unlock()
+	out := make(map[string]int64, len(conn.generationErrors))
+	for category, n := range conn.generationErrors {
+		out[category] = n
+	}
+	return out
+}
+
+// Diagnostics returns the resources skipped by the last generation of each type, by type.
+func (conn *Connection) Diagnostics() map[string]model.GenerationDiagnostics {
+	conn.diagnosticsMutex.Lock()
+	defer conn.diagnosticsMutex.Unlock()
+	out := make(map[string]model.GenerationDiagnostics, len(conn.diagnostics))
+	for typeURL, diags := range conn.diagnostics {
+		out[typeURL] = diags
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// generationErrorConfig has a DestinationRule with an outlier detection which the validation would have rejected,
+// producing an invalid cluster.
+const generationErrorConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: ratings
+  namespace: default
+spec:
+  hosts:
+  - ratings.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  host: reviews.example.com
+  trafficPolicy:
+    outlierDetection:
+      consecutive5xxErrors: 5
+      maxEjectionPercent: 150
+`
+
+func TestGenerationDiagnostics(t *testing.T) {
+	defaultValidate := features.ValidateGeneratedResources
+	features.ValidateGeneratedResources = true
+	defer func() { features.ValidateGeneratedResources = defaultValidate }()
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: generationErrorConfig})
+	watch := []string{v3.ClusterType}
+	ads := s.Connect(nil, watch, watch)
+
+	// The generation proceeds with the valid clusters.
+	clusters := ads.GetClusters()
+	if _, f := clusters["outbound|80||reviews.example.com"]; f {
+		t.Fatalf("expected the invalid cluster to be skipped")
+	}
+	if _, f := clusters["outbound|80||ratings.example.com"]; !f {
+		t.Fatalf("expected the valid cluster to be pushed, got %v", clusters)
+	}
+
+	s.Discovery.adsClientsMutex.RLock()
+	var con *Connection
+	for _, c := range s.Discovery.adsClients {
+		con = c
+	}
+	s.Discovery.adsClientsMutex.RUnlock()
+	if con == nil {
+		t.Fatal("expected a connection")
+	}
+
+	diags := con.Diagnostics()[v3.ClusterType]
+	if len(diags) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", diags)
+	}
+	d := diags[0]
+	want := model.ConfigKey{Kind: gvk.DestinationRule, Name: "reviews", Namespace: "default"}
+	if d.Resource != "outbound|80||reviews.example.com" || d.Category != "cluster.OutlierDetection" ||
+		d.Config == nil || *d.Config != want {
+		t.Fatalf("unexpected diagnostic %+v", d)
+	}
+	if got := con.GenerationErrors()["cluster.OutlierDetection"]; got == 0 {
+		t.Fatalf("expected the generation error to be counted")
+	}
+
+	// The connection record of adsz exposes the diagnostics.
+	rr := httptest.NewRecorder()
+	s.Discovery.adsz(rr, httptest.NewRequest("GET", "/debug/adsz", nil))
+	out := AdsClients{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+		t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+	}
+	if len(out.Connected) != 1 {
+		t.Fatalf("expected one client, got %+v", out.Connected)
+	}
+	client := out.Connected[0]
+	if client.GenerationErrors["cluster.OutlierDetection"] == 0 || len(client.Diagnostics["CDS"]) != 1 {
+		t.Fatalf("expected the diagnostic on the connection, got %+v", client)
+	}
+
+	// The config appears in the push status.
+	status := s.PushContext().ProxyStatus[model.ProxyStatusInvalidResources.Name()]
+	if _, f := status["DestinationRule/default/reviews"]; !f {
+		t.Fatalf("expected the destination rule in the push status, got %+v", status)
+	}
+}
+
+func TestValidateClustersUnchanged(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	proxy := s.SetupProxy(nil)
+	clusters := s.Discovery.ConfigGenerator.BuildClusters(proxy, s.PushContext(), nil)
+	valid, diags := validateClusters(clusters)
+	if len(diags) != 0 {
+		t.Fatalf("expected the generated clusters to be valid, got %+v", diags)
+	}
+	if len(valid) != len(clusters) {
+		t.Fatalf("expected %d clusters, got %d", len(clusters), len(valid))
+	}
+}
+
+func TestShouldLogDiagnostic(t *testing.T) {
+	d := model.GenerationDiagnostic{Resource: "outbound|80||reviews.example.com", Category: "cluster.OutlierDetection"}
+	if !shouldLogDiagnostic(v3.ClusterType, d) {
+		t.Fatalf("expected the first occurrence to be logged")
+	}
+	if shouldLogDiagnostic(v3.ClusterType, d) {
+		t.Fatalf("expected a repeated diagnostic not to be logged")
+	}
+	d.Resource = "outbound|80||ratings.example.com"
+	if !shouldLogDiagnostic(v3.ClusterType, d) {
+		t.Fatalf("expected a distinct diagnostic to be logged")
+	}
+}
@@ -16,6 +16,9 @@ package xds
 
 import (
 	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
@@ -24,9 +27,12 @@ import (
 	"github.com/golang/protobuf/ptypes/any"
 	structpb "github.com/golang/protobuf/ptypes/struct"
 
+	"istio.io/istio/pilot/pkg/controller/workloadentry"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/pkg/log"
 )
 
@@ -50,10 +56,32 @@ const (
 
 // InternalGen is a Generator for XDS status updates: connect, disconnect, nacks, acks
 type InternalGen struct {
+	// nackedProxies is the number of connections with at least one active NACK, accessed atomically.
+	// It is first in the struct so that it is 64-bit aligned.
+	nackedProxies int64
+
 	Server *DiscoveryServer
 
-	// TODO: track last N Nacks and connection events, with 'version' based on timestamp.
+	// WorkloadEntryController, if set, records the health of the workloads reported by their agents.
+	WorkloadEntryController *workloadentry.Controller
+
+	// TODO: track last N connection events, with 'version' based on timestamp.
 	// On new connect, use version to send recent events since last update.
+
+	// now is used instead of time.Now if set, for tests.
+	now func() time.Time
+}
+
+// NackEvent is the last NACK of a proxy for a type, until the proxy ACKs the type.
+type NackEvent struct {
+	ProxyID string `json:"proxyID"`
+	TypeURL string `json:"typeUrl"`
+	// RejectedVersion is the version of the rejected response, if the proxy still tracks it.
+	RejectedVersion string `json:"rejectedVersion,omitempty"`
+	// Nonce of the rejected response.
+	Nonce string    `json:"nonce"`
+	Error string    `json:"error"`
+	Time  time.Time `json:"time"`
 }
 
 func (sg *InternalGen) OnConnect(con *Connection) {
@@ -83,15 +111,172 @@ func (sg *InternalGen) OnDisconnect(con *Connection) {
 		}
 	}
 
+	// The NACKs of the connection are dropped with it. A request already received may still be processed,
+	// so NACKs are no longer recorded either.
+	con.nacksMutex.Lock()
+	if len(con.nacks) > 0 {
+		sg.addNackedProxies(-1)
+	}
+	con.nacks = nil
+	con.nacksClosed = true
+	con.nacksMutex.Unlock()
+
 	// Note that it is quite possible for a 'connect' on a different istiod to happen before a disconnect.
 }
 
-func (sg *InternalGen) OnNack(node *model.Proxy, dr *discovery.DiscoveryRequest) {
+func (sg *InternalGen) OnNack(con *Connection, dr *discovery.DiscoveryRequest) {
 	// Make sure we include the ID - the DR may not include metadata
-	dr.Node.Id = node.ID
+	dr.Node.Id = con.proxy.ID
+	sg.recordNack(con, dr)
 	sg.startPush(TypeURLNACK, []proto.Message{dr})
 }
 
+// OnHealthInfo relays the health of the workload of the proxy, reported by its agent, to the WorkloadEntry controller.
+// The identities are the authenticated identities of the connection of the proxy.
+func (sg *InternalGen) OnHealthInfo(node *model.Proxy, identities []spiffe.Identity, dr *discovery.DiscoveryRequest) {
+	if sg.WorkloadEntryController == nil {
+		return
+	}
+	event := workloadentry.HealthEvent{Healthy: dr.ErrorDetail == nil}
+	if dr.ErrorDetail != nil {
+		event.Message = dr.ErrorDetail.Message
+	}
+	sg.WorkloadEntryController.QueueWorkloadEntryHealth(node, identities, event)
+}
+
+// OnAck clears the last NACK of the connection for the type, if any.
+func (sg *InternalGen) OnAck(con *Connection, dr *discovery.DiscoveryRequest) {
+	con.nacksMutex.Lock()
+	defer con.nacksMutex.Unlock()
+	if _, f := con.nacks[dr.TypeUrl]; !f {
+		return
+	}
+	delete(con.nacks, dr.TypeUrl)
+	if len(con.nacks) == 0 {
+		sg.addNackedProxies(-1)
+	}
+}
+
+func (sg *InternalGen) recordNack(con *Connection, dr *discovery.DiscoveryRequest) {
+	node := con.proxy
+	e := &NackEvent{
+		ProxyID: node.ID,
+		TypeURL: dr.TypeUrl,
+		Nonce:   dr.ResponseNonce,
+		Error:   dr.ErrorDetail.GetMessage(),
+		Time:    sg.timeNow(),
+	}
+	node.RLock()
+	if w := node.WatchedResources[dr.TypeUrl]; w != nil && w.NonceSent == dr.ResponseNonce {
+		e.RejectedVersion = w.VersionSent
+	}
+	node.RUnlock()
+
+	con.nacksMutex.Lock()
+	defer con.nacksMutex.Unlock()
+	if con.nacksClosed {
+		return
+	}
+	nacked := len(con.nacks) > 0
+	if con.nacks == nil {
+		con.nacks = map[string]*NackEvent{}
+	}
+	con.nacks[e.TypeURL] = e
+	con.pruneNacksLocked(e.Time)
+	if !nacked {
+		sg.addNackedProxies(1)
+	}
+}
+
+// pruneNacks drops the expired NACKs of the connected proxies, so that the proxies which neither ACK nor
+// NACK the type again are eventually no longer counted as NACKed.
+func (sg *InternalGen) pruneNacks() {
+	now := sg.timeNow()
+	sg.Server.adsClientsMutex.RLock()
+	cons := make([]*Connection, 0, len(sg.Server.adsClients))
+	for _, con := range sg.Server.adsClients {
+		cons = append(cons, con)
+	}
+	sg.Server.adsClientsMutex.RUnlock()
+
+	for _, con := range cons {
+		con.nacksMutex.Lock()
+		nacked := len(con.nacks) > 0
+		con.pruneNacksLocked(now)
+		if nacked && len(con.nacks) == 0 {
+			sg.addNackedProxies(-1)
+		}
+		con.nacksMutex.Unlock()
+	}
+}
+
+// Nacks returns the NACKs of the connected proxies, sorted by proxy and type. If proxyID is set, only
+// the NACKs of that proxy are returned.
+func (sg *InternalGen) Nacks(proxyID string) []NackEvent {
+	now := sg.timeNow()
+	sg.Server.adsClientsMutex.RLock()
+	cons := make([]*Connection, 0, len(sg.Server.adsClients))
+	for _, con := range sg.Server.adsClients {
+		if proxyID == "" || (con.proxy != nil && con.proxy.ID == proxyID) {
+			cons = append(cons, con)
+		}
+	}
+	sg.Server.adsClientsMutex.RUnlock()
+
+	out := make([]NackEvent, 0)
+	for _, con := range cons {
+		con.nacksMutex.Lock()
+		nacked := len(con.nacks) > 0
+		con.pruneNacksLocked(now)
+		if nacked && len(con.nacks) == 0 {
+			sg.addNackedProxies(-1)
+		}
+		for _, e := range con.nacks {
+			out = append(out, *e)
+		}
+		con.nacksMutex.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ProxyID != out[j].ProxyID {
+			return out[i].ProxyID < out[j].ProxyID
+		}
+		return out[i].TypeURL < out[j].TypeURL
+	})
+	return out
+}
+
+func (sg *InternalGen) addNackedProxies(delta int64) {
+	xdsNacksActive.Record(float64(atomic.AddInt64(&sg.nackedProxies, delta)))
+}
+
+func (sg *InternalGen) timeNow() time.Time {
+	if sg.now != nil {
+		return sg.now()
+	}
+	return time.Now()
+}
+
+// activeNacks returns the NACKs of the connection not cleared by an ACK yet, by type.
+func (con *Connection) activeNacks() map[string]NackEvent {
+	con.nacksMutex.Lock()
+	defer con.nacksMutex.Unlock()
+	out := make(map[string]NackEvent, len(con.nacks))
+	for typeURL, e := range con.nacks {
+		out[typeURL] = *e
+	}
+	return out
+}
+
+// pruneNacksLocked drops the NACKs received before the retention window, which the proxy neither ACKed nor
+// NACKed again since.
+func (con *Connection) pruneNacksLocked(now time.Time) {
+	for k, e := range con.nacks {
+		if now.Sub(e.Time) > features.XDSNackRetention {
+			delete(con.nacks, k)
+		}
+	}
+}
+
 // PushAll will immediately send a response to all connections that
 // are watching for the specific type.
 // TODO: additional filters can be added, for example namespace.
@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestNackTracking(t *testing.T) {
+	now := time.Now()
+	s := &DiscoveryServer{adsClients: map[string]*Connection{}}
+	sg := &InternalGen{Server: s, now: func() time.Time { return now }}
+	s.InternalGen = sg
+
+	connect := func(id string, watched map[string]*model.WatchedResource) *Connection {
+		con := &Connection{
+			ConID: id + "-1",
+			node:  &core.Node{Id: id},
+			proxy: &model.Proxy{ID: id, WatchedResources: watched},
+		}
+		s.adsClients[con.ConID] = con
+		return con
+	}
+	con := connect("app.default", map[string]*model.WatchedResource{
+		v3.ClusterType: {TypeUrl: v3.ClusterType, VersionSent: "v2", NonceSent: "nonce-2"},
+	})
+	other := connect("other.default", map[string]*model.WatchedResource{})
+	nack := func(c *Connection, typeURL, nonce string) {
+		sg.OnNack(c, &discovery.DiscoveryRequest{
+			Node:          &core.Node{},
+			TypeUrl:       typeURL,
+			VersionInfo:   "v1",
+			ResponseNonce: nonce,
+			ErrorDetail:   &status.Status{Message: "invalid cluster"},
+		})
+	}
+	active := func() int64 {
+		return atomic.LoadInt64(&sg.nackedProxies)
+	}
+
+	nack(con, v3.ClusterType, "nonce-2")
+	nack(con, v3.ListenerType, "nonce-3")
+	nack(other, v3.ClusterType, "nonce-4")
+	if got := active(); got != 2 {
+		t.Fatalf("expected 2 proxies with active NACKs, got %v", got)
+	}
+	got := sg.Nacks(con.proxy.ID)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 NACKs for %v, got %+v", con.proxy.ID, got)
+	}
+	if e := got[0]; e.TypeURL != v3.ClusterType || e.RejectedVersion != "v2" || e.Error != "invalid cluster" {
+		t.Errorf("unexpected NACK %+v", e)
+	}
+
+	// ACKing one type keeps the proxy NACKed for the other.
+	now = now.Add(time.Minute)
+	sg.OnAck(con, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+	if got := active(); got != 2 {
+		t.Fatalf("expected 2 proxies with active NACKs, got %v", got)
+	}
+	if got := sg.Nacks(con.proxy.ID); len(got) != 1 || got[0].TypeURL != v3.ListenerType {
+		t.Fatalf("expected only the NACK of the listeners to be kept, got %+v", got)
+	}
+	sg.OnAck(con, &discovery.DiscoveryRequest{TypeUrl: v3.ListenerType})
+	if got := active(); got != 1 {
+		t.Fatalf("expected only %v to have active NACKs, got %v", other.proxy.ID, got)
+	}
+	if got := sg.Nacks(con.proxy.ID); len(got) != 0 {
+		t.Errorf("expected the NACKs to be cleared by the ACKs, got %+v", got)
+	}
+
+	rr := httptest.NewRecorder()
+	s.nackz(rr, httptest.NewRequest("GET", "/debug/nackz?proxyID="+other.proxy.ID, nil))
+	var nackz []NackEvent
+	if err := json.Unmarshal(rr.Body.Bytes(), &nackz); err != nil {
+		t.Fatal(err)
+	}
+	if len(nackz) != 1 || nackz[0].ProxyID != other.proxy.ID {
+		t.Errorf("unexpected nackz response %+v", nackz)
+	}
+
+	// NACKs expire after the retention window, including the active NACK of a proxy which neither ACKed
+	// nor NACKed the type again since.
+	now = now.Add(features.XDSNackRetention + time.Second)
+	sg.pruneNacks()
+	if got := active(); got != 0 {
+		t.Fatalf("expected no active NACKs after the retention window, got %v", got)
+	}
+	if got := sg.Nacks(""); len(got) != 0 {
+		t.Fatalf("expected no NACKs to be retained, got %+v", got)
+	}
+
+	// The NACKs of a proxy are dropped when it disconnects, and no longer recorded.
+	nack(other, v3.ListenerType, "nonce-5")
+	if got := active(); got != 1 {
+		t.Fatalf("expected 1 proxy with active NACKs, got %v", got)
+	}
+	delete(s.adsClients, other.ConID)
+	sg.OnDisconnect(other)
+	if got := active(); got != 0 {
+		t.Fatalf("expected no active NACKs after disconnect, got %v", got)
+	}
+	nack(other, v3.ListenerType, "nonce-6")
+	if got := active(); got != 0 || len(other.nacks) != 0 {
+		t.Fatalf("expected no NACK recorded after disconnect, got %v active, %+v", got, other.nacks)
+	}
+}
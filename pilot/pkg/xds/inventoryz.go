@@ -0,0 +1,161 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/spiffe"
+)
+
+// InventoryEntry describes a proxy connected to this Istiod on "/debug/inventoryz".
+type InventoryEntry struct {
+	ConnectionID string         `json:"connectionId"`
+	ProxyID      string         `json:"proxy"`
+	Namespace    string         `json:"namespace"`
+	Type         model.NodeType `json:"type"`
+	IstioVersion string         `json:"istioVersion,omitempty"`
+	ClusterID    string         `json:"clusterId,omitempty"`
+	// Istiod is the instance of Istiod the proxy is connected to.
+	Istiod      string    `json:"istiod,omitempty"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	// LastFullPush is unset if no full push was sent on the connection since it was established.
+	LastFullPush *time.Time `json:"lastFullPush,omitempty"`
+	Identities   []string   `json:"identities,omitempty"`
+	// SpiffeIdentities are the Identities which are valid SPIFFE identities.
+	SpiffeIdentities []spiffe.Identity `json:"spiffeIdentities,omitempty"`
+	// InvalidIdentities is the number of Identities which are not valid SPIFFE identities.
+	InvalidIdentities int `json:"invalidIdentities,omitempty"`
+	// CertExpiry is unset if the proxy did not present a client certificate.
+	CertExpiry *time.Time `json:"certExpiry,omitempty"`
+	// Nacked is true if the last response sent for one of the types was rejected.
+	Nacked bool `json:"nacked"`
+	// Resources holds the state of the resources watched by the proxy, by short type.
+	Resources map[string]InventoryResource `json:"resources"`
+}
+
+// InventoryResource describes the state of a type watched by a connected proxy.
+type InventoryResource struct {
+	VersionSent  string `json:"versionSent,omitempty"`
+	NonceSent    string `json:"nonceSent,omitempty"`
+	VersionAcked string `json:"versionAcked,omitempty"`
+	NonceAcked   string `json:"nonceAcked,omitempty"`
+	// LastSent is unset if no response was sent for the type.
+	LastSent *time.Time `json:"lastSent,omitempty"`
+	// Nacked is true if the last response sent for the type was rejected.
+	Nacked bool `json:"nacked"`
+}
+
+// inventoryz lists the connected proxies, sorted by connection ID. It can be filtered with ?namespace=, and paginated
+// with ?limit= and ?after=, the connection ID of the last entry of the previous page.
+func (s *DiscoveryServer) inventoryz(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	namespace, after := q.Get("namespace"), q.Get("after")
+	limit := 0
+	if l := q.Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid limit %q", l)
+			return
+		}
+	}
+
+	s.adsClientsMutex.RLock()
+	cons := make([]*Connection, 0, len(s.adsClients))
+	for _, con := range s.adsClients {
+		if con.proxy == nil || con.ConID <= after || (namespace != "" && con.proxy.ConfigNamespace != namespace) {
+			continue
+		}
+		cons = append(cons, con)
+	}
+	s.adsClientsMutex.RUnlock()
+	sort.Slice(cons, func(i, j int) bool {
+		return cons[i].ConID < cons[j].ConID
+	})
+	if limit > 0 && len(cons) > limit {
+		cons = cons[:limit]
+	}
+
+	inventory := make([]InventoryEntry, 0, len(cons))
+	for _, con := range cons {
+		inventory = append(inventory, s.inventoryEntry(con))
+	}
+	out, err := json.MarshalIndent(&inventory, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal inventoryz information: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// inventoryEntry describes the connection.
+func (s *DiscoveryServer) inventoryEntry(con *Connection) InventoryEntry {
+	node := con.proxy
+	entry := InventoryEntry{
+		ConnectionID: con.ConID,
+		ProxyID:      node.ID,
+		Namespace:    node.ConfigNamespace,
+		Type:         node.Type,
+		Istiod:       s.InstanceID,
+		ConnectedAt:  con.Connect,
+		Identities:   con.Identities,
+		Resources:    map[string]InventoryResource{},
+	}
+	entry.SpiffeIdentities = con.SpiffeIdentities()
+	entry.InvalidIdentities, _ = con.InvalidIdentities()
+	if node.Metadata != nil {
+		entry.IstioVersion = node.Metadata.IstioVersion
+		entry.ClusterID = node.Metadata.ClusterID
+	}
+	if t := con.LastFullPush(); !t.IsZero() {
+		entry.LastFullPush = &t
+	}
+	if !con.CertExpiry.IsZero() {
+		expiry := con.CertExpiry
+		entry.CertExpiry = &expiry
+	}
+	nacks := con.activeNacks()
+	node.RLock()
+	for typeURL, wr := range node.WatchedResources {
+		res := InventoryResource{
+			VersionSent:  wr.VersionSent,
+			NonceSent:    wr.NonceSent,
+			VersionAcked: wr.VersionAcked,
+			NonceAcked:   wr.NonceAcked,
+		}
+		if !wr.LastSent.IsZero() {
+			lastSent := wr.LastSent
+			res.LastSent = &lastSent
+		}
+		// A NACK of an older response was superseded by the response sent since.
+		if e, f := nacks[typeURL]; f && wr.NonceSent != "" && e.Nonce == wr.NonceSent {
+			res.Nacked = true
+			entry.Nacked = true
+		}
+		entry.Resources[v3.GetShortType(typeURL)] = res
+	}
+	node.RUnlock()
+	return entry
+}
@@ -0,0 +1,170 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/spiffe"
+)
+
+func TestInventoryz(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	s := &DiscoveryServer{
+		adsClients: map[string]*Connection{},
+		InstanceID: "istiod-1",
+	}
+	s.InternalGen = &InternalGen{Server: s, now: func() time.Time { return now }}
+
+	connect := func(id, namespace string, nodeType model.NodeType) *Connection {
+		con := &Connection{
+			ConID:      id,
+			Connect:    now.Add(-time.Hour),
+			Identities: []string{fmt.Sprintf("spiffe://cluster.local/ns/%s/sa/default", namespace)},
+			proxy: &model.Proxy{
+				ID:              id + "." + namespace,
+				Type:            nodeType,
+				ConfigNamespace: namespace,
+				Metadata:        &model.NodeMetadata{IstioVersion: "1.8.0", ClusterID: "Kubernetes"},
+				WatchedResources: map[string]*model.WatchedResource{
+					v3.ClusterType: {
+						TypeUrl:      v3.ClusterType,
+						VersionSent:  "v2",
+						NonceSent:    "n2",
+						VersionAcked: "v1",
+						NonceAcked:   "n1",
+						LastSent:     now.Add(-time.Minute),
+					},
+					v3.ListenerType: {
+						TypeUrl:      v3.ListenerType,
+						VersionSent:  "v2",
+						NonceSent:    "n3",
+						VersionAcked: "v2",
+						NonceAcked:   "n3",
+						LastSent:     now.Add(-time.Minute),
+					},
+				},
+			},
+		}
+		s.addCon(id, con)
+		return con
+	}
+	nack := func(con *Connection, typeURL, nonce string) {
+		s.InternalGen.recordNack(con, &discovery.DiscoveryRequest{
+			TypeUrl:       typeURL,
+			ResponseNonce: nonce,
+			ErrorDetail:   &status.Status{Message: "rejected"},
+		})
+	}
+	inventoryz := func(query string) []InventoryEntry {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.inventoryz(rr, httptest.NewRequest(http.MethodGet, "/debug/inventoryz"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rr.Code, rr.Body.String())
+		}
+		var got []InventoryEntry
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	ids := func(entries []InventoryEntry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.ConnectionID)
+		}
+		return out
+	}
+
+	a := connect("a", "default", model.SidecarProxy)
+	connect("b", "istio-system", model.Router)
+	c := connect("c", "default", model.SidecarProxy)
+	connect("d", "default", model.SidecarProxy)
+	a.CertExpiry = now.Add(24 * time.Hour)
+	a.recordFullPush(now.Add(-time.Minute))
+	// The NACK of the last cluster response is reported, but not the superseded listener NACK.
+	nack(a, v3.ClusterType, "n2")
+	nack(a, v3.ListenerType, "n0")
+	// A NACK superseded by an ACK is not reported.
+	nack(c, v3.ClusterType, "n2")
+	s.InternalGen.OnAck(c, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+
+	got := inventoryz("")
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(ids(got), want) {
+		t.Fatalf("got connections %v, want %v", ids(got), want)
+	}
+	lastSent := now.Add(-time.Minute)
+	lastFullPush := now.Add(-time.Minute)
+	expiry := now.Add(24 * time.Hour)
+	want := InventoryEntry{
+		ConnectionID:     "a",
+		ProxyID:          "a.default",
+		Namespace:        "default",
+		Type:             model.SidecarProxy,
+		IstioVersion:     "1.8.0",
+		ClusterID:        "Kubernetes",
+		Istiod:           "istiod-1",
+		ConnectedAt:      now.Add(-time.Hour),
+		LastFullPush:     &lastFullPush,
+		Identities:       []string{"spiffe://cluster.local/ns/default/sa/default"},
+		SpiffeIdentities: []spiffe.Identity{{TrustDomain: "cluster.local", Namespace: "default", ServiceAccount: "default"}},
+		CertExpiry:       &expiry,
+		Nacked:           true,
+		Resources: map[string]InventoryResource{
+			"cds": {VersionSent: "v2", NonceSent: "n2", VersionAcked: "v1", NonceAcked: "n1", LastSent: &lastSent, Nacked: true},
+			"lds": {VersionSent: "v2", NonceSent: "n3", VersionAcked: "v2", NonceAcked: "n3", LastSent: &lastSent},
+		},
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Fatalf("got entry %+v, want %+v", got[0], want)
+	}
+	if got[1].Type != model.Router || got[1].LastFullPush != nil || got[1].CertExpiry != nil || got[1].Nacked {
+		t.Errorf("unexpected gateway entry %+v", got[1])
+	}
+	if got[2].Nacked || got[2].Resources["cds"].Nacked {
+		t.Errorf("expected the acked NACK not to be reported, got %+v", got[2])
+	}
+
+	if got := ids(inventoryz("?namespace=default")); !reflect.DeepEqual(got, []string{"a", "c", "d"}) {
+		t.Errorf("got connections %v for the default namespace", got)
+	}
+	if got := ids(inventoryz("?namespace=default&limit=2")); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("got connections %v for the first page", got)
+	}
+	if got := ids(inventoryz("?namespace=default&limit=2&after=c")); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Errorf("got connections %v for the second page", got)
+	}
+	if got := inventoryz("?namespace=missing"); len(got) != 0 {
+		t.Errorf("expected no connections for a missing namespace, got %v", ids(got))
+	}
+
+	rr := httptest.NewRecorder()
+	s.inventoryz(rr, httptest.NewRequest(http.MethodGet, "/debug/inventoryz?limit=0", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid limit to be rejected, got %d", rr.Code)
+	}
+}
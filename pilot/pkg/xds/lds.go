@@ -15,6 +15,7 @@
 package xds
 
 import (
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config"
@@ -42,6 +43,10 @@ func ldsNeedsPush(req *model.PushRequest) bool {
 		// LDS only handles full push
 		return false
 	}
+	if !req.MeshConfigImpact.Affects(model.MeshImpactListeners) {
+		// The mesh config change does not affect listeners
+		return false
+	}
 	// If none set, we will always push
 	if len(req.ConfigsUpdated) == 0 {
 		return true
@@ -54,14 +59,34 @@ func ldsNeedsPush(req *model.PushRequest) bool {
 	return false
 }
 
+var _ model.XdsDiagnosticResourceGenerator = LdsGenerator{}
+
 func (l LdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource, req *model.PushRequest) model.Resources {
+	return l.GenerateWithContext(nil, proxy, push, w, req)
+}
+
+func (l LdsGenerator) GenerateWithContext(ctx *model.GenerationContext, proxy *model.Proxy, push *model.PushContext,
+	w *model.WatchedResource, req *model.PushRequest) model.Resources {
+	resources, _ := l.GenerateWithDiagnostics(ctx, proxy, push, w, req)
+	return resources
+}
+
+func (l LdsGenerator) GenerateWithDiagnostics(ctx *model.GenerationContext, proxy *model.Proxy, push *model.PushContext,
+	w *model.WatchedResource, req *model.PushRequest) (model.Resources, model.GenerationDiagnostics) {
 	if !ldsNeedsPush(req) {
-		return nil
+		return nil, nil
+	}
+	listeners := l.Server.ConfigGenerator.BuildListeners(proxy, push, ctx)
+	// The resources skipped by the builders are always reported, the validation of the built ones is opt-in.
+	diags := ctx.Diagnostics()
+	if features.ValidateGeneratedResources {
+		var invalid model.GenerationDiagnostics
+		listeners, invalid = validateListeners(listeners)
+		diags = append(diags, invalid...)
 	}
-	listeners := l.Server.ConfigGenerator.BuildListeners(proxy, push)
 	resources := model.Resources{}
 	for _, c := range listeners {
 		resources = append(resources, util.MessageToAny(c))
 	}
-	return resources
+	return resources, diags
 }
@@ -0,0 +1,256 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// ClusterLoad aggregates the load reported by a proxy for one of its clusters.
+type ClusterLoad struct {
+	TotalSuccessfulRequests uint64 `json:"totalSuccessfulRequests"`
+	TotalErrorRequests      uint64 `json:"totalErrorRequests"`
+	TotalIssuedRequests     uint64 `json:"totalIssuedRequests"`
+	TotalDroppedRequests    uint64 `json:"totalDroppedRequests"`
+	// RequestsInProgress is the number of requests in progress as of the last report.
+	RequestsInProgress uint64 `json:"requestsInProgress"`
+	// Localities is the load by upstream locality, keyed by region/zone/subzone.
+	Localities map[string]*LocalityLoad `json:"localities,omitempty"`
+	Reports    int                      `json:"reports"`
+	LastReport time.Time                `json:"lastReport"`
+}
+
+// LocalityLoad aggregates the load reported by a proxy for the endpoints of a cluster in one locality.
+type LocalityLoad struct {
+	TotalSuccessfulRequests uint64 `json:"totalSuccessfulRequests"`
+	TotalErrorRequests      uint64 `json:"totalErrorRequests"`
+	TotalIssuedRequests     uint64 `json:"totalIssuedRequests"`
+	RequestsInProgress      uint64 `json:"requestsInProgress"`
+}
+
+// LoadReportingServer implements the Envoy Load Reporting Service, aggregating the load stats reported
+// by each connected proxy. The load of a proxy is dropped when its last stream closes.
+type LoadReportingServer struct {
+	interval time.Duration
+	clusters []string
+	// authenticate returns the identities of the peer of a stream, nil if it is not authenticated. It is set by
+	// the DiscoveryServer, to authenticate streams like ADS connections.
+	authenticate func(ctx context.Context) ([]string, error)
+
+	mutex sync.RWMutex
+	// loads is keyed by proxy ID, then cluster name.
+	loads map[string]map[string]*ClusterLoad
+	// streams counts the open streams of each proxy, which may overlap on reconnection.
+	streams map[string]int
+	now     func() time.Time
+}
+
+var _ lrs.LoadReportingServiceServer = &LoadReportingServer{}
+
+// NewLoadReportingServer creates a LoadReportingServer asking proxies to report every interval, for the given
+// clusters or all of them if empty.
+func NewLoadReportingServer(interval time.Duration, clusters []string) *LoadReportingServer {
+	return &LoadReportingServer{
+		interval: interval,
+		clusters: clusters,
+		loads:    map[string]map[string]*ClusterLoad{},
+		streams:  map[string]int{},
+		now:      time.Now,
+	}
+}
+
+func newLoadReportingServerFromFeatures() *LoadReportingServer {
+	var clusters []string
+	for _, c := range strings.Split(features.LoadReportingClusters, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			clusters = append(clusters, c)
+		}
+	}
+	return NewLoadReportingServer(features.LoadReportingInterval, clusters)
+}
+
+// StreamLoadStats implements lrs.LoadReportingServiceServer. The first request of a stream identifies the proxy,
+// and is answered with the reporting interval and clusters; subsequent requests carry the load reports.
+func (l *LoadReportingServer) StreamLoadStats(stream lrs.LoadReportingService_StreamLoadStatsServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	proxyID := req.GetNode().GetId()
+	if proxyID == "" {
+		return status.Error(codes.InvalidArgument, "missing node id in first load stats request")
+	}
+	if l.authenticate != nil {
+		ids, err := l.authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		if err := authorizeLoadReporter(req.Node, ids); err != nil {
+			recordIdentityMismatch(err)
+			adsLog.Warnf("Unauthorized LRS: %s with identity %v: %v", proxyID, ids, err)
+			return status.Errorf(codes.PermissionDenied, "authorization failed: %v", err)
+		}
+	}
+	l.connect(proxyID)
+	defer l.disconnect(proxyID)
+
+	if err := stream.Send(&lrs.LoadStatsResponse{
+		Clusters:              l.clusters,
+		SendAllClusters:       len(l.clusters) == 0,
+		LoadReportingInterval: ptypes.DurationProto(l.interval),
+	}); err != nil {
+		return err
+	}
+	adsLog.Debugf("LRS: %s connected", proxyID)
+
+	for {
+		l.record(proxyID, req.ClusterStats)
+		if req, err = stream.Recv(); err != nil {
+			if isExpectedGRPCError(err) {
+				adsLog.Debugf("LRS: %s terminated: %v", proxyID, err)
+				return nil
+			}
+			adsLog.Warnf("LRS: %s terminated with error: %v", proxyID, err)
+			return err
+		}
+	}
+}
+
+// authorizeLoadReporter verifies the identities of a stream match the namespace and service account claimed by
+// the node, with the same check as ADS connections. Unlike ADS connections, mismatching streams are rejected in
+// every check mode, as there is no way to quarantine load reports attributed to another proxy.
+func authorizeLoadReporter(node *core.Node, ids []string) error {
+	if !features.EnableXDSIdentityCheck || ids == nil {
+		return nil
+	}
+	meta, err := model.ParseMetadata(node.Metadata)
+	if err != nil {
+		return err
+	}
+	proxy, err := model.ParseServiceNodeWithMetadata(node.Id, meta)
+	if err != nil {
+		return err
+	}
+	proxy.ConfigNamespace = model.GetProxyConfigNamespace(proxy)
+	// The load is keyed by the node ID, so the namespace of the ID, <pod name>.<namespace>, must be the one checked
+	// against the identities too: the namespace of the metadata alone would let a proxy report for another one.
+	if i := strings.LastIndexByte(proxy.ID, '.'); i >= 0 && proxy.ID[i+1:] != proxy.ConfigNamespace {
+		return &identityMismatchError{
+			reason: "namespace",
+			msg:    fmt.Sprintf("node %v does not match namespace %v", proxy.ID, proxy.ConfigNamespace),
+		}
+	}
+	con := &Connection{Identities: ids, proxy: proxy}
+	return checkConnectionIdentity(con, strings.ToUpper(features.XDSIdentityCheckMode) == identityCheckStrict)
+}
+
+func (l *LoadReportingServer) record(proxyID string, stats []*endpoint.ClusterStats) {
+	if len(stats) == 0 {
+		return
+	}
+	now := l.now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	loads := l.loads[proxyID]
+	if loads == nil {
+		loads = map[string]*ClusterLoad{}
+		l.loads[proxyID] = loads
+	}
+	for _, cs := range stats {
+		load := loads[cs.ClusterName]
+		if load == nil {
+			load = &ClusterLoad{Localities: map[string]*LocalityLoad{}}
+			loads[cs.ClusterName] = load
+		}
+		load.TotalDroppedRequests += cs.TotalDroppedRequests
+		load.RequestsInProgress = 0
+		for _, ls := range cs.UpstreamLocalityStats {
+			locality := util.LocalityToString(ls.Locality)
+			ll := load.Localities[locality]
+			if ll == nil {
+				ll = &LocalityLoad{}
+				load.Localities[locality] = ll
+			}
+			ll.TotalSuccessfulRequests += ls.TotalSuccessfulRequests
+			ll.TotalErrorRequests += ls.TotalErrorRequests
+			ll.TotalIssuedRequests += ls.TotalIssuedRequests
+			ll.RequestsInProgress = ls.TotalRequestsInProgress
+
+			load.TotalSuccessfulRequests += ls.TotalSuccessfulRequests
+			load.TotalErrorRequests += ls.TotalErrorRequests
+			load.TotalIssuedRequests += ls.TotalIssuedRequests
+			load.RequestsInProgress += ls.TotalRequestsInProgress
+		}
+		load.Reports++
+		load.LastReport = now
+	}
+}
+
+func (l *LoadReportingServer) connect(proxyID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.streams[proxyID]++
+}
+
+// disconnect drops the load of a proxy once none of its streams is open anymore, so that a stream closing after
+// the proxy reconnected does not drop the load reported on the new stream.
+func (l *LoadReportingServer) disconnect(proxyID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.streams[proxyID]--; l.streams[proxyID] > 0 {
+		return
+	}
+	delete(l.streams, proxyID)
+	delete(l.loads, proxyID)
+}
+
+// Loads returns a copy of the load reported by each proxy, by cluster, optionally filtered on a proxy.
+func (l *LoadReportingServer) Loads(proxyID string) map[string]map[string]ClusterLoad {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	out := map[string]map[string]ClusterLoad{}
+	for id, loads := range l.loads {
+		if proxyID != "" && id != proxyID {
+			continue
+		}
+		clusters := make(map[string]ClusterLoad, len(loads))
+		for cluster, load := range loads {
+			c := *load
+			c.Localities = make(map[string]*LocalityLoad, len(load.Localities))
+			for locality, ll := range load.Localities {
+				llc := *ll
+				c.Localities[locality] = &llc
+			}
+			clusters[cluster] = c
+		}
+		out[id] = clusters
+	}
+	return out
+}
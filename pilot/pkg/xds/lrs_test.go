@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
+	"github.com/golang/protobuf/ptypes"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestLoadReporting(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	client := s.ConnectLRS()
+
+	proxyID := "app.default"
+	if err := client.Send(&lrs.LoadStatsRequest{Node: &core.Node{Id: proxyID}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval, _ := ptypes.Duration(resp.LoadReportingInterval); !resp.SendAllClusters || interval != features.LoadReportingInterval {
+		t.Fatalf("unexpected response %v", resp)
+	}
+
+	report := func(success, errors, inProgress uint64) *lrs.LoadStatsRequest {
+		return &lrs.LoadStatsRequest{ClusterStats: []*endpoint.ClusterStats{{
+			ClusterName:          "outbound|80||b.default.svc.cluster.local",
+			TotalDroppedRequests: 1,
+			UpstreamLocalityStats: []*endpoint.UpstreamLocalityStats{
+				{
+					Locality:                &core.Locality{Region: "region", Zone: "zone1"},
+					TotalSuccessfulRequests: success,
+					TotalErrorRequests:      errors,
+					TotalIssuedRequests:     success + errors,
+					TotalRequestsInProgress: inProgress,
+				},
+				{
+					Locality:                &core.Locality{Region: "region", Zone: "zone2"},
+					TotalSuccessfulRequests: success,
+					TotalIssuedRequests:     success,
+				},
+			},
+		}}}
+	}
+	if err := client.Send(report(10, 2, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(report(5, 1, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[string]ClusterLoad{proxyID: {
+		"outbound|80||b.default.svc.cluster.local": {
+			TotalSuccessfulRequests: 30,
+			TotalErrorRequests:      3,
+			TotalIssuedRequests:     33,
+			TotalDroppedRequests:    2,
+			RequestsInProgress:      1,
+			Localities: map[string]*LocalityLoad{
+				"region/zone1": {TotalSuccessfulRequests: 15, TotalErrorRequests: 3, TotalIssuedRequests: 18, RequestsInProgress: 1},
+				"region/zone2": {TotalSuccessfulRequests: 15, TotalIssuedRequests: 15},
+			},
+			Reports: 2,
+		},
+	}}
+	retry.UntilSuccessOrFail(t, func() error {
+		rr := httptest.NewRecorder()
+		s.Discovery.loadz(rr, httptest.NewRequest("GET", "/debug/loadz?proxyID="+proxyID, nil))
+		got := map[string]map[string]ClusterLoad{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			return err
+		}
+		for _, loads := range got {
+			for cluster, load := range loads {
+				if load.LastReport.IsZero() {
+					return fmt.Errorf("missing last report time for %v", cluster)
+				}
+				load.LastReport = want[proxyID][cluster].LastReport
+				loads[cluster] = load
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("got load %+v, want %+v", got, want)
+		}
+		return nil
+	})
+
+	// The load of a proxy is dropped when it disconnects.
+	if err := client.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if loads := s.Discovery.LoadReporting.Loads(""); len(loads) != 0 {
+			return fmt.Errorf("expected no loads after disconnect, got %v", loads)
+		}
+		return nil
+	})
+}
+
+func TestLoadReportingReconnect(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	proxyID := "app.default"
+	connect := func() lrs.LoadReportingService_StreamLoadStatsClient {
+		client := s.ConnectLRS()
+		if err := client.Send(&lrs.LoadStatsRequest{Node: &core.Node{Id: proxyID}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Recv(); err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+	expectReports := func(reports int) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			load := s.Discovery.LoadReporting.Loads(proxyID)[proxyID]["outbound|80||b.default.svc.cluster.local"]
+			if load.Reports != reports {
+				return fmt.Errorf("got %d reports, want %d", load.Reports, reports)
+			}
+			return nil
+		})
+	}
+
+	// The proxy reconnects before its old stream is closed.
+	old := connect()
+	current := connect()
+	if err := current.Send(&lrs.LoadStatsRequest{ClusterStats: []*endpoint.ClusterStats{{
+		ClusterName: "outbound|80||b.default.svc.cluster.local",
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+	expectReports(1)
+
+	// Closing the old stream keeps the load reported on the current one.
+	if err := old.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Recv(); err == nil {
+		t.Fatal("expected the old stream to be closed")
+	}
+	expectReports(1)
+
+	if err := current.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	expectReports(0)
+}
+
+func TestLoadReportingIdentityCheck(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	s.Discovery.LoadReporting.authenticate = func(context.Context) ([]string, error) {
+		return []string{"spiffe://cluster.local/ns/default/sa/app"}, nil
+	}
+	connect := func(namespace string, meta *structpb.Struct) error {
+		client := s.ConnectLRS()
+		node := &core.Node{Id: fmt.Sprintf("sidecar~1.1.1.1~app-1.%s~%s.svc.cluster.local", namespace, namespace), Metadata: meta}
+		if err := client.Send(&lrs.LoadStatsRequest{Node: node}); err != nil {
+			t.Fatal(err)
+		}
+		_, err := client.Recv()
+		return err
+	}
+
+	if err := connect("default", nil); err != nil {
+		t.Fatalf("expected the proxy of the authenticated namespace to be accepted, got %v", err)
+	}
+	if err := connect("other", nil); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected a proxy claiming another namespace to be denied, got %v", err)
+	}
+	// The metadata claiming the authenticated namespace does not allow to report for a proxy of another namespace.
+	meta := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"NAMESPACE": {Kind: &structpb.Value_StringValue{StringValue: "default"}},
+	}}
+	if err := connect("other", meta); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected a proxy ID of another namespace to be denied, got %v", err)
+	}
+}
@@ -19,6 +19,7 @@ import (
 
 	"google.golang.org/grpc/codes"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/mcp/status"
@@ -26,10 +27,16 @@ import (
 )
 
 var (
-	errTag     = monitoring.MustCreateLabel("err")
-	nodeTag    = monitoring.MustCreateLabel("node")
-	typeTag    = monitoring.MustCreateLabel("type")
-	versionTag = monitoring.MustCreateLabel("version")
+	categoryTag  = monitoring.MustCreateLabel("category")
+	errTag       = monitoring.MustCreateLabel("err")
+	gatewayTag   = monitoring.MustCreateLabel("gateway")
+	namespaceTag = monitoring.MustCreateLabel("namespace")
+	nodeTag      = monitoring.MustCreateLabel("node")
+	proxyTypeTag = monitoring.MustCreateLabel("proxy_type")
+	pushTypeTag  = monitoring.MustCreateLabel("push_type")
+	reasonTag    = monitoring.MustCreateLabel("reason")
+	typeTag      = monitoring.MustCreateLabel("type")
+	versionTag   = monitoring.MustCreateLabel("version")
 
 	cdsReject = monitoring.NewGauge(
 		"pilot_xds_cds_reject",
@@ -55,16 +62,73 @@ var (
 		monitoring.WithLabels(nodeTag, errTag),
 	)
 
+	xdsClientCertExpiry = monitoring.NewDistribution(
+		"pilot_xds_client_cert_expiry_seconds",
+		"Time in seconds until the client certificate presented by a proxy expires, recorded when the proxy connects.",
+		[]float64{0, 3600, 6 * 3600, 24 * 3600, 2 * 24 * 3600, 7 * 24 * 3600, 30 * 24 * 3600, 90 * 24 * 3600, 365 * 24 * 3600},
+		monitoring.WithLabels(namespaceTag),
+	)
+
+	xdsNacksActive = monitoring.NewGauge(
+		"pilot_xds_nacks_active",
+		"Number of proxies whose last response for at least one type was NACKed, and not superseded by an ACK since, "+
+			"within PILOT_XDS_NACK_RETENTION.",
+	)
+
 	xdsExpiredNonce = monitoring.NewSum(
 		"pilot_xds_expired_nonce",
 		"Total number of XDS requests with an expired nonce.",
 	)
 
+	xdsResourceLimitRejects = monitoring.NewSum(
+		"pilot_xds_resource_limit_rejects",
+		"Total number of XDS requests rejected for exceeding the resource name limits.",
+		monitoring.WithLabels(typeTag),
+	)
+
+	xdsGenerationErrors = monitoring.NewSum(
+		"pilot_xds_generation_errors",
+		"Total number of generated resources skipped as invalid instead of being pushed, by type and category.",
+		monitoring.WithLabels(typeTag, categoryTag),
+	)
+
 	totalXDSRejects = monitoring.NewSum(
 		"pilot_total_xds_rejects",
 		"Total number of XDS responses from pilot rejected by proxy.",
 	)
 
+	sdsSecretsOutOfScope = monitoring.NewSum(
+		"pilot_sds_secrets_out_of_scope",
+		"Total number of SDS requests for secrets outside the namespaces or label selector watched by pilot.",
+	)
+
+	sdsCertRotations = monitoring.NewSum(
+		"pilot_sds_gateway_cert_rotations",
+		"Total number of rotated secrets pushed over SDS, by gateway workload.",
+		monitoring.WithLabels(gatewayTag),
+	)
+
+	xdsCompressedResponses = monitoring.NewSum(
+		"pilot_xds_compressed_responses",
+		"Total number of gRPC messages compressed because of their size.",
+	)
+
+	xdsCompressionBytesSaved = monitoring.NewSum(
+		"pilot_xds_compression_bytes_saved",
+		"Total number of bytes saved by compressing the large gRPC messages.",
+	)
+
+	xdsCompressionTime = monitoring.NewDistribution(
+		"pilot_xds_compression_time",
+		"Time in seconds taken to compress a large gRPC message.",
+		[]float64{.0001, .001, .01, .1, 1, 10},
+	)
+
+	debounceDelay = monitoring.NewGauge(
+		"pilot_debounce_delay_seconds",
+		"The debounce delay currently in effect, which adapts to the push pressure when PILOT_DEBOUNCE_AFTER_MAX is set.",
+	)
+
 	monServices = monitoring.NewGauge(
 		"pilot_services",
 		"Total services known to pilot.",
@@ -97,11 +161,29 @@ var (
 	ldsSendErrPushes = pushes.With(typeTag.Value("lds_senderr"))
 	rdsSendErrPushes = pushes.With(typeTag.Value("rds_senderr"))
 
+	// pushTime and proxiesConvergeDelay are labeled with the reason of the push, see PushRequest.ReasonLabel,
+	// and whether it is full or incremental.
 	pushTime = monitoring.NewDistribution(
 		"pilot_xds_push_time",
 		"Total time in seconds Pilot takes to push lds, rds, cds and eds.",
 		[]float64{.01, .1, 1, 3, 5, 10, 20, 30},
-		monitoring.WithLabels(typeTag),
+		monitoring.WithLabels(typeTag, reasonTag, pushTypeTag),
+	)
+
+	// xdsGenerationTime and xdsPushSize are labeled with the type, the proxy type and, if
+	// PILOT_XDS_NAMESPACE_METRICS is enabled, the namespace of the proxy.
+	xdsGenerationTime = monitoring.NewDistribution(
+		"pilot_xds_generation_time",
+		"Time in seconds Pilot takes to generate the resources of a type for a proxy.",
+		[]float64{.001, .01, .1, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(typeTag, proxyTypeTag, namespaceTag),
+	)
+
+	xdsPushSize = monitoring.NewDistribution(
+		"pilot_xds_push_size_bytes",
+		"Serialized size in bytes of the resources of a type pushed to a proxy.",
+		[]float64{1000, 10000, 100000, 1000000, 4000000, 10000000, 40000000},
+		monitoring.WithLabels(typeTag, proxyTypeTag, namespaceTag),
 	)
 
 	// only supported dimension is millis, unfortunately. default to unitdimensionless.
@@ -111,6 +193,18 @@ var (
 		[]float64{.1, 1, 3, 5, 10, 20, 30},
 	)
 
+	// pushPoolWaitTime is labeled with the push pool, either full, incremental or shared
+	// by both when a single push is allowed at a time.
+	pushPoolWaitTime = monitoring.NewDistribution(
+		"pilot_push_pool_wait_time",
+		"Time in seconds the proxies of a push pool wait for a free slot of the pool before being dequeued.",
+		[]float64{.01, .1, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(typeTag),
+	)
+	fullPushPoolWaitTime        = pushPoolWaitTime.With(typeTag.Value("full"))
+	incrementalPushPoolWaitTime = pushPoolWaitTime.With(typeTag.Value("incremental"))
+	sharedPushPoolWaitTime      = pushPoolWaitTime.With(typeTag.Value("shared"))
+
 	pushTriggers = monitoring.NewSum(
 		"pilot_push_triggers",
 		"Total number of times a push was triggered, labeled by reason for the push.",
@@ -122,6 +216,7 @@ var (
 		"pilot_proxy_convergence_time",
 		"Delay in seconds between config change and a proxy receiving all required configuration.",
 		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(reasonTag, pushTypeTag),
 	)
 
 	pushContextErrors = monitoring.NewSum(
@@ -134,12 +229,27 @@ var (
 		"Total number of internal XDS errors in pilot.",
 	)
 
+	xdsIdentityMismatches = monitoring.NewSum(
+		"pilot_xds_identity_mismatches",
+		"Total number of XDS clients whose identity did not match the proxy they claimed to be, labeled by reason.",
+		monitoring.WithLabels(typeTag),
+	)
+
 	inboundUpdates = monitoring.NewSum(
 		"pilot_inbound_updates",
 		"Total number of updates received by pilot.",
 		monitoring.WithLabels(typeTag),
 	)
 
+	edsUpdates = monitoring.NewSum(
+		"pilot_eds_updates",
+		"Total number of endpoint updates, labeled by whether they were batched or applied immediately.",
+		monitoring.WithLabels(typeTag),
+	)
+
+	edsBatchedUpdates   = edsUpdates.With(typeTag.Value("batched"))
+	edsImmediateUpdates = edsUpdates.With(typeTag.Value("immediate"))
+
 	inboundConfigUpdates  = inboundUpdates.With(typeTag.Value("config"))
 	inboundEDSUpdates     = inboundUpdates.With(typeTag.Value("eds"))
 	inboundServiceUpdates = inboundUpdates.With(typeTag.Value("svc"))
@@ -153,6 +263,21 @@ func recordXDSClients(version string, delta float64) {
 	xdsClients.With(versionTag.Value(version)).Record(xdsClientTracker[version])
 }
 
+func recordIdentityMismatch(err error) {
+	reason := "unknown"
+	if e, ok := err.(*identityMismatchError); ok {
+		reason = e.reason
+	}
+	xdsIdentityMismatches.With(typeTag.Value(reason)).Increment()
+}
+
+func recordClientCertExpiry(con *Connection) {
+	if con.CertExpiry.IsZero() {
+		return
+	}
+	xdsClientCertExpiry.With(namespaceTag.Value(con.proxy.ConfigNamespace)).Record(time.Until(con.CertExpiry).Seconds())
+}
+
 func recordPushTriggers(reasons ...model.TriggerReason) {
 	for _, r := range reasons {
 		pushTriggers.With(typeTag.Value(string(r))).Increment()
@@ -195,11 +320,44 @@ func incrementXDSRejects(xdsType string, node, errCode string) {
 	}
 }
 
-func recordPushTime(xdsType string, duration time.Duration) {
-	pushTime.With(typeTag.Value(v3.GetMetricType(xdsType))).Record(duration.Seconds())
+// pushRequestLabels returns the reason and push type labels of the metrics recorded for a push.
+func pushRequestLabels(req *model.PushRequest) []monitoring.LabelValue {
+	pushType := "incremental"
+	if req == nil || req.Full {
+		pushType = "full"
+	}
+	return []monitoring.LabelValue{
+		reasonTag.Value(string(req.ReasonLabel())),
+		pushTypeTag.Value(pushType),
+	}
+}
+
+func recordPushTime(xdsType string, req *model.PushRequest, duration time.Duration) {
+	labels := append([]monitoring.LabelValue{typeTag.Value(v3.GetMetricType(xdsType))}, pushRequestLabels(req)...)
+	pushTime.With(labels...).Record(duration.Seconds())
 	pushes.With(typeTag.Value(v3.GetMetricType(xdsType))).Increment()
 }
 
+// proxyClassLabels returns the labels of the metrics recorded by proxy class for a push of xdsType to the proxy.
+func proxyClassLabels(xdsType string, proxy *model.Proxy) []monitoring.LabelValue {
+	labels := []monitoring.LabelValue{
+		typeTag.Value(v3.GetMetricType(xdsType)),
+		proxyTypeTag.Value(string(proxy.Type)),
+	}
+	if features.EnableXDSNamespaceMetrics {
+		labels = append(labels, namespaceTag.Value(proxy.ConfigNamespace))
+	}
+	return labels
+}
+
+func recordGenerationTime(xdsType string, proxy *model.Proxy, duration time.Duration) {
+	xdsGenerationTime.With(proxyClassLabels(xdsType, proxy)...).Record(duration.Seconds())
+}
+
+func recordPushSize(xdsType string, proxy *model.Proxy, size int) {
+	xdsPushSize.With(proxyClassLabels(xdsType, proxy)...).Record(float64(size))
+}
+
 func init() {
 	monitoring.MustRegister(
 		cdsReject,
@@ -207,17 +365,32 @@ func init() {
 		ldsReject,
 		rdsReject,
 		xdsExpiredNonce,
+		xdsResourceLimitRejects,
+		xdsGenerationErrors,
+		xdsNacksActive,
+		xdsClientCertExpiry,
 		totalXDSRejects,
+		sdsSecretsOutOfScope,
+		sdsCertRotations,
+		xdsCompressedResponses,
+		xdsCompressionBytesSaved,
+		xdsCompressionTime,
+		debounceDelay,
 		monServices,
 		xdsClients,
 		xdsResponseWriteTimeouts,
 		pushes,
 		pushTime,
+		xdsGenerationTime,
+		xdsPushSize,
 		proxiesConvergeDelay,
 		proxiesQueueTime,
+		pushPoolWaitTime,
 		pushContextErrors,
 		totalXDSInternalErrors,
+		xdsIdentityMismatches,
 		inboundUpdates,
+		edsUpdates,
 		pushTriggers,
 	)
 }
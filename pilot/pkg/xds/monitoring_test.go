@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"go.opencensus.io/stats/view"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestPushMetricsByProxyClass(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	watch := []string{v3.ClusterType}
+	s.Connect(&model.Proxy{IPAddresses: []string{"1.1.1.1"}}, watch, watch)
+	s.Connect(&model.Proxy{Type: model.Router, ConfigNamespace: "istio-system", IPAddresses: []string{"1.1.1.2"}}, watch, watch)
+
+	for _, metric := range []string{"pilot_xds_generation_time", "pilot_xds_push_size_bytes"} {
+		rows, err := view.RetrieveData(metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proxyTypes := map[string]int64{}
+		for _, row := range rows {
+			tags := map[string]string{}
+			for _, tag := range row.Tags {
+				tags[tag.Key.Name()] = tag.Value
+			}
+			if tags["type"] == "cds" {
+				proxyTypes[tags["proxy_type"]] += row.Data.(*view.DistributionData).Count
+			}
+		}
+		for _, proxyType := range []model.NodeType{model.SidecarProxy, model.Router} {
+			if proxyTypes[string(proxyType)] == 0 {
+				t.Errorf("expected %s to be recorded for %s, got %v", metric, proxyType, proxyTypes)
+			}
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	s.Discovery.adsz(rr, httptest.NewRequest("GET", "/debug/adsz", nil))
+	clients := AdsClients{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &clients); err != nil {
+		t.Fatal(err)
+	}
+	if len(clients.Connected) != 2 {
+		t.Fatalf("expected 2 connected clients, got %+v", clients.Connected)
+	}
+	for _, c := range clients.Connected {
+		if c.BytesSent["CDS"] <= 0 {
+			t.Errorf("expected CDS bytes sent to %v, got %v", c.ConnectionID, c.BytesSent)
+		}
+	}
+}
+
+func TestPushMetricsByReason(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	watch := []string{v3.ClusterType}
+	ads := s.Connect(nil, watch, watch)
+
+	// Requests with mixed reasons, merged as when debounced.
+	req := (&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.ConfigUpdate}}).
+		Merge(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.ServiceUpdate}})
+	req.Push = s.PushContext()
+	req.Start = time.Now()
+	s.Discovery.AdsPushAll("v1", req)
+	if _, err := ads.Wait(5*time.Second, v3.ClusterType); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]map[string]string{
+		"pilot_xds_push_time": {
+			{"type": "cds", "reason": string(model.ProxyRequest), "push_type": "full"},
+			{"type": "cds", "reason": string(model.MultipleTriggers), "push_type": "full"},
+		},
+		"pilot_proxy_convergence_time": {
+			{"reason": string(model.MultipleTriggers), "push_type": "full"},
+		},
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		for metric, want := range expected {
+			rows, err := view.RetrieveData(metric)
+			if err != nil {
+				return err
+			}
+			for _, labels := range want {
+				if !hasMetricRow(rows, labels) {
+					return fmt.Errorf("expected %s to be recorded with labels %v", metric, labels)
+				}
+			}
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+}
+
+// hasMetricRow returns true if one of the rows has all the labels.
+func hasMetricRow(rows []*view.Row, labels map[string]string) bool {
+	for _, row := range rows {
+		tags := map[string]string{}
+		for _, tag := range row.Tags {
+			tags[tag.Key.Name()] = tag.Value
+		}
+		matches := true
+		for k, v := range labels {
+			if tags[k] != v {
+				matches = false
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+type failingStream struct {
+	fakeStream
+}
+
+func (h *failingStream) Send(*discovery.DiscoveryResponse) error {
+	return errors.New("stream closed")
+}
+
+type fixedGenerator model.Resources
+
+func (g fixedGenerator) Generate(*model.Proxy, *model.PushContext, *model.WatchedResource, *model.PushRequest) model.Resources {
+	return model.Resources(g)
+}
+
+func TestPushSizeSkippedOnSendError(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	typeURL := "type.googleapis.com/istio.test.SendError"
+	con := newConnection("", &failingStream{})
+	con.ConID = "send-error"
+	con.proxy = &model.Proxy{Type: model.SidecarProxy, WatchedResources: map[string]*model.WatchedResource{}}
+	gen := fixedGenerator{{TypeUrl: typeURL, Value: []byte("resource")}}
+	w := &model.WatchedResource{TypeUrl: typeURL}
+	if err := s.Discovery.pushXds(con, s.Discovery.globalPushContext(), gen, "v1", w, nil); err == nil {
+		t.Fatal("expected the push to fail")
+	}
+	rows, err := view.RetrieveData("pilot_xds_push_size_bytes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key.Name() == "type" && tag.Value == typeURL {
+				t.Errorf("unexpected push size recorded for a failed send: %+v", row.Data)
+			}
+		}
+	}
+}
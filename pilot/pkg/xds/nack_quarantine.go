@@ -0,0 +1,217 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// nackTracker attributes the NACKs of proxies to the configs changed by the push of the rejected version, to
+// quarantine the configs rejected by features.NackQuarantineThreshold percent of the connected proxies.
+type nackTracker struct {
+	mu sync.Mutex
+	// pushes holds the full pushes of changed configs within features.NackQuarantineWindow, by version.
+	pushes map[string]*trackedPush
+	// rejecting holds the version each connection rejects for a type, until it is superseded by an ACK. Only the
+	// first version rejected is attributed a NACK, later versions are likely rejected for the same reason.
+	rejecting map[nackKey]string
+	// now is used instead of time.Now if set, for tests.
+	now func() time.Time
+}
+
+type nackKey struct {
+	conID   string
+	typeURL string
+}
+
+// trackedPush is a push whose NACKs are attributed to the configs it changed.
+type trackedPush struct {
+	time    time.Time
+	configs map[model.ConfigKey]struct{}
+	// nacks holds the connections which started rejecting the version, by type.
+	nacks map[string]map[string]struct{}
+}
+
+func newNackTracker() *nackTracker {
+	return &nackTracker{
+		pushes:    map[string]*trackedPush{},
+		rejecting: map[nackKey]string{},
+	}
+}
+
+func (t *nackTracker) timeNow() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// recordPush tracks the push of the version for the configs it changed. The first push of a version is kept, as
+// the pushes of changes outside of the config store, such as services, don't change the config version.
+func (t *nackTracker) recordPush(version string, configs map[model.ConfigKey]struct{}) {
+	if features.NackQuarantineThreshold <= 0 || len(configs) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.timeNow()
+	t.pruneLocked(now)
+	if _, f := t.pushes[version]; f {
+		return
+	}
+	t.pushes[version] = &trackedPush{time: now, configs: configs, nacks: map[string]map[string]struct{}{}}
+}
+
+// onNack records the NACK of the version by the connection, and returns the configs to quarantine if the
+// connections which started rejecting it reach the threshold of the connected proxies.
+func (t *nackTracker) onNack(conID, typeURL, version string, connected int) (map[model.ConfigKey]struct{}, int) {
+	if features.NackQuarantineThreshold <= 0 {
+		return nil, 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := nackKey{conID, typeURL}
+	if _, f := t.rejecting[key]; f {
+		// Already rejecting an earlier version.
+		return nil, 0
+	}
+	t.rejecting[key] = version
+	now := t.timeNow()
+	t.pruneLocked(now)
+	push := t.pushes[version]
+	if push == nil {
+		return nil, 0
+	}
+	if push.nacks[typeURL] == nil {
+		push.nacks[typeURL] = map[string]struct{}{}
+	}
+	push.nacks[typeURL][conID] = struct{}{}
+	rejected := len(push.nacks[typeURL])
+	if float64(rejected) < math.Max(1, features.NackQuarantineThreshold/100*float64(connected)) {
+		return nil, 0
+	}
+	// The configs are quarantined once, the push is no longer tracked.
+	delete(t.pushes, version)
+	return push.configs, rejected
+}
+
+// onAck marks the NACK of the connection for the type as superseded.
+func (t *nackTracker) onAck(conID, typeURL string) {
+	if features.NackQuarantineThreshold <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.rejecting, nackKey{conID, typeURL})
+}
+
+// onDisconnect forgets the NACKs of the connection.
+func (t *nackTracker) onDisconnect(conID string) {
+	if features.NackQuarantineThreshold <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.rejecting {
+		if key.conID == conID {
+			delete(t.rejecting, key)
+		}
+	}
+}
+
+// pruneLocked drops the pushes older than the quarantine window.
+func (t *nackTracker) pruneLocked(now time.Time) {
+	for version, push := range t.pushes {
+		if now.Sub(push.time) > features.NackQuarantineWindow {
+			delete(t.pushes, version)
+		}
+	}
+}
+
+// trackNack attributes the NACK of the request to the configs changed by the push of the rejected version, and
+// quarantines them if enough connected proxies rejected it.
+func (s *DiscoveryServer) trackNack(con *Connection, request *discovery.DiscoveryRequest) {
+	if features.NackQuarantineThreshold <= 0 {
+		return
+	}
+	con.proxy.RLock()
+	w := con.proxy.WatchedResources[request.TypeUrl]
+	version := ""
+	if w != nil && w.NonceSent == request.ResponseNonce {
+		version = w.VersionSent
+	}
+	con.proxy.RUnlock()
+	if version == "" {
+		// The NACK of a response that is no longer the last one sent can't be attributed.
+		return
+	}
+	connected := s.adsClientCount()
+	configs, rejected := s.nackTracker.onNack(con.ConID, request.TypeUrl, version, connected)
+	if len(configs) == 0 {
+		return
+	}
+	reason := fmt.Sprintf("%d of %d connected proxies rejected %s version %s: %s", rejected, connected,
+		v3.GetShortType(request.TypeUrl), version, request.ErrorDetail.GetMessage())
+	s.quarantineConfigs(configs, reason)
+}
+
+// quarantineConfigs excludes the configs from the push context, and pushes the config without them.
+func (s *DiscoveryServer) quarantineConfigs(configs map[model.ConfigKey]struct{}, reason string) {
+	now := time.Now()
+	quarantined := map[model.ConfigKey]struct{}{}
+	for key := range configs {
+		if s.ConfigQuarantine.Add(key, reason, now) {
+			quarantined[key] = struct{}{}
+			adsLog.Errorf("ADS: quarantined %s %s/%s, %s", key.Kind.Kind, key.Namespace, key.Name, reason)
+		}
+	}
+	if len(quarantined) == 0 {
+		return
+	}
+	s.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: quarantined,
+		Reason:         []model.TriggerReason{model.QuarantineTrigger},
+	})
+}
+
+// ReleaseQuarantinedConfig releases the quarantined configs of the kind, name and namespace, and pushes the config
+// with them. It returns false if no config was quarantined.
+func (s *DiscoveryServer) ReleaseQuarantinedConfig(kind, name, namespace string) bool {
+	released := s.ConfigQuarantine.Release(kind, name, namespace)
+	if len(released) == 0 {
+		return false
+	}
+	configs := map[model.ConfigKey]struct{}{}
+	for _, key := range released {
+		adsLog.Infof("ADS: released %s %s/%s from quarantine", key.Kind.Kind, key.Namespace, key.Name)
+		configs[key] = struct{}{}
+	}
+	s.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: configs,
+		Reason:         []model.TriggerReason{model.QuarantineTrigger},
+	})
+	return true
+}
@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestNackQuarantine(t *testing.T) {
+	defer func(threshold float64, window time.Duration) {
+		features.NackQuarantineThreshold = threshold
+		features.NackQuarantineWindow = window
+	}(features.NackQuarantineThreshold, features.NackQuarantineWindow)
+	features.NackQuarantineThreshold = 50
+	features.NackQuarantineWindow = time.Minute
+
+	now := time.Now()
+	s := &DiscoveryServer{
+		adsClients:       map[string]*Connection{},
+		pushChannel:      make(chan *model.PushRequest, 10),
+		ConfigQuarantine: model.NewConfigQuarantine(),
+		nackTracker:      newNackTracker(),
+	}
+	s.nackTracker.now = func() time.Time { return now }
+
+	var cons []*Connection
+	for i := 0; i < 4; i++ {
+		id := fmt.Sprintf("sidecar-%d", i)
+		con := &Connection{ConID: id, proxy: &model.Proxy{
+			ID:               id,
+			Metadata:         &model.NodeMetadata{},
+			WatchedResources: map[string]*model.WatchedResource{},
+		}}
+		s.addCon(id, con)
+		cons = append(cons, con)
+	}
+	// send records the push of the version to all connections.
+	send := func(version, nonce string) {
+		for _, con := range cons {
+			con.proxy.WatchedResources[v3.ListenerType] = &model.WatchedResource{
+				TypeUrl:     v3.ListenerType,
+				VersionSent: version,
+				NonceSent:   nonce,
+			}
+		}
+	}
+	nack := func(con *Connection, nonce string) {
+		s.shouldRespond(con, &discovery.DiscoveryRequest{
+			TypeUrl:       v3.ListenerType,
+			ResponseNonce: nonce,
+			ErrorDetail:   &status.Status{Message: "invalid listener"},
+		})
+	}
+	ack := func(con *Connection, version, nonce string) {
+		s.shouldRespond(con, &discovery.DiscoveryRequest{
+			TypeUrl:       v3.ListenerType,
+			VersionInfo:   version,
+			ResponseNonce: nonce,
+		})
+	}
+	pushed := func() *model.PushRequest {
+		select {
+		case req := <-s.pushChannel:
+			return req
+		default:
+			return nil
+		}
+	}
+
+	bad := model.ConfigKey{Kind: gvk.VirtualService, Name: "bad", Namespace: "default"}
+	s.nackTracker.recordPush("v1", map[model.ConfigKey]struct{}{bad: {}})
+	send("v1", "n1")
+
+	nack(cons[0], "n1")
+	// Repeated and stale NACKs are not attributed again.
+	nack(cons[0], "n1")
+	nack(cons[1], "n0")
+	if !s.ConfigQuarantine.Empty() {
+		t.Fatalf("expected no quarantine below the threshold, got %v", s.ConfigQuarantine.List())
+	}
+
+	nack(cons[1], "n1")
+	if !s.ConfigQuarantine.Contains(bad) {
+		t.Fatal("expected the config to be quarantined once half of the proxies rejected it")
+	}
+	req := pushed()
+	if req == nil || !req.Full || len(req.Reason) != 1 || req.Reason[0] != model.QuarantineTrigger {
+		t.Fatalf("expected a full push for the quarantine, got %+v", req)
+	}
+	if _, f := req.ConfigsUpdated[bad]; !f {
+		t.Fatalf("expected the quarantined config to be updated, got %v", req.ConfigsUpdated)
+	}
+	// The version is no longer tracked once quarantined.
+	nack(cons[2], "n1")
+	if req := pushed(); req != nil {
+		t.Fatalf("expected a single push for the quarantine, got %+v", req)
+	}
+
+	// The proxies recover with the config pushed without the quarantined config.
+	send("v1", "n2")
+	for _, con := range cons {
+		ack(con, "v1", "n2")
+	}
+
+	rec := httptest.NewRecorder()
+	s.configQuarantinez(rec, httptest.NewRequest(http.MethodGet, "/debug/config_quarantinez", nil))
+	var listed []model.QuarantinedConfig
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].Name != "bad" || listed[0].Kind != "VirtualService" || listed[0].Reason == "" {
+		t.Fatalf("unexpected quarantined configs %+v", listed)
+	}
+
+	release := func() int {
+		rec := httptest.NewRecorder()
+		s.configQuarantinez(rec, httptest.NewRequest(http.MethodPost,
+			"/debug/config_quarantinez?kind=VirtualService&name=bad&namespace=default", nil))
+		return rec.Code
+	}
+	if code := release(); code != http.StatusOK {
+		t.Fatalf("expected the config to be released, got %d", code)
+	}
+	if s.ConfigQuarantine.Contains(bad) {
+		t.Fatal("expected the config to be released")
+	}
+	if req := pushed(); req == nil || !req.Full {
+		t.Fatalf("expected a full push for the release, got %+v", req)
+	}
+	if code := release(); code != http.StatusNotFound {
+		t.Fatalf("expected the released config not to be found, got %d", code)
+	}
+
+	// NACKs of pushes older than the window are not attributed.
+	s.nackTracker.recordPush("v2", map[model.ConfigKey]struct{}{bad: {}})
+	send("v2", "n3")
+	now = now.Add(2 * time.Minute)
+	for _, con := range cons {
+		nack(con, "n3")
+	}
+	if !s.ConfigQuarantine.Empty() {
+		t.Fatalf("expected no quarantine after the window, got %v", s.ConfigQuarantine.List())
+	}
+}
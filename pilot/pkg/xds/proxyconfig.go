@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	gogotypes "github.com/gogo/protobuf/types"
+	golangany "github.com/golang/protobuf/ptypes/any"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+// ProxyConfigGenerator generates the effective proxy config of a proxy, the default proxy config of the mesh with
+// the ProxyConfigOverrides of its namespace and workload applied. It is requested by the agent of the proxy.
+type ProxyConfigGenerator struct{}
+
+var _ model.XdsResourceGenerator = &ProxyConfigGenerator{}
+
+func proxyConfigNeedsPush(req *model.PushRequest) bool {
+	if req == nil {
+		return true
+	}
+	if !req.Full {
+		return false
+	}
+	// Config changes do not affect the proxy config, only changes of the mesh config, of the overrides or of
+	// the proxies do.
+	return len(req.ConfigsUpdated) == 0 && req.MeshConfigImpact.Affects(model.MeshImpactProxyConfig)
+}
+
+func (g *ProxyConfigGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource,
+	req *model.PushRequest) model.Resources {
+	if !proxyConfigNeedsPush(req) {
+		return nil
+	}
+	pc, err := gogotypes.MarshalAny(push.EffectiveProxyConfig(proxy))
+	if err != nil {
+		adsLog.Warnf("failed to marshal the proxy config of %s: %v", proxy.ID, err)
+		return nil
+	}
+	return model.Resources{&golangany.Any{
+		TypeUrl: pc.TypeUrl,
+		Value:   pc.Value,
+	}}
+}
+
+// proxyConfigDebug is the effective proxy config of a proxy.
+type proxyConfigDebug struct {
+	ProxyConfig map[string]interface{} `json:"proxyConfig"`
+	// RestartRequired are the fields which differ from the proxy config the proxy was started with, and are
+	// only applied once it is restarted.
+	RestartRequired []string `json:"restartRequired,omitempty"`
+}
+
+// proxyconfigz displays the effective proxy config of a connected proxy, or of a workload given by its namespace
+// and labels.
+func (s *DiscoveryServer) proxyconfigz(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	proxyID, namespace := query.Get("proxy"), query.Get("namespace")
+	if (proxyID == "") == (namespace == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide either a proxy or a namespace in the query string"))
+		return
+	}
+
+	push := s.globalPushContext()
+	out := proxyConfigDebug{}
+	var pc *meshconfig.ProxyConfig
+	if proxyID != "" {
+		con := s.getProxyConnection(proxyID)
+		if con == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Proxy not connected to this Pilot instance"))
+			return
+		}
+		pc = push.EffectiveProxyConfig(con.proxy)
+		if startup := con.proxy.Metadata.ProxyConfig; startup != nil {
+			out.RestartRequired = mesh.RestartRequiredProxyConfigFields((*meshconfig.ProxyConfig)(startup), pc)
+		}
+	} else {
+		workloadLabels, err := parseWorkloadLabels(query.Get("labels"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		var def *meshconfig.ProxyConfig
+		if push.Mesh != nil {
+			def = push.Mesh.DefaultConfig
+		}
+		if pc, err = push.ProxyConfigOverrides.EffectiveProxyConfig(def, namespace, workloadLabels); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = fmt.Fprintf(w, "invalid proxy config overrides for the workload: %v", err)
+			return
+		}
+	}
+
+	var err error
+	if out.ProxyConfig, err = gogoprotomarshal.ToJSONMap(pc); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal proxy config: %v", err)
+		return
+	}
+	b, err := json.MarshalIndent(out, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal proxy config: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
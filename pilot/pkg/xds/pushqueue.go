@@ -30,6 +30,11 @@ type PushQueue struct {
 	// queue maintains ordering of the queue
 	queue []*Connection
 
+	// queuedFull and queuedIncremental count the connections of the queue pending a full and an incremental push,
+	// so that a dequeue of a class only scans the queue if it has a connection of the class.
+	queuedFull        int
+	queuedIncremental int
+
 	// processing stores all connections that have been Dequeue(), but not MarkDone().
 	// The value stored will be initially be nil, but may be populated if the connection is Enqueue().
 	// If model.PushRequest is not nil, it will be Enqueued again once MarkDone has been called.
@@ -63,35 +68,113 @@ func (p *PushQueue) Enqueue(con *Connection, pushRequest *model.PushRequest) {
 	}
 
 	if request, f := p.pending[con]; f {
-		p.pending[con] = request.Merge(pushRequest)
+		merged := request.Merge(pushRequest)
+		if merged.Full && !request.Full {
+			p.queuedIncremental--
+			p.queuedFull++
+			// A waiter on the full pushes may now dequeue the connection.
+			p.cond.Broadcast()
+		}
+		p.pending[con] = merged
 		return
 	}
 
-	p.pending[con] = pushRequest
+	p.push(con, pushRequest)
+}
+
+// push appends the connection pending the request to the queue. The lock must be held.
+func (p *PushQueue) push(con *Connection, request *model.PushRequest) {
+	p.pending[con] = request
 	p.queue = append(p.queue, con)
-	// Signal waiters on Dequeue that a new item is available
-	p.cond.Signal()
+	if request.Full {
+		p.queuedFull++
+	} else {
+		p.queuedIncremental++
+	}
+	// Signal waiters on Dequeue that a new item is available. The waiters may be waiting for different classes
+	// of push, so all of them are woken up.
+	p.cond.Broadcast()
+}
+
+// pushClass selects the pushes a dequeue accepts.
+type pushClass int
+
+const (
+	anyPush pushClass = iota
+	fullPush
+	incrementalPush
+)
+
+// available returns the index in the queue of the first connection pending a push of the class, or -1. The lock
+// must be held.
+func (p *PushQueue) available(class pushClass) int {
+	switch class {
+	case fullPush:
+		if p.queuedFull == 0 {
+			return -1
+		}
+	case incrementalPush:
+		if p.queuedIncremental == 0 {
+			return -1
+		}
+	default:
+		if len(p.queue) == 0 {
+			return -1
+		}
+		return 0
+	}
+	for i, con := range p.queue {
+		if p.pending[con].Full == (class == fullPush) {
+			return i
+		}
+	}
+	return -1
 }
 
 // Remove a proxy from the queue. If there are no proxies ready to be removed, this will block
 func (p *PushQueue) Dequeue() (con *Connection, request *model.PushRequest, shutdown bool) {
+	return p.dequeue(anyPush)
+}
+
+// DequeueFull removes the first proxy pending a full push, if full is set, or an incremental push otherwise, from the
+// queue. If there are no such proxies, this will block.
+func (p *PushQueue) DequeueFull(full bool) (con *Connection, request *model.PushRequest, shutdown bool) {
+	if full {
+		return p.dequeue(fullPush)
+	}
+	return p.dequeue(incrementalPush)
+}
+
+func (p *PushQueue) dequeue(class pushClass) (con *Connection, request *model.PushRequest, shutdown bool) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 
 	// Block until there is one to remove. Enqueue will signal when one is added.
-	for len(p.queue) == 0 && !p.shuttingDown {
+	i := p.available(class)
+	for i < 0 && !p.shuttingDown {
 		p.cond.Wait()
+		i = p.available(class)
 	}
 
-	if len(p.queue) == 0 {
+	if i < 0 {
 		// We must be shutting down.
 		return nil, nil, true
 	}
 
-	con, p.queue = p.queue[0], p.queue[1:]
+	con = p.queue[i]
+	if i == 0 {
+		p.queue = p.queue[1:]
+	} else {
+		p.queue = append(p.queue[:i], p.queue[i+1:]...)
+	}
 
 	request = p.pending[con]
 	delete(p.pending, con)
+	if request.Full {
+		p.queuedFull--
+	} else {
+		p.queuedIncremental--
+	}
 
 	// Mark the connection as in progress
 	p.processing[con] = nil
@@ -108,9 +191,7 @@ func (p *PushQueue) MarkDone(con *Connection) {
 	// If the info is present, that means Enqueue was called while connection was not yet marked done.
 	// This means we need to add it back to the queue.
 	if request != nil {
-		p.pending[con] = request
-		p.queue = append(p.queue, con)
-		p.cond.Signal()
+		p.push(con, request)
 	}
 }
 
@@ -121,6 +202,13 @@ func (p *PushQueue) Pending() int {
 	return len(p.queue)
 }
 
+// Backlog returns the number of proxies waiting to be pushed or being pushed.
+func (p *PushQueue) Backlog() int {
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+	return len(p.queue) + len(p.processing)
+}
+
 // ShutDown will cause queue to ignore all new items added to it. As soon as the
 // worker goroutines have drained the existing items in the queue, they will be
 // instructed to exit.
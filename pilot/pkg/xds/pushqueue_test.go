@@ -291,3 +291,66 @@ func TestProxyQueue(t *testing.T) {
 		}
 	})
 }
+
+func TestProxyQueueDequeueFull(t *testing.T) {
+	proxies := make([]*Connection, 0, 3)
+	for p := 0; p < 3; p++ {
+		proxies = append(proxies, &Connection{ConID: fmt.Sprintf("proxy-%d", p)})
+	}
+	p := NewPushQueue()
+	defer p.ShutDown()
+
+	p.Enqueue(proxies[0], &model.PushRequest{Full: true})
+	p.Enqueue(proxies[1], &model.PushRequest{})
+	p.Enqueue(proxies[2], &model.PushRequest{})
+
+	con, req, _ := p.DequeueFull(false)
+	if con != proxies[1] || req.Full {
+		t.Fatalf("expected the incremental push of %v, got %v", proxies[1].ConID, con.ConID)
+	}
+	// An incremental push merged with a full push is dequeued as a full push.
+	p.Enqueue(proxies[2], &model.PushRequest{Full: true})
+	for _, want := range []*Connection{proxies[0], proxies[2]} {
+		con, req, _ := p.DequeueFull(true)
+		if con != want || !req.Full {
+			t.Fatalf("expected the full push of %v, got %v", want.ConID, con.ConID)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.DequeueFull(false)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("expected the dequeue to block without incremental pushes")
+	case <-time.After(time.Millisecond * 100):
+	}
+	p.MarkDone(proxies[1])
+	p.Enqueue(proxies[1], &model.PushRequest{})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the incremental push to be dequeued")
+	}
+}
+
+func TestPushQueueDepth(t *testing.T) {
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, nil)
+	for i := 0; i < 3; i++ {
+		s.pushQueue.Enqueue(&Connection{ConID: fmt.Sprint(i)}, &model.PushRequest{Full: true})
+	}
+	if got := s.PushQueueDepth(); got != 3 {
+		t.Fatalf("expected a push queue depth of 3, got %d", got)
+	}
+	// A push in flight is still part of the backlog.
+	con, _, _ := s.pushQueue.Dequeue()
+	if got := s.PushQueueDepth(); got != 3 {
+		t.Fatalf("expected a push queue depth of 3 with a push in flight, got %d", got)
+	}
+	s.pushQueue.MarkDone(con)
+	if got := s.PushQueueDepth(); got != 2 {
+		t.Fatalf("expected a push queue depth of 2, got %d", got)
+	}
+}
@@ -25,7 +25,7 @@ type RdsGenerator struct {
 	Server *DiscoveryServer
 }
 
-var _ model.XdsResourceGenerator = &RdsGenerator{}
+var _ model.XdsPartialResourceGenerator = &RdsGenerator{}
 
 // Map of all configs that do not impact RDS
 var skippedRdsConfigs = map[config.GroupVersionKind]struct{}{
@@ -45,6 +45,10 @@ func rdsNeedsPush(req *model.PushRequest) bool {
 		// RDS only handles full push
 		return false
 	}
+	if !req.MeshConfigImpact.Affects(model.MeshImpactRoutes) {
+		// The mesh config change does not affect routes
+		return false
+	}
 	// If none set, we will always push
 	if len(req.ConfigsUpdated) == 0 {
 		return true
@@ -61,7 +65,20 @@ func (c RdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *m
 	if !rdsNeedsPush(req) {
 		return nil
 	}
-	rawRoutes := c.Server.ConfigGenerator.BuildHTTPRoutes(proxy, push, w.ResourceNames)
+	return c.buildRoutes(proxy, push, w.ResourceNames)
+}
+
+// GeneratePartial generates the routes of the names only, when a proxy requests routes on demand.
+func (c RdsGenerator) GeneratePartial(proxy *model.Proxy, push *model.PushContext, w *model.WatchedResource,
+	names []string, req *model.PushRequest) model.Resources {
+	if !rdsNeedsPush(req) {
+		return nil
+	}
+	return c.buildRoutes(proxy, push, names)
+}
+
+func (c RdsGenerator) buildRoutes(proxy *model.Proxy, push *model.PushContext, names []string) model.Resources {
+	rawRoutes := c.Server.ConfigGenerator.BuildHTTPRoutes(proxy, push, names)
 	resources := model.Resources{}
 	for _, c := range rawRoutes {
 		resources = append(resources, util.MessageToAny(c))
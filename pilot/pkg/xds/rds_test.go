@@ -14,8 +14,17 @@
 package xds_test
 
 import (
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 )
 
@@ -66,3 +75,57 @@ func TestRDS(t *testing.T) {
 		})
 	}
 }
+
+func TestRDSPartialResponse(t *testing.T) {
+	defer func(enabled bool) { features.EnablePartialXDSResponses = enabled }(features.EnablePartialXDSResponses)
+	features.EnablePartialXDSResponses = true
+
+	routeNames := func(res *discovery.DiscoveryResponse) []string {
+		var names []string
+		for _, r := range res.Resources {
+			rc := &route.RouteConfiguration{}
+			if err := ptypes.UnmarshalAny(r, rc); err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, rc.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	adscon := s.ConnectADS()
+	node := sidecarID(app3Ip, "app3")
+	if err := sendRDSReq(node, []string{"80"}, "", "", adscon); err != nil {
+		t.Fatal(err)
+	}
+	res, err := adsReceive(adscon, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := routeNames(res); !reflect.DeepEqual(got, []string{"80"}) {
+		t.Fatalf("got routes %v, want [80]", got)
+	}
+
+	// Envoy requests a route on demand, acknowledging the response.
+	if err := sendRDSReq(node, []string{"80", "8080"}, res.VersionInfo, res.Nonce, adscon); err != nil {
+		t.Fatal(err)
+	}
+	res, err = adsReceive(adscon, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := routeNames(res); !reflect.DeepEqual(got, []string{"8080"}) {
+		t.Fatalf("got routes %v, want only the added route [8080]", got)
+	}
+
+	// A full push sends all the watched routes.
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+	res, err = adsReceive(adscon, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := routeNames(res); !reflect.DeepEqual(got, []string{"80", "8080"}) {
+		t.Fatalf("got routes %v, want [80 8080]", got)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
+	"istio.io/istio/pilot/pkg/serviceregistry/mock"
+)
+
+func TestRegistryhealthz(t *testing.T) {
+	ctrl := aggregate.NewController(aggregate.Options{})
+	ctrl.AddRegistry(serviceregistry.Simple{
+		ProviderID: serviceregistry.Mock,
+		ClusterID:  "cluster-1",
+		Controller: &mock.Controller{},
+	})
+	s := &DiscoveryServer{Env: &model.Environment{ServiceDiscovery: ctrl}}
+
+	rr := httptest.NewRecorder()
+	s.registryhealthz(rr, httptest.NewRequest("GET", "/debug/registryhealthz", nil))
+	var got []aggregate.RegistryHealth
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+	}
+	if len(got) != 1 || got[0].Cluster != "cluster-1" || got[0].Provider != string(serviceregistry.Mock) || got[0].Stale {
+		t.Fatalf("unexpected registry health %+v", got)
+	}
+}
+
+func TestBuildStaleLbEndpoint(t *testing.T) {
+	ep := buildEnvoyLbEndpoint(&model.IstioEndpoint{Address: "10.0.0.1", EndpointPort: 8080, TLSMode: model.IstioMutualTLSModeLabel})
+	stale := buildStaleLbEndpoint(ep)
+
+	if _, f := ep.Metadata.FilterMetadata[util.IstioMetadataKey].GetFields()[staleRegistryMetadataKey]; f {
+		t.Fatal("the endpoint shared by the pushes was modified")
+	}
+	md := stale.Metadata.FilterMetadata[util.IstioMetadataKey].GetFields()
+	if !md[staleRegistryMetadataKey].GetBoolValue() {
+		t.Fatalf("expected the endpoint to be flagged stale, got %v", stale.Metadata)
+	}
+	// The other metadata, such as the TLS mode used by the transport socket matches, is kept.
+	if _, f := stale.Metadata.FilterMetadata[util.EnvoyTransportSocketMetadataKey]; !f {
+		t.Fatalf("expected the transport socket metadata to be kept, got %v", stale.Metadata)
+	}
+}
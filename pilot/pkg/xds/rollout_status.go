@@ -0,0 +1,469 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// defaultRolloutStatusTimeout is how long /debug/rollout_status waits for a change to be distributed, unless
+// ?timeout= is set.
+const defaultRolloutStatusTimeout = 30 * time.Second
+
+// RolloutStatus is the distribution of the last change of a config to the proxies.
+type RolloutStatus struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Version is the first config version containing the change.
+	Version string `json:"version"`
+	// Pushed holds the connections a version containing the change was pushed to.
+	Pushed []string `json:"pushed"`
+	// Acked is the number of connections which ACKed all the types pushed with the change.
+	Acked int `json:"acked"`
+	// Pending is the number of connections the change is being pushed to, or which did not respond yet.
+	Pending int `json:"pending"`
+	// Nacks holds the connections which rejected a version containing the change.
+	Nacks []RolloutNack `json:"nacks,omitempty"`
+	// Distributed is true once all the connections the change was pushed to ACKed it.
+	Distributed bool `json:"distributed"`
+	// TimedOut is true if the change was not distributed within the timeout of the request.
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// RolloutNack is the rejection of a version containing a change by a connection.
+type RolloutNack struct {
+	ConnectionID string `json:"connectionID"`
+	Type         string `json:"type"`
+	Message      string `json:"message"`
+}
+
+// rolloutTracker tracks the distribution of the changed configs, from the config versions containing the change
+// to the connections they were pushed to and their ACKs or NACKs, within features.RolloutStatusWindow.
+type rolloutTracker struct {
+	mu sync.Mutex
+	// rollouts holds the rollout of the last change of each config. The configs changed by a push share its rollout.
+	rollouts map[model.ConfigKey]*rollout
+	// active holds the rollouts which are the last change of a config, in push order.
+	active []*rollout
+	// byConnection holds the distribution of the active rollouts to each connection, by connection ID, so that
+	// the responses of a connection only visit the rollouts it is part of.
+	byConnection map[string]map[*rollout]*proxyRollout
+	// changed is closed and replaced when a rollout progresses, to wake up its watchers.
+	changed chan struct{}
+	// now is used instead of time.Now if set, for tests.
+	now func() time.Time
+}
+
+// rollout is the distribution of the configs changed by a push.
+type rollout struct {
+	started time.Time
+	// version is the first config version containing the change.
+	version string
+	// versions holds all the config versions containing the change: the versions pushed since, until the config
+	// changes again.
+	versions map[string]struct{}
+	// configs holds the configs whose last change is this rollout.
+	configs map[model.ConfigKey]struct{}
+	// targeted is true once the connections to push a version containing the change to are known.
+	targeted bool
+	// proxies holds the distribution to each connection, by connection ID.
+	proxies map[string]*proxyRollout
+}
+
+// proxyRollout is the distribution of a config change to a connection.
+type proxyRollout struct {
+	// pushed is true once a full push of a version containing the change completed.
+	pushed bool
+	// skipped is true if the connection did not need the push.
+	skipped bool
+	// pending holds the nonces sent with the change which were not responded to yet, by type.
+	pending map[string]string
+	// nack is the last rejection of a version containing the change, until the type is ACKed.
+	nack *RolloutNack
+}
+
+func (p *proxyRollout) acked() bool {
+	return p.pushed && len(p.pending) == 0 && p.nack == nil
+}
+
+func newRolloutTracker() *rolloutTracker {
+	return &rolloutTracker{
+		rollouts:     map[model.ConfigKey]*rollout{},
+		byConnection: map[string]map[*rollout]*proxyRollout{},
+		changed:      make(chan struct{}),
+	}
+}
+
+func (t *rolloutTracker) timeNow() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// enabled returns false if rollouts are not tracked. The tracker is nil for servers created without it in tests.
+func (t *rolloutTracker) enabled() bool {
+	return t != nil && features.RolloutStatusWindow > 0
+}
+
+// notifyLocked wakes up the watchers of the rollouts.
+func (t *rolloutTracker) notifyLocked() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// rolloutConfigs returns the configs changed by the push request whose rollout is tracked. The service entries
+// updated by the endpoints of a registry are not tracked: they cannot be told apart from the service entries of
+// the config store in a request merged with an endpoint update, so the latter are not tracked either.
+func rolloutConfigs(req *model.PushRequest) map[model.ConfigKey]struct{} {
+	endpointUpdate := false
+	for _, reason := range req.Reason {
+		if reason == model.EndpointUpdate {
+			endpointUpdate = true
+			break
+		}
+	}
+	if !endpointUpdate {
+		return req.ConfigsUpdated
+	}
+	configs := make(map[model.ConfigKey]struct{}, len(req.ConfigsUpdated))
+	for key := range req.ConfigsUpdated {
+		if key.Kind != gvk.ServiceEntry {
+			configs[key] = struct{}{}
+		}
+	}
+	return configs
+}
+
+// recordPush starts the rollout of the configs changed by the version, which the rollouts of the other configs
+// are also part of.
+func (t *rolloutTracker) recordPush(version string, configs map[model.ConfigKey]struct{}) {
+	if !t.enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.timeNow()
+	active := t.active[:0]
+	for _, r := range t.active {
+		if now.Sub(r.started) > features.RolloutStatusWindow {
+			for key := range r.configs {
+				delete(t.rollouts, key)
+			}
+			t.untrackConnectionsLocked(r)
+			continue
+		}
+		r.versions[version] = struct{}{}
+		active = append(active, r)
+	}
+	t.active = active
+	if len(configs) == 0 {
+		return
+	}
+	r := &rollout{
+		started:  now,
+		version:  version,
+		versions: map[string]struct{}{version: {}},
+		configs:  make(map[model.ConfigKey]struct{}, len(configs)),
+		proxies:  map[string]*proxyRollout{},
+	}
+	for key := range configs {
+		if previous := t.rollouts[key]; previous != nil {
+			delete(previous.configs, key)
+			if len(previous.configs) == 0 {
+				t.dropLocked(previous)
+			}
+		}
+		t.rollouts[key] = r
+		r.configs[key] = struct{}{}
+	}
+	t.active = append(t.active, r)
+	t.notifyLocked()
+}
+
+// dropLocked stops tracking the rollout, which is no longer the last change of any config.
+func (t *rolloutTracker) dropLocked(r *rollout) {
+	for i, active := range t.active {
+		if active == r {
+			t.active = append(t.active[:i], t.active[i+1:]...)
+			break
+		}
+	}
+	t.untrackConnectionsLocked(r)
+}
+
+// untrackConnectionsLocked removes the rollout from the index of the connections it was pushed to.
+func (t *rolloutTracker) untrackConnectionsLocked(r *rollout) {
+	for id := range r.proxies {
+		delete(t.byConnection[id], r)
+		if len(t.byConnection[id]) == 0 {
+			delete(t.byConnection, id)
+		}
+	}
+}
+
+// recordTargets records the connections a full push of the version is sent to.
+func (t *rolloutTracker) recordTargets(version string, conIDs []string) {
+	if !t.enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	updated := false
+	for _, r := range t.active {
+		if _, f := r.versions[version]; !f {
+			continue
+		}
+		r.targeted = true
+		updated = true
+		for _, id := range conIDs {
+			if r.proxies[id] != nil {
+				continue
+			}
+			p := &proxyRollout{pending: map[string]string{}}
+			r.proxies[id] = p
+			if t.byConnection[id] == nil {
+				t.byConnection[id] = map[*rollout]*proxyRollout{}
+			}
+			t.byConnection[id][r] = p
+		}
+	}
+	if updated {
+		t.notifyLocked()
+	}
+}
+
+// update applies f to the distribution to the connection of its rollouts, or of those containing the version if set.
+func (t *rolloutTracker) update(conID, version string, f func(p *proxyRollout) bool) {
+	if !t.enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	updated := false
+	// Only the connections targeted by a full push are tracked, new connections receive the change on their
+	// initial requests.
+	for r, p := range t.byConnection[conID] {
+		if version != "" {
+			if _, ok := r.versions[version]; !ok {
+				continue
+			}
+		}
+		if f(p) {
+			updated = true
+		}
+	}
+	if updated {
+		t.notifyLocked()
+	}
+}
+
+// onPushed records the response sent for the type with a version containing the changes.
+func (t *rolloutTracker) onPushed(conID, typeURL, version, nonce string) {
+	t.update(conID, version, func(p *proxyRollout) bool {
+		p.pending[typeURL] = nonce
+		p.skipped = false
+		return true
+	})
+}
+
+// onPushDone records the completion of the full push of the version to the connection.
+func (t *rolloutTracker) onPushDone(conID, version string) {
+	t.update(conID, version, func(p *proxyRollout) bool {
+		p.pushed = true
+		return true
+	})
+}
+
+// onSkipped records that the connection did not need the full push of the version.
+func (t *rolloutTracker) onSkipped(conID, version string) {
+	t.update(conID, version, func(p *proxyRollout) bool {
+		if p.pushed || len(p.pending) > 0 {
+			// A version containing the change is already being distributed to the connection.
+			return false
+		}
+		p.skipped = true
+		return true
+	})
+}
+
+// onAck records the ACK of the response with the nonce.
+func (t *rolloutTracker) onAck(conID, typeURL, nonce string) {
+	t.update(conID, "", func(p *proxyRollout) bool {
+		if p.pending[typeURL] != nonce {
+			return false
+		}
+		delete(p.pending, typeURL)
+		if p.nack != nil && p.nack.Type == v3.GetShortType(typeURL) {
+			p.nack = nil
+		}
+		return true
+	})
+}
+
+// onNack records the rejection of the response with the nonce.
+func (t *rolloutTracker) onNack(conID, typeURL, nonce, message string) {
+	t.update(conID, "", func(p *proxyRollout) bool {
+		if p.pending[typeURL] != nonce {
+			return false
+		}
+		delete(p.pending, typeURL)
+		p.nack = &RolloutNack{ConnectionID: conID, Type: v3.GetShortType(typeURL), Message: message}
+		return true
+	})
+}
+
+// onDisconnect stops expecting the connection to respond.
+func (t *rolloutTracker) onDisconnect(conID string) {
+	if !t.enabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rollouts, f := t.byConnection[conID]
+	if !f {
+		return
+	}
+	for r := range rollouts {
+		delete(r.proxies, conID)
+	}
+	delete(t.byConnection, conID)
+	t.notifyLocked()
+}
+
+// status returns the rollout of the last change of the config, or nil if it is not tracked, and a channel closed
+// once a rollout progresses.
+func (t *rolloutTracker) status(kind, name, namespace string) (*RolloutStatus, <-chan struct{}) {
+	if t == nil {
+		return nil, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, r := range t.rollouts {
+		if key.Kind.Kind != kind || key.Name != name || key.Namespace != namespace {
+			continue
+		}
+		out := &RolloutStatus{
+			Kind:      kind,
+			Name:      name,
+			Namespace: namespace,
+			Version:   r.version,
+			Pushed:    []string{},
+		}
+		for id, p := range r.proxies {
+			if p.pushed || len(p.pending) > 0 || p.nack != nil {
+				out.Pushed = append(out.Pushed, id)
+			}
+			switch {
+			case p.skipped:
+			case p.nack != nil:
+				out.Nacks = append(out.Nacks, *p.nack)
+			case p.acked():
+				out.Acked++
+			default:
+				out.Pending++
+			}
+		}
+		sort.Strings(out.Pushed)
+		sort.Slice(out.Nacks, func(i, j int) bool {
+			return out.Nacks[i].ConnectionID < out.Nacks[j].ConnectionID
+		})
+		out.Distributed = r.targeted && out.Pending == 0 && len(out.Nacks) == 0
+		return out, t.changed
+	}
+	return nil, t.changed
+}
+
+// rolloutStatus streams the distribution of the last change of the ?kind=&name=&namespace= config, as a JSON object
+// per line on every progress, until it is distributed to all the proxies it was pushed to or ?timeout= elapses.
+func (s *DiscoveryServer) rolloutStatus(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	kind, name, namespace := q.Get("kind"), q.Get("name"), q.Get("namespace")
+	if kind == "" || name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a kind and a name in the query string"))
+		return
+	}
+	timeout := defaultRolloutStatusTimeout
+	if t := q.Get("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid timeout %q: %v", t, err)
+			return
+		}
+		timeout = d
+	}
+	if !s.rollouts.enabled() {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("Rollouts are not tracked, PILOT_ROLLOUT_STATUS_WINDOW is not set"))
+		return
+	}
+	st, changed := s.rollouts.status(kind, name, namespace)
+	if st == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, "No change of the config within PILOT_ROLLOUT_STATUS_WINDOW (%v)", features.RolloutStatusWindow)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	write := func(st *RolloutStatus) {
+		_ = enc.Encode(st)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var last *RolloutStatus
+	for {
+		if !reflect.DeepEqual(st, last) {
+			write(st)
+			last = st
+		}
+		if st.Distributed {
+			return
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			st.TimedOut = true
+			write(st)
+			return
+		case <-req.Context().Done():
+			return
+		}
+		next, nextChanged := s.rollouts.status(kind, name, namespace)
+		if next == nil {
+			// The rollout is no longer tracked.
+			return
+		}
+		st, changed = next, nextChanged
+	}
+}
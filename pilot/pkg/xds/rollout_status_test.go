@@ -0,0 +1,315 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// rolloutProgress is the progress of a rollout, without the connections it was pushed to.
+type rolloutProgress struct {
+	pushed, acked, pending, nacks int
+	distributed                   bool
+}
+
+func progressOf(st *RolloutStatus) rolloutProgress {
+	return rolloutProgress{
+		pushed:      len(st.Pushed),
+		acked:       st.Acked,
+		pending:     st.Pending,
+		nacks:       len(st.Nacks),
+		distributed: st.Distributed,
+	}
+}
+
+// rolloutStatusRequest returns the request of /debug/rollout_status for the reviews VirtualService.
+func rolloutStatusRequest(timeout string) *http.Request {
+	return httptest.NewRequest("GET",
+		"/debug/rollout_status?kind=VirtualService&name=reviews&namespace=default&timeout="+timeout, nil)
+}
+
+// parseRolloutStatus returns the statuses streamed by /debug/rollout_status.
+func parseRolloutStatus(t *testing.T, rr *httptest.ResponseRecorder) []*RolloutStatus {
+	t.Helper()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	var out []*RolloutStatus
+	scanner := bufio.NewScanner(strings.NewReader(rr.Body.String()))
+	for scanner.Scan() {
+		st := &RolloutStatus{}
+		if err := json.Unmarshal(scanner.Bytes(), st); err != nil {
+			t.Fatalf("invalid status %q: %v", scanner.Text(), err)
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// readRolloutStatus returns the statuses streamed by /debug/rollout_status for the reviews VirtualService.
+func readRolloutStatus(t *testing.T, s *DiscoveryServer, timeout string) []*RolloutStatus {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	s.rolloutStatus(rr, rolloutStatusRequest(timeout))
+	return parseRolloutStatus(t, rr)
+}
+
+// flushNotifier is a recorder notifying each status streamed.
+type flushNotifier struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+}
+
+func (r *flushNotifier) Flush() {
+	r.ResponseRecorder.Flush()
+	r.flushed <- struct{}{}
+}
+
+func TestRolloutStatus(t *testing.T) {
+	defer func(window time.Duration) { features.RolloutStatusWindow = window }(features.RolloutStatusWindow)
+	features.RolloutStatusWindow = time.Minute
+
+	s := &DiscoveryServer{
+		adsClients:  map[string]*Connection{},
+		nackTracker: newNackTracker(),
+		rollouts:    newRolloutTracker(),
+	}
+	var cons []*Connection
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("sidecar-%d", i)
+		con := &Connection{ConID: id, proxy: &model.Proxy{
+			ID:               id,
+			Metadata:         &model.NodeMetadata{},
+			WatchedResources: map[string]*model.WatchedResource{},
+		}}
+		s.addCon(id, con)
+		cons = append(cons, con)
+		ids = append(ids, id)
+	}
+	// push simulates the full push of the version to the connection, with a route configuration response.
+	push := func(con *Connection, version, nonce string) {
+		con.proxy.WatchedResources[v3.RouteType] = &model.WatchedResource{
+			TypeUrl:     v3.RouteType,
+			VersionSent: version,
+			NonceSent:   nonce,
+		}
+		s.rollouts.onPushed(con.ConID, v3.RouteType, version, nonce)
+		s.rollouts.onPushDone(con.ConID, version)
+	}
+	respond := func(con *Connection, version, nonce, nack string) {
+		req := &discovery.DiscoveryRequest{TypeUrl: v3.RouteType, VersionInfo: version, ResponseNonce: nonce}
+		if nack != "" {
+			req.ErrorDetail = &status.Status{Message: nack}
+		}
+		s.shouldRespond(con, req)
+	}
+
+	var got []rolloutProgress
+	record := func() {
+		st, _ := s.rollouts.status(gvk.VirtualService.Kind, "reviews", "default")
+		if st == nil {
+			t.Fatal("expected the rollout of the config to be tracked")
+		}
+		got = append(got, progressOf(st))
+	}
+
+	if st, _ := s.rollouts.status(gvk.VirtualService.Kind, "reviews", "default"); st != nil {
+		t.Fatalf("expected no rollout before the config changed, got %+v", st)
+	}
+
+	reviews := model.ConfigKey{Kind: gvk.VirtualService, Name: "reviews", Namespace: "default"}
+	s.rollouts.recordPush("v1", map[model.ConfigKey]struct{}{reviews: {}})
+	record()
+	s.rollouts.recordTargets("v1", ids)
+	record()
+	for _, con := range cons {
+		push(con, "v1", "n1")
+	}
+	record()
+	respond(cons[0], "v1", "n1", "")
+	record()
+	respond(cons[1], "v1", "n1", "")
+	record()
+	respond(cons[2], "v0", "n1", "route weights must sum to 100")
+	record()
+
+	want := []rolloutProgress{
+		// Not yet pushed, the connections to push to are not known.
+		{},
+		{pending: 3},
+		{pushed: 3, pending: 3},
+		{pushed: 3, acked: 1, pending: 2},
+		{pushed: 3, acked: 2, pending: 1},
+		{pushed: 3, acked: 2, nacks: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got progress %+v, want %+v", got, want)
+	}
+
+	// The stream of the rollout ends on the timeout, as a proxy rejected the change.
+	statuses := readRolloutStatus(t, s, "10ms")
+	if len(statuses) != 2 {
+		t.Fatalf("expected the status and its timeout, got %d statuses", len(statuses))
+	}
+	last := statuses[1]
+	if !last.TimedOut || last.Distributed || last.Version != "v1" {
+		t.Fatalf("expected the rollout of v1 to time out, got %+v", last)
+	}
+	if want := []string{"sidecar-0", "sidecar-1", "sidecar-2"}; !reflect.DeepEqual(last.Pushed, want) {
+		t.Fatalf("got pushed %v, want %v", last.Pushed, want)
+	}
+	wantNacks := []RolloutNack{{ConnectionID: "sidecar-2", Type: "RDS", Message: "route weights must sum to 100"}}
+	if !reflect.DeepEqual(last.Nacks, wantNacks) {
+		t.Fatalf("got NACKs %+v, want %+v", last.Nacks, wantNacks)
+	}
+
+	// An unrelated change is pushed, and accepted by the proxy which rejected the previous version.
+	other := model.ConfigKey{Kind: gvk.VirtualService, Name: "ratings", Namespace: "default"}
+	s.rollouts.recordPush("v2", map[model.ConfigKey]struct{}{other: {}})
+	s.rollouts.recordTargets("v2", ids[2:])
+	push(cons[2], "v2", "n2")
+	respond(cons[2], "v2", "n2", "")
+
+	statuses = readRolloutStatus(t, s, "10ms")
+	if len(statuses) != 1 {
+		t.Fatalf("expected the stream to end once distributed, got %d statuses", len(statuses))
+	}
+	if got, want := progressOf(statuses[0]), (rolloutProgress{pushed: 3, acked: 3, distributed: true}); got != want {
+		t.Fatalf("got progress %+v, want %+v", got, want)
+	}
+}
+
+func TestRolloutStatusWatch(t *testing.T) {
+	defer func(window time.Duration) { features.RolloutStatusWindow = window }(features.RolloutStatusWindow)
+	features.RolloutStatusWindow = time.Minute
+
+	s := &DiscoveryServer{rollouts: newRolloutTracker()}
+	reviews := model.ConfigKey{Kind: gvk.VirtualService, Name: "reviews", Namespace: "default"}
+	s.rollouts.recordPush("v1", map[model.ConfigKey]struct{}{reviews: {}})
+	s.rollouts.recordTargets("v1", []string{"sidecar-0"})
+	s.rollouts.onPushed("sidecar-0", v3.RouteType, "v1", "n1")
+	s.rollouts.onPushDone("sidecar-0", "v1")
+
+	rr := &flushNotifier{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		s.rolloutStatus(rr, rolloutStatusRequest("1m"))
+		close(done)
+	}()
+	// The status is streamed until the proxy ACKs the change.
+	<-rr.flushed
+	s.rollouts.onAck("sidecar-0", v3.RouteType, "n1")
+	<-rr.flushed
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the stream did not end once the change was distributed")
+	}
+
+	var got []rolloutProgress
+	for _, st := range parseRolloutStatus(t, rr.ResponseRecorder) {
+		got = append(got, progressOf(st))
+	}
+	want := []rolloutProgress{{pushed: 1, pending: 1}, {pushed: 1, acked: 1, distributed: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got progress %+v, want %+v", got, want)
+	}
+}
+
+func TestRolloutStatusUnknownConfig(t *testing.T) {
+	s := &DiscoveryServer{rollouts: newRolloutTracker()}
+	rr := httptest.NewRecorder()
+	s.rolloutStatus(rr, httptest.NewRequest("GET", "/debug/rollout_status?kind=VirtualService&name=missing", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRolloutConfigs(t *testing.T) {
+	reviews := model.ConfigKey{Kind: gvk.VirtualService, Name: "reviews", Namespace: "default"}
+	hostname := model.ConfigKey{Kind: gvk.ServiceEntry, Name: "reviews.default.svc.cluster.local", Namespace: "default"}
+	req := &model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: map[model.ConfigKey]struct{}{reviews: {}, hostname: {}},
+		Reason:         []model.TriggerReason{model.ConfigUpdate},
+	}
+	if got := rolloutConfigs(req); len(got) != 2 {
+		t.Fatalf("expected the configs of a config update to be tracked, got %v", got)
+	}
+	req.Reason = append(req.Reason, model.EndpointUpdate)
+	want := map[model.ConfigKey]struct{}{reviews: {}}
+	if got := rolloutConfigs(req); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want the service entries of the endpoint update to be skipped", got)
+	}
+}
+
+func TestRolloutTrackerIndex(t *testing.T) {
+	defer func(window time.Duration) { features.RolloutStatusWindow = window }(features.RolloutStatusWindow)
+	features.RolloutStatusWindow = time.Minute
+
+	now := time.Now()
+	tracker := newRolloutTracker()
+	tracker.now = func() time.Time { return now }
+	reviews := model.ConfigKey{Kind: gvk.VirtualService, Name: "reviews", Namespace: "default"}
+	ratings := model.ConfigKey{Kind: gvk.VirtualService, Name: "ratings", Namespace: "default"}
+
+	// the configs changed by a push share its rollout.
+	tracker.recordPush("v1", map[model.ConfigKey]struct{}{reviews: {}, ratings: {}})
+	tracker.recordTargets("v1", []string{"sidecar-0", "sidecar-1"})
+	if len(tracker.active) != 1 || len(tracker.byConnection["sidecar-0"]) != 1 {
+		t.Fatalf("expected a single rollout for the push, got %d rollouts", len(tracker.active))
+	}
+
+	// the rollout is dropped once both configs changed again.
+	tracker.recordPush("v2", map[model.ConfigKey]struct{}{reviews: {}})
+	tracker.recordPush("v3", map[model.ConfigKey]struct{}{ratings: {}})
+	if len(tracker.active) != 2 || len(tracker.byConnection) != 0 {
+		t.Fatalf("expected the rollout of v1 to be dropped, got %d rollouts and connections %v",
+			len(tracker.active), tracker.byConnection)
+	}
+
+	tracker.recordTargets("v3", []string{"sidecar-0", "sidecar-1"})
+	tracker.onDisconnect("sidecar-1")
+	if _, f := tracker.byConnection["sidecar-1"]; f {
+		t.Fatalf("expected the disconnected connection to be untracked")
+	}
+	if st, _ := tracker.status(gvk.VirtualService.Kind, "ratings", "default"); st.Pending != 1 {
+		t.Fatalf("expected a pending connection, got %+v", st)
+	}
+
+	// rollouts expire after the window.
+	now = now.Add(2 * time.Minute)
+	tracker.recordPush("v4", nil)
+	if len(tracker.active) != 0 || len(tracker.rollouts) != 0 || len(tracker.byConnection) != 0 {
+		t.Fatalf("expected the rollouts to expire, got %d rollouts", len(tracker.active))
+	}
+}
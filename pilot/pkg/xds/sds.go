@@ -15,8 +15,10 @@
 package xds
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
@@ -36,18 +38,29 @@ const (
 )
 
 type SecretResource struct {
-	Type         string
+	Type string
+	// Name of the secret, without the GatewaySdsCaSuffix of root CA resources.
 	Name         string
 	Namespace    string
 	ResourceName string
+	// CaCert is set if the resource is the root CA of the secret, named with the GatewaySdsCaSuffix.
+	CaCert bool
+	// Cluster the secret is read from. Secrets with the same name may differ between clusters.
+	Cluster string
 }
 
+// Key includes the namespace, as resource names without a namespace are read from the namespace of the proxy.
 func (sr SecretResource) Key() string {
-	return "sds://" + sr.ResourceName
+	return "sds://" + sr.Namespace + "/" + sr.ResourceName + "@" + sr.Cluster
 }
 
 func (sr SecretResource) DependentConfigs() []model.ConfigKey {
-	return []model.ConfigKey{{Kind: gvk.Secret, Name: sr.Name, Namespace: sr.Namespace}}
+	configs := []model.ConfigKey{{Kind: gvk.Secret, Name: sr.Name, Namespace: sr.Namespace}}
+	if sr.CaCert {
+		// The root CA falls back to the legacy -cacert secret.
+		configs = append(configs, model.ConfigKey{Kind: gvk.Secret, Name: sr.Name + GatewaySdsCaSuffix, Namespace: sr.Namespace})
+	}
+	return configs
 }
 
 func (sr SecretResource) Cacheable() bool {
@@ -67,7 +80,14 @@ func parseResourceName(resource, defaultNamespace string) (SecretResource, error
 			namespace = split[0]
 			name = split[1]
 		}
-		return SecretResource{Type: authnmodel.KubernetesSecretType, Name: name, Namespace: namespace, ResourceName: resource}, nil
+		caCert := strings.HasSuffix(name, GatewaySdsCaSuffix)
+		return SecretResource{
+			Type:         authnmodel.KubernetesSecretType,
+			Name:         strings.TrimSuffix(name, GatewaySdsCaSuffix),
+			Namespace:    namespace,
+			ResourceName: resource,
+			CaCert:       caCert,
+		}, nil
 	}
 	return SecretResource{}, fmt.Errorf("unknown resource type: %v", resource)
 }
@@ -103,6 +123,15 @@ func (s *SecretGen) Generate(proxy *model.Proxy, _ *model.PushContext, w *model.
 	if !req.Full {
 		updatedSecrets = model.ConfigsOfKind(req.ConfigsUpdated, gvk.Secret)
 	}
+	cluster := s.configCluster
+	if proxy.Metadata != nil && proxy.Metadata.ClusterID != "" {
+		cluster = proxy.Metadata.ClusterID
+	}
+	sc, err := s.secrets.ForCluster(cluster)
+	if err != nil {
+		adsLog.Warnf("cannot fetch secrets for proxy %v: %v", proxy.ID, err)
+		return nil
+	}
 	results := model.Resources{}
 	for _, resource := range w.ResourceNames {
 		sr, err := parseResourceName(resource, proxy.ConfigNamespace)
@@ -110,12 +139,11 @@ func (s *SecretGen) Generate(proxy *model.Proxy, _ *model.PushContext, w *model.
 			adsLog.Warnf("error parsing resource name: %v", err)
 			continue
 		}
+		sr.Cluster = cluster
 
-		if updatedSecrets != nil {
-			if _, f := updatedSecrets[model.ConfigKey{Kind: gvk.Secret, Name: sr.Name, Namespace: sr.Namespace}]; !f {
-				// This is an incremental update, filter out secrets that are not updated.
-				continue
-			}
+		if updatedSecrets != nil && !secretUpdated(sr, updatedSecrets) {
+			// This is an incremental update, filter out secrets that are not updated.
+			continue
 		}
 
 		if !s.proxyAuthorizedForSecret(proxy, sr) {
@@ -128,30 +156,70 @@ func (s *SecretGen) Generate(proxy *model.Proxy, _ *model.PushContext, w *model.
 			continue
 		}
 
-		isCAOnlySecret := strings.HasSuffix(sr.Name, GatewaySdsCaSuffix)
-		if isCAOnlySecret {
-			secret := s.secrets.GetCaCert(sr.Name, sr.Namespace)
+		if sr.CaCert {
+			secret := sc.GetCaCert(sr.Name+GatewaySdsCaSuffix, sr.Namespace)
 			if secret != nil {
 				res := toEnvoyCaSecret(sr.ResourceName, secret)
 				results = append(results, res)
 				s.cache.Add(sr, res)
 			} else {
-				adsLog.Warnf("failed to fetch ca certificate for %v", sr.ResourceName)
+				s.fetchFailed(sc, sr, "ca certificate")
 			}
 		} else {
-			key, cert := s.secrets.GetKeyAndCert(sr.Name, sr.Namespace)
+			key, cert := sc.GetKeyAndCert(sr.Name, sr.Namespace)
 			if key != nil && cert != nil {
 				res := toEnvoyKeyCertSecret(sr.ResourceName, key, cert)
 				results = append(results, res)
 				s.cache.Add(sr, res)
 			} else {
-				adsLog.Warnf("failed to fetch key and certificate for %v", sr.ResourceName)
+				s.fetchFailed(sc, sr, "key and certificate")
 			}
 		}
 	}
+	// An incremental push only sends the updated secrets, so it is a rotation for the gateway, whether the secrets
+	// were generated for it or read from the cache.
+	if updatedSecrets != nil && len(results) > 0 {
+		sdsCertRotations.With(gatewayTag.Value(gatewayName(proxy))).Increment()
+	}
 	return results
 }
 
+// gatewayName returns the name of the gateway workload of the proxy, as <workload name>.<namespace>, or "unknown"
+// if the workload name is not reported, so that the gateway label is not set per pod.
+func gatewayName(proxy *model.Proxy) string {
+	if proxy.Metadata == nil || proxy.Metadata.WorkloadName == "" {
+		return "unknown"
+	}
+	return proxy.Metadata.WorkloadName + "." + proxy.ConfigNamespace
+}
+
+// secretUpdated returns whether one of the secrets the resource is read from is updated.
+func secretUpdated(sr SecretResource, updatedSecrets map[model.ConfigKey]struct{}) bool {
+	for _, key := range sr.DependentConfigs() {
+		if _, f := updatedSecrets[key]; f {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchFailed reports a secret that could not be fetched, calling out secrets outside the scope
+// of the secrets controller, which would otherwise be indistinguishable from missing ones.
+func (s *SecretGen) fetchFailed(sc secrets.Controller, sr SecretResource, what string) {
+	err := sc.SecretError(sr.Name, sr.Namespace)
+	var scopeErr *secrets.OutOfScopeError
+	switch {
+	case errors.As(err, &scopeErr):
+		sdsSecretsOutOfScope.Increment()
+		adsLog.Warnf("failed to fetch %s for %v in cluster %s, the secret is not watched by istiod: %v",
+			what, sr.ResourceName, sr.Cluster, err)
+	case err != nil:
+		adsLog.Warnf("failed to fetch %s for %v in cluster %s: %v", what, sr.ResourceName, sr.Cluster, err)
+	default:
+		adsLog.Warnf("failed to fetch %s for %v in cluster %s: the secret has no %s", what, sr.ResourceName, sr.Cluster, what)
+	}
+}
+
 func toEnvoyCaSecret(name string, cert []byte) *any.Any {
 	return util.MessageToAny(&tls.Secret{
 		Name: name,
@@ -188,18 +256,111 @@ func toEnvoyKeyCertSecret(name string, key, cert []byte) *any.Any {
 }
 
 type SecretGen struct {
-	secrets secrets.Controller
+	secrets secrets.MulticlusterController
 	// Cache for XDS resources
 	cache model.XdsCache
+	// configCluster is the cluster secrets are read from for proxies not reporting their cluster.
+	configCluster string
 }
 
 var _ model.XdsResourceGenerator = &SecretGen{}
 
-func NewSecretGen(sc secrets.Controller, cache model.XdsCache) *SecretGen {
+func NewSecretGen(sc secrets.MulticlusterController, cache model.XdsCache, configCluster string) *SecretGen {
 	// TODO: Currently we only have a single secrets controller (Kubernetes). In the future, we will need a mapping
 	// of resource type to secret controller (ie kubernetes:// -> KubernetesController, vault:// -> VaultController)
 	return &SecretGen{
-		secrets: sc,
-		cache:   cache,
+		secrets:       sc,
+		cache:         cache,
+		configCluster: configCluster,
+	}
+}
+
+// secretWatchers indexes the connections by the secrets their SDS resource names are read from, so that secret
+// changes are only pushed to the connections watching them.
+type secretWatchers struct {
+	mu sync.RWMutex
+	// bySecret holds the connections watching each secret, by connection ID.
+	bySecret map[model.ConfigKey]map[string]*Connection
+	// byConnection holds the secrets watched by each connection.
+	byConnection map[string][]model.ConfigKey
+}
+
+func newSecretWatchers() *secretWatchers {
+	return &secretWatchers{
+		bySecret:     map[model.ConfigKey]map[string]*Connection{},
+		byConnection: map[string][]model.ConfigKey{},
+	}
+}
+
+// update replaces the secrets watched by the connection with the ones of the SDS resource names.
+func (w *secretWatchers) update(con *Connection, resourceNames []string) {
+	var secrets []model.ConfigKey
+	for _, resource := range resourceNames {
+		sr, err := parseResourceName(resource, con.proxy.ConfigNamespace)
+		if err != nil {
+			continue
+		}
+		secrets = append(secrets, sr.DependentConfigs()...)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.removeLocked(con.ConID)
+	if len(secrets) == 0 {
+		return
+	}
+	w.byConnection[con.ConID] = secrets
+	for _, key := range secrets {
+		if w.bySecret[key] == nil {
+			w.bySecret[key] = map[string]*Connection{}
+		}
+		w.bySecret[key][con.ConID] = con
+	}
+}
+
+// remove forgets the secrets watched by the connection.
+func (w *secretWatchers) remove(conID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.removeLocked(conID)
+}
+
+func (w *secretWatchers) removeLocked(conID string) {
+	for _, key := range w.byConnection[conID] {
+		delete(w.bySecret[key], conID)
+		if len(w.bySecret[key]) == 0 {
+			delete(w.bySecret, key)
+		}
 	}
+	delete(w.byConnection, conID)
+}
+
+// watching returns the connections watching one of the secrets.
+func (w *secretWatchers) watching(secrets map[model.ConfigKey]struct{}) []*Connection {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	seen := map[string]struct{}{}
+	var out []*Connection
+	for key := range secrets {
+		for id, con := range w.bySecret[key] {
+			if _, f := seen[id]; f {
+				continue
+			}
+			seen[id] = struct{}{}
+			out = append(out, con)
+		}
+	}
+	return out
+}
+
+// onlySecretsUpdated returns whether the push is an incremental push of secret changes only.
+func onlySecretsUpdated(req *model.PushRequest) bool {
+	if req.Full || len(req.ConfigsUpdated) == 0 {
+		return false
+	}
+	for key := range req.ConfigsUpdated {
+		if key.Kind != gvk.Secret {
+			return false
+		}
+	}
+	return true
 }
@@ -15,9 +15,17 @@
 package xds
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/stats/view"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -28,6 +36,7 @@ import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/config/schema/gvk"
+	kubelib "istio.io/istio/pkg/kube"
 )
 
 func TestParseResourceName(t *testing.T) {
@@ -60,6 +69,18 @@ func TestParseResourceName(t *testing.T) {
 				ResourceName: "kubernetes://namespace/cert",
 			},
 		},
+		{
+			name:             "root cert",
+			resource:         "kubernetes://namespace/cert-cacert",
+			defaultNamespace: "default",
+			expected: SecretResource{
+				Type:         authnmodel.KubernetesSecretType,
+				Name:         "cert",
+				Namespace:    "namespace",
+				ResourceName: "kubernetes://namespace/cert-cacert",
+				CaCert:       true,
+			},
+		},
 		{
 			name:             "plain",
 			resource:         "cert",
@@ -249,3 +270,349 @@ func TestGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateOutOfScope(t *testing.T) {
+	labeled := makeSecret("labeled", map[string]string{
+		kubesecrets.GenericScrtCert: "labeled-cert", kubesecrets.GenericScrtKey: "labeled-key",
+	})
+	labeled.Labels = map[string]string{"istio.io/credential": "true"}
+	client := kubelib.NewFakeClient(genericCert, labeled)
+	sc, err := kubesecrets.NewScopedSecretsController(client.Kube(), kubesecrets.Scope{
+		Namespaces:    []string{"istio-system"},
+		LabelSelector: "istio.io/credential=true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	sc.Run(stop)
+	gen := NewSecretGen(kubesecrets.NewMulticluster("Kubernetes", sc, kubesecrets.Scope{}), &model.DisabledCache{}, "Kubernetes")
+
+	outOfScope := func() float64 {
+		data, err := view.RetrieveData("pilot_sds_secrets_out_of_scope")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) == 0 {
+			return 0
+		}
+		return data[0].Data.(*view.SumData).Value
+	}
+	before := outOfScope()
+
+	raw := xdstest.ExtractTLSSecrets(t, gen.Generate(
+		&model.Proxy{Type: model.Router, ConfigNamespace: "istio-system"}, nil,
+		&model.WatchedResource{ResourceNames: []string{"kubernetes://labeled", "kubernetes://generic", "kubernetes://missing"}},
+		&model.PushRequest{Full: true}))
+	if len(raw) != 1 || raw["kubernetes://labeled"] == nil {
+		t.Fatalf("expected only the labeled secret to be generated, got %v", raw)
+	}
+	// The missing secret is not reported as out of scope.
+	if got := outOfScope() - before; got != 1 {
+		t.Fatalf("expected 1 out of scope secret to be reported, got %v", got)
+	}
+}
+
+func TestGenerateMulticluster(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	configClient := kubelib.NewFakeClient(genericCert)
+	sc := kubesecrets.NewSecretsController(configClient.KubeInformer().Core().V1().Secrets())
+	configClient.RunAndWait(stop)
+	msc := kubesecrets.NewMulticluster("Kubernetes", sc, kubesecrets.Scope{})
+
+	remoteCert := makeSecret("generic", map[string]string{
+		kubesecrets.GenericScrtCert: "remote-cert", kubesecrets.GenericScrtKey: "remote-key",
+	})
+	remoteClient := kubelib.NewFakeClient(remoteCert)
+	if err := msc.ClusterAdded(remoteClient, "remote", stop); err != nil {
+		t.Fatal(err)
+	}
+	remoteClient.RunAndWait(stop)
+
+	// Use a real cache, so that same-named secrets of different clusters must not collide.
+	gen := NewSecretGen(msc, model.NewXdsCache(), "Kubernetes")
+	cases := []struct {
+		name    string
+		cluster string
+		cert    string
+	}{
+		{"unset cluster", "", "generic-cert"},
+		{"config cluster", "Kubernetes", "generic-cert"},
+		{"remote cluster", "remote", "remote-cert"},
+		{"unknown cluster", "unknown", ""},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &model.Proxy{
+				Type:            model.Router,
+				ConfigNamespace: "istio-system",
+				Metadata:        &model.NodeMetadata{ClusterID: tt.cluster},
+			}
+			raw := xdstest.ExtractTLSSecrets(t, gen.Generate(proxy, nil,
+				&model.WatchedResource{ResourceNames: []string{"kubernetes://generic"}}, &model.PushRequest{Full: true}))
+			got := string(raw["kubernetes://generic"].GetTlsCertificate().GetCertificateChain().GetInlineBytes())
+			if got != tt.cert {
+				t.Fatalf("got cert %q, want %q", got, tt.cert)
+			}
+		})
+	}
+
+	if err := msc.ClusterDeleted("remote"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := msc.ForCluster("remote"); err == nil {
+		t.Fatal("expected the removed cluster to be unknown")
+	}
+}
+
+func TestSecretPushScope(t *testing.T) {
+	s := &DiscoveryServer{pushQueue: NewPushQueue(), adsClients: map[string]*Connection{}, secretWatchers: newSecretWatchers()}
+	defer s.pushQueue.ShutDown()
+	connect := func(id string, proxyType model.NodeType, credentialNames ...string) *Connection {
+		con := &Connection{ConID: id, proxy: &model.Proxy{
+			Type:             proxyType,
+			ConfigNamespace:  "istio-system",
+			Metadata:         &model.NodeMetadata{},
+			WatchedResources: map[string]*model.WatchedResource{},
+		}}
+		s.addCon(id, con)
+		if len(credentialNames) > 0 {
+			con.proxy.WatchedResources[v3.SecretType] = &model.WatchedResource{TypeUrl: v3.SecretType, ResourceNames: credentialNames}
+			s.secretWatchers.update(con, credentialNames)
+		}
+		return con
+	}
+	connect("gateway-a", model.Router, "kubernetes://a")
+	connect("gateway-b", model.Router, "kubernetes://b", "kubernetes://b-cacert")
+	connect("gateway-c", model.Router, "kubernetes://c-cacert")
+	connect("sidecar", model.SidecarProxy)
+
+	secretUpdate := func(names ...string) *model.PushRequest {
+		req := &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{}, Reason: []model.TriggerReason{model.SecretTrigger}}
+		for _, name := range names {
+			req.ConfigsUpdated[model.ConfigKey{Kind: gvk.Secret, Name: name, Namespace: "istio-system"}] = struct{}{}
+		}
+		return req
+	}
+	pushed := func() []string {
+		var ids []string
+		for s.pushQueue.Pending() > 0 {
+			con, _, _ := s.pushQueue.Dequeue()
+			s.pushQueue.MarkDone(con)
+			ids = append(ids, con.ConID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	cases := []struct {
+		name string
+		req  *model.PushRequest
+		want []string
+	}{
+		{"single secret", secretUpdate("a"), []string{"gateway-a"}},
+		{"secret and its ca", secretUpdate("b"), []string{"gateway-b"}},
+		{"ca only resource", secretUpdate("c"), []string{"gateway-c"}},
+		{"legacy ca secret", secretUpdate("c-cacert"), []string{"gateway-c"}},
+		{"several secrets", secretUpdate("a", "c"), []string{"gateway-a", "gateway-c"}},
+		{"unwatched secret", secretUpdate("d"), nil},
+		{"other namespace", &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{
+			{Kind: gvk.Secret, Name: "a", Namespace: "default"}: {},
+		}}, nil},
+		{"not only secrets", &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{
+			{Kind: gvk.Secret, Name: "a", Namespace: "istio-system"}:    {},
+			{Kind: gvk.ServiceEntry, Name: "svc", Namespace: "default"}: {},
+		}}, []string{"gateway-a", "gateway-b", "gateway-c", "sidecar"}},
+		{"full push", &model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{
+			{Kind: gvk.Secret, Name: "a", Namespace: "istio-system"}: {},
+		}}, []string{"gateway-a", "gateway-b", "gateway-c", "sidecar"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			s.startPush(tt.req)
+			if got := pushed(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got pushes to %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("updated resource names", func(t *testing.T) {
+		s.secretWatchers.update(s.adsClients["gateway-a"], []string{"kubernetes://d"})
+		s.startPush(secretUpdate("a", "d"))
+		if got, want := pushed(), []string{"gateway-a"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got pushes to %v, want %v", got, want)
+		}
+		s.startPush(secretUpdate("a"))
+		if got := pushed(); len(got) != 0 {
+			t.Fatalf("got pushes to %v for a secret no longer watched", got)
+		}
+	})
+
+	t.Run("disconnected", func(t *testing.T) {
+		s.removeCon("gateway-b")
+		s.startPush(secretUpdate("b"))
+		if got := pushed(); len(got) != 0 {
+			t.Fatalf("got pushes to %v after disconnecting", got)
+		}
+	})
+}
+
+// gatewayCertRotations returns the number of rotations reported for the gateway.
+func gatewayCertRotations(t *testing.T, gateway string) float64 {
+	t.Helper()
+	data, err := view.RetrieveData("pilot_sds_gateway_cert_rotations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range data {
+		for _, tag := range row.Tags {
+			if tag.Key.Name() == "gateway" && tag.Value == gateway {
+				return row.Data.(*view.SumData).Value
+			}
+		}
+	}
+	return 0
+}
+
+// TestGatewaySecretRotationCached checks that the rotation is reported for each gateway watching the secret, including
+// those the secret is read from the cache for.
+func TestGatewaySecretRotationCached(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	client := kubelib.NewFakeClient(genericCert)
+	sc := kubesecrets.NewSecretsController(client.KubeInformer().Core().V1().Secrets())
+	client.RunAndWait(stop)
+	cache := model.NewXdsCache()
+	gen := NewSecretGen(kubesecrets.NewMulticluster("Kubernetes", sc, kubesecrets.Scope{}), cache, "Kubernetes")
+
+	gateway := func(name string) *model.Proxy {
+		return &model.Proxy{Type: model.Router, ConfigNamespace: "istio-system", Metadata: &model.NodeMetadata{WorkloadName: name}}
+	}
+	a, b := gateway("cached-a"), gateway("cached-b")
+	w := &model.WatchedResource{ResourceNames: []string{"kubernetes://generic"}}
+	updated := map[model.ConfigKey]struct{}{{Kind: gvk.Secret, Name: "generic", Namespace: "istio-system"}: {}}
+	beforeA, beforeB := gatewayCertRotations(t, "cached-a.istio-system"), gatewayCertRotations(t, "cached-b.istio-system")
+
+	// The update clears the cache, the secret is generated for the first gateway and read from the cache for the other.
+	cache.Clear(updated)
+	for _, proxy := range []*model.Proxy{a, b} {
+		if got := gen.Generate(proxy, nil, w, &model.PushRequest{ConfigsUpdated: updated}); len(got) != 1 {
+			t.Fatalf("expected the updated secret to be pushed to %s, got %v", proxy.Metadata.WorkloadName, got)
+		}
+	}
+	if _, f := cache.Get(SecretResource{Type: authnmodel.KubernetesSecretType, Name: "generic", Namespace: "istio-system",
+		ResourceName: "kubernetes://generic", Cluster: "Kubernetes"}); !f {
+		t.Fatal("expected the secret to be cached")
+	}
+	if got := gatewayCertRotations(t, "cached-a.istio-system") - beforeA; got != 1 {
+		t.Errorf("expected 1 rotation of cached-a to be reported, got %v", got)
+	}
+	if got := gatewayCertRotations(t, "cached-b.istio-system") - beforeB; got != 1 {
+		t.Errorf("expected 1 rotation of cached-b to be reported, got %v", got)
+	}
+
+	// A full push is not a rotation.
+	gen.Generate(a, nil, w, &model.PushRequest{Full: true})
+	if got := gatewayCertRotations(t, "cached-a.istio-system") - beforeA; got != 1 {
+		t.Errorf("expected no rotation to be reported for a full push, got %v", got)
+	}
+}
+
+func TestGatewaySecretRotation(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{KubernetesObjects: []runtime.Object{genericCert, genericMtlsCert}})
+
+	type gatewayStream struct {
+		stream    discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+		node      *core.Node
+		responses chan *discovery.DiscoveryResponse
+	}
+	connect := func(name, credentialName string) *gatewayStream {
+		g := &gatewayStream{
+			stream: s.ConnectADS(),
+			node: &core.Node{
+				Id:       fmt.Sprintf("router~10.0.0.1~%s-pod.istio-system~istio-system.svc.cluster.local", name),
+				Metadata: model.NodeMetadata{WorkloadName: name}.ToStruct(),
+			},
+			responses: make(chan *discovery.DiscoveryResponse, 10),
+		}
+		go func() {
+			for {
+				resp, err := g.stream.Recv()
+				if err != nil {
+					return
+				}
+				g.responses <- resp
+			}
+		}()
+		if err := g.stream.Send(&discovery.DiscoveryRequest{
+			Node:          g.node,
+			TypeUrl:       v3.SecretType,
+			ResourceNames: []string{credentialName},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	receive := func(g *gatewayStream, timeout time.Duration) *discovery.DiscoveryResponse {
+		select {
+		case resp := <-g.responses:
+			// ACK the response, as the gateway would.
+			if err := g.stream.Send(&discovery.DiscoveryRequest{
+				Node:          g.node,
+				TypeUrl:       resp.TypeUrl,
+				VersionInfo:   resp.VersionInfo,
+				ResponseNonce: resp.Nonce,
+				ResourceNames: []string{},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			return resp
+		case <-time.After(timeout):
+			return nil
+		}
+	}
+	certOf := func(resp *discovery.DiscoveryResponse, resource string) string {
+		return string(xdstest.ExtractTLSSecrets(t, resp.Resources)[resource].GetTlsCertificate().
+			GetCertificateChain().GetInlineBytes())
+	}
+
+	a := connect("gateway-a", "kubernetes://generic")
+	b := connect("gateway-b", "kubernetes://generic-mtls")
+	if resp := receive(a, 5*time.Second); resp == nil || certOf(resp, "kubernetes://generic") != "generic-cert" {
+		t.Fatalf("unexpected initial response to gateway-a: %v", resp)
+	}
+	if resp := receive(b, 5*time.Second); resp == nil || certOf(resp, "kubernetes://generic-mtls") != "generic-mtls-cert" {
+		t.Fatalf("unexpected initial response to gateway-b: %v", resp)
+	}
+	// Drain the pushes of the events of the initial secrets, if they raced with the connections.
+	for _, g := range []*gatewayStream{a, b} {
+		for {
+			if receive(g, 100*time.Millisecond) == nil {
+				break
+			}
+		}
+	}
+	before := gatewayCertRotations(t, "gateway-a.istio-system")
+
+	rotated := makeSecret("generic", map[string]string{
+		kubesecrets.GenericScrtCert: "rotated-cert", kubesecrets.GenericScrtKey: "rotated-key",
+	})
+	if _, err := s.SecretsClient.Kube().CoreV1().Secrets("istio-system").Update(context.TODO(), rotated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	resp := receive(a, 5*time.Second)
+	if resp == nil {
+		t.Fatal("expected the rotated secret to be pushed to gateway-a")
+	}
+	if got := certOf(resp, "kubernetes://generic"); got != "rotated-cert" {
+		t.Fatalf("got cert %q, want rotated-cert", got)
+	}
+	if resp := receive(b, 500*time.Millisecond); resp != nil {
+		t.Fatalf("unexpected push to gateway-b, which does not watch the rotated secret: %v", resp)
+	}
+	if got := gatewayCertRotations(t, "gateway-a.istio-system") - before; got != 1 {
+		t.Fatalf("expected 1 rotation of gateway-a to be reported, got %v", got)
+	}
+}
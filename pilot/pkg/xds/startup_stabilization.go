@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"time"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// criticalPushKinds are the kinds of the configs whose changes are pushed to the proxies in their startup
+// stabilization window, as they are security relevant.
+var criticalPushKinds = map[config.GroupVersionKind]struct{}{
+	gvk.AuthorizationPolicy:   {},
+	gvk.PeerAuthentication:    {},
+	gvk.RequestAuthentication: {},
+	gvk.Secret:                {},
+}
+
+// DeferredPush describes the full pushes deferred during the startup stabilization window of a connection.
+type DeferredPush struct {
+	// Pushes is the number of pushes deferred.
+	Pushes int `json:"pushes"`
+	// Reasons are the reasons of the pushes deferred.
+	Reasons []model.TriggerReason `json:"reasons"`
+	// Until is the end of the stabilization window, when the pushes are sent as a single push.
+	Until time.Time `json:"until"`
+}
+
+// isCriticalPush returns true if the push is not deferred during the startup stabilization window: incremental
+// pushes, pushes triggered by a change of the proxy itself, pushes of security relevant changes, and global pushes,
+// whose changes are not known and may be security relevant.
+func isCriticalPush(req *model.PushRequest) bool {
+	if !req.Full || len(req.ConfigsUpdated) == 0 {
+		return true
+	}
+	for _, r := range req.Reason {
+		if r == model.ProxyUpdate || r == model.SecretTrigger {
+			return true
+		}
+	}
+	for key := range req.ConfigsUpdated {
+		if _, f := criticalPushKinds[key.Kind]; f {
+			return true
+		}
+	}
+	return false
+}
+
+// stabilizationWindowEnd returns the end of the startup stabilization window of the connection.
+func (conn *Connection) stabilizationWindowEnd() time.Time {
+	return conn.Connect.Add(features.XDSStartupStabilizationWindow)
+}
+
+// deferPush defers the push if the connection is in its startup stabilization window and the push is not
+// critical. The pushes deferred are merged, and enqueued as a single push at the end of the window.
+func (s *DiscoveryServer) deferPush(con *Connection, req *model.PushRequest) bool {
+	if features.XDSStartupStabilizationWindow <= 0 || isCriticalPush(req) {
+		return false
+	}
+	remaining := time.Until(con.stabilizationWindowEnd())
+	if remaining <= 0 {
+		return false
+	}
+
+	con.deferredPushMutex.Lock()
+	defer con.deferredPushMutex.Unlock()
+	if con.deferredPush == nil {
+		time.AfterFunc(remaining, func() {
+			s.releaseDeferredPush(con)
+		})
+	}
+	con.deferredPush = con.deferredPush.Merge(req)
+	con.deferredPushes++
+	return true
+}
+
+// takeDeferredPush returns the pushes deferred, merged, and clears them.
+func (conn *Connection) takeDeferredPush() *model.PushRequest {
+	conn.deferredPushMutex.Lock()
+	defer conn.deferredPushMutex.Unlock()
+	req := conn.deferredPush
+	conn.deferredPush = nil
+	conn.deferredPushes = 0
+	return req
+}
+
+// DeferredPush returns the pushes deferred during the startup stabilization window of the connection, or nil
+// if there are none.
+func (conn *Connection) DeferredPush() *DeferredPush {
+	conn.deferredPushMutex.Lock()
+	defer conn.deferredPushMutex.Unlock()
+	if conn.deferredPush == nil {
+		return nil
+	}
+	return &DeferredPush{
+		Pushes:  conn.deferredPushes,
+		Reasons: append([]model.TriggerReason{}, conn.deferredPush.Reason...),
+		Until:   conn.stabilizationWindowEnd(),
+	}
+}
+
+// releaseDeferredPush enqueues the pushes deferred during the startup stabilization window of the connection,
+// unless they were already sent along with another push, or the connection is closed.
+func (s *DiscoveryServer) releaseDeferredPush(con *Connection) {
+	req := con.takeDeferredPush()
+	if req == nil {
+		return
+	}
+	s.adsClientsMutex.RLock()
+	connected := s.adsClients[con.ConID] == con
+	s.adsClientsMutex.RUnlock()
+	if !connected {
+		return
+	}
+	// The request may be shared with the other connections it was enqueued for.
+	released := *req
+	released.Push = s.globalPushContext()
+	adsLog.Debugf("Sending the pushes deferred during the startup stabilization window of %v", con.ConID)
+	s.pushQueue.Enqueue(con, &released)
+}
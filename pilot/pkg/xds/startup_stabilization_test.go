@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestIsCriticalPush(t *testing.T) {
+	cases := []struct {
+		name string
+		req  *model.PushRequest
+		want bool
+	}{
+		{"incremental", &model.PushRequest{Reason: []model.TriggerReason{model.EndpointUpdate}}, true},
+		{"config", &model.PushRequest{
+			Full:           true,
+			Reason:         []model.TriggerReason{model.ConfigUpdate},
+			ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.VirtualService, Name: "vs"}: {}},
+		}, false},
+		{"global", &model.PushRequest{Full: true, Reason: []model.TriggerReason{model.GlobalUpdate}}, true},
+		{"proxy update", &model.PushRequest{Full: true, Reason: []model.TriggerReason{model.ConfigUpdate, model.ProxyUpdate}}, true},
+		{"authorization policy", &model.PushRequest{
+			Full:   true,
+			Reason: []model.TriggerReason{model.ConfigUpdate},
+			ConfigsUpdated: map[model.ConfigKey]struct{}{
+				{Kind: gvk.VirtualService, Name: "vs"}:        {},
+				{Kind: gvk.AuthorizationPolicy, Name: "deny"}: {},
+			},
+		}, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCriticalPush(tt.req); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartupStabilizationWindow(t *testing.T) {
+	old := features.XDSStartupStabilizationWindow
+	features.XDSStartupStabilizationWindow = 2 * time.Second
+	defer func() { features.XDSStartupStabilizationWindow = old }()
+
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	watch := []string{v3.ClusterType}
+	ads := s.Connect(nil, watch, watch)
+
+	for _, name := range []string{"a", "b"} {
+		s.Discovery.AdsPushAll(versionInfo(), &model.PushRequest{
+			Full:           true,
+			Push:           s.PushContext(),
+			Reason:         []model.TriggerReason{model.ConfigUpdate},
+			ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.VirtualService, Name: name, Namespace: "default"}: {}},
+		})
+	}
+
+	// An incremental push is sent, without the deferred pushes.
+	s.Discovery.AdsPushAll(versionInfo(), &model.PushRequest{
+		Push:           s.PushContext(),
+		Reason:         []model.TriggerReason{model.EndpointUpdate},
+		ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.ServiceEntry, Name: "a.com", Namespace: "default"}: {}},
+	})
+
+	var deferred *DeferredPush
+	retry.UntilSuccessOrFail(t, func() error {
+		rr := httptest.NewRecorder()
+		s.Discovery.adsz(rr, httptest.NewRequest("GET", "/debug/adsz", nil))
+		clients := AdsClients{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &clients); err != nil {
+			return err
+		}
+		if len(clients.Connected) != 1 || clients.Connected[0].DeferredPush == nil ||
+			clients.Connected[0].DeferredPush.Pushes != 2 {
+			return fmt.Errorf("expected 2 deferred pushes, got %+v", clients.Connected)
+		}
+		deferred = clients.Connected[0].DeferredPush
+		return nil
+	}, retry.Timeout(time.Second))
+
+	if _, err := ads.Wait(5*time.Second, v3.ClusterType); err != nil {
+		t.Fatalf("expected the deferred pushes to be sent: %v", err)
+	}
+	if now := time.Now(); now.Before(deferred.Until) {
+		t.Fatalf("the deferred pushes were sent at %v, before the end of the window %v", now, deferred.Until)
+	}
+	if upd, err := ads.Wait(time.Second, v3.ClusterType); err == nil {
+		t.Fatalf("expected the deferred pushes to be sent as a single push, got another push %v", upd)
+	}
+}
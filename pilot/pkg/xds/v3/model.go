@@ -25,6 +25,12 @@ const (
 	RouteType     = resource.RouteType
 	SecretType    = resource.SecretType
 	NameTableType = "type.googleapis.com/istio.networking.nds.v1.NameTable"
+	// HealthInfoType is the type of the requests of the agents reporting the health of their workload. The workload
+	// is healthy if the request has no error detail. Istiod does not respond to them.
+	HealthInfoType = "type.googleapis.com/istio.v1.HealthInformation"
+	// ProxyConfigType is the type of the effective proxy config of a proxy, requested by its agent which applies
+	// the fields that can change without restarting the proxy.
+	ProxyConfigType = "type.googleapis.com/istio.mesh.v1alpha1.ProxyConfig"
 )
 
 // GetShortType returns an abbreviated form of a type, useful for logging or human friendly messages
@@ -0,0 +1,51 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"strings"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// configVersionInfo returns the version info of the resources generated for push. This is the config version of the
+// push context, the root hash of the config ledger, so that all istiod instances with the same config send the same
+// version and an ACK can be correlated on any of them, e.g. after the proxy reconnected to another instance.
+// Without a config version, the version of the last push local to this instance is used.
+func configVersionInfo(push *model.PushContext) string {
+	if push != nil && push.Version != "" {
+		return push.Version
+	}
+	return versionInfo()
+}
+
+// isConfigVersion returns true for version info sent by configVersionInfo from a config version, rather than the
+// legacy "<time>/<counter>" versions local to an istiod instance.
+func isConfigVersion(versionInfo string) bool {
+	return len(versionInfo) == VersionLen && !strings.Contains(versionInfo, "/")
+}
+
+// AckedConfigVersion returns the config version acknowledged by a request with the given version info and response
+// nonce. Proxies which last received config from an older istiod instance hold a legacy version info, during
+// upgrades, in which case the config version is read from the nonce, which is prefixed with it.
+func AckedConfigVersion(versionInfo, nonce string) string {
+	if isConfigVersion(versionInfo) {
+		return versionInfo
+	}
+	if len(nonce) > VersionLen {
+		return nonce[:VersionLen]
+	}
+	return nonce
+}
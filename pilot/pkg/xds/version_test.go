@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestAckedConfigVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		versionInfo string
+		nonce       string
+		want        string
+	}{
+		{
+			name:        "config version",
+			versionInfo: "AAAAAAAAAAAA",
+			nonce:       "AAAAAAAAAAAAb4e1d3e2-56c6-4d1c-9f10-0bf5b6d1c5f1",
+			want:        "AAAAAAAAAAAA",
+		},
+		{
+			name:        "config version of another instance",
+			versionInfo: "BBBBBBBBBBBB",
+			nonce:       "AAAAAAAAAAAAb4e1d3e2-56c6-4d1c-9f10-0bf5b6d1c5f1",
+			want:        "BBBBBBBBBBBB",
+		},
+		{
+			name:        "legacy version",
+			versionInfo: "2020-10-01T10:00:00Z/12",
+			nonce:       "AAAAAAAAAAAAb4e1d3e2-56c6-4d1c-9f10-0bf5b6d1c5f1",
+			want:        "AAAAAAAAAAAA",
+		},
+		{
+			name:        "legacy version of the same length",
+			versionInfo: "2020-10-01/1",
+			nonce:       "AAAAAAAAAAAAb4e1d3e2-56c6-4d1c-9f10-0bf5b6d1c5f1",
+			want:        "AAAAAAAAAAAA",
+		},
+		{
+			name:  "first request",
+			nonce: "",
+			want:  "",
+		},
+		{
+			name:  "short nonce",
+			nonce: "nonce",
+			want:  "nonce",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := xds.AckedConfigVersion(tt.versionInfo, tt.nonce); got != tt.want {
+				t.Fatalf("AckedConfigVersion(%q, %q) = %q, want %q", tt.versionInfo, tt.nonce, got, tt.want)
+			}
+		})
+	}
+}
+
+type recordingStatusCache struct {
+	mu     sync.Mutex
+	events map[string][]string
+}
+
+func (r *recordingStatusCache) RegisterEvent(conID string, eventType xds.EventType, nonce string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[eventType] = append(r.events[eventType], nonce)
+}
+
+func (r *recordingStatusCache) RegisterDisconnect(string, []xds.EventType) {}
+
+func (r *recordingStatusCache) QueryLastNonce(string, xds.EventType) string { return "" }
+
+func (r *recordingStatusCache) eventsFor(eventType xds.EventType) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events[eventType]...)
+}
+
+// TestConfigVersionAcrossInstances simulates a proxy reconnecting to another istiod instance with the same config,
+// and checks both instances send the same version info and the ACK of the first one is correlated by the second.
+func TestConfigVersionAcrossInstances(t *testing.T) {
+	first := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	second := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	if first.PushContext().Version != second.PushContext().Version {
+		t.Fatalf("instances with the same config have different config versions: %q, %q",
+			first.PushContext().Version, second.PushContext().Version)
+	}
+	reporter := &recordingStatusCache{events: map[string][]string{}}
+	second.Discovery.StatusReporter = reporter
+
+	node := &core.Node{Id: sidecarID(app3Ip, "app3"), Metadata: nodeMetadata}
+	ads := first.ConnectADS()
+	if err := ads.Send(&discovery.DiscoveryRequest{Node: node, TypeUrl: v3.ClusterType}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ads.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.VersionInfo != first.PushContext().Version {
+		t.Fatalf("expected version info %q, got %q", first.PushContext().Version, res.VersionInfo)
+	}
+
+	// The proxy reconnects to the second instance, acking the config received from the first one.
+	ads = second.ConnectADS()
+	if err := ads.Send(&discovery.DiscoveryRequest{
+		Node:          node,
+		TypeUrl:       v3.ClusterType,
+		VersionInfo:   res.VersionInfo,
+		ResponseNonce: res.Nonce,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reconnected, err := ads.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reconnected.VersionInfo != res.VersionInfo {
+		t.Fatalf("expected the same version info from both instances, got %q and %q", res.VersionInfo, reconnected.VersionInfo)
+	}
+	if reconnected.Nonce == res.Nonce {
+		t.Fatalf("expected unique nonces, got %q from both instances", res.Nonce)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		events := reporter.eventsFor(v3.ClusterType)
+		if len(events) == 0 || events[0] != res.VersionInfo {
+			return fmt.Errorf("expected the ack of version %q to be registered, got %v", res.VersionInfo, events)
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+}
@@ -15,13 +15,25 @@
 package xds
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/gogo/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/any"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/gvk"
 )
 
@@ -125,4 +137,244 @@ func TestInMemoryCache(t *testing.T) {
 			t.Fatalf("unexpected result, found key when not expected: %v", c.Keys())
 		}
 	})
+	for _, size := range []int{0, 10} {
+		size := size
+		t.Run(fmt.Sprintf("snapshot with a cache size of %d", size), func(t *testing.T) {
+			old := features.XDSCacheMaxSize
+			features.XDSCacheMaxSize = size
+			defer func() { features.XDSCacheMaxSize = old }()
+			c := model.NewXdsCache()
+			c.Add(ep1, any1)
+			c.Add(ep2, any2)
+
+			got := c.Snapshot(1)
+			if len(got) != 1 {
+				t.Fatalf("expected 1 entry, got: %v", got)
+			}
+			// The most recently used entry is returned first.
+			want := model.XdsCacheSnapshotEntry{
+				Key:              ep2.Key(),
+				DependentConfigs: []model.ConfigKey{{Kind: gvk.ServiceEntry, Name: "foo.com"}},
+				Value:            any2,
+			}
+			if !reflect.DeepEqual(got[0], want) {
+				t.Fatalf("unexpected snapshot: %+v, want %+v", got[0], want)
+			}
+			c.Get(ep1)
+			got = c.Snapshot(10)
+			if len(got) != 2 || got[0].Key != ep1.Key() || got[1].Key != ep2.Key() {
+				t.Fatalf("expected the entry read last first, got: %v", got)
+			}
+		})
+	}
+}
+
+const warmStartConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hosts:
+  - foo.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: foo
+  namespace: default
+spec:
+  host: foo.example.com
+`
+
+func TestXdsCacheWarmStart(t *testing.T) {
+	networks := mesh.NewFixedNetworksWatcher(nil)
+	s := NewFakeDiscoveryServer(t, FakeOptions{NetworksWatcher: networks, ConfigString: warmStartConfig})
+	proxy := s.SetupProxy(&model.Proxy{})
+	ep := NewEndpointBuilder("outbound|80||foo.example.com", proxy, s.PushContext())
+	if len(ep.DependentConfigs()) != 2 {
+		t.Fatalf("expected the entry to depend on the service and destination rule, got %v", ep.DependentConfigs())
+	}
+	subset := NewEndpointBuilder("outbound|80|v1|foo.example.com", proxy, s.PushContext())
+	sds := SecretResource{Name: "foo", Namespace: "default", ResourceName: "kubernetes://foo"}
+	c := s.Discovery.Cache
+	c.ClearAll()
+	c.Add(ep, any1)
+	c.Add(subset, any2)
+	c.Add(sds, any2)
+	// The entry read last is the most recently used.
+	c.Get(ep)
+
+	data, err := s.Discovery.marshalXdsCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.ClearAll()
+	loaded, err := s.Discovery.unmarshalXdsCache(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Secrets are not versioned by the config store, so they are not persisted.
+	if loaded != 2 {
+		t.Fatalf("expected 2 entries to be loaded, got %d: %v", loaded, c.Keys())
+	}
+	// The entries keep their order of use.
+	if got := c.Snapshot(10); len(got) != 2 || got[0].Key != ep.Key() || got[1].Key != subset.Key() {
+		t.Fatalf("expected the most recently used entry first, got %v", got)
+	}
+	if got, f := c.Get(ep); !f || got.TypeUrl != any1.TypeUrl {
+		t.Fatalf("unexpected result: %v, want %v", got, any1)
+	}
+
+	// Changing a dependent config makes the persisted entry stale.
+	dr := s.Store().Get(gvk.DestinationRule, "foo", "default")
+	if _, err := s.Store().Update(*dr); err != nil {
+		t.Fatal(err)
+	}
+	c.ClearAll()
+	loaded, err = s.Discovery.unmarshalXdsCache(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != 0 {
+		t.Fatalf("expected stale entries to be discarded, got %v", c.Keys())
+	}
+
+	// Changing the mesh networks makes the persisted entries stale, as the endpoints depend on them.
+	c.Add(NewEndpointBuilder("outbound|80||foo.example.com", proxy, s.PushContext()), any1)
+	data, err = s.Discovery.marshalXdsCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot := (xdsCacheSnapshot{}); json.Unmarshal(data, &snapshot) != nil || len(snapshot.Entries) != 1 {
+		t.Fatalf("expected 1 persisted entry, got %s", data)
+	}
+	networks.(interface {
+		SetNetworks(*meshconfig.MeshNetworks)
+	}).SetNetworks(&meshconfig.MeshNetworks{Networks: map[string]*meshconfig.Network{"network1": {
+		Endpoints: []*meshconfig.Network_NetworkEndpoints{{Ne: &meshconfig.Network_NetworkEndpoints_FromRegistry{FromRegistry: "Kubernetes"}}},
+		Gateways: []*meshconfig.Network_IstioNetworkGateway{{
+			Gw:   &meshconfig.Network_IstioNetworkGateway_Address{Address: "2.2.2.2"},
+			Port: 15443,
+		}},
+	}}})
+	c.ClearAll()
+	loaded, err = s.Discovery.unmarshalXdsCache(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != 0 {
+		t.Fatalf("expected the entries built with other mesh networks to be discarded, got %v", c.Keys())
+	}
+
+	if _, err := s.Discovery.unmarshalXdsCache([]byte(`{"format":0}`)); err == nil {
+		t.Fatalf("expected snapshots of another format to be rejected")
+	}
+}
+
+func TestXdsCacheWarmStartBeforeReady(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: warmStartConfig})
+	proxy := s.SetupProxy(&model.Proxy{})
+	ep := NewEndpointBuilder("outbound|80||foo.example.com", proxy, s.PushContext())
+	// The persisted entry has no endpoints, unlike the one the server would generate.
+	persisted := util.MessageToAny(&endpoint.ClusterLoadAssignment{ClusterName: "outbound|80||foo.example.com"})
+	c := s.Discovery.Cache
+	c.ClearAll()
+	c.Add(ep, persisted)
+	data, err := s.Discovery.marshalXdsCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	oldEnabled, oldPath := features.EnableXDSCacheWarmStart, features.XDSCacheWarmStartPath
+	features.EnableXDSCacheWarmStart, features.XDSCacheWarmStartPath = true, path
+	t.Cleanup(func() {
+		features.EnableXDSCacheWarmStart, features.XDSCacheWarmStartPath = oldEnabled, oldPath
+	})
+
+	// The server restarts with a cold cache.
+	c.ClearAll()
+	s.Discovery.updateMutex.Lock()
+	s.Discovery.serverReady = false
+	s.Discovery.updateMutex.Unlock()
+	s.Discovery.CachesSynced()
+	if _, f := c.Get(ep); !f {
+		t.Fatalf("expected the persisted entry to be loaded before the server is ready, got %v", c.Keys())
+	}
+
+	// A proxy connecting right after the server is ready is served from the warm cache.
+	ads := s.Connect(&model.Proxy{}, []string{v3.ClusterType, v3.EndpointType}, []string{v3.ClusterType, v3.EndpointType})
+	cla := ads.GetEndpoints()["outbound|80||foo.example.com"]
+	if cla == nil || len(cla.Endpoints) != 0 {
+		t.Fatalf("expected the persisted endpoints to be served, got %v", cla)
+	}
+}
+
+func TestMeshConfigUpdateCache(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: foo
+  namespace: default
+spec:
+  hosts:
+  - foo.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+`})
+	watch := []string{v3.ClusterType, v3.ListenerType}
+	ads := s.Connect(&model.Proxy{}, watch, watch)
+	ep := NewEndpointBuilder("outbound|80||foo.example.com", s.SetupProxy(&model.Proxy{}), s.PushContext())
+	expectPush := func(typeURL string, want bool) {
+		t.Helper()
+		timeout := 5 * time.Second
+		if !want {
+			timeout = 200 * time.Millisecond
+		}
+		if _, err := ads.Wait(timeout, typeURL); (err == nil) != want {
+			t.Fatalf("expected %v push %v, got error %v", v3.GetShortType(typeURL), want, err)
+		}
+	}
+	update := func(change func(m *meshconfig.MeshConfig)) {
+		t.Helper()
+		prev := s.Env().Mesh()
+		curr := proto.Clone(prev).(*meshconfig.MeshConfig)
+		change(curr)
+		s.Discovery.Cache.Add(ep, any1)
+		ads.WaitClear()
+		s.Discovery.MeshConfigUpdate(model.DiffMeshConfig(prev, curr))
+	}
+
+	// Only the listeners are regenerated for an access log change, and the cached endpoints are kept.
+	update(func(m *meshconfig.MeshConfig) { m.AccessLogFormat = "[%START_TIME%]" })
+	expectPush(v3.ListenerType, true)
+	expectPush(v3.ClusterType, false)
+	if got, f := s.Discovery.Cache.Get(ep); !f || got != any1 {
+		t.Fatalf("expected the cached endpoints to be kept, got %v", got)
+	}
+
+	// An unknown field affects all types.
+	update(func(m *meshconfig.MeshConfig) { m.TrustDomain = "example.com" })
+	expectPush(v3.ClusterType, true)
+	if got, _ := s.Discovery.Cache.Get(ep); got == any1 {
+		t.Fatalf("expected the cache to be cleared")
+	}
 }
@@ -0,0 +1,291 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/any"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/protomarshal"
+	istioversion "istio.io/pkg/version"
+)
+
+// xdsCacheSnapshotFormat is bumped whenever the persisted format changes, so older snapshots are ignored.
+const xdsCacheSnapshotFormat = 1
+
+// xdsCacheSnapshot is the persisted form of the XDS cache, used to warm start the cache after a restart.
+type xdsCacheSnapshot struct {
+	Format  int                     `json:"format"`
+	Entries []xdsCacheSnapshotEntry `json:"entries"`
+}
+
+type xdsCacheSnapshotEntry struct {
+	Key              string            `json:"key"`
+	DependentConfigs []model.ConfigKey `json:"dependentConfigs"`
+	// ConfigVersion identifies the state of the dependent configs the resource was built from.
+	ConfigVersion string `json:"configVersion"`
+	TypeURL       string `json:"typeUrl"`
+	Value         []byte `json:"value"`
+}
+
+// warmStartEntry is the XdsCacheEntry of a resource loaded from a snapshot.
+type warmStartEntry struct {
+	key     string
+	configs []model.ConfigKey
+}
+
+var _ model.XdsCacheEntry = warmStartEntry{}
+
+func (e warmStartEntry) Key() string {
+	return e.key
+}
+
+func (e warmStartEntry) DependentConfigs() []model.ConfigKey {
+	return e.configs
+}
+
+func (e warmStartEntry) Cacheable() bool {
+	return true
+}
+
+// marshalXdsCache serializes the cache entries that can be validated on startup.
+func (s *DiscoveryServer) marshalXdsCache(limit int) ([]byte, error) {
+	snapshot := xdsCacheSnapshot{Format: xdsCacheSnapshotFormat}
+	for _, entry := range s.Cache.Snapshot(limit) {
+		version, ok := s.xdsCacheConfigVersion(entry.DependentConfigs)
+		if !ok {
+			continue
+		}
+		snapshot.Entries = append(snapshot.Entries, xdsCacheSnapshotEntry{
+			Key:              entry.Key,
+			DependentConfigs: entry.DependentConfigs,
+			ConfigVersion:    version,
+			TypeURL:          entry.Value.TypeUrl,
+			Value:            entry.Value.Value,
+		})
+	}
+	return json.Marshal(snapshot)
+}
+
+// unmarshalXdsCache adds the serialized entries to the cache, discarding the ones built from configs
+// that have changed since. It returns the number of entries added.
+func (s *DiscoveryServer) unmarshalXdsCache(data []byte) (int, error) {
+	snapshot := xdsCacheSnapshot{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return 0, err
+	}
+	if snapshot.Format != xdsCacheSnapshotFormat {
+		return 0, fmt.Errorf("unsupported snapshot format %d", snapshot.Format)
+	}
+	existing := map[string]struct{}{}
+	for _, k := range s.Cache.Keys() {
+		existing[k] = struct{}{}
+	}
+	loaded := 0
+	// The entries are ordered from the most recently used, they are added from the least recently used so that
+	// the most recently used remain so, and are the last to be evicted.
+	for i := len(snapshot.Entries) - 1; i >= 0; i-- {
+		entry := snapshot.Entries[i]
+		if _, f := existing[entry.Key]; f {
+			// The entry was already generated with the current state, which is never older than the snapshot.
+			continue
+		}
+		if version, ok := s.xdsCacheConfigVersion(entry.DependentConfigs); !ok || version != entry.ConfigVersion {
+			continue
+		}
+		s.Cache.Add(warmStartEntry{key: entry.Key, configs: entry.DependentConfigs}, &any.Any{TypeUrl: entry.TypeURL, Value: entry.Value})
+		loaded++
+	}
+	return loaded, nil
+}
+
+// saveXdsCache persists the XDS cache to the warm start path.
+func (s *DiscoveryServer) saveXdsCache() {
+	data, err := s.marshalXdsCache(features.XDSCacheWarmStartMaxEntries)
+	if err != nil {
+		adsLog.Warnf("failed to serialize the XDS cache: %v", err)
+		return
+	}
+	path := features.XDSCacheWarmStartPath
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		adsLog.Warnf("failed to persist the XDS cache: %v", err)
+		return
+	}
+	// Write to a temporary file first, so that an interrupted shutdown does not leave a truncated snapshot.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		adsLog.Warnf("failed to persist the XDS cache: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		adsLog.Warnf("failed to persist the XDS cache: %v", err)
+		return
+	}
+	adsLog.Infof("persisted the XDS cache to %s", path)
+}
+
+// loadXdsCache pre-populates the XDS cache from the warm start path.
+func (s *DiscoveryServer) loadXdsCache() {
+	path := features.XDSCacheWarmStartPath
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			adsLog.Warnf("failed to read the persisted XDS cache: %v", err)
+		}
+		return
+	}
+	loaded, err := s.unmarshalXdsCache(data)
+	if err != nil {
+		adsLog.Warnf("failed to load the persisted XDS cache from %s: %v", path, err)
+		return
+	}
+	adsLog.Infof("warm started the XDS cache with %d entries from %s", loaded, path)
+}
+
+// xdsCacheConfigVersion returns a version of the given configs, which changes whenever any of them changes.
+// The boolean is false if one of the configs cannot be versioned, in which case the entries depending on
+// it are not persisted.
+func (s *DiscoveryServer) xdsCacheConfigVersion(configs []model.ConfigKey) (string, bool) {
+	if len(configs) == 0 {
+		// Nothing would invalidate such an entry.
+		return "", false
+	}
+	sorted := make([]model.ConfigKey, len(configs))
+	copy(sorted, configs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return configKeyString(sorted[i]) < configKeyString(sorted[j])
+	})
+
+	h := sha256.New()
+	// Resources built by another version of istiod, or with another mesh config, cannot be reused.
+	_, _ = h.Write([]byte(istioversion.Info.String()))
+	mesh, err := protomarshal.ToJSON(s.Env.Mesh())
+	if err != nil {
+		return "", false
+	}
+	_, _ = h.Write([]byte(mesh))
+	// The endpoints and clusters of the services also depend on the mesh networks and their gateways, which are
+	// not configs the entries depend on.
+	if networks := s.Env.Networks(); networks != nil {
+		networksJSON, err := protomarshal.ToJSON(networks)
+		if err != nil {
+			return "", false
+		}
+		_, _ = h.Write([]byte(networksJSON))
+	}
+	writeNetworkGateways(h, s.globalPushContext().NetworkGateways())
+	for _, key := range sorted {
+		version, ok := s.configVersion(key)
+		if !ok {
+			return "", false
+		}
+		_, _ = fmt.Fprintf(h, "%s=%s\n", configKeyString(key), version)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// writeNetworkGateways writes the gateways of the networks, sorted, to the hash.
+func writeNetworkGateways(h io.Writer, gateways map[string][]*model.Gateway) {
+	networks := make([]string, 0, len(gateways))
+	for network := range gateways {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	for _, network := range networks {
+		addrs := make([]string, 0, len(gateways[network]))
+		for _, gw := range gateways[network] {
+			addrs = append(addrs, net.JoinHostPort(gw.Addr, strconv.Itoa(int(gw.Port))))
+		}
+		sort.Strings(addrs)
+		_, _ = fmt.Fprintf(h, "network %s=%s\n", network, strings.Join(addrs, ","))
+	}
+}
+
+func configKeyString(key model.ConfigKey) string {
+	return key.Kind.String() + "/" + key.Namespace + "/" + key.Name
+}
+
+func (s *DiscoveryServer) configVersion(key model.ConfigKey) (string, bool) {
+	if key.Kind == gvk.ServiceEntry {
+		// Services are keyed by hostname, and the cached endpoints depend on the endpoint shards as well.
+		return s.serviceVersion(host.Name(key.Name), key.Namespace)
+	}
+	if s.Env.IstioConfigStore == nil {
+		return "", false
+	}
+	if _, f := s.Env.IstioConfigStore.Schemas().FindByGroupVersionKind(key.Kind); !f {
+		return "", false
+	}
+	cfg := s.Env.IstioConfigStore.Get(key.Kind, key.Name, key.Namespace)
+	if cfg == nil {
+		return "absent", true
+	}
+	if cfg.ResourceVersion == "" {
+		return "", false
+	}
+	return cfg.ResourceVersion, true
+}
+
+func (s *DiscoveryServer) serviceVersion(hostname host.Name, namespace string) (string, bool) {
+	svc, err := s.Env.GetService(hostname)
+	if err != nil || svc == nil || svc.Attributes.Namespace != namespace {
+		return "", false
+	}
+	svc.Mutex.RLock()
+	svcJSON, err := json.Marshal(svc)
+	svc.Mutex.RUnlock()
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	_, _ = h.Write(svcJSON)
+	s.mutex.RLock()
+	shards, f := s.EndpointShardsByService[string(hostname)][namespace]
+	s.mutex.RUnlock()
+	if f {
+		var endpoints []string
+		shards.mutex.RLock()
+		for cluster, eps := range shards.Shards {
+			for _, ep := range eps {
+				endpoints = append(endpoints, fmt.Sprintf("%s|%s|%d|%s|%s|%s|%s|%s|%d|%s|%v", cluster, ep.Address,
+					ep.EndpointPort, ep.ServicePortName, ep.Network, ep.Locality.Label, ep.Locality.ClusterID,
+					ep.ServiceAccount, ep.LbWeight, ep.TLSMode, ep.Labels))
+			}
+		}
+		shards.mutex.RUnlock()
+		sort.Strings(endpoints)
+		for _, ep := range endpoints {
+			_, _ = fmt.Fprintln(h, ep)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
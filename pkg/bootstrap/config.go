@@ -54,6 +54,13 @@ const (
 	// "component" suffix is for istio_build metric.
 	v2Prefixes = "reporter=,"
 	v2Suffix   = ",component"
+
+	// DefaultStatusPort is the default port of the status server of the agent.
+	DefaultStatusPort = 15020
+	// DefaultReadinessPath is the default path of the readiness probe of the status server of the agent.
+	DefaultReadinessPath = "/healthz/ready"
+	// EnvoyReadinessPort is the port of the listener of Envoy forwarding the readiness probes to the agent.
+	EnvoyReadinessPort = 15021
 )
 
 var (
@@ -87,6 +94,41 @@ type Config struct {
 	ProvCert            string
 	DiscoveryHost       string
 	CallCredentials     bool
+	// IPFamily is the IP family of the primary address of the proxy.
+	IPFamily IPFamily
+	// LoadStatsReporting configures Envoy to report upstream load stats to the discovery server.
+	LoadStatsReporting bool
+	// StatusPort is the port of the status server of the agent, which Envoy forwards the readiness probes it
+	// receives to. Defaults to 15020.
+	StatusPort int
+	// ReadinessPath is the path of the readiness probe of the status server. Defaults to /healthz/ready.
+	ReadinessPath string
+	// GatewayTopologyAnnotated is reported in the node metadata, so that istiod prefers the gateway topology of the
+	// proxy config, set by the proxy.istio.io/config annotation of the pod, to the one it resolves.
+	GatewayTopologyAnnotated bool
+}
+
+// IPFamily is the IP family of the primary address of a proxy, which determines the localhost and wildcard
+// addresses used by Envoy and its DNS lookup family.
+type IPFamily string
+
+const (
+	// IPFamilyAuto derives the IP family from the node IPs: the proxy is IPv6 only if all of them are IPv6.
+	IPFamilyAuto IPFamily = ""
+	IPFamilyIPv4 IPFamily = "IPv4"
+	IPFamilyIPv6 IPFamily = "IPv6"
+)
+
+// IsIPv6 returns true if the proxy with the given node IPs should be set up for IPv6.
+func (f IPFamily) IsIPv6(nodeIPs []string) bool {
+	switch f {
+	case IPFamilyIPv4:
+		return false
+	case IPFamilyIPv6:
+		return true
+	default:
+		return isIPv6Proxy(nodeIPs)
+	}
 }
 
 // newTemplateParams creates a new template configuration for the given configuration.
@@ -109,8 +151,21 @@ func (cfg Config) toTemplateParams() (map[string]interface{}, error) {
 		option.OutlierLogPath(cfg.OutlierLogPath),
 		option.ProvCert(cfg.ProvCert),
 		option.CallCredentials(cfg.CallCredentials),
+		// Load stats are sent over the xds-grpc cluster, which is not used with call credentials.
+		option.LoadStatsReporting(cfg.LoadStatsReporting && !cfg.CallCredentials),
 		option.DiscoveryHost(cfg.DiscoveryHost))
 
+	statusPort, readinessPath := cfg.StatusPort, cfg.ReadinessPath
+	if statusPort <= 0 {
+		statusPort = DefaultStatusPort
+	}
+	if readinessPath == "" {
+		readinessPath = DefaultReadinessPath
+	}
+	opts = append(opts,
+		option.StatusPort(statusPort),
+		option.ReadinessPath(readinessPath))
+
 	if cfg.STSPort > 0 {
 		opts = append(opts,
 			option.STSEnabled(true),
@@ -126,10 +181,11 @@ func (cfg Config) toTemplateParams() (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	meta.GatewayTopologyAnnotated = model.StringBool(cfg.GatewayTopologyAnnotated)
 	opts = append(opts, getNodeMetadataOptions(meta, rawMeta, cfg.PlatEnv, cfg.Proxy)...)
 
-	// Check if nodeIP carries IPv4 or IPv6 and set up proxy accordingly
-	if isIPv6Proxy(cfg.NodeIPs) {
+	// Check if the primary IP family is IPv4 or IPv6 and set up proxy accordingly
+	if cfg.IPFamily.IsIPv6(cfg.NodeIPs) {
 		opts = append(opts,
 			option.Localhost(option.LocalhostIPv6),
 			option.Wildcard(option.WildcardIPv6),
@@ -390,6 +446,10 @@ func shouldExtract(envVar, prefix string) bool {
 	if strings.HasPrefix(envVar, "ISTIO_META_WORKLOAD") {
 		return false
 	}
+	// The dns config file is only used by the agent, its local path is of no use to istiod.
+	if strings.HasPrefix(envVar, "ISTIO_META_DNS_CONFIG_FILE=") {
+		return false
+	}
 	return strings.HasPrefix(envVar, prefix)
 }
 
@@ -85,7 +85,10 @@ func TestGolden(t *testing.T) {
 		stats                      stats
 		checkLocality              bool
 		proxyViaAgent              bool
+		loadStatsReporting         bool
 		stsPort                    int
+		statusPort                 int
+		readinessPath              string
 		platformMeta               map[string]string
 		setup                      func()
 		teardown                   func()
@@ -106,6 +109,15 @@ func TestGolden(t *testing.T) {
 		{
 			base: "default",
 		},
+		{
+			base:               "loadstats",
+			loadStatsReporting: true,
+		},
+		{
+			base:          "routerstatus",
+			statusPort:    15030,
+			readinessPath: "/gateway/ready",
+		},
 		{
 			base: "running",
 			envVars: map[string]string{
@@ -327,12 +339,15 @@ func TestGolden(t *testing.T) {
 				},
 				PilotSubjectAltName: []string{
 					"spiffe://cluster.local/ns/istio-system/sa/istio-pilot-service-account"},
-				LocalEnv:          localEnv,
-				NodeIPs:           []string{"10.3.3.3", "10.4.4.4", "10.5.5.5", "10.6.6.6", "10.4.4.4"},
-				OutlierLogPath:    "/dev/stdout",
-				PilotCertProvider: "istiod",
-				STSPort:           c.stsPort,
-				ProxyViaAgent:     c.proxyViaAgent,
+				LocalEnv:           localEnv,
+				NodeIPs:            []string{"10.3.3.3", "10.4.4.4", "10.5.5.5", "10.6.6.6", "10.4.4.4"},
+				OutlierLogPath:     "/dev/stdout",
+				PilotCertProvider:  "istiod",
+				STSPort:            c.stsPort,
+				ProxyViaAgent:      c.proxyViaAgent,
+				LoadStatsReporting: c.loadStatsReporting,
+				StatusPort:         c.statusPort,
+				ReadinessPath:      c.readinessPath,
 			}).CreateFileForEpoch(0)
 			if err != nil {
 				t.Fatal(err)
@@ -594,6 +609,46 @@ func TestIsIPv6Proxy(t *testing.T) {
 	}
 }
 
+func TestIPFamilyIsIPv6(t *testing.T) {
+	dualStack := []string{"1111:2222::1", "2.2.2.2"}
+	tests := []struct {
+		name     string
+		family   IPFamily
+		addrs    []string
+		expected bool
+	}{
+		{
+			name:     "auto dual-stack",
+			family:   IPFamilyAuto,
+			addrs:    dualStack,
+			expected: false,
+		},
+		{
+			name:     "auto ipv6 only",
+			family:   IPFamilyAuto,
+			addrs:    []string{"1111:2222::1"},
+			expected: true,
+		},
+		{
+			name:     "ipv6 primary dual-stack",
+			family:   IPFamilyIPv6,
+			addrs:    dualStack,
+			expected: true,
+		},
+		{
+			name:     "ipv4 primary dual-stack",
+			family:   IPFamilyIPv4,
+			addrs:    dualStack,
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		if result := tt.family.IsIPv6(tt.addrs); result != tt.expected {
+			t.Errorf("Test %s failed, expected: %t got: %t", tt.name, tt.expected, result)
+		}
+	}
+}
+
 // createEnv takes labels and annotations are returns environment in go format.
 func createEnv(t *testing.T, labels map[string]string, anno map[string]string) (map[string]string, []string) {
 	merged := map[string]string{}
@@ -663,6 +718,26 @@ func TestNodeMetadata(t *testing.T) {
 	}
 }
 
+func TestNodeMetadataDNSConfigFile(t *testing.T) {
+	envs := []string{
+		"ISTIO_META_DNS_CAPTURE=true",
+		"ISTIO_META_DNS_CONFIG_FILE=/etc/istio/dns/config.yaml",
+	}
+	nm, untyped, err := getNodeMetaData(envs, nil, nil, 0, &meshconfig.ProxyConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nm.Raw["DNS_CONFIG_FILE"]; ok {
+		t.Fatalf("DNS_CONFIG_FILE should not be encoded in node metadata")
+	}
+	if _, ok := untyped["DNS_CONFIG_FILE"]; ok {
+		t.Fatalf("DNS_CONFIG_FILE should not be encoded in node metadata")
+	}
+	if nm.DNSCapture != "true" {
+		t.Fatalf("Expected DNSCapture true, got %v", nm.DNSCapture)
+	}
+}
+
 func mergeMap(to map[string]string, from map[string]string) {
 	for k, v := range from {
 		to[k] = v
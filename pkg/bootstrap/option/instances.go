@@ -216,6 +216,21 @@ func CallCredentials(value bool) Instance {
 	return newOption("call_credentials", value)
 }
 
+// LoadStatsReporting configures Envoy to report load stats to the Load Reporting Service of the discovery server.
+func LoadStatsReporting(value bool) Instance {
+	return newOption("load_stats_reporting", value)
+}
+
+// StatusPort is the port of the status server of the agent, which Envoy forwards the readiness probes to.
+func StatusPort(value int) Instance {
+	return newOption("status_port", value)
+}
+
+// ReadinessPath is the path of the readiness probe of the status server of the agent.
+func ReadinessPath(value string) Instance {
+	return newOption("readiness_path", value)
+}
+
 func DiscoveryHost(value string) Instance {
 	return newOption("discovery_host", value)
 }
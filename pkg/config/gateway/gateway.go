@@ -15,10 +15,32 @@
 package gateway
 
 import (
+	"strings"
+
 	"istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pkg/config/protocol"
 )
 
+// ExportToAnnotation restricts the namespaces whose VirtualServices may bind to a Gateway. It is a comma
+// separated list with the same meaning as the exportTo of a VirtualService: "." is the namespace of the
+// Gateway and "*" is every namespace. Gateways without it may be bound from any namespace.
+const ExportToAnnotation = "networking.istio.io/exportTo"
+
+// ExportTo returns the exportTo list of a Gateway from its annotations, or nil if it is not restricted.
+func ExportTo(annotations map[string]string) []string {
+	value, ok := annotations[ExportToAnnotation]
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, e := range strings.Split(value, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
 // IsTLSServer returns true if this server is non HTTP, with some TLS settings for termination/passthrough
 func IsTLSServer(server *v1alpha3.Server) bool {
 	if server.Tls != nil && !protocol.Parse(server.Port.Protocol).IsHTTP() {
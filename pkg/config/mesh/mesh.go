@@ -250,6 +250,13 @@ func ResolveHostsInNetworksConfig(config *meshconfig.MeshNetworks) {
 	}
 }
 
+const (
+	// watchDebounceDelay is the time without events of a watched file before it is reloaded.
+	watchDebounceDelay = 100 * time.Millisecond
+	// watchMaxDelay bounds the reload delay of a watched file which keeps changing.
+	watchMaxDelay = time.Second
+)
+
 // Add to the FileWatcher the provided file and execute the provided function
 // on any change event for this file.
 // Using a debouncing mechanism to avoid calling the callback multiple times
@@ -258,16 +265,24 @@ func addFileWatcher(fileWatcher filewatcher.FileWatcher, file string, callback f
 	_ = fileWatcher.Add(file)
 	go func() {
 		var timerC <-chan time.Time
+		var firstEvent time.Time
 		for {
 			select {
 			case <-timerC:
 				timerC = nil
 				callback()
 			case <-fileWatcher.Events(file):
-				// Use a timer to debounce configuration updates
+				// Use a timer to debounce configuration updates. Updates of mounted ConfigMaps surface as a
+				// series of events, so wait for the file to settle, but no longer than watchMaxDelay.
+				now := time.Now()
 				if timerC == nil {
-					timerC = time.After(100 * time.Millisecond)
+					firstEvent = now
+				}
+				delay := watchDebounceDelay
+				if remaining := firstEvent.Add(watchMaxDelay).Sub(now); remaining < delay {
+					delay = remaining
 				}
+				timerC = time.After(delay)
 			}
 		}
 	}()
@@ -0,0 +1,208 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/go-multierror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/host"
+	"istio.io/pkg/filewatcher"
+	"istio.io/pkg/log"
+)
+
+// MeshExtensions are the settings of the mesh applied by istiod which are not part of MeshConfig. They are read
+// from the meshExtensions key of the mesh config map, and applied with a push when they change, for example:
+//
+//   egressGatewayRedirects:
+//   - namespace: foo
+//     hosts:
+//     - api.example.com
+//     gateway: istio-egressgateway.istio-system.svc.cluster.local
+//   accessLogFilter:
+//     minStatusCode: 500
+//     responseFlags: true
+type MeshExtensions struct {
+	// EgressGatewayRedirects are the hosts the sidecars send to an egress gateway.
+	EgressGatewayRedirects []EgressGatewayRedirect `json:"egressGatewayRedirects,omitempty"`
+	// AccessLogFilter, if set, restricts the file and gRPC access logs of the proxies to the matching entries.
+	AccessLogFilter *AccessLogFilter `json:"accessLogFilter,omitempty"`
+	// ServiceEntryHostCollisionPolicy is how a ServiceEntry host which is also the host of a Kubernetes service is
+	// handled, one of HostCollisionAllow (the default), HostCollisionReject or HostCollisionPreferKubernetes.
+	ServiceEntryHostCollisionPolicy string `json:"serviceEntryHostCollisionPolicy,omitempty"`
+}
+
+// Values of MeshExtensions.ServiceEntryHostCollisionPolicy.
+const (
+	// HostCollisionAllow uses both services, the oldest one taking precedence.
+	HostCollisionAllow = "ALLOW"
+	// HostCollisionReject ignores the ServiceEntry host.
+	HostCollisionReject = "REJECT"
+	// HostCollisionPreferKubernetes uses both services, the Kubernetes one taking precedence.
+	HostCollisionPreferKubernetes = "PREFER_KUBERNETES"
+)
+
+// EgressGatewayRedirect routes the traffic of the sidecars to the hosts through an egress gateway. The clusters and
+// endpoints the sidecars get for the hosts are those of the gateway service, while the gateway itself resolves the
+// hosts to their ServiceEntry.
+type EgressGatewayRedirect struct {
+	// Namespace of the sidecars the redirect applies to. If empty, it applies to the sidecars of all namespaces,
+	// unless a redirect of their namespace lists the same host.
+	Namespace string `json:"namespace,omitempty"`
+	// Hosts routed through the gateway.
+	Hosts []string `json:"hosts"`
+	// Gateway is the hostname of the egress gateway service.
+	Gateway string `json:"gateway"`
+	// Port of the gateway service the traffic is sent to. If unset, it is the gateway port with the number of the
+	// port of the host.
+	Port int `json:"port,omitempty"`
+}
+
+// AccessLogFilter holds the conditions under which an access log entry is emitted. An entry is emitted if any
+// of the set conditions match; if none is set, every entry is emitted.
+type AccessLogFilter struct {
+	// MinStatusCode, if set, matches HTTP responses with at least this status code. It does not apply to TCP.
+	MinStatusCode int `json:"minStatusCode,omitempty"`
+	// ResponseFlags matches requests and connections with any Envoy response flag set.
+	ResponseFlags bool `json:"responseFlags,omitempty"`
+	// MinDuration, if set, matches requests and connections lasting at least this long, e.g. "2s".
+	MinDuration metav1.Duration `json:"minDuration,omitempty"`
+}
+
+// ParseMeshExtensions returns the MeshExtensions decoded from the input YAML.
+func ParseMeshExtensions(yml string) (*MeshExtensions, error) {
+	out := &MeshExtensions{}
+	if err := yaml.Unmarshal([]byte(yml), out); err != nil {
+		return nil, multierror.Prefix(err, "failed to parse mesh extensions.")
+	}
+	var errs error
+	for i, r := range out.EgressGatewayRedirects {
+		if r.Gateway == "" || host.Name(r.Gateway).IsWildCarded() {
+			errs = multierror.Append(errs, fmt.Errorf("egress gateway redirect %d: gateway must be a hostname, got %q", i, r.Gateway))
+		}
+		if len(r.Hosts) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("egress gateway redirect %d: hosts are required", i))
+		}
+		if r.Port < 0 || r.Port > 65535 {
+			errs = multierror.Append(errs, fmt.Errorf("egress gateway redirect %d: invalid port %d", i, r.Port))
+		}
+	}
+	if f := out.AccessLogFilter; f != nil {
+		if f.MinStatusCode < 0 || f.MinStatusCode > 599 {
+			errs = multierror.Append(errs, fmt.Errorf("access log filter: invalid status code %d", f.MinStatusCode))
+		}
+		if f.MinDuration.Duration < 0 {
+			errs = multierror.Append(errs, fmt.Errorf("access log filter: invalid duration %v", f.MinDuration.Duration))
+		}
+	}
+	switch out.ServiceEntryHostCollisionPolicy {
+	case "", HostCollisionAllow, HostCollisionReject, HostCollisionPreferKubernetes:
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid service entry host collision policy %q", out.ServiceEntryHostCollisionPolicy))
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return out, nil
+}
+
+// ReadMeshExtensions gets the mesh extensions from a config file.
+func ReadMeshExtensions(filename string) (*MeshExtensions, error) {
+	yml, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, multierror.Prefix(err, "cannot read mesh extensions file")
+	}
+	return ParseMeshExtensions(string(yml))
+}
+
+// MeshExtensionsWatcher watches changes to the mesh extensions.
+type MeshExtensionsWatcher interface {
+	MeshExtensions() *MeshExtensions
+	AddMeshExtensionsHandler(func())
+}
+
+var _ MeshExtensionsWatcher = &meshExtensionsWatcher{}
+
+type meshExtensionsWatcher struct {
+	mutex      sync.Mutex
+	handlers   []func()
+	extensions *MeshExtensions
+}
+
+// NewFixedMeshExtensionsWatcher creates a new MeshExtensionsWatcher that always returns the given extensions.
+// It will never fire any events, since the extensions never change.
+func NewFixedMeshExtensionsWatcher(extensions *MeshExtensions) MeshExtensionsWatcher {
+	return &meshExtensionsWatcher{
+		extensions: extensions,
+	}
+}
+
+// NewMeshExtensionsWatcher creates a new watcher for changes to the given mesh extensions file.
+func NewMeshExtensionsWatcher(fileWatcher filewatcher.FileWatcher, filename string) (MeshExtensionsWatcher, error) {
+	extensions, err := ReadMeshExtensions(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mesh extensions from %q: %v", filename, err)
+	}
+
+	w := &meshExtensionsWatcher{
+		extensions: extensions,
+	}
+
+	addFileWatcher(fileWatcher, filename, func() {
+		extensions, err := ReadMeshExtensions(filename)
+		if err != nil {
+			log.Warnf("failed to read mesh extensions from %q, keeping the current ones: %v", filename, err)
+			return
+		}
+		w.setMeshExtensions(extensions)
+	})
+	return w, nil
+}
+
+// MeshExtensions returns the latest mesh extensions.
+func (w *meshExtensionsWatcher) MeshExtensions() *MeshExtensions {
+	return (*MeshExtensions)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&w.extensions))))
+}
+
+func (w *meshExtensionsWatcher) setMeshExtensions(extensions *MeshExtensions) {
+	var handlers []func()
+
+	w.mutex.Lock()
+	if !reflect.DeepEqual(extensions, w.extensions) {
+		log.Infof("mesh extensions updated")
+		atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&w.extensions)), unsafe.Pointer(extensions))
+		handlers = append([]func(){}, w.handlers...)
+	}
+	w.mutex.Unlock()
+
+	for _, h := range handlers {
+		h()
+	}
+}
+
+// AddMeshExtensionsHandler registers a callback handler for changes to the mesh extensions.
+func (w *meshExtensionsWatcher) AddMeshExtensionsHandler(h func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.handlers = append(w.handlers, h)
+}
@@ -0,0 +1,75 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestParseMeshExtensions(t *testing.T) {
+	got, err := mesh.ParseMeshExtensions(`
+egressGatewayRedirects:
+- namespace: foo
+  hosts:
+  - api.example.com
+  gateway: istio-egressgateway.istio-system.svc.cluster.local
+  port: 443
+accessLogFilter:
+  minStatusCode: 500
+  minDuration: 2s
+serviceEntryHostCollisionPolicy: REJECT
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &mesh.MeshExtensions{
+		EgressGatewayRedirects: []mesh.EgressGatewayRedirect{{
+			Namespace: "foo",
+			Hosts:     []string{"api.example.com"},
+			Gateway:   "istio-egressgateway.istio-system.svc.cluster.local",
+			Port:      443,
+		}},
+		AccessLogFilter: &mesh.AccessLogFilter{
+			MinStatusCode: 500,
+			MinDuration:   metav1.Duration{Duration: 2 * time.Second},
+		},
+		ServiceEntryHostCollisionPolicy: mesh.HostCollisionReject,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseMeshExtensionsInvalid(t *testing.T) {
+	for _, yml := range []string{
+		"egressGatewayRedirects:\n- hosts: [api.example.com]",
+		"egressGatewayRedirects:\n- hosts: [api.example.com]\n  gateway: '*.example.com'",
+		"egressGatewayRedirects:\n- gateway: istio-egressgateway.istio-system.svc.cluster.local",
+		"egressGatewayRedirects:\n- hosts: [api.example.com]\n  gateway: egress.example.com\n  port: 70000",
+		"accessLogFilter:\n  minStatusCode: 700",
+		"accessLogFilter:\n  minDuration: -1s",
+		"serviceEntryHostCollisionPolicy: DENY",
+	} {
+		if _, err := mesh.ParseMeshExtensions(yml); err == nil {
+			t.Errorf("expected an error parsing %q", yml)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ghodss/yaml"
+	"github.com/gogo/protobuf/proto"
+	"github.com/hashicorp/go-multierror"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/validation"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+	"istio.io/pkg/filewatcher"
+	"istio.io/pkg/log"
+)
+
+// DynamicProxyConfigFields are the ProxyConfig fields, by JSON name, applied to a running proxy when they are
+// pushed by istiod, by the agent or, for gatewayTopology, in the gateway listeners. A change of any other field
+// only applies once the proxy is restarted. The log level of the proxy is not part of the proxy config: it is set
+// at startup by the sidecar.istio.io/logLevel annotation, and changed at runtime through the /logging endpoint of
+// the Envoy admin API.
+var DynamicProxyConfigFields = map[string]struct{}{
+	"gatewayTopology":          {},
+	"terminationDrainDuration": {},
+}
+
+// ProxyConfigOverrides are overrides of the default proxy config of the mesh for the workloads of a namespace,
+// or the workloads selected by labels. They are read from the proxyConfigOverrides key of the mesh config map,
+// set from the pilot.proxyConfigOverrides value of the istiod chart, for example:
+//
+//   overrides:
+//   - namespace: foo
+//     proxyConfig:
+//       terminationDrainDuration: 30s
+//   - namespace: foo
+//     selector:
+//       app: bar
+//     proxyConfig:
+//       terminationDrainDuration: 60s
+type ProxyConfigOverrides struct {
+	Overrides []ProxyConfigOverride `json:"overrides,omitempty"`
+}
+
+// ProxyConfigOverride overrides the fields set in ProxyConfig for the workloads it selects.
+type ProxyConfigOverride struct {
+	// Namespace of the workloads the override applies to. If empty, it applies to the workloads of all namespaces
+	// matching the selector.
+	Namespace string `json:"namespace,omitempty"`
+	// Selector of the workloads the override applies to. If empty, the override applies to all the workloads of
+	// the namespace.
+	Selector map[string]string `json:"selector,omitempty"`
+	// ProxyConfig holds the fields overridden, in the JSON format of ProxyConfig.
+	ProxyConfig json.RawMessage `json:"proxyConfig"`
+}
+
+// isWorkloadScoped returns true if the override selects workloads, and takes precedence over namespace overrides.
+func (o ProxyConfigOverride) isWorkloadScoped() bool {
+	return len(o.Selector) > 0
+}
+
+func (o ProxyConfigOverride) matches(namespace string, workloadLabels map[string]string) bool {
+	if o.Namespace != "" && o.Namespace != namespace {
+		return false
+	}
+	return labels.Instance(o.Selector).SubsetOf(workloadLabels)
+}
+
+// ParseProxyConfigOverrides returns the ProxyConfigOverrides decoded from the input YAML.
+func ParseProxyConfigOverrides(yml string) (*ProxyConfigOverrides, error) {
+	out := &ProxyConfigOverrides{}
+	if err := yaml.Unmarshal([]byte(yml), out); err != nil {
+		return nil, multierror.Prefix(err, "failed to parse proxy config overrides.")
+	}
+	var errs error
+	for i, o := range out.Overrides {
+		if o.Namespace == "" && len(o.Selector) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("override %d: namespace or selector is required, "+
+				"the mesh wide proxy config is set by defaultConfig", i))
+		}
+		if err := labels.Instance(o.Selector).Validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("override %d: invalid selector: %v", i, err))
+		}
+		if len(o.ProxyConfig) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("override %d: proxyConfig is required", i))
+		} else if err := gogoprotomarshal.ApplyJSONStrict(string(o.ProxyConfig), &meshconfig.ProxyConfig{}); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("override %d: invalid proxy config: %v", i, err))
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return out, nil
+}
+
+// ReadProxyConfigOverrides gets the proxy config overrides from a config file.
+func ReadProxyConfigOverrides(filename string) (*ProxyConfigOverrides, error) {
+	yml, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, multierror.Prefix(err, "cannot read proxy config overrides file")
+	}
+	return ParseProxyConfigOverrides(string(yml))
+}
+
+// EffectiveProxyConfig returns the proxy config of a workload of the namespace with the labels: the default proxy
+// config, with the overrides of the namespace applied, then the overrides selecting the workload. Overrides of
+// the same scope are applied in the order they are listed. The default is returned, with an error, if the result
+// is not a valid proxy config.
+func (o *ProxyConfigOverrides) EffectiveProxyConfig(defaultConfig *meshconfig.ProxyConfig, namespace string,
+	workloadLabels map[string]string) (*meshconfig.ProxyConfig, error) {
+	if defaultConfig == nil {
+		pc := DefaultProxyConfig()
+		defaultConfig = &pc
+	}
+	if o == nil || len(o.Overrides) == 0 {
+		return defaultConfig, nil
+	}
+	pc := proto.Clone(defaultConfig).(*meshconfig.ProxyConfig)
+	applied := false
+	for _, workloadScoped := range []bool{false, true} {
+		for _, override := range o.Overrides {
+			if override.isWorkloadScoped() != workloadScoped || !override.matches(namespace, workloadLabels) {
+				continue
+			}
+			if err := gogoprotomarshal.ApplyJSONStrict(string(override.ProxyConfig), pc); err != nil {
+				return defaultConfig, err
+			}
+			applied = true
+		}
+	}
+	if !applied {
+		return defaultConfig, nil
+	}
+	if err := validation.ValidateProxyConfig(pc); err != nil {
+		return defaultConfig, err
+	}
+	return pc, nil
+}
+
+// DiffProxyConfig returns the JSON names of the fields changed between two proxy configs.
+func DiffProxyConfig(prev, curr *meshconfig.ProxyConfig) []string {
+	if prev == nil || curr == nil {
+		return nil
+	}
+	var fields []string
+	ov, nv := reflect.ValueOf(prev).Elem(), reflect.ValueOf(curr).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		field := ov.Type().Field(i)
+		if strings.HasPrefix(field.Name, "XXX_") {
+			continue
+		}
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// RestartRequiredProxyConfigFields returns the fields changed between the proxy config a proxy was started with
+// and the current one which are not DynamicProxyConfigFields.
+func RestartRequiredProxyConfigFields(startup, current *meshconfig.ProxyConfig) []string {
+	var out []string
+	for _, f := range DiffProxyConfig(startup, current) {
+		if _, dynamic := DynamicProxyConfigFields[f]; !dynamic {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// ProxyConfigOverridesWatcher watches changes to the proxy config overrides.
+type ProxyConfigOverridesWatcher interface {
+	ProxyConfigOverrides() *ProxyConfigOverrides
+	AddProxyConfigOverridesHandler(func())
+}
+
+var _ ProxyConfigOverridesWatcher = &proxyConfigOverridesWatcher{}
+
+type proxyConfigOverridesWatcher struct {
+	mutex     sync.Mutex
+	handlers  []func()
+	overrides *ProxyConfigOverrides
+}
+
+// NewFixedProxyConfigOverridesWatcher creates a new ProxyConfigOverridesWatcher that always returns the given
+// overrides. It will never fire any events, since the overrides never change.
+func NewFixedProxyConfigOverridesWatcher(overrides *ProxyConfigOverrides) ProxyConfigOverridesWatcher {
+	return &proxyConfigOverridesWatcher{
+		overrides: overrides,
+	}
+}
+
+// NewProxyConfigOverridesWatcher creates a new watcher for changes to the given proxy config overrides file.
+func NewProxyConfigOverridesWatcher(fileWatcher filewatcher.FileWatcher, filename string) (ProxyConfigOverridesWatcher, error) {
+	overrides, err := ReadProxyConfigOverrides(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proxy config overrides from %q: %v", filename, err)
+	}
+	log.Infof("proxy config overrides: %d", len(overrides.Overrides))
+
+	w := &proxyConfigOverridesWatcher{
+		overrides: overrides,
+	}
+
+	addFileWatcher(fileWatcher, filename, func() {
+		overrides, err := ReadProxyConfigOverrides(filename)
+		if err != nil {
+			log.Warnf("failed to read proxy config overrides from %q, keeping the current ones: %v", filename, err)
+			return
+		}
+		w.setProxyConfigOverrides(overrides)
+	})
+	return w, nil
+}
+
+// ProxyConfigOverrides returns the latest proxy config overrides.
+func (w *proxyConfigOverridesWatcher) ProxyConfigOverrides() *ProxyConfigOverrides {
+	return (*ProxyConfigOverrides)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&w.overrides))))
+}
+
+func (w *proxyConfigOverridesWatcher) setProxyConfigOverrides(overrides *ProxyConfigOverrides) {
+	var handlers []func()
+
+	w.mutex.Lock()
+	if !reflect.DeepEqual(overrides, w.overrides) {
+		log.Infof("proxy config overrides updated: %d", len(overrides.Overrides))
+		atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&w.overrides)), unsafe.Pointer(overrides))
+		handlers = append([]func(){}, w.handlers...)
+	}
+	w.mutex.Unlock()
+
+	for _, h := range handlers {
+		h()
+	}
+}
+
+// AddProxyConfigOverridesHandler registers a callback handler for changes to the proxy config overrides.
+func (w *proxyConfigOverridesWatcher) AddProxyConfigOverridesHandler(h func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.handlers = append(w.handlers, h)
+}
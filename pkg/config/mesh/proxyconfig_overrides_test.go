@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+
+	"istio.io/istio/pkg/config/mesh"
+)
+
+const testOverrides = `
+overrides:
+- namespace: foo
+  selector:
+    app: bar
+  proxyConfig:
+    terminationDrainDuration: 30s
+- namespace: foo
+  proxyConfig:
+    terminationDrainDuration: 20s
+    concurrency: 4
+- selector:
+    app: baz
+  proxyConfig:
+    concurrency: 8
+`
+
+func TestEffectiveProxyConfig(t *testing.T) {
+	overrides, err := mesh.ParseProxyConfigOverrides(testOverrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := mesh.DefaultProxyConfig()
+
+	cases := []struct {
+		name        string
+		namespace   string
+		labels      map[string]string
+		drain       time.Duration
+		concurrency int32
+	}{
+		{"mesh default", "other", map[string]string{"app": "bar"}, 5 * time.Second, 2},
+		{"namespace", "foo", map[string]string{"app": "other"}, 20 * time.Second, 4},
+		{"workload over namespace", "foo", map[string]string{"app": "bar", "version": "v1"}, 30 * time.Second, 4},
+		{"workload in all namespaces", "other", map[string]string{"app": "baz"}, 5 * time.Second, 8},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			pc, err := overrides.EffectiveProxyConfig(&def, tt.namespace, tt.labels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			drain, _ := types.DurationFromProto(pc.TerminationDrainDuration)
+			if drain != tt.drain {
+				t.Errorf("got termination drain duration %v, want %v", drain, tt.drain)
+			}
+			if pc.Concurrency.GetValue() != tt.concurrency {
+				t.Errorf("got concurrency %v, want %v", pc.Concurrency.GetValue(), tt.concurrency)
+			}
+		})
+	}
+	if def.Concurrency.GetValue() != 2 {
+		t.Errorf("the default proxy config was modified: %v", def)
+	}
+}
+
+func TestParseProxyConfigOverridesInvalid(t *testing.T) {
+	for _, yml := range []string{
+		"overrides:\n- proxyConfig:\n    concurrency: 4",
+		"overrides:\n- namespace: foo",
+		"overrides:\n- namespace: foo\n  proxyConfig:\n    unknownField: 4",
+	} {
+		if _, err := mesh.ParseProxyConfigOverrides(yml); err == nil {
+			t.Errorf("expected an error parsing %q", yml)
+		}
+	}
+}
+
+func TestRestartRequiredProxyConfigFields(t *testing.T) {
+	startup := mesh.DefaultProxyConfig()
+	current := mesh.DefaultProxyConfig()
+	current.TerminationDrainDuration = types.DurationProto(time.Minute)
+	current.Concurrency = &types.Int32Value{Value: 4}
+
+	got := mesh.RestartRequiredProxyConfigFields(&startup, &current)
+	if want := []string{"concurrency"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -15,16 +15,22 @@
 package mesh
 
 import (
+	"fmt"
+	"io/ioutil"
 	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/hashicorp/go-multierror"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/pkg/filewatcher"
 	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
 )
 
 // Holder of a mesh configuration.
@@ -38,21 +44,60 @@ type Watcher interface {
 
 	// AddMeshHandler registers a callback handler for changes to the mesh config.
 	AddMeshHandler(func())
+
+	// Snapshot returns a consistent view of the mesh config and its reload state.
+	Snapshot() Snapshot
+}
+
+// Snapshot is a consistent view of a watched mesh config, taken at a single point in time.
+type Snapshot struct {
+	// Mesh is the current mesh config.
+	Mesh *meshconfig.MeshConfig
+	// Initial is the mesh config the watcher was created with, before any reload.
+	Initial *meshconfig.MeshConfig
+	// LastReload is when the mesh config was last changed by a reload, or zero if it never was.
+	LastReload time.Time
+}
+
+// Reloader is a Watcher whose mesh config can be reloaded on demand, e.g. on SIGHUP.
+type Reloader interface {
+	// Reload re-reads the mesh config, and applies it if it is valid and has changed.
+	Reload() error
 }
 
-var _ Watcher = &watcher{}
+var (
+	meshConfigReloadErrors = monitoring.NewSum(
+		"mesh_config_reload_errors",
+		"The number of reloads of the mesh config file which failed, keeping the previous mesh config.",
+	)
+
+	_ Watcher  = &watcher{}
+	_ Reloader = &watcher{}
+)
+
+func init() {
+	monitoring.MustRegister(meshConfigReloadErrors)
+}
 
 type watcher struct {
-	mutex    sync.Mutex
-	handlers []func()
-	mesh     *meshconfig.MeshConfig
+	mutex      sync.Mutex
+	handlers   []func()
+	mesh       *meshconfig.MeshConfig
+	initial    *meshconfig.MeshConfig
+	lastReload time.Time
+
+	// filename is the mesh config file, empty for fixed watchers.
+	filename string
+	// reloadMutex serializes the reloads triggered by the file watcher and by Reload.
+	reloadMutex sync.Mutex
 }
 
 // NewFixedWatcher creates a new Watcher that always returns the given mesh config. It will never
 // fire any events, since the config never changes.
 func NewFixedWatcher(mesh *meshconfig.MeshConfig) Watcher {
 	return &watcher{
-		mesh: mesh,
+		mesh:    mesh,
+		initial: mesh,
 	}
 }
 
@@ -65,40 +110,71 @@ func NewWatcher(fileWatcher filewatcher.FileWatcher, filename string) (Watcher,
 	}
 
 	w := &watcher{
-		mesh: meshConfig,
+		mesh:     meshConfig,
+		initial:  meshConfig,
+		filename: filename,
 	}
 
 	// Watch the config file for changes and reload if it got modified
 	addFileWatcher(fileWatcher, filename, func() {
-		// Reload the config file
-		meshConfig, err = ReadMeshConfig(filename)
-		if err != nil {
-			log.Warnf("failed to read mesh configuration, using default: %v", err)
-			return
+		if err := w.Reload(); err != nil {
+			log.Warnf("failed to reload mesh configuration, keeping the current one: %v", err)
 		}
+	})
+	return w, nil
+}
 
-		var handlers []func()
+// Reload re-reads the mesh config file, and applies it if it is valid and differs from the current mesh config.
+// If the file is empty or fails to parse, e.g. when read while being written, the current mesh config is kept.
+func (w *watcher) Reload() error {
+	if w.filename == "" {
+		// Fixed watcher, nothing to reload.
+		return nil
+	}
+	w.reloadMutex.Lock()
+	defer w.reloadMutex.Unlock()
 
-		w.mutex.Lock()
-		if !reflect.DeepEqual(meshConfig, w.mesh) {
-			log.Infof("mesh configuration updated to: %s", spew.Sdump(meshConfig))
-			if !reflect.DeepEqual(meshConfig.ConfigSources, w.mesh.ConfigSources) {
-				log.Infof("mesh configuration sources have changed")
-				//TODO Need to re-create or reload initConfigController()
-			}
+	meshConfig, err := readMeshConfigFile(w.filename)
+	if err != nil {
+		meshConfigReloadErrors.Increment()
+		return err
+	}
 
-			// Store the new mesh.
-			atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&w.mesh)), unsafe.Pointer(meshConfig))
-			handlers = append([]func(){}, w.handlers...)
-		}
-		w.mutex.Unlock()
+	var handlers []func()
 
-		// Notify the handlers of the change.
-		for _, h := range handlers {
-			h()
+	w.mutex.Lock()
+	if !reflect.DeepEqual(meshConfig, w.mesh) {
+		log.Infof("mesh configuration updated to: %s", spew.Sdump(meshConfig))
+		if !reflect.DeepEqual(meshConfig.ConfigSources, w.mesh.ConfigSources) {
+			log.Infof("mesh configuration sources have changed")
+			//TODO Need to re-create or reload initConfigController()
 		}
-	})
-	return w, nil
+
+		// Store the new mesh.
+		atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&w.mesh)), unsafe.Pointer(meshConfig))
+		w.lastReload = time.Now()
+		handlers = append([]func(){}, w.handlers...)
+	}
+	w.mutex.Unlock()
+
+	// Notify the handlers of the change.
+	for _, h := range handlers {
+		h()
+	}
+	return nil
+}
+
+// readMeshConfigFile reads the mesh config file, rejecting an empty file. Unlike an empty mesh config, which
+// is valid, an empty file is most likely read while being replaced.
+func readMeshConfigFile(filename string) (*meshconfig.MeshConfig, error) {
+	yaml, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, multierror.Prefix(err, "cannot read mesh config file")
+	}
+	if strings.TrimSpace(string(yaml)) == "" {
+		return nil, fmt.Errorf("mesh config file %s is empty", filename)
+	}
+	return ApplyMeshConfigDefaults(string(yaml))
 }
 
 // Mesh returns the latest mesh config.
@@ -106,6 +182,18 @@ func (w *watcher) Mesh() *meshconfig.MeshConfig {
 	return (*meshconfig.MeshConfig)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&w.mesh))))
 }
 
+// Snapshot returns the current mesh config along with the state of the last reload. Reloads are
+// applied under the same lock, so the returned fields are always consistent with each other.
+func (w *watcher) Snapshot() Snapshot {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return Snapshot{
+		Mesh:       w.mesh,
+		Initial:    w.initial,
+		LastReload: w.lastReload,
+	}
+}
+
 // AddMeshHandler registers a callback handler for changes to the mesh config.
 func (w *watcher) AddMeshHandler(h func()) {
 	w.mutex.Lock()
@@ -71,6 +71,81 @@ func TestWatcherShouldNotifyHandlers(t *testing.T) {
 	}
 }
 
+func TestWatcherShouldIgnorePartialWrites(t *testing.T) {
+	g := NewWithT(t)
+
+	path := newTempFile(t)
+	defer removeSilent(path)
+
+	m := mesh.DefaultMeshConfig()
+	m.IngressClass = "initial"
+	writeMessage(t, path, &m)
+
+	w := newWatcher(t, path)
+	updates := make(chan *meshconfig.MeshConfig, 10)
+	w.AddMeshHandler(func() {
+		updates <- w.Mesh()
+	})
+
+	// A ConfigMap update may be read while the file is empty, it must not revert the mesh config to the defaults.
+	writeFile(t, path, "")
+	select {
+	case got := <-updates:
+		t.Fatalf("unexpected update for an empty file: %v", got)
+	case <-time.After(time.Second):
+	}
+	g.Expect(w.Mesh()).To(Equal(&m))
+	g.Expect(w.(mesh.Reloader).Reload()).ToNot(Succeed())
+
+	m.IngressClass = "updated"
+	writeMessage(t, path, &m)
+	select {
+	case got := <-updates:
+		g.Expect(got).To(Equal(&m))
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for update")
+	}
+	select {
+	case got := <-updates:
+		t.Fatalf("unexpected second update: %v", got)
+	case <-time.After(time.Second):
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	g := NewWithT(t)
+
+	path := newTempFile(t)
+	defer removeSilent(path)
+
+	m := mesh.DefaultMeshConfig()
+	writeMessage(t, path, &m)
+
+	w := newWatcher(t, path)
+	updates := make(chan *meshconfig.MeshConfig, 10)
+	w.AddMeshHandler(func() {
+		updates <- w.Mesh()
+	})
+
+	// A forced reload applies the new content without waiting for the file events.
+	m.IngressClass = "foo"
+	writeMessage(t, path, &m)
+	g.Expect(w.(mesh.Reloader).Reload()).To(Succeed())
+	g.Expect(w.Mesh()).To(Equal(&m))
+
+	// The handlers are notified once, whichever of the reload or the file event applied the change.
+	g.Expect(<-updates).To(Equal(&m))
+	select {
+	case got := <-updates:
+		t.Fatalf("unexpected second update: %v", got)
+	case <-time.After(time.Second):
+	}
+
+	// Reloading an unchanged file doesn't notify the handlers.
+	g.Expect(w.(mesh.Reloader).Reload()).To(Succeed())
+	g.Expect(updates).To(BeEmpty())
+}
+
 func newWatcher(t testing.TB, filename string) mesh.Watcher {
 	t.Helper()
 	w, err := mesh.NewWatcher(filewatcher.NewWatcher(), filename)
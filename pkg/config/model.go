@@ -87,6 +87,10 @@ type Config struct {
 
 	// Spec holds the configuration object as a gogo protobuf message
 	Spec Spec
+
+	// Status holds the status of the configuration object, if its config source records one, e.g. the
+	// *v1alpha1.IstioStatus of the Istio custom resources.
+	Status Status
 }
 
 // Spec defines the spec for the config. In order to use below helper methods,
@@ -96,6 +100,9 @@ type Config struct {
 // * Able to marshal/unmarshal using json
 type Spec interface{}
 
+// Status defines the status of the config. It has the same requirements as the Spec.
+type Status interface{}
+
 func ToProtoGogo(s Spec) (*gogotypes.Any, error) {
 	// golang protobuf. Use protoreflect.ProtoMessage to distinguish from gogo
 	// golang/protobuf 1.4+ will have this interface. Older golang/protobuf are gogo compatible
@@ -283,6 +290,9 @@ func (c Config) DeepCopy() Config {
 		}
 	}
 	clone.Spec = DeepCopy(c.Spec)
+	if c.Status != nil {
+		clone.Status = DeepCopy(c.Status)
+	}
 	return clone
 }
 
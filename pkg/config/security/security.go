@@ -53,6 +53,10 @@ const (
 	attrExperimental     = "experimental.envoy.filters."
 )
 
+// DryRunAnnotation puts an AuthorizationPolicy in dry-run mode when set to "true": the policy is evaluated, and the
+// requests it would deny are counted, but it is not enforced. It is only supported for ALLOW and DENY policies.
+const DryRunAnnotation = "istio.io/dry-run"
+
 // ParseJwksURI parses the input URI and returns the corresponding hostname, port, and whether SSL is used.
 // URI must start with "http://" or "https://", which corresponding to "http" or "https" scheme.
 // Port number is extracted from URI if available (i.e from postfix :<port>, eg. ":80"), or assigned
@@ -178,3 +182,84 @@ func validateMapKey(key string) error {
 	}
 	return fmt.Errorf("bad key (%s): should have format a[b]", key)
 }
+
+const (
+	// TLSMinProtocolVersionAnnotation pins the minimum TLS version accepted by the inbound mTLS filter chains
+	// of the workloads a PeerAuthentication applies to, e.g. "TLSV1_2".
+	TLSMinProtocolVersionAnnotation = "security.istio.io/tlsMinProtocolVersion"
+	// TLSMaxProtocolVersionAnnotation pins the maximum TLS version accepted by the inbound mTLS filter chains
+	// of the workloads a PeerAuthentication applies to, e.g. "TLSV1_3".
+	TLSMaxProtocolVersionAnnotation = "security.istio.io/tlsMaxProtocolVersion"
+	// TLSCipherSuitesAnnotation is a comma separated list of the cipher suites accepted by the inbound mTLS
+	// filter chains of the workloads a PeerAuthentication applies to.
+	TLSCipherSuitesAnnotation = "security.istio.io/tlsCipherSuites"
+)
+
+// TLSProtocolVersions are the supported TLS protocol versions, in increasing order.
+var TLSProtocolVersions = []string{"TLSV1_0", "TLSV1_1", "TLSV1_2", "TLSV1_3"}
+
+// InboundTLSSettings are the TLS parameters of the inbound mTLS filter chains. Empty fields keep the
+// Envoy defaults.
+type InboundTLSSettings struct {
+	MinProtocolVersion string
+	MaxProtocolVersion string
+	CipherSuites       []string
+}
+
+// IsEmpty returns true if none of the settings is set.
+func (s InboundTLSSettings) IsEmpty() bool {
+	return s.MinProtocolVersion == "" && s.MaxProtocolVersion == "" && len(s.CipherSuites) == 0
+}
+
+// ParseInboundTLSSettings parses and validates the inbound TLS settings. The versions are one of
+// TLSProtocolVersions, and the cipher suites a comma separated list.
+func ParseInboundTLSSettings(minVersion, maxVersion, cipherSuites string) (InboundTLSSettings, error) {
+	settings := InboundTLSSettings{
+		MinProtocolVersion: strings.TrimSpace(minVersion),
+		MaxProtocolVersion: strings.TrimSpace(maxVersion),
+	}
+	var errs error
+	minIndex, maxIndex := -1, -1
+	if settings.MinProtocolVersion != "" {
+		if minIndex = tlsProtocolVersionIndex(settings.MinProtocolVersion); minIndex < 0 {
+			errs = multierror.Append(errs, fmt.Errorf("invalid minimum TLS version %q, must be one of %v",
+				settings.MinProtocolVersion, TLSProtocolVersions))
+		}
+	}
+	if settings.MaxProtocolVersion != "" {
+		if maxIndex = tlsProtocolVersionIndex(settings.MaxProtocolVersion); maxIndex < 0 {
+			errs = multierror.Append(errs, fmt.Errorf("invalid maximum TLS version %q, must be one of %v",
+				settings.MaxProtocolVersion, TLSProtocolVersions))
+		}
+	}
+	if minIndex >= 0 && maxIndex >= 0 && minIndex > maxIndex {
+		errs = multierror.Append(errs, fmt.Errorf("minimum TLS version %s is greater than the maximum TLS version %s",
+			settings.MinProtocolVersion, settings.MaxProtocolVersion))
+	}
+	if strings.TrimSpace(cipherSuites) != "" {
+		for _, cipher := range strings.Split(cipherSuites, ",") {
+			cipher = strings.TrimSpace(cipher)
+			if cipher == "" {
+				errs = multierror.Append(errs, fmt.Errorf("invalid cipher suites %q: empty cipher suite", cipherSuites))
+				continue
+			}
+			settings.CipherSuites = append(settings.CipherSuites, cipher)
+		}
+	}
+	return settings, errs
+}
+
+// ParseInboundTLSAnnotations parses and validates the inbound TLS settings annotations of a PeerAuthentication.
+func ParseInboundTLSAnnotations(annotations map[string]string) (InboundTLSSettings, error) {
+	return ParseInboundTLSSettings(annotations[TLSMinProtocolVersionAnnotation],
+		annotations[TLSMaxProtocolVersionAnnotation], annotations[TLSCipherSuitesAnnotation])
+}
+
+func tlsProtocolVersionIndex(version string) int {
+	for i, v := range TLSProtocolVersions {
+		if v == version {
+			return i
+		}
+	}
+	return -1
+}
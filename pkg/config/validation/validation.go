@@ -284,6 +284,8 @@ var ValidateGateway = registerValidateFunc("ValidateGateway",
 			return
 		}
 
+		errs = appendErrors(errs, validateExportTo(cfg.Namespace, gateway.ExportTo(cfg.Annotations), false))
+
 		if len(value.Servers) == 0 {
 			errs = appendErrors(errs, fmt.Errorf("gateway must have at least one server"))
 		} else {
@@ -1350,6 +1352,12 @@ var ValidateAuthorizationPolicy = registerValidateFunc("ValidateAuthorizationPol
 			return fmt.Errorf("a deny policy without `rules` is meaningless and has no effect, found in %s.%s", name, namespace)
 		}
 
+		if dryRun, _ := strconv.ParseBool(cfg.Annotations[security.DryRunAnnotation]); dryRun &&
+			in.Action != security_beta.AuthorizationPolicy_ALLOW && in.Action != security_beta.AuthorizationPolicy_DENY {
+			return fmt.Errorf("the %s annotation is only supported for ALLOW and DENY policies, found %s in %s.%s",
+				security.DryRunAnnotation, in.Action, name, namespace)
+		}
+
 		var errs error
 		for i, rule := range in.GetRules() {
 			if rule == nil {
@@ -1517,6 +1525,10 @@ var ValidatePeerAuthentication = registerValidateFunc("ValidatePeerAuthenticatio
 
 		errs = appendErrors(errs, validateWorkloadSelector(in.Selector))
 
+		if _, err := security.ParseInboundTLSAnnotations(cfg.Annotations); err != nil {
+			errs = appendErrors(errs, err)
+		}
+
 		return errs
 	})
 
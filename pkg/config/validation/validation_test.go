@@ -30,6 +30,8 @@ import (
 	api "istio.io/api/type/v1beta1"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/security"
 )
 
 const (
@@ -737,6 +739,43 @@ func TestValidateGateway(t *testing.T) {
 	}
 }
 
+func TestValidateGatewayExportTo(t *testing.T) {
+	gw := &networking.Gateway{
+		Servers: []*networking.Server{{
+			Hosts: []string{"foo.bar.com"},
+			Port:  &networking.Port{Name: "name1", Number: 7, Protocol: "http"},
+		}},
+	}
+	tests := []struct {
+		exportTo string
+		out      string
+	}{
+		{".,ns1", ""},
+		{"*", ""},
+		{"ns1,ns1", "duplicate entries"},
+		{"*,ns1", "cannot have both public"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.exportTo, func(t *testing.T) {
+			err := ValidateGateway(config.Config{
+				Meta: config.Meta{
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: map[string]string{gateway.ExportToAnnotation: tt.exportTo},
+				},
+				Spec: gw,
+			})
+			if err == nil && tt.out != "" {
+				t.Fatalf("ValidateGateway(%v) = nil, wanted %q", tt.exportTo, tt.out)
+			} else if err != nil && tt.out == "" {
+				t.Fatalf("ValidateGateway(%v) = %v, wanted nil", tt.exportTo, err)
+			} else if err != nil && !strings.Contains(err.Error(), tt.out) {
+				t.Fatalf("ValidateGateway(%v) = %v, wanted %q", tt.exportTo, err, tt.out)
+			}
+		})
+	}
+}
+
 func TestValidateServer(t *testing.T) {
 	tests := []struct {
 		name string
@@ -4007,6 +4046,37 @@ func TestValidateAuthorizationPolicy(t *testing.T) {
 	}
 }
 
+func TestValidateAuthorizationPolicyDryRun(t *testing.T) {
+	cases := []struct {
+		action security_beta.AuthorizationPolicy_Action
+		dryRun string
+		valid  bool
+	}{
+		{security_beta.AuthorizationPolicy_ALLOW, "true", true},
+		{security_beta.AuthorizationPolicy_DENY, "true", true},
+		{security_beta.AuthorizationPolicy_AUDIT, "true", false},
+		{security_beta.AuthorizationPolicy_AUDIT, "false", true},
+	}
+	for _, c := range cases {
+		t.Run(c.action.String()+"-"+c.dryRun, func(t *testing.T) {
+			cfg := config.Config{
+				Meta: config.Meta{
+					Name:        "policy",
+					Namespace:   "default",
+					Annotations: map[string]string{security.DryRunAnnotation: c.dryRun},
+				},
+				Spec: &security_beta.AuthorizationPolicy{
+					Action: c.action,
+					Rules:  []*security_beta.Rule{{}},
+				},
+			}
+			if got := ValidateAuthorizationPolicy(cfg); (got == nil) != c.valid {
+				t.Errorf("got: %v\nwant: %v", got, c.valid)
+			}
+		})
+	}
+}
+
 func TestValidateSidecar(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -4929,10 +4999,11 @@ func TestValidateRequestAuthentication(t *testing.T) {
 
 func TestValidatePeerAuthentication(t *testing.T) {
 	cases := []struct {
-		name       string
-		configName string
-		in         proto.Message
-		valid      bool
+		name        string
+		configName  string
+		in          proto.Message
+		annotations map[string]string
+		valid       bool
 	}{
 		{
 			name:       "empty spec",
@@ -5030,14 +5101,54 @@ func TestValidatePeerAuthentication(t *testing.T) {
 			},
 			valid: true,
 		},
+		{
+			name:       "inbound TLS settings",
+			configName: someName,
+			in:         &security_beta.PeerAuthentication{},
+			annotations: map[string]string{
+				"security.istio.io/tlsMinProtocolVersion": "TLSV1_2",
+				"security.istio.io/tlsMaxProtocolVersion": "TLSV1_3",
+				"security.istio.io/tlsCipherSuites":       "ECDHE-ECDSA-AES128-GCM-SHA256,ECDHE-RSA-AES128-GCM-SHA256",
+			},
+			valid: true,
+		},
+		{
+			name:       "inbound TLS min version greater than max version",
+			configName: someName,
+			in:         &security_beta.PeerAuthentication{},
+			annotations: map[string]string{
+				"security.istio.io/tlsMinProtocolVersion": "TLSV1_3",
+				"security.istio.io/tlsMaxProtocolVersion": "TLSV1_2",
+			},
+			valid: false,
+		},
+		{
+			name:       "invalid inbound TLS version",
+			configName: someName,
+			in:         &security_beta.PeerAuthentication{},
+			annotations: map[string]string{
+				"security.istio.io/tlsMinProtocolVersion": "TLSv1.2",
+			},
+			valid: false,
+		},
+		{
+			name:       "empty inbound TLS cipher suite",
+			configName: someName,
+			in:         &security_beta.PeerAuthentication{},
+			annotations: map[string]string{
+				"security.istio.io/tlsCipherSuites": "ECDHE-RSA-AES128-GCM-SHA256,,",
+			},
+			valid: false,
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			if got := ValidatePeerAuthentication(config.Config{
 				Meta: config.Meta{
-					Name:      c.configName,
-					Namespace: someNamespace,
+					Name:        c.configName,
+					Namespace:   someNamespace,
+					Annotations: c.annotations,
 				},
 				Spec: c.in,
 			}); (got == nil) != c.valid {
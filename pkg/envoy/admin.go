@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
@@ -48,6 +49,30 @@ func DrainListeners(adminPort uint32, inboundonly bool) error {
 	return err
 }
 
+// GetActiveDownstreamConnections returns the number of active downstream connections across the listeners of
+// Envoy, excluding the admin listener.
+func GetActiveDownstreamConnections(adminPort uint32) (int, error) {
+	buffer, err := doEnvoyGet("stats?usedonly&filter=downstream_cx_active$", adminPort)
+	if err != nil {
+		return 0, err
+	}
+	active := 0
+	for _, line := range strings.Split(buffer.String(), "\n") {
+		// Lines are of the form listener.0.0.0.0_15006.downstream_cx_active: 1
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "listener.") || strings.HasPrefix(parts[0], "listener.admin.") ||
+			!strings.HasSuffix(parts[0], ".downstream_cx_active") {
+			continue
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return 0, fmt.Errorf("invalid value for stat %s: %v", parts[0], err)
+		}
+		active += v
+	}
+	return active, nil
+}
+
 // GetServerInfo returns a structure representing a call to /server_info
 func GetServerInfo(adminPort uint32) (*envoyAdmin.ServerInfo, error) {
 	buffer, err := doEnvoyGet("server_info", adminPort)
@@ -17,7 +17,10 @@ package envoy
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -56,6 +59,11 @@ import (
 // scheduled configuration updates, exits from older proxy epochs, and retry
 // attempt timers. The call to schedule a configuration update will block until
 // the control loop is ready to accept and process the configuration update.
+//
+// With a RestartPolicy, the agent restarts the proxy when all epochs exited
+// with an error instead of terminating. If the proxy exits too often, the agent
+// considers it crash looping and stops restarting it until ResumeRestarts is
+// called.
 type Agent interface {
 	// Run starts the agent control loop and awaits for a signal on the input
 	// channel to exit the loop.
@@ -63,20 +71,67 @@ type Agent interface {
 
 	// Restart triggers a hot restart of envoy, applying the given config to the new process
 	Restart(config interface{})
+
+	// CrashLoopError returns an error describing the crash loop if the agent stopped restarting the proxy, nil otherwise.
+	CrashLoopError() error
+
+	// ResumeRestarts restarts a crash looping proxy, resetting the restarts counted by the RestartPolicy.
+	ResumeRestarts()
 }
 
 var errAbort = errors.New("epoch aborted")
 
 const errOutOfMemory = "signal: killed"
 
+// defaultDrainPollInterval is how often Envoy is polled for active connections when draining with ExitOnIdle.
+const defaultDrainPollInterval = time.Second
+
+// DrainPolicy controls how long the agent lets the proxy drain on termination before terminating it.
+type DrainPolicy struct {
+	// Duration returns the maximum time to drain for. It is called when draining starts, so changes to
+	// the configured duration after the agent started are honored.
+	Duration func() time.Duration
+
+	// ExitOnIdle ends draining early, once Envoy reports no active downstream connections on its listeners.
+	ExitOnIdle bool
+	// AdminPort is the Envoy admin port polled for active connections when ExitOnIdle is set.
+	AdminPort uint32
+
+	// pollInterval overrides defaultDrainPollInterval in tests.
+	pollInterval time.Duration
+}
+
+// NewFixedDrainPolicy returns a DrainPolicy always draining for the given duration.
+func NewFixedDrainPolicy(duration time.Duration) DrainPolicy {
+	return DrainPolicy{
+		Duration: func() time.Duration {
+			return duration
+		},
+	}
+}
+
+// RestartPolicy controls whether the agent restarts the proxy when it exits with an error, and when it gives up.
+type RestartPolicy struct {
+	// MaxRestarts is the number of restarts allowed within Window. Once exceeded the proxy is considered crash
+	// looping and is no longer restarted. Restarts are disabled when zero: the agent terminates when the proxy exits.
+	MaxRestarts int
+	Window      time.Duration
+
+	// DiagnosticsPath is the file the last lines of the proxy standard error are written to when it is crash looping,
+	// if set and the proxy keeps them.
+	DiagnosticsPath string
+}
+
 // NewAgent creates a new proxy agent for the proxy start-up and clean-up functions.
-func NewAgent(proxy Proxy, terminationDrainDuration time.Duration) Agent {
+func NewAgent(proxy Proxy, drainPolicy DrainPolicy, restartPolicy RestartPolicy) Agent {
 	return &agent{
-		proxy:                    proxy,
-		statusCh:                 make(chan exitStatus),
-		activeEpochs:             map[int]chan error{},
-		terminationDrainDuration: terminationDrainDuration,
-		currentEpoch:             -1,
+		proxy:         proxy,
+		statusCh:      make(chan exitStatus),
+		resumeCh:      make(chan struct{}, 1),
+		activeEpochs:  map[int]chan error{},
+		drainPolicy:   drainPolicy,
+		restartPolicy: restartPolicy,
+		currentEpoch:  -1,
 	}
 }
 
@@ -95,6 +150,11 @@ type Proxy interface {
 	Cleanup(int)
 }
 
+// stderrTailer is implemented by proxies keeping the last lines of their standard error, for diagnostics.
+type stderrTailer interface {
+	StderrTail() []string
+}
+
 type agent struct {
 	// proxy commands
 	proxy Proxy
@@ -112,8 +172,20 @@ type agent struct {
 	// channel for proxy exit notifications
 	statusCh chan exitStatus
 
-	// time to allow for the proxy to drain before terminating all remaining proxy processes
-	terminationDrainDuration time.Duration
+	// policy for draining the proxy before terminating all remaining proxy processes
+	drainPolicy DrainPolicy
+
+	// policy for restarting the proxy when it exits with an error
+	restartPolicy RestartPolicy
+
+	// restarts are the times of the restarts within the window of the restart policy. Only used by the control loop.
+	restarts []time.Time
+
+	// crashLoopErr is set while the proxy is crash looping and not restarted.
+	crashLoopErr error
+
+	// channel for requests to resume restarting a crash looping proxy
+	resumeCh chan struct{}
 }
 
 type exitStatus struct {
@@ -138,12 +210,18 @@ func (a *agent) Restart(config interface{}) {
 	hasActiveEpoch := len(a.activeEpochs) > 0
 	activeEpoch := a.currentEpoch
 
-	// Increment the latest running epoch
+	// Increment the latest running epoch. Without a running epoch, e.g. after a crash loop, there is no parent to
+	// hot restart from and the proxy starts over from epoch 0.
 	epoch := a.currentEpoch + 1
+	if !hasActiveEpoch {
+		epoch = 0
+	}
 	log.Infof("Received new config, creating new Envoy epoch %d", epoch)
 
 	a.currentEpoch = epoch
 	a.currentConfig = config
+	// A new configuration may fix a crash looping proxy, so it is started regardless.
+	a.crashLoopErr = nil
 
 	// Add the new epoch to the map.
 	abortCh := make(chan error, 1)
@@ -227,6 +305,11 @@ func (a *agent) Run(ctx context.Context) error {
 			active := len(a.activeEpochs)
 			a.mutex.Unlock()
 
+			if active == 0 && status.err != nil && a.restartPolicy.MaxRestarts > 0 {
+				a.restartOrGiveUp(status.err)
+				continue
+			}
+
 			if active == 0 {
 				log.Infof("No more active epochs, terminating")
 				return nil
@@ -234,6 +317,17 @@ func (a *agent) Run(ctx context.Context) error {
 
 			log.Infof("%d active epochs running", active)
 
+		case <-a.resumeCh:
+			if a.CrashLoopError() == nil {
+				continue
+			}
+			log.Infof("Resuming restarts of the crash looping proxy")
+			a.mutex.Lock()
+			a.crashLoopErr = nil
+			a.mutex.Unlock()
+			a.restarts = nil
+			a.restartCurrentConfig()
+
 		case <-ctx.Done():
 			a.terminate()
 			log.Info("Agent has successfully terminated")
@@ -248,12 +342,135 @@ func (a *agent) terminate() {
 	if e != nil {
 		log.Warnf("Error in invoking drain listeners endpoint %v", e)
 	}
-	log.Infof("Graceful termination period is %v, starting...", a.terminationDrainDuration)
-	time.Sleep(a.terminationDrainDuration)
+	var duration time.Duration
+	if a.drainPolicy.Duration != nil {
+		duration = a.drainPolicy.Duration()
+	}
+	log.Infof("Graceful termination period is %v, starting...", duration)
+	if a.drainPolicy.ExitOnIdle {
+		a.waitForIdle(duration)
+	} else {
+		time.Sleep(duration)
+	}
 	log.Infof("Graceful termination period complete, terminating remaining proxies.")
 	a.abortAll()
 }
 
+// waitForIdle waits until Envoy reports no active downstream connections, for at most maxDuration.
+func (a *agent) waitForIdle(maxDuration time.Duration) {
+	interval := a.drainPolicy.pollInterval
+	if interval == 0 {
+		interval = defaultDrainPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	timer := time.NewTimer(maxDuration)
+	defer func() {
+		ticker.Stop()
+		timer.Stop()
+	}()
+
+	for {
+		select {
+		case <-timer.C:
+			log.Infof("Graceful termination period elapsed with active connections remaining")
+			return
+		case <-ticker.C:
+			active, err := GetActiveDownstreamConnections(a.drainPolicy.AdminPort)
+			if err != nil {
+				log.Warnf("Failed to get active downstream connections: %v", err)
+				continue
+			}
+			if active == 0 {
+				log.Infof("No active downstream connections remaining, ending graceful termination period early")
+				return
+			}
+			log.Debugf("%d active downstream connections remaining", active)
+		}
+	}
+}
+
+// restartOrGiveUp restarts the proxy after all the epochs exited, unless it restarted more than allowed by the
+// restart policy within its window, in which case the proxy is considered crash looping.
+func (a *agent) restartOrGiveUp(exitErr error) {
+	now := time.Now()
+	kept := a.restarts[:0]
+	for _, t := range a.restarts {
+		if now.Sub(t) < a.restartPolicy.Window {
+			kept = append(kept, t)
+		}
+	}
+	a.restarts = kept
+
+	if len(a.restarts) >= a.restartPolicy.MaxRestarts {
+		err := fmt.Errorf("proxy restarted %d times within %v, last exit: %v", len(a.restarts), a.restartPolicy.Window, exitErr)
+		a.mutex.Lock()
+		a.crashLoopErr = err
+		a.mutex.Unlock()
+		proxyCrashLoops.Increment()
+		log.Errorf("Proxy is crash looping, no longer restarting it: %v", err)
+		a.writeDiagnostics(err)
+		return
+	}
+
+	a.restarts = append(a.restarts, now)
+	proxyRestarts.Increment()
+	log.Warnf("All epochs exited, restarting the proxy (%d/%d restarts within %v)",
+		len(a.restarts), a.restartPolicy.MaxRestarts, a.restartPolicy.Window)
+	a.restartCurrentConfig()
+}
+
+// restartCurrentConfig starts the proxy again with the current configuration, after all the epochs exited.
+// As there is no parent process to hot restart from, the restart epoch goes back to 0.
+func (a *agent) restartCurrentConfig() {
+	a.mutex.Lock()
+	if len(a.activeEpochs) > 0 {
+		// A new configuration was applied concurrently, which already started the proxy.
+		a.mutex.Unlock()
+		return
+	}
+	epoch := 0
+	a.currentEpoch = epoch
+	config := a.currentConfig
+	abortCh := make(chan error, 1)
+	a.activeEpochs[epoch] = abortCh
+	a.mutex.Unlock()
+
+	go a.runWait(config, epoch, abortCh)
+}
+
+// writeDiagnostics writes the crash loop error and the last lines of the proxy standard error to the diagnostics file.
+func (a *agent) writeDiagnostics(crashLoopErr error) {
+	tailer, ok := a.proxy.(stderrTailer)
+	if a.restartPolicy.DiagnosticsPath == "" || !ok {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %v\n", time.Now().Format(time.RFC3339), crashLoopErr)
+	for _, line := range tailer.StderrTail() {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if err := ioutil.WriteFile(a.restartPolicy.DiagnosticsPath, []byte(b.String()), 0644); err != nil {
+		log.Warnf("Failed to write proxy crash loop diagnostics to %s: %v", a.restartPolicy.DiagnosticsPath, err)
+		return
+	}
+	log.Infof("Wrote proxy crash loop diagnostics to %s", a.restartPolicy.DiagnosticsPath)
+}
+
+func (a *agent) CrashLoopError() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.crashLoopErr
+}
+
+func (a *agent) ResumeRestarts() {
+	select {
+	case a.resumeCh <- struct{}{}:
+	default:
+		// A resume is already pending.
+	}
+}
+
 // runWait runs the start-up command as a go routine and waits for it to finish
 func (a *agent) runWait(config interface{}, epoch int, abortCh <-chan error) {
 	log.Infof("Epoch %d starting", epoch)
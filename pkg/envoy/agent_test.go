@@ -17,12 +17,22 @@ package envoy
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	. "github.com/onsi/gomega"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
 )
 
 // TestProxy sample struct for proxy
@@ -62,7 +72,7 @@ func (tp TestProxy) Cleanup(epoch int) {
 func TestStartExit(t *testing.T) {
 	ctx := context.Background()
 	done := make(chan struct{})
-	a := NewAgent(TestProxy{}, 0)
+	a := NewAgent(TestProxy{}, NewFixedDrainPolicy(0), RestartPolicy{})
 	go func() {
 		_ = a.Run(ctx)
 		done <- struct{}{}
@@ -99,7 +109,7 @@ func TestStartDrain(t *testing.T) {
 		}
 		return nil
 	}
-	a := NewAgent(TestProxy{run: start, blockChannel: blockChan}, -10*time.Second)
+	a := NewAgent(TestProxy{run: start, blockChannel: blockChan}, NewFixedDrainPolicy(-10*time.Second), RestartPolicy{})
 	go func() { _ = a.Run(ctx) }()
 	a.Restart(startConfig)
 	<-blockChan
@@ -149,7 +159,7 @@ func TestWaitForLive(t *testing.T) {
 	isLive := func() bool {
 		return atomic.LoadUint32(&live) > 0
 	}
-	a := NewAgent(TestProxy{run: start, live: isLive}, -10*time.Second)
+	a := NewAgent(TestProxy{run: start, live: isLive}, NewFixedDrainPolicy(-10*time.Second), RestartPolicy{})
 	go func() { _ = a.Run(ctx) }()
 
 	// Start the first epoch.
@@ -192,7 +202,7 @@ func TestExitDuringWaitForLive(t *testing.T) {
 		// Never go live.
 		return false
 	}
-	a := NewAgent(TestProxy{run: start, live: neverLive}, -10*time.Second)
+	a := NewAgent(TestProxy{run: start, live: neverLive}, NewFixedDrainPolicy(-10*time.Second), RestartPolicy{})
 	go func() { _ = a.Run(ctx) }()
 
 	// Start the first epoch.
@@ -234,7 +244,7 @@ func TestApplyTwice(t *testing.T) {
 		<-ctx.Done()
 		return nil
 	}
-	a := NewAgent(TestProxy{run: start}, -10*time.Second)
+	a := NewAgent(TestProxy{run: start}, NewFixedDrainPolicy(-10*time.Second), RestartPolicy{})
 	go func() { _ = a.Run(ctx) }()
 	a.Restart(desired)
 	applyCount++
@@ -290,7 +300,7 @@ func TestStartTwiceStop(t *testing.T) {
 			cancel()
 		}
 	}
-	a := NewAgent(TestProxy{run: start, cleanup: cleanup}, 0)
+	a := NewAgent(TestProxy{run: start, cleanup: cleanup}, NewFixedDrainPolicy(0), RestartPolicy{})
 	go func() { _ = a.Run(ctx) }()
 	a.Restart(desired0)
 	a.Restart(desired1)
@@ -314,7 +324,7 @@ func TestRecovery(t *testing.T) {
 		<-ctx.Done()
 		return nil
 	}
-	a := NewAgent(TestProxy{run: start}, 0)
+	a := NewAgent(TestProxy{run: start}, NewFixedDrainPolicy(0), RestartPolicy{})
 	go func() { _ = a.Run(ctx) }()
 	a.Restart(desired)
 
@@ -322,3 +332,155 @@ func TestRecovery(t *testing.T) {
 	<-time.After(100 * time.Millisecond)
 	cancel()
 }
+
+// fakeAdmin serves the Envoy admin /stats endpoint, reporting active connections on a listener and
+// on the admin listener, which must be ignored.
+func fakeAdmin(t *testing.T, active *int32) uint32 {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stats" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "http.inbound_0.0.0.0_8080.downstream_cx_active: 5\n"+
+			"listener.0.0.0.0_15006.downstream_cx_active: %d\n"+
+			"listener.admin.downstream_cx_active: 1\n", atomic.LoadInt32(active))
+	}))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uint32(port)
+}
+
+func TestDrainPolicy(t *testing.T) {
+	cases := []struct {
+		name string
+		// idleAfter is when the fake Envoy stops reporting active connections, never if zero.
+		idleAfter   time.Duration
+		maxDuration time.Duration
+		minElapsed  time.Duration
+		maxElapsed  time.Duration
+	}{
+		{
+			name:        "exit when idle",
+			idleAfter:   200 * time.Millisecond,
+			maxDuration: 10 * time.Second,
+			minElapsed:  200 * time.Millisecond,
+			maxElapsed:  5 * time.Second,
+		},
+		{
+			name:        "capped by max duration",
+			maxDuration: 500 * time.Millisecond,
+			minElapsed:  500 * time.Millisecond,
+			maxElapsed:  5 * time.Second,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			active := int32(2)
+			adminPort := fakeAdmin(t, &active)
+			if tt.idleAfter > 0 {
+				time.AfterFunc(tt.idleAfter, func() {
+					atomic.StoreInt32(&active, 0)
+				})
+			}
+
+			// The drain duration is read when draining starts, not when the agent is created.
+			duration := time.Duration(0)
+			a := NewAgent(TestProxy{blockChannel: make(chan interface{}, 1)}, DrainPolicy{
+				Duration: func() time.Duration {
+					return duration
+				},
+				ExitOnIdle:   true,
+				AdminPort:    adminPort,
+				pollInterval: 50 * time.Millisecond,
+			}, RestartPolicy{}).(*agent)
+			duration = tt.maxDuration
+			abortCh := make(chan error, 1)
+			a.activeEpochs[0] = abortCh
+
+			start := time.Now()
+			a.terminate()
+			elapsed := time.Since(start)
+			if elapsed < tt.minElapsed || elapsed > tt.maxElapsed {
+				t.Fatalf("expected drain to take between %v and %v, took %v", tt.minElapsed, tt.maxElapsed, elapsed)
+			}
+			select {
+			case err := <-abortCh:
+				if err != errAbort {
+					t.Fatalf("unexpected abort error %v", err)
+				}
+			default:
+				t.Fatal("expected the epoch to be aborted after draining")
+			}
+		})
+	}
+}
+
+func TestGetActiveDownstreamConnections(t *testing.T) {
+	active := int32(3)
+	got, err := GetActiveDownstreamConnections(fakeAdmin(t, &active))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 3 {
+		t.Fatalf("expected 3 active connections, got %d", got)
+	}
+}
+
+// TestCrashLoop runs a fake proxy binary which exits immediately, and checks the agent stops restarting it once
+// crash looping, writes the diagnostics, and resumes restarting it on demand.
+func TestCrashLoop(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+	diagnostics := filepath.Join(dir, "crashloop.log")
+	proxy := NewProxy(ProxyConfig{
+		Config: meshconfig.ProxyConfig{
+			BinaryPath:       filepath.Join("testdata", "crash.sh"),
+			CustomConfigFile: filepath.Join("testdata", "bootstrap.json"),
+			ConfigPath:       dir,
+		},
+	})
+	a := NewAgent(proxy, NewFixedDrainPolicy(0), RestartPolicy{
+		MaxRestarts:     3,
+		Window:          time.Minute,
+		DiagnosticsPath: diagnostics,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = a.Run(ctx)
+		close(done)
+	}()
+
+	a.Restart("config")
+	g.Eventually(a.CrashLoopError, 10*time.Second).ShouldNot(BeNil())
+	g.Expect(a.CrashLoopError().Error()).To(ContainSubstring("restarted 3 times"))
+	g.Eventually(func() (string, error) {
+		b, err := ioutil.ReadFile(diagnostics)
+		return string(b), err
+	}).Should(ContainSubstring("crashing epoch with args"))
+	// The initial start and the 3 restarts all crashed.
+	g.Expect(proxy.(stderrTailer).StderrTail()).To(HaveLen(4))
+
+	// The agent keeps running while the proxy is crash looping.
+	g.Consistently(done, 200*time.Millisecond).ShouldNot(BeClosed())
+
+	g.Expect(os.Remove(diagnostics)).To(Succeed())
+	a.ResumeRestarts()
+	g.Eventually(func() error {
+		_, err := os.Stat(diagnostics)
+		return err
+	}, 10*time.Second).Should(Succeed())
+	g.Expect(a.CrashLoopError()).NotTo(BeNil())
+	g.Expect(proxy.(stderrTailer).StderrTail()).To(HaveLen(8))
+
+	cancel()
+	g.Eventually(done).Should(BeClosed())
+}
@@ -0,0 +1,36 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import "istio.io/pkg/monitoring"
+
+var (
+	proxyRestarts = monitoring.NewSum(
+		"envoy_restarts_total",
+		"Total number of restarts of the proxy after it exited with an error.",
+	)
+
+	proxyCrashLoops = monitoring.NewSum(
+		"envoy_crash_loops_total",
+		"Total number of times the agent stopped restarting the proxy because it was crash looping.",
+	)
+)
+
+func init() {
+	monitoring.MustRegister(
+		proxyRestarts,
+		proxyCrashLoops,
+	)
+}
@@ -15,13 +15,15 @@
 package envoy
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"net"
 	"os"
 	"os/exec"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
@@ -36,11 +38,15 @@ import (
 const (
 	// epochFileTemplate is a template for the root config JSON
 	epochFileTemplate = "envoy-rev%d.json"
+
+	// stderrTailLines is the number of lines of the Envoy standard error kept for crash loop diagnostics.
+	stderrTailLines = 100
 )
 
 type envoy struct {
 	ProxyConfig
 	extraArgs []string
+	stderr    *lineTail
 }
 
 type ProxyConfig struct {
@@ -57,6 +63,12 @@ type ProxyConfig struct {
 	Sidecar             bool
 	ProxyViaAgent       bool
 	CallCredentials     bool
+	IPFamily            bootstrap.IPFamily
+	LoadStatsReporting  bool
+	StatusPort          int
+	ReadinessPath       string
+	// GatewayTopologyAnnotated is set if the proxy.istio.io/config annotation of the pod sets the gateway topology.
+	GatewayTopologyAnnotated bool
 }
 
 // NewProxy creates an instance of the proxy control commands
@@ -73,6 +85,7 @@ func NewProxy(cfg ProxyConfig) Proxy {
 	return &envoy{
 		ProxyConfig: cfg,
 		extraArgs:   args,
+		stderr:      newLineTail(stderrTailLines),
 	}
 }
 
@@ -105,7 +118,7 @@ func (e *envoy) Drain() error {
 
 func (e *envoy) args(fname string, epoch int, bootstrapConfig string) []string {
 	proxyLocalAddressType := "v4"
-	if isIPv6Proxy(e.NodeIPs) {
+	if e.IPFamily.IsIPv6(e.NodeIPs) {
 		proxyLocalAddressType = "v6"
 	}
 	startupArgs := []string{"-c", fname,
@@ -165,6 +178,12 @@ func (e *envoy) Run(config interface{}, epoch int, abort <-chan error) error {
 			ProvCert:            e.ProvCert,
 			CallCredentials:     e.CallCredentials,
 			DiscoveryHost:       discHost,
+			IPFamily:            e.IPFamily,
+			LoadStatsReporting:  e.LoadStatsReporting,
+			StatusPort:          e.StatusPort,
+			ReadinessPath:       e.ReadinessPath,
+			// Reported in the node metadata.
+			GatewayTopologyAnnotated: e.GatewayTopologyAnnotated,
 		}).CreateFileForEpoch(epoch)
 		if err != nil {
 			log.Errora("Failed to generate bootstrap config: ", err)
@@ -180,7 +199,7 @@ func (e *envoy) Run(config interface{}, epoch int, abort <-chan error) error {
 	/* #nosec */
 	cmd := exec.Command(e.Config.BinaryPath, args...)
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = io.MultiWriter(os.Stderr, e.stderr)
 	if err := cmd.Start(); err != nil {
 		return err
 	}
@@ -201,6 +220,11 @@ func (e *envoy) Run(config interface{}, epoch int, abort <-chan error) error {
 	}
 }
 
+// StderrTail returns the last lines written by Envoy to its standard error, across epochs.
+func (e *envoy) StderrTail() []string {
+	return e.stderr.Lines()
+}
+
 func (e *envoy) Cleanup(epoch int) {
 	// should return when use the parameter "--templateFile=/path/xxx.tmpl".
 	if e.Config.CustomConfigFile != "" {
@@ -228,19 +252,44 @@ func configFile(config string, epoch int) string {
 	return path.Join(config, fmt.Sprintf(epochFileTemplate, epoch))
 }
 
-// isIPv6Proxy check the addresses slice and returns true for a valid IPv6 address
-// for all other cases it returns false
-func isIPv6Proxy(ipAddrs []string) bool {
-	for i := 0; i < len(ipAddrs); i++ {
-		addr := net.ParseIP(ipAddrs[i])
-		if addr == nil {
-			// Should not happen, invalid IP in proxy's IPAddresses slice should have been caught earlier,
-			// skip it to prevent a panic.
-			continue
-		}
-		if addr.To4() != nil {
-			return false
+// lineTail is a writer keeping the last lines written to it.
+type lineTail struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func newLineTail(max int) *lineTail {
+	return &lineTail{max: max}
+}
+
+func (t *lineTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data := append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
 		}
+		t.lines = append(t.lines, string(data[:i]))
+		data = data[i+1:]
+	}
+	if len(t.lines) > t.max {
+		t.lines = append(t.lines[:0], t.lines[len(t.lines)-t.max:]...)
+	}
+	t.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// Lines returns the last complete lines, followed by the incomplete one if any.
+func (t *lineTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := append([]string(nil), t.lines...)
+	if len(t.partial) > 0 {
+		out = append(out, string(t.partial))
 	}
-	return true
+	return out
 }
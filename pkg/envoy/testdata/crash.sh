@@ -0,0 +1,4 @@
+#!/bin/sh
+# Fake proxy binary exiting immediately with an error, to drive the crash loop detection of the agent.
+echo "crashing epoch with args: $*" >&2
+exit 1
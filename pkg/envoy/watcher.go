@@ -17,10 +17,20 @@ package envoy
 import (
 	"context"
 	"crypto/sha256"
+	"hash"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
 
+	"istio.io/pkg/filewatcher"
 	"istio.io/pkg/log"
 )
 
+// defaultCertsDebounce is the minimum delay between two restarts caused by changes to the watched certs,
+// so that a flapping file does not restart the proxy repeatedly.
+const defaultCertsDebounce = 10 * time.Second
+
 // Watcher triggers reloads on changes to the proxy config
 type Watcher interface {
 	// Run the watcher loop (blocking call)
@@ -28,13 +38,20 @@ type Watcher interface {
 }
 
 type watcher struct {
-	updates func(interface{})
+	// certs are the files referenced by the proxy bootstrap, which are only read by the proxy
+	// at startup. Changes to their content restart the proxy.
+	certs    []string
+	updates  func(interface{})
+	debounce time.Duration
 }
 
-// NewWatcher creates a new watcher instance from a proxy agent
-func NewWatcher(updates func(interface{})) Watcher {
+// NewWatcher creates a new watcher instance from a proxy agent. The proxy is restarted when the content
+// of any of the certs files changes.
+func NewWatcher(certs []string, updates func(interface{})) Watcher {
 	return &watcher{
-		updates: updates,
+		certs:    certs,
+		updates:  updates,
+		debounce: defaultCertsDebounce,
 	}
 }
 
@@ -42,11 +59,90 @@ func (w *watcher) Run(ctx context.Context) {
 	// kick start the proxy with partial state (in case there are no notifications coming)
 	w.SendConfig()
 
+	if len(w.certs) > 0 {
+		w.watchCerts(ctx)
+	}
+
 	<-ctx.Done()
 	log.Info("Watcher has successfully terminated")
 }
 
+// watchCerts sends the config again when the certs change, at most once per debounce interval.
+func (w *watcher) watchCerts(ctx context.Context) {
+	fw := filewatcher.NewWatcher()
+	defer func() { _ = fw.Close() }()
+
+	// The forwarding goroutines return with the context, and are waited for before the file watcher is closed.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	changes := make(chan string, len(w.certs))
+	for _, cert := range w.certs {
+		if err := fw.Add(cert); err != nil {
+			log.Warnf("Failed to watch %s, the proxy will not be restarted when it changes: %v", cert, err)
+			continue
+		}
+		cert, events := cert, fw.Events(cert)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case _, ok := <-events:
+					if !ok {
+						return
+					}
+					// The loop reading the changes returns once the context is done.
+					select {
+					case changes <- cert:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	var timerC <-chan time.Time
+	changed := map[string]struct{}{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case cert := <-changes:
+			changed[cert] = struct{}{}
+			if timerC == nil {
+				timerC = time.After(w.debounce)
+			}
+		case <-timerC:
+			timerC = nil
+			files := make([]string, 0, len(changed))
+			for cert := range changed {
+				files = append(files, cert)
+			}
+			sort.Strings(files)
+			changed = map[string]struct{}{}
+			log.Infof("Certificates referenced by the proxy bootstrap changed (%v), restarting the proxy to load them", files)
+			w.SendConfig()
+		}
+	}
+}
+
 func (w *watcher) SendConfig() {
 	h := sha256.New()
+	generateCertHash(h, w.certs)
 	w.updates(h.Sum(nil))
 }
+
+// generateCertHash writes the content of the certs files to the hash, skipping missing files.
+func generateCertHash(h hash.Hash, certs []string) {
+	for _, cert := range certs {
+		b, err := ioutil.ReadFile(cert)
+		if err != nil {
+			continue
+		}
+		h.Write(b)
+	}
+}
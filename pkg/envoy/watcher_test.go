@@ -16,6 +16,8 @@ package envoy
 
 import (
 	"context"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -36,7 +38,7 @@ func TestRunSendConfig(t *testing.T) {
 	agent := &TestAgent{
 		configCh: make(chan interface{}),
 	}
-	watcher := NewWatcher(agent.Restart)
+	watcher := NewWatcher(nil, agent.Restart)
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// watcher starts agent and schedules a config update
@@ -51,3 +53,83 @@ func TestRunSendConfig(t *testing.T) {
 		cancel()
 	}
 }
+
+func TestRestartOnCertChange(t *testing.T) {
+	cert := filepath.Join(t.TempDir(), "root-cert.pem")
+	writeCert := func(content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(cert, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeCert("initial")
+
+	agent := &TestAgent{
+		configCh: make(chan interface{}, 10),
+	}
+	w := &watcher{
+		certs:    []string{cert},
+		updates:  agent.Restart,
+		debounce: 500 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	select {
+	case <-agent.configCh:
+	case <-time.After(time.Second):
+		t.Fatal("expected initial config")
+	}
+	// Give the file watcher time to start.
+	time.Sleep(100 * time.Millisecond)
+
+	// Multiple changes within the debounce window restart the proxy once.
+	for _, content := range []string{"rotated-1", "rotated-2", "rotated-3"} {
+		writeCert(content)
+		time.Sleep(50 * time.Millisecond)
+	}
+	select {
+	case <-agent.configCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected restart after the cert changed")
+	}
+	select {
+	case c := <-agent.configCh:
+		t.Fatalf("expected a single restart per debounce window, got another config %v", c)
+	case <-time.After(time.Second):
+	}
+}
+
+func TestWatchCertsStopsWithContext(t *testing.T) {
+	cert := filepath.Join(t.TempDir(), "root-cert.pem")
+	if err := ioutil.WriteFile(cert, []byte("initial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := &watcher{
+		certs:    []string{cert},
+		updates:  func(interface{}) {},
+		debounce: time.Hour,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.watchCerts(ctx)
+		close(done)
+	}()
+	// Give the file watcher time to start, and queue more changes than are buffered.
+	time.Sleep(100 * time.Millisecond)
+	for _, content := range []string{"rotated-1", "rotated-2", "rotated-3"} {
+		if err := ioutil.WriteFile(cert, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+
+	// watchCerts waits for its goroutines, so it only returns once they stopped.
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the cert watcher to stop with the context")
+	}
+}
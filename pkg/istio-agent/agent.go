@@ -15,6 +15,7 @@
 package istioagent
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -28,9 +29,11 @@ import (
 	mesh "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/istio-agent/health"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/security/pkg/nodeagent/cache"
+	"istio.io/istio/security/pkg/nodeagent/caclient/failover"
 	citadel "istio.io/istio/security/pkg/nodeagent/caclient/providers/citadel"
 	gca "istio.io/istio/security/pkg/nodeagent/caclient/providers/google"
 	"istio.io/istio/security/pkg/nodeagent/plugin/providers/google/stsclient"
@@ -93,6 +96,8 @@ type Agent struct {
 	CitadelClient security.Client
 
 	proxyConfig *mesh.ProxyConfig
+	// dynamicProxyConfig is the proxy config pushed by istiod through the XDS proxy.
+	dynamicProxyConfig *DynamicProxyConfig
 
 	cfg     *AgentConfig
 	secOpts *security.Options
@@ -116,6 +121,16 @@ type AgentConfig struct {
 	// DNSCapture indicates if the XDS proxy has dns capture enabled or not
 	// This option will not be considered if proxyXDSViaAgent is false.
 	DNSCapture bool
+	// DNSConfigFile is an optional file with static entries and forward zones for the
+	// local dns server, reloaded when it changes. Only used if DNSCapture is true.
+	DNSConfigFile string
+	// HealthProbe, if set, checks the health of the application, reported to istiod by the XDS proxy.
+	// This option will not be considered if proxyXDSViaAgent is false.
+	HealthProbe *health.Probe
+	// XDSCompression, if true, compresses the requests of the XDS proxy to istiod with gzip, for istiod to compress
+	// its large responses, see PILOT_XDS_COMPRESSION_THRESHOLD.
+	// This option will not be considered if proxyXDSViaAgent is false.
+	XDSCompression bool
 	// ProxyNamespace to use for local dns resolution
 	ProxyNamespace string
 	// ProxyDomain is the DNS domain associated with the proxy (assumed
@@ -149,9 +164,10 @@ type AgentConfig struct {
 func NewAgent(proxyConfig *mesh.ProxyConfig, cfg *AgentConfig,
 	sopts *security.Options) *Agent {
 	sa := &Agent{
-		proxyConfig: proxyConfig,
-		cfg:         cfg,
-		secOpts:     sopts,
+		proxyConfig:        proxyConfig,
+		dynamicProxyConfig: NewDynamicProxyConfig(proxyConfig),
+		cfg:                cfg,
+		secOpts:            sopts,
 	}
 
 	// Fix the defaults - mainly for tests ( main uses env )
@@ -263,6 +279,11 @@ func (sa *Agent) Close() {
 	sa.closeLocalXDSGenerator()
 }
 
+// DynamicProxyConfig returns the proxy config pushed by istiod, only received if the XDS proxy is enabled.
+func (sa *Agent) DynamicProxyConfig() *DynamicProxyConfig {
+	return sa.dynamicProxyConfig
+}
+
 func (sa *Agent) GetLocalXDSGeneratorListener() net.Listener {
 	if sa.localXDSGenerator != nil {
 		return sa.localXDSGenerator.listener
@@ -356,42 +377,17 @@ func (sa *Agent) newWorkloadSecretCache() (workloadSecretCache *cache.SecretCach
 		return
 	}
 
-	// TODO: this should all be packaged in a plugin, possibly with optional compilation.
-	log.Infof("sa.serverOptions.CAEndpoint == %v %s", sa.secOpts.CAEndpoint, sa.secOpts.CAProviderName)
-	if sa.secOpts.CAProviderName == "GoogleCA" || strings.Contains(sa.secOpts.CAEndpoint, "googleapis.com") {
-		// Use a plugin to an external CA - this has direct support for the K8S JWT token
-		// This is only used if the proper env variables are injected - otherwise the existing Citadel or Istiod will be
-		// used.
-		caClient, err = gca.NewGoogleCAClient(sa.secOpts.CAEndpoint, true)
-		sa.secOpts.PluginNames = []string{"GoogleTokenExchange"}
-		sa.secOpts.TokenExchangers = []security.TokenExchanger{stsclient.NewPlugin()}
-	} else {
-		var rootCert []byte
-		// Special case: if Istiod runs on a secure network, on the default port, don't use TLS
-		// TODO: may add extra cases or explicit settings - but this is a rare use cases, mostly debugging
-		tls := true
-		if strings.HasSuffix(sa.secOpts.CAEndpoint, ":15010") {
-			tls = false
-			log.Warna("Debug mode or IP-secure network")
-		}
-		if tls {
-			caCertFile := sa.FindRootCAForCA()
-			if rootCert, err = ioutil.ReadFile(caCertFile); err != nil {
-				log.Fatalf("invalid config - %s missing a root certificate %s", sa.secOpts.CAEndpoint, caCertFile)
-			} else {
-				log.Infof("Using CA %s cert with certs: %s", sa.secOpts.CAEndpoint, caCertFile)
-
-				sa.RootCert = rootCert
-			}
-		}
-
-		// Will use TLS unless the reserved 15010 port is used ( istiod on an ipsec/secure VPC)
-		// rootCert may be nil - in which case the system roots are used, and the CA is expected to have public key
-		// Otherwise assume the injection has mounted /etc/certs/root-cert.pem
-		caClient, err = citadel.NewCitadelClient(sa.secOpts.CAEndpoint, tls, rootCert, sa.secOpts.ClusterID)
+	if len(sa.secOpts.CAEndpoints) == 0 {
+		var exchangers []security.TokenExchanger
+		caClient, exchangers, err = sa.newCAClient(security.CAEndpoint{
+			Address:      sa.secOpts.CAEndpoint,
+			ProviderName: sa.secOpts.CAProviderName,
+		})
 		if err == nil {
-			sa.CitadelClient = caClient
+			caClient = sa.withTokenExchange(caClient, exchangers)
 		}
+	} else {
+		caClient, err = sa.newFailoverCAClient()
 	}
 
 	// This has to be called after sa.secOpts.PluginNames is set. Otherwise,
@@ -409,6 +405,113 @@ func (sa *Agent) newWorkloadSecretCache() (workloadSecretCache *cache.SecretCach
 	return
 }
 
+// newFailoverCAClient creates a client which sends CSRs to each of CAEndpoints in turn, until one succeeds.
+func (sa *Agent) newFailoverCAClient() (security.Client, error) {
+	endpoints := make([]failover.Endpoint, 0, len(sa.secOpts.CAEndpoints))
+	for _, ep := range sa.secOpts.CAEndpoints {
+		if ep.ProviderName == "" {
+			ep.ProviderName = "Citadel"
+		}
+		client, exchangers, err := sa.newCAClient(ep)
+		if err != nil {
+			return nil, err
+		}
+		// Only this CA expects an exchanged token, the token of the CSRs to the other CAs is left as is.
+		client = sa.withTokenExchange(client, exchangers)
+		endpoints = append(endpoints, failover.Endpoint{
+			Provider: ep.ProviderName,
+			Address:  ep.Address,
+			Client:   client,
+			Timeout:  ep.Timeout,
+		})
+	}
+	return failover.NewClient(endpoints, sa.secOpts.CAPrimaryReprobeInterval)
+}
+
+// newCAClient creates the client for a single CA, along with the token exchangers the CA requires, if any.
+func (sa *Agent) newCAClient(ep security.CAEndpoint) (caClient security.Client,
+	exchangers []security.TokenExchanger, err error) {
+	// TODO: this should all be packaged in a plugin, possibly with optional compilation.
+	log.Infof("sa.serverOptions.CAEndpoint == %v %s", ep.Address, ep.ProviderName)
+	if ep.ProviderName == "GoogleCA" || strings.Contains(ep.Address, "googleapis.com") {
+		// Use a plugin to an external CA - this has direct support for the K8S JWT token
+		// This is only used if the proper env variables are injected - otherwise the existing Citadel or Istiod will be
+		// used.
+		caClient, err = gca.NewGoogleCAClient(ep.Address, true)
+		exchangers = []security.TokenExchanger{stsclient.NewPlugin()}
+		return
+	}
+
+	var rootCert []byte
+	// Special case: if Istiod runs on a secure network, on the default port, don't use TLS
+	// TODO: may add extra cases or explicit settings - but this is a rare use cases, mostly debugging
+	tls := true
+	if strings.HasSuffix(ep.Address, ":15010") {
+		tls = false
+		log.Warna("Debug mode or IP-secure network")
+	}
+	if tls {
+		caCertFile := ep.RootCertFile
+		if caCertFile == "" {
+			caCertFile = sa.FindRootCAForCA()
+		}
+		if rootCert, err = ioutil.ReadFile(caCertFile); err != nil {
+			return nil, nil, fmt.Errorf("invalid config - %s missing a root certificate %s: %v", ep.Address, caCertFile, err)
+		}
+		log.Infof("Using CA %s cert with certs: %s", ep.Address, caCertFile)
+
+		// an explicit root only verifies that CA, it is not the root of the mesh.
+		if ep.RootCertFile == "" {
+			sa.RootCert = rootCert
+		}
+	}
+
+	// Will use TLS unless the reserved 15010 port is used ( istiod on an ipsec/secure VPC)
+	// rootCert may be nil - in which case the system roots are used, and the CA is expected to have public key
+	// Otherwise assume the injection has mounted /etc/certs/root-cert.pem
+	caClient, err = citadel.NewCitadelClient(ep.Address, tls, rootCert, sa.secOpts.ClusterID)
+	if err == nil && sa.CitadelClient == nil {
+		sa.CitadelClient = caClient
+	}
+	return
+}
+
+// withTokenExchange wraps client to exchange the token of each CSR with the first of exchangers, if any. The
+// exchange is local to the client, so the token exchangers of the agent options are left to the other CAs.
+func (sa *Agent) withTokenExchange(client security.Client, exchangers []security.TokenExchanger) security.Client {
+	if len(exchangers) == 0 {
+		return client
+	}
+	return &tokenExchangingClient{
+		Client:      client,
+		exchanger:   exchangers[0],
+		credFetcher: sa.secOpts.CredFetcher,
+		trustDomain: sa.secOpts.TrustDomain,
+	}
+}
+
+// tokenExchangingClient exchanges the token of each CSR before sending it to a CA which requires an exchanged
+// token, when the other CAs the agent fails over to do not.
+type tokenExchangingClient struct {
+	security.Client
+	exchanger   security.TokenExchanger
+	credFetcher security.CredFetcher
+	trustDomain string
+}
+
+func (c *tokenExchangingClient) CSRSign(ctx context.Context, reqID string, csrPEM []byte, subjectID string,
+	certValidTTLInSec int64) ([]string, error) {
+	if subjectID == "" {
+		// the CSR is authenticated with the current certificate.
+		return c.Client.CSRSign(ctx, reqID, csrPEM, subjectID, certValidTTLInSec)
+	}
+	token, _, _, err := c.exchanger.ExchangeToken(ctx, c.credFetcher, c.trustDomain, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %v", err)
+	}
+	return c.Client.CSRSign(ctx, reqID, csrPEM, token, certValidTTLInSec)
+}
+
 // TODO: use existing 'sidecar/router' config to enable loading Secrets
 func (sa *Agent) newSecretCache(namespace string) (gatewaySecretCache *cache.SecretCache) {
 	gSecretFetcher := &secretfetcher.SecretFetcher{}
@@ -81,3 +81,52 @@ func TestNewAgent(t *testing.T) {
 		}
 	}
 }
+
+func TestNewFailoverCAClient(t *testing.T) {
+	proxyConfig := mesh.DefaultProxyConfig()
+	secOpts := &security.Options{
+		CAEndpoints: []security.CAEndpoint{
+			{Address: "meshca.googleapis.com:443", ProviderName: "GoogleCA"},
+			{Address: "istiod.istio-system.svc:15012", RootCertFile: "/nonexistent/root-cert.pem"},
+		},
+	}
+	sa := NewAgent(&proxyConfig, &AgentConfig{}, secOpts)
+	if _, err := sa.newFailoverCAClient(); err == nil {
+		t.Fatal("expected an error for a missing root certificate")
+	}
+	// the token exchange of a single CA must not apply to the CSRs to every CA.
+	if sa.secOpts.PluginNames != nil || sa.secOpts.TokenExchangers != nil {
+		t.Errorf("got plugins %v and token exchangers %v, want none", sa.secOpts.PluginNames, sa.secOpts.TokenExchangers)
+	}
+
+	secOpts.CAEndpoints[1].RootCertFile = ""
+	secOpts.CAEndpoints[1].Address = "istiod.istio-system.svc:15010"
+	client, err := sa.newFailoverCAClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client == nil {
+		t.Fatal("expected a client")
+	}
+	if sa.secOpts.PluginNames != nil || sa.secOpts.TokenExchangers != nil {
+		t.Errorf("got plugins %v and token exchangers %v, want none", sa.secOpts.PluginNames, sa.secOpts.TokenExchangers)
+	}
+}
+
+func TestWorkloadSecretCacheGoogleCA(t *testing.T) {
+	proxyConfig := mesh.DefaultProxyConfig()
+	secOpts := &security.Options{
+		CAEndpoint:     "meshca.googleapis.com:443",
+		CAProviderName: "GoogleCA",
+	}
+	sa := NewAgent(&proxyConfig, &AgentConfig{}, secOpts)
+	secretCache, client := sa.newWorkloadSecretCache()
+	defer secretCache.Close()
+	if _, ok := client.(*tokenExchangingClient); !ok {
+		t.Fatalf("got client %T, want the token exchange of the CA", client)
+	}
+	// the token exchange is local to the client of the CA.
+	if sa.secOpts.PluginNames != nil || len(sa.secOpts.TokenExchangers) != 0 {
+		t.Errorf("got plugins %v and token exchangers %v, want none", sa.secOpts.PluginNames, sa.secOpts.TokenExchangers)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"istio.io/pkg/log"
+)
+
+var healthLog = log.RegisterScope("healthcheck", "Workload health checking", 0)
+
+// ProbeProxyMetadata is the key of the JSON Probe in the proxy metadata of the proxy config of a workload. It is set
+// by istiod in the mesh config of the onboarding bundle of a WorkloadGroup with a probe.
+const ProbeProxyMetadata = "ISTIO_WORKLOAD_HEALTH_PROBE"
+
+// Probe is the health check of the application of a workload, with the fields of a Kubernetes readiness probe
+// supported by the agent.
+type Probe struct {
+	HTTPGet   *corev1.HTTPGetAction   `json:"httpGet,omitempty"`
+	TCPSocket *corev1.TCPSocketAction `json:"tcpSocket,omitempty"`
+	// TimeoutSeconds is the timeout of a single check. Defaults to 1 second.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// PeriodSeconds is the interval between two checks. Defaults to 10 seconds.
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+	// SuccessThreshold is the number of consecutive successful checks for an unhealthy workload to be reported
+	// healthy. Defaults to 1.
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+	// FailureThreshold is the number of consecutive failed checks for a healthy workload to be reported
+	// unhealthy. Defaults to 3.
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// ParseProbe parses and validates a JSON Probe.
+func ParseProbe(s string) (*Probe, error) {
+	probe := &Probe{}
+	if err := json.Unmarshal([]byte(s), probe); err != nil {
+		return nil, fmt.Errorf("invalid health probe: %v", err)
+	}
+	if _, err := NewWorkloadHealthChecker(*probe); err != nil {
+		return nil, err
+	}
+	return probe, nil
+}
+
+// ProbeEvent is a change of the health of the workload.
+type ProbeEvent struct {
+	Healthy bool
+	// Message is the reason of the last failed check, if the workload is unhealthy.
+	Message string
+}
+
+// WorkloadHealthChecker periodically checks the health of the application with a Probe.
+type WorkloadHealthChecker struct {
+	probe  Probe
+	period time.Duration
+	check  func() error
+}
+
+// NewWorkloadHealthChecker validates the probe and creates a checker of the application with it.
+func NewWorkloadHealthChecker(probe Probe) (*WorkloadHealthChecker, error) {
+	if (probe.HTTPGet == nil) == (probe.TCPSocket == nil) {
+		return nil, errors.New("exactly one of httpGet and tcpSocket must be set in the health probe")
+	}
+	if probe.TimeoutSeconds < 0 || probe.PeriodSeconds < 0 || probe.SuccessThreshold < 0 || probe.FailureThreshold < 0 {
+		return nil, errors.New("the durations and thresholds of the health probe must not be negative")
+	}
+	var port intstr.IntOrString
+	if probe.HTTPGet != nil {
+		port = probe.HTTPGet.Port
+	} else {
+		port = probe.TCPSocket.Port
+	}
+	if _, err := probePort(port); err != nil {
+		return nil, err
+	}
+	if probe.TimeoutSeconds == 0 {
+		probe.TimeoutSeconds = 1
+	}
+	if probe.PeriodSeconds == 0 {
+		probe.PeriodSeconds = 10
+	}
+	if probe.SuccessThreshold == 0 {
+		probe.SuccessThreshold = 1
+	}
+	if probe.FailureThreshold == 0 {
+		probe.FailureThreshold = 3
+	}
+	w := &WorkloadHealthChecker{
+		probe:  probe,
+		period: time.Duration(probe.PeriodSeconds) * time.Second,
+	}
+	if probe.HTTPGet != nil {
+		w.check = w.checkHTTP
+	} else {
+		w.check = w.checkTCP
+	}
+	return w, nil
+}
+
+// PerformApplicationHealthCheck checks the application until stop is closed, calling notify when its health
+// changes. The workload is reported unhealthy after FailureThreshold consecutive failed checks and healthy again
+// after SuccessThreshold consecutive successful ones, so that a flapping application is not reported at every
+// check. The first check is reported as soon as its threshold is reached.
+func (w *WorkloadHealthChecker) PerformApplicationHealthCheck(notify func(ProbeEvent), stop <-chan struct{}) {
+	var reported *bool
+	successes, failures := int32(0), int32(0)
+	ticker := time.NewTicker(w.period)
+	defer ticker.Stop()
+	for {
+		err := w.check()
+		if err == nil {
+			successes, failures = successes+1, 0
+			if successes >= w.probe.SuccessThreshold && (reported == nil || !*reported) {
+				healthy := true
+				reported = &healthy
+				healthLog.Infof("application is healthy")
+				notify(ProbeEvent{Healthy: true})
+			}
+		} else {
+			successes, failures = 0, failures+1
+			if failures >= w.probe.FailureThreshold && (reported == nil || *reported) {
+				healthy := false
+				reported = &healthy
+				healthLog.Warnf("application is unhealthy: %v", err)
+				notify(ProbeEvent{Healthy: false, Message: err.Error()})
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *WorkloadHealthChecker) timeout() time.Duration {
+	return time.Duration(w.probe.TimeoutSeconds) * time.Second
+}
+
+func (w *WorkloadHealthChecker) checkHTTP() error {
+	get := w.probe.HTTPGet
+	scheme := "http"
+	if get.Scheme == corev1.URISchemeHTTPS {
+		scheme = "https"
+	}
+	host := "localhost"
+	if get.Host != "" {
+		host = get.Host
+	}
+	path := get.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	port, err := probePort(get.Port)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, port), path), nil)
+	if err != nil {
+		return err
+	}
+	for _, h := range get.HTTPHeaders {
+		if strings.EqualFold(h.Name, "Host") {
+			req.Host = h.Value
+		} else {
+			req.Header.Add(h.Name, h.Value)
+		}
+	}
+	client := &http.Client{
+		Timeout: w.timeout(),
+		// The certificate of the application is not verified, as done by the kubelet.
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("http probe failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *WorkloadHealthChecker) checkTCP() error {
+	host := "localhost"
+	if w.probe.TCPSocket.Host != "" {
+		host = w.probe.TCPSocket.Host
+	}
+	port, err := probePort(w.probe.TCPSocket.Port)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), w.timeout())
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probePort returns the port of the probe. Named ports are not supported, the workload has no container spec.
+func probePort(port intstr.IntOrString) (string, error) {
+	if port.Type != intstr.Int || port.IntValue() <= 0 {
+		return "", fmt.Errorf("invalid health probe port %s, it must be a number", port.String())
+	}
+	return strconv.Itoa(port.IntValue()), nil
+}
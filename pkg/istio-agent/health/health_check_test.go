@@ -0,0 +1,189 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestNewWorkloadHealthChecker(t *testing.T) {
+	httpGet := &corev1.HTTPGetAction{Port: intstr.FromInt(8080)}
+	cases := []struct {
+		name  string
+		probe Probe
+		valid bool
+	}{
+		{"http", Probe{HTTPGet: httpGet}, true},
+		{"tcp", Probe{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(8080)}}, true},
+		{"no action", Probe{}, false},
+		{"two actions", Probe{HTTPGet: httpGet, TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(8080)}}, false},
+		{"named port", Probe{HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromString("http")}}, false},
+		{"negative threshold", Probe{HTTPGet: httpGet, FailureThreshold: -1}, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWorkloadHealthChecker(tt.probe)
+			if tt.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected the probe to be rejected")
+			}
+		})
+	}
+
+	w, err := NewWorkloadHealthChecker(Probe{HTTPGet: httpGet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Probe{HTTPGet: httpGet, TimeoutSeconds: 1, PeriodSeconds: 10, SuccessThreshold: 1, FailureThreshold: 3}
+	if w.probe != want || w.period != 10*time.Second {
+		t.Errorf("got probe %+v with period %v, want the defaults %+v", w.probe, w.period, want)
+	}
+}
+
+func TestParseProbe(t *testing.T) {
+	probe, err := ParseProbe(`{"tcpSocket": {"port": 3306}, "failureThreshold": 5}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probe.TCPSocket == nil || probe.TCPSocket.Port.IntValue() != 3306 || probe.FailureThreshold != 5 {
+		t.Errorf("unexpected probe %+v", probe)
+	}
+	for _, invalid := range []string{`{"tcpSocket": `, `{"periodSeconds": 5}`} {
+		if _, err := ParseProbe(invalid); err == nil {
+			t.Errorf("expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestPerformApplicationHealthCheck(t *testing.T) {
+	w, err := NewWorkloadHealthChecker(Probe{
+		TCPSocket:        &corev1.TCPSocketAction{Port: intstr.FromInt(8080)},
+		SuccessThreshold: 2,
+		FailureThreshold: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.period = time.Millisecond
+	stop := make(chan struct{})
+	defer close(stop)
+	ready := make(chan struct{})
+	results := make(chan error)
+	w.check = func() error {
+		select {
+		case ready <- struct{}{}:
+		case <-stop:
+			return nil
+		}
+		return <-results
+	}
+	events := make(chan ProbeEvent, 10)
+	go w.PerformApplicationHealthCheck(func(e ProbeEvent) { events <- e }, stop)
+	<-ready
+
+	// check completes a check with its result, and returns the event notified, if any.
+	check := func(result error) *ProbeEvent {
+		t.Helper()
+		results <- result
+		// The event is notified before the next check.
+		select {
+		case <-ready:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the next check")
+		}
+		select {
+		case e := <-events:
+			return &e
+		default:
+			return nil
+		}
+	}
+	failure := errors.New("connection refused")
+
+	if e := check(nil); e != nil {
+		t.Fatalf("unexpected event %+v before the success threshold", e)
+	}
+	if e := check(nil); e == nil || !e.Healthy {
+		t.Fatalf("expected the application to be reported healthy, got %+v", e)
+	}
+	for i := 0; i < 2; i++ {
+		if e := check(failure); e != nil {
+			t.Fatalf("unexpected event %+v before the failure threshold", e)
+		}
+	}
+	// A successful check resets the failures.
+	if e := check(nil); e != nil {
+		t.Fatalf("unexpected event %+v of a healthy application", e)
+	}
+	for i := 0; i < 2; i++ {
+		if e := check(failure); e != nil {
+			t.Fatalf("unexpected event %+v before the failure threshold", e)
+		}
+	}
+	if e := check(failure); e == nil || e.Healthy || e.Message != failure.Error() {
+		t.Fatalf("expected the application to be reported unhealthy, got %+v", e)
+	}
+	if e := check(failure); e != nil {
+		t.Fatalf("unexpected event %+v of an application still unhealthy", e)
+	}
+	if e := check(nil); e != nil {
+		t.Fatalf("unexpected event %+v before the success threshold", e)
+	}
+	if e := check(nil); e == nil || !e.Healthy {
+		t.Fatalf("expected the application to be reported healthy again, got %+v", e)
+	}
+}
+
+func TestCheckHTTP(t *testing.T) {
+	code := atomic.NewInt32(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" || r.Header.Get("X-Probe") != "istio" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(int(code.Load()))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	w, err := NewWorkloadHealthChecker(Probe{HTTPGet: &corev1.HTTPGetAction{
+		Path:        "ready",
+		Port:        intstr.FromInt(p),
+		HTTPHeaders: []corev1.HTTPHeader{{Name: "X-Probe", Value: "istio"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.check(); err != nil {
+		t.Fatalf("unexpected failure of a healthy application: %v", err)
+	}
+	code.Store(http.StatusServiceUnavailable)
+	if err := w.check(); err == nil {
+		t.Fatal("expected the check of an unhealthy application to fail")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+// DynamicProxyConfig holds the proxy config pushed by istiod, with the overrides of the namespace and workload of
+// the proxy applied. Only the mesh.DynamicProxyConfigFields are applied to the running proxy, the other fields
+// keep the value the proxy was started with until it is restarted.
+type DynamicProxyConfig struct {
+	startup *meshconfig.ProxyConfig
+
+	mutex sync.RWMutex
+	// pushed is the last proxy config pushed by istiod, nil until one is received.
+	pushed *meshconfig.ProxyConfig
+}
+
+// NewDynamicProxyConfig returns a DynamicProxyConfig for a proxy started with the proxy config.
+func NewDynamicProxyConfig(startup *meshconfig.ProxyConfig) *DynamicProxyConfig {
+	return &DynamicProxyConfig{startup: startup}
+}
+
+// Update applies the proxy config pushed by istiod. It returns the fields which differ from the proxy config the
+// proxy was started with, and which are only applied once it restarts. A nil proxy config drops the pushed one,
+// the proxy then uses the proxy config it was started with.
+func (d *DynamicProxyConfig) Update(pc *meshconfig.ProxyConfig) []string {
+	d.mutex.Lock()
+	d.pushed = pc
+	d.mutex.Unlock()
+	return mesh.RestartRequiredProxyConfigFields(d.startup, pc)
+}
+
+// TerminationDrainDuration returns the termination drain duration pushed by istiod, if any.
+func (d *DynamicProxyConfig) TerminationDrainDuration() (time.Duration, bool) {
+	if d == nil {
+		return 0, false
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if d.pushed == nil || d.pushed.TerminationDrainDuration == nil {
+		return 0, false
+	}
+	duration, err := types.DurationFromProto(d.pushed.TerminationDrainDuration)
+	if err != nil {
+		return 0, false
+	}
+	return duration, true
+}
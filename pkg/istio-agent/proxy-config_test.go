@@ -0,0 +1,83 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	gogotypes "github.com/gogo/protobuf/types"
+	"github.com/golang/protobuf/ptypes/any"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestApplyProxyConfig(t *testing.T) {
+	startup := mesh.DefaultProxyConfig()
+	p := &XdsProxy{proxyConfig: NewDynamicProxyConfig(&startup)}
+	if _, f := p.proxyConfig.TerminationDrainDuration(); f {
+		t.Fatal("expected no termination drain duration before a push")
+	}
+
+	pushed := mesh.DefaultProxyConfig()
+	pushed.TerminationDrainDuration = gogotypes.DurationProto(30 * time.Second)
+	pushed.Concurrency = &gogotypes.Int32Value{Value: 4}
+	pc, err := gogotypes.MarshalAny(&pushed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := p.applyProxyConfig(&discovery.DiscoveryResponse{
+		TypeUrl:     v3.ProxyConfigType,
+		VersionInfo: "v1",
+		Nonce:       "n1",
+		Resources:   []*any.Any{{TypeUrl: pc.TypeUrl, Value: pc.Value}},
+	})
+	if req.ErrorDetail != nil || req.VersionInfo != "v1" || req.ResponseNonce != "n1" {
+		t.Fatalf("expected the proxy config to be acknowledged, got %v", req)
+	}
+	if d, f := p.proxyConfig.TerminationDrainDuration(); !f || d != 30*time.Second {
+		t.Fatalf("got termination drain duration %v, want 30s", d)
+	}
+	if got := p.proxyConfig.Update(&pushed); !reflect.DeepEqual(got, []string{"concurrency"}) {
+		t.Fatalf("got fields requiring a restart %v, want [concurrency]", got)
+	}
+
+	req = p.applyProxyConfig(&discovery.DiscoveryResponse{
+		TypeUrl:   v3.ProxyConfigType,
+		Nonce:     "n2",
+		Resources: []*any.Any{{TypeUrl: pc.TypeUrl, Value: []byte("invalid")}},
+	})
+	if req.ErrorDetail == nil {
+		t.Fatal("expected the invalid proxy config to be rejected")
+	}
+	if d, _ := p.proxyConfig.TerminationDrainDuration(); d != 30*time.Second {
+		t.Fatalf("the rejected proxy config was applied, got termination drain duration %v", d)
+	}
+
+	// An older istiod, e.g. after a rollback, answers with no proxy config.
+	req = p.applyProxyConfig(&discovery.DiscoveryResponse{
+		TypeUrl: v3.ProxyConfigType,
+		Nonce:   "n3",
+	})
+	if req.ErrorDetail != nil || req.ResponseNonce != "n3" {
+		t.Fatalf("expected the empty response to be acknowledged, got %v", req)
+	}
+	if _, f := p.proxyConfig.TerminationDrainDuration(); f {
+		t.Fatal("expected the pushed proxy config to be dropped")
+	}
+}
@@ -20,27 +20,35 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
+	gogotypes "github.com/gogo/protobuf/types"
 	"github.com/golang/protobuf/ptypes"
 	"golang.org/x/oauth2"
+	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/dns"
 	nds "istio.io/istio/pilot/pkg/proto"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/istio-agent/health"
 	"istio.io/pkg/log"
 )
 
@@ -57,6 +65,17 @@ type XdsProxy struct {
 	istiodAddress        string
 	istiodDialOptions    []grpc.DialOption
 	localDNSServer       *dns.LocalDNSServer
+
+	// healthStop stops the health checks of the application, if the agent was configured with a probe.
+	healthStop chan struct{}
+	// healthUpdates is notified when the health of the application changes.
+	healthUpdates chan struct{}
+	healthMutex   sync.Mutex
+	// lastHealth is the last health of the application, sent again to istiod on reconnection.
+	lastHealth *discovery.DiscoveryRequest
+
+	// proxyConfig receives the proxy config pushed by istiod.
+	proxyConfig *DynamicProxyConfig
 }
 
 var proxyLog = log.RegisterScope("xdsproxy", "XDS Proxy in Istio Agent", 0)
@@ -71,6 +90,15 @@ func initXdsProxy(sa *Agent, isSidecar bool) (*XdsProxy, error) {
 	var err error
 	proxy := &XdsProxy{
 		istiodAddress: sa.proxyConfig.DiscoveryAddress,
+		healthUpdates: make(chan struct{}, 1),
+		proxyConfig:   sa.dynamicProxyConfig,
+	}
+
+	var healthChecker *health.WorkloadHealthChecker
+	if sa.cfg.HealthProbe != nil {
+		if healthChecker, err = health.NewWorkloadHealthChecker(*sa.cfg.HealthProbe); err != nil {
+			return nil, fmt.Errorf("invalid health probe: %v", err)
+		}
 	}
 
 	if err = proxy.initDownstreamServer(); err != nil {
@@ -86,9 +114,20 @@ func initXdsProxy(sa *Agent, isSidecar bool) (*XdsProxy, error) {
 		if proxy.localDNSServer, err = dns.NewLocalDNSServer(sa.cfg.ProxyNamespace, sa.cfg.ProxyDomain); err != nil {
 			return nil, err
 		}
+		if sa.cfg.DNSConfigFile != "" {
+			if err = proxy.localDNSServer.WatchConfigFile(sa.cfg.DNSConfigFile); err != nil {
+				proxy.localDNSServer.Close()
+				return nil, err
+			}
+		}
 		proxy.localDNSServer.StartDNS()
 	}
 
+	if healthChecker != nil {
+		proxy.healthStop = make(chan struct{})
+		go healthChecker.PerformApplicationHealthCheck(proxy.PublishHealthEvent, proxy.healthStop)
+	}
+
 	go func() {
 		_ = proxy.downstreamGrpcServer.Serve(proxy.downstreamListener)
 	}()
@@ -96,6 +135,28 @@ func initXdsProxy(sa *Agent, isSidecar bool) (*XdsProxy, error) {
 	return proxy, nil
 }
 
+// PublishHealthEvent reports the health of the application to istiod, which updates the WorkloadEntry of the workload.
+func (p *XdsProxy) PublishHealthEvent(event health.ProbeEvent) {
+	req := &discovery.DiscoveryRequest{TypeUrl: v3.HealthInfoType}
+	if !event.Healthy {
+		req.ErrorDetail = &status.Status{Message: event.Message}
+	}
+	p.healthMutex.Lock()
+	p.lastHealth = req
+	p.healthMutex.Unlock()
+	select {
+	case p.healthUpdates <- struct{}{}:
+	default:
+		// An update is already pending, and will send the last health.
+	}
+}
+
+func (p *XdsProxy) healthRequest() *discovery.DiscoveryRequest {
+	p.healthMutex.Lock()
+	defer p.healthMutex.Unlock()
+	return p.lastHealth
+}
+
 // Every time envoy makes a fresh connection to the agent, we reestablish a new connection to the upstream xds
 // This ensures that a new connection between istiod and agent doesn't end up consuming pending messages from envoy
 // as the new connection may not go to the same istiod. Vice versa case also applies.
@@ -122,6 +183,9 @@ func (p *XdsProxy) StreamAggregatedResources(downstream discovery.AggregatedDisc
 	}
 
 	firstNDSSent := false
+	// The health and the proxy config request are sent once the connection to istiod was initialized with the
+	// first request of Envoy, which holds the node.
+	initialized := false
 
 	go func() {
 		for {
@@ -169,6 +233,28 @@ func (p *XdsProxy) StreamAggregatedResources(downstream discovery.AggregatedDisc
 				proxyLog.Errorf("upstream send error: %v", err)
 				return err
 			}
+			if !initialized {
+				initialized = true
+				if healthReq := p.healthRequest(); healthReq != nil {
+					if err = upstream.Send(healthReq); err != nil {
+						proxyLog.Errorf("upstream send error for health: %v", err)
+						return err
+					}
+				}
+				if err = upstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ProxyConfigType}); err != nil {
+					proxyLog.Errorf("upstream send error for proxy config: %v", err)
+					return err
+				}
+			}
+		case <-p.healthUpdates:
+			if !initialized {
+				// Sent with the first request.
+				continue
+			}
+			if err = upstream.Send(p.healthRequest()); err != nil {
+				proxyLog.Errorf("upstream send error for health: %v", err)
+				return err
+			}
 		case req := <-ndsRequestChan:
 			if err = upstream.Send(req); err != nil {
 				proxyLog.Errorf("upstream send error for nds: %v", err)
@@ -193,6 +279,16 @@ func (p *XdsProxy) StreamAggregatedResources(downstream discovery.AggregatedDisc
 					TypeUrl:       v3.NameTableType,
 					ResponseNonce: resp.Nonce,
 				}
+			} else if resp.TypeUrl == v3.ProxyConfigType {
+				// intercept. The proxy config is applied by the agent.
+				if err = upstream.Send(p.applyProxyConfig(resp)); err != nil {
+					proxyLog.Errorf("upstream send error for proxy config: %v", err)
+					return err
+				}
+			} else if resp.TypeUrl == v3.HealthInfoType {
+				// Istiod versions older than the health reporting handle the health as a watched type, and may
+				// respond to it. Envoy did not request it.
+				proxyLog.Debugf("ignoring the response of istiod to the health report")
 			} else if err := downstream.Send(resp); err != nil {
 				proxyLog.Errorf("downstream send error: %v", err)
 				// we cannot return partial error and hope to restart just the downstream
@@ -207,11 +303,99 @@ func (p *XdsProxy) StreamAggregatedResources(downstream discovery.AggregatedDisc
 	}
 }
 
+// applyProxyConfig applies the proxy config pushed by istiod, and returns the request acknowledging it, or
+// rejecting it if it is invalid.
+func (p *XdsProxy) applyProxyConfig(resp *discovery.DiscoveryResponse) *discovery.DiscoveryRequest {
+	req := &discovery.DiscoveryRequest{
+		TypeUrl:       v3.ProxyConfigType,
+		ResponseNonce: resp.Nonce,
+	}
+	if len(resp.Resources) == 0 {
+		// Istiod versions older than the proxy config push serve the request with an empty response of their
+		// generator for the unknown types. The proxy config pushed by a newer istiod the agent was connected to,
+		// if any, is dropped as it is no longer updated.
+		p.proxyConfig.Update(nil)
+	} else {
+		pc := &meshconfig.ProxyConfig{}
+		err := gogotypes.UnmarshalAny(&gogotypes.Any{TypeUrl: resp.Resources[0].TypeUrl, Value: resp.Resources[0].Value}, pc)
+		if err != nil {
+			proxyLog.Warnf("failed to unmarshal the proxy config: %v", err)
+			req.ErrorDetail = &status.Status{Message: err.Error()}
+			return req
+		}
+		if restartRequired := p.proxyConfig.Update(pc); len(restartRequired) > 0 {
+			proxyLog.Warnf("proxy config fields %v changed, they are applied once the proxy restarts", restartRequired)
+		}
+	}
+	req.VersionInfo = resp.VersionInfo
+	return req
+}
+
+// StreamLoadStats forwards the load reports of Envoy to istiod, as the xds-grpc cluster Envoy reports to points
+// to the agent when the XDS proxy is enabled.
+func (p *XdsProxy) StreamLoadStats(downstream lrs.LoadReportingService_StreamLoadStatsServer) error {
+	upstreamConn, err := grpc.Dial(p.istiodAddress, p.istiodDialOptions...)
+	if err != nil {
+		proxyLog.Errorf("failed to connect to upstream %s for load stats: %v", p.istiodAddress, err)
+		return err
+	}
+	defer upstreamConn.Close()
+
+	// The upstream stream is canceled once the downstream one is done.
+	ctx, cancel := context.WithCancel(downstream.Context())
+	defer cancel()
+	upstream, err := lrs.NewLoadReportingServiceClient(upstreamConn).StreamLoadStats(ctx)
+	if err != nil {
+		proxyLog.Errorf("failed to create upstream load stats client: %v", err)
+		return err
+	}
+
+	errChan := make(chan error, 2)
+	go func() {
+		for {
+			// from istiod
+			resp, err := upstream.Recv()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if err = downstream.Send(resp); err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			// From Envoy
+			req, err := downstream.Recv()
+			if err != nil {
+				_ = upstream.CloseSend()
+				errChan <- err
+				return
+			}
+			if err = upstream.Send(req); err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	if err = <-errChan; err == io.EOF {
+		return nil
+	}
+	proxyLog.Debugf("load stats stream terminated: %v", err)
+	return err
+}
+
 func (p *XdsProxy) DeltaAggregatedResources(server discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
 	return errors.New("delta XDS is not implemented")
 }
 
 func (p *XdsProxy) close() {
+	if p.healthStop != nil {
+		close(p.healthStop)
+	}
 	p.stopChan <- struct{}{}
 	if p.downstreamGrpcServer != nil {
 		_ = p.downstreamGrpcServer.Stop
@@ -290,6 +474,7 @@ func (p *XdsProxy) initDownstreamServer() error {
 	}
 	grpcs := grpc.NewServer()
 	discovery.RegisterAggregatedDiscoveryServiceServer(grpcs, p)
+	lrs.RegisterLoadReportingServiceServer(grpcs, p)
 	reflection.Register(grpcs)
 	p.downstreamGrpcServer = grpcs
 	p.downstreamListener = l
@@ -321,6 +506,10 @@ func buildUpstreamClientDialOpts(sa *Agent) ([]grpc.DialOption, error) {
 		keepaliveOption, initialWindowSizeOption, initialConnWindowSizeOption, msgSizeOption,
 		grpc.WithBlock(),
 	}
+	if sa.cfg.XDSCompression {
+		// istiod compresses the responses of the streams whose requests are compressed.
+		dialOptions = append(dialOptions, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
 
 	// TODO: This is not a valid way of detecting if we are on VM vs k8s
 	// Some end users do not use Istiod for CA but run on k8s with file mounted certs
@@ -29,6 +29,10 @@ import (
 	"istio.io/pkg/log"
 )
 
+// AppProbeHTTP2Annotation requests that the rewritten app probes reach the application over HTTP/2 with prior
+// knowledge, for applications serving gRPC health checks over HTTP/2 only.
+const AppProbeHTTP2Annotation = "sidecar.istio.io/rewriteAppHTTPProbersHTTP2"
+
 // ShouldRewriteAppHTTPProbers returns if we should rewrite apps' probers config.
 func ShouldRewriteAppHTTPProbers(annotations map[string]string, spec *SidecarInjectionSpec) bool {
 	if annotations != nil {
@@ -74,8 +78,9 @@ func convertAppProber(probe *corev1.Probe, newURL string, statusPort int) *corev
 
 // DumpAppProbers returns a json encoded string as `status.KubeAppProbers`.
 // Also update the probers so that all usages of named port will be resolved to integer.
-func DumpAppProbers(podspec *corev1.PodSpec) string {
+func DumpAppProbers(podspec *corev1.PodSpec, annotations map[string]string) string {
 	out := status.KubeAppProbers{}
+	http2, _ := strconv.ParseBool(annotations[AppProbeHTTP2Annotation])
 	updateNamedPort := func(p *status.Prober, portMap map[string]int32) *status.Prober {
 		if p == nil || p.HTTPGet == nil {
 			return nil
@@ -87,6 +92,7 @@ func DumpAppProbers(podspec *corev1.PodSpec) string {
 			}
 			p.HTTPGet.Port = intstr.FromInt(int(port))
 		}
+		p.HTTP2 = http2
 		return p
 	}
 	for _, c := range podspec.Containers {
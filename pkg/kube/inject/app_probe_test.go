@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"istio.io/api/annotation"
 )
@@ -109,3 +110,42 @@ func TestShouldRewriteAppHTTPProbers(t *testing.T) {
 		}
 	}
 }
+
+func TestDumpAppProbersHTTP2(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name: "grpc",
+			ReadinessProbe: &corev1.Probe{
+				Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(8080)}},
+			},
+		}},
+	}
+	for _, tc := range []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{
+			name: "default",
+			want: `{"/app-health/grpc/readyz":{"httpGet":{"path":"/healthz","port":8080}}}`,
+		},
+		{
+			name:        "http2",
+			annotations: map[string]string{AppProbeHTTP2Annotation: "true"},
+			want:        `{"/app-health/grpc/readyz":{"httpGet":{"path":"/healthz","port":8080},"http2":true}}`,
+		},
+	} {
+		if got := DumpAppProbers(podSpec, tc.annotations); got != tc.want {
+			t.Errorf("[%v] failed, want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestValidateAppProbeHTTP2Annotation(t *testing.T) {
+	if err := validateAnnotations(map[string]string{AppProbeHTTP2Annotation: "true"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateAnnotations(map[string]string{AppProbeHTTP2Annotation: "yes"}); err == nil {
+		t.Errorf("expected an error for a non boolean value")
+	}
+}
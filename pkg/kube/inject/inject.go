@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"os"
 	"path"
@@ -59,6 +60,10 @@ import (
 
 type annotationValidationFunc func(value string) error
 
+// AccessLogSamplingAnnotation is the percentage of the access log entries emitted by the inbound listeners of the
+// sidecar, optionally overridden per port, e.g. "10,8080:50".
+const AccessLogSamplingAnnotation = "sidecar.istio.io/accessLogSampling"
+
 // per-sidecar policy and status
 var (
 	alwaysValidFunc = func(value string) error {
@@ -69,6 +74,7 @@ var (
 		annotation.SidecarInject.Name:                             alwaysValidFunc,
 		annotation.SidecarStatus.Name:                             alwaysValidFunc,
 		annotation.SidecarRewriteAppHTTPProbers.Name:              alwaysValidFunc,
+		AppProbeHTTP2Annotation:                                   validateBool,
 		annotation.SidecarControlPlaneAuthPolicy.Name:             alwaysValidFunc,
 		annotation.SidecarDiscoveryAddress.Name:                   alwaysValidFunc,
 		annotation.SidecarProxyImage.Name:                         alwaysValidFunc,
@@ -94,6 +100,7 @@ var (
 		annotation.SidecarTrafficKubevirtInterfaces.Name:          alwaysValidFunc,
 		annotation.PrometheusMergeMetrics.Name:                    validateBool,
 		annotation.ProxyConfig.Name:                               validateProxyConfig,
+		AccessLogSamplingAnnotation:                               validateAccessLogSampling,
 		"k8s.v1.cni.cncf.io/networks":                             alwaysValidFunc,
 	}
 )
@@ -302,6 +309,25 @@ func validateStatusPort(port string) error {
 	return nil
 }
 
+// validateAccessLogSampling validates the accessLogSampling annotation: a comma separated list of percentages
+// between 0 and 100, each optionally prefixed by the port it applies to.
+func validateAccessLogSampling(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		percent := strings.TrimSpace(entry)
+		if i := strings.IndexByte(percent, ':'); i >= 0 {
+			if port, err := parsePort(percent[:i]); err != nil || port == 0 {
+				return fmt.Errorf("accessLogSampling invalid port: %q", percent[:i])
+			}
+			percent = percent[i+1:]
+		}
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || math.IsNaN(p) || p < 0 || p > 100 {
+			return fmt.Errorf("accessLogSampling invalid percentage: %q", percent)
+		}
+	}
+	return nil
+}
+
 // validateUInt32 validates that the given annotation value is a positive integer.
 func validateUInt32(value string) error {
 	_, err := strconv.ParseUint(value, 10, 32)
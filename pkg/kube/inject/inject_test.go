@@ -586,3 +586,16 @@ func TestAppendMultusNetwork(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAccessLogSamplingAnnotation(t *testing.T) {
+	for _, value := range []string{"10", "0.5", "10,8080:50", " 10 , 8080:100 "} {
+		if err := validateAnnotations(map[string]string{AccessLogSamplingAnnotation: value}); err != nil {
+			t.Errorf("%q: unexpected error: %v", value, err)
+		}
+	}
+	for _, value := range []string{"", "10%", "150", "-1", "NaN", "10,http:50", "10,0:50", "10,70000:50"} {
+		if err := validateAnnotations(map[string]string{AccessLogSamplingAnnotation: value}); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
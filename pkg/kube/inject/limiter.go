@@ -0,0 +1,82 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"sync"
+)
+
+const (
+	// throttledByNamespace and throttledGlobally are the limits a throttled request exceeded.
+	throttledByNamespace = "namespace"
+	throttledGlobally    = "global"
+)
+
+// inflightLimiter limits the number of injection requests served concurrently, per namespace and in total, so that
+// a namespace creating many pods does not slow down the injection of the others. A nil limiter does not limit.
+type inflightLimiter struct {
+	// perNamespace and global are the limits, a limit of 0 or less is disabled.
+	perNamespace int
+	global       int
+
+	mu          sync.Mutex
+	total       int
+	byNamespace map[string]int
+}
+
+// newInflightLimiter returns a limiter with the limits, or nil if both are disabled.
+func newInflightLimiter(perNamespace, global int) *inflightLimiter {
+	if perNamespace <= 0 && global <= 0 {
+		return nil
+	}
+	return &inflightLimiter{
+		perNamespace: perNamespace,
+		global:       global,
+		byNamespace:  map[string]int{},
+	}
+}
+
+// acquire reserves a slot for a request of the namespace. If a limit is exceeded, it returns false and the limit,
+// and the request must be rejected. Otherwise release must be called once the request is served.
+func (l *inflightLimiter) acquire(namespace string) (bool, string) {
+	if l == nil {
+		return true, ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perNamespace > 0 && l.byNamespace[namespace] >= l.perNamespace {
+		return false, throttledByNamespace
+	}
+	if l.global > 0 && l.total >= l.global {
+		return false, throttledGlobally
+	}
+	l.byNamespace[namespace]++
+	l.total++
+	return true, ""
+}
+
+// release frees the slot reserved for a request of the namespace.
+func (l *inflightLimiter) release(namespace string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+	l.byNamespace[namespace]--
+	if l.byNamespace[namespace] <= 0 {
+		delete(l.byNamespace, namespace)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"k8s.io/api/admission/v1beta1"
+)
+
+func TestInflightLimiter(t *testing.T) {
+	if l := newInflightLimiter(0, 0); l != nil {
+		t.Fatal("expected no limiter when the limits are disabled")
+	}
+	var disabled *inflightLimiter
+	if ok, _ := disabled.acquire("ns"); !ok {
+		t.Fatal("expected a nil limiter not to limit")
+	}
+	disabled.release("ns")
+
+	l := newInflightLimiter(2, 3)
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.acquire("a"); !ok {
+			t.Fatalf("request %d of a was throttled", i)
+		}
+	}
+	if ok, limit := l.acquire("a"); ok || limit != throttledByNamespace {
+		t.Fatalf("expected the namespace limit to be exceeded, got %v %q", ok, limit)
+	}
+	if ok, _ := l.acquire("b"); !ok {
+		t.Fatal("request of b was throttled")
+	}
+	if ok, limit := l.acquire("c"); ok || limit != throttledGlobally {
+		t.Fatalf("expected the global limit to be exceeded, got %v %q", ok, limit)
+	}
+	l.release("a")
+	if ok, _ := l.acquire("c"); !ok {
+		t.Fatal("request of c was throttled after a request was released")
+	}
+
+	// Releasing concurrently leaves no slot reserved.
+	var wg sync.WaitGroup
+	for _, ns := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(ns string) {
+			defer wg.Done()
+			l.release(ns)
+		}(ns)
+	}
+	wg.Wait()
+	if l.total != 0 || len(l.byNamespace) != 0 {
+		t.Fatalf("expected no request in flight, got %d %v", l.total, l.byNamespace)
+	}
+}
+
+func makeNamespacedTestData(t *testing.T, namespace string) []byte {
+	t.Helper()
+	review := v1beta1.AdmissionReview{}
+	if err := json.Unmarshal(makeTestData(t, false, "v1beta1"), &review); err != nil {
+		t.Fatal(err)
+	}
+	review.Request.Namespace = namespace
+	b, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestInjectThrottling(t *testing.T) {
+	wh, cleanup := createWebhook(t, minimalSidecarTemplate)
+	defer cleanup()
+	wh.limiter = newInflightLimiter(50, 0)
+
+	// The batch namespace already has as many injections in flight as allowed, e.g. for a job creating many pods.
+	for i := 0; i < 50; i++ {
+		if ok, _ := wh.limiter.acquire("batch"); !ok {
+			t.Fatal("unexpected throttling")
+		}
+	}
+
+	reviews := map[string][]byte{
+		"batch":   makeNamespacedTestData(t, "batch"),
+		"default": makeNamespacedTestData(t, "default"),
+	}
+	serve := func(namespace string) int {
+		req := httptest.NewRequest("POST", "http://sidecar-injector/inject", bytes.NewReader(reviews[namespace]))
+		req.Header.Add("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		wh.serveInject(w, req)
+		// The webhook call fails, the API server applying the failurePolicy rather than retrying it.
+		if w.Code == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "" {
+			t.Errorf("unexpected Retry-After header on throttled requests")
+		}
+		return w.Code
+	}
+
+	// Flood the webhook from both namespaces.
+	var mu sync.Mutex
+	codes := map[string]map[int]int{"batch": {}, "default": {}}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, ns := range []string{"batch", "default"} {
+			wg.Add(1)
+			go func(ns string) {
+				defer wg.Done()
+				code := serve(ns)
+				mu.Lock()
+				codes[ns][code]++
+				mu.Unlock()
+			}(ns)
+		}
+	}
+	wg.Wait()
+
+	if codes["batch"][http.StatusServiceUnavailable] != 50 {
+		t.Errorf("expected all the requests of batch to be throttled, got %v", codes["batch"])
+	}
+	if codes["default"][http.StatusOK] != 50 {
+		t.Errorf("expected no request of default to be throttled, got %v", codes["default"])
+	}
+	if wh.limiter.byNamespace["default"] != 0 || wh.limiter.total != 50 {
+		t.Errorf("expected only the batch injections to be in flight, got %d %v", wh.limiter.total, wh.limiter.byNamespace)
+	}
+
+	// Once the batch injections complete, the namespace is served again.
+	for i := 0; i < 50; i++ {
+		wh.limiter.release("batch")
+	}
+	if code := serve("batch"); code != http.StatusOK {
+		t.Errorf("expected the batch namespace to be served once its injections completed, got %d", code)
+	}
+}
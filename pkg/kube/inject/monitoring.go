@@ -37,6 +37,9 @@ type monitor struct {
 }
 
 var (
+	namespaceTag = monitoring.MustCreateLabel("namespace")
+	limitTag     = monitoring.MustCreateLabel("limit")
+
 	totalInjections = monitoring.NewSum(
 		"sidecar_injection_requests_total",
 		"Total number of sidecar injection requests.",
@@ -56,6 +59,13 @@ var (
 		"sidecar_injection_skip_total",
 		"Total number of skipped sidecar injection requests.",
 	)
+
+	totalThrottledInjections = monitoring.NewSum(
+		"sidecar_injection_throttled_total",
+		"Total number of sidecar injection requests rejected because too many requests were being served "+
+			"concurrently, by namespace and by the limit exceeded.",
+		monitoring.WithLabels(namespaceTag, limitTag),
+	)
 )
 
 func init() {
@@ -64,6 +74,7 @@ func init() {
 		totalSuccessfulInjections,
 		totalFailedInjections,
 		totalSkippedInjections,
+		totalThrottledInjections,
 	)
 }
 
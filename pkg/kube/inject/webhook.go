@@ -87,6 +87,9 @@ type Webhook struct {
 	mon      *monitor
 	env      *model.Environment
 	revision string
+
+	// limiter limits the injection requests served concurrently.
+	limiter *inflightLimiter
 }
 
 //nolint directives: interfacer
@@ -147,6 +150,13 @@ type WebhookParameters struct {
 
 	// The istio.io/rev this injector is responsible for
 	Revision string
+
+	// MaxInflightPerNamespace is the maximum number of injection requests of a namespace served concurrently, and
+	// MaxInflight of all the namespaces. The requests exceeding them are rejected with a 503, which the API server
+	// does not retry: the pod creation fails with the Fail failurePolicy, and the pod is created without a sidecar
+	// with Ignore, so the limits must only be set with Fail. A limit of 0 is disabled.
+	MaxInflightPerNamespace int
+	MaxInflight             int
 }
 
 // NewWebhook creates a new instance of a mutating webhook for automatic sidecar injection.
@@ -183,6 +193,7 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 		healthCheckFile:        p.HealthCheckFile,
 		env:                    p.Env,
 		revision:               p.Revision,
+		limiter:                newInflightLimiter(p.MaxInflightPerNamespace, p.MaxInflight),
 	}
 	p.Mux.HandleFunc("/inject", wh.serveInject)
 	p.Mux.HandleFunc("/inject/", wh.serveInject)
@@ -515,7 +526,7 @@ func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, revision s
 	sidecar := FindSidecar(sic.Containers)
 	// We don't have to escape json encoding here when using golang libraries.
 	if rewrite && sidecar != nil {
-		if prober := DumpAppProbers(&pod.Spec); prober != "" {
+		if prober := DumpAppProbers(&pod.Spec, pod.Annotations); prober != "" {
 			sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: status.KubeAppProberEnvName, Value: prober})
 		}
 	}
@@ -882,6 +893,21 @@ func (wh *Webhook) serveInject(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			handleError(fmt.Sprintf("Could not decode object: %v", err))
 		}
+		namespace := ""
+		if ar != nil && ar.Request != nil {
+			namespace = ar.Request.Namespace
+		}
+		if ok, limit := wh.limiter.acquire(namespace); !ok {
+			totalThrottledInjections.With(namespaceTag.Value(namespace), limitTag.Value(limit)).Increment()
+			log.Warnf("Throttling sidecar injection request for namespace %s, too many requests in flight (%s limit)",
+				namespace, limit)
+			// The API server does not retry the request: the webhook call fails, and with the Fail failurePolicy,
+			// which throttling requires, the pod creation is rejected and retried by the controller of the pod.
+			http.Error(w, fmt.Sprintf("too many injection requests in flight (%s limit)", limit),
+				http.StatusServiceUnavailable)
+			return
+		}
+		defer wh.limiter.release(namespace)
 		reviewResponse = wh.inject(ar, path)
 	}
 
@@ -16,6 +16,8 @@ package security
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
@@ -87,6 +89,14 @@ type Options struct {
 	// The CA provider name.
 	CAProviderName string
 
+	// CAEndpoints is an ordered list of CAs to send CSRs to. CSRs go to the CA which last succeeded,
+	// failing over to the others in order. If empty, only CAEndpoint and CAProviderName are used.
+	CAEndpoints []CAEndpoint
+
+	// CAPrimaryReprobeInterval is how long CSRs go to a fallback CA before the first of CAEndpoints
+	// is tried again.
+	CAPrimaryReprobeInterval time.Duration
+
 	// TrustDomain corresponds to the trust root of a system.
 	// https://github.com/spiffe/spiffe/blob/master/standards/SPIFFE-ID.md#21-trust-domain
 	TrustDomain string
@@ -179,6 +189,16 @@ type Options struct {
 	// Key rotation job running interval.
 	RotationInterval time.Duration
 
+	// CertNotBeforeTolerance is how far in the future the NotBefore of a certificate issued by the CA may be, because
+	// of clock skew, for the certificate to be used. Certificates valid later than that are rejected.
+	CertNotBeforeTolerance time.Duration
+
+	// CachedCertsStartTimeout is how long the agent waits for the CA at startup before using the valid workload
+	// certificate cached in OutputKeyCertToDir, or provisioned in ProvCert, if any. The CA is then retried in the
+	// background until the certificate is replaced. If 0, the agent always waits for the CA.
+	// Only the certificate is cached: the XDS configuration of Envoy is not, and is received once istiod is reachable.
+	CachedCertsStartTimeout time.Duration
+
 	// Cached secret will be removed from cache if (time.now - secretItem.CreatedTime >= evictionDuration), this prevents cache growing indefinitely.
 	EvictionDuration time.Duration
 
@@ -199,6 +219,56 @@ type Options struct {
 	SkipParseToken bool
 }
 
+// CAEndpoint is one of the CAs the agent may send CSRs to.
+type CAEndpoint struct {
+	// Address of the CA.
+	Address string
+	// ProviderName is the type of the CA, as in CA_PROVIDER. Defaults to Citadel.
+	ProviderName string
+	// RootCertFile is used to verify the CA. Defaults to the root certificate used for CAEndpoint.
+	RootCertFile string
+	// Timeout bounds a single CSR to this CA, so that an unreachable CA fails over promptly.
+	Timeout time.Duration
+}
+
+// ParseCAEndpoints parses a JSON list of CAs, in order of preference, for example:
+// [{"address": "ca.example.com:443", "provider": "Custom", "rootCert": "/etc/ca/root-cert.pem", "timeout": "5s"},
+// {"address": "istiod.istio-system.svc:15012"}]
+func ParseCAEndpoints(in string) ([]CAEndpoint, error) {
+	if in == "" {
+		return nil, nil
+	}
+	var raw []struct {
+		Address  string `json:"address"`
+		Provider string `json:"provider"`
+		RootCert string `json:"rootCert"`
+		Timeout  string `json:"timeout"`
+	}
+	if err := json.Unmarshal([]byte(in), &raw); err != nil {
+		return nil, fmt.Errorf("invalid CA endpoints: %v", err)
+	}
+	out := make([]CAEndpoint, 0, len(raw))
+	for i, r := range raw {
+		if r.Address == "" {
+			return nil, fmt.Errorf("invalid CA endpoint %d: address is required", i)
+		}
+		ep := CAEndpoint{
+			Address:      r.Address,
+			ProviderName: r.Provider,
+			RootCertFile: r.RootCert,
+		}
+		if r.Timeout != "" {
+			timeout, err := time.ParseDuration(r.Timeout)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("invalid CA endpoint %s: invalid timeout %q", r.Address, r.Timeout)
+			}
+			ep.Timeout = timeout
+		}
+		out = append(out, ep)
+	}
+	return out, nil
+}
+
 // Client interface defines the clients need to implement to talk to CA for CSR.
 // The Agent will create a key pair and a CSR, and use an implementation of this
 // interface to get back a signed certificate. There is no guarantee that the SAN
@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spiffe
+
+import (
+	"crypto/x509"
+	"math/rand"
+	"time"
+
+	"istio.io/pkg/monitoring"
+)
+
+// bundleRefreshJitter is the maximum fraction of the refresh interval added to each wait, so that the
+// instances of a deployment do not fetch the bundles at the same time.
+const bundleRefreshJitter = 0.1
+
+var (
+	trustDomainTag = monitoring.MustCreateLabel("trust_domain")
+
+	bundleRefreshTimestamp = monitoring.NewGauge(
+		"spiffe_bundle_last_refresh_timestamp",
+		"The unix timestamp, in seconds, of the last successful refresh of the SPIFFE bundle of a trust domain.",
+		monitoring.WithLabels(trustDomainTag),
+	)
+
+	bundleRefreshFailures = monitoring.NewSum(
+		"spiffe_bundle_refresh_failures",
+		"The number of failed refreshes of the SPIFFE bundle of a trust domain.",
+		monitoring.WithLabels(trustDomainTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(
+		bundleRefreshTimestamp,
+		bundleRefreshFailures,
+	)
+}
+
+// BundleRefresher periodically re-fetches the SPIFFE bundle endpoints of trust domains, and updates the root
+// certificates of a PeerCertVerifier. If the bundle of a trust domain cannot be fetched, its previous root
+// certificates are kept.
+type BundleRefresher struct {
+	endpoints         map[string]string
+	extraTrustedCerts []*x509.Certificate
+	verifier          *PeerCertVerifier
+	interval          time.Duration
+	jitter            float64
+}
+
+// NewBundleRefresher creates a BundleRefresher fetching the bundles of the trust domain to endpoint mappings
+// every interval, trusting the system cert pool and extraTrustedCerts for the endpoints. If the interval is
+// not positive, the bundles are not fetched again.
+func NewBundleRefresher(endpoints map[string]string, extraTrustedCerts []*x509.Certificate,
+	verifier *PeerCertVerifier, interval time.Duration) *BundleRefresher {
+	return &BundleRefresher{
+		endpoints:         endpoints,
+		extraTrustedCerts: extraTrustedCerts,
+		verifier:          verifier,
+		interval:          interval,
+		jitter:            bundleRefreshJitter,
+	}
+}
+
+// Run refreshes the bundles until stop is closed.
+func (r *BundleRefresher) Run(stop <-chan struct{}) {
+	// Without an interval, tick is nil and never fires.
+	var timer *time.Timer
+	var tick <-chan time.Time
+	if r.interval > 0 {
+		timer = time.NewTimer(r.nextRefresh())
+		defer timer.Stop()
+		tick = timer.C
+	}
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick:
+			r.Refresh()
+			timer.Reset(r.nextRefresh())
+		}
+	}
+}
+
+func (r *BundleRefresher) nextRefresh() time.Duration {
+	if maxJitter := int64(float64(r.interval) * r.jitter); maxJitter > 0 {
+		return r.interval + time.Duration(rand.Int63n(maxJitter))
+	}
+	return r.interval
+}
+
+// Refresh fetches the bundle of each trust domain, and updates the verifier with the ones fetched successfully.
+func (r *BundleRefresher) Refresh() {
+	certMap := make(map[string][]*x509.Certificate, len(r.endpoints))
+	for trustDomain, endpoint := range r.endpoints {
+		certs, err := RetrieveSpiffeBundleRootCerts(map[string]string{trustDomain: endpoint}, r.extraTrustedCerts)
+		if err != nil {
+			spiffeLog.Warnf("failed to refresh the SPIFFE bundle of trust domain %s, keeping the previous bundle: %v",
+				trustDomain, err)
+			bundleRefreshFailures.With(trustDomainTag.Value(trustDomain)).Increment()
+			continue
+		}
+		certMap[trustDomain] = certs[trustDomain]
+		bundleRefreshTimestamp.With(trustDomainTag.Value(trustDomain)).Record(float64(time.Now().Unix()))
+	}
+	if len(certMap) > 0 {
+		r.verifier.UpdateMappings(certMap)
+	}
+}
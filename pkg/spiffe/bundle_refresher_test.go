@@ -0,0 +1,158 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spiffe
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+func parseTestCert(t *testing.T, certPem string) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode([]byte(certPem))
+	if block == nil {
+		t.Fatalf("failed to decode PEM cert")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func testBundle(t *testing.T, cert *x509.Certificate) []byte {
+	t.Helper()
+	doc := bundleDoc{JSONWebKeySet: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:          cert.PublicKey,
+		Certificates: []*x509.Certificate{cert},
+		Use:          "x509-svid",
+	}}}}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBundleRefresher(t *testing.T) {
+	oldRetryTimeout := totalRetryTimeout
+	totalRetryTimeout = time.Millisecond * 50
+	t.Cleanup(func() { totalRetryTimeout = oldRetryTimeout })
+
+	rootCert, rootCert2 := parseTestCert(t, validRootCert), parseTestCert(t, validRootCert2)
+
+	// The bundle of foo is rotated after the first fetch.
+	var fooFetches int32
+	foo := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&fooFetches, 1) == 1 {
+			_, _ = w.Write(testBundle(t, rootCert))
+		} else {
+			_, _ = w.Write(testBundle(t, rootCert2))
+		}
+	}))
+	defer foo.Close()
+	// The bundle endpoint of bar fails after the first fetch.
+	var barFetches int32
+	bar := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&barFetches, 1) == 1 {
+			_, _ = w.Write(testBundle(t, rootCert))
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer bar.Close()
+
+	endpoints := map[string]string{
+		"foo.domain.com": foo.Listener.Addr().String(),
+		"bar.domain.com": bar.Listener.Addr().String(),
+	}
+	trustedCerts := []*x509.Certificate{foo.Certificate(), bar.Certificate()}
+	certMap, err := RetrieveSpiffeBundleRootCerts(endpoints, trustedCerts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewPeerCertVerifier()
+	verifier.AddMapping("cluster.local", []*x509.Certificate{rootCert2})
+	verifier.UpdateMappings(certMap)
+	generalCertPool := verifier.GetGeneralCertPool()
+
+	expectCerts := func(want map[string]*x509.Certificate) {
+		t.Helper()
+		got := verifier.TrustDomainCerts()
+		if len(got) != len(want) {
+			t.Fatalf("got trust domains %v, want %v", got, want)
+		}
+		for trustDomain, cert := range want {
+			if len(got[trustDomain]) != 1 || !got[trustDomain][0].Equal(cert) {
+				t.Errorf("got certs %v for trust domain %s, want %v", got[trustDomain], trustDomain, cert.Subject)
+			}
+		}
+	}
+	expectCerts(map[string]*x509.Certificate{
+		"cluster.local":  rootCert2,
+		"foo.domain.com": rootCert,
+		"bar.domain.com": rootCert,
+	})
+
+	r := NewBundleRefresher(endpoints, trustedCerts, verifier, time.Minute)
+	r.Refresh()
+	// foo is rotated, and bar keeps its previous bundle.
+	expectCerts(map[string]*x509.Certificate{
+		"cluster.local":  rootCert2,
+		"foo.domain.com": rootCert2,
+		"bar.domain.com": rootCert,
+	})
+	if atomic.LoadInt32(&barFetches) < 2 {
+		t.Errorf("expected the bundle of bar to be fetched again")
+	}
+	if verifier.GetGeneralCertPool() == generalCertPool {
+		t.Errorf("expected the general cert pool to be replaced")
+	}
+}
+
+func TestBundleRefresherJitter(t *testing.T) {
+	r := NewBundleRefresher(nil, nil, NewPeerCertVerifier(), time.Minute)
+	for i := 0; i < 100; i++ {
+		if next := r.nextRefresh(); next < time.Minute || next >= time.Minute+6*time.Second {
+			t.Fatalf("refresh in %v is out of the jitter range", next)
+		}
+	}
+}
+
+func TestUpdateMappingsKeepsAddedCerts(t *testing.T) {
+	rootCert, rootCert2 := parseTestCert(t, validRootCert), parseTestCert(t, validRootCert2)
+	verifier := NewPeerCertVerifier()
+	verifier.AddMapping("cluster.local", []*x509.Certificate{rootCert})
+
+	// The bundle of the local trust domain is merged with its root certificate.
+	verifier.UpdateMappings(map[string][]*x509.Certificate{"cluster.local": {rootCert2}})
+	if got := verifier.TrustDomainCerts()["cluster.local"]; len(got) != 2 || !got[0].Equal(rootCert) || !got[1].Equal(rootCert2) {
+		t.Fatalf("got certs %v, want the added and the bundle certs", got)
+	}
+
+	// A refresh replaces the bundle certs only.
+	verifier.UpdateMappings(map[string][]*x509.Certificate{"cluster.local": {rootCert}})
+	if got := verifier.TrustDomainCerts()["cluster.local"]; len(got) != 1 || !got[0].Equal(rootCert) {
+		t.Fatalf("got certs %v, want only the added cert", got)
+	}
+}
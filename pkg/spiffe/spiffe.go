@@ -17,7 +17,9 @@ package spiffe
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -172,6 +174,16 @@ func GetTrustDomainFromURISAN(uriSan string) (string, error) {
 func RetrieveSpiffeBundleRootCertsFromStringInput(inputString string, extraTrustedCerts []*x509.Certificate) (
 	map[string][]*x509.Certificate, error) {
 	spiffeLog.Infof("Processing SPIFFE bundle configuration: %v", inputString)
+	config, err := ParseSpiffeBundleEndpoints(inputString)
+	if err != nil {
+		return nil, err
+	}
+	return RetrieveSpiffeBundleRootCerts(config, extraTrustedCerts)
+}
+
+// ParseSpiffeBundleEndpoints parses SPIFFE bundle endpoints in the format of "foo|URL1||bar|URL2||baz|URL3..."
+// into a trust domain to endpoint map.
+func ParseSpiffeBundleEndpoints(inputString string) (map[string]string, error) {
 	config := make(map[string]string)
 	tuples := strings.Split(inputString, "||")
 	for _, tuple := range tuples {
@@ -183,7 +195,7 @@ func RetrieveSpiffeBundleRootCertsFromStringInput(inputString string, extraTrust
 		endpoint := items[1]
 		config[trustDomain] = endpoint
 	}
-	return RetrieveSpiffeBundleRootCerts(config, extraTrustedCerts)
+	return config, nil
 }
 
 // RetrieveSpiffeBundleRootCerts retrieves the trusted CA certificates from a list of SPIFFE bundle endpoints.
@@ -278,10 +290,51 @@ func RetrieveSpiffeBundleRootCerts(config map[string]string, extraTrustedCerts [
 	return ret, nil
 }
 
+// ErrCertificateRevoked is returned by VerifyPeerCert, wrapped, when the peer certificate or an intermediate
+// certificate of its chain is revoked by the revocation list of the verifier.
+var ErrCertificateRevoked = errors.New("certificate revoked")
+
+// revocationList holds the serial numbers of the certificates revoked by a CRL.
+type revocationList struct {
+	crl     *pkix.CertificateList
+	serials map[string]struct{}
+}
+
+func newRevocationList(crl *pkix.CertificateList) *revocationList {
+	rl := &revocationList{
+		crl:     crl,
+		serials: make(map[string]struct{}, len(crl.TBSCertList.RevokedCertificates)),
+	}
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		rl.serials[revoked.SerialNumber.String()] = struct{}{}
+	}
+	return rl
+}
+
+// revoked returns true if the certificate, issued by issuer, is revoked. The revocation only applies if the list
+// is signed by the issuer of the certificate. The issuer names are not compared: the encoding of the name of the
+// CRL, e.g. UTF8String rather than PrintableString, may differ from the one of the certificate.
+func (rl *revocationList) revoked(cert, issuer *x509.Certificate) bool {
+	if rl == nil {
+		return false
+	}
+	if _, f := rl.serials[cert.SerialNumber.String()]; !f {
+		return false
+	}
+	return issuer.CheckCRLSignature(rl.crl) == nil
+}
+
 // PeerCertVerifier is an instance to verify the peer certificate in the SPIFFE way using the retrieved root certificates.
+// It is safe for concurrent use, so that the mappings can be updated while verifying peer certificates.
 type PeerCertVerifier struct {
+	mutex           sync.RWMutex
 	generalCertPool *x509.CertPool
 	certPools       map[string]*x509.CertPool
+	certs           map[string][]*x509.Certificate
+	// bundleCerts are the certificates of the SPIFFE bundles by trust domain, replaced on each refresh. They are
+	// merged with the certs added for the same trust domain.
+	bundleCerts    map[string][]*x509.Certificate
+	revocationList *revocationList
 }
 
 // NewPeerCertVerifier returns a new PeerCertVerifier.
@@ -289,16 +342,22 @@ func NewPeerCertVerifier() *PeerCertVerifier {
 	return &PeerCertVerifier{
 		generalCertPool: x509.NewCertPool(),
 		certPools:       make(map[string]*x509.CertPool),
+		certs:           make(map[string][]*x509.Certificate),
+		bundleCerts:     make(map[string][]*x509.Certificate),
 	}
 }
 
 // GetGeneralCertPool returns generalCertPool containing all root certs.
 func (v *PeerCertVerifier) GetGeneralCertPool() *x509.CertPool {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
 	return v.generalCertPool
 }
 
 // AddMapping adds a new trust domain to certificates mapping to the certPools map.
 func (v *PeerCertVerifier) AddMapping(trustDomain string, certs []*x509.Certificate) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
 	if v.certPools[trustDomain] == nil {
 		v.certPools[trustDomain] = x509.NewCertPool()
 	}
@@ -306,6 +365,7 @@ func (v *PeerCertVerifier) AddMapping(trustDomain string, certs []*x509.Certific
 		v.certPools[trustDomain].AddCert(cert)
 		v.generalCertPool.AddCert(cert)
 	}
+	v.certs[trustDomain] = append(v.certs[trustDomain], certs...)
 	spiffeLog.Infof("Added %d certs to trust domain %s in peer cert verifier", len(certs), trustDomain)
 }
 
@@ -316,8 +376,85 @@ func (v *PeerCertVerifier) AddMappings(certMap map[string][]*x509.Certificate) {
 	}
 }
 
+// UpdateMappings replaces the SPIFFE bundle certificates of the trust domains in certMap, keeping the other trust
+// domains. The certificates added with AddMapping for the same trust domains are kept as well.
+// The update is atomic: a peer certificate is verified either before or after all the trust domains are updated.
+func (v *PeerCertVerifier) UpdateMappings(certMap map[string][]*x509.Certificate) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for trustDomain, certs := range certMap {
+		pool := x509.NewCertPool()
+		for _, cert := range v.certs[trustDomain] {
+			pool.AddCert(cert)
+		}
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+		v.certPools[trustDomain] = pool
+		v.bundleCerts[trustDomain] = append([]*x509.Certificate{}, certs...)
+	}
+	v.rebuildGeneralCertPoolLocked()
+}
+
+func (v *PeerCertVerifier) rebuildGeneralCertPoolLocked() {
+	// The general cert pool may be in use by a TLS config, so it is rebuilt rather than modified.
+	generalCertPool := x509.NewCertPool()
+	for _, certMap := range []map[string][]*x509.Certificate{v.certs, v.bundleCerts} {
+		for _, certs := range certMap {
+			for _, cert := range certs {
+				generalCertPool.AddCert(cert)
+			}
+		}
+	}
+	v.generalCertPool = generalCertPool
+}
+
+// TrustDomainCerts returns a copy of the root certificates by trust domain.
+func (v *PeerCertVerifier) TrustDomainCerts() map[string][]*x509.Certificate {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	out := make(map[string][]*x509.Certificate, len(v.certs))
+	for trustDomain, certs := range v.certs {
+		out[trustDomain] = append([]*x509.Certificate{}, certs...)
+	}
+	for trustDomain, certs := range v.bundleCerts {
+		for _, cert := range certs {
+			if !containsCert(out[trustDomain], cert) {
+				out[trustDomain] = append(out[trustDomain], cert)
+			}
+		}
+	}
+	return out
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRevocationList sets the CRL the peer certificates are checked against, replacing the previous one. A nil CRL
+// disables the revocation checks.
+func (v *PeerCertVerifier) SetRevocationList(crl *pkix.CertificateList) {
+	var rl *revocationList
+	if crl != nil {
+		rl = newRevocationList(crl)
+		if crl.HasExpired(time.Now()) {
+			spiffeLog.Warnf("CRL of %s is past its next update %v", crl.TBSCertList.Issuer, crl.TBSCertList.NextUpdate)
+		}
+		spiffeLog.Infof("Loaded CRL of %s revoking %d certs", crl.TBSCertList.Issuer, len(rl.serials))
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.revocationList = rl
+}
+
 // VerifyPeerCert is an implementation of tls.Config.VerifyPeerCertificate.
-// It verifies the peer certificate using the root certificates associated with its trust domain.
+// It verifies the peer certificate using the root certificates associated with its trust domain, and that neither
+// it nor the intermediate certificates of its chain are revoked, if a revocation list is set.
 func (v *PeerCertVerifier) VerifyPeerCert(rawCerts [][]byte, _ [][]*x509.Certificate) error {
 	if len(rawCerts) == 0 {
 		// Peer doesn't present a certificate. Just skip. Other authn methods may be used.
@@ -343,14 +480,27 @@ func (v *PeerCertVerifier) VerifyPeerCert(rawCerts [][]byte, _ [][]*x509.Certifi
 	if err != nil {
 		return err
 	}
+	v.mutex.RLock()
 	rootCertPool, ok := v.certPools[trustDomain]
+	rl := v.revocationList
+	v.mutex.RUnlock()
 	if !ok {
 		return fmt.Errorf("no cert pool found for trust domain %s", trustDomain)
 	}
 
-	_, err = peerCert.Verify(x509.VerifyOptions{
+	chains, err := peerCert.Verify(x509.VerifyOptions{
 		Roots:         rootCertPool,
 		Intermediates: intCertPool,
 	})
-	return err
+	if err != nil || rl == nil {
+		return err
+	}
+	for _, chain := range chains {
+		for i := 0; i+1 < len(chain); i++ {
+			if rl.revoked(chain[i], chain[i+1]) {
+				return fmt.Errorf("%w: serial number %x issued by %q", ErrCertificateRevoked, chain[i].SerialNumber, chain[i].Issuer)
+			}
+		}
+	}
+	return nil
 }
@@ -16,11 +16,19 @@ package spiffe
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
+	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
@@ -635,6 +643,157 @@ func TestGetGeneralCertPoolAndVerifyPeerCert(t *testing.T) {
 	}
 }
 
+// newTestCert returns a certificate with the serial number, signed by the parent with its key, or self-signed if
+// parent is nil.
+func newTestCert(t *testing.T, serial int64, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+	isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{Organization: []string{"cluster.local"}},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	if !isCA {
+		tmpl.URIs = []*url.URL{{Scheme: "spiffe", Host: "cluster.local", Path: "/ns/foo/sa/bar"}}
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestVerifyPeerCertRevoked(t *testing.T) {
+	ca, caKey := newTestCert(t, 1, nil, nil, true)
+	// otherCA has the same subject as ca, so that its CRLs have the issuer of the certificates signed by ca.
+	otherCA, otherCAKey := newTestCert(t, 1, nil, nil, true)
+	revoked, _ := newTestCert(t, 2, ca, caKey, false)
+	valid, _ := newTestCert(t, 3, ca, caKey, false)
+
+	crl := func(ca *x509.Certificate, key *ecdsa.PrivateKey) *pkix.CertificateList {
+		der, err := ca.CreateCRL(rand.Reader, key, []pkix.RevokedCertificate{
+			{SerialNumber: revoked.SerialNumber, RevocationTime: time.Now()},
+		}, time.Now(), time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := x509.ParseCRL(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	verifier := NewPeerCertVerifier()
+	verifier.AddMapping("cluster.local", []*x509.Certificate{ca})
+
+	cases := []struct {
+		name        string
+		crl         *pkix.CertificateList
+		cert        *x509.Certificate
+		wantRevoked bool
+	}{
+		{"no CRL", nil, revoked, false},
+		{"revoked", crl(ca, caKey), revoked, true},
+		{"not revoked", crl(ca, caKey), valid, false},
+		{"CRL not signed by the issuer", crl(otherCA, otherCAKey), revoked, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier.SetRevocationList(tt.crl)
+			err := verifier.VerifyPeerCert([][]byte{tt.cert.Raw}, nil)
+			if tt.wantRevoked {
+				if !errors.Is(err, ErrCertificateRevoked) {
+					t.Fatalf("expected the certificate to be rejected as revoked, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestVerifyPeerCertRevokedUTF8Issuer tests a CRL whose issuer name is not encoded like the issuer name of the
+// certificates, as OpenSSL encodes the names in UTF8String while Go encodes them in PrintableString.
+func TestVerifyPeerCertRevokedUTF8Issuer(t *testing.T) {
+	type attribute struct {
+		Type  asn1.ObjectIdentifier
+		Value string `asn1:"utf8"`
+	}
+	type rdnSET []attribute
+	rawSubject, err := asn1.Marshal([]rdnSET{{{Type: asn1.ObjectIdentifier{2, 5, 4, 10}, Value: "cluster.local"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		RawSubject:            rawSubject,
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, _ := newTestCert(t, 2, ca, caKey, false)
+	if !bytes.Equal(revoked.RawIssuer, rawSubject) {
+		t.Fatal("expected the issuer of the certificate to be encoded in UTF8String")
+	}
+
+	// The issuer of the CRL is re-encoded from the parsed name, in PrintableString.
+	der, err := ca.CreateCRL(rand.Reader, caKey, []pkix.RevokedCertificate{
+		{SerialNumber: revoked.SerialNumber, RevocationTime: time.Now()},
+	}, time.Now(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := x509.ParseCRL(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawCRLIssuer, err := asn1.Marshal(crl.TBSCertList.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(rawCRLIssuer, rawSubject) {
+		t.Fatal("expected the issuer of the CRL to be encoded differently from the issuer of the certificate")
+	}
+
+	verifier := NewPeerCertVerifier()
+	verifier.AddMapping("cluster.local", []*x509.Certificate{ca})
+	verifier.SetRevocationList(crl)
+	if err := verifier.VerifyPeerCert([][]byte{revoked.Raw}, nil); !errors.Is(err, ErrCertificateRevoked) {
+		t.Fatalf("expected the certificate to be rejected as revoked, got %v", err)
+	}
+}
+
 func TestExpandWithTrustDomains(t *testing.T) {
 	testCases := []struct {
 		name         string
@@ -75,6 +75,22 @@ func patchMutatingWebhookConfig(client admissionregistrationv1beta1client.Mutati
 	return err
 }
 
+// FailurePolicyIgnore returns whether the webhook of the specified webhook config ignores the failures of the
+// webhook, admitting the requests it fails or rejects. The failurePolicy defaults to Ignore in v1beta1.
+func FailurePolicyIgnore(client kubernetes.Interface, webhookConfigName, webhookName string) (bool, error) {
+	config, err := client.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().
+		Get(context.TODO(), webhookConfigName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, w := range config.Webhooks {
+		if w.Name == webhookName {
+			return w.FailurePolicy == nil || *w.FailurePolicy == v1beta1.Ignore, nil
+		}
+	}
+	return false, fmt.Errorf("webhook entry %q not found in config %q", webhookName, webhookConfigName)
+}
+
 const delayedRetryTime = time.Second
 
 // Moved out of injector main. Changes:
@@ -108,3 +108,37 @@ func TestMutatingWebhookPatch(t *testing.T) {
 		})
 	}
 }
+
+func TestFailurePolicyIgnore(t *testing.T) {
+	fail := admissionregistrationv1beta1.Fail
+	ignore := admissionregistrationv1beta1.Ignore
+	ts := []struct {
+		name          string
+		failurePolicy *admissionregistrationv1beta1.FailurePolicyType
+		want          bool
+	}{
+		{"default", nil, true},
+		{"ignore", &ignore, true},
+		{"fail", &fail, false},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(&admissionregistrationv1beta1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "config1"},
+				Webhooks: []admissionregistrationv1beta1.MutatingWebhook{
+					{Name: "webhook1", FailurePolicy: tc.failurePolicy},
+				},
+			})
+			got, err := FailurePolicyIgnore(client, "config1", "webhook1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+	if _, err := FailurePolicyIgnore(fake.NewSimpleClientset(), "config1", "webhook1"); err == nil {
+		t.Fatal("expected an error for a missing config")
+	}
+}
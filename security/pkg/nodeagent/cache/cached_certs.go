@@ -0,0 +1,245 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+	nodeagentutil "istio.io/istio/security/pkg/nodeagent/util"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
+	"istio.io/istio/security/pkg/util"
+)
+
+// generatedSecret is the result of the generation of a secret.
+type generatedSecret struct {
+	secret *security.SecretItem
+	err    error
+}
+
+// generateSecretOrCached generates the secret. For the first workload certificate, if the CA does not respond
+// within CachedCertsStartTimeout, the valid certificate cached in OutputKeyCertToDir or provisioned in ProvCert is
+// returned instead, with the channel the generated secret is sent on, which must replace it. Expired cached
+// certificates are never used: the generation is then waited for.
+//
+// Only the workload certificate is cached. The Envoy bootstrap does not depend on the control plane, being generated
+// locally at each start, while the XDS configuration is not cached: Envoy starts without listeners and clusters
+// until istiod is reachable.
+func (sc *SecretCache) generateSecretOrCached(ctx context.Context, token string, connKey ConnKey,
+	t time.Time) (*security.SecretItem, <-chan generatedSecret, error) {
+	timeout := sc.configOptions.CachedCertsStartTimeout
+	if timeout == 0 || connKey.ResourceName != WorkloadKeyCertResourceName || atomic.LoadInt32(&sc.started) == 1 {
+		ns, err := sc.generateSecret(ctx, token, connKey, t)
+		return ns, nil, err
+	}
+
+	generated := make(chan generatedSecret, 1)
+	go func() {
+		ns, err := sc.generateSecret(ctx, token, connKey, t)
+		generated <- generatedSecret{secret: ns, err: err}
+	}()
+	select {
+	case g := <-generated:
+		return sc.startedWith(g)
+	case <-time.After(timeout):
+	}
+
+	cached, err := sc.loadCachedCerts(token, connKey, time.Now())
+	if err != nil {
+		cacheLog.Warnf("the CA did not respond within %v, and no cached certificate can be used: %v", timeout, err)
+		return sc.startedWith(<-generated)
+	}
+	cacheLog.Warnf("the CA did not respond within %v, starting in degraded mode with the cached certificate "+
+		"valid until %v, the CA is retried in the background; the XDS configuration is not cached",
+		timeout, cached.ExpireTime)
+	atomic.StoreInt32(&sc.degraded, 1)
+	atomic.StoreInt32(&sc.started, 1)
+	certStartMode.With(startMode.Value(degradedStart)).Increment()
+	return cached, generated, nil
+}
+
+// startedWith records the start with the certificate issued by the CA, if it was generated.
+func (sc *SecretCache) startedWith(g generatedSecret) (*security.SecretItem, <-chan generatedSecret, error) {
+	if g.err == nil {
+		atomic.StoreInt32(&sc.started, 1)
+		certStartMode.With(startMode.Value(normalStart)).Increment()
+	}
+	return g.secret, nil, g.err
+}
+
+// replaceCachedSecret replaces the cached certificate used in degraded mode with the secret generated once the CA
+// responds. If the generation failed, the rotation job keeps retrying.
+func (sc *SecretCache) replaceCachedSecret(connKey ConnKey, generated <-chan generatedSecret) {
+	logPrefix := cacheLogPrefix(connKey.ResourceName)
+	g := <-generated
+	if g.err != nil {
+		cacheLog.Warnf("%s the CA is still unreachable, keeping the cached certificate: %v", logPrefix, g.err)
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&sc.degraded, 1, 0) {
+		// already replaced by the rotation job
+		return
+	}
+	if err := nodeagentutil.OutputKeyCertToDir(sc.configOptions.OutputKeyCertToDir, g.secret.PrivateKey,
+		g.secret.CertificateChain, g.secret.RootCert); err != nil {
+		cacheLog.Errorf("%s error when output the key and cert: %v", logPrefix, err)
+	}
+	sc.secrets.Store(connKey, *g.secret)
+	sc.callbackWithTimeout(connKey, g.secret)
+	cacheLog.Infof("%s the CA is reachable, the cached certificate is replaced", logPrefix)
+}
+
+// loadCachedCerts returns the workload key and certificate cached in OutputKeyCertToDir, or else provisioned in
+// ProvCert, if the certificate is valid at now and issued to the requested identity. Their root certificate is used if none was received from the CA.
+func (sc *SecretCache) loadCachedCerts(token string, connKey ConnKey, now time.Time) (*security.SecretItem, error) {
+	var errs []error
+	for _, dir := range []string{sc.configOptions.OutputKeyCertToDir, sc.configOptions.ProvCert} {
+		if dir == "" {
+			continue
+		}
+		ns, err := sc.loadCachedCertsFromDir(dir, token, connKey, now)
+		if err == nil {
+			return ns, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %v", dir, err))
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("neither OUTPUT_CERTS nor PROV_CERT is set")
+	}
+	return nil, fmt.Errorf("%v", errs)
+}
+
+func (sc *SecretCache) loadCachedCertsFromDir(dir, token string, connKey ConnKey, now time.Time) (*security.SecretItem, error) {
+	certChain, err := ioutil.ReadFile(filepath.Join(dir, "cert-chain.pem"))
+	if err != nil {
+		return nil, err
+	}
+	key, err := ioutil.ReadFile(filepath.Join(dir, "key.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tls.X509KeyPair(certChain, key); err != nil {
+		return nil, fmt.Errorf("invalid key pair: %v", err)
+	}
+	expireTime, err := nodeagentutil.ParseCertAndGetExpiryTimestamp(certChain)
+	if err != nil {
+		return nil, err
+	}
+	if !now.Before(expireTime) {
+		return nil, fmt.Errorf("the certificate expired at %v", expireTime)
+	}
+	notBefore, err := nodeagentutil.ParseCertAndGetNotBeforeTimestamp(certChain)
+	if err != nil {
+		return nil, err
+	}
+	if notBefore.After(now.Add(sc.configOptions.CertNotBeforeTolerance)) {
+		return nil, fmt.Errorf("the certificate is only valid from %v", notBefore)
+	}
+	if err := sc.checkCachedIdentity(certChain, token); err != nil {
+		return nil, err
+	}
+
+	if rootCert, _ := sc.getRootCert(); rootCert == nil {
+		rootCert, err := ioutil.ReadFile(filepath.Join(dir, "root-cert.pem"))
+		if err != nil {
+			return nil, err
+		}
+		rootCertExpireTime, err := nodeagentutil.ParseCertAndGetExpiryTimestamp(rootCert)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root certificate: %v", err)
+		}
+		sc.setRootCert(rootCert, rootCertExpireTime)
+	}
+
+	return &security.SecretItem{
+		CertificateChain: certChain,
+		PrivateKey:       key,
+		ResourceName:     connKey.ResourceName,
+		Token:            token,
+		CreatedTime:      notBefore,
+		ExpireTime:       expireTime,
+		Version:          now.Format("01-02 15:04:05.000"),
+	}, nil
+}
+
+// checkCachedIdentity checks that the certificate is issued to the identity the CA would issue it to: the service
+// account of the token, in the trust domain if it is set, or else the identity of the certificate provisioned in
+// ProvCert, which authenticates to the CA without a token.
+func (sc *SecretCache) checkCachedIdentity(certChain []byte, token string) error {
+	ids, err := certIDs(certChain)
+	if err != nil {
+		return err
+	}
+	namespace, sa, tokenErr := util.GetServiceAccount(token)
+	if tokenErr == nil {
+		path := "/ns/" + namespace + "/sa/" + sa
+		for _, id := range ids {
+			trustDomain, idPath, ok := splitSpiffeID(id)
+			if ok && idPath == path && (sc.configOptions.TrustDomain == "" || trustDomain == sc.configOptions.TrustDomain) {
+				return nil
+			}
+		}
+		return fmt.Errorf("the certificate is issued to %v, not to the service account %s/%s of the token", ids, namespace, sa)
+	}
+	if sc.configOptions.ProvCert == "" {
+		return fmt.Errorf("the requested identity is unknown: %v", tokenErr)
+	}
+	provCert, err := ioutil.ReadFile(filepath.Join(sc.configOptions.ProvCert, "cert-chain.pem"))
+	if err != nil {
+		return fmt.Errorf("the requested identity is unknown: %v", err)
+	}
+	provIDs, err := certIDs(provCert)
+	if err != nil {
+		return fmt.Errorf("the requested identity is unknown: %v", err)
+	}
+	for _, id := range ids {
+		for _, provID := range provIDs {
+			if id == provID {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the certificate is issued to %v, not to the identity %v of PROV_CERT", ids, provIDs)
+}
+
+// certIDs returns the SAN identities of the leaf certificate of the chain.
+func certIDs(certChain []byte) ([]string, error) {
+	cert, err := pkiutil.ParsePemEncodedCertificate(certChain)
+	if err != nil {
+		return nil, err
+	}
+	return pkiutil.ExtractIDs(cert.Extensions)
+}
+
+// splitSpiffeID splits spiffe://<trust domain>/<path> into the trust domain and the path.
+func splitSpiffeID(id string) (string, string, bool) {
+	if !strings.HasPrefix(id, spiffe.URIPrefix) {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(id, spiffe.URIPrefix)
+	i := strings.Index(rest, "/")
+	if i <= 0 {
+		return "", "", false
+	}
+	return rest[:i], rest[i:], true
+}
@@ -21,8 +21,15 @@ const (
 	CSR           = "csr"
 )
 
+// Start modes of the workload certificate.
+const (
+	normalStart   = "normal"
+	degradedStart = "degraded"
+)
+
 var (
 	RequestType = monitoring.MustCreateLabel("request_type")
+	startMode   = monitoring.MustCreateLabel("mode")
 )
 
 // Metrics for outgoing requests from citadel agent to external services such as token exchange server or a CA.
@@ -48,6 +55,17 @@ var (
 		"num_failed_outgoing_requests",
 		"Number of failed outgoing requests (e.g. to a token exchange server, CA, etc.)",
 		monitoring.WithLabels(RequestType))
+
+	certNotBeforeSkew = monitoring.NewGauge(
+		"cert_not_before_skew_seconds",
+		"Seconds the NotBefore of the last certificate received from the CA is ahead of the local time. "+
+			"Positive values mean the certificate was not yet valid when received, because of clock skew.")
+
+	certStartMode = monitoring.NewSum(
+		"cert_start_mode",
+		"Number of times the first workload certificate was issued by the CA (normal), or loaded from the cached "+
+			"certificates because the CA was unreachable at startup (degraded).",
+		monitoring.WithLabels(startMode))
 )
 
 func init() {
@@ -56,5 +74,7 @@ func init() {
 		numOutgoingRequests,
 		numOutgoingRetries,
 		numFailedOutgoingRequests,
+		certNotBeforeSkew,
+		certStartMode,
 	)
 }
@@ -147,6 +147,11 @@ type SecretCache struct {
 	// unique certs being watched with file watcher.
 	fileCerts map[string]map[ConnKey]struct{}
 	certMutex *sync.RWMutex
+
+	// started is set once the first workload certificate was generated, and degraded while the workload
+	// certificate is the cached one, the CA being unreachable at startup. See CachedCertsStartTimeout.
+	started  int32
+	degraded int32
 }
 
 // NewSecretCache creates a new secret cache.
@@ -226,7 +231,7 @@ func (sc *SecretCache) GenerateSecret(ctx context.Context, connectionID, resourc
 		// If working as Citadel agent, send request for normal key/cert pair.
 		// If working as ingress gateway agent, fetch key/cert or root cert from SecretFetcher. Resource name for
 		// root cert ends with "-cacert".
-		ns, err := sc.generateSecret(ctx, token, connKey, time.Now())
+		ns, pending, err := sc.generateSecretOrCached(ctx, token, connKey, time.Now())
 		if err != nil {
 			cacheLog.Errorf("%s failed to generate secret for proxy: %v",
 				logPrefix, err)
@@ -235,6 +240,9 @@ func (sc *SecretCache) GenerateSecret(ctx context.Context, connectionID, resourc
 
 		cacheLog.Infoa("GenerateSecret ", resourceName)
 		sc.secrets.Store(connKey, *ns)
+		if pending != nil {
+			go sc.replaceCachedSecret(connKey, pending)
+		}
 		return ns, nil
 	}
 
@@ -617,6 +625,9 @@ func (sc *SecretCache) rotate(updateRootFlag bool) {
 					cacheLog.Errorf("%s failed to rotate secret: %v", logPrefix, err)
 					return
 				}
+				if atomic.CompareAndSwapInt32(&sc.degraded, 1, 0) {
+					cacheLog.Infof("%s the CA is reachable, the cached certificate is replaced", logPrefix)
+				}
 				// Output the key and cert to dir to make sure key and cert are rotated.
 				if err = nodeagentutil.OutputKeyCertToDir(sc.configOptions.OutputKeyCertToDir, ns.PrivateKey,
 					ns.CertificateChain, ns.RootCert); err != nil {
@@ -887,6 +898,11 @@ func (sc *SecretCache) generateSecret(ctx context.Context, token string, connKey
 		return nil, fmt.Errorf("failed to extract expire time from server certificate in CSR response: %v", err)
 	}
 
+	if err := sc.checkCertNotBefore(certChain, time.Now()); err != nil {
+		cacheLog.Errorf("%s %v", logPrefix, err)
+		return nil, err
+	}
+
 	length := len(certChainPEM)
 	rootCert, _ := sc.getRootCert()
 	// Leaf cert is element '0'. Root cert is element 'n'.
@@ -917,7 +933,32 @@ func (sc *SecretCache) generateSecret(ctx context.Context, token string, connKey
 	}, nil
 }
 
+// checkCertNotBefore records the skew between the NotBefore of the certificate received from the CA and the local
+// time. A certificate not yet valid is used if it is valid within CertNotBeforeTolerance, the CA and the agent
+// clocks being slightly skewed, and is rejected otherwise.
+func (sc *SecretCache) checkCertNotBefore(certChain []byte, now time.Time) error {
+	notBefore, err := nodeagentutil.ParseCertAndGetNotBeforeTimestamp(certChain)
+	if err != nil {
+		return fmt.Errorf("failed to extract the NotBefore of the server certificate in CSR response: %v", err)
+	}
+	skew := notBefore.Sub(now)
+	certNotBeforeSkew.Record(skew.Seconds())
+	if skew <= 0 {
+		return nil
+	}
+	if skew > sc.configOptions.CertNotBeforeTolerance {
+		return fmt.Errorf("certificate in CSR response is only valid from %v, %v in the future, more than the tolerated %v",
+			notBefore, skew, sc.configOptions.CertNotBeforeTolerance)
+	}
+	cacheLog.Warnf("certificate in CSR response is only valid from %v, %v in the future: the clock is skewed", notBefore, skew)
+	return nil
+}
+
 func (sc *SecretCache) shouldRotate(secret *security.SecretItem) bool {
+	// The cached certificate used while the CA is unreachable is replaced as soon as possible.
+	if atomic.LoadInt32(&sc.degraded) == 1 {
+		return true
+	}
 	// secret should be rotated before it expired.
 	secretLifeTime := secret.ExpireTime.Sub(secret.CreatedTime)
 	gracePeriod := time.Duration(sc.configOptions.SecretRotationGracePeriodRatio * float64(secretLifeTime))
@@ -37,6 +37,7 @@ import (
 	"istio.io/istio/security/pkg/nodeagent/cache/mock"
 	"istio.io/istio/security/pkg/nodeagent/secretfetcher"
 	nodeagentutil "istio.io/istio/security/pkg/nodeagent/util"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
 	"istio.io/pkg/filewatcher"
 )
 
@@ -1232,3 +1233,161 @@ func TestShouldRotate(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckCertNotBefore(t *testing.T) {
+	now := time.Now()
+	sc := &SecretCache{configOptions: &security.Options{CertNotBeforeTolerance: time.Minute}}
+
+	testCases := map[string]struct {
+		notBefore time.Time
+		accepted  bool
+	}{
+		"Valid": {
+			notBefore: now.Add(-time.Minute),
+			accepted:  true,
+		},
+		"Not yet valid within the tolerance": {
+			notBefore: now.Add(30 * time.Second),
+			accepted:  true,
+		},
+		"Not yet valid beyond the tolerance": {
+			notBefore: now.Add(2 * time.Minute),
+			accepted:  false,
+		},
+	}
+
+	for name, tc := range testCases {
+		certPEM, _, err := pkiutil.GenCertKeyFromOptions(pkiutil.CertOptions{
+			Host:         "spiffe://cluster.local/ns/default/sa/default",
+			NotBefore:    tc.notBefore,
+			TTL:          time.Hour,
+			IsSelfSigned: true,
+			RSAKeySize:   2048,
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to generate the certificate: %v", name, err)
+		}
+		if err := sc.checkCertNotBefore(certPEM, now); (err == nil) != tc.accepted {
+			t.Errorf("%s: unexpected checkCertNotBefore error %v, expected the certificate accepted: %v", name, err, tc.accepted)
+		}
+	}
+}
+
+func TestCachedCertsStart(t *testing.T) {
+	originalTimeout := totalTimeout
+	totalTimeout = 500 * time.Millisecond
+	defer func() {
+		totalTimeout = originalTimeout
+	}()
+
+	// saToken is an unsigned JWT for the service account default/default.
+	saToken := "eyJhbGciOiJub25lIn0.eyJzdWIiOiJzeXN0ZW06c2VydmljZWFjY291bnQ6ZGVmYXVsdDpkZWZhdWx0In0.signature"
+
+	writeCachedCerts := func(t *testing.T, host string, notBefore time.Time) string {
+		dir := t.TempDir()
+		certPEM, keyPEM, err := pkiutil.GenCertKeyFromOptions(pkiutil.CertOptions{
+			Host:         host,
+			NotBefore:    notBefore,
+			TTL:          time.Hour,
+			IsSelfSigned: true,
+			RSAKeySize:   2048,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := nodeagentutil.OutputKeyCertToDir(dir, keyPEM, certPEM, certPEM); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	newCache := func(caErrors uint64, dir string) (*SecretCache, *mock.CAClient) {
+		fakeCACli, err := mock.NewMockCAClient(caErrors, time.Hour)
+		if err != nil {
+			t.Fatalf("Error creating Mock CA client: %v", err)
+		}
+		opt := &security.Options{
+			RotationInterval:        time.Hour,
+			OutputKeyCertToDir:      dir,
+			CachedCertsStartTimeout: 10 * time.Millisecond,
+		}
+		return NewSecretCache(&secretfetcher.SecretFetcher{CaClient: fakeCACli}, notifyCb, opt), fakeCACli
+	}
+
+	t.Run("valid cached certs with unreachable CA", func(t *testing.T) {
+		dir := writeCachedCerts(t, "spiffe://cluster.local/ns/default/sa/default", time.Now().Add(-time.Minute))
+		sc, _ := newCache(1000, dir)
+		defer sc.Close()
+
+		cached, err := ioutil.ReadFile(filepath.Join(dir, "cert-chain.pem"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret, err := sc.GenerateSecret(context.Background(), "proxy1-id", WorkloadKeyCertResourceName, saToken)
+		if err != nil {
+			t.Fatalf("expected the cached certificate to be used, got %v", err)
+		}
+		if !bytes.Equal(secret.CertificateChain, cached) {
+			t.Errorf("got certificate chain %s, want the cached one", secret.CertificateChain)
+		}
+		if atomic.LoadInt32(&sc.degraded) != 1 {
+			t.Error("expected a degraded start")
+		}
+		if rootCert, _ := sc.getRootCert(); !bytes.Equal(rootCert, cached) {
+			t.Errorf("got root cert %s, want the cached one", rootCert)
+		}
+	})
+
+	t.Run("cached certs replaced once the CA responds", func(t *testing.T) {
+		dir := writeCachedCerts(t, "spiffe://cluster.local/ns/default/sa/default", time.Now().Add(-time.Minute))
+		// The CA responds after two retries, longer than CachedCertsStartTimeout.
+		sc, fakeCACli := newCache(2, dir)
+		defer sc.Close()
+
+		if _, err := sc.GenerateSecret(context.Background(), "proxy1-id", WorkloadKeyCertResourceName, saToken); err != nil {
+			t.Fatal(err)
+		}
+		connKey := ConnKey{ConnectionID: "proxy1-id", ResourceName: WorkloadKeyCertResourceName}
+		var want []byte
+		for i := 0; ; i++ {
+			if atomic.LoadInt32(&sc.degraded) == 0 {
+				want = []byte(strings.Join(fakeCACli.GeneratedCerts[0], ""))
+				if v, f := sc.secrets.Load(connKey); f && bytes.Equal(v.(security.SecretItem).CertificateChain, want) {
+					break
+				}
+			}
+			if i == 100 {
+				t.Fatal("the cached certificate was not replaced by the one issued by the CA")
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if written, _ := ioutil.ReadFile(filepath.Join(dir, "cert-chain.pem")); !bytes.Equal(written, want) {
+			t.Error("the certificate issued by the CA was not written to the output directory")
+		}
+	})
+
+	t.Run("expired cached certs", func(t *testing.T) {
+		dir := writeCachedCerts(t, "spiffe://cluster.local/ns/default/sa/default", time.Now().Add(-2*time.Hour))
+		sc, _ := newCache(1000, dir)
+		defer sc.Close()
+
+		if _, err := sc.GenerateSecret(context.Background(), "proxy1-id", WorkloadKeyCertResourceName, saToken); err == nil {
+			t.Fatal("expected the expired cached certificate not to be used")
+		}
+		if atomic.LoadInt32(&sc.degraded) != 0 {
+			t.Error("expected no degraded start")
+		}
+	})
+
+	t.Run("cached certs of another identity", func(t *testing.T) {
+		dir := writeCachedCerts(t, "spiffe://cluster.local/ns/default/sa/other", time.Now().Add(-time.Minute))
+		sc, _ := newCache(1000, dir)
+		defer sc.Close()
+
+		if _, err := sc.GenerateSecret(context.Background(), "proxy1-id", WorkloadKeyCertResourceName, saToken); err == nil {
+			t.Fatal("expected the cached certificate of another identity not to be used")
+		}
+		if atomic.LoadInt32(&sc.degraded) != 0 {
+			t.Error("expected no degraded start")
+		}
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/log"
+)
+
+var failoverLog = log.RegisterScope("cafailover", "CA failover debugging", 0)
+
+// Endpoint is one of the CAs a Client sends CSRs to.
+type Endpoint struct {
+	// Provider names the CA in logs and metrics.
+	Provider string
+	Address  string
+	Client   security.Client
+	// Timeout bounds a single CSR to this CA. If zero, only the deadline of the caller applies.
+	Timeout time.Duration
+}
+
+// Client signs CSRs with the first of several CAs which succeeds. The CA which last succeeded is tried first,
+// so a down primary only costs the failover latency until a fallback has succeeded once.
+type Client struct {
+	endpoints []Endpoint
+	// how long to stay on a fallback CA before trying the primary again. Zero never tries it again.
+	reprobeInterval time.Duration
+	now             func() time.Time
+
+	mu sync.Mutex
+	// index of the endpoint which last succeeded.
+	active int
+	// when the primary was last given up on, or last re-probed.
+	lastPrimaryAttempt time.Time
+}
+
+var _ security.Client = &Client{}
+
+// NewClient creates a Client for the endpoints, in order of preference.
+func NewClient(endpoints []Endpoint, reprobeInterval time.Duration) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("at least one CA endpoint is required")
+	}
+	return &Client{
+		endpoints:       endpoints,
+		reprobeInterval: reprobeInterval,
+		now:             time.Now,
+	}, nil
+}
+
+// CSRSign sends the CSR to each CA in turn, until one of them signs it.
+func (c *Client) CSRSign(ctx context.Context, reqID string, csrPEM []byte, subjectID string,
+	certValidTTLInSec int64) ([]string, error) {
+	var errs *multierror.Error
+	for _, i := range c.attemptOrder() {
+		ep := c.endpoints[i]
+		certs, err := c.sign(ctx, ep, reqID, csrPEM, subjectID, certValidTTLInSec)
+		if err == nil {
+			issuanceAttempts.With(providerTag.Value(ep.Provider), resultTag.Value(resultSuccess)).Increment()
+			c.succeeded(i)
+			return certs, nil
+		}
+		issuanceAttempts.With(providerTag.Value(ep.Provider), resultTag.Value(resultFailure)).Increment()
+		failoverLog.Warnf("CA %s (%s) failed to sign CSR %s: %v", ep.Provider, ep.Address, reqID, err)
+		errs = multierror.Append(errs, fmt.Errorf("%s (%s): %v", ep.Provider, ep.Address, err))
+		if ctx.Err() != nil {
+			// the caller has given up, don't wait on the remaining CAs.
+			break
+		}
+	}
+	return nil, fmt.Errorf("no CA was able to sign CSR %s: %v", reqID, errs.ErrorOrNil())
+}
+
+func (c *Client) sign(ctx context.Context, ep Endpoint, reqID string, csrPEM []byte, subjectID string,
+	certValidTTLInSec int64) ([]string, error) {
+	if ep.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ep.Timeout)
+		defer cancel()
+	}
+	return ep.Client.CSRSign(ctx, reqID, csrPEM, subjectID, certValidTTLInSec)
+}
+
+// attemptOrder returns the indexes of the endpoints in the order they should be tried: the one which last
+// succeeded, or the primary if it is due to be re-probed, followed by the rest in order of preference.
+func (c *Client) attemptOrder() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	first := c.active
+	if first != 0 && c.reprobeInterval > 0 && c.now().Sub(c.lastPrimaryAttempt) >= c.reprobeInterval {
+		// only one CSR per interval pays for probing a primary which is still down.
+		c.lastPrimaryAttempt = c.now()
+		first = 0
+	}
+	order := make([]int, 0, len(c.endpoints))
+	order = append(order, first)
+	for i := range c.endpoints {
+		if i != first {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+func (c *Client) succeeded(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i == c.active {
+		return
+	}
+	if i == 0 {
+		failoverLog.Infof("CA %s (%s) has recovered, no longer failing over", c.endpoints[i].Provider, c.endpoints[i].Address)
+	} else {
+		failoverLog.Warnf("Failing over to CA %s (%s)", c.endpoints[i].Provider, c.endpoints[i].Address)
+		if c.active == 0 {
+			c.lastPrimaryAttempt = c.now()
+		}
+	}
+	c.active = i
+}
@@ -0,0 +1,175 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeCA struct {
+	mu    sync.Mutex
+	name  string
+	down  bool
+	calls int
+}
+
+func (f *fakeCA) CSRSign(ctx context.Context, reqID string, csrPEM []byte, subjectID string,
+	certValidTTLInSec int64) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.down {
+		return nil, errors.New(f.name + " is unavailable")
+	}
+	return []string{f.name + "-cert", f.name + "-root"}, nil
+}
+
+func (f *fakeCA) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *fakeCA) takeCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := f.calls
+	f.calls = 0
+	return calls
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Step(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+func newTestClient(t *testing.T, reprobeInterval time.Duration) (*Client, *fakeCA, *fakeCA, *fakeClock) {
+	primary := &fakeCA{name: "primary"}
+	fallback := &fakeCA{name: "fallback"}
+	c, err := NewClient([]Endpoint{
+		{Provider: "Custom", Address: "ca.example.com:443", Client: primary, Timeout: time.Second},
+		{Provider: "Citadel", Address: "istiod:15012", Client: fallback, Timeout: time.Second},
+	}, reprobeInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Now()}
+	c.now = clock.Now
+	return c, primary, fallback, clock
+}
+
+func expectSignedBy(t *testing.T, c *Client, ca string) {
+	t.Helper()
+	certs, err := c.CSRSign(context.Background(), "req", []byte("csr"), "", 3600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if certs[0] != ca+"-cert" {
+		t.Fatalf("expected CSR to be signed by %s, got %v", ca, certs)
+	}
+}
+
+func TestPrimaryDownThenRecovered(t *testing.T) {
+	c, primary, fallback, clock := newTestClient(t, time.Minute)
+
+	expectSignedBy(t, c, "primary")
+	if primary.takeCalls() != 1 || fallback.takeCalls() != 0 {
+		t.Fatalf("expected only the primary to be used while it is up")
+	}
+
+	primary.setDown(true)
+	expectSignedBy(t, c, "fallback")
+	if primary.takeCalls() != 1 || fallback.takeCalls() != 1 {
+		t.Fatalf("expected the primary to be tried before failing over")
+	}
+
+	// the fallback is remembered, later CSRs don't pay for the primary being down.
+	expectSignedBy(t, c, "fallback")
+	if primary.takeCalls() != 0 || fallback.takeCalls() != 1 {
+		t.Fatalf("expected the fallback to be used directly after failing over")
+	}
+
+	// the primary is re-probed once the interval has passed, and is given up on again while it is down.
+	clock.Step(time.Minute)
+	expectSignedBy(t, c, "fallback")
+	if primary.takeCalls() != 1 || fallback.takeCalls() != 1 {
+		t.Fatalf("expected the primary to be re-probed")
+	}
+	expectSignedBy(t, c, "fallback")
+	if primary.takeCalls() != 0 {
+		t.Fatalf("expected the primary not to be re-probed again within the interval")
+	}
+	fallback.takeCalls()
+
+	primary.setDown(false)
+	clock.Step(time.Minute)
+	expectSignedBy(t, c, "primary")
+	expectSignedBy(t, c, "primary")
+	if primary.takeCalls() != 2 || fallback.takeCalls() != 0 {
+		t.Fatalf("expected the recovered primary to be used again")
+	}
+}
+
+func TestAllCAsDown(t *testing.T) {
+	c, primary, fallback, _ := newTestClient(t, time.Minute)
+	primary.setDown(true)
+	fallback.setDown(true)
+
+	_, err := c.CSRSign(context.Background(), "req", []byte("csr"), "", 3600)
+	if err == nil {
+		t.Fatal("expected an error when no CA is available")
+	}
+	// the error must describe every CA which was tried.
+	for _, want := range []string{"primary is unavailable", "fallback is unavailable", "ca.example.com:443", "istiod:15012"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error %q to contain %q", err, want)
+		}
+	}
+
+	// once a CA recovers, signing succeeds again.
+	fallback.setDown(false)
+	expectSignedBy(t, c, "fallback")
+}
+
+func TestCallerCancelled(t *testing.T) {
+	c, primary, fallback, _ := newTestClient(t, time.Minute)
+	primary.setDown(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CSRSign(ctx, "req", []byte("csr"), "", 3600); err == nil {
+		t.Fatal("expected an error")
+	}
+	if fallback.takeCalls() != 0 {
+		t.Fatalf("expected no failover once the caller has given up")
+	}
+}
+
+func TestNewClientRequiresEndpoints(t *testing.T) {
+	if _, err := NewClient(nil, time.Minute); err == nil {
+		t.Fatal("expected an error without endpoints")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failover
+
+import "istio.io/pkg/monitoring"
+
+var (
+	providerTag = monitoring.MustCreateLabel("provider")
+	resultTag   = monitoring.MustCreateLabel("result")
+
+	issuanceAttempts = monitoring.NewSum(
+		"num_ca_issuance_attempts",
+		"Number of CSRs sent to each CA provider when failover between CAs is configured.",
+		monitoring.WithLabels(providerTag, resultTag))
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+func init() {
+	monitoring.MustRegister(issuanceAttempts)
+}
@@ -39,6 +39,20 @@ func ParseCertAndGetExpiryTimestamp(certByte []byte) (time.Time, error) {
 	return cert.NotAfter, nil
 }
 
+// ParseCertAndGetNotBeforeTimestamp parses the first certificate in certByte and returns the time it is valid
+// from, or return error if fails to parse certificate.
+func ParseCertAndGetNotBeforeTimestamp(certByte []byte) (time.Time, error) {
+	block, _ := pem.Decode(certByte)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("failed to decode certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	return cert.NotBefore, nil
+}
+
 // GetMetricsCounterValue returns counter value in float64. For test purpose only.
 func GetMetricsCounterValue(metricName string) (float64, error) {
 	rows, err := view.RetrieveData(metricName)
@@ -78,6 +78,9 @@ type IstioCAOptions struct {
 	DefaultCertTTL time.Duration
 	MaxCertTTL     time.Duration
 	CARSAKeySize   int
+	// CertBackdate is how long before the time they are signed the certificates are valid, to tolerate the
+	// clock skew of the workloads.
+	CertBackdate time.Duration
 
 	KeyCertBundle util.KeyCertBundle
 
@@ -247,6 +250,7 @@ type IstioCA struct {
 	defaultCertTTL time.Duration
 	maxCertTTL     time.Duration
 	caRSAKeySize   int
+	certBackdate   time.Duration
 
 	keyCertBundle util.KeyCertBundle
 
@@ -264,6 +268,7 @@ func NewIstioCA(opts *IstioCAOptions) (*IstioCA, error) {
 		keyCertBundle: opts.KeyCertBundle,
 		livenessProbe: probe.NewProbe(),
 		caRSAKeySize:  opts.CARSAKeySize,
+		certBackdate:  opts.CertBackdate,
 	}
 
 	if opts.CAType == selfSignedCA && opts.RotatorConfig.CheckInterval > time.Duration(0) {
@@ -314,7 +319,8 @@ func (ca *IstioCA) Sign(csrPEM []byte, subjectIDs []string, requestedLifetime ti
 			"requested TTL %s is greater than the max allowed TTL %s", requestedLifetime, ca.maxCertTTL))
 	}
 
-	certBytes, err := util.GenCertFromCSR(csr, signingCert, csr.PublicKey, *signingKey, subjectIDs, lifetime, forCA)
+	certBytes, err := util.GenCertFromCSRWithBackdate(csr, signingCert, csr.PublicKey, *signingKey, subjectIDs, lifetime,
+		ca.certBackdate, forCA)
 	if err != nil {
 		return nil, caerror.NewError(caerror.CertGenError, err)
 	}
@@ -219,10 +219,24 @@ func MergeCertOptions(defaultOpts, deltaOpts CertOptions) CertOptions {
 // GenCertFromCSR generates a X.509 certificate with the given CSR.
 func GenCertFromCSR(csr *x509.CertificateRequest, signingCert *x509.Certificate, publicKey interface{},
 	signingKey crypto.PrivateKey, subjectIDs []string, ttl time.Duration, isCA bool) (cert []byte, err error) {
+	return GenCertFromCSRWithBackdate(csr, signingCert, publicKey, signingKey, subjectIDs, ttl, 0, isCA)
+}
+
+// GenCertFromCSRWithBackdate generates a X.509 certificate with the given CSR, valid from backdate before the
+// current time so that it is not rejected as not yet valid by hosts whose clock is behind. The NotBefore of the
+// certificate is not set before the one of the signing certificate, and its NotAfter is not changed.
+func GenCertFromCSRWithBackdate(csr *x509.CertificateRequest, signingCert *x509.Certificate, publicKey interface{},
+	signingKey crypto.PrivateKey, subjectIDs []string, ttl, backdate time.Duration, isCA bool) (cert []byte, err error) {
 	tmpl, err := genCertTemplateFromCSR(csr, subjectIDs, ttl, isCA)
 	if err != nil {
 		return nil, err
 	}
+	if backdate > 0 {
+		tmpl.NotBefore = tmpl.NotBefore.Add(-backdate)
+		if signingCert != nil && tmpl.NotBefore.Before(signingCert.NotBefore) {
+			tmpl.NotBefore = signingCert.NotBefore
+		}
+	}
 	return x509.CreateCertificate(rand.Reader, tmpl, signingCert, publicKey, signingKey)
 }
 
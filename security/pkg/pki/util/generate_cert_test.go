@@ -670,6 +670,74 @@ func TestGenCertFromCSR(t *testing.T) {
 	}
 }
 
+func TestGenCertFromCSRWithBackdate(t *testing.T) {
+	keycert, err := NewVerifiedKeyCertBundleFromFile("../testdata/cert.pem", "../testdata/key.pem", "", "../testdata/cert.pem")
+	if err != nil {
+		t.Fatalf("Failed to load CA key and cert from files: %v", err)
+	}
+	signingCert, signingKey, _, _ := keycert.GetAll()
+
+	signeeKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate signee key pair %v", err)
+	}
+	derBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		SignatureAlgorithm: x509.SHA256WithRSA,
+		Version:            3,
+	}, signeeKey)
+	if err != nil {
+		t.Fatal("failed to create certificate request")
+	}
+	csr, err := x509.ParseCertificateRequest(derBytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificate request %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		backdate time.Duration
+		// notBefore returns the expected NotBefore of a certificate issued at now.
+		notBefore func(now time.Time) time.Time
+	}{
+		{
+			name:      "No backdate",
+			notBefore: func(now time.Time) time.Time { return now },
+		},
+		{
+			name:      "Backdate",
+			backdate:  time.Minute,
+			notBefore: func(now time.Time) time.Time { return now.Add(-time.Minute) },
+		},
+		{
+			name:      "Backdate before the signing certificate",
+			backdate:  100 * 365 * 24 * time.Hour,
+			notBefore: func(time.Time) time.Time { return signingCert.NotBefore },
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			now := time.Now()
+			derBytes, err := GenCertFromCSRWithBackdate(csr, signingCert, &signeeKey.PublicKey, *signingKey,
+				[]string{"spiffe://test.com/abc/def"}, time.Hour, c.backdate, false)
+			if err != nil {
+				t.Fatalf("failed to GenCertFromCSRWithBackdate, error %v", err)
+			}
+			out, err := x509.ParseCertificate(derBytes)
+			if err != nil {
+				t.Fatalf("failed to parse generated certificate %v", err)
+			}
+			// The certificate validity has a second precision.
+			if want := c.notBefore(now); out.NotBefore.Sub(want) > time.Second || want.Sub(out.NotBefore) > time.Second {
+				t.Errorf("unexpected NotBefore, expected %v, got %v", want, out.NotBefore)
+			}
+			if want := now.Add(time.Hour); out.NotAfter.Sub(want) > time.Second || want.Sub(out.NotAfter) > time.Second {
+				t.Errorf("the NotAfter should not be backdated, expected %v, got %v", want, out.NotAfter)
+			}
+		})
+	}
+}
+
 func TestLoadSignerCredsFromFiles(t *testing.T) {
 	testCases := map[string]struct {
 		certFile    string
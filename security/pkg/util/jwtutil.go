@@ -79,6 +79,22 @@ func GetAud(token string) ([]string, error) {
 	return nil, err
 }
 
+// GetServiceAccount returns the namespace and the service account of the claim `sub` of a K8S token,
+// `system:serviceaccount:<namespace>:<service account>`, without validating it.
+func GetServiceAccount(token string) (string, string, error) {
+	claims, err := parseJwtClaims(token)
+	if err != nil {
+		return "", "", err
+	}
+
+	sub, _ := claims["sub"].(string)
+	parts := strings.Split(sub, ":")
+	if len(parts) != 4 || parts[0] != "system" || parts[1] != "serviceaccount" || parts[2] == "" || parts[3] == "" {
+		return "", "", fmt.Errorf("the sub %q of the token is not a service account", sub)
+	}
+	return parts[2], parts[3], nil
+}
+
 type jwtPayload struct {
 	// Aud is JWT token audience - used to identify 3p tokens.
 	// It is empty for the default K8S tokens.
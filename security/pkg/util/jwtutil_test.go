@@ -148,3 +148,43 @@ func Test3p(t *testing.T) {
 		t.Error("Expecting unbound, detected bound ", firstPartyJwt)
 	}
 }
+
+func TestGetServiceAccount(t *testing.T) {
+	testCases := map[string]struct {
+		jwt       string
+		namespace string
+		sa        string
+		wantErr   bool
+	}{
+		"first party": {
+			jwt:       firstPartyJwt,
+			namespace: "foo",
+			sa:        "httpbin",
+		},
+		"third party": {
+			jwt:       thirdPartyJwt,
+			namespace: "foo",
+			sa:        "httpbin",
+		},
+		"not a service account": {
+			jwt:     oneAudString,
+			wantErr: true,
+		},
+		"invalid token": {
+			jwt:     "InvalidToken",
+			wantErr: true,
+		},
+	}
+
+	for id, tc := range testCases {
+		t.Run(id, func(t *testing.T) {
+			namespace, sa, err := GetServiceAccount(tc.jwt)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("want error %v but got %v", tc.wantErr, err)
+			}
+			if namespace != tc.namespace || sa != tc.sa {
+				t.Errorf("want %s/%s but got %s/%s", tc.namespace, tc.sa, namespace, sa)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"istio.io/istio/tools/istio-iptables/pkg/config"
+	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
+)
+
+// DryRunSummary describes the decisions taken when rendering the commands of a dry run.
+type DryRunSummary struct {
+	Config *config.Config `json:"config"`
+	// DNSCapture is true if the rules redirecting DNS to the agent are rendered.
+	DNSCapture bool          `json:"dnsCapture"`
+	IPv4       IPFamilyRules `json:"ipv4"`
+	IPv6       IPFamilyRules `json:"ipv6"`
+	// Commands is the number of rendered commands, including the ones configuring routing.
+	Commands int `json:"commands"`
+}
+
+// IPFamilyRules describes the rules rendered for an IP family.
+type IPFamilyRules struct {
+	// Enabled is false when no rules are rendered for the family.
+	Enabled                 bool     `json:"enabled"`
+	OutboundIPRangesInclude []string `json:"outboundIPRangesInclude"`
+	OutboundIPRangesExclude []string `json:"outboundIPRangesExclude"`
+	Rules                   int      `json:"rules"`
+}
+
+// dryRun validates the configuration and renders the commands it would run to out, one per line,
+// followed by a single line JSON summary. Nothing is executed.
+func dryRun(cfg *config.Config, out io.Writer) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	cfg.DryRun = true
+	ext := &dep.RecordingStubDependencies{}
+	iptConfigurator := NewIptablesConfigurator(cfg, ext)
+	iptConfigurator.run()
+
+	summary, err := iptConfigurator.dryRunSummary(len(ext.Commands))
+	if err != nil {
+		return err
+	}
+	for _, cmd := range FormatIptablesCommands(ext.Commands) {
+		if _, err := fmt.Fprintln(out, cmd); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(b))
+	return err
+}
+
+func (iptConfigurator *IptablesConfigurator) dryRunSummary(commands int) (*DryRunSummary, error) {
+	ipv4RangesExclude, ipv6RangesExclude, err := iptConfigurator.separateV4V6(iptConfigurator.cfg.OutboundIPRangesExclude)
+	if err != nil {
+		return nil, err
+	}
+	ipv4RangesInclude, ipv6RangesInclude, err := iptConfigurator.separateV4V6(iptConfigurator.cfg.OutboundIPRangesInclude)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunSummary{
+		Config:     iptConfigurator.cfg,
+		DNSCapture: dnsCaptureByAgent.Get() != "",
+		IPv4: IPFamilyRules{
+			Enabled:                 true,
+			OutboundIPRangesInclude: networkRangeStrings(ipv4RangesInclude),
+			OutboundIPRangesExclude: networkRangeStrings(ipv4RangesExclude),
+			Rules:                   len(iptConfigurator.iptables.BuildV4()),
+		},
+		IPv6: IPFamilyRules{
+			Enabled:                 iptConfigurator.cfg.EnableInboundIPv6,
+			OutboundIPRangesInclude: networkRangeStrings(ipv6RangesInclude),
+			OutboundIPRangesExclude: networkRangeStrings(ipv6RangesExclude),
+			Rules:                   len(iptConfigurator.iptables.BuildV6()),
+		},
+		Commands: commands,
+	}, nil
+}
+
+func networkRangeStrings(r NetworkRange) []string {
+	if r.IsWildcard {
+		return []string{"*"}
+	}
+	res := make([]string, 0, len(r.IPNets))
+	for _, n := range r.IPNets {
+		res = append(res, n.String())
+	}
+	return res
+}
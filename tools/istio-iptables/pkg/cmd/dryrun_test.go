@@ -0,0 +1,198 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"istio.io/istio/tools/istio-iptables/pkg/config"
+)
+
+func runDryRun(t *testing.T, dnsCapture string, modify func(*config.Config)) ([]string, DryRunSummary) {
+	t.Helper()
+	defer func(v string) { dnsCaptureByAgent.DefaultValue = v }(dnsCaptureByAgent.DefaultValue)
+	dnsCaptureByAgent.DefaultValue = dnsCapture
+
+	cfg := constructTestConfig()
+	modify(cfg)
+	var out bytes.Buffer
+	if err := dryRun(cfg, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var summary DryRunSummary
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatalf("last line is not a JSON summary: %v", err)
+	}
+	return lines[:len(lines)-1], summary
+}
+
+func TestDryRunOutboundPortAndUIDExclusions(t *testing.T) {
+	commands, summary := runDryRun(t, "", func(cfg *config.Config) {
+		cfg.ProxyUID = "1337,1338"
+		cfg.ProxyGID = "1337"
+		cfg.InboundPortsInclude = "*"
+		cfg.OutboundIPRangesInclude = "*"
+		cfg.OutboundPortsExclude = "8080,9090"
+	})
+	expected := []string{
+		"iptables -t nat -N ISTIO_INBOUND",
+		"iptables -t nat -N ISTIO_REDIRECT",
+		"iptables -t nat -N ISTIO_IN_REDIRECT",
+		"iptables -t nat -N ISTIO_OUTPUT",
+		"iptables -t nat -A ISTIO_INBOUND -p tcp --dport 15008 -j RETURN",
+		"iptables -t nat -A ISTIO_REDIRECT -p tcp -j REDIRECT --to-ports 15001",
+		"iptables -t nat -A ISTIO_IN_REDIRECT -p tcp -j REDIRECT --to-ports 15006",
+		"iptables -t nat -A PREROUTING -p tcp -j ISTIO_INBOUND",
+		"iptables -t nat -A ISTIO_INBOUND -p tcp --dport 22 -j RETURN",
+		"iptables -t nat -A ISTIO_INBOUND -p tcp -j ISTIO_IN_REDIRECT",
+		"iptables -t nat -A OUTPUT -p tcp -j ISTIO_OUTPUT",
+		"iptables -t nat -A ISTIO_OUTPUT -p tcp --dport 8080 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -p tcp --dport 9090 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -o lo -s 127.0.0.6/32 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -o lo ! -d 127.0.0.1/32 -m owner --uid-owner 1337 -j ISTIO_IN_REDIRECT",
+		"iptables -t nat -A ISTIO_OUTPUT -o lo -m owner ! --uid-owner 1337 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -m owner --uid-owner 1337 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -o lo ! -d 127.0.0.1/32 -m owner --uid-owner 1338 -j ISTIO_IN_REDIRECT",
+		"iptables -t nat -A ISTIO_OUTPUT -o lo -m owner ! --uid-owner 1338 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -m owner --uid-owner 1338 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -o lo ! -d 127.0.0.1/32 -m owner --gid-owner 1337 -j ISTIO_IN_REDIRECT",
+		"iptables -t nat -A ISTIO_OUTPUT -o lo -m owner ! --gid-owner 1337 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -m owner --gid-owner 1337 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -d 127.0.0.1/32 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -j ISTIO_REDIRECT",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", expected, commands)
+	}
+	if summary.DNSCapture || summary.IPv6.Enabled || summary.IPv6.Rules != 0 {
+		t.Errorf("expected IPv4 only rules without DNS capture, got %+v", summary)
+	}
+	if summary.Commands != len(expected) || summary.IPv4.Rules != len(expected) {
+		t.Errorf("expected %d commands in summary, got %+v", len(expected), summary)
+	}
+	if !reflect.DeepEqual(summary.IPv4.OutboundIPRangesInclude, []string{"*"}) {
+		t.Errorf("expected wildcard IPv4 outbound ranges, got %v", summary.IPv4.OutboundIPRangesInclude)
+	}
+}
+
+func TestDryRunDNSCaptureAndIPFamilies(t *testing.T) {
+	commands, summary := runDryRun(t, "ALL", func(cfg *config.Config) {
+		cfg.OutboundIPRangesInclude = "10.0.0.0/8,fd00::/8"
+		cfg.OutboundIPRangesExclude = "10.1.0.0/16"
+	})
+	expectedTail := []string{
+		"iptables -t nat -A ISTIO_OUTPUT -d 127.0.0.1/32 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -d 10.1.0.0/16 -j RETURN",
+		"iptables -t nat -A ISTIO_OUTPUT -d 10.0.0.0/8 -j ISTIO_REDIRECT",
+		"iptables -t nat -A ISTIO_OUTPUT -j RETURN",
+		"iptables -t nat -A OUTPUT -p udp --dport 53 -m owner --uid-owner 1337 -j RETURN",
+		"iptables -t nat -A OUTPUT -p udp --dport 53 -m owner --gid-owner 1337 -j RETURN",
+		"iptables -t nat -A OUTPUT -p udp --dport 53 -j DNAT --to-destination 127.0.0.1:15053",
+		"iptables -t nat -A POSTROUTING -p udp --dport 15053 -j SNAT --to-source 127.0.0.1",
+	}
+	if len(commands) < len(expectedTail) {
+		t.Fatalf("expected at least %d commands, got %#v", len(expectedTail), commands)
+	}
+	if tail := commands[len(commands)-len(expectedTail):]; !reflect.DeepEqual(tail, expectedTail) {
+		t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", expectedTail, tail)
+	}
+	if !summary.DNSCapture {
+		t.Errorf("expected DNS capture in summary")
+	}
+	if !reflect.DeepEqual(summary.IPv4.OutboundIPRangesInclude, []string{"10.0.0.0/8"}) ||
+		!reflect.DeepEqual(summary.IPv4.OutboundIPRangesExclude, []string{"10.1.0.0/16"}) ||
+		!reflect.DeepEqual(summary.IPv6.OutboundIPRangesInclude, []string{"fd00::/8"}) {
+		t.Errorf("unexpected IP family ranges %+v %+v", summary.IPv4, summary.IPv6)
+	}
+	if summary.IPv6.Enabled {
+		t.Errorf("expected IPv6 rules to be disabled for an IPv4 pod")
+	}
+}
+
+func TestDryRunNamedOwners(t *testing.T) {
+	commands, _ := runDryRun(t, "", func(cfg *config.Config) {
+		cfg.ProxyUID = "istio-proxy"
+		cfg.ProxyGID = "istio-proxy"
+	})
+	for _, want := range []string{"--uid-owner istio-proxy", "--gid-owner istio-proxy"} {
+		found := false
+		for _, cmd := range commands {
+			if strings.Contains(cmd, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected a command with %q, got %v", want, commands)
+		}
+	}
+}
+
+func TestDryRunValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*config.Config)
+		errors []string
+	}{
+		{
+			name: "invalid ports",
+			modify: func(cfg *config.Config) {
+				cfg.ProxyPort = "0"
+				cfg.OutboundPortsExclude = "8080,80a"
+				cfg.InboundPortsExclude = "*"
+			},
+			errors: []string{"PROXY_PORT", "OUTBOUND_PORTS_EXCLUDE", "INBOUND_PORTS_EXCLUDE"},
+		},
+		{
+			name: "invalid CIDRs",
+			modify: func(cfg *config.Config) {
+				cfg.OutboundIPRangesInclude = "10.0.0.0/8,10.0.0.1"
+				cfg.OutboundIPRangesExclude = "*"
+			},
+			errors: []string{"OUTBOUND_IPRANGES_INCLUDE", "OUTBOUND_IPRANGES_EXCLUDE"},
+		},
+		{
+			name: "invalid UID",
+			modify: func(cfg *config.Config) {
+				cfg.ProxyUID = "istio proxy"
+			},
+			errors: []string{"PROXY_UID"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := constructTestConfig()
+			tt.modify(cfg)
+			var out bytes.Buffer
+			err := dryRun(cfg, &out)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			for _, e := range tt.errors {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("expected error to mention %s, got %v", e, err)
+				}
+			}
+			if out.Len() != 0 {
+				t.Errorf("expected no commands to be rendered, got %q", out.String())
+			}
+		})
+	}
+}
@@ -38,6 +38,8 @@ var (
 	// Enable interception of DNS.
 	dnsCaptureByAgent = env.RegisterStringVar("ISTIO_META_DNS_CAPTURE", "",
 		"If set, enable the capture of outgoing DNS packets on port 53, redirecting to istio-agent on :15053")
+	dryRunVar = env.RegisterBoolVar("IPTABLES_DRY_RUN", false,
+		"If set, validate the configuration and print the iptables commands instead of running them")
 )
 
 var rootCmd = &cobra.Command{
@@ -46,14 +48,14 @@ var rootCmd = &cobra.Command{
 	Long:  "Script responsible for setting up port forwarding for Istio sidecar.",
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := constructConfig()
-		var ext dep.Dependencies
 		if cfg.DryRun {
-			ext = &dep.StdoutStubDependencies{}
-		} else {
-			ext = &dep.RealDependencies{}
+			if err := dryRun(cfg, os.Stdout); err != nil {
+				handleError(err)
+			}
+			return
 		}
 
-		iptConfigurator := NewIptablesConfigurator(cfg, ext)
+		iptConfigurator := NewIptablesConfigurator(cfg, &dep.RealDependencies{})
 		if !cfg.SkipRuleApply {
 			iptConfigurator.run()
 		}
@@ -74,7 +76,7 @@ var rootCmd = &cobra.Command{
 
 func constructConfig() *config.Config {
 	cfg := &config.Config{
-		DryRun:                  viper.GetBool(constants.DryRun),
+		DryRun:                  viper.GetBool(constants.DryRun) || dryRunVar.Get(),
 		RestoreFormat:           viper.GetBool(constants.RestoreFormat),
 		ProxyPort:               viper.GetString(constants.EnvoyPort),
 		InboundCapturePort:      viper.GetString(constants.InboundCapturePort),
@@ -260,7 +262,9 @@ func init() {
 	}
 	viper.SetDefault(constants.InboundTProxyRouteTable, "133")
 
-	rootCmd.Flags().BoolP(constants.DryRun, "n", false, "Do not call any external dependencies like iptables")
+	rootCmd.Flags().BoolP(constants.DryRun, "n", false,
+		"Validate the configuration and print the commands, one per line, followed by a JSON summary, "+
+			"without calling any external dependencies like iptables (default $IPTABLES_DRY_RUN)")
 	if err := viper.BindPFlag(constants.DryRun, rootCmd.Flags().Lookup(constants.DryRun)); err != nil {
 		handleError(err)
 	}
@@ -339,6 +339,10 @@ func (iptConfigurator *IptablesConfigurator) shortCircuitKubeInternalInterface()
 
 func (iptConfigurator *IptablesConfigurator) run() {
 	defer func() {
+		if iptConfigurator.cfg.DryRun {
+			// Nothing was applied, so there is nothing to dump.
+			return
+		}
 		// Best effort since we don't know if the commands exist
 		_ = iptConfigurator.ext.Run(constants.IPTABLESSAVE)
 		if iptConfigurator.cfg.EnableInboundIPv6 {
@@ -368,7 +372,10 @@ func (iptConfigurator *IptablesConfigurator) run() {
 	if dnsCaptureByAgent.Get() != "" {
 		redirectDNS = true
 	}
-	iptConfigurator.logConfig()
+	if !iptConfigurator.cfg.DryRun {
+		// A dry run prints the configuration in its summary instead.
+		iptConfigurator.logConfig()
+	}
 
 	if iptConfigurator.cfg.EnableInboundIPv6 {
 		//TODO: (abhide): Move this out of this method
@@ -550,7 +557,8 @@ func (iptConfigurator *IptablesConfigurator) executeIptablesRestoreCommand(isIpv
 }
 
 func (iptConfigurator *IptablesConfigurator) executeCommands() {
-	if iptConfigurator.cfg.RestoreFormat {
+	// A dry run renders individual commands, as iptables-restore would require writing rules files.
+	if iptConfigurator.cfg.RestoreFormat && !iptConfigurator.cfg.DryRun {
 		// Execute iptables-restore
 		err := iptConfigurator.executeIptablesRestoreCommand(true)
 		if err != nil {
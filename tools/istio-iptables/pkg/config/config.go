@@ -17,6 +17,10 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"istio.io/pkg/log"
@@ -78,3 +82,93 @@ func (c *Config) Print() {
 	fmt.Printf("ENABLE_INBOUND_IPV6=%t\n", c.EnableInboundIPv6)
 	fmt.Println("")
 }
+
+// Validate checks the ports, UIDs, GIDs and IP ranges of the configuration, returning an error listing
+// all the invalid values.
+func (c *Config) Validate() error {
+	var errs []string
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	check(validatePort("PROXY_PORT", c.ProxyPort))
+	check(validatePort("INBOUND_CAPTURE_PORT", c.InboundCapturePort))
+	check(validatePort("INBOUND_TUNNEL_PORT", c.InboundTunnelPort))
+	check(validatePortList("INBOUND_PORTS_INCLUDE", c.InboundPortsInclude, true))
+	check(validatePortList("INBOUND_PORTS_EXCLUDE", c.InboundPortsExclude, false))
+	check(validatePortList("OUTBOUND_PORTS_INCLUDE", c.OutboundPortsInclude, false))
+	check(validatePortList("OUTBOUND_PORTS_EXCLUDE", c.OutboundPortsExclude, false))
+	check(validateOwnerList("PROXY_UID", c.ProxyUID))
+	check(validateOwnerList("PROXY_GID", c.ProxyGID))
+	check(validateCIDRList("OUTBOUND_IPRANGES_INCLUDE", c.OutboundIPRangesInclude, true))
+	check(validateCIDRList("OUTBOUND_IPRANGES_EXCLUDE", c.OutboundIPRangesExclude, false))
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func validatePort(name, port string) error {
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return fmt.Errorf("%s: %q is not a valid port", name, port)
+	}
+	return nil
+}
+
+func validatePortList(name, ports string, allowWildcard bool) error {
+	if allowWildcard && ports == "*" {
+		return nil
+	}
+	for _, port := range splitList(ports) {
+		if port == "" {
+			continue
+		}
+		if err := validatePort(name, port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownerNamePattern matches the user and group names iptables resolves for --uid-owner and --gid-owner, as
+// allowed by useradd and groupadd.
+var ownerNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]*\$?$`)
+
+// validateOwnerList validates a list of user or group IDs, or names.
+func validateOwnerList(name, owners string) error {
+	for _, owner := range splitList(owners) {
+		if owner == "" {
+			continue
+		}
+		if _, err := strconv.ParseUint(owner, 10, 32); err == nil {
+			continue
+		}
+		if len(owner) > 32 || !ownerNamePattern.MatchString(owner) {
+			return fmt.Errorf("%s: %q is not a valid ID or name", name, owner)
+		}
+	}
+	return nil
+}
+
+func validateCIDRList(name, cidrs string, allowWildcard bool) error {
+	if allowWildcard && cidrs == "*" {
+		return nil
+	}
+	for _, cidr := range splitList(cidrs) {
+		if cidr == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("%s: %q is not a valid CIDR", name, cidr)
+		}
+	}
+	return nil
+}
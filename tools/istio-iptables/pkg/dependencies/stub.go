@@ -38,3 +38,29 @@ func (s *StdoutStubDependencies) Run(cmd string, args ...string) error {
 func (s *StdoutStubDependencies) RunQuietlyAndIgnore(cmd string, args ...string) {
 	fmt.Printf("%s %s\n", cmd, strings.Join(args, " "))
 }
+
+// RecordingStubDependencies implementation of interface Dependencies, which records the commands
+// instead of running them
+type RecordingStubDependencies struct {
+	Commands [][]string
+}
+
+func (s *RecordingStubDependencies) record(cmd string, args ...string) {
+	s.Commands = append(s.Commands, append([]string{cmd}, args...))
+}
+
+// RunOrFail records a command
+func (s *RecordingStubDependencies) RunOrFail(cmd string, args ...string) {
+	s.record(cmd, args...)
+}
+
+// Run records a command
+func (s *RecordingStubDependencies) Run(cmd string, args ...string) error {
+	s.record(cmd, args...)
+	return nil
+}
+
+// RunQuietlyAndIgnore records a command
+func (s *RecordingStubDependencies) RunQuietlyAndIgnore(cmd string, args ...string) {
+	s.record(cmd, args...)
+}